@@ -6,16 +6,21 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"net/http"
 	"net/http/pprof"
 	"runtime"
+	"strings"
 	"sync"
 
 	"github.com/gorilla/mux"
 	yaml "gopkg.in/yaml.v3"
 
+	"github.com/benthosdev/benthos/v4/internal/audit"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/httpserver"
 	"github.com/benthosdev/benthos/v4/internal/log"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
 )
 
 // OptFunc applies an option to an API type during construction.
@@ -25,6 +30,9 @@ type OptFunc func(t *Type)
 func OptWithMiddleware(m func(http.Handler) http.Handler) OptFunc {
 	return func(t *Type) {
 		t.server.Handler = m(t.server.Handler)
+		if t.adminServer != nil {
+			t.adminServer.Handler = m(t.adminServer.Handler)
+		}
 	}
 }
 
@@ -40,6 +48,8 @@ func OptWithTLS(tls *tls.Config) OptFunc {
 // Type implements the Benthos HTTP API.
 type Type struct {
 	conf         Config
+	socketMode   fs.FileMode
+	auth         *authenticator
 	endpoints    map[string]string
 	endpointsMut sync.Mutex
 
@@ -52,6 +62,9 @@ type Type struct {
 	log    log.Modular
 	mux    *mux.Router
 	server *http.Server
+
+	adminMux    *mux.Router
+	adminServer *http.Server
 }
 
 // New creates a new Benthos HTTP API.
@@ -82,13 +95,57 @@ func New(
 		return nil, err
 	}
 
+	socketMode, err := httpserver.ParseSocketMode(conf.SocketMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// When an admin address is configured the metrics, health and
+	// administrative endpoints are served from a separate listener.
+	var adminMux *mux.Router
+	var adminServer *http.Server
+	if conf.Admin.Address != "" {
+		if conf.Admin.CertFile != "" || conf.Admin.KeyFile != "" {
+			if conf.Admin.CertFile == "" || conf.Admin.KeyFile == "" {
+				return nil, errors.New("both admin.cert_file and admin.key_file must be specified, or neither")
+			}
+		}
+		adminMux = mux.NewRouter()
+		adminServer = &http.Server{Addr: conf.Admin.Address}
+		if adminServer.Handler, err = conf.CORS.WrapHandler(adminMux); err != nil {
+			return nil, fmt.Errorf("bad CORS configuration: %w", err)
+		}
+	}
+
+	var auth *authenticator
+	if conf.Auth.Enabled {
+		if auth, err = newAuthenticator(conf.Auth, log); err != nil {
+			return nil, fmt.Errorf("bad auth configuration: %w", err)
+		}
+		if caFile := conf.Auth.MTLS.ClientCAFile; caFile != "" {
+			if adminServer != nil {
+				adminServer.TLSConfig, err = clientCertTLSConfig(conf.Admin.CertFile, conf.Admin.KeyFile, caFile)
+			} else {
+				server.TLSConfig, err = clientCertTLSConfig(conf.CertFile, conf.KeyFile, caFile)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	t := &Type{
-		conf:      conf,
-		endpoints: map[string]string{},
-		handlers:  map[string]http.HandlerFunc{},
-		mux:       gMux,
-		server:    server,
-		log:       log,
+		conf:       conf,
+		socketMode: socketMode,
+		auth:       auth,
+		endpoints:  map[string]string{},
+		handlers:   map[string]http.HandlerFunc{},
+		mux:        gMux,
+		server:     server,
+		log:        log,
+
+		adminMux:    adminMux,
+		adminServer: adminServer,
 	}
 	t.ctx, t.cancel = context.WithCancel(context.Background())
 
@@ -146,6 +203,15 @@ func New(
 		}
 	}
 
+	handleTLSPolicy := func(w http.ResponseWriter, r *http.Request) {
+		resBytes, err := json.Marshal(btls.CurrentPolicy().Describe())
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+		} else {
+			_, _ = w.Write(resBytes)
+		}
+	}
+
 	if t.conf.DebugEndpoints {
 		t.RegisterEndpoint(
 			"/debug/config/json", "DEBUG: Returns the loaded config as JSON.",
@@ -201,6 +267,7 @@ func New(
 	t.RegisterEndpoint("/ping", "Ping me.", handlePing)
 	t.RegisterEndpoint("/version", "Returns the service version.", handleVersion)
 	t.RegisterEndpoint("/endpoints", "Returns this map of endpoints.", handleEndpoints)
+	t.RegisterEndpoint("/tls/policy", "Returns the TLS policy enforced by components.", handleTLSPolicy)
 
 	// If we want to expose a stats endpoint we register the endpoints.
 	if wHandlerFunc := stats.HandlerFunc(); wHandlerFunc != nil {
@@ -220,6 +287,32 @@ func (t *Type) Handler() http.Handler {
 	return t.server.Handler
 }
 
+// AdminHandler returns the underlying http.Handler where metrics, health and
+// administrative paths are registered, which is the same as Handler unless a
+// separate admin listener is configured.
+func (t *Type) AdminHandler() http.Handler {
+	if t.adminServer != nil {
+		return t.adminServer.Handler
+	}
+	return t.server.Handler
+}
+
+// adminPathPrefixes are the paths of endpoints that are served by the admin
+// listener when one is configured.
+var adminPathPrefixes = []string{
+	"/ping", "/ready", "/version", "/endpoints", "/stats", "/metrics",
+	"/debug/", "/streams", "/resources/", "/tls/",
+}
+
+func isAdminPath(path string) bool {
+	for _, p := range adminPathPrefixes {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) || strings.HasPrefix(path, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // RegisterEndpoint registers a http.HandlerFunc under a path with a
 // description that will be displayed under the /endpoints path.
 func (t *Type) RegisterEndpoint(path, desc string, handlerFunc http.HandlerFunc) {
@@ -232,40 +325,134 @@ func (t *Type) RegisterEndpoint(path, desc string, handlerFunc http.HandlerFunc)
 	defer t.handlersMut.Unlock()
 
 	if _, exists := t.handlers[path]; !exists {
-		wrapHandler := t.conf.BasicAuth.WrapHandler(func(w http.ResponseWriter, r *http.Request) {
+		var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
 			t.handlersMut.RLock()
 			h := t.handlers[path]
 			t.handlersMut.RUnlock()
-			h(w, r)
-		})
+			h(w, t.withAuditPrincipal(r))
+		}
+		if t.auth != nil && isManagementPath(path) {
+			handler = t.auth.wrapHandler(path, handler)
+		}
+		wrapHandler := t.conf.BasicAuth.WrapHandler(handler)
 
-		GetMuxRoute(t.mux, path).Handler(wrapHandler)
-		GetMuxRoute(t.mux, t.conf.RootPath+path).Handler(wrapHandler)
+		targetMux := t.mux
+		if t.adminMux != nil && isAdminPath(path) {
+			targetMux = t.adminMux
+		}
+		GetMuxRoute(targetMux, path).Handler(wrapHandler)
+		GetMuxRoute(targetMux, t.conf.RootPath+path).Handler(wrapHandler)
 	}
 	t.handlers[path] = handlerFunc
 }
 
+// withAuditPrincipal ensures that the principal recorded within audit events
+// caused by a request is the caller, and not the user running the process. The
+// username of basic authentication is only trusted when it has been verified,
+// otherwise the caller is identified by its remote address.
+func (t *Type) withAuditPrincipal(r *http.Request) *http.Request {
+	if _, exists := audit.PrincipalFromContext(r.Context()); exists {
+		return r
+	}
+	principal := "anonymous"
+	if r.RemoteAddr != "" {
+		principal += " (" + r.RemoteAddr + ")"
+	}
+	if t.conf.BasicAuth.Enabled {
+		if user, _, ok := r.BasicAuth(); ok && user != "" {
+			principal = user
+		}
+	}
+	return r.WithContext(audit.ContextWithPrincipal(r.Context(), principal))
+}
+
 // ListenAndServe launches the API and blocks until the server closes or fails.
 func (t *Type) ListenAndServe() error {
 	if !t.conf.Enabled {
 		<-t.ctx.Done()
 		return nil
 	}
+	if t.adminServer == nil {
+		return t.listenAndServe()
+	}
+
+	// Both listeners are served until either of them fails, at which point the
+	// other is closed so that a failed listener isn't left unnoticed.
+	errChan, adminErrChan := make(chan error, 1), make(chan error, 1)
+	go func() {
+		errChan <- t.listenAndServe()
+	}()
+	go func() {
+		adminErrChan <- t.adminListenAndServe()
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.log.Error("HTTP server error, closing admin server: %v\n", err)
+			_ = t.adminServer.Close()
+			<-adminErrChan
+			return err
+		}
+		return <-adminErrChan
+	case err = <-adminErrChan:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			t.log.Error("Admin HTTP server error, closing HTTP server: %v\n", err)
+			_ = t.server.Close()
+			<-errChan
+			return err
+		}
+		return <-errChan
+	}
+}
+
+func (t *Type) listenAndServe() error {
 	t.log.Info(
 		"Listening for HTTP requests at: %v\n",
 		"http://"+t.conf.Address,
 	)
+	ln, err := httpserver.Listen(t.conf.Address, t.socketMode)
+	if err != nil {
+		return err
+	}
 	if t.server.TLSConfig != nil {
-		return t.server.ListenAndServeTLS("", "")
+		return t.server.ServeTLS(ln, "", "")
 	}
 	if t.conf.CertFile != "" {
-		return t.server.ListenAndServeTLS(t.conf.CertFile, t.conf.KeyFile)
+		t.server.TLSConfig = btls.PolicyConfig()
+		return t.server.ServeTLS(ln, t.conf.CertFile, t.conf.KeyFile)
+	}
+	return t.server.Serve(ln)
+}
+
+func (t *Type) adminListenAndServe() error {
+	t.log.Info(
+		"Listening for admin HTTP requests at: %v\n",
+		"http://"+t.conf.Admin.Address,
+	)
+	ln, err := httpserver.Listen(t.conf.Admin.Address, t.socketMode)
+	if err != nil {
+		return err
+	}
+	if t.adminServer.TLSConfig != nil {
+		return t.adminServer.ServeTLS(ln, "", "")
 	}
-	return t.server.ListenAndServe()
+	if t.conf.Admin.CertFile != "" {
+		t.adminServer.TLSConfig = btls.PolicyConfig()
+		return t.adminServer.ServeTLS(ln, t.conf.Admin.CertFile, t.conf.Admin.KeyFile)
+	}
+	return t.adminServer.Serve(ln)
 }
 
 // Shutdown attempts to close the http server.
 func (t *Type) Shutdown(ctx context.Context) error {
 	t.cancel()
+	if t.adminServer != nil {
+		if err := t.adminServer.Shutdown(ctx); err != nil {
+			_ = t.server.Shutdown(ctx)
+			return err
+		}
+	}
 	return t.server.Shutdown(ctx)
 }
@@ -1,14 +1,18 @@
 package api_test
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/api"
+	"github.com/benthosdev/benthos/v4/internal/audit"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	"github.com/benthosdev/benthos/v4/internal/log"
 
@@ -183,3 +187,113 @@ func TestAPIBasicAuth(t *testing.T) {
 		}(tc))
 	}
 }
+
+func TestAPIAuditPrincipal(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		conf := api.NewConfig()
+		conf.BasicAuth.Enabled = enabled
+		conf.BasicAuth.Algorithm = "sha256"
+		conf.BasicAuth.Username = "myuser"
+		conf.BasicAuth.PasswordHash = "K7gNU3sdo+OL0wNhqoVWhr3g6s1xYv72ol/pe/Unols="
+		conf.BasicAuth.Salt = "EzrwNJYw2wkErVVV1P36FQ=="
+
+		s, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+		require.NoError(t, err)
+
+		var principal string
+		s.RegisterEndpoint("/foo", "A test endpoint", func(w http.ResponseWriter, r *http.Request) {
+			principal = audit.Principal(r.Context())
+		})
+
+		request, _ := http.NewRequest("GET", "/foo", http.NoBody)
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.SetBasicAuth("myuser", "secret")
+
+		response := httptest.NewRecorder()
+		s.Handler().ServeHTTP(response, request)
+		require.Equal(t, http.StatusOK, response.Code)
+
+		if enabled {
+			assert.Equal(t, "myuser", principal)
+		} else {
+			// An unverified username must not be recorded as the principal.
+			assert.Equal(t, "anonymous (10.0.0.1:1234)", principal)
+		}
+	}
+}
+
+func TestAPIAdminListener(t *testing.T) {
+	conf := api.NewConfig()
+	conf.Admin.Address = "127.0.0.1:0"
+
+	s, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	s.RegisterEndpoint("/post", "A data endpoint", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("data"))
+	})
+	s.RegisterEndpoint("/streams/{id}", "An admin endpoint", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("admin"))
+	})
+
+	for _, test := range []struct {
+		handler http.Handler
+		path    string
+		status  int
+	}{
+		{handler: s.Handler(), path: "/post", status: http.StatusOK},
+		{handler: s.Handler(), path: "/benthos/post", status: http.StatusOK},
+		{handler: s.Handler(), path: "/ping", status: http.StatusNotFound},
+		{handler: s.Handler(), path: "/streams/foo", status: http.StatusNotFound},
+		{handler: s.AdminHandler(), path: "/ping", status: http.StatusOK},
+		{handler: s.AdminHandler(), path: "/benthos/version", status: http.StatusOK},
+		{handler: s.AdminHandler(), path: "/streams/foo", status: http.StatusOK},
+		{handler: s.AdminHandler(), path: "/post", status: http.StatusNotFound},
+	} {
+		request, _ := http.NewRequest("GET", test.path, http.NoBody)
+		response := httptest.NewRecorder()
+		test.handler.ServeHTTP(response, request)
+		assert.Equal(t, test.status, response.Code, test.path)
+	}
+}
+
+func TestAPIAdminListenerFailure(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = ln.Close() })
+
+	conf := api.NewConfig()
+	conf.Address = "127.0.0.1:0"
+	conf.Admin.Address = ln.Addr().String()
+
+	s, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- s.ListenAndServe()
+	}()
+
+	// The admin address is already in use, which must fail the API without
+	// waiting for the main server to close.
+	select {
+	case err := <-errChan:
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(time.Second * 5):
+		_ = s.Shutdown(context.Background())
+		t.Fatal("timed out waiting for admin listener failure")
+	}
+}
+
+func TestAPITLSPolicy(t *testing.T) {
+	s, err := api.New("", "", api.NewConfig(), nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	request, _ := http.NewRequest("GET", "/tls/policy", http.NoBody)
+	response := httptest.NewRecorder()
+	s.Handler().ServeHTTP(response, request)
+
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Contains(t, response.Body.String(), `"min_version":"TLS 1.2"`)
+}
@@ -0,0 +1,626 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/benthosdev/benthos/v4/internal/audit"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
+)
+
+const (
+	fieldAuth                 = "auth"
+	fieldAuthEnabled          = "enabled"
+	fieldAuthAPIKeys          = "api_keys"
+	fieldAuthAPIKeysKey       = "key"
+	fieldAuthAPIKeysSubject   = "subject"
+	fieldAuthAPIKeysRole      = "role"
+	fieldAuthMTLS             = "mtls"
+	fieldAuthMTLSClientCAFile = "client_ca_file"
+	fieldAuthMTLSRoles        = "roles"
+	fieldAuthOIDC             = "oidc"
+	fieldAuthOIDCIssuerURL    = "issuer_url"
+	fieldAuthOIDCAudience     = "audience"
+	fieldAuthOIDCRoleClaim    = "role_claim"
+	fieldAuthPublicPaths      = "public_paths"
+	fieldAuthAuditLog         = "audit_log"
+)
+
+// Role is a level of access to the HTTP server, where each role is permitted
+// everything that lesser roles are.
+type Role int
+
+// Roles that can be assigned to callers of the HTTP server.
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleEditor
+	RoleAdmin
+)
+
+// RoleFromString parses a role from its name.
+func RoleFromString(s string) (Role, error) {
+	switch s {
+	case "viewer":
+		return RoleViewer, nil
+	case "editor":
+		return RoleEditor, nil
+	case "admin":
+		return RoleAdmin, nil
+	}
+	return RoleNone, fmt.Errorf("role '%v' is not recognised, expected viewer, editor or admin", s)
+}
+
+func (r Role) String() string {
+	switch r {
+	case RoleViewer:
+		return "viewer"
+	case RoleEditor:
+		return "editor"
+	case RoleAdmin:
+		return "admin"
+	}
+	return "none"
+}
+
+// APIKeyConfig describes a static API key and the role that it grants.
+type APIKeyConfig struct {
+	Key     string `json:"key" yaml:"key"`
+	Subject string `json:"subject" yaml:"subject"`
+	Role    string `json:"role" yaml:"role"`
+}
+
+// MTLSAuthConfig describes the roles granted to verified client certificates.
+type MTLSAuthConfig struct {
+	ClientCAFile string            `json:"client_ca_file" yaml:"client_ca_file"`
+	Roles        map[string]string `json:"roles" yaml:"roles"`
+}
+
+// OIDCAuthConfig describes how bearer tokens issued by an OpenID Connect
+// provider are verified and mapped to roles.
+type OIDCAuthConfig struct {
+	IssuerURL string `json:"issuer_url" yaml:"issuer_url"`
+	Audience  string `json:"audience" yaml:"audience"`
+	RoleClaim string `json:"role_claim" yaml:"role_claim"`
+}
+
+// AuthConfig contains the authentication and authorization fields of the HTTP
+// server.
+type AuthConfig struct {
+	Enabled     bool           `json:"enabled" yaml:"enabled"`
+	APIKeys     []APIKeyConfig `json:"api_keys" yaml:"api_keys"`
+	MTLS        MTLSAuthConfig `json:"mtls" yaml:"mtls"`
+	OIDC        OIDCAuthConfig `json:"oidc" yaml:"oidc"`
+	PublicPaths []string       `json:"public_paths" yaml:"public_paths"`
+	AuditLog    bool           `json:"audit_log" yaml:"audit_log"`
+}
+
+// NewAuthConfig returns an AuthConfig with default values.
+func NewAuthConfig() AuthConfig {
+	return AuthConfig{
+		Enabled: false,
+		APIKeys: []APIKeyConfig{},
+		MTLS: MTLSAuthConfig{
+			Roles: map[string]string{},
+		},
+		OIDC: OIDCAuthConfig{
+			RoleClaim: "role",
+		},
+		PublicPaths: []string{"/ping", "/ready"},
+		AuditLog:    true,
+	}
+}
+
+// AuthFieldSpec returns the spec for the auth fields of the HTTP server.
+func AuthFieldSpec() docs.FieldSpec {
+	return docs.FieldObject(fieldAuth, "Allows you to enforce authentication and role based authorization for requests to the management API of the HTTP server. Callers can be identified by API keys, client certificates or OpenID Connect bearer tokens, and are granted one of the roles `viewer` (read only requests), `editor` (all requests other than debug endpoints) or `admin` (all requests). The management API consists of the endpoints of the server itself, such as `/streams`, `/resources`, `/metrics` and `/debug`, and the endpoints of `dynamic` inputs and outputs and `backfill` inputs. Endpoints registered by components that serve data, such as the `http_server` input and output, are not subject to these rules and should be secured with the fields of those components.").WithChildren(
+		docs.FieldBool(fieldAuthEnabled, "Whether to enforce authentication.").HasDefault(false),
+		docs.FieldObject(fieldAuthAPIKeys, "A list of API keys, which are accepted from either the `X-API-Key` header or as a bearer token of the `Authorization` header.").Array().WithChildren(
+			docs.FieldString(fieldAuthAPIKeysKey, "The secret API key, which should be provided via an environment variable.", "${ADMIN_API_KEY}").Secret(),
+			docs.FieldString(fieldAuthAPIKeysSubject, "A name identifying the holder of the key, which is included in audit logs.", "ci-pipeline").HasDefault(""),
+			docs.FieldString(fieldAuthAPIKeysRole, "The role granted by the key.").HasOptions("viewer", "editor", "admin"),
+		).HasDefault([]any{}),
+		docs.FieldObject(fieldAuthMTLS, "Identifies callers by TLS client certificates, which requires `cert_file` and `key_file` to be set.").WithChildren(
+			docs.FieldString(fieldAuthMTLSClientCAFile, "A file containing the certificate authorities that client certificates are verified against. Requests without a certificate are still served and may authenticate by other means.").HasDefault(""),
+			docs.FieldString(fieldAuthMTLSRoles, "A map of client certificate subject common names to the roles they are granted.", map[string]any{"ops-team": "admin"}).Map().HasDefault(map[string]any{}),
+		),
+		docs.FieldObject(fieldAuthOIDC, "Identifies callers by bearer tokens issued by an OpenID Connect provider, which are verified with the signing keys published by the issuer.").WithChildren(
+			docs.FieldString(fieldAuthOIDCIssuerURL, "The URL of the token issuer, from which the discovery document is obtained. Leave empty to disable OpenID Connect authentication.", "https://accounts.example.com").HasDefault(""),
+			docs.FieldString(fieldAuthOIDCAudience, "The audience that tokens must have been issued for, which must be set when an `issuer_url` is set in order to reject tokens issued by the provider to other clients.", "benthos").HasDefault(""),
+			docs.FieldString(fieldAuthOIDCRoleClaim, "The claim of tokens that contains the role, or a list of roles, of the caller.").HasDefault("role"),
+		),
+		docs.FieldString(fieldAuthPublicPaths, "A list of endpoint paths that do not require authentication, such as liveness and readiness probes.").Array().HasDefault([]any{"/ping", "/ready"}),
+		docs.FieldBool(fieldAuthAuditLog, "Whether to log all requests that mutate state, such as changes to streams, along with the identity of the caller.").HasDefault(true),
+	).Advanced().AtVersion("4.28.0")
+}
+
+// AuthConfigFromParsed extracts an AuthConfig from a parsed config.
+func AuthConfigFromParsed(pConf *docs.ParsedConfig) (conf AuthConfig, err error) {
+	pConf = pConf.Namespace(fieldAuth)
+	if conf.Enabled, err = pConf.FieldBool(fieldAuthEnabled); err != nil {
+		return
+	}
+
+	var keyConfs []*docs.ParsedConfig
+	if keyConfs, err = pConf.FieldObjectList(fieldAuthAPIKeys); err != nil {
+		return
+	}
+	for _, kc := range keyConfs {
+		var key APIKeyConfig
+		if key.Key, err = kc.FieldString(fieldAuthAPIKeysKey); err != nil {
+			return
+		}
+		if key.Subject, err = kc.FieldString(fieldAuthAPIKeysSubject); err != nil {
+			return
+		}
+		if key.Role, err = kc.FieldString(fieldAuthAPIKeysRole); err != nil {
+			return
+		}
+		conf.APIKeys = append(conf.APIKeys, key)
+	}
+
+	if conf.MTLS.ClientCAFile, err = pConf.FieldString(fieldAuthMTLS, fieldAuthMTLSClientCAFile); err != nil {
+		return
+	}
+	if conf.MTLS.Roles, err = pConf.FieldStringMap(fieldAuthMTLS, fieldAuthMTLSRoles); err != nil {
+		return
+	}
+	if conf.OIDC.IssuerURL, err = pConf.FieldString(fieldAuthOIDC, fieldAuthOIDCIssuerURL); err != nil {
+		return
+	}
+	if conf.OIDC.Audience, err = pConf.FieldString(fieldAuthOIDC, fieldAuthOIDCAudience); err != nil {
+		return
+	}
+	if conf.OIDC.RoleClaim, err = pConf.FieldString(fieldAuthOIDC, fieldAuthOIDCRoleClaim); err != nil {
+		return
+	}
+	if conf.PublicPaths, err = pConf.FieldStringList(fieldAuthPublicPaths); err != nil {
+		return
+	}
+	if conf.AuditLog, err = pConf.FieldBool(fieldAuthAuditLog); err != nil {
+		return
+	}
+	return
+}
+
+// clientCertTLSConfig returns a TLS config for the HTTP server that verifies
+// client certificates against the configured certificate authorities.
+func clientCertTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("cert_file and key_file must be specified in order to authenticate client certificates")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	caBytes, err := ifs.ReadFile(ifs.OS(), clientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client_ca_file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("client_ca_file does not contain any valid certificates")
+	}
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if err := btls.ApplyPolicy(conf); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+//------------------------------------------------------------------------------
+
+type apiKey struct {
+	digest  [sha256.Size]byte
+	subject string
+	role    Role
+}
+
+type authenticator struct {
+	keys        []apiKey
+	certRoles   map[string]Role
+	oidc        *oidcVerifier
+	publicPaths map[string]struct{}
+	auditLog    bool
+	log         log.Modular
+}
+
+func newAuthenticator(conf AuthConfig, log log.Modular) (*authenticator, error) {
+	a := &authenticator{
+		certRoles:   map[string]Role{},
+		publicPaths: map[string]struct{}{},
+		auditLog:    conf.AuditLog,
+		log:         log,
+	}
+	for i, k := range conf.APIKeys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("api key %v must not be empty", i)
+		}
+		role, err := RoleFromString(k.Role)
+		if err != nil {
+			return nil, fmt.Errorf("api key %v: %w", i, err)
+		}
+		subject := k.Subject
+		if subject == "" {
+			subject = fmt.Sprintf("api_key_%v", i)
+		}
+		a.keys = append(a.keys, apiKey{
+			digest:  sha256.Sum256([]byte(k.Key)),
+			subject: subject,
+			role:    role,
+		})
+	}
+	for cn, r := range conf.MTLS.Roles {
+		role, err := RoleFromString(r)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate %v: %w", cn, err)
+		}
+		a.certRoles[cn] = role
+	}
+	if conf.OIDC.IssuerURL != "" {
+		if conf.OIDC.Audience == "" {
+			return nil, errors.New("oidc audience must be set when an issuer_url is set")
+		}
+		a.oidc = newOIDCVerifier(conf.OIDC)
+	}
+	for _, p := range conf.PublicPaths {
+		a.publicPaths[p] = struct{}{}
+	}
+	return a, nil
+}
+
+// identify returns the subject and role of the caller of a request, or an
+// empty subject if the caller could not be identified.
+func (a *authenticator) identify(r *http.Request) (subject string, role Role, err error) {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		if role, exists := a.certRoles[cn]; exists {
+			return "cert:" + cn, role, nil
+		}
+	}
+
+	token := r.Header.Get("X-API-Key")
+	if token == "" {
+		if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+			token = strings.TrimPrefix(authHeader, "Bearer ")
+		}
+	}
+	if token == "" {
+		return "", RoleNone, nil
+	}
+
+	digest := sha256.Sum256([]byte(token))
+	for _, k := range a.keys {
+		if subtle.ConstantTimeCompare(digest[:], k.digest[:]) == 1 {
+			return "key:" + k.subject, k.role, nil
+		}
+	}
+
+	if a.oidc != nil {
+		return a.oidc.identify(r.Context(), token)
+	}
+	return "", RoleNone, nil
+}
+
+// dynamicPathPattern matches the paths of the endpoints that dynamic inputs and
+// outputs and backfill inputs register beneath their prefix.
+var dynamicPathPattern = regexp.MustCompile(`(^|/)(inputs|outputs|backfills)(/\{id\}(/uptime)?)?$`)
+
+// isManagementPath returns whether an endpoint path belongs to the management
+// API, as opposed to endpoints registered by components in order to serve
+// data, such as the http_server input.
+func isManagementPath(path string) bool {
+	return isAdminPath(path) || dynamicPathPattern.MatchString(path)
+}
+
+// requiredRole returns the minimum role required to make a request to an
+// endpoint path.
+func requiredRole(method, path string) Role {
+	if strings.HasPrefix(path, "/debug/") {
+		return RoleAdmin
+	}
+	if isMutatingMethod(method) {
+		return RoleEditor
+	}
+	return RoleViewer
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (a *authenticator) audit(r *http.Request, subject string, role Role, status int) {
+	if !a.auditLog || !isMutatingMethod(r.Method) {
+		return
+	}
+	a.log.With(
+		"audit", true,
+		"subject", subject,
+		"role", role.String(),
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", status,
+	).Info("Admin API request %v %v by '%v' responded with status %v\n", r.Method, r.URL.Path, subject, status)
+}
+
+// wrapHandler wraps the handler of an endpoint path with middleware that
+// authenticates and authorizes requests.
+func (a *authenticator) wrapHandler(path string, next http.HandlerFunc) http.HandlerFunc {
+	if _, exists := a.publicPaths[path]; exists {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		subject, role, err := a.identify(r)
+		if err != nil {
+			a.log.Debug("Failed to authenticate request to %v: %v\n", r.URL.Path, err)
+		}
+		if subject == "" {
+			a.audit(r, "anonymous", RoleNone, http.StatusUnauthorized)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="benthos"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if role < requiredRole(r.Method, path) {
+			a.audit(r, subject, role, http.StatusForbidden)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r.WithContext(audit.ContextWithPrincipal(r.Context(), subject)))
+		a.audit(r, subject, role, rec.status)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const oidcMinRefreshInterval = time.Minute
+
+type oidcVerifier struct {
+	conf   OIDCAuthConfig
+	client *http.Client
+	parser *jwt.Parser
+
+	fetches     singleflight.Group
+	mut         sync.Mutex
+	keys        map[string]any
+	lastFetched time.Time
+}
+
+func newOIDCVerifier(conf OIDCAuthConfig) *oidcVerifier {
+	return &oidcVerifier{
+		conf:   conf,
+		client: &http.Client{Timeout: 10 * time.Second},
+		parser: jwt.NewParser(jwt.WithValidMethods([]string{
+			"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512",
+		})),
+		keys: map[string]any{},
+	}
+}
+
+func (o *oidcVerifier) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return err
+	}
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("request to %v returned status %v", url, res.StatusCode)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeJWKInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %v", k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %v", k.Kty)
+}
+
+// refreshKeys fetches the signing keys of the issuer, unless they were fetched
+// within the minimum refresh interval. The keys are fetched without holding the
+// mutex so that requests with known keys are not blocked by a slow issuer.
+func (o *oidcVerifier) refreshKeys(ctx context.Context) error {
+	o.mut.Lock()
+	if time.Since(o.lastFetched) < oidcMinRefreshInterval {
+		o.mut.Unlock()
+		return nil
+	}
+	o.lastFetched = time.Now()
+	o.mut.Unlock()
+
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := o.getJSON(ctx, strings.TrimSuffix(o.conf.IssuerURL, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return fmt.Errorf("failed to obtain discovery document: %w", err)
+	}
+	if discovery.JWKSURI == "" {
+		return errors.New("discovery document does not contain a jwks_uri")
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := o.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+		return fmt.Errorf("failed to obtain signing keys: %w", err)
+	}
+
+	keys := map[string]any{}
+	for _, k := range jwks.Keys {
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+
+	o.mut.Lock()
+	o.keys = keys
+	o.mut.Unlock()
+	return nil
+}
+
+func (o *oidcVerifier) getKey(kid string) (any, bool) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	key, exists := o.keys[kid]
+	return key, exists
+}
+
+func (o *oidcVerifier) keyFunc(ctx context.Context) jwt.Keyfunc {
+	return func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		if key, exists := o.getKey(kid); exists {
+			return key, nil
+		}
+
+		// Concurrent requests with unknown keys share a single fetch, which
+		// isn't cancelled along with the request that started it.
+		if _, err, _ := o.fetches.Do("keys", func() (any, error) {
+			return nil, o.refreshKeys(context.WithoutCancel(ctx))
+		}); err != nil {
+			return nil, err
+		}
+		if key, exists := o.getKey(kid); exists {
+			return key, nil
+		}
+		return nil, fmt.Errorf("signing key '%v' is unknown", kid)
+	}
+}
+
+func (o *oidcVerifier) identify(ctx context.Context, token string) (subject string, role Role, err error) {
+	claims := jwt.MapClaims{}
+	if _, err = o.parser.ParseWithClaims(token, claims, o.keyFunc(ctx)); err != nil {
+		return "", RoleNone, err
+	}
+	if !claims.VerifyIssuer(o.conf.IssuerURL, true) {
+		return "", RoleNone, errors.New("token issuer does not match")
+	}
+	if !claims.VerifyAudience(o.conf.Audience, true) {
+		return "", RoleNone, errors.New("token audience does not match")
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return "", RoleNone, errors.New("token does not contain a subject")
+	}
+
+	var roleNames []string
+	switch t := claims[o.conf.RoleClaim].(type) {
+	case string:
+		roleNames = append(roleNames, t)
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				roleNames = append(roleNames, s)
+			}
+		}
+	}
+	for _, n := range roleNames {
+		if r, err := RoleFromString(n); err == nil && r > role {
+			role = r
+		}
+	}
+	return "oidc:" + sub, role, nil
+}
@@ -0,0 +1,208 @@
+package api_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/api"
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+func authTestHandler(t *testing.T, conf api.Config) http.Handler {
+	t.Helper()
+
+	s, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+
+	s.RegisterEndpoint("/streams/foo", "A test endpoint", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Method))
+	})
+	return s.Handler()
+}
+
+func authTestRequest(h http.Handler, method, path string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, http.NoBody)
+	for i := 0; i < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, req)
+	return res
+}
+
+func TestAPIAuthAPIKeys(t *testing.T) {
+	conf := api.NewConfig()
+	conf.DebugEndpoints = true
+	conf.Auth.Enabled = true
+	conf.Auth.APIKeys = []api.APIKeyConfig{
+		{Key: "viewkey", Subject: "dashboard", Role: "viewer"},
+		{Key: "editkey", Subject: "ci", Role: "editor"},
+		{Key: "adminkey", Subject: "ops", Role: "admin"},
+	}
+
+	h := authTestHandler(t, conf)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header []string
+		status int
+	}{
+		{name: "public path", method: "GET", path: "/ping", status: http.StatusOK},
+		{name: "public path with root", method: "GET", path: "/benthos/ping", status: http.StatusOK},
+		{name: "no key", method: "GET", path: "/version", status: http.StatusUnauthorized},
+		{name: "bad key", method: "GET", path: "/version", header: []string{"X-API-Key", "nope"}, status: http.StatusUnauthorized},
+		{name: "viewer read", method: "GET", path: "/streams/foo", header: []string{"X-API-Key", "viewkey"}, status: http.StatusOK},
+		{name: "viewer write", method: "POST", path: "/streams/foo", header: []string{"X-API-Key", "viewkey"}, status: http.StatusForbidden},
+		{name: "editor write", method: "POST", path: "/streams/foo", header: []string{"X-API-Key", "editkey"}, status: http.StatusOK},
+		{name: "editor bearer write", method: "DELETE", path: "/benthos/streams/foo", header: []string{"Authorization", "Bearer editkey"}, status: http.StatusOK},
+		{name: "editor debug", method: "GET", path: "/debug/stack", header: []string{"X-API-Key", "editkey"}, status: http.StatusForbidden},
+		{name: "admin debug", method: "GET", path: "/debug/stack", header: []string{"X-API-Key", "adminkey"}, status: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			res := authTestRequest(h, test.method, test.path, test.header...)
+			assert.Equal(t, test.status, res.Code)
+			if test.status == http.StatusUnauthorized {
+				assert.Contains(t, res.Header().Get("WWW-Authenticate"), "Bearer")
+			}
+		})
+	}
+}
+
+func TestAPIAuthDisabled(t *testing.T) {
+	conf := api.NewConfig()
+	conf.Auth.APIKeys = []api.APIKeyConfig{
+		{Key: "viewkey", Role: "viewer"},
+	}
+
+	h := authTestHandler(t, conf)
+	assert.Equal(t, http.StatusOK, authTestRequest(h, "POST", "/streams/foo").Code)
+}
+
+func TestAPIAuthBadRole(t *testing.T) {
+	conf := api.NewConfig()
+	conf.Auth.Enabled = true
+	conf.Auth.APIKeys = []api.APIKeyConfig{
+		{Key: "foo", Role: "superuser"},
+	}
+
+	_, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "role 'superuser' is not recognised")
+}
+
+func TestAPIAuthDataEndpoints(t *testing.T) {
+	conf := api.NewConfig()
+	conf.Auth.Enabled = true
+	conf.Auth.APIKeys = []api.APIKeyConfig{
+		{Key: "editkey", Role: "editor"},
+	}
+
+	s, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	for _, p := range []string{"/post", "/foo/post", "/foo/inputs", "/foo/inputs/{id}", "/outputs/{id}/uptime"} {
+		s.RegisterEndpoint(p, "A test endpoint", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.Method))
+		})
+	}
+	h := s.Handler()
+
+	// Endpoints that serve data are not subject to auth.
+	assert.Equal(t, http.StatusOK, authTestRequest(h, "POST", "/post").Code)
+	assert.Equal(t, http.StatusOK, authTestRequest(h, "POST", "/foo/post").Code)
+
+	// Endpoints of dynamic components are part of the management API.
+	assert.Equal(t, http.StatusUnauthorized, authTestRequest(h, "GET", "/foo/inputs").Code)
+	assert.Equal(t, http.StatusUnauthorized, authTestRequest(h, "POST", "/foo/inputs/bar").Code)
+	assert.Equal(t, http.StatusUnauthorized, authTestRequest(h, "GET", "/outputs/bar/uptime").Code)
+	assert.Equal(t, http.StatusOK, authTestRequest(h, "POST", "/foo/inputs/bar", "X-API-Key", "editkey").Code)
+}
+
+func TestAPIAuthOIDCAudienceRequired(t *testing.T) {
+	conf := api.NewConfig()
+	conf.Auth.Enabled = true
+	conf.Auth.OIDC.IssuerURL = "https://accounts.example.com"
+
+	_, err := api.New("", "", conf, nil, log.Noop(), metrics.Noop())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "oidc audience must be set")
+}
+
+func TestAPIAuthOIDC(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var issuerURL string
+	issuer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"issuer":   issuerURL,
+				"jwks_uri": issuerURL + "/keys",
+			})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"keys": []any{map[string]any{
+					"kid": "test",
+					"kty": "RSA",
+					"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+					"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+				}},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(issuer.Close)
+	issuerURL = issuer.URL
+
+	conf := api.NewConfig()
+	conf.Auth.Enabled = true
+	conf.Auth.OIDC.IssuerURL = issuerURL
+	conf.Auth.OIDC.Audience = "benthos"
+	conf.Auth.OIDC.RoleClaim = "roles"
+
+	h := authTestHandler(t, conf)
+
+	signToken := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return "Bearer " + signed
+	}
+
+	exp := time.Now().Add(time.Hour).Unix()
+
+	viewer := signToken(jwt.MapClaims{"iss": issuerURL, "aud": "benthos", "sub": "alice", "exp": exp, "roles": []any{"viewer"}})
+	assert.Equal(t, http.StatusOK, authTestRequest(h, "GET", "/streams/foo", "Authorization", viewer).Code)
+	assert.Equal(t, http.StatusForbidden, authTestRequest(h, "POST", "/streams/foo", "Authorization", viewer).Code)
+
+	editor := signToken(jwt.MapClaims{"iss": issuerURL, "aud": "benthos", "sub": "bob", "exp": exp, "roles": []any{"viewer", "editor"}})
+	assert.Equal(t, http.StatusOK, authTestRequest(h, "POST", "/streams/foo", "Authorization", editor).Code)
+
+	wrongAudience := signToken(jwt.MapClaims{"iss": issuerURL, "aud": "other", "sub": "bob", "exp": exp, "roles": "admin"})
+	assert.Equal(t, http.StatusUnauthorized, authTestRequest(h, "GET", "/streams/foo", "Authorization", wrongAudience).Code)
+
+	noAudience := signToken(jwt.MapClaims{"iss": issuerURL, "sub": "bob", "exp": exp, "roles": "admin"})
+	assert.Equal(t, http.StatusUnauthorized, authTestRequest(h, "GET", "/streams/foo", "Authorization", noAudience).Code)
+
+	expired := signToken(jwt.MapClaims{"iss": issuerURL, "aud": "benthos", "sub": "bob", "exp": time.Now().Add(-time.Hour).Unix(), "roles": "admin"})
+	assert.Equal(t, http.StatusUnauthorized, authTestRequest(h, "GET", "/streams/foo", "Authorization", expired).Code)
+}
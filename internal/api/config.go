@@ -14,8 +14,21 @@ const (
 	fieldKeyFile        = "key_file"
 	fieldCORS           = "cors"
 	fieldBasicAuth      = "basic_auth"
+	fieldSocketMode     = "socket_mode"
+	fieldAdmin          = "admin"
+	fieldAdminAddress   = "address"
+	fieldAdminCertFile  = "cert_file"
+	fieldAdminKeyFile   = "key_file"
 )
 
+// AdminConfig contains the configuration fields of an optional listener
+// dedicated to metrics, health and administrative endpoints.
+type AdminConfig struct {
+	Address  string `json:"address" yaml:"address"`
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+}
+
 // Config contains the configuration fields for the Benthos API.
 type Config struct {
 	Address        string                     `json:"address" yaml:"address"`
@@ -26,6 +39,9 @@ type Config struct {
 	KeyFile        string                     `json:"key_file" yaml:"key_file"`
 	CORS           httpserver.CORSConfig      `json:"cors" yaml:"cors"`
 	BasicAuth      httpserver.BasicAuthConfig `json:"basic_auth" yaml:"basic_auth"`
+	Auth           AuthConfig                 `json:"auth" yaml:"auth"`
+	SocketMode     string                     `json:"socket_mode" yaml:"socket_mode"`
+	Admin          AdminConfig                `json:"admin" yaml:"admin"`
 }
 
 // NewConfig creates a new API config with default values.
@@ -39,6 +55,9 @@ func NewConfig() Config {
 		KeyFile:        "",
 		CORS:           httpserver.NewServerCORSConfig(),
 		BasicAuth:      httpserver.NewBasicAuthConfig(),
+		Auth:           NewAuthConfig(),
+		SocketMode:     "0660",
+		Admin:          AdminConfig{},
 	}
 }
 
@@ -67,5 +86,20 @@ func FromParsed(pConf *docs.ParsedConfig) (conf Config, err error) {
 	if conf.BasicAuth, err = httpserver.BasicAuthConfigFromParsed(pConf); err != nil {
 		return
 	}
+	if conf.Auth, err = AuthConfigFromParsed(pConf); err != nil {
+		return
+	}
+	if conf.SocketMode, err = pConf.FieldString(fieldSocketMode); err != nil {
+		return
+	}
+	if conf.Admin.Address, err = pConf.FieldString(fieldAdmin, fieldAdminAddress); err != nil {
+		return
+	}
+	if conf.Admin.CertFile, err = pConf.FieldString(fieldAdmin, fieldAdminCertFile); err != nil {
+		return
+	}
+	if conf.Admin.KeyFile, err = pConf.FieldString(fieldAdmin, fieldAdminKeyFile); err != nil {
+		return
+	}
 	return
 }
@@ -14,7 +14,7 @@ import (
 func Spec() docs.FieldSpecs {
 	return docs.FieldSpecs{
 		docs.FieldBool(fieldEnabled, "Whether to enable to HTTP server.").HasDefault(true),
-		docs.FieldString(fieldAddress, "The address to bind to.").HasDefault("0.0.0.0:4195"),
+		docs.FieldString(fieldAddress, "The address to bind to. "+httpserver.ListenAddressDescription).HasDefault("0.0.0.0:4195"),
 		docs.FieldString(
 			fieldRootPath, "Specifies a general prefix for all endpoints, this can help isolate the service endpoints when using a reverse proxy with other shared services. All endpoints will still be registered at the root as well as behind the prefix, e.g. with a root_path set to `/foo` the endpoint `/version` will be accessible from both `/version` and `/foo/version`.",
 		).HasDefault("/benthos"),
@@ -25,6 +25,13 @@ func Spec() docs.FieldSpecs {
 		docs.FieldString(fieldKeyFile, "An optional key file for enabling TLS.").Advanced().HasDefault(""),
 		httpserver.ServerCORSFieldSpec(),
 		httpserver.BasicAuthFieldSpec(),
+		AuthFieldSpec(),
+		docs.FieldString(fieldSocketMode, "The octal file mode to apply to unix domain sockets created by the HTTP server. When empty the mode is determined by the umask of the process.").HasDefault("0660").Advanced().AtVersion("4.28.0"),
+		docs.FieldObject(fieldAdmin, "Allows metrics, health and administrative endpoints to be served from a separate listener to those registered by components such as the `http_server` input and output, which is useful for satisfying network segmentation policies.").WithChildren(
+			docs.FieldString(fieldAdminAddress, "The address for the administrative listener to bind to. When empty all endpoints are served from the main listener. "+httpserver.ListenAddressDescription, "127.0.0.1:4196", "unix:///run/benthos/admin.sock").HasDefault(""),
+			docs.FieldString(fieldAdminCertFile, "An optional certificate file for enabling TLS on the administrative listener.").HasDefault(""),
+			docs.FieldString(fieldAdminKeyFile, "An optional key file for enabling TLS on the administrative listener.").HasDefault(""),
+		).Advanced().AtVersion("4.28.0"),
 	}
 }
 
@@ -68,6 +75,23 @@ http:
     password_hash: ""
     algorithm: "sha256"
     salt: ""
+  auth:
+    enabled: false
+    api_keys: []
+    mtls:
+      client_ca_file: ""
+      roles: {}
+    oidc:
+      issuer_url: ""
+      audience: ""
+      role_claim: role
+    public_paths: [ /ping, /ready ]
+    audit_log: true
+  socket_mode: "0660"
+  admin:
+    address: ""
+    cert_file: ""
+    key_file: ""
 `,
 	})
 
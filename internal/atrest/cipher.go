@@ -0,0 +1,168 @@
+package atrest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	fieldKey          = "key"
+	fieldPreviousKeys = "previous_keys"
+)
+
+// FieldSpec returns a config field that enables the encryption of state
+// written to disk by a component.
+func FieldSpec(name string) *service.ConfigField {
+	return service.NewObjectField(name,
+		service.NewStringField(fieldKey).
+			Description("A base64 encoded AES key of 16, 24 or 32 bytes, selecting AES-128, AES-192 or AES-256 respectively. Data is always encrypted with this key. The key should be obtained from a secret store, such as with an [environment variable](/docs/configuration/interpolation#environment-variables).").
+			Example("${ENCRYPTION_KEY}").
+			Secret(),
+		service.NewStringListField(fieldPreviousKeys).
+			Description("A list of base64 encoded keys that were previously used, which are only used to decrypt existing data. When rotating keys the previous key should be added here until all data encrypted with it has been rewritten or has expired.").
+			Example([]string{"${OLD_ENCRYPTION_KEY}"}).
+			Default([]any{}).
+			Secret(),
+	).
+		Description("Encrypt data written to disk with AES-GCM. Existing data that was written without encryption, or with a key that is not configured, cannot be read once this is enabled.").
+		Optional().
+		Advanced().
+		Version("4.28.0")
+}
+
+// CipherFromParsed creates a cipher from a config field created with
+// FieldSpec. If the field is not present a nil cipher is returned, which leaves
+// data unencrypted.
+func CipherFromParsed(conf *service.ParsedConfig, name string) (*Cipher, error) {
+	if !conf.Contains(name) {
+		return nil, nil
+	}
+	conf = conf.Namespace(name)
+
+	keyStr, err := conf.FieldString(fieldKey)
+	if err != nil {
+		return nil, err
+	}
+	previousStrs, err := conf.FieldStringList(fieldPreviousKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, 0, len(previousStrs)+1)
+	for i, s := range append([]string{keyStr}, previousStrs...) {
+		key, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("failed to decode %v: %w", fieldKey, err)
+			}
+			return nil, fmt.Errorf("failed to decode %v %v: %w", fieldPreviousKeys, i-1, err)
+		}
+		keys = append(keys, key)
+	}
+	return NewCipher(keys[0], keys[1:]...)
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	formatVersion = 1
+	keyIDLen      = 8
+)
+
+type keyedAEAD struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// Cipher encrypts data with a primary key and decrypts data encrypted with
+// either the primary key or any previous keys. A nil Cipher returns data
+// unchanged, allowing components to call it regardless of whether encryption
+// is enabled.
+//
+// Encrypted data is prefixed with a format version and an identifier derived
+// from the key it was encrypted with, followed by a random nonce.
+type Cipher struct {
+	primary keyedAEAD
+	all     []keyedAEAD
+}
+
+// NewCipher creates a cipher from a primary key and any previous keys.
+func NewCipher(key []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{}
+	for _, k := range append([][]byte{key}, previous...) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(k)
+		c.all = append(c.all, keyedAEAD{id: sum[:keyIDLen], aead: aead})
+	}
+	c.primary = c.all[0]
+	return c, nil
+}
+
+// Seal encrypts data with the primary key.
+func (c *Cipher) Seal(plaintext []byte) ([]byte, error) {
+	if c == nil {
+		return plaintext, nil
+	}
+
+	nonceSize := c.primary.aead.NonceSize()
+
+	out := make([]byte, 0, 1+keyIDLen+nonceSize+len(plaintext)+c.primary.aead.Overhead())
+	out = append(out, formatVersion)
+	out = append(out, c.primary.id...)
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out = append(out, nonce...)
+
+	// The header is authenticated in order to detect tampering of the key ID.
+	return c.primary.aead.Seal(out, nonce, plaintext, out[:1+keyIDLen]), nil
+}
+
+// Open decrypts data that was encrypted with Seal.
+func (c *Cipher) Open(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+
+	if len(data) < 1+keyIDLen {
+		return nil, errors.New("encrypted data is too short")
+	}
+	if data[0] != formatVersion {
+		return nil, fmt.Errorf("encrypted data has an unsupported format version: %v", data[0])
+	}
+
+	id := data[1 : 1+keyIDLen]
+	for _, k := range c.all {
+		if !bytes.Equal(k.id, id) {
+			continue
+		}
+		rest := data[1+keyIDLen:]
+		nonceSize := k.aead.NonceSize()
+		if len(rest) < nonceSize {
+			return nil, errors.New("encrypted data is too short")
+		}
+		plaintext, err := k.aead.Open(nil, rest[:nonceSize], rest[nonceSize:], data[:1+keyIDLen])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt data: %w", err)
+		}
+		return plaintext, nil
+	}
+	return nil, errors.New("data was encrypted with a key that is not configured")
+}
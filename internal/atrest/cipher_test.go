@@ -0,0 +1,118 @@
+package atrest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestCipherRoundTrip(t *testing.T) {
+	c, err := NewCipher(bytes.Repeat([]byte("a"), 32))
+	require.NoError(t, err)
+
+	sealed, err := c.Seal([]byte("hello world"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "hello world")
+
+	sealedAgain, err := c.Seal([]byte("hello world"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, sealedAgain)
+
+	opened, err := c.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(opened))
+
+	sealed[len(sealed)-1] ^= 0xff
+	_, err = c.Open(sealed)
+	require.Error(t, err)
+
+	_, err = c.Open([]byte("hello world"))
+	require.Error(t, err)
+}
+
+func TestCipherRotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte("a"), 16), bytes.Repeat([]byte("b"), 32)
+
+	oldC, err := NewCipher(oldKey)
+	require.NoError(t, err)
+
+	sealed, err := oldC.Seal([]byte("foo"))
+	require.NoError(t, err)
+
+	newC, err := NewCipher(newKey)
+	require.NoError(t, err)
+	_, err = newC.Open(sealed)
+	require.EqualError(t, err, "data was encrypted with a key that is not configured")
+
+	rotatedC, err := NewCipher(newKey, oldKey)
+	require.NoError(t, err)
+
+	opened, err := rotatedC.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(opened))
+
+	resealed, err := rotatedC.Seal(opened)
+	require.NoError(t, err)
+	opened, err = newC.Open(resealed)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(opened))
+}
+
+func TestCipherNil(t *testing.T) {
+	var c *Cipher
+
+	sealed, err := c.Seal([]byte("foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(sealed))
+
+	opened, err := c.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(opened))
+}
+
+func TestCipherFromParsed(t *testing.T) {
+	spec := service.NewConfigSpec().Field(FieldSpec("encryption"))
+
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("a"), 32))
+	oldKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte("b"), 24))
+
+	pConf, err := spec.ParseYAML(``, nil)
+	require.NoError(t, err)
+
+	c, err := CipherFromParsed(pConf, "encryption")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	pConf, err = spec.ParseYAML(`
+encryption:
+  key: `+key+`
+  previous_keys: [ `+oldKey+` ]
+`, nil)
+	require.NoError(t, err)
+
+	c, err = CipherFromParsed(pConf, "encryption")
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	assert.Len(t, c.all, 2)
+
+	for _, test := range []struct {
+		conf        string
+		errContains string
+	}{
+		{conf: `key: not base64!`, errContains: "failed to decode key"},
+		{conf: `key: ` + base64.StdEncoding.EncodeToString([]byte("short")), errContains: "invalid key size"},
+		{conf: "key: " + key + "\n  previous_keys: [ nope! ]", errContains: "failed to decode previous_keys 0"},
+	} {
+		pConf, err = spec.ParseYAML("encryption:\n  "+test.conf, nil)
+		if err == nil {
+			_, err = CipherFromParsed(pConf, "encryption")
+		}
+		require.Error(t, err, test.conf)
+		assert.Contains(t, err.Error(), test.errContains, test.conf)
+	}
+}
@@ -0,0 +1,3 @@
+// Package atrest provides a Benthos configuration field and an AES-GCM cipher
+// for encrypting state that components write to disk.
+package atrest
@@ -0,0 +1,167 @@
+// Package audit records events related to the configuration of Benthos, such
+// as config loads and reloads, changes made in streams mode and the resolution
+// of secrets, to a configurable sink.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kind describes the type of an audited event.
+type Kind string
+
+// Kinds of audited events.
+const (
+	KindConfigLoad     Kind = "config_load"
+	KindConfigReload   Kind = "config_reload"
+	KindStreamCreate   Kind = "stream_create"
+	KindStreamUpdate   Kind = "stream_update"
+	KindStreamDelete   Kind = "stream_delete"
+	KindResourceUpdate Kind = "resource_update"
+	KindSecretResolve  Kind = "secret_resolve"
+)
+
+// Event is an audited event.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Kind      Kind      `json:"kind"`
+	Principal string    `json:"principal"`
+
+	// Source identifies what the event applies to, such as the path of a
+	// config file or the ID of a stream.
+	Source string `json:"source,omitempty"`
+
+	// ConfigHash is the hash of the config involved in the event, prior to any
+	// interpolation of environment variables.
+	ConfigHash string `json:"config_hash,omitempty"`
+
+	// Secret is the name of a resolved secret, the value is never recorded.
+	Secret string `json:"secret,omitempty"`
+	Found  *bool  `json:"found,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Sink is a destination for audited events.
+type Sink interface {
+	Write(e Event) error
+	Close() error
+}
+
+var (
+	sink    Sink
+	onErr   func(error)
+	sinkMut sync.RWMutex
+)
+
+// SetSink sets the sink that all events are recorded to from this point
+// onwards, along with a func called when an event could not be written. A nil
+// sink disables auditing.
+func SetSink(s Sink, errFn func(error)) {
+	sinkMut.Lock()
+	sink, onErr = s, errFn
+	sinkMut.Unlock()
+}
+
+// Enabled returns true if a sink has been set.
+func Enabled() bool {
+	sinkMut.RLock()
+	defer sinkMut.RUnlock()
+	return sink != nil
+}
+
+// Record an event to the configured sink, the time and principal of the event
+// are populated when empty. This is a no-op when no sink has been set.
+func Record(ctx context.Context, e Event) {
+	sinkMut.RLock()
+	s, errFn := sink, onErr
+	sinkMut.RUnlock()
+	if s == nil {
+		return
+	}
+
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Principal == "" {
+		e.Principal = Principal(ctx)
+	}
+	if err := s.Write(e); err != nil && errFn != nil {
+		errFn(err)
+	}
+}
+
+// Hash returns the hash of a config used within events.
+func Hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ErrorString returns the string form of an error for use within events.
+func ErrorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+//------------------------------------------------------------------------------
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a context carrying the identity of the principal
+// responsible for actions performed with it.
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the identity of the principal carried by a
+// context, if any.
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	p, ok := ctx.Value(principalKey{}).(string)
+	return p, ok && p != ""
+}
+
+var processPrincipal = sync.OnceValue(func() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "uid:" + strconv.Itoa(os.Getuid())
+})
+
+// Principal returns the identity of the principal carried by a context, or the
+// user running the process when there is none.
+func Principal(ctx context.Context) string {
+	if ctx != nil {
+		if p, ok := PrincipalFromContext(ctx); ok {
+			return p
+		}
+	}
+	return processPrincipal()
+}
+
+// WrapLookupEnv wraps an environment variable lookup func such that each
+// resolution is recorded as an event.
+func WrapLookupEnv(ctx context.Context, source string, fn func(string) (string, bool)) func(string) (string, bool) {
+	if !Enabled() {
+		return fn
+	}
+	return func(name string) (string, bool) {
+		v, ok := fn(name)
+		found := ok
+		Record(ctx, Event{
+			Kind:   KindSecretResolve,
+			Source: source,
+			Secret: name,
+			Found:  &found,
+		})
+		return v, ok
+	}
+}
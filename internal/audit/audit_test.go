@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseEvents(t *testing.T, b []byte) (events []Event) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		events = append(events, e)
+	}
+	return
+}
+
+func TestRecord(t *testing.T) {
+	// No-op without a sink
+	Record(context.Background(), Event{Kind: KindConfigLoad})
+
+	var buf bytes.Buffer
+	SetSink(NewWriterSink(&buf), nil)
+	t.Cleanup(func() {
+		SetSink(nil, nil)
+	})
+
+	Record(context.Background(), Event{
+		Kind:       KindConfigLoad,
+		Source:     "./foo.yaml",
+		ConfigHash: Hash([]byte("foo")),
+	})
+	Record(ContextWithPrincipal(context.Background(), "alice"), Event{
+		Kind:   KindStreamDelete,
+		Source: "bar",
+	})
+
+	events := parseEvents(t, buf.Bytes())
+	require.Len(t, events, 2)
+
+	assert.Equal(t, KindConfigLoad, events[0].Kind)
+	assert.Equal(t, "./foo.yaml", events[0].Source)
+	assert.Equal(t, "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", events[0].ConfigHash)
+	assert.Equal(t, processPrincipal(), events[0].Principal)
+	assert.NotEmpty(t, events[0].Principal)
+	assert.False(t, events[0].Time.IsZero())
+
+	assert.Equal(t, KindStreamDelete, events[1].Kind)
+	assert.Equal(t, "alice", events[1].Principal)
+}
+
+func TestWrapLookupEnv(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		if name == "FOO" {
+			return "hunter2", true
+		}
+		return "", false
+	}
+
+	var buf bytes.Buffer
+	SetSink(NewWriterSink(&buf), nil)
+	t.Cleanup(func() {
+		SetSink(nil, nil)
+	})
+
+	fn := WrapLookupEnv(context.Background(), "./foo.yaml", lookup)
+
+	v, ok := fn("FOO")
+	assert.True(t, ok)
+	assert.Equal(t, "hunter2", v)
+
+	_, ok = fn("BAR")
+	assert.False(t, ok)
+
+	assert.NotContains(t, buf.String(), "hunter2")
+
+	events := parseEvents(t, buf.Bytes())
+	require.Len(t, events, 2)
+
+	assert.Equal(t, KindSecretResolve, events[0].Kind)
+	assert.Equal(t, "./foo.yaml", events[0].Source)
+	assert.Equal(t, "FOO", events[0].Secret)
+	require.NotNil(t, events[0].Found)
+	assert.True(t, *events[0].Found)
+
+	assert.Equal(t, "BAR", events[1].Secret)
+	require.NotNil(t, events[1].Found)
+	assert.False(t, *events[1].Found)
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"kind":"config_load","principal":"bob"}`+"\n"), 0o600))
+
+	s, err := NewFileSink(path)
+	require.NoError(t, err)
+	require.NoError(t, s.Write(Event{Kind: KindConfigReload, Principal: "alice"}))
+	require.NoError(t, s.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	events := parseEvents(t, b)
+	require.Len(t, events, 2)
+	assert.Equal(t, "bob", events[0].Principal)
+	assert.Equal(t, KindConfigReload, events[1].Kind)
+	assert.Equal(t, "alice", events[1].Principal)
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+type writerSink struct {
+	w     io.Writer
+	close func() error
+	mut   sync.Mutex
+}
+
+// NewWriterSink returns a sink that writes events to an io.Writer as lines of
+// JSON.
+func NewWriterSink(w io.Writer) Sink {
+	return &writerSink{w: w}
+}
+
+// NewFileSink returns a sink that appends events to a file as lines of JSON,
+// where each event is synced to disk before the write returns. When the path is
+// `-` events are written to stdout.
+func NewFileSink(path string) (Sink, error) {
+	if path == "-" {
+		return NewWriterSink(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &writerSink{
+		w: &syncWriter{f: f},
+		close: func() error {
+			return f.Close()
+		},
+	}, nil
+}
+
+type syncWriter struct {
+	f *os.File
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	n, err := s.f.Write(p)
+	if err != nil {
+		return n, err
+	}
+	return n, s.f.Sync()
+}
+
+func (s *writerSink) Write(e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	_, err = s.w.Write(b)
+	return err
+}
+
+func (s *writerSink) Close() error {
+	if s.close != nil {
+		return s.close()
+	}
+	return nil
+}
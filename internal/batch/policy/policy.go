@@ -13,6 +13,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
 	iprocessor "github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/memguard"
 	"github.com/benthosdev/benthos/v4/internal/message"
 )
 
@@ -100,12 +101,18 @@ func (p *Batcher) Add(part *message.Part) bool {
 	}
 	p.parts = append(p.parts, part)
 
-	if !p.triggered && p.count > 0 && len(p.parts) >= p.count {
+	// Whilst the memory guard is shedding load batches are flushed early.
+	count, byteSize, period := p.count, p.byteSize, p.period
+	if memguard.Active(memguard.PolicyShrinkBatches) {
+		count, byteSize, period = shrink(count), shrink(byteSize), period/memguard.ShrinkDivisor
+	}
+
+	if !p.triggered && count > 0 && len(p.parts) >= count {
 		p.triggered = true
 		p.mCountBatch.Incr(1)
 		p.log.Trace("Batching based on count")
 	}
-	if !p.triggered && p.byteSize > 0 && p.sizeTally >= p.byteSize {
+	if !p.triggered && byteSize > 0 && p.sizeTally >= byteSize {
 		p.triggered = true
 		p.mSizeBatch.Incr(1)
 		p.log.Trace("Batching based on byte_size")
@@ -123,7 +130,17 @@ func (p *Batcher) Add(part *message.Part) bool {
 			p.log.Trace("Batching based on check query")
 		}
 	}
-	return p.triggered || (p.period > 0 && time.Since(p.lastBatch) > p.period)
+	return p.triggered || (period > 0 && time.Since(p.lastBatch) > period)
+}
+
+func shrink(limit int) int {
+	if limit <= 0 {
+		return limit
+	}
+	if limit = limit / memguard.ShrinkDivisor; limit < 1 {
+		limit = 1
+	}
+	return limit
 }
 
 // Flush clears all messages stored by this batch policy. Returns nil if the
@@ -0,0 +1,303 @@
+// Package batchformat provides a config field for outputs that write batches
+// of messages to files or objects, allowing each batch to be serialized as a
+// single document of a structured file format.
+package batchformat
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// FieldFormat is the name of the field returned by ConfigField.
+	FieldFormat = "format"
+
+	bfFieldType        = "type"
+	bfFieldCompression = "compression"
+	bfFieldSchema      = "schema"
+)
+
+// Formats supported by the format field.
+const (
+	TypeJSONL   = "jsonl"
+	TypeCSV     = "csv"
+	TypeAvroOCF = "avro_ocf"
+)
+
+var compressionsByType = map[string][]string{
+	TypeJSONL:   {"none", "gzip", "zstd"},
+	TypeCSV:     {"none", "gzip", "zstd"},
+	TypeAvroOCF: {"none", "deflate", "snappy"},
+}
+
+// ConfigField returns an optional config field for the format of batches
+// written by an output.
+func ConfigField() *service.ConfigField {
+	return service.NewObjectField(FieldFormat,
+		service.NewStringAnnotatedEnumField(bfFieldType, map[string]string{
+			TypeJSONL:   "Each message is written as a line of JSON.",
+			TypeCSV:     "Each message is parsed as a JSON object and written as a row of a CSV file with a header row.",
+			TypeAvroOCF: "Messages are parsed as JSON and written as the records of an [Avro Object Container File](https://avro.apache.org/docs/current/specification/#object-container-files).",
+		}).Description("The format in which batches are serialized."),
+		service.NewStringField(bfFieldCompression).
+			Description("The compression applied to the file. The formats `jsonl` and `csv` support `none`, `gzip` and `zstd`, and `avro_ocf` supports `none`, `deflate` and `snappy`.").
+			Default("none"),
+		service.NewStringField(bfFieldSchema).
+			Description("The schema of the file. For `avro_ocf` this is a full Avro schema, where messages are standard JSON rather than Avro JSON. For `csv` this is an optional comma separated list of columns, otherwise the columns are all keys of the objects sorted alphabetically. This field is not used by `jsonl`.").
+			Examples(
+				`{"type":"record","name":"event","fields":[{"name":"id","type":"string"}]}`,
+				`id,name,value`,
+			).
+			Default(""),
+	).
+		Description("Serialize each batch as a single file of a structured format, rather than writing each message separately. The file is written to the path resolved from the first message of the batch and replaces any file that already exists at that path, and therefore the path should resolve a unique name for each batch, e.g. with a timestamp or UUID. Batch sizes can be controlled with a [batching policy](/docs/configuration/batching).").
+		Version("4.28.0").
+		Optional()
+}
+
+// Encoder serializes batches of messages in a format.
+type Encoder struct {
+	formatType  string
+	compression string
+
+	columns []string
+	avro    *goavro.Codec
+}
+
+// FromParsed returns an encoder from the format field of a parsed config, or
+// nil if the field is not set.
+func FromParsed(pConf *service.ParsedConfig) (*Encoder, error) {
+	if !pConf.Contains(FieldFormat) {
+		return nil, nil
+	}
+	conf := pConf.Namespace(FieldFormat)
+
+	e := &Encoder{}
+
+	var err error
+	if e.formatType, err = conf.FieldString(bfFieldType); err != nil {
+		return nil, err
+	}
+	if e.compression, err = conf.FieldString(bfFieldCompression); err != nil {
+		return nil, err
+	}
+	if !isOneOf(e.compression, compressionsByType[e.formatType]) {
+		return nil, fmt.Errorf("compression %v is not supported by format %v, expected one of: %v", e.compression, e.formatType, strings.Join(compressionsByType[e.formatType], ", "))
+	}
+
+	schema, err := conf.FieldString(bfFieldSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.formatType {
+	case TypeCSV:
+		if schema != "" {
+			for _, c := range strings.Split(schema, ",") {
+				e.columns = append(e.columns, strings.TrimSpace(c))
+			}
+		}
+	case TypeAvroOCF:
+		if schema == "" {
+			return nil, errors.New("a schema must be specified for the avro_ocf format")
+		}
+		if e.avro, err = goavro.NewCodecForStandardJSONFull(schema); err != nil {
+			return nil, fmt.Errorf("failed to parse avro schema: %w", err)
+		}
+	}
+	return e, nil
+}
+
+func isOneOf(s string, options []string) bool {
+	for _, o := range options {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode serializes a batch as a single message, which adopts the metadata of
+// the first message of the batch.
+func (e *Encoder) Encode(batch service.MessageBatch) (*service.Message, error) {
+	if len(batch) == 0 {
+		return nil, errors.New("cannot encode an empty batch")
+	}
+
+	var data []byte
+	var err error
+	switch e.formatType {
+	case TypeJSONL:
+		data, err = encodeJSONL(batch)
+	case TypeCSV:
+		data, err = encodeCSV(batch, e.columns)
+	case TypeAvroOCF:
+		data, err = e.encodeAvroOCF(batch)
+	default:
+		err = fmt.Errorf("format not recognised: %v", e.formatType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Avro files compress their blocks internally.
+	if e.formatType == TypeJSONL || e.formatType == TypeCSV {
+		if data, err = compress(e.compression, data); err != nil {
+			return nil, err
+		}
+	}
+
+	msg := batch[0].Copy()
+	msg.SetBytes(data)
+	return msg, nil
+}
+
+func compress(algorithm string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch algorithm {
+	case "none":
+		return data, nil
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zstd":
+		var err error
+		if w, err = zstd.NewWriter(&buf); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("compression not recognised: %v", algorithm)
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeJSONL(batch service.MessageBatch) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, m := range batch {
+		mBytes, err := m.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		// Documents spanning multiple lines are compacted in order to keep one
+		// document per line.
+		if bytes.ContainsAny(mBytes, "\r\n") {
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, mBytes); err != nil {
+				return nil, fmt.Errorf("message %v: failed to parse message as JSON: %w", i, err)
+			}
+			mBytes = compacted.Bytes()
+		}
+		buf.Write(mBytes)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// EncodeCSV serializes a batch of JSON objects as a CSV file with a header row
+// of the given columns, or of all keys of the objects sorted alphabetically
+// when no columns are given. Keys that are missing from an object result in
+// empty values, and values that are objects or arrays are written as JSON.
+func EncodeCSV(batch service.MessageBatch, columns []string) ([]byte, error) {
+	return encodeCSV(batch, columns)
+}
+
+func encodeCSV(batch service.MessageBatch, columns []string) ([]byte, error) {
+	rows := make([]map[string]any, len(batch))
+	keys := map[string]struct{}{}
+	for i, part := range batch {
+		doc, err := part.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message as JSON: %v", err)
+		}
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected message to be a JSON object, got %T", doc)
+		}
+		if columns == nil {
+			for k := range obj {
+				keys[k] = struct{}{}
+			}
+		}
+		rows[i] = obj
+	}
+
+	header := columns
+	if header == nil {
+		header = make([]string, 0, len(keys))
+		for k := range keys {
+			header = append(header, k)
+		}
+		sort.Strings(header)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(header)
+
+	record := make([]string, len(header))
+	for _, row := range rows {
+		for i, k := range header {
+			record[i] = ""
+			if v := row[k]; v != nil {
+				record[i] = value.IToString(v)
+			}
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (e *Encoder) encodeAvroOCF(batch service.MessageBatch) ([]byte, error) {
+	datums := make([]any, len(batch))
+	for i, m := range batch {
+		mBytes, err := m.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if datums[i], _, err = e.avro.NativeFromTextual(mBytes); err != nil {
+			return nil, fmt.Errorf("failed to convert JSON to Avro schema: %v", err)
+		}
+	}
+
+	compression := e.compression
+	if compression == "none" {
+		compression = goavro.CompressionNullLabel
+	}
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Codec:           e.avro,
+		CompressionName: compression,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Append(datums); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
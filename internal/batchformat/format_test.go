@@ -0,0 +1,147 @@
+package batchformat
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testEncoder(t *testing.T, conf string) *Encoder {
+	t.Helper()
+
+	spec := service.NewConfigSpec().Field(ConfigField())
+	pConf, err := spec.ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	e, err := FromParsed(pConf)
+	require.NoError(t, err)
+	return e
+}
+
+func testBatch(docs ...string) service.MessageBatch {
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+	return batch
+}
+
+func TestFormatNotSet(t *testing.T) {
+	assert.Nil(t, testEncoder(t, `{}`))
+}
+
+func TestFormatConfigErrors(t *testing.T) {
+	spec := service.NewConfigSpec().Field(ConfigField())
+	for _, test := range []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: `format: { type: jsonl, compression: snappy }`,
+			err:  "compression snappy is not supported by format jsonl, expected one of: none, gzip, zstd",
+		},
+		{
+			conf: `format: { type: avro_ocf }`,
+			err:  "a schema must be specified for the avro_ocf format",
+		},
+	} {
+		pConf, err := spec.ParseYAML(test.conf, nil)
+		require.NoError(t, err, test.conf)
+
+		_, err = FromParsed(pConf)
+		require.ErrorContains(t, err, test.err, test.conf)
+	}
+}
+
+func TestFormatJSONL(t *testing.T) {
+	e := testEncoder(t, `
+format:
+  type: jsonl
+  compression: gzip
+`)
+
+	batch := testBatch(`{"id":1}`, "{\n  \"id\": 2\n}", `"foo"`)
+	batch[0].MetaSetMut("path", "a.jsonl.gz")
+
+	msg, err := e.Encode(batch)
+	require.NoError(t, err)
+
+	path, _ := msg.MetaGet("path")
+	assert.Equal(t, "a.jsonl.gz", path)
+
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+
+	r, err := gzip.NewReader(bytes.NewReader(mBytes))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":1}\n{\"id\":2}\n\"foo\"\n", string(data))
+}
+
+func TestFormatCSV(t *testing.T) {
+	batch := testBatch(
+		`{"id":1,"name":"foo","tags":["a"]}`,
+		`{"id":2,"value":"bar, baz"}`,
+	)
+
+	msg, err := testEncoder(t, `format: { type: csv }`).Encode(batch)
+	require.NoError(t, err)
+
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "id,name,tags,value\n1,foo,\"[\"\"a\"\"]\",\n2,,,\"bar, baz\"\n", string(mBytes))
+
+	msg, err = testEncoder(t, `format: { type: csv, schema: "value, id" }`).Encode(batch)
+	require.NoError(t, err)
+
+	mBytes, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "value,id\n,1\n\"bar, baz\",2\n", string(mBytes))
+
+	_, err = testEncoder(t, `format: { type: csv }`).Encode(testBatch(`[1,2]`))
+	require.Error(t, err)
+}
+
+func TestFormatAvroOCF(t *testing.T) {
+	for _, compression := range []string{"none", "deflate", "snappy"} {
+		t.Run(compression, func(t *testing.T) {
+			e := testEncoder(t, `
+format:
+  type: avro_ocf
+  compression: `+compression+`
+  schema: '{"type":"record","name":"event","fields":[{"name":"id","type":"long"},{"name":"name","type":["null","string"]}]}'
+`)
+
+			msg, err := e.Encode(testBatch(`{"id":1,"name":"foo"}`, `{"id":2,"name":null}`))
+			require.NoError(t, err)
+
+			mBytes, err := msg.AsBytes()
+			require.NoError(t, err)
+
+			r, err := goavro.NewOCFReader(bytes.NewReader(mBytes))
+			require.NoError(t, err)
+
+			var records []any
+			for r.Scan() {
+				record, err := r.Read()
+				require.NoError(t, err)
+				records = append(records, record)
+			}
+			require.NoError(t, r.Err())
+
+			assert.Equal(t, []any{
+				map[string]any{"id": int64(1), "name": map[string]any{"string": "foo"}},
+				map[string]any{"id": int64(2), "name": nil},
+			}, records)
+		})
+	}
+}
@@ -35,13 +35,21 @@ func (s StaticResolver) ResolveBytes(index int, msg Message, escaped bool) ([]by
 // QueryResolver executes a query and returns a string representation of the
 // result.
 type QueryResolver struct {
-	fn query.Function
+	fn   query.Function
+	maps map[string]query.Function
 }
 
 // NewQueryResolver creates a field query resolver that returns the result of a
 // query function.
 func NewQueryResolver(fn query.Function) *QueryResolver {
-	return &QueryResolver{fn}
+	return &QueryResolver{fn: fn}
+}
+
+// NewQueryResolverWithMaps creates a field query resolver that returns the
+// result of a query function, where the query is able to apply the provided
+// named maps.
+func NewQueryResolverWithMaps(fn query.Function, maps map[string]query.Function) *QueryResolver {
+	return &QueryResolver{fn: fn, maps: maps}
 }
 
 // ResolveString returns a string.
@@ -52,6 +60,7 @@ func (q QueryResolver) ResolveString(index int, msg Message, escaped bool) (stri
 	return query.ExecToString(q.fn, query.FunctionContext{
 		Index:    index,
 		MsgBatch: msg,
+		Maps:     q.maps,
 		NewMeta:  msg.Get(index),
 	}.WithValueFunc(func() *any {
 		if jObj, err := msg.Get(index).AsStructured(); err == nil {
@@ -69,6 +78,7 @@ func (q QueryResolver) ResolveBytes(index int, msg Message, escaped bool) ([]byt
 	bs, err := query.ExecToBytes(q.fn, query.FunctionContext{
 		Index:    index,
 		MsgBatch: msg,
+		Maps:     q.maps,
 		NewMeta:  msg.Get(index),
 	}.WithValueFunc(func() *any {
 		if jObj, err := msg.Get(index).AsStructured(); err == nil {
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 )
 
 func intoStaticResolver(p Func[string]) Func[field.Resolver] {
@@ -16,7 +17,27 @@ func intoStaticResolver(p Func[string]) Func[field.Resolver] {
 
 var interpStart = Term("${!")
 
-func aFunction(pCtx Context) Func[field.Resolver] {
+// anImport parses an interpolation containing an import statement, where the
+// maps of the imported file are added to the maps of the field and the
+// interpolation itself resolves to an empty string.
+func anImport(pCtx Context, maps map[string]query.Function) Func[field.Resolver] {
+	pattern := Sequence(
+		interpStart,
+		Optional(SpacesAndTabs),
+		importParser(pCtx, maps),
+		Optional(SpacesAndTabs),
+		MustBe(Expect(charSquigClose, "end of expression")),
+	)
+	return func(input []rune) Result[field.Resolver] {
+		res := pattern(input)
+		if res.Err != nil {
+			return Fail[field.Resolver](res.Err, input)
+		}
+		return Success[field.Resolver](field.StaticResolver(""), res.Remaining)
+	}
+}
+
+func aFunction(pCtx Context, maps map[string]query.Function) Func[field.Resolver] {
 	pattern := TakeOnly(2, Sequence(
 		strToQuery(interpStart),
 		strToQuery(Optional(SpacesAndTabs)),
@@ -29,7 +50,7 @@ func aFunction(pCtx Context) Func[field.Resolver] {
 		if res.Err != nil {
 			return Fail[field.Resolver](res.Err, input)
 		}
-		return Success[field.Resolver](field.NewQueryResolver(res.Payload), res.Remaining)
+		return Success[field.Resolver](field.NewQueryResolverWithMaps(res.Payload, maps), res.Remaining)
 	}
 }
 
@@ -59,9 +80,14 @@ var escapedBlock = func() Func[field.Resolver] {
 func parseFieldResolvers(pCtx Context, expr string) ([]field.Resolver, *Error) {
 	var resolvers []field.Resolver
 
+	// Maps imported anywhere within the field are available to all of its
+	// interpolations.
+	maps := map[string]query.Function{}
+
 	p := OneOf(
 		escapedBlock,
-		aFunction(pCtx),
+		anImport(pCtx, maps),
+		aFunction(pCtx, maps),
 		intoStaticResolver(charDollar),
 		intoStaticResolver(NotChar('$')),
 	)
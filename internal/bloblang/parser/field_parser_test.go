@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -218,3 +220,63 @@ func TestFieldExpressions(t *testing.T) {
 		})
 	}
 }
+
+func TestFieldExpressionImports(t *testing.T) {
+	dir := t.TempDir()
+
+	libDir := filepath.Join(dir, "lib")
+	require.NoError(t, os.MkdirAll(libDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(libDir, "normalise.blobl"), []byte(`
+map normalise_name {
+  root = this.trim().lowercase().replace_all(" ", "_")
+}
+
+import "./paths.blobl"
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(libDir, "paths.blobl"), []byte(`
+map object_path {
+  root = "%v/%v".format(this.type, this.id)
+}
+`), 0o644))
+
+	pCtx := GlobalContext().WithImporterRelativeToFile(filepath.Join(dir, "config.yaml"))
+
+	tests := map[string]struct {
+		input  string
+		output string
+	}{
+		"import and apply": {
+			input:  `${! import "lib/normalise.blobl" }${! this.name.apply("normalise_name") }`,
+			output: `foo_bar`,
+		},
+		"import after apply": {
+			input:  `${!this.name.apply("normalise_name")}.json${!import "lib/normalise.blobl"}`,
+			output: `foo_bar.json`,
+		},
+		"nested import": {
+			input:  `objects/${! import "lib/normalise.blobl" }${! this.apply("object_path") }`,
+			output: `objects/user/123`,
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			e, pErr := ParseField(pCtx, test.input)
+			require.Nil(t, pErr, "%v", pErr)
+
+			msg := message.QuickBatch([][]byte{[]byte(`{"name":"  Foo Bar ","type":"user","id":123}`)})
+			res, err := e.String(0, msg)
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+		})
+	}
+
+	_, pErr := ParseField(pCtx, `${! import "lib/nope.blobl" }`)
+	require.NotNil(t, pErr)
+	assert.Contains(t, pErr.ErrorAtChar([]rune(`${! import "lib/nope.blobl" }`)), "failed to read import")
+
+	_, pErr = ParseField(pCtx, `${! import "lib/normalise.blobl" }${! import "lib/paths.blobl" }`)
+	require.NotNil(t, pErr)
+	assert.Contains(t, pErr.ErrorAtChar([]rune(`${! import "lib/normalise.blobl" }${! import "lib/paths.blobl" }`)), "map name collisions")
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/manager"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/memguard"
 )
 
 // CreateManager from a CLI context and a stream config.
@@ -87,6 +88,13 @@ func CreateManager(
 		return
 	}
 
+	// Create the process wide memory guard, which is nil when disabled.
+	var memGuard *memguard.Guard
+	if memGuard, err = memguard.New(conf.MemoryGuard, logger, stats); err != nil {
+		err = fmt.Errorf("failed to initialise memory guard: %w", err)
+		return
+	}
+
 	var httpServer *api.Type
 	if httpServer, err = api.New(version, dateBuilt, conf.HTTP, sanitNode, logger, stats); err != nil {
 		err = fmt.Errorf("failed to initialise API: %w", err)
@@ -109,7 +117,7 @@ func CreateManager(
 		return
 	}
 
-	stoppableMgr = newStoppableManager(httpServer, mgr)
+	stoppableMgr = newStoppableManager(httpServer, mgr, memGuard)
 	return
 }
 
@@ -221,11 +229,15 @@ func RunManagerUntilStopped(
 	return 0
 }
 
-func newStoppableManager(api *api.Type, mgr *manager.Type) *StoppableManager {
+func newStoppableManager(api *api.Type, mgr *manager.Type, memGuard *memguard.Guard) *StoppableManager {
 	s := &StoppableManager{
 		api:           api,
 		apiClosedChan: make(chan struct{}),
 		mgr:           mgr,
+		memGuard:      memGuard,
+	}
+	if memGuard != nil {
+		memGuard.Run()
 	}
 	// Start HTTP server.
 	go func() {
@@ -245,6 +257,7 @@ type StoppableManager struct {
 	api           *api.Type
 	apiClosedChan chan struct{}
 	mgr           *manager.Type
+	memGuard      *memguard.Guard
 }
 
 // Manager returns the underlying manager type.
@@ -281,6 +294,11 @@ func (s *StoppableManager) Stop(ctx context.Context) error {
 	if err := s.mgr.WaitForClose(ctx); err != nil {
 		return err
 	}
+	if s.memGuard != nil {
+		if err := s.memGuard.Close(ctx); err != nil {
+			return err
+		}
+	}
 	if err := s.mgr.CloseObservability(ctx); err != nil {
 		s.mgr.Logger().Error("Failed to cleanly close observability components: %w", err)
 	}
@@ -9,9 +9,10 @@ import (
 
 // ReadConfig attempts to read a general service wide config via a returned
 // config.Reader based on input CLI flags. This includes applying any config
-// overrides expressed by the --set flag.
-func ReadConfig(c *cli.Context, streamsMode bool) (mainPath string, inferred bool, conf *config.Reader) {
-	path := c.String("config")
+// overlays expressed by the --overlay flag and overrides expressed by the --set
+// flag. Config files fetched remotely are read from their local copies.
+func ReadConfig(c *cli.Context, streamsMode bool, remote *RemoteConfigs) (mainPath string, inferred bool, conf *config.Reader) {
+	path := remote.LocalPath(c.String("config"))
 	if path == "" {
 		// Iterate default config paths
 		for _, dpath := range []string{
@@ -27,11 +28,16 @@ func ReadConfig(c *cli.Context, streamsMode bool) (mainPath string, inferred boo
 		}
 	}
 	opts := []config.OptFunc{
+		config.OptAddOverlays(c.StringSlice("overlay")...),
 		config.OptAddOverrides(c.StringSlice("set")...),
 		config.OptTestSuffix("_benthos_test"),
 	}
 	if streamsMode {
 		opts = append(opts, config.OptSetStreamPaths(c.Args().Slice()...))
 	}
-	return path, inferred, config.NewReader(path, c.StringSlice("resources"), opts...)
+	var resourcePaths []string
+	for _, p := range c.StringSlice("resources") {
+		resourcePaths = append(resourcePaths, remote.LocalPath(p))
+	}
+	return path, inferred, config.NewReader(path, resourcePaths, opts...)
 }
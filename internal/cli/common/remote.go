@@ -0,0 +1,331 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// RemoteConfigs fetches config files specified as HTTP(S), S3 or git URLs into
+// a local directory, from where they are read like any other config file. When polling
+// is enabled the remote files are periodically fetched again and, when they
+// have changed, the local files are updated in order to trigger a reload.
+type RemoteConfigs struct {
+	client       *http.Client
+	headers      http.Header
+	checksum     bool
+	publicKey    ed25519.PublicKey
+	pollInterval time.Duration
+
+	s3        s3GetObjectAPI
+	gitClones map[string]*gitClone
+
+	dir   string
+	files map[string]*remoteFile
+}
+
+type remoteFile struct {
+	url       string
+	localPath string
+	etag      string
+	hash      [sha256.Size]byte
+}
+
+func isRemotePath(p string) bool {
+	for _, prefix := range []string{"http://", "https://", "s3://", "git+"} {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchRemoteConfigs fetches any config and resource files that were specified
+// as URLs via CLI flags. Returns nil if no remote files were specified.
+func FetchRemoteConfigs(c *cli.Context) (*RemoteConfigs, error) {
+	var urls []string
+	if p := c.String("config"); isRemotePath(p) {
+		urls = append(urls, p)
+	}
+	for _, p := range c.StringSlice("resources") {
+		if isRemotePath(p) {
+			urls = append(urls, p)
+		}
+	}
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	r := &RemoteConfigs{
+		client:    &http.Client{Timeout: 30 * time.Second},
+		headers:   http.Header{},
+		gitClones: map[string]*gitClone{},
+		files:     map[string]*remoteFile{},
+	}
+
+	for _, h := range c.StringSlice("remote-header") {
+		k, v, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid remote header '%v': expected key:value syntax", h)
+		}
+		r.headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+
+	r.checksum = c.Bool("remote-checksum")
+	if keyPath := c.String("remote-public-key"); keyPath != "" {
+		var err error
+		if r.publicKey, err = readEd25519PublicKey(keyPath); err != nil {
+			return nil, fmt.Errorf("failed to read remote public key: %w", err)
+		}
+	}
+
+	if intervalStr := c.String("remote-poll-interval"); intervalStr != "" {
+		var err error
+		if r.pollInterval, err = time.ParseDuration(intervalStr); err != nil {
+			return nil, fmt.Errorf("failed to parse remote poll interval: %w", err)
+		}
+	}
+
+	var err error
+	if r.dir, err = os.MkdirTemp("", "benthos-remote-config-*"); err != nil {
+		return nil, err
+	}
+
+	for i, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to parse remote config url: %w", err)
+		}
+		f := &remoteFile{
+			url:       u,
+			localPath: filepath.Join(r.dir, fmt.Sprintf("%v_%v", i, path.Base(parsed.Path))),
+		}
+		if _, err := r.fetch(context.Background(), f); err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to fetch remote config %v: %w", u, err)
+		}
+		r.files[u] = f
+	}
+	return r, nil
+}
+
+func readEd25519PublicKey(keyPath string) (ed25519.PublicKey, error) {
+	keyBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(keyBytes)
+	if block == nil {
+		return nil, errors.New("expected a PEM encoded key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ed25519 public key, got %T", key)
+	}
+	return edKey, nil
+}
+
+// LocalPath returns the path of the local copy of a config file if it was
+// fetched remotely, otherwise the path is returned unchanged.
+func (r *RemoteConfigs) LocalPath(p string) string {
+	if r == nil {
+		return p
+	}
+	if f, exists := r.files[p]; exists {
+		return f.localPath
+	}
+	return p
+}
+
+// Polling returns whether remote files should be polled for changes.
+func (r *RemoteConfigs) Polling() bool {
+	return r != nil && r.pollInterval > 0
+}
+
+// Poll fetches remote files periodically until the context is cancelled,
+// updating the local copies of any that have changed.
+func (r *RemoteConfigs) Poll(ctx context.Context, logger log.Modular) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		for _, f := range r.files {
+			changed, err := r.fetch(ctx, f)
+			if err != nil {
+				if ctx.Err() == nil {
+					logger.Error("Failed to fetch remote config %v: %v", f.url, err)
+				}
+				continue
+			}
+			if changed {
+				logger.Info("Remote config %v has changed, reloading", f.url)
+			}
+		}
+	}
+}
+
+// fetch obtains the latest version of a remote file, verifies it and writes it
+// to the local copy. Returns true if the file has changed since it was last
+// fetched.
+func (r *RemoteConfigs) fetch(ctx context.Context, f *remoteFile) (bool, error) {
+	body, etag, err := r.get(ctx, f.url, f.etag)
+	if err != nil || body == nil {
+		return false, err
+	}
+
+	hash := sha256.Sum256(body)
+	if hash == f.hash {
+		f.etag = etag
+		return false, nil
+	}
+
+	if r.checksum {
+		sumBytes, _, err := r.get(ctx, sidecarURL(f.url, ".sha256"), "")
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch checksum: %w", err)
+		}
+		sumFields := strings.Fields(string(sumBytes))
+		if len(sumFields) == 0 || !strings.EqualFold(sumFields[0], hex.EncodeToString(hash[:])) {
+			return false, errors.New("checksum does not match")
+		}
+	}
+
+	if r.publicKey != nil {
+		sigBytes, _, err := r.get(ctx, sidecarURL(f.url, ".sig"), "")
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch signature: %w", err)
+		}
+		sig, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(sigBytes)))
+		if err != nil {
+			return false, fmt.Errorf("failed to decode signature: %w", err)
+		}
+		if !ed25519.Verify(r.publicKey, body, sig) {
+			return false, errors.New("signature verification failed")
+		}
+	}
+
+	if err := writeFileAtomic(f.localPath, body); err != nil {
+		return false, err
+	}
+	f.etag, f.hash = etag, hash
+	return true, nil
+}
+
+// writeFileAtomic replaces the contents of a file by writing them to a
+// temporary file in the same directory and renaming it, so that a watcher of
+// the file never reads it partially written.
+func writeFileAtomic(p string, body []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(body); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// sidecarURL returns the URL of a file stored alongside a remote file, such as
+// its checksum, by adding a suffix to the path of the URL.
+func sidecarURL(u, suffix string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return u + suffix
+	}
+	parsed.Path += suffix
+	parsed.RawPath = ""
+	return parsed.String()
+}
+
+// get obtains the contents of a remote file, returning a nil body if the ETag
+// of the file matches the one provided.
+func (r *RemoteConfigs) get(ctx context.Context, u, etag string) (body []byte, newETag string, err error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case parsed.Scheme == "s3":
+		return r.getS3(ctx, parsed, etag)
+	case strings.HasPrefix(parsed.Scheme, "git+"):
+		return r.getGit(ctx, parsed, etag)
+	}
+	return r.getHTTP(ctx, u, etag)
+}
+
+// getHTTP performs a GET request of a URL, returning a nil body if the ETag of
+// the resource matches the one provided.
+func (r *RemoteConfigs) getHTTP(ctx context.Context, u, etag string) (body []byte, newETag string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range r.headers {
+		req.Header[k] = v
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotModified {
+		return nil, etag, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, "", fmt.Errorf("unexpected status code: %v", res.StatusCode)
+	}
+	if body, err = io.ReadAll(res.Body); err != nil {
+		return nil, "", err
+	}
+	return body, res.Header.Get("ETag"), nil
+}
+
+// Close removes the local copies of remote files.
+func (r *RemoteConfigs) Close() error {
+	if r == nil {
+		return nil
+	}
+	return os.RemoveAll(r.dir)
+}
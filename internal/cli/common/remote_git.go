@@ -0,0 +1,146 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// gitClone is a shallow clone of a git repository at a reference, from which
+// the remote files of the repository are read.
+type gitClone struct {
+	hash plumbing.Hash
+	tree *object.Tree
+}
+
+// parseGitURL splits a URL of the form
+// git+https://host/repo.git//path/to/file.yaml?ref=main into the URL of the
+// repository, the reference to read and the path of a file within it.
+func parseGitURL(u *url.URL) (repoURL, ref, filePath string, err error) {
+	repoPath, filePath, _ := strings.Cut(u.Path, "//")
+	if filePath == "" {
+		return "", "", "", errors.New("expected a git url with the path of a file within the repository after '//'")
+	}
+
+	repo := *u
+	repo.Scheme = strings.TrimPrefix(u.Scheme, "git+")
+	repo.Path, repo.RawPath = repoPath, ""
+	repo.RawQuery, repo.Fragment = "", ""
+	return repo.String(), u.Query().Get("ref"), filePath, nil
+}
+
+// getGit reads a file from a git repository, where the ETag is the hash that
+// the reference of the URL points to. The repository is only cloned again
+// when the reference has moved, and therefore reading the checksum and
+// signature of a file reuses the clone of the file itself.
+func (r *RemoteConfigs) getGit(ctx context.Context, u *url.URL, etag string) (body []byte, newETag string, err error) {
+	repoURL, ref, filePath, err := parseGitURL(u)
+	if err != nil {
+		return nil, "", err
+	}
+
+	hash, refName, err := resolveGitRef(ctx, repoURL, ref)
+	if err != nil {
+		return nil, "", err
+	}
+	if hash.String() == etag {
+		return nil, etag, nil
+	}
+
+	key := repoURL + "?ref=" + ref
+	c, exists := r.gitClones[key]
+	if !exists || c.hash != hash {
+		if c, err = cloneGitRef(ctx, repoURL, refName); err != nil {
+			return nil, "", err
+		}
+		c.hash = hash
+		r.gitClones[key] = c
+	}
+
+	file, err := c.tree.File(filePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %v: %w", filePath, err)
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(contents), hash.String(), nil
+}
+
+// resolveGitRef lists the references of a remote repository and returns the
+// hash and full name of a reference, which can be a full reference name or the
+// name of a branch or tag, where an empty reference is the HEAD of the
+// repository.
+func resolveGitRef(ctx context.Context, repoURL, ref string) (plumbing.Hash, plumbing.ReferenceName, error) {
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: "origin",
+		URLs: []string{repoURL},
+	})
+	refs, err := remote.ListContext(ctx, &git.ListOptions{})
+	if err != nil {
+		return plumbing.ZeroHash, "", err
+	}
+
+	byName := make(map[plumbing.ReferenceName]*plumbing.Reference, len(refs))
+	for _, rf := range refs {
+		byName[rf.Name()] = rf
+	}
+
+	candidates := []plumbing.ReferenceName{plumbing.HEAD}
+	if ref != "" {
+		candidates = []plumbing.ReferenceName{
+			plumbing.ReferenceName(ref),
+			plumbing.NewBranchReferenceName(ref),
+			plumbing.NewTagReferenceName(ref),
+		}
+	}
+	for _, name := range candidates {
+		rf, exists := byName[name]
+		for i := 0; exists && rf.Type() == plumbing.SymbolicReference && i < 5; i++ {
+			rf, exists = byName[rf.Target()]
+		}
+		if exists && rf.Type() == plumbing.HashReference {
+			return rf.Hash(), name, nil
+		}
+	}
+	if ref == "" {
+		ref = string(plumbing.HEAD)
+	}
+	return plumbing.ZeroHash, "", fmt.Errorf("reference %v not found", ref)
+}
+
+func cloneGitRef(ctx context.Context, repoURL string, refName plumbing.ReferenceName) (*gitClone, error) {
+	repo, err := git.CloneContext(ctx, memory.NewStorage(), nil, &git.CloneOptions{
+		URL:           repoURL,
+		ReferenceName: refName,
+		SingleBranch:  true,
+		Depth:         1,
+		Tags:          git.NoTags,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone repository: %w", err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(plumbing.HEAD))
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	return &gitClone{tree: tree}, nil
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type s3GetObjectAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// getS3 obtains an object from a URL of the form s3://bucket/key, returning a
+// nil body if the ETag of the object matches the one provided. The client is
+// created on first use from the default AWS credentials chain, which is also
+// where the region and endpoint are taken from.
+func (r *RemoteConfigs) getS3(ctx context.Context, u *url.URL, etag string) (body []byte, newETag string, err error) {
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Host == "" || key == "" {
+		return nil, "", errors.New("expected an s3 url of the form s3://bucket/key")
+	}
+
+	if r.s3 == nil {
+		awsConf, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		r.s3 = s3.NewFromConfig(awsConf)
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(key),
+	}
+	if etag != "" {
+		input.IfNoneMatch = aws.String(etag)
+	}
+
+	out, err := r.s3.GetObject(ctx, input)
+	if err != nil {
+		var resErr *awshttp.ResponseError
+		if errors.As(err, &resErr) && resErr.HTTPStatusCode() == http.StatusNotModified {
+			return nil, etag, nil
+		}
+		return nil, "", err
+	}
+	defer out.Body.Close()
+
+	if body, err = io.ReadAll(out.Body); err != nil {
+		return nil, "", err
+	}
+	return body, aws.ToString(out.ETag), nil
+}
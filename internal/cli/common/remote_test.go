@@ -0,0 +1,255 @@
+package common
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRemoteServer struct {
+	mut      sync.Mutex
+	content  string
+	checksum string
+	sig      string
+	requests int
+}
+
+func (s *testRemoteServer) set(content string, key ed25519.PrivateKey) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	hash := sha256.Sum256([]byte(content))
+	s.content = content
+	s.checksum = hex.EncodeToString(hash[:]) + "  config.yaml\n"
+	s.sig = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(content)))
+}
+
+func (s *testRemoteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	switch r.URL.Path {
+	case "/config.yaml":
+		s.requests++
+		etag := `"` + s.checksum[:8] + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(s.content))
+	case "/config.yaml.sha256":
+		_, _ = w.Write([]byte(s.checksum))
+	case "/config.yaml.sig":
+		_, _ = w.Write([]byte(s.sig))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestRemoteConfigsFetch(t *testing.T) {
+	pubKey, privKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	srv := &testRemoteServer{}
+	srv.set("foo: bar\n", privKey)
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	r := &RemoteConfigs{
+		client:    ts.Client(),
+		headers:   http.Header{},
+		checksum:  true,
+		publicKey: pubKey,
+	}
+	f := &remoteFile{
+		url:       ts.URL + "/config.yaml",
+		localPath: filepath.Join(t.TempDir(), "config.yaml"),
+	}
+
+	changed, err := r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	fileBytes, err := os.ReadFile(f.localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "foo: bar\n", string(fileBytes))
+
+	// Unchanged content is detected by the ETag.
+	changed, err = r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	srv.set("foo: baz\n", privKey)
+	changed, err = r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	fileBytes, err = os.ReadFile(f.localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "foo: baz\n", string(fileBytes))
+
+	// Content signed by another key is rejected and the local copy is kept.
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	srv.set("foo: buz\n", otherKey)
+	_, err = r.fetch(context.Background(), f)
+	require.ErrorContains(t, err, "signature verification failed")
+
+	fileBytes, err = os.ReadFile(f.localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "foo: baz\n", string(fileBytes))
+
+	// Content that does not match its checksum is rejected.
+	srv.set("foo: buz\n", privKey)
+	srv.mut.Lock()
+	srv.content = "foo: tampered\n"
+	srv.mut.Unlock()
+
+	_, err = r.fetch(context.Background(), f)
+	require.ErrorContains(t, err, "checksum does not match")
+}
+
+type testS3Client struct {
+	objects map[string]string
+}
+
+func (c *testS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	content, exists := c.objects[*params.Bucket+"/"+*params.Key]
+	if !exists {
+		return nil, errors.New("no such key")
+	}
+	hash := sha256.Sum256([]byte(content))
+	etag := `"` + hex.EncodeToString(hash[:4]) + `"`
+	if params.IfNoneMatch != nil && *params.IfNoneMatch == etag {
+		return nil, &awshttp.ResponseError{
+			ResponseError: &smithyhttp.ResponseError{
+				Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusNotModified}},
+				Err:      errors.New("not modified"),
+			},
+		}
+	}
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader(content)),
+		ETag: &etag,
+	}, nil
+}
+
+func TestRemoteConfigsFetchS3(t *testing.T) {
+	hash := sha256.Sum256([]byte("foo: bar\n"))
+	client := &testS3Client{objects: map[string]string{
+		"configs/benthos/config.yaml":        "foo: bar\n",
+		"configs/benthos/config.yaml.sha256": hex.EncodeToString(hash[:]),
+	}}
+
+	r := &RemoteConfigs{s3: client, checksum: true}
+	f := &remoteFile{
+		url:       "s3://configs/benthos/config.yaml",
+		localPath: filepath.Join(t.TempDir(), "config.yaml"),
+	}
+
+	changed, err := r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	fileBytes, err := os.ReadFile(f.localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "foo: bar\n", string(fileBytes))
+
+	changed, err = r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	client.objects["configs/benthos/config.yaml"] = "foo: baz\n"
+	_, err = r.fetch(context.Background(), f)
+	require.ErrorContains(t, err, "checksum does not match")
+}
+
+func TestRemoteConfigsFetchGit(t *testing.T) {
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+
+	commit := func(content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Join(repoDir, "configs"), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(repoDir, "configs", "config.yaml"), []byte(content), 0o644))
+		_, err := wt.Add("configs/config.yaml")
+		require.NoError(t, err)
+		_, err = wt.Commit("update config", &git.CommitOptions{
+			Author: &object.Signature{Name: "foo", Email: "foo@example.com", When: time.Now()},
+		})
+		require.NoError(t, err)
+	}
+	commit("foo: bar\n")
+
+	r := &RemoteConfigs{gitClones: map[string]*gitClone{}}
+	f := &remoteFile{
+		url:       "git+file://" + filepath.ToSlash(repoDir) + "//configs/config.yaml?ref=master",
+		localPath: filepath.Join(t.TempDir(), "config.yaml"),
+	}
+
+	changed, err := r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	fileBytes, err := os.ReadFile(f.localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "foo: bar\n", string(fileBytes))
+
+	// The file is unchanged while the reference has not moved.
+	changed, err = r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	commit("foo: baz\n")
+	changed, err = r.fetch(context.Background(), f)
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	fileBytes, err = os.ReadFile(f.localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "foo: baz\n", string(fileBytes))
+
+	_, err = r.fetch(context.Background(), &remoteFile{
+		url:       "git+file://" + filepath.ToSlash(repoDir) + "//configs/config.yaml?ref=nope",
+		localPath: filepath.Join(t.TempDir(), "config.yaml"),
+	})
+	require.ErrorContains(t, err, "reference nope not found")
+}
+
+func TestIsRemotePath(t *testing.T) {
+	for p, exp := range map[string]bool{
+		"./config.yaml":                  false,
+		"https://example.com/foo.yaml":   true,
+		"s3://bucket/foo.yaml":           true,
+		"git+ssh://git@example.com/repo": true,
+	} {
+		assert.Equal(t, exp, isRemotePath(p), p)
+	}
+}
@@ -21,7 +21,16 @@ import (
 // RunService runs a service command (either the default or the streams
 // subcommand).
 func RunService(c *cli.Context, version, dateBuilt string, streamsMode bool) int {
-	mainPath, inferredMainPath, confReader := ReadConfig(c, streamsMode)
+	remote, err := FetchRemoteConfigs(c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Remote configuration fetch error: %v\n", err)
+		return 1
+	}
+	defer func() {
+		_ = remote.Close()
+	}()
+
+	mainPath, inferredMainPath, confReader := ReadConfig(c, streamsMode, remote)
 
 	conf, lints, err := confReader.Read()
 	if err != nil {
@@ -39,7 +48,9 @@ func RunService(c *cli.Context, version, dateBuilt string, streamsMode bool) int
 	}
 
 	verLogger := logger.With("benthos_version", version)
-	if mainPath == "" {
+	if remote != nil && mainPath != c.String("config") {
+		verLogger.With("url", c.String("config")).Info("Running main config from remote file")
+	} else if mainPath == "" {
 		verLogger.Info("Running without a main config file")
 	} else if inferredMainPath {
 		verLogger.With("path", mainPath).Info("Running main config from file found in a default path")
@@ -69,8 +80,14 @@ func RunService(c *cli.Context, version, dateBuilt string, streamsMode bool) int
 	var stoppableStream Stoppable
 	var dataStreamClosedChan chan struct{}
 
-	// Create data streams.
-	watching := c.Bool("watcher")
+	// Create data streams. Polled remote configs are reloaded by watching their
+	// local copies.
+	watching := c.Bool("watcher") || remote.Polling()
+	if remote.Polling() {
+		pollCtx, pollDone := context.WithCancel(c.Context)
+		defer pollDone()
+		go remote.Poll(pollCtx, stoppableManager.Manager().Logger())
+	}
 	if streamsMode {
 		enableStreamsAPI := !c.Bool("no-api")
 		stoppableStream = initStreamsMode(strict, watching, enableStreamsAPI, confReader, stoppableManager.Manager())
@@ -9,10 +9,12 @@ import (
 	"github.com/urfave/cli/v2"
 	"gopkg.in/yaml.v3"
 
+	"github.com/benthosdev/benthos/v4/internal/audit"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/parser"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/cli/blobl"
 	"github.com/benthosdev/benthos/v4/internal/cli/common"
+	"github.com/benthosdev/benthos/v4/internal/cli/schema"
 	"github.com/benthosdev/benthos/v4/internal/cli/studio"
 	clitemplate "github.com/benthosdev/benthos/v4/internal/cli/template"
 	"github.com/benthosdev/benthos/v4/internal/cli/test"
@@ -21,6 +23,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/filepath"
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
 	"github.com/benthosdev/benthos/v4/internal/template"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
 )
 
 // Build stamps.
@@ -90,18 +93,52 @@ func App() *cli.App {
 			Name:    "config",
 			Aliases: []string{"c"},
 			Value:   "",
-			Usage:   "a path to a configuration file",
+			Usage:   "a path or HTTP(S), S3 or git URL of a configuration file",
+		},
+		&cli.StringSliceFlag{
+			Name:    "overlay",
+			Aliases: []string{"o"},
+			Usage:   "merge a config file over the main configuration file, overlays are applied in the order they are specified and before any --set overrides",
 		},
 		&cli.StringSliceFlag{
 			Name:    "resources",
 			Aliases: []string{"r"},
 			Usage:   "pull in extra resources from a file, which can be referenced the same as resources defined in the main config, supports glob patterns (requires quotes)",
 		},
+		&cli.StringFlag{
+			Name:  "remote-poll-interval",
+			Value: "",
+			Usage: "when config or resource files are URLs, poll them for changes at this interval and reload them gracefully when they change, e.g. `30s`",
+		},
+		&cli.StringSliceFlag{
+			Name:  "remote-header",
+			Usage: "add a header to HTTP requests for remote config files, e.g. `\"Authorization: Bearer foo\"`",
+		},
+		&cli.BoolFlag{
+			Name:  "remote-checksum",
+			Value: false,
+			Usage: "require remote config files to match the SHA-256 checksum found at the same URL with a .sha256 suffix added to its path",
+		},
+		&cli.StringFlag{
+			Name:  "remote-public-key",
+			Value: "",
+			Usage: "a path to a PEM encoded ed25519 public key, remote config files must match the base64 encoded signature found at the same URL with a .sig suffix added to its path",
+		},
 		&cli.StringSliceFlag{
 			Name:    "templates",
 			Aliases: []string{"t"},
 			Usage:   "EXPERIMENTAL: import Benthos templates, supports glob patterns (requires quotes)",
 		},
+		&cli.StringFlag{
+			Name:  "tls-policy",
+			Value: "",
+			Usage: "enforce a TLS policy on all components and fail linting on settings it does not permit, options are: default, fips",
+		},
+		&cli.StringFlag{
+			Name:  "audit-log",
+			Value: "",
+			Usage: "append an audit log of config loads and reloads, streams mode changes and the resolution of environment variables to a file as lines of JSON, or to stdout with `-`",
+		},
 		&cli.BoolFlag{
 			Name:  "chilled",
 			Value: false,
@@ -127,6 +164,24 @@ Either run Benthos as a stream processor or choose a command:
   benthos -r "./production/*.yaml" -c ./config.yaml`[1:],
 		Flags: flags,
 		Before: func(c *cli.Context) error {
+			if policy := c.String("tls-policy"); policy != "" {
+				if err := btls.SetPolicy(policy); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to set TLS policy: %v\n", err)
+					os.Exit(1)
+				}
+			}
+
+			if auditPath := c.String("audit-log"); auditPath != "" {
+				sink, err := audit.NewFileSink(auditPath)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to open audit log: %v\n", err)
+					os.Exit(1)
+				}
+				audit.SetSink(sink, func(err error) {
+					fmt.Fprintf(os.Stderr, "Failed to write audit event: %v\n", err)
+				})
+			}
+
 			dotEnvPaths, err := filepath.Globs(ifs.OS(), c.StringSlice("env-file"))
 			if err != nil {
 				fmt.Printf("Failed to resolve env file glob pattern: %v\n", err)
@@ -197,7 +252,16 @@ variables have been resolved:
 
   benthos -c ./config.yaml echo | less`[1:],
 				Action: func(c *cli.Context) error {
-					_, _, confReader := common.ReadConfig(c, false)
+					remote, err := common.FetchRemoteConfigs(c)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Remote configuration fetch error: %v\n", err)
+						os.Exit(1)
+					}
+					defer func() {
+						_ = remote.Close()
+					}()
+
+					_, _, confReader := common.ReadConfig(c, false, remote)
 					conf, _, err := confReader.Read()
 					if err != nil {
 						fmt.Fprintf(os.Stderr, "Configuration file read error: %v\n", err)
@@ -223,6 +287,45 @@ variables have been resolved:
 					return nil
 				},
 			},
+			{
+				Name:  "render",
+				Usage: "Compose a config file with its includes and overlays and print the result",
+				Description: `
+Resolves the include directives of a config file, merges any overlay files and
+--set overrides over it, and prints the resulting config before it is parsed,
+with any secrets scrubbed:
+
+  benthos -c ./config.yaml -o ./production.yaml render`[1:],
+				Action: func(c *cli.Context) error {
+					remote, err := common.FetchRemoteConfigs(c)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Remote configuration fetch error: %v\n", err)
+						os.Exit(1)
+					}
+					defer func() {
+						_ = remote.Close()
+					}()
+
+					_, _, confReader := common.ReadConfig(c, false, remote)
+					node, err := confReader.Render()
+					if err == nil {
+						sanitConf := docs.NewSanitiseConfig(bundle.GlobalEnvironment)
+						sanitConf.ScrubSecrets = true
+						err = config.Spec().SanitiseYAML(node, sanitConf)
+					}
+					if err == nil {
+						var configYAML []byte
+						if configYAML, err = docs.MarshalYAML(*node); err == nil {
+							fmt.Println(string(configYAML))
+						}
+					}
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Render error: %v\n", err)
+						os.Exit(1)
+					}
+					return nil
+				},
+			},
 			lintCliCommand(),
 			{
 				Name:  "streams",
@@ -263,6 +366,7 @@ https://benthos.dev/docs/guides/streams_mode/about`[1:],
 			listCliCommand(),
 			createCliCommand(),
 			test.CliCommand(),
+			schema.CliCommand(),
 			clitemplate.CliCommand(),
 			blobl.CliCommand(),
 			studio.CliCommand(Version, DateBuilt),
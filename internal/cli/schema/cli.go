@@ -0,0 +1,219 @@
+package schema
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/cli/test"
+	ifilepath "github.com/benthosdev/benthos/v4/internal/filepath"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+var (
+	green = color.New(color.FgGreen).SprintFunc()
+	red   = color.New(color.FgRed).SprintFunc()
+)
+
+// CliCommand is a cli.Command definition for registering the payload schemas
+// of configs and checking changes to them for compatibility.
+func CliCommand() *cli.Command {
+	flags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "store",
+			Required: true,
+			Usage:    "a directory or an s3://bucket/prefix URL where schemas are registered.",
+		},
+		&cli.StringFlag{
+			Name:  "name",
+			Value: "",
+			Usage: "the name to register a schema under, defaults to the config file name without its extension. Can only be used with a single config.",
+		},
+	}
+
+	return &cli.Command{
+		Name:  "schema",
+		Usage: "Register and check the payload schemas of configs",
+		Description: `
+Infer the shape of the payloads produced by configs from the outputs of their
+unit tests, and either register them within a store or check them for breaking
+changes against the schemas previously registered.
+
+  benthos schema register --store ./schemas ./orders.yaml
+  benthos schema check --store s3://foo/schemas ./configs/...
+
+For more information check out the docs at:
+https://benthos.dev/docs/configuration/unit_testing#payload-schemas`[1:],
+		Subcommands: []*cli.Command{
+			{
+				Name:  "register",
+				Usage: "Register the payload schemas of configs",
+				Description: `
+Infer the payload schemas of configs from the outputs of their unit tests and
+register them within a store, replacing any previously registered versions.
+
+  benthos schema register --store ./schemas ./orders.yaml`[1:],
+				Flags: flags,
+				Action: func(c *cli.Context) error {
+					return run(c, register)
+				},
+			},
+			{
+				Name:  "check",
+				Usage: "Check configs for breaking changes to their payload schemas",
+				Description: `
+Infer the payload schemas of configs from the outputs of their unit tests and
+check them against the versions registered within a store. If any config
+produces payloads that are incompatible with its registered schema the
+process will report the differences and exit with a status code 1.
+
+  benthos schema check --store ./schemas ./orders.yaml`[1:],
+				Flags: flags,
+				Action: func(c *cli.Context) error {
+					return run(c, check)
+				},
+			},
+		},
+	}
+}
+
+type actionFn func(ctx context.Context, w io.Writer, s *store, shapes map[string]*Shape) bool
+
+func run(c *cli.Context, fn actionFn) error {
+	resourcesPaths := c.StringSlice("resources")
+	var err error
+	if resourcesPaths, err = ifilepath.Globs(ifs.OS(), resourcesPaths); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve resource glob pattern: %v\n", err)
+		os.Exit(1)
+	}
+
+	shapes, err := InferTargets(c.Args().Slice(), c.String("name"), resourcesPaths)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to infer schemas: %v\n", err)
+		os.Exit(1)
+	}
+
+	s, err := newStore(c.String("store"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open schema store: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() {
+		_ = s.close(context.Background())
+	}()
+
+	if !fn(c.Context, os.Stdout, s, shapes) {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// InferTargets infers the payload shapes of the configs found at the provided
+// paths from the outputs of their unit tests, keyed by the name of each
+// schema. Configs without tests are ignored.
+func InferTargets(paths []string, name string, resourcesPaths []string) (map[string]*Shape, error) {
+	targets, err := test.GetTestTargets(paths, "_benthos_test")
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no configs with unit tests were found")
+	}
+	if name != "" && len(targets) > 1 {
+		return nil, fmt.Errorf("a schema name can only be provided for a single config, found %v", len(targets))
+	}
+
+	shapes := map[string]*Shape{}
+	sources := map[string]string{}
+	for target, cases := range targets {
+		tName := name
+		if tName == "" {
+			tName = strings.TrimSuffix(filepath.Base(target), filepath.Ext(target))
+		}
+		if existing, exists := sources[tName]; exists {
+			return nil, fmt.Errorf("configs '%v' and '%v' share the schema name '%v'", existing, target, tName)
+		}
+		sources[tName] = target
+
+		batches, err := test.Outputs(cases, target, resourcesPaths, log.Noop())
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", target, err)
+		}
+
+		var shape *Shape
+		for _, b := range batches {
+			for i, p := range b {
+				if err := p.ErrorGet(); err != nil {
+					return nil, fmt.Errorf("%v: output message %v failed: %w", target, i, err)
+				}
+				pShape := &Shape{Types: []string{typeBytes}}
+				if v, err := p.AsStructured(); err == nil {
+					pShape = InferShape(v)
+				}
+				shape = MergeShapes(shape, pShape)
+			}
+		}
+		if shape == nil {
+			return nil, fmt.Errorf("%v: unit tests did not produce any output messages", target)
+		}
+		shapes[tName] = shape
+	}
+	return shapes, nil
+}
+
+func sortedNames(shapes map[string]*Shape) []string {
+	names := make([]string, 0, len(shapes))
+	for k := range shapes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func register(ctx context.Context, w io.Writer, s *store, shapes map[string]*Shape) bool {
+	for _, name := range sortedNames(shapes) {
+		if err := s.put(ctx, &Entry{
+			Name:         name,
+			RegisteredAt: time.Now().UTC(),
+			Shape:        shapes[name],
+		}); err != nil {
+			fmt.Fprintf(w, "Schema '%v': %v\n  Failed to register: %v\n", name, red("FAILED"), err)
+			return false
+		}
+		fmt.Fprintf(w, "Schema '%v': %v\n", name, green("REGISTERED"))
+	}
+	return true
+}
+
+func check(ctx context.Context, w io.Writer, s *store, shapes map[string]*Shape) bool {
+	passed := true
+	for _, name := range sortedNames(shapes) {
+		e, err := s.get(ctx, name)
+		if err != nil {
+			fmt.Fprintf(w, "Schema '%v': %v\n  %v\n", name, red("FAILED"), err)
+			passed = false
+			continue
+		}
+		issues := Incompatibilities(e.Shape, shapes[name])
+		if len(issues) == 0 {
+			fmt.Fprintf(w, "Schema '%v': %v\n", name, green("PASSED"))
+			continue
+		}
+		passed = false
+		fmt.Fprintf(w, "Schema '%v': %v\n", name, red("INCOMPATIBLE"))
+		for _, issue := range issues {
+			fmt.Fprintf(w, "  %v\n", issue)
+		}
+	}
+	return passed
+}
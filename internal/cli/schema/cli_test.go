@@ -0,0 +1,54 @@
+package schema
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+)
+
+func TestRegisterAndCheck(t *testing.T) {
+	ctx := context.Background()
+
+	c := &mock.Cache{Values: map[string]mock.CacheItem{}}
+	s := &store{c: c, prefix: "contracts"}
+
+	var out bytes.Buffer
+
+	shapes := map[string]*Shape{
+		"orders": InferShape(map[string]any{"id": "a", "qty": 1.0}),
+	}
+
+	assert.False(t, check(ctx, &out, s, shapes))
+	assert.Contains(t, out.String(), "schema has not been registered")
+
+	out.Reset()
+	assert.True(t, register(ctx, &out, s, shapes))
+	assert.Contains(t, out.String(), "Schema 'orders'")
+	require.Contains(t, c.Values, "contracts/orders.json")
+
+	e, err := s.get(ctx, "orders")
+	require.NoError(t, err)
+	assert.Equal(t, "orders", e.Name)
+	assert.Equal(t, shapes["orders"], e.Shape)
+	assert.False(t, e.RegisteredAt.IsZero())
+
+	// Adding fields is compatible.
+	shapes["orders"] = InferShape(map[string]any{"id": "a", "qty": 1.0, "source": "shop"})
+
+	out.Reset()
+	assert.True(t, check(ctx, &out, s, shapes), out.String())
+
+	// Removing and changing the type of fields is not.
+	shapes["orders"] = InferShape(map[string]any{"qty": "1"})
+
+	out.Reset()
+	assert.False(t, check(ctx, &out, s, shapes))
+	assert.Contains(t, out.String(), "INCOMPATIBLE")
+	assert.Contains(t, out.String(), ".id: required field was removed")
+	assert.Contains(t, out.String(), ".qty: type string is produced but the registered schema only allows [number]")
+}
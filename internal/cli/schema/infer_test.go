@@ -0,0 +1,72 @@
+package schema_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/cli/schema"
+
+	_ "github.com/benthosdev/benthos/v4/public/components/pure"
+)
+
+func TestInferTargets(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "nested"), 0o755))
+
+	for path, content := range map[string]string{
+		"orders.yaml": `
+pipeline:
+  processors:
+    - mapping: 'root = this.merge({"total": this.qty * 2})'
+
+tests:
+  - name: first
+    input_batches:
+      - - content: '{"id":"a","qty":1}'
+  - name: second
+    input_batches:
+      - - content: '{"id":"b","qty":2,"note":"hi"}'
+`,
+		"nested/raw.yaml": `
+pipeline:
+  processors:
+    - mapping: 'root = content().uppercase()'
+`,
+		"nested/raw_benthos_test.yaml": `
+tests:
+  - name: raw
+    input_batches:
+      - - content: 'hello world'
+`,
+		"untested.yaml": `
+pipeline:
+  processors: []
+`,
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, path), []byte(content), 0o644))
+	}
+
+	shapes, err := schema.InferTargets([]string{tmpDir + "/..."}, "", nil)
+	require.NoError(t, err)
+	require.Len(t, shapes, 2)
+
+	require.Contains(t, shapes, "orders")
+	assert.Equal(t, []string{"object"}, shapes["orders"].Types)
+	assert.Equal(t, []string{"id", "qty", "total"}, shapes["orders"].Required)
+	assert.Contains(t, shapes["orders"].Properties, "note")
+
+	require.Contains(t, shapes, "raw")
+	assert.Equal(t, []string{"bytes"}, shapes["raw"].Types)
+
+	shapes, err = schema.InferTargets([]string{filepath.Join(tmpDir, "orders.yaml")}, "orders-v2", nil)
+	require.NoError(t, err)
+	assert.Contains(t, shapes, "orders-v2")
+
+	_, err = schema.InferTargets([]string{tmpDir + "/..."}, "foo", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "single config")
+}
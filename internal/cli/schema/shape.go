@@ -0,0 +1,182 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Value types of a shape.
+const (
+	typeNull    = "null"
+	typeBool    = "boolean"
+	typeNumber  = "number"
+	typeString  = "string"
+	typeArray   = "array"
+	typeObject  = "object"
+	typeBytes   = "bytes"
+	typeUnknown = "unknown"
+)
+
+// Shape describes the structure of the payloads produced by a pipeline, as
+// inferred from a set of samples.
+type Shape struct {
+	// Types are the value types observed, in sorted order.
+	Types []string `json:"types"`
+
+	// Properties are the shapes of the fields of objects.
+	Properties map[string]*Shape `json:"properties,omitempty"`
+
+	// Required are the fields present within all observed objects, in sorted
+	// order.
+	Required []string `json:"required,omitempty"`
+
+	// Items is the shape of the elements of arrays.
+	Items *Shape `json:"items,omitempty"`
+}
+
+func typeOf(v any) string {
+	switch v.(type) {
+	case nil:
+		return typeNull
+	case bool:
+		return typeBool
+	case float64, float32, int, int64, int32, uint64, uint32, json.Number:
+		return typeNumber
+	case string:
+		return typeString
+	case []any:
+		return typeArray
+	case map[string]any:
+		return typeObject
+	}
+	return typeUnknown
+}
+
+// InferShape returns the shape of a structured value.
+func InferShape(v any) *Shape {
+	t := typeOf(v)
+	s := &Shape{Types: []string{t}}
+	switch t {
+	case typeObject:
+		obj := v.(map[string]any)
+		s.Properties = make(map[string]*Shape, len(obj))
+		for k, fv := range obj {
+			s.Properties[k] = InferShape(fv)
+			s.Required = append(s.Required, k)
+		}
+		sort.Strings(s.Required)
+	case typeArray:
+		for _, ev := range v.([]any) {
+			s.Items = MergeShapes(s.Items, InferShape(ev))
+		}
+	}
+	return s
+}
+
+func hasString(s []string, v string) bool {
+	i := sort.SearchStrings(s, v)
+	return i < len(s) && s[i] == v
+}
+
+func unionStrings(a, b []string) []string {
+	u := append([]string{}, a...)
+	for _, v := range b {
+		if !hasString(a, v) {
+			u = append(u, v)
+		}
+	}
+	sort.Strings(u)
+	return u
+}
+
+// MergeShapes returns a shape that accepts the samples of both a and b, either
+// of which may be nil.
+func MergeShapes(a, b *Shape) *Shape {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+
+	m := &Shape{Types: unionStrings(a.Types, b.Types)}
+
+	aObj, bObj := hasString(a.Types, typeObject), hasString(b.Types, typeObject)
+	if aObj || bObj {
+		m.Properties = map[string]*Shape{}
+		for k, v := range a.Properties {
+			m.Properties[k] = v
+		}
+		for k, v := range b.Properties {
+			m.Properties[k] = MergeShapes(m.Properties[k], v)
+		}
+		// A field is only required when it is present in all objects of both
+		// shapes.
+		switch {
+		case aObj && bObj:
+			for _, k := range a.Required {
+				if hasString(b.Required, k) {
+					m.Required = append(m.Required, k)
+				}
+			}
+		case aObj:
+			m.Required = a.Required
+		default:
+			m.Required = b.Required
+		}
+	}
+
+	m.Items = MergeShapes(a.Items, b.Items)
+	return m
+}
+
+// Incompatibilities returns a description of each change from a registered
+// shape to a current shape that could break consumers of the registered shape.
+// Adding fields is compatible, whereas removing fields, making them optional or
+// producing new value types are not.
+func Incompatibilities(registered, current *Shape) []string {
+	var issues []string
+	compareShapes(".", registered, current, &issues)
+	return issues
+}
+
+func fieldPath(path, field string) string {
+	if path == "." {
+		return "." + field
+	}
+	return path + "." + field
+}
+
+func compareShapes(path string, registered, current *Shape, issues *[]string) {
+	if registered == nil || current == nil {
+		return
+	}
+	for _, t := range current.Types {
+		if !hasString(registered.Types, t) {
+			*issues = append(*issues, fmt.Sprintf("%v: type %v is produced but the registered schema only allows %v", path, t, registered.Types))
+		}
+	}
+
+	if hasString(registered.Types, typeObject) && hasString(current.Types, typeObject) {
+		for _, k := range registered.Required {
+			p := fieldPath(path, k)
+			if _, exists := current.Properties[k]; !exists {
+				*issues = append(*issues, fmt.Sprintf("%v: required field was removed", p))
+			} else if !hasString(current.Required, k) {
+				*issues = append(*issues, fmt.Sprintf("%v: required field is no longer always present", p))
+			}
+		}
+
+		keys := make([]string, 0, len(registered.Properties))
+		for k := range registered.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			compareShapes(fieldPath(path, k), registered.Properties[k], current.Properties[k], issues)
+		}
+	}
+
+	compareShapes(path+"[]", registered.Items, current.Items, issues)
+}
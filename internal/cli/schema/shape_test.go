@@ -0,0 +1,127 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferShape(t *testing.T) {
+	s := InferShape(map[string]any{
+		"id":   "foo",
+		"tags": []any{"a", 5.0},
+		"meta": map[string]any{"count": 10.0},
+	})
+
+	assert.Equal(t, &Shape{
+		Types: []string{typeObject},
+		Properties: map[string]*Shape{
+			"id": {Types: []string{typeString}},
+			"tags": {
+				Types: []string{typeArray},
+				Items: &Shape{Types: []string{typeNumber, typeString}},
+			},
+			"meta": {
+				Types:      []string{typeObject},
+				Properties: map[string]*Shape{"count": {Types: []string{typeNumber}}},
+				Required:   []string{"count"},
+			},
+		},
+		Required: []string{"id", "meta", "tags"},
+	}, s)
+}
+
+func TestMergeShapes(t *testing.T) {
+	s := MergeShapes(
+		InferShape(map[string]any{"id": "foo", "name": "bar"}),
+		InferShape(map[string]any{"id": 10.0, "age": 5.0}),
+	)
+
+	assert.Equal(t, &Shape{
+		Types: []string{typeObject},
+		Properties: map[string]*Shape{
+			"id":   {Types: []string{typeNumber, typeString}},
+			"name": {Types: []string{typeString}},
+			"age":  {Types: []string{typeNumber}},
+		},
+		Required: []string{"id"},
+	}, s)
+
+	s = MergeShapes(s, InferShape("nope"))
+	assert.Equal(t, []string{typeObject, typeString}, s.Types)
+	assert.Equal(t, []string{"id"}, s.Required)
+}
+
+func TestIncompatibilities(t *testing.T) {
+	registered := InferShape(map[string]any{
+		"id":    "foo",
+		"items": []any{map[string]any{"sku": "a", "qty": 1.0}},
+	})
+
+	tests := []struct {
+		name    string
+		current *Shape
+		issues  []string
+	}{
+		{
+			name: "identical",
+			current: InferShape(map[string]any{
+				"id":    "bar",
+				"items": []any{map[string]any{"sku": "b", "qty": 2.0}},
+			}),
+		},
+		{
+			name: "added field",
+			current: InferShape(map[string]any{
+				"id":    "bar",
+				"extra": true,
+				"items": []any{map[string]any{"sku": "b", "qty": 2.0, "note": "c"}},
+			}),
+		},
+		{
+			name: "removed field",
+			current: InferShape(map[string]any{
+				"items": []any{map[string]any{"sku": "b"}},
+			}),
+			issues: []string{
+				".id: required field was removed",
+				".items[].qty: required field was removed",
+			},
+		},
+		{
+			name: "optional field",
+			current: MergeShapes(
+				InferShape(map[string]any{"id": "bar", "items": []any{}}),
+				InferShape(map[string]any{"items": []any{}}),
+			),
+			issues: []string{
+				".id: required field is no longer always present",
+			},
+		},
+		{
+			name: "changed type",
+			current: InferShape(map[string]any{
+				"id":    5.0,
+				"items": []any{map[string]any{"sku": "b", "qty": "2"}},
+			}),
+			issues: []string{
+				".id: type number is produced but the registered schema only allows [string]",
+				".items[].qty: type string is produced but the registered schema only allows [number]",
+			},
+		},
+		{
+			name:    "changed root type",
+			current: InferShape([]any{}),
+			issues: []string{
+				".: type array is produced but the registered schema only allows [object]",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.issues, Incompatibilities(registered, test.current))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package schema
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+)
+
+// Entry is a schema registered within a store.
+type Entry struct {
+	Name         string    `json:"name"`
+	RegisteredAt time.Time `json:"registered_at"`
+	Shape        *Shape    `json:"shape"`
+}
+
+// errNotRegistered is returned when a schema has not been registered.
+var errNotRegistered = errors.New("schema has not been registered")
+
+// store persists schemas within a cache, which is either a file cache for a
+// local directory or an S3 cache for an `s3://bucket/prefix` URL.
+type store struct {
+	c      cache.V1
+	prefix string
+}
+
+func newStore(location string) (*store, error) {
+	var cacheConf any
+	var prefix string
+	if strings.HasPrefix(location, "s3://") {
+		u, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse store URL: %w", err)
+		}
+		if u.Host == "" {
+			return nil, errors.New("store URL must contain a bucket")
+		}
+		cacheConf = map[string]any{
+			"aws_s3": map[string]any{
+				"bucket":       u.Host,
+				"content_type": "application/json",
+			},
+		}
+		prefix = strings.Trim(u.Path, "/")
+	} else {
+		if err := os.MkdirAll(location, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create store directory: %w", err)
+		}
+		cacheConf = map[string]any{
+			"file": map[string]any{
+				"directory": location,
+			},
+		}
+	}
+
+	conf, err := cache.FromAny(bundle.GlobalEnvironment, cacheConf)
+	if err != nil {
+		return nil, err
+	}
+
+	mgr, err := manager.New(manager.NewResourceConfig())
+	if err != nil {
+		return nil, err
+	}
+	c, err := mgr.NewCache(conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+	return &store{c: c, prefix: prefix}, nil
+}
+
+func (s *store) key(name string) string {
+	return path.Join(s.prefix, name+".json")
+}
+
+func (s *store) get(ctx context.Context, name string) (*Entry, error) {
+	b, err := s.c.Get(ctx, s.key(name))
+	if errors.Is(err, component.ErrKeyNotFound) {
+		return nil, errNotRegistered
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse registered schema: %w", err)
+	}
+	return &e, nil
+}
+
+func (s *store) put(ctx context.Context, e *Entry) error {
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return s.c.Set(ctx, s.key(e.Name), b, nil)
+}
+
+func (s *store) close(ctx context.Context) error {
+	return s.c.Close(ctx)
+}
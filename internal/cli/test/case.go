@@ -32,42 +32,60 @@ type ProcProvider interface {
 	ProvideBloblang(path string) ([]iprocessor.V1, error)
 }
 
-// ExecuteFrom executes a test case from the perspective of a given directory,
-// which is used for obtaining relative condition file imports.
-func ExecuteFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) (failures []CaseFailure, err error) {
-	var procSet []iprocessor.V1
+// prepareFrom initialises the processors targeted by a test case along with its
+// input batches.
+func prepareFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) (procSet []iprocessor.V1, inputMsg []message.Batch, err error) {
 	if c.TargetMapping != "" {
 		if procSet, err = provider.ProvideBloblang(c.TargetMapping); err != nil {
-			return nil, fmt.Errorf("failed to initialise Bloblang mapping '%v': %v", c.TargetMapping, err)
+			return nil, nil, fmt.Errorf("failed to initialise Bloblang mapping '%v': %v", c.TargetMapping, err)
 		}
 	} else {
 		if procSet, err = provider.Provide(c.TargetProcessors, c.Environment, c.Mocks); err != nil {
-			return nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
+			return nil, nil, fmt.Errorf("failed to initialise processors '%v': %v", c.TargetProcessors, err)
 		}
 	}
 
-	reportFailure := func(reason string) {
-		failures = append(failures, CaseFailure{
-			Name:     c.Name,
-			TestLine: c.Line(),
-			Reason:   reason,
-		})
-	}
-
-	var inputMsg []message.Batch
-
 	for _, inputBatch := range c.InputBatches {
 		parts := make([]*message.Part, len(inputBatch))
 		for i, v := range inputBatch {
 			if parts[i], err = v.ToMessage(fs, dir); err != nil {
-				err = fmt.Errorf("failed to create test input %v: %w", i, err)
-				return
+				return nil, nil, fmt.Errorf("failed to create test input %v: %w", i, err)
 			}
 		}
+		inputMsg = append(inputMsg, message.Batch(parts))
+	}
+	return
+}
 
-		currentBatch := message.Batch(parts)
-		inputMsg = append(inputMsg, currentBatch)
+// OutputsFrom executes the processors of a test case from the perspective of a
+// given directory and returns the resulting batches, the output conditions of
+// the case are not checked.
+func OutputsFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) ([]message.Batch, error) {
+	procSet, inputMsg, err := prepareFrom(fs, dir, c, provider)
+	if err != nil {
+		return nil, err
+	}
+	outputBatches, result := iprocessor.ExecuteAll(context.Background(), procSet, inputMsg...)
+	if result != nil {
+		return nil, fmt.Errorf("processors resulted in error: %v", result)
+	}
+	return outputBatches, nil
+}
 
+// ExecuteFrom executes a test case from the perspective of a given directory,
+// which is used for obtaining relative condition file imports.
+func ExecuteFrom(fs fs.FS, dir string, c test.Case, provider ProcProvider) (failures []CaseFailure, err error) {
+	procSet, inputMsg, err := prepareFrom(fs, dir, c, provider)
+	if err != nil {
+		return nil, err
+	}
+
+	reportFailure := func(reason string) {
+		failures = append(failures, CaseFailure{
+			Name:     c.Name,
+			TestLine: c.Line(),
+			Reason:   reason,
+		})
 	}
 
 	outputBatches, result := iprocessor.ExecuteAll(context.Background(), procSet, inputMsg...)
@@ -7,6 +7,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/config/test"
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
 	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/message"
 )
 
 // Execute the test definition.
@@ -33,3 +34,27 @@ func Execute(cases []test.Case, testFilePath string, resourcesPaths []string, lo
 
 	return totalFailures, nil
 }
+
+// Outputs executes the processors of each case within a test definition and
+// returns the resulting batches of all cases combined.
+func Outputs(cases []test.Case, testFilePath string, resourcesPaths []string, logger log.Modular) ([]message.Batch, error) {
+	procsProvider := NewProcessorsProvider(
+		testFilePath,
+		OptAddResourcesPaths(resourcesPaths),
+		OptProcessorsProviderSetLogger(logger),
+	)
+
+	dir := filepath.Dir(testFilePath)
+
+	var totalBatches []message.Batch
+	for i, c := range cases {
+		cleanupEnv := setEnvironment(c.Environment)
+		batches, err := OutputsFrom(ifs.OS(), dir, c, procsProvider)
+		cleanupEnv()
+		if err != nil {
+			return nil, fmt.Errorf("test case %v failed: %v", i, err)
+		}
+		totalBatches = append(totalBatches, batches...)
+	}
+	return totalBatches, nil
+}
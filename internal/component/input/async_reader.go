@@ -12,6 +12,7 @@ import (
 	"github.com/Jeffail/shutdown"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/memguard"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 )
@@ -154,6 +155,14 @@ func (r *AsyncReader) loop() {
 	atomic.StoreInt32(&r.connected, 1)
 
 	for {
+		// Stop consuming whilst the memory guard is shedding load.
+		if memguard.Active(memguard.PolicyPauseInputs) {
+			r.mgr.Logger().Debug("Pausing input %v whilst memory usage is high", r.typeStr)
+			if memguard.WaitUntilInactive(closeAtLeisureCtx, memguard.PolicyPauseInputs) != nil {
+				return
+			}
+		}
+
 		msg, ackFn, err := r.reader.ReadBatch(closeAtLeisureCtx)
 
 		// If our reader says it is not connected.
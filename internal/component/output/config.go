@@ -13,10 +13,11 @@ import (
 // Deprecated: Do not add new components here. Instead, use the public plugin
 // APIs. Examples can be found in: ./internal/impl.
 type Config struct {
-	Label      string             `json:"label" yaml:"label"`
-	Type       string             `json:"type" yaml:"type"`
-	Plugin     any                `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	Processors []processor.Config `json:"processors" yaml:"processors"`
+	Label          string             `json:"label" yaml:"label"`
+	Type           string             `json:"type" yaml:"type"`
+	Plugin         any                `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Processors     []processor.Config `json:"processors" yaml:"processors"`
+	PostProcessors []processor.Config `json:"post_processors" yaml:"post_processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -24,10 +25,11 @@ type Config struct {
 // APIs. Examples can be found in: ./internal/impl.
 func NewConfig() Config {
 	return Config{
-		Label:      "",
-		Type:       "stdout",
-		Plugin:     nil,
-		Processors: []processor.Config{},
+		Label:          "",
+		Type:           "stdout",
+		Plugin:         nil,
+		Processors:     []processor.Config{},
+		PostProcessors: []processor.Config{},
 	}
 }
 
@@ -52,20 +54,11 @@ func fromMap(prov docs.Provider, value map[string]any) (conf Config, err error)
 
 	conf.Label, _ = value["label"].(string)
 
-	if procV, exists := value["processors"]; exists {
-		procArr, ok := procV.([]any)
-		if !ok {
-			err = fmt.Errorf("processors: unexpected value, expected array got %T", procV)
-			return
-		}
-		for i, pv := range procArr {
-			var tmpProc processor.Config
-			if tmpProc, err = processor.FromAny(prov, pv); err != nil {
-				err = fmt.Errorf("%v: %w", i, err)
-				return
-			}
-			conf.Processors = append(conf.Processors, tmpProc)
-		}
+	if conf.Processors, err = processorsFromMap(prov, value, "processors"); err != nil {
+		return
+	}
+	if conf.PostProcessors, err = processorsFromMap(prov, value, "post_processors"); err != nil {
+		return
 	}
 
 	if p, exists := value[conf.Type]; exists {
@@ -76,6 +69,27 @@ func fromMap(prov docs.Provider, value map[string]any) (conf Config, err error)
 	return
 }
 
+func processorsFromMap(prov docs.Provider, value map[string]any, key string) (procs []processor.Config, err error) {
+	procV, exists := value[key]
+	if !exists {
+		return
+	}
+	procArr, ok := procV.([]any)
+	if !ok {
+		err = fmt.Errorf("%v: unexpected value, expected array got %T", key, procV)
+		return
+	}
+	for i, pv := range procArr {
+		var tmpProc processor.Config
+		if tmpProc, err = processor.FromAny(prov, pv); err != nil {
+			err = fmt.Errorf("%v: %w", i, err)
+			return
+		}
+		procs = append(procs, tmpProc)
+	}
+	return
+}
+
 func fromYAML(prov docs.Provider, value *yaml.Node) (conf Config, err error) {
 	if conf.Type, _, err = docs.GetInferenceCandidateFromYAML(prov, docs.TypeOutput, value); err != nil {
 		err = docs.NewLintError(value.Line, docs.LintComponentNotFound, err)
@@ -95,6 +109,15 @@ func fromYAML(prov docs.Provider, value *yaml.Node) (conf Config, err error) {
 				}
 				conf.Processors = append(conf.Processors, tmpProc)
 			}
+		case "post_processors":
+			for i, n := range value.Content[i+1].Content {
+				var tmpProc processor.Config
+				if tmpProc, err = processor.FromAny(prov, n); err != nil {
+					err = fmt.Errorf("%v: %w", i, err)
+					return
+				}
+				conf.PostProcessors = append(conf.PostProcessors, tmpProc)
+			}
 		}
 	}
 
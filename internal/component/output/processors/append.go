@@ -31,13 +31,22 @@ func AppendFromConfig(conf output.Config, mgr bundle.NewManagement, pipelines ..
 }
 
 // WrapConstructor provides a way to define an output constructor without
-// manually initializing processors of the config.
+// manually initializing processors and post processors of the config.
 func WrapConstructor(fn func(output.Config, bundle.NewManagement) (output.Streamed, error)) bundle.OutputConstructor {
 	return func(c output.Config, nm bundle.NewManagement, pcf ...processor.PipelineConstructorFunc) (output.Streamed, error) {
 		o, err := fn(c, nm)
 		if err != nil {
 			return nil, err
 		}
+		if len(c.PostProcessors) > 0 {
+			postProcs := make([]processor.V1, len(c.PostProcessors))
+			for j, procConf := range c.PostProcessors {
+				if postProcs[j], err = nm.IntoPath("post_processors", strconv.Itoa(j)).NewProcessor(procConf); err != nil {
+					return nil, err
+				}
+			}
+			o = output.WrapWithPostProcessors(o, postProcs...)
+		}
 		pcf = AppendFromConfig(c, nm, pcf...)
 		return output.WrapWithPipelines(o, pcf...)
 	}
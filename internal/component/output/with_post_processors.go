@@ -0,0 +1,136 @@
+package output
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	iprocessor "github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+// WithPostProcessors wraps an output with processors that are executed on each
+// message batch once it has been delivered successfully, and before the batch
+// is acknowledged.
+type WithPostProcessors struct {
+	out   Streamed
+	procs []iprocessor.V1
+
+	inChan  <-chan message.Transaction
+	outChan chan message.Transaction
+
+	shutSig *shutdown.Signaller
+}
+
+// WrapWithPostProcessors returns an output that executes processors on each
+// message batch once it has been delivered successfully by the wrapped output.
+func WrapWithPostProcessors(out Streamed, procs ...iprocessor.V1) *WithPostProcessors {
+	return &WithPostProcessors{
+		out:     out,
+		procs:   procs,
+		outChan: make(chan message.Transaction),
+		shutSig: shutdown.NewSignaller(),
+	}
+}
+
+// postProcess executes the processors on a delivered batch. The batch was
+// delivered successfully and therefore any errors flagged on its messages
+// beforehand are cleared, so that only errors of the processors themselves
+// result in the batch being rejected.
+func (w *WithPostProcessors) postProcess(ctx context.Context, msg message.Batch) error {
+	msg = msg.ShallowCopy()
+	for _, p := range msg {
+		p.ErrorSet(nil)
+	}
+
+	batches, err := iprocessor.ExecuteAll(ctx, w.procs, msg)
+	if err != nil {
+		return fmt.Errorf("post processors: %w", err)
+	}
+	for _, b := range batches {
+		for _, p := range b {
+			if err := p.ErrorGet(); err != nil {
+				return fmt.Errorf("post processors: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (w *WithPostProcessors) loop() {
+	ctx, done := w.shutSig.HardStopCtx(context.Background())
+	defer done()
+
+	defer func() {
+		close(w.outChan)
+		w.out.TriggerCloseNow()
+		_ = w.out.WaitForClose(context.Background())
+		for _, p := range w.procs {
+			_ = p.Close(context.Background())
+		}
+		w.shutSig.TriggerHasStopped()
+	}()
+
+	for {
+		var tran message.Transaction
+		var open bool
+		select {
+		case tran, open = <-w.inChan:
+			if !open {
+				return
+			}
+		case <-w.shutSig.HardStopChan():
+			return
+		}
+
+		select {
+		case w.outChan <- message.NewTransactionFunc(tran.Payload, func(ackCtx context.Context, err error) error {
+			if err == nil {
+				err = w.postProcess(ackCtx, tran.Payload)
+			}
+			return tran.Ack(ackCtx, err)
+		}):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// Consume starts the type listening to a message channel from a producer.
+func (w *WithPostProcessors) Consume(ts <-chan message.Transaction) error {
+	if w.inChan != nil {
+		return component.ErrAlreadyStarted
+	}
+	if err := w.out.Consume(w.outChan); err != nil {
+		return err
+	}
+	w.inChan = ts
+	go w.loop()
+	return nil
+}
+
+// Connected returns a boolean indicating whether this output is currently
+// connected to its target.
+func (w *WithPostProcessors) Connected() bool {
+	return w.out.Connected()
+}
+
+// TriggerCloseNow triggers a closure of this object but does not block.
+func (w *WithPostProcessors) TriggerCloseNow() {
+	w.shutSig.TriggerHardStop()
+}
+
+// WaitForClose is a blocking call to wait until the object has finished closing
+// down and cleaning up resources.
+func (w *WithPostProcessors) WaitForClose(ctx context.Context) error {
+	select {
+	case <-w.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package output_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+func newPostProcessorsOutput(t *testing.T, mgr *mock.Manager, procConfs ...string) (*mockOutput, *output.WithPostProcessors) {
+	t.Helper()
+
+	var procs []processor.V1
+	for _, c := range procConfs {
+		conf, err := testutil.ProcessorFromYAML(c)
+		require.NoError(t, err)
+
+		proc, err := mgr.NewProcessor(conf)
+		require.NoError(t, err)
+		procs = append(procs, proc)
+	}
+
+	mockOut := &mockOutput{}
+	return mockOut, output.WrapWithPostProcessors(mockOut, procs...)
+}
+
+func sendAndDeliver(t *testing.T, tChan chan<- message.Transaction, mockOut *mockOutput, msg message.Batch, fn func(message.Batch) error) error {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	resChan := make(chan error)
+	select {
+	case tChan <- message.NewTransaction(msg, resChan):
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+
+	var tran message.Transaction
+	select {
+	case tran = <-mockOut.ts:
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+
+	go func() {
+		require.NoError(t, tran.Ack(ctx, fn(tran.Payload)))
+	}()
+
+	select {
+	case err := <-resChan:
+		return err
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+	return nil
+}
+
+func TestPostProcessorsAfterDelivery(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	mgr := mock.NewManager()
+	mgr.Caches["audit"] = map[string]mock.CacheItem{}
+
+	mockOut, newOutput := newPostProcessorsOutput(t, mgr, `
+cache:
+  resource: audit
+  operator: set
+  key: ${! content() }
+  value: ${! @result }
+`)
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, newOutput.Consume(tChan))
+	assert.Error(t, newOutput.Consume(tChan))
+
+	// Errors flagged before delivery are ignored by the post processors.
+	msg := message.QuickBatch([][]byte{[]byte("foo"), []byte("bar")})
+	msg.Get(1).ErrorSet(errors.New("earlier failure"))
+
+	require.NoError(t, sendAndDeliver(t, tChan, mockOut, msg, func(b message.Batch) error {
+		for _, p := range b {
+			p.MetaSetMut("result", "delivered "+string(p.AsBytes()))
+		}
+		return nil
+	}))
+
+	assert.Equal(t, map[string]mock.CacheItem{
+		"foo": {Value: "delivered foo"},
+		"bar": {Value: "delivered bar"},
+	}, mgr.Caches["audit"])
+
+	// Post processors are not executed for batches that failed to deliver.
+	err := sendAndDeliver(t, tChan, mockOut, message.QuickBatch([][]byte{[]byte("baz")}), func(b message.Batch) error {
+		return errors.New("delivery failed")
+	})
+	require.EqualError(t, err, "delivery failed")
+	assert.Len(t, mgr.Caches["audit"], 2)
+
+	close(tChan)
+	newOutput.TriggerCloseNow()
+	require.NoError(t, newOutput.WaitForClose(ctx))
+}
+
+func TestPostProcessorsErrors(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	mockOut, newOutput := newPostProcessorsOutput(t, mock.NewManager(), `
+mapping: |
+  root = if content() == "bad" { throw("nope") }
+`)
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, newOutput.Consume(tChan))
+
+	require.NoError(t, sendAndDeliver(t, tChan, mockOut, message.QuickBatch([][]byte{[]byte("good")}), func(message.Batch) error {
+		return nil
+	}))
+
+	err := sendAndDeliver(t, tChan, mockOut, message.QuickBatch([][]byte{[]byte("good"), []byte("bad")}), func(message.Batch) error {
+		return nil
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "post processors")
+	assert.Contains(t, err.Error(), "nope")
+
+	close(tChan)
+	newOutput.TriggerCloseNow()
+	require.NoError(t, newOutput.WaitForClose(ctx))
+}
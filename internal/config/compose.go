@@ -0,0 +1,152 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	ifilepath "github.com/benthosdev/benthos/v4/internal/filepath"
+)
+
+const fieldInclude = "include"
+
+var includeField = docs.FieldString(fieldInclude, "A list of config files to include, which are merged beneath the contents of this file. Paths are relative to the file they are included from, and glob patterns are supported.").Array().Optional().Advanced().AtVersion("4.28.0")
+
+// readComposedFile reads a config file and resolves any include directives
+// within it, returning a single node where the contents of the file have been
+// merged over the contents of its includes. The paths of all files read,
+// including the file itself, are added to deps along with their modified times.
+func (r *Reader) readComposedFile(path string, chain []string, deps map[string]time.Time) (node *yaml.Node, confBytes []byte, modTime time.Time, lints []string, err error) {
+	cleanPath := filepath.Clean(path)
+	for _, p := range chain {
+		if p == cleanPath {
+			err = fmt.Errorf("include cycle detected: %v", append(chain, cleanPath))
+			return
+		}
+	}
+	chain = append(chain, cleanPath)
+
+	var dLints []docs.Lint
+	if confBytes, dLints, modTime, err = r.readFileEnvSwap(path); err != nil {
+		return
+	}
+	deps[cleanPath] = modTime
+	for _, l := range dLints {
+		lints = append(lints, l.Error())
+	}
+	if node, err = docs.UnmarshalYAML(confBytes); err != nil {
+		return
+	}
+
+	var includes []string
+	if includes, err = extractIncludes(node); err != nil {
+		err = fmt.Errorf("%v: %w", path, err)
+		return
+	}
+	if len(includes) == 0 {
+		return
+	}
+
+	for i, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			includes[i] = filepath.Join(filepath.Dir(path), inc)
+		}
+	}
+	if includes, err = ifilepath.Globs(r.fs, includes); err != nil {
+		err = fmt.Errorf("%v: failed to resolve includes: %w", path, err)
+		return
+	}
+	if len(includes) == 0 {
+		return
+	}
+
+	var base *yaml.Node
+	for _, inc := range includes {
+		incNode, _, _, incLints, incErr := r.readComposedFile(inc, chain, deps)
+		if incErr != nil {
+			err = fmt.Errorf("%v: %w", inc, incErr)
+			return
+		}
+		for _, l := range incLints {
+			lints = append(lints, fmt.Sprintf("%v: %v", inc, l))
+		}
+		if base == nil {
+			base = incNode
+		} else {
+			mergeYAMLNodes(base, incNode)
+		}
+	}
+	mergeYAMLNodes(base, node)
+	node = base
+	return
+}
+
+// extractIncludes removes the include directive from the root of a config and
+// returns the paths it contained.
+func extractIncludes(node *yaml.Node) ([]string, error) {
+	root := node
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	for i := 0; i < len(root.Content)-1; i += 2 {
+		if root.Content[i].Value != fieldInclude {
+			continue
+		}
+
+		var includes []string
+		v := root.Content[i+1]
+		switch v.Kind {
+		case yaml.ScalarNode:
+			includes = []string{v.Value}
+		case yaml.SequenceNode:
+			if err := v.Decode(&includes); err != nil {
+				return nil, fmt.Errorf("failed to parse %v: %w", fieldInclude, err)
+			}
+		default:
+			return nil, errors.New("expected include to be a string or a list of strings")
+		}
+
+		root.Content = append(root.Content[:i], root.Content[i+2:]...)
+		return includes, nil
+	}
+	return nil, nil
+}
+
+// mergeYAMLNodes merges the contents of src into dst, where the fields of
+// mappings are merged recursively and any other values of src replace those of
+// dst.
+func mergeYAMLNodes(dst, src *yaml.Node) {
+	if dst.Kind == yaml.DocumentNode && len(dst.Content) > 0 {
+		dst = dst.Content[0]
+	}
+	if src.Kind == yaml.DocumentNode {
+		if len(src.Content) == 0 {
+			return
+		}
+		src = src.Content[0]
+	}
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		*dst = *src
+		return
+	}
+
+srcFields:
+	for i := 0; i < len(src.Content)-1; i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		for j := 0; j < len(dst.Content)-1; j += 2 {
+			if dst.Content[j].Value == key.Value {
+				mergeYAMLNodes(dst.Content[j+1], value)
+				continue srcFields
+			}
+		}
+		dst.Content = append(dst.Content, key, value)
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+func TestReaderIncludesAndOverlays(t *testing.T) {
+	testFS := &testFS{m: fstest.MapFS{
+		"conf/main.yaml": &fstest.MapFile{
+			Data: []byte(`
+include: [ ./base/*.yaml ]
+
+http:
+  address: 0.0.0.0:4196
+
+input:
+  label: main_in
+  generate:
+    mapping: 'root = "main"'
+`),
+		},
+		"conf/base/a.yaml": &fstest.MapFile{
+			Data: []byte(`
+include: ../shared.yaml
+
+http:
+  address: 0.0.0.0:1111
+  debug_endpoints: true
+
+logger:
+  level: DEBUG
+`),
+		},
+		"conf/base/b.yaml": &fstest.MapFile{
+			Data: []byte(`
+logger:
+  level: WARN
+  format: json
+`),
+		},
+		"conf/shared.yaml": &fstest.MapFile{
+			Data: []byte(`
+output:
+  label: shared_out
+  drop: {}
+`),
+		},
+		"overlays/prod.yaml": &fstest.MapFile{
+			Data: []byte(`
+logger:
+  level: ERROR
+output:
+  label: prod_out
+`),
+		},
+	}}
+
+	rdr := newDummyReader("conf/main.yaml", nil,
+		OptUseFS(testFS),
+		OptAddOverlays("overlays/prod.yaml"),
+		OptAddOverrides("http.debug_endpoints=false"),
+	)
+
+	conf, lints, err := rdr.Read()
+	require.NoError(t, err)
+	assert.Empty(t, lints)
+
+	assert.Equal(t, "0.0.0.0:4196", conf.HTTP.Address)
+	assert.False(t, conf.HTTP.DebugEndpoints)
+	assert.Equal(t, "ERROR", conf.Logger.LogLevel)
+	assert.Equal(t, "json", conf.Logger.Format)
+	assert.Equal(t, "main_in", conf.Input.Label)
+	assert.Equal(t, "prod_out", conf.Output.Label)
+	assert.Equal(t, "drop", conf.Output.Type)
+
+	node, err := rdr.Render()
+	require.NoError(t, err)
+
+	rendered, err := docs.MarshalYAML(*node)
+	require.NoError(t, err)
+	assert.NotContains(t, string(rendered), "include")
+	assert.Contains(t, string(rendered), "prod_out")
+}
+
+func TestReaderIncludeErrors(t *testing.T) {
+	testFS := &testFS{m: fstest.MapFS{
+		"cycle_a.yaml": &fstest.MapFile{
+			Data: []byte(`include: [ cycle_b.yaml ]`),
+		},
+		"cycle_b.yaml": &fstest.MapFile{
+			Data: []byte(`include: [ cycle_a.yaml ]`),
+		},
+		"missing.yaml": &fstest.MapFile{
+			Data: []byte(`include: [ nope.yaml ]`),
+		},
+		"bad.yaml": &fstest.MapFile{
+			Data: []byte(`include: { foo: bar }`),
+		},
+	}}
+
+	for path, errContains := range map[string]string{
+		"cycle_a.yaml": "include cycle detected",
+		"missing.yaml": "nope.yaml",
+		"bad.yaml":     "expected include to be a string or a list of strings",
+	} {
+		_, _, err := newDummyReader(path, nil, OptUseFS(testFS)).Read()
+		require.Error(t, err, path)
+		assert.Contains(t, err.Error(), errContains, path)
+	}
+}
+
+func TestReaderIncludeNoMatches(t *testing.T) {
+	testFS := &testFS{m: fstest.MapFS{
+		"main.yaml": &fstest.MapFile{
+			Data: []byte(`
+include: [ ./none/*.yaml ]
+
+input:
+  label: main_in
+  generate:
+    mapping: 'root = "main"'
+`),
+		},
+	}}
+
+	conf, _, err := newDummyReader("main.yaml", nil, OptUseFS(testFS)).Read()
+	require.NoError(t, err)
+	assert.Equal(t, "main_in", conf.Input.Label)
+}
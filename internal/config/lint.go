@@ -77,34 +77,41 @@ func LintYAMLBytes(lintConf docs.LintConfig, rawBytes []byte) ([]docs.Lint, erro
 //
 // An modTime timestamp is returned if the modtime of the file is available.
 func ReadFileEnvSwap(store ifs.FS, path string, lookupEnvFn func(name string) (string, bool)) (configBytes []byte, lints []docs.Lint, modTime time.Time, err error) {
+	if configBytes, modTime, err = readFile(store, path); err != nil {
+		return
+	}
+	configBytes, lints, err = envSwap(configBytes, lookupEnvFn)
+	return
+}
+
+func readFile(store ifs.FS, path string) (configBytes []byte, modTime time.Time, err error) {
 	var configFile fs.File
 	if configFile, err = store.Open(path); err != nil {
 		return
 	}
+	defer configFile.Close()
 
 	if info, ierr := configFile.Stat(); ierr == nil {
 		modTime = info.ModTime()
 	}
+	configBytes, err = io.ReadAll(configFile)
+	return
+}
 
-	if configBytes, err = io.ReadAll(configFile); err != nil {
-		return
-	}
-
-	if !utf8.Valid(configBytes) {
+func envSwap(rawBytes []byte, lookupEnvFn func(name string) (string, bool)) (configBytes []byte, lints []docs.Lint, err error) {
+	if !utf8.Valid(rawBytes) {
 		lints = append(lints, docs.NewLintError(
 			1, docs.LintFailedRead,
 			errors.New("detected invalid utf-8 encoding in config, this may result in interpolation functions not working as expected"),
 		))
 	}
 
-	if configBytes, err = ReplaceEnvVariables(configBytes, lookupEnvFn); err != nil {
+	if configBytes, err = ReplaceEnvVariables(rawBytes, lookupEnvFn); err != nil {
 		var errEnvMissing *ErrMissingEnvVars
 		if errors.As(err, &errEnvMissing) {
 			configBytes = errEnvMissing.BestAttempt
 			lints = append(lints, docs.NewLintError(1, docs.LintMissingEnvVar, err))
 			err = nil
-		} else {
-			return
 		}
 	}
 	return
@@ -14,6 +14,7 @@ import (
 	"github.com/Jeffail/gabs/v2"
 	"gopkg.in/yaml.v3"
 
+	"github.com/benthosdev/benthos/v4/internal/audit"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
@@ -58,10 +59,19 @@ type Reader struct {
 	mainPath      string
 	resourcePaths []string
 	streamsPaths  []string
+	overlayPaths  []string
 	overrides     []string
 
 	modTimeLastRead map[string]time.Time
 
+	// Tracks the included and overlay files that the main config was composed
+	// from when we last read it, which are watched along with the main config.
+	mainDependencies []string
+
+	// Tracks the files that have been read at least once, in order to
+	// distinguish loads from reloads within audit events.
+	readPaths map[string]struct{}
+
 	// Controls whether the main config should include input, output, etc.
 	streamsMode bool
 
@@ -98,6 +108,7 @@ func NewReader(mainPath string, resourcePaths []string, opts ...OptFunc) *Reader
 		mainPath:           mainPath,
 		resourcePaths:      resourcePaths,
 		modTimeLastRead:    map[string]time.Time{},
+		readPaths:          map[string]struct{}{},
 		streamFileInfo:     map[string]streamFileInfo{},
 		resourceFileInfo:   map[string]resourceFileInfo{},
 		resourceSources:    newResourceSourceInfo(),
@@ -146,6 +157,15 @@ func OptAddOverrides(overrides ...string) OptFunc {
 	}
 }
 
+// OptAddOverlays adds one or more paths of config files to the config reader,
+// which are merged over the main config in the order they are provided and
+// before any overrides are applied.
+func OptAddOverlays(paths ...string) OptFunc {
+	return func(r *Reader) {
+		r.overlayPaths = append(r.overlayPaths, paths...)
+	}
+}
+
 // OptSetLintConfig sets the config used for linting files.
 func OptSetLintConfig(lConf docs.LintConfig) OptFunc {
 	return func(r *Reader) {
@@ -177,6 +197,34 @@ func (r *Reader) lintCtx() docs.LintContext {
 	return docs.NewLintContext(r.lintConf)
 }
 
+// readFileEnvSwap reads a config file and replaces any environment variable
+// interpolations, recording both the read of the file and the resolution of
+// each variable as audit events.
+func (r *Reader) readFileEnvSwap(path string) (configBytes []byte, lints []docs.Lint, modTime time.Time, err error) {
+	kind := audit.KindConfigLoad
+	if _, exists := r.readPaths[path]; exists {
+		kind = audit.KindConfigReload
+	}
+
+	var rawBytes []byte
+	if rawBytes, modTime, err = readFile(r.fs, path); err == nil {
+		r.readPaths[path] = struct{}{}
+		configBytes, lints, err = envSwap(rawBytes, audit.WrapLookupEnv(context.Background(), path, os.LookupEnv))
+	}
+	if err == nil || !errors.Is(err, fs.ErrNotExist) {
+		e := audit.Event{
+			Kind:   kind,
+			Source: path,
+			Error:  audit.ErrorString(err),
+		}
+		if rawBytes != nil {
+			e.ConfigHash = audit.Hash(rawBytes)
+		}
+		audit.Record(context.Background(), e)
+	}
+	return
+}
+
 // Read a Benthos config from the files and options specified.
 func (r *Reader) Read() (conf Type, lints []string, err error) {
 	if conf, lints, err = r.readMain(r.mainPath); err != nil {
@@ -285,38 +333,11 @@ func (r *Reader) readMain(mainPath string) (conf Type, lints []string, err error
 
 	var rawNode *yaml.Node
 	var confBytes []byte
-	if mainPath != "" {
-		var dLints []docs.Lint
-		var modTime time.Time
-		if confBytes, dLints, modTime, err = ReadFileEnvSwap(r.fs, mainPath, os.LookupEnv); err != nil {
-			return
-		}
-		for _, l := range dLints {
-			lints = append(lints, l.Error())
-		}
-		r.modTimeLastRead[mainPath] = modTime
-
-		if rawNode, err = docs.UnmarshalYAML(confBytes); err != nil {
-			return
-		}
-	} else {
-		var tmpNode yaml.Node
-		if err = tmpNode.Encode(map[string]any{}); err != nil {
-			return
-		}
-		rawNode = &tmpNode
-	}
-
-	confSpec := r.specFullConfig
-	if r.streamsMode {
-		// Spec is limited to just non-stream fields when in streams mode (no
-		// input, output, etc)
-		confSpec = r.specObservability
-	}
-	if err = applyOverrides(confSpec, rawNode, r.overrides...); err != nil {
+	if rawNode, confBytes, lints, err = r.readComposedMain(mainPath); err != nil {
 		return
 	}
 
+	confSpec := r.confSpec()
 	if !bytes.HasPrefix(confBytes, []byte("# BENTHOS LINT DISABLE")) {
 		lintFilePrefix := mainPath
 		for _, lint := range confSpec.LintYAML(r.lintCtx(), rawNode) {
@@ -341,6 +362,65 @@ func (r *Reader) readMain(mainPath string) (conf Type, lints []string, err error
 	return
 }
 
+func (r *Reader) confSpec() docs.FieldSpecs {
+	if r.streamsMode {
+		// Spec is limited to just non-stream fields when in streams mode (no
+		// input, output, etc)
+		return r.specObservability
+	}
+	return r.specFullConfig
+}
+
+// readComposedMain reads the main config file and resolves its includes, then
+// merges any overlays and applies overrides.
+func (r *Reader) readComposedMain(mainPath string) (rawNode *yaml.Node, confBytes []byte, lints []string, err error) {
+	deps := map[string]time.Time{}
+	if mainPath != "" {
+		if rawNode, confBytes, _, lints, err = r.readComposedFile(mainPath, nil, deps); err != nil {
+			return
+		}
+	} else {
+		var tmpNode yaml.Node
+		if err = tmpNode.Encode(map[string]any{}); err != nil {
+			return
+		}
+		rawNode = &tmpNode
+	}
+
+	for _, overlayPath := range r.overlayPaths {
+		overlayNode, _, _, oLints, oErr := r.readComposedFile(overlayPath, nil, deps)
+		if oErr != nil {
+			err = fmt.Errorf("overlay %v: %w", overlayPath, oErr)
+			return
+		}
+		for _, l := range oLints {
+			lints = append(lints, fmt.Sprintf("%v: %v", overlayPath, l))
+		}
+		mergeYAMLNodes(rawNode, overlayNode)
+	}
+
+	r.mainDependencies = r.mainDependencies[:0]
+	for p, modTime := range deps {
+		r.modTimeLastRead[p] = modTime
+		if p != filepath.Clean(mainPath) {
+			r.mainDependencies = append(r.mainDependencies, p)
+		}
+	}
+
+	err = applyOverrides(r.confSpec(), rawNode, r.overrides...)
+	return
+}
+
+// Render reads the main config file and returns the result of resolving its
+// includes, merging any overlays and applying overrides, before it is parsed.
+func (r *Reader) Render() (*yaml.Node, error) {
+	rawNode, _, _, err := r.readComposedMain(r.mainPath)
+	if err != nil && r.mainPath != "" {
+		err = fmt.Errorf("%v: %w", r.mainPath, err)
+	}
+	return rawNode, err
+}
+
 // TriggerMainUpdate attempts to re-read the main configuration file, trigger
 // the provided main update func, and apply changes to resources to the provided
 // manager as appropriate.
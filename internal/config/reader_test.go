@@ -1,8 +1,11 @@
 package config
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"io/fs"
+	"strings"
 	"testing"
 	"testing/fstest"
 	"time"
@@ -10,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/audit"
 	"github.com/benthosdev/benthos/v4/internal/manager"
 	"github.com/benthosdev/benthos/v4/internal/stream"
 )
@@ -239,3 +243,54 @@ processor_resources:
 	assert.True(t, testMgr.ProbeProcessor("c"))
 	assert.True(t, testMgr.ProbeProcessor("d"))
 }
+
+func TestReaderAuditEvents(t *testing.T) {
+	t.Setenv("BENTHOS_TEST_AUDIT_LABEL", "fooin")
+
+	mainBytes := []byte(`
+input:
+  label: ${BENTHOS_TEST_AUDIT_LABEL}
+  inproc: foo
+
+output:
+  inproc: bar
+`)
+	testFS := &testFS{m: fstest.MapFS{
+		"foo_main.yaml": &fstest.MapFile{Data: mainBytes},
+	}}
+
+	var buf bytes.Buffer
+	audit.SetSink(audit.NewWriterSink(&buf), nil)
+	t.Cleanup(func() {
+		audit.SetSink(nil, nil)
+	})
+
+	rdr := newDummyReader("foo_main.yaml", nil, OptUseFS(testFS))
+
+	conf, _, err := rdr.Read()
+	require.NoError(t, err)
+	assert.Equal(t, "fooin", conf.Input.Label)
+
+	_, _, err = rdr.Read()
+	require.NoError(t, err)
+
+	var events []audit.Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e audit.Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		events = append(events, e)
+	}
+	require.Len(t, events, 4)
+
+	assert.Equal(t, audit.KindSecretResolve, events[0].Kind)
+	assert.Equal(t, "BENTHOS_TEST_AUDIT_LABEL", events[0].Secret)
+	assert.Equal(t, "foo_main.yaml", events[0].Source)
+
+	assert.Equal(t, audit.KindConfigLoad, events[1].Kind)
+	assert.Equal(t, "foo_main.yaml", events[1].Source)
+	assert.Equal(t, audit.Hash(mainBytes), events[1].ConfigHash)
+
+	assert.Equal(t, audit.KindSecretResolve, events[2].Kind)
+	assert.Equal(t, audit.KindConfigReload, events[3].Kind)
+	assert.Equal(t, audit.Hash(mainBytes), events[3].ConfigHash)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/docs"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/manager"
+	"github.com/benthosdev/benthos/v4/internal/memguard"
 	"github.com/benthosdev/benthos/v4/internal/stream"
 )
 
@@ -17,6 +18,7 @@ const (
 	fieldLogger             = "logger"
 	fieldMetrics            = "metrics"
 	fieldTracer             = "tracer"
+	fieldMemoryGuard        = "memory_guard"
 	fieldSystemCloseDelay   = "shutdown_delay"
 	fieldSystemCloseTimeout = "shutdown_timeout"
 	fieldTests              = "tests"
@@ -27,12 +29,13 @@ type Type struct {
 	HTTP                   api.Config `yaml:"http"`
 	stream.Config          `yaml:",inline"`
 	manager.ResourceConfig `yaml:",inline"`
-	Logger                 log.Config     `yaml:"logger"`
-	Metrics                metrics.Config `yaml:"metrics"`
-	Tracer                 tracer.Config  `yaml:"tracer"`
-	SystemCloseDelay       string         `yaml:"shutdown_delay"`
-	SystemCloseTimeout     string         `yaml:"shutdown_timeout"`
-	Tests                  []any          `yaml:"tests"`
+	Logger                 log.Config      `yaml:"logger"`
+	Metrics                metrics.Config  `yaml:"metrics"`
+	Tracer                 tracer.Config   `yaml:"tracer"`
+	MemoryGuard            memguard.Config `yaml:"memory_guard"`
+	SystemCloseDelay       string          `yaml:"shutdown_delay"`
+	SystemCloseTimeout     string          `yaml:"shutdown_timeout"`
+	Tests                  []any           `yaml:"tests"`
 
 	rawSource any
 }
@@ -57,6 +60,7 @@ func observabilityFields() docs.FieldSpecs {
 		docs.FieldTracer(fieldTracer, "A mechanism for exporting traces.").HasDefault(map[string]any{
 			"none": map[string]any{},
 		}),
+		docs.FieldObject(fieldMemoryGuard, "A process wide watchdog that applies load shedding policies when the memory used by the process crosses configured thresholds, preventing it from running out of memory during downstream stalls.").WithChildren(memguard.Spec()...).AtVersion("4.28.0").Advanced(),
 		docs.FieldString(fieldSystemCloseDelay, "A period of time to wait for metrics and traces to be pulled or pushed from the process.").HasDefault("0s"),
 		docs.FieldString(fieldSystemCloseTimeout, "The maximum period of time to wait for a clean shutdown. If this time is exceeded Benthos will forcefully close.").HasDefault("20s"),
 	}
@@ -64,7 +68,7 @@ func observabilityFields() docs.FieldSpecs {
 
 // Spec returns a docs.FieldSpec for an entire Benthos configuration.
 func Spec() docs.FieldSpecs {
-	fields := docs.FieldSpecs{httpField}
+	fields := docs.FieldSpecs{includeField, httpField}
 	fields = append(fields, stream.Spec()...)
 	fields = append(fields, manager.Spec()...)
 	fields = append(fields, observabilityFields()...)
@@ -74,7 +78,7 @@ func Spec() docs.FieldSpecs {
 
 // SpecWithoutStream describes a stream config without the core stream fields.
 func SpecWithoutStream() docs.FieldSpecs {
-	fields := docs.FieldSpecs{httpField}
+	fields := docs.FieldSpecs{includeField, httpField}
 	fields = append(fields, manager.Spec()...)
 	fields = append(fields, observabilityFields()...)
 	fields = append(fields, test.ConfigSpec())
@@ -122,6 +126,13 @@ func noStreamFromParsed(prov docs.Provider, pConf *docs.ParsedConfig, conf *Type
 	} else {
 		conf.Tracer = tracer.NewConfig()
 	}
+	if pConf.Contains(fieldMemoryGuard) {
+		if conf.MemoryGuard, err = memguard.FromParsed(pConf.Namespace(fieldMemoryGuard)); err != nil {
+			return
+		}
+	} else {
+		conf.MemoryGuard = memguard.NewConfig()
+	}
 	if pConf.Contains(fieldSystemCloseDelay) {
 		if conf.SystemCloseDelay, err = pConf.FieldString(fieldSystemCloseDelay); err != nil {
 			return
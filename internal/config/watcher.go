@@ -59,6 +59,17 @@ func (r *Reader) modifiedSinceLastRead(name string) bool {
 	return info.ModTime().After(r.modTimeLastRead[name])
 }
 
+// isMainDependency returns whether a path is an included or overlay file that
+// the main config was composed from.
+func (r *Reader) isMainDependency(name string) bool {
+	for _, p := range r.mainDependencies {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
 // BeginFileWatching creates a goroutine that watches all active configuration
 // files for changes. If a resource is changed then it is swapped out
 // automatically through the provided manager. If a main config or stream config
@@ -108,6 +119,13 @@ func (r *Reader) BeginFileWatching(mgr bundle.NewManagement, strict bool) error
 					return err
 				}
 			}
+			for _, p := range r.mainDependencies {
+				if _, err := r.fs.Stat(p); err == nil {
+					if err := addNotWatching([]string{p}); err != nil {
+						return err
+					}
+				}
+			}
 		}
 
 		streamsPaths, err := r.streamPathsExpanded()
@@ -172,7 +190,7 @@ func (r *Reader) BeginFileWatching(mgr bundle.NewManagement, strict bool) error
 						continue
 					}
 					var succeeded bool
-					if nameClean == r.mainPath {
+					if nameClean == r.mainPath || r.isMainDependency(nameClean) {
 						succeeded = !ShouldReread(r.TriggerMainUpdate(mgr, strict, r.mainPath))
 					} else if _, exists := r.streamFileInfo[nameClean]; exists {
 						succeeded = !ShouldReread(r.TriggerStreamUpdate(mgr, strict, nameClean))
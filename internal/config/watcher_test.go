@@ -64,6 +64,64 @@ output:
 	assert.Equal(t, "drop", updatedConf.Output.Type)
 }
 
+func TestReaderFileWatchingIncludesAndOverlays(t *testing.T) {
+	confDir := t.TempDir()
+
+	confFilePath := filepath.Join(confDir, "main.yaml")
+	incFilePath := filepath.Join(confDir, "inc.yaml")
+	overlayFilePath := filepath.Join(confDir, "overlay.yaml")
+	require.NoError(t, os.WriteFile(confFilePath, []byte(`
+include: [ ./inc.yaml ]
+output:
+  drop: {}
+`), 0o644))
+	require.NoError(t, os.WriteFile(incFilePath, []byte(`
+input:
+  generate:
+    mapping: 'root = "foo"'
+`), 0o644))
+	require.NoError(t, os.WriteFile(overlayFilePath, []byte(`{}`), 0o644))
+
+	rdr := newDummyReader(confFilePath, nil, OptAddOverlays(overlayFilePath))
+	_, _, err := rdr.Read()
+	require.NoError(t, err)
+
+	confChan := make(chan stream.Config, 10)
+	require.NoError(t, rdr.SubscribeConfigChanges(func(conf *Type) error {
+		confChan <- conf.Config
+		return nil
+	}))
+
+	testMgr, err := manager.New(manager.ResourceConfig{})
+	require.NoError(t, err)
+	require.NoError(t, rdr.BeginFileWatching(testMgr, true))
+
+	waitForConf := func() stream.Config {
+		t.Helper()
+		select {
+		case conf := <-confChan:
+			return conf
+		case <-time.After(time.Second * 5):
+			require.FailNow(t, "Expected a config change to be triggered")
+		}
+		return stream.Config{}
+	}
+
+	require.NoError(t, os.WriteFile(incFilePath, []byte(`
+input:
+  label: from_include
+  generate:
+    mapping: 'root = "foo"'
+`), 0o644))
+	assert.Equal(t, "from_include", waitForConf().Input.Label)
+
+	require.NoError(t, os.WriteFile(overlayFilePath, []byte(`
+output:
+  label: from_overlay
+`), 0o644))
+	assert.Equal(t, "from_overlay", waitForConf().Output.Label)
+}
+
 func TestReaderFileWatchingSymlinkReplace(t *testing.T) {
 	dummyConfig := []byte(`
 input:
@@ -13,8 +13,9 @@ type componentOptions struct {
 	collectionIdent  *ast.Ident
 	disjunctionIdent *ast.Ident
 
-	canLabel      bool
-	canPreProcess bool
+	canLabel       bool
+	canPreProcess  bool
+	canPostProcess bool
 }
 
 func doComponents(specs []docs.ComponentSpec, opts *componentOptions) ([]ast.Decl, error) {
@@ -73,5 +74,16 @@ func doComponents(specs []docs.ComponentSpec, opts *componentOptions) ([]ast.Dec
 		})
 	}
 
+	if opts.canPostProcess {
+		decls = append(decls, &ast.Field{
+			Label: opts.disjunctionIdent,
+			Value: ast.NewStruct(
+				ast.NewIdent("post_processors"),
+				token.OPTION,
+				ast.NewList(&ast.Ellipsis{Type: ast.NewIdent("#Processor")}),
+			),
+		})
+	}
+
 	return decls, nil
 }
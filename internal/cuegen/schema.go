@@ -46,6 +46,7 @@ func GenerateSchema(sch schema.Full) ([]byte, error) {
 			disjunctionIdent: identOutputDisjunction,
 			canLabel:         true,
 			canPreProcess:    true,
+			canPostProcess:   true,
 		},
 	)
 	if err != nil {
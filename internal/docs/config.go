@@ -74,6 +74,14 @@ func ReservedFieldsByType(t Type) map[string]FieldSpec {
 			return "", false
 		})
 	}
+	if t == TypeOutput {
+		m["post_processors"] = FieldProcessor("post_processors", "").Array().OmitWhen(func(field, _ any) (string, bool) {
+			if arr, ok := field.([]any); ok && len(arr) == 0 {
+				return "field post_processors is empty and can be removed", true
+			}
+			return "", false
+		})
+	}
 	if t == TypeMetrics {
 		m["mapping"] = MetricsMappingFieldSpec("mapping")
 	}
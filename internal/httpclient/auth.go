@@ -1,6 +1,7 @@
 package httpclient
 
 import (
+	"fmt"
 	"io/fs"
 	"net/http"
 
@@ -173,10 +174,13 @@ func oauthFromParsed(conf *service.ParsedConfig) (res OAuthConfig, err error) {
 
 const (
 	ao2FieldEnabled        = "enabled"
+	ao2FieldGrantType      = "grant_type"
 	ao2FieldClientKey      = "client_key"
 	ao2FieldClientSecret   = "client_secret"
+	ao2FieldRefreshToken   = "refresh_token"
 	ao2FieldTokenURL       = "token_url"
 	ao2FieldScopes         = "scopes"
+	ao2FieldAudience       = "audience"
 	ao2FieldEndpointParams = "endpoint_params"
 )
 
@@ -186,6 +190,15 @@ func oAuth2FieldSpec() *service.ConfigField {
 			Description("Whether to use OAuth version 2 in requests.").
 			Default(false),
 
+		service.NewStringAnnotatedEnumField(ao2FieldGrantType, map[string]string{
+			OAuth2GrantClientCredentials: "Obtain tokens with the client key and secret.",
+			OAuth2GrantRefreshToken:      "Obtain tokens with a `refresh_token`, which is replaced when the token provider issues a new refresh token.",
+		}).
+			Description("The grant type used to obtain tokens.").
+			Default(OAuth2GrantClientCredentials).
+			Advanced().
+			Version("4.28.0"),
+
 		service.NewStringField(ao2FieldClientKey).
 			Description("A value used to identify the client to the token provider.").
 			Default(""),
@@ -194,6 +207,13 @@ func oAuth2FieldSpec() *service.ConfigField {
 			Description("A secret used to establish ownership of the client key.").
 			Default("").Secret(),
 
+		service.NewStringField(ao2FieldRefreshToken).
+			Description("The refresh token used to obtain tokens when the `grant_type` is `refresh_token`.").
+			Default("").
+			Secret().
+			Advanced().
+			Version("4.28.0"),
+
 		service.NewURLField(ao2FieldTokenURL).
 			Description("The URL of the token provider.").
 			Default(""),
@@ -204,6 +224,12 @@ func oAuth2FieldSpec() *service.ConfigField {
 			Advanced().
 			Version("3.45.0"),
 
+		service.NewStringField(ao2FieldAudience).
+			Description("An optional audience of the requested tokens, which is sent as the `audience` endpoint parameter with the `client_credentials` grant type.").
+			Default("").
+			Advanced().
+			Version("4.28.0"),
+
 		service.NewAnyMapField(ao2FieldEndpointParams).
 			Description("A list of optional endpoint parameters, values should be arrays of strings.").
 			Advanced().
@@ -227,7 +253,7 @@ root = if this.type() == "object" {
 }
 `),
 	).
-		Description("Allows you to specify open authentication via OAuth version 2 using the client credentials token flow.").
+		Description("Allows you to specify open authentication via OAuth version 2 using the client credentials or refresh token flows. Tokens are refreshed automatically before they expire, and are shared by all components with the same `oauth2` config, although each component obtains tokens using its own `tls` and `proxy_url` settings.").
 		Optional().Advanced()
 }
 
@@ -240,18 +266,31 @@ func oauth2FromParsed(conf *service.ParsedConfig) (res OAuth2Config, err error)
 	if res.Enabled, err = conf.FieldBool(ao2FieldEnabled); err != nil {
 		return
 	}
+	if res.GrantType, err = conf.FieldString(ao2FieldGrantType); err != nil {
+		return
+	}
 	if res.ClientKey, err = conf.FieldString(ao2FieldClientKey); err != nil {
 		return
 	}
 	if res.ClientSecret, err = conf.FieldString(ao2FieldClientSecret); err != nil {
 		return
 	}
+	if res.RefreshToken, err = conf.FieldString(ao2FieldRefreshToken); err != nil {
+		return
+	}
+	if res.Enabled && res.GrantType == OAuth2GrantRefreshToken && res.RefreshToken == "" {
+		err = fmt.Errorf("a %v must be provided with the %v grant type", ao2FieldRefreshToken, OAuth2GrantRefreshToken)
+		return
+	}
 	if res.TokenURL, err = conf.FieldString(ao2FieldTokenURL); err != nil {
 		return
 	}
 	if res.Scopes, err = conf.FieldStringList(ao2FieldScopes); err != nil {
 		return
 	}
+	if res.Audience, err = conf.FieldString(ao2FieldAudience); err != nil {
+		return
+	}
 	var endpointParams map[string]*service.ParsedConfig
 	if endpointParams, err = conf.FieldAnyMap(ao2FieldEndpointParams); err != nil {
 		return
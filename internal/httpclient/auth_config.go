@@ -5,7 +5,10 @@ import (
 	"crypto"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"math/rand"
@@ -20,6 +23,7 @@ import (
 	"golang.org/x/oauth2/clientcredentials"
 
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 // AuthConfig contains configuration params for various HTTP auth strategies.
@@ -260,13 +264,22 @@ func (oauth OAuthConfig) computeHMAC(
 
 //------------------------------------------------------------------------------
 
+// OAuth2 grant types supported by OAuth2Config.
+const (
+	OAuth2GrantClientCredentials = "client_credentials"
+	OAuth2GrantRefreshToken      = "refresh_token"
+)
+
 // OAuth2Config holds the configuration parameters for an OAuth2 exchange.
 type OAuth2Config struct {
 	Enabled        bool
+	GrantType      string
 	ClientKey      string
 	ClientSecret   string
+	RefreshToken   string
 	TokenURL       string
 	Scopes         []string
+	Audience       string
 	EndpointParams map[string][]string
 }
 
@@ -274,27 +287,169 @@ type OAuth2Config struct {
 func NewOAuth2Config() OAuth2Config {
 	return OAuth2Config{
 		Enabled:        false,
+		GrantType:      OAuth2GrantClientCredentials,
 		ClientKey:      "",
 		ClientSecret:   "",
+		RefreshToken:   "",
 		TokenURL:       "",
 		Scopes:         []string{},
+		Audience:       "",
 		EndpointParams: map[string][]string{},
 	}
 }
 
-// Client returns an http.Client with OAuth2 configured.
-func (oauth OAuth2Config) Client(ctx context.Context, base *http.Client) *http.Client {
-	if !oauth.Enabled {
-		return base
+func (oauth OAuth2Config) cacheKey() string {
+	b, _ := json.Marshal(oauth)
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// Tokens are shared by all components of a manager with the same OAuth2
+// config, so that they're only obtained and refreshed once, and are released
+// once the last of those components is closed.
+type oauth2TokensKey struct{}
+
+type oauth2Tokens struct {
+	mut    sync.Mutex
+	shared map[string]*oauth2SharedToken
+}
+
+type oauth2SharedToken struct {
+	refs int
+
+	mut sync.Mutex
+	tok *oauth2.Token
+}
+
+func oauth2TokensFromResources(mgr *service.Resources) *oauth2Tokens {
+	t, _ := mgr.GetOrSetGeneric(oauth2TokensKey{}, &oauth2Tokens{
+		shared: map[string]*oauth2SharedToken{},
+	})
+	return t.(*oauth2Tokens)
+}
+
+func (t *oauth2Tokens) acquire(key string) *oauth2SharedToken {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	s, exists := t.shared[key]
+	if !exists {
+		s = &oauth2SharedToken{}
+		t.shared[key] = s
 	}
+	s.refs++
+	return s
+}
+
+func (t *oauth2Tokens) release(key string) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
 
-	conf := &clientcredentials.Config{
-		ClientID:       oauth.ClientKey,
-		ClientSecret:   oauth.ClientSecret,
-		TokenURL:       oauth.TokenURL,
-		Scopes:         oauth.Scopes,
-		EndpointParams: oauth.EndpointParams,
+	if s, exists := t.shared[key]; exists {
+		if s.refs--; s.refs <= 0 {
+			delete(t.shared, key)
+		}
 	}
+}
 
-	return conf.Client(context.WithValue(ctx, oauth2.HTTPClient, base))
+// oauth2TokenSource provides the token shared by components with the same
+// config, where the token is obtained or refreshed with the client of the
+// component that finds it invalid.
+type oauth2TokenSource struct {
+	oauth  OAuth2Config
+	ctx    context.Context
+	shared *oauth2SharedToken
+}
+
+func (o *oauth2TokenSource) Token() (*oauth2.Token, error) {
+	o.shared.mut.Lock()
+	defer o.shared.mut.Unlock()
+
+	if o.shared.tok.Valid() {
+		return o.shared.tok, nil
+	}
+
+	var src oauth2.TokenSource
+	if o.oauth.GrantType == OAuth2GrantRefreshToken {
+		// Refresh tokens may be rotated by the provider, in which case the
+		// latest one is used.
+		refreshToken := o.oauth.RefreshToken
+		if o.shared.tok != nil && o.shared.tok.RefreshToken != "" {
+			refreshToken = o.shared.tok.RefreshToken
+		}
+		conf := &oauth2.Config{
+			ClientID:     o.oauth.ClientKey,
+			ClientSecret: o.oauth.ClientSecret,
+			Endpoint:     oauth2.Endpoint{TokenURL: o.oauth.TokenURL},
+			Scopes:       o.oauth.Scopes,
+		}
+		src = conf.TokenSource(o.ctx, &oauth2.Token{RefreshToken: refreshToken})
+	} else {
+		params := url.Values{}
+		for k, v := range o.oauth.EndpointParams {
+			params[k] = v
+		}
+		if o.oauth.Audience != "" {
+			params.Set("audience", o.oauth.Audience)
+		}
+		conf := &clientcredentials.Config{
+			ClientID:       o.oauth.ClientKey,
+			ClientSecret:   o.oauth.ClientSecret,
+			TokenURL:       o.oauth.TokenURL,
+			Scopes:         o.oauth.Scopes,
+			EndpointParams: params,
+		}
+		src = conf.TokenSource(o.ctx)
+	}
+
+	tok, err := src.Token()
+	if err != nil {
+		return nil, err
+	}
+	o.shared.tok = tok
+	return tok, nil
+}
+
+// TokenSource returns an oauth2.TokenSource that obtains and automatically
+// refreshes tokens according to the config, where the base client is used for
+// requests to the token provider. Tokens are shared by all components of the
+// resources with the same config until the returned release func is called by
+// each of them.
+func (oauth OAuth2Config) TokenSource(base *http.Client, mgr *service.Resources) (ts oauth2.TokenSource, release func()) {
+	key := oauth.cacheKey()
+	tokens := oauth2TokensFromResources(mgr)
+
+	var releaseOnce sync.Once
+	return &oauth2TokenSource{
+		oauth: oauth,
+		// Tokens might be refreshed at any point during the lifetime of
+		// the component and therefore must not be bound to a context.
+		ctx:    context.WithValue(context.Background(), oauth2.HTTPClient, base),
+		shared: tokens.acquire(key),
+	}, func() {
+		releaseOnce.Do(func() {
+			tokens.release(key)
+		})
+	}
+}
+
+// Client returns an http.Client with OAuth2 configured, along with a func that
+// must be called once the client is no longer used.
+func (oauth OAuth2Config) Client(base *http.Client, mgr *service.Resources) (*http.Client, func()) {
+	if !oauth.Enabled {
+		return base, func() {}
+	}
+
+	transport := base.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	source, release := oauth.TokenSource(base, mgr)
+	return &http.Client{
+		Transport: &oauth2.Transport{
+			Source: source,
+			Base:   transport,
+		},
+		Timeout: base.Timeout,
+	}, release
 }
@@ -1,6 +1,8 @@
 package httpclient
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -100,3 +102,43 @@ oauth2:
 		"third":  {"and those"},
 	}, authConf.EndpointParams)
 }
+
+type countingTransport struct {
+	reqs int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.reqs++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestOAuth2TokenSourceUsesCallerClient(t *testing.T) {
+	tsOAuth2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Tokens expire immediately and are therefore obtained on each call.
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"footoken","token_type":"Bearer","expires_in":1}`))
+	}))
+	defer tsOAuth2.Close()
+
+	conf := NewOAuth2Config()
+	conf.Enabled = true
+	conf.TokenURL = tsOAuth2.URL
+
+	mgr := service.MockResources()
+	tA, tB := &countingTransport{}, &countingTransport{}
+
+	srcA, releaseA := conf.TokenSource(&http.Client{Transport: tA}, mgr)
+	defer releaseA()
+	srcB, releaseB := conf.TokenSource(&http.Client{Transport: tB}, mgr)
+	defer releaseB()
+
+	_, err := srcA.Token()
+	require.NoError(t, err)
+	_, err = srcB.Token()
+	require.NoError(t, err)
+	_, err = srcB.Token()
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, tA.reqs)
+	assert.Equal(t, 2, tB.reqs)
+}
@@ -3,6 +3,7 @@ package httpclient
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
@@ -14,7 +15,7 @@ import (
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/component"
-	"github.com/benthosdev/benthos/v4/internal/old/util/throttle"
+	"github.com/benthosdev/benthos/v4/internal/retries"
 	"github.com/benthosdev/benthos/v4/internal/tracing/v2"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -24,17 +25,18 @@ type Client struct {
 	reqCreator *RequestCreator
 
 	// Client creator
-	client       *http.Client
-	clientCtx    context.Context
-	clientCancel func()
+	client        *http.Client
+	releaseOAuth2 func()
 
 	// Request execution and retry logic
-	rateLimit     string
-	numRetries    int
-	retryThrottle *throttle.Type
-	backoffOn     map[int]struct{}
-	dropOn        map[int]struct{}
-	successOn     map[int]struct{}
+	rateLimit   string
+	numRetries  int
+	retryPeriod time.Duration
+	retries     *retries.Policy
+	backoffOn   map[int]struct{}
+	dropOn      map[int]struct{}
+	successOn   map[int]struct{}
+	retryPolicy *RetryPolicy
 
 	// Response extraction
 	metaExtractFilter *service.MetadataFilter
@@ -69,7 +71,6 @@ func NewClientFromOldConfig(conf OldConfig, mgr *service.Resources, opts ...Requ
 		mgr: mgr,
 		log: mgr.Logger(),
 	}
-	h.clientCtx, h.clientCancel = context.WithCancel(context.Background())
 
 	if conf.Timeout > 0 {
 		h.client.Timeout = conf.Timeout
@@ -110,7 +111,7 @@ func NewClientFromOldConfig(conf OldConfig, mgr *service.Resources, opts ...Requ
 		return nil, fmt.Errorf("failed to config logger for request dump: %v", err)
 	}
 
-	h.client = conf.OAuth2.Client(h.clientCtx, h.client)
+	h.client, h.releaseOAuth2 = conf.OAuth2.Client(h.client, mgr)
 
 	for _, c := range conf.BackoffOn {
 		h.backoffOn[c] = struct{}{}
@@ -132,11 +133,11 @@ func NewClientFromOldConfig(conf OldConfig, mgr *service.Resources, opts ...Requ
 	}
 
 	h.numRetries = conf.NumRetries
-	h.retryThrottle = throttle.New(
-		throttle.OptMaxUnthrottledRetries(0),
-		throttle.OptThrottlePeriod(conf.Retry),
-		throttle.OptMaxExponentPeriod(conf.MaxBackoff),
-	)
+	h.retryPeriod = conf.Retry
+	h.retryPolicy = conf.RetryPolicy
+	if h.retries, err = retries.NewPolicy(conf.retriesConfig()); err != nil {
+		return nil, err
+	}
 
 	return &h, nil
 }
@@ -334,71 +335,107 @@ func (h *Client) SendToResponse(ctx context.Context, sendMsg service.MessageBatc
 	}()
 
 	if !h.waitForAccess(ctx) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
 		return nil, component.ErrTypeClosed
 	}
 
-	rateLimited := false
-	numRetries := h.numRetries
-
-	startedAt := time.Now()
-	if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
-		h.incrCode(res.StatusCode)
-		if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
-			rateLimited = retryStrat == retryBackoff
-			if retryStrat == noRetry {
-				numRetries = 0
+	// Attempts are limited by the fields retries and retry_policy, and
+	// therefore the shared policy is only responsible for the back off of
+	// retries and the retry budget.
+	var (
+		attempted   bool
+		numRetries  int
+		ruleRetries map[*RetryRule]int
+	)
+	err = h.retries.Do(ctx, func(ctx context.Context) error {
+		if attempted {
+			if !h.waitForAccess(ctx) {
+				return retries.Permanent(component.ErrTypeClosed)
 			}
-			err = unexpectedErr(res)
-			if res.Body != nil {
-				res.Body.Close()
+			var cErr error
+			if req, cErr = h.reqCreator.Create(sendMsg); cErr != nil {
+				logErr(cErr)
+				return retries.Permanent(cErr)
 			}
 		}
-	}
-	h.mLatency.Timing(time.Since(startedAt).Nanoseconds())
+		attempted = true
 
-	i, j := 0, numRetries
-	for i < j && err != nil {
-		logErr(err)
-		if req, err = h.reqCreator.Create(sendMsg); err != nil {
-			continue
-		}
-		if rateLimited {
-			if !h.retryThrottle.ExponentialRetryWithContext(ctx) {
-				return nil, component.ErrTypeClosed
-			}
-		} else {
-			if !h.retryThrottle.RetryWithContext(ctx) {
-				return nil, component.ErrTypeClosed
-			}
-		}
-		if !h.waitForAccess(ctx) {
-			return nil, component.ErrTypeClosed
-		}
-		rateLimited = false
+		var (
+			retryStrat = retryLinear
+			retryDelay = time.Duration(-1)
+			rule       *RetryRule
+			doErr      error
+		)
 
-		startedAt = time.Now()
-		if res, err = h.client.Do(req.WithContext(ctx)); err == nil {
+		startedAt := time.Now()
+		if res, doErr = h.client.Do(req.WithContext(ctx)); doErr == nil {
 			h.incrCode(res.StatusCode)
-			if resolved, retryStrat := h.checkStatus(res.StatusCode); !resolved {
-				rateLimited = retryStrat == retryBackoff
-				if retryStrat == noRetry {
-					j = 0
+			var resolved bool
+			if resolved, retryStrat = h.checkStatus(res.StatusCode); !resolved {
+				if rule = h.retryPolicy.ruleFor(res.StatusCode); rule != nil {
+					if rule.Fatal {
+						retryStrat = noRetry
+					} else {
+						retryDelay = rule.delay(ruleRetries[rule])
+					}
+				}
+				if retryStrat != noRetry {
+					if d, exists := h.retryPolicy.retryAfter(res); exists {
+						retryDelay = d
+					}
 				}
-				err = unexpectedErr(res)
+				doErr = unexpectedErr(res)
 				if res.Body != nil {
 					res.Body.Close()
 				}
 			}
 		}
 		h.mLatency.Timing(time.Since(startedAt).Nanoseconds())
-		i++
-	}
+		if doErr == nil {
+			return nil
+		}
+		logErr(doErr)
+
+		if retryStrat == noRetry {
+			return retries.Permanent(doErr)
+		}
+		if rule != nil {
+			maxRetries := rule.MaxRetries
+			if maxRetries < 0 {
+				maxRetries = h.numRetries
+			}
+			if ruleRetries[rule] >= maxRetries {
+				return retries.Permanent(doErr)
+			}
+			if ruleRetries == nil {
+				ruleRetries = map[*RetryRule]int{}
+			}
+			ruleRetries[rule]++
+		} else {
+			if numRetries >= h.numRetries {
+				return retries.Permanent(doErr)
+			}
+			numRetries++
+		}
+
+		switch {
+		case retryDelay >= 0:
+			return retries.After(retryDelay, doErr)
+		case retryStrat == retryBackoff:
+			// Retries of backoff_on codes use the exponential back off of the
+			// policy with full jitter.
+			return doErr
+		}
+		return retries.After(h.retryPeriod, doErr)
+	})
 	if err != nil {
-		logErr(err)
+		if ctxErr := ctx.Err(); ctxErr != nil && errors.Is(err, ctxErr) {
+			err = component.ErrTypeClosed
+		}
 		return nil, err
 	}
-
-	h.retryThrottle.Reset()
 	return res, nil
 }
 
@@ -427,6 +464,6 @@ func (h *Client) Send(ctx context.Context, sendMsg service.MessageBatch) (servic
 
 // Close the client.
 func (h *Client) Close(ctx context.Context) error {
-	h.clientCancel()
+	h.releaseOAuth2()
 	return nil
 }
@@ -12,6 +12,7 @@ import (
 	"net/textproto"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -194,6 +195,137 @@ successful_on: [ 400 ]
 	assert.Equal(t, int32(1), atomic.LoadInt32(&reqs))
 }
 
+func TestHTTPClientRetryPolicy(t *testing.T) {
+	var reqs sync.Map
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := reqs.LoadOrStore(r.URL.Path, new(int32))
+		count := atomic.AddInt32(n.(*int32), 1)
+
+		switch r.URL.Path {
+		case "/throttled":
+			if count < 3 {
+				w.Header().Set("Retry-After", "10")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		case "/invalid":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	conf := clientConfig(t, `
+url: %v${! content() }
+retry_period: 1ms
+retries: 1
+retry_policy:
+  max_retry_after: 10ms
+  rules:
+    - status: [ "429" ]
+      initial_interval: 1h
+      max_retries: 2
+    - status: [ "5xx" ]
+      initial_interval: 1ms
+      max_retries: 4
+    - status: [ "422" ]
+      fatal: true
+`, ts.URL)
+
+	h, err := NewClientFromOldConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	reqCount := func(path string) int32 {
+		n, _ := reqs.Load(path)
+		return atomic.LoadInt32(n.(*int32))
+	}
+
+	// The Retry-After header takes precedence over the rule interval, capped
+	// to the max_retry_after.
+	started := time.Now()
+	_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("/throttled"))})
+	require.NoError(t, err)
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, int32(3), reqCount("/throttled"))
+
+	_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("/unavailable"))})
+	require.Error(t, err)
+	assert.Equal(t, int32(5), reqCount("/unavailable"))
+
+	_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("/invalid"))})
+	require.Error(t, err)
+	assert.Equal(t, int32(1), reqCount("/invalid"))
+
+	_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("/forbidden"))})
+	require.Error(t, err)
+	assert.Equal(t, int32(2), reqCount("/forbidden"))
+}
+
+func TestHTTPClientRetryBudget(t *testing.T) {
+	var reqs int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqs, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	conf := clientConfig(t, `
+url: %v
+retry_period: 1ms
+max_retry_backoff: 1ms
+retries: 3
+retry_policy:
+  budget:
+    ratio: 0.5
+    min_retries: 2
+    window: 1h
+`, ts.URL)
+
+	h, err := NewClientFromOldConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	send := func() int32 {
+		t.Helper()
+		before := atomic.LoadInt32(&reqs)
+		_, err := h.Send(context.Background(), service.MessageBatch{service.NewMessage(nil)})
+		require.Error(t, err)
+		return atomic.LoadInt32(&reqs) - before
+	}
+
+	// The first request is retried until the minimum number of retries within
+	// the window are spent, after which a retry is only allowed once there are
+	// two requests for every retry.
+	assert.Equal(t, int32(3), send())
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, int32(1), send())
+	}
+	assert.Equal(t, int32(2), send())
+	assert.Equal(t, int32(1), send())
+}
+
+func TestHTTPClientRetryPolicyBadStatus(t *testing.T) {
+	spec := service.NewConfigSpec().Field(ConfigField("GET", false))
+	parsed, err := spec.ParseYAML(`
+url: http://localhost
+retry_policy:
+  rules:
+    - status: [ "6xx" ]
+`, nil)
+	require.NoError(t, err)
+
+	_, err = ConfigFromParsed(parsed)
+	require.EqualError(t, err, "rule 0: invalid status '6xx', expected a status code or class such as 5xx")
+}
+
 func TestHTTPClientSendInterpolate(t *testing.T) {
 	nTestLoops := 1000
 
@@ -712,3 +844,96 @@ tls:
 	require.NoError(t, err)
 	assert.Equal(t, "HELLO WORLD", string(mBytes))
 }
+
+func TestHTTPClientOAuth2SharedTokens(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer footoken", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	var tokenReqs int32
+	tsOAuth2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&tokenReqs, 1)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.Form.Get("grant_type"))
+		assert.Equal(t, "https://api.example.com", r.Form.Get("audience"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"footoken","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tsOAuth2.Close()
+
+	conf := clientConfig(t, `
+url: %v
+oauth2:
+  enabled: true
+  token_url: %v
+  client_key: fookey
+  client_secret: foosecret
+  audience: https://api.example.com
+`, ts.URL, tsOAuth2.URL)
+
+	sendWith := func(mgr *service.Resources) *Client {
+		h, err := NewClientFromOldConfig(conf, mgr)
+		require.NoError(t, err)
+
+		_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("hello"))})
+		require.NoError(t, err)
+		return h
+	}
+
+	mgr := service.MockResources()
+	hA, hB := sendWith(mgr), sendWith(mgr)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&tokenReqs))
+
+	// Tokens are not shared with other resources.
+	hOther := sendWith(service.MockResources())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&tokenReqs))
+	require.NoError(t, hOther.Close(context.Background()))
+
+	// Tokens are released once all clients sharing them are closed.
+	require.NoError(t, hA.Close(context.Background()))
+	require.NoError(t, hA.Close(context.Background()))
+	assert.Len(t, oauth2TokensFromResources(mgr).shared, 1)
+
+	require.NoError(t, hB.Close(context.Background()))
+	assert.Empty(t, oauth2TokensFromResources(mgr).shared)
+
+	require.NoError(t, sendWith(mgr).Close(context.Background()))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&tokenReqs))
+}
+
+func TestHTTPClientOAuth2RefreshToken(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer bartoken", r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	tsOAuth2 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh_token", r.Form.Get("grant_type"))
+		assert.Equal(t, "barrefresh", r.Form.Get("refresh_token"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"bartoken","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tsOAuth2.Close()
+
+	conf := clientConfig(t, `
+url: %v
+oauth2:
+  enabled: true
+  grant_type: refresh_token
+  token_url: %v
+  client_key: barkey
+  client_secret: barsecret
+  refresh_token: barrefresh
+`, ts.URL, tsOAuth2.URL)
+
+	h, err := NewClientFromOldConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	defer h.Close(context.Background())
+
+	_, err = h.Send(context.Background(), service.MessageBatch{service.NewMessage([]byte("hello"))})
+	require.NoError(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/tls"
 	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/retries"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -83,7 +84,7 @@ func ConfigField(defaultVerb string, forOutput bool, extraChildren ...*service.C
 			Advanced().
 			Default(3),
 		service.NewIntListField(hcFieldBackoffOn).
-			Description("A list of status codes whereby the request should be considered to have failed and retries should be attempted, but the period between them should be increased gradually.").
+			Description("A list of status codes whereby the request should be considered to have failed and retries should be attempted, but the period between them should be increased exponentially from `retry_period` up to `max_retry_backoff`, where each period is chosen at random up to that bound (full jitter).").
 			Advanced().
 			Default([]any{429}),
 		service.NewIntListField(hcFieldDropOn).
@@ -94,6 +95,7 @@ func ConfigField(defaultVerb string, forOutput bool, extraChildren ...*service.C
 			Description("A list of status codes whereby the attempt should be considered successful, this is useful for dropping requests that return non-2XX codes indicating that the message has been dealt with, such as a 303 See Other or a 409 Conflict. All 2XX codes are considered successful unless they are present within `backoff_on` or `drop_on`, regardless of this field.").
 			Advanced().
 			Default([]any{}),
+		retryPolicyField(),
 		service.NewStringField(hcFieldProxyURL).
 			Description("An optional HTTP proxy URL.").
 			Advanced().
@@ -146,6 +148,9 @@ func ConfigFromParsed(pConf *service.ParsedConfig) (conf OldConfig, err error) {
 	if conf.SuccessfulOn, err = pConf.FieldIntList(hcFieldSuccessfulOn); err != nil {
 		return
 	}
+	if conf.RetryPolicy, err = retryPolicyFromParsed(pConf); err != nil {
+		return
+	}
 	conf.DumpRequestLogLevel, _ = pConf.FieldString(hcFieldDumpRequestLogLevel)
 	if conf.TLSConf, conf.TLSEnabled, err = pConf.FieldTLSToggled(hcFieldTLS); err != nil {
 		return
@@ -175,6 +180,7 @@ type OldConfig struct {
 	BackoffOn           []int
 	DropOn              []int
 	SuccessfulOn        []int
+	RetryPolicy         *RetryPolicy
 	DumpRequestLogLevel string
 	TLSEnabled          bool
 	TLSConf             *tls.Config
@@ -182,3 +188,21 @@ type OldConfig struct {
 	Auth                AuthConfig
 	OAuth2              OAuth2Config
 }
+
+// retriesConfig returns the config of the shared retry policy of a client,
+// which determines the back off of retries and the retry budget.
+func (c OldConfig) retriesConfig() retries.Config {
+	conf := retries.Config{
+		InitialInterval: c.Retry,
+		MaxInterval:     c.MaxBackoff,
+	}
+	if conf.MaxInterval < conf.InitialInterval {
+		conf.MaxInterval = conf.InitialInterval
+	}
+	if c.RetryPolicy != nil {
+		conf.BudgetRatio = c.RetryPolicy.BudgetRatio
+		conf.BudgetMinRetries = c.RetryPolicy.BudgetMinRetries
+		conf.BudgetWindow = c.RetryPolicy.BudgetWindow
+	}
+	return conf
+}
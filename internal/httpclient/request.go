@@ -11,6 +11,7 @@ import (
 	"strings"
 
 	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -38,7 +39,9 @@ type RequestCreator struct {
 	host             *service.InterpolatedString
 	verb             string
 	headers          map[string]*service.InterpolatedString
+	headersMapping   *bloblang.Executor
 	metaInsertFilter *service.MetadataFilter
+	streamBody       bool
 }
 
 // RequestOpt represents a customisation of a request creator.
@@ -81,6 +84,24 @@ func WithExplicitBody(e *service.InterpolatedString) RequestOpt {
 	}
 }
 
+// WithHeadersMapping modifies the request creator to execute a mapping on each
+// message in order to obtain an object of headers to set, which override any
+// headers of the same name.
+func WithHeadersMapping(m *bloblang.Executor) RequestOpt {
+	return func(r *RequestCreator) {
+		r.headersMapping = m
+	}
+}
+
+// WithStreamingBody modifies the request creator to send bodies with chunked
+// transfer encoding, where multipart bodies are written as the request is sent
+// rather than being buffered in memory beforehand.
+func WithStreamingBody() RequestOpt {
+	return func(r *RequestCreator) {
+		r.streamBody = true
+	}
+}
+
 // WithExplicitMultipart modifies the request creator to instead only use input
 // reference messages for headers and metadata, and use a list of multipart
 // expressions for creating a body.
@@ -98,84 +119,119 @@ func (r *RequestCreator) bodyFromExplicit(refBatch service.MessageBatch) (body i
 	if bBytes, err = refBatch.TryInterpolatedBytes(0, r.explicitBody); err != nil {
 		return
 	}
-	body = bytes.NewBuffer(bBytes)
+	body = r.bytesBody(bBytes)
 	return
 }
 
-func (r *RequestCreator) bodyFromExplicitMultipart(refBatch service.MessageBatch) (body io.Reader, overrideContentType string, err error) {
-	buf := &bytes.Buffer{}
-	writer := multipart.NewWriter(buf)
+func (r *RequestCreator) writeExplicitMultipart(writer *multipart.Writer, refBatch service.MessageBatch) error {
 	for _, v := range r.explicitMultiparts {
 		mh := make(textproto.MIMEHeader)
-		var cTypeStr, cDispStr string
-		if cTypeStr, err = refBatch.TryInterpolatedString(0, v.ContentType); err != nil {
-			err = fmt.Errorf("content-type interpolation error: %w", err)
-			return
+		cTypeStr, err := refBatch.TryInterpolatedString(0, v.ContentType)
+		if err != nil {
+			return fmt.Errorf("content-type interpolation error: %w", err)
 		}
-		if cDispStr, err = refBatch.TryInterpolatedString(0, v.ContentDisposition); err != nil {
-			err = fmt.Errorf("content-disposition interpolation error: %w", err)
-			return
+		cDispStr, err := refBatch.TryInterpolatedString(0, v.ContentDisposition)
+		if err != nil {
+			return fmt.Errorf("content-disposition interpolation error: %w", err)
 		}
 		mh.Set("Content-Type", cTypeStr)
 		mh.Set("Content-Disposition", cDispStr)
 
-		var part io.Writer
-		if part, err = writer.CreatePart(mh); err != nil {
-			return
+		part, err := writer.CreatePart(mh)
+		if err != nil {
+			return err
 		}
-		var partBytes []byte
-		if partBytes, err = refBatch.TryInterpolatedBytes(0, v.Body); err != nil {
-			err = fmt.Errorf("part body interpolation error: %w", err)
+		partBytes, err := refBatch.TryInterpolatedBytes(0, v.Body)
+		if err != nil {
+			return fmt.Errorf("part body interpolation error: %w", err)
+		}
+		if _, err = part.Write(partBytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bytesBody returns a reader of the body of a request. When streaming is
+// enabled the length of the reader is hidden from the HTTP client, resulting
+// in the body being sent with chunked transfer encoding.
+func (r *RequestCreator) bytesBody(b []byte) io.Reader {
+	if r.streamBody {
+		return struct{ io.Reader }{bytes.NewReader(b)}
+	}
+	return bytes.NewBuffer(b)
+}
+
+// multipartBody returns a reader of a multipart body written by a function.
+// When streaming is enabled the parts are written into a pipe as the body is
+// consumed by the HTTP client, and therefore the complete body is never
+// buffered in memory, otherwise the parts are written into a buffer.
+func (r *RequestCreator) multipartBody(write func(writer *multipart.Writer) error) (body io.Reader, contentType string, err error) {
+	if !r.streamBody {
+		buf := &bytes.Buffer{}
+		writer := multipart.NewWriter(buf)
+		if err = write(writer); err != nil {
 			return
 		}
-		if _, err = io.Copy(part, bytes.NewReader(partBytes)); err != nil {
+		if err = writer.Close(); err != nil {
 			return
 		}
+		return buf, writer.FormDataContentType(), nil
 	}
-	writer.Close()
-	body = buf
-	overrideContentType = writer.FormDataContentType()
-	return
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+	go func() {
+		err := write(writer)
+		if err == nil {
+			err = writer.Close()
+		}
+		_ = pw.CloseWithError(err)
+	}()
+	return pr, writer.FormDataContentType(), nil
 }
 
-func (r *RequestCreator) body(refBatch service.MessageBatch) (body io.Reader, overrideContentType string, err error) {
-	if r.explicitBody != nil {
-		body, overrideContentType, err = r.bodyFromExplicit(refBatch)
-		return
+// mappedHeaders executes the headers mapping, if any, on a message of a batch
+// and returns the resulting headers.
+func (r *RequestCreator) mappedHeaders(refBatch service.MessageBatch, index int) (map[string]string, error) {
+	if r.headersMapping == nil {
+		return nil, nil
 	}
 
-	if len(r.explicitMultiparts) > 0 {
-		body, overrideContentType, err = r.bodyFromExplicitMultipart(refBatch)
-		return
+	resMsg, err := refBatch.BloblangQuery(index, r.headersMapping)
+	if err != nil {
+		return nil, fmt.Errorf("headers mapping error: %w", err)
+	}
+	if resMsg == nil {
+		return nil, nil
 	}
 
-	if len(refBatch) == 0 {
-		return
+	res, err := resMsg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("headers mapping error: %w", err)
+	}
+	obj, ok := res.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("headers mapping returned non-object result: %T", res)
 	}
 
-	if len(refBatch) == 1 {
-		if _, exists := r.headers["Content-Type"]; !exists {
-			overrideContentType = "application/octet-stream"
-		}
-		var bodyBytes []byte
-		if bodyBytes, err = refBatch[0].AsBytes(); err != nil {
-			return
+	headers := make(map[string]string, len(obj))
+	for k, v := range obj {
+		if v == nil {
+			continue
 		}
-		body = bytes.NewBuffer(bodyBytes)
-		return
+		headers[k] = value.IToString(v)
 	}
+	return headers, nil
+}
 
-	// More than one message in the batch, create a multipart message by
-	// default.
-	buf := &bytes.Buffer{}
-	writer := multipart.NewWriter(buf)
-
+func (r *RequestCreator) writeBatchMultipart(writer *multipart.Writer, refBatch service.MessageBatch) error {
 	for i, p := range refBatch {
 		contentType := "application/octet-stream"
 		if v, exists := r.headers["Content-Type"]; exists {
+			var err error
 			if contentType, err = refBatch.TryInterpolatedString(i, v); err != nil {
-				err = fmt.Errorf("content-type interpolation error: %w", err)
-				return
+				return fmt.Errorf("content-type interpolation error: %w", err)
 			}
 		}
 
@@ -187,25 +243,63 @@ func (r *RequestCreator) body(refBatch service.MessageBatch) (body io.Reader, ov
 			return nil
 		})
 
-		var part io.Writer
-		if part, err = writer.CreatePart(headers); err != nil {
-			return
+		mapped, err := r.mappedHeaders(refBatch, i)
+		if err != nil {
+			return err
+		}
+		for k, v := range mapped {
+			headers.Set(k, v)
 		}
 
-		var pBytes []byte
-		if pBytes, err = p.AsBytes(); err != nil {
-			return
+		part, err := writer.CreatePart(headers)
+		if err != nil {
+			return err
 		}
-		if _, err = io.Copy(part, bytes.NewReader(pBytes)); err != nil {
-			return
+
+		pBytes, err := p.AsBytes()
+		if err != nil {
+			return err
+		}
+		if _, err = part.Write(pBytes); err != nil {
+			return err
 		}
 	}
+	return nil
+}
 
-	writer.Close()
-	overrideContentType = writer.FormDataContentType()
+func (r *RequestCreator) body(refBatch service.MessageBatch) (body io.Reader, overrideContentType string, err error) {
+	if r.explicitBody != nil {
+		body, overrideContentType, err = r.bodyFromExplicit(refBatch)
+		return
+	}
 
-	body = buf
-	return
+	if len(r.explicitMultiparts) > 0 {
+		return r.multipartBody(func(writer *multipart.Writer) error {
+			return r.writeExplicitMultipart(writer, refBatch)
+		})
+	}
+
+	if len(refBatch) == 0 {
+		return
+	}
+
+	if len(refBatch) == 1 {
+		if _, exists := r.headers["Content-Type"]; !exists {
+			overrideContentType = "application/octet-stream"
+		}
+		var bodyBytes []byte
+		if bodyBytes, err = refBatch[0].AsBytes(); err != nil {
+			return
+		}
+		body = r.bytesBody(bodyBytes)
+		return
+	}
+
+	// More than one message in the batch, create a multipart message by
+	// default.
+	return r.multipartBody(func(writer *multipart.Writer) error {
+		return r.writeBatchMultipart(writer, refBatch)
+	})
 }
 
 // Create an *http.Request using a reference message batch to extract the body
@@ -218,6 +312,13 @@ func (r *RequestCreator) Create(refBatch service.MessageBatch) (req *http.Reques
 	if body, overrideContentType, err = r.body(refBatch); err != nil {
 		return
 	}
+	defer func() {
+		// Streamed bodies must be closed in order to release their writer
+		// when the request is abandoned.
+		if c, ok := body.(io.Closer); ok && err != nil {
+			_ = c.Close()
+		}
+	}()
 
 	var urlStr string
 	if urlStr, err = refBatch.TryInterpolatedString(0, r.url); err != nil {
@@ -243,6 +344,16 @@ func (r *RequestCreator) Create(refBatch service.MessageBatch) (req *http.Reques
 		})
 	}
 
+	if len(refBatch) > 0 {
+		var mapped map[string]string
+		if mapped, err = r.mappedHeaders(refBatch, 0); err != nil {
+			return
+		}
+		for k, v := range mapped {
+			req.Header.Set(k, v)
+		}
+	}
+
 	if r.host != nil {
 		if req.Host, err = refBatch.TryInterpolatedString(0, r.host); err != nil {
 			err = fmt.Errorf("host interpolation error: %w", err)
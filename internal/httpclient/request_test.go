@@ -1,8 +1,12 @@
 package httpclient
 
 import (
+	"io"
+	"mime"
+	"mime/multipart"
 	"testing"
 
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 
 	"github.com/stretchr/testify/assert"
@@ -39,3 +43,120 @@ metadata:
 	assert.Equal(t, []string{"barvalue"}, req.Header.Values("more_bar"))
 	assert.Equal(t, []string(nil), req.Header.Values("ignore_baz"))
 }
+
+func TestHeadersMapping(t *testing.T) {
+	spec := service.NewConfigSpec().Field(ConfigField("POST", false))
+	parsed, err := spec.ParseYAML(`
+url: example.com/foo
+headers:
+  X-Static: foo
+  X-Event-Type: overridden
+`, nil)
+	require.NoError(t, err)
+
+	oldConf, err := ConfigFromParsed(parsed)
+	require.NoError(t, err)
+
+	mapping, err := bloblang.Parse(`
+root."X-Event-Type" = this.type
+root."X-Tenant" = @tenant
+`)
+	require.NoError(t, err)
+
+	reqCreator, err := RequestCreatorFromOldConfig(oldConf, service.MockResources(), WithHeadersMapping(mapping))
+	require.NoError(t, err)
+
+	partA := service.NewMessage([]byte(`{"type":"foo"}`))
+	partA.MetaSetMut("tenant", "a")
+	partB := service.NewMessage([]byte(`{"type":"bar"}`))
+	partB.MetaSetMut("tenant", "b")
+
+	req, err := reqCreator.Create(service.MessageBatch{partA})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"foo"}, req.Header.Values("X-Static"))
+	assert.Equal(t, []string{"foo"}, req.Header.Values("X-Event-Type"))
+	assert.Equal(t, []string{"a"}, req.Header.Values("X-Tenant"))
+
+	// Each part of a multipart request receives its own headers.
+	req, err = reqCreator.Create(service.MessageBatch{partA, partB})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, req.Header.Values("X-Tenant"))
+
+	mr, err := req.MultipartReader()
+	require.NoError(t, err)
+
+	var partTenants, partTypes []string
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			require.ErrorIs(t, err, io.EOF)
+			break
+		}
+		partTenants = append(partTenants, p.Header.Get("X-Tenant"))
+		partTypes = append(partTypes, p.Header.Get("X-Event-Type"))
+	}
+	assert.Equal(t, []string{"a", "b"}, partTenants)
+	assert.Equal(t, []string{"foo", "bar"}, partTypes)
+
+	// Mappings that do not result in an object are rejected.
+	mapping, err = bloblang.Parse(`root = [ "nope" ]`)
+	require.NoError(t, err)
+
+	reqCreator, err = RequestCreatorFromOldConfig(oldConf, service.MockResources(), WithHeadersMapping(mapping))
+	require.NoError(t, err)
+
+	_, err = reqCreator.Create(service.MessageBatch{partA})
+	require.ErrorContains(t, err, "non-object result")
+}
+
+func TestStreamingMultipartBody(t *testing.T) {
+	spec := service.NewConfigSpec().Field(ConfigField("POST", false))
+	parsed, err := spec.ParseYAML(`
+url: example.com/foo
+`, nil)
+	require.NoError(t, err)
+
+	oldConf, err := ConfigFromParsed(parsed)
+	require.NoError(t, err)
+
+	reqCreator, err := RequestCreatorFromOldConfig(oldConf, service.MockResources(), WithStreamingBody())
+	require.NoError(t, err)
+
+	req, err := reqCreator.Create(service.MessageBatch{
+		service.NewMessage([]byte("hello")),
+		service.NewMessage([]byte("world")),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), req.ContentLength)
+
+	_, params, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	mr := multipart.NewReader(req.Body, params["boundary"])
+	for _, exp := range []string{"hello", "world"} {
+		p, err := mr.NextPart()
+		require.NoError(t, err)
+
+		pBytes, err := io.ReadAll(p)
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(pBytes))
+	}
+	_, err = mr.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	// Errors that occur whilst writing parts are surfaced by the body.
+	badCType, err := service.NewInterpolatedString(`${! throw("nope") }`)
+	require.NoError(t, err)
+	reqCreator, err = RequestCreatorFromOldConfig(oldConf, service.MockResources(), WithStreamingBody(), WithExplicitMultipart([]MultipartExpressions{
+		{ContentType: badCType, ContentDisposition: badCType, Body: badCType},
+	}))
+	require.NoError(t, err)
+
+	req, err = reqCreator.Create(service.MessageBatch{service.NewMessage([]byte("hello"))})
+	require.NoError(t, err)
+
+	_, err = io.ReadAll(req.Body)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nope")
+}
@@ -0,0 +1,250 @@
+package httpclient
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/retries"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hcFieldRetryPolicy                  = "retry_policy"
+	hcFieldRetryPolicyRespectRetryAfter = "respect_retry_after"
+	hcFieldRetryPolicyMaxRetryAfter     = "max_retry_after"
+	hcFieldRetryPolicyRules             = "rules"
+	hcFieldRetryPolicyBudget            = "budget"
+	hcFieldRetryBudgetRatio             = "ratio"
+	hcFieldRetryBudgetMinRetries        = "min_retries"
+	hcFieldRetryBudgetWindow            = "window"
+	hcFieldRetryRuleStatus              = "status"
+	hcFieldRetryRuleFatal               = "fatal"
+	hcFieldRetryRuleInitialInterval     = "initial_interval"
+	hcFieldRetryRuleMaxInterval         = "max_interval"
+	hcFieldRetryRuleMultiplier          = "multiplier"
+	hcFieldRetryRuleMaxRetries          = "max_retries"
+)
+
+func retryPolicyField() *service.ConfigField {
+	def := retries.NewConfig()
+	return service.NewObjectField(hcFieldRetryPolicy,
+		service.NewBoolField(hcFieldRetryPolicyRespectRetryAfter).
+			Description("Whether the period to wait before retrying a request should be taken from the `Retry-After` header of the response when present.").
+			Default(true),
+		service.NewDurationField(hcFieldRetryPolicyMaxRetryAfter).
+			Description("The maximum period to wait as instructed by a `Retry-After` header.").
+			Default("5m"),
+		service.NewObjectListField(hcFieldRetryPolicyRules,
+			service.NewStringListField(hcFieldRetryRuleStatus).
+				Description("A list of status codes matched by this rule, where a class of status codes can be matched with a pattern such as `5xx`.").
+				Example([]string{"429"}).
+				Example([]string{"5xx"}),
+			service.NewBoolField(hcFieldRetryRuleFatal).
+				Description("Whether the matched status codes are considered fatal, in which case the request is not retried and the message is rejected.").
+				Default(false),
+			service.NewDurationField(hcFieldRetryRuleInitialInterval).
+				Description("The period to wait before the first retry.").
+				Default("1s"),
+			service.NewDurationField(hcFieldRetryRuleMaxInterval).
+				Description("The maximum period to wait between retries.").
+				Default("60s"),
+			service.NewFloatField(hcFieldRetryRuleMultiplier).
+				Description("The factor by which the period to wait is multiplied after each retry, where a value of 1 results in a constant period.").
+				Default(2.0),
+			service.NewIntField(hcFieldRetryRuleMaxRetries).
+				Description("The maximum number of retries for requests that result in the matched status codes, where a negative value defaults to the `retries` field.").
+				Default(-1),
+		).
+			Description("A list of rules that determine how requests that result in particular status codes are retried, where the first rule that matches a status code is applied. Status codes that are not matched by a rule are retried according to the fields `retry_period`, `max_retry_backoff`, `retries`, `backoff_on` and `drop_on`.").
+			Default([]any{}),
+		service.NewObjectField(hcFieldRetryPolicyBudget,
+			service.NewFloatField(hcFieldRetryBudgetRatio).
+				Description("The maximum ratio of retries to requests within each window, where `0` disables the budget. For example, a ratio of `0.2` allows at most one retry for every five requests.").
+				Default(def.BudgetRatio).Example(0.2),
+			service.NewIntField(hcFieldRetryBudgetMinRetries).
+				Description("A number of retries allowed within each window regardless of the ratio, which prevents the budget from blocking retries when there are few requests.").
+				Default(def.BudgetMinRetries),
+			service.NewDurationField(hcFieldRetryBudgetWindow).
+				Description("The period of time over which requests and retries are counted.").
+				Default(def.BudgetWindow.String()),
+		).
+			Description("A retry budget shared by all requests of the component, which prevents retries from amplifying the load on a service that is already failing. Requests that are not retried due to the budget fail with their last error."),
+	).
+		Description("An optional policy for retrying failed requests based on their response, which takes precedence over the fields `backoff_on` and `drop_on`.").
+		Example(map[string]any{
+			hcFieldRetryPolicyRules: []any{
+				map[string]any{
+					hcFieldRetryRuleStatus:          []any{"429"},
+					hcFieldRetryRuleInitialInterval: "5s",
+					hcFieldRetryRuleMaxInterval:     "5m",
+					hcFieldRetryRuleMaxRetries:      10,
+				},
+				map[string]any{
+					hcFieldRetryRuleStatus:          []any{"5xx"},
+					hcFieldRetryRuleInitialInterval: "500ms",
+					hcFieldRetryRuleMaxInterval:     "10s",
+				},
+				map[string]any{
+					hcFieldRetryRuleStatus: []any{"400", "422"},
+					hcFieldRetryRuleFatal:  true,
+				},
+			},
+		}).
+		Version("4.28.0").
+		Advanced().
+		Optional()
+}
+
+// RetryPolicy determines how failed requests are retried based on their
+// response.
+type RetryPolicy struct {
+	RespectRetryAfter bool
+	MaxRetryAfter     time.Duration
+	Rules             []*RetryRule
+
+	BudgetRatio      float64
+	BudgetMinRetries int
+	BudgetWindow     time.Duration
+}
+
+// RetryRule determines how requests that result in matching status codes are
+// retried.
+type RetryRule struct {
+	Codes           map[int]struct{}
+	Classes         map[int]struct{}
+	Fatal           bool
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	MaxRetries      int
+}
+
+func retryPolicyFromParsed(pConf *service.ParsedConfig) (*RetryPolicy, error) {
+	if !pConf.Contains(hcFieldRetryPolicy) {
+		return nil, nil
+	}
+	pConf = pConf.Namespace(hcFieldRetryPolicy)
+
+	p := &RetryPolicy{}
+	var err error
+	if p.RespectRetryAfter, err = pConf.FieldBool(hcFieldRetryPolicyRespectRetryAfter); err != nil {
+		return nil, err
+	}
+	if p.MaxRetryAfter, err = pConf.FieldDuration(hcFieldRetryPolicyMaxRetryAfter); err != nil {
+		return nil, err
+	}
+	if p.BudgetRatio, err = pConf.FieldFloat(hcFieldRetryPolicyBudget, hcFieldRetryBudgetRatio); err != nil {
+		return nil, err
+	}
+	if p.BudgetMinRetries, err = pConf.FieldInt(hcFieldRetryPolicyBudget, hcFieldRetryBudgetMinRetries); err != nil {
+		return nil, err
+	}
+	if p.BudgetWindow, err = pConf.FieldDuration(hcFieldRetryPolicyBudget, hcFieldRetryBudgetWindow); err != nil {
+		return nil, err
+	}
+
+	ruleConfs, err := pConf.FieldObjectList(hcFieldRetryPolicyRules)
+	if err != nil {
+		return nil, err
+	}
+	for i, rConf := range ruleConfs {
+		r := &RetryRule{
+			Codes:   map[int]struct{}{},
+			Classes: map[int]struct{}{},
+		}
+		statuses, err := rConf.FieldStringList(hcFieldRetryRuleStatus)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range statuses {
+			if err := r.addStatus(s); err != nil {
+				return nil, fmt.Errorf("rule %v: %w", i, err)
+			}
+		}
+		if r.Fatal, err = rConf.FieldBool(hcFieldRetryRuleFatal); err != nil {
+			return nil, err
+		}
+		if r.InitialInterval, err = rConf.FieldDuration(hcFieldRetryRuleInitialInterval); err != nil {
+			return nil, err
+		}
+		if r.MaxInterval, err = rConf.FieldDuration(hcFieldRetryRuleMaxInterval); err != nil {
+			return nil, err
+		}
+		if r.Multiplier, err = rConf.FieldFloat(hcFieldRetryRuleMultiplier); err != nil {
+			return nil, err
+		}
+		if r.Multiplier < 1 {
+			return nil, fmt.Errorf("rule %v: multiplier must be at least 1, got %v", i, r.Multiplier)
+		}
+		if r.MaxRetries, err = rConf.FieldInt(hcFieldRetryRuleMaxRetries); err != nil {
+			return nil, err
+		}
+		p.Rules = append(p.Rules, r)
+	}
+	return p, nil
+}
+
+func (r *RetryRule) addStatus(s string) error {
+	if len(s) == 3 && strings.HasSuffix(strings.ToLower(s), "xx") && s[0] >= '1' && s[0] <= '5' {
+		r.Classes[int(s[0]-'0')] = struct{}{}
+		return nil
+	}
+	code, err := strconv.Atoi(s)
+	if err != nil || code < 100 || code > 599 {
+		return fmt.Errorf("invalid status '%v', expected a status code or class such as 5xx", s)
+	}
+	r.Codes[code] = struct{}{}
+	return nil
+}
+
+func (r *RetryRule) matches(code int) bool {
+	if _, exists := r.Codes[code]; exists {
+		return true
+	}
+	_, exists := r.Classes[code/100]
+	return exists
+}
+
+// delay returns the period to wait before a retry, where retries is the
+// number of retries already attempted for the rule.
+func (r *RetryRule) delay(retries int) time.Duration {
+	d := float64(r.InitialInterval) * math.Pow(r.Multiplier, float64(retries))
+	if d > float64(r.MaxInterval) {
+		return r.MaxInterval
+	}
+	return time.Duration(d)
+}
+
+// ruleFor returns the first rule that matches a status code, or nil if none
+// match.
+func (p *RetryPolicy) ruleFor(code int) *RetryRule {
+	if p == nil {
+		return nil
+	}
+	for _, r := range p.Rules {
+		if r.matches(code) {
+			return r
+		}
+	}
+	return nil
+}
+
+// retryAfter returns the period to wait before retrying a request as
+// instructed by the Retry-After header of a response, if present.
+func (p *RetryPolicy) retryAfter(res *http.Response) (time.Duration, bool) {
+	if p == nil || !p.RespectRetryAfter {
+		return 0, false
+	}
+	d, ok := retries.ParseRetryAfter(res.Header.Get("Retry-After"))
+	if !ok {
+		return 0, false
+	}
+	if d > p.MaxRetryAfter {
+		d = p.MaxRetryAfter
+	}
+	return d, true
+}
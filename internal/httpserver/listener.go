@@ -0,0 +1,143 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	unixAddressPrefix    = "unix://"
+	systemdAddressPrefix = "systemd"
+
+	// The first file descriptor passed by systemd socket activation, see
+	// sd_listen_fds(3).
+	systemdListenFDsStart = 3
+)
+
+// ListenAddressDescription is a documentation snippet that describes the
+// address formats supported by Listen.
+const ListenAddressDescription = "The address may also be a unix domain socket of the form `unix:///path/to/socket`, or `systemd` in order to use a socket passed via systemd socket activation, where `systemd:name` selects the socket with a given `FileDescriptorName`."
+
+// ParseSocketMode parses an octal file mode to apply to unix domain sockets.
+// An empty string results in a mode of zero, in which case the mode of the
+// socket is not changed.
+func ParseSocketMode(s string) (fs.FileMode, error) {
+	if s == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse socket mode '%v' as an octal file mode: %w", s, err)
+	}
+	return fs.FileMode(m), nil
+}
+
+// Listen creates a listener for an address, which may be a TCP address, a unix
+// domain socket of the form unix:///path/to/socket, or a socket passed via
+// systemd socket activation of the form systemd or systemd:name. When a socket
+// mode is provided it is applied to unix domain sockets.
+func Listen(address string, socketMode fs.FileMode) (net.Listener, error) {
+	if path, isUnix := strings.CutPrefix(address, unixAddressPrefix); isUnix {
+		return listenUnix(path, socketMode)
+	}
+	if address == systemdAddressPrefix || strings.HasPrefix(address, systemdAddressPrefix+":") {
+		return listenSystemd(strings.TrimPrefix(strings.TrimPrefix(address, systemdAddressPrefix), ":"))
+	}
+	return net.Listen("tcp", address)
+}
+
+func listenUnix(path string, socketMode fs.FileMode) (net.Listener, error) {
+	if path == "" {
+		return nil, errors.New("unix socket path must not be empty")
+	}
+
+	// Remove a stale socket left behind by a previous process, but never any
+	// other type of file.
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&fs.ModeSocket == 0 {
+			return nil, fmt.Errorf("path %v already exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if socketMode != 0 {
+		if err := os.Chmod(path, socketMode); err != nil {
+			_ = ln.Close()
+			return nil, fmt.Errorf("failed to set socket mode: %w", err)
+		}
+	}
+	return ln, nil
+}
+
+type systemdSocket struct {
+	name string
+	file *os.File
+}
+
+var (
+	systemdSocketsOnce sync.Once
+	systemdSocketsMut  sync.Mutex
+	systemdSockets     []*systemdSocket
+)
+
+func loadSystemdSockets() {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return
+	}
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count <= 0 {
+		return
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < count; i++ {
+		fd := systemdListenFDsStart + i
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		systemdSockets = append(systemdSockets, &systemdSocket{
+			name: name,
+			file: os.NewFile(uintptr(fd), name),
+		})
+	}
+}
+
+// listenSystemd claims a socket passed via systemd socket activation, either
+// the first unclaimed socket or the first unclaimed socket with a given name.
+// Each socket can only be claimed once.
+func listenSystemd(name string) (net.Listener, error) {
+	systemdSocketsOnce.Do(loadSystemdSockets)
+
+	systemdSocketsMut.Lock()
+	defer systemdSocketsMut.Unlock()
+
+	for i, s := range systemdSockets {
+		if s == nil || (name != "" && s.name != name) {
+			continue
+		}
+		ln, err := net.FileListener(s.file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use systemd socket %v: %w", s.name, err)
+		}
+		_ = s.file.Close()
+		systemdSockets[i] = nil
+		return ln, nil
+	}
+	if name != "" {
+		return nil, fmt.Errorf("no unclaimed socket named %v was passed via systemd socket activation", name)
+	}
+	return nil, errors.New("no unclaimed sockets were passed via systemd socket activation")
+}
@@ -0,0 +1,97 @@
+package httpserver
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSocketMode(t *testing.T) {
+	m, err := ParseSocketMode("0660")
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o660), m)
+
+	m, err = ParseSocketMode("")
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0), m)
+
+	_, err = ParseSocketMode("rw-rw----")
+	require.Error(t, err)
+}
+
+func TestListenTCP(t *testing.T) {
+	ln, err := Listen("127.0.0.1:0", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "tcp", ln.Addr().Network())
+	require.NoError(t, ln.Close())
+}
+
+func TestListenUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket modes are not supported on windows")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.sock")
+
+	ln, err := Listen("unix://"+path, 0o600)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o600), info.Mode().Perm())
+
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	// Simulate a stale socket from a previous process, which should be
+	// replaced.
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, ln.Close())
+
+	ln, err = Listen("unix://"+path, 0)
+	require.NoError(t, err)
+	require.NoError(t, ln.Close())
+}
+
+func TestListenUnixNotSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not_a_socket")
+	require.NoError(t, os.WriteFile(path, []byte("hello"), 0o600))
+
+	_, err := Listen("unix://"+path, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not a socket")
+}
+
+func TestListenSystemd(t *testing.T) {
+	tcpLn, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = tcpLn.Close()
+	})
+
+	f, err := tcpLn.(*net.TCPListener).File()
+	require.NoError(t, err)
+
+	systemdSocketsOnce.Do(func() {})
+	systemdSocketsMut.Lock()
+	systemdSockets = []*systemdSocket{{name: "http", file: f}}
+	systemdSocketsMut.Unlock()
+
+	_, err = Listen("systemd:admin", 0)
+	require.EqualError(t, err, "no unclaimed socket named admin was passed via systemd socket activation")
+
+	ln, err := Listen("systemd:http", 0)
+	require.NoError(t, err)
+	assert.Equal(t, tcpLn.Addr().String(), ln.Addr().String())
+	require.NoError(t, ln.Close())
+
+	_, err = Listen("systemd", 0)
+	require.EqualError(t, err, "no unclaimed sockets were passed via systemd socket activation")
+}
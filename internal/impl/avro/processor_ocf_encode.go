@@ -0,0 +1,158 @@
+package avro
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/linkedin/goavro/v2"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	aoeFieldSchema      = "schema"
+	aoeFieldSchemaPath  = "schema_path"
+	aoeFieldRawJSON     = "raw_json"
+	aoeFieldCompression = "compression"
+)
+
+func avroOCFEncodeProcessorConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Encodes a batch of JSON messages as an [Avro Object Container File](https://avro.apache.org/docs/current/specification/#object-container-files), resulting in a single message.").
+		Description(`
+This processor is useful for writing batches of messages to files or objects in a format that can be read by analytics tools, and is normally applied within the `+"`batching`"+` processors of an output. The resulting message adopts the metadata of the first message of the batch.
+
+The functionality of this processor depends on being applied across messages that are batched. You can find out more about batching [in this doc](/docs/configuration/batching).`).
+		Fields(
+			service.NewStringField(aoeFieldSchema).
+				Description("A full Avro schema to use.").
+				Default(""),
+			service.NewStringField(aoeFieldSchemaPath).
+				Description("The path of a schema document to apply. Use either this or the `schema` field.").
+				Default("").
+				Example("file://path/to/spec.avsc").
+				Example("http://localhost:8081/path/to/spec/versions/1"),
+			service.NewBoolField(aoeFieldRawJSON).
+				Description("Whether messages are normal JSON rather than [Avro JSON](https://avro.apache.org/docs/current/specification/_print/#json-encoding), where the values of unions are not wrapped within an object named after their type.").
+				Default(false),
+			service.NewStringEnumField(aoeFieldCompression, goavro.CompressionNullLabel, goavro.CompressionDeflateLabel, goavro.CompressionSnappyLabel).
+				Description("The compression codec applied to the blocks of the file.").
+				Default(goavro.CompressionNullLabel),
+		).
+		Example("Writing Avro Files to S3", "Batches of documents are written to S3 as Avro files compressed with deflate.", `
+output:
+  aws_s3:
+    bucket: TODO
+    path: 'events/${! timestamp_unix_nano() }.avro'
+    batching:
+      count: 1000
+      period: 1m
+      processors:
+        - avro_ocf_encode:
+            schema_path: file://./schemas/event.avsc
+            raw_json: true
+            compression: deflate
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("avro_ocf_encode", avroOCFEncodeProcessorConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newAvroOCFEncodeProcessorFromConfig(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type avroOCFEncodeProcessor struct {
+	codec       *goavro.Codec
+	compression string
+}
+
+func newAvroOCFEncodeProcessorFromConfig(conf *service.ParsedConfig) (*avroOCFEncodeProcessor, error) {
+	p := &avroOCFEncodeProcessor{}
+
+	var schema, schemaPath string
+	var rawJSON bool
+	var err error
+
+	if schema, err = conf.FieldString(aoeFieldSchema); err != nil {
+		return nil, err
+	}
+	if schemaPath, err = conf.FieldString(aoeFieldSchemaPath); err != nil {
+		return nil, err
+	}
+	if rawJSON, err = conf.FieldBool(aoeFieldRawJSON); err != nil {
+		return nil, err
+	}
+	if p.compression, err = conf.FieldString(aoeFieldCompression); err != nil {
+		return nil, err
+	}
+
+	if schemaPath != "" {
+		if !(strings.HasPrefix(schemaPath, "file://") || strings.HasPrefix(schemaPath, "http://")) {
+			return nil, errors.New("invalid schema_path provided, must start with file:// or http://")
+		}
+		if schema, err = loadSchema(schemaPath); err != nil {
+			return nil, fmt.Errorf("failed to load Avro schema definition: %v", err)
+		}
+	}
+	if schema == "" {
+		return nil, errors.New("a schema must be specified with either the `schema` or `schema_path` fields")
+	}
+
+	if rawJSON {
+		p.codec, err = goavro.NewCodecForStandardJSONFull(schema)
+	} else {
+		p.codec, err = goavro.NewCodec(schema)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %v", err)
+	}
+	return p, nil
+}
+
+func (p *avroOCFEncodeProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if len(batch) == 0 {
+		return nil, nil
+	}
+
+	datums := make([]any, len(batch))
+	for i, m := range batch {
+		mBytes, err := m.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if datums[i], _, err = p.codec.NativeFromTextual(mBytes); err != nil {
+			return nil, fmt.Errorf("failed to convert JSON to Avro schema: %v", err)
+		}
+	}
+
+	var buf bytes.Buffer
+	w, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               &buf,
+		Codec:           p.codec,
+		CompressionName: p.compression,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Append(datums); err != nil {
+		return nil, err
+	}
+
+	outMsg := batch[0]
+	outMsg.SetBytes(buf.Bytes())
+	return []service.MessageBatch{{outMsg}}, nil
+}
+
+func (p *avroOCFEncodeProcessor) Close(ctx context.Context) error {
+	return nil
+}
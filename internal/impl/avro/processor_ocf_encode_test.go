@@ -0,0 +1,109 @@
+package avro
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestAvroOCFEncode(t *testing.T) {
+	schema := `{
+  "type": "record",
+  "name": "event",
+  "fields": [
+    { "name": "id", "type": "long" },
+    { "name": "name", "type": ["null", "string"] }
+  ]
+}`
+
+	for _, test := range []struct {
+		name     string
+		conf     string
+		messages []string
+	}{
+		{
+			name:     "avro json",
+			conf:     "compression: null",
+			messages: []string{`{"id":1,"name":{"string":"foo"}}`, `{"id":2,"name":null}`},
+		},
+		{
+			name:     "raw json deflate",
+			conf:     "raw_json: true\ncompression: deflate",
+			messages: []string{`{"id":1,"name":"foo"}`, `{"id":2,"name":null}`},
+		},
+		{
+			name:     "raw json snappy",
+			conf:     "raw_json: true\ncompression: snappy",
+			messages: []string{`{"id":1,"name":"foo"}`, `{"id":2,"name":null}`},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := avroOCFEncodeProcessorConfig().ParseYAML(test.conf+"\nschema: '"+schema+"'", nil)
+			require.NoError(t, err)
+
+			proc, err := newAvroOCFEncodeProcessorFromConfig(conf)
+			require.NoError(t, err)
+
+			var batch service.MessageBatch
+			for _, m := range test.messages {
+				msg := service.NewMessage([]byte(m))
+				msg.MetaSetMut("source", "first")
+				batch = append(batch, msg)
+			}
+
+			res, err := proc.ProcessBatch(context.Background(), batch)
+			require.NoError(t, err)
+			require.Len(t, res, 1)
+			require.Len(t, res[0], 1)
+
+			v, exists := res[0][0].MetaGetMut("source")
+			require.True(t, exists)
+			assert.Equal(t, "first", v)
+
+			ocfBytes, err := res[0][0].AsBytes()
+			require.NoError(t, err)
+
+			r, err := goavro.NewOCFReader(bytes.NewReader(ocfBytes))
+			require.NoError(t, err)
+
+			var datums []any
+			for r.Scan() {
+				d, err := r.Read()
+				require.NoError(t, err)
+				datums = append(datums, d)
+			}
+			require.NoError(t, r.Err())
+
+			assert.Equal(t, []any{
+				map[string]any{"id": int64(1), "name": map[string]any{"string": "foo"}},
+				map[string]any{"id": int64(2), "name": nil},
+			}, datums)
+		})
+	}
+}
+
+func TestAvroOCFEncodeErrors(t *testing.T) {
+	conf, err := avroOCFEncodeProcessorConfig().ParseYAML(`{}`, nil)
+	require.NoError(t, err)
+
+	_, err = newAvroOCFEncodeProcessorFromConfig(conf)
+	require.ErrorContains(t, err, "a schema must be specified")
+
+	conf, err = avroOCFEncodeProcessorConfig().ParseYAML(`schema: '{"type":"long"}'`, nil)
+	require.NoError(t, err)
+
+	proc, err := newAvroOCFEncodeProcessorFromConfig(conf)
+	require.NoError(t, err)
+
+	_, err = proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`"not a number"`)),
+	})
+	require.Error(t, err)
+}
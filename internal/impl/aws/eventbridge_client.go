@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// eventBridgeClient performs calls against the JSON API of EventBridge, signed
+// with the credentials of an AWS config.
+type eventBridgeClient struct {
+	aconf  aws.Config
+	signer *v4.Signer
+	nowFn  func() time.Time
+}
+
+func newEventBridgeClient(aconf aws.Config) *eventBridgeClient {
+	return &eventBridgeClient{
+		aconf:  aconf,
+		signer: v4.NewSigner(),
+		nowFn:  time.Now,
+	}
+}
+
+// eventBridgeAPIError is an error returned by the EventBridge API.
+type eventBridgeAPIError struct {
+	StatusCode int
+	Type       string `json:"__type"`
+	Message    string `json:"message"`
+}
+
+func (e *eventBridgeAPIError) Error() string {
+	return fmt.Sprintf("eventbridge request failed with status %v: %v: %v", e.StatusCode, e.Type, e.Message)
+}
+
+func (e *eventBridgeClient) endpoint() string {
+	if e.aconf.BaseEndpoint != nil {
+		return *e.aconf.BaseEndpoint
+	}
+	return fmt.Sprintf("https://events.%v.amazonaws.com", e.aconf.Region)
+}
+
+// call invokes an action of the EventBridge API, such as PutEvents, with an
+// input that is marshalled as JSON and a response that is unmarshalled into
+// output, which may be nil.
+func (e *eventBridgeClient) call(ctx context.Context, action string, input, output any) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint()+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AWSEvents."+action)
+
+	creds, err := e.aconf.Credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credentials: %w", err)
+	}
+	payloadHash := sha256.Sum256(body)
+	if err := e.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), "events", e.aconf.Region, e.nowFn()); err != nil {
+		return fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	httpClient := e.aconf.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		apiErr := &eventBridgeAPIError{StatusCode: res.StatusCode}
+		_ = json.Unmarshal(resBody, apiErr)
+		return apiErr
+	}
+	if output == nil || len(resBody) == 0 {
+		return nil
+	}
+	return json.Unmarshal(resBody, output)
+}
@@ -0,0 +1,344 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// EventBridge Input Fields
+	ebiFieldEventBus      = "event_bus"
+	ebiFieldRule          = "rule"
+	ebiFieldEventPattern  = "event_pattern"
+	ebiFieldQueue         = "queue"
+	ebiFieldUnwrapDetail  = "unwrap_detail"
+	ebiFieldDeleteOnClose = "delete_on_close"
+
+	ebiTargetID = "benthos"
+)
+
+func eventBridgeInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Services", "AWS").
+		Summary(`Consumes events from an [Amazon EventBridge](https://aws.amazon.com/eventbridge/) event bus by routing them to an SQS queue that is managed by the input.`).
+		Description(`
+When connecting, the input creates (or updates) an SQS queue, a rule on the event bus with the configured event pattern, a policy allowing the rule to send messages to the queue, and a target of the rule that routes matching events to the queue. These resources are named after the `+"`rule`"+` and `+"`queue`"+` fields and are reused by subsequent runs, such that events matched whilst Benthos is not running are consumed once it is restarted. When `+"`delete_on_close`"+` is enabled the resources are deleted when the input is closed instead.
+
+Events are consumed from the queue in the same way as the `+"[`aws_sqs` input](/docs/components/inputs/aws_sqs)"+`, where messages are deleted from the queue once they have been acknowledged.
+
+The credentials used by the input require permissions to manage the queue (`+"`sqs:CreateQueue`, `sqs:GetQueueAttributes`, `sqs:SetQueueAttributes`, `sqs:DeleteQueue`"+`), consume from it, and manage the rule (`+"`events:PutRule`, `events:PutTargets`, `events:RemoveTargets`, `events:DeleteRule`"+`).
+
+### Metadata
+
+When `+"`unwrap_detail`"+` is enabled the contents of each message is the `+"`detail`"+` of the event, and the following fields of the event are added as metadata:
+
+`+"```text"+`
+- eventbridge_id
+- eventbridge_source
+- eventbridge_detail_type
+- eventbridge_time
+- eventbridge_account
+- eventbridge_region
+`+"```"+`
+
+The metadata fields of the `+"`aws_sqs`"+` input are also added.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`).
+		Fields(
+			service.NewStringField(ebiFieldEventBus).
+				Description("The name or ARN of the event bus to consume events from.").
+				Default("default"),
+			service.NewStringField(ebiFieldRule).
+				Description("The name of the rule managed by the input.").
+				Example("benthos-orders"),
+			service.NewStringField(ebiFieldEventPattern).
+				Description("The [event pattern](https://docs.aws.amazon.com/eventbridge/latest/userguide/eb-event-patterns.html) of the rule, which determines the events that are consumed.").
+				Example(`{"source":["com.example.orders"]}`),
+			service.NewStringField(ebiFieldQueue).
+				Description("The name of the SQS queue managed by the input, which defaults to the name of the `rule`.").
+				Optional(),
+			service.NewBoolField(ebiFieldUnwrapDetail).
+				Description("Whether the contents of messages should be the `detail` of events, with the other fields of events added as metadata, rather than the entire event.").
+				Default(true),
+			service.NewBoolField(ebiFieldDeleteOnClose).
+				Description("Whether the rule, its target and the queue should be deleted when the input is closed. Events that are pending in the queue are lost when it is deleted.").
+				Default(false).
+				Advanced(),
+		).
+		Fields(config.SessionFields()...).
+		Example("Consuming Order Events", "Order events from a custom event bus are consumed and written to Kafka.", `
+input:
+  aws_eventbridge:
+    event_bus: orders
+    rule: benthos-orders-to-kafka
+    event_pattern: '{"source":["com.example.orders"],"detail-type":["OrderCreated"]}'
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders
+`)
+}
+
+func init() {
+	err := service.RegisterInput("aws_eventbridge", eventBridgeInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			aconf, err := GetSession(context.TODO(), conf)
+			if err != nil {
+				return nil, err
+			}
+			return newEventBridgeReaderFromParsed(conf, aconf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type eventBridgeQueueAPI interface {
+	sqsAPI
+	CreateQueue(context.Context, *sqs.CreateQueueInput, ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error)
+	GetQueueAttributes(context.Context, *sqs.GetQueueAttributesInput, ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error)
+	SetQueueAttributes(context.Context, *sqs.SetQueueAttributesInput, ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error)
+	DeleteQueue(context.Context, *sqs.DeleteQueueInput, ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error)
+}
+
+type eventBridgeReader struct {
+	eventBus      string
+	rule          string
+	eventPattern  string
+	queue         string
+	unwrapDetail  bool
+	deleteOnClose bool
+
+	client   *eventBridgeClient
+	queueAPI eventBridgeQueueAPI
+	reader   *awsSQSReader
+	log      *service.Logger
+}
+
+func newEventBridgeReaderFromParsed(conf *service.ParsedConfig, aconf aws.Config, mgr *service.Resources) (*eventBridgeReader, error) {
+	e := &eventBridgeReader{
+		client:   newEventBridgeClient(aconf),
+		queueAPI: sqs.NewFromConfig(aconf),
+		log:      mgr.Logger(),
+	}
+
+	var err error
+	if e.eventBus, err = conf.FieldString(ebiFieldEventBus); err != nil {
+		return nil, err
+	}
+	if e.rule, err = conf.FieldString(ebiFieldRule); err != nil {
+		return nil, err
+	}
+	if e.rule == "" {
+		return nil, errors.New("rule must not be empty")
+	}
+	if e.eventPattern, err = conf.FieldString(ebiFieldEventPattern); err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(e.eventPattern)) {
+		return nil, errors.New("event_pattern must be valid JSON")
+	}
+	e.queue = e.rule
+	if conf.Contains(ebiFieldQueue) {
+		if e.queue, err = conf.FieldString(ebiFieldQueue); err != nil {
+			return nil, err
+		}
+	}
+	if e.unwrapDetail, err = conf.FieldBool(ebiFieldUnwrapDetail); err != nil {
+		return nil, err
+	}
+	if e.deleteOnClose, err = conf.FieldBool(ebiFieldDeleteOnClose); err != nil {
+		return nil, err
+	}
+
+	if e.reader, err = newAWSSQSReader(sqsiConfig{
+		WaitTimeSeconds:     20,
+		DeleteMessage:       true,
+		ResetVisibility:     true,
+		MaxNumberOfMessages: 10,
+	}, aconf, e.log); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// setup creates or updates the queue, rule and target that route events to the
+// queue, and returns the URL of the queue.
+func (e *eventBridgeReader) setup(ctx context.Context) (string, error) {
+	qRes, err := e.queueAPI.CreateQueue(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String(e.queue),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create queue: %w", err)
+	}
+	queueURL := *qRes.QueueUrl
+
+	aRes, err := e.queueAPI.GetQueueAttributes(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queueURL),
+		AttributeNames: []types.QueueAttributeName{types.QueueAttributeNameQueueArn},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get queue attributes: %w", err)
+	}
+	queueARN := aRes.Attributes[string(types.QueueAttributeNameQueueArn)]
+
+	var ruleRes struct {
+		RuleArn string `json:"RuleArn"`
+	}
+	if err := e.client.call(ctx, "PutRule", map[string]any{
+		"Name":         e.rule,
+		"EventBusName": e.eventBus,
+		"EventPattern": e.eventPattern,
+		"State":        "ENABLED",
+		"Description":  "Routes events to a queue consumed by Benthos.",
+	}, &ruleRes); err != nil {
+		return "", fmt.Errorf("failed to put rule: %w", err)
+	}
+
+	policy, err := json.Marshal(map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []any{map[string]any{
+			"Effect":    "Allow",
+			"Principal": map[string]any{"Service": "events.amazonaws.com"},
+			"Action":    "sqs:SendMessage",
+			"Resource":  queueARN,
+			"Condition": map[string]any{
+				"ArnEquals": map[string]any{"aws:SourceArn": ruleRes.RuleArn},
+			},
+		}},
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := e.queueAPI.SetQueueAttributes(ctx, &sqs.SetQueueAttributesInput{
+		QueueUrl: aws.String(queueURL),
+		Attributes: map[string]string{
+			string(types.QueueAttributeNamePolicy): string(policy),
+		},
+	}); err != nil {
+		return "", fmt.Errorf("failed to set queue policy: %w", err)
+	}
+
+	var targetsRes struct {
+		FailedEntryCount int `json:"FailedEntryCount"`
+		FailedEntries    []struct {
+			ErrorCode    string `json:"ErrorCode"`
+			ErrorMessage string `json:"ErrorMessage"`
+		} `json:"FailedEntries"`
+	}
+	if err := e.client.call(ctx, "PutTargets", map[string]any{
+		"Rule":         e.rule,
+		"EventBusName": e.eventBus,
+		"Targets": []any{map[string]any{
+			"Id":  ebiTargetID,
+			"Arn": queueARN,
+		}},
+	}, &targetsRes); err != nil {
+		return "", fmt.Errorf("failed to put target: %w", err)
+	}
+	if targetsRes.FailedEntryCount > 0 && len(targetsRes.FailedEntries) > 0 {
+		f := targetsRes.FailedEntries[0]
+		return "", fmt.Errorf("failed to put target: %v: %v", f.ErrorCode, f.ErrorMessage)
+	}
+	return queueURL, nil
+}
+
+func (e *eventBridgeReader) Connect(ctx context.Context) error {
+	if e.reader.sqs != nil {
+		return nil
+	}
+
+	queueURL, err := e.setup(ctx)
+	if err != nil {
+		return err
+	}
+	e.log.Debugf("Routing events matched by rule '%v' to queue '%v'", e.rule, queueURL)
+
+	e.reader.conf.URL = queueURL
+	e.reader.sqs = e.queueAPI
+	return e.reader.Connect(ctx)
+}
+
+func (e *eventBridgeReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	msg, ackFn, err := e.reader.Read(ctx)
+	if err != nil || !e.unwrapDetail {
+		return msg, ackFn, err
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	var event struct {
+		ID         string          `json:"id"`
+		Source     string          `json:"source"`
+		DetailType string          `json:"detail-type"`
+		Time       string          `json:"time"`
+		Account    string          `json:"account"`
+		Region     string          `json:"region"`
+		Detail     json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(mBytes, &event); err != nil || len(event.Detail) == 0 {
+		e.log.Warnf("Received message that is not an EventBridge event, passing it through unchanged")
+		return msg, ackFn, nil
+	}
+
+	msg.SetBytes(event.Detail)
+	for k, v := range map[string]string{
+		"eventbridge_id":          event.ID,
+		"eventbridge_source":      event.Source,
+		"eventbridge_detail_type": event.DetailType,
+		"eventbridge_time":        event.Time,
+		"eventbridge_account":     event.Account,
+		"eventbridge_region":      event.Region,
+	} {
+		msg.MetaSetMut(k, v)
+	}
+	return msg, ackFn, nil
+}
+
+func (e *eventBridgeReader) cleanup(ctx context.Context) error {
+	if err := e.client.call(ctx, "RemoveTargets", map[string]any{
+		"Rule":         e.rule,
+		"EventBusName": e.eventBus,
+		"Ids":          []string{ebiTargetID},
+	}, nil); err != nil {
+		return fmt.Errorf("failed to remove target: %w", err)
+	}
+	if err := e.client.call(ctx, "DeleteRule", map[string]any{
+		"Name":         e.rule,
+		"EventBusName": e.eventBus,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to delete rule: %w", err)
+	}
+	if _, err := e.queueAPI.DeleteQueue(ctx, &sqs.DeleteQueueInput{
+		QueueUrl: aws.String(e.reader.conf.URL),
+	}); err != nil {
+		return fmt.Errorf("failed to delete queue: %w", err)
+	}
+	return nil
+}
+
+func (e *eventBridgeReader) Close(ctx context.Context) error {
+	if err := e.reader.Close(ctx); err != nil {
+		return err
+	}
+	if !e.deleteOnClose || e.reader.conf.URL == "" {
+		return nil
+	}
+	return e.cleanup(ctx)
+}
@@ -0,0 +1,161 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockEventBridgeQueue struct {
+	sqsAPI
+
+	mut      sync.Mutex
+	policy   string
+	pending  []types.Message
+	deleted  []string
+	queueDel bool
+}
+
+func (m *mockEventBridgeQueue) CreateQueue(ctx context.Context, in *sqs.CreateQueueInput, _ ...func(*sqs.Options)) (*sqs.CreateQueueOutput, error) {
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String("https://sqs/" + *in.QueueName)}, nil
+}
+
+func (m *mockEventBridgeQueue) GetQueueAttributes(ctx context.Context, in *sqs.GetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.GetQueueAttributesOutput, error) {
+	return &sqs.GetQueueAttributesOutput{Attributes: map[string]string{"QueueArn": "arn:aws:sqs:us-east-1:123:orders"}}, nil
+}
+
+func (m *mockEventBridgeQueue) SetQueueAttributes(ctx context.Context, in *sqs.SetQueueAttributesInput, _ ...func(*sqs.Options)) (*sqs.SetQueueAttributesOutput, error) {
+	m.mut.Lock()
+	m.policy = in.Attributes["Policy"]
+	m.mut.Unlock()
+	return &sqs.SetQueueAttributesOutput{}, nil
+}
+
+func (m *mockEventBridgeQueue) DeleteQueue(ctx context.Context, in *sqs.DeleteQueueInput, _ ...func(*sqs.Options)) (*sqs.DeleteQueueOutput, error) {
+	m.mut.Lock()
+	m.queueDel = true
+	m.mut.Unlock()
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+func (m *mockEventBridgeQueue) ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	m.mut.Lock()
+	msgs := m.pending
+	m.pending = nil
+	m.mut.Unlock()
+	if len(msgs) == 0 {
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return &sqs.ReceiveMessageOutput{Messages: msgs}, nil
+}
+
+func (m *mockEventBridgeQueue) DeleteMessageBatch(ctx context.Context, in *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.DeleteMessageBatchOutput, error) {
+	m.mut.Lock()
+	for _, e := range in.Entries {
+		m.deleted = append(m.deleted, *e.Id)
+	}
+	m.mut.Unlock()
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+func (m *mockEventBridgeQueue) ChangeMessageVisibilityBatch(ctx context.Context, in *sqs.ChangeMessageVisibilityBatchInput, _ ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityBatchOutput, error) {
+	return &sqs.ChangeMessageVisibilityBatchOutput{}, nil
+}
+
+func TestEventBridgeInput(t *testing.T) {
+	pConf, err := eventBridgeInputSpec().ParseYAML(`
+event_bus: orders
+rule: benthos-orders
+event_pattern: '{"source":["com.example.orders"]}'
+delete_on_close: true
+region: us-east-1
+`, nil)
+	require.NoError(t, err)
+
+	fake, aconf := newFakeEventBridge(t, func(action string, body map[string]any) (int, any) {
+		if action == "PutRule" {
+			return http.StatusOK, map[string]any{"RuleArn": "arn:aws:events:us-east-1:123:rule/orders/benthos-orders"}
+		}
+		return http.StatusOK, map[string]any{}
+	})
+
+	r, err := newEventBridgeReaderFromParsed(pConf, aconf, service.MockResources())
+	require.NoError(t, err)
+
+	queue := &mockEventBridgeQueue{
+		pending: []types.Message{{
+			MessageId:     aws.String("a"),
+			ReceiptHandle: aws.String("ha"),
+			Body: aws.String(`{
+  "id": "e1",
+  "detail-type": "OrderCreated",
+  "source": "com.example.orders",
+  "account": "123",
+  "time": "2024-04-23T10:00:00Z",
+  "region": "us-east-1",
+  "detail": {"order_id":"o1"}
+}`),
+		}},
+	}
+	r.queueAPI = queue
+
+	ctx, done := context.WithTimeout(context.Background(), 10*time.Second)
+	defer done()
+
+	require.NoError(t, r.Connect(ctx))
+
+	msg, ackFn, err := r.Read(ctx)
+	require.NoError(t, err)
+
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"order_id":"o1"}`, string(mBytes))
+
+	for k, exp := range map[string]string{
+		"eventbridge_id":          "e1",
+		"eventbridge_detail_type": "OrderCreated",
+		"eventbridge_source":      "com.example.orders",
+		"sqs_message_id":          "a",
+	} {
+		v, _ := msg.MetaGet(k)
+		assert.Equal(t, exp, v, k)
+	}
+	require.NoError(t, ackFn(ctx, nil))
+
+	require.NoError(t, r.Close(ctx))
+
+	queue.mut.Lock()
+	assert.Equal(t, []string{"a"}, queue.deleted)
+	assert.True(t, queue.queueDel)
+
+	var policy map[string]any
+	require.NoError(t, json.Unmarshal([]byte(queue.policy), &policy))
+	statement := policy["Statement"].([]any)[0].(map[string]any)
+	assert.Equal(t, "arn:aws:sqs:us-east-1:123:orders", statement["Resource"])
+	assert.Equal(t, map[string]any{
+		"ArnEquals": map[string]any{"aws:SourceArn": "arn:aws:events:us-east-1:123:rule/orders/benthos-orders"},
+	}, statement["Condition"])
+	queue.mut.Unlock()
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	assert.Equal(t, []string{"PutRule", "PutTargets", "RemoveTargets", "DeleteRule"}, fake.calls)
+	assert.Equal(t, `{"source":["com.example.orders"]}`, fake.bodies[0]["EventPattern"])
+	assert.Equal(t, "orders", fake.bodies[0]["EventBusName"])
+	assert.Equal(t, []any{map[string]any{"Id": "benthos", "Arn": "arn:aws:sqs:us-east-1:123:orders"}}, fake.bodies[1]["Targets"])
+}
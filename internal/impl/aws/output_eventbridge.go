@@ -0,0 +1,276 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// EventBridge Output Fields
+	eboFieldEventBus   = "event_bus"
+	eboFieldSource     = "source"
+	eboFieldDetailType = "detail_type"
+	eboFieldResources  = "resources"
+	eboFieldTimeout    = "timeout"
+	eboFieldBatching   = "batching"
+
+	// The limits of a single PutEvents request.
+	eboMaxEntries   = 10
+	eboMaxEntrySize = 256 * 1024
+)
+
+func eventBridgeOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Services", "AWS").
+		Summary(`Puts events onto an [Amazon EventBridge](https://aws.amazon.com/eventbridge/) event bus, where the contents of each message is the detail of an event.`).
+		Description(output.Description(true, true, `
+The contents of each message must be a JSON object, which becomes the `+"`detail`"+` of the event, and the `+"`source`"+` and `+"`detail-type`"+` of each event are resolved from interpolations.
+
+### Batching
+
+A batch of messages is split into as few `+"`PutEvents`"+` requests as possible within the limits of the API, which are 10 entries and 256KB per request. Messages that exceed the size limit on their own are rejected. When only some entries of a request fail, for example due to throttling, only the messages of the failed entries are sent again.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`)).
+		Fields(
+			service.NewInterpolatedStringField(eboFieldEventBus).
+				Description("The name or ARN of the event bus to put events onto.").
+				Default("default"),
+			service.NewInterpolatedStringField(eboFieldSource).
+				Description("The source of each event.").
+				Example("com.example.orders").
+				Example(`${! @source }`),
+			service.NewInterpolatedStringField(eboFieldDetailType).
+				Description("The detail type of each event.").
+				Example("OrderCreated").
+				Example(`${! this.type }`),
+			service.NewInterpolatedStringListField(eboFieldResources).
+				Description("An optional list of ARNs of the resources involved in each event.").
+				Default([]any{}).
+				Advanced(),
+			service.NewOutputMaxInFlightField(),
+			service.NewDurationField(eboFieldTimeout).
+				Description("The maximum period of time to wait for a request to complete.").
+				Default("5s").
+				Advanced(),
+			service.NewBatchPolicyField(eboFieldBatching),
+		).
+		Fields(config.SessionFields()...).
+		Example("Publishing Domain Events", "Order events are put onto a custom event bus, where the detail type is taken from each event.", `
+output:
+  aws_eventbridge:
+    event_bus: orders
+    source: com.example.orders
+    detail_type: ${! this.type }
+    batching:
+      count: 10
+      period: 500ms
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("aws_eventbridge", eventBridgeOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(eboFieldBatching); err != nil {
+				return
+			}
+			out, err = newEventBridgeWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type eventBridgeEntry struct {
+	Source       string   `json:"Source"`
+	DetailType   string   `json:"DetailType"`
+	Detail       string   `json:"Detail"`
+	EventBusName string   `json:"EventBusName"`
+	Resources    []string `json:"Resources,omitempty"`
+}
+
+// size returns the size of an entry as calculated by EventBridge.
+func (e *eventBridgeEntry) size() int {
+	size := len(e.Source) + len(e.DetailType) + len(e.Detail)
+	for _, r := range e.Resources {
+		size += len(r)
+	}
+	return size
+}
+
+type eventBridgePutEventsOutput struct {
+	FailedEntryCount int `json:"FailedEntryCount"`
+	Entries          []struct {
+		EventID      string `json:"EventId"`
+		ErrorCode    string `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+	} `json:"Entries"`
+}
+
+type eventBridgeWriter struct {
+	eventBus   *service.InterpolatedString
+	source     *service.InterpolatedString
+	detailType *service.InterpolatedString
+	resources  []*service.InterpolatedString
+	timeout    time.Duration
+
+	client *eventBridgeClient
+	log    *service.Logger
+}
+
+func newEventBridgeWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*eventBridgeWriter, error) {
+	e := &eventBridgeWriter{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if e.eventBus, err = conf.FieldInterpolatedString(eboFieldEventBus); err != nil {
+		return nil, err
+	}
+	if e.source, err = conf.FieldInterpolatedString(eboFieldSource); err != nil {
+		return nil, err
+	}
+	if e.detailType, err = conf.FieldInterpolatedString(eboFieldDetailType); err != nil {
+		return nil, err
+	}
+	if e.resources, err = conf.FieldInterpolatedStringList(eboFieldResources); err != nil {
+		return nil, err
+	}
+	if e.timeout, err = conf.FieldDuration(eboFieldTimeout); err != nil {
+		return nil, err
+	}
+
+	aconf, err := GetSession(context.TODO(), conf)
+	if err != nil {
+		return nil, err
+	}
+	e.client = newEventBridgeClient(aconf)
+	return e, nil
+}
+
+func (e *eventBridgeWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (e *eventBridgeWriter) entry(batch service.MessageBatch, i int) (*eventBridgeEntry, error) {
+	var entry eventBridgeEntry
+	var err error
+	if entry.EventBusName, err = batch.TryInterpolatedString(i, e.eventBus); err != nil {
+		return nil, fmt.Errorf("event bus interpolation: %w", err)
+	}
+	if entry.Source, err = batch.TryInterpolatedString(i, e.source); err != nil {
+		return nil, fmt.Errorf("source interpolation: %w", err)
+	}
+	if entry.DetailType, err = batch.TryInterpolatedString(i, e.detailType); err != nil {
+		return nil, fmt.Errorf("detail type interpolation: %w", err)
+	}
+	for _, r := range e.resources {
+		rStr, err := batch.TryInterpolatedString(i, r)
+		if err != nil {
+			return nil, fmt.Errorf("resource interpolation: %w", err)
+		}
+		entry.Resources = append(entry.Resources, rStr)
+	}
+
+	mBytes, err := batch[i].AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	var detail map[string]any
+	if err := json.Unmarshal(mBytes, &detail); err != nil {
+		return nil, errors.New("message must be a JSON object")
+	}
+	entry.Detail = string(mBytes)
+
+	if entry.size() > eboMaxEntrySize {
+		return nil, fmt.Errorf("event size %v exceeds the limit of %v bytes", entry.size(), eboMaxEntrySize)
+	}
+	return &entry, nil
+}
+
+func (e *eventBridgeWriter) put(ctx context.Context, entries []*eventBridgeEntry) (*eventBridgePutEventsOutput, error) {
+	ctx, done := context.WithTimeout(ctx, e.timeout)
+	defer done()
+
+	var out eventBridgePutEventsOutput
+	if err := e.client.call(ctx, "PutEvents", map[string]any{"Entries": entries}, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Entries) != len(entries) {
+		return nil, fmt.Errorf("expected %v entries in response, got %v", len(entries), len(out.Entries))
+	}
+	return &out, nil
+}
+
+func (e *eventBridgeWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	var (
+		entries []*eventBridgeEntry
+		indexes []int
+		size    int
+	)
+	flush := func() {
+		if len(entries) == 0 {
+			return
+		}
+		out, err := e.put(ctx, entries)
+		if err != nil {
+			for _, i := range indexes {
+				failed(i, err)
+			}
+		} else if out.FailedEntryCount > 0 {
+			for j, res := range out.Entries {
+				if res.ErrorCode != "" {
+					failed(indexes[j], fmt.Errorf("failed to put event: %v: %v", res.ErrorCode, res.ErrorMessage))
+				}
+			}
+		}
+		entries, indexes, size = nil, nil, 0
+	}
+
+	for i := range batch {
+		entry, err := e.entry(batch, i)
+		if err != nil {
+			e.log.Errorf("Failed to create event: %v", err)
+			failed(i, err)
+			continue
+		}
+		if len(entries) == eboMaxEntries || size+entry.size() > eboMaxEntrySize {
+			flush()
+		}
+		entries = append(entries, entry)
+		indexes = append(indexes, i)
+		size += entry.size()
+	}
+	flush()
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (e *eventBridgeWriter) Close(ctx context.Context) error {
+	return nil
+}
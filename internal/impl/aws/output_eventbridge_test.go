@@ -0,0 +1,139 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeEventBridge struct {
+	mut     sync.Mutex
+	calls   []string
+	bodies  []map[string]any
+	handler func(action string, body map[string]any) (int, any)
+}
+
+func newFakeEventBridge(t *testing.T, handler func(action string, body map[string]any) (int, any)) (*fakeEventBridge, aws.Config) {
+	t.Helper()
+
+	f := &fakeEventBridge{handler: handler}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 "))
+
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AWSEvents.")
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		f.mut.Lock()
+		f.calls = append(f.calls, action)
+		f.bodies = append(f.bodies, body)
+		f.mut.Unlock()
+
+		code, res := f.handler(action, body)
+		w.WriteHeader(code)
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(ts.Close)
+
+	return f, aws.Config{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+		BaseEndpoint: aws.String(ts.URL),
+	}
+}
+
+func TestEventBridgeOutput(t *testing.T) {
+	pConf, err := eventBridgeOutputSpec().ParseYAML(`
+event_bus: orders
+source: com.example.orders
+detail_type: ${! this.type }
+region: us-east-1
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventBridgeWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	fake, aconf := newFakeEventBridge(t, func(action string, body map[string]any) (int, any) {
+		var entries []any
+		failed := 0
+		for _, e := range body["Entries"].([]any) {
+			if strings.Contains(e.(map[string]any)["Detail"].(string), "throttle") {
+				failed++
+				entries = append(entries, map[string]any{"ErrorCode": "ThrottlingException", "ErrorMessage": "slow down"})
+			} else {
+				entries = append(entries, map[string]any{"EventId": "foo"})
+			}
+		}
+		return http.StatusOK, map[string]any{"FailedEntryCount": failed, "Entries": entries}
+	})
+	w.client = newEventBridgeClient(aconf)
+
+	var batch service.MessageBatch
+	for i := 0; i < 13; i++ {
+		batch = append(batch, service.NewMessage([]byte(`{"type":"OrderCreated"}`)))
+	}
+	batch[3] = service.NewMessage([]byte(`{"type":"OrderCreated","throttle":true}`))
+	batch[5] = service.NewMessage([]byte(`not json`))
+	batch[7] = service.NewMessage([]byte(`{"type":"big","data":"` + strings.Repeat("a", eboMaxEntrySize) + `"}`))
+
+	err = w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+
+	var failed []int
+	bErr.WalkMessages(func(i int, m *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.Equal(t, []int{3, 5, 7}, failed)
+
+	fake.mut.Lock()
+	defer fake.mut.Unlock()
+
+	assert.Equal(t, []string{"PutEvents", "PutEvents"}, fake.calls)
+	assert.Len(t, fake.bodies[0]["Entries"], 10)
+	assert.Len(t, fake.bodies[1]["Entries"], 1)
+
+	entry := fake.bodies[0]["Entries"].([]any)[0].(map[string]any)
+	assert.Equal(t, "orders", entry["EventBusName"])
+	assert.Equal(t, "com.example.orders", entry["Source"])
+	assert.Equal(t, "OrderCreated", entry["DetailType"])
+}
+
+func TestEventBridgeOutputRequestError(t *testing.T) {
+	pConf, err := eventBridgeOutputSpec().ParseYAML(`
+source: foo
+detail_type: bar
+region: us-east-1
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newEventBridgeWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	_, aconf := newFakeEventBridge(t, func(action string, body map[string]any) (int, any) {
+		return http.StatusBadRequest, map[string]any{"__type": "AccessDeniedException", "message": "nope"}
+	})
+	w.client = newEventBridgeClient(aconf)
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{service.NewMessage([]byte(`{}`))})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AccessDeniedException: nope")
+}
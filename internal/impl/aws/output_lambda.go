@@ -0,0 +1,161 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Lambda Output Fields
+	lambdaoFieldFunction  = "function"
+	lambdaoFieldRateLimit = "rate_limit"
+	lambdaoFieldTimeout   = "timeout"
+	lambdaoFieldRetries   = "retries"
+	lambdaoFieldBatching  = "batching"
+)
+
+func lambdaoOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Services", "AWS").
+		Summary(`Invokes an AWS lambda for each message, where the contents of the message is the payload of the invocation.`).
+		Description(output.Description(true, true, `
+By default functions are invoked asynchronously with the `+"`Event`"+` invocation type, in which case a message is acknowledged once the event has been queued by Lambda. When the `+"`invocation_type`"+` is set to `+"`RequestResponse`"+` messages are acknowledged once the function has returned, and function errors result in the message being rejected.
+
+In order to use the response of an invocation use the `+"[`aws_lambda` processor](/docs/components/processors/aws_lambda)"+` instead.
+
+### Payload Limits
+
+Messages that exceed the [payload limit](https://docs.aws.amazon.com/lambda/latest/dg/gettingstarted-limits.html) of the invocation type, which is 256KB for `+"`Event`"+` and 6MB for `+"`RequestResponse`"+` invocations, are rejected without the function being invoked. A batch of messages can be sent as a single invocation by archiving it with an `+"[`archive` processor](/docs/components/processors/archive)"+`, in which case the archived batch is subject to the same limit.
+
+### Credentials
+
+By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`)).
+		Fields(
+			service.NewStringField(lambdaoFieldFunction).
+				Description("The function to invoke."),
+			lambdaInvocationTypeField().Default(string(types.InvocationTypeEvent)),
+			service.NewStringField(lambdaoFieldRateLimit).
+				Description("An optional [`rate_limit`](/docs/components/rate_limits/about) to throttle invocations by.").
+				Default("").
+				Advanced(),
+			service.NewOutputMaxInFlightField(),
+			service.NewDurationField(lambdaoFieldTimeout).
+				Description("The maximum period of time to wait before abandoning an invocation.").
+				Default("5s").
+				Advanced(),
+			service.NewIntField(lambdaoFieldRetries).
+				Description("The maximum number of retry attempts for each message.").
+				Default(3).
+				Advanced(),
+			service.NewBatchPolicyField(lambdaoFieldBatching),
+		).
+		Fields(config.SessionFields()...).
+		Example("Batched Events", "Batches of messages are archived into JSON arrays so that each invocation processes up to 100 events.", `
+output:
+  aws_lambda:
+    function: process_events
+    batching:
+      count: 100
+      period: 1s
+      processors:
+        - archive:
+            format: json_array
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("aws_lambda", lambdaoOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(lambdaoFieldBatching); err != nil {
+				return
+			}
+			out, err = newLambdaWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type lambdaWriter struct {
+	aconf  aws.Config
+	client *lambdaClient
+}
+
+func newLambdaWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*lambdaWriter, error) {
+	function, err := conf.FieldString(lambdaoFieldFunction)
+	if err != nil {
+		return nil, err
+	}
+	invocationType, err := conf.FieldString(lambdaFieldInvocationType)
+	if err != nil {
+		return nil, err
+	}
+	rateLimit, err := conf.FieldString(lambdaoFieldRateLimit)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := conf.FieldDuration(lambdaoFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	numRetries, err := conf.FieldInt(lambdaoFieldRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &lambdaWriter{}
+	if l.aconf, err = GetSession(context.TODO(), conf); err != nil {
+		return nil, err
+	}
+	if l.client, err = newLambdaClient(nil, function, types.InvocationType(invocationType), numRetries, rateLimit, timeout, mgr); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *lambdaWriter) Connect(ctx context.Context) error {
+	if l.client.lambda != nil {
+		return nil
+	}
+	l.client.lambda = lambda.NewFromConfig(l.aconf)
+	return nil
+}
+
+func (l *lambdaWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if l.client.lambda == nil {
+		return service.ErrNotConnected
+	}
+
+	return batch.WalkWithBatchedErrors(func(i int, msg *service.Message) error {
+		// Invocations replace the contents of the message with the response,
+		// and therefore we work on a copy.
+		msg = msg.Copy()
+		msg.MetaDelete("lambda_function_error")
+		if err := l.client.InvokeV2(msg); err != nil {
+			return err
+		}
+		if fnErr, exists := msg.MetaGet("lambda_function_error"); exists {
+			errMsg, _ := msg.AsBytes()
+			return fmt.Errorf("lambda function '%v' failed due to %v: %s", l.client.function, fnErr, errMsg)
+		}
+		return nil
+	})
+}
+
+func (l *lambdaWriter) Close(ctx context.Context) error {
+	return nil
+}
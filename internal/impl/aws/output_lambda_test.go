@@ -0,0 +1,63 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestLambdaOutput(t *testing.T) {
+	pConf, err := lambdaoOutputSpec().ParseYAML(`
+function: foofn
+invocation_type: RequestResponse
+region: us-east-1
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newLambdaWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	w.client.lambda = &mockLambda{
+		fn: func(ii *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+			require.Equal(t, "foofn", *ii.FunctionName)
+			require.Equal(t, types.InvocationTypeRequestResponse, ii.InvocationType)
+			if string(ii.Payload) == "bad" {
+				return &lambda.InvokeOutput{
+					FunctionError: aws.String("Unhandled"),
+					Payload:       []byte(`{"errorMessage":"nope"}`),
+				}, nil
+			}
+			return &lambda.InvokeOutput{Payload: []byte("ignored")}, nil
+		},
+	}
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("good")),
+		service.NewMessage([]byte("bad")),
+	}
+	err = w.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 1, bErr.IndexedErrors())
+
+	var failed []string
+	bErr.WalkMessages(func(i int, m *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+		return true
+	})
+	assert.Equal(t, []string{`lambda function 'foofn' failed due to Unhandled: {"errorMessage":"nope"}`}, failed)
+
+	b, _ := batch[0].AsBytes()
+	assert.Equal(t, "good", string(b))
+}
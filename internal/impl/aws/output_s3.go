@@ -13,7 +13,9 @@ import (
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gofrs/uuid"
 
+	"github.com/benthosdev/benthos/v4/internal/batchformat"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
@@ -63,6 +65,7 @@ type s3oConfig struct {
 	KMSKeyID                string
 	ServerSideEncryption    string
 	UsePathStyle            bool
+	Format                  *batchformat.Encoder
 
 	aconf aws.Config
 }
@@ -126,6 +129,9 @@ func s3oConfigFromParsed(pConf *service.ParsedConfig) (conf s3oConfig, err error
 	if conf.ServerSideEncryption, err = pConf.FieldString(s3oFieldServerSideEncryption); err != nil {
 		return
 	}
+	if conf.Format, err = batchformat.FromParsed(pConf); err != nil {
+		return
+	}
 	if conf.aconf, err = GetSession(context.TODO(), pConf); err != nil {
 		return
 	}
@@ -145,6 +151,23 @@ In order to have a different path for each object you should use function interp
 
 Metadata fields on messages will be sent as headers, in order to mutate these values (or remove them) check out the [metadata docs](/docs/configuration/metadata).
 
+### Result Metadata
+
+Once a message has been uploaded the following [result metadata](/docs/configuration/metadata#result-metadata) fields are added to it:
+
+`+"```text"+`
+- s3_bucket
+- s3_key
+- s3_version_id (when the bucket is versioned)
+- s3_etag
+`+"```"+`
+
+The fields `+"`s3_version_id` and `s3_etag`"+` are not added to messages written within a [`+"`two_phase_commit`"+` output](/docs/components/outputs/two_phase_commit).
+
+### Staging
+
+When written within a `+"`two_phase_commit`"+` output each message is uploaded to a temporary object with the key of the message suffixed with `+"`.benthos-staging-`"+` and a random identifier. Once the write is committed the temporary object is copied to the key of the message and deleted, and when the write is aborted the temporary object is deleted. Since objects are copied with a single request staged objects cannot exceed 5GB.
+
 ### Tags
 
 The tags field allows you to specify key/value pairs to attach to objects as tags, where the values support [interpolation functions](/docs/configuration/interpolation#bloblang-queries):
@@ -196,6 +219,21 @@ output:
       processors:
         - archive:
             format: json_array
+`+"```"+`
+
+Batches can also be written in a structured file format with the `+"`format`"+` field, for example as gzip compressed CSV files with a header row:
+
+`+"```yaml"+`
+output:
+  aws_s3:
+    bucket: TODO
+    path: ${!count("files")}-${!timestamp_unix_nano()}.csv.gz
+    format:
+      type: csv
+      compression: gzip
+    batching:
+      count: 100
+      period: 10s
 `+"```"+``)).
 		Fields(
 			service.NewStringField(s3oFieldBucket).
@@ -262,6 +300,7 @@ output:
 				Description("The maximum period to wait on an upload before abandoning it and reattempting.").
 				Advanced().
 				Default("5s"),
+			batchformat.ConfigField(),
 			service.NewBatchPolicyField(s3oFieldBatching),
 		).
 		Fields(config.SessionFields()...)
@@ -290,6 +329,7 @@ func init() {
 
 type amazonS3Writer struct {
 	conf     s3oConfig
+	client   *s3.Client
 	uploader *manager.Uploader
 	log      *service.Logger
 }
@@ -307,10 +347,10 @@ func (a *amazonS3Writer) Connect(ctx context.Context) error {
 		return nil
 	}
 
-	client := s3.NewFromConfig(a.conf.aconf, func(o *s3.Options) {
+	a.client = s3.NewFromConfig(a.conf.aconf, func(o *s3.Options) {
 		o.UsePathStyle = a.conf.UsePathStyle
 	})
-	a.uploader = manager.NewUploader(client)
+	a.uploader = manager.NewUploader(a.client)
 	return nil
 }
 
@@ -319,6 +359,31 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 		return component.ErrNotConnected
 	}
 
+	if a.conf.Format == nil {
+		return a.writeBatch(wctx, msg)
+	}
+
+	fMsg, err := a.conf.Format.Encode(msg)
+	if err != nil {
+		return err
+	}
+	if err := a.writeBatch(wctx, service.MessageBatch{fMsg}); err != nil {
+		return err
+	}
+
+	// The whole batch is written as a single object, and therefore the result
+	// metadata of that object applies to each message of the batch.
+	for _, m := range msg {
+		for _, k := range []string{"s3_bucket", "s3_key", "s3_version_id", "s3_etag"} {
+			if v, exists := fMsg.MetaGetMut(k); exists {
+				m.SetResultMetadata(k, v)
+			}
+		}
+	}
+	return nil
+}
+
+func (a *amazonS3Writer) writeBatch(wctx context.Context, msg service.MessageBatch) error {
 	ctx, cancel := context.WithTimeout(wctx, a.conf.Timeout)
 	defer cancel()
 
@@ -425,13 +490,68 @@ func (a *amazonS3Writer) WriteBatch(wctx context.Context, msg service.MessageBat
 			uploadInput.ServerSideEncryption = types.ServerSideEncryption(a.conf.ServerSideEncryption)
 		}
 
-		if _, err := a.uploader.Upload(ctx, uploadInput); err != nil {
+		staging, staged := service.OutputStagingFromContext(m.Context())
+		if staged {
+			uploadInput.Key = aws.String(s3StagingKey(key))
+		}
+
+		res, err := a.uploader.Upload(ctx, uploadInput)
+		if err != nil {
 			return err
 		}
+
+		m.SetResultMetadata("s3_bucket", a.conf.Bucket)
+		m.SetResultMetadata("s3_key", key)
+		if staged {
+			a.stage(staging, uploadInput, key)
+			return nil
+		}
+		if res.VersionID != nil {
+			m.SetResultMetadata("s3_version_id", *res.VersionID)
+		}
+		if res.ETag != nil {
+			m.SetResultMetadata("s3_etag", *res.ETag)
+		}
 		return nil
 	})
 }
 
+// s3StagingKey returns the key of a temporary object that a message is
+// uploaded to when its write is staged.
+func s3StagingKey(key string) string {
+	return key + ".benthos-staging-" + uuid.Must(uuid.NewV4()).String()
+}
+
+// stage registers an object uploaded to a temporary key with a staging, where
+// committing copies it to its target key and aborting deletes it.
+func (a *amazonS3Writer) stage(staging *service.OutputStaging, uploaded *s3.PutObjectInput, key string) {
+	client, bucket, stagingKey := a.client, a.conf.Bucket, *uploaded.Key
+	deleteStaged := func(ctx context.Context) error {
+		if _, err := client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &bucket,
+			Key:    &stagingKey,
+		}); err != nil {
+			return fmt.Errorf("failed to delete staged object %v: %w", stagingKey, err)
+		}
+		return nil
+	}
+
+	staging.Stage(func(ctx context.Context) error {
+		// Properties that are not carried over by a copy are set again.
+		if _, err := client.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:               &bucket,
+			Key:                  &key,
+			CopySource:           aws.String(url.PathEscape(bucket + "/" + stagingKey)),
+			StorageClass:         uploaded.StorageClass,
+			ServerSideEncryption: uploaded.ServerSideEncryption,
+			SSEKMSKeyId:          uploaded.SSEKMSKeyId,
+		}); err != nil {
+			return fmt.Errorf("failed to copy staged object %v: %w", stagingKey, err)
+		}
+		return deleteStaged(ctx)
+	}, deleteStaged)
+}
+
 func (a *amazonS3Writer) Close(context.Context) error {
 	return nil
 }
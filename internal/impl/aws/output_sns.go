@@ -2,6 +2,7 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"sort"
@@ -22,6 +23,9 @@ import (
 const (
 	// SNS Output Fields
 	snsoFieldTopicARN        = "topic_arn"
+	snsoFieldTargetARN       = "target_arn"
+	snsoFieldPhoneNumber     = "phone_number"
+	snsoFieldMessageStruct   = "message_structure"
 	snsoFieldMessageGroupID  = "message_group_id"
 	snsoFieldMessageDedupeID = "message_deduplication_id"
 	snsoFieldMetadata        = "metadata"
@@ -30,6 +34,9 @@ const (
 
 type snsoConfig struct {
 	TopicArn               string
+	TargetArn              *service.InterpolatedString
+	PhoneNumber            *service.InterpolatedString
+	MessageStructure       string
 	MessageGroupID         *service.InterpolatedString
 	MessageDeduplicationID *service.InterpolatedString
 	Timeout                time.Duration
@@ -42,6 +49,29 @@ func snsoConfigFromParsed(pConf *service.ParsedConfig) (conf snsoConfig, err err
 	if conf.TopicArn, err = pConf.FieldString(snsoFieldTopicARN); err != nil {
 		return
 	}
+	destinations := 0
+	if conf.TopicArn != "" {
+		destinations++
+	}
+	if pConf.Contains(snsoFieldTargetARN) {
+		if conf.TargetArn, err = pConf.FieldInterpolatedString(snsoFieldTargetARN); err != nil {
+			return
+		}
+		destinations++
+	}
+	if pConf.Contains(snsoFieldPhoneNumber) {
+		if conf.PhoneNumber, err = pConf.FieldInterpolatedString(snsoFieldPhoneNumber); err != nil {
+			return
+		}
+		destinations++
+	}
+	if destinations != 1 {
+		err = errors.New("exactly one of topic_arn, target_arn or phone_number must be set")
+		return
+	}
+	if conf.MessageStructure, err = pConf.FieldString(snsoFieldMessageStruct); err != nil {
+		return
+	}
 	if pConf.Contains(snsoFieldMessageGroupID) {
 		if conf.MessageGroupID, err = pConf.FieldInterpolatedString(snsoFieldMessageGroupID); err != nil {
 			return
@@ -69,14 +99,38 @@ func snsoOutputSpec() *service.ConfigSpec {
 		Stable().
 		Version("3.36.0").
 		Categories("Services", "AWS").
-		Summary(`Sends messages to an AWS SNS topic.`).
+		Summary(`Sends messages to an AWS SNS topic, mobile push endpoint or phone number.`).
 		Description(output.Description(true, false, `
+### Destinations
+
+Messages are published to the topic set with `+"`topic_arn`"+` by default. Alternatively, messages can be sent directly to a mobile device by setting `+"`target_arn`"+` to the ARN of a [platform application endpoint](https://docs.aws.amazon.com/sns/latest/dg/mobile-platform-endpoint.html), which delivers push notifications via services such as Firebase Cloud Messaging (FCM) and Apple Push Notification Service (APNs), or sent as SMS messages by setting `+"`phone_number`"+`. Both of these fields support interpolation functions, allowing the destination to be resolved from each message.
+
+When `+"`message_structure`"+` is set to `+"`json`"+` the contents of each message must be a JSON object containing a `+"`default`"+` message and optionally messages for specific protocols or push platforms.
+
+Metadata is sent as message attributes and is therefore available to subscribers of a topic, whereas the delivery status of push notifications and SMS messages can be tracked by enabling [delivery status logging](https://docs.aws.amazon.com/sns/latest/dg/sns-msg-status.html) for the platform application or account.
+
 ### Credentials
 
 By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`)).
 		Fields(
 			service.NewStringField(snsoFieldTopicARN).
-				Description("The topic to publish to."),
+				Description("The topic to publish to.").
+				Default(""),
+			service.NewInterpolatedStringField(snsoFieldTargetARN).
+				Description("The ARN of a mobile platform endpoint to send push notifications to, which can be set instead of a topic.").
+				Example(`${! @device_endpoint_arn }`).
+				Version("4.28.0").
+				Optional(),
+			service.NewInterpolatedStringField(snsoFieldPhoneNumber).
+				Description("A phone number in E.164 format to send SMS messages to, which can be set instead of a topic.").
+				Example(`${! this.phone }`).
+				Version("4.28.0").
+				Optional(),
+			service.NewStringEnumField(snsoFieldMessageStruct, "", "json").
+				Description("Set to `json` in order to send a different message to each protocol or push platform.").
+				Version("4.28.0").
+				Default("").
+				Advanced(),
 			service.NewInterpolatedStringField(snsoFieldMessageGroupID).
 				Description("An optional group ID to set for messages.").
 				Version("3.60.0").
@@ -94,7 +148,20 @@ By default Benthos will use a shared credentials file when connecting to AWS ser
 				Advanced().
 				Default("5s"),
 		).
-		Fields(config.SessionFields()...)
+		Fields(config.SessionFields()...).
+		Example("Mobile Push Notifications", "Push notifications are sent to the platform endpoint of each device, with a custom payload for Firebase Cloud Messaging.", `
+pipeline:
+  processors:
+    - mapping: |
+        meta endpoint_arn = this.device.endpoint_arn
+        root.default = this.text
+        root.GCM = { "notification": { "title": this.title, "body": this.text } }.format_json(no_indent: true)
+
+output:
+  aws_sns:
+    target_arn: ${! @endpoint_arn }
+    message_structure: json
+`)
 }
 
 func init() {
@@ -115,9 +182,13 @@ func init() {
 	}
 }
 
+type snsAPI interface {
+	Publish(context.Context, *sns.PublishInput, ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
 type snsWriter struct {
 	conf snsoConfig
-	sns  *sns.Client
+	sns  snsAPI
 	log  *service.Logger
 }
 
@@ -214,12 +285,30 @@ func (a *snsWriter) Write(wctx context.Context, msg *service.Message) error {
 		return err
 	}
 	message := &sns.PublishInput{
-		TopicArn:               aws.String(a.conf.TopicArn),
 		Message:                aws.String(string(mBytes)),
 		MessageAttributes:      attrs.attrMap,
 		MessageGroupId:         attrs.groupID,
 		MessageDeduplicationId: attrs.dedupeID,
 	}
+	switch {
+	case a.conf.TargetArn != nil:
+		targetArn, err := a.conf.TargetArn.TryString(msg)
+		if err != nil {
+			return fmt.Errorf("target arn interpolation: %w", err)
+		}
+		message.TargetArn = aws.String(targetArn)
+	case a.conf.PhoneNumber != nil:
+		phoneNumber, err := a.conf.PhoneNumber.TryString(msg)
+		if err != nil {
+			return fmt.Errorf("phone number interpolation: %w", err)
+		}
+		message.PhoneNumber = aws.String(phoneNumber)
+	default:
+		message.TopicArn = aws.String(a.conf.TopicArn)
+	}
+	if a.conf.MessageStructure != "" {
+		message.MessageStructure = aws.String(a.conf.MessageStructure)
+	}
 	_, err = a.sns.Publish(ctx, message)
 	return err
 }
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type mockSNS struct {
+	inputs []*sns.PublishInput
+}
+
+func (m *mockSNS) Publish(ctx context.Context, in *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	m.inputs = append(m.inputs, in)
+	return &sns.PublishOutput{MessageId: aws.String("foo")}, nil
+}
+
+func testSNSWriter(t *testing.T, yamlStr string) (*snsWriter, *mockSNS) {
+	t.Helper()
+
+	pConf, err := snsoOutputSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	conf, err := snsoConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	w, err := newSNSWriter(conf, service.MockResources())
+	require.NoError(t, err)
+
+	mock := &mockSNS{}
+	w.sns = mock
+	return w, mock
+}
+
+func TestSNSWriteDestinations(t *testing.T) {
+	tCtx := context.Background()
+
+	w, mock := testSNSWriter(t, `
+topic_arn: arn:aws:sns:us-east-1:000000000000:foo
+region: us-east-1
+`)
+	require.NoError(t, w.Write(tCtx, service.NewMessage([]byte("hello"))))
+
+	w2, mock2 := testSNSWriter(t, `
+target_arn: ${! @endpoint }
+message_structure: json
+region: us-east-1
+`)
+	msg := service.NewMessage([]byte(`{"default":"hello"}`))
+	msg.MetaSetMut("endpoint", "arn:aws:sns:us-east-1:000000000000:endpoint/GCM/app/device")
+	require.NoError(t, w2.Write(tCtx, msg))
+
+	w3, mock3 := testSNSWriter(t, `
+phone_number: ${! this.phone }
+region: us-east-1
+`)
+	require.NoError(t, w3.Write(tCtx, service.NewMessage([]byte(`{"phone":"+15551234567"}`))))
+
+	require.Len(t, mock.inputs, 1)
+	assert.Equal(t, "arn:aws:sns:us-east-1:000000000000:foo", aws.ToString(mock.inputs[0].TopicArn))
+	assert.Nil(t, mock.inputs[0].TargetArn)
+	assert.Nil(t, mock.inputs[0].MessageStructure)
+	assert.Equal(t, "hello", aws.ToString(mock.inputs[0].Message))
+
+	require.Len(t, mock2.inputs, 1)
+	assert.Nil(t, mock2.inputs[0].TopicArn)
+	assert.Equal(t, "arn:aws:sns:us-east-1:000000000000:endpoint/GCM/app/device", aws.ToString(mock2.inputs[0].TargetArn))
+	assert.Equal(t, "json", aws.ToString(mock2.inputs[0].MessageStructure))
+
+	require.Len(t, mock3.inputs, 1)
+	assert.Nil(t, mock3.inputs[0].TopicArn)
+	assert.Equal(t, "+15551234567", aws.ToString(mock3.inputs[0].PhoneNumber))
+}
+
+func TestSNSConfigDestinationValidation(t *testing.T) {
+	for _, yamlStr := range []string{
+		`region: us-east-1`,
+		`
+topic_arn: foo
+target_arn: bar
+region: us-east-1
+`,
+	} {
+		pConf, err := snsoOutputSpec().ParseYAML(yamlStr, nil)
+		require.NoError(t, err)
+
+		_, err = snsoConfigFromParsed(pConf)
+		require.Error(t, err)
+	}
+}
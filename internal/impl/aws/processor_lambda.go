@@ -9,6 +9,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 
 	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -52,6 +53,14 @@ output:
           resource: somewhere_else
 `+"```"+`
 
+### Asynchronous Invocations
+
+When the `+"`invocation_type`"+` is set to `+"`Event`"+` the function is invoked asynchronously, in which case the contents of messages are unchanged and no function errors are reported. In order to invoke functions asynchronously as the final step of a pipeline use the `+"[`aws_lambda` output](/docs/components/outputs/aws_lambda)"+` instead.
+
+### Payload Limits
+
+Messages that exceed the [payload limit](https://docs.aws.amazon.com/lambda/latest/dg/gettingstarted-limits.html) of the invocation type, which is 6MB for `+"`RequestResponse`"+` and 256KB for `+"`Event`"+` invocations, are flagged as having failed without the function being invoked.
+
 ### Credentials
 
 By default Benthos will use a shared credentials file when connecting to AWS services. It's also possible to set them explicitly at the component level, allowing you to transfer data across accounts. You can find out more [in this document](/docs/guides/cloud/aws).`).
@@ -76,6 +85,7 @@ pipeline:
 			Default(false)).
 		Field(service.NewStringField("function").
 			Description("The function to invoke.")).
+		Field(lambdaInvocationTypeField().Default(string(types.InvocationTypeRequestResponse))).
 		Field(service.NewStringField("rate_limit").
 			Description("An optional [`rate_limit`](/docs/components/rate_limits/about) to throttle invocations by.").
 			Default("").
@@ -112,6 +122,11 @@ pipeline:
 				return nil, err
 			}
 
+			invocationType, err := conf.FieldString(lambdaFieldInvocationType)
+			if err != nil {
+				return nil, err
+			}
+
 			numRetries, err := conf.FieldInt("retries")
 			if err != nil {
 				return nil, err
@@ -127,7 +142,7 @@ pipeline:
 				return nil, err
 			}
 
-			return newLambdaProc(lambda.NewFromConfig(aconf), parallel, function, numRetries, rateLimit, timeout, mgr)
+			return newLambdaProc(lambda.NewFromConfig(aconf), parallel, function, types.InvocationType(invocationType), numRetries, rateLimit, timeout, mgr)
 		})
 	if err != nil {
 		panic(err)
@@ -136,6 +151,28 @@ pipeline:
 
 //------------------------------------------------------------------------------
 
+const lambdaFieldInvocationType = "invocation_type"
+
+func lambdaInvocationTypeField() *service.ConfigField {
+	return service.NewStringAnnotatedEnumField(lambdaFieldInvocationType, map[string]string{
+		string(types.InvocationTypeRequestResponse): "Invoke the function synchronously and wait for the response.",
+		string(types.InvocationTypeEvent):           "Invoke the function asynchronously, where the event is queued by Lambda and the response is not awaited.",
+		string(types.InvocationTypeDryRun):          "Validate the parameters of the invocation without invoking the function.",
+	}).
+		Description("The type of invocation to perform.").
+		Version("4.28.0").
+		Advanced()
+}
+
+// lambdaPayloadLimit returns the maximum size of a payload for an invocation
+// type, see https://docs.aws.amazon.com/lambda/latest/dg/gettingstarted-limits.html
+func lambdaPayloadLimit(invocationType types.InvocationType) int {
+	if invocationType == types.InvocationTypeEvent {
+		return 256 * 1024
+	}
+	return 6 * 1024 * 1024
+}
+
 type lambdaAPI interface {
 	Invoke(context.Context, *lambda.InvokeInput, ...func(*lambda.Options)) (*lambda.InvokeOutput, error)
 }
@@ -152,6 +189,7 @@ func newLambdaProc(
 	lambda lambdaAPI,
 	parallel bool,
 	function string,
+	invocationType types.InvocationType,
 	numRetries int,
 	rateLimit string,
 	timeout time.Duration,
@@ -163,7 +201,7 @@ func newLambdaProc(
 		parallel:     parallel,
 	}
 	var err error
-	if l.client, err = newLambdaClient(lambda, function, numRetries, rateLimit, timeout, mgr); err != nil {
+	if l.client, err = newLambdaClient(lambda, function, invocationType, numRetries, rateLimit, timeout, mgr); err != nil {
 		return nil, err
 	}
 	return l, nil
@@ -212,28 +250,31 @@ type lambdaClient struct {
 	log *service.Logger
 	mgr *service.Resources
 
-	function  string
-	retries   int
-	rateLimit string
-	timeout   time.Duration
+	function       string
+	invocationType types.InvocationType
+	retries        int
+	rateLimit      string
+	timeout        time.Duration
 }
 
 func newLambdaClient(
 	lambda lambdaAPI,
 	function string,
+	invocationType types.InvocationType,
 	numRetries int,
 	rateLimit string,
 	timeout time.Duration,
 	mgr *service.Resources,
 ) (*lambdaClient, error) {
 	l := lambdaClient{
-		lambda:    lambda,
-		log:       mgr.Logger(),
-		mgr:       mgr,
-		function:  function,
-		retries:   numRetries,
-		rateLimit: rateLimit,
-		timeout:   timeout,
+		lambda:         lambda,
+		log:            mgr.Logger(),
+		mgr:            mgr,
+		function:       function,
+		invocationType: invocationType,
+		retries:        numRetries,
+		rateLimit:      rateLimit,
+		timeout:        timeout,
 	}
 	if function == "" {
 		return nil, errors.New("lambda function must not be empty")
@@ -275,26 +316,32 @@ func (l *lambdaClient) waitForAccess(ctx context.Context) bool {
 }
 
 func (l *lambdaClient) InvokeV2(p *service.Message) error {
+	mBytes, err := p.AsBytes()
+	if err != nil {
+		return err
+	}
+	if limit := lambdaPayloadLimit(l.invocationType); len(mBytes) > limit {
+		return fmt.Errorf("payload of %v bytes exceeds the limit of %v bytes for %v invocations", len(mBytes), limit, l.invocationType)
+	}
+
 	remainingRetries := l.retries
 	for {
 		l.waitForAccess(context.Background())
 
-		mBytes, err := p.AsBytes()
-		if err != nil {
-			return err
-		}
-
 		ctx, done := context.WithTimeout(context.Background(), l.timeout)
 		result, err := l.lambda.Invoke(ctx, &lambda.InvokeInput{
-			FunctionName: aws.String(l.function),
-			Payload:      mBytes,
+			FunctionName:   aws.String(l.function),
+			InvocationType: l.invocationType,
+			Payload:        mBytes,
 		})
 		done()
 		if err == nil {
 			if result.FunctionError != nil {
 				p.MetaSet("lambda_function_error", *result.FunctionError)
 			}
-			p.SetBytes(result.Payload)
+			if l.invocationType == types.InvocationTypeRequestResponse {
+				p.SetBytes(result.Payload)
+			}
 			return nil
 		}
 
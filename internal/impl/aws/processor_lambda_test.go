@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -29,7 +30,7 @@ func TestLambdaErrors(t *testing.T) {
 		},
 	}
 
-	p, err := newLambdaProc(mock, false, "foofn", 3, "", time.Second, service.MockResources())
+	p, err := newLambdaProc(mock, false, "foofn", types.InvocationTypeRequestResponse, 3, "", time.Second, service.MockResources())
 	require.NoError(t, err)
 
 	bCtx := context.Background()
@@ -49,7 +50,7 @@ func TestLambdaErrors(t *testing.T) {
 	assert.EqualError(t, outBatches[0][1].GetError(), "meow bar")
 	assert.EqualError(t, outBatches[0][2].GetError(), "meow baz")
 
-	p, err = newLambdaProc(mock, true, "foofn", 3, "", time.Second, service.MockResources())
+	p, err = newLambdaProc(mock, true, "foofn", types.InvocationTypeRequestResponse, 3, "", time.Second, service.MockResources())
 	require.NoError(t, err)
 
 	outBatches, err = p.ProcessBatch(bCtx, inBatch)
@@ -73,7 +74,7 @@ func TestLambdaMutations(t *testing.T) {
 		},
 	}
 
-	p, err := newLambdaProc(mock, false, "foofn", 3, "", time.Second, service.MockResources())
+	p, err := newLambdaProc(mock, false, "foofn", types.InvocationTypeRequestResponse, 3, "", time.Second, service.MockResources())
 	require.NoError(t, err)
 
 	bCtx := context.Background()
@@ -104,7 +105,7 @@ func TestLambdaMutations(t *testing.T) {
 	b, _ = inBatch[2].AsBytes()
 	assert.Equal(t, "baz", string(b))
 
-	p, err = newLambdaProc(mock, true, "foofn", 3, "", time.Second, service.MockResources())
+	p, err = newLambdaProc(mock, true, "foofn", types.InvocationTypeRequestResponse, 3, "", time.Second, service.MockResources())
 	require.NoError(t, err)
 
 	outBatches, err = p.ProcessBatch(bCtx, inBatch.Copy())
@@ -128,3 +129,31 @@ func TestLambdaMutations(t *testing.T) {
 	b, _ = inBatch[2].AsBytes()
 	assert.Equal(t, "baz", string(b))
 }
+
+func TestLambdaAsync(t *testing.T) {
+	var invoked []string
+	mock := &mockLambda{
+		fn: func(ii *lambda.InvokeInput) (*lambda.InvokeOutput, error) {
+			require.Equal(t, types.InvocationTypeEvent, ii.InvocationType)
+			invoked = append(invoked, string(ii.Payload))
+			return &lambda.InvokeOutput{StatusCode: 202}, nil
+		},
+	}
+
+	p, err := newLambdaProc(mock, false, "foofn", types.InvocationTypeEvent, 3, "", time.Second, service.MockResources())
+	require.NoError(t, err)
+
+	outBatches, err := p.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage(make([]byte, 256*1024+1)),
+	})
+	require.NoError(t, err)
+	require.Len(t, outBatches, 1)
+	require.Len(t, outBatches[0], 2)
+
+	b, _ := outBatches[0][0].AsBytes()
+	assert.Equal(t, "foo", string(b))
+	assert.NoError(t, outBatches[0][0].GetError())
+	assert.EqualError(t, outBatches[0][1].GetError(), "payload of 262145 bytes exceeds the limit of 262144 bytes for Event invocations")
+	assert.Equal(t, []string{"foo"}, invoked)
+}
@@ -0,0 +1,134 @@
+package azure
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	// Blob Storage Cache Fields
+	bscaFieldContainer   = "container"
+	bscaFieldContentType = "content_type"
+)
+
+func bscaSpec() *service.ConfigSpec {
+	return azureComponentSpec(true).
+		Beta().
+		Version("4.28.0").
+		Summary(`Stores each item in an Azure Blob Storage container as a block blob, where an item ID is the name of the blob within the container.`).
+		Description(`
+Supports multiple authentication methods but only one of the following is required:
+- `+"`storage_connection_string`"+`
+- `+"`storage_account` and `storage_access_key`"+`
+- `+"`storage_account` and `storage_sas_token`"+`
+- `+"`storage_account` to access via [DefaultAzureCredential](https://pkg.go.dev/github.com/Azure/azure-sdk-for-go/sdk/azidentity#DefaultAzureCredential)"+`
+
+If multiple are set then the `+"`storage_connection_string`"+` is given priority.
+
+The `+"`add`"+` operation uploads blobs on the condition that they do not already exist, and is therefore atomic. Time to live (TTL) values are not supported, consider a [lifecycle management policy](https://learn.microsoft.com/en-us/azure/storage/blobs/lifecycle-management-overview) for the container instead.`).
+		Fields(
+			service.NewStringField(bscaFieldContainer).
+				Description("The container to store items in, which must already exist."),
+			service.NewStringField(bscaFieldContentType).
+				Description("The content type to set for each item.").
+				Default("application/octet-stream"),
+		)
+}
+
+func init() {
+	err := service.RegisterCache("azure_blob_storage", bscaSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newAzureBlobStorageCacheFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type azureBlobStorageCache struct {
+	client      *azblob.Client
+	container   string
+	contentType string
+}
+
+func newAzureBlobStorageCacheFromParsed(conf *service.ParsedConfig) (*azureBlobStorageCache, error) {
+	c := &azureBlobStorageCache{}
+
+	var err error
+	if c.container, err = conf.FieldString(bscaFieldContainer); err != nil {
+		return nil, err
+	}
+	if c.contentType, err = conf.FieldString(bscaFieldContentType); err != nil {
+		return nil, err
+	}
+
+	var containerSASToken bool
+	if c.client, containerSASToken, err = blobStorageClientFromParsed(conf, c.container); err != nil {
+		return nil, err
+	}
+	if containerSASToken {
+		// if using a container SAS token, the container is already implicit
+		c.container = ""
+	}
+	return c, nil
+}
+
+func (c *azureBlobStorageCache) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := c.client.DownloadStream(ctx, c.container, key, nil)
+	if err != nil {
+		if isErrorCode(err, bloberror.BlobNotFound) {
+			return nil, service.ErrKeyNotFound
+		}
+		return nil, err
+	}
+
+	body := res.NewRetryReader(ctx, nil)
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (c *azureBlobStorageCache) upload(ctx context.Context, key string, value []byte, conditions *blob.AccessConditions) error {
+	_, err := c.client.UploadBuffer(ctx, c.container, key, value, &azblob.UploadBufferOptions{
+		HTTPHeaders: &blob.HTTPHeaders{
+			BlobContentType: &c.contentType,
+		},
+		AccessConditions: conditions,
+	})
+	return err
+}
+
+func (c *azureBlobStorageCache) Set(ctx context.Context, key string, value []byte, _ *time.Duration) error {
+	return c.upload(ctx, key, value, nil)
+}
+
+func (c *azureBlobStorageCache) Add(ctx context.Context, key string, value []byte, _ *time.Duration) error {
+	err := c.upload(ctx, key, value, &blob.AccessConditions{
+		ModifiedAccessConditions: &blob.ModifiedAccessConditions{
+			IfNoneMatch: to.Ptr(azcore.ETagAny),
+		},
+	})
+	if isErrorCode(err, bloberror.BlobAlreadyExists) || isErrorCode(err, bloberror.ConditionNotMet) {
+		return service.ErrKeyAlreadyExists
+	}
+	return err
+}
+
+func (c *azureBlobStorageCache) Delete(ctx context.Context, key string) error {
+	if _, err := c.client.DeleteBlob(ctx, c.container, key, nil); err != nil && !isErrorCode(err, bloberror.BlobNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (c *azureBlobStorageCache) Close(ctx context.Context) error {
+	return nil
+}
@@ -3,6 +3,7 @@ package beanstalkd
 import (
 	"context"
 	"errors"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,14 +13,52 @@ import (
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+const (
+	biFieldAddress       = "address"
+	biFieldTubes         = "tubes"
+	biFieldTouchInterval = "touch_interval"
+	biFieldReleaseDelay  = "release_delay"
+)
+
 func beanstalkdInputConfig() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Categories("Services").
 		Version("4.7.0").
 		Summary("Reads messages from a Beanstalkd queue.").
-		Field(service.NewStringField("address").
+		Description(`
+Jobs are reserved from the watched tubes and deleted once their messages are successfully processed, or released back to the queue otherwise.
+
+### Time To Run
+
+Each job is reserved for the TTR (time to run) that it was put with, and once that period has passed without the job being deleted the server releases it to other consumers. When processing a job could take longer than its TTR a ` + "`touch_interval`" + ` can be specified, in which case jobs are touched periodically until they are acknowledged in order to reset their TTR.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- beanstalkd_id
+` + "```" + `
+`).
+		Field(service.NewStringField(biFieldAddress).
 			Description("An address to connect to.").
-			Example("127.0.0.1:11300"))
+			Example("127.0.0.1:11300")).
+		Field(service.NewStringListField(biFieldTubes).
+			Description("A list of tubes to watch and reserve jobs from.").
+			Example([]string{"emails", "thumbnails"}).
+			Default([]string{"default"}).
+			Version("4.28.0")).
+		Field(service.NewDurationField(biFieldTouchInterval).
+			Description("An optional interval at which jobs are touched whilst they are being processed, which resets their TTR and prevents them from being released to other consumers. This should be lower than the TTR of the jobs consumed.").
+			Example("10s").
+			Optional().
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewDurationField(biFieldReleaseDelay).
+			Description("The delay applied to jobs that are released back to the queue after failing to be processed.").
+			Default("200ms").
+			Advanced().
+			Version("4.28.0"))
 }
 
 func init() {
@@ -35,10 +74,14 @@ func init() {
 
 type beanstalkdReader struct {
 	connection *beanstalk.Conn
+	tubeSet    *beanstalk.TubeSet
 	connMut    sync.Mutex
 
-	address string
-	log     *service.Logger
+	address       string
+	tubes         []string
+	touchInterval time.Duration
+	releaseDelay  time.Duration
+	log           *service.Logger
 }
 
 func newBeanstalkdReaderFromConfig(conf *service.ParsedConfig, log *service.Logger) (*beanstalkdReader, error) {
@@ -46,12 +89,27 @@ func newBeanstalkdReaderFromConfig(conf *service.ParsedConfig, log *service.Logg
 		log: log,
 	}
 
-	tcpAddr, err := conf.FieldString("address")
+	tcpAddr, err := conf.FieldString(biFieldAddress)
 	if err != nil {
 		return nil, err
 	}
 	bs.address = tcpAddr
 
+	if bs.tubes, err = conf.FieldStringList(biFieldTubes); err != nil {
+		return nil, err
+	}
+	if len(bs.tubes) == 0 {
+		return nil, errors.New("at least one tube must be specified")
+	}
+	if conf.Contains(biFieldTouchInterval) {
+		if bs.touchInterval, err = conf.FieldDuration(biFieldTouchInterval); err != nil {
+			return nil, err
+		}
+	}
+	if bs.releaseDelay, err = conf.FieldDuration(biFieldReleaseDelay); err != nil {
+		return nil, err
+	}
+
 	return &bs, nil
 }
 
@@ -65,6 +123,7 @@ func (bs *beanstalkdReader) Connect(ctx context.Context) error {
 	}
 
 	bs.connection = conn
+	bs.tubeSet = beanstalk.NewTubeSet(conn, bs.tubes...)
 	return nil
 }
 
@@ -76,17 +135,23 @@ func (bs *beanstalkdReader) disconnect() error {
 		if err := bs.connection.Close(); err != nil {
 			return err
 		}
+		bs.connection = nil
+		bs.tubeSet = nil
 	}
 
 	return nil
 }
 
 func (bs *beanstalkdReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
-	if bs.connection == nil {
+	bs.connMut.Lock()
+	conn, tubeSet := bs.connection, bs.tubeSet
+	bs.connMut.Unlock()
+
+	if conn == nil {
 		return nil, nil, service.ErrNotConnected
 	}
 
-	id, body, err := bs.connection.Reserve(time.Millisecond * 200)
+	id, body, err := tubeSet.Reserve(time.Millisecond * 200)
 	if err != nil {
 		if errors.Is(err, beanstalk.ErrTimeout) {
 			err = component.ErrTimeout
@@ -94,15 +159,57 @@ func (bs *beanstalkdReader) Read(ctx context.Context) (*service.Message, service
 		return nil, nil, err
 	}
 
+	var stopTouching func()
+	if bs.touchInterval > 0 {
+		stopTouching = bs.touchUntilStopped(conn, id)
+	}
+
 	msg := service.NewMessage(body)
+	msg.MetaSetMut("beanstalkd_id", strconv.FormatUint(id, 10))
 	return msg, func(ctx context.Context, res error) error {
+		if stopTouching != nil {
+			stopTouching()
+		}
 		if res == nil {
-			return bs.connection.Delete(id)
+			return conn.Delete(id)
 		}
-		return bs.connection.Release(id, 2, time.Millisecond*200)
+		return conn.Release(id, 2, bs.releaseDelay)
 	}, nil
 }
 
+// touchUntilStopped periodically touches a reserved job in order to reset its
+// TTR until the returned func is called.
+func (bs *beanstalkdReader) touchUntilStopped(conn *beanstalk.Conn, id uint64) func() {
+	stopChan := make(chan struct{})
+	doneChan := make(chan struct{})
+	go func() {
+		defer close(doneChan)
+
+		ticker := time.NewTicker(bs.touchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.Touch(id); err != nil {
+					bs.log.Warnf("Failed to touch job %v: %v", id, err)
+					return
+				}
+			case <-stopChan:
+				return
+			}
+		}
+	}()
+
+	var stopOnce sync.Once
+	return func() {
+		stopOnce.Do(func() {
+			close(stopChan)
+			<-doneChan
+		})
+	}
+}
+
 func (bs *beanstalkdReader) Close(ctx context.Context) (err error) {
 	err = bs.disconnect()
 	return
@@ -15,11 +15,14 @@ const template string = `
 output:
   beanstalkd:
     address: localhost:$PORT
+    tube: tube-$ID
     max_in_flight: $MAX_IN_FLIGHT
 
 input:
   beanstalkd:
     address: localhost:$PORT
+    tubes: [ tube-$ID ]
+    touch_interval: 1s
 `
 
 func TestIntegrationBeanstalkdOpenClose(t *testing.T) {
@@ -2,6 +2,8 @@ package beanstalkd
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"sync"
 	"time"
 
@@ -10,14 +12,43 @@ import (
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
+const (
+	boFieldAddress  = "address"
+	boFieldTube     = "tube"
+	boFieldPriority = "priority"
+	boFieldDelay    = "delay"
+	boFieldTTR      = "ttr"
+)
+
 func beanstalkdOutputConfig() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Categories("Services").
 		Version("4.7.0").
 		Summary("Write messages to a Beanstalkd queue.").
-		Field(service.NewStringField("address").
+		Description(`The ` + "`tube`" + ` field can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(service.NewStringField(boFieldAddress).
 			Description("An address to connect to.").
 			Example("127.0.0.1:11300")).
+		Field(service.NewInterpolatedStringField(boFieldTube).
+			Description("The tube to put jobs into.").
+			Example(`${! @job_type }`).
+			Default("default").
+			Version("4.28.0")).
+		Field(service.NewIntField(boFieldPriority).
+			Description("The priority of jobs, where jobs with a lower value are reserved before jobs with a higher value.").
+			Default(2).
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewDurationField(boFieldDelay).
+			Description("A delay to wait after putting a job before it becomes ready to be reserved.").
+			Default("0s").
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewDurationField(boFieldTTR).
+			Description("The TTR (time to run) of jobs, which is the period that a consumer is allowed to process a reserved job before it is released back to the queue.").
+			Default("2s").
+			Advanced().
+			Version("4.28.0")).
 		Field(service.NewIntField("max_in_flight").
 			Description("The maximum number of messages to have in flight at a given time. Increase to improve throughput.").
 			Default(64))
@@ -43,8 +74,12 @@ type beanstalkdWriter struct {
 	connection *beanstalk.Conn
 	connMut    sync.Mutex
 
-	address string
-	log     *service.Logger
+	address  string
+	tube     *service.InterpolatedString
+	priority uint32
+	delay    time.Duration
+	ttr      time.Duration
+	log      *service.Logger
 }
 
 func newBeanstalkdWriterFromConfig(conf *service.ParsedConfig, log *service.Logger) (*beanstalkdWriter, error) {
@@ -52,12 +87,30 @@ func newBeanstalkdWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 		log: log,
 	}
 
-	tcpAddr, err := conf.FieldString("address")
+	tcpAddr, err := conf.FieldString(boFieldAddress)
 	if err != nil {
 		return nil, err
 	}
 	bs.address = tcpAddr
 
+	if bs.tube, err = conf.FieldInterpolatedString(boFieldTube); err != nil {
+		return nil, err
+	}
+	priority, err := conf.FieldInt(boFieldPriority)
+	if err != nil {
+		return nil, err
+	}
+	if priority < 0 || priority > math.MaxUint32 {
+		return nil, fmt.Errorf("priority %v is outside of the range 0 to %v", priority, uint32(math.MaxUint32))
+	}
+	bs.priority = uint32(priority)
+	if bs.delay, err = conf.FieldDuration(boFieldDelay); err != nil {
+		return nil, err
+	}
+	if bs.ttr, err = conf.FieldDuration(boFieldTTR); err != nil {
+		return nil, err
+	}
+
 	return &bs, nil
 }
 
@@ -83,11 +136,16 @@ func (bs *beanstalkdWriter) Write(ctx context.Context, msg *service.Message) err
 		return service.ErrNotConnected
 	}
 
+	tube, err := bs.tube.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("tube interpolation error: %w", err)
+	}
+
 	msgBytes, err := msg.AsBytes()
 	if err != nil {
 		return err
 	}
-	_, err = conn.Put(msgBytes, 2, 0, time.Second*2)
+	_, err = beanstalk.NewTube(conn, tube).Put(msgBytes, bs.priority, bs.delay, bs.ttr)
 	return err
 }
 
@@ -99,6 +157,7 @@ func (bs *beanstalkdWriter) Close(context.Context) error {
 		if err := bs.connection.Close(); err != nil {
 			return err
 		}
+		bs.connection = nil
 	}
 	return nil
 }
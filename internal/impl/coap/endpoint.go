@@ -0,0 +1,679 @@
+package coap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"github.com/pion/dtls/v2"
+	"github.com/pion/dtls/v2/pkg/protocol"
+	"github.com/pion/dtls/v2/pkg/protocol/recordlayer"
+	"github.com/pion/logging"
+	"github.com/pion/transport/v2/udp"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// Transmission parameters from section 4.8 of RFC 7252.
+const (
+	ackTimeout       = 2 * time.Second
+	maxRetransmit    = 4
+	exchangeLifetime = 247 * time.Second
+	nonLifetime      = 145 * time.Second
+
+	// Peers without any activity are forgotten after a while, where secure
+	// sessions are kept for longer as devices typically sleep between
+	// transmissions and must perform a new handshake once forgotten.
+	plainIdleTimeout  = exchangeLifetime
+	secureIdleTimeout = time.Hour
+
+	handshakeTimeout = 30 * time.Second
+
+	maxDatagramSize = 65535
+)
+
+// dtlsCipherSuites are the cipher suites offered and accepted by DTLS
+// sessions, which are those recommended for pre-shared keys by RFC 7252.
+var dtlsCipherSuites = []dtls.CipherSuiteID{
+	dtls.TLS_PSK_WITH_AES_128_CCM_8,
+	dtls.TLS_PSK_WITH_AES_128_GCM_SHA256,
+}
+
+type endpointConfig struct {
+	// accept determines whether peers that aren't dialled can send messages,
+	// which are requests to be passed to the handler.
+	accept  bool
+	handler func(ex *exchange)
+
+	// psk returns the pre-shared key of an identity or nil when unknown,
+	// where DTLS is required of accepted peers when set.
+	psk func(identity string) []byte
+}
+
+// endpoint exchanges CoAP messages with peers over a UDP socket, or over the
+// DTLS sessions of secure peers.
+type endpoint struct {
+	// conn is the socket of plain peers, and listener accepts the sessions of
+	// secure peers.
+	conn     net.PacketConn
+	listener net.Listener
+
+	conf     endpointConfig
+	dtlsConf *dtls.Config
+	log      *service.Logger
+
+	peersMut sync.Mutex
+	peers    map[string]*peer
+
+	shutSig *shutdown.Signaller
+}
+
+func newEndpoint(conn net.PacketConn, conf endpointConfig, log *service.Logger) *endpoint {
+	e := &endpoint{
+		conn:    conn,
+		conf:    conf,
+		log:     log,
+		peers:   map[string]*peer{},
+		shutSig: shutdown.NewSignaller(),
+	}
+	go e.readLoop()
+	go e.pruneLoop()
+	return e
+}
+
+// listen creates an endpoint that accepts peers on a UDP address, where peers
+// must establish a DTLS session with a pre-shared key when the config has
+// them.
+func listen(address string, conf endpointConfig, log *service.Logger) (*endpoint, error) {
+	if conf.psk == nil {
+		conn, err := net.ListenPacket("udp", address)
+		if err != nil {
+			return nil, err
+		}
+		return newEndpoint(conn, conf, log), nil
+	}
+
+	laddr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only datagrams that begin a handshake create sessions, and therefore
+	// plain requests are ignored.
+	lc := udp.ListenConfig{
+		AcceptFilter: func(packet []byte) bool {
+			pkts, err := recordlayer.UnpackDatagram(packet)
+			if err != nil || len(pkts) == 0 {
+				return false
+			}
+			var h recordlayer.Header
+			if err := h.Unmarshal(pkts[0]); err != nil {
+				return false
+			}
+			return h.ContentType == protocol.ContentTypeHandshake
+		},
+	}
+	listener, err := lc.Listen("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &endpoint{
+		listener: listener,
+		conf:     conf,
+		dtlsConf: &dtls.Config{
+			PSK: func(identity []byte) ([]byte, error) {
+				if key := conf.psk(string(identity)); key != nil {
+					return key, nil
+				}
+				return nil, fmt.Errorf("unknown PSK identity '%s'", identity)
+			},
+			CipherSuites:  dtlsCipherSuites,
+			LoggerFactory: dtlsLoggerFactory{log: log},
+		},
+		log:     log,
+		peers:   map[string]*peer{},
+		shutSig: shutdown.NewSignaller(),
+	}
+	go e.acceptLoop()
+	go e.pruneLoop()
+	return e, nil
+}
+
+// addr returns the local address of the endpoint.
+func (e *endpoint) addr() net.Addr {
+	if e.listener != nil {
+		return e.listener.Addr()
+	}
+	return e.conn.LocalAddr()
+}
+
+func (e *endpoint) close() {
+	e.shutSig.TriggerHardStop()
+	if e.listener != nil {
+		_ = e.listener.Close()
+	} else {
+		_ = e.conn.Close()
+	}
+
+	e.peersMut.Lock()
+	for k, p := range e.peers {
+		p.close()
+		delete(e.peers, k)
+	}
+	e.peersMut.Unlock()
+}
+
+func (e *endpoint) readLoop() {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, addr, err := e.conn.ReadFrom(buf)
+		if err != nil {
+			if !e.shutSig.IsHardStopSignalled() {
+				e.log.Errorf("Failed to read datagram: %v", err)
+			}
+			return
+		}
+		data := append([]byte(nil), buf[:n]...)
+
+		e.peersMut.Lock()
+		p := e.peers[addr.String()]
+		if p == nil && e.conf.accept {
+			p = e.newPeer(addr)
+			e.peers[addr.String()] = p
+		}
+		e.peersMut.Unlock()
+
+		if p != nil {
+			p.receive(data)
+		}
+	}
+}
+
+func (e *endpoint) acceptLoop() {
+	for {
+		conn, err := e.listener.Accept()
+		if err != nil {
+			if !e.shutSig.IsHardStopSignalled() {
+				e.log.Errorf("Failed to accept DTLS session: %v", err)
+			}
+			return
+		}
+		go e.handshake(conn)
+	}
+}
+
+// handshake establishes the DTLS session of a peer that's been accepted, which
+// is performed concurrently so that peers can't stall each other.
+func (e *endpoint) handshake(conn net.Conn) {
+	ctx, done := e.shutSig.HardStopCtx(context.Background())
+	defer done()
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	session, err := dtls.ServerWithContext(ctx, conn, e.dtlsConf)
+	if err != nil {
+		e.log.Debugf("Failed DTLS handshake with %v: %v", conn.RemoteAddr(), err)
+		_ = conn.Close()
+		return
+	}
+
+	p := e.newPeer(conn.RemoteAddr())
+	p.session = session
+	p.identityName = string(session.ConnectionState().IdentityHint)
+
+	e.peersMut.Lock()
+	if e.shutSig.IsHardStopSignalled() {
+		e.peersMut.Unlock()
+		p.close()
+		return
+	}
+	e.peers[p.addr.String()] = p
+	e.peersMut.Unlock()
+
+	e.readSession(p)
+}
+
+// readSession receives the datagrams of a secure peer until its session is
+// closed.
+func (e *endpoint) readSession(p *peer) {
+	buf := make([]byte, maxDatagramSize)
+	for {
+		n, err := p.session.Read(buf)
+		if err != nil {
+			if !e.shutSig.IsHardStopSignalled() {
+				e.log.Debugf("Closing secure session with %v: %v", p.addr, err)
+			}
+			e.removePeer(p)
+			return
+		}
+		p.receive(append([]byte(nil), buf[:n]...))
+	}
+}
+
+func (e *endpoint) pruneLoop() {
+	ticker := time.NewTicker(time.Second * 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.shutSig.HardStopChan():
+			return
+		}
+
+		now := time.Now()
+		e.peersMut.Lock()
+		for k, p := range e.peers {
+			if p.prune(now) {
+				p.close()
+				delete(e.peers, k)
+			}
+		}
+		e.peersMut.Unlock()
+	}
+}
+
+func (e *endpoint) newPeer(addr net.Addr) *peer {
+	var id [2]byte
+	_, _ = rand.Read(id[:])
+	return &peer{
+		e:         e,
+		addr:      addr,
+		lastSeen:  time.Now(),
+		nextID:    binary.BigEndian.Uint16(id[:]),
+		received:  map[uint16]*receivedMessage{},
+		pending:   map[uint16]chan *message{},
+		observers: map[string]func(*message){},
+	}
+}
+
+func (e *endpoint) removePeer(p *peer) {
+	e.peersMut.Lock()
+	if e.peers[p.addr.String()] == p {
+		delete(e.peers, p.addr.String())
+	}
+	e.peersMut.Unlock()
+	p.close()
+}
+
+// dial adds a peer that requests can be sent to, establishing a DTLS session
+// with it as a client when a key is provided.
+func (e *endpoint) dial(ctx context.Context, addr net.Addr, identity string, key []byte) (*peer, error) {
+	p := e.newPeer(addr)
+	p.dialled = true
+
+	if key != nil {
+		raddr, err := net.ResolveUDPAddr("udp", addr.String())
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+		defer cancel()
+		if p.session, err = dtls.DialWithContext(ctx, "udp", raddr, &dtls.Config{
+			PSK: func([]byte) ([]byte, error) {
+				return key, nil
+			},
+			PSKIdentityHint: []byte(identity),
+			CipherSuites:    dtlsCipherSuites,
+			LoggerFactory:   dtlsLoggerFactory{log: e.log},
+		}); err != nil {
+			return nil, err
+		}
+		p.identityName = identity
+	}
+
+	e.peersMut.Lock()
+	e.peers[addr.String()] = p
+	e.peersMut.Unlock()
+
+	if p.session != nil {
+		go e.readSession(p)
+	}
+	return p, nil
+}
+
+//------------------------------------------------------------------------------
+
+// dtlsLoggerFactory passes the logs of DTLS sessions to the logger of a
+// component, where failures of sessions are already logged by the endpoint and
+// are therefore only debug logs.
+type dtlsLoggerFactory struct {
+	log *service.Logger
+}
+
+func (f dtlsLoggerFactory) NewLogger(scope string) logging.LeveledLogger {
+	return dtlsLogger{log: f.log.With("scope", scope)}
+}
+
+type dtlsLogger struct {
+	log *service.Logger
+}
+
+func (l dtlsLogger) Trace(msg string)                  { l.log.Trace(msg) }
+func (l dtlsLogger) Tracef(format string, args ...any) { l.log.Tracef(format, args...) }
+func (l dtlsLogger) Debug(msg string)                  { l.log.Trace(msg) }
+func (l dtlsLogger) Debugf(format string, args ...any) { l.log.Tracef(format, args...) }
+func (l dtlsLogger) Info(msg string)                   { l.log.Debug(msg) }
+func (l dtlsLogger) Infof(format string, args ...any)  { l.log.Debugf(format, args...) }
+func (l dtlsLogger) Warn(msg string)                   { l.log.Debug(msg) }
+func (l dtlsLogger) Warnf(format string, args ...any)  { l.log.Debugf(format, args...) }
+func (l dtlsLogger) Error(msg string)                  { l.log.Debug(msg) }
+func (l dtlsLogger) Errorf(format string, args ...any) { l.log.Debugf(format, args...) }
+
+//------------------------------------------------------------------------------
+
+type receivedMessage struct {
+	expires time.Time
+
+	// The reply to send again when the message is duplicated, which is nil
+	// while a request is being handled.
+	reply []byte
+}
+
+// peer is a remote endpoint with which messages are exchanged.
+type peer struct {
+	e       *endpoint
+	addr    net.Addr
+	dialled bool
+
+	// session is the DTLS session of a secure peer, and identityName is the
+	// identity of its pre-shared key.
+	session      *dtls.Conn
+	identityName string
+
+	mut       sync.Mutex
+	lastSeen  time.Time
+	keepUntil time.Time
+	nextID    uint16
+	received  map[uint16]*receivedMessage
+	pending   map[uint16]chan *message
+	observers map[string]func(*message)
+}
+
+// identity returns the PSK identity of a secure peer.
+func (p *peer) identity() string {
+	return p.identityName
+}
+
+// close ends the session of a secure peer.
+func (p *peer) close() {
+	if p.session != nil {
+		_ = p.session.Close()
+	}
+}
+
+// keep prevents an idle peer from being forgotten until a given time.
+func (p *peer) keep(until time.Time) {
+	p.mut.Lock()
+	p.keepUntil = until
+	p.mut.Unlock()
+}
+
+func (p *peer) prune(now time.Time) bool {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	for id, r := range p.received {
+		if now.After(r.expires) {
+			delete(p.received, id)
+		}
+	}
+	if p.dialled || len(p.observers) > 0 || now.Before(p.keepUntil) {
+		return false
+	}
+	idle := plainIdleTimeout
+	if p.session != nil {
+		idle = secureIdleTimeout
+	}
+	return now.Sub(p.lastSeen) > idle
+}
+
+func (p *peer) messageID() uint16 {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.nextID++
+	return p.nextID
+}
+
+// send sends a message to the peer, protecting it when the peer is secure.
+func (p *peer) send(m *message) error {
+	return p.sendBytes(m.marshal())
+}
+
+func (p *peer) sendBytes(b []byte) error {
+	var err error
+	if p.session != nil {
+		_, err = p.session.Write(b)
+	} else {
+		_, err = p.e.conn.WriteTo(b, p.addr)
+	}
+	return err
+}
+
+func (p *peer) receive(data []byte) {
+	p.mut.Lock()
+	p.lastSeen = time.Now()
+	p.mut.Unlock()
+	p.handleMessage(data)
+}
+
+// duplicate determines whether a confirmable or non-confirmable message has
+// already been received, sending the reply to the original when there is
+// one.
+func (p *peer) duplicate(m *message) bool {
+	p.mut.Lock()
+	r, exists := p.received[m.id]
+	if !exists {
+		lifetime := exchangeLifetime
+		if m.typ == typeNonConfirmable {
+			lifetime = nonLifetime
+		}
+		p.received[m.id] = &receivedMessage{expires: time.Now().Add(lifetime)}
+	}
+	p.mut.Unlock()
+
+	if exists && r.reply != nil {
+		_ = p.sendBytes(r.reply)
+	}
+	return exists
+}
+
+func (p *peer) handleMessage(b []byte) {
+	m, err := parseMessage(b)
+	if err != nil {
+		if len(b) >= 4 && messageType(b[0]>>4&0x03) == typeConfirmable {
+			_ = p.send(&message{typ: typeReset, id: binary.BigEndian.Uint16(b[2:])})
+		}
+		p.e.log.Debugf("Ignoring message from %v: %v", p.addr, err)
+		return
+	}
+
+	switch {
+	case m.code == codeEmpty:
+		switch m.typ {
+		case typeConfirmable:
+			// A ping, which is answered with a reset.
+			_ = p.send(&message{typ: typeReset, id: m.id})
+		case typeAcknowledgement, typeReset:
+			p.complete(m)
+		}
+
+	case m.code.isRequest():
+		if m.typ != typeConfirmable && m.typ != typeNonConfirmable {
+			return
+		}
+		if p.duplicate(m) {
+			return
+		}
+		ex := &exchange{peer: p, req: m}
+		if number, unknown := m.unknownCriticalOption(); unknown {
+			p.e.log.Debugf("Rejecting request from %v with unsupported option %v", p.addr, number)
+			ex.respond(&message{code: codeBadOption})
+			return
+		}
+		if p.e.conf.handler == nil {
+			ex.respond(&message{code: codeNotFound})
+			return
+		}
+		go p.e.conf.handler(ex)
+
+	case m.code.isResponse():
+		switch m.typ {
+		case typeAcknowledgement:
+			p.deliver(m)
+			p.complete(m)
+		case typeConfirmable, typeNonConfirmable:
+			if p.duplicate(m) {
+				return
+			}
+			p.mut.Lock()
+			_, known := p.observers[string(m.token)]
+			p.mut.Unlock()
+
+			reply := &message{typ: typeAcknowledgement, id: m.id}
+			if !known {
+				// Tells the peer to stop sending notifications.
+				reply.typ = typeReset
+			}
+			if m.typ == typeConfirmable || !known {
+				p.setReply(m.id, reply.marshal())
+				_ = p.send(reply)
+			}
+			if known {
+				p.deliver(m)
+			}
+		}
+	}
+}
+
+// setReply records the reply to a received message, which is sent again when
+// the message is duplicated.
+func (p *peer) setReply(id uint16, b []byte) {
+	p.mut.Lock()
+	if r := p.received[id]; r != nil {
+		r.reply = b
+	}
+	p.mut.Unlock()
+}
+
+// complete signals the sender of a confirmable message that it was
+// acknowledged or reset.
+func (p *peer) complete(m *message) {
+	p.mut.Lock()
+	c := p.pending[m.id]
+	delete(p.pending, m.id)
+	p.mut.Unlock()
+	if c != nil {
+		c <- m
+	}
+}
+
+func (p *peer) deliver(m *message) {
+	p.mut.Lock()
+	fn := p.observers[string(m.token)]
+	p.mut.Unlock()
+	if fn != nil {
+		fn(m)
+	}
+}
+
+var errReset = errors.New("request was reset by the peer")
+
+// request sends a confirmable request and waits for it to be acknowledged,
+// retransmitting it as required. Its response, and any notifications that
+// follow when observing a resource, are passed to fn until cancel is called
+// with the token of the request.
+func (p *peer) request(ctx context.Context, req *message, fn func(*message)) ([]byte, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	req.typ = typeConfirmable
+	req.id = p.messageID()
+	req.token = token
+
+	done := make(chan *message, 1)
+	p.mut.Lock()
+	p.observers[string(token)] = fn
+	p.pending[req.id] = done
+	p.mut.Unlock()
+
+	fail := func(err error) ([]byte, error) {
+		p.mut.Lock()
+		delete(p.pending, req.id)
+		p.mut.Unlock()
+		p.cancel(token)
+		return nil, err
+	}
+
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(ackTimeout/2)))
+	if err != nil {
+		return fail(err)
+	}
+	timeout := ackTimeout + time.Duration(jitter.Int64())
+
+	for i := 0; ; i++ {
+		if err := p.send(req); err != nil {
+			return fail(err)
+		}
+		select {
+		case m := <-done:
+			if m.typ == typeReset {
+				return fail(errReset)
+			}
+			return token, nil
+		case <-time.After(timeout):
+		case <-ctx.Done():
+			return fail(ctx.Err())
+		}
+		if i == maxRetransmit {
+			return fail(errors.New("request timed out"))
+		}
+		timeout *= 2
+	}
+}
+
+// cancel stops passing the responses to a request to its function.
+func (p *peer) cancel(token []byte) {
+	p.mut.Lock()
+	delete(p.observers, string(token))
+	p.mut.Unlock()
+}
+
+//------------------------------------------------------------------------------
+
+// exchange is a request received from a peer awaiting its response.
+type exchange struct {
+	peer *peer
+	req  *message
+	once sync.Once
+}
+
+// respond sends the response to a request, which is piggybacked on the
+// acknowledgement of confirmable requests. Only the first response of an
+// exchange is sent.
+func (ex *exchange) respond(res *message) {
+	ex.once.Do(func() {
+		res.token = ex.req.token
+		if ex.req.typ == typeConfirmable {
+			res.typ = typeAcknowledgement
+			res.id = ex.req.id
+		} else {
+			res.typ = typeNonConfirmable
+			res.id = ex.peer.messageID()
+		}
+		ex.peer.setReply(ex.req.id, res.marshal())
+		if err := ex.peer.send(res); err != nil {
+			ex.peer.e.log.Debugf("Failed to respond to %v: %v", ex.peer.addr, err)
+		}
+	})
+}
@@ -0,0 +1,569 @@
+package coap
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	csFieldAddress        = "address"
+	csFieldPath           = "path"
+	csFieldAllowedMethods = "allowed_methods"
+	csFieldTimeout        = "timeout"
+	csFieldDTLS           = "dtls"
+	csFieldDTLSEnabled    = "enabled"
+	csFieldDTLSPSKs       = "psks"
+	csFieldPSKIdentity    = "identity"
+	csFieldPSKKey         = "key"
+	csFieldObserve        = "observe"
+	csFieldObserveURL     = "url"
+	csFieldObserveIdent   = "psk_identity"
+	csFieldObserveKey     = "psk"
+
+	// observeRetryPeriod is the period to wait before observing a resource
+	// again after its observation has ended.
+	observeRetryPeriod = 5 * time.Second
+)
+
+func dtlsField() *service.ConfigField {
+	return service.NewObjectField(csFieldDTLS,
+		service.NewBoolField(csFieldDTLSEnabled).
+			Description("Whether to require clients to connect with DTLS using a pre-shared key.").
+			Default(false),
+		service.NewObjectListField(csFieldDTLSPSKs,
+			service.NewStringField(csFieldPSKIdentity).
+				Description("The identity of the key."),
+			service.NewStringField(csFieldPSKKey).
+				Description("The hex encoded key.").
+				Secret(),
+		).
+			Description("The pre-shared keys that clients can authenticate with.").
+			Example([]any{map[string]any{"identity": "sensor-1", "key": "${SENSOR_1_KEY}"}}).
+			Default([]any{}),
+	).Description("Settings for accepting clients with DTLS 1.2, which supports the cipher suites `TLS_PSK_WITH_AES_128_CCM_8` and `TLS_PSK_WITH_AES_128_GCM_SHA256`.")
+}
+
+// pskFromParsed returns the function that looks up the pre-shared keys of
+// identities, or nil when DTLS is disabled.
+func pskFromParsed(conf *service.ParsedConfig) (func(string) []byte, error) {
+	conf = conf.Namespace(csFieldDTLS)
+	enabled, err := conf.FieldBool(csFieldDTLSEnabled)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	pskConfs, err := conf.FieldObjectList(csFieldDTLSPSKs)
+	if err != nil {
+		return nil, err
+	}
+	if len(pskConfs) == 0 {
+		return nil, errors.New("at least one pre-shared key is required when DTLS is enabled")
+	}
+	keys := map[string][]byte{}
+	for i, pc := range pskConfs {
+		identity, err := pc.FieldString(csFieldPSKIdentity)
+		if err != nil {
+			return nil, err
+		}
+		keyStr, err := pc.FieldString(csFieldPSKKey)
+		if err != nil {
+			return nil, err
+		}
+		if keys[identity], err = decodeKey(keyStr); err != nil {
+			return nil, fmt.Errorf("psk %v: %w", i, err)
+		}
+	}
+	return func(identity string) []byte {
+		return keys[identity]
+	}, nil
+}
+
+func decodeKey(s string) ([]byte, error) {
+	key, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %w", err)
+	}
+	if len(key) == 0 {
+		return nil, errors.New("key must not be empty")
+	}
+	return key, nil
+}
+
+func inputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Receives messages sent by CoAP clients and observes resources of CoAP servers.").
+		Description(`
+Each request sent to this input with one of the `+"`allowed_methods`"+` becomes a message with the payload of the request. Confirmable requests are responded to once the message has been processed, with `+"`2.04 Changed`"+` (or `+"`2.02 Deleted`"+` and `+"`2.05 Content`"+` for those methods) on success, `+"`5.00 Internal Server Error`"+` when processing fails and `+"`5.03 Service Unavailable`"+` when it takes longer than `+"`timeout`"+`. Non-confirmable requests receive a non-confirmable response.
+
+Block-wise transfers are not supported, and therefore the payloads of requests are limited by the size of a datagram.
+
+### Observing Resources
+
+Resources of other CoAP servers listed in `+"`observe`"+` are observed as described in RFC 7641, which results in a message for the current representation of each resource followed by a message for each notification of a change. Observations are registered again when the server ends them, and when no notification has arrived within the max age of the last one.
+
+### DTLS
+
+When `+"`dtls.enabled`"+` is true clients must establish a DTLS session with one of the pre-shared keys listed before sending requests, and the port of the address customarily changes to 5684. Resources are observed securely when their URL has the `+"`coaps`"+` scheme, with the pre-shared key of the observation.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- coap_method (requests only)
+- coap_path
+- coap_query
+- coap_content_format
+- coap_remote_addr
+- coap_psk_identity (secure requests only)
+- coap_observe (notifications only)
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewStringField(csFieldAddress).
+				Description("The UDP address to listen on, where an empty string disables receiving requests.").
+				Default("0.0.0.0:5683"),
+			service.NewStringField(csFieldPath).
+				Description("The path that requests must be sent to, where an empty string accepts requests to any path.").
+				Example("/telemetry").
+				Default(""),
+			service.NewStringListField(csFieldAllowedMethods).
+				Description("The request methods that are accepted, other methods are responded to with `4.05 Method Not Allowed`.").
+				Default([]string{"POST", "PUT"}),
+			service.NewDurationField(csFieldTimeout).
+				Description("The maximum period to wait for a message to be processed before responding to its request with an error.").
+				Default("5s").
+				Advanced(),
+			dtlsField(),
+			service.NewObjectListField(csFieldObserve,
+				service.NewStringField(csFieldObserveURL).
+					Description("The URL of the resource, with the scheme `coap` or `coaps`."),
+				service.NewStringField(csFieldObserveIdent).
+					Description("The identity of the pre-shared key of a `coaps` resource.").
+					Default(""),
+				service.NewStringField(csFieldObserveKey).
+					Description("The hex encoded pre-shared key of a `coaps` resource.").
+					Secret().
+					Default(""),
+			).
+				Description("Resources of CoAP servers to observe.").
+				Example([]any{map[string]any{"url": "coap://10.0.0.20/sensors/temperature"}}).
+				Default([]any{}),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Device Telemetry", "Devices post their telemetry over DTLS with their own pre-shared keys, and the identity of each device is added to its readings.", `
+input:
+  coap_server:
+    address: 0.0.0.0:5684
+    path: /telemetry
+    dtls:
+      enabled: true
+      psks:
+        - identity: sensor-1
+          key: ${SENSOR_1_KEY}
+        - identity: sensor-2
+          key: ${SENSOR_2_KEY}
+  processors:
+    - mapping: |
+        root = this
+        root.device = @coap_psk_identity
+`).
+		Example("Observe a Sensor", "The temperature of a sensor is observed without receiving any requests.", `
+input:
+  coap_server:
+    address: ""
+    observe:
+      - url: coap://10.0.0.20/sensors/temperature
+`)
+}
+
+func init() {
+	err := service.RegisterInput("coap_server", inputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newCoAPServerInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type observeTarget struct {
+	url      string
+	address  string
+	path     string
+	queries  []string
+	identity string
+	key      []byte
+}
+
+func parseObserveTarget(rawURL, identity, keyStr string) (observeTarget, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return observeTarget{}, err
+	}
+	t := observeTarget{url: rawURL, path: u.Path}
+
+	port := "5683"
+	switch u.Scheme {
+	case "coap":
+	case "coaps":
+		port = "5684"
+		if t.key, err = decodeKey(keyStr); err != nil {
+			return observeTarget{}, fmt.Errorf("psk: %w", err)
+		}
+		t.identity = identity
+	default:
+		return observeTarget{}, fmt.Errorf("unsupported scheme '%v'", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return observeTarget{}, errors.New("a host is required")
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	t.address = net.JoinHostPort(u.Hostname(), port)
+	if u.RawQuery != "" {
+		t.queries = strings.Split(u.RawQuery, "&")
+	}
+	return t, nil
+}
+
+type pendingMessage struct {
+	msg   *service.Message
+	ackFn service.AckFunc
+}
+
+type coapServerInput struct {
+	address string
+	path    string
+	methods map[code]bool
+	timeout time.Duration
+	psk     func(string) []byte
+	targets []observeTarget
+
+	connMut sync.Mutex
+	ep      *endpoint
+	started bool
+	msgChan chan pendingMessage
+	shutSig *shutdown.Signaller
+
+	log *service.Logger
+}
+
+func newCoAPServerInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*coapServerInput, error) {
+	c := &coapServerInput{
+		methods: map[code]bool{},
+		msgChan: make(chan pendingMessage),
+		shutSig: shutdown.NewSignaller(),
+		log:     mgr.Logger(),
+	}
+
+	var err error
+	if c.address, err = conf.FieldString(csFieldAddress); err != nil {
+		return nil, err
+	}
+	if c.path, err = conf.FieldString(csFieldPath); err != nil {
+		return nil, err
+	}
+	if c.path != "" && !strings.HasPrefix(c.path, "/") {
+		c.path = "/" + c.path
+	}
+	methods, err := conf.FieldStringList(csFieldAllowedMethods)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range methods {
+		method, err := parseMethod(m)
+		if err != nil {
+			return nil, err
+		}
+		c.methods[method] = true
+	}
+	if c.timeout, err = conf.FieldDuration(csFieldTimeout); err != nil {
+		return nil, err
+	}
+	if c.psk, err = pskFromParsed(conf); err != nil {
+		return nil, err
+	}
+
+	observeConfs, err := conf.FieldObjectList(csFieldObserve)
+	if err != nil {
+		return nil, err
+	}
+	for i, oc := range observeConfs {
+		rawURL, err := oc.FieldString(csFieldObserveURL)
+		if err != nil {
+			return nil, err
+		}
+		identity, err := oc.FieldString(csFieldObserveIdent)
+		if err != nil {
+			return nil, err
+		}
+		keyStr, err := oc.FieldString(csFieldObserveKey)
+		if err != nil {
+			return nil, err
+		}
+		t, err := parseObserveTarget(rawURL, identity, keyStr)
+		if err != nil {
+			return nil, fmt.Errorf("%v %v: %w", csFieldObserve, i, err)
+		}
+		c.targets = append(c.targets, t)
+	}
+
+	if c.address == "" && len(c.targets) == 0 {
+		return nil, errors.New("either an address or resources to observe must be set")
+	}
+	return c, nil
+}
+
+func (c *coapServerInput) Connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.started {
+		return nil
+	}
+
+	if c.address != "" {
+		var err error
+		if c.ep, err = listen(c.address, endpointConfig{
+			accept:  true,
+			handler: c.handle,
+			psk:     c.psk,
+		}, c.log); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range c.targets {
+		go c.observeLoop(t)
+	}
+	c.started = true
+	return nil
+}
+
+func requestMetadata(msg *service.Message, p *peer, m *message) {
+	msg.MetaSetMut("coap_path", m.path())
+	msg.MetaSetMut("coap_query", strings.Join(m.optionValues(optionURIQuery), "&"))
+	if f, exists := m.uintOption(optionContentFormat); exists {
+		msg.MetaSetMut("coap_content_format", contentFormatName(f))
+	}
+	msg.MetaSetMut("coap_remote_addr", p.addr.String())
+	if identity := p.identity(); identity != "" {
+		msg.MetaSetMut("coap_psk_identity", identity)
+	}
+}
+
+func (c *coapServerInput) handle(ex *exchange) {
+	if c.path != "" && ex.req.path() != c.path {
+		ex.respond(&message{code: codeNotFound})
+		return
+	}
+	if !c.methods[ex.req.code] {
+		ex.respond(&message{code: codeMethodNotAllowed})
+		return
+	}
+
+	msg := service.NewMessage(ex.req.payload)
+	msg.MetaSetMut("coap_method", ex.req.code.String())
+	requestMetadata(msg, ex.peer, ex.req)
+
+	ctx, done := c.shutSig.SoftStopCtx(context.Background())
+	defer done()
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resChan := make(chan error, 1)
+	select {
+	case c.msgChan <- pendingMessage{
+		msg: msg,
+		ackFn: func(ctx context.Context, err error) error {
+			resChan <- err
+			return nil
+		},
+	}:
+	case <-ctx.Done():
+		ex.respond(&message{code: codeServiceUnavailable})
+		return
+	}
+
+	select {
+	case err := <-resChan:
+		if err != nil {
+			ex.respond(&message{code: codeInternalServerError, payload: []byte(err.Error())})
+			return
+		}
+		res := &message{code: codeChanged}
+		switch ex.req.code {
+		case codeDELETE:
+			res.code = codeDeleted
+		case codeGET:
+			res.code = codeContent
+		}
+		ex.respond(res)
+	case <-ctx.Done():
+		ex.respond(&message{code: codeServiceUnavailable})
+	}
+}
+
+func (c *coapServerInput) observeLoop(t observeTarget) {
+	ctx, done := c.shutSig.SoftStopCtx(context.Background())
+	defer done()
+
+	for {
+		if err := c.observe(ctx, t); err != nil && ctx.Err() == nil {
+			c.log.Errorf("Failed to observe %v: %v", t.url, err)
+		}
+		select {
+		case <-time.After(observeRetryPeriod):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// observe registers an observation of a resource and emits its notifications
+// until the observation ends.
+func (c *coapServerInput) observe(ctx context.Context, t observeTarget) error {
+	addr, err := net.ResolveUDPAddr("udp", t.address)
+	if err != nil {
+		return err
+	}
+	conn, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		return err
+	}
+	ep := newEndpoint(conn, endpointConfig{}, c.log)
+	defer ep.close()
+
+	p, err := ep.dial(ctx, addr, t.identity, t.key)
+	if err != nil {
+		return err
+	}
+
+	ended := make(chan error, 1)
+	notified := make(chan time.Duration, 1)
+	end := func(err error) {
+		select {
+		case ended <- err:
+		default:
+		}
+	}
+
+	req := &message{code: codeGET}
+	req.addUintOption(optionObserve, 0)
+	req.setPath(t.path)
+	for _, q := range t.queries {
+		req.addOption(optionURIQuery, []byte(q))
+	}
+	if _, err := p.request(ctx, req, func(m *message) {
+		if m.code.class() != 2 {
+			end(fmt.Errorf("server responded with %v", m.code))
+			return
+		}
+
+		msg := service.NewMessage(m.payload)
+		requestMetadata(msg, p, req)
+		if f, exists := m.uintOption(optionContentFormat); exists {
+			msg.MetaSetMut("coap_content_format", contentFormatName(f))
+		}
+		seq, observing := m.uintOption(optionObserve)
+		if observing {
+			msg.MetaSetMut("coap_observe", strconv.FormatUint(uint64(seq), 10))
+		}
+
+		select {
+		case c.msgChan <- pendingMessage{msg: msg, ackFn: func(context.Context, error) error { return nil }}:
+		case <-ctx.Done():
+			return
+		}
+
+		if !observing {
+			end(errors.New("server does not support observing the resource"))
+			return
+		}
+		maxAge := 60 * time.Second
+		if v, exists := m.uintOption(optionMaxAge); exists {
+			maxAge = time.Duration(v) * time.Second
+		}
+		select {
+		case <-notified:
+		default:
+		}
+		notified <- maxAge
+	}); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		select {
+		case maxAge := <-notified:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(maxAge + ackTimeout)
+		case <-timer.C:
+			// The observation is no longer fresh and is registered again.
+			return nil
+		case err := <-ended:
+			return err
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *coapServerInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	c.connMut.Lock()
+	started := c.started
+	c.connMut.Unlock()
+	if !started {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case m := <-c.msgChan:
+		return m.msg, m.ackFn, nil
+	case <-c.shutSig.SoftStopChan():
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (c *coapServerInput) Close(ctx context.Context) error {
+	c.shutSig.TriggerSoftStop()
+
+	c.connMut.Lock()
+	if c.ep != nil {
+		c.ep.close()
+		c.ep = nil
+	}
+	c.connMut.Unlock()
+	return nil
+}
@@ -0,0 +1,281 @@
+package coap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testEndpoint(t testing.TB, conf endpointConfig) *endpoint {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	e := newEndpoint(conn, conf, service.MockResources().Logger())
+	t.Cleanup(e.close)
+	return e
+}
+
+// testRequest sends a request to a peer and returns its response.
+func testRequest(t testing.TB, p *peer, req *message) *message {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	resChan := make(chan *message, 1)
+	token, err := p.request(ctx, req, func(m *message) {
+		resChan <- m
+	})
+	require.NoError(t, err)
+	defer p.cancel(token)
+
+	select {
+	case res := <-resChan:
+		return res
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for response")
+	}
+	return nil
+}
+
+func testServerInput(t testing.TB, confStr string) *coapServerInput {
+	t.Helper()
+
+	conf, err := inputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newCoAPServerInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	require.NoError(t, i.Connect(context.Background()))
+	return i
+}
+
+func TestServerInputRequests(t *testing.T) {
+	i := testServerInput(t, `
+address: 127.0.0.1:0
+path: /telemetry
+allowed_methods: [ POST ]
+`)
+	client := testEndpoint(t, endpointConfig{})
+	p, err := client.dial(context.Background(), i.ep.addr(), "", nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	for _, test := range []struct {
+		name   string
+		nack   bool
+		expect code
+	}{
+		{name: "acked", expect: codeChanged},
+		{name: "nacked", nack: true, expect: codeInternalServerError},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			go func() {
+				msg, ackFn, err := i.Read(ctx)
+				if !assert.NoError(t, err) {
+					return
+				}
+				b, err := msg.AsBytes()
+				assert.NoError(t, err)
+				assert.Equal(t, `{"temperature":21.5}`, string(b))
+
+				for k, v := range map[string]string{
+					"coap_method":         "POST",
+					"coap_path":           "/telemetry",
+					"coap_query":          "device=foo&unit=c",
+					"coap_content_format": "application/json",
+					"coap_remote_addr":    client.conn.LocalAddr().String(),
+				} {
+					actual, exists := msg.MetaGet(k)
+					assert.True(t, exists, k)
+					assert.Equal(t, v, actual, k)
+				}
+				_, exists := msg.MetaGet("coap_psk_identity")
+				assert.False(t, exists)
+
+				var ackErr error
+				if test.nack {
+					ackErr = errors.New("nope")
+				}
+				assert.NoError(t, ackFn(ctx, ackErr))
+			}()
+
+			req := &message{code: codePOST, payload: []byte(`{"temperature":21.5}`)}
+			req.setPath("/telemetry")
+			req.addOption(optionURIQuery, []byte("device=foo"))
+			req.addOption(optionURIQuery, []byte("unit=c"))
+			req.addUintOption(optionContentFormat, 50)
+
+			res := testRequest(t, p, req)
+			assert.Equal(t, typeAcknowledgement, res.typ)
+			assert.Equal(t, test.expect, res.code)
+		})
+	}
+
+	req := &message{code: codePOST}
+	req.setPath("/other")
+	assert.Equal(t, codeNotFound, testRequest(t, p, req).code)
+
+	req = &message{code: codeGET}
+	req.setPath("/telemetry")
+	assert.Equal(t, codeMethodNotAllowed, testRequest(t, p, req).code)
+
+	req = &message{code: codePOST}
+	req.setPath("/telemetry")
+	req.addOption(optionProxyURI, []byte("coap://elsewhere"))
+	assert.Equal(t, codeBadOption, testRequest(t, p, req).code)
+}
+
+func TestServerInputDTLS(t *testing.T) {
+	i := testServerInput(t, `
+address: 127.0.0.1:0
+dtls:
+  enabled: true
+  psks:
+    - identity: device
+      key: 736563726574
+`)
+	addr := i.ep.addr()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	// Requests without DTLS are ignored entirely.
+	plain := testEndpoint(t, endpointConfig{})
+	p, err := plain.dial(ctx, addr, "", nil)
+	require.NoError(t, err)
+	reqCtx, reqCancel := context.WithTimeout(ctx, time.Millisecond*500)
+	_, err = p.request(reqCtx, &message{code: codePOST}, func(*message) {})
+	reqCancel()
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	client := testEndpoint(t, endpointConfig{})
+	p, err = client.dial(ctx, addr, "device", []byte("secret"))
+	require.NoError(t, err)
+
+	go func() {
+		msg, ackFn, err := i.Read(ctx)
+		if !assert.NoError(t, err) {
+			return
+		}
+		b, err := msg.AsBytes()
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", string(b))
+
+		identity, _ := msg.MetaGet("coap_psk_identity")
+		assert.Equal(t, "device", identity)
+		assert.NoError(t, ackFn(ctx, nil))
+	}()
+
+	res := testRequest(t, p, &message{code: codePUT, payload: []byte("hello world")})
+	assert.Equal(t, codeChanged, res.code)
+
+	other := testEndpoint(t, endpointConfig{})
+	dialCtx, dialCancel := context.WithTimeout(ctx, time.Second*2)
+	defer dialCancel()
+	_, err = other.dial(dialCtx, addr, "unknown", []byte("secret"))
+	require.Error(t, err)
+}
+
+func TestServerInputObserve(t *testing.T) {
+	registered := make(chan struct{}, 1)
+	server := testEndpoint(t, endpointConfig{
+		accept: true,
+		handler: func(ex *exchange) {
+			if ex.req.path() != "/temperature" {
+				ex.respond(&message{code: codeNotFound})
+				return
+			}
+			if _, exists := ex.req.uintOption(optionObserve); !exists {
+				ex.respond(&message{code: codeBadRequest})
+				return
+			}
+			assert.Equal(t, map[string]string{"unit": "c"}, ex.req.queries())
+
+			res := &message{code: codeContent, payload: []byte("20")}
+			res.addUintOption(optionObserve, 1)
+			res.addUintOption(optionContentFormat, 0)
+			ex.respond(res)
+
+			for i, v := range []string{"21", "22"} {
+				n := &message{
+					typ:     typeConfirmable,
+					code:    codeContent,
+					id:      ex.peer.messageID(),
+					token:   ex.req.token,
+					payload: []byte(v),
+				}
+				n.addUintOption(optionObserve, uint32(i+2))
+				assert.NoError(t, ex.peer.send(n))
+			}
+			registered <- struct{}{}
+		},
+	})
+
+	i := testServerInput(t, `
+address: ""
+observe:
+  - url: coap://`+server.conn.LocalAddr().String()+`/temperature?unit=c
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	for j, v := range []string{"20", "21", "22"} {
+		msg, ackFn, err := i.Read(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, v, string(b))
+
+		for k, expected := range map[string]string{
+			"coap_path":        "/temperature",
+			"coap_query":       "unit=c",
+			"coap_observe":     []string{"1", "2", "3"}[j],
+			"coap_remote_addr": server.conn.LocalAddr().String(),
+		} {
+			actual, _ := msg.MetaGet(k)
+			assert.Equal(t, expected, actual, k)
+		}
+		if j == 0 {
+			format, _ := msg.MetaGet("coap_content_format")
+			assert.Equal(t, "text/plain;charset=utf-8", format)
+		}
+	}
+	<-registered
+}
+
+func TestServerInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		conf string
+		err  string
+	}{
+		{conf: `address: ""`, err: "either an address or resources to observe must be set"},
+		{conf: `allowed_methods: [ PATCH ]`, err: "unrecognised method 'PATCH'"},
+		{conf: `observe: [ { url: "http://foo/bar" } ]`, err: "observe 0: unsupported scheme 'http'"},
+		{conf: `observe: [ { url: "coaps://foo/bar", psk_identity: foo, psk: nothex } ]`, err: "observe 0: psk: failed to decode key: encoding/hex: invalid byte: U+006E 'n'"},
+	} {
+		conf, err := inputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newCoAPServerInputFromParsed(conf, service.MockResources())
+		assert.EqualError(t, err, test.err, test.conf)
+	}
+}
@@ -0,0 +1,538 @@
+package coap
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lsFieldAddress            = "address"
+	lsFieldObserve            = "observe"
+	lsFieldRegistrationEvents = "registration_events"
+
+	defaultLifetime = 86400
+)
+
+func lwm2mInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Acts as a LwM2M server that devices register with, observing their resources and emitting the notifications they send.").
+		Description(`
+Devices register with this input with the LwM2M registration interface over CoAP. Once a device has registered, each path listed in `+"`observe`"+` that belongs to an object the device reported is observed, which results in a message with the current value of the path followed by a message each time the device sends a notification.
+
+Observations are cancelled when a device deregisters or its registration expires, and are made again when it registers again. The payloads of notifications are emitted as they are, the format of which is given by the `+"`lwm2m_content_format`"+` metadata field and is typically plain text for single resources, and either `+"`application/vnd.oma.lwm2m+tlv`"+` or `+"`application/senml+json`"+` for object instances.
+
+### Registration Events
+
+When `+"`registration_events`"+` is true a message is also emitted each time a device registers, updates its registration, deregisters or its registration expires, with the event given by the metadata field `+"`lwm2m_event`"+` and a body describing the registration:
+
+`+"```json"+`
+{
+  "endpoint": "urn:imei:490154203237518",
+  "lifetime": 300,
+  "binding": "U",
+  "version": "1.1",
+  "objects": [ "/1/0", "/3/0", "/3303/0" ]
+}
+`+"```"+`
+
+### DTLS
+
+When `+"`dtls.enabled`"+` is true devices must establish a DTLS session with one of the pre-shared keys listed before registering, and the port of the address customarily changes to 5684.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- lwm2m_event (register, update, deregister, expire or notify)
+- lwm2m_endpoint
+- lwm2m_path (notifications only)
+- lwm2m_content_format (notifications only)
+- lwm2m_remote_addr
+- lwm2m_psk_identity (secure sessions only)
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewStringField(lsFieldAddress).
+				Description("The UDP address to listen on.").
+				Default("0.0.0.0:5683"),
+			dtlsField(),
+			service.NewStringListField(lsFieldObserve).
+				Description("Paths of objects, object instances or resources to observe on each device.").
+				Example([]string{"/3303/0/5700", "/3/0/9"}).
+				Default([]string{}),
+			service.NewBoolField(lsFieldRegistrationEvents).
+				Description("Whether to emit a message each time a device registers, updates its registration, deregisters or its registration expires.").
+				Default(false),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Temperature Readings", "The temperature sensor and battery level of each device are observed and written as readings tagged with the endpoint name of the device.", `
+input:
+  lwm2m_server:
+    address: 0.0.0.0:5684
+    dtls:
+      enabled: true
+      psks:
+        - identity: urn:imei:490154203237518
+          key: ${DEVICE_KEY}
+    observe: [ /3303/0/5700, /3/0/9 ]
+  processors:
+    - mapping: |
+        root.device = @lwm2m_endpoint
+        root.path = @lwm2m_path
+        root.value = content().string().number()
+`)
+}
+
+func init() {
+	err := service.RegisterInput("lwm2m_server", lwm2mInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newLwM2MInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type registration struct {
+	id       string
+	endpoint string
+	lifetime time.Duration
+	binding  string
+	version  string
+	objects  []string
+	peer     *peer
+	expires  time.Time
+	tokens   [][]byte
+}
+
+func (r *registration) structured() map[string]any {
+	objects := make([]any, 0, len(r.objects))
+	for _, o := range r.objects {
+		objects = append(objects, o)
+	}
+	return map[string]any{
+		"endpoint": r.endpoint,
+		"lifetime": int64(r.lifetime / time.Second),
+		"binding":  r.binding,
+		"version":  r.version,
+		"objects":  objects,
+	}
+}
+
+// hasObject determines whether a path belongs to an object the device has
+// reported, which is assumed when it reported none.
+func (r *registration) hasObject(path string) bool {
+	if len(r.objects) == 0 {
+		return true
+	}
+	object, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	for _, o := range r.objects {
+		if oo, _, _ := strings.Cut(strings.TrimPrefix(o, "/"), "/"); oo == object {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLinkFormat returns the paths of the objects and object instances
+// listed in a CoRE link format payload.
+func parseLinkFormat(payload string) []string {
+	var paths []string
+	for _, link := range strings.Split(payload, ",") {
+		start, end := strings.Index(link, "<"), strings.Index(link, ">")
+		if start < 0 || end < start {
+			continue
+		}
+		if p := link[start+1 : end]; p != "" && p != "/" {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+type lwm2mInput struct {
+	address            string
+	psk                func(string) []byte
+	observe            []string
+	registrationEvents bool
+
+	connMut sync.Mutex
+	ep      *endpoint
+	msgChan chan pendingMessage
+	shutSig *shutdown.Signaller
+
+	regMut     sync.Mutex
+	regs       map[string]*registration
+	endpoints  map[string]*registration
+	expiryLoop sync.Once
+
+	log *service.Logger
+}
+
+func newLwM2MInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*lwm2mInput, error) {
+	l := &lwm2mInput{
+		msgChan:   make(chan pendingMessage),
+		shutSig:   shutdown.NewSignaller(),
+		regs:      map[string]*registration{},
+		endpoints: map[string]*registration{},
+		log:       mgr.Logger(),
+	}
+
+	var err error
+	if l.address, err = conf.FieldString(lsFieldAddress); err != nil {
+		return nil, err
+	}
+	if l.psk, err = pskFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if l.observe, err = conf.FieldStringList(lsFieldObserve); err != nil {
+		return nil, err
+	}
+	for i, p := range l.observe {
+		if !strings.HasPrefix(p, "/") {
+			l.observe[i] = "/" + p
+		}
+	}
+	if l.registrationEvents, err = conf.FieldBool(lsFieldRegistrationEvents); err != nil {
+		return nil, err
+	}
+	if len(l.observe) == 0 && !l.registrationEvents {
+		return nil, errors.New("at least one path to observe is required unless registration events are enabled")
+	}
+	return l, nil
+}
+
+func (l *lwm2mInput) Connect(ctx context.Context) error {
+	l.connMut.Lock()
+	defer l.connMut.Unlock()
+
+	if l.ep != nil {
+		return nil
+	}
+
+	var err error
+	if l.ep, err = listen(l.address, endpointConfig{
+		accept:  true,
+		handler: l.handle,
+		psk:     l.psk,
+	}, l.log); err != nil {
+		return err
+	}
+	l.expiryLoop.Do(func() {
+		go l.expire()
+	})
+	return nil
+}
+
+func (l *lwm2mInput) handle(ex *exchange) {
+	segments := ex.req.optionValues(optionURIPath)
+	if len(segments) == 0 || segments[0] != "rd" {
+		ex.respond(&message{code: codeNotFound})
+		return
+	}
+
+	switch {
+	case len(segments) == 1 && ex.req.code == codePOST:
+		l.register(ex)
+	case len(segments) == 2 && ex.req.code == codePOST:
+		l.update(ex, segments[1])
+	case len(segments) == 2 && ex.req.code == codeDELETE:
+		l.deregister(ex, segments[1])
+	case len(segments) <= 2:
+		ex.respond(&message{code: codeMethodNotAllowed})
+	default:
+		ex.respond(&message{code: codeNotFound})
+	}
+}
+
+// applyQueries sets the parameters of a registration given by the queries of
+// a registration or update request.
+func applyQueries(reg *registration, queries map[string]string) error {
+	if lt, exists := queries["lt"]; exists {
+		n, err := strconv.ParseUint(lt, 10, 32)
+		if err != nil || n == 0 {
+			return errors.New("invalid lifetime")
+		}
+		reg.lifetime = time.Duration(n) * time.Second
+	}
+	if b, exists := queries["b"]; exists {
+		reg.binding = b
+	}
+	return nil
+}
+
+func (l *lwm2mInput) register(ex *exchange) {
+	queries := ex.req.queries()
+	reg := &registration{
+		endpoint: queries["ep"],
+		lifetime: defaultLifetime * time.Second,
+		binding:  "U",
+		version:  "1.0",
+		objects:  parseLinkFormat(string(ex.req.payload)),
+		peer:     ex.peer,
+	}
+	if reg.endpoint == "" {
+		ex.respond(&message{code: codeBadRequest, payload: []byte("endpoint name is required")})
+		return
+	}
+	if err := applyQueries(reg, queries); err != nil {
+		ex.respond(&message{code: codeBadRequest, payload: []byte(err.Error())})
+		return
+	}
+	if v, exists := queries["lwm2m"]; exists {
+		reg.version = v
+	}
+
+	var id [6]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		ex.respond(&message{code: codeInternalServerError})
+		return
+	}
+	reg.id = hex.EncodeToString(id[:])
+	reg.expires = time.Now().Add(reg.lifetime)
+	reg.peer.keep(reg.expires)
+
+	l.regMut.Lock()
+	previous := l.endpoints[reg.endpoint]
+	if previous != nil {
+		delete(l.regs, previous.id)
+	}
+	l.regs[reg.id] = reg
+	l.endpoints[reg.endpoint] = reg
+	l.regMut.Unlock()
+	if previous != nil {
+		l.cancelObservations(previous)
+	}
+
+	res := &message{code: codeCreated}
+	res.addOption(optionLocationPath, []byte("rd"))
+	res.addOption(optionLocationPath, []byte(reg.id))
+	ex.respond(res)
+
+	l.log.Debugf("Device %v registered from %v", reg.endpoint, reg.peer.addr)
+	l.emitEvent(reg, "register")
+	l.observeAll(reg)
+}
+
+func (l *lwm2mInput) update(ex *exchange, id string) {
+	l.regMut.Lock()
+	reg := l.regs[id]
+	if reg == nil {
+		l.regMut.Unlock()
+		ex.respond(&message{code: codeNotFound})
+		return
+	}
+	if err := applyQueries(reg, ex.req.queries()); err != nil {
+		l.regMut.Unlock()
+		ex.respond(&message{code: codeBadRequest, payload: []byte(err.Error())})
+		return
+	}
+	if len(ex.req.payload) > 0 {
+		reg.objects = parseLinkFormat(string(ex.req.payload))
+	}
+	reg.expires = time.Now().Add(reg.lifetime)
+	reg.peer.keep(reg.expires)
+	l.regMut.Unlock()
+
+	ex.respond(&message{code: codeChanged})
+	l.emitEvent(reg, "update")
+}
+
+func (l *lwm2mInput) deregister(ex *exchange, id string) {
+	l.regMut.Lock()
+	reg := l.regs[id]
+	if reg != nil {
+		l.removeRegistration(reg)
+	}
+	l.regMut.Unlock()
+
+	if reg == nil {
+		ex.respond(&message{code: codeNotFound})
+		return
+	}
+	ex.respond(&message{code: codeDeleted})
+
+	l.log.Debugf("Device %v deregistered", reg.endpoint)
+	l.cancelObservations(reg)
+	l.emitEvent(reg, "deregister")
+}
+
+// removeRegistration removes a registration, which must be called with the
+// lock held.
+func (l *lwm2mInput) removeRegistration(reg *registration) {
+	delete(l.regs, reg.id)
+	if l.endpoints[reg.endpoint] == reg {
+		delete(l.endpoints, reg.endpoint)
+	}
+	reg.peer.keep(time.Time{})
+}
+
+func (l *lwm2mInput) cancelObservations(reg *registration) {
+	l.regMut.Lock()
+	tokens := reg.tokens
+	reg.tokens = nil
+	l.regMut.Unlock()
+
+	for _, t := range tokens {
+		reg.peer.cancel(t)
+	}
+}
+
+func (l *lwm2mInput) expire() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-l.shutSig.SoftStopChan():
+			return
+		}
+
+		now := time.Now()
+		var expired []*registration
+		l.regMut.Lock()
+		for _, reg := range l.regs {
+			if now.After(reg.expires) {
+				l.removeRegistration(reg)
+				expired = append(expired, reg)
+			}
+		}
+		l.regMut.Unlock()
+
+		for _, reg := range expired {
+			l.log.Debugf("Registration of device %v expired", reg.endpoint)
+			l.cancelObservations(reg)
+			l.emitEvent(reg, "expire")
+		}
+	}
+}
+
+func (l *lwm2mInput) metadata(msg *service.Message, reg *registration, event string) {
+	msg.MetaSetMut("lwm2m_event", event)
+	msg.MetaSetMut("lwm2m_endpoint", reg.endpoint)
+	msg.MetaSetMut("lwm2m_remote_addr", reg.peer.addr.String())
+	if identity := reg.peer.identity(); identity != "" {
+		msg.MetaSetMut("lwm2m_psk_identity", identity)
+	}
+}
+
+func (l *lwm2mInput) push(msg *service.Message) {
+	select {
+	case l.msgChan <- pendingMessage{msg: msg, ackFn: func(context.Context, error) error { return nil }}:
+	case <-l.shutSig.SoftStopChan():
+	}
+}
+
+func (l *lwm2mInput) emitEvent(reg *registration, event string) {
+	if !l.registrationEvents {
+		return
+	}
+	l.regMut.Lock()
+	structured := reg.structured()
+	l.regMut.Unlock()
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(structured)
+	l.metadata(msg, reg, event)
+	l.push(msg)
+}
+
+// observeAll observes each configured path of the objects of a device.
+func (l *lwm2mInput) observeAll(reg *registration) {
+	ctx, done := l.shutSig.SoftStopCtx(context.Background())
+	defer done()
+
+	for _, path := range l.observe {
+		l.regMut.Lock()
+		hasObject := reg.hasObject(path)
+		l.regMut.Unlock()
+		if !hasObject {
+			continue
+		}
+
+		path := path
+		req := &message{code: codeGET}
+		req.addUintOption(optionObserve, 0)
+		req.setPath(path)
+
+		token, err := reg.peer.request(ctx, req, func(m *message) {
+			if m.code.class() != 2 {
+				l.log.Warnf("Device %v responded to the observation of %v with %v", reg.endpoint, path, m.code)
+				return
+			}
+			msg := service.NewMessage(m.payload)
+			l.metadata(msg, reg, "notify")
+			msg.MetaSetMut("lwm2m_path", path)
+			if f, exists := m.uintOption(optionContentFormat); exists {
+				msg.MetaSetMut("lwm2m_content_format", contentFormatName(f))
+			}
+			l.push(msg)
+		})
+		if err != nil {
+			if ctx.Err() == nil {
+				l.log.Errorf("Failed to observe %v of device %v: %v", path, reg.endpoint, err)
+			}
+			continue
+		}
+
+		l.regMut.Lock()
+		_, registered := l.regs[reg.id]
+		if registered {
+			reg.tokens = append(reg.tokens, token)
+		}
+		l.regMut.Unlock()
+		if !registered {
+			reg.peer.cancel(token)
+			return
+		}
+	}
+}
+
+func (l *lwm2mInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	l.connMut.Lock()
+	connected := l.ep != nil
+	l.connMut.Unlock()
+	if !connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case m := <-l.msgChan:
+		return m.msg, m.ackFn, nil
+	case <-l.shutSig.SoftStopChan():
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (l *lwm2mInput) Close(ctx context.Context) error {
+	l.shutSig.TriggerSoftStop()
+
+	l.connMut.Lock()
+	if l.ep != nil {
+		l.ep.close()
+	}
+	l.connMut.Unlock()
+	return nil
+}
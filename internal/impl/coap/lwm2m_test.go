@@ -0,0 +1,153 @@
+package coap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testLwM2MInput(t testing.TB, confStr string) *lwm2mInput {
+	t.Helper()
+
+	conf, err := lwm2mInputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newLwM2MInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	require.NoError(t, i.Connect(context.Background()))
+	return i
+}
+
+func TestLwM2MInput(t *testing.T) {
+	i := testLwM2MInput(t, `
+address: 127.0.0.1:0
+observe: [ /3303/0/5700, /5/0/1 ]
+registration_events: true
+`)
+
+	observed := make(chan string, 10)
+	device := testEndpoint(t, endpointConfig{
+		accept: true,
+		handler: func(ex *exchange) {
+			observed <- ex.req.path()
+			if ex.req.path() != "/3303/0/5700" {
+				ex.respond(&message{code: codeNotFound})
+				return
+			}
+			res := &message{code: codeContent, payload: []byte("21.5")}
+			res.addUintOption(optionObserve, 1)
+			res.addUintOption(optionContentFormat, 0)
+			ex.respond(res)
+
+			n := &message{
+				typ:     typeNonConfirmable,
+				code:    codeContent,
+				id:      ex.peer.messageID(),
+				token:   ex.req.token,
+				payload: []byte("22.0"),
+			}
+			n.addUintOption(optionObserve, 2)
+			n.addUintOption(optionContentFormat, 0)
+			assert.NoError(t, ex.peer.send(n))
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	p, err := device.dial(ctx, i.ep.addr(), "", nil)
+	require.NoError(t, err)
+
+	req := &message{code: codePOST}
+	req.setPath("/rd")
+	req.addOption(optionURIQuery, []byte("lt=60"))
+	assert.Equal(t, codeBadRequest, testRequest(t, p, req).code)
+
+	req = &message{code: codePOST, payload: []byte(`</>;rt="oma.lwm2m",</1/0>,</3/0>,</3303/0>`)}
+	req.setPath("/rd")
+	req.addOption(optionURIQuery, []byte("ep=dev1"))
+	req.addOption(optionURIQuery, []byte("lt=60"))
+	req.addOption(optionURIQuery, []byte("lwm2m=1.1"))
+	res := testRequest(t, p, req)
+	require.Equal(t, codeCreated, res.code)
+
+	location := res.optionValues(optionLocationPath)
+	require.Len(t, location, 2)
+	assert.Equal(t, "rd", location[0])
+
+	readEvent := func(event string) *service.Message {
+		t.Helper()
+
+		msg, ackFn, err := i.Read(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		actual, _ := msg.MetaGet("lwm2m_event")
+		require.Equal(t, event, actual)
+		endpoint, _ := msg.MetaGet("lwm2m_endpoint")
+		assert.Equal(t, "dev1", endpoint)
+		addr, _ := msg.MetaGet("lwm2m_remote_addr")
+		assert.Equal(t, device.conn.LocalAddr().String(), addr)
+		return msg
+	}
+
+	msg := readEvent("register")
+	structured, err := msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"endpoint": "dev1",
+		"lifetime": int64(60),
+		"binding":  "U",
+		"version":  "1.1",
+		"objects":  []any{"/1/0", "/3/0", "/3303/0"},
+	}, structured)
+
+	for _, v := range []string{"21.5", "22.0"} {
+		msg = readEvent("notify")
+		b, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, v, string(b))
+
+		path, _ := msg.MetaGet("lwm2m_path")
+		assert.Equal(t, "/3303/0/5700", path)
+		format, _ := msg.MetaGet("lwm2m_content_format")
+		assert.Equal(t, "text/plain;charset=utf-8", format)
+	}
+
+	// Only paths of objects reported by the device are observed.
+	assert.Equal(t, "/3303/0/5700", <-observed)
+	assert.Empty(t, observed)
+
+	req = &message{code: codePOST}
+	req.setPath("/rd/" + location[1])
+	req.addOption(optionURIQuery, []byte("lt=120"))
+	assert.Equal(t, codeChanged, testRequest(t, p, req).code)
+
+	msg = readEvent("update")
+	structured, err = msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), structured.(map[string]any)["lifetime"])
+
+	req = &message{code: codeDELETE}
+	req.setPath("/rd/" + location[1])
+	assert.Equal(t, codeDeleted, testRequest(t, p, req).code)
+	readEvent("deregister")
+
+	assert.Equal(t, codeNotFound, testRequest(t, p, req).code)
+}
+
+func TestLwM2MInputConfigErrors(t *testing.T) {
+	conf, err := lwm2mInputSpec().ParseYAML(`registration_events: false`, nil)
+	require.NoError(t, err)
+
+	_, err = newLwM2MInputFromParsed(conf, service.MockResources())
+	require.EqualError(t, err, "at least one path to observe is required unless registration events are enabled")
+}
@@ -0,0 +1,346 @@
+package coap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type messageType uint8
+
+const (
+	typeConfirmable     messageType = 0
+	typeNonConfirmable  messageType = 1
+	typeAcknowledgement messageType = 2
+	typeReset           messageType = 3
+)
+
+// code is a request method or response code, where the upper three bits are
+// the class and the lower five the detail.
+type code uint8
+
+func newCode(class, detail uint8) code {
+	return code(class<<5 | detail)
+}
+
+func (c code) class() uint8 {
+	return uint8(c) >> 5
+}
+
+func (c code) isRequest() bool {
+	return c.class() == 0 && c != codeEmpty
+}
+
+func (c code) isResponse() bool {
+	return c.class() >= 2
+}
+
+func (c code) String() string {
+	if name, exists := methodNames[c]; exists {
+		return name
+	}
+	return fmt.Sprintf("%d.%02d", c.class(), uint8(c)&0x1f)
+}
+
+var (
+	codeEmpty  = newCode(0, 0)
+	codeGET    = newCode(0, 1)
+	codePOST   = newCode(0, 2)
+	codePUT    = newCode(0, 3)
+	codeDELETE = newCode(0, 4)
+
+	codeCreated = newCode(2, 1)
+	codeDeleted = newCode(2, 2)
+	codeChanged = newCode(2, 4)
+	codeContent = newCode(2, 5)
+
+	codeBadRequest       = newCode(4, 0)
+	codeBadOption        = newCode(4, 2)
+	codeNotFound         = newCode(4, 4)
+	codeMethodNotAllowed = newCode(4, 5)
+
+	codeInternalServerError = newCode(5, 0)
+	codeServiceUnavailable  = newCode(5, 3)
+)
+
+var methodNames = map[code]string{
+	codeGET:    "GET",
+	codePOST:   "POST",
+	codePUT:    "PUT",
+	codeDELETE: "DELETE",
+}
+
+func parseMethod(s string) (code, error) {
+	for c, name := range methodNames {
+		if strings.EqualFold(name, s) {
+			return c, nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognised method '%v'", s)
+}
+
+const (
+	optionIfMatch       uint16 = 1
+	optionURIHost       uint16 = 3
+	optionETag          uint16 = 4
+	optionIfNoneMatch   uint16 = 5
+	optionObserve       uint16 = 6
+	optionURIPort       uint16 = 7
+	optionLocationPath  uint16 = 8
+	optionURIPath       uint16 = 11
+	optionContentFormat uint16 = 12
+	optionMaxAge        uint16 = 14
+	optionURIQuery      uint16 = 15
+	optionAccept        uint16 = 17
+	optionLocationQuery uint16 = 20
+	optionProxyURI      uint16 = 35
+	optionProxyScheme   uint16 = 39
+	optionSize1         uint16 = 60
+)
+
+// knownCriticalOptions are the critical options understood by this package,
+// requests with any other critical option are rejected.
+var knownCriticalOptions = map[uint16]bool{
+	optionIfMatch:     true,
+	optionURIHost:     true,
+	optionIfNoneMatch: true,
+	optionURIPort:     true,
+	optionURIPath:     true,
+	optionURIQuery:    true,
+	optionAccept:      true,
+}
+
+var contentFormats = map[uint32]string{
+	0:     "text/plain;charset=utf-8",
+	40:    "application/link-format",
+	41:    "application/xml",
+	42:    "application/octet-stream",
+	47:    "application/exi",
+	50:    "application/json",
+	60:    "application/cbor",
+	110:   "application/senml+json",
+	112:   "application/senml+cbor",
+	11542: "application/vnd.oma.lwm2m+tlv",
+	11543: "application/vnd.oma.lwm2m+json",
+}
+
+func contentFormatName(f uint32) string {
+	if name, exists := contentFormats[f]; exists {
+		return name
+	}
+	return strconv.FormatUint(uint64(f), 10)
+}
+
+type option struct {
+	number uint16
+	value  []byte
+}
+
+// message is a CoAP message as defined in RFC 7252.
+type message struct {
+	typ     messageType
+	code    code
+	id      uint16
+	token   []byte
+	options []option
+	payload []byte
+}
+
+var errMessageFormat = errors.New("message format error")
+
+func parseMessage(b []byte) (*message, error) {
+	if len(b) < 4 {
+		return nil, errMessageFormat
+	}
+	if b[0]>>6 != 1 {
+		return nil, fmt.Errorf("unsupported version %v", b[0]>>6)
+	}
+	m := &message{
+		typ:  messageType(b[0] >> 4 & 0x03),
+		code: code(b[1]),
+		id:   binary.BigEndian.Uint16(b[2:]),
+	}
+	tokenLen := int(b[0] & 0x0f)
+	if tokenLen > 8 || len(b) < 4+tokenLen {
+		return nil, errMessageFormat
+	}
+	if tokenLen > 0 {
+		m.token = append([]byte(nil), b[4:4+tokenLen]...)
+	}
+	b = b[4+tokenLen:]
+
+	if m.code == codeEmpty && (tokenLen > 0 || len(b) > 0) {
+		return nil, errMessageFormat
+	}
+
+	var number uint16
+	for len(b) > 0 {
+		if b[0] == 0xff {
+			if len(b) == 1 {
+				return nil, errMessageFormat
+			}
+			m.payload = append([]byte(nil), b[1:]...)
+			break
+		}
+		delta, length := int(b[0]>>4), int(b[0]&0x0f)
+		b = b[1:]
+
+		var err error
+		if delta, b, err = extendedOptionValue(delta, b); err != nil {
+			return nil, err
+		}
+		if length, b, err = extendedOptionValue(length, b); err != nil {
+			return nil, err
+		}
+		if int(number)+delta > 0xffff || len(b) < length {
+			return nil, errMessageFormat
+		}
+		number += uint16(delta)
+		m.options = append(m.options, option{number: number, value: append([]byte(nil), b[:length]...)})
+		b = b[length:]
+	}
+	return m, nil
+}
+
+func extendedOptionValue(v int, b []byte) (int, []byte, error) {
+	switch v {
+	case 13:
+		if len(b) < 1 {
+			return 0, nil, errMessageFormat
+		}
+		return int(b[0]) + 13, b[1:], nil
+	case 14:
+		if len(b) < 2 {
+			return 0, nil, errMessageFormat
+		}
+		return int(binary.BigEndian.Uint16(b)) + 269, b[2:], nil
+	case 15:
+		return 0, nil, errMessageFormat
+	}
+	return v, b, nil
+}
+
+func (m *message) marshal() []byte {
+	b := make([]byte, 4, 64+len(m.payload))
+	b[0] = 1<<6 | byte(m.typ)<<4 | byte(len(m.token))
+	b[1] = byte(m.code)
+	binary.BigEndian.PutUint16(b[2:], m.id)
+	b = append(b, m.token...)
+
+	sort.SliceStable(m.options, func(i, j int) bool {
+		return m.options[i].number < m.options[j].number
+	})
+
+	var number uint16
+	for _, o := range m.options {
+		delta, length := int(o.number-number), len(o.value)
+		number = o.number
+
+		header := len(b)
+		b = append(b, 0)
+		var deltaNibble, lengthNibble byte
+		b, deltaNibble = appendExtendedOptionValue(b, delta)
+		b, lengthNibble = appendExtendedOptionValue(b, length)
+		b[header] = deltaNibble<<4 | lengthNibble
+		b = append(b, o.value...)
+	}
+
+	if len(m.payload) > 0 {
+		b = append(b, 0xff)
+		b = append(b, m.payload...)
+	}
+	return b
+}
+
+func appendExtendedOptionValue(b []byte, v int) ([]byte, byte) {
+	switch {
+	case v < 13:
+		return b, byte(v)
+	case v < 269:
+		return append(b, byte(v-13)), 13
+	}
+	return binary.BigEndian.AppendUint16(b, uint16(v-269)), 14
+}
+
+func (m *message) option(number uint16) ([]byte, bool) {
+	for _, o := range m.options {
+		if o.number == number {
+			return o.value, true
+		}
+	}
+	return nil, false
+}
+
+func (m *message) optionValues(number uint16) []string {
+	var values []string
+	for _, o := range m.options {
+		if o.number == number {
+			values = append(values, string(o.value))
+		}
+	}
+	return values
+}
+
+func (m *message) uintOption(number uint16) (uint32, bool) {
+	v, exists := m.option(number)
+	if !exists || len(v) > 4 {
+		return 0, false
+	}
+	var n uint32
+	for _, b := range v {
+		n = n<<8 | uint32(b)
+	}
+	return n, true
+}
+
+func (m *message) addOption(number uint16, value []byte) {
+	m.options = append(m.options, option{number: number, value: value})
+}
+
+// addUintOption adds an option with an unsigned integer value, which is
+// encoded in as few bytes as possible.
+func (m *message) addUintOption(number uint16, n uint32) {
+	var v []byte
+	for ; n > 0; n >>= 8 {
+		v = append([]byte{byte(n)}, v...)
+	}
+	m.addOption(number, v)
+}
+
+// path returns the URI path of a request with a leading slash.
+func (m *message) path() string {
+	return "/" + strings.Join(m.optionValues(optionURIPath), "/")
+}
+
+// setPath adds the URI path options of a request from a path.
+func (m *message) setPath(p string) {
+	for _, segment := range strings.Split(strings.Trim(p, "/"), "/") {
+		if segment != "" {
+			m.addOption(optionURIPath, []byte(segment))
+		}
+	}
+}
+
+// queries returns the URI query options of a request as a map.
+func (m *message) queries() map[string]string {
+	q := map[string]string{}
+	for _, v := range m.optionValues(optionURIQuery) {
+		k, v, _ := strings.Cut(v, "=")
+		q[k] = v
+	}
+	return q
+}
+
+// unknownCriticalOption returns the first critical option of a request that
+// isn't understood.
+func (m *message) unknownCriticalOption() (uint16, bool) {
+	for _, o := range m.options {
+		if o.number&1 == 1 && !knownCriticalOptions[o.number] {
+			return o.number, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package coap
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := &message{
+		typ:     typeConfirmable,
+		code:    codePOST,
+		id:      0xbeef,
+		token:   []byte{1, 2, 3, 4},
+		payload: []byte(`{"temperature":21.5}`),
+	}
+	m.setPath("/sensors/" + strings.Repeat("a", 20) + "/")
+	m.addOption(optionURIQuery, []byte("ep=foo"))
+	m.addUintOption(optionContentFormat, 50)
+	m.addUintOption(optionObserve, 0)
+	m.addOption(2048, make([]byte, 300))
+
+	b := m.marshal()
+	parsed, err := parseMessage(b)
+	require.NoError(t, err)
+	assert.Equal(t, m, parsed)
+
+	assert.Equal(t, "/sensors/"+strings.Repeat("a", 20), parsed.path())
+	assert.Equal(t, map[string]string{"ep": "foo"}, parsed.queries())
+	f, exists := parsed.uintOption(optionContentFormat)
+	assert.True(t, exists)
+	assert.Equal(t, uint32(50), f)
+	seq, exists := parsed.uintOption(optionObserve)
+	assert.True(t, exists)
+	assert.Equal(t, uint32(0), seq)
+	assert.Equal(t, "POST", parsed.code.String())
+	assert.Equal(t, "2.05", codeContent.String())
+
+	_, unknown := parsed.unknownCriticalOption()
+	assert.False(t, unknown)
+	parsed.addOption(23, []byte{0})
+	number, unknown := parsed.unknownCriticalOption()
+	assert.True(t, unknown)
+	assert.Equal(t, uint16(23), number)
+}
+
+func TestMessageParseErrors(t *testing.T) {
+	for _, b := range [][]byte{
+		{0x40, 0x01},
+		{0x80, 0x01, 0x00, 0x01},
+		{0x49, 0x01, 0x00, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		{0x41, 0x00, 0x00, 0x01, 0xaa},
+		{0x40, 0x01, 0x00, 0x01, 0xff},
+		{0x40, 0x01, 0x00, 0x01, 0xb5, 'a'},
+		{0x40, 0x01, 0x00, 0x01, 0xf0},
+		{0x40, 0x01, 0x00, 0x01, 0xd0},
+	} {
+		_, err := parseMessage(b)
+		assert.Error(t, err, "%x", b)
+	}
+}
@@ -0,0 +1,208 @@
+package confluent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testDriftJSONSchema = `{
+  "type": "object",
+  "properties": {
+    "id": { "type": "string" },
+    "total": { "type": "number" },
+    "customer": {
+      "type": "object",
+      "properties": {
+        "name": { "type": "string" },
+        "email": { "type": ["string", "null"] }
+      },
+      "required": [ "name" ]
+    },
+    "items": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "properties": {
+          "sku": { "type": "string" },
+          "quantity": { "type": "integer" }
+        },
+        "required": [ "sku" ]
+      }
+    },
+    "attributes": { "type": "object" }
+  },
+  "required": [ "id", "total" ]
+}`
+
+const testDriftAvroSchema = `{
+  "type": "record",
+  "name": "order",
+  "fields": [
+    { "name": "id", "type": "string" },
+    { "name": "total", "type": "double" },
+    { "name": "note", "type": ["null", "string"] },
+    { "name": "status", "type": { "type": "enum", "name": "status", "symbols": ["OPEN", "CLOSED"] }, "default": "OPEN" },
+    { "name": "tags", "type": { "type": "array", "items": "string" } },
+    { "name": "labels", "type": { "type": "map", "values": "string" } }
+  ]
+}`
+
+func testDriftProc(t testing.TB, confStr string) *schemaDriftProc {
+	t.Helper()
+
+	pConf, err := schemaDriftProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newSchemaDriftProcFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func processDrift(t testing.TB, proc *schemaDriftProc, doc string) *service.Message {
+	t.Helper()
+
+	batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(doc)),
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+	return batches[0][0]
+}
+
+func TestSchemaDriftJSONSchema(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.json")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(testDriftJSONSchema), 0o644))
+
+	proc := testDriftProc(t, `
+schema_path: `+schemaPath+`
+`)
+
+	msg := processDrift(t, proc, `{"id":"a","total":10,"customer":{"name":"foo","email":null},"items":[{"sku":"b","quantity":2}],"attributes":{"anything":true}}`)
+	_, exists := msg.MetaGetMut("schema_drift")
+	assert.False(t, exists)
+	require.NoError(t, msg.GetError())
+
+	msg = processDrift(t, proc, `{"id":"a","total":"10","customer":{"email":"foo@example.com","phone":"123"},"items":[{"quantity":2.5},{"sku":"c","colour":"red"},{"colour":"blue"}],"discount":5}`)
+	drift, exists := msg.MetaGetMut("schema_drift")
+	require.True(t, exists)
+	assert.Equal(t, map[string]any{
+		"new":     []any{"customer.phone", "discount", "items.*.colour"},
+		"removed": []any{"customer.name", "items.*.sku"},
+		"retyped": []any{
+			map[string]any{"path": "items.*.quantity", "expected": []any{"integer"}, "actual": "number"},
+			map[string]any{"path": "total", "expected": []any{"number"}, "actual": "string"},
+		},
+	}, drift)
+	require.NoError(t, msg.GetError())
+
+	msg = processDrift(t, proc, `{"total":10}`)
+	drift, exists = msg.MetaGetMut("schema_drift")
+	require.True(t, exists)
+	assert.Equal(t, []any{"id"}, drift.(map[string]any)["removed"])
+}
+
+func TestSchemaDriftAvroErrorOnDrift(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "schema.avsc")
+	require.NoError(t, os.WriteFile(schemaPath, []byte(testDriftAvroSchema), 0o644))
+
+	proc := testDriftProc(t, `
+schema_path: `+schemaPath+`
+error_on_drift: true
+`)
+
+	msg := processDrift(t, proc, `{"id":"a","total":1.5,"tags":["a","b"],"labels":{"foo":"bar"}}`)
+	_, exists := msg.MetaGetMut("schema_drift")
+	assert.False(t, exists)
+	require.NoError(t, msg.GetError())
+
+	msg = processDrift(t, proc, `{"id":5,"total":1,"note":"hey","tags":["a",7],"extra":true}`)
+	drift, exists := msg.MetaGetMut("schema_drift")
+	require.True(t, exists)
+	assert.Equal(t, map[string]any{
+		"new":     []any{"extra"},
+		"removed": []any{"labels"},
+		"retyped": []any{
+			map[string]any{"path": "id", "expected": []any{"string"}, "actual": "integer"},
+			map[string]any{"path": "tags.*", "expected": []any{"string"}, "actual": "integer"},
+		},
+	}, drift)
+	require.EqualError(t, msg.GetError(), "message drifts from baseline schema: new fields: extra; removed fields: labels; retyped fields: id (integer), tags.* (integer)")
+}
+
+func TestSchemaDriftRegistry(t *testing.T) {
+	var reqs atomic.Int64
+	var fail atomic.Bool
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		reqs.Add(1)
+		if fail.Load() {
+			return nil, errors.New("nope")
+		}
+		if path == "/subjects/orders-value/versions/latest" {
+			return mustJBytes(t, map[string]any{
+				"id":      1,
+				"version": 3,
+				"schema":  testDriftAvroSchema,
+			}), nil
+		}
+		return nil, nil
+	})
+
+	proc := testDriftProc(t, `
+url: `+urlStr+`
+subject: orders-value
+refresh_period: 1m
+`)
+
+	now := time.Now()
+	proc.nowFn = func() time.Time { return now }
+
+	msg := processDrift(t, proc, `{"id":"a","total":1.5,"tags":[],"labels":{},"nope":1}`)
+	drift, exists := msg.MetaGetMut("schema_drift")
+	require.True(t, exists)
+	assert.Equal(t, []any{"nope"}, drift.(map[string]any)["new"])
+
+	_ = processDrift(t, proc, `{}`)
+	assert.Equal(t, int64(1), reqs.Load())
+
+	// Failed refreshes continue with the previous baseline
+	fail.Store(true)
+	now = now.Add(time.Minute * 2)
+	msg = processDrift(t, proc, `{"id":"a","total":1.5,"tags":[],"labels":{}}`)
+	_, exists = msg.MetaGetMut("schema_drift")
+	assert.False(t, exists)
+	assert.Equal(t, int64(2), reqs.Load())
+}
+
+func TestSchemaDriftBadConfig(t *testing.T) {
+	for _, confStr := range []string{
+		`error_on_drift: true`,
+		`
+schema_path: ./foo.json
+url: http://localhost:8081
+subject: foo
+`,
+		`
+url: http://localhost:8081
+`,
+		`
+schema_path: ./does/not/exist.json
+`,
+	} {
+		pConf, err := schemaDriftProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newSchemaDriftProcFromConfig(pConf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
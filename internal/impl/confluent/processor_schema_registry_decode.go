@@ -189,6 +189,10 @@ func extractID(b []byte) (id int, remaining []byte, err error) {
 		err = fmt.Errorf("serialization format version number %v not supported", b[0])
 		return
 	}
+	if len(b) < 5 {
+		err = errors.New("message is too short to contain a schema ID")
+		return
+	}
 	id = int(binary.BigEndian.Uint32(b[1:5]))
 	remaining = b[5:]
 	return
@@ -190,30 +190,35 @@ func (s *schemaRegistryEncoder) ProcessBatch(ctx context.Context, batch service.
 			continue
 		}
 
-		encoder, id, err := s.getEncoder(subject)
-		if err != nil {
+		if err := s.encodeMessage(msg, subject); err != nil {
 			msg.SetError(err)
-			continue
 		}
+	}
+	return []service.MessageBatch{batch}, nil
+}
 
-		if err := encoder(msg); err != nil {
-			msg.SetError(err)
-			continue
-		}
+// encodeMessage encodes a message in place with the latest schema of a subject,
+// prefixing it with the ID of the schema.
+func (s *schemaRegistryEncoder) encodeMessage(msg *service.Message, subject string) error {
+	encoder, id, err := s.getEncoder(subject)
+	if err != nil {
+		return err
+	}
 
-		rawBytes, err := msg.AsBytes()
-		if err != nil {
-			msg.SetError(errors.New("unable to reference encoded message as bytes"))
-			continue
-		}
+	if err := encoder(msg); err != nil {
+		return err
+	}
 
-		if rawBytes, err = insertID(id, rawBytes); err != nil {
-			msg.SetError(err)
-			continue
-		}
-		msg.SetBytes(rawBytes)
+	rawBytes, err := msg.AsBytes()
+	if err != nil {
+		return errors.New("unable to reference encoded message as bytes")
 	}
-	return []service.MessageBatch{batch}, nil
+
+	if rawBytes, err = insertID(id, rawBytes); err != nil {
+		return err
+	}
+	msg.SetBytes(rawBytes)
+	return nil
 }
 
 func (s *schemaRegistryEncoder) Close(ctx context.Context) error {
@@ -0,0 +1,433 @@
+package confluent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// baselineField describes the expected shape of a field within a baseline
+// schema.
+type baselineField struct {
+	// types is the set of JSON types permitted for the field, where an empty
+	// set permits any type.
+	types    map[string]struct{}
+	required bool
+	// open is set for objects that may contain arbitrary fields, such as Avro
+	// maps or JSON Schema objects without any properties defined.
+	open bool
+}
+
+// baselineFields is a flattened representation of a schema, where each field is
+// identified by a dot separated path and the items of arrays are identified by
+// the path segment `*`.
+type baselineFields map[string]*baselineField
+
+// schemaBaseline is a schema that documents can be compared against.
+type schemaBaseline struct {
+	fields baselineFields
+	// requiredChildren maps the path of each object to the paths of its
+	// required fields.
+	requiredChildren map[string][]string
+}
+
+func newSchemaBaseline(fields baselineFields) *schemaBaseline {
+	b := &schemaBaseline{
+		fields:           fields,
+		requiredChildren: map[string][]string{},
+	}
+	for p, f := range fields {
+		if !f.required || p == "" {
+			continue
+		}
+		parent := ""
+		if i := strings.LastIndex(p, "."); i >= 0 {
+			parent = p[:i]
+		}
+		b.requiredChildren[parent] = append(b.requiredChildren[parent], p)
+	}
+	for _, children := range b.requiredChildren {
+		sort.Strings(children)
+	}
+	return b
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// parseSchemaBaseline parses a JSON Schema or Avro schema document into a
+// baseline, the format is detected from the structure of the document.
+func parseSchemaBaseline(schema []byte) (*schemaBaseline, error) {
+	var root any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	if obj, ok := root.(map[string]any); ok {
+		if t, _ := obj["type"].(string); t == "record" {
+			return parseAvroBaseline(obj)
+		}
+	}
+	return parseJSONSchemaBaseline(root)
+}
+
+//------------------------------------------------------------------------------
+
+func parseJSONSchemaBaseline(root any) (*schemaBaseline, error) {
+	obj, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected schema to be an object, got %T", root)
+	}
+	b := baselineFields{}
+	rootField := &baselineField{types: map[string]struct{}{}}
+	if err := b.walkJSONSchema("", obj, rootField); err != nil {
+		return nil, err
+	}
+	b[""] = rootField
+	return newSchemaBaseline(b), nil
+}
+
+func (b baselineFields) walkJSONSchema(path string, obj map[string]any, field *baselineField) error {
+	switch t := obj["type"].(type) {
+	case string:
+		field.types[t] = struct{}{}
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				field.types[s] = struct{}{}
+			}
+		}
+	case nil:
+		if _, hasProps := obj["properties"]; hasProps {
+			field.types["object"] = struct{}{}
+		}
+	default:
+		return fmt.Errorf("unexpected type at path '%v': %T", path, t)
+	}
+
+	if props, ok := obj["properties"].(map[string]any); ok {
+		required := map[string]struct{}{}
+		if reqs, ok := obj["required"].([]any); ok {
+			for _, r := range reqs {
+				if s, ok := r.(string); ok {
+					required[s] = struct{}{}
+				}
+			}
+		}
+		for k, v := range props {
+			childObj, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			childPath := joinPath(path, k)
+			child := &baselineField{types: map[string]struct{}{}}
+			_, child.required = required[k]
+			if err := b.walkJSONSchema(childPath, childObj, child); err != nil {
+				return err
+			}
+			b[childPath] = child
+		}
+	} else if _, isObj := field.types["object"]; isObj || len(field.types) == 0 {
+		field.open = true
+	}
+
+	if items, ok := obj["items"].(map[string]any); ok {
+		itemPath := joinPath(path, "*")
+		item := &baselineField{types: map[string]struct{}{}}
+		if err := b.walkJSONSchema(itemPath, items, item); err != nil {
+			return err
+		}
+		b[itemPath] = item
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+var avroPrimitiveTypes = map[string]string{
+	"null":    "null",
+	"boolean": "boolean",
+	"int":     "integer",
+	"long":    "integer",
+	"float":   "number",
+	"double":  "number",
+	"bytes":   "string",
+	"string":  "string",
+}
+
+func parseAvroBaseline(root map[string]any) (*schemaBaseline, error) {
+	b := baselineFields{}
+	rootField := &baselineField{types: map[string]struct{}{}}
+	if err := b.walkAvro("", root, rootField); err != nil {
+		return nil, err
+	}
+	b[""] = rootField
+	return newSchemaBaseline(b), nil
+}
+
+func (b baselineFields) walkAvro(path string, t any, field *baselineField) error {
+	switch v := t.(type) {
+	case string:
+		if jType, exists := avroPrimitiveTypes[v]; exists {
+			field.types[jType] = struct{}{}
+		} else {
+			// A reference to a named type, which could be anything.
+			field.open = true
+		}
+	case []any:
+		for _, ut := range v {
+			if err := b.walkAvro(path, ut, field); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		typeName, _ := v["type"].(string)
+		switch typeName {
+		case "record":
+			field.types["object"] = struct{}{}
+			fields, _ := v["fields"].([]any)
+			for _, f := range fields {
+				fObj, ok := f.(map[string]any)
+				if !ok {
+					return fmt.Errorf("expected record field at path '%v' to be an object, got %T", path, f)
+				}
+				name, _ := fObj["name"].(string)
+				if name == "" {
+					return fmt.Errorf("record field at path '%v' is missing a name", path)
+				}
+				childPath := joinPath(path, name)
+				child := &baselineField{types: map[string]struct{}{}}
+				if err := b.walkAvro(childPath, fObj["type"], child); err != nil {
+					return err
+				}
+				_, hasDefault := fObj["default"]
+				_, nullable := child.types["null"]
+				child.required = !hasDefault && !nullable
+				b[childPath] = child
+			}
+		case "array":
+			field.types["array"] = struct{}{}
+			itemPath := joinPath(path, "*")
+			item := &baselineField{types: map[string]struct{}{}}
+			if err := b.walkAvro(itemPath, v["items"], item); err != nil {
+				return err
+			}
+			b[itemPath] = item
+		case "map":
+			field.types["object"] = struct{}{}
+			field.open = true
+		case "enum", "fixed":
+			field.types["string"] = struct{}{}
+		case "":
+			return fmt.Errorf("schema at path '%v' is missing a type", path)
+		default:
+			// Primitive types with logical type annotations.
+			return b.walkAvro(path, typeName, field)
+		}
+	default:
+		return fmt.Errorf("unexpected schema type at path '%v': %T", path, t)
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func jsonTypeOf(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string, []byte:
+		return "string"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if t == float64(int64(t)) {
+			return "integer"
+		}
+		return "number"
+	case float32:
+		if t == float32(int64(t)) {
+			return "integer"
+		}
+		return "number"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return "integer"
+	}
+	return "unknown"
+}
+
+func (f *baselineField) permits(jType string) bool {
+	if len(f.types) == 0 {
+		return true
+	}
+	if _, exists := f.types[jType]; exists {
+		return true
+	}
+	if jType == "integer" {
+		_, exists := f.types["number"]
+		return exists
+	}
+	return false
+}
+
+// retypedField describes a field that is present within a baseline but has an
+// unexpected type.
+type retypedField struct {
+	Path     string
+	Expected []string
+	Actual   string
+}
+
+// schemaDrift describes the differences between a document and a baseline.
+type schemaDrift struct {
+	New     []string
+	Removed []string
+	Retyped []retypedField
+}
+
+func (d *schemaDrift) empty() bool {
+	return len(d.New) == 0 && len(d.Removed) == 0 && len(d.Retyped) == 0
+}
+
+func (d *schemaDrift) summary() string {
+	var parts []string
+	if len(d.New) > 0 {
+		parts = append(parts, "new fields: "+strings.Join(d.New, ", "))
+	}
+	if len(d.Removed) > 0 {
+		parts = append(parts, "removed fields: "+strings.Join(d.Removed, ", "))
+	}
+	if len(d.Retyped) > 0 {
+		retyped := make([]string, len(d.Retyped))
+		for i, r := range d.Retyped {
+			retyped[i] = fmt.Sprintf("%v (%v)", r.Path, r.Actual)
+		}
+		parts = append(parts, "retyped fields: "+strings.Join(retyped, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (d *schemaDrift) toStructured() map[string]any {
+	newFields := make([]any, len(d.New))
+	for i, p := range d.New {
+		newFields[i] = p
+	}
+	removed := make([]any, len(d.Removed))
+	for i, p := range d.Removed {
+		removed[i] = p
+	}
+	retyped := make([]any, len(d.Retyped))
+	for i, r := range d.Retyped {
+		expected := make([]any, len(r.Expected))
+		for j, e := range r.Expected {
+			expected[j] = e
+		}
+		retyped[i] = map[string]any{
+			"path":     r.Path,
+			"expected": expected,
+			"actual":   r.Actual,
+		}
+	}
+	return map[string]any{
+		"new":     newFields,
+		"removed": removed,
+		"retyped": retyped,
+	}
+}
+
+// compare walks a structured document and returns the ways in which it drifts
+// from the baseline.
+func (b *schemaBaseline) compare(doc any) *schemaDrift {
+	d := &schemaDrift{}
+	b.compareValue("", doc, d, driftSeen{
+		newFields: map[string]struct{}{},
+		removed:   map[string]struct{}{},
+		retyped:   map[string]struct{}{},
+	})
+
+	sort.Strings(d.New)
+	sort.Strings(d.Removed)
+	sort.Slice(d.Retyped, func(i, j int) bool {
+		return d.Retyped[i].Path < d.Retyped[j].Path
+	})
+	return d
+}
+
+// driftSeen tracks the paths already reported within a drift, as array items
+// share the same path.
+type driftSeen struct {
+	newFields map[string]struct{}
+	removed   map[string]struct{}
+	retyped   map[string]struct{}
+}
+
+func (b *schemaBaseline) compareValue(path string, v any, d *schemaDrift, seen driftSeen) {
+	field := b.fields[path]
+
+	jType := jsonTypeOf(v)
+	if !field.permits(jType) {
+		if _, exists := seen.retyped[path]; !exists {
+			seen.retyped[path] = struct{}{}
+			expected := make([]string, 0, len(field.types))
+			for t := range field.types {
+				expected = append(expected, t)
+			}
+			sort.Strings(expected)
+			d.Retyped = append(d.Retyped, retypedField{
+				Path:     path,
+				Expected: expected,
+				Actual:   jType,
+			})
+		}
+		return
+	}
+	if field.open {
+		return
+	}
+
+	switch t := v.(type) {
+	case map[string]any:
+		for k, cv := range t {
+			childPath := joinPath(path, k)
+			if _, exists := b.fields[childPath]; !exists {
+				if _, exists := seen.newFields[childPath]; !exists {
+					seen.newFields[childPath] = struct{}{}
+					d.New = append(d.New, childPath)
+				}
+				continue
+			}
+			b.compareValue(childPath, cv, d, seen)
+		}
+		for _, childPath := range b.requiredChildren[path] {
+			key := childPath[strings.LastIndex(childPath, ".")+1:]
+			if _, exists := t[key]; exists {
+				continue
+			}
+			if _, exists := seen.removed[childPath]; !exists {
+				seen.removed[childPath] = struct{}{}
+				d.Removed = append(d.Removed, childPath)
+			}
+		}
+	case []any:
+		itemPath := joinPath(path, "*")
+		if _, exists := b.fields[itemPath]; !exists {
+			return
+		}
+		for _, cv := range t {
+			b.compareValue(itemPath, cv, d, seen)
+		}
+	}
+}
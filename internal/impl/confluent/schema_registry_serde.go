@@ -0,0 +1,211 @@
+package confluent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/httpclient"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	srsFieldURL                 = "url"
+	srsFieldAvroRawJSON         = "avro_raw_json"
+	srsFieldSubjectNameStrategy = "subject_name_strategy"
+	srsFieldRecordName          = "record_name"
+	srsFieldRefreshPeriod       = "refresh_period"
+	srsFieldTLS                 = "tls"
+)
+
+func srsCommonFields() []*service.ConfigField {
+	fields := []*service.ConfigField{
+		service.NewURLField(srsFieldURL).
+			Description("The base URL of the schema registry service."),
+		service.NewBoolField(srsFieldAvroRawJSON).
+			Description("Whether Avro messages should be represented as normal JSON rather than [Avro JSON](https://avro.apache.org/docs/current/specification/_print/#json-encoding). This has the same behaviour as the field of the same name in the `schema_registry_decode` and `schema_registry_encode` processors.").
+			Advanced().Default(false),
+	}
+	fields = append(fields, httpclient.AuthFieldSpecs()...)
+	return append(fields, service.NewTLSField(srsFieldTLS))
+}
+
+// SchemaRegistryDecodeField returns a config field that enables the decoding of
+// consumed messages from the Confluent Schema Registry wire format.
+func SchemaRegistryDecodeField(name string) *service.ConfigField {
+	return service.NewObjectField(name, srsCommonFields()...).
+		Description("Decode consumed messages in the [Confluent Schema Registry wire format](https://docs.confluent.io/platform/current/schema-registry/fundamentals/serdes-develop/index.html#wire-format) into JSON documents, with Avro, Protobuf and JSON schemas obtained by the schema ID of each message and cached. Messages that fail to be decoded remain unchanged and are flagged with the error, which can be caught using error handling methods outlined [here](/docs/configuration/error_handling).").
+		Optional().
+		Advanced().
+		Version("4.28.0")
+}
+
+// SchemaRegistryEncodeField returns a config field that enables the encoding of
+// messages into the Confluent Schema Registry wire format before they are
+// written.
+func SchemaRegistryEncodeField(name string) *service.ConfigField {
+	fields := []*service.ConfigField{
+		service.NewStringAnnotatedEnumField(srsFieldSubjectNameStrategy, map[string]string{
+			"topic_name":        "The subject is the topic of a message followed by `-value`.",
+			"record_name":       "The subject is the `record_name`.",
+			"topic_record_name": "The subject is the topic of a message followed by `-` and the `record_name`.",
+		}).
+			Description("The strategy used to derive the subject of the schema that each message is encoded with.").
+			Default("topic_name"),
+		service.NewInterpolatedStringField(srsFieldRecordName).
+			Description("The fully qualified name of the record type of messages, which is required by the `record_name` and `topic_record_name` strategies.").
+			Example("com.example.Order").
+			Optional(),
+		service.NewDurationField(srsFieldRefreshPeriod).
+			Description("The period after which the latest schema of a subject is obtained again from the schema registry service.").
+			Default("10m").
+			Advanced(),
+	}
+	return service.NewObjectField(name, append(srsCommonFields(), fields...)...).
+		Description("Encode messages into the [Confluent Schema Registry wire format](https://docs.confluent.io/platform/current/schema-registry/fundamentals/serdes-develop/index.html#wire-format) with the latest Avro, Protobuf or JSON schema of a subject, which is cached and refreshed periodically. Messages that fail to be encoded, including those that do not match the schema, are not written and are rejected with the error.").
+		Optional().
+		Advanced().
+		Version("4.28.0")
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryDecoder decodes messages from the Confluent Schema Registry
+// wire format.
+type SchemaRegistryDecoder struct {
+	d *schemaRegistryDecoder
+}
+
+// NewSchemaRegistryDecoderFromParsed creates a decoder from a parsed config
+// namespaced to a field created with SchemaRegistryDecodeField.
+func NewSchemaRegistryDecoderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*SchemaRegistryDecoder, error) {
+	urlStr, err := conf.FieldString(srsFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS(srsFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	authSigner, err := httpclient.AuthSignerFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	avroRawJSON, err := conf.FieldBool(srsFieldAvroRawJSON)
+	if err != nil {
+		return nil, err
+	}
+	d, err := newSchemaRegistryDecoder(urlStr, authSigner, tlsConf, avroRawJSON, mgr)
+	if err != nil {
+		return nil, err
+	}
+	return &SchemaRegistryDecoder{d: d}, nil
+}
+
+// DecodeBatch decodes each message of a batch in place. Messages that fail to
+// be decoded are left unchanged and flagged with the error.
+func (s *SchemaRegistryDecoder) DecodeBatch(ctx context.Context, batch service.MessageBatch) {
+	for i, msg := range batch {
+		res, err := s.d.Process(ctx, msg.Copy())
+		if err != nil {
+			msg.SetError(fmt.Errorf("failed to decode message: %w", err))
+			continue
+		}
+		batch[i] = res[0]
+	}
+}
+
+// Close stops the decoder from refreshing its cached schemas.
+func (s *SchemaRegistryDecoder) Close(ctx context.Context) error {
+	return s.d.Close(ctx)
+}
+
+//------------------------------------------------------------------------------
+
+// SchemaRegistryEncoder encodes messages into the Confluent Schema Registry
+// wire format.
+type SchemaRegistryEncoder struct {
+	e          *schemaRegistryEncoder
+	strategy   string
+	recordName *service.InterpolatedString
+}
+
+// NewSchemaRegistryEncoderFromParsed creates an encoder from a parsed config
+// namespaced to a field created with SchemaRegistryEncodeField.
+func NewSchemaRegistryEncoderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*SchemaRegistryEncoder, error) {
+	s := &SchemaRegistryEncoder{}
+
+	var err error
+	if s.strategy, err = conf.FieldString(srsFieldSubjectNameStrategy); err != nil {
+		return nil, err
+	}
+	if conf.Contains(srsFieldRecordName) {
+		if s.recordName, err = conf.FieldInterpolatedString(srsFieldRecordName); err != nil {
+			return nil, err
+		}
+	}
+	if s.strategy != "topic_name" && s.recordName == nil {
+		return nil, fmt.Errorf("a %v must be specified for the %v subject name strategy", srsFieldRecordName, s.strategy)
+	}
+
+	urlStr, err := conf.FieldString(srsFieldURL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConf, err := conf.FieldTLS(srsFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	authSigner, err := httpclient.AuthSignerFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+	avroRawJSON, err := conf.FieldBool(srsFieldAvroRawJSON)
+	if err != nil {
+		return nil, err
+	}
+	refreshPeriod, err := conf.FieldDuration(srsFieldRefreshPeriod)
+	if err != nil {
+		return nil, err
+	}
+	refreshTicker := refreshPeriod / 10
+	if refreshTicker < time.Second {
+		refreshTicker = time.Second
+	}
+
+	if s.e, err = newSchemaRegistryEncoder(urlStr, authSigner, tlsConf, nil, avroRawJSON, refreshPeriod, refreshTicker, mgr); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Subject returns the subject that a message of a batch written to a topic is
+// encoded with.
+func (s *SchemaRegistryEncoder) Subject(batch service.MessageBatch, index int, topic string) (string, error) {
+	if s.strategy == "topic_name" {
+		return topic + "-value", nil
+	}
+
+	recordName, err := batch.TryInterpolatedString(index, s.recordName)
+	if err != nil {
+		return "", fmt.Errorf("record name interpolation error: %w", err)
+	}
+	if recordName == "" {
+		return "", errors.New("record name is empty")
+	}
+	if s.strategy == "record_name" {
+		return recordName, nil
+	}
+	return topic + "-" + recordName, nil
+}
+
+// Encode encodes a message in place with the latest schema of a subject.
+func (s *SchemaRegistryEncoder) Encode(msg *service.Message, subject string) error {
+	return s.e.encodeMessage(msg, subject)
+}
+
+// Close stops the encoder from refreshing its cached schemas.
+func (s *SchemaRegistryEncoder) Close(ctx context.Context) error {
+	return s.e.Close(ctx)
+}
@@ -0,0 +1,155 @@
+package confluent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestSchemaRegistrySerdeSubjects(t *testing.T) {
+	spec := service.NewConfigSpec().Field(SchemaRegistryEncodeField("schema_registry"))
+
+	tests := []struct {
+		name        string
+		config      string
+		expected    string
+		errContains string
+	}{
+		{
+			name: "topic name",
+			config: `
+schema_registry:
+  url: http://example.com
+`,
+			expected: "orders-value",
+		},
+		{
+			name: "record name",
+			config: `
+schema_registry:
+  url: http://example.com
+  subject_name_strategy: record_name
+  record_name: com.example.${! @type }
+`,
+			expected: "com.example.Order",
+		},
+		{
+			name: "topic record name",
+			config: `
+schema_registry:
+  url: http://example.com
+  subject_name_strategy: topic_record_name
+  record_name: com.example.Order
+`,
+			expected: "orders-com.example.Order",
+		},
+		{
+			name: "missing record name",
+			config: `
+schema_registry:
+  url: http://example.com
+  subject_name_strategy: record_name
+`,
+			errContains: "a record_name must be specified",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := spec.ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			e, err := NewSchemaRegistryEncoderFromParsed(pConf.Namespace("schema_registry"), service.MockResources())
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				_ = e.Close(context.Background())
+			})
+
+			msg := service.NewMessage(nil)
+			msg.MetaSetMut("type", "Order")
+
+			subject, err := e.Subject(service.MessageBatch{msg}, 0, "orders")
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, subject)
+		})
+	}
+}
+
+func TestSchemaRegistrySerdeRoundTrip(t *testing.T) {
+	schemaRes, err := json.Marshal(struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+		ID         int    `json:"id"`
+	}{
+		Schema:     testJSONSchema,
+		SchemaType: "JSON",
+		ID:         3,
+	})
+	require.NoError(t, err)
+
+	urlStr := runSchemaRegistryServer(t, func(path string) ([]byte, error) {
+		switch path {
+		case "/subjects/orders-value/versions/latest", "/schemas/ids/3":
+			return schemaRes, nil
+		}
+		return nil, errors.New("nope")
+	})
+
+	spec := service.NewConfigSpec().
+		Field(SchemaRegistryEncodeField("encode")).
+		Field(SchemaRegistryDecodeField("decode"))
+	pConf, err := spec.ParseYAML(`
+encode:
+  url: `+urlStr+`
+decode:
+  url: `+urlStr+`
+`, nil)
+	require.NoError(t, err)
+
+	encoder, err := NewSchemaRegistryEncoderFromParsed(pConf.Namespace("encode"), service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = encoder.Close(context.Background())
+	})
+
+	decoder, err := NewSchemaRegistryDecoderFromParsed(pConf.Namespace("decode"), service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = decoder.Close(context.Background())
+	})
+
+	msg := service.NewMessage([]byte(`{"Name":"foo","MaybeHobby":null}`))
+	require.NoError(t, encoder.Encode(msg, "orders-value"))
+
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "\x00\x00\x00\x00\x03{\"Name\":\"foo\",\"MaybeHobby\":null}", string(mBytes))
+
+	badMsg := service.NewMessage([]byte(`{"Address":"not this","Name":"foo"}`))
+	assert.Error(t, encoder.Encode(badMsg, "orders-value"))
+
+	batch := service.MessageBatch{msg, service.NewMessage([]byte("not encoded"))}
+	decoder.DecodeBatch(context.Background(), batch)
+
+	mBytes, err = batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Name":"foo","MaybeHobby":null}`, string(mBytes))
+	assert.NoError(t, batch[0].GetError())
+
+	mBytes, err = batch[1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "not encoded", string(mBytes))
+	assert.Error(t, batch[1].GetError())
+}
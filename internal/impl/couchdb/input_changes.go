@@ -0,0 +1,524 @@
+package couchdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/checkpoint"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ccFieldURL             = "url"
+	ccFieldDatabase        = "database"
+	ccFieldUsername        = "username"
+	ccFieldPassword        = "password"
+	ccFieldTLS             = "tls"
+	ccFieldIncludeDocs     = "include_docs"
+	ccFieldFilter          = "filter"
+	ccFieldSelector        = "selector"
+	ccFieldSince           = "since"
+	ccFieldHeartbeat       = "heartbeat"
+	ccFieldCheckpointCache = "checkpoint_cache"
+	ccFieldCheckpointKey   = "checkpoint_key"
+	ccFieldCheckpointLimit = "checkpoint_limit"
+)
+
+func changesInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Consumes document changes from the `_changes` feed of a CouchDB database in continuous mode.").
+		Description(`
+Each change within the feed is emitted as a structured message containing the fields `+"`seq`, `id`, `changes` and `deleted`"+`, and when `+"`include_docs`"+` is enabled the document itself within the field `+"`doc`"+`.
+
+### Checkpointing
+
+When a `+"`checkpoint_cache`"+` is configured the sequence ID of the latest change that has been acknowledged, along with all prior changes, is stored within the cache. Upon starting the feed resumes from the stored sequence ID, otherwise the feed starts from the sequence ID set with `+"`since`"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- couchdb_database
+- couchdb_id
+- couchdb_seq
+- couchdb_rev
+- couchdb_deleted
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewURLField(ccFieldURL).
+				Description("The URL of the CouchDB server.").
+				Example("http://localhost:5984"),
+			service.NewStringField(ccFieldDatabase).
+				Description("The name of the database to consume changes from."),
+			service.NewStringField(ccFieldUsername).
+				Description("An optional username for basic authentication.").
+				Default(""),
+			service.NewStringField(ccFieldPassword).
+				Description("An optional password for basic authentication.").
+				Secret().
+				Default(""),
+			service.NewTLSToggledField(ccFieldTLS),
+			service.NewBoolField(ccFieldIncludeDocs).
+				Description("Whether to include the contents of each changed document within messages.").
+				Default(true),
+			service.NewStringField(ccFieldFilter).
+				Description("An optional filter function, in the form `designdoc/filtername`, used to filter changes on the server.").
+				Example("app/important").
+				Optional().
+				Advanced(),
+			service.NewStringField(ccFieldSelector).
+				Description("An optional [Mango selector](https://docs.couchdb.org/en/stable/api/database/find.html#find-selectors) as a JSON object, used to filter changes on the server. This field cannot be combined with `filter`.").
+				Example(`{"type":"order"}`).
+				Optional().
+				Advanced(),
+			service.NewStringField(ccFieldSince).
+				Description("The sequence ID to begin consuming changes from when no checkpoint has been stored. The value `now` consumes only changes that occur after connecting, and `0` consumes all changes from the beginning of the database.").
+				Examples("now", "0").
+				Default("now"),
+			service.NewDurationField(ccFieldHeartbeat).
+				Description("The period after which the server sends an empty line when no changes have occurred, keeping the connection alive.").
+				Default("30s").
+				Advanced(),
+			service.NewStringField(ccFieldCheckpointCache).
+				Description("A [cache resource](/docs/components/caches/about) in which the sequence ID of the latest acknowledged change is stored.").
+				Optional(),
+			service.NewStringField(ccFieldCheckpointKey).
+				Description("The key under which the sequence ID of the latest acknowledged change is stored.").
+				Default("couchdb_changes_seq").
+				Advanced(),
+			service.NewIntField(ccFieldCheckpointLimit).
+				Description("The maximum number of changes that can be pending acknowledgement at any given time.").
+				Default(1024).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Replicate Orders", "Changes to order documents are consumed from the beginning of the database and written to Kafka, with progress checkpointed within a file cache so that restarts resume from the last acknowledged change.", `
+input:
+  couchdb_changes:
+    url: http://localhost:5984
+    database: shop
+    username: benthos
+    password: ${COUCHDB_PASSWORD}
+    selector: '{"type":"order"}'
+    since: "0"
+    checkpoint_cache: checkpoints
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders
+    key: ${! @couchdb_id }
+
+cache_resources:
+  - label: checkpoints
+    file:
+      directory: ./checkpoints
+`)
+}
+
+func init() {
+	err := service.RegisterInput("couchdb_changes", changesInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newChangesInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type couchChange struct {
+	raw      []byte
+	seq      string
+	id       string
+	rev      string
+	deleted  bool
+	finished bool
+}
+
+type changesInput struct {
+	url         *url.URL
+	database    string
+	username    string
+	password    string
+	includeDocs bool
+	filter      string
+	selector    []byte
+	heartbeat   time.Duration
+
+	cache    string
+	cacheKey string
+
+	client       *http.Client
+	checkpointer *checkpoint.Capped[string]
+
+	connMut   sync.Mutex
+	resumed   bool
+	since     string
+	changesCh chan couchChange
+	errCh     chan error
+	closeFn   func()
+
+	mgr     *service.Resources
+	log     *service.Logger
+	shutSig *shutdown.Signaller
+}
+
+func newChangesInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*changesInput, error) {
+	c := &changesInput{
+		mgr:     mgr,
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if c.url, err = conf.FieldURL(ccFieldURL); err != nil {
+		return nil, err
+	}
+	if c.database, err = conf.FieldString(ccFieldDatabase); err != nil {
+		return nil, err
+	}
+	if c.database == "" {
+		return nil, errors.New("database must not be empty")
+	}
+	if c.username, err = conf.FieldString(ccFieldUsername); err != nil {
+		return nil, err
+	}
+	if c.password, err = conf.FieldString(ccFieldPassword); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(ccFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsEnabled {
+		transport.TLSClientConfig = tlsConf
+	}
+	c.client = &http.Client{Transport: transport}
+
+	if c.includeDocs, err = conf.FieldBool(ccFieldIncludeDocs); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ccFieldFilter) {
+		if c.filter, err = conf.FieldString(ccFieldFilter); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(ccFieldSelector) {
+		selectorStr, err := conf.FieldString(ccFieldSelector)
+		if err != nil {
+			return nil, err
+		}
+		if c.filter != "" {
+			return nil, errors.New("a selector cannot be combined with a filter")
+		}
+		var selector any
+		if err := json.Unmarshal([]byte(selectorStr), &selector); err != nil {
+			return nil, fmt.Errorf("failed to parse selector: %w", err)
+		}
+		if c.selector, err = json.Marshal(map[string]any{"selector": selector}); err != nil {
+			return nil, err
+		}
+	}
+	if c.since, err = conf.FieldString(ccFieldSince); err != nil {
+		return nil, err
+	}
+	if c.heartbeat, err = conf.FieldDuration(ccFieldHeartbeat); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(ccFieldCheckpointCache) {
+		if c.cache, err = conf.FieldString(ccFieldCheckpointCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(c.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", c.cache)
+		}
+	}
+	if c.cacheKey, err = conf.FieldString(ccFieldCheckpointKey); err != nil {
+		return nil, err
+	}
+	limit, err := conf.FieldInt(ccFieldCheckpointLimit)
+	if err != nil {
+		return nil, err
+	}
+	c.checkpointer = checkpoint.NewCapped[string](int64(limit))
+	return c, nil
+}
+
+func (c *changesInput) changesURL(since string) string {
+	u := c.url.JoinPath(url.PathEscape(c.database), "_changes")
+
+	q := url.Values{}
+	q.Set("feed", "continuous")
+	q.Set("since", since)
+	q.Set("heartbeat", strconv.FormatInt(c.heartbeat.Milliseconds(), 10))
+	if c.includeDocs {
+		q.Set("include_docs", "true")
+	}
+	if c.filter != "" {
+		q.Set("filter", c.filter)
+	}
+	if c.selector != nil {
+		q.Set("filter", "_selector")
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func (c *changesInput) readCheckpoint(ctx context.Context) (string, error) {
+	if c.cache == "" {
+		return "", nil
+	}
+	var seqBytes []byte
+	var cErr error
+	if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+		seqBytes, cErr = cache.Get(ctx, c.cacheKey)
+	}); err != nil {
+		return "", err
+	}
+	if errors.Is(cErr, service.ErrKeyNotFound) {
+		return "", nil
+	}
+	if cErr != nil {
+		return "", cErr
+	}
+	return string(seqBytes), nil
+}
+
+func (c *changesInput) Connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.changesCh != nil {
+		return nil
+	}
+
+	// A stored checkpoint only takes precedence upon the first connection,
+	// after which reconnects resume from the latest change read.
+	if !c.resumed {
+		seq, err := c.readCheckpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to obtain checkpoint: %w", err)
+		}
+		if seq != "" {
+			c.since = seq
+		}
+	}
+
+	feedCtx, feedDone := c.shutSig.SoftStopCtx(context.Background())
+
+	method, body := http.MethodGet, io.Reader(nil)
+	if c.selector != nil {
+		method, body = http.MethodPost, bytes.NewReader(c.selector)
+	}
+	req, err := http.NewRequestWithContext(feedCtx, method, c.changesURL(c.since), body)
+	if err != nil {
+		feedDone()
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.selector != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		feedDone()
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		_ = res.Body.Close()
+		feedDone()
+		return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, strings.TrimSpace(string(resBody)))
+	}
+
+	changesCh, errCh := make(chan couchChange), make(chan error, 1)
+	go func() {
+		defer close(changesCh)
+		errCh <- readChanges(feedCtx, res.Body, changesCh)
+	}()
+
+	c.resumed = true
+	c.changesCh, c.errCh = changesCh, errCh
+	c.closeFn = func() {
+		feedDone()
+		_ = res.Body.Close()
+	}
+	return nil
+}
+
+// readChanges parses the lines of a continuous changes feed until the feed
+// ends or the context is cancelled.
+func readChanges(ctx context.Context, body io.Reader, changesCh chan<- couchChange) error {
+	rdr := bufio.NewReader(body)
+	for {
+		line, err := rdr.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			change, pErr := parseChange(line)
+			if pErr != nil {
+				return pErr
+			}
+			select {
+			case changesCh <- change:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			if change.finished {
+				return nil
+			}
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func seqString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+func parseChange(line []byte) (couchChange, error) {
+	var rawChange struct {
+		Seq     json.RawMessage `json:"seq"`
+		LastSeq json.RawMessage `json:"last_seq"`
+		ID      string          `json:"id"`
+		Deleted bool            `json:"deleted"`
+		Changes []struct {
+			Rev string `json:"rev"`
+		} `json:"changes"`
+	}
+	if err := json.Unmarshal(line, &rawChange); err != nil {
+		return couchChange{}, fmt.Errorf("failed to parse change: %w", err)
+	}
+	if rawChange.ID == "" && rawChange.LastSeq != nil {
+		return couchChange{seq: seqString(rawChange.LastSeq), finished: true}, nil
+	}
+
+	change := couchChange{
+		raw:     line,
+		seq:     seqString(rawChange.Seq),
+		id:      rawChange.ID,
+		deleted: rawChange.Deleted,
+	}
+	if len(rawChange.Changes) > 0 {
+		change.rev = rawChange.Changes[0].Rev
+	}
+	return change, nil
+}
+
+func (c *changesInput) disconnect() {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.closeFn != nil {
+		c.closeFn()
+		c.closeFn = nil
+	}
+	c.changesCh, c.errCh = nil, nil
+}
+
+func (c *changesInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	c.connMut.Lock()
+	changesCh, errCh := c.changesCh, c.errCh
+	c.connMut.Unlock()
+
+	if changesCh == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var change couchChange
+	var open bool
+	select {
+	case change, open = <-changesCh:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	if !open || change.finished {
+		if change.finished {
+			c.connMut.Lock()
+			c.since = change.seq
+			c.connMut.Unlock()
+		}
+		c.disconnect()
+		if !open {
+			if err := <-errCh; err != nil {
+				c.log.Errorf("Changes feed failed: %v", err)
+			}
+		}
+		return nil, nil, service.ErrNotConnected
+	}
+
+	c.connMut.Lock()
+	c.since = change.seq
+	c.connMut.Unlock()
+
+	msg := service.NewMessage(change.raw)
+	msg.MetaSetMut("couchdb_database", c.database)
+	msg.MetaSetMut("couchdb_id", change.id)
+	msg.MetaSetMut("couchdb_seq", change.seq)
+	msg.MetaSetMut("couchdb_rev", change.rev)
+	msg.MetaSetMut("couchdb_deleted", change.deleted)
+
+	release, err := c.checkpointer.Track(ctx, change.seq, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msg, func(ctx context.Context, err error) error {
+		highestSeq := release()
+		if highestSeq == nil || c.cache == "" {
+			return nil
+		}
+		var setErr error
+		if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+			setErr = cache.Set(ctx, c.cacheKey, []byte(*highestSeq), nil)
+		}); err != nil {
+			return err
+		}
+		return setErr
+	}, nil
+}
+
+func (c *changesInput) Close(ctx context.Context) error {
+	c.shutSig.TriggerSoftStop()
+	c.disconnect()
+	return nil
+}
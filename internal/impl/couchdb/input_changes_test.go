@@ -0,0 +1,159 @@
+package couchdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestParseChange(t *testing.T) {
+	change, err := parseChange([]byte(`{"seq":"3-abc","id":"foo","changes":[{"rev":"2-def"}],"deleted":true}`))
+	require.NoError(t, err)
+	assert.Equal(t, "3-abc", change.seq)
+	assert.Equal(t, "foo", change.id)
+	assert.Equal(t, "2-def", change.rev)
+	assert.True(t, change.deleted)
+	assert.False(t, change.finished)
+
+	change, err = parseChange([]byte(`{"seq":12,"id":"bar","changes":[{"rev":"1-abc"}]}`))
+	require.NoError(t, err)
+	assert.Equal(t, "12", change.seq)
+	assert.False(t, change.deleted)
+
+	change, err = parseChange([]byte(`{"last_seq":"5-xyz","pending":0}`))
+	require.NoError(t, err)
+	assert.Equal(t, "5-xyz", change.seq)
+	assert.True(t, change.finished)
+
+	_, err = parseChange([]byte(`not json`))
+	require.Error(t, err)
+}
+
+func TestChangesInput(t *testing.T) {
+	var reqMut sync.Mutex
+	var sinces []string
+	var authed bool
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/shop/_changes", r.URL.Path)
+		assert.Equal(t, "continuous", r.URL.Query().Get("feed"))
+		assert.Equal(t, "true", r.URL.Query().Get("include_docs"))
+
+		reqMut.Lock()
+		since := r.URL.Query().Get("since")
+		sinces = append(sinces, since)
+		_, _, authed = r.BasicAuth()
+		reqMut.Unlock()
+
+		switch since {
+		case "1-a":
+			_, _ = fmt.Fprintln(w, `{"seq":"2-b","id":"foo","changes":[{"rev":"2-x"}],"doc":{"_id":"foo","v":2}}`)
+			_, _ = fmt.Fprintln(w, ``)
+			_, _ = fmt.Fprintln(w, `{"seq":"3-c","id":"bar","changes":[{"rev":"1-y"}],"deleted":true,"doc":{"_id":"bar","_deleted":true}}`)
+			_, _ = fmt.Fprintln(w, `{"last_seq":"3-c","pending":0}`)
+		default:
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := changesInputSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+database: shop
+username: foo
+password: bar
+checkpoint_cache: checkpoints
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	res := service.MockResources(service.MockResourcesOptAddCache("checkpoints"))
+	require.NoError(t, res.AccessCache(context.Background(), "checkpoints", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "couchdb_changes_seq", []byte("1-a"), nil))
+	}))
+
+	i, err := newChangesInputFromParsed(conf, res)
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, i.Connect(ctx))
+
+	msg, ackFn, err := i.Read(ctx)
+	require.NoError(t, err)
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"seq":"2-b","id":"foo","changes":[{"rev":"2-x"}],"doc":{"_id":"foo","v":2}}`, string(mBytes))
+	v, _ := msg.MetaGetMut("couchdb_id")
+	assert.Equal(t, "foo", v)
+	v, _ = msg.MetaGetMut("couchdb_rev")
+	assert.Equal(t, "2-x", v)
+	v, _ = msg.MetaGetMut("couchdb_deleted")
+	assert.Equal(t, false, v)
+
+	msg2, ackFn2, err := i.Read(ctx)
+	require.NoError(t, err)
+	v, _ = msg2.MetaGetMut("couchdb_seq")
+	assert.Equal(t, "3-c", v)
+	v, _ = msg2.MetaGetMut("couchdb_deleted")
+	assert.Equal(t, true, v)
+
+	getCheckpoint := func() string {
+		var seq []byte
+		require.NoError(t, res.AccessCache(ctx, "checkpoints", func(c service.Cache) {
+			seq, err = c.Get(ctx, "couchdb_changes_seq")
+		}))
+		require.NoError(t, err)
+		return string(seq)
+	}
+
+	// Acknowledging out of order must not checkpoint beyond unacknowledged
+	// changes.
+	require.NoError(t, ackFn2(ctx, nil))
+	assert.Equal(t, "1-a", getCheckpoint())
+	require.NoError(t, ackFn(ctx, nil))
+	assert.Equal(t, "3-c", getCheckpoint())
+
+	// The end of the feed triggers a reconnect from the last sequence ID.
+	_, _, err = i.Read(ctx)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+	require.NoError(t, i.Connect(ctx))
+
+	reqMut.Lock()
+	assert.Equal(t, []string{"1-a", "3-c"}, sinces)
+	assert.True(t, authed)
+	reqMut.Unlock()
+
+	require.NoError(t, i.Close(ctx))
+}
+
+func TestChangesInputStatusError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"not_found","reason":"Database does not exist."}`, http.StatusNotFound)
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := changesInputSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+database: nope
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	i, err := newChangesInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = i.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unexpected status code 404")
+	assert.Contains(t, err.Error(), "Database does not exist.")
+}
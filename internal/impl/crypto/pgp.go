@@ -0,0 +1,59 @@
+package crypto
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const pgpMessageType = "PGP MESSAGE"
+
+// readPGPKeyRing parses an ASCII armored key ring and decrypts any private
+// keys within it with a passphrase.
+func readPGPKeyRing(key, passphrase string) (openpgp.EntityList, error) {
+	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key ring: %w", err)
+	}
+
+	decryptKey := func(k *packet.PrivateKey) error {
+		if k == nil || !k.Encrypted {
+			return nil
+		}
+		if passphrase == "" {
+			return errors.New("a passphrase is required to decrypt the private keys")
+		}
+		return k.Decrypt([]byte(passphrase))
+	}
+	for _, e := range keyRing {
+		if err := decryptKey(e.PrivateKey); err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+		}
+		for _, s := range e.Subkeys {
+			if err := decryptKey(s.PrivateKey); err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key: %w", err)
+			}
+		}
+	}
+	return keyRing, nil
+}
+
+// pgpMessageReader returns a reader of a binary OpenPGP message from a reader
+// of a message that is either binary or ASCII armored.
+func pgpMessageReader(r io.Reader) (io.Reader, error) {
+	bRdr := bufio.NewReader(r)
+	if prefix, _ := bRdr.Peek(len("-----BEGIN")); string(prefix) != "-----BEGIN" {
+		return bRdr, nil
+	}
+	block, err := armor.Decode(bRdr)
+	if err != nil {
+		return nil, err
+	}
+	return block.Body, nil
+}
@@ -0,0 +1,289 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ppFieldOperator    = "operator"
+	ppFieldKeyRing     = "key_ring"
+	ppFieldKeyRingFile = "key_ring_file"
+	ppFieldPassphrase  = "passphrase"
+	ppFieldArmor       = "armor"
+
+	pgpOpEncrypt = "encrypt"
+	pgpOpDecrypt = "decrypt"
+	pgpOpSign    = "sign"
+	pgpOpVerify  = "verify"
+)
+
+func pgpProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Encrypts, decrypts, signs or verifies messages with OpenPGP.").
+		Description(`
+The keys used by this processor are read from an ASCII armored key ring, which is either provided within the config, where it can be loaded from the environment with [environment variable interpolation](/docs/configuration/interpolation#environment-variables), or read from a file.
+
+### Operators
+
+#### `+"`encrypt`"+`
+
+Encrypts the contents of each message for all of the keys of the key ring.
+
+#### `+"`decrypt`"+`
+
+Decrypts the contents of each message with the private keys of the key ring. Messages that are binary or ASCII armored are both supported. When a decrypted message was signed by a key of the key ring the signature is also verified, and messages with invalid signatures fail to decrypt.
+
+#### `+"`sign`"+`
+
+Signs the contents of each message with the first private key of the key ring, replacing the contents with a signed OpenPGP message.
+
+#### `+"`verify`"+`
+
+Verifies that each message is an OpenPGP message signed by a key of the key ring, replacing the contents with those that were signed. Messages that are not signed by a key of the key ring, or that have invalid signatures, fail to verify.
+
+### Metadata
+
+The `+"`decrypt` and `verify`"+` operators add the following metadata fields to each signed message:
+
+`+"```text"+`
+- pgp_signer_key_id
+`+"```"+`
+
+Which is the hex encoded ID of the key that signed the message.`).
+		Fields(
+			service.NewStringEnumField(ppFieldOperator, pgpOpEncrypt, pgpOpDecrypt, pgpOpSign, pgpOpVerify).
+				Description("The operation to perform on each message."),
+			service.NewStringField(ppFieldKeyRing).
+				Description("An ASCII armored key ring. Either this field or `"+ppFieldKeyRingFile+"` must be set.").
+				Secret().
+				Optional(),
+			service.NewStringField(ppFieldKeyRingFile).
+				Description("The path of a file containing an ASCII armored key ring. Either this field or `"+ppFieldKeyRing+"` must be set.").
+				Optional(),
+			service.NewStringField(ppFieldPassphrase).
+				Description("An optional passphrase used to decrypt the private keys of the key ring.").
+				Secret().
+				Default(""),
+			service.NewBoolField(ppFieldArmor).
+				Description("Whether the messages produced by the `encrypt` and `sign` operators are ASCII armored, otherwise they are binary.").
+				Default(false),
+		).
+		Example("Partner File Exchange", "Encrypts files for a partner with their public key before they are uploaded over SFTP.", `
+input:
+  file:
+    paths: [ ./outbound/*.csv ]
+    scanner:
+      to_the_end: {}
+
+pipeline:
+  processors:
+    - pgp:
+        operator: encrypt
+        key_ring_file: ./keys/partner.asc
+        armor: true
+
+output:
+  sftp:
+    address: sftp.example.com:22
+    path: /inbound/${! @path.filepath_split().index(-1) }.asc
+    credentials:
+      username: foo
+      password: ${SFTP_PASSWORD}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("pgp", pgpProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newPGPProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pgpProcessor struct {
+	operator string
+	keyRing  openpgp.EntityList
+	signer   *openpgp.Entity
+	armor    bool
+}
+
+func newPGPProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*pgpProcessor, error) {
+	p := &pgpProcessor{}
+
+	var err error
+	if p.operator, err = conf.FieldString(ppFieldOperator); err != nil {
+		return nil, err
+	}
+	if p.armor, err = conf.FieldBool(ppFieldArmor); err != nil {
+		return nil, err
+	}
+
+	hasKeyRing, hasKeyRingFile := conf.Contains(ppFieldKeyRing), conf.Contains(ppFieldKeyRingFile)
+	if hasKeyRing == hasKeyRingFile {
+		return nil, fmt.Errorf("exactly one of %v or %v must be specified", ppFieldKeyRing, ppFieldKeyRingFile)
+	}
+
+	var key string
+	if hasKeyRing {
+		if key, err = conf.FieldString(ppFieldKeyRing); err != nil {
+			return nil, err
+		}
+	} else {
+		var keyPath string
+		if keyPath, err = conf.FieldString(ppFieldKeyRingFile); err != nil {
+			return nil, err
+		}
+		keyBytes, err := ifs.ReadFile(mgr.FS(), keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key ring file: %w", err)
+		}
+		key = string(keyBytes)
+	}
+
+	var passphrase string
+	if passphrase, err = conf.FieldString(ppFieldPassphrase); err != nil {
+		return nil, err
+	}
+	if p.keyRing, err = readPGPKeyRing(key, passphrase); err != nil {
+		return nil, err
+	}
+
+	switch p.operator {
+	case pgpOpDecrypt:
+		if len(p.keyRing.DecryptionKeys()) == 0 {
+			return nil, errors.New("key ring does not contain any private keys")
+		}
+	case pgpOpSign:
+		for _, e := range p.keyRing {
+			if e.PrivateKey != nil {
+				p.signer = e
+				break
+			}
+		}
+		if p.signer == nil {
+			return nil, errors.New("key ring does not contain any private keys")
+		}
+	case pgpOpEncrypt, pgpOpVerify:
+	default:
+		return nil, fmt.Errorf("unrecognised operator: %v", p.operator)
+	}
+	return p, nil
+}
+
+func (p *pgpProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	var signerID uint64
+	switch p.operator {
+	case pgpOpEncrypt:
+		mBytes, err = p.write(mBytes, func(w io.Writer) (io.WriteCloser, error) {
+			return openpgp.Encrypt(w, p.keyRing, nil, nil, nil)
+		})
+	case pgpOpSign:
+		mBytes, err = p.write(mBytes, func(w io.Writer) (io.WriteCloser, error) {
+			return openpgp.Sign(w, p.signer, nil, nil)
+		})
+	default:
+		mBytes, signerID, err = p.read(mBytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg.SetBytes(mBytes)
+	if signerID != 0 {
+		msg.MetaSetMut("pgp_signer_key_id", fmt.Sprintf("%016X", signerID))
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *pgpProcessor) write(mBytes []byte, fn func(w io.Writer) (io.WriteCloser, error)) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var w io.WriteCloser = nopWriteCloser{&buf}
+	if p.armor {
+		var err error
+		if w, err = armor.Encode(&buf, pgpMessageType, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	plainW, err := fn(w)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := plainW.Write(mBytes); err != nil {
+		return nil, err
+	}
+	if err := plainW.Close(); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (p *pgpProcessor) read(mBytes []byte) ([]byte, uint64, error) {
+	r, err := pgpMessageReader(bytes.NewReader(mBytes))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	md, err := openpgp.ReadMessage(r, p.keyRing, nil, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.operator == pgpOpVerify && !md.IsSigned {
+		return nil, 0, errors.New("message is not signed")
+	}
+
+	// Signatures are only checked once the body has been read entirely.
+	body, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !md.IsSigned {
+		return body, 0, nil
+	}
+	if md.SignedBy == nil {
+		if p.operator == pgpOpVerify {
+			return nil, 0, fmt.Errorf("message is signed by unknown key %016X", md.SignedByKeyId)
+		}
+		return body, 0, nil
+	}
+	if md.SignatureError != nil {
+		return nil, 0, fmt.Errorf("invalid signature: %w", md.SignatureError)
+	}
+	return body, md.SignedByKeyId, nil
+}
+
+func (p *pgpProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
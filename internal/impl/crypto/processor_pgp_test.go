@@ -0,0 +1,195 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testPGPEntity(t *testing.T, name string) (entity *openpgp.Entity, privateKey, publicKey string) {
+	t.Helper()
+
+	pgpConf := &packet.Config{RSABits: 1024}
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", pgpConf)
+	require.NoError(t, err)
+	for _, id := range entity.Identities {
+		id.SelfSignature.PreferredHash = []uint8{8} // SHA256
+	}
+
+	var privBuf bytes.Buffer
+	w, err := armor.Encode(&privBuf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(w, pgpConf))
+	require.NoError(t, w.Close())
+
+	var pubBuf bytes.Buffer
+	w, err = armor.Encode(&pubBuf, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.Serialize(w))
+	require.NoError(t, w.Close())
+
+	return entity, privBuf.String(), pubBuf.String()
+}
+
+func testPGPProc(t *testing.T, operator, keyRing string, armor bool) *pgpProcessor {
+	t.Helper()
+
+	pConf, err := pgpProcessorSpec().ParseYAML(fmt.Sprintf(`
+operator: %v
+armor: %v
+key_ring: |
+%v
+`, operator, armor, indent(keyRing)), nil)
+	require.NoError(t, err)
+
+	proc, err := newPGPProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func processPGP(t *testing.T, proc *pgpProcessor, content []byte) (*service.Message, error) {
+	t.Helper()
+
+	batch, err := proc.Process(context.Background(), service.NewMessage(content))
+	if err != nil {
+		return nil, err
+	}
+	require.Len(t, batch, 1)
+	return batch[0], nil
+}
+
+func TestPGPProcessorEncryptDecrypt(t *testing.T) {
+	_, privKey, pubKey := testPGPEntity(t, "recipient")
+
+	for _, armored := range []bool{false, true} {
+		t.Run(fmt.Sprintf("armor %v", armored), func(t *testing.T) {
+			encrypted, err := processPGP(t, testPGPProc(t, "encrypt", pubKey, armored), []byte("hello world"))
+			require.NoError(t, err)
+
+			encBytes, err := encrypted.AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, armored, strings.HasPrefix(string(encBytes), "-----BEGIN PGP MESSAGE-----"))
+			assert.NotContains(t, string(encBytes), "hello world")
+
+			decrypted, err := processPGP(t, testPGPProc(t, "decrypt", privKey, false), encBytes)
+			require.NoError(t, err)
+
+			decBytes, err := decrypted.AsBytes()
+			require.NoError(t, err)
+			assert.Equal(t, "hello world", string(decBytes))
+
+			_, exists := decrypted.MetaGet("pgp_signer_key_id")
+			assert.False(t, exists)
+		})
+	}
+
+	// A key ring without the recipient key cannot decrypt the message.
+	_, otherPrivKey, _ := testPGPEntity(t, "other")
+	encrypted, err := processPGP(t, testPGPProc(t, "encrypt", pubKey, false), []byte("hello world"))
+	require.NoError(t, err)
+
+	encBytes, err := encrypted.AsBytes()
+	require.NoError(t, err)
+
+	_, err = processPGP(t, testPGPProc(t, "decrypt", otherPrivKey, false), encBytes)
+	require.Error(t, err)
+}
+
+func TestPGPProcessorSignVerify(t *testing.T) {
+	signer, privKey, pubKey := testPGPEntity(t, "signer")
+
+	signed, err := processPGP(t, testPGPProc(t, "sign", privKey, true), []byte("hello world"))
+	require.NoError(t, err)
+
+	signedBytes, err := signed.AsBytes()
+	require.NoError(t, err)
+
+	verified, err := processPGP(t, testPGPProc(t, "verify", pubKey, false), signedBytes)
+	require.NoError(t, err)
+
+	verifiedBytes, err := verified.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(verifiedBytes))
+
+	signerID, exists := verified.MetaGet("pgp_signer_key_id")
+	require.True(t, exists)
+	assert.Equal(t, signer.PrimaryKey.KeyIdString(), signerID)
+
+	// Messages signed by an unknown key fail to verify.
+	_, _, otherPubKey := testPGPEntity(t, "other")
+	_, err = processPGP(t, testPGPProc(t, "verify", otherPubKey, false), signedBytes)
+	require.ErrorContains(t, err, "unknown key")
+
+	// Messages that are not signed fail to verify.
+	encrypted, err := processPGP(t, testPGPProc(t, "encrypt", pubKey, false), []byte("hello world"))
+	require.NoError(t, err)
+
+	encBytes, err := encrypted.AsBytes()
+	require.NoError(t, err)
+
+	_, err = processPGP(t, testPGPProc(t, "verify", privKey, false), encBytes)
+	require.ErrorContains(t, err, "not signed")
+}
+
+func TestPGPProcessorKeyRingFile(t *testing.T) {
+	_, privKey, _ := testPGPEntity(t, "recipient")
+
+	keyPath := filepath.Join(t.TempDir(), "key.asc")
+	require.NoError(t, os.WriteFile(keyPath, []byte(privKey), 0o600))
+
+	pConf, err := pgpProcessorSpec().ParseYAML(fmt.Sprintf(`
+operator: sign
+key_ring_file: %v
+`, keyPath), nil)
+	require.NoError(t, err)
+
+	_, err = newPGPProcessorFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+}
+
+func TestPGPProcessorConfigErrors(t *testing.T) {
+	_, _, pubKey := testPGPEntity(t, "recipient")
+
+	for name, test := range map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"no key ring": {
+			conf:        `operator: encrypt`,
+			errContains: "exactly one of",
+		},
+		"sign without private key": {
+			conf:        "operator: sign\nkey_ring: |\n" + indent(pubKey),
+			errContains: "does not contain any private keys",
+		},
+		"decrypt without private key": {
+			conf:        "operator: decrypt\nkey_ring: |\n" + indent(pubKey),
+			errContains: "does not contain any private keys",
+		},
+		"missing file": {
+			conf:        "operator: encrypt\nkey_ring_file: ./does_not_exist.asc",
+			errContains: "failed to read key ring file",
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			pConf, err := pgpProcessorSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newPGPProcessorFromParsed(pConf, service.MockResources())
+			require.ErrorContains(t, err, test.errContains)
+		})
+	}
+}
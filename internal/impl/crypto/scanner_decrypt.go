@@ -0,0 +1,256 @@
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sdFieldAlgorithm  = "algorithm"
+	sdFieldKey        = "key"
+	sdFieldPassphrase = "passphrase"
+	sdFieldChild      = "into"
+
+	decryptAlgAESGCM = "aes_gcm"
+	decryptAlgAge    = "age"
+	decryptAlgPGP    = "pgp"
+)
+
+func decryptScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary("Decrypt the stream of bytes according to an algorithm, before feeding it into a child scanner.").
+		Description(`
+This scanner allows encrypted data, such as objects dropped into a bucket, to be consumed without an extra processor stage, as the decrypted stream is fed into a child scanner that can decompress it with the `+"[`decompress` scanner](/docs/components/scanners/decompress)"+` and break it into messages.
+
+Keys can be provided from the environment with [environment variable interpolation](/docs/configuration/interpolation#environment-variables), and are scrubbed from exported configs.
+
+### Algorithms
+
+#### `+"`aes_gcm`"+`
+
+The stream is expected to consist of a 12 byte nonce followed by the ciphertext and the authentication tag, and the key must be a hex encoded AES key of 16, 24 or 32 bytes.
+
+Since the authentication tag covers the whole stream, and no plaintext should be emitted before it is authenticated, each stream is read into memory entirely before it is decrypted and fed into the child scanner. Memory usage therefore grows with the size of the largest stream, such as the largest object consumed by an input, and `+"`age`"+` or `+"`pgp`"+` should be preferred for large streams.
+
+#### `+"`age`"+`
+
+The stream is expected to be an [age](https://age-encryption.org) encrypted file, either binary or ASCII armored. The key is a list of age identities (`+"`AGE-SECRET-KEY-1...`"+`), one per line, in the format produced by `+"`age-keygen`"+`. Files encrypted with a passphrase are decrypted with the `+"`passphrase`"+` instead, in which case the key can be left empty. Since age authenticates the stream in chunks of 64KiB the stream is decrypted as it is read.
+
+#### `+"`pgp`"+`
+
+The stream is expected to be an OpenPGP message, either binary or ASCII armored, that is encrypted for one of the keys of the ASCII armored private key ring. Messages compressed by OpenPGP are decompressed as they are decrypted, and the stream is decrypted as it is read. Signatures of messages are not verified.`).
+		Fields(
+			service.NewStringEnumField(sdFieldAlgorithm, decryptAlgAESGCM, decryptAlgAge, decryptAlgPGP).
+				Description("The algorithm by which the stream is decrypted."),
+			service.NewStringField(sdFieldKey).
+				Description("The key to decrypt the stream with, which for `aes_gcm` is a hex encoded key, for `age` is a list of identities and for `pgp` is an ASCII armored private key ring. Only `age` files encrypted with a passphrase can be decrypted without a key.").
+				Secret().
+				Default(""),
+			service.NewStringField(sdFieldPassphrase).
+				Description("An optional passphrase used to decrypt the private keys of a `pgp` key ring, or to decrypt `age` files that were encrypted with a passphrase.").
+				Secret().
+				Default(""),
+			service.NewScannerField(sdFieldChild).
+				Description("The child scanner to feed the decrypted stream into.").
+				Default(map[string]any{"to_the_end": map[string]any{}}),
+		).
+		Example("Encrypted Compressed Objects", "Reads objects that were compressed with gzip and then encrypted with AES-GCM, where the key is provided by an environment variable.", `
+input:
+  aws_s3:
+    bucket: TODO
+    prefix: drops/
+    scanner:
+      decrypt:
+        algorithm: aes_gcm
+        key: ${DROPS_KEY}
+        into:
+          decompress:
+            algorithm: gzip
+            into:
+              lines: {}
+`)
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("decrypt", decryptScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return decryptScannerFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func decryptScannerFromParsed(conf *service.ParsedConfig) (d *decryptScannerCreator, err error) {
+	d = &decryptScannerCreator{}
+
+	var alg, key, passphrase string
+	if alg, err = conf.FieldString(sdFieldAlgorithm); err != nil {
+		return
+	}
+	if key, err = conf.FieldString(sdFieldKey); err != nil {
+		return
+	}
+	if passphrase, err = conf.FieldString(sdFieldPassphrase); err != nil {
+		return
+	}
+
+	if key == "" && (alg != decryptAlgAge || passphrase == "") {
+		return nil, fmt.Errorf("a key must be specified for the %v algorithm", alg)
+	}
+
+	switch alg {
+	case decryptAlgAESGCM:
+		d.open, err = aesGCMDecrypter(key)
+	case decryptAlgAge:
+		d.open, err = ageDecrypter(key, passphrase)
+	case decryptAlgPGP:
+		d.open, err = pgpDecrypter(key, passphrase)
+	default:
+		err = fmt.Errorf("unrecognised algorithm: %v", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if d.child, err = conf.FieldScanner(sdFieldChild); err != nil {
+		return
+	}
+	return
+}
+
+func aesGCMDecrypter(key string) (func(io.Reader) (io.Reader, error), error) {
+	keyBytes, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key: %w", err)
+	}
+	block, err := aes.NewCipher(keyBytes)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return func(r io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < aead.NonceSize()+aead.Overhead() {
+			return nil, errors.New("encrypted stream is too short")
+		}
+		nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+		plaintext, err := aead.Open(ciphertext[:0], nonce, ciphertext, nil)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(plaintext), nil
+	}, nil
+}
+
+func ageDecrypter(key, passphrase string) (func(io.Reader) (io.Reader, error), error) {
+	var identities []age.Identity
+	if key != "" {
+		var err error
+		if identities, err = age.ParseIdentities(strings.NewReader(key)); err != nil {
+			return nil, fmt.Errorf("failed to parse identities: %w", err)
+		}
+	}
+	if passphrase != "" {
+		scryptID, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, scryptID)
+	}
+
+	return func(r io.Reader) (io.Reader, error) {
+		bRdr := bufio.NewReader(r)
+		var src io.Reader = bRdr
+		if prefix, _ := bRdr.Peek(len(armor.Header)); string(prefix) == armor.Header {
+			src = armor.NewReader(bRdr)
+		}
+		return age.Decrypt(src, identities...)
+	}, nil
+}
+
+func pgpDecrypter(key, passphrase string) (func(io.Reader) (io.Reader, error), error) {
+	keyRing, err := readPGPKeyRing(key, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(keyRing.DecryptionKeys()) == 0 {
+		return nil, errors.New("key ring does not contain any private keys")
+	}
+
+	return func(r io.Reader) (io.Reader, error) {
+		msgRdr, err := pgpMessageReader(r)
+		if err != nil {
+			return nil, err
+		}
+		md, err := openpgp.ReadMessage(msgRdr, keyRing, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return md.UnverifiedBody, nil
+	}, nil
+}
+
+type decryptScannerCreator struct {
+	open  func(io.Reader) (io.Reader, error)
+	child *service.OwnedScannerCreator
+}
+
+func (c *decryptScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	return c.child.Create(&decryptReader{
+		source: rdr,
+		open:   c.open,
+	}, aFn, details)
+}
+
+func (c *decryptScannerCreator) Close(context.Context) error {
+	return nil
+}
+
+// decryptReader opens the decrypted stream on the first read so that creating
+// a scanner does not block on reading from the source.
+type decryptReader struct {
+	source io.ReadCloser
+	open   func(io.Reader) (io.Reader, error)
+
+	r   io.Reader
+	err error
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	if d.r == nil && d.err == nil {
+		if d.r, d.err = d.open(d.source); d.err != nil {
+			d.err = fmt.Errorf("failed to decrypt stream: %w", d.err)
+		}
+	}
+	if d.err != nil {
+		return 0, d.err
+	}
+	return d.r.Read(p)
+}
+
+func (d *decryptReader) Close() error {
+	return d.source.Close()
+}
@@ -0,0 +1,247 @@
+package crypto
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+	agearmor "filippo.io/age/armor"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/scanner/testutil"
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
+)
+
+func decryptScannerFromYAML(t *testing.T, conf string) (*service.OwnedScannerCreator, error) {
+	t.Helper()
+
+	confSpec := service.NewConfigSpec().Field(service.NewScannerField("test"))
+	pConf, err := confSpec.ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	return pConf.FieldScanner("test")
+}
+
+func TestDecryptScannerAESGCM(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, 32)
+
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	nonce := bytes.Repeat([]byte{0x01}, aead.NonceSize())
+	inputBytes := aead.Seal(nonce, nonce, []byte("hello\nworld\nthis\nis\nencrypted"), nil)
+
+	rdr, err := decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: aes_gcm
+    key: `+hex.EncodeToString(key)+`
+    into:
+      lines: {}
+`)
+	require.NoError(t, err)
+
+	testutil.ScannerTestSuite(t, rdr, nil, inputBytes, "hello", "world", "this", "is", "encrypted")
+
+	// Tampered streams fail authentication.
+	inputBytes[len(inputBytes)-1] ^= 0xff
+	s, err := rdr.Create(io.NopCloser(bytes.NewReader(inputBytes)), func(context.Context, error) error {
+		return nil
+	}, &service.ScannerSourceDetails{})
+	require.NoError(t, err)
+
+	_, _, err = s.NextBatch(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decrypt stream")
+	require.NoError(t, s.Close(context.Background()))
+}
+
+func TestDecryptScannerPGP(t *testing.T) {
+	pgpConf := &packet.Config{RSABits: 1024}
+	entity, err := openpgp.NewEntity("test", "", "test@example.com", pgpConf)
+	require.NoError(t, err)
+	for _, id := range entity.Identities {
+		id.SelfSignature.PreferredHash = []uint8{8} // SHA256
+	}
+
+	var keyBuf bytes.Buffer
+	keyWriter, err := armor.Encode(&keyBuf, openpgp.PrivateKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, entity.SerializePrivate(keyWriter, pgpConf))
+	require.NoError(t, keyWriter.Close())
+
+	var msgBuf bytes.Buffer
+	msgWriter, err := armor.Encode(&msgBuf, "PGP MESSAGE", nil)
+	require.NoError(t, err)
+	plainWriter, err := openpgp.Encrypt(msgWriter, []*openpgp.Entity{entity}, nil, nil, pgpConf)
+	require.NoError(t, err)
+	_, err = plainWriter.Write([]byte("hello\nworld\nthis\nis\nencrypted"))
+	require.NoError(t, err)
+	require.NoError(t, plainWriter.Close())
+	require.NoError(t, msgWriter.Close())
+
+	rdr, err := decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: pgp
+    key: |
+`+indent(keyBuf.String())+`
+    into:
+      lines: {}
+`)
+	require.NoError(t, err)
+
+	testutil.ScannerTestSuite(t, rdr, nil, msgBuf.Bytes(), "hello", "world", "this", "is", "encrypted")
+}
+
+func ageEncrypt(t *testing.T, armored bool, recipients ...age.Recipient) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	var dst io.Writer = &buf
+	var armorWriter io.WriteCloser
+	if armored {
+		armorWriter = agearmor.NewWriter(&buf)
+		dst = armorWriter
+	}
+
+	plainWriter, err := age.Encrypt(dst, recipients...)
+	require.NoError(t, err)
+	_, err = plainWriter.Write([]byte("hello\nworld\nthis\nis\nencrypted"))
+	require.NoError(t, err)
+	require.NoError(t, plainWriter.Close())
+	if armorWriter != nil {
+		require.NoError(t, armorWriter.Close())
+	}
+	return buf.Bytes()
+}
+
+func TestDecryptScannerAge(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	rdr, err := decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: age
+    key: `+identity.String()+`
+    into:
+      lines: {}
+`)
+	require.NoError(t, err)
+
+	t.Run("binary", func(t *testing.T) {
+		inputBytes := ageEncrypt(t, false, identity.Recipient())
+		testutil.ScannerTestSuite(t, rdr, nil, inputBytes, "hello", "world", "this", "is", "encrypted")
+	})
+
+	t.Run("armored", func(t *testing.T) {
+		inputBytes := ageEncrypt(t, true, identity.Recipient())
+		testutil.ScannerTestSuite(t, rdr, nil, inputBytes, "hello", "world", "this", "is", "encrypted")
+	})
+
+	t.Run("other recipient", func(t *testing.T) {
+		other, err := age.GenerateX25519Identity()
+		require.NoError(t, err)
+
+		s, err := rdr.Create(io.NopCloser(bytes.NewReader(ageEncrypt(t, false, other.Recipient()))), func(context.Context, error) error {
+			return nil
+		}, &service.ScannerSourceDetails{})
+		require.NoError(t, err)
+
+		_, _, err = s.NextBatch(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to decrypt stream")
+		require.NoError(t, s.Close(context.Background()))
+	})
+}
+
+func TestDecryptScannerAgePassphrase(t *testing.T) {
+	recipient, err := age.NewScryptRecipient("foobar")
+	require.NoError(t, err)
+	recipient.SetWorkFactor(10)
+
+	rdr, err := decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: age
+    passphrase: foobar
+    into:
+      lines: {}
+`)
+	require.NoError(t, err)
+
+	testutil.ScannerTestSuite(t, rdr, nil, ageEncrypt(t, false, recipient), "hello", "world", "this", "is", "encrypted")
+}
+
+func TestDecryptScannerConfigErrors(t *testing.T) {
+	_, err := decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: aes_gcm
+    key: nothex
+`)
+	require.ErrorContains(t, err, "failed to decode key")
+
+	_, err = decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: aes_gcm
+    key: 0011
+`)
+	require.Error(t, err)
+
+	_, err = decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: pgp
+    key: not a key ring
+`)
+	require.ErrorContains(t, err, "failed to read key ring")
+
+	_, err = decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: age
+    key: not an identity
+`)
+	require.ErrorContains(t, err, "failed to parse identities")
+
+	_, err = decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: age
+`)
+	require.ErrorContains(t, err, "a key must be specified for the age algorithm")
+
+	_, err = decryptScannerFromYAML(t, `
+test:
+  decrypt:
+    algorithm: pgp
+    passphrase: foobar
+`)
+	require.ErrorContains(t, err, "a key must be specified for the pgp algorithm")
+}
+
+func indent(s string) string {
+	var buf bytes.Buffer
+	for _, line := range bytes.Split([]byte(s), []byte("\n")) {
+		buf.WriteString("      ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
@@ -3,10 +3,13 @@ package discord
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sync"
 
 	"github.com/bwmarrin/discordgo"
 
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -17,13 +20,21 @@ func outputConfig() *service.ConfigSpec {
 		Description(`
 This output POSTs messages to the `+"`/channels/{channel_id}/messages`"+` Discord API endpoint authenticated as a bot using token based authentication.
 
-If the format of a message is a JSON object matching the [Discord API message type](https://discord.com/developers/docs/resources/channel#message-object) then it is sent directly, otherwise an object matching the API type is created with the content of the message added as a string.
+If the format of a message is a JSON object matching the [Discord API message type](https://discord.com/developers/docs/resources/channel#message-object) then it is sent directly, otherwise an object matching the API type is created with the content of the message added as a string. Messages can instead be constructed with a `+"`message`"+` mapping, which results in either the content of the message or an object matching the API type.
+
+The channel ID can be set dynamically with interpolation functions, which allows routing messages to different channels or threads based on their contents or metadata.
 `).
 		Fields(
-			service.NewStringField("channel_id").
-				Description("A discord channel ID to write messages to."),
+			service.NewInterpolatedStringField("channel_id").
+				Description("A discord channel ID to write messages to, which can also be the ID of a thread.").
+				Example(`${! @discord_channel_id }`),
 			service.NewStringField("bot_token").
 				Description("A bot token used for authentication."),
+			service.NewBloblangField("message").
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) used to construct the Discord message from each message, which results in either the content of the message or an object matching the API message type.").
+				Example(`root.embeds = [ { "title": this.title, "description": this.description } ]`).
+				Version("4.28.0").
+				Optional(),
 
 			// Deprecated
 			service.NewStringField("rate_limit").
@@ -50,8 +61,9 @@ type writer struct {
 	log *service.Logger
 
 	// Config
-	channelID string
+	channelID *service.InterpolatedString
 	botToken  string
+	message   *bloblang.Executor
 
 	connMut sync.Mutex
 	sess    *discordgo.Session
@@ -63,12 +75,17 @@ func newWriter(conf *service.ParsedConfig, mgr *service.Resources) (*writer, err
 		log: mgr.Logger(),
 	}
 	var err error
-	if w.channelID, err = conf.FieldString("channel_id"); err != nil {
+	if w.channelID, err = conf.FieldInterpolatedString("channel_id"); err != nil {
 		return nil, err
 	}
 	if w.botToken, err = conf.FieldString("bot_token"); err != nil {
 		return nil, err
 	}
+	if conf.Contains("message") {
+		if w.message, err = conf.FieldBloblang("message"); err != nil {
+			return nil, err
+		}
+	}
 	return w, nil
 }
 
@@ -94,6 +111,20 @@ func (w *writer) Write(ctx context.Context, msg *service.Message) error {
 		return service.ErrNotConnected
 	}
 
+	channelID, err := w.channelID.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("channel_id interpolation: %w", err)
+	}
+
+	if w.message != nil {
+		if msg, err = msg.BloblangQuery(w.message); err != nil {
+			return fmt.Errorf("message mapping failed: %w", err)
+		}
+		if msg == nil {
+			return errors.New("message mapping resulted in a deleted message")
+		}
+	}
+
 	rawContent, err := msg.AsBytes()
 	if err != nil {
 		return err
@@ -101,11 +132,11 @@ func (w *writer) Write(ctx context.Context, msg *service.Message) error {
 
 	var cMsg discordgo.MessageSend
 	if err := json.Unmarshal(rawContent, &cMsg); err == nil {
-		_, err = sess.ChannelMessageSendComplex(w.channelID, &cMsg)
+		_, err = sess.ChannelMessageSendComplex(channelID, &cMsg)
 		return err
 	}
 
-	_, err = sess.ChannelMessageSend(w.channelID, string(rawContent))
+	_, err = sess.ChannelMessageSend(channelID, string(rawContent))
 	return err
 }
 
@@ -12,6 +12,7 @@ import (
 	"github.com/gofrs/uuid"
 	"go.uber.org/multierr"
 
+	"github.com/benthosdev/benthos/v4/internal/batchformat"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -49,6 +50,7 @@ type csoConfig struct {
 	ChunkSize       int
 	CollisionMode   string
 	Timeout         time.Duration
+	Format          *batchformat.Encoder
 }
 
 func csoConfigFromParsed(pConf *service.ParsedConfig) (conf csoConfig, err error) {
@@ -73,6 +75,9 @@ func csoConfigFromParsed(pConf *service.ParsedConfig) (conf csoConfig, err error
 	if conf.Timeout, err = pConf.FieldDuration(csoFieldTimeout); err != nil {
 		return
 	}
+	if conf.Format, err = batchformat.FromParsed(pConf); err != nil {
+		return
+	}
 	return
 }
 
@@ -126,6 +131,21 @@ output:
       processors:
         - archive:
             format: json_array
+`+"```"+`
+
+Batches can also be written in a structured file format with the `+"`format`"+` field, for example as gzip compressed CSV files with a header row:
+
+`+"```yaml"+`
+output:
+  gcp_cloud_storage:
+    bucket: TODO
+    path: ${!count("files")}-${!timestamp_unix_nano()}.csv.gz
+    format:
+      type: csv
+      compression: gzip
+    batching:
+      count: 100
+      period: 10s
 `+"```"+``)).
 		Fields(
 			service.NewStringField(csoFieldBucket).
@@ -163,6 +183,7 @@ output:
 				Default("3s"),
 			service.NewOutputMaxInFlightField().
 				Description("The maximum number of message batches to have in flight at a given time. Increase this to improve throughput."),
+			batchformat.ConfigField(),
 			service.NewBatchPolicyField(csoFieldBatching),
 		)
 }
@@ -233,6 +254,14 @@ func (g *gcpCloudStorageOutput) WriteBatch(ctx context.Context, batch service.Me
 		return service.ErrNotConnected
 	}
 
+	if g.conf.Format != nil {
+		msg, err := g.conf.Format.Encode(batch)
+		if err != nil {
+			return err
+		}
+		batch = service.MessageBatch{msg}
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, g.conf.Timeout)
 	defer cancel()
 
@@ -0,0 +1,213 @@
+package gcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/api/idtoken"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gcfpFieldURL      = "url"
+	gcfpFieldAudience = "audience"
+	gcfpFieldParallel = "parallel"
+	gcfpFieldTimeout  = "timeout"
+	gcfpFieldRetries  = "retries"
+)
+
+func cloudFunctionProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Invokes a Google Cloud Function or Cloud Run service for each message. The contents of the message is the body of the request, and the body of the response will become the new contents of the message.").
+		Description(`
+Requests are authenticated with an ID token obtained from the [application default credentials](https://cloud.google.com/docs/authentication/application-default-credentials), where the service account must have the Cloud Functions Invoker or Cloud Run Invoker role. Requests are sent with a `+"`POST`"+` method and a `+"`Content-Type`"+` of `+"`application/json`"+`.
+
+In order to map or encode the payload to a specific request body, and map the response back into the original payload instead of replacing it entirely, you can use the `+"[`branch` processor](/docs/components/processors/branch)"+`. A batch of messages can be sent as a single request by archiving it with an `+"[`archive` processor](/docs/components/processors/archive)"+` within the branch.
+
+### Error Handling
+
+Requests that fail to be sent, or that result in a 429 or 5xx status code, are retried according to the configured number of retries. Once these attempts have been exhausted, or when any other status code outside of the 2xx range is returned, the failed message will continue through the pipeline with its contents unchanged, but flagged as having failed, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+The status code of the response is added to each message as the metadata field `+"`gcp_function_status_code`"+`.`).
+		Fields(
+			service.NewStringField(gcfpFieldURL).
+				Description("The URL of the function or service to invoke.").
+				Example("https://us-central1-my-project.cloudfunctions.net/my-function").
+				Example("https://my-service-abcdefghij-uc.a.run.app"),
+			service.NewStringField(gcfpFieldAudience).
+				Description("The audience of the ID token used to authenticate requests, which defaults to the `url`.").
+				Optional().
+				Advanced(),
+			service.NewBoolField(gcfpFieldParallel).
+				Description("Whether messages of a batch should be dispatched in parallel.").
+				Default(false),
+			service.NewDurationField(gcfpFieldTimeout).
+				Description("The maximum period of time to wait before abandoning a request.").
+				Default("30s").
+				Advanced(),
+			service.NewIntField(gcfpFieldRetries).
+				Description("The maximum number of retry attempts for each message.").
+				Default(3).
+				Advanced(),
+		).
+		Example("Batched Enrichment", "Here we send batches of documents to a Cloud Run service as JSON arrays, which responds with an array of enrichments that are merged back into the original documents.", `
+pipeline:
+  processors:
+    - branch:
+        request_map: 'root.text = this.text'
+        processors:
+          - archive:
+              format: json_array
+          - gcp_cloud_function:
+              url: https://enrich-abcdefghij-uc.a.run.app
+          - unarchive:
+              format: json_array
+        result_map: 'root.enrichment = this'
+`)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("gcp_cloud_function", cloudFunctionProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newCloudFunctionProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type cloudFunctionProcessor struct {
+	client   *http.Client
+	url      string
+	parallel bool
+	timeout  time.Duration
+	retries  int
+	log      *service.Logger
+}
+
+func newCloudFunctionProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*cloudFunctionProcessor, error) {
+	c := &cloudFunctionProcessor{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if c.url, err = conf.FieldString(gcfpFieldURL); err != nil {
+		return nil, err
+	}
+	audience := c.url
+	if conf.Contains(gcfpFieldAudience) {
+		if audience, err = conf.FieldString(gcfpFieldAudience); err != nil {
+			return nil, err
+		}
+	}
+	if c.parallel, err = conf.FieldBool(gcfpFieldParallel); err != nil {
+		return nil, err
+	}
+	if c.timeout, err = conf.FieldDuration(gcfpFieldTimeout); err != nil {
+		return nil, err
+	}
+	if c.retries, err = conf.FieldInt(gcfpFieldRetries); err != nil {
+		return nil, err
+	}
+
+	if c.client, err = idtoken.NewClient(context.Background(), audience); err != nil {
+		return nil, fmt.Errorf("failed to create authenticated client: %w", err)
+	}
+	return c, nil
+}
+
+// retryableStatus returns whether a request that resulted in a status code
+// should be attempted again.
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+func (c *cloudFunctionProcessor) invoke(ctx context.Context, msg *service.Message) error {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	remainingRetries := c.retries
+	for {
+		code, resBytes, err := c.request(ctx, mBytes)
+		if err == nil {
+			msg.MetaSetMut("gcp_function_status_code", code)
+			if code >= 200 && code < 300 {
+				msg.SetBytes(resBytes)
+				return nil
+			}
+			err = fmt.Errorf("function returned status %v: %s", code, resBytes)
+			if !retryableStatus(code) {
+				return err
+			}
+		}
+
+		remainingRetries--
+		if remainingRetries < 0 {
+			return err
+		}
+	}
+}
+
+func (c *cloudFunctionProcessor) request(ctx context.Context, body []byte) (int, []byte, error) {
+	ctx, done := context.WithTimeout(ctx, c.timeout)
+	defer done()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	resBytes, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return res.StatusCode, resBytes, nil
+}
+
+func (c *cloudFunctionProcessor) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	if !c.parallel || len(batch) == 1 {
+		for _, msg := range batch {
+			if err := c.invoke(ctx, msg); err != nil {
+				c.log.Errorf("Cloud function '%v' failed: %v", c.url, err)
+				msg.SetError(err)
+			}
+		}
+		return []service.MessageBatch{batch}, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(batch))
+	for _, msg := range batch {
+		go func(msg *service.Message) {
+			defer wg.Done()
+			if err := c.invoke(ctx, msg); err != nil {
+				c.log.Errorf("Cloud function '%v' failed: %v", c.url, err)
+				msg.SetError(err)
+			}
+		}(msg)
+	}
+	wg.Wait()
+	return []service.MessageBatch{batch}, nil
+}
+
+func (c *cloudFunctionProcessor) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,77 @@
+package gcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestCloudFunctionProcessor(t *testing.T) {
+	var unavailable, badRequests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch string(body) {
+		case "flaky":
+			if unavailable.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "bad":
+			badRequests.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("nope"))
+			return
+		}
+		_, _ = w.Write([]byte("hello " + string(body)))
+	}))
+	defer ts.Close()
+
+	for _, parallel := range []bool{false, true} {
+		unavailable.Store(0)
+		badRequests.Store(0)
+		proc := &cloudFunctionProcessor{
+			client:   ts.Client(),
+			url:      ts.URL,
+			parallel: parallel,
+			timeout:  time.Second,
+			retries:  3,
+			log:      service.MockResources().Logger(),
+		}
+
+		batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+			service.NewMessage([]byte("world")),
+			service.NewMessage([]byte("flaky")),
+			service.NewMessage([]byte("bad")),
+		})
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		require.Len(t, batches[0], 3)
+
+		b, _ := batches[0][0].AsBytes()
+		assert.Equal(t, "hello world", string(b))
+		require.NoError(t, batches[0][0].GetError())
+
+		b, _ = batches[0][1].AsBytes()
+		assert.Equal(t, "hello flaky", string(b))
+		require.NoError(t, batches[0][1].GetError())
+
+		b, _ = batches[0][2].AsBytes()
+		assert.Equal(t, "bad", string(b))
+		require.EqualError(t, batches[0][2].GetError(), "function returned status 400: nope")
+		code, _ := batches[0][2].MetaGetMut("gcp_function_status_code")
+		assert.Equal(t, 400, code)
+
+		// The bad request was not retried.
+		assert.Equal(t, int32(1), badRequests.Load())
+		assert.Equal(t, int32(3), unavailable.Load())
+	}
+}
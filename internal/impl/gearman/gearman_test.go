@@ -0,0 +1,272 @@
+package gearman
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writePacket(&buf, magicRes, ptJobAssignUniq,
+		[]byte("H:1"), []byte("foo"), []byte(""), []byte("data\x00with\x00nulls")))
+	require.NoError(t, writePacket(&buf, magicRes, ptNoJob))
+
+	p, err := readPacket(&buf, magicRes)
+	require.NoError(t, err)
+	assert.Equal(t, ptJobAssignUniq, p.typ)
+	assert.Equal(t, "H:1", string(p.arg(0)))
+	assert.Equal(t, "foo", string(p.arg(1)))
+	assert.Equal(t, "", string(p.arg(2)))
+	assert.Equal(t, "data\x00with\x00nulls", string(p.arg(3)))
+
+	p, err = readPacket(&buf, magicRes)
+	require.NoError(t, err)
+	assert.Equal(t, ptNoJob, p.typ)
+	assert.Empty(t, p.args)
+
+	require.NoError(t, writePacket(&buf, magicReq, ptGrabJobUniq))
+	_, err = readPacket(&buf, magicRes)
+	require.Error(t, err)
+}
+
+//------------------------------------------------------------------------------
+
+type fakeJob struct {
+	handle    string
+	function  string
+	uniqueID  string
+	data      []byte
+	submitter *fakeConn
+}
+
+type fakeConn struct {
+	netConn  net.Conn
+	writeMut sync.Mutex
+}
+
+func (c *fakeConn) write(typ packetType, args ...[]byte) {
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+	_ = writePacket(c.netConn, magicRes, typ, args...)
+}
+
+// fakeServer is a minimal Gearman job server that queues jobs of all
+// functions together.
+type fakeServer struct {
+	ln net.Listener
+
+	mut        sync.Mutex
+	nextHandle int
+	queue      []*fakeJob
+	running    map[string]*fakeJob
+	sleeping   map[*fakeConn]struct{}
+	completed  []string
+	failed     []string
+}
+
+func newFakeServer(t testing.TB) *fakeServer {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &fakeServer{
+		ln:       ln,
+		running:  map[string]*fakeJob{},
+		sleeping: map[*fakeConn]struct{}{},
+	}
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+
+	go func() {
+		for {
+			netConn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(&fakeConn{netConn: netConn})
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) handle(c *fakeConn) {
+	defer c.netConn.Close()
+
+	for {
+		p, err := readPacket(c.netConn, magicReq)
+		if err != nil {
+			return
+		}
+
+		s.mut.Lock()
+		switch p.typ {
+		case ptSubmitJob, ptSubmitJobBG:
+			s.nextHandle++
+			job := &fakeJob{
+				handle:   fmt.Sprintf("H:%v", s.nextHandle),
+				function: string(p.arg(0)),
+				uniqueID: string(p.arg(1)),
+				data:     p.arg(2),
+			}
+			if p.typ == ptSubmitJob {
+				job.submitter = c
+			}
+			s.queue = append(s.queue, job)
+			c.write(ptJobCreated, []byte(job.handle))
+			for sleeper := range s.sleeping {
+				sleeper.write(ptNoop)
+			}
+			s.sleeping = map[*fakeConn]struct{}{}
+		case ptGrabJobUniq:
+			if len(s.queue) == 0 {
+				c.write(ptNoJob)
+				break
+			}
+			job := s.queue[0]
+			s.queue = s.queue[1:]
+			s.running[job.handle] = job
+			c.write(ptJobAssignUniq, []byte(job.handle), []byte(job.function), []byte(job.uniqueID), job.data)
+		case ptPreSleep:
+			if len(s.queue) > 0 {
+				c.write(ptNoop)
+			} else {
+				s.sleeping[c] = struct{}{}
+			}
+		case ptWorkComplete, ptWorkFail:
+			handle := string(p.arg(0))
+			if job, exists := s.running[handle]; exists {
+				delete(s.running, handle)
+				if p.typ == ptWorkComplete {
+					s.completed = append(s.completed, string(job.data))
+				} else {
+					s.failed = append(s.failed, string(job.data))
+				}
+				if job.submitter != nil {
+					job.submitter.write(p.typ, p.args...)
+				}
+			}
+		}
+		s.mut.Unlock()
+	}
+}
+
+func (s *fakeServer) results() (completed, failed []string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return append([]string(nil), s.completed...), append([]string(nil), s.failed...)
+}
+
+//------------------------------------------------------------------------------
+
+func testReaderAndWriter(t testing.TB, outConf string) (*fakeServer, *gearmanReader, *gearmanWriter) {
+	t.Helper()
+
+	s := newFakeServer(t)
+	addr := s.ln.Addr().String()
+
+	pConf, err := inputConfigSpec().ParseYAML(fmt.Sprintf(`
+addresses: [ %v ]
+functions: [ foo ]
+`, addr), nil)
+	require.NoError(t, err)
+
+	r, err := newGearmanReaderFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	pConf, err = outputConfigSpec().ParseYAML(fmt.Sprintf(`
+addresses: [ %v ]
+function: foo
+%v
+`, addr, outConf), nil)
+	require.NoError(t, err)
+
+	w, err := newGearmanWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	require.NoError(t, r.Connect(ctx))
+	require.NoError(t, w.Connect(ctx))
+	t.Cleanup(func() {
+		_ = r.Close(context.Background())
+		_ = w.Close(context.Background())
+	})
+	return s, r, w
+}
+
+func TestGearmanBackgroundJobs(t *testing.T) {
+	s, r, w := testReaderAndWriter(t, `unique_id: 'id-${! content() }'`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	for _, d := range []string{"first", "second", "third"} {
+		require.NoError(t, w.Write(ctx, service.NewMessage([]byte(d))))
+	}
+
+	for i, d := range []string{"first", "second", "third"} {
+		msg, ackFn, err := r.Read(ctx)
+		require.NoError(t, err)
+
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, d, string(mBytes))
+
+		v, _ := msg.MetaGet("gearman_function")
+		assert.Equal(t, "foo", v)
+		v, _ = msg.MetaGet("gearman_unique_id")
+		assert.Equal(t, "id-"+d, v)
+		v, _ = msg.MetaGet("gearman_handle")
+		assert.Equal(t, fmt.Sprintf("H:%v", i+1), v)
+
+		var ackErr error
+		if d == "second" {
+			ackErr = fmt.Errorf("nope")
+		}
+		require.NoError(t, ackFn(ctx, ackErr))
+	}
+
+	assert.Eventually(t, func() bool {
+		completed, failed := s.results()
+		return assert.ObjectsAreEqual([]string{"first", "third"}, completed) &&
+			assert.ObjectsAreEqual([]string{"second"}, failed)
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestGearmanForegroundJobs(t *testing.T) {
+	_, r, w := testReaderAndWriter(t, `background: false`)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			msg, ackFn, err := r.Read(ctx)
+			if err != nil {
+				return
+			}
+			mBytes, _ := msg.AsBytes()
+			var ackErr error
+			if string(mBytes) == "bad" {
+				ackErr = fmt.Errorf("nope")
+			}
+			_ = ackFn(ctx, ackErr)
+		}
+	}()
+
+	require.NoError(t, w.Write(ctx, service.NewMessage([]byte("good"))))
+	require.EqualError(t, w.Write(ctx, service.NewMessage([]byte("bad"))), "job failed")
+}
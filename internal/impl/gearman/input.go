@@ -0,0 +1,311 @@
+package gearman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	giFieldAddresses = "addresses"
+	giFieldFunctions = "functions"
+	giFieldTimeout   = "timeout"
+	giFieldClientID  = "client_id"
+)
+
+func inputConfigSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Registers as a worker of functions with Gearman job servers and consumes their jobs.").
+		Description(`
+The data of each job assigned to this worker is consumed as a message. Once the message is successfully processed the job is completed with an empty result, and if it is rejected the job is failed.
+
+Jobs are grabbed from all of the listed job servers concurrently.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"``` text"+`
+- gearman_function
+- gearman_handle
+- gearman_unique_id
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).
+`).
+		Fields(
+			service.NewStringListField(giFieldAddresses).
+				Description("A list of Gearman job server addresses to connect to. If an item of the list contains commas it will be expanded into multiple addresses.").
+				Example([]string{"localhost:4730"}),
+			service.NewStringListField(giFieldFunctions).
+				Description("A list of function names to register as a worker of.").
+				Example([]string{"resize_image"}),
+			service.NewDurationField(giFieldTimeout).
+				Description("An optional timeout to register functions with, after which the job servers consider a job assigned to this worker as failed. Timeouts are rounded to the nearest second.").
+				Example("60s").
+				Optional().
+				Advanced(),
+			service.NewStringField(giFieldClientID).
+				Description("An optional identifier of this worker reported by the job servers.").
+				Optional().
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		)
+}
+
+func init() {
+	err := service.RegisterInput("gearman", inputConfigSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+		r, err := newGearmanReaderFromParsed(conf, mgr)
+		if err != nil {
+			return nil, err
+		}
+		return service.AutoRetryNacksToggled(conf, r)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type gearmanJob struct {
+	conn     *conn
+	handle   []byte
+	function []byte
+	uniqueID []byte
+	data     []byte
+}
+
+type gearmanReader struct {
+	addresses []string
+	functions []string
+	timeout   time.Duration
+	clientID  string
+
+	log *service.Logger
+
+	connMut  sync.Mutex
+	conns    []*conn
+	jobChan  chan gearmanJob
+	errChan  chan error
+	grabSig  *shutdown.Signaller
+	grabWait sync.WaitGroup
+}
+
+func newGearmanReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*gearmanReader, error) {
+	r := &gearmanReader{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if r.addresses, err = addressesFromParsed(conf, giFieldAddresses); err != nil {
+		return nil, err
+	}
+	if r.functions, err = conf.FieldStringList(giFieldFunctions); err != nil {
+		return nil, err
+	}
+	if len(r.functions) == 0 {
+		return nil, errors.New("at least one function must be specified")
+	}
+	if conf.Contains(giFieldTimeout) {
+		if r.timeout, err = conf.FieldDuration(giFieldTimeout); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(giFieldClientID) {
+		if r.clientID, err = conf.FieldString(giFieldClientID); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *gearmanReader) register(c *conn) error {
+	if r.clientID != "" {
+		if err := c.write(ptSetClientID, []byte(r.clientID)); err != nil {
+			return err
+		}
+	}
+	for _, fn := range r.functions {
+		var err error
+		if r.timeout > 0 {
+			timeoutSecs := strconv.FormatInt(int64(r.timeout.Round(time.Second)/time.Second), 10)
+			err = c.write(ptCanDoTimeout, []byte(fn), []byte(timeoutSecs))
+		} else {
+			err = c.write(ptCanDo, []byte(fn))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *gearmanReader) Connect(ctx context.Context) (err error) {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.conns != nil {
+		return nil
+	}
+
+	var conns []*conn
+	defer func() {
+		if err != nil {
+			for _, c := range conns {
+				_ = c.Close()
+			}
+		}
+	}()
+
+	for _, addr := range r.addresses {
+		var c *conn
+		if c, err = dial(ctx, addr); err != nil {
+			return
+		}
+		conns = append(conns, c)
+		if err = r.register(c); err != nil {
+			return
+		}
+	}
+
+	jobChan := make(chan gearmanJob)
+	errChan := make(chan error, len(conns))
+	grabSig := shutdown.NewSignaller()
+
+	for _, c := range conns {
+		r.grabWait.Add(1)
+		go func(c *conn) {
+			defer r.grabWait.Done()
+			if err := r.grabLoop(c, jobChan, grabSig); err != nil && !grabSig.IsSoftStopSignalled() {
+				errChan <- fmt.Errorf("job server %v: %w", c.address, err)
+			}
+		}(c)
+	}
+
+	r.conns, r.jobChan, r.errChan, r.grabSig = conns, jobChan, errChan, grabSig
+	return nil
+}
+
+// grabLoop continuously grabs jobs from a job server, sleeping whenever there
+// are no jobs available until the server wakes us.
+func (r *gearmanReader) grabLoop(c *conn, jobChan chan<- gearmanJob, grabSig *shutdown.Signaller) error {
+	for {
+		if err := c.write(ptGrabJobUniq); err != nil {
+			return err
+		}
+
+		p, err := c.read()
+		if err != nil {
+			return err
+		}
+
+		switch p.typ {
+		case ptJobAssignUniq:
+			job := gearmanJob{
+				conn:     c,
+				handle:   p.arg(0),
+				function: p.arg(1),
+				uniqueID: p.arg(2),
+				data:     p.arg(3),
+			}
+			select {
+			case jobChan <- job:
+			case <-grabSig.SoftStopChan():
+				// The job is failed in order for it to be reassigned.
+				_ = c.write(ptWorkFail, job.handle)
+				return nil
+			}
+		case ptNoJob:
+			if err := c.write(ptPreSleep); err != nil {
+				return err
+			}
+			if err := waitForNoop(c); err != nil {
+				return err
+			}
+		case ptError:
+			return p.asError()
+		case ptNoop:
+		default:
+			r.log.Debugf("Ignoring unexpected packet type %v from job server %v", p.typ, c.address)
+		}
+	}
+}
+
+func waitForNoop(c *conn) error {
+	for {
+		p, err := c.read()
+		if err != nil {
+			return err
+		}
+		switch p.typ {
+		case ptNoop:
+			return nil
+		case ptError:
+			return p.asError()
+		}
+	}
+}
+
+func (r *gearmanReader) disconnect() {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.conns == nil {
+		return
+	}
+
+	r.grabSig.TriggerSoftStop()
+	for _, c := range r.conns {
+		_ = c.Close()
+	}
+	r.grabWait.Wait()
+	r.conns = nil
+}
+
+func (r *gearmanReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	r.connMut.Lock()
+	jobChan, errChan := r.jobChan, r.errChan
+	connected := r.conns != nil
+	r.connMut.Unlock()
+
+	if !connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var job gearmanJob
+	select {
+	case job = <-jobChan:
+	case err := <-errChan:
+		r.log.Errorf("Lost connection to Gearman: %v", err)
+		r.disconnect()
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	msg := service.NewMessage(job.data)
+	msg.MetaSetMut("gearman_function", string(job.function))
+	msg.MetaSetMut("gearman_handle", string(job.handle))
+	msg.MetaSetMut("gearman_unique_id", string(job.uniqueID))
+
+	return msg, func(ctx context.Context, res error) error {
+		if res != nil {
+			return job.conn.write(ptWorkFail, job.handle)
+		}
+		return job.conn.write(ptWorkComplete, job.handle, nil)
+	}, nil
+}
+
+func (r *gearmanReader) Close(ctx context.Context) error {
+	r.disconnect()
+	return nil
+}
@@ -0,0 +1,71 @@
+package gearman
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service/integration"
+)
+
+func TestIntegrationGearman(t *testing.T) {
+	integration.CheckSkip(t)
+	t.Parallel()
+
+	pool, err := dockertest.NewPool("")
+	require.NoError(t, err)
+
+	pool.MaxWait = time.Second * 30
+	resource, err := pool.Run("artefactual/gearmand", "latest", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	_ = resource.Expire(900)
+	require.NoError(t, pool.Retry(func() error {
+		conn, err := net.DialTimeout("tcp", "localhost:"+resource.GetPort("4730/tcp"), time.Second)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}))
+
+	template := `
+output:
+  gearman:
+    addresses: [ localhost:$PORT ]
+    function: function-$ID
+    background: $VAR1
+    max_in_flight: $MAX_IN_FLIGHT
+
+input:
+  gearman:
+    addresses: [ localhost:$PORT ]
+    functions: [ function-$ID ]
+`
+	suite := integration.StreamTests(
+		integration.StreamTestOpenClose(),
+		integration.StreamTestSendBatch(10),
+		integration.StreamTestStreamSequential(100),
+		integration.StreamTestStreamParallel(100),
+	)
+	suite.Run(
+		t, template,
+		integration.StreamTestOptPort(resource.GetPort("4730/tcp")),
+		integration.StreamTestOptVarSet("VAR1", "true"),
+	)
+
+	t.Run("foreground", func(t *testing.T) {
+		t.Parallel()
+		suite.Run(
+			t, template,
+			integration.StreamTestOptPort(resource.GetPort("4730/tcp")),
+			integration.StreamTestOptVarSet("VAR1", "false"),
+		)
+	})
+}
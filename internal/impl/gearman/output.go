@@ -0,0 +1,369 @@
+package gearman
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	goFieldAddresses  = "addresses"
+	goFieldFunction   = "function"
+	goFieldUniqueID   = "unique_id"
+	goFieldPriority   = "priority"
+	goFieldBackground = "background"
+)
+
+func outputConfigSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Submits messages as jobs to Gearman job servers.").
+		Description(output.Description(true, false, `
+Jobs are submitted to the listed job servers in a round robin fashion. By default jobs are submitted in the background, where a message is acknowledged once the job server has accepted its job. When `+"`background`"+` is set to `+"`false`"+` a message is only acknowledged once a worker has completed its job, and is rejected when the job fails.
+
+The fields `+"`function`"+` and `+"`unique_id`"+` can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries).`)).
+		Fields(
+			service.NewStringListField(goFieldAddresses).
+				Description("A list of Gearman job server addresses to connect to. If an item of the list contains commas it will be expanded into multiple addresses.").
+				Example([]string{"localhost:4730"}),
+			service.NewInterpolatedStringField(goFieldFunction).
+				Description("The name of the function to submit jobs to.").
+				Example("resize_image"),
+			service.NewInterpolatedStringField(goFieldUniqueID).
+				Description("An optional unique identifier of each job, where jobs submitted with the same identifier whilst a prior job is queued are coalesced by the job server.").
+				Example(`${! @id }`).
+				Optional().
+				Advanced(),
+			service.NewStringEnumField(goFieldPriority, "normal", "high", "low").
+				Description("The priority to submit jobs with.").
+				Default("normal").
+				Advanced(),
+			service.NewBoolField(goFieldBackground).
+				Description("Whether to submit jobs in the background, otherwise messages are not acknowledged until their jobs are completed by a worker.").
+				Default(true),
+			service.NewOutputMaxInFlightField(),
+		)
+}
+
+func init() {
+	err := service.RegisterOutput("gearman", outputConfigSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
+		w, err := newGearmanWriterFromParsed(conf, mgr)
+		if err != nil {
+			return nil, 0, err
+		}
+		mIF, err := conf.FieldMaxInFlight()
+		if err != nil {
+			return nil, 0, err
+		}
+		return w, mIF, nil
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func addressesFromParsed(conf *service.ParsedConfig, name string) ([]string, error) {
+	addrStrs, err := conf.FieldStringList(name)
+	if err != nil {
+		return nil, err
+	}
+	var addresses []string
+	for _, a := range addrStrs {
+		for _, splitAddr := range strings.Split(a, ",") {
+			if trimmed := strings.TrimSpace(splitAddr); trimmed != "" {
+				addresses = append(addresses, trimmed)
+			}
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("at least one address must be specified")
+	}
+	return addresses, nil
+}
+
+func submitPacketType(priority string, background bool) packetType {
+	switch priority {
+	case "high":
+		if background {
+			return ptSubmitJobHighBG
+		}
+		return ptSubmitJobHigh
+	case "low":
+		if background {
+			return ptSubmitJobLowBG
+		}
+		return ptSubmitJobLow
+	}
+	if background {
+		return ptSubmitJobBG
+	}
+	return ptSubmitJob
+}
+
+type gearmanWriter struct {
+	addresses  []string
+	function   *service.InterpolatedString
+	uniqueID   *service.InterpolatedString
+	submitType packetType
+	background bool
+
+	log *service.Logger
+
+	connMut sync.RWMutex
+	clients []*client
+	next    atomic.Uint64
+}
+
+func newGearmanWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*gearmanWriter, error) {
+	w := &gearmanWriter{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if w.addresses, err = addressesFromParsed(conf, goFieldAddresses); err != nil {
+		return nil, err
+	}
+	if w.function, err = conf.FieldInterpolatedString(goFieldFunction); err != nil {
+		return nil, err
+	}
+	if conf.Contains(goFieldUniqueID) {
+		if w.uniqueID, err = conf.FieldInterpolatedString(goFieldUniqueID); err != nil {
+			return nil, err
+		}
+	}
+	priority, err := conf.FieldString(goFieldPriority)
+	if err != nil {
+		return nil, err
+	}
+	if w.background, err = conf.FieldBool(goFieldBackground); err != nil {
+		return nil, err
+	}
+	w.submitType = submitPacketType(priority, w.background)
+	return w, nil
+}
+
+func (w *gearmanWriter) Connect(ctx context.Context) error {
+	w.connMut.Lock()
+	defer w.connMut.Unlock()
+
+	if w.clients != nil {
+		return nil
+	}
+
+	var clients []*client
+	for _, addr := range w.addresses {
+		c, err := dial(ctx, addr)
+		if err != nil {
+			for _, cl := range clients {
+				cl.Close()
+			}
+			return err
+		}
+		clients = append(clients, newClient(c))
+	}
+	w.clients = clients
+	return nil
+}
+
+func (w *gearmanWriter) disconnect() {
+	w.connMut.Lock()
+	defer w.connMut.Unlock()
+
+	for _, c := range w.clients {
+		c.Close()
+	}
+	w.clients = nil
+}
+
+func (w *gearmanWriter) Write(ctx context.Context, msg *service.Message) error {
+	w.connMut.RLock()
+	clients := w.clients
+	w.connMut.RUnlock()
+
+	if clients == nil {
+		return service.ErrNotConnected
+	}
+
+	function, err := w.function.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("function interpolation error: %w", err)
+	}
+	var uniqueID string
+	if w.uniqueID != nil {
+		if uniqueID, err = w.uniqueID.TryString(msg); err != nil {
+			return fmt.Errorf("unique id interpolation error: %w", err)
+		}
+	}
+	data, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	c := clients[w.next.Add(1)%uint64(len(clients))]
+	if err := c.submit(ctx, w.submitType, !w.background, function, uniqueID, data); err != nil {
+		if errors.Is(err, errClientClosed) {
+			w.log.Errorf("Lost connection to Gearman job server %v: %v", c.conn.address, c.Err())
+			w.disconnect()
+			return service.ErrNotConnected
+		}
+		return err
+	}
+	return nil
+}
+
+func (w *gearmanWriter) Close(context.Context) error {
+	w.disconnect()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+var errClientClosed = errors.New("connection closed")
+
+type clientJob struct {
+	wait    bool
+	created chan error
+	result  chan error
+}
+
+// client submits jobs to a job server. Since job servers respond to
+// submissions in the order that they're received a queue of submitted jobs is
+// kept in order to pair them with the handles they're assigned.
+type client struct {
+	conn *conn
+
+	jobsMut   sync.Mutex
+	submitted []*clientJob
+	running   map[string]*clientJob
+
+	closeOnce sync.Once
+	doneChan  chan struct{}
+	err       error
+}
+
+func newClient(c *conn) *client {
+	cl := &client{
+		conn:     c,
+		running:  map[string]*clientJob{},
+		doneChan: make(chan struct{}),
+	}
+	go cl.readLoop()
+	return cl
+}
+
+func (c *client) readLoop() {
+	var err error
+	defer func() {
+		c.jobsMut.Lock()
+		c.err = err
+		for _, j := range c.submitted {
+			j.created <- errClientClosed
+		}
+		for _, j := range c.running {
+			j.result <- errClientClosed
+		}
+		c.submitted, c.running = nil, nil
+		c.jobsMut.Unlock()
+		close(c.doneChan)
+	}()
+
+	for {
+		var p packet
+		if p, err = c.conn.read(); err != nil {
+			return
+		}
+
+		c.jobsMut.Lock()
+		switch p.typ {
+		case ptJobCreated, ptError:
+			if len(c.submitted) == 0 {
+				c.jobsMut.Unlock()
+				err = fmt.Errorf("received unexpected packet type %v", p.typ)
+				return
+			}
+			j := c.submitted[0]
+			c.submitted = c.submitted[1:]
+			if p.typ == ptError {
+				j.created <- p.asError()
+			} else {
+				if j.wait {
+					c.running[string(p.arg(0))] = j
+				}
+				j.created <- nil
+			}
+		case ptWorkComplete, ptWorkFail, ptWorkException:
+			handle := string(p.arg(0))
+			if j, exists := c.running[handle]; exists {
+				delete(c.running, handle)
+				switch p.typ {
+				case ptWorkComplete:
+					j.result <- nil
+				case ptWorkFail:
+					j.result <- errors.New("job failed")
+				default:
+					j.result <- fmt.Errorf("job failed with exception: %s", p.arg(1))
+				}
+			}
+		}
+		c.jobsMut.Unlock()
+	}
+}
+
+func (c *client) submit(ctx context.Context, typ packetType, wait bool, function, uniqueID string, data []byte) error {
+	j := &clientJob{
+		wait:    wait,
+		created: make(chan error, 1),
+		result:  make(chan error, 1),
+	}
+
+	// The job is queued and written under the same lock in order to ensure
+	// that the queue matches the order of submissions.
+	c.jobsMut.Lock()
+	if c.running == nil {
+		c.jobsMut.Unlock()
+		return errClientClosed
+	}
+	c.submitted = append(c.submitted, j)
+	err := c.conn.write(typ, []byte(function), []byte(uniqueID), data)
+	c.jobsMut.Unlock()
+	if err != nil {
+		c.Close()
+		return errClientClosed
+	}
+
+	select {
+	case err := <-j.created:
+		if err != nil || !wait {
+			return err
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-j.result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Err returns the error that caused the client to close.
+func (c *client) Err() error {
+	<-c.doneChan
+	return c.err
+}
+
+func (c *client) Close() {
+	c.closeOnce.Do(func() {
+		_ = c.conn.Close()
+	})
+}
@@ -0,0 +1,190 @@
+package gearman
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// packetType is the type of a packet within the Gearman binary protocol, as
+// described at http://gearman.org/protocol/.
+type packetType uint32
+
+const (
+	ptCanDo           packetType = 1
+	ptPreSleep        packetType = 4
+	ptNoop            packetType = 6
+	ptSubmitJob       packetType = 7
+	ptJobCreated      packetType = 8
+	ptNoJob           packetType = 10
+	ptWorkStatus      packetType = 12
+	ptWorkComplete    packetType = 13
+	ptWorkFail        packetType = 14
+	ptSubmitJobBG     packetType = 18
+	ptError           packetType = 19
+	ptSubmitJobHigh   packetType = 21
+	ptSetClientID     packetType = 22
+	ptCanDoTimeout    packetType = 23
+	ptWorkException   packetType = 25
+	ptWorkData        packetType = 28
+	ptWorkWarning     packetType = 29
+	ptGrabJobUniq     packetType = 30
+	ptJobAssignUniq   packetType = 31
+	ptSubmitJobHighBG packetType = 32
+	ptSubmitJobLow    packetType = 33
+	ptSubmitJobLowBG  packetType = 34
+)
+
+var (
+	magicReq = [4]byte{0, 'R', 'E', 'Q'}
+	magicRes = [4]byte{0, 'R', 'E', 'S'}
+)
+
+// maxPacketSize limits the size of packets that are read in order to protect
+// against corrupt or hostile headers.
+const maxPacketSize = 256 * 1024 * 1024
+
+// packetArgCounts is the number of null separated arguments of each packet
+// type, where the final argument may contain null bytes.
+var packetArgCounts = map[packetType]int{
+	ptPreSleep:        0,
+	ptNoop:            0,
+	ptNoJob:           0,
+	ptGrabJobUniq:     0,
+	ptCanDo:           1,
+	ptSetClientID:     1,
+	ptCanDoTimeout:    2,
+	ptSubmitJob:       3,
+	ptSubmitJobBG:     3,
+	ptSubmitJobHigh:   3,
+	ptSubmitJobHighBG: 3,
+	ptSubmitJobLow:    3,
+	ptSubmitJobLowBG:  3,
+	ptJobCreated:      1,
+	ptWorkFail:        1,
+	ptError:           2,
+	ptWorkComplete:    2,
+	ptWorkException:   2,
+	ptWorkData:        2,
+	ptWorkWarning:     2,
+	ptWorkStatus:      3,
+	ptJobAssignUniq:   4,
+}
+
+type packet struct {
+	typ  packetType
+	args [][]byte
+}
+
+func (p packet) arg(i int) []byte {
+	if i < len(p.args) {
+		return p.args[i]
+	}
+	return nil
+}
+
+// asError returns the error described by an ERROR packet.
+func (p packet) asError() error {
+	return fmt.Errorf("gearman error %s: %s", p.arg(0), p.arg(1))
+}
+
+func writePacket(w io.Writer, magic [4]byte, typ packetType, args ...[]byte) error {
+	size := 0
+	for i, a := range args {
+		if i > 0 {
+			size++
+		}
+		size += len(a)
+	}
+
+	buf := make([]byte, 12, 12+size)
+	copy(buf, magic[:])
+	binary.BigEndian.PutUint32(buf[4:], uint32(typ))
+	binary.BigEndian.PutUint32(buf[8:], uint32(size))
+	for i, a := range args {
+		if i > 0 {
+			buf = append(buf, 0)
+		}
+		buf = append(buf, a...)
+	}
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func readPacket(r io.Reader, magic [4]byte) (p packet, err error) {
+	var header [12]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	if !bytes.Equal(header[:4], magic[:]) {
+		err = errors.New("received packet with unexpected magic code")
+		return
+	}
+	p.typ = packetType(binary.BigEndian.Uint32(header[4:]))
+
+	size := binary.BigEndian.Uint32(header[8:])
+	if size > maxPacketSize {
+		err = fmt.Errorf("received packet size %v exceeds the limit of %v", size, maxPacketSize)
+		return
+	}
+
+	data := make([]byte, size)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return
+	}
+
+	argCount, exists := packetArgCounts[p.typ]
+	if !exists {
+		argCount = 1
+	}
+	if argCount > 0 {
+		p.args = bytes.SplitN(data, []byte{0}, argCount)
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// conn is a connection to a Gearman job server, where writes are safe to
+// perform concurrently but reads must only be performed by a single goroutine.
+type conn struct {
+	address string
+	netConn net.Conn
+	reader  *bufio.Reader
+
+	writeMut sync.Mutex
+}
+
+func dial(ctx context.Context, address string) (*conn, error) {
+	var dialer net.Dialer
+	netConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{
+		address: address,
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+	}, nil
+}
+
+func (c *conn) write(typ packetType, args ...[]byte) error {
+	c.writeMut.Lock()
+	defer c.writeMut.Unlock()
+	return writePacket(c.netConn, magicReq, typ, args...)
+}
+
+func (c *conn) read() (packet, error) {
+	return readPacket(c.reader, magicRes)
+}
+
+func (c *conn) Close() error {
+	return c.netConn.Close()
+}
@@ -0,0 +1,25 @@
+package gitlab
+
+import (
+	_ "embed"
+
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/template"
+)
+
+//go:embed template_webhook_input.yaml
+var webhookInputTemplate []byte
+
+//go:embed template_api_output.yaml
+var apiOutputTemplate []byte
+
+//go:embed template_api_processor.yaml
+var apiProcessorTemplate []byte
+
+func init() {
+	for _, t := range [][]byte{webhookInputTemplate, apiOutputTemplate, apiProcessorTemplate} {
+		if err := template.RegisterTemplateYAML(bundle.GlobalEnvironment, t); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,498 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/impl/protobuf"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gsiFieldAddress         = "address"
+	gsiFieldService         = "service"
+	gsiFieldImportPaths     = "import_paths"
+	gsiFieldReflection      = "reflection"
+	gsiFieldUseProtoNames   = "use_proto_names"
+	gsiFieldResponseMapping = "response_mapping"
+	gsiFieldTimeout         = "timeout"
+	gsiFieldCertFile        = "cert_file"
+	gsiFieldKeyFile         = "key_file"
+	gsiFieldClientCAFile    = "client_ca_file"
+)
+
+func serverInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Receives messages by serving a [gRPC](https://grpc.io/) service, where each request message received by the service becomes a message of the pipeline.").
+		Description(`
+The service is described by the `+"`.proto`"+` files found within `+"`import_paths`"+`, and each of its methods is served, whether unary, client streaming, server streaming or bidirectional streaming. Each request message is converted into its [JSON representation](https://protobuf.dev/programming-guides/proto3/#json), and when `+"`reflection`"+` is enabled the service can be discovered by clients such as `+"`grpcurl`"+` with [server reflection](https://github.com/grpc/grpc/blob/master/doc/server-reflection.md).
+
+### Responses
+
+Responses are sent once messages have been acknowledged, which means that they have reached their destination. For methods that are not server streaming a single response is sent once all request messages of a call have been acknowledged, and for server streaming methods a response is sent for each request message once it has been acknowledged. The response messages are empty by default, or the result of the `+"`response_mapping`"+` executed on the request message, where the last request message of a call is used for methods that are not server streaming.
+
+Calls with messages that are rejected, for example due to an output failing to deliver them, result in an error with the status code `+"`UNAVAILABLE`"+`, and calls with messages that are not acknowledged within the `+"`timeout`"+` result in an error with the status code `+"`DEADLINE_EXCEEDED`"+`, allowing clients to retry them.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- grpc_server_method
+- grpc_server_remote_addr
+- All metadata of the call
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewStringField(gsiFieldAddress).
+				Description("The address to listen on.").
+				Default("0.0.0.0:50051"),
+			service.NewStringField(gsiFieldService).
+				Description("The fully qualified name of the service to serve.").
+				Example("example.ingest.v1.IngestService"),
+			service.NewStringListField(gsiFieldImportPaths).
+				Description("A list of directories containing the `.proto` files that describe the service, including its imports.").
+				Example([]string{"./protos"}),
+			service.NewBoolField(gsiFieldReflection).
+				Description("Whether server reflection should be enabled, allowing clients to discover the service.").
+				Default(false),
+			service.NewBoolField(gsiFieldUseProtoNames).
+				Description("Whether the field names of messages should be the names within the `.proto` files rather than their lowerCamelCase JSON names.").
+				Default(false).
+				Advanced(),
+			service.NewBloblangField(gsiFieldResponseMapping).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) executed on request messages that results in the JSON representation of the response message.").
+				Example(`root.id = this.id`).
+				Example(`root.accepted = true`).
+				Optional(),
+			service.NewDurationField(gsiFieldTimeout).
+				Description("The maximum period to wait for messages to be acknowledged before responding with an error.").
+				Default("5s").
+				Advanced(),
+			service.NewStringField(gsiFieldCertFile).
+				Description("An optional certificate file for enabling TLS.").
+				Default("").
+				Advanced(),
+			service.NewStringField(gsiFieldKeyFile).
+				Description("An optional key file for enabling TLS.").
+				Default("").
+				Advanced(),
+			service.NewStringField(gsiFieldClientCAFile).
+				Description("An optional file of certificate authorities used to verify client certificates, which enables mutual TLS where clients without a valid certificate are rejected.").
+				Default("").
+				Advanced(),
+		).
+		Example("Ingesting Events", "A streaming service that receives events from clients, where each event is acknowledged once it has been written to Kafka.", `
+input:
+  grpc_server:
+    address: 0.0.0.0:50051
+    service: example.ingest.v1.IngestService
+    import_paths: [ ./protos ]
+    reflection: true
+    response_mapping: 'root.event_id = this.id'
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: events
+`)
+}
+
+func init() {
+	err := service.RegisterInput("grpc_server", serverInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			return newServerInputFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type serverRequest struct {
+	msg    *service.Message
+	ackErr chan error
+}
+
+type serverInput struct {
+	address         string
+	service         protoreflect.ServiceDescriptor
+	files           *protoregistry.Files
+	types           *protoregistry.Types
+	reflection      bool
+	useProtoNames   bool
+	responseMapping *bloblang.Executor
+	timeout         time.Duration
+	tlsConf         *tls.Config
+	log             *service.Logger
+
+	requests chan serverRequest
+
+	serverMut sync.Mutex
+	server    *grpc.Server
+	closed    chan struct{}
+}
+
+func newServerInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*serverInput, error) {
+	s := &serverInput{
+		log:      mgr.Logger(),
+		requests: make(chan serverRequest),
+		closed:   make(chan struct{}),
+	}
+
+	var err error
+	if s.address, err = conf.FieldString(gsiFieldAddress); err != nil {
+		return nil, err
+	}
+
+	importPaths, err := conf.FieldStringList(gsiFieldImportPaths)
+	if err != nil {
+		return nil, err
+	}
+	if len(importPaths) == 0 {
+		return nil, errors.New("at least one import path must be specified")
+	}
+	if s.files, s.types, err = protobuf.LoadDescriptors(mgr.FS(), importPaths); err != nil {
+		return nil, err
+	}
+
+	serviceName, err := conf.FieldString(gsiFieldService)
+	if err != nil {
+		return nil, err
+	}
+	d, err := s.files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find service '%v' definition within '%v'", serviceName, importPaths)
+	}
+	var ok bool
+	if s.service, ok = d.(protoreflect.ServiceDescriptor); !ok {
+		return nil, fmt.Errorf("descriptor %v was unexpected type %T", serviceName, d)
+	}
+
+	if s.reflection, err = conf.FieldBool(gsiFieldReflection); err != nil {
+		return nil, err
+	}
+	if s.useProtoNames, err = conf.FieldBool(gsiFieldUseProtoNames); err != nil {
+		return nil, err
+	}
+	if conf.Contains(gsiFieldResponseMapping) {
+		if s.responseMapping, err = conf.FieldBloblang(gsiFieldResponseMapping); err != nil {
+			return nil, err
+		}
+	}
+	if s.timeout, err = conf.FieldDuration(gsiFieldTimeout); err != nil {
+		return nil, err
+	}
+	if s.tlsConf, err = serverTLSFromParsed(conf); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func serverTLSFromParsed(conf *service.ParsedConfig) (*tls.Config, error) {
+	certFile, err := conf.FieldString(gsiFieldCertFile)
+	if err != nil {
+		return nil, err
+	}
+	keyFile, err := conf.FieldString(gsiFieldKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	clientCAFile, err := conf.FieldString(gsiFieldClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return nil, errors.New("a cert_file and key_file must be specified in order to use a client_ca_file")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate: %w", err)
+	}
+	tlsConf := btls.PolicyConfig()
+	tlsConf.Certificates = []tls.Certificate{cert}
+	if clientCAFile != "" {
+		caBytes, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caBytes) {
+			return nil, errors.New("failed to parse client CA file")
+		}
+		tlsConf.ClientCAs = pool
+		tlsConf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConf, nil
+}
+
+func (s *serverInput) serviceDesc() *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: string(s.service.FullName()),
+		HandlerType: (*any)(nil),
+		Metadata:    s.service.ParentFile().Path(),
+	}
+	methods := s.service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+
+		// All methods are served as streams, which is compatible with unary
+		// calls on the wire and allows a single handler for every method.
+		desc.Streams = append(desc.Streams, grpc.StreamDesc{
+			StreamName:    string(md.Name()),
+			ServerStreams: md.IsStreamingServer(),
+			ClientStreams: md.IsStreamingClient(),
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				return s.handle(md, stream)
+			},
+		})
+	}
+	return desc
+}
+
+func (s *serverInput) Connect(ctx context.Context) error {
+	s.serverMut.Lock()
+	defer s.serverMut.Unlock()
+
+	if s.server != nil {
+		return nil
+	}
+
+	var opts []grpc.ServerOption
+	if s.tlsConf != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(s.tlsConf)))
+	}
+	server := grpc.NewServer(opts...)
+	server.RegisterService(s.serviceDesc(), struct{}{})
+	if s.reflection {
+		refOpts := reflection.ServerOptions{
+			Services:           server,
+			DescriptorResolver: s.files,
+			ExtensionResolver:  s.types,
+		}
+		reflectionv1.RegisterServerReflectionServer(server, reflection.NewServerV1(refOpts))
+		reflectionv1alpha.RegisterServerReflectionServer(server, reflection.NewServer(refOpts))
+	}
+
+	lis, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return err
+	}
+	s.log.Infof("Serving gRPC service '%v' at: %v", s.service.FullName(), lis.Addr())
+
+	go func() {
+		if err := server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.log.Errorf("gRPC server error: %v", err)
+		}
+	}()
+	s.server = server
+	return nil
+}
+
+func (s *serverInput) message(ctx context.Context, md protoreflect.MethodDescriptor, req *dynamicpb.Message) (*service.Message, error) {
+	opts := protojson.MarshalOptions{
+		Resolver:      s.types,
+		UseProtoNames: s.useProtoNames,
+	}
+	mBytes, err := opts.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := service.NewMessage(mBytes)
+	msg.MetaSetMut("grpc_server_method", "/"+string(md.Parent().FullName())+"/"+string(md.Name()))
+	if p, ok := peer.FromContext(ctx); ok {
+		msg.MetaSetMut("grpc_server_remote_addr", p.Addr.String())
+	}
+	if callMD, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, v := range callMD {
+			if len(v) > 0 {
+				msg.MetaSetMut(k, v[0])
+			}
+		}
+	}
+	return msg, nil
+}
+
+// dispatch sends a message into the pipeline and returns a channel that
+// receives the result of its acknowledgement.
+func (s *serverInput) dispatch(ctx context.Context, msg *service.Message) (chan error, error) {
+	req := serverRequest{msg: msg, ackErr: make(chan error, 1)}
+	select {
+	case s.requests <- req:
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	case <-s.closed:
+		return nil, status.Error(codes.Unavailable, "server is shutting down")
+	}
+	return req.ackErr, nil
+}
+
+func (s *serverInput) awaitAck(ctx context.Context, ackErr chan error) error {
+	select {
+	case err := <-ackErr:
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "message was rejected: %v", err)
+		}
+		return nil
+	case <-time.After(s.timeout):
+		return status.Error(codes.DeadlineExceeded, "timed out waiting for message to be acknowledged")
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (s *serverInput) response(md protoreflect.MethodDescriptor, msg *service.Message) (*dynamicpb.Message, error) {
+	res := dynamicpb.NewMessage(md.Output())
+	if s.responseMapping == nil || msg == nil {
+		return res, nil
+	}
+
+	resMsg, err := msg.BloblangQuery(s.responseMapping)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "response mapping failed: %v", err)
+	}
+	if resMsg == nil {
+		return res, nil
+	}
+	resBytes, err := resMsg.AsBytes()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "response mapping failed: %v", err)
+	}
+	opts := protojson.UnmarshalOptions{Resolver: s.types}
+	if err := opts.Unmarshal(resBytes, res); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert response mapping result to '%v': %v", md.Output().FullName(), err)
+	}
+	return res, nil
+}
+
+func (s *serverInput) handle(md protoreflect.MethodDescriptor, stream grpc.ServerStream) error {
+	ctx := stream.Context()
+
+	var (
+		pending []chan error
+		lastMsg *service.Message
+	)
+	for {
+		req := dynamicpb.NewMessage(md.Input())
+		if err := stream.RecvMsg(req); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+
+		msg, err := s.message(ctx, md, req)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "failed to convert request: %v", err)
+		}
+		ackErr, err := s.dispatch(ctx, msg)
+		if err != nil {
+			return err
+		}
+
+		if !md.IsStreamingServer() {
+			pending = append(pending, ackErr)
+			lastMsg = msg
+			continue
+		}
+
+		if err := s.awaitAck(ctx, ackErr); err != nil {
+			return err
+		}
+		res, err := s.response(md, msg)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(res); err != nil {
+			return err
+		}
+	}
+
+	if md.IsStreamingServer() {
+		return nil
+	}
+	for _, ackErr := range pending {
+		if err := s.awaitAck(ctx, ackErr); err != nil {
+			return err
+		}
+	}
+	res, err := s.response(md, lastMsg)
+	if err != nil {
+		return err
+	}
+	return stream.SendMsg(res)
+}
+
+func (s *serverInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	select {
+	case req := <-s.requests:
+		return req.msg, func(ctx context.Context, err error) error {
+			req.ackErr <- err
+			return nil
+		}, nil
+	case <-s.closed:
+		return nil, nil, component.ErrTypeClosed
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (s *serverInput) Close(ctx context.Context) error {
+	s.serverMut.Lock()
+	defer s.serverMut.Unlock()
+
+	select {
+	case <-s.closed:
+	default:
+		close(s.closed)
+	}
+	if s.server == nil {
+		return nil
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.server.Stop()
+	}
+	s.server = nil
+	return nil
+}
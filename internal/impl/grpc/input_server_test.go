@@ -0,0 +1,180 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jhump/protoreflect/grpcreflect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testServerInput(t *testing.T, confStr string) (*serverInput, *grpc.ClientConn) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	pConf, err := serverInputSpec().ParseYAML(`
+address: `+addr+`
+service: test.v1.Orders
+import_paths: [ `+testProtoDir(t)+` ]
+`+confStr, nil)
+	require.NoError(t, err)
+
+	s, err := newServerInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, s.Connect(context.Background()))
+	t.Cleanup(func() {
+		ctx, done := context.WithTimeout(context.Background(), time.Second)
+		defer done()
+		_ = s.Close(ctx)
+	})
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return s, conn
+}
+
+func testOrder(t *testing.T, s *serverInput, id string) *dynamicpb.Message {
+	t.Helper()
+
+	md := s.service.Methods().ByName("Create").Input()
+	m := dynamicpb.NewMessage(md)
+	m.Set(md.Fields().ByName("id"), protoreflect.ValueOfString(id))
+	return m
+}
+
+// consume reads messages from the input and acknowledges them with the result
+// of a function.
+func consume(t *testing.T, s *serverInput, n int, ackFn func(msg *service.Message) error) chan string {
+	t.Helper()
+
+	received := make(chan string, n)
+	go func() {
+		for i := 0; i < n; i++ {
+			msg, ack, err := s.Read(context.Background())
+			if err != nil {
+				return
+			}
+			mBytes, _ := msg.AsBytes()
+			received <- string(mBytes)
+			_ = ack(context.Background(), ackFn(msg))
+		}
+	}()
+	return received
+}
+
+func TestGRPCServerUnary(t *testing.T) {
+	s, conn := testServerInput(t, ``)
+
+	received := consume(t, s, 2, func(msg *service.Message) error {
+		tenant, _ := msg.MetaGet("x-tenant")
+		assert.Equal(t, "foo", tenant)
+		method, _ := msg.MetaGet("grpc_server_method")
+		assert.Equal(t, "/test.v1.Orders/Create", method)
+
+		mBytes, _ := msg.AsBytes()
+		if string(mBytes) == `{"id":"bad"}` {
+			return errors.New("nope")
+		}
+		return nil
+	})
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "foo")
+	output := s.service.Methods().ByName("Create").Output()
+
+	require.NoError(t, conn.Invoke(ctx, "/test.v1.Orders/Create", testOrder(t, s, "a"), dynamicpb.NewMessage(output)))
+	assert.Equal(t, `{"id":"a"}`, <-received)
+
+	err := conn.Invoke(ctx, "/test.v1.Orders/Create", testOrder(t, s, "bad"), dynamicpb.NewMessage(output))
+	require.Error(t, err)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestGRPCServerClientStream(t *testing.T) {
+	s, conn := testServerInput(t, `
+response_mapping: 'root = {}'
+`)
+
+	received := consume(t, s, 3, func(msg *service.Message) error { return nil })
+
+	stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ClientStreams: true}, "/test.v1.Orders/Upload")
+	require.NoError(t, err)
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, stream.SendMsg(testOrder(t, s, id)))
+	}
+	require.NoError(t, stream.CloseSend())
+	require.NoError(t, stream.RecvMsg(dynamicpb.NewMessage(s.service.Methods().ByName("Upload").Output())))
+
+	assert.Equal(t, `{"id":"a"}`, <-received)
+	assert.Equal(t, `{"id":"b"}`, <-received)
+	assert.Equal(t, `{"id":"c"}`, <-received)
+}
+
+func TestGRPCServerServerStream(t *testing.T) {
+	s, conn := testServerInput(t, ``)
+
+	received := consume(t, s, 1, func(msg *service.Message) error { return nil })
+
+	stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, "/test.v1.Orders/Watch")
+	require.NoError(t, err)
+	require.NoError(t, stream.SendMsg(testOrder(t, s, "a")))
+	require.NoError(t, stream.CloseSend())
+
+	output := s.service.Methods().ByName("Watch").Output()
+	require.NoError(t, stream.RecvMsg(dynamicpb.NewMessage(output)))
+	assert.Equal(t, `{"id":"a"}`, <-received)
+
+	err = stream.RecvMsg(dynamicpb.NewMessage(output))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestGRPCServerTimeout(t *testing.T) {
+	s, conn := testServerInput(t, `
+timeout: 50ms
+`)
+
+	go func() {
+		_, _, _ = s.Read(context.Background())
+	}()
+
+	err := conn.Invoke(context.Background(), "/test.v1.Orders/Create", testOrder(t, s, "a"), dynamicpb.NewMessage(s.service.Methods().ByName("Create").Output()))
+	require.Error(t, err)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestGRPCServerReflection(t *testing.T) {
+	s, conn := testServerInput(t, `
+reflection: true
+`)
+
+	refClient := grpcreflect.NewClientAuto(context.Background(), conn)
+	defer refClient.Reset()
+
+	services, err := refClient.ListServices()
+	require.NoError(t, err)
+	assert.Contains(t, services, "test.v1.Orders")
+
+	sd, err := refClient.ResolveService("test.v1.Orders")
+	require.NoError(t, err)
+	assert.Equal(t, s.service.Methods().Len(), len(sd.GetMethods()))
+}
@@ -0,0 +1,396 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jhump/protoreflect/grpcreflect"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/internal/component/output"
+	"github.com/benthosdev/benthos/v4/internal/impl/protobuf"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	gcoFieldAddress        = "address"
+	gcoFieldMethod         = "method"
+	gcoFieldImportPaths    = "import_paths"
+	gcoFieldRequestMapping = "request_mapping"
+	gcoFieldDiscardUnknown = "discard_unknown"
+	gcoFieldMetadata       = "metadata"
+	gcoFieldTLS            = "tls"
+	gcoFieldTimeout        = "timeout"
+	gcoFieldBatching       = "batching"
+)
+
+func clientOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Invokes a unary or client streaming [gRPC](https://grpc.io/) method for each message or batch of messages.").
+		Description(output.Description(true, true, `
+The descriptor of the method is either parsed from the `+"`.proto`"+` files found within `+"`import_paths`"+`, or when no import paths are configured it is obtained from the server with [server reflection](https://github.com/grpc/grpc/blob/master/doc/server-reflection.md).
+
+Each message is converted into the request message of the method from its JSON representation, following the [JSON mapping of protobuf](https://protobuf.dev/programming-guides/proto3/#json), where the field `+"`request_mapping`"+` can be used in order to map a message into the shape of the request beforehand.
+
+### Methods
+
+Unary methods are invoked once for each message, and a message is acknowledged once the call has succeeded. Client streaming methods are invoked once for each batch of messages, where each message of the batch is sent as a message of the stream, and the batch is acknowledged once the server has responded. Server and bidirectional streaming methods are not supported.
+
+### Metadata
+
+The field `+"`metadata`"+` sets the metadata of calls, which can be interpolated from each message. When a batch is sent with a client streaming method the metadata is interpolated from the first message of the batch.`)).
+		Fields(
+			service.NewStringField(gcoFieldAddress).
+				Description("The address of the server, in the form of a [gRPC target](https://github.com/grpc/grpc/blob/master/doc/naming.md).").
+				Example("localhost:50051").
+				Example("dns:///orders.example.com:443"),
+			service.NewStringField(gcoFieldMethod).
+				Description("The fully qualified name of the method to invoke.").
+				Example("example.orders.v1.OrderService/CreateOrder"),
+			service.NewStringListField(gcoFieldImportPaths).
+				Description("A list of directories containing the `.proto` files that describe the method, including its imports. When empty the method is described by the server using server reflection.").
+				Example([]string{"./protos"}).
+				Default([]any{}),
+			service.NewBloblangField(gcoFieldRequestMapping).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the JSON representation of the request message, which defaults to the contents of the message.").
+				Example(`root.order_id = this.id
+root.items = this.lines.map_each(l -> { "sku": l.sku, "quantity": l.qty })`).
+				Optional(),
+			service.NewBoolField(gcoFieldDiscardUnknown).
+				Description("Whether fields that are not part of the request message should be ignored, otherwise they result in an error.").
+				Default(false).
+				Advanced(),
+			service.NewInterpolatedStringMapField(gcoFieldMetadata).
+				Description("A map of metadata to set on calls.").
+				Example(map[string]any{
+					"authorization": "Bearer ${! env(\"TOKEN\") }",
+					"x-tenant-id":   "${! @tenant }",
+				}).
+				Default(map[string]any{}),
+			service.NewTLSToggledField(gcoFieldTLS),
+			service.NewDurationField(gcoFieldTimeout).
+				Description("The maximum period of time to wait for a call to complete.").
+				Default("5s").
+				Advanced(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(gcoFieldBatching),
+		).
+		Example("Creating Orders", "Orders are created with a unary method of a service that supports server reflection, where each message is mapped into the request.", `
+output:
+  grpc_client:
+    address: orders.example.com:443
+    method: example.orders.v1.OrderService/CreateOrder
+    tls:
+      enabled: true
+    request_mapping: |
+      root.customer_id = this.customer.id
+      root.items = this.items
+    metadata:
+      x-request-id: ${! @request_id }
+`).
+		Example("Streaming Batches", "Batches of readings are sent as a single call of a client streaming method, where the method is described by local `.proto` files.", `
+output:
+  grpc_client:
+    address: localhost:50051
+    method: example.telemetry.v1.Ingest/Record
+    import_paths: [ ./protos ]
+    batching:
+      count: 500
+      period: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("grpc_client", clientOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(gcoFieldBatching); err != nil {
+				return
+			}
+			out, err = newClientWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type clientWriter struct {
+	address        string
+	service        protoreflect.FullName
+	methodName     protoreflect.Name
+	fullMethod     string
+	files          *protoregistry.Files
+	types          *protoregistry.Types
+	requestMapping *bloblang.Executor
+	discardUnknown bool
+	metadata       map[string]*service.InterpolatedString
+	tlsConf        *tls.Config
+	timeout        time.Duration
+	log            *service.Logger
+
+	connMut sync.RWMutex
+	conn    *grpc.ClientConn
+	method  protoreflect.MethodDescriptor
+}
+
+func newClientWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*clientWriter, error) {
+	c := &clientWriter{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if c.address, err = conf.FieldString(gcoFieldAddress); err != nil {
+		return nil, err
+	}
+
+	methodStr, err := conf.FieldString(gcoFieldMethod)
+	if err != nil {
+		return nil, err
+	}
+	serviceStr, methodName, ok := strings.Cut(strings.TrimPrefix(methodStr, "/"), "/")
+	if !ok || serviceStr == "" || methodName == "" {
+		return nil, fmt.Errorf("method '%v' must be of the form package.Service/Method", methodStr)
+	}
+	c.service = protoreflect.FullName(serviceStr)
+	c.methodName = protoreflect.Name(methodName)
+	c.fullMethod = "/" + serviceStr + "/" + methodName
+
+	importPaths, err := conf.FieldStringList(gcoFieldImportPaths)
+	if err != nil {
+		return nil, err
+	}
+	if len(importPaths) > 0 {
+		if c.files, c.types, err = protobuf.LoadDescriptors(mgr.FS(), importPaths); err != nil {
+			return nil, err
+		}
+		if c.method, err = c.methodFromFiles(); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains(gcoFieldRequestMapping) {
+		if c.requestMapping, err = conf.FieldBloblang(gcoFieldRequestMapping); err != nil {
+			return nil, err
+		}
+	}
+	if c.discardUnknown, err = conf.FieldBool(gcoFieldDiscardUnknown); err != nil {
+		return nil, err
+	}
+	if c.metadata, err = conf.FieldInterpolatedStringMap(gcoFieldMetadata); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(gcoFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		c.tlsConf = tlsConf
+	}
+	if c.timeout, err = conf.FieldDuration(gcoFieldTimeout); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *clientWriter) methodFromFiles() (protoreflect.MethodDescriptor, error) {
+	d, err := c.files.FindDescriptorByName(c.service)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find service '%v' definition: %w", c.service, err)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("descriptor %v was unexpected type %T", c.service, d)
+	}
+	return checkMethod(sd, c.methodName)
+}
+
+func (c *clientWriter) methodFromReflection(ctx context.Context, conn *grpc.ClientConn) (protoreflect.MethodDescriptor, error) {
+	refClient := grpcreflect.NewClientAuto(ctx, conn)
+	defer refClient.Reset()
+
+	sd, err := refClient.ResolveService(string(c.service))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve service '%v' with server reflection: %w", c.service, err)
+	}
+	return checkMethod(sd.UnwrapService(), c.methodName)
+}
+
+func checkMethod(sd protoreflect.ServiceDescriptor, name protoreflect.Name) (protoreflect.MethodDescriptor, error) {
+	md := sd.Methods().ByName(name)
+	if md == nil {
+		return nil, fmt.Errorf("service '%v' does not have a method '%v'", sd.FullName(), name)
+	}
+	if md.IsStreamingServer() {
+		return nil, fmt.Errorf("method '%v' is server streaming, which is not supported", md.FullName())
+	}
+	return md, nil
+}
+
+func (c *clientWriter) Connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.conn != nil {
+		return nil
+	}
+
+	creds := insecure.NewCredentials()
+	if c.tlsConf != nil {
+		creds = credentials.NewTLS(c.tlsConf)
+	}
+	conn, err := grpc.DialContext(ctx, c.address, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+
+	if c.method == nil {
+		md, err := c.methodFromReflection(ctx, conn)
+		if err != nil {
+			_ = conn.Close()
+			return err
+		}
+		c.method = md
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *clientWriter) request(md protoreflect.MethodDescriptor, msg *service.Message) (*dynamicpb.Message, error) {
+	if c.requestMapping != nil {
+		var err error
+		if msg, err = msg.BloblangQuery(c.requestMapping); err != nil {
+			return nil, fmt.Errorf("request mapping failed: %w", err)
+		}
+		if msg == nil {
+			return nil, errors.New("request mapping resulted in a deleted message")
+		}
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	req := dynamicpb.NewMessage(md.Input())
+	opts := protojson.UnmarshalOptions{
+		DiscardUnknown: c.discardUnknown,
+	}
+	if c.types != nil {
+		opts.Resolver = c.types
+	}
+	if err := opts.Unmarshal(mBytes, req); err != nil {
+		return nil, fmt.Errorf("failed to convert message to '%v': %w", md.Input().FullName(), err)
+	}
+	return req, nil
+}
+
+func (c *clientWriter) callContext(ctx context.Context, msg *service.Message) (context.Context, context.CancelFunc, error) {
+	pairs := make([]string, 0, len(c.metadata)*2)
+	for k, v := range c.metadata {
+		vStr, err := v.TryString(msg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("metadata '%v' interpolation: %w", k, err)
+		}
+		pairs = append(pairs, k, vStr)
+	}
+	if len(pairs) > 0 {
+		ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+	}
+	ctx, done := context.WithTimeout(ctx, c.timeout)
+	return ctx, done, nil
+}
+
+func (c *clientWriter) invokeUnary(ctx context.Context, conn *grpc.ClientConn, md protoreflect.MethodDescriptor, msg *service.Message) error {
+	req, err := c.request(md, msg)
+	if err != nil {
+		return err
+	}
+
+	ctx, done, err := c.callContext(ctx, msg)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	return conn.Invoke(ctx, c.fullMethod, req, dynamicpb.NewMessage(md.Output()))
+}
+
+func (c *clientWriter) invokeStream(ctx context.Context, conn *grpc.ClientConn, md protoreflect.MethodDescriptor, batch service.MessageBatch) error {
+	reqs := make([]*dynamicpb.Message, len(batch))
+	for i, msg := range batch {
+		var err error
+		if reqs[i], err = c.request(md, msg); err != nil {
+			return err
+		}
+	}
+
+	ctx, done, err := c.callContext(ctx, batch[0])
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true}, c.fullMethod)
+	if err != nil {
+		return err
+	}
+	for _, req := range reqs {
+		if err := stream.SendMsg(req); err != nil {
+			// The cause of a failed send is surfaced by receiving.
+			break
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	return stream.RecvMsg(dynamicpb.NewMessage(md.Output()))
+}
+
+func (c *clientWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	c.connMut.RLock()
+	conn, md := c.conn, c.method
+	c.connMut.RUnlock()
+
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+
+	if md.IsStreamingClient() {
+		return c.invokeStream(ctx, conn, md, batch)
+	}
+	return batch.WalkWithBatchedErrors(func(i int, msg *service.Message) error {
+		return c.invokeUnary(ctx, conn, md, msg)
+	})
+}
+
+func (c *clientWriter) Close(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
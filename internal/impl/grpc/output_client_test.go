@@ -0,0 +1,226 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/protobuf"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testProto = `
+syntax = "proto3";
+
+package test.v1;
+
+message Order {
+  string id = 1;
+  int64 quantity = 2;
+}
+
+message Ack {}
+
+service Orders {
+  rpc Create(Order) returns (Ack);
+  rpc Upload(stream Order) returns (Ack);
+  rpc Watch(Order) returns (stream Ack);
+}
+`
+
+type testServer struct {
+	mut      sync.Mutex
+	received []string
+	streams  [][]string
+	md       []metadata.MD
+}
+
+func startTestServer(t *testing.T, files *protoregistry.Files) (*testServer, string) {
+	t.Helper()
+
+	d, err := files.FindDescriptorByName("test.v1.Orders")
+	require.NoError(t, err)
+	sd := d.(protoreflect.ServiceDescriptor)
+	orderDesc := sd.Methods().ByName("Create").Input()
+
+	ts := &testServer{}
+	record := func(m *dynamicpb.Message) string {
+		return fmt.Sprintf("%v:%v",
+			m.Get(orderDesc.Fields().ByName("id")).String(),
+			m.Get(orderDesc.Fields().ByName("quantity")).Int())
+	}
+
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.v1.Orders",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Create",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				m := dynamicpb.NewMessage(orderDesc)
+				if err := dec(m); err != nil {
+					return nil, err
+				}
+				if m.Get(orderDesc.Fields().ByName("id")).String() == "bad" {
+					return nil, status.Error(codes.InvalidArgument, "bad order")
+				}
+				md, _ := metadata.FromIncomingContext(ctx)
+
+				ts.mut.Lock()
+				ts.received = append(ts.received, record(m))
+				ts.md = append(ts.md, md)
+				ts.mut.Unlock()
+				return dynamicpb.NewMessage(sd.Methods().ByName("Create").Output()), nil
+			},
+		}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Upload",
+			ClientStreams: true,
+			Handler: func(_ any, stream grpc.ServerStream) error {
+				var orders []string
+				for {
+					m := dynamicpb.NewMessage(orderDesc)
+					if err := stream.RecvMsg(m); err != nil {
+						break
+					}
+					orders = append(orders, record(m))
+				}
+				ts.mut.Lock()
+				ts.streams = append(ts.streams, orders)
+				ts.mut.Unlock()
+				return stream.SendMsg(dynamicpb.NewMessage(sd.Methods().ByName("Upload").Output()))
+			},
+		}},
+	}, struct{}{})
+	reflectionv1.RegisterServerReflectionServer(srv, reflection.NewServerV1(reflection.ServerOptions{
+		Services:           srv,
+		DescriptorResolver: files,
+	}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	return ts, lis.Addr().String()
+}
+
+func testProtoDir(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "orders.proto"), []byte(testProto), 0o644))
+	return dir
+}
+
+func testClientWriter(t *testing.T, confStr string) *clientWriter {
+	t.Helper()
+
+	pConf, err := clientOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	w, err := newClientWriterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return w
+}
+
+func TestGRPCClientUnaryReflection(t *testing.T) {
+	files, _, err := protobuf.LoadDescriptors(service.MockResources().FS(), []string{testProtoDir(t)})
+	require.NoError(t, err)
+
+	ts, addr := startTestServer(t, files)
+
+	w := testClientWriter(t, `
+address: `+addr+`
+method: test.v1.Orders/Create
+request_mapping: 'root = this.order'
+metadata:
+  x-tenant: ${! @tenant }
+`)
+	require.NoError(t, w.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+
+	msgA := service.NewMessage([]byte(`{"order":{"id":"a","quantity":2}}`))
+	msgA.MetaSetMut("tenant", "foo")
+	msgB := service.NewMessage([]byte(`{"order":{"id":"bad"}}`))
+	msgC := service.NewMessage([]byte(`{"order":{"id":"c","nope":true}}`))
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{msgA, msgB, msgC})
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.ErrorAs(t, err, &bErr)
+	assert.Equal(t, 2, bErr.IndexedErrors())
+
+	ts.mut.Lock()
+	defer ts.mut.Unlock()
+	assert.Equal(t, []string{"a:2"}, ts.received)
+	assert.Equal(t, []string{"foo"}, ts.md[0].Get("x-tenant"))
+}
+
+func TestGRPCClientStreamImportPaths(t *testing.T) {
+	dir := testProtoDir(t)
+	files, _, err := protobuf.LoadDescriptors(service.MockResources().FS(), []string{dir})
+	require.NoError(t, err)
+
+	ts, addr := startTestServer(t, files)
+
+	w := testClientWriter(t, `
+address: `+addr+`
+method: /test.v1.Orders/Upload
+import_paths: [ `+dir+` ]
+`)
+	require.NoError(t, w.Connect(context.Background()))
+	t.Cleanup(func() {
+		_ = w.Close(context.Background())
+	})
+
+	require.NoError(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{"id":"b","quantity":3}`)),
+	}))
+
+	ts.mut.Lock()
+	defer ts.mut.Unlock()
+	assert.Equal(t, [][]string{{"a:0", "b:3"}}, ts.streams)
+}
+
+func TestGRPCClientUnsupportedMethods(t *testing.T) {
+	dir := testProtoDir(t)
+
+	for _, conf := range []string{
+		`method: test.v1.Orders/Watch`,
+		`method: test.v1.Orders/Nope`,
+		`method: test.v1.Nope/Create`,
+		`method: Create`,
+	} {
+		pConf, err := clientOutputSpec().ParseYAML(`
+address: localhost:50051
+import_paths: [ `+dir+` ]
+`+conf, nil)
+		require.NoError(t, err)
+
+		_, err = newClientWriterFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
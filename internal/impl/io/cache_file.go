@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/benthosdev/benthos/v4/internal/atrest"
 	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -18,7 +19,8 @@ func fileCacheConfig() *service.ConfigSpec {
 		Summary(`Stores each item in a directory as a file, where an item ID is the path relative to the configured directory.`).
 		Description(`This type currently offers no form of item expiry or garbage collection, and is intended to be used for development and debugging purposes only.`).
 		Field(service.NewStringField("directory").
-			Description("The directory within which to store items."))
+			Description("The directory within which to store items.")).
+		Field(atrest.FieldSpec("encryption"))
 
 	return spec
 }
@@ -43,7 +45,11 @@ func newFileCacheFromConfig(conf *service.ParsedConfig, mgr *service.Resources)
 	if err != nil {
 		return nil, err
 	}
-	return newFileCache(directory, mgr), nil
+	f := newFileCache(directory, mgr)
+	if f.cipher, err = atrest.CipherFromParsed(conf, "encryption"); err != nil {
+		return nil, err
+	}
+	return f, nil
 }
 
 //------------------------------------------------------------------------------
@@ -53,8 +59,9 @@ func newFileCache(dir string, mgr *service.Resources) *fileCache {
 }
 
 type fileCache struct {
-	mgr *service.Resources
-	dir string
+	mgr    *service.Resources
+	dir    string
+	cipher *atrest.Cipher
 }
 
 func (f *fileCache) Get(_ context.Context, key string) ([]byte, error) {
@@ -62,14 +69,25 @@ func (f *fileCache) Get(_ context.Context, key string) ([]byte, error) {
 	if errors.Is(err, fs.ErrNotExist) {
 		return nil, service.ErrKeyNotFound
 	}
-	return b, err
+	if err != nil {
+		return nil, err
+	}
+	return f.cipher.Open(b)
 }
 
 func (f *fileCache) Set(_ context.Context, key string, value []byte, _ *time.Duration) error {
+	value, err := f.cipher.Seal(value)
+	if err != nil {
+		return err
+	}
 	return ifs.WriteFile(f.mgr.FS(), filepath.Join(f.dir, key), value, 0o644)
 }
 
 func (f *fileCache) Add(_ context.Context, key string, value []byte, _ *time.Duration) error {
+	value, err := f.cipher.Seal(value)
+	if err != nil {
+		return err
+	}
 	file, err := f.mgr.FS().OpenFile(filepath.Join(f.dir, key), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
 	if err != nil {
 		if errors.Is(err, fs.ErrExist) {
@@ -3,6 +3,7 @@ package io
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,3 +49,36 @@ func TestFileCache(t *testing.T) {
 	_, err = c.Get(tCtx, "foo")
 	assert.Equal(t, service.ErrKeyNotFound, err)
 }
+
+func TestFileCacheEncryption(t *testing.T) {
+	dir := t.TempDir()
+	tCtx := context.Background()
+
+	pConf, err := fileCacheConfig().ParseYAML(`
+directory: `+dir+`
+encryption:
+  key: YWFhYWFhYWFhYWFhYWFhYWFhYWFhYWFhYWFhYWFhYWE=
+`, nil)
+	require.NoError(t, err)
+
+	c, err := newFileCacheFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	require.NoError(t, c.Set(tCtx, "foo", []byte("hello world"), nil))
+	require.NoError(t, c.Add(tCtx, "bar", []byte("hello bar"), nil))
+
+	for k, exp := range map[string]string{"foo": "hello world", "bar": "hello bar"} {
+		raw, err := os.ReadFile(filepath.Join(dir, k))
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), exp)
+
+		act, err := c.Get(tCtx, k)
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(act))
+	}
+
+	// Items written without encryption cannot be read.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "baz"), []byte("plain"), 0o644))
+	_, err = c.Get(tCtx, "baz")
+	require.Error(t, err)
+}
@@ -0,0 +1,311 @@
+package io
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/api"
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/component/input"
+	"github.com/benthosdev/benthos/v4/internal/component/interop"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	bfiFieldInput       = "input"
+	bfiFieldPrefix      = "prefix"
+	bfiFieldMetadataKey = "metadata_key"
+
+	bfiLiveID = "live"
+)
+
+func backfillInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Utility").
+		Summary(`Consumes from a live input and allows bounded backfills of replayable inputs to be run alongside it, which are created and removed during runtime via a REST HTTP interface.`).
+		Description(`
+A backfill is described by a child input configured to begin at the desired start position, such as a `+"`kafka_franz`"+` input with a new consumer group and `+"`start_from_oldest`"+` enabled, a `+"`aws_kinesis`"+` input with a new checkpoint table, or an `+"`aws_s3`"+` input with a date based `+"`prefix`"+`. The positions at which the backfill starts and ends can be narrowed further with [Bloblang queries](/docs/guides/bloblang/about/) executed against each consumed message:
+
+- Messages for which the `+"`start_check`"+` does not return `+"`true`"+` are acknowledged and dropped, which allows backfills to begin from a timestamp or offset, e.g. `+"`meta(\"kafka_timestamp_unix\").number() >= 1713916800`"+`.
+- Once a message is consumed for which the `+"`end_check`"+` returns `+"`true`"+` that message is sent and the backfill is stopped, e.g. `+"`meta(\"kafka_offset\").number() >= 1000`"+`. Bear in mind that for inputs that consume multiple partitions or shards in parallel the end check is met by whichever reaches it first.
+
+Backfills that have neither an end check nor a natural end (such as the end of an S3 listing) run until they are deleted.
+
+Messages consumed by a backfill are given a metadata field (`+"`backfill_id` by default"+`) containing the identifier of the backfill, and can therefore be distinguished from those of the live input, which is left unchanged.`).
+		Footnotes(`
+## Endpoints
+
+### GET `+"`/backfills`"+`
+
+Returns a JSON object detailing all running backfills and their uptimes.
+
+### GET `+"`/backfills/{id}`"+`
+
+Returns the configuration of a backfill.
+
+### POST `+"`/backfills/{id}`"+`
+
+Creates a backfill, or restarts it when it already exists, with a configuration provided in the request body (in YAML or JSON format) of the form:
+
+`+"```yaml"+`
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: orders_backfill_20240424
+    start_from_oldest: true
+start_check: 'meta("kafka_timestamp_unix").number() >= 1713916800'
+end_check: 'meta("kafka_timestamp_unix").number() >= 1714003200'
+`+"```"+`
+
+The identifier `+"`live`"+` is reserved.
+
+### DELETE `+"`/backfills/{id}`"+`
+
+Stops and removes a backfill.
+
+### GET `+"`/backfills/{id}/uptime`"+`
+
+Returns the uptime of a backfill as a duration string (of the form "72h3m0.5s"), or "stopped" in the case where the backfill has finished.`).
+		Fields(
+			service.NewInputField(bfiFieldInput).
+				Description("The live input to consume from."),
+			service.NewStringField(bfiFieldPrefix).
+				Description("A path prefix for HTTP endpoints that are registered.").
+				Default(""),
+			service.NewStringField(bfiFieldMetadataKey).
+				Description("The metadata key in which the identifier of the backfill is stored for backfilled messages.").
+				Default("backfill_id").
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchInput("backfill", backfillInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newBackfillInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return interop.NewUnwrapInternalInput(i), nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// backfillRequest is the body of a request to create a backfill.
+type backfillRequest struct {
+	Input      map[string]any `yaml:"input"`
+	StartCheck string         `yaml:"start_check"`
+	EndCheck   string         `yaml:"end_check"`
+}
+
+// backfillInputConfig composes the configuration of an input that implements
+// a backfill request from existing components, where the start check is a
+// filter, the end check is a `read_until` input and the backfill identifier is
+// added with a mutation.
+func backfillInputConfig(id, metaKey string, req backfillRequest) (any, error) {
+	if len(req.Input) == 0 {
+		return nil, errors.New("an input must be specified")
+	}
+
+	idBytes, err := json.Marshal(id)
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := json.Marshal(metaKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var procs []any
+	if existing, exists := req.Input["processors"]; exists {
+		existingProcs, ok := existing.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected input processors to be an array, got %T", existing)
+		}
+		procs = append(procs, existingProcs...)
+	}
+	if req.StartCheck != "" {
+		procs = append(procs, map[string]any{
+			"mapping": fmt.Sprintf("root = if !(%v) { deleted() }", req.StartCheck),
+		})
+	}
+	procs = append(procs, map[string]any{
+		"mutation": fmt.Sprintf("meta %s = %s", keyBytes, idBytes),
+	})
+
+	childConf := make(map[string]any, len(req.Input)+1)
+	for k, v := range req.Input {
+		childConf[k] = v
+	}
+	childConf["processors"] = procs
+
+	if req.EndCheck == "" {
+		return childConf, nil
+	}
+	return map[string]any{
+		"read_until": map[string]any{
+			"check": req.EndCheck,
+			"input": childConf,
+		},
+	}, nil
+}
+
+func newBackfillInputFromParsed(conf *service.ParsedConfig, res *service.Resources) (input.Streamed, error) {
+	liveInput, err := conf.FieldInput(bfiFieldInput)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, err := conf.FieldString(bfiFieldPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	metaKey, err := conf.FieldString(bfiFieldMetadataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var reqConfsMut sync.Mutex
+	reqConfs := map[string][]byte{}
+
+	dynAPI := api.NewDynamic()
+	mgr := interop.UnwrapManagement(res)
+	fanIn, err := newDynamicFanInInput(
+		map[string]input.Streamed{
+			bfiLiveID: interop.UnwrapOwnedInput(liveInput),
+		},
+		mgr.Logger(),
+		func(ctx context.Context, l string) {
+			reqConfsMut.Lock()
+			defer reqConfsMut.Unlock()
+
+			confBytes, exists := reqConfs[l]
+			if !exists {
+				return
+			}
+
+			dynAPI.Started(l, confBytes)
+			delete(reqConfs, l)
+		},
+		func(ctx context.Context, l string) {
+			if l != bfiLiveID {
+				dynAPI.Stopped(l)
+			}
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	dynAPI.OnUpdate(func(ctx context.Context, id string, c []byte) error {
+		if id == bfiLiveID {
+			return fmt.Errorf("the backfill identifier %v is reserved", bfiLiveID)
+		}
+
+		var req backfillRequest
+		if err := yaml.Unmarshal(c, &req); err != nil {
+			return fmt.Errorf("failed to parse backfill request: %w", err)
+		}
+
+		childConf, err := backfillInputConfig(id, metaKey, req)
+		if err != nil {
+			return err
+		}
+
+		var confNode yaml.Node
+		if err := confNode.Encode(childConf); err != nil {
+			return err
+		}
+
+		newConf, err := input.FromAny(bundle.GlobalEnvironment, &confNode)
+		if err != nil {
+			return err
+		}
+
+		iMgr := mgr.IntoPath("backfill", "backfills", id)
+		newInput, err := iMgr.NewInput(newConf)
+		if err != nil {
+			return err
+		}
+
+		reqConfsMut.Lock()
+		reqConfs[id] = backfillRequestYAML(req)
+		reqConfsMut.Unlock()
+		if err = fanIn.SetInput(ctx, id, newInput); err != nil {
+			mgr.Logger().Error("Failed to start backfill '%v': %v", id, err)
+			reqConfsMut.Lock()
+			delete(reqConfs, id)
+			reqConfsMut.Unlock()
+		}
+		return err
+	})
+
+	dynAPI.OnDelete(func(ctx context.Context, id string) error {
+		if id == bfiLiveID {
+			return fmt.Errorf("the backfill identifier %v is reserved", bfiLiveID)
+		}
+		err := fanIn.SetInput(ctx, id, nil)
+		if err != nil {
+			mgr.Logger().Error("Failed to stop backfill '%v': %v", id, err)
+		}
+		return err
+	})
+
+	mgr.RegisterEndpoint(
+		path.Join(prefix, "/backfills/{id}/uptime"),
+		`Returns the uptime of a specific backfill as a duration string, or "stopped" for backfills that have finished.`,
+		dynAPI.HandleUptime,
+	)
+	mgr.RegisterEndpoint(
+		path.Join(prefix, "/backfills/{id}"),
+		"Perform CRUD operations on backfills. For more information read the"+
+			" `backfill` input type documentation.",
+		dynAPI.HandleCRUD,
+	)
+	mgr.RegisterEndpoint(
+		path.Join(prefix, "/backfills"),
+		"Get a map of running backfill identifiers with their current uptimes.",
+		dynAPI.HandleList,
+	)
+
+	return fanIn, nil
+}
+
+// backfillRequestYAML returns a sanitised YAML representation of a backfill
+// request with secrets of the child input scrubbed.
+func backfillRequestYAML(req backfillRequest) []byte {
+	var inputNode yaml.Node
+	if err := inputNode.Encode(req.Input); err != nil {
+		return nil
+	}
+
+	sanitConf := docs.NewSanitiseConfig(bundle.GlobalEnvironment)
+	sanitConf.RemoveTypeField = true
+	sanitConf.ScrubSecrets = true
+	if err := docs.FieldInput(bfiFieldInput, "").SanitiseYAML(&inputNode, sanitConf); err != nil {
+		return nil
+	}
+
+	reqMap := map[string]any{bfiFieldInput: &inputNode}
+	if req.StartCheck != "" {
+		reqMap["start_check"] = req.StartCheck
+	}
+	if req.EndCheck != "" {
+		reqMap["end_check"] = req.EndCheck
+	}
+	confBytes, _ := yaml.Marshal(reqMap)
+	return confBytes
+}
@@ -0,0 +1,121 @@
+package io_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	bmock "github.com/benthosdev/benthos/v4/internal/manager/mock"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
+)
+
+func TestBackfillInputAPI(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	gMux := mux.NewRouter()
+
+	mgr := bmock.NewManager()
+	mgr.OnRegisterEndpoint = func(path string, h http.HandlerFunc) {
+		gMux.HandleFunc(path, h)
+	}
+
+	conf, err := testutil.InputFromYAML(`
+backfill:
+  input:
+    generate:
+      interval: 50ms
+      mapping: 'root.source = "live"'
+`)
+	require.NoError(t, err)
+
+	i, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/backfills", http.NoBody)
+	res := httptest.NewRecorder()
+	gMux.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, `{}`, res.Body.String())
+
+	req = httptest.NewRequest("POST", "/backfills/live", bytes.NewBufferString(`
+input:
+  generate:
+    mapping: 'root = {}'
+`))
+	res = httptest.NewRecorder()
+	gMux.ServeHTTP(res, req)
+
+	assert.Equal(t, http.StatusBadGateway, res.Code)
+
+	fooConf := `
+input:
+  generate:
+    interval: 1ns
+    count: 10
+    mapping: 'root.n = count("backfill_foo")'
+start_check: 'this.n > 3'
+end_check: 'this.n >= 6'
+`
+	req = httptest.NewRequest("POST", "/backfills/foo", bytes.NewBufferString(fooConf))
+	res = httptest.NewRecorder()
+	gMux.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code, res.Body.String())
+
+	var backfilled []string
+	var sawLive bool
+	for !sawLive || len(backfilled) < 3 {
+		select {
+		case ts, open := <-i.TransactionChan():
+			require.True(t, open)
+			p := ts.Payload.Get(0)
+			if id := p.MetaGetStr("backfill_id"); id != "" {
+				assert.Equal(t, "foo", id)
+				backfilled = append(backfilled, string(p.AsBytes()))
+			} else {
+				assert.Equal(t, `{"source":"live"}`, string(p.AsBytes()))
+				sawLive = true
+			}
+			require.NoError(t, ts.Ack(ctx, nil))
+		case <-ctx.Done():
+			t.Fatal(ctx.Err())
+		}
+	}
+	assert.Equal(t, []string{`{"n":4}`, `{"n":5}`, `{"n":6}`}, backfilled)
+
+	req = httptest.NewRequest(http.MethodGet, "/backfills/foo", http.NoBody)
+	res = httptest.NewRecorder()
+	gMux.ServeHTTP(res, req)
+
+	assert.Equal(t, 200, res.Code)
+	assert.Equal(t, `end_check: this.n >= 6
+input:
+    generate:
+        mapping: root.n = count("backfill_foo")
+        interval: 1ns
+        count: 10
+start_check: this.n > 3
+`, res.Body.String())
+
+	assert.Eventually(t, func() bool {
+		req = httptest.NewRequest(http.MethodGet, "/backfills/foo/uptime", http.NoBody)
+		res = httptest.NewRecorder()
+		gMux.ServeHTTP(res, req)
+
+		return res.Code == 200 && res.Body.String() == "stopped"
+	}, time.Second*5, time.Millisecond*10)
+
+	i.TriggerStopConsuming()
+	require.NoError(t, i.WaitForClose(ctx))
+}
@@ -7,11 +7,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"mime"
 	"mime/multipart"
 	"net"
 	"net/http"
-	"net/textproto"
 	"strconv"
 	"strings"
 	"sync"
@@ -24,6 +24,7 @@ import (
 	"github.com/Jeffail/shutdown"
 
 	"github.com/benthosdev/benthos/v4/internal/api"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/input"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
@@ -31,10 +32,13 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/component/ratelimit"
 	"github.com/benthosdev/benthos/v4/internal/httpserver"
 	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/internal/memguard"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/internal/old/util/throttle"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
 	"github.com/benthosdev/benthos/v4/internal/tracing"
 	"github.com/benthosdev/benthos/v4/internal/transaction"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -49,6 +53,7 @@ const (
 	hsiFieldRateLimit               = "rate_limit"
 	hsiFieldCertFile                = "cert_file"
 	hsiFieldKeyFile                 = "key_file"
+	hsiFieldSocketMode              = "socket_mode"
 	hsiFieldCORS                    = "cors"
 	hsiFieldCORSEnabled             = "enabled"
 	hsiFieldCORSAllowedOrigins      = "allowed_origins"
@@ -56,6 +61,13 @@ const (
 	hsiFieldResponseStatus          = "status"
 	hsiFieldResponseHeaders         = "headers"
 	hsiFieldResponseExtractMetadata = "metadata_headers"
+	hsiFieldResponseBatchFormat     = "batch_format"
+	hsiFieldResponseStream          = "stream"
+	hsiFieldPaths                   = "paths"
+	hsiFieldPathsPath               = "path"
+	hsiFieldPathsAllowedVerbs       = "allowed_verbs"
+	hsiFieldPathsMetadata           = "metadata"
+	hsiFieldPathsMapping            = "mapping"
 )
 
 type hsiConfig struct {
@@ -69,14 +81,28 @@ type hsiConfig struct {
 	RateLimit          string
 	CertFile           string
 	KeyFile            string
+	SocketMode         fs.FileMode
 	CORS               httpserver.CORSConfig
 	Response           hsiResponseConfig
+	Paths              []hsiPathConfig
+	Signature          *hsiSignatureConfig
+	EventStream        hsiEventStreamConfig
+	Idempotency        hsiIdempotencyConfig
+}
+
+type hsiPathConfig struct {
+	Path         string
+	AllowedVerbs map[string]struct{}
+	Metadata     map[string]string
+	Mapping      *mapping.Executor
 }
 
 type hsiResponseConfig struct {
 	Status          *service.InterpolatedString
 	Headers         map[string]*service.InterpolatedString
 	ExtractMetadata *service.MetadataFilter
+	BatchFormat     string
+	Stream          bool
 }
 
 func hsiConfigFromParsed(pConf *service.ParsedConfig) (conf hsiConfig, err error) {
@@ -121,12 +147,81 @@ func hsiConfigFromParsed(pConf *service.ParsedConfig) (conf hsiConfig, err error
 	if conf.KeyFile, err = pConf.FieldString(hsiFieldKeyFile); err != nil {
 		return
 	}
+	{
+		var socketModeStr string
+		if socketModeStr, err = pConf.FieldString(hsiFieldSocketMode); err != nil {
+			return
+		}
+		if conf.SocketMode, err = httpserver.ParseSocketMode(socketModeStr); err != nil {
+			return
+		}
+	}
 	if conf.CORS, err = corsConfigFromParsed(pConf.Namespace(hsiFieldCORS)); err != nil {
 		return
 	}
 	if conf.Response, err = hsiResponseConfigFromParsed(pConf.Namespace(hsiFieldResponse)); err != nil {
 		return
 	}
+	if conf.Paths, err = hsiPathsConfigFromParsed(pConf, conf.AllowedVerbs); err != nil {
+		return
+	}
+	if conf.Signature, err = hsiSignatureConfigFromParsed(pConf.Namespace(hsiFieldSignature)); err != nil {
+		return
+	}
+	if conf.EventStream, err = hsiEventStreamConfigFromParsed(pConf.Namespace(hsiFieldEventStream)); err != nil {
+		return
+	}
+	if conf.Idempotency, err = hsiIdempotencyConfigFromParsed(pConf.Namespace(hsiFieldIdempotency)); err != nil {
+		return
+	}
+	return
+}
+
+func hsiPathsConfigFromParsed(pConf *service.ParsedConfig, defaultVerbs map[string]struct{}) (paths []hsiPathConfig, err error) {
+	var pathConfs []*service.ParsedConfig
+	if pathConfs, err = pConf.FieldObjectList(hsiFieldPaths); err != nil {
+		return
+	}
+
+	for i, pc := range pathConfs {
+		var pathConf hsiPathConfig
+		if pathConf.Path, err = pc.FieldString(hsiFieldPathsPath); err != nil {
+			return
+		}
+		if pathConf.Path == "" {
+			err = fmt.Errorf("path %v must not be empty", i)
+			return
+		}
+
+		pathConf.AllowedVerbs = defaultVerbs
+		if pc.Contains(hsiFieldPathsAllowedVerbs) {
+			var verbsList []string
+			if verbsList, err = pc.FieldStringList(hsiFieldPathsAllowedVerbs); err != nil {
+				return
+			}
+			if len(verbsList) > 0 {
+				pathConf.AllowedVerbs = map[string]struct{}{}
+				for _, v := range verbsList {
+					pathConf.AllowedVerbs[v] = struct{}{}
+				}
+			}
+		}
+
+		if pathConf.Metadata, err = pc.FieldStringMap(hsiFieldPathsMetadata); err != nil {
+			return
+		}
+
+		if pc.Contains(hsiFieldPathsMapping) {
+			var blobl *bloblang.Executor
+			if blobl, err = pc.FieldBloblang(hsiFieldPathsMapping); err != nil {
+				return
+			}
+			pathConf.Mapping = blobl.XUnwrapper().(interface {
+				Unwrap() *mapping.Executor
+			}).Unwrap()
+		}
+		paths = append(paths, pathConf)
+	}
 	return
 }
 
@@ -150,6 +245,12 @@ func hsiResponseConfigFromParsed(pConf *service.ParsedConfig) (conf hsiResponseC
 	if conf.ExtractMetadata, err = pConf.FieldMetadataFilter(hsiFieldResponseExtractMetadata); err != nil {
 		return
 	}
+	if conf.BatchFormat, err = pConf.FieldString(hsiFieldResponseBatchFormat); err != nil {
+		return
+	}
+	if conf.Stream, err = pConf.FieldBool(hsiFieldResponseStream); err != nil {
+		return
+	}
 	return
 }
 
@@ -168,10 +269,24 @@ The field `+"`rate_limit`"+` allows you to specify an optional `+"[`rate_limit`
 
 When the rate limit is breached HTTP requests will have a 429 response returned with a Retry-After header. Websocket payloads will be dropped and an optional response payload will be sent as per `+"`ws_rate_limit_message`"+`.
 
+When the [memory guard](/docs/configuration/about#memory-guard) of the process is rejecting requests HTTP requests will have a 503 response returned with a Retry-After header.
+
 ### Responses
 
 It's possible to return a response for each message received using [synchronous responses](/docs/guides/sync_responses). When doing so you can customise headers with the `+"`sync_response` field `headers`"+`, which can also use [function interpolation](/docs/configuration/interpolation#bloblang-queries) in the value based on the response message contents.
 
+When a request results in a response of multiple messages they are sent as a multipart body by default, which can be changed to a JSON array or newline delimited JSON with the `+"`sync_response` field `batch_format`"+`. Responses can also be streamed to the client with chunked transfer encoding as they are produced by setting the `+"`sync_response` field `stream`"+` to `+"`true`"+`, which is useful when a request results in a large or slow series of results.
+
+### Webhook Signatures
+
+Requests sent by webhook providers can be verified with the `+"`webhook_signature`"+` field, which supports the signature schemes of GitHub, Stripe and Slack as well as a generic HMAC scheme with a configurable header and timestamp. Requests with a missing or invalid signature, or with a timestamp outside of the configured tolerance, are rejected with a 401 status code before they are consumed.
+
+### Idempotency
+
+Webhook producers often retry deliveries that they believe have failed, which can result in duplicate messages. When the `+"`idempotency.header`"+` field is set the response to each request containing that header is stored within the `+"`idempotency.cache`"+`, keyed by the header value, once the request has been successfully processed. Requests that arrive with the same key within the `+"`idempotency.ttl`"+` are responded to with the stored response, along with a header `+"`Idempotent-Replayed: true`"+`, without being consumed again.
+
+Only responses with a 2XX status code are stored, and therefore requests that fail are processed again when they are retried. Requests with a key that is already being processed are rejected with a 409 status code.
+
 ### Endpoints
 
 The following fields specify endpoints that are registered for sending messages, and support path parameters of the form `+"`/{foo}`"+`, which are added to ingested messages as metadata. A path ending in `+"`/`"+` will match against all extensions of that path:
@@ -182,6 +297,10 @@ This endpoint expects POST requests where the entire request body is consumed as
 
 If the request contains a multipart `+"`content-type`"+` header as per [rfc1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html) then the multiple parts are consumed as a batch of messages, where each body part is a message of the batch.
 
+#### `+"`paths`"+`
+
+Registers further endpoints that behave the same as `+"`path`"+`, which allows a single listener to receive messages from multiple endpoints. Each endpoint can have its own allowed verbs, static metadata that is added to its messages, and a [Bloblang mapping](/docs/guides/bloblang/about) that is executed on each message received from it. Messages that are deleted by a mapping are acknowledged without being consumed, and requests where the mapping fails are rejected with a 400 status code.
+
 #### `+"`ws_path` (defaults to `/post/ws`)"+`
 
 Creates a websocket connection, where payloads received on the socket are passed through the pipeline as a batch of one message.
@@ -192,6 +311,15 @@ You may specify an optional `+"`ws_welcome_message`"+`, which is a static payloa
 
 It's also possible to specify a `+"`ws_rate_limit_message`"+`, which is a static payload to be sent to clients that have triggered the servers rate limit.
 
+#### `+"`event_stream.path`"+`
+
+Streams the [synchronous responses](/docs/guides/sync_responses) of all requests received by this input to clients as events, where each message of a response is an event with an incrementing ID. Only GET requests are accepted, and the `+"`event_stream.mode`"+` selects how events are delivered:
+
+- `+"`sse`"+`: Events are written as [Server-Sent Events](https://html.spec.whatwg.org/multipage/server-sent-events.html) with the content type `+"`text/event-stream`"+` for as long as the client remains connected.
+- `+"`long_poll`"+`: Requests are held open until events are available, which are then returned as a JSON array of objects of the form `+"`{\"id\":1,\"data\":\"...\"}`"+` along with a `+"`Last-Event-ID`"+` header. When no events arrive within the `+"`event_stream.poll_timeout`"+` a 204 status code is returned.
+
+Clients resume from where they left off by providing the ID of the last event they received with a `+"`Last-Event-ID`"+` header, which browsers set automatically when an `+"`EventSource`"+` reconnects, or with a `+"`last_event_id`"+` query parameter. Events that followed that ID are then delivered immediately, as long as they are still retained within the `+"`event_stream.buffer_size`"+`. Clients that don't provide an ID only receive events published after they connect.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -199,6 +327,7 @@ This input adds the following metadata fields to each message:
 `+"``` text"+`
 - http_server_user_agent
 - http_server_request_path
+- http_server_route
 - http_server_verb
 - http_server_remote_ip
 - All headers (only first values are taken)
@@ -217,7 +346,8 @@ If HTTPS is enabled, the following fields are added as well:
 You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
 		Fields(
 			service.NewStringField(hsiFieldAddress).
-				Description("An alternative address to host from. If left empty the service wide address is used.").
+				Description("An alternative address to host from. If left empty the service wide address is used. "+httpserver.ListenAddressDescription).
+				Examples("0.0.0.0:4196", "unix:///run/benthos/http.sock", "systemd").
 				Default(""),
 			service.NewStringField(hsiFieldPath).
 				Description("The endpoint path to listen for POST requests.").
@@ -251,6 +381,11 @@ You can access these metadata fields using [function interpolation](/docs/config
 				Description("Enable TLS by specifying a certificate and key file. Only valid with a custom `address`.").
 				Advanced().
 				Default(""),
+			service.NewStringField(hsiFieldSocketMode).
+				Description("The octal file mode to apply when the custom `address` is a unix domain socket. When empty the mode is determined by the umask of the process.").
+				Advanced().
+				Version("4.28.0").
+				Default("0660"),
 			service.NewInternalField(corsSpec),
 			service.NewObjectField(hsiFieldResponse,
 				service.NewInterpolatedStringField(hsiFieldResponseStatus).
@@ -264,9 +399,41 @@ You can access these metadata fields using [function interpolation](/docs/config
 					}),
 				service.NewMetadataFilterField(hsiFieldResponseExtractMetadata).
 					Description("Specify criteria for which metadata values are added to the response as headers."),
+				service.NewStringAnnotatedEnumField(hsiFieldResponseBatchFormat, map[string]string{
+					"multipart":  "Responses of multiple messages are sent as a multipart body with a part per message, and responses of a single message are sent as the raw message.",
+					"json_array": "Responses are sent as a JSON array with an element per message, where messages that are not valid JSON are added as strings.",
+					"ndjson":     "Responses are sent as newline delimited JSON, with a line per message.",
+				}).
+					Description("The format of responses that consist of multiple messages.").
+					Version("4.28.0").
+					Default("multipart"),
+				service.NewBoolField(hsiFieldResponseStream).
+					Description("Whether to stream responses with chunked transfer encoding as they are produced, rather than once the request has been fully processed. The status code and headers are resolved from the first response message, and when processing fails after a response has begun the connection is terminated.").
+					Version("4.28.0").
+					Default(false),
 			).
 				Description("Customise messages returned via [synchronous responses](/docs/guides/sync_responses).").
 				Advanced(),
+			service.NewObjectListField(hsiFieldPaths,
+				service.NewStringField(hsiFieldPathsPath).
+					Description("The endpoint path to listen for requests, which is added to messages within the metadata field `http_server_route`.").
+					Example("/orders/{id}"),
+				service.NewStringListField(hsiFieldPathsAllowedVerbs).
+					Description("An array of verbs that are allowed for the endpoint. If omitted the top level `allowed_verbs` are used.").
+					Optional(),
+				service.NewStringMapField(hsiFieldPathsMetadata).
+					Description("Static metadata fields to add to messages received from the endpoint.").
+					Default(map[string]any{}),
+				service.NewBloblangField(hsiFieldPathsMapping).
+					Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) to execute on each message received from the endpoint.").
+					Optional(),
+			).
+				Description("A list of further endpoints to listen for requests, each with their own verbs, metadata and mapping.").
+				Version("4.28.0").
+				Default([]any{}),
+			hsiSignatureFieldSpec(),
+			hsiEventStreamFieldSpec(),
+			hsiIdempotencyFieldSpec(),
 		).
 		Example(
 			"Path Switching",
@@ -319,6 +486,40 @@ input:
 
     - sync_response: {}
     - mapping: 'root = deleted()'
+`).
+		Example(
+			"Multiple Endpoints",
+			"This example shows an `http_server` input that receives orders and refunds on separate endpoints of the same listener, where each message is tagged with its type and refunds are normalised by a mapping:", `
+input:
+  http_server:
+    path: ""
+    ws_path: ""
+    paths:
+      - path: /orders
+        metadata:
+          event_type: order
+      - path: /refunds/{order_id}
+        allowed_verbs: [ POST, PUT ]
+        metadata:
+          event_type: refund
+        mapping: |
+          root = this
+          root.order_id = @order_id
+          root.amount = this.amount.number()
+`).
+		Example(
+			"GitHub Webhooks",
+			"This example shows an `http_server` input that receives GitHub webhook deliveries, where deliveries without a valid signature are rejected, and the event type of each delivery is taken from its headers:", `
+input:
+  http_server:
+    path: /github
+    webhook_signature:
+      scheme: github
+      secret: ${GITHUB_WEBHOOK_SECRET}
+  processors:
+    - mapping: |
+        root = this
+        root.event = @X-Github-Event
 `)
 }
 
@@ -359,6 +560,10 @@ type httpServerInput struct {
 
 	handlerWG    sync.WaitGroup
 	transactions chan message.Transaction
+	events       *hsiEventLog
+
+	idempotentMut      sync.Mutex
+	idempotentInFlight map[string]struct{}
 
 	shutSig *shutdown.Signaller
 
@@ -374,7 +579,7 @@ func newHTTPServerInput(conf hsiConfig, mgr bundle.NewManagement) (input.Streame
 	var err error
 	if conf.Address != "" {
 		gMux = mux.NewRouter()
-		server = &http.Server{Addr: conf.Address}
+		server = &http.Server{Addr: conf.Address, TLSConfig: btls.PolicyConfig()}
 		if server.Handler, err = conf.CORS.WrapHandler(gMux); err != nil {
 			return nil, fmt.Errorf("bad CORS configuration: %w", err)
 		}
@@ -390,6 +595,8 @@ func newHTTPServerInput(conf hsiConfig, mgr bundle.NewManagement) (input.Streame
 		server:       server,
 		transactions: make(chan message.Transaction),
 
+		idempotentInFlight: map[string]struct{}{},
+
 		mLatency:  mgr.Metrics().GetTimer("input_latency_ns"),
 		mWSRcvd:   mRcvd,
 		mPostRcvd: mRcvd,
@@ -416,12 +623,40 @@ func newHTTPServerInput(conf hsiConfig, mgr bundle.NewManagement) (input.Streame
 			)
 		}
 	}
+	if h.conf.EventStream.Path != "" {
+		// Events are streamed to clients as they're published, and therefore
+		// the handler isn't wrapped with gzip compression.
+		h.events = newHSIEventLog(h.conf.EventStream.BufferSize)
+		if gMux != nil {
+			api.GetMuxRoute(gMux, h.conf.EventStream.Path).HandlerFunc(h.eventStreamHandler)
+		} else {
+			mgr.RegisterEndpoint(
+				h.conf.EventStream.Path, "Stream sync responses from Benthos.", h.eventStreamHandler,
+			)
+		}
+	}
+	for _, route := range h.conf.Paths {
+		route := route
+		routeHdlr := gzipHandler(func(w http.ResponseWriter, r *http.Request) {
+			h.routeHandler(w, r, route)
+		})
+		if gMux != nil {
+			api.GetMuxRoute(gMux, route.Path).Handler(routeHdlr)
+		} else {
+			mgr.RegisterEndpoint(route.Path, "Post a message into Benthos.", routeHdlr)
+		}
+	}
 
 	if h.conf.RateLimit != "" {
 		if !h.mgr.ProbeRateLimit(h.conf.RateLimit) {
 			return nil, fmt.Errorf("rate limit resource '%v' was not found", h.conf.RateLimit)
 		}
 	}
+	if h.conf.Idempotency.Header != "" {
+		if !h.mgr.ProbeCache(h.conf.Idempotency.Cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", h.conf.Idempotency.Cache)
+		}
+	}
 
 	go h.loop()
 	return &h, nil
@@ -523,6 +758,39 @@ func (h *httpServerInput) extractMessageFromRequest(r *http.Request) (message.Ba
 }
 
 func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
+	h.routeHandler(w, r, hsiPathConfig{
+		Path:         h.conf.Path,
+		AllowedVerbs: h.conf.AllowedVerbs,
+	})
+}
+
+// applyRoute tags messages with the metadata of the route they were received
+// from and executes the mapping of the route, returning the remaining messages.
+func (h *httpServerInput) applyRoute(msg message.Batch, route hsiPathConfig) (message.Batch, error) {
+	for _, p := range msg {
+		p.MetaSetMut("http_server_route", route.Path)
+		for k, v := range route.Metadata {
+			p.MetaSetMut(k, v)
+		}
+	}
+	if route.Mapping == nil {
+		return msg, nil
+	}
+
+	newMsg := make(message.Batch, 0, len(msg))
+	for i := range msg {
+		p, err := route.Mapping.MapPart(i, msg)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			newMsg = append(newMsg, p)
+		}
+	}
+	return newMsg, nil
+}
+
+func (h *httpServerInput) routeHandler(w http.ResponseWriter, r *http.Request, route hsiPathConfig) {
 	if h.shutSig.IsSoftStopSignalled() {
 		http.Error(w, "Server closing", http.StatusServiceUnavailable)
 		return
@@ -532,11 +800,17 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 	defer h.handlerWG.Done()
 	defer r.Body.Close()
 
-	if _, exists := h.conf.AllowedVerbs[r.Method]; !exists {
+	if _, exists := route.AllowedVerbs[r.Method]; !exists {
 		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
 		return
 	}
 
+	if memguard.Active(memguard.PolicyRejectRequests) {
+		w.Header().Add("Retry-After", "1")
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
 	if h.conf.RateLimit != "" {
 		var tUntil time.Duration
 		var err error
@@ -558,6 +832,30 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if h.conf.Signature != nil {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			h.log.Warn("Request read failed: %v\n", err)
+			return
+		}
+		if err := h.conf.Signature.Verify(r.Header, body); err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			h.log.Warn("Request signature verification failed: %v\n", err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	if h.conf.Idempotency.Header != "" {
+		var done func()
+		var proceed bool
+		if w, done, proceed = h.idempotentRequest(w, r); !proceed {
+			return
+		}
+		defer done()
+	}
+
 	msg, err := h.extractMessageFromRequest(r)
 	if err != nil {
 		http.Error(w, "Bad request", http.StatusBadRequest)
@@ -566,13 +864,29 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tracing.FinishSpans(msg)
 
+	if msg, err = h.applyRoute(msg, route); err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		h.log.Warn("Mapping of request to '%v' failed: %v\n", route.Path, err)
+		return
+	}
+	if len(msg) == 0 {
+		return
+	}
+
 	startedAt := time.Now()
 
-	store := transaction.NewResultStore()
+	var queue *hsiResponseQueue
+	var store transaction.ResultStore
+	if h.conf.Response.Stream {
+		queue = newHSIResponseQueue()
+		store = transaction.NewResultStoreWithCallback(queue.Push)
+	} else {
+		store = transaction.NewResultStore()
+	}
 	transaction.AddResultStore(msg, store)
 
 	h.mPostRcvd.Incr(int64(msg.Len()))
-	h.log.Trace("Consumed %v messages from POST to '%v'.\n", msg.Len(), h.conf.Path)
+	h.log.Trace("Consumed %v messages from POST to '%v'.\n", msg.Len(), route.Path)
 
 	resChan := make(chan error, 1)
 	select {
@@ -588,6 +902,11 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if queue != nil {
+		h.streamSyncResponse(w, r, resChan, queue, startedAt)
+		return
+	}
+
 	select {
 	case res, open := <-resChan:
 		if !open {
@@ -615,98 +934,10 @@ func (h *httpServerInput) postHandler(w http.ResponseWriter, r *http.Request) {
 		for i := 0; i < len(resMsg); i++ {
 			svcBatch = append(svcBatch, service.NewInternalMessage(resMsg[i]))
 		}
+		h.publishEvents(resMsg)
 	}
 	if len(svcBatch) > 0 {
-		for k, v := range h.conf.Response.Headers {
-			headerStr, err := svcBatch.TryInterpolatedString(0, v)
-			if err != nil {
-				h.log.Error("Interpolation of response header %v error: %v", k, err)
-				continue
-			}
-			w.Header().Set(k, headerStr)
-		}
-
-		statusCode := 200
-		statusCodeStr, err := svcBatch.TryInterpolatedString(0, h.conf.Response.Status)
-		if err != nil {
-			h.log.Error("Interpolation of response status code error: %v", err)
-			w.WriteHeader(http.StatusBadGateway)
-			return
-		}
-		if statusCodeStr != "200" {
-			if statusCode, err = strconv.Atoi(statusCodeStr); err != nil {
-				h.log.Error("Failed to parse sync response status code expression: %v\n", err)
-				w.WriteHeader(http.StatusBadGateway)
-				return
-			}
-		}
-
-		if plen := len(svcBatch); plen == 1 {
-			part := svcBatch[0]
-			_ = h.conf.Response.ExtractMetadata.Walk(part, func(k, v string) error {
-				w.Header().Set(k, v)
-				return nil
-			})
-			payload, err := part.AsBytes()
-			if err != nil {
-				h.log.Error("Failed to extract message bytes for sync response: %v\n", err)
-				w.WriteHeader(http.StatusBadGateway)
-				return
-			}
-			if w.Header().Get("Content-Type") == "" {
-				w.Header().Set("Content-Type", http.DetectContentType(payload))
-			}
-			w.WriteHeader(statusCode)
-			_, _ = w.Write(payload)
-		} else if plen > 1 {
-			customContentType, customContentTypeExists := h.conf.Response.Headers["content-type"]
-
-			var buf bytes.Buffer
-			writer := multipart.NewWriter(&buf)
-
-			var merr error
-			for i := 0; i < plen && merr == nil; i++ {
-				part := svcBatch[i]
-				_ = h.conf.Response.ExtractMetadata.Walk(part, func(k, v string) error {
-					w.Header().Set(k, v)
-					return nil
-				})
-				payload, err := part.AsBytes()
-				if err != nil {
-					h.log.Error("Failed to extract message bytes for sync response: %v\n", err)
-					continue
-				}
-
-				mimeHeader := textproto.MIMEHeader{}
-				if customContentTypeExists {
-					contentTypeStr, err := svcBatch.TryInterpolatedString(i, customContentType)
-					if err != nil {
-						h.log.Error("Interpolation of content-type header error: %v", err)
-						mimeHeader.Set("Content-Type", http.DetectContentType(payload))
-					} else {
-						mimeHeader.Set("Content-Type", contentTypeStr)
-					}
-				} else {
-					mimeHeader.Set("Content-Type", http.DetectContentType(payload))
-				}
-
-				var partWriter io.Writer
-				if partWriter, merr = writer.CreatePart(mimeHeader); merr == nil {
-					_, merr = io.Copy(partWriter, bytes.NewReader(payload))
-				}
-			}
-
-			merr = writer.Close()
-			if merr == nil {
-				w.Header().Del("Content-Type")
-				w.Header().Add("Content-Type", writer.FormDataContentType())
-				w.WriteHeader(statusCode)
-				_, _ = buf.WriteTo(w)
-			} else {
-				h.log.Error("Failed to return sync response: %v\n", merr)
-				w.WriteHeader(http.StatusBadGateway)
-			}
-		}
+		h.writeSyncResponse(w, svcBatch)
 	}
 }
 
@@ -823,6 +1054,7 @@ func (h *httpServerInput) wsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		for _, responseMsg := range store.Get() {
+			h.publishEvents(responseMsg)
 			if err := responseMsg.Iter(func(i int, part *message.Part) error {
 				return ws.WriteMessage(websocket.TextMessage, part.AsBytes())
 			}); err != nil {
@@ -834,6 +1066,19 @@ func (h *httpServerInput) wsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// publishEvents broadcasts the messages of a sync response to the clients of
+// the event stream endpoint, if enabled.
+func (h *httpServerInput) publishEvents(msg message.Batch) {
+	if h.events == nil {
+		return
+	}
+	data := make([][]byte, len(msg))
+	for i, part := range msg {
+		data[i] = part.AsBytes()
+	}
+	h.events.Publish(data...)
+}
+
 //------------------------------------------------------------------------------
 
 func (h *httpServerInput) loop() {
@@ -865,6 +1110,11 @@ func (h *httpServerInput) loop() {
 						http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
 					})
 				}
+				if h.conf.EventStream.Path != "" {
+					h.mgr.RegisterEndpoint(h.conf.EventStream.Path, "Endpoint disabled.", func(w http.ResponseWriter, r *http.Request) {
+						http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+					})
+				}
 			}()
 		}
 
@@ -876,13 +1126,18 @@ func (h *httpServerInput) loop() {
 
 	if h.server != nil {
 		go func() {
+			ln, err := httpserver.Listen(h.conf.Address, h.conf.SocketMode)
+			if err != nil {
+				h.log.Error("Server error: %v\n", err)
+				return
+			}
 			if h.conf.KeyFile != "" || h.conf.CertFile != "" {
 				h.log.Info(
 					"Receiving HTTPS messages at: https://%s\n",
 					h.conf.Address+h.conf.Path,
 				)
-				if err := h.server.ListenAndServeTLS(
-					h.conf.CertFile, h.conf.KeyFile,
+				if err := h.server.ServeTLS(
+					ln, h.conf.CertFile, h.conf.KeyFile,
 				); err != http.ErrServerClosed {
 					h.log.Error("Server error: %v\n", err)
 				}
@@ -891,7 +1146,7 @@ func (h *httpServerInput) loop() {
 					"Receiving HTTP messages at: http://%s\n",
 					h.conf.Address+h.conf.Path,
 				)
-				if err := h.server.ListenAndServe(); err != http.ErrServerClosed {
+				if err := h.server.Serve(ln); err != http.ErrServerClosed {
 					h.log.Error("Server error: %v\n", err)
 				}
 			}
@@ -945,6 +1200,15 @@ func (w gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+func (w gzipResponseWriter) Flush() {
+	if f, ok := w.Writer.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func gzipHandler(fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
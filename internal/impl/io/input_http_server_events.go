@@ -0,0 +1,299 @@
+package io
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hsiFieldEventStream            = "event_stream"
+	hsiFieldEventStreamPath        = "path"
+	hsiFieldEventStreamMode        = "mode"
+	hsiFieldEventStreamBufferSize  = "buffer_size"
+	hsiFieldEventStreamHeartbeat   = "heartbeat"
+	hsiFieldEventStreamPollTimeout = "poll_timeout"
+)
+
+func hsiEventStreamFieldSpec() *service.ConfigField {
+	return service.NewObjectField(hsiFieldEventStream,
+		service.NewStringField(hsiFieldEventStreamPath).
+			Description("The endpoint path at which sync responses are streamed to clients. An empty string disables the endpoint.").
+			Example("/events").
+			Default(""),
+		service.NewStringAnnotatedEnumField(hsiFieldEventStreamMode, map[string]string{
+			"sse":       "Clients receive events as [Server-Sent Events](https://html.spec.whatwg.org/multipage/server-sent-events.html) over a long lived `text/event-stream` response.",
+			"long_poll": "Each request is held open until at least one event is available, or the `poll_timeout` is reached, and is then responded to with a JSON array of events.",
+		}).
+			Description("The mode in which events are delivered to clients.").
+			Default("sse"),
+		service.NewIntField(hsiFieldEventStreamBufferSize).
+			Description("The number of most recent events to retain in memory, which clients are able to resume from after reconnecting.").
+			Default(1000),
+		service.NewDurationField(hsiFieldEventStreamHeartbeat).
+			Description("The period of time after which a comment is sent to `sse` clients in order to keep idle connections open. Set to `0s` in order to disable heartbeats.").
+			Default("15s"),
+		service.NewDurationField(hsiFieldEventStreamPollTimeout).
+			Description("The maximum period of time to hold open a `long_poll` request before responding with a 204 status code when no events are available.").
+			Default("30s"),
+	).
+		Description("Expose an endpoint where the sync responses of all requests received by this input are broadcast to clients as a stream of events, each with an incrementing ID.").
+		Advanced().
+		Version("4.28.0")
+}
+
+type hsiEventStreamConfig struct {
+	Path        string
+	Mode        string
+	BufferSize  int
+	Heartbeat   time.Duration
+	PollTimeout time.Duration
+}
+
+func hsiEventStreamConfigFromParsed(pConf *service.ParsedConfig) (conf hsiEventStreamConfig, err error) {
+	if conf.Path, err = pConf.FieldString(hsiFieldEventStreamPath); err != nil {
+		return
+	}
+	if conf.Mode, err = pConf.FieldString(hsiFieldEventStreamMode); err != nil {
+		return
+	}
+	if conf.BufferSize, err = pConf.FieldInt(hsiFieldEventStreamBufferSize); err != nil {
+		return
+	}
+	if conf.BufferSize < 1 {
+		err = fmt.Errorf("%v must be greater than zero", hsiFieldEventStreamBufferSize)
+		return
+	}
+	if conf.Heartbeat, err = pConf.FieldDuration(hsiFieldEventStreamHeartbeat); err != nil {
+		return
+	}
+	if conf.PollTimeout, err = pConf.FieldDuration(hsiFieldEventStreamPollTimeout); err != nil {
+		return
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+type hsiEvent struct {
+	ID   uint64
+	Data []byte
+}
+
+// hsiEventLog retains a bounded number of the most recent events, and allows
+// subscribers to wait for events that follow a given ID.
+type hsiEventLog struct {
+	mut    sync.Mutex
+	size   int
+	lastID uint64
+	events []hsiEvent
+	notify chan struct{}
+}
+
+func newHSIEventLog(size int) *hsiEventLog {
+	return &hsiEventLog{
+		size:   size,
+		notify: make(chan struct{}),
+	}
+}
+
+// Publish adds events to the log and wakes all waiting subscribers.
+func (l *hsiEventLog) Publish(data ...[]byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	for _, d := range data {
+		l.lastID++
+		l.events = append(l.events, hsiEvent{ID: l.lastID, Data: d})
+	}
+	if len(l.events) > l.size {
+		l.events = l.events[len(l.events)-l.size:]
+	}
+
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// LastID returns the ID of the most recently published event.
+func (l *hsiEventLog) LastID() uint64 {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.lastID
+}
+
+// Since returns all retained events with an ID greater than lastID, along
+// with a channel that is closed when further events are published.
+func (l *hsiEventLog) Since(lastID uint64) ([]hsiEvent, <-chan struct{}) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if len(l.events) == 0 || lastID >= l.lastID {
+		return nil, l.notify
+	}
+
+	start := 0
+	if firstID := l.events[0].ID; lastID >= firstID {
+		start = int(lastID - firstID + 1)
+	}
+	events := make([]hsiEvent, len(l.events)-start)
+	copy(events, l.events[start:])
+	return events, l.notify
+}
+
+//------------------------------------------------------------------------------
+
+func writeSSEEvent(w io.Writer, e hsiEvent) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "id: %v\n", e.ID)
+	for _, line := range bytes.Split(e.Data, []byte("\n")) {
+		buf.WriteString("data: ")
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+func (h *httpServerInput) eventStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if h.shutSig.IsSoftStopSignalled() {
+		http.Error(w, "Server closing", http.StatusServiceUnavailable)
+		return
+	}
+
+	h.handlerWG.Add(1)
+	defer h.handlerWG.Done()
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "Incorrect method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Clients resume from an event ID either with the header used by the
+	// EventSource API, or with a query parameter when headers can't be set.
+	lastIDStr := r.Header.Get("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = r.URL.Query().Get("last_event_id")
+	}
+
+	var lastID uint64
+	if lastIDStr == "" {
+		lastID = h.events.LastID()
+	} else {
+		var err error
+		if lastID, err = strconv.ParseUint(lastIDStr, 10, 64); err != nil {
+			http.Error(w, "Bad request", http.StatusBadRequest)
+			h.log.Warn("Invalid last event ID: %v\n", err)
+			return
+		}
+	}
+
+	if h.conf.EventStream.Mode == "long_poll" {
+		h.longPollEvents(w, r, lastID)
+		return
+	}
+	h.streamEvents(w, r, lastID)
+}
+
+func (h *httpServerInput) streamEvents(w http.ResponseWriter, r *http.Request, lastID uint64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var heartbeatChan <-chan time.Time
+	if h.conf.EventStream.Heartbeat > 0 {
+		ticker := time.NewTicker(h.conf.EventStream.Heartbeat)
+		defer ticker.Stop()
+		heartbeatChan = ticker.C
+	}
+
+	for {
+		events, notify := h.events.Since(lastID)
+		for _, e := range events {
+			if err := writeSSEEvent(w, e); err != nil {
+				h.log.Debug("Failed to write event: %v\n", err)
+				return
+			}
+			lastID = e.ID
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+		}
+
+		select {
+		case <-notify:
+		case <-heartbeatChan:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		case <-h.shutSig.SoftStopChan():
+			return
+		}
+	}
+}
+
+func (h *httpServerInput) longPollEvents(w http.ResponseWriter, r *http.Request, lastID uint64) {
+	timeout := time.NewTimer(h.conf.EventStream.PollTimeout)
+	defer timeout.Stop()
+
+	for {
+		events, notify := h.events.Since(lastID)
+		if len(events) > 0 {
+			type jsonEvent struct {
+				ID   uint64 `json:"id"`
+				Data string `json:"data"`
+			}
+			jEvents := make([]jsonEvent, len(events))
+			for i, e := range events {
+				jEvents[i] = jsonEvent{ID: e.ID, Data: string(e.Data)}
+			}
+			body, err := json.Marshal(jEvents)
+			if err != nil {
+				http.Error(w, "Server error", http.StatusInternalServerError)
+				h.log.Error("Failed to marshal events: %v\n", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("Last-Event-ID", strconv.FormatUint(events[len(events)-1].ID, 10))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+			return
+		}
+
+		select {
+		case <-notify:
+		case <-timeout.C:
+			w.Header().Set("Last-Event-ID", strconv.FormatUint(lastID, 10))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case <-r.Context().Done():
+			return
+		case <-h.shutSig.SoftStopChan():
+			http.Error(w, "Server closing", http.StatusServiceUnavailable)
+			return
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package io
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hsiFieldIdempotency       = "idempotency"
+	hsiFieldIdempotencyHeader = "header"
+	hsiFieldIdempotencyCache  = "cache"
+	hsiFieldIdempotencyTTL    = "ttl"
+)
+
+func hsiIdempotencyFieldSpec() *service.ConfigField {
+	return service.NewObjectField(hsiFieldIdempotency,
+		service.NewStringField(hsiFieldIdempotencyHeader).
+			Description("The request header that contains the idempotency key of a request. An empty string disables idempotency handling.").
+			Example("Idempotency-Key").
+			Example("X-GitHub-Delivery").
+			Default(""),
+		service.NewStringField(hsiFieldIdempotencyCache).
+			Description("The name of a [cache resource](/docs/components/caches/about) in which the responses of requests are stored.").
+			Default(""),
+		service.NewDurationField(hsiFieldIdempotencyTTL).
+			Description("The period of time for which a stored response is replayed to requests with the same idempotency key. This field is ignored by caches that don't support per-key TTLs.").
+			Default("24h"),
+	).
+		Description("Deduplicate requests by an idempotency key, where the response of a successfully processed request is stored in a cache and replayed to later requests with the same key instead of consuming them again.").
+		Advanced().
+		Version("4.28.0")
+}
+
+type hsiIdempotencyConfig struct {
+	Header string
+	Cache  string
+	TTL    time.Duration
+}
+
+func hsiIdempotencyConfigFromParsed(pConf *service.ParsedConfig) (conf hsiIdempotencyConfig, err error) {
+	if conf.Header, err = pConf.FieldString(hsiFieldIdempotencyHeader); err != nil {
+		return
+	}
+	if conf.Cache, err = pConf.FieldString(hsiFieldIdempotencyCache); err != nil {
+		return
+	}
+	if conf.TTL, err = pConf.FieldDuration(hsiFieldIdempotencyTTL); err != nil {
+		return
+	}
+	if conf.Header != "" && conf.Cache == "" {
+		err = fmt.Errorf("a %v must be specified when an idempotency %v is set", hsiFieldIdempotencyCache, hsiFieldIdempotencyHeader)
+	}
+	return
+}
+
+//------------------------------------------------------------------------------
+
+// hsiStoredResponse is the outcome of a request that is stored against its
+// idempotency key.
+type hsiStoredResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+}
+
+// hsiResponseRecorder captures a response as it is written to the underlying
+// writer so that it can be stored.
+type hsiResponseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *hsiResponseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *hsiResponseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	_, _ = r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap allows a response controller to access the underlying writer, which
+// is required in order to flush streamed responses.
+func (r *hsiResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+func (r *hsiResponseRecorder) stored() hsiStoredResponse {
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	headers := r.Header().Clone()
+	headers.Del("Content-Length")
+	return hsiStoredResponse{
+		Status:  status,
+		Headers: headers,
+		Body:    r.body.Bytes(),
+	}
+}
+
+// lookupIdempotent returns the stored response of an idempotency key, or nil
+// if the key has not been stored.
+func (h *httpServerInput) lookupIdempotent(ctx context.Context, key string) (*hsiStoredResponse, error) {
+	var resBytes []byte
+	var err error
+	if cerr := h.mgr.AccessCache(ctx, h.conf.Idempotency.Cache, func(c cache.V1) {
+		resBytes, err = c.Get(ctx, key)
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if errors.Is(err, component.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var res hsiStoredResponse
+	if err := json.Unmarshal(resBytes, &res); err != nil {
+		return nil, fmt.Errorf("failed to parse stored response: %w", err)
+	}
+	return &res, nil
+}
+
+func (h *httpServerInput) storeIdempotent(ctx context.Context, key string, res hsiStoredResponse) error {
+	resBytes, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	ttl := h.conf.Idempotency.TTL
+	if cerr := h.mgr.AccessCache(ctx, h.conf.Idempotency.Cache, func(c cache.V1) {
+		err = c.Set(ctx, key, resBytes, &ttl)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// idempotentRequest handles the idempotency key of a request, if present. When
+// a response has already been stored for the key it is replayed and false is
+// returned. Otherwise a writer that records the response is returned along
+// with a func that must be deferred until the response has been written.
+func (h *httpServerInput) idempotentRequest(w http.ResponseWriter, r *http.Request) (http.ResponseWriter, func(), bool) {
+	key := r.Header.Get(h.conf.Idempotency.Header)
+	if key == "" {
+		return w, func() {}, true
+	}
+
+	h.idempotentMut.Lock()
+	if _, exists := h.idempotentInFlight[key]; exists {
+		h.idempotentMut.Unlock()
+		w.Header().Add("Retry-After", "1")
+		http.Error(w, "Request with the same idempotency key is in progress", http.StatusConflict)
+		return nil, nil, false
+	}
+	h.idempotentInFlight[key] = struct{}{}
+	h.idempotentMut.Unlock()
+
+	release := func() {
+		h.idempotentMut.Lock()
+		delete(h.idempotentInFlight, key)
+		h.idempotentMut.Unlock()
+	}
+
+	stored, err := h.lookupIdempotent(r.Context(), key)
+	if err != nil {
+		release()
+		http.Error(w, "Server error", http.StatusBadGateway)
+		h.log.Warn("Failed to access idempotency cache: %v\n", err)
+		return nil, nil, false
+	}
+	if stored != nil {
+		release()
+		for k, v := range stored.Headers {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		w.WriteHeader(stored.Status)
+		_, _ = w.Write(stored.Body)
+		return nil, nil, false
+	}
+
+	rec := &hsiResponseRecorder{ResponseWriter: w}
+	return rec, func() {
+		defer release()
+
+		// A handler that panics, such as when a streamed response is aborted,
+		// hasn't written a complete response.
+		if p := recover(); p != nil {
+			panic(p)
+		}
+
+		// Only successful outcomes are stored, as failed requests are expected
+		// to be retried and processed again.
+		res := rec.stored()
+		if res.Status < 200 || res.Status > 299 {
+			return
+		}
+		// The request context may already be cancelled by this point.
+		ctx, done := context.WithTimeout(context.Background(), h.conf.Timeout)
+		defer done()
+		if err := h.storeIdempotent(ctx, key, res); err != nil {
+			h.log.Error("Failed to store response for idempotency key: %v\n", err)
+		}
+	}, true
+}
@@ -0,0 +1,327 @@
+package io
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// hsiBatchEncoder writes the messages of sync responses to a response body in
+// the configured batch format.
+type hsiBatchEncoder struct {
+	format string
+	w      io.Writer
+	mw     *multipart.Writer
+	count  int
+}
+
+func newHSIBatchEncoder(format string, w io.Writer) *hsiBatchEncoder {
+	e := &hsiBatchEncoder{format: format, w: w}
+	if format == "multipart" {
+		e.mw = multipart.NewWriter(w)
+	}
+	return e
+}
+
+// ContentType returns the content type of the encoded body.
+func (e *hsiBatchEncoder) ContentType() string {
+	switch e.format {
+	case "json_array":
+		return "application/json"
+	case "ndjson":
+		return "application/x-ndjson"
+	}
+	return e.mw.FormDataContentType()
+}
+
+// Write encodes a message, where the content type is only used by the
+// multipart format.
+func (e *hsiBatchEncoder) Write(contentType string, payload []byte) (err error) {
+	defer func() {
+		e.count++
+	}()
+
+	switch e.format {
+	case "json_array":
+		delim := ","
+		if e.count == 0 {
+			delim = "["
+		}
+		if _, err = io.WriteString(e.w, delim); err != nil {
+			return
+		}
+		if !json.Valid(payload) {
+			if payload, err = json.Marshal(string(payload)); err != nil {
+				return
+			}
+		}
+		_, err = e.w.Write(payload)
+	case "ndjson":
+		if _, err = e.w.Write(payload); err == nil {
+			_, err = io.WriteString(e.w, "\n")
+		}
+	default:
+		mimeHeader := textproto.MIMEHeader{}
+		mimeHeader.Set("Content-Type", contentType)
+
+		var partWriter io.Writer
+		if partWriter, err = e.mw.CreatePart(mimeHeader); err == nil {
+			_, err = partWriter.Write(payload)
+		}
+	}
+	return
+}
+
+// Close terminates the encoded body.
+func (e *hsiBatchEncoder) Close() error {
+	switch e.format {
+	case "json_array":
+		if e.count == 0 {
+			_, err := io.WriteString(e.w, "[]")
+			return err
+		}
+		_, err := io.WriteString(e.w, "]")
+		return err
+	case "ndjson":
+		return nil
+	}
+	return e.mw.Close()
+}
+
+//------------------------------------------------------------------------------
+
+// hsiResponseQueue collects the batches of a streamed sync response as they
+// are produced.
+type hsiResponseQueue struct {
+	mut     sync.Mutex
+	batches []message.Batch
+	notify  chan struct{}
+}
+
+func newHSIResponseQueue() *hsiResponseQueue {
+	return &hsiResponseQueue{notify: make(chan struct{}, 1)}
+}
+
+// Push adds a batch to the queue without blocking.
+func (q *hsiResponseQueue) Push(msg message.Batch) {
+	q.mut.Lock()
+	q.batches = append(q.batches, msg)
+	q.mut.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Drain removes and returns all batches of the queue.
+func (q *hsiResponseQueue) Drain() []message.Batch {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	batches := q.batches
+	q.batches = nil
+	return batches
+}
+
+//------------------------------------------------------------------------------
+
+// applyResponseHeaders sets the configured headers of a sync response from its
+// messages, and returns the status code of the response.
+func (h *httpServerInput) applyResponseHeaders(w http.ResponseWriter, batch service.MessageBatch) (int, error) {
+	for k, v := range h.conf.Response.Headers {
+		headerStr, err := batch.TryInterpolatedString(0, v)
+		if err != nil {
+			h.log.Error("Interpolation of response header %v error: %v", k, err)
+			continue
+		}
+		w.Header().Set(k, headerStr)
+	}
+
+	statusCodeStr, err := batch.TryInterpolatedString(0, h.conf.Response.Status)
+	if err != nil {
+		return 0, fmt.Errorf("interpolation of response status code error: %w", err)
+	}
+	statusCode, err := strconv.Atoi(statusCodeStr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse sync response status code expression: %w", err)
+	}
+
+	for _, part := range batch {
+		_ = h.conf.Response.ExtractMetadata.Walk(part, func(k, v string) error {
+			w.Header().Set(k, v)
+			return nil
+		})
+	}
+	return statusCode, nil
+}
+
+// partContentType returns the content type of a message within a multipart
+// sync response.
+func (h *httpServerInput) partContentType(batch service.MessageBatch, i int, payload []byte) string {
+	if customContentType, exists := h.conf.Response.Headers["content-type"]; exists {
+		contentTypeStr, err := batch.TryInterpolatedString(i, customContentType)
+		if err == nil {
+			return contentTypeStr
+		}
+		h.log.Error("Interpolation of content-type header error: %v", err)
+	}
+	return http.DetectContentType(payload)
+}
+
+func (h *httpServerInput) writeSyncResponse(w http.ResponseWriter, batch service.MessageBatch) {
+	statusCode, err := h.applyResponseHeaders(w, batch)
+	if err != nil {
+		h.log.Error("Failed to return sync response: %v\n", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	if len(batch) == 1 && h.conf.Response.BatchFormat == "multipart" {
+		payload, err := batch[0].AsBytes()
+		if err != nil {
+			h.log.Error("Failed to extract message bytes for sync response: %v\n", err)
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(payload))
+		}
+		w.WriteHeader(statusCode)
+		_, _ = w.Write(payload)
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := newHSIBatchEncoder(h.conf.Response.BatchFormat, &buf)
+	for i, part := range batch {
+		payload, err := part.AsBytes()
+		if err != nil {
+			h.log.Error("Failed to extract message bytes for sync response: %v\n", err)
+			continue
+		}
+		if err = enc.Write(h.partContentType(batch, i, payload), payload); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = enc.Close()
+	}
+	if err != nil {
+		h.log.Error("Failed to return sync response: %v\n", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Del("Content-Type")
+	w.Header().Add("Content-Type", enc.ContentType())
+	w.WriteHeader(statusCode)
+	_, _ = buf.WriteTo(w)
+}
+
+// streamSyncResponse waits for a transaction to complete, writing the batches
+// of its sync response with chunked transfer encoding as they are produced.
+// Once a response has begun errors can no longer be reported with a status
+// code, and therefore the connection is aborted instead so that the client
+// can detect an incomplete response.
+func (h *httpServerInput) streamSyncResponse(w http.ResponseWriter, r *http.Request, resChan <-chan error, queue *hsiResponseQueue, startedAt time.Time) {
+	timeout := time.NewTimer(h.conf.Timeout)
+	defer timeout.Stop()
+
+	rc := http.NewResponseController(w)
+
+	var enc *hsiBatchEncoder
+	writeQueued := func() error {
+		batches := queue.Drain()
+		for _, resMsg := range batches {
+			h.publishEvents(resMsg)
+
+			svcBatch := make(service.MessageBatch, len(resMsg))
+			for i, p := range resMsg {
+				svcBatch[i] = service.NewInternalMessage(p)
+			}
+
+			if enc == nil {
+				statusCode, err := h.applyResponseHeaders(w, svcBatch)
+				if err != nil {
+					return err
+				}
+				enc = newHSIBatchEncoder(h.conf.Response.BatchFormat, w)
+				w.Header().Del("Content-Type")
+				w.Header().Add("Content-Type", enc.ContentType())
+				w.WriteHeader(statusCode)
+			}
+
+			for i, part := range svcBatch {
+				payload, err := part.AsBytes()
+				if err != nil {
+					return err
+				}
+				if err := enc.Write(h.partContentType(svcBatch, i, payload), payload); err != nil {
+					return err
+				}
+			}
+		}
+		if len(batches) > 0 {
+			_ = rc.Flush()
+		}
+		return nil
+	}
+
+	fail := func(msg string, status int) {
+		if enc == nil {
+			http.Error(w, msg, status)
+			return
+		}
+		panic(http.ErrAbortHandler)
+	}
+
+	for {
+		select {
+		case <-queue.notify:
+			if err := writeQueued(); err != nil {
+				h.log.Error("Failed to stream sync response: %v\n", err)
+				fail("Server error", http.StatusBadGateway)
+				return
+			}
+		case res, open := <-resChan:
+			if !open {
+				fail("Server closing", http.StatusServiceUnavailable)
+				return
+			} else if res != nil {
+				fail(res.Error(), http.StatusBadGateway)
+				return
+			}
+			tTaken := time.Since(startedAt).Nanoseconds()
+			h.mLatency.Timing(tTaken)
+
+			err := writeQueued()
+			if err == nil && enc != nil {
+				err = enc.Close()
+			}
+			if err != nil {
+				h.log.Error("Failed to stream sync response: %v\n", err)
+				fail("Server error", http.StatusBadGateway)
+			}
+			return
+		case <-timeout.C:
+			fail("Request timed out", http.StatusRequestTimeout)
+			return
+		case <-r.Context().Done():
+			return
+		case <-h.shutSig.HardStopChan():
+			fail("Server closing", http.StatusServiceUnavailable)
+			return
+		}
+	}
+}
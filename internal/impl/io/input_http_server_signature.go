@@ -0,0 +1,299 @@
+package io
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	hsiFieldSignature                = "webhook_signature"
+	hsiFieldSignatureScheme          = "scheme"
+	hsiFieldSignatureSecret          = "secret"
+	hsiFieldSignatureHeader          = "header"
+	hsiFieldSignatureAlgorithm       = "algorithm"
+	hsiFieldSignatureEncoding        = "encoding"
+	hsiFieldSignaturePrefix          = "prefix"
+	hsiFieldSignatureTimestampHeader = "timestamp_header"
+	hsiFieldSignatureTolerance       = "tolerance"
+)
+
+func hsiSignatureFieldSpec() *service.ConfigField {
+	return service.NewObjectField(hsiFieldSignature,
+		service.NewStringAnnotatedEnumField(hsiFieldSignatureScheme, map[string]string{
+			"none":   "Requests are not verified.",
+			"github": "Verifies the `X-Hub-Signature-256` header of [GitHub webhooks](https://docs.github.com/en/webhooks/using-webhooks/validating-webhook-deliveries).",
+			"stripe": "Verifies the `Stripe-Signature` header of [Stripe webhooks](https://docs.stripe.com/webhooks#verify-events), including the age of its timestamp.",
+			"slack":  "Verifies the `X-Slack-Signature` and `X-Slack-Request-Timestamp` headers of [Slack requests](https://api.slack.com/authentication/verifying-requests-from-slack), including the age of the timestamp.",
+			"hmac":   "Verifies an HMAC of the request body from a configurable header, and optionally a timestamp from another header.",
+		}).
+			Description("The signature scheme of the webhook provider.").
+			Default("none"),
+		service.NewStringField(hsiFieldSignatureSecret).
+			Description("The secret used to sign requests, which is the webhook secret for `github`, the endpoint signing secret for `stripe` and the signing secret of the app for `slack`.").
+			Secret().
+			Default(""),
+		service.NewStringField(hsiFieldSignatureHeader).
+			Description("The header that contains the signature when the scheme is `hmac`.").
+			Example("X-Signature").
+			Default(""),
+		service.NewStringEnumField(hsiFieldSignatureAlgorithm, "sha1", "sha256", "sha512").
+			Description("The hash algorithm of the HMAC when the scheme is `hmac`.").
+			Default("sha256"),
+		service.NewStringEnumField(hsiFieldSignatureEncoding, "hex", "base64").
+			Description("The encoding of the signature when the scheme is `hmac`.").
+			Default("hex"),
+		service.NewStringField(hsiFieldSignaturePrefix).
+			Description("A prefix that the signature header value must have, which is removed before the signature is decoded, when the scheme is `hmac`.").
+			Example("sha256=").
+			Default(""),
+		service.NewStringField(hsiFieldSignatureTimestampHeader).
+			Description("An optional header that contains a unix timestamp in seconds when the scheme is `hmac`. When set the signed content is the timestamp followed by a `.` and the request body, and requests with a timestamp outside of the `tolerance` are rejected.").
+			Example("X-Timestamp").
+			Default(""),
+		service.NewDurationField(hsiFieldSignatureTolerance).
+			Description("The maximum difference between the signed timestamp of a request and the current time, which protects against replayed requests. Set to `0s` in order to disable this check.").
+			Default("5m"),
+	).
+		Description("Verify the signature of requests sent by webhook providers, where requests with a missing or invalid signature are rejected with a 401 status code before they are consumed. Verification is applied to requests received on the `path` and `paths` endpoints.").
+		Advanced().
+		Version("4.28.0")
+}
+
+type hsiSignatureConfig struct {
+	Scheme          string
+	Secret          []byte
+	Header          string
+	HashFn          func() hash.Hash
+	Encoding        string
+	Prefix          string
+	TimestampHeader string
+	Tolerance       time.Duration
+
+	nowFn func() time.Time
+}
+
+func hsiSignatureConfigFromParsed(pConf *service.ParsedConfig) (*hsiSignatureConfig, error) {
+	scheme, err := pConf.FieldString(hsiFieldSignatureScheme)
+	if err != nil {
+		return nil, err
+	}
+	if scheme == "none" {
+		return nil, nil
+	}
+
+	conf := &hsiSignatureConfig{
+		Scheme: scheme,
+		HashFn: sha256.New,
+		nowFn:  time.Now,
+	}
+
+	secret, err := pConf.FieldString(hsiFieldSignatureSecret)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("a %v must be specified for the %v signature scheme", hsiFieldSignatureSecret, scheme)
+	}
+	conf.Secret = []byte(secret)
+
+	if conf.Tolerance, err = pConf.FieldDuration(hsiFieldSignatureTolerance); err != nil {
+		return nil, err
+	}
+	if scheme != "hmac" {
+		return conf, nil
+	}
+
+	if conf.Header, err = pConf.FieldString(hsiFieldSignatureHeader); err != nil {
+		return nil, err
+	}
+	if conf.Header == "" {
+		return nil, fmt.Errorf("a %v must be specified for the hmac signature scheme", hsiFieldSignatureHeader)
+	}
+
+	algorithm, err := pConf.FieldString(hsiFieldSignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	switch algorithm {
+	case "sha1":
+		conf.HashFn = sha1.New
+	case "sha256":
+		conf.HashFn = sha256.New
+	case "sha512":
+		conf.HashFn = sha512.New
+	default:
+		return nil, fmt.Errorf("unrecognised algorithm: %v", algorithm)
+	}
+
+	if conf.Encoding, err = pConf.FieldString(hsiFieldSignatureEncoding); err != nil {
+		return nil, err
+	}
+	if conf.Prefix, err = pConf.FieldString(hsiFieldSignaturePrefix); err != nil {
+		return nil, err
+	}
+	if conf.TimestampHeader, err = pConf.FieldString(hsiFieldSignatureTimestampHeader); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+func (s *hsiSignatureConfig) sign(parts ...[]byte) []byte {
+	mac := hmac.New(s.HashFn, s.Secret)
+	for _, p := range parts {
+		_, _ = mac.Write(p)
+	}
+	return mac.Sum(nil)
+}
+
+func (s *hsiSignatureConfig) checkTimestamp(ts string) error {
+	if ts == "" {
+		return errors.New("missing signature timestamp")
+	}
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp: %w", err)
+	}
+	if s.Tolerance <= 0 {
+		return nil
+	}
+	age := s.nowFn().Sub(time.Unix(secs, 0))
+	if age < 0 {
+		age = -age
+	}
+	if age > s.Tolerance {
+		return fmt.Errorf("signature timestamp is outside of the tolerance of %v", s.Tolerance)
+	}
+	return nil
+}
+
+// verifyHex checks whether a hex encoded signature matches the expected MAC.
+func verifyHex(sig string, expected []byte) bool {
+	sigBytes, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(sigBytes, expected)
+}
+
+// Verify returns an error if the signature of a request, consisting of its
+// headers and body, is missing or invalid.
+func (s *hsiSignatureConfig) Verify(header http.Header, body []byte) error {
+	switch s.Scheme {
+	case "github":
+		return s.verifyGitHub(header, body)
+	case "stripe":
+		return s.verifyStripe(header, body)
+	case "slack":
+		return s.verifySlack(header, body)
+	case "hmac":
+		return s.verifyHMAC(header, body)
+	}
+	return fmt.Errorf("unrecognised signature scheme: %v", s.Scheme)
+}
+
+func (s *hsiSignatureConfig) verifyGitHub(header http.Header, body []byte) error {
+	sig := header.Get("X-Hub-Signature-256")
+	if sig == "" {
+		return errors.New("missing X-Hub-Signature-256 header")
+	}
+	if !strings.HasPrefix(sig, "sha256=") || !verifyHex(strings.TrimPrefix(sig, "sha256="), s.sign(body)) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (s *hsiSignatureConfig) verifyStripe(header http.Header, body []byte) error {
+	sigHeader := header.Get("Stripe-Signature")
+	if sigHeader == "" {
+		return errors.New("missing Stripe-Signature header")
+	}
+
+	var ts string
+	var sigs []string
+	for _, kv := range strings.Split(sigHeader, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(kv), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if err := s.checkTimestamp(ts); err != nil {
+		return err
+	}
+	if len(sigs) == 0 {
+		return errors.New("missing v1 signature")
+	}
+
+	expected := s.sign([]byte(ts), []byte("."), body)
+	for _, sig := range sigs {
+		if verifyHex(sig, expected) {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
+
+func (s *hsiSignatureConfig) verifySlack(header http.Header, body []byte) error {
+	sig := header.Get("X-Slack-Signature")
+	if sig == "" {
+		return errors.New("missing X-Slack-Signature header")
+	}
+	ts := header.Get("X-Slack-Request-Timestamp")
+	if err := s.checkTimestamp(ts); err != nil {
+		return err
+	}
+	expected := s.sign([]byte("v0:"+ts+":"), body)
+	if !strings.HasPrefix(sig, "v0=") || !verifyHex(strings.TrimPrefix(sig, "v0="), expected) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func (s *hsiSignatureConfig) verifyHMAC(header http.Header, body []byte) error {
+	sig := header.Get(s.Header)
+	if sig == "" {
+		return fmt.Errorf("missing %v header", s.Header)
+	}
+	if !strings.HasPrefix(sig, s.Prefix) {
+		return errors.New("signature mismatch")
+	}
+	sig = strings.TrimPrefix(sig, s.Prefix)
+
+	var expected []byte
+	if s.TimestampHeader != "" {
+		ts := header.Get(s.TimestampHeader)
+		if err := s.checkTimestamp(ts); err != nil {
+			return err
+		}
+		expected = s.sign([]byte(ts), []byte("."), body)
+	} else {
+		expected = s.sign(body)
+	}
+
+	var sigBytes []byte
+	var err error
+	if s.Encoding == "base64" {
+		sigBytes, err = base64.StdEncoding.DecodeString(sig)
+	} else {
+		sigBytes, err = hex.DecodeString(sig)
+	}
+	if err != nil || !hmac.Equal(sigBytes, expected) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
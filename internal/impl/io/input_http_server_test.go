@@ -1,10 +1,17 @@
 package io_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"mime"
 	"mime/multipart"
@@ -13,6 +20,8 @@ import (
 	"net/http/httptest"
 	"net/textproto"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -425,6 +434,99 @@ http_server:
 	assert.Equal(t, "will go on", part.MetaGetStr("mylove"))
 }
 
+func TestHTTPServerMultiplePaths(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := parseYAMLInputConf(t, `
+http_server:
+  path: /default
+  ws_path: ""
+  paths:
+    - path: /orders
+      metadata:
+        event_type: order
+    - path: /refunds/{order_id}
+      allowed_verbs: [ PUT ]
+      metadata:
+        event_type: refund
+      mapping: |
+        root.order_id = @order_id
+        root.amount = content().number()
+    - path: /ignored
+      mapping: root = deleted()
+    - path: /broken
+      mapping: root = this.nope.number()
+`)
+
+	server, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	defer func() {
+		server.TriggerStopConsuming()
+		assert.NoError(t, server.WaitForClose(tCtx))
+	}()
+
+	testServer := httptest.NewServer(reg.mut)
+	defer testServer.Close()
+
+	sendReq := func(verb, path, body string) int {
+		req, err := http.NewRequest(verb, testServer.URL+path, bytes.NewBufferString(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	readNextMsg := func() *message.Part {
+		select {
+		case tran := <-server.TransactionChan():
+			require.NoError(t, tran.Ack(tCtx, nil))
+			require.Equal(t, 1, tran.Payload.Len())
+			return tran.Payload.Get(0)
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out")
+		}
+		return nil
+	}
+
+	go func() {
+		assert.Equal(t, http.StatusOK, sendReq("POST", "/default?foo=bar", "default"))
+	}()
+	part := readNextMsg()
+	assert.Equal(t, "default", string(part.AsBytes()))
+	assert.Equal(t, "/default", part.MetaGetStr("http_server_route"))
+	assert.Equal(t, "bar", part.MetaGetStr("foo"))
+
+	go func() {
+		assert.Equal(t, http.StatusOK, sendReq("POST", "/orders", `{"id":"foo"}`))
+	}()
+	part = readNextMsg()
+	assert.Equal(t, `{"id":"foo"}`, string(part.AsBytes()))
+	assert.Equal(t, "/orders", part.MetaGetStr("http_server_route"))
+	assert.Equal(t, "/orders", part.MetaGetStr("http_server_request_path"))
+	assert.Equal(t, "order", part.MetaGetStr("event_type"))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, sendReq("POST", "/refunds/foo", "10"))
+
+	go func() {
+		assert.Equal(t, http.StatusOK, sendReq("PUT", "/refunds/foo", "10"))
+	}()
+	part = readNextMsg()
+	assert.Equal(t, `{"amount":10,"order_id":"foo"}`, string(part.AsBytes()))
+	assert.Equal(t, "/refunds/{order_id}", part.MetaGetStr("http_server_route"))
+	assert.Equal(t, "PUT", part.MetaGetStr("http_server_verb"))
+	assert.Equal(t, "refund", part.MetaGetStr("event_type"))
+
+	assert.Equal(t, http.StatusOK, sendReq("POST", "/ignored", "nope"))
+	assert.Equal(t, http.StatusBadRequest, sendReq("POST", "/broken", `{}`))
+}
+
 func TestHTTPServerPathIsPrefix(t *testing.T) {
 	tCtx, done := context.WithTimeout(context.Background(), time.Minute)
 	defer done()
@@ -1047,6 +1149,72 @@ http_server:
 	wg.Wait()
 }
 
+func TestHTTPSyncResponseResultMetadata(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	t.Parallel()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := parseYAMLInputConf(t, `
+http_server:
+  path: /testpost
+  sync_response:
+    headers:
+      X-Version-Id: '${! @s3_version_id }'
+    metadata_headers:
+      include_prefixes: [ 'kafka_' ]
+`)
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewBufferString("hello world"))
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "v1", res.Header.Get("X-Version-Id"))
+		assert.Equal(t, "12", res.Header.Get("kafka_offset"))
+
+		resBytes, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello world", string(resBytes))
+	}()
+
+	var ts message.Transaction
+	select {
+	case ts = <-h.TransactionChan():
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	// The response is set before result metadata is added by other outputs,
+	// which is still included.
+	require.NoError(t, transaction.SetAsResponse(ts.Payload.ShallowCopy()))
+	transaction.SetResultMetadata(ts.Payload.Get(0), "s3_version_id", "v1")
+	transaction.SetResultMetadata(ts.Payload.Get(0), "kafka_offset", 12)
+	require.NoError(t, ts.Ack(tCtx, nil))
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+
+	wg.Wait()
+}
+
 func createMultipart(payloads []string, contentType string) (hdr string, bodyBytes []byte, err error) {
 	body := &bytes.Buffer{}
 	writer := multipart.NewWriter(body)
@@ -1336,3 +1504,701 @@ http_server:
 	assert.Equal(t, "200 OK", resp.Status)
 	assert.Equal(t, "foo", resp.Header.Get("Access-Control-Allow-Origin"))
 }
+
+func TestHTTPServerWebhookSignature(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"action":"opened"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	hexMAC := func(newHash func() hash.Hash, parts ...string) string {
+		mac := hmac.New(newHash, []byte("shhh"))
+		for _, p := range parts {
+			_, _ = mac.Write([]byte(p))
+		}
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name    string
+		conf    string
+		headers map[string]string
+		status  int
+	}{
+		{
+			name:    "github valid",
+			conf:    `scheme: github`,
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + hexMAC(sha256.New, string(body))},
+			status:  http.StatusOK,
+		},
+		{
+			name:    "github invalid",
+			conf:    `scheme: github`,
+			headers: map[string]string{"X-Hub-Signature-256": "sha256=" + hexMAC(sha256.New, "nope")},
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name:   "github missing",
+			conf:   `scheme: github`,
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "stripe valid",
+			conf: `scheme: stripe`,
+			headers: map[string]string{
+				"Stripe-Signature": "t=" + now + ",v1=" + hexMAC(sha256.New, "nope") + ",v1=" + hexMAC(sha256.New, now, ".", string(body)),
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "stripe stale",
+			conf: `scheme: stripe`,
+			headers: map[string]string{
+				"Stripe-Signature": "t=" + stale + ",v1=" + hexMAC(sha256.New, stale, ".", string(body)),
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "slack valid",
+			conf: `scheme: slack`,
+			headers: map[string]string{
+				"X-Slack-Request-Timestamp": now,
+				"X-Slack-Signature":         "v0=" + hexMAC(sha256.New, "v0:", now, ":", string(body)),
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "slack stale",
+			conf: `scheme: slack`,
+			headers: map[string]string{
+				"X-Slack-Request-Timestamp": stale,
+				"X-Slack-Signature":         "v0=" + hexMAC(sha256.New, "v0:", stale, ":", string(body)),
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "hmac valid",
+			conf: `
+scheme: hmac
+header: X-Signature
+algorithm: sha512
+prefix: "sha512="
+timestamp_header: X-Timestamp
+`,
+			headers: map[string]string{
+				"X-Timestamp": now,
+				"X-Signature": "sha512=" + hexMAC(sha512.New, now, ".", string(body)),
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "hmac wrong algorithm",
+			conf: `
+scheme: hmac
+header: X-Signature
+`,
+			headers: map[string]string{"X-Signature": hexMAC(sha512.New, string(body))},
+			status:  http.StatusUnauthorized,
+		},
+		{
+			name: "hmac base64",
+			conf: `
+scheme: hmac
+header: X-Signature
+encoding: base64
+`,
+			headers: map[string]string{"X-Signature": func() string {
+				mac := hmac.New(sha256.New, []byte("shhh"))
+				_, _ = mac.Write(body)
+				return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+			}()},
+			status: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+			defer done()
+
+			reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+			mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+			require.NoError(t, err)
+
+			conf := parseYAMLInputConf(t, `
+http_server:
+  path: /webhook
+  webhook_signature:
+    secret: shhh
+    %v
+`, strings.ReplaceAll(strings.TrimSpace(test.conf), "\n", "\n    "))
+
+			h, err := mgr.NewInput(conf)
+			require.NoError(t, err)
+
+			server := httptest.NewServer(reg.mut)
+			defer server.Close()
+
+			go func() {
+				select {
+				case ts, open := <-h.TransactionChan():
+					if !open {
+						return
+					}
+					assert.Equal(t, string(body), string(ts.Payload.Get(0).AsBytes()))
+					assert.NoError(t, ts.Ack(ctx, nil))
+				case <-ctx.Done():
+				}
+			}()
+
+			req, err := http.NewRequest(http.MethodPost, server.URL+"/webhook", bytes.NewReader(body))
+			require.NoError(t, err)
+			for k, v := range test.headers {
+				req.Header.Set(k, v)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			_ = res.Body.Close()
+			assert.Equal(t, test.status, res.StatusCode)
+
+			h.TriggerStopConsuming()
+			assert.NoError(t, h.WaitForClose(ctx))
+		})
+	}
+}
+
+func TestHTTPServerWebhookSignatureConfigErrors(t *testing.T) {
+	mgr := mock.NewManager()
+
+	for _, c := range []string{
+		`
+http_server:
+  webhook_signature:
+    scheme: github
+`,
+		`
+http_server:
+  webhook_signature:
+    scheme: hmac
+    secret: shhh
+`,
+	} {
+		_, err := mgr.NewInput(parseYAMLInputConf(t, c))
+		require.Error(t, err)
+	}
+}
+
+func TestHTTPServerEventStream(t *testing.T) {
+	t.Parallel()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := parseYAMLInputConf(t, `
+http_server:
+  path: /testpost
+  event_stream:
+    path: /events
+    heartbeat: 0s
+`)
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	go func() {
+		for {
+			select {
+			case ts, open := <-h.TransactionChan():
+				if !open {
+					return
+				}
+				ts.Payload.Get(0).SetBytes(append([]byte("echo "), ts.Payload.Get(0).AsBytes()...))
+				assert.NoError(t, transaction.SetAsResponse(ts.Payload))
+				assert.NoError(t, ts.Ack(ctx, nil))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	post := func(body string) {
+		res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	res, err := http.Get(server.URL + "/events")
+	require.NoError(t, err)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	post("foo")
+	post("bar\nbaz")
+
+	readEvents := func(r io.Reader, n int) string {
+		var buf bytes.Buffer
+		b := make([]byte, 1)
+		for strings.Count(buf.String(), "\n\n") < n {
+			_, err := r.Read(b)
+			require.NoError(t, err)
+			buf.Write(b)
+		}
+		return buf.String()
+	}
+
+	assert.Equal(t, "id: 1\ndata: echo foo\n\nid: 2\ndata: echo bar\ndata: baz\n\n", readEvents(res.Body, 2))
+	_ = res.Body.Close()
+
+	// Reconnect and resume after the first event.
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/events", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+
+	res, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, "id: 2\ndata: echo bar\ndata: baz\n\n", readEvents(res.Body, 1))
+	_ = res.Body.Close()
+
+	res, err = http.Post(server.URL+"/events", "text/plain", http.NoBody)
+	require.NoError(t, err)
+	_ = res.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+
+	h.TriggerStopConsuming()
+	assert.NoError(t, h.WaitForClose(ctx))
+}
+
+func TestHTTPServerEventStreamLongPoll(t *testing.T) {
+	t.Parallel()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := parseYAMLInputConf(t, `
+http_server:
+  path: /testpost
+  event_stream:
+    path: /events
+    mode: long_poll
+    buffer_size: 2
+    poll_timeout: 100ms
+`)
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	go func() {
+		for {
+			select {
+			case ts, open := <-h.TransactionChan():
+				if !open {
+					return
+				}
+				assert.NoError(t, transaction.SetAsResponse(ts.Payload))
+				assert.NoError(t, ts.Ack(ctx, nil))
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for _, body := range []string{"foo", "bar", "baz"} {
+		res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		_ = res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	poll := func(query string) (int, string, string) {
+		res, err := http.Get(server.URL + "/events" + query)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, res.Header.Get("Last-Event-ID"), string(body)
+	}
+
+	// The first event has been evicted from the buffer.
+	status, lastID, body := poll("?last_event_id=0")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "3", lastID)
+	assert.Equal(t, `[{"id":2,"data":"bar"},{"id":3,"data":"baz"}]`, body)
+
+	status, lastID, _ = poll("?last_event_id=3")
+	assert.Equal(t, http.StatusNoContent, status)
+	assert.Equal(t, "3", lastID)
+
+	status, _, _ = poll("?last_event_id=nope")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// A waiting poll is responded to as soon as an event is published.
+	resChan := make(chan string)
+	go func() {
+		req, err := http.NewRequest(http.MethodGet, server.URL+"/events?last_event_id=3", http.NoBody)
+		if !assert.NoError(t, err) {
+			return
+		}
+		res, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		resChan <- string(body)
+	}()
+
+	res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewBufferString("buz"))
+	require.NoError(t, err)
+	_ = res.Body.Close()
+
+	select {
+	case body := <-resChan:
+		if body != "" {
+			assert.Equal(t, `[{"id":4,"data":"buz"}]`, body)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out")
+	}
+
+	h.TriggerStopConsuming()
+	assert.NoError(t, h.WaitForClose(ctx))
+}
+
+func TestHTTPServerIdempotency(t *testing.T) {
+	t.Parallel()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+
+	mgrConf, err := testutil.ManagerFromYAML(`
+cache_resources:
+  - label: foocache
+    memory: {}
+`)
+	require.NoError(t, err)
+
+	mgr, err := manager.New(mgrConf, manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := parseYAMLInputConf(t, `
+http_server:
+  path: /testpost
+  idempotency:
+    header: Idempotency-Key
+    cache: foocache
+`)
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	var processed []string
+	go func() {
+		for {
+			select {
+			case ts, open := <-h.TransactionChan():
+				if !open {
+					return
+				}
+				body := string(ts.Payload.Get(0).AsBytes())
+				processed = append(processed, body)
+				if body == "fail" {
+					assert.NoError(t, ts.Ack(tCtx, errors.New("nope")))
+					continue
+				}
+				ts.Payload.Get(0).SetBytes([]byte(fmt.Sprintf("processed %v", len(processed))))
+				assert.NoError(t, transaction.SetAsResponse(ts.Payload))
+				assert.NoError(t, ts.Ack(tCtx, nil))
+			case <-tCtx.Done():
+				return
+			}
+		}
+	}()
+
+	post := func(key, body string) (int, string, string) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/testpost", bytes.NewBufferString(body))
+		require.NoError(t, err)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		resBytes, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, res.Header.Get("Idempotent-Replayed"), string(resBytes)
+	}
+
+	// Failed requests are not stored and are processed again.
+	status, _, _ := post("a", "fail")
+	assert.Equal(t, http.StatusBadGateway, status)
+
+	status, replayed, body := post("a", "foo")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, replayed)
+	assert.Equal(t, "processed 2", body)
+
+	status, replayed, body = post("a", "foo")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "true", replayed)
+	assert.Equal(t, "processed 2", body)
+
+	status, replayed, body = post("b", "bar")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, replayed)
+	assert.Equal(t, "processed 3", body)
+
+	status, replayed, body = post("", "baz")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, replayed)
+	assert.Equal(t, "processed 4", body)
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+
+	assert.Equal(t, []string{"fail", "foo", "bar", "baz"}, processed)
+}
+
+func TestHTTPServerIdempotencyConfigErrors(t *testing.T) {
+	mgr := mock.NewManager()
+
+	_, err := mgr.NewInput(parseYAMLInputConf(t, `
+http_server:
+  idempotency:
+    header: Idempotency-Key
+`))
+	require.Error(t, err)
+
+	_, err = mgr.NewInput(parseYAMLInputConf(t, `
+http_server:
+  idempotency:
+    header: Idempotency-Key
+    cache: nope
+`))
+	require.Error(t, err)
+}
+
+func TestHTTPSyncResponseBatchFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format      string
+		contentType string
+		body        string
+	}{
+		{
+			format:      "json_array",
+			contentType: "application/json",
+			body:        `[{"id":1},"not json",{"id":3}]`,
+		},
+		{
+			format:      "ndjson",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1}\nnot json\n{\"id\":3}\n",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.format, func(t *testing.T) {
+			t.Parallel()
+
+			tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+			defer done()
+
+			reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+			mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+			require.NoError(t, err)
+
+			conf := parseYAMLInputConf(t, `
+http_server:
+  path: /testpost
+  sync_response:
+    batch_format: %v
+`, test.format)
+
+			h, err := mgr.NewInput(conf)
+			require.NoError(t, err)
+
+			server := httptest.NewServer(reg.mut)
+			defer server.Close()
+
+			go func() {
+				select {
+				case ts := <-h.TransactionChan():
+					for i, o := range []string{`{"id":1}`, `not json`, `{"id":3}`} {
+						if i > 0 {
+							ts.Payload = append(ts.Payload, ts.Payload.Get(0).ShallowCopy())
+						}
+						ts.Payload.Get(i).SetBytes([]byte(o))
+					}
+					assert.NoError(t, transaction.SetAsResponse(ts.Payload))
+					assert.NoError(t, ts.Ack(tCtx, nil))
+				case <-tCtx.Done():
+				}
+			}()
+
+			res, err := http.Post(server.URL+"/testpost", "text/plain", bytes.NewBufferString("hello"))
+			require.NoError(t, err)
+			defer res.Body.Close()
+
+			resBytes, err := io.ReadAll(res.Body)
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Equal(t, test.contentType, res.Header.Get("Content-Type"))
+			assert.Equal(t, test.body, string(resBytes))
+
+			h.TriggerStopConsuming()
+			require.NoError(t, h.WaitForClose(tCtx))
+		})
+	}
+}
+
+func TestHTTPSyncResponseStream(t *testing.T) {
+	t.Parallel()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := parseYAMLInputConf(t, `
+http_server:
+  path: /testpost
+  sync_response:
+    batch_format: ndjson
+    stream: true
+`)
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	input := []string{`{"id":1}`, `{"id":2}`}
+	firstReceived := make(chan struct{})
+
+	go func() {
+		select {
+		case ts := <-h.TransactionChan():
+			// Each message is added as a separate response, where the second is
+			// only added once the first has reached the client.
+			assert.NoError(t, transaction.SetAsResponse(ts.Payload[:1]))
+			select {
+			case <-firstReceived:
+			case <-tCtx.Done():
+				return
+			}
+			assert.NoError(t, transaction.SetAsResponse(ts.Payload[1:]))
+			assert.NoError(t, ts.Ack(tCtx, nil))
+		case <-tCtx.Done():
+		}
+	}()
+
+	hdr, body, err := createMultipart(input, "application/json")
+	require.NoError(t, err)
+
+	res, err := http.Post(server.URL+"/testpost", hdr, bytes.NewReader(body))
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+
+	lines := bufio.NewReader(res.Body)
+	line, err := lines.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":1}\n", line)
+	close(firstReceived)
+
+	rest, err := io.ReadAll(lines)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"id\":2}\n", string(rest))
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+}
+
+func TestHTTPSyncResponseStreamAborted(t *testing.T) {
+	t.Parallel()
+
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	reg := apiRegGorillaMutWrapper{mut: mux.NewRouter()}
+	mgr, err := manager.New(manager.ResourceConfig{}, manager.OptSetAPIReg(reg))
+	require.NoError(t, err)
+
+	conf := parseYAMLInputConf(t, `
+http_server:
+  path: /testpost
+  sync_response:
+    batch_format: json_array
+    stream: true
+`)
+
+	h, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(reg.mut)
+	defer server.Close()
+
+	firstReceived := make(chan struct{})
+	go func() {
+		select {
+		case ts := <-h.TransactionChan():
+			assert.NoError(t, transaction.SetAsResponse(ts.Payload))
+			select {
+			case <-firstReceived:
+			case <-tCtx.Done():
+				return
+			}
+			assert.NoError(t, ts.Ack(tCtx, errors.New("nope")))
+		case <-tCtx.Done():
+		}
+	}()
+
+	res, err := http.Post(server.URL+"/testpost", "application/json", bytes.NewBufferString(`{"id":1}`))
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	b := make([]byte, len(`[{"id":1}`))
+	_, err = io.ReadFull(res.Body, b)
+	require.NoError(t, err)
+	assert.Equal(t, `[{"id":1}`, string(b))
+	close(firstReceived)
+
+	_, err = io.ReadAll(res.Body)
+	require.Error(t, err)
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+}
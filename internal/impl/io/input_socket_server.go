@@ -22,6 +22,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/codec/interop"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/scanner"
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -146,9 +147,8 @@ func (t *socketServerInput) Connect(ctx context.Context) error {
 		if cert, err = loadOrCreateCertificate(t.tlsCert, t.tlsKey, t.tlsSelfSigned); err != nil {
 			return err
 		}
-		config := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-		}
+		config := btls.PolicyConfig()
+		config.Certificates = []tls.Certificate{cert}
 		ln, err = tls.Listen("tcp", t.address, config)
 	case "udp":
 		cn, err = net.ListenPacket(t.network, t.address)
@@ -0,0 +1,313 @@
+package io
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/httpclient"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sseFieldReconnect      = "reconnect"
+	sseFieldReconnectDelay = "reconnect_delay"
+	sseFieldLastEventID    = "last_event_id"
+)
+
+func sseClientInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Network").
+		Summary("Connects to a [Server-Sent Events](https://html.spec.whatwg.org/multipage/server-sent-events.html) endpoint and consumes each event as a message.").
+		Description(`
+The data of each event is consumed as the contents of a message, where events with multiple `+"`data`"+` lines are joined with newlines. Comments and events without data are ignored.
+
+When the connection is lost it is re-established after the `+"`reconnect_delay`"+`, or the delay specified by the server with a `+"`retry`"+` field, and the ID of the last event received is sent with a `+"`Last-Event-ID`"+` header so that the server can resume the stream from where it left off.
+
+The URL and header values of this type can be dynamically set using function interpolations described [here](/docs/configuration/interpolation#bloblang-queries), and the ID of the last event received is available to them with the metadata field `+"`sse_last_event_id`"+`. The `+"`timeout`"+` field is not applied as the response body of a connection is consumed for as long as it remains open.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- sse_event
+- sse_id
+`+"```"+`
+
+The field `+"`sse_event`"+` is the name of the event, which is `+"`message`"+` when the server doesn't specify one, and `+"`sse_id`"+` is the last event ID at the time the event was received, which is omitted when the server hasn't sent one.
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Field(httpclient.ConfigField("GET", false,
+			service.NewBoolField(sseFieldReconnect).
+				Description("Whether to re-establish the connection once it is lost. When disabled the input shuts down once the server closes the stream.").
+				Default(true),
+			service.NewDurationField(sseFieldReconnectDelay).
+				Description("The period of time to wait before re-establishing a lost connection, unless the server specifies a different delay with a `retry` field.").
+				Default("3s").
+				Advanced(),
+			service.NewStringField(sseFieldLastEventID).
+				Description("An optional event ID to send with the first connection in order to resume a stream from a known position.").
+				Default("").
+				Advanced(),
+		)).
+		Field(service.NewAutoRetryNacksToggleField()).
+		Example(
+			"Wikimedia Recent Changes",
+			"Consumes the public stream of changes made to Wikimedia projects, keeping only changes made to English Wikipedia:",
+			`
+input:
+  sse_client:
+    url: https://stream.wikimedia.org/v2/stream/recentchange
+  processors:
+    - mapping: |
+        root = if this.wiki != "enwiki" { deleted() }
+`,
+		).
+		Example(
+			"Mastodon Public Timeline",
+			"Consumes new statuses from the public timeline of a Mastodon instance, where other kinds of events are dropped:",
+			`
+input:
+  sse_client:
+    url: https://mastodon.social/api/v1/streaming/public
+    headers:
+      Authorization: Bearer ${MASTODON_TOKEN}
+  processors:
+    - mapping: |
+        root = if @sse_event != "update" { deleted() }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"sse_client", sseClientInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			rdr, err := newSSEClientInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, rdr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type sseClientInput struct {
+	client    *httpclient.Client
+	reconnect bool
+	log       *service.Logger
+
+	mut            sync.Mutex
+	body           io.ReadCloser
+	reader         *bufio.Reader
+	cancelFn       context.CancelFunc
+	lastID         string
+	reconnectDelay time.Duration
+	reconnecting   bool
+}
+
+func newSSEClientInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sseClientInput, error) {
+	oldConf, err := httpclient.ConfigFromParsed(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	// Timeout should be left at zero as the stream is consumed indefinitely.
+	oldConf.Timeout = 0
+
+	hasAccept := false
+	for k := range oldConf.Headers {
+		if strings.EqualFold(k, "Accept") {
+			hasAccept = true
+		}
+	}
+	if !hasAccept {
+		if oldConf.Headers == nil {
+			oldConf.Headers = map[string]*service.InterpolatedString{}
+		}
+		if oldConf.Headers["Accept"], err = service.NewInterpolatedString("text/event-stream"); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &sseClientInput{
+		log: mgr.Logger(),
+	}
+	if s.reconnect, err = conf.FieldBool(sseFieldReconnect); err != nil {
+		return nil, err
+	}
+	if s.reconnectDelay, err = conf.FieldDuration(sseFieldReconnectDelay); err != nil {
+		return nil, err
+	}
+	if s.lastID, err = conf.FieldString(sseFieldLastEventID); err != nil {
+		return nil, err
+	}
+
+	headersMapping, err := bloblang.Parse(`root = {}
+root."Last-Event-ID" = @sse_last_event_id`)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.client, err = httpclient.NewClientFromOldConfig(oldConf, mgr, httpclient.WithHeadersMapping(headersMapping)); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sseClientInput) Connect(ctx context.Context) error {
+	s.mut.Lock()
+	if s.body != nil {
+		s.mut.Unlock()
+		return nil
+	}
+	reconnecting, delay, lastID := s.reconnecting, s.reconnectDelay, s.lastID
+	s.mut.Unlock()
+
+	if reconnecting && delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	refMsg := service.NewMessage(nil)
+	if lastID != "" {
+		refMsg.MetaSetMut("sse_last_event_id", lastID)
+	}
+
+	// The request context outlives this call as the body of the response is
+	// consumed until the input is closed.
+	reqCtx, cancelFn := context.WithCancel(context.Background())
+	res, err := s.client.SendToResponse(reqCtx, service.MessageBatch{refMsg})
+	if err != nil {
+		cancelFn()
+		return err
+	}
+
+	s.mut.Lock()
+	s.body = res.Body
+	s.reader = bufio.NewReader(res.Body)
+	s.cancelFn = cancelFn
+	s.reconnecting = true
+	s.mut.Unlock()
+	return nil
+}
+
+func (s *sseClientInput) disconnect() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.body != nil {
+		_ = s.body.Close()
+		s.body = nil
+		s.reader = nil
+	}
+	if s.cancelFn != nil {
+		s.cancelFn()
+		s.cancelFn = nil
+	}
+}
+
+func (s *sseClientInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	s.mut.Lock()
+	reader := s.reader
+	s.mut.Unlock()
+
+	if reader == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var (
+		eventType string
+		data      strings.Builder
+		hasData   bool
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			s.disconnect()
+			if !s.reconnect {
+				return nil, nil, service.ErrEndOfInput
+			}
+			if !errors.Is(err, io.EOF) {
+				s.log.Debugf("Lost connection to event stream: %v", err)
+			}
+			return nil, nil, service.ErrNotConnected
+		}
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		// An empty line dispatches the event that has been accumulated.
+		if line == "" {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			if eventType == "" {
+				eventType = "message"
+			}
+
+			msg := service.NewMessage([]byte(data.String()))
+			msg.MetaSetMut("sse_event", eventType)
+
+			s.mut.Lock()
+			if s.lastID != "" {
+				msg.MetaSetMut("sse_id", s.lastID)
+			}
+			s.mut.Unlock()
+
+			return msg, func(context.Context, error) error {
+				return nil
+			}, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				s.mut.Lock()
+				s.lastID = value
+				s.mut.Unlock()
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 64); err == nil {
+				s.mut.Lock()
+				s.reconnectDelay = time.Duration(ms) * time.Millisecond
+				s.mut.Unlock()
+			}
+		}
+	}
+}
+
+func (s *sseClientInput) Close(ctx context.Context) error {
+	s.disconnect()
+	return s.client.Close(ctx)
+}
@@ -0,0 +1,121 @@
+package io_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/manager/mock"
+)
+
+func TestSSEClientReconnect(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	var mut sync.Mutex
+	var lastEventIDs, accepts []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		accepts = append(accepts, r.Header.Get("Accept"))
+		n := len(lastEventIDs)
+		mut.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch n {
+		case 1:
+			_, _ = w.Write([]byte("retry: 10\n: a comment\nid: 1\nevent: greet\ndata: hello\ndata: world\n\nevent: ignored\n\ndata: plain\r\n\r\n"))
+		case 2:
+			_, _ = w.Write([]byte("id: 2\ndata:again\n\n"))
+		default:
+			<-r.Context().Done()
+		}
+	}))
+	defer ts.Close()
+
+	conf := parseYAMLInputConf(t, `
+sse_client:
+  url: %v/events
+  reconnect_delay: 1h
+  last_event_id: "0"
+`, ts.URL)
+
+	h, err := mock.NewManager().NewInput(conf)
+	require.NoError(t, err)
+
+	type event struct {
+		data, event, id string
+	}
+	for _, exp := range []event{
+		{data: "hello\nworld", event: "greet", id: "1"},
+		{data: "plain", event: "message", id: "1"},
+		{data: "again", event: "message", id: "2"},
+	} {
+		select {
+		case tr, open := <-h.TransactionChan():
+			require.True(t, open)
+			p := tr.Payload.Get(0)
+			assert.Equal(t, exp.data, string(p.AsBytes()))
+			assert.Equal(t, exp.event, p.MetaGetStr("sse_event"))
+			assert.Equal(t, exp.id, p.MetaGetStr("sse_id"))
+			require.NoError(t, tr.Ack(tCtx, nil))
+		case <-tCtx.Done():
+			t.Fatal("timed out")
+		}
+	}
+
+	h.TriggerStopConsuming()
+	require.NoError(t, h.WaitForClose(tCtx))
+
+	mut.Lock()
+	defer mut.Unlock()
+	assert.Equal(t, []string{"0", "1"}, lastEventIDs[:2])
+	assert.Equal(t, "text/event-stream", accepts[0])
+}
+
+func TestSSEClientNoReconnect(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Last-Event-ID"))
+		_, _ = w.Write([]byte("data: foo\n\ndata: bar\n\n"))
+	}))
+	defer ts.Close()
+
+	conf := parseYAMLInputConf(t, `
+sse_client:
+  url: %v/events
+  reconnect: false
+`, ts.URL)
+
+	h, err := mock.NewManager().NewInput(conf)
+	require.NoError(t, err)
+
+	for _, exp := range []string{"foo", "bar"} {
+		select {
+		case tr, open := <-h.TransactionChan():
+			require.True(t, open)
+			assert.Equal(t, exp, string(tr.Payload.Get(0).AsBytes()))
+			_, exists := tr.Payload.Get(0).MetaGetMut("sse_id")
+			assert.False(t, exists)
+			require.NoError(t, tr.Ack(tCtx, nil))
+		case <-tCtx.Done():
+			t.Fatal("timed out")
+		}
+	}
+
+	select {
+	case _, open := <-h.TransactionChan():
+		assert.False(t, open)
+	case <-tCtx.Done():
+		t.Fatal("timed out")
+	}
+}
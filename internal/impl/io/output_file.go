@@ -10,13 +10,15 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/benthosdev/benthos/v4/internal/batchformat"
 	"github.com/benthosdev/benthos/v4/internal/codec"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
 const (
-	fileOutputFieldPath  = "path"
-	fileOutputFieldCodec = "codec"
+	fileOutputFieldPath     = "path"
+	fileOutputFieldCodec    = "codec"
+	fileOutputFieldBatching = "batching"
 )
 
 func fileOutputSpec() *service.ConfigSpec {
@@ -27,7 +29,7 @@ func fileOutputSpec() *service.ConfigSpec {
 		Description(`Messages can be written to different files by using [interpolation functions](/docs/configuration/interpolation#bloblang-queries) in the path field. However, only one file is ever open at a given time, and therefore when the path changes the previously open file is closed.`).
 		Fields(
 			service.NewInterpolatedStringField(fileOutputFieldPath).
-				Description("The file to write to, if the file does not yet exist it will be created.").
+				Description("The file to write to, if the file does not yet exist it will be created. When `format` is set each batch is written as a whole file that replaces any existing file at this path, and therefore the path must be interpolated in order to resolve a unique file for each batch, otherwise each batch would overwrite the previous one.").
 				Examples(
 					"/tmp/data.txt",
 					"/tmp/${! timestamp_unix() }.txt",
@@ -35,12 +37,29 @@ func fileOutputSpec() *service.ConfigSpec {
 				).
 				Version("3.33.0"),
 			service.NewInternalField(codec.NewWriterDocs(fileOutputFieldCodec)).Version("3.33.0").Default("lines"),
-		)
+			batchformat.ConfigField(),
+			service.NewBatchPolicyField(fileOutputFieldBatching).Version("4.28.0"),
+		).
+		LintRule(`root = match {
+  this.exists("format") && this.codec.or("lines") != "lines" => [ "field codec cannot be set with format, as formats write each batch as a whole file" ]
+  this.exists("format") && !this.path.or("").contains("${!") => [ "field path must be interpolated when format is set, otherwise each batch overwrites the file of the previous batch" ]
+}`).
+		Example("Hourly CSV Files", "Messages are written to a CSV file with a header row for every thousand messages, or every hour.", `
+output:
+  file:
+    path: '/tmp/exports/${! timestamp_unix_nano() }.csv'
+    format:
+      type: csv
+    batching:
+      count: 1000
+      period: 1h
+`)
 }
 
 type fileOutputConfig struct {
-	Path  *service.InterpolatedString
-	Codec string
+	Path   *service.InterpolatedString
+	Codec  string
+	Format *batchformat.Encoder
 }
 
 func fileOutputConfigFromParsed(pConf *service.ParsedConfig) (conf fileOutputConfig, err error) {
@@ -50,19 +69,37 @@ func fileOutputConfigFromParsed(pConf *service.ParsedConfig) (conf fileOutputCon
 	if conf.Codec, err = pConf.FieldString(fileOutputFieldCodec); err != nil {
 		return
 	}
+	if conf.Format, err = batchformat.FromParsed(pConf); err != nil {
+		return
+	}
+	if conf.Format != nil {
+		// The codec has a default, and therefore can only be rejected when it
+		// has been changed.
+		if conf.Codec != "lines" {
+			err = errors.New("field codec cannot be set with format, as formats write each batch as a whole file")
+			return
+		}
+		if _, isStatic := conf.Path.Static(); isStatic {
+			err = errors.New("field path must be interpolated when format is set, otherwise each batch overwrites the file of the previous batch")
+			return
+		}
+	}
 	return
 }
 
 func init() {
-	err := service.RegisterOutput("file", fileOutputSpec(),
-		func(pConf *service.ParsedConfig, res *service.Resources) (out service.Output, mif int, err error) {
+	err := service.RegisterBatchOutput("file", fileOutputSpec(),
+		func(pConf *service.ParsedConfig, res *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, mif int, err error) {
 			var conf fileOutputConfig
 			if conf, err = fileOutputConfigFromParsed(pConf); err != nil {
 				return
 			}
+			if batchPolicy, err = pConf.FieldBatchPolicy(fileOutputFieldBatching); err != nil {
+				return
+			}
 
 			mif = 1
-			out, err = newFileWriter(conf.Path, conf.Codec, res)
+			out, err = newFileWriter(conf.Path, conf.Codec, conf.Format, res)
 			return
 		})
 	if err != nil {
@@ -79,13 +116,14 @@ type fileWriter struct {
 	path       *service.InterpolatedString
 	suffixFn   codec.SuffixFn
 	appendMode bool
+	format     *batchformat.Encoder
 
 	handleMut  sync.Mutex
 	handlePath string
 	handle     io.WriteCloser
 }
 
-func newFileWriter(path *service.InterpolatedString, codecStr string, mgr *service.Resources) (*fileWriter, error) {
+func newFileWriter(path *service.InterpolatedString, codecStr string, format *batchformat.Encoder, mgr *service.Resources) (*fileWriter, error) {
 	codec, appendMode, err := codec.GetWriter(codecStr)
 	if err != nil {
 		return nil, err
@@ -93,6 +131,7 @@ func newFileWriter(path *service.InterpolatedString, codecStr string, mgr *servi
 	return &fileWriter{
 		suffixFn:   codec,
 		appendMode: appendMode,
+		format:     format,
 		path:       path,
 		log:        mgr.Logger(),
 		nm:         mgr,
@@ -124,7 +163,20 @@ func (w *fileWriter) writeTo(wtr io.Writer, p *service.Message) error {
 	return nil
 }
 
-func (w *fileWriter) Write(ctx context.Context, msg *service.Message) error {
+func (w *fileWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if w.format == nil {
+		return batch.WalkWithBatchedErrors(func(i int, msg *service.Message) error {
+			return w.write(msg)
+		})
+	}
+
+	// The batch is written as a whole file, and therefore replaces any file
+	// at the path.
+	msg, err := w.format.Encode(batch)
+	if err != nil {
+		return err
+	}
+
 	path, err := w.path.TryString(msg)
 	if err != nil {
 		return fmt.Errorf("path interpolation error: %w", err)
@@ -135,34 +187,75 @@ func (w *fileWriter) Write(ctx context.Context, msg *service.Message) error {
 	defer w.handleMut.Unlock()
 
 	if w.handle != nil && path == w.handlePath {
-		return w.writeTo(w.handle, msg)
-	}
-	if w.handle != nil {
 		if err := w.handle.Close(); err != nil {
 			return err
 		}
+		w.handle = nil
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	handle, err := w.openFile(path, false)
+	if err != nil {
+		return err
+	}
+	if _, err := handle.Write(mBytes); err != nil {
+		_ = handle.Close()
+		return err
 	}
+	return handle.Close()
+}
 
+func (w *fileWriter) openFile(path string, appendMode bool) (io.WriteCloser, error) {
 	flag := os.O_CREATE | os.O_RDWR
-	if w.appendMode {
+	if appendMode {
 		flag |= os.O_APPEND
 	} else {
 		flag |= os.O_TRUNC
 	}
 
 	if err := w.nm.FS().MkdirAll(filepath.Dir(path), fs.FileMode(0o777)); err != nil {
-		return err
+		return nil, err
 	}
 
 	file, err := w.nm.FS().OpenFile(path, flag, fs.FileMode(0o666))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	handle, ok := file.(io.WriteCloser)
 	if !ok {
 		_ = file.Close()
-		return errors.New("failed to open file for writing")
+		return nil, errors.New("failed to open file for writing")
+	}
+	return handle, nil
+}
+
+func (w *fileWriter) write(msg *service.Message) error {
+	path, err := w.path.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("path interpolation error: %w", err)
+	}
+	path = filepath.Clean(path)
+
+	w.handleMut.Lock()
+	defer w.handleMut.Unlock()
+
+	if w.handle != nil && path == w.handlePath {
+		return w.writeTo(w.handle, msg)
+	}
+	if w.handle != nil {
+		if err := w.handle.Close(); err != nil {
+			return err
+		}
+	}
+
+	handle, err := w.openFile(path, w.appendMode)
+	if err != nil {
+		return err
 	}
 
 	w.handlePath = path
@@ -0,0 +1,178 @@
+package io
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func fileWriterFromConf(t testing.TB, confStr string) *fileWriter {
+	t.Helper()
+
+	pConf, err := fileOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	conf, err := fileOutputConfigFromParsed(pConf)
+	require.NoError(t, err)
+
+	w, err := newFileWriter(conf.Path, conf.Codec, conf.Format, service.MockResources())
+	require.NoError(t, err)
+	return w
+}
+
+func TestFileOutputLines(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	w := fileWriterFromConf(t, `
+path: `+filepath.Join(tmpDir, `${! meta("name") }.txt`)+`
+codec: lines
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+		service.NewMessage([]byte("baz")),
+	}
+	batch[0].MetaSetMut("name", "a")
+	batch[1].MetaSetMut("name", "a")
+	batch[2].MetaSetMut("name", "b")
+
+	ctx := context.Background()
+	require.NoError(t, w.Connect(ctx))
+	require.NoError(t, w.WriteBatch(ctx, batch))
+	require.NoError(t, w.Close(ctx))
+
+	a, err := os.ReadFile(filepath.Join(tmpDir, "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo\nbar\n", string(a))
+
+	b, err := os.ReadFile(filepath.Join(tmpDir, "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "baz\n", string(b))
+}
+
+func TestFileOutputFormat(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	w := fileWriterFromConf(t, `
+path: `+filepath.Join(tmpDir, `${! meta("name") }.csv`)+`
+format:
+  type: csv
+`)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":"foo"}`)),
+		service.NewMessage([]byte(`{"id":2,"name":"bar"}`)),
+	}
+	batch[0].MetaSetMut("name", "a")
+	batch[1].MetaSetMut("name", "b")
+
+	ctx := context.Background()
+	require.NoError(t, w.Connect(ctx))
+	require.NoError(t, w.WriteBatch(ctx, batch))
+
+	// The whole batch is written to the path of the first message, and
+	// replaces the file when written again.
+	require.NoError(t, w.WriteBatch(ctx, batch))
+	require.NoError(t, w.Close(ctx))
+
+	a, err := os.ReadFile(filepath.Join(tmpDir, "a.csv"))
+	require.NoError(t, err)
+	assert.Equal(t, "id,name\n1,foo\n2,bar\n", string(a))
+
+	_, err = os.Stat(filepath.Join(tmpDir, "b.csv"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileOutputFormatConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+		errStr string
+	}{
+		{
+			name: "static path",
+			config: `
+path: /tmp/data.csv
+format:
+  type: csv
+`,
+			errStr: "field path must be interpolated when format is set, otherwise each batch overwrites the file of the previous batch",
+		},
+		{
+			name: "codec",
+			config: `
+path: /tmp/${! uuid_v4() }.csv
+codec: all-bytes
+format:
+  type: csv
+`,
+			errStr: "field codec cannot be set with format, as formats write each batch as a whole file",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := fileOutputSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = fileOutputConfigFromParsed(pConf)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
+
+func TestFileOutputFormatLint(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+		lint   string
+	}{
+		{
+			name: "interpolated path",
+			config: `
+file:
+  path: /tmp/${! uuid_v4() }.csv
+  format:
+    type: csv
+`,
+		},
+		{
+			name: "static path",
+			config: `
+file:
+  path: /tmp/data.csv
+  format:
+    type: csv
+`,
+			lint: "field path must be interpolated when format is set, otherwise each batch overwrites the file of the previous batch",
+		},
+		{
+			name: "codec",
+			config: `
+file:
+  path: /tmp/${! uuid_v4() }.csv
+  codec: all-bytes
+  format:
+    type: csv
+`,
+			lint: "field codec cannot be set with format, as formats write each batch as a whole file",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			err := service.NewStreamBuilder().AddOutputYAML(test.config)
+			if test.lint == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.lint)
+		})
+	}
+}
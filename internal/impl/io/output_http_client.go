@@ -20,6 +20,10 @@ The URL and header values of this type can be dynamically set using function int
 
 The body of the HTTP request is the raw contents of the message payload. If the message has multiple parts (is a batch) the request will be sent according to [RFC1341](https://www.w3.org/Protocols/rfc1341/7_2_Multipart.html). This behaviour can be disabled by setting the field `+"[`batch_as_multipart`](#batch_as_multipart) to `false`"+`.
 
+### Result Metadata
+
+Once a message has been sent the [result metadata](/docs/configuration/metadata#result-metadata) field `+"`http_status_code`"+` is added to it, along with any response headers matching the field `+"[`extract_headers`](#extract_headers)"+`.
+
 ### Propagating Responses
 
 It's possible to propagate the response from each HTTP request back to the input source by setting `+"`propagate_response` to `true`"+`. Only inputs that support [synchronous responses](/docs/guides/sync_responses) are able to make use of these propagated responses.`)).
@@ -30,9 +34,21 @@ It's possible to propagate the response from each HTTP request back to the input
 			service.NewBoolField("propagate_response").
 				Description("Whether responses from the server should be [propagated back](/docs/guides/sync_responses) to the input.").
 				Advanced().Default(false),
+			service.NewBoolField("stream_body").
+				Description("Whether request bodies should be streamed with chunked transfer encoding. When enabled multipart bodies are written as the request is sent rather than being buffered in memory beforehand, which bounds the memory used when sending large messages or batches. Since the length of streamed bodies is unknown some servers may reject them.").
+				Advanced().
+				Default(false).
+				Version("4.28.0"),
 			service.NewIntField("max_in_flight").
 				Description("The maximum number of parallel message batches to have in flight at any given time.").
 				Default(64),
+			service.NewBloblangField("headers_mapping").
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) executed on each message that should result in an object of headers to set, which take precedence over `headers` of the same name. When a batch is sent as a single multipart request the headers of the request are derived from the first message of the batch, and the headers of each part are derived from each message of the batch.").
+				Example(`root."X-Event-Type" = this.type`).
+				Example(`root.Authorization = "Bearer " + @tenant_token`).
+				Advanced().
+				Optional().
+				Version("4.28.0"),
 			service.NewBatchPolicyField("batching"),
 			service.NewObjectListField("multipart",
 				service.NewInterpolatedStringField("content_type").
@@ -108,6 +124,22 @@ func newHTTPClientOutputFromParsed(conf *service.ParsedConfig, mgr *service.Reso
 		opts = append(opts, httpclient.WithExplicitMultipart(parts))
 	}
 
+	if conf.Contains("headers_mapping") {
+		headersMapping, err := conf.FieldBloblang("headers_mapping")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, httpclient.WithHeadersMapping(headersMapping))
+	}
+
+	streamBody, err := conf.FieldBool("stream_body")
+	if err != nil {
+		return nil, err
+	}
+	if streamBody {
+		opts = append(opts, httpclient.WithStreamingBody())
+	}
+
 	oldHTTPConf, err := httpclient.ConfigFromParsed(conf)
 	if err != nil {
 		return nil, err
@@ -147,6 +179,14 @@ func (h *httpClientWriter) WriteBatch(ctx context.Context, msg service.MessageBa
 	}
 
 	resultMsg, err := h.client.Send(ctx, msg)
+	if err == nil && len(resultMsg) > 0 {
+		_ = resultMsg[0].MetaWalkMut(func(k string, v any) error {
+			for _, m := range msg {
+				m.SetResultMetadata(k, v)
+			}
+			return nil
+		})
+	}
 	if err == nil && h.propResponse {
 		parts := make(service.MessageBatch, len(resultMsg))
 		for i, p := range resultMsg {
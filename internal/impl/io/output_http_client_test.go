@@ -392,6 +392,48 @@ http_client:
 	require.NoError(t, h.WaitForClose(ctx))
 }
 
+func TestHTTPClientResultMetadata(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Location", "/things/1")
+		w.Header().Add("fooheader", "foovalue")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	conf := parseYAMLOutputConf(t, `
+http_client:
+  url: %v/testpost
+  extract_headers:
+    include_patterns: [ "^location$" ]
+`, ts.URL)
+
+	h, err := mock.NewManager().NewOutput(conf)
+	require.NoError(t, err)
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, h.Consume(tChan))
+
+	resultStore := transaction.NewResultStore()
+	testMsg := message.QuickBatch([][]byte{[]byte("hello world")})
+	transaction.AddResultStore(testMsg, resultStore)
+	require.NoError(t, transaction.SetAsResponse(testMsg.ShallowCopy()))
+
+	require.NoError(t, writeBatchToChan(ctx, t, testMsg, tChan))
+
+	for _, p := range []*message.Part{testMsg.Get(0), resultStore.Get()[0].Get(0)} {
+		v, _ := p.MetaGetMut("http_status_code")
+		assert.Equal(t, http.StatusCreated, v)
+		assert.Equal(t, "/things/1", p.MetaGetStr("location"))
+		assert.Equal(t, "", p.MetaGetStr("fooheader"))
+	}
+
+	h.TriggerCloseNow()
+	require.NoError(t, h.WaitForClose(ctx))
+}
+
 func TestHTTPClientMultipart(t *testing.T) {
 	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
 	defer done()
@@ -662,3 +704,82 @@ http_client:
 	h.TriggerCloseNow()
 	require.NoError(t, h.WaitForClose(ctx))
 }
+
+func TestHTTPClientStreamBody(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	resultChan := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, int64(-1), r.ContentLength)
+		assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+
+		mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			msgBytes, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			resultChan <- string(msgBytes)
+			return
+		}
+
+		mr := multipart.NewReader(r.Body, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+
+			msgBytes, err := io.ReadAll(p)
+			require.NoError(t, err)
+
+			resultChan <- string(msgBytes)
+		}
+	}))
+	defer ts.Close()
+
+	conf := parseYAMLOutputConf(t, `
+http_client:
+  url: %v/testpost
+  batch_as_multipart: true
+  stream_body: true
+`, ts.URL)
+
+	h, err := mock.NewManager().NewOutput(conf)
+	require.NoError(t, err)
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, h.Consume(tChan))
+
+	for _, batch := range [][][]byte{
+		{[]byte("PART-A"), []byte("PART-B")},
+		{[]byte("PART-C")},
+	} {
+		resChan := make(chan error)
+		select {
+		case tChan <- message.NewTransaction(message.QuickBatch(batch), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+
+		select {
+		case res := <-resChan:
+			assert.NoError(t, res)
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	for _, exp := range []string{"PART-A", "PART-B", "PART-C"} {
+		select {
+		case resMsg := <-resultChan:
+			assert.Equal(t, exp, resMsg)
+		case <-time.After(time.Second):
+			t.Fatal("Action timed out")
+		}
+	}
+
+	h.TriggerCloseNow()
+	require.NoError(t, h.WaitForClose(ctx))
+}
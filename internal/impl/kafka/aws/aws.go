@@ -2,6 +2,17 @@ package aws
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 
 	"github.com/benthosdev/benthos/v4/internal/impl/kafka"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -32,4 +43,79 @@ func init() {
 			}, nil
 		}), nil
 	}
+
+	kafka.AWSSaramaTokenProviderFromConfigFn = func(c *service.ParsedConfig) (sarama.AccessTokenProvider, error) {
+		awsConf, err := sess.GetSession(context.TODO(), c)
+		if err != nil {
+			return nil, err
+		}
+		if awsConf.Region == "" {
+			return nil, errors.New("a region must be specified for AWS_MSK_IAM authentication")
+		}
+		return &mskIAMTokenProvider{conf: awsConf}, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+const (
+	mskIAMTokenExpiry     = 15 * time.Minute
+	mskIAMSigningName     = "kafka-cluster"
+	mskIAMUserAgent       = "benthos"
+	mskIAMEmptyBodySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// mskIAMTokenProvider produces SASL OAUTHBEARER tokens for MSK clusters with
+// IAM access control enabled. Each token is a presigned kafka-cluster:Connect
+// request, as generated by the aws-msk-iam-sasl-signer libraries.
+type mskIAMTokenProvider struct {
+	conf aws.Config
+}
+
+func (m *mskIAMTokenProvider) Token() (*sarama.AccessToken, error) {
+	ctx, done := context.WithTimeout(context.Background(), 30*time.Second)
+	defer done()
+
+	tok, err := mskIAMToken(ctx, m.conf, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return &sarama.AccessToken{Token: tok}, nil
+}
+
+func mskIAMToken(ctx context.Context, conf aws.Config, now time.Time) (string, error) {
+	creds, err := conf.Credentials.Retrieve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	endpoint := &url.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("kafka.%v.amazonaws.com", conf.Region),
+		Path:   "/",
+	}
+	query := url.Values{}
+	query.Set("Action", "kafka-cluster:Connect")
+	query.Set("X-Amz-Expires", strconv.Itoa(int(mskIAMTokenExpiry.Seconds())))
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), http.NoBody)
+	if err != nil {
+		return "", err
+	}
+
+	signedURL, _, err := v4.NewSigner().PresignHTTP(ctx, creds, req, mskIAMEmptyBodySHA256, mskIAMSigningName, conf.Region, now)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	u, err := url.Parse(signedURL)
+	if err != nil {
+		return "", err
+	}
+	query = u.Query()
+	query.Set("User-Agent", mskIAMUserAgent)
+	u.RawQuery = query.Encode()
+
+	return base64.RawURLEncoding.EncodeToString([]byte(u.String())), nil
 }
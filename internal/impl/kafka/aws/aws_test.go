@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMSKIAMToken(t *testing.T) {
+	conf := aws.Config{
+		Region:      "eu-west-1",
+		Credentials: credentials.NewStaticCredentialsProvider("AKIAFOO", "barsecret", "baztoken"),
+	}
+
+	tok, err := mskIAMToken(context.Background(), conf, time.Date(2024, 4, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	urlBytes, err := base64.RawURLEncoding.DecodeString(tok)
+	require.NoError(t, err)
+
+	u, err := url.Parse(string(urlBytes))
+	require.NoError(t, err)
+
+	assert.Equal(t, "https", u.Scheme)
+	assert.Equal(t, "kafka.eu-west-1.amazonaws.com", u.Host)
+
+	query := u.Query()
+	assert.Equal(t, "kafka-cluster:Connect", query.Get("Action"))
+	assert.Equal(t, "900", query.Get("X-Amz-Expires"))
+	assert.Equal(t, "AWS4-HMAC-SHA256", query.Get("X-Amz-Algorithm"))
+	assert.Equal(t, "AKIAFOO/20240401/eu-west-1/kafka-cluster/aws4_request", query.Get("X-Amz-Credential"))
+	assert.Equal(t, "20240401T120000Z", query.Get("X-Amz-Date"))
+	assert.Equal(t, "baztoken", query.Get("X-Amz-Security-Token"))
+	assert.Equal(t, "host", query.Get("X-Amz-SignedHeaders"))
+	assert.Equal(t, "benthos", query.Get("User-Agent"))
+	assert.NotEmpty(t, query.Get("X-Amz-Signature"))
+}
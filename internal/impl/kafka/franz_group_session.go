@@ -0,0 +1,117 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// franzGroupSession tracks the membership of a kafka_franz input within a
+// consumer group whilst its offsets are committed within the transactions of
+// a kafka_franz output, which uses it in order to commit offsets with the live
+// generation of the group and to detect records of partitions that were
+// revoked since they were consumed.
+type franzGroupSession struct {
+	group      string
+	instanceID string
+
+	mut    sync.Mutex
+	client *kgo.Client
+	closed bool
+
+	// Incremented for a partition each time it is revoked or lost, records
+	// consumed with an older epoch belong to a previous assignment.
+	epochs map[string]map[int32]int64
+}
+
+func newFranzGroupSession(group, instanceID string) *franzGroupSession {
+	return &franzGroupSession{
+		group:      group,
+		instanceID: instanceID,
+		epochs:     map[string]map[int32]int64{},
+	}
+}
+
+func (s *franzGroupSession) setClient(cl *kgo.Client) {
+	s.mut.Lock()
+	s.client = cl
+	s.mut.Unlock()
+}
+
+// close marks all partitions as revoked, since a new client joins the group
+// as a new member.
+func (s *franzGroupSession) close() {
+	s.mut.Lock()
+	s.closed = true
+	s.mut.Unlock()
+}
+
+func (s *franzGroupSession) revoke(m map[string][]int32) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for topic, partitions := range m {
+		epochs, exists := s.epochs[topic]
+		if !exists {
+			epochs = map[int32]int64{}
+			s.epochs[topic] = epochs
+		}
+		for _, p := range partitions {
+			epochs[p]++
+		}
+	}
+}
+
+func (s *franzGroupSession) epoch(topic string, partition int32) int64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.epochs[topic][partition]
+}
+
+// assigned returns whether a record consumed with an epoch still belongs to
+// the current assignment of its partition.
+func (s *franzGroupSession) assigned(r *franzConsumedRecord) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return !s.closed && s.epochs[r.topic][r.partition] == r.epoch
+}
+
+// member returns the current member ID and generation of the session within
+// the group.
+func (s *franzGroupSession) member() (memberID string, generation int32, err error) {
+	s.mut.Lock()
+	cl, closed := s.client, s.closed
+	s.mut.Unlock()
+
+	if cl == nil || closed {
+		return "", 0, errors.New("consumer is not connected")
+	}
+	if memberID, generation = cl.GroupMetadata(); memberID == "" || generation < 0 {
+		return "", 0, errors.New("consumer has not joined the group")
+	}
+	return
+}
+
+// franzConsumedRecord identifies the record that a message was consumed from
+// by an input with a group session.
+type franzConsumedRecord struct {
+	session   *franzGroupSession
+	topic     string
+	partition int32
+	offset    int64
+	epoch     int64
+}
+
+type franzConsumedRecordKey struct{}
+
+func withFranzConsumedRecord(msg *service.Message, r *franzConsumedRecord) *service.Message {
+	return msg.WithContext(context.WithValue(msg.Context(), franzConsumedRecordKey{}, r))
+}
+
+func franzConsumedRecordFromMessage(msg *service.Message) (*franzConsumedRecord, bool) {
+	r, ok := msg.Context().Value(franzConsumedRecordKey{}).(*franzConsumedRecord)
+	return r, ok
+}
@@ -16,6 +16,7 @@ import (
 
 	"github.com/Jeffail/shutdown"
 
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -30,6 +31,12 @@ When a consumer group is specified this input consumes one or more topics where
 
 This input often out-performs the traditional ` + "`kafka`" + ` input as well as providing more useful logs and error messages.
 
+### Rebalancing
+
+Partitions are assigned to the members of a consumer group with the cooperative sticky balancer, which preserves existing assignments where possible and only revokes the partitions that move between members during a rebalance, allowing the remaining partitions to be consumed throughout.
+
+Rebalances caused by restarts, such as during rolling deployments, can be avoided entirely with static group membership by setting a unique and persistent ` + "[`instance_id`](#instance_id)" + ` for each consumer. A static member doesn't leave the group when it is shut down, and when it rejoins within the ` + "[`session_timeout`](#session_timeout)" + ` it resumes its previous assignments without triggering a rebalance. Static membership requires Kafka 2.3.0 or later.
+
 ### Metadata
 
 This input adds the following metadata fields to each message:
@@ -40,6 +47,7 @@ This input adds the following metadata fields to each message:
 - kafka_partition
 - kafka_offset
 - kafka_timestamp_unix
+- kafka_timestamp_ms
 - kafka_tombstone_message
 - All record headers
 ` + "```" + `
@@ -68,6 +76,17 @@ Finally, it's also possible to specify an explicit offset to consume from by add
 		Field(service.NewStringField("consumer_group").
 			Description("An optional consumer group to consume as. When specified the partitions of specified topics are automatically distributed across consumers sharing a consumer group, and partition offsets are automatically committed and resumed under this name. Consumer groups are not supported when specifying explicit partitions to consume from in the `topics` field.").
 			Optional()).
+		Field(service.NewStringField("instance_id").
+			Description("An optional identifier that makes this consumer a static member of the consumer group. Each consumer of the group must have a different instance ID, which must persist across restarts in order to avoid rebalances.").
+			Example("${HOSTNAME}").
+			Optional().
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewDurationField("session_timeout").
+			Description("The period of time after which a consumer group member is considered dead and its partitions are reassigned, when it has not sent a heartbeat. When using static membership this should be longer than the time it takes for a consumer to restart.").
+			Default("45s").
+			Advanced().
+			Version("4.28.0")).
 		Field(service.NewStringField("client_id").
 			Description("An identifier for the client connection.").
 			Default("benthos").
@@ -89,12 +108,23 @@ Finally, it's also possible to specify an explicit offset to consume from by add
 			Description("Determines whether to consume from the oldest available offset, otherwise messages are consumed from the latest offset. The setting is applied when creating a new consumer group or the saved offset no longer exists.").
 			Default(true).
 			Advanced()).
+		Field(service.NewBoolField("read_committed").
+			Description("Whether to only consume records of committed transactions, skipping those of transactions that are open or were aborted. This should be enabled when consuming topics written to by a transactional producer, such as a `kafka_franz` output with a `transactional_id`.").
+			Default(false).
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewBoolField("transactional_commits").
+			Description("Whether offsets are committed within the transactions of a `kafka_franz` output with a `transaction_consumer_group` matching the `consumer_group` of this input, in which case this input never commits offsets itself. See [exactly-once processing](/docs/components/outputs/kafka_franz#exactly-once-processing) for more information. This field requires a `consumer_group`.").
+			Default(false).
+			Advanced().
+			Version("4.28.0")).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField()).
 		Field(service.NewBoolField("multi_header").Description("Decode headers into lists to allow handling of multiple values with the same key").Default(false).Advanced()).
 		Field(service.NewBatchPolicyField("batching").
 			Description("Allows you to configure a [batching policy](/docs/configuration/batching) that applies to individual topic partitions in order to batch messages together before flushing them for processing. Batching can be beneficial for performance as well as useful for windowed processing, and doing so this way preserves the ordering of topic partitions.").
 			Advanced()).
+		Field(confluent.SchemaRegistryDecodeField(srFieldSchemaRegistry)).
 		LintRule(`
 let has_topic_partitions = this.topics.any(t -> t.contains(":"))
 root = if $has_topic_partitions {
@@ -114,7 +144,11 @@ func init() {
 			if err != nil {
 				return nil, err
 			}
-			return service.AutoRetryNacksBatchedToggled(conf, rdr)
+			d, err := schemaRegistryDecodingInput(conf, mgr, rdr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatchedToggled(conf, d)
 		})
 	if err != nil {
 		panic(err)
@@ -135,10 +169,14 @@ type franzKafkaReader struct {
 	clientID        string
 	rackID          string
 	consumerGroup   string
+	instanceID      string
+	sessionTimeout  time.Duration
 	tlsConf         *tls.Config
 	saslConfs       []sasl.Mechanism
 	checkpointLimit int
 	startFromOldest bool
+	readCommitted   bool
+	txnCommits      bool
 	commitPeriod    time.Duration
 	regexPattern    bool
 	multiHeader     bool
@@ -215,7 +253,22 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, res *service.Reso
 		return nil, err
 	}
 
-	if f.consumerGroup, err = conf.FieldString("consumer_group"); err != nil {
+	if conf.Contains("consumer_group") {
+		if f.consumerGroup, err = conf.FieldString("consumer_group"); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Contains("instance_id") {
+		if f.instanceID, err = conf.FieldString("instance_id"); err != nil {
+			return nil, err
+		}
+		if f.instanceID != "" && f.consumerGroup == "" {
+			return nil, errors.New("an instance_id can only be specified with a consumer_group")
+		}
+	}
+
+	if f.sessionTimeout, err = conf.FieldDuration("session_timeout"); err != nil {
 		return nil, err
 	}
 
@@ -241,6 +294,15 @@ func newFranzKafkaReaderFromConfig(conf *service.ParsedConfig, res *service.Reso
 	if f.multiHeader, err = conf.FieldBool("multi_header"); err != nil {
 		return nil, err
 	}
+	if f.readCommitted, err = conf.FieldBool("read_committed"); err != nil {
+		return nil, err
+	}
+	if f.txnCommits, err = conf.FieldBool("transactional_commits"); err != nil {
+		return nil, err
+	}
+	if f.txnCommits && f.consumerGroup == "" {
+		return nil, errors.New("a consumer_group must be set when transactional_commits is enabled")
+	}
 	if f.saslConfs, err = saslMechanismsFromConfig(conf); err != nil {
 		return nil, err
 	}
@@ -253,13 +315,14 @@ type msgWithRecord struct {
 	r   *kgo.Record
 }
 
-func (f *franzKafkaReader) recordToMessage(record *kgo.Record) *msgWithRecord {
+func (f *franzKafkaReader) recordToMessage(record *kgo.Record, session *franzGroupSession) *msgWithRecord {
 	msg := service.NewMessage(record.Value)
 	msg.MetaSetMut("kafka_key", string(record.Key))
 	msg.MetaSetMut("kafka_topic", record.Topic)
 	msg.MetaSetMut("kafka_partition", int(record.Partition))
 	msg.MetaSetMut("kafka_offset", int(record.Offset))
 	msg.MetaSetMut("kafka_timestamp_unix", record.Timestamp.Unix())
+	msg.MetaSetMut("kafka_timestamp_ms", record.Timestamp.UnixMilli())
 	msg.MetaSetMut("kafka_tombstone_message", record.Value == nil)
 	if f.multiHeader {
 		// in multi header mode we gather headers so we can encode them as lists
@@ -278,6 +341,16 @@ func (f *franzKafkaReader) recordToMessage(record *kgo.Record) *msgWithRecord {
 		}
 	}
 
+	if session != nil {
+		msg = withFranzConsumedRecord(msg, &franzConsumedRecord{
+			session:   session,
+			topic:     record.Topic,
+			partition: record.Partition,
+			offset:    record.Offset,
+			epoch:     session.epoch(record.Topic, record.Partition),
+		})
+	}
+
 	// The record lives on for checkpointing, but we don't need the contents
 	// going forward so discard these. This looked fine to me but could
 	// potentially be a source of problems so treat this as sus.
@@ -596,8 +669,12 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 	batchChan := make(chan batchWithAckFn)
 
 	var cl *kgo.Client
+	var session *franzGroupSession
 	commitFn := func(r *kgo.Record) {}
-	if f.consumerGroup != "" {
+	if f.txnCommits {
+		// Offsets are committed within the transactions of an output.
+		session = newFranzGroupSession(f.consumerGroup, f.instanceID)
+	} else if f.consumerGroup != "" {
 		commitFn = func(r *kgo.Record) {
 			if cl == nil {
 				return
@@ -618,7 +695,29 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 		kgo.Rack(f.rackID),
 	}
 
-	if f.consumerGroup != "" {
+	if f.readCommitted {
+		clientOpts = append(clientOpts, kgo.FetchIsolationLevel(kgo.ReadCommitted()))
+	}
+
+	if session != nil {
+		clientOpts = append(clientOpts,
+			kgo.OnPartitionsRevoked(func(rctx context.Context, _ *kgo.Client, m map[string][]int32) {
+				session.revoke(m)
+				checkpoints.removeTopicPartitions(rctx, m)
+			}),
+			kgo.OnPartitionsLost(func(rctx context.Context, _ *kgo.Client, m map[string][]int32) {
+				session.revoke(m)
+				checkpoints.removeTopicPartitions(rctx, m)
+			}),
+			kgo.DisableAutoCommit(),
+			kgo.RequireStableFetchOffsets(),
+			kgo.SessionTimeout(f.sessionTimeout),
+			kgo.WithLogger(&kgoLogger{f.log}),
+		)
+		if f.instanceID != "" {
+			clientOpts = append(clientOpts, kgo.InstanceID(f.instanceID))
+		}
+	} else if f.consumerGroup != "" {
 		clientOpts = append(clientOpts,
 			kgo.OnPartitionsRevoked(func(rctx context.Context, c *kgo.Client, m map[string][]int32) {
 				if commitErr := c.CommitMarkedOffsets(rctx); commitErr != nil {
@@ -632,8 +731,12 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 			}),
 			kgo.AutoCommitMarks(),
 			kgo.AutoCommitInterval(f.commitPeriod),
+			kgo.SessionTimeout(f.sessionTimeout),
 			kgo.WithLogger(&kgoLogger{f.log}),
 		)
+		if f.instanceID != "" {
+			clientOpts = append(clientOpts, kgo.InstanceID(f.instanceID))
+		}
 	}
 
 	if f.tlsConf != nil {
@@ -648,9 +751,15 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 	if cl, err = kgo.NewClient(clientOpts...); err != nil {
 		return err
 	}
+	if session != nil {
+		session.setClient(cl)
+	}
 
 	go func() {
 		defer func() {
+			if session != nil {
+				session.close()
+			}
 			cl.Close()
 			checkpoints.close()
 			f.storeBatchChan(nil)
@@ -708,7 +817,7 @@ func (f *franzKafkaReader) Connect(ctx context.Context) error {
 			iter := fetches.RecordIter()
 			for !iter.Done() {
 				record := iter.Next()
-				if checkpoints.addRecord(closeCtx, f.recordToMessage(record), f.checkpointLimit) {
+				if checkpoints.addRecord(closeCtx, f.recordToMessage(record, session), f.checkpointLimit) {
 					pauseTopicPartitions[record.Topic] = append(pauseTopicPartitions[record.Topic], record.Partition)
 				}
 			}
@@ -0,0 +1,61 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestFranzKafkaInputStaticMembership(t *testing.T) {
+	pConf, err := franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topics: [ foo ]
+instance_id: baz
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newFranzKafkaReaderFromConfig(pConf, service.MockResources())
+	require.EqualError(t, err, "an instance_id can only be specified with a consumer_group")
+
+	pConf, err = franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topics: [ foo ]
+consumer_group: bar
+instance_id: baz
+session_timeout: 2m
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newFranzKafkaReaderFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+	assert.Equal(t, "baz", r.instanceID)
+	assert.Equal(t, time.Minute*2, r.sessionTimeout)
+}
+
+func TestFranzKafkaInputTransactionalCommits(t *testing.T) {
+	pConf, err := franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topics: [ foo ]
+transactional_commits: true
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newFranzKafkaReaderFromConfig(pConf, service.MockResources())
+	require.EqualError(t, err, "a consumer_group must be set when transactional_commits is enabled")
+
+	pConf, err = franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topics: [ foo ]
+consumer_group: bar
+transactional_commits: true
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newFranzKafkaReaderFromConfig(pConf, service.MockResources())
+	require.NoError(t, err)
+	assert.True(t, r.txnCommits)
+}
@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/Jeffail/checkpoint"
 
 	"github.com/benthosdev/benthos/v4/internal/component/input/span"
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -31,6 +33,8 @@ const (
 	iskFieldGroupSessionTimeout           = "session_timeout"
 	iskFieldGroupSessionHeartbeatInterval = "heartbeat_interval"
 	iskFieldGroupSessionRebalanceTimeout  = "rebalance_timeout"
+	iskFieldGroupRebalanceStrategy        = "rebalance_strategy"
+	iskFieldGroupInstanceID               = "instance_id"
 	iskFieldFetchBufferCap                = "fetch_buffer_cap"
 	iskFieldMultiHeader                   = "multi_header"
 	iskFieldBatching                      = "batching"
@@ -46,6 +50,12 @@ Offsets are managed within Kafka under the specified consumer group, and partiti
 
 The Kafka input allows parallel processing of messages from different topic partitions, and messages of the same topic partition are processed with a maximum parallelism determined by the field `+"[`checkpoint_limit`](#checkpoint_limit)"+`.
 
+### Rebalancing
+
+By default partitions are assigned to the members of a consumer group with the `+"`range`"+` strategy, where every partition is revoked from every member whenever a member joins or leaves the group. The `+"[`group.rebalance_strategy`](#grouprebalance_strategy)"+` can be set to `+"`sticky`"+` in order to preserve as many existing assignments as possible, which reduces the work repeated after a rebalance, although the assignments of members are still revoked for the duration of the rebalance. Incremental cooperative rebalancing, where only the partitions that move between members are revoked, is not supported by this input, but is the default behaviour of the `+"[`kafka_franz` input](/docs/components/inputs/kafka_franz)"+`, which also supports static group membership.
+
+Rebalances caused by restarts, such as during rolling deployments, can be avoided entirely with static group membership by setting a unique and persistent `+"[`group.instance_id`](#groupinstance_id)"+` for each consumer, which requires a `+"`target_version`"+` of at least 2.3.0. A consumer with a static instance ID doesn't leave the group when it is shut down, and when it rejoins within the `+"`group.session_timeout`"+` it resumes its previous assignments without triggering a rebalance.
+
 In order to enforce ordered processing of partition messages set the `+"[`checkpoint_limit`](#checkpoint_limit) to `1`"+` and this will force partitions to be processed in lock-step, where a message will only be processed once the prior message is delivered.
 
 Batching messages before processing can be enabled using the `+"[`batching`](#batching)"+` field, and this batching is performed per-partition such that messages of a batch will always originate from the same partition. This batching mechanism is capable of creating batches of greater size than the `+"[`checkpoint_limit`](#checkpoint_limit)"+`, in which case the next batch will only be created upon delivery of the current one.
@@ -61,6 +71,7 @@ This input adds the following metadata fields to each message:
 - kafka_offset
 - kafka_lag
 - kafka_timestamp_unix
+- kafka_timestamp_ms
 - kafka_tombstone_message
 - All existing message headers (version 0.11+)
 `+"```"+`
@@ -137,6 +148,19 @@ Unfortunately this error message will appear for a wide range of connection prob
 				service.NewDurationField(iskFieldGroupSessionRebalanceTimeout).
 					Description("A period after which rebalancing is abandoned if unresolved.").
 					Default("60s"),
+				service.NewStringAnnotatedEnumField(iskFieldGroupRebalanceStrategy, map[string]string{
+					"range":      "Assigns contiguous ranges of the partitions of each topic to members.",
+					"roundrobin": "Assigns the partitions of all topics to members in turn.",
+					"sticky":     "Assigns partitions evenly across members whilst preserving as many existing assignments as possible.",
+				}).
+					Description("The strategy used to assign partitions to the members of the consumer group. All members of a group must support the strategy in use.").
+					Default("range").
+					Version("4.28.0"),
+				service.NewStringField(iskFieldGroupInstanceID).
+					Description("An optional identifier that enables static membership of the consumer group, which must be unique for each member of the group and persist across restarts. Requires a `target_version` of at least 2.3.0.").
+					Example("${HOSTNAME}").
+					Default("").
+					Version("4.28.0"),
 			).
 				Description("Tuning parameters for consumer group synchronization.").
 				Advanced(),
@@ -147,6 +171,7 @@ Unfortunately this error message will appear for a wide range of connection prob
 				Description("Decode headers into lists to allow handling of multiple values with the same key").
 				Advanced().Default(false),
 			service.NewBatchPolicyField(iskFieldBatching).Advanced(),
+			confluent.SchemaRegistryDecodeField(srFieldSchemaRegistry),
 		)
 }
 
@@ -157,7 +182,12 @@ func init() {
 			return nil, err
 		}
 
-		r, err := service.AutoRetryNacksBatchedToggled(conf, i)
+		d, err := schemaRegistryDecodingInput(conf, mgr, i)
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := service.AutoRetryNacksBatchedToggled(conf, d)
 		if err != nil {
 			return nil, err
 		}
@@ -407,6 +437,7 @@ func dataToPart(highestOffset int64, data *sarama.ConsumerMessage, multiHeader b
 	part.MetaSetMut("kafka_offset", int(data.Offset))
 	part.MetaSetMut("kafka_lag", lag)
 	part.MetaSetMut("kafka_timestamp_unix", data.Timestamp.Unix())
+	part.MetaSetMut("kafka_timestamp_ms", data.Timestamp.UnixMilli())
 	part.MetaSetMut("kafka_tombstone_message", data.Value == nil)
 
 	return part
@@ -478,6 +509,28 @@ func (k *kafkaReader) saramaConfigFromParsed(conf *service.ParsedConfig) (*saram
 		if config.Consumer.Group.Rebalance.Timeout, err = cConf.FieldDuration(iskFieldGroupSessionRebalanceTimeout); err != nil {
 			return nil, err
 		}
+
+		strategy, err := cConf.FieldString(iskFieldGroupRebalanceStrategy)
+		if err != nil {
+			return nil, err
+		}
+		switch strategy {
+		case "range":
+			config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRange()}
+		case "roundrobin":
+			config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategyRoundRobin()}
+		case "sticky":
+			config.Consumer.Group.Rebalance.GroupStrategies = []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()}
+		default:
+			return nil, fmt.Errorf("unrecognised rebalance strategy: %v", strategy)
+		}
+
+		if config.Consumer.Group.InstanceId, err = cConf.FieldString(iskFieldGroupInstanceID); err != nil {
+			return nil, err
+		}
+		if config.Consumer.Group.InstanceId != "" && !config.Version.IsAtLeast(sarama.V2_3_0_0) {
+			return nil, fmt.Errorf("a %v of at least 2.3.0 is required in order to use a group %v", iskFieldTargetVersion, iskFieldGroupInstanceID)
+		}
 	}
 	if config.ChannelBufferSize, err = conf.FieldInt(iskFieldFetchBufferCap); err != nil {
 		return nil, err
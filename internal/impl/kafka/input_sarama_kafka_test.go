@@ -1,8 +1,10 @@
 package kafka_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/gabs/v2"
 	"github.com/stretchr/testify/assert"
@@ -59,3 +61,37 @@ kafka:
 		})
 	}
 }
+
+func TestKafkaGroupInstanceIDVersion(t *testing.T) {
+	conf := parseYAMLInputConf(t, `
+kafka:
+  addresses: [ example.com:1234 ]
+  topics: [ foo ]
+  consumer_group: bar
+  group:
+    instance_id: baz
+`)
+
+	_, err := mock.NewManager().NewInput(conf)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "a target_version of at least 2.3.0 is required")
+
+	conf = parseYAMLInputConf(t, `
+kafka:
+  addresses: [ example.com:1234 ]
+  topics: [ foo ]
+  consumer_group: bar
+  target_version: 2.3.0
+  group:
+    rebalance_strategy: sticky
+    instance_id: baz
+`)
+
+	in, err := mock.NewManager().NewInput(conf)
+	require.NoError(t, err)
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	in.TriggerCloseNow()
+	require.NoError(t, in.WaitForClose(ctx))
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -210,6 +211,52 @@ input:
 			integration.StreamTestOptVarSet("VAR1", ""),
 		)
 	})
+
+	transactionalTemplate := `
+output:
+  kafka_franz:
+    seed_brokers: [ localhost:$PORT ]
+    topic: topic-$ID
+    timeout: "5s"
+    transactional_id: txn-$ID
+    metadata:
+      include_patterns: [ .* ]
+    batching:
+      count: $OUTPUT_BATCH_COUNT
+
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:$PORT ]
+    topics: [ topic-$ID$VAR1 ]
+    consumer_group: "$VAR4"
+    checkpoint_limit: 100
+    commit_period: "1s"
+    read_committed: true
+`
+	t.Run("transactional", func(t *testing.T) {
+		suite.Run(
+			t, transactionalTemplate,
+			integration.StreamTestOptPreTest(func(t testing.TB, ctx context.Context, vars *integration.StreamTestConfigVars) {
+				vars.General["VAR4"] = "group" + vars.ID
+				require.NoError(t, createKafkaTopic(ctx, "localhost:"+kafkaPortStr, vars.ID, 4))
+			}),
+			integration.StreamTestOptPort(kafkaPortStr),
+			integration.StreamTestOptVarSet("VAR1", ""),
+		)
+	})
+
+	t.Run("transactional offsets", func(t *testing.T) {
+		suite.Run(
+			t, strings.Replace(transactionalTemplate, "transactional_id: txn-$ID", `transactional_id: txn-$ID
+    transaction_consumer_group: "$VAR4"`, 1),
+			integration.StreamTestOptPreTest(func(t testing.TB, ctx context.Context, vars *integration.StreamTestConfigVars) {
+				vars.General["VAR4"] = "group" + vars.ID
+				require.NoError(t, createKafkaTopic(ctx, "localhost:"+kafkaPortStr, vars.ID, 4))
+			}),
+			integration.StreamTestOptPort(kafkaPortStr),
+			integration.StreamTestOptVarSet("VAR1", ""),
+		)
+	})
 }
 
 func createKafkaTopicSasl(address, id string, partitions int32) error {
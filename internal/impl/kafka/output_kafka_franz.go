@@ -3,6 +3,7 @@ package kafka
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
@@ -10,9 +11,12 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
 	"github.com/twmb/franz-go/pkg/sasl"
 
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
 
@@ -26,6 +30,26 @@ func franzKafkaOutputConfig() *service.ConfigSpec {
 Writes a batch of messages to Kafka brokers and waits for acknowledgement before propagating it back to the input.
 
 This output often out-performs the traditional ` + "`kafka`" + ` output as well as providing more useful logs and error messages.
+
+### Result Metadata
+
+Once a message has been acknowledged the [result metadata](/docs/configuration/metadata#result-metadata) fields ` + "`kafka_topic`, `kafka_partition` and `kafka_offset`" + ` are added to it, giving the location of the record it was written as.
+
+### Transactions
+
+When a ` + "`transactional_id`" + ` is set each batch is written within a [Kafka transaction](https://www.confluent.io/blog/transactions-apache-kafka/), which is committed once all messages of the batch have been acknowledged by the brokers and aborted otherwise. Consumers reading with an isolation level of ` + "`read_committed`" + ` therefore only ever observe complete batches, and never those of failed attempts that are subsequently retried.
+
+Since the input of a pipeline only commits its own position (such as consumer group offsets) once a batch has been committed, a pipeline with a transactional output only redelivers batches when the process terminates between the transaction being committed and the input position being committed. Transactions are performed sequentially, and therefore ` + "`max_in_flight`" + ` is ignored when a ` + "`transactional_id`" + ` is set, and the same identifier must not be used by other producers concurrently.
+
+Transactions only span the messages written by this output. In order to coordinate transactions with writes of other outputs place this output within a [` + "`two_phase_commit`" + ` output](/docs/components/outputs/two_phase_commit), which then commits the transaction of a batch only once all of its outputs have staged their writes.
+
+#### Exactly-Once Processing
+
+When consuming from a ` + "`kafka_franz`" + ` input the offsets of consumed messages can be committed within the same transaction as the messages produced from them by setting ` + "`transaction_consumer_group`" + ` to the ` + "`consumer_group`" + ` of the input, and enabling the field ` + "`transactional_commits`" + ` of the input, which then stops committing offsets itself. Since the consumed offsets and produced messages of a batch are then committed atomically, a batch that is redelivered after a failure is never observed twice by consumers reading with an isolation level of ` + "`read_committed`" + `, which should also be enabled on the input with the field ` + "`read_committed`" + `.
+
+Offsets are committed with the current member ID and generation of the input within its consumer group, and therefore the brokers reject commits of members that have since left the group. Messages consumed from partitions that were revoked from the input since they were consumed are dropped without being produced, as they are consumed again from the last committed offset by the new owner of the partition, and a transaction is aborted when a partition of its messages is revoked before it is committed.
+
+The consumed record of each message is tracked by the message itself, and messages that were not consumed by a ` + "`kafka_franz`" + ` input with ` + "`transactional_commits`" + ` enabled for the same consumer group are produced without committing offsets. Processors that combine messages (such as ` + "`archive`" + `) lose track of the records they were consumed from and should therefore be avoided.
 `).
 		Field(service.NewStringListField("seed_brokers").
 			Description("A list of broker addresses to connect to in order to establish connections. If an item of the list contains commas it will be expanded into multiple addresses.").
@@ -60,6 +84,23 @@ This output often out-performs the traditional ` + "`kafka`" + ` output as well
 			Description("Enable the idempotent write producer option. This requires the `IDEMPOTENT_WRITE` permission on `CLUSTER` and can be disabled if this permission is not available.").
 			Default(true).
 			Advanced()).
+		Field(service.NewStringField("transactional_id").
+			Description("An optional transactional identifier, when set batches are written within [transactions](#transactions). The identifier should be unique to this output and stable across restarts.").
+			Example("benthos-orders-replicator").
+			Optional().
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewDurationField("transaction_timeout").
+			Description("The maximum period of time a transaction may remain open before it is aborted by the brokers. This field is only relevant when a `transactional_id` is set.").
+			Default("40s").
+			Advanced().
+			Version("4.28.0")).
+		Field(service.NewStringField("transaction_consumer_group").
+			Description("An optional consumer group to commit the offsets of consumed messages to within each transaction, enabling [exactly-once processing](#exactly-once-processing) from a `kafka_franz` input with `transactional_commits` enabled. This field requires a `transactional_id`.").
+			Example("benthos-orders-group").
+			Optional().
+			Advanced().
+			Version("4.28.0")).
 		Field(service.NewMetadataFilterField("metadata").
 			Description("Determine which (if any) metadata values should be added to messages as headers.").
 			Optional()).
@@ -83,6 +124,7 @@ This output often out-performs the traditional ` + "`kafka`" + ` output as well
 			Advanced()).
 		Field(service.NewTLSToggledField("tls")).
 		Field(saslField()).
+		Field(confluent.SchemaRegistryEncodeField(srFieldSchemaRegistry)).
 		LintRule(`
 root = if this.partitioner == "manual" {
   if this.partition.or("") == "" {
@@ -104,10 +146,17 @@ func init() {
 			if maxInFlight, err = conf.FieldInt("max_in_flight"); err != nil {
 				return
 			}
+			if txnID, _ := conf.FieldString("transactional_id"); txnID != "" {
+				// Only one transaction can be open at a time.
+				maxInFlight = 1
+			}
 			if batchPolicy, err = conf.FieldBatchPolicy("batching"); err != nil {
 				return
 			}
-			output, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger())
+			if output, err = newFranzKafkaWriterFromConfig(conf, mgr.Logger()); err != nil {
+				return
+			}
+			output, err = schemaRegistryEncodingOutput(conf, mgr, output)
 			return
 		})
 	if err != nil {
@@ -126,6 +175,9 @@ type franzKafkaWriter struct {
 	clientID         string
 	rackID           string
 	idempotentWrite  bool
+	transactionalID  string
+	txnTimeout       time.Duration
+	txnGroup         string
 	tlsConf          *tls.Config
 	saslConfs        []sasl.Mechanism
 	metaFilter       *service.MetadataFilter
@@ -136,12 +188,17 @@ type franzKafkaWriter struct {
 
 	client *kgo.Client
 
+	// Held from the beginning of a transaction until it is ended, which for
+	// staged transactions outlives the call to WriteBatch.
+	txnSem chan struct{}
+
 	log *service.Logger
 }
 
 func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logger) (*franzKafkaWriter, error) {
 	f := franzKafkaWriter{
-		log: log,
+		txnSem: make(chan struct{}, 1),
+		log:    log,
 	}
 
 	brokerList, err := conf.FieldStringList("seed_brokers")
@@ -244,6 +301,28 @@ func newFranzKafkaWriterFromConfig(conf *service.ParsedConfig, log *service.Logg
 		return nil, err
 	}
 
+	if conf.Contains("transactional_id") {
+		if f.transactionalID, err = conf.FieldString("transactional_id"); err != nil {
+			return nil, err
+		}
+		if f.transactionalID != "" && !f.idempotentWrite {
+			return nil, errors.New("idempotent_write must be enabled when a transactional_id is set")
+		}
+	}
+
+	if f.txnTimeout, err = conf.FieldDuration("transaction_timeout"); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains("transaction_consumer_group") {
+		if f.txnGroup, err = conf.FieldString("transaction_consumer_group"); err != nil {
+			return nil, err
+		}
+		if f.txnGroup != "" && f.transactionalID == "" {
+			return nil, errors.New("a transactional_id must be set when a transaction_consumer_group is set")
+		}
+	}
+
 	if conf.Contains("metadata") {
 		if f.metaFilter, err = conf.FieldMetadataFilter("metadata"); err != nil {
 			return nil, err
@@ -293,6 +372,12 @@ func (f *franzKafkaWriter) Connect(ctx context.Context) error {
 	if len(f.compressionPrefs) > 0 {
 		clientOpts = append(clientOpts, kgo.ProducerBatchCompression(f.compressionPrefs...))
 	}
+	if f.transactionalID != "" {
+		clientOpts = append(clientOpts,
+			kgo.TransactionalID(f.transactionalID),
+			kgo.TransactionTimeout(f.txnTimeout),
+		)
+	}
 
 	cl, err := kgo.NewClient(clientOpts...)
 	if err != nil {
@@ -345,9 +430,277 @@ func (f *franzKafkaWriter) WriteBatch(ctx context.Context, b service.MessageBatc
 		records = append(records, record)
 	}
 
-	// TODO: This is very cool and allows us to easily return granular errors,
-	// so we should honor travis by doing it.
-	err = f.client.ProduceSync(ctx, records...).FirstErr()
+	var staging *service.OutputStaging
+	if len(b) > 0 {
+		staging, _ = service.OutputStagingFromContext(b[0].Context())
+	}
+
+	if f.transactionalID != "" {
+		var txnOffsets *transactionOffsets
+		if f.txnGroup != "" {
+			var fenced []bool
+			if txnOffsets, fenced, err = transactionOffsetsFromBatch(b, f.txnGroup); err != nil {
+				return err
+			}
+			var nFenced int
+			for i, isFenced := range fenced {
+				if isFenced {
+					records[i] = nil
+					nFenced++
+				}
+			}
+			if nFenced > 0 {
+				f.log.Debugf("Dropping %v messages consumed from partitions that were revoked, which are consumed again by their new owner", nFenced)
+			}
+		}
+		err = f.produceTransaction(ctx, records, txnOffsets, staging)
+	} else if staging != nil {
+		return errors.New("a transactional_id must be set in order to stage writes")
+	} else {
+		// TODO: This is very cool and allows us to easily return granular
+		// errors, so we should honor travis by doing it.
+		err = f.client.ProduceSync(ctx, records...).FirstErr()
+	}
+	if err != nil {
+		return
+	}
+
+	for i, record := range records {
+		if record == nil {
+			continue
+		}
+		b[i].SetResultMetadata("kafka_topic", record.Topic)
+		b[i].SetResultMetadata("kafka_partition", int(record.Partition))
+		b[i].SetResultMetadata("kafka_offset", int(record.Offset))
+	}
+	return
+}
+
+// produceTransaction writes records within a transaction, which is only
+// committed when all records were produced successfully, where nil records are
+// skipped. When offsets are provided they are committed to the transaction
+// consumer group within the same transaction. When a staging is provided the
+// transaction is left open and is instead committed or aborted through the
+// staging.
+func (f *franzKafkaWriter) produceTransaction(ctx context.Context, records []*kgo.Record, offsets *transactionOffsets, staging *service.OutputStaging) error {
+	produce := make([]*kgo.Record, 0, len(records))
+	for _, r := range records {
+		if r != nil {
+			produce = append(produce, r)
+		}
+	}
+	if len(produce) == 0 && offsets.empty() {
+		if staging != nil {
+			noop := func(context.Context) error { return nil }
+			staging.Stage(noop, noop)
+		}
+		return nil
+	}
+
+	select {
+	case f.txnSem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	release := func() { <-f.txnSem }
+
+	if err := f.client.BeginTransaction(); err != nil {
+		release()
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	var err error
+	if len(produce) > 0 {
+		err = f.client.ProduceSync(ctx, produce...).FirstErr()
+	}
+	if err == nil && !offsets.empty() {
+		if err = f.commitTransactionOffsets(ctx, offsets); err != nil {
+			err = fmt.Errorf("failed to commit offsets within transaction: %w", err)
+		}
+	}
+	if err != nil {
+		f.abortTransaction(ctx)
+		release()
+		return err
+	}
+
+	client := f.client
+	commit := func(ctx context.Context) error {
+		// Partitions revoked whilst the transaction was open are consumed
+		// again by their new owner.
+		if err := offsets.checkAssigned(); err != nil {
+			if abortErr := f.abortTransactionWith(ctx, client); abortErr != nil {
+				f.log.Errorf("%v", abortErr)
+			}
+			return err
+		}
+		if err := client.EndTransaction(ctx, kgo.TryCommit); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+
+	if staging != nil {
+		staging.Stage(func(ctx context.Context) error {
+			defer release()
+			return commit(ctx)
+		}, func(ctx context.Context) error {
+			defer release()
+			return f.abortTransactionWith(ctx, client)
+		})
+		return nil
+	}
+
+	defer release()
+	return commit(ctx)
+}
+
+func (f *franzKafkaWriter) abortTransaction(ctx context.Context) {
+	if err := f.abortTransactionWith(ctx, f.client); err != nil {
+		f.log.Errorf("%v", err)
+	}
+}
+
+// abortTransactionWith discards any buffered records and aborts the open
+// transaction of a client.
+func (f *franzKafkaWriter) abortTransactionWith(ctx context.Context, client *kgo.Client) error {
+	if err := client.AbortBufferedRecords(ctx); err != nil {
+		return fmt.Errorf("failed to abort buffered records: %w", err)
+	}
+	if err := client.EndTransaction(ctx, kgo.TryAbort); err != nil {
+		return fmt.Errorf("failed to abort transaction: %w", err)
+	}
+	return nil
+}
+
+// commitTransactionOffsets adds the transaction consumer group to the open
+// transaction and commits offsets to it, using the current member ID and
+// generation of the consumer in order for the brokers to fence commits of
+// members that have since left the group.
+func (f *franzKafkaWriter) commitTransactionOffsets(ctx context.Context, offsets *transactionOffsets) error {
+	memberID, generation, err := offsets.session.member()
+	if err != nil {
+		return err
+	}
+
+	producerID, producerEpoch, err := f.client.ProducerID(ctx)
+	if err != nil {
+		return err
+	}
+
+	addReq := kmsg.NewPtrAddOffsetsToTxnRequest()
+	addReq.TransactionalID = f.transactionalID
+	addReq.ProducerID = producerID
+	addReq.ProducerEpoch = producerEpoch
+	addReq.Group = f.txnGroup
+	addRes, err := addReq.RequestWith(ctx, f.client)
+	if err != nil {
+		return err
+	}
+	if err := kerr.ErrorForCode(addRes.ErrorCode); err != nil {
+		return err
+	}
+
+	commitReq := kmsg.NewPtrTxnOffsetCommitRequest()
+	commitReq.TransactionalID = f.transactionalID
+	commitReq.Group = f.txnGroup
+	commitReq.ProducerID = producerID
+	commitReq.ProducerEpoch = producerEpoch
+	commitReq.MemberID = memberID
+	commitReq.Generation = generation
+	if offsets.session.instanceID != "" {
+		commitReq.InstanceID = kmsg.StringPtr(offsets.session.instanceID)
+	}
+	for topic, partitions := range offsets.offsets {
+		reqTopic := kmsg.NewTxnOffsetCommitRequestTopic()
+		reqTopic.Topic = topic
+		for partition, offset := range partitions {
+			reqPartition := kmsg.NewTxnOffsetCommitRequestTopicPartition()
+			reqPartition.Partition = partition
+			reqPartition.Offset = offset
+			reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
+		}
+		commitReq.Topics = append(commitReq.Topics, reqTopic)
+	}
+
+	commitRes, err := commitReq.RequestWith(ctx, f.client)
+	if err != nil {
+		return err
+	}
+	for _, topic := range commitRes.Topics {
+		for _, partition := range topic.Partitions {
+			if err := kerr.ErrorForCode(partition.ErrorCode); err != nil {
+				return fmt.Errorf("topic %v partition %v: %w", topic.Topic, partition.Partition, err)
+			}
+		}
+	}
+	return nil
+}
+
+// transactionOffsets are the offsets to commit within a transaction for the
+// records that the messages of a batch were consumed from.
+type transactionOffsets struct {
+	session *franzGroupSession
+	records []*franzConsumedRecord
+	offsets map[string]map[int32]int64
+}
+
+func (t *transactionOffsets) empty() bool {
+	return t == nil || len(t.offsets) == 0
+}
+
+// checkAssigned returns an error if the partition of any record has been
+// revoked since it was consumed.
+func (t *transactionOffsets) checkAssigned() error {
+	if t == nil {
+		return nil
+	}
+	for _, r := range t.records {
+		if !t.session.assigned(r) {
+			return fmt.Errorf("topic %v partition %v was revoked during the transaction", r.topic, r.partition)
+		}
+	}
+	return nil
+}
+
+// transactionOffsetsFromBatch returns the offsets to commit for the topic
+// partitions that the messages of a batch were consumed from by a kafka_franz
+// input with transactional commits for a group, which is the offset following
+// the highest offset consumed from each. Messages that were consumed from a
+// partition that has since been revoked are marked as fenced, and are neither
+// produced nor committed. Messages that were not consumed with a session of
+// the group are ignored.
+func transactionOffsetsFromBatch(b service.MessageBatch, group string) (offsets *transactionOffsets, fenced []bool, err error) {
+	fenced = make([]bool, len(b))
+	for i, msg := range b {
+		r, ok := franzConsumedRecordFromMessage(msg)
+		if !ok || r.session.group != group {
+			continue
+		}
+		if !r.session.assigned(r) {
+			fenced[i] = true
+			continue
+		}
+
+		if offsets == nil {
+			offsets = &transactionOffsets{
+				session: r.session,
+				offsets: map[string]map[int32]int64{},
+			}
+		} else if offsets.session != r.session {
+			return nil, nil, errors.New("messages of a batch were consumed by different members of the consumer group")
+		}
+		offsets.records = append(offsets.records, r)
+
+		partitions, exists := offsets.offsets[r.topic]
+		if !exists {
+			partitions = map[int32]int64{}
+			offsets.offsets[r.topic] = partitions
+		}
+		if r.offset+1 > partitions[r.partition] {
+			partitions[r.partition] = r.offset + 1
+		}
+	}
 	return
 }
 
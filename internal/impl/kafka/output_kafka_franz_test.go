@@ -1,7 +1,9 @@
 package kafka
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -68,3 +70,150 @@ kafka_franz:
 		})
 	}
 }
+
+func TestKafkaFranzOutputTransactionalConfig(t *testing.T) {
+	pConf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+transactional_id: bar
+transaction_timeout: 20s
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "bar", w.transactionalID)
+	assert.Equal(t, time.Second*20, w.txnTimeout)
+
+	pConf, err = franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+transactional_id: bar
+idempotent_write: false
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newFranzKafkaWriterFromConfig(pConf, nil)
+	require.EqualError(t, err, "idempotent_write must be enabled when a transactional_id is set")
+
+	pConf, err = franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+transactional_id: ""
+idempotent_write: false
+`, nil)
+	require.NoError(t, err)
+
+	w, err = newFranzKafkaWriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+	assert.Empty(t, w.transactionalID)
+}
+
+func TestKafkaFranzOutputTransactionConsumerGroupConfig(t *testing.T) {
+	pConf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+transactional_id: bar
+transaction_consumer_group: baz
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "baz", w.txnGroup)
+
+	pConf, err = franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+transaction_consumer_group: baz
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newFranzKafkaWriterFromConfig(pConf, nil)
+	require.EqualError(t, err, "a transactional_id must be set when a transaction_consumer_group is set")
+}
+
+func TestKafkaFranzTransactionOffsetsFromBatch(t *testing.T) {
+	session := newFranzGroupSession("foo_group", "")
+	otherSession := newFranzGroupSession("bar_group", "")
+
+	newMsg := func(s *franzGroupSession, topic string, partition int32, offset int64) *service.Message {
+		return withFranzConsumedRecord(service.NewMessage(nil), &franzConsumedRecord{
+			session:   s,
+			topic:     topic,
+			partition: partition,
+			offset:    offset,
+			epoch:     s.epoch(topic, partition),
+		})
+	}
+
+	batch := service.MessageBatch{
+		newMsg(session, "foo", 0, 5),
+		newMsg(session, "foo", 0, 3),
+		newMsg(session, "foo", 1, 2),
+		newMsg(session, "bar", 0, 10),
+		newMsg(otherSession, "baz", 0, 20),
+		service.NewMessage(nil),
+	}
+
+	offsets, fenced, err := transactionOffsetsFromBatch(batch, "foo_group")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int32]int64{
+		"foo": {0: 6, 1: 3},
+		"bar": {0: 11},
+	}, offsets.offsets)
+	assert.Equal(t, []bool{false, false, false, false, false, false}, fenced)
+	require.NoError(t, offsets.checkAssigned())
+
+	// Messages of revoked partitions are fenced.
+	session.revoke(map[string][]int32{"foo": {0}})
+	require.Error(t, offsets.checkAssigned())
+
+	offsets, fenced, err = transactionOffsetsFromBatch(batch, "foo_group")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int32]int64{
+		"foo": {1: 3},
+		"bar": {0: 11},
+	}, offsets.offsets)
+	assert.Equal(t, []bool{true, true, false, false, false, false}, fenced)
+
+	// Messages consumed after the partition is assigned again are not.
+	offsets, fenced, err = transactionOffsetsFromBatch(service.MessageBatch{
+		newMsg(session, "foo", 0, 7),
+	}, "foo_group")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[int32]int64{"foo": {0: 8}}, offsets.offsets)
+	assert.Equal(t, []bool{false}, fenced)
+
+	// All messages are fenced once the consumer is closed.
+	session.close()
+	_, fenced, err = transactionOffsetsFromBatch(batch, "foo_group")
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, true, true, true, false, false}, fenced)
+
+	_, _, err = transactionOffsetsFromBatch(service.MessageBatch{
+		newMsg(otherSession, "foo", 0, 1),
+		newMsg(newFranzGroupSession("bar_group", ""), "foo", 1, 1),
+	}, "bar_group")
+	require.Error(t, err)
+}
+
+func TestKafkaFranzOutputStagingRequiresTransactions(t *testing.T) {
+	pConf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ foo:1234 ]
+topic: foo
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newFranzKafkaWriterFromConfig(pConf, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.Connect(context.Background()))
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+
+	staging := service.NewOutputStaging()
+	msg := service.NewMessage([]byte("foo")).WithContext(service.ContextWithOutputStaging(context.Background(), staging))
+
+	err = w.WriteBatch(context.Background(), service.MessageBatch{msg})
+	require.EqualError(t, err, "a transactional_id must be set in order to stage writes")
+	assert.Equal(t, 0, staging.Len())
+}
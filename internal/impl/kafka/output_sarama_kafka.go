@@ -16,6 +16,7 @@ import (
 
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/component/output/span"
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent"
 	"github.com/benthosdev/benthos/v4/internal/value"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -34,9 +35,15 @@ const (
 	oskFieldCustomTopicEnabled           = "enabled"
 	oskFieldCustomTopicPartitions        = "partitions"
 	oskFieldCustomTopicReplicationFactor = "replication_factor"
+	oskFieldCustomTopicConfigs           = "configs"
+	oskFieldCustomTopicAssertExisting    = "assert_existing"
 	oskFieldCompression                  = "compression"
 	oskFieldStaticHeaders                = "static_headers"
 	oskFieldMetadata                     = "metadata"
+	oskFieldPreserveSource               = "preserve_source"
+	oskFieldPreserveSourceEnabled        = "enabled"
+	oskFieldPreserveSourceHeaderPrefix   = "header_prefix"
+	oskFieldPreserveSourceTimestamp      = "timestamp"
 	oskFieldAckReplicas                  = "ack_replicas"
 	oskFieldMaxMsgBytes                  = "max_msg_bytes"
 	oskFieldTimeout                      = "timeout"
@@ -60,6 +67,14 @@ Both the `+"`key` and `topic`"+` fields can be dynamically set using function in
 
 [Metadata](/docs/configuration/metadata) will be added to each message sent as headers (version 0.11+), but can be restricted using the field `+"[`metadata`](#metadata)"+`.
 
+### Result Metadata
+
+Once a message has been acknowledged the [result metadata](/docs/configuration/metadata#result-metadata) fields `+"`kafka_topic`, `kafka_partition` and `kafka_offset`"+` are added to it, giving the location of the record it was written as.
+
+### Preserving Source Records
+
+When replicating records consumed with a `+"[`kafka` input](/docs/components/inputs/kafka) or [`kafka_franz` input](/docs/components/inputs/kafka_franz)"+` the field `+"[`preserve_source.enabled`](#preserve_sourceenabled)"+` can be set in order to produce records that retain the provenance of the originals, similar to MirrorMaker. In this mode the `+"`kafka_`"+` metadata fields added by those inputs are not sent as headers, instead the original topic, partition, offset and timestamp of each record are added as headers with the prefix `+"`preserve_source.header_prefix`"+`, the original record timestamp is kept, tombstone records remain tombstones and multiple headers of the same key (see the `+"`multi_header`"+` input field) are written back as individual headers.
+
 ### Strict Ordering and Retries
 
 When strict ordering is required for messages written to topic partitions it is important to ensure that both the field `+"`max_in_flight` is set to `1` and that the field `retry_as_batch` is set to `true`"+`.
@@ -115,7 +130,16 @@ Unfortunately this error message will appear for a wide range of connection prob
 				service.NewIntField(oskFieldCustomTopicReplicationFactor).
 					Description("The replication factor to use for new topics. Leave at -1 to use the broker configured default. Must be an odd number, and less then or equal to the number of brokers.").
 					Default(-1),
-			).Description("If enabled, topics will be created with the specified number of partitions and replication factor if they do not already exist.").
+				service.NewStringMapField(oskFieldCustomTopicConfigs).
+					Description("A map of [topic configs](https://kafka.apache.org/documentation/#topicconfigs) to set on new topics.").
+					Example(map[string]any{"cleanup.policy": "compact", "retention.ms": "86400000"}).
+					Default(map[string]any{}).
+					Version("4.28.0"),
+				service.NewBoolField(oskFieldCustomTopicAssertExisting).
+					Description("Whether topics that already exist should be checked against the configured partitions, replication factor and configs, in which case a mismatch results in an error rather than messages being written to the topic.").
+					Default(false).
+					Version("4.28.0"),
+			).Description("If enabled, topics will be created with the specified number of partitions, replication factor and configs if they do not already exist. When the `topic` field is static the topic is created, or checked when `assert_existing` is enabled, upon connecting, and therefore a misconfigured cluster is reported before any messages are consumed.").
 				Advanced().Optional(),
 			service.NewStringEnumField(oskFieldCompression, "none", "snappy", "lz4", "gzip", "zstd").
 				Description("The compression algorithm to use.").
@@ -126,6 +150,20 @@ Unfortunately this error message will appear for a wide range of connection prob
 				Optional(),
 			service.NewMetadataExcludeFilterField(oskFieldMetadata).
 				Description("Specify criteria for which metadata values are sent with messages as headers."),
+			service.NewObjectField(oskFieldPreserveSource,
+				service.NewBoolField(oskFieldPreserveSourceEnabled).
+					Description("Whether to preserve the source topic, partition, offset, timestamp and headers of records consumed from Kafka.").
+					Default(false),
+				service.NewStringField(oskFieldPreserveSourceHeaderPrefix).
+					Description("A prefix to add to the headers `topic`, `partition`, `offset` and `timestamp` that describe the source record. Set this to an empty string in order to omit these headers.").
+					Default("source_"),
+				service.NewBoolField(oskFieldPreserveSourceTimestamp).
+					Description("Whether to produce records with the timestamp of the source record rather than the time at which they are sent.").
+					Default(true),
+			).
+				Description("Preserve the provenance of records consumed from Kafka when writing them, which is useful for replication and fan-out pipelines. Read more about this mode [in this section](#preserving-source-records).").
+				Version("4.28.0").
+				Advanced(),
 			span.InjectTracingSpanMappingDocs(),
 			service.NewOutputMaxInFlightField(),
 			service.NewBoolField(oskFieldIdempotentWrite).
@@ -153,6 +191,7 @@ Unfortunately this error message will appear for a wide range of connection prob
 				MaxInterval:     time.Second * 10,
 				MaxElapsedTime:  time.Second * 30,
 			}).Description("Control time intervals between retry attempts.").Advanced(),
+			confluent.SchemaRegistryEncodeField(srFieldSchemaRegistry),
 		)
 }
 
@@ -170,6 +209,10 @@ func init() {
 			return
 		}
 
+		if o, err = schemaRegistryEncodingOutput(conf, mgr, o); err != nil {
+			return
+		}
+
 		o, err = span.NewBatchOutput("kafka", conf, o, mgr)
 		return
 	})
@@ -189,9 +232,15 @@ type kafkaWriter struct {
 	metaFilter    *service.MetadataExcludeFilter
 	retryAsBatch  bool
 
+	preserveSource       bool
+	preserveHeaderPrefix string
+	preserveTimestamp    bool
+
 	customTopicCreation bool
 	customTopicParts    int
 	customTopicRepls    int
+	customTopicConfigs  map[string]*string
+	customTopicAssert   bool
 
 	mgr         *service.Resources
 	backoffCtor func() backoff.BackOff
@@ -233,6 +282,19 @@ func NewKafkaWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources
 		return nil, err
 	}
 
+	{
+		pConf := conf.Namespace(oskFieldPreserveSource)
+		if k.preserveSource, err = pConf.FieldBool(oskFieldPreserveSourceEnabled); err != nil {
+			return nil, err
+		}
+		if k.preserveHeaderPrefix, err = pConf.FieldString(oskFieldPreserveSourceHeaderPrefix); err != nil {
+			return nil, err
+		}
+		if k.preserveTimestamp, err = pConf.FieldBool(oskFieldPreserveSourceTimestamp); err != nil {
+			return nil, err
+		}
+	}
+
 	if k.key, err = conf.FieldInterpolatedString(oskFieldKey); err != nil {
 		return nil, err
 	}
@@ -277,6 +339,20 @@ func NewKafkaWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources
 		if k.customTopicRepls, err = cConf.FieldInt(oskFieldCustomTopicReplicationFactor); err != nil {
 			return nil, err
 		}
+		topicConfigs, err := cConf.FieldStringMap(oskFieldCustomTopicConfigs)
+		if err != nil {
+			return nil, err
+		}
+		if len(topicConfigs) > 0 {
+			k.customTopicConfigs = make(map[string]*string, len(topicConfigs))
+			for key, value := range topicConfigs {
+				value := value
+				k.customTopicConfigs[key] = &value
+			}
+		}
+		if k.customTopicAssert, err = cConf.FieldBool(oskFieldCustomTopicAssertExisting); err != nil {
+			return nil, err
+		}
 	}
 
 	if k.customTopicCreation {
@@ -343,6 +419,9 @@ func strToPartitioner(str string) (sarama.PartitionerConstructor, error) {
 
 func (k *kafkaWriter) buildSystemHeaders(part *service.Message) []sarama.RecordHeader {
 	if k.saramConf.Version.IsAtLeast(sarama.V0_11_0_0) {
+		if k.preserveSource {
+			return k.buildPreservedHeaders(part)
+		}
 		out := []sarama.RecordHeader{}
 		_ = k.metaFilter.Walk(part, func(k, v string) error {
 			out = append(out, sarama.RecordHeader{
@@ -360,6 +439,88 @@ func (k *kafkaWriter) buildSystemHeaders(part *service.Message) []sarama.RecordH
 
 //------------------------------------------------------------------------------
 
+// kafkaInputMetaKeys are the metadata keys added to messages by the kafka
+// inputs, which describe the source record rather than being headers of it.
+var kafkaInputMetaKeys = map[string]struct{}{
+	"kafka_key":               {},
+	"kafka_topic":             {},
+	"kafka_partition":         {},
+	"kafka_offset":            {},
+	"kafka_lag":               {},
+	"kafka_timestamp_unix":    {},
+	"kafka_timestamp_ms":      {},
+	"kafka_tombstone_message": {},
+}
+
+func (k *kafkaWriter) buildPreservedHeaders(part *service.Message) []sarama.RecordHeader {
+	out := []sarama.RecordHeader{}
+	_ = k.metaFilter.WalkMut(part, func(key string, v any) error {
+		if _, isInputMeta := kafkaInputMetaKeys[key]; isInputMeta {
+			return nil
+		}
+		if vs, isList := v.([]any); isList {
+			for _, lv := range vs {
+				out = append(out, sarama.RecordHeader{
+					Key:   []byte(key),
+					Value: []byte(value.IToString(lv)),
+				})
+			}
+			return nil
+		}
+		out = append(out, sarama.RecordHeader{
+			Key:   []byte(key),
+			Value: []byte(value.IToString(v)),
+		})
+		return nil
+	})
+
+	if k.preserveHeaderPrefix == "" {
+		return out
+	}
+	for _, f := range []string{"topic", "partition", "offset"} {
+		if v, exists := part.MetaGetMut("kafka_" + f); exists {
+			out = append(out, sarama.RecordHeader{
+				Key:   []byte(k.preserveHeaderPrefix + f),
+				Value: []byte(value.IToString(v)),
+			})
+		}
+	}
+	if ts, exists := sourceTimestamp(part); exists {
+		out = append(out, sarama.RecordHeader{
+			Key:   []byte(k.preserveHeaderPrefix + "timestamp"),
+			Value: []byte(strconv.FormatInt(ts.UnixMilli(), 10)),
+		})
+	}
+	return out
+}
+
+// sourceTimestamp attempts to obtain the timestamp of the record a message was
+// consumed from, preferring the millisecond precision metadata field.
+func sourceTimestamp(part *service.Message) (time.Time, bool) {
+	if v, exists := part.MetaGetMut("kafka_timestamp_ms"); exists {
+		if ms, err := value.IGetInt(v); err == nil {
+			return time.UnixMilli(ms), true
+		}
+	}
+	if v, exists := part.MetaGetMut("kafka_timestamp_unix"); exists {
+		if secs, err := value.IGetInt(v); err == nil {
+			return time.Unix(secs, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isSourceTombstone(part *service.Message) bool {
+	v, exists := part.MetaGetMut("kafka_tombstone_message")
+	if !exists {
+		return false
+	}
+	b, err := value.IGetBool(v)
+	return err == nil && b
+}
+
+//------------------------------------------------------------------------------
+
 func (k *kafkaWriter) buildUserDefinedHeaders(staticHeaders map[string]string) []sarama.RecordHeader {
 	if k.saramConf.Version.IsAtLeast(sarama.V0_11_0_0) {
 		out := make([]sarama.RecordHeader, 0, len(staticHeaders))
@@ -463,6 +624,14 @@ func (k *kafkaWriter) Connect(ctx context.Context) error {
 		return nil
 	}
 
+	if k.customTopicCreation {
+		if topic, isStatic := k.topic.Static(); isStatic {
+			if err := k.createTopic(topic); err != nil {
+				return fmt.Errorf("failed to create topic '%v': %w", topic, err)
+			}
+		}
+	}
+
 	var err error
 	k.producer, err = sarama.NewSyncProducer(k.addresses, k.saramConf)
 	return err
@@ -512,6 +681,16 @@ func (k *kafkaWriter) WriteBatch(ctx context.Context, msg service.MessageBatch)
 		if len(key) > 0 {
 			nextMsg.Key = sarama.ByteEncoder(key)
 		}
+		if k.preserveSource {
+			if k.preserveTimestamp {
+				if ts, exists := sourceTimestamp(msg[i]); exists {
+					nextMsg.Timestamp = ts
+				}
+			}
+			if isSourceTombstone(msg[i]) {
+				nextMsg.Value = nil
+			}
+		}
 
 		// Only parse and set the partition if we are configured for manual
 		// partitioner.  Although samara will (currently) ignore the partition
@@ -539,6 +718,7 @@ func (k *kafkaWriter) WriteBatch(ctx context.Context, msg service.MessageBatch)
 		msgs = append(msgs, nextMsg)
 	}
 
+	sent := msgs
 	err := producer.SendMessages(msgs)
 	for err != nil {
 		if pErrs, ok := err.(sarama.ProducerErrors); !k.retryAsBatch && ok {
@@ -587,6 +767,15 @@ func (k *kafkaWriter) WriteBatch(ctx context.Context, msg service.MessageBatch)
 		err = producer.SendMessages(msgs)
 	}
 
+	// The producer sets the partition and offset of each message once it has
+	// been acknowledged.
+	for _, m := range sent {
+		if i, ok := m.Metadata.(int); ok {
+			msg[i].SetResultMetadata("kafka_topic", m.Topic)
+			msg[i].SetResultMetadata("kafka_partition", int(m.Partition))
+			msg[i].SetResultMetadata("kafka_offset", int(m.Offset))
+		}
+	}
 	return nil
 }
 
@@ -703,38 +892,82 @@ func (mur *murmur2) Sum32() uint32 {
 //------------------------------------------------------------------------------
 
 // createTopic creates a topic in the Kafka cluster if it does not already
-// exist.
+// exist, or checks that the existing topic matches the configured partitions,
+// replication factor and configs when k.customTopicAssert is set.
 //
-// If k.conf.PartitionsPerNewTopic is set to a value greater than 0, then the
-// topic will be created with that number of partitions.
+// If k.customTopicParts is set to a value greater than 0, then the topic will
+// be created with that number of partitions.
 func (k *kafkaWriter) createTopic(topic string) error {
-	if exists, err := k.checkIfTopicExists(topic); err != nil {
+	if initialized, ok := k.topicCache.Load(topic); ok && initialized.(bool) {
+		return nil
+	}
+
+	detail, err := k.checkIfTopicExists(topic)
+	if err != nil {
 		return err
-	} else if exists {
+	}
+	if detail != nil {
+		if k.customTopicAssert {
+			if err := k.assertTopic(topic, detail); err != nil {
+				return err
+			}
+		}
+		k.topicCache.Store(topic, true)
 		return nil
 	}
 
-	k.topicCache.Store(topic, false)
 	topicDetail := sarama.TopicDetail{
 		NumPartitions:     int32(k.customTopicParts),
 		ReplicationFactor: int16(k.customTopicRepls),
+		ConfigEntries:     k.customTopicConfigs,
 	}
 	return k.admin.CreateTopic(topic, &topicDetail, false)
 }
 
-// checkIfTopicExists checks if a topic exists in the Kafka cluster.
-func (k *kafkaWriter) checkIfTopicExists(topic string) (exists bool, err error) {
-	initialized, ok := k.topicCache.Load(topic)
-	if ok && initialized.(bool) {
-		return true, nil
+// checkIfTopicExists checks if a topic exists in the Kafka cluster, returning
+// its details if so.
+func (k *kafkaWriter) checkIfTopicExists(topic string) (*sarama.TopicDetail, error) {
+	topics, err := k.admin.ListTopics()
+	if err != nil {
+		return nil, err
 	}
 
-	var topics map[string]sarama.TopicDetail
-	if topics, err = k.admin.ListTopics(); err != nil {
-		return
+	detail, exists := topics[topic]
+	if !exists {
+		return nil, nil
 	}
+	return &detail, nil
+}
 
-	_, exists = topics[topic]
-	k.topicCache.Store(topic, exists)
-	return
+// assertTopic returns an error if an existing topic does not match the
+// configured partitions, replication factor and configs. Configs that are not
+// configured are not checked.
+func (k *kafkaWriter) assertTopic(topic string, detail *sarama.TopicDetail) error {
+	if k.customTopicParts != -1 && int(detail.NumPartitions) != k.customTopicParts {
+		return fmt.Errorf("topic has %v partitions, expected %v", detail.NumPartitions, k.customTopicParts)
+	}
+	if k.customTopicRepls != -1 && int(detail.ReplicationFactor) != k.customTopicRepls {
+		return fmt.Errorf("topic has a replication factor of %v, expected %v", detail.ReplicationFactor, k.customTopicRepls)
+	}
+	if len(k.customTopicConfigs) == 0 {
+		return nil
+	}
+
+	entries, err := k.admin.DescribeConfig(sarama.ConfigResource{
+		Type: sarama.TopicResource,
+		Name: topic,
+	})
+	if err != nil {
+		return err
+	}
+	actual := make(map[string]string, len(entries))
+	for _, e := range entries {
+		actual[e.Name] = e.Value
+	}
+	for key, value := range k.customTopicConfigs {
+		if actualValue, exists := actual[key]; !exists || actualValue != *value {
+			return fmt.Errorf("topic config %v is '%v', expected '%v'", key, actualValue, *value)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,145 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestKafkaOutputPreservedHeaders(t *testing.T) {
+	spec := service.NewConfigSpec().Field(service.NewMetadataExcludeFilterField(oskFieldMetadata))
+	pConf, err := spec.ParseYAML(`
+metadata:
+  exclude_prefixes: [ secret_ ]
+`, nil)
+	require.NoError(t, err)
+
+	metaFilter, err := pConf.FieldMetadataExcludeFilter(oskFieldMetadata)
+	require.NoError(t, err)
+
+	k := &kafkaWriter{
+		saramConf:            sarama.NewConfig(),
+		metaFilter:           metaFilter,
+		preserveSource:       true,
+		preserveHeaderPrefix: "mirror_",
+		preserveTimestamp:    true,
+	}
+	k.saramConf.Version = sarama.V2_1_0_0
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("kafka_key", "foo")
+	msg.MetaSetMut("kafka_topic", "bar")
+	msg.MetaSetMut("kafka_partition", 3)
+	msg.MetaSetMut("kafka_offset", 42)
+	msg.MetaSetMut("kafka_lag", 10)
+	msg.MetaSetMut("kafka_timestamp_unix", int64(1700000000))
+	msg.MetaSetMut("kafka_timestamp_ms", int64(1700000000123))
+	msg.MetaSetMut("kafka_tombstone_message", false)
+	msg.MetaSetMut("secret_thing", "nope")
+	msg.MetaSetMut("trace", []any{"a", "b"})
+
+	headers := map[string][]string{}
+	for _, h := range k.buildSystemHeaders(msg) {
+		headers[string(h.Key)] = append(headers[string(h.Key)], string(h.Value))
+	}
+	assert.Equal(t, map[string][]string{
+		"trace":            {"a", "b"},
+		"mirror_topic":     {"bar"},
+		"mirror_partition": {"3"},
+		"mirror_offset":    {"42"},
+		"mirror_timestamp": {"1700000000123"},
+	}, headers)
+
+	ts, exists := sourceTimestamp(msg)
+	require.True(t, exists)
+	assert.Equal(t, time.UnixMilli(1700000000123), ts)
+	assert.False(t, isSourceTombstone(msg))
+
+	k.preserveHeaderPrefix = ""
+	assert.Equal(t, []sarama.RecordHeader{
+		{Key: []byte("trace"), Value: []byte("a")},
+		{Key: []byte("trace"), Value: []byte("b")},
+	}, k.buildSystemHeaders(msg))
+}
+
+func TestKafkaOutputSourceTimestampFallback(t *testing.T) {
+	msg := service.NewMessage(nil)
+	_, exists := sourceTimestamp(msg)
+	assert.False(t, exists)
+
+	msg.MetaSetMut("kafka_timestamp_unix", int64(1700000000))
+	msg.MetaSetMut("kafka_tombstone_message", true)
+
+	ts, exists := sourceTimestamp(msg)
+	require.True(t, exists)
+	assert.Equal(t, time.Unix(1700000000, 0), ts)
+	assert.True(t, isSourceTombstone(msg))
+}
+
+type fakeClusterAdmin struct {
+	sarama.ClusterAdmin
+
+	topics  map[string]sarama.TopicDetail
+	configs map[string][]sarama.ConfigEntry
+	created map[string]sarama.TopicDetail
+}
+
+func (f *fakeClusterAdmin) ListTopics() (map[string]sarama.TopicDetail, error) {
+	return f.topics, nil
+}
+
+func (f *fakeClusterAdmin) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
+	return f.configs[resource.Name], nil
+}
+
+func (f *fakeClusterAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	f.created[topic] = *detail
+	return nil
+}
+
+func TestKafkaOutputCustomTopicCreation(t *testing.T) {
+	retention := "1000"
+	admin := &fakeClusterAdmin{
+		topics: map[string]sarama.TopicDetail{
+			"good": {NumPartitions: 3, ReplicationFactor: 1},
+			"bad":  {NumPartitions: 5, ReplicationFactor: 1},
+			"meh":  {NumPartitions: 3, ReplicationFactor: 1},
+		},
+		configs: map[string][]sarama.ConfigEntry{
+			"good": {{Name: "retention.ms", Value: "1000"}, {Name: "cleanup.policy", Value: "delete"}},
+			"meh":  {{Name: "retention.ms", Value: "2000"}},
+		},
+		created: map[string]sarama.TopicDetail{},
+	}
+
+	k := &kafkaWriter{
+		admin:               admin,
+		customTopicCreation: true,
+		customTopicParts:    3,
+		customTopicRepls:    1,
+		customTopicConfigs:  map[string]*string{"retention.ms": &retention},
+	}
+
+	// Without assertions existing topics are left alone.
+	require.NoError(t, k.createTopic("bad"))
+
+	k.customTopicAssert = true
+	require.NoError(t, k.createTopic("good"))
+	require.EqualError(t, k.createTopic("meh"), "topic config retention.ms is '2000', expected '1000'")
+	require.NoError(t, k.createTopic("new"))
+	assert.Equal(t, map[string]sarama.TopicDetail{
+		"new": {
+			NumPartitions:     3,
+			ReplicationFactor: 1,
+			ConfigEntries:     map[string]*string{"retention.ms": &retention},
+		},
+	}, admin.created)
+
+	k.topicCache.Delete("bad")
+	require.EqualError(t, k.createTopic("bad"), "topic has 5 partitions, expected 3")
+}
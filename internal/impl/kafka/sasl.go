@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 
 	"github.com/IBM/sarama"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
 
 	"github.com/benthosdev/benthos/v4/internal/impl/aws/config"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -20,9 +23,17 @@ func notImportedAWSFn(c *service.ParsedConfig) (sasl.Mechanism, error) {
 	return nil, errors.New("unable to configure AWS SASL as this binary does not import components/aws")
 }
 
+func notImportedAWSTokenProviderFn(c *service.ParsedConfig) (sarama.AccessTokenProvider, error) {
+	return nil, errors.New("unable to configure AWS SASL as this binary does not import components/aws")
+}
+
 // AWSSASLFromConfigFn is populated with the child `aws` package when imported.
 var AWSSASLFromConfigFn = notImportedAWSFn
 
+// AWSSaramaTokenProviderFromConfigFn is populated with the child `aws` package
+// when imported.
+var AWSSaramaTokenProviderFromConfigFn = notImportedAWSTokenProviderFn
+
 func saslField() *service.ConfigField {
 	return service.NewObjectListField("sasl",
 		service.NewStringAnnotatedEnumField("mechanism", map[string]string{
@@ -196,6 +207,22 @@ const (
 	saramaFieldSASLAccessToken = "access_token"
 	saramaFieldSASLTokenCache  = "token_cache"
 	saramaFieldSASLTokenKey    = "token_key"
+	saramaFieldSASLAWS         = "aws"
+
+	saramaFieldSASLOAuth2               = "oauth2"
+	saramaFieldSASLOAuth2Enabled        = "enabled"
+	saramaFieldSASLOAuth2ClientKey      = "client_key"
+	saramaFieldSASLOAuth2ClientSecret   = "client_secret"
+	saramaFieldSASLOAuth2TokenURL       = "token_url"
+	saramaFieldSASLOAuth2Scopes         = "scopes"
+	saramaFieldSASLOAuth2EndpointParams = "endpoint_params"
+
+	saramaFieldSASLKerberos                = "kerberos"
+	saramaFieldSASLKerberosServiceName     = "service_name"
+	saramaFieldSASLKerberosRealm           = "realm"
+	saramaFieldSASLKerberosConfigPath      = "config_path"
+	saramaFieldSASLKerberosKeyTabPath      = "keytab_path"
+	saramaFieldSASLKerberosDisablePAFXFAST = "disable_pa_fx_fast"
 )
 
 // SaramaSASLField returns a field spec definition for SASL within the sarama
@@ -209,15 +236,17 @@ func SaramaSASLField() *service.ConfigField {
 				"OAUTHBEARER":   "OAuth Bearer based authentication.",
 				"SCRAM-SHA-256": "Authentication using the SCRAM-SHA-256 mechanism.",
 				"SCRAM-SHA-512": "Authentication using the SCRAM-SHA-512 mechanism.",
+				"GSSAPI":        "Kerberos based authentication, configured with the `kerberos` field.",
+				"AWS_MSK_IAM":   "AWS IAM based authentication as specified by the 'aws-msk-iam-auth' java library, configured with the `aws` field.",
 			}).
 			Description("The SASL authentication mechanism, if left empty SASL authentication is not used.").
 			Default("none"),
 		service.NewStringField(saramaFieldSASLUser).
-			Description("A PLAIN username. It is recommended that you use environment variables to populate this field.").
+			Description("A PLAIN, SCRAM or GSSAPI username. It is recommended that you use environment variables to populate this field.").
 			Example("${USER}").
 			Default(""),
 		service.NewStringField(saramaFieldSASLPassword).
-			Description("A PLAIN password. It is recommended that you use environment variables to populate this field.").
+			Description("A PLAIN, SCRAM or GSSAPI password. It is recommended that you use environment variables to populate this field.").
 			Example("${PASSWORD}").
 			Default("").
 			Secret(),
@@ -230,6 +259,50 @@ func SaramaSASLField() *service.ConfigField {
 		service.NewStringField(saramaFieldSASLTokenKey).
 			Description("Required when using a `token_cache`, the key to query the cache with for tokens.").
 			Default(""),
+		service.NewObjectField(saramaFieldSASLOAuth2,
+			service.NewBoolField(saramaFieldSASLOAuth2Enabled).
+				Description("Whether to obtain OAUTHBEARER tokens using the OAuth2 client credentials flow, which takes precedence over `access_token` and `token_cache`.").
+				Default(false),
+			service.NewStringField(saramaFieldSASLOAuth2ClientKey).
+				Description("A value used to identify the client to the token provider.").
+				Default(""),
+			service.NewStringField(saramaFieldSASLOAuth2ClientSecret).
+				Description("A secret used to establish ownership of the client key.").
+				Default("").Secret(),
+			service.NewURLField(saramaFieldSASLOAuth2TokenURL).
+				Description("The URL of the token provider.").
+				Default(""),
+			service.NewStringListField(saramaFieldSASLOAuth2Scopes).
+				Description("A list of optional requested permissions.").
+				Default([]any{}),
+			service.NewStringMapField(saramaFieldSASLOAuth2EndpointParams).
+				Description("Optional parameters to add to token requests.").
+				Example(map[string]any{"audience": "kafka"}).
+				Default(map[string]any{}),
+		).
+			Description("Allows OAUTHBEARER tokens to be obtained from an OpenID Connect or OAuth2 token provider using the client credentials flow. Tokens are cached and refreshed before they expire."),
+		service.NewObjectField(saramaFieldSASLKerberos,
+			service.NewStringField(saramaFieldSASLKerberosServiceName).
+				Description("The Kerberos service name of the brokers.").
+				Default("kafka"),
+			service.NewStringField(saramaFieldSASLKerberosRealm).
+				Description("The Kerberos realm to authenticate with.").
+				Example("EXAMPLE.COM").
+				Default(""),
+			service.NewStringField(saramaFieldSASLKerberosConfigPath).
+				Description("The path of a Kerberos configuration file.").
+				Default("/etc/krb5.conf"),
+			service.NewStringField(saramaFieldSASLKerberosKeyTabPath).
+				Description("The path of a keytab file to authenticate with. When empty the `user` and `password` fields are used instead.").
+				Default(""),
+			service.NewBoolField(saramaFieldSASLKerberosDisablePAFXFAST).
+				Description("Whether to disable the Kerberos PA-FX-FAST pre-authentication mechanism, which some KDCs such as Active Directory do not support.").
+				Default(false),
+		).
+			Description("Contains Kerberos specific fields for when the `mechanism` is set to `GSSAPI`."),
+		service.NewObjectField(saramaFieldSASLAWS, config.SessionFields()...).
+			Description("Contains AWS specific fields for when the `mechanism` is set to `AWS_MSK_IAM`.").
+			Optional(),
 	).
 		Description("Enables SASL authentication.").
 		Optional().
@@ -276,7 +349,16 @@ func ApplySaramaSASLFromParsed(pConf *service.ParsedConfig, mgr *service.Resourc
 		var tp sarama.AccessTokenProvider
 		var err error
 
-		if tokenCache != "" {
+		oauthEnabled, err := pConf.FieldBool(saramaFieldSASLOAuth2, saramaFieldSASLOAuth2Enabled)
+		if err != nil {
+			return err
+		}
+
+		if oauthEnabled {
+			if tp, err = newOAuth2AccessTokenProvider(pConf.Namespace(saramaFieldSASLOAuth2)); err != nil {
+				return err
+			}
+		} else if tokenCache != "" {
 			if tp, err = newCacheAccessTokenProvider(mgr, tokenCache, tokenKey); err != nil {
 				return err
 			}
@@ -301,6 +383,20 @@ func ApplySaramaSASLFromParsed(pConf *service.ParsedConfig, mgr *service.Resourc
 	case sarama.SASLTypePlaintext:
 		conf.Net.SASL.User = username
 		conf.Net.SASL.Password = password
+	case sarama.SASLTypeGSSAPI:
+		if err := applySaramaKerberos(pConf.Namespace(saramaFieldSASLKerberos), username, password, conf); err != nil {
+			return err
+		}
+	case "AWS_MSK_IAM":
+		if !pConf.Contains(saramaFieldSASLAWS) {
+			return errors.New("the aws field must be set when the mechanism is AWS_MSK_IAM")
+		}
+		tp, err := AWSSaramaTokenProviderFromConfigFn(pConf.Namespace(saramaFieldSASLAWS))
+		if err != nil {
+			return err
+		}
+		conf.Net.SASL.TokenProvider = tp
+		mechanism = sarama.SASLTypeOAuth
 	case "", "none":
 		return nil
 	default:
@@ -313,6 +409,88 @@ func ApplySaramaSASLFromParsed(pConf *service.ParsedConfig, mgr *service.Resourc
 	return nil
 }
 
+func applySaramaKerberos(pConf *service.ParsedConfig, username, password string, conf *sarama.Config) error {
+	gConf := &conf.Net.SASL.GSSAPI
+
+	var err error
+	if gConf.ServiceName, err = pConf.FieldString(saramaFieldSASLKerberosServiceName); err != nil {
+		return err
+	}
+	if gConf.Realm, err = pConf.FieldString(saramaFieldSASLKerberosRealm); err != nil {
+		return err
+	}
+	if gConf.KerberosConfigPath, err = pConf.FieldString(saramaFieldSASLKerberosConfigPath); err != nil {
+		return err
+	}
+	if gConf.KeyTabPath, err = pConf.FieldString(saramaFieldSASLKerberosKeyTabPath); err != nil {
+		return err
+	}
+	if gConf.DisablePAFXFAST, err = pConf.FieldBool(saramaFieldSASLKerberosDisablePAFXFAST); err != nil {
+		return err
+	}
+
+	gConf.Username = username
+	if gConf.KeyTabPath != "" {
+		gConf.AuthType = sarama.KRB5_KEYTAB_AUTH
+	} else {
+		gConf.AuthType = sarama.KRB5_USER_AUTH
+		gConf.Password = password
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// oauth2AccessTokenProvider fetches SASL OAUTHBEARER access tokens from an
+// OAuth2 token provider using the client credentials flow.
+type oauth2AccessTokenProvider struct {
+	source oauth2.TokenSource
+}
+
+func newOAuth2AccessTokenProvider(pConf *service.ParsedConfig) (*oauth2AccessTokenProvider, error) {
+	cConf := &clientcredentials.Config{}
+
+	var err error
+	if cConf.ClientID, err = pConf.FieldString(saramaFieldSASLOAuth2ClientKey); err != nil {
+		return nil, err
+	}
+	if cConf.ClientSecret, err = pConf.FieldString(saramaFieldSASLOAuth2ClientSecret); err != nil {
+		return nil, err
+	}
+	if cConf.TokenURL, err = pConf.FieldString(saramaFieldSASLOAuth2TokenURL); err != nil {
+		return nil, err
+	}
+	if cConf.TokenURL == "" {
+		return nil, errors.New("a token_url must be specified when oauth2 is enabled")
+	}
+	if cConf.Scopes, err = pConf.FieldStringList(saramaFieldSASLOAuth2Scopes); err != nil {
+		return nil, err
+	}
+
+	params, err := pConf.FieldStringMap(saramaFieldSASLOAuth2EndpointParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) > 0 {
+		cConf.EndpointParams = url.Values{}
+		for k, v := range params {
+			cConf.EndpointParams.Set(k, v)
+		}
+	}
+
+	return &oauth2AccessTokenProvider{
+		source: cConf.TokenSource(context.Background()),
+	}, nil
+}
+
+func (o *oauth2AccessTokenProvider) Token() (*sarama.AccessToken, error) {
+	tok, err := o.source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain OAuth2 token: %w", err)
+	}
+	return &sarama.AccessToken{Token: tok.AccessToken}, nil
+}
+
 //------------------------------------------------------------------------------
 
 // cacheAccessTokenProvider fetches SASL OAUTHBEARER access tokens from a cache.
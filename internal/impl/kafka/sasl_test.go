@@ -2,9 +2,12 @@ package kafka_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/internal/impl/kafka"
@@ -125,6 +128,99 @@ sasl:
 	}
 }
 
+func TestApplyOAuthBearerClientCredentialsProvider(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+		assert.Equal(t, "kafka", r.PostForm.Get("audience"))
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "foo", user)
+		assert.Equal(t, "bar", pass)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"baz","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer ts.Close()
+
+	saslConf := service.NewConfigSpec().Field(kafka.SaramaSASLField())
+	pConf, err := saslConf.ParseYAML(`
+sasl:
+  mechanism: OAUTHBEARER
+  oauth2:
+    enabled: true
+    client_key: foo
+    client_secret: bar
+    token_url: `+ts.URL+`
+    endpoint_params:
+      audience: kafka
+`, nil)
+	require.NoError(t, err)
+
+	conf := &sarama.Config{}
+	require.NoError(t, kafka.ApplySaramaSASLFromParsed(pConf, service.MockResources(), conf))
+
+	assert.True(t, conf.Net.SASL.Enable)
+	assert.Equal(t, sarama.SASLTypeOAuth, string(conf.Net.SASL.Mechanism))
+
+	token, err := conf.Net.SASL.TokenProvider.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "baz", token.Token)
+}
+
+func TestApplyGSSAPI(t *testing.T) {
+	saslConf := service.NewConfigSpec().Field(kafka.SaramaSASLField())
+	pConf, err := saslConf.ParseYAML(`
+sasl:
+  mechanism: GSSAPI
+  user: foo
+  password: bar
+  kerberos:
+    realm: EXAMPLE.COM
+`, nil)
+	require.NoError(t, err)
+
+	conf := &sarama.Config{}
+	require.NoError(t, kafka.ApplySaramaSASLFromParsed(pConf, service.MockResources(), conf))
+
+	assert.True(t, conf.Net.SASL.Enable)
+	assert.Equal(t, sarama.SASLTypeGSSAPI, string(conf.Net.SASL.Mechanism))
+	assert.Equal(t, sarama.GSSAPIConfig{
+		AuthType:           sarama.KRB5_USER_AUTH,
+		KerberosConfigPath: "/etc/krb5.conf",
+		ServiceName:        "kafka",
+		Username:           "foo",
+		Password:           "bar",
+		Realm:              "EXAMPLE.COM",
+	}, conf.Net.SASL.GSSAPI)
+
+	pConf, err = saslConf.ParseYAML(`
+sasl:
+  mechanism: GSSAPI
+  user: foo
+  password: bar
+  kerberos:
+    service_name: brokers
+    realm: EXAMPLE.COM
+    config_path: /tmp/krb5.conf
+    keytab_path: /tmp/foo.keytab
+    disable_pa_fx_fast: true
+`, nil)
+	require.NoError(t, err)
+
+	conf = &sarama.Config{}
+	require.NoError(t, kafka.ApplySaramaSASLFromParsed(pConf, service.MockResources(), conf))
+
+	assert.Equal(t, sarama.GSSAPIConfig{
+		AuthType:           sarama.KRB5_KEYTAB_AUTH,
+		KeyTabPath:         "/tmp/foo.keytab",
+		KerberosConfigPath: "/tmp/krb5.conf",
+		ServiceName:        "brokers",
+		Username:           "foo",
+		Realm:              "EXAMPLE.COM",
+		DisablePAFXFAST:    true,
+	}, conf.Net.SASL.GSSAPI)
+}
+
 func TestApplyUnknownMechanism(t *testing.T) {
 	saslConf := service.NewConfigSpec().Field(kafka.SaramaSASLField())
 	pConf, err := saslConf.ParseYAML(`
@@ -0,0 +1,144 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/internal/impl/confluent"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	srFieldSchemaRegistry = "schema_registry"
+)
+
+// srDecodingInput decodes the messages of batches consumed by a child input
+// from the Confluent Schema Registry wire format.
+type srDecodingInput struct {
+	service.BatchInput
+	decoder *confluent.SchemaRegistryDecoder
+}
+
+// schemaRegistryDecodingInput wraps a Kafka input with a schema registry
+// decoder when the schema_registry field is configured.
+func schemaRegistryDecodingInput(conf *service.ParsedConfig, mgr *service.Resources, rdr service.BatchInput) (service.BatchInput, error) {
+	if !conf.Contains(srFieldSchemaRegistry) {
+		return rdr, nil
+	}
+	decoder, err := confluent.NewSchemaRegistryDecoderFromParsed(conf.Namespace(srFieldSchemaRegistry), mgr)
+	if err != nil {
+		return nil, err
+	}
+	return &srDecodingInput{BatchInput: rdr, decoder: decoder}, nil
+}
+
+func (s *srDecodingInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	batch, ackFn, err := s.BatchInput.ReadBatch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.decoder.DecodeBatch(ctx, batch)
+	return batch, ackFn, nil
+}
+
+func (s *srDecodingInput) Close(ctx context.Context) error {
+	_ = s.decoder.Close(ctx)
+	return s.BatchInput.Close(ctx)
+}
+
+//------------------------------------------------------------------------------
+
+// srEncodingOutput encodes the messages of batches into the Confluent Schema
+// Registry wire format before they are written by a child output.
+type srEncodingOutput struct {
+	service.BatchOutput
+	encoder *confluent.SchemaRegistryEncoder
+	topic   *service.InterpolatedString
+}
+
+// schemaRegistryEncodingOutput wraps a Kafka output with a schema registry
+// encoder when the schema_registry field is configured.
+func schemaRegistryEncodingOutput(conf *service.ParsedConfig, mgr *service.Resources, out service.BatchOutput) (service.BatchOutput, error) {
+	if !conf.Contains(srFieldSchemaRegistry) {
+		return out, nil
+	}
+	topic, err := conf.FieldInterpolatedString("topic")
+	if err != nil {
+		return nil, err
+	}
+	encoder, err := confluent.NewSchemaRegistryEncoderFromParsed(conf.Namespace(srFieldSchemaRegistry), mgr)
+	if err != nil {
+		return nil, err
+	}
+	return &srEncodingOutput{BatchOutput: out, encoder: encoder, topic: topic}, nil
+}
+
+// WriteBatch writes the messages of a batch that are successfully encoded, and
+// rejects the remaining messages with their encoding errors.
+func (s *srEncodingOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var batchErr *service.BatchError
+	fail := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	encoded := make(service.MessageBatch, 0, len(batch))
+	indexes := make([]int, 0, len(batch))
+	for i := range batch {
+		topic, err := batch.TryInterpolatedString(i, s.topic)
+		if err != nil {
+			fail(i, fmt.Errorf("topic interpolation error: %w", err))
+			continue
+		}
+		subject, err := s.encoder.Subject(batch, i, topic)
+		if err != nil {
+			fail(i, err)
+			continue
+		}
+		msg := batch[i].Copy()
+		if err := s.encoder.Encode(msg, subject); err != nil {
+			fail(i, fmt.Errorf("failed to encode message with subject %v: %w", subject, err))
+			continue
+		}
+		encoded = append(encoded, msg)
+		indexes = append(indexes, i)
+	}
+
+	if len(encoded) > 0 {
+		indexer := encoded.Index()
+		if err := s.BatchOutput.WriteBatch(ctx, encoded); err != nil {
+			var wErr *service.BatchError
+			isBatchErr := errors.As(err, &wErr) && wErr.IndexedErrors() > 0
+			if batchErr == nil && !isBatchErr {
+				// Errors such as service.ErrNotConnected are returned as they
+				// are when all messages were encoded.
+				return err
+			}
+			if isBatchErr {
+				wErr.WalkMessagesIndexedBy(indexer, func(j int, _ *service.Message, mErr error) bool {
+					if mErr != nil && j >= 0 && j < len(indexes) {
+						fail(indexes[j], mErr)
+					}
+					return true
+				})
+			} else {
+				for _, i := range indexes {
+					fail(i, err)
+				}
+			}
+		}
+	}
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (s *srEncodingOutput) Close(ctx context.Context) error {
+	_ = s.encoder.Close(ctx)
+	return s.BatchOutput.Close(ctx)
+}
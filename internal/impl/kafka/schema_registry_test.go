@@ -0,0 +1,167 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const srTestSchema = `{"schema":"{\"type\":\"object\",\"properties\":{\"id\":{\"type\":\"string\"}},\"required\":[\"id\"]}","schemaType":"JSON","id":7}`
+
+func runSRTestServer(t testing.TB) string {
+	t.Helper()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/subjects/orders-value/versions/latest", "/schemas/ids/7":
+			_, _ = w.Write([]byte(srTestSchema))
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+type srTestOutput struct {
+	written service.MessageBatch
+	err     error
+}
+
+func (o *srTestOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (o *srTestOutput) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	if o.err != nil {
+		return o.err
+	}
+	o.written = append(o.written, b...)
+	return nil
+}
+
+func (o *srTestOutput) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestSchemaRegistryEncodingOutput(t *testing.T) {
+	urlStr := runSRTestServer(t)
+
+	pConf, err := franzKafkaOutputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topic: ${! @topic }
+schema_registry:
+  url: `+urlStr+`
+`, nil)
+	require.NoError(t, err)
+
+	inner := &srTestOutput{}
+	out, err := schemaRegistryEncodingOutput(pConf, service.MockResources(), inner)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = out.Close(context.Background())
+	})
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a"}`)),
+		service.NewMessage([]byte(`{"name":"b"}`)),
+		service.NewMessage([]byte(`{"id":"c"}`)),
+		service.NewMessage([]byte(`{"id":"d"}`)),
+	}
+	for i, topic := range []string{"orders", "orders", "orders", "unknown"} {
+		batch[i].MetaSetMut("topic", topic)
+	}
+
+	indexer := batch.Index()
+	err = out.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessagesIndexedBy(indexer, func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+		}
+		return true
+	})
+	assert.ElementsMatch(t, []int{1, 3}, failed)
+
+	require.Len(t, inner.written, 2)
+	for i, exp := range []string{`{"id":"a"}`, `{"id":"c"}`} {
+		mBytes, err := inner.written[i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, "\x00\x00\x00\x00\x07"+exp, string(mBytes))
+	}
+
+	// The original messages are unchanged.
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":"a"}`, string(mBytes))
+
+	// Errors of the child output are returned unchanged when every message
+	// was encoded.
+	inner.err = service.ErrNotConnected
+	err = out.WriteBatch(context.Background(), batch[:1])
+	assert.ErrorIs(t, err, service.ErrNotConnected)
+}
+
+type srTestInput struct {
+	batch service.MessageBatch
+}
+
+func (i *srTestInput) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (i *srTestInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	return i.batch, func(context.Context, error) error { return nil }, nil
+}
+
+func (i *srTestInput) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestSchemaRegistryDecodingInput(t *testing.T) {
+	urlStr := runSRTestServer(t)
+
+	pConf, err := franzKafkaInputConfig().ParseYAML(`
+seed_brokers: [ localhost:9092 ]
+topics: [ orders ]
+schema_registry:
+  url: `+urlStr+`
+`, nil)
+	require.NoError(t, err)
+
+	inner := &srTestInput{batch: service.MessageBatch{
+		service.NewMessage([]byte("\x00\x00\x00\x00\x07{\"id\":\"a\"}")),
+		service.NewMessage([]byte("\x00\x00")),
+	}}
+	in, err := schemaRegistryDecodingInput(pConf, service.MockResources(), inner)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = in.Close(context.Background())
+	})
+
+	batch, _, err := in.ReadBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"a"}`, string(mBytes))
+	assert.NoError(t, batch[0].GetError())
+
+	mBytes, err = batch[1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "\x00\x00", string(mBytes))
+	assert.Error(t, batch[1].GetError())
+}
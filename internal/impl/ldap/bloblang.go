@@ -0,0 +1,27 @@
+package ldap
+
+import (
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func init() {
+	if err := bloblang.RegisterMethodV2("escape_ldap_filter",
+		bloblang.NewPluginSpec().
+			Category(query.MethodCategoryStrings).
+			Version("4.28.0").
+			Description("Escapes a string so that it can be used as a value within an LDAP search filter, as described in [RFC 4515](https://datatracker.ietf.org/doc/html/rfc4515).").
+			Example("", `root.filter = "(sAMAccountName=%s)".format(this.user.escape_ldap_filter())`, [2]string{
+				`{"user":"j*doe)(cn=*"}`,
+				`{"filter":"(sAMAccountName=j\\2adoe\\29\\28cn=\\2a)"}`,
+			}),
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			return bloblang.StringMethod(func(s string) (any, error) {
+				return ldap.EscapeFilter(s), nil
+			}), nil
+		}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,160 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-ldap/ldap/v3"
+
+	btls "github.com/benthosdev/benthos/v4/internal/tls"
+)
+
+type connConfig struct {
+	url          *url.URL
+	tlsConf      *tls.Config
+	startTLS     bool
+	bindDN       string
+	bindPassword string
+}
+
+// setTimeout limits the period that the next operation on a connection waits
+// for a response to the deadline of a context.
+func setTimeout(ctx context.Context, c *ldap.Conn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetTimeout(time.Until(deadline))
+	}
+}
+
+// dial establishes a connection to a directory server, upgrading it with
+// StartTLS when configured, and binds it when a bind DN or password is set.
+// Connections perform operations synchronously, and therefore must not be used
+// concurrently.
+func dial(ctx context.Context, conf connConfig) (*ldap.Conn, error) {
+	tlsConf := conf.tlsConf
+	if tlsConf == nil {
+		tlsConf = btls.PolicyConfig()
+	}
+	if tlsConf.ServerName == "" {
+		tlsConf = tlsConf.Clone()
+		tlsConf.ServerName = conf.url.Hostname()
+	}
+
+	d := &net.Dialer{}
+	d.Deadline, _ = ctx.Deadline()
+	c, err := ldap.DialURL(conf.url.String(), ldap.DialWithDialer(d), ldap.DialWithTLSConfig(tlsConf))
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.url.Scheme == "ldap" && conf.startTLS {
+		setTimeout(ctx, c)
+		if err = c.StartTLS(tlsConf); err != nil {
+			err = fmt.Errorf("start tls: %w", err)
+		}
+	}
+	if err == nil && (conf.bindDN != "" || conf.bindPassword != "") {
+		setTimeout(ctx, c)
+		if err = c.Bind(conf.bindDN, conf.bindPassword); err != nil {
+			err = fmt.Errorf("bind: %w", err)
+		}
+	}
+	if err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// isResultError returns whether an error is the result of an operation that
+// the server responded to, as opposed to a failure of the connection.
+func isResultError(err error) bool {
+	var lErr *ldap.Error
+	return errors.As(err, &lErr) && lErr.ResultCode != ldap.ErrorNetwork
+}
+
+//------------------------------------------------------------------------------
+
+type searchRequest struct {
+	baseDN     string
+	scope      int
+	filter     string
+	attributes []string
+	sizeLimit  int
+	pageSize   int
+}
+
+// search performs a search, following pages of results when paging is
+// enabled, and returns the entries found.
+func search(ctx context.Context, c *ldap.Conn, req searchRequest) ([]any, error) {
+	var paging *ldap.ControlPaging
+	if req.pageSize > 0 {
+		paging = ldap.NewControlPaging(uint32(req.pageSize))
+	}
+
+	entries := []any{}
+	for {
+		sizeLimit := req.sizeLimit
+		if sizeLimit > 0 {
+			sizeLimit -= len(entries)
+		}
+
+		sr := ldap.NewSearchRequest(req.baseDN, req.scope, ldap.NeverDerefAliases, sizeLimit, 0, false, req.filter, req.attributes, nil)
+		if paging != nil {
+			sr.Controls = []ldap.Control{paging}
+		}
+
+		setTimeout(ctx, c)
+		res, err := c.Search(sr)
+		if res != nil {
+			for _, e := range res.Entries {
+				if req.sizeLimit == 0 || len(entries) < req.sizeLimit {
+					entries = append(entries, structuredEntry(e))
+				}
+			}
+		}
+		if err != nil {
+			if ldap.IsErrorAnyOf(err, ldap.LDAPResultSizeLimitExceeded, ldap.LDAPResultNoSuchObject) {
+				return entries, nil
+			}
+			return nil, err
+		}
+		if paging == nil || (req.sizeLimit > 0 && len(entries) >= req.sizeLimit) {
+			return entries, nil
+		}
+
+		resPaging, _ := ldap.FindControl(res.Controls, ldap.ControlTypePaging).(*ldap.ControlPaging)
+		if resPaging == nil || len(resPaging.Cookie) == 0 {
+			return entries, nil
+		}
+		paging.SetCookie(resPaging.Cookie)
+	}
+}
+
+// structuredEntry converts a search result entry into a structured object with
+// the fields dn and attributes, where attribute values that aren't valid UTF-8
+// are base64 encoded.
+func structuredEntry(e *ldap.Entry) map[string]any {
+	attrs := make(map[string]any, len(e.Attributes))
+	for _, a := range e.Attributes {
+		values := make([]any, 0, len(a.ByteValues))
+		for _, v := range a.ByteValues {
+			if utf8.Valid(v) {
+				values = append(values, string(v))
+			} else {
+				values = append(values, base64.StdEncoding.EncodeToString(v))
+			}
+		}
+		attrs[a.Name] = values
+	}
+	return map[string]any{
+		"dn":         e.DN,
+		"attributes": attrs,
+	}
+}
@@ -0,0 +1,375 @@
+package ldap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lpFieldURL          = "url"
+	lpFieldTLS          = "tls"
+	lpFieldStartTLS     = "start_tls"
+	lpFieldBindDN       = "bind_dn"
+	lpFieldBindPassword = "bind_password"
+	lpFieldBaseDN       = "base_dn"
+	lpFieldScope        = "scope"
+	lpFieldFilter       = "filter"
+	lpFieldAttributes   = "attributes"
+	lpFieldSizeLimit    = "size_limit"
+	lpFieldPageSize     = "page_size"
+	lpFieldCache        = "cache"
+	lpFieldCacheTTL     = "cache_ttl"
+	lpFieldTimeout      = "timeout"
+)
+
+var searchScopes = map[string]int{
+	"base": ldap.ScopeBaseObject,
+	"one":  ldap.ScopeSingleLevel,
+	"sub":  ldap.ScopeWholeSubtree,
+}
+
+func processorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Integration").
+		Version("4.28.0").
+		Summary("Searches an LDAP directory, such as Active Directory, for entries matching a filter derived from each message.").
+		Description(`
+The search filter is constructed for each message with the `+"`filter`"+` mapping, which must result in a string filter as described in [RFC 4515](https://datatracker.ietf.org/doc/html/rfc4515), such as `+"`(&(objectClass=user)(sAMAccountName=jdoe))`"+`. Values taken from messages should be escaped with the `+"[`escape_ldap_filter`](/docs/guides/bloblang/methods#escape_ldap_filter)"+` method in order to prevent them from altering the filter.
+
+The contents of each message are replaced with an array of the entries found, where each entry is an object containing the fields `+"`dn`"+` and `+"`attributes`"+`. Each attribute is an array of values, where values that aren't valid UTF-8, such as `+"`objectGUID`"+` and `+"`objectSid`"+`, are base64 encoded. In order to enrich messages with these results use a `+"[`branch` processor](/docs/components/processors/branch)"+`.
+
+Searches return all results by requesting pages of `+"`page_size`"+` entries using the paged results control, which is required in order to retrieve more entries than the size limit of the server, and stop once `+"`size_limit`"+` entries have been found. A search of a base DN that does not exist results in an empty array.
+
+### Caching
+
+When a `+"`cache`"+` is configured the results of each search are stored within it, keyed by the scope, base DN and filter of the search, and searches with results present in the cache are not sent to the server. The cache should therefore not be shared with `+"`ldap`"+` processors that select different attributes.
+
+### Connections
+
+A single connection is established and bound with the `+"`bind_dn`"+` and `+"`bind_password`"+` when set, or anonymously otherwise. Connections are encrypted when the URL scheme is `+"`ldaps`"+`, or when `+"`start_tls`"+` is enabled. Searches are performed one at a time over the connection, and when a search fails due to a broken connection it is reconnected and the search is reattempted once.`).
+		Fields(
+			service.NewURLField(lpFieldURL).
+				Description("The URL of the directory server, where the scheme `ldaps` establishes a TLS connection.").
+				Examples("ldap://localhost:389", "ldaps://dc1.example.com"),
+			service.NewTLSField(lpFieldTLS).
+				Description("Custom TLS settings used for `ldaps` and StartTLS connections."),
+			service.NewBoolField(lpFieldStartTLS).
+				Description("Whether to upgrade `ldap` connections to TLS with the StartTLS operation.").
+				Default(false),
+			service.NewStringField(lpFieldBindDN).
+				Description("The DN to bind as, which can also be a user principal name when binding to Active Directory. When empty the connection is bound anonymously.").
+				Examples("cn=benthos,ou=services,dc=example,dc=com", "benthos@example.com").
+				Default(""),
+			service.NewStringField(lpFieldBindPassword).
+				Description("The password of the bind DN.").
+				Secret().
+				Default(""),
+			service.NewInterpolatedStringField(lpFieldBaseDN).
+				Description("The DN of the entry at which to start searches.").
+				Example("dc=example,dc=com"),
+			service.NewStringEnumField(lpFieldScope, "base", "one", "sub").
+				Description("The scope of searches, where `base` searches only the base entry, `one` searches its immediate children and `sub` searches the entire subtree.").
+				Default("sub"),
+			service.NewBloblangField(lpFieldFilter).
+				Description("A [Bloblang mapping](/docs/guides/bloblang/about) that results in the search filter for each message.").
+				Examples(
+					`root = "(&(objectClass=user)(sAMAccountName=%s))".format(this.user.escape_ldap_filter())`,
+					`root = "(&(objectClass=computer)(dNSHostName=%s))".format(this.host.escape_ldap_filter())`,
+				),
+			service.NewStringListField(lpFieldAttributes).
+				Description("The attributes to return for each entry, where an empty list returns all user attributes.").
+				Example([]string{"cn", "mail", "memberOf"}).
+				Default([]string{}),
+			service.NewIntField(lpFieldSizeLimit).
+				Description("The maximum number of entries to return for each search, where `0` returns all entries.").
+				Default(0),
+			service.NewIntField(lpFieldPageSize).
+				Description("The number of entries to request for each page of results, where `0` disables paging.").
+				Default(500).
+				Advanced(),
+			service.NewStringField(lpFieldCache).
+				Description("An optional [cache resource](/docs/components/caches/about) to store the results of searches within.").
+				Optional(),
+			service.NewDurationField(lpFieldCacheTTL).
+				Description("An optional expiry period to set for cached results. Some caches only have a general TTL and will therefore ignore this setting.").
+				Example("10m").
+				Optional(),
+			service.NewDurationField(lpFieldTimeout).
+				Description("The maximum period to wait for each search, including establishing a connection.").
+				Default("10s").
+				Advanced(),
+		).
+		Example("Enrich Authentication Logs", "Authentication events are enriched with the display name, department and group memberships of the user from Active Directory, with results cached for ten minutes.", `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - ldap:
+              url: ldaps://dc1.example.com
+              bind_dn: benthos@example.com
+              bind_password: ${LDAP_PASSWORD}
+              base_dn: dc=example,dc=com
+              filter: |
+                root = "(&(objectCategory=person)(sAMAccountName=%s))".format(this.user.escape_ldap_filter())
+              attributes: [ displayName, department, memberOf ]
+              size_limit: 1
+              cache: ldap_cache
+              cache_ttl: 10m
+        result_map: |
+          root.user_info.name = this.0.attributes.displayName.0 | null
+          root.user_info.department = this.0.attributes.department.0 | null
+          root.user_info.groups = this.0.attributes.memberOf | []
+
+cache_resources:
+  - label: ldap_cache
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor("ldap", processorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newLDAPProcessorFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ldapProcessor struct {
+	connConf   connConfig
+	baseDN     *service.InterpolatedString
+	scope      int
+	filter     *bloblang.Executor
+	attributes []string
+	sizeLimit  int
+	pageSize   int
+	cache      string
+	cacheTTL   *time.Duration
+	timeout    time.Duration
+
+	connMut sync.Mutex
+	conn    *ldap.Conn
+
+	mgr *service.Resources
+	log *service.Logger
+}
+
+func newLDAPProcessorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*ldapProcessor, error) {
+	l := &ldapProcessor{
+		mgr: mgr,
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if l.connConf.url, err = conf.FieldURL(lpFieldURL); err != nil {
+		return nil, err
+	}
+	if s := l.connConf.url.Scheme; s != "ldap" && s != "ldaps" {
+		return nil, fmt.Errorf("unsupported url scheme '%v', expected ldap or ldaps", s)
+	}
+	if l.connConf.tlsConf, err = conf.FieldTLS(lpFieldTLS); err != nil {
+		return nil, err
+	}
+	if l.connConf.startTLS, err = conf.FieldBool(lpFieldStartTLS); err != nil {
+		return nil, err
+	}
+	if l.connConf.bindDN, err = conf.FieldString(lpFieldBindDN); err != nil {
+		return nil, err
+	}
+	if l.connConf.bindPassword, err = conf.FieldString(lpFieldBindPassword); err != nil {
+		return nil, err
+	}
+
+	if l.baseDN, err = conf.FieldInterpolatedString(lpFieldBaseDN); err != nil {
+		return nil, err
+	}
+	scopeStr, err := conf.FieldString(lpFieldScope)
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	if l.scope, exists = searchScopes[scopeStr]; !exists {
+		return nil, fmt.Errorf("unrecognised scope '%v'", scopeStr)
+	}
+	if l.filter, err = conf.FieldBloblang(lpFieldFilter); err != nil {
+		return nil, err
+	}
+	if l.attributes, err = conf.FieldStringList(lpFieldAttributes); err != nil {
+		return nil, err
+	}
+	if l.sizeLimit, err = conf.FieldInt(lpFieldSizeLimit); err != nil {
+		return nil, err
+	}
+	if l.pageSize, err = conf.FieldInt(lpFieldPageSize); err != nil {
+		return nil, err
+	}
+	if l.sizeLimit < 0 || l.pageSize < 0 {
+		return nil, errors.New("size_limit and page_size must not be negative")
+	}
+
+	if conf.Contains(lpFieldCache) {
+		if l.cache, err = conf.FieldString(lpFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(l.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", l.cache)
+		}
+	}
+	if conf.Contains(lpFieldCacheTTL) {
+		ttl, err := conf.FieldDuration(lpFieldCacheTTL)
+		if err != nil {
+			return nil, err
+		}
+		l.cacheTTL = &ttl
+	}
+	if l.timeout, err = conf.FieldDuration(lpFieldTimeout); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *ldapProcessor) searchRequest(msg *service.Message) (searchRequest, string, error) {
+	req := searchRequest{
+		scope:      l.scope,
+		attributes: l.attributes,
+		sizeLimit:  l.sizeLimit,
+		pageSize:   l.pageSize,
+	}
+
+	var err error
+	if req.baseDN, err = l.baseDN.TryString(msg); err != nil {
+		return req, "", fmt.Errorf("base_dn interpolation: %w", err)
+	}
+
+	filterMsg, err := msg.BloblangQuery(l.filter)
+	if err != nil {
+		return req, "", fmt.Errorf("filter mapping failed: %w", err)
+	}
+	if filterMsg == nil {
+		return req, "", errors.New("filter mapping resulted in a deleted message")
+	}
+	filterBytes, err := filterMsg.AsBytes()
+	if err != nil {
+		return req, "", err
+	}
+	if req.filter = strings.TrimSpace(string(filterBytes)); req.filter == "" {
+		return req, "", errors.New("filter is empty")
+	}
+	if req.filter[0] != '(' {
+		req.filter = "(" + req.filter + ")"
+	}
+	if _, err := ldap.CompileFilter(req.filter); err != nil {
+		return req, "", fmt.Errorf("invalid filter '%v': %w", req.filter, err)
+	}
+	return req, req.filter, nil
+}
+
+func (l *ldapProcessor) search(ctx context.Context, req searchRequest) ([]any, error) {
+	l.connMut.Lock()
+	defer l.connMut.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		reused := l.conn != nil
+		if !reused {
+			c, err := dial(ctx, l.connConf)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect: %w", err)
+			}
+			l.conn = c
+		}
+
+		entries, err := search(ctx, l.conn, req)
+		if err == nil {
+			return entries, nil
+		}
+		if isResultError(err) {
+			return nil, err
+		}
+
+		// The connection is in an unknown state after any other error, which
+		// is often the result of the server closing idle connections.
+		_ = l.conn.Close()
+		l.conn = nil
+		if !reused || attempt > 0 || ctx.Err() != nil {
+			return nil, err
+		}
+		l.log.Debugf("Search failed, reconnecting: %v", err)
+	}
+}
+
+func (l *ldapProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	req, filter, err := l.searchRequest(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheKey := fmt.Sprintf("%v:%v:%v", req.scope, req.baseDN, filter)
+	if l.cache != "" {
+		var cached []byte
+		var cErr error
+		if err := l.mgr.AccessCache(ctx, l.cache, func(c service.Cache) {
+			cached, cErr = c.Get(ctx, cacheKey)
+		}); err != nil {
+			return nil, err
+		}
+		if cErr == nil {
+			var entries []any
+			if err := json.Unmarshal(cached, &entries); err != nil {
+				return nil, fmt.Errorf("failed to parse cached result: %w", err)
+			}
+			msg.SetStructuredMut(entries)
+			return service.MessageBatch{msg}, nil
+		}
+		if !errors.Is(cErr, service.ErrKeyNotFound) {
+			l.log.Warnf("Failed to read cached result: %v", cErr)
+		}
+	}
+
+	sctx, done := context.WithTimeout(ctx, l.timeout)
+	defer done()
+
+	entries, err := l.search(sctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.cache != "" {
+		if cached, err := json.Marshal(entries); err == nil {
+			var cErr error
+			if err := l.mgr.AccessCache(ctx, l.cache, func(c service.Cache) {
+				cErr = c.Set(ctx, cacheKey, cached, l.cacheTTL)
+			}); err != nil {
+				cErr = err
+			}
+			if cErr != nil {
+				l.log.Warnf("Failed to cache result: %v", cErr)
+			}
+		}
+	}
+
+	msg.SetStructuredMut(entries)
+	return service.MessageBatch{msg}, nil
+}
+
+func (l *ldapProcessor) Close(ctx context.Context) error {
+	l.connMut.Lock()
+	defer l.connMut.Unlock()
+	if l.conn != nil {
+		err := l.conn.Close()
+		l.conn = nil
+		return err
+	}
+	return nil
+}
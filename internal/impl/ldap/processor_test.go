@@ -0,0 +1,405 @@
+package ldap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeEntry struct {
+	dn    string
+	attrs map[string][]string
+}
+
+// fakeServer is a directory server that responds to binds and searches, where
+// searches match all entries and the filter of each search is recorded.
+type fakeServer struct {
+	t        *testing.T
+	listener net.Listener
+	entries  []fakeEntry
+	tlsConf  *tls.Config
+
+	mut       sync.Mutex
+	conns     []net.Conn
+	binds     []string
+	filters   []string
+	pageSize  []uint32
+	startTLSs int
+}
+
+func newFakeServer(t *testing.T, entries []fakeEntry) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	// Borrow the self-signed certificate of an httptest server for StartTLS.
+	ts := httptest.NewTLSServer(nil)
+	ts.Close()
+
+	s := &fakeServer{t: t, listener: l, entries: entries, tlsConf: ts.TLS}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mut.Lock()
+			s.conns = append(s.conns, c)
+			s.mut.Unlock()
+			go s.serve(c)
+		}
+	}()
+	t.Cleanup(func() { _ = l.Close() })
+	return s
+}
+
+func (s *fakeServer) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+// dropConns closes all connections, simulating a server closing idle
+// connections.
+func (s *fakeServer) dropConns() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, c := range s.conns {
+		_ = c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeServer) serve(c net.Conn) {
+	defer c.Close()
+
+	r := bufio.NewReader(c)
+	write := func(id int64, op *ber.Packet, controls ...ldap.Control) {
+		msg := ber.NewSequence("LDAP Response")
+		msg.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+		msg.AppendChild(op)
+		if len(controls) > 0 {
+			ctrls := ber.Encode(ber.ClassContext, ber.TypeConstructed, 0, nil, "Controls")
+			for _, ctrl := range controls {
+				ctrls.AppendChild(ctrl.Encode())
+			}
+			msg.AppendChild(ctrls)
+		}
+		_, _ = c.Write(msg.Bytes())
+	}
+	result := func(tag ber.Tag, code int64, message string) *ber.Packet {
+		p := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+		p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+		p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+		p.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+		return p
+	}
+
+	for {
+		msg, err := ber.ReadPacket(r)
+		if err != nil {
+			return
+		}
+		id := msg.Children[0].Value.(int64)
+
+		op := msg.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			s.mut.Lock()
+			s.binds = append(s.binds, op.Children[1].Data.String())
+			s.mut.Unlock()
+
+			if op.Children[2].Data.String() != "hunter2" {
+				write(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, "bad password"))
+				return
+			}
+			write(id, result(ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, ""))
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationExtendedRequest:
+			s.mut.Lock()
+			s.startTLSs++
+			s.mut.Unlock()
+
+			write(id, result(ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess, ""))
+			tc := tls.Server(c, s.tlsConf)
+			require.NoError(s.t, tc.Handshake())
+			c, r = tc, bufio.NewReader(tc)
+		case ldap.ApplicationSearchRequest:
+			var pageSize uint32
+			var cookie int
+			if len(msg.Children) > 2 {
+				ctrl, err := ldap.DecodeControl(msg.Children[2].Children[0])
+				require.NoError(s.t, err)
+				paging, ok := ctrl.(*ldap.ControlPaging)
+				require.True(s.t, ok)
+
+				pageSize = paging.PagingSize
+				if len(paging.Cookie) > 0 {
+					cookie, err = strconv.Atoi(string(paging.Cookie))
+					require.NoError(s.t, err)
+				}
+			}
+
+			filter, err := ldap.DecompileFilter(op.Children[6])
+			require.NoError(s.t, err)
+
+			s.mut.Lock()
+			s.filters = append(s.filters, filter)
+			s.pageSize = append(s.pageSize, pageSize)
+			s.mut.Unlock()
+
+			if op.Children[0].Data.String() == "dc=missing" {
+				write(id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, "no such object"))
+				continue
+			}
+
+			end := len(s.entries)
+			if pageSize > 0 && cookie+int(pageSize) < end {
+				end = cookie + int(pageSize)
+			}
+			for _, e := range s.entries[cookie:end] {
+				entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+				entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "DN"))
+				attrs := ber.NewSequence("Attributes")
+				for k, vs := range e.attrs {
+					attr := ber.NewSequence("Attribute")
+					attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, k, "Type"))
+					vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+					for _, v := range vs {
+						vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+					}
+					attr.AppendChild(vals)
+					attrs.AppendChild(attr)
+				}
+				entry.AppendChild(attrs)
+				write(id, entry)
+			}
+
+			var controls []ldap.Control
+			if pageSize > 0 {
+				next := ""
+				if end < len(s.entries) {
+					next = strconv.Itoa(end)
+				}
+				controls = append(controls, &ldap.ControlPaging{Cookie: []byte(next)})
+			}
+			write(id, result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""), controls...)
+		default:
+			s.t.Errorf("unexpected operation tag %#x", op.Tag)
+			return
+		}
+	}
+}
+
+func TestLDAPProcessorSearch(t *testing.T) {
+	var entries []fakeEntry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, fakeEntry{
+			dn: fmt.Sprintf("cn=user%v,dc=example,dc=com", i),
+			attrs: map[string][]string{
+				"memberOf": {"cn=admins,dc=example,dc=com", "cn=users,dc=example,dc=com"},
+			},
+		})
+	}
+	entries[0].attrs["objectGUID"] = []string{"\xff\xfe"}
+	srv := newFakeServer(t, entries)
+
+	conf, err := processorSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+bind_dn: cn=benthos,dc=example,dc=com
+bind_password: hunter2
+base_dn: ${! @base }
+filter: 'root = "(sAMAccountName=%%s)".format(this.user.escape_ldap_filter())'
+page_size: 2
+`, srv.url()), nil)
+	require.NoError(t, err)
+
+	proc, err := newLDAPProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	msg := service.NewMessage([]byte(`{"user":"user*"}`))
+	msg.MetaSetMut("base", "dc=example,dc=com")
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	res, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	resArr, ok := res.([]any)
+	require.True(t, ok)
+	require.Len(t, resArr, 5)
+	assert.Equal(t, map[string]any{
+		"dn": "cn=user0,dc=example,dc=com",
+		"attributes": map[string]any{
+			"memberOf":   []any{"cn=admins,dc=example,dc=com", "cn=users,dc=example,dc=com"},
+			"objectGUID": []any{"//4="},
+		},
+	}, resArr[0])
+	assert.Equal(t, "cn=user4,dc=example,dc=com", resArr[4].(map[string]any)["dn"])
+
+	msg = service.NewMessage([]byte(`{"user":"foo"}`))
+	msg.MetaSetMut("base", "dc=missing")
+	batch, err = proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	res, err = batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, []any{}, res)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	assert.Equal(t, []string{"cn=benthos,dc=example,dc=com"}, srv.binds)
+	assert.Equal(t, []uint32{2, 2, 2, 2}, srv.pageSize)
+	assert.Equal(t, `(sAMAccountName=user\2a)`, srv.filters[0])
+}
+
+func TestLDAPProcessorSizeLimitAndCache(t *testing.T) {
+	var entries []fakeEntry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, fakeEntry{dn: fmt.Sprintf("cn=user%v,dc=example,dc=com", i)})
+	}
+	srv := newFakeServer(t, entries)
+
+	conf, err := processorSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+base_dn: dc=example,dc=com
+filter: 'root = "(cn=%%s)".format(content().string())'
+size_limit: 3
+page_size: 2
+cache: foo
+`, srv.url()), nil)
+	require.NoError(t, err)
+
+	mgr := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	proc, err := newLDAPProcessorFromParsed(conf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	for i := 0; i < 3; i++ {
+		batch, err := proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+
+		res, err := batch[0].AsStructured()
+		require.NoError(t, err)
+		require.Len(t, res, 3)
+		assert.Equal(t, "cn=user2,dc=example,dc=com", res.([]any)[2].(map[string]any)["dn"])
+	}
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+
+	assert.Empty(t, srv.binds)
+	assert.Len(t, srv.filters, 2)
+}
+
+func TestLDAPProcessorBindFailure(t *testing.T) {
+	srv := newFakeServer(t, nil)
+
+	conf, err := processorSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+bind_dn: cn=benthos,dc=example,dc=com
+bind_password: nope
+base_dn: dc=example,dc=com
+filter: 'root = "(cn=foo)"'
+`, srv.url()), nil)
+	require.NoError(t, err)
+
+	proc, err := newLDAPProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid Credentials")
+}
+
+func TestLDAPProcessorReconnect(t *testing.T) {
+	srv := newFakeServer(t, []fakeEntry{{dn: "cn=foo"}})
+
+	conf, err := processorSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+base_dn: dc=example,dc=com
+filter: 'root = "(cn=foo)"'
+`, srv.url()), nil)
+	require.NoError(t, err)
+
+	proc, err := newLDAPProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.NoError(t, err)
+
+	srv.dropConns()
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.NoError(t, err)
+	res, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+}
+
+func TestLDAPProcessorStartTLS(t *testing.T) {
+	srv := newFakeServer(t, []fakeEntry{{dn: "cn=foo"}})
+
+	conf, err := processorSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+start_tls: true
+tls:
+  skip_cert_verify: true
+bind_dn: cn=benthos,dc=example,dc=com
+bind_password: hunter2
+base_dn: dc=example,dc=com
+filter: 'root = "(cn=foo)"'
+`, srv.url()), nil)
+	require.NoError(t, err)
+
+	proc, err := newLDAPProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.NoError(t, err)
+	res, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	srv.mut.Lock()
+	defer srv.mut.Unlock()
+	assert.Equal(t, 1, srv.startTLSs)
+	assert.Equal(t, []string{"cn=benthos,dc=example,dc=com"}, srv.binds)
+}
+
+func TestLDAPProcessorInvalidFilter(t *testing.T) {
+	conf, err := processorSpec().ParseYAML(`
+url: ldap://localhost
+base_dn: dc=example,dc=com
+filter: 'root = content().string()'
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newLDAPProcessorFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = proc.Close(context.Background()) })
+
+	for _, filter := range []string{"", "(cn=foo", "(&(cn=foo)", "(cn=a\\zz)"} {
+		_, err = proc.Process(context.Background(), service.NewMessage([]byte(filter)))
+		assert.Error(t, err, filter)
+	}
+}
@@ -0,0 +1,160 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// Function codes of the read requests supported.
+const (
+	funcReadCoils            byte = 0x01
+	funcReadDiscreteInputs   byte = 0x02
+	funcReadHoldingRegisters byte = 0x03
+	funcReadInputRegisters   byte = 0x04
+)
+
+const (
+	// The maximum quantities that can be read within a single request, as
+	// defined by the Modbus application protocol specification.
+	maxReadBits      = 2000
+	maxReadRegisters = 125
+
+	mbapHeaderLen = 7
+	maxADULen     = 260
+)
+
+var exceptionNames = map[byte]string{
+	0x01: "illegal function",
+	0x02: "illegal data address",
+	0x03: "illegal data value",
+	0x04: "server device failure",
+	0x05: "acknowledge",
+	0x06: "server device busy",
+	0x08: "memory parity error",
+	0x0a: "gateway path unavailable",
+	0x0b: "gateway target device failed to respond",
+}
+
+// exceptionError is returned when a server responds to a request with an
+// exception, which unlike other errors does not invalidate the connection.
+type exceptionError struct {
+	function byte
+	code     byte
+}
+
+func (e *exceptionError) Error() string {
+	name := exceptionNames[e.code]
+	if name == "" {
+		name = "unknown exception"
+	}
+	return fmt.Sprintf("function %#02x failed with exception %#02x (%v)", e.function, e.code, name)
+}
+
+// client performs requests against a Modbus TCP server one at a time.
+type client struct {
+	conn    net.Conn
+	timeout time.Duration
+	txID    uint16
+	buf     []byte
+}
+
+func dial(ctx context.Context, address string, timeout time.Duration) (*client, error) {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "502")
+	}
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return &client{
+		conn:    conn,
+		timeout: timeout,
+		buf:     make([]byte, maxADULen),
+	}, nil
+}
+
+func (c *client) close() error {
+	return c.conn.Close()
+}
+
+// request sends a PDU to a unit and returns the data of the response PDU,
+// which excludes the function code.
+func (c *client) request(ctx context.Context, unitID, function byte, data []byte) ([]byte, error) {
+	c.txID++
+
+	adu := make([]byte, mbapHeaderLen+1+len(data))
+	binary.BigEndian.PutUint16(adu[0:], c.txID)
+	binary.BigEndian.PutUint16(adu[2:], 0)
+	binary.BigEndian.PutUint16(adu[4:], uint16(2+len(data)))
+	adu[6] = unitID
+	adu[7] = function
+	copy(adu[8:], data)
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = c.conn.SetDeadline(deadline)
+
+	if _, err := c.conn.Write(adu); err != nil {
+		return nil, err
+	}
+
+	for {
+		if _, err := io.ReadFull(c.conn, c.buf[:mbapHeaderLen]); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(c.buf[4:]))
+		if length < 3 || mbapHeaderLen-1+length > len(c.buf) {
+			return nil, fmt.Errorf("invalid response length %v", length)
+		}
+		if _, err := io.ReadFull(c.conn, c.buf[mbapHeaderLen:mbapHeaderLen-1+length]); err != nil {
+			return nil, err
+		}
+
+		// Responses to earlier requests that timed out are discarded.
+		if binary.BigEndian.Uint16(c.buf[0:]) != c.txID {
+			continue
+		}
+		if binary.BigEndian.Uint16(c.buf[2:]) != 0 {
+			return nil, errors.New("response has an unexpected protocol identifier")
+		}
+
+		pdu := c.buf[mbapHeaderLen : mbapHeaderLen-1+length]
+		switch pdu[0] {
+		case function:
+			return append([]byte(nil), pdu[1:]...), nil
+		case function | 0x80:
+			return nil, &exceptionError{function: function, code: pdu[1]}
+		}
+		return nil, fmt.Errorf("response has an unexpected function code %#02x", pdu[0])
+	}
+}
+
+// read performs a read request of a quantity of bits or registers from an
+// address, returning the raw bytes of the values read.
+func (c *client) read(ctx context.Context, unitID, function byte, address, quantity uint16) ([]byte, error) {
+	req := make([]byte, 4)
+	binary.BigEndian.PutUint16(req[0:], address)
+	binary.BigEndian.PutUint16(req[2:], quantity)
+
+	res, err := c.request(ctx, unitID, function, req)
+	if err != nil {
+		return nil, err
+	}
+
+	expected := int(quantity) * 2
+	if function == funcReadCoils || function == funcReadDiscreteInputs {
+		expected = (int(quantity) + 7) / 8
+	}
+	if len(res) < 1 || int(res[0]) != expected || len(res) != expected+1 {
+		return nil, fmt.Errorf("response contains an unexpected number of bytes for a quantity of %v", quantity)
+	}
+	return res[1:], nil
+}
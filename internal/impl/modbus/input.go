@@ -0,0 +1,367 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	miFieldAddress         = "address"
+	miFieldUnitID          = "unit_id"
+	miFieldRegisters       = "registers"
+	miFieldRegName         = "name"
+	miFieldRegType         = "type"
+	miFieldRegAddress      = "address"
+	miFieldRegDataType     = "data_type"
+	miFieldRegLength       = "length"
+	miFieldRegByteOrder    = "byte_order"
+	miFieldRegWordOrder    = "word_order"
+	miFieldRegScale        = "scale"
+	miFieldRegOffset       = "offset"
+	miFieldInterval        = "interval"
+	miFieldTimeout         = "timeout"
+	miFieldMaxRegsPerQuery = "max_registers_per_request"
+)
+
+func inputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Polls coils and registers from a Modbus TCP server on an interval.").
+		Description(`
+Each interval the configured registers are read from the server and emitted as a batch, with a message for each register where the contents of the message is the decoded value of the register. Registers of the same type with contiguous or overlapping addresses are read within a single request.
+
+When a server responds with an exception the registers of that request are emitted with a `+"`null`"+` value, a `+"`modbus_quality`"+` of `+"`bad`"+` and the exception within `+"`modbus_error`"+`. Any other error closes the connection, which is then reestablished.
+
+### Data Types
+
+Registers are decoded according to their `+"`data_type`"+`, where values spanning multiple registers are big-endian unless the `+"`byte_order`"+` (of the bytes within each register) or `+"`word_order`"+` (of the registers) are set to `+"`little`"+`. Coils and discrete inputs are always decoded as booleans, and therefore `+"`data_type`"+` is ignored for them.
+
+Numeric values can be converted into engineering units with `+"`scale`"+` and `+"`offset`"+`, in which case the value emitted is the floating point result of `+"`raw * scale + offset`"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- modbus_name
+- modbus_type
+- modbus_address
+- modbus_unit_id
+- modbus_quality (good or bad)
+- modbus_timestamp (the time the value was read, in RFC 3339 format)
+- modbus_error (when the quality is bad)
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewStringField(miFieldAddress).
+				Description("The address of the server, where the port defaults to 502.").
+				Example("10.0.0.5:502"),
+			service.NewIntField(miFieldUnitID).
+				Description("The unit identifier of the device to read from, which is usually only significant when the server is a gateway.").
+				Default(1),
+			service.NewObjectListField(miFieldRegisters,
+				service.NewStringField(miFieldRegName).
+					Description("A name that identifies the value."),
+				service.NewStringEnumField(miFieldRegType,
+					string(registerCoil), string(registerDiscreteInput), string(registerHolding), string(registerInput)).
+					Description("The type of the register.").
+					Default(string(registerHolding)),
+				service.NewIntField(miFieldRegAddress).
+					Description("The zero based address of the register."),
+				service.NewStringEnumField(miFieldRegDataType,
+					string(dataBool), string(dataInt16), string(dataUint16), string(dataInt32), string(dataUint32),
+					string(dataInt64), string(dataUint64), string(dataFloat32), string(dataFloat64), string(dataString)).
+					Description("The type of the value held by the register, which for 32 and 64 bit types spans two and four consecutive registers respectively.").
+					Default(string(dataUint16)),
+				service.NewIntField(miFieldRegLength).
+					Description("The length in bytes of string values, which span consecutive registers. Trailing null and space characters are removed from strings.").
+					Default(0),
+				service.NewStringEnumField(miFieldRegByteOrder, "big", "little").
+					Description("The order of the bytes within each register.").
+					Default("big").
+					Advanced(),
+				service.NewStringEnumField(miFieldRegWordOrder, "big", "little").
+					Description("The order of the registers of values that span multiple registers.").
+					Default("big").
+					Advanced(),
+				service.NewFloatField(miFieldRegScale).
+					Description("A factor to multiply numeric values by.").
+					Default(1.0),
+				service.NewFloatField(miFieldRegOffset).
+					Description("An amount to add to numeric values after scaling.").
+					Default(0.0),
+			).Description("The registers to read."),
+			service.NewDurationField(miFieldInterval).
+				Description("The period between polls.").
+				Default("1s"),
+			service.NewDurationField(miFieldTimeout).
+				Description("The maximum period to wait for a connection or response.").
+				Default("5s").
+				Advanced(),
+			service.NewIntField(miFieldMaxRegsPerQuery).
+				Description("The maximum number of registers to read within a single request, which can be lowered for devices that limit the size of requests.").
+				Default(maxReadRegisters).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Energy Meter", "The voltage, current and total energy of a power meter are read every five seconds and converted into a single structured message.", `
+input:
+  modbus_tcp:
+    address: 10.0.0.5:502
+    registers:
+      - name: voltage
+        type: input_register
+        address: 0
+        data_type: float32
+      - name: current
+        type: input_register
+        address: 6
+        data_type: float32
+      - name: energy_kwh
+        type: input_register
+        address: 342
+        data_type: uint32
+        word_order: little
+        scale: 0.01
+    interval: 5s
+  processors:
+    - mapping: 'root = { @modbus_name: this }'
+    - archive:
+        format: json_array
+    - mapping: 'root = this.squash()'
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("modbus_tcp", inputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newModbusInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatchedToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type modbusInput struct {
+	address  string
+	unitID   byte
+	blocks   []*block
+	interval time.Duration
+	timeout  time.Duration
+
+	clientMut sync.Mutex
+	client    *client
+	nextPoll  time.Time
+
+	log     *service.Logger
+	shutSig *shutdown.Signaller
+}
+
+func newModbusInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*modbusInput, error) {
+	m := &modbusInput{
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if m.address, err = conf.FieldString(miFieldAddress); err != nil {
+		return nil, err
+	}
+	unitID, err := conf.FieldInt(miFieldUnitID)
+	if err != nil {
+		return nil, err
+	}
+	if unitID < 0 || unitID > 255 {
+		return nil, fmt.Errorf("unit_id %v is outside of the range 0 to 255", unitID)
+	}
+	m.unitID = byte(unitID)
+
+	maxRegisters, err := conf.FieldInt(miFieldMaxRegsPerQuery)
+	if err != nil {
+		return nil, err
+	}
+	if maxRegisters < 1 || maxRegisters > maxReadRegisters {
+		return nil, fmt.Errorf("max_registers_per_request must be between 1 and %v", maxReadRegisters)
+	}
+
+	regConfs, err := conf.FieldObjectList(miFieldRegisters)
+	if err != nil {
+		return nil, err
+	}
+	if len(regConfs) == 0 {
+		return nil, errors.New("at least one register must be specified")
+	}
+	registers := make([]*register, 0, len(regConfs))
+	names := map[string]struct{}{}
+	for i, rc := range regConfs {
+		r, err := registerFromParsed(rc)
+		if err != nil {
+			return nil, fmt.Errorf("register %v: %w", i, err)
+		}
+		if _, exists := names[r.name]; exists {
+			return nil, fmt.Errorf("register %v: name '%v' is used more than once", i, r.name)
+		}
+		names[r.name] = struct{}{}
+		registers = append(registers, r)
+	}
+	m.blocks = planBlocks(registers, maxRegisters)
+	if err := validateBlocks(m.blocks, maxRegisters); err != nil {
+		return nil, err
+	}
+
+	if m.interval, err = conf.FieldDuration(miFieldInterval); err != nil {
+		return nil, err
+	}
+	if m.timeout, err = conf.FieldDuration(miFieldTimeout); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func registerFromParsed(conf *service.ParsedConfig) (*register, error) {
+	r := &register{}
+
+	var err error
+	if r.name, err = conf.FieldString(miFieldRegName); err != nil {
+		return nil, err
+	}
+	typeStr, err := conf.FieldString(miFieldRegType)
+	if err != nil {
+		return nil, err
+	}
+	r.typ = registerType(typeStr)
+	address, err := conf.FieldInt(miFieldRegAddress)
+	if err != nil {
+		return nil, err
+	}
+	if address < 0 || address > 0xffff {
+		return nil, fmt.Errorf("address %v is outside of the range 0 to 65535", address)
+	}
+	r.address = uint16(address)
+	dataStr, err := conf.FieldString(miFieldRegDataType)
+	if err != nil {
+		return nil, err
+	}
+	r.dataType = dataType(dataStr)
+	if r.length, err = conf.FieldInt(miFieldRegLength); err != nil {
+		return nil, err
+	}
+	byteOrder, err := conf.FieldString(miFieldRegByteOrder)
+	if err != nil {
+		return nil, err
+	}
+	r.byteSwap = byteOrder == "little"
+	wordOrder, err := conf.FieldString(miFieldRegWordOrder)
+	if err != nil {
+		return nil, err
+	}
+	r.wordSwap = wordOrder == "little"
+	if r.scale, err = conf.FieldFloat(miFieldRegScale); err != nil {
+		return nil, err
+	}
+	if r.offset, err = conf.FieldFloat(miFieldRegOffset); err != nil {
+		return nil, err
+	}
+	r.transform = r.scale != 1 || r.offset != 0
+
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (m *modbusInput) Connect(ctx context.Context) error {
+	m.clientMut.Lock()
+	defer m.clientMut.Unlock()
+
+	if m.client != nil {
+		return nil
+	}
+	c, err := dial(ctx, m.address, m.timeout)
+	if err != nil {
+		return err
+	}
+	m.client = c
+	return nil
+}
+
+func (m *modbusInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	m.clientMut.Lock()
+	defer m.clientMut.Unlock()
+
+	if m.client == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	if wait := time.Until(m.nextPoll); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-m.shutSig.SoftStopChan():
+			return nil, nil, service.ErrEndOfInput
+		}
+	}
+	m.nextPoll = time.Now().Add(m.interval)
+
+	batch := make(service.MessageBatch, 0, len(m.blocks))
+	for _, b := range m.blocks {
+		data, err := m.client.read(ctx, m.unitID, b.typ.function(), b.address, uint16(b.quantity))
+		readAt := time.Now()
+
+		var exception *exceptionError
+		if err != nil && !errors.As(err, &exception) {
+			m.log.Errorf("Failed to read from %v: %v", m.address, err)
+			_ = m.client.close()
+			m.client = nil
+			return nil, nil, service.ErrNotConnected
+		}
+
+		for _, r := range b.registers {
+			msg := service.NewMessage(nil)
+			msg.MetaSetMut("modbus_name", r.name)
+			msg.MetaSetMut("modbus_type", string(r.typ))
+			msg.MetaSetMut("modbus_address", strconv.Itoa(int(r.address)))
+			msg.MetaSetMut("modbus_unit_id", strconv.Itoa(int(m.unitID)))
+			msg.MetaSetMut("modbus_timestamp", readAt.Format(time.RFC3339Nano))
+			if err != nil {
+				msg.SetStructuredMut(nil)
+				msg.MetaSetMut("modbus_quality", "bad")
+				msg.MetaSetMut("modbus_error", err.Error())
+			} else {
+				msg.SetStructuredMut(r.decode(b.extract(r, data)))
+				msg.MetaSetMut("modbus_quality", "good")
+			}
+			batch = append(batch, msg)
+		}
+	}
+	return batch, func(context.Context, error) error { return nil }, nil
+}
+
+func (m *modbusInput) Close(ctx context.Context) error {
+	m.shutSig.TriggerSoftStop()
+
+	m.clientMut.Lock()
+	defer m.clientMut.Unlock()
+
+	if m.client != nil {
+		_ = m.client.close()
+		m.client = nil
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeServer serves reads of coils and holding registers, responding to
+// reads of addresses that aren't mapped with an exception.
+type fakeServer struct {
+	listener net.Listener
+
+	mut       sync.Mutex
+	coils     map[uint16]bool
+	registers map[uint16]uint16
+	requests  int
+}
+
+func newFakeServer(t testing.TB) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	s := &fakeServer{
+		listener:  l,
+		coils:     map[uint16]bool{},
+		registers: map[uint16]uint16{},
+	}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	header := make([]byte, mbapHeaderLen)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		pdu := make([]byte, binary.BigEndian.Uint16(header[4:])-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+		address := binary.BigEndian.Uint16(pdu[1:])
+		quantity := binary.BigEndian.Uint16(pdu[3:])
+
+		s.mut.Lock()
+		s.requests++
+		res := []byte{pdu[0]}
+		switch pdu[0] {
+		case funcReadCoils:
+			data := make([]byte, (quantity+7)/8)
+			for i := uint16(0); i < quantity; i++ {
+				v, exists := s.coils[address+i]
+				if !exists {
+					res = []byte{pdu[0] | 0x80, 0x02}
+					break
+				}
+				if v {
+					data[i/8] |= 1 << (i % 8)
+				}
+			}
+			if len(res) == 1 {
+				res = append(append(res, byte(len(data))), data...)
+			}
+		case funcReadHoldingRegisters:
+			data := make([]byte, quantity*2)
+			for i := uint16(0); i < quantity; i++ {
+				v, exists := s.registers[address+i]
+				if !exists {
+					res = []byte{pdu[0] | 0x80, 0x02}
+					break
+				}
+				binary.BigEndian.PutUint16(data[i*2:], v)
+			}
+			if len(res) == 1 {
+				res = append(append(res, byte(len(data))), data...)
+			}
+		default:
+			res = []byte{pdu[0] | 0x80, 0x01}
+		}
+		s.mut.Unlock()
+
+		out := make([]byte, mbapHeaderLen, mbapHeaderLen+len(res))
+		copy(out, header[:4])
+		binary.BigEndian.PutUint16(out[4:], uint16(len(res)+1))
+		out[6] = header[6]
+		if _, err := conn.Write(append(out, res...)); err != nil {
+			return
+		}
+	}
+}
+
+func TestInputRead(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	server := newFakeServer(t)
+	server.coils[0] = false
+	server.coils[1] = true
+	server.registers[100] = 0x4366
+	server.registers[101] = 0x8000
+	server.registers[102] = 0x0320
+	server.registers[103] = 0x6869
+
+	conf, err := inputSpec().ParseYAML(`
+address: `+server.listener.Addr().String()+`
+unit_id: 3
+registers:
+  - name: pump_running
+    type: coil
+    address: 1
+  - name: voltage
+    address: 100
+    data_type: float32
+  - name: temperature
+    address: 102
+    data_type: int16
+    scale: 0.1
+    offset: -40
+  - name: label
+    address: 103
+    data_type: string
+    length: 2
+  - name: missing
+    address: 200
+interval: 50ms
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newModbusInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	require.NoError(t, i.Connect(ctx))
+
+	for n := 0; n < 2; n++ {
+		batch, ackFn, err := i.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+		require.Len(t, batch, 5)
+
+		values := map[string]any{}
+		for _, msg := range batch {
+			name, _ := msg.MetaGetMut("modbus_name")
+			v, err := msg.AsStructured()
+			require.NoError(t, err)
+			values[name.(string)] = v
+
+			unitID, _ := msg.MetaGetMut("modbus_unit_id")
+			assert.Equal(t, "3", unitID)
+			ts, _ := msg.MetaGetMut("modbus_timestamp")
+			_, err = time.Parse(time.RFC3339Nano, ts.(string))
+			require.NoError(t, err)
+
+			quality, _ := msg.MetaGetMut("modbus_quality")
+			if name == "missing" {
+				assert.Equal(t, "bad", quality)
+				errStr, _ := msg.MetaGetMut("modbus_error")
+				assert.Equal(t, "function 0x03 failed with exception 0x02 (illegal data address)", errStr)
+			} else {
+				assert.Equal(t, "good", quality)
+			}
+		}
+		assert.Equal(t, true, values["pump_running"])
+		assert.InDelta(t, 230.5, values["voltage"], 1e-9)
+		assert.InDelta(t, 40.0, values["temperature"], 1e-9)
+		assert.Equal(t, "hi", values["label"])
+		assert.Nil(t, values["missing"])
+	}
+
+	server.mut.Lock()
+	assert.Equal(t, 6, server.requests)
+	server.mut.Unlock()
+}
+
+func TestInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		confStr string
+		errStr  string
+	}{
+		{
+			name: "string without length",
+			confStr: `
+address: localhost
+registers:
+  - name: foo
+    address: 0
+    data_type: string
+`,
+			errStr: "register 0: strings require a length",
+		},
+		{
+			name: "duplicate names",
+			confStr: `
+address: localhost
+registers:
+  - name: foo
+    address: 0
+  - name: foo
+    address: 1
+`,
+			errStr: "register 1: name 'foo' is used more than once",
+		},
+		{
+			name: "scaled coil",
+			confStr: `
+address: localhost
+registers:
+  - name: foo
+    type: coil
+    address: 0
+    scale: 2
+`,
+			errStr: "register 0: scale and offset cannot be applied to bits",
+		},
+		{
+			name: "string too long",
+			confStr: `
+address: localhost
+registers:
+  - name: foo
+    address: 0
+    data_type: string
+    length: 20
+max_registers_per_request: 8
+`,
+			errStr: "register foo occupies 10 registers, which exceeds the maximum of 8 per request",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := inputSpec().ParseYAML(test.confStr, nil)
+			require.NoError(t, err)
+
+			_, err = newModbusInputFromParsed(conf, service.MockResources())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type registerType string
+
+const (
+	registerCoil          registerType = "coil"
+	registerDiscreteInput registerType = "discrete_input"
+	registerHolding       registerType = "holding_register"
+	registerInput         registerType = "input_register"
+)
+
+func (r registerType) function() byte {
+	switch r {
+	case registerCoil:
+		return funcReadCoils
+	case registerDiscreteInput:
+		return funcReadDiscreteInputs
+	case registerInput:
+		return funcReadInputRegisters
+	}
+	return funcReadHoldingRegisters
+}
+
+func (r registerType) isBit() bool {
+	return r == registerCoil || r == registerDiscreteInput
+}
+
+type dataType string
+
+const (
+	dataBool    dataType = "bool"
+	dataInt16   dataType = "int16"
+	dataUint16  dataType = "uint16"
+	dataInt32   dataType = "int32"
+	dataUint32  dataType = "uint32"
+	dataInt64   dataType = "int64"
+	dataUint64  dataType = "uint64"
+	dataFloat32 dataType = "float32"
+	dataFloat64 dataType = "float64"
+	dataString  dataType = "string"
+)
+
+// register describes a value to read and how to decode it.
+type register struct {
+	name      string
+	typ       registerType
+	address   uint16
+	dataType  dataType
+	length    int
+	byteSwap  bool
+	wordSwap  bool
+	scale     float64
+	offset    float64
+	transform bool
+}
+
+// quantity returns the number of bits or registers that the value occupies.
+func (r *register) quantity() int {
+	if r.typ.isBit() {
+		return 1
+	}
+	switch r.dataType {
+	case dataInt32, dataUint32, dataFloat32:
+		return 2
+	case dataInt64, dataUint64, dataFloat64:
+		return 4
+	case dataString:
+		return (r.length + 1) / 2
+	}
+	return 1
+}
+
+func (r *register) validate() error {
+	if r.name == "" {
+		return errors.New("a name is required")
+	}
+	if r.typ.isBit() {
+		if r.transform {
+			return errors.New("scale and offset cannot be applied to bits")
+		}
+		return nil
+	}
+	switch r.dataType {
+	case dataString:
+		if r.length <= 0 {
+			return errors.New("strings require a length")
+		}
+		if r.transform {
+			return errors.New("scale and offset cannot be applied to strings")
+		}
+	case dataBool:
+		if r.transform {
+			return errors.New("scale and offset cannot be applied to booleans")
+		}
+	}
+	if int(r.address)+r.quantity() > 0x10000 {
+		return errors.New("value exceeds the address space")
+	}
+	return nil
+}
+
+// decode converts the raw bytes of a value into its structured form.
+func (r *register) decode(raw []byte) any {
+	if r.typ.isBit() {
+		return raw[0]&1 == 1
+	}
+
+	b := append([]byte(nil), raw...)
+	if r.byteSwap {
+		for i := 0; i+1 < len(b); i += 2 {
+			b[i], b[i+1] = b[i+1], b[i]
+		}
+	}
+	if r.dataType == dataString {
+		return strings.TrimRight(string(b[:r.length]), "\x00 ")
+	}
+	if r.wordSwap {
+		for i, j := 0, len(b)-2; i < j; i, j = i+2, j-2 {
+			b[i], b[i+1], b[j], b[j+1] = b[j], b[j+1], b[i], b[i+1]
+		}
+	}
+
+	var v any
+	switch r.dataType {
+	case dataBool:
+		return binary.BigEndian.Uint16(b) != 0
+	case dataInt16:
+		v = int64(int16(binary.BigEndian.Uint16(b)))
+	case dataUint16:
+		v = uint64(binary.BigEndian.Uint16(b))
+	case dataInt32:
+		v = int64(int32(binary.BigEndian.Uint32(b)))
+	case dataUint32:
+		v = uint64(binary.BigEndian.Uint32(b))
+	case dataInt64:
+		v = int64(binary.BigEndian.Uint64(b))
+	case dataUint64:
+		v = binary.BigEndian.Uint64(b)
+	case dataFloat32:
+		v = float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	case dataFloat64:
+		v = math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+	if !r.transform {
+		return v
+	}
+
+	var f float64
+	switch t := v.(type) {
+	case int64:
+		f = float64(t)
+	case uint64:
+		f = float64(t)
+	case float64:
+		f = t
+	}
+	return f*r.scale + r.offset
+}
+
+//------------------------------------------------------------------------------
+
+// block is a contiguous range of bits or registers of a single type that are
+// read with one request.
+type block struct {
+	typ       registerType
+	address   uint16
+	quantity  int
+	registers []*register
+}
+
+// extract returns the raw bytes of a register from the bytes read for the
+// block.
+func (b *block) extract(r *register, data []byte) []byte {
+	start := int(r.address - b.address)
+	if r.typ.isBit() {
+		return []byte{(data[start/8] >> (start % 8)) & 1}
+	}
+	return data[start*2 : (start+r.quantity())*2]
+}
+
+// planBlocks groups registers into the fewest blocks of contiguous or
+// overlapping addresses that do not exceed the maximum quantity of a request.
+// Gaps are never read across, as servers commonly respond to reads of
+// unmapped addresses with an exception.
+func planBlocks(registers []*register, maxRegisters int) []*block {
+	sorted := append([]*register(nil), registers...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].typ != sorted[j].typ {
+			return sorted[i].typ < sorted[j].typ
+		}
+		return sorted[i].address < sorted[j].address
+	})
+
+	var blocks []*block
+	var current *block
+	for _, r := range sorted {
+		limit := maxRegisters
+		if r.typ.isBit() {
+			limit = maxReadBits
+		}
+		if current != nil && current.typ == r.typ && int(r.address) <= int(current.address)+current.quantity {
+			end := int(r.address) + r.quantity()
+			if end < int(current.address)+current.quantity {
+				end = int(current.address) + current.quantity
+			}
+			if end-int(current.address) <= limit {
+				current.quantity = end - int(current.address)
+				current.registers = append(current.registers, r)
+				continue
+			}
+		}
+		current = &block{
+			typ:       r.typ,
+			address:   r.address,
+			quantity:  r.quantity(),
+			registers: []*register{r},
+		}
+		blocks = append(blocks, current)
+	}
+	return blocks
+}
+
+func validateBlocks(blocks []*block, maxRegisters int) error {
+	for _, b := range blocks {
+		if !b.typ.isBit() && b.quantity > maxRegisters {
+			return fmt.Errorf("register %v occupies %v registers, which exceeds the maximum of %v per request", b.registers[0].name, b.quantity, maxRegisters)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package modbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterDecode(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		reg    register
+		raw    []byte
+		output any
+	}{
+		{
+			name:   "coil",
+			reg:    register{typ: registerCoil},
+			raw:    []byte{1},
+			output: true,
+		},
+		{
+			name:   "int16",
+			reg:    register{typ: registerHolding, dataType: dataInt16},
+			raw:    []byte{0xff, 0xfe},
+			output: int64(-2),
+		},
+		{
+			name:   "uint16 little byte order",
+			reg:    register{typ: registerHolding, dataType: dataUint16, byteSwap: true},
+			raw:    []byte{0x01, 0x02},
+			output: uint64(0x0201),
+		},
+		{
+			name:   "uint32",
+			reg:    register{typ: registerInput, dataType: dataUint32},
+			raw:    []byte{0x00, 0x01, 0x86, 0xa0},
+			output: uint64(100000),
+		},
+		{
+			name:   "uint32 little word order",
+			reg:    register{typ: registerInput, dataType: dataUint32, wordSwap: true},
+			raw:    []byte{0x86, 0xa0, 0x00, 0x01},
+			output: uint64(100000),
+		},
+		{
+			name:   "int64 little byte and word order",
+			reg:    register{typ: registerInput, dataType: dataInt64, byteSwap: true, wordSwap: true},
+			raw:    []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+			output: int64(-2),
+		},
+		{
+			name:   "float32",
+			reg:    register{typ: registerInput, dataType: dataFloat32},
+			raw:    []byte{0x43, 0x66, 0x80, 0x00},
+			output: float64(230.5),
+		},
+		{
+			name:   "float64",
+			reg:    register{typ: registerInput, dataType: dataFloat64},
+			raw:    []byte{0x40, 0x09, 0x21, 0xfb, 0x54, 0x44, 0x2d, 0x18},
+			output: 3.141592653589793,
+		},
+		{
+			name:   "scaled",
+			reg:    register{typ: registerHolding, dataType: dataInt16, scale: 0.1, offset: -40, transform: true},
+			raw:    []byte{0x03, 0x20},
+			output: float64(40),
+		},
+		{
+			name:   "string",
+			reg:    register{typ: registerHolding, dataType: dataString, length: 5},
+			raw:    []byte{'a', 'b', 'c', ' ', 0, 0},
+			output: "abc",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if f, ok := test.output.(float64); ok {
+				assert.InDelta(t, f, test.reg.decode(test.raw), 1e-9)
+				return
+			}
+			assert.Equal(t, test.output, test.reg.decode(test.raw))
+		})
+	}
+}
+
+func TestPlanBlocks(t *testing.T) {
+	registers := []*register{
+		{name: "a", typ: registerHolding, address: 10, dataType: dataUint32},
+		{name: "b", typ: registerHolding, address: 12, dataType: dataUint16},
+		{name: "c", typ: registerHolding, address: 11, dataType: dataUint16},
+		{name: "d", typ: registerHolding, address: 14, dataType: dataUint16},
+		{name: "e", typ: registerCoil, address: 3},
+		{name: "f", typ: registerCoil, address: 4},
+		{name: "g", typ: registerHolding, address: 15, dataType: dataUint64},
+	}
+
+	blocks := planBlocks(registers, 125)
+	require.Len(t, blocks, 3)
+
+	assert.Equal(t, registerCoil, blocks[0].typ)
+	assert.Equal(t, uint16(3), blocks[0].address)
+	assert.Equal(t, 2, blocks[0].quantity)
+
+	assert.Equal(t, uint16(10), blocks[1].address)
+	assert.Equal(t, 3, blocks[1].quantity)
+	assert.Len(t, blocks[1].registers, 3)
+
+	assert.Equal(t, uint16(14), blocks[2].address)
+	assert.Equal(t, 5, blocks[2].quantity)
+
+	// A lower maximum splits blocks.
+	blocks = planBlocks(registers, 4)
+	require.Len(t, blocks, 4)
+	assert.Equal(t, uint16(14), blocks[2].address)
+	assert.Equal(t, 1, blocks[2].quantity)
+	assert.Equal(t, uint16(15), blocks[3].address)
+	assert.Equal(t, 4, blocks[3].quantity)
+
+	// Bits are extracted from packed bytes.
+	b := &block{typ: registerCoil, address: 3, quantity: 10}
+	data := []byte{0x02, 0x02}
+	assert.Equal(t, []byte{1}, b.extract(&register{typ: registerCoil, address: 4}, data))
+	assert.Equal(t, []byte{0}, b.extract(&register{typ: registerCoil, address: 5}, data))
+	assert.Equal(t, []byte{1}, b.extract(&register{typ: registerCoil, address: 12}, data))
+}
@@ -0,0 +1,484 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/checkpoint"
+	"github.com/Jeffail/shutdown"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	mcsFieldScope                    = "scope"
+	mcsFieldCollection               = "collection"
+	mcsFieldPipeline                 = "pipeline"
+	mcsFieldFullDocument             = "full_document"
+	mcsFieldFullDocumentBeforeChange = "full_document_before_change"
+	mcsFieldBatchSize                = "batch_size"
+	mcsFieldMaxAwaitTime             = "max_await_time"
+	mcsFieldJSONMarshalMode          = "json_marshal_mode"
+	mcsFieldCheckpointCache          = "checkpoint_cache"
+	mcsFieldCheckpointKey            = "checkpoint_key"
+	mcsFieldCheckpointLimit          = "checkpoint_limit"
+)
+
+const (
+	changeStreamScopeCollection = "collection"
+	changeStreamScopeDatabase   = "database"
+	changeStreamScopeDeployment = "deployment"
+)
+
+func changeStreamInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Consumes change events from a MongoDB [change stream](https://www.mongodb.com/docs/manual/changeStreams/) of a collection, database or an entire deployment.").
+		Description(`
+Change streams are only available on replica sets and sharded clusters. Each change event is emitted as a message containing the event document, marshalled to JSON according to `+"`json_marshal_mode`"+`.
+
+### Checkpointing
+
+When a `+"`checkpoint_cache`"+` is configured the resume token of the latest change event that has been acknowledged, along with all prior events, is stored within the cache. Upon starting the change stream resumes after the stored token, otherwise only changes that occur after connecting are consumed. The stored token must still be present within the oplog of the deployment in order for the stream to resume.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- mongo_database
+- mongo_collection
+- mongo_operation_type
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringAnnotatedEnumField(mcsFieldScope, map[string]string{
+				changeStreamScopeCollection: "Watch a single collection of the database.",
+				changeStreamScopeDatabase:   "Watch all collections of the database.",
+				changeStreamScopeDeployment: "Watch all databases of the deployment, in which case the field `database` is ignored.",
+			}).
+				Description("The scope of the changes to watch.").
+				Default(changeStreamScopeCollection),
+			service.NewStringField(mcsFieldCollection).
+				Description("The collection to watch, required when the scope is `collection`.").
+				Optional(),
+			service.NewBloblangField(mcsFieldPipeline).
+				Description("An optional Bloblang mapping that results in an array of [aggregation pipeline stages](https://www.mongodb.com/docs/manual/changeStreams/#modify-change-stream-output) used to filter and modify change events on the server.").
+				Example(`root = [ { "$match": { "operationType": { "$in": [ "insert", "update" ] } } } ]`).
+				Optional(),
+			service.NewStringAnnotatedEnumField(mcsFieldFullDocument, map[string]string{
+				string(options.Default):       "Update events only include the delta of the change.",
+				string(options.UpdateLookup):  "Update events include the current majority committed version of the document.",
+				string(options.WhenAvailable): "Update events include the post-image of the document when available.",
+				string(options.Required):      "Update events include the post-image of the document, failing when it is not available.",
+			}).
+				Description("Whether update events include the full document, post-images require `changeStreamPreAndPostImages` to be enabled for the collection.").
+				Default(string(options.Default)),
+			service.NewStringAnnotatedEnumField(mcsFieldFullDocumentBeforeChange, map[string]string{
+				string(options.Off):           "Events do not include the pre-image of the document.",
+				string(options.WhenAvailable): "Events include the pre-image of the document when available.",
+				string(options.Required):      "Events include the pre-image of the document, failing when it is not available.",
+			}).
+				Description("Whether update, replace and delete events include the document as it was prior to the change, which requires `changeStreamPreAndPostImages` to be enabled for the collection.").
+				Default(string(options.Off)).
+				Advanced(),
+			service.NewIntField(mcsFieldBatchSize).
+				Description("The maximum number of change events returned by the server within each batch.").
+				Optional().
+				Advanced(),
+			service.NewDurationField(mcsFieldMaxAwaitTime).
+				Description("The maximum period the server waits for new change events before returning an empty batch.").
+				Optional().
+				Advanced(),
+			service.NewStringAnnotatedEnumField(mcsFieldJSONMarshalMode, map[string]string{
+				string(JSONMarshalModeCanonical): "A string format that emphasizes type preservation at the expense of readability and interoperability.",
+				string(JSONMarshalModeRelaxed):   "A string format that emphasizes readability and interoperability at the expense of type preservation.",
+			}).
+				Description("Controls the format of the JSON messages created from change events.").
+				Default(string(JSONMarshalModeCanonical)).
+				Advanced(),
+			service.NewStringField(mcsFieldCheckpointCache).
+				Description("A [cache resource](/docs/components/caches/about) in which the resume token of the latest acknowledged change event is stored.").
+				Optional(),
+			service.NewStringField(mcsFieldCheckpointKey).
+				Description("The key under which the resume token of the latest acknowledged change event is stored.").
+				Default("mongodb_change_stream_resume_token").
+				Advanced(),
+			service.NewIntField(mcsFieldCheckpointLimit).
+				Description("The maximum number of change events that can be pending acknowledgement at any given time.").
+				Default(1024).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Stream Order Changes", "Inserts and updates of order documents are written to Kafka along with the current version of each document, with progress checkpointed within a file cache so that restarts resume from the last acknowledged change.", `
+input:
+  mongodb_change_stream:
+    url: mongodb://localhost:27017/?replicaSet=rs0
+    database: shop
+    collection: orders
+    full_document: updateLookup
+    pipeline: |
+      root = [ { "$match": { "operationType": { "$in": [ "insert", "update" ] } } } ]
+    checkpoint_cache: checkpoints
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders
+    key: ${! json("documentKey._id") }
+
+cache_resources:
+  - label: checkpoints
+    file:
+      directory: ./checkpoints
+`)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"mongodb_change_stream", changeStreamInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newChangeStreamInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type changeEvent struct {
+	raw        bson.Raw
+	token      bson.Raw
+	database   string
+	collection string
+	operation  string
+}
+
+// parseChangeEvent copies a change event from the current position of a change
+// stream and extracts the fields added as metadata.
+func parseChangeEvent(current, token bson.Raw) changeEvent {
+	e := changeEvent{
+		raw:   append(bson.Raw(nil), current...),
+		token: append(bson.Raw(nil), token...),
+	}
+	e.operation, _ = e.raw.Lookup("operationType").StringValueOK()
+	e.database, _ = e.raw.Lookup("ns", "db").StringValueOK()
+	e.collection, _ = e.raw.Lookup("ns", "coll").StringValueOK()
+	return e
+}
+
+type changeStreamInput struct {
+	scope        string
+	collection   string
+	pipeline     []any
+	opts         *options.ChangeStreamOptions
+	marshalCanon bool
+
+	cache    string
+	cacheKey string
+
+	client       *mongo.Client
+	database     *mongo.Database
+	checkpointer *checkpoint.Capped[bson.Raw]
+
+	connMut     sync.Mutex
+	resumed     bool
+	resumeToken bson.Raw
+	eventsCh    chan changeEvent
+	errCh       chan error
+	closeFn     func()
+
+	mgr     *service.Resources
+	log     *service.Logger
+	shutSig *shutdown.Signaller
+}
+
+func newChangeStreamInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*changeStreamInput, error) {
+	c := &changeStreamInput{
+		mgr:     mgr,
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+		opts:    options.ChangeStream(),
+	}
+
+	var err error
+	if c.scope, err = conf.FieldString(mcsFieldScope); err != nil {
+		return nil, err
+	}
+	if conf.Contains(mcsFieldCollection) {
+		if c.collection, err = conf.FieldString(mcsFieldCollection); err != nil {
+			return nil, err
+		}
+	}
+	if c.scope == changeStreamScopeCollection && c.collection == "" {
+		return nil, errors.New("a collection must be specified when the scope is collection")
+	}
+
+	if conf.Contains(mcsFieldPipeline) {
+		exec, err := conf.FieldBloblang(mcsFieldPipeline)
+		if err != nil {
+			return nil, err
+		}
+		v, err := exec.Query(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute pipeline mapping: %w", err)
+		}
+		var ok bool
+		if c.pipeline, ok = v.([]any); !ok {
+			return nil, fmt.Errorf("pipeline mapping must result in an array, got %T", v)
+		}
+	}
+
+	fullDoc, err := conf.FieldString(mcsFieldFullDocument)
+	if err != nil {
+		return nil, err
+	}
+	c.opts.SetFullDocument(options.FullDocument(fullDoc))
+
+	fullDocBefore, err := conf.FieldString(mcsFieldFullDocumentBeforeChange)
+	if err != nil {
+		return nil, err
+	}
+	c.opts.SetFullDocumentBeforeChange(options.FullDocument(fullDocBefore))
+
+	if conf.Contains(mcsFieldBatchSize) {
+		batchSize, err := conf.FieldInt(mcsFieldBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		if batchSize < 1 {
+			return nil, errors.New("batch_size must be >0")
+		}
+		c.opts.SetBatchSize(int32(batchSize))
+	}
+	if conf.Contains(mcsFieldMaxAwaitTime) {
+		maxAwait, err := conf.FieldDuration(mcsFieldMaxAwaitTime)
+		if err != nil {
+			return nil, err
+		}
+		c.opts.SetMaxAwaitTime(maxAwait)
+	}
+
+	marshalMode, err := conf.FieldString(mcsFieldJSONMarshalMode)
+	if err != nil {
+		return nil, err
+	}
+	c.marshalCanon = marshalMode == string(JSONMarshalModeCanonical)
+
+	if conf.Contains(mcsFieldCheckpointCache) {
+		if c.cache, err = conf.FieldString(mcsFieldCheckpointCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(c.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", c.cache)
+		}
+	}
+	if c.cacheKey, err = conf.FieldString(mcsFieldCheckpointKey); err != nil {
+		return nil, err
+	}
+
+	limit, err := conf.FieldInt(mcsFieldCheckpointLimit)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 {
+		return nil, errors.New("checkpoint_limit must be >0")
+	}
+	c.checkpointer = checkpoint.NewCapped[bson.Raw](int64(limit))
+
+	if c.client, c.database, err = getClient(conf); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *changeStreamInput) readCheckpoint(ctx context.Context) (bson.Raw, error) {
+	if c.cache == "" {
+		return nil, nil
+	}
+	var token []byte
+	var cErr error
+	if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+		token, cErr = cache.Get(ctx, c.cacheKey)
+	}); err != nil {
+		return nil, err
+	}
+	if errors.Is(cErr, service.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if cErr != nil {
+		return nil, cErr
+	}
+	if err := bson.Raw(token).Validate(); err != nil {
+		return nil, fmt.Errorf("stored resume token is invalid: %w", err)
+	}
+	return token, nil
+}
+
+func (c *changeStreamInput) watch(ctx context.Context, opts *options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	pipeline := c.pipeline
+	if pipeline == nil {
+		pipeline = []any{}
+	}
+	switch c.scope {
+	case changeStreamScopeDeployment:
+		return c.client.Watch(ctx, pipeline, opts)
+	case changeStreamScopeDatabase:
+		return c.database.Watch(ctx, pipeline, opts)
+	}
+	return c.database.Collection(c.collection).Watch(ctx, pipeline, opts)
+}
+
+func (c *changeStreamInput) Connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.eventsCh != nil {
+		return nil
+	}
+
+	// A stored checkpoint only takes precedence upon the first connection,
+	// after which reconnects resume after the latest change event read.
+	if !c.resumed {
+		token, err := c.readCheckpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to obtain checkpoint: %w", err)
+		}
+		c.resumeToken = token
+	}
+
+	opts := *c.opts
+	if c.resumeToken != nil {
+		opts.SetStartAfter(c.resumeToken)
+	}
+
+	cs, err := c.watch(ctx, &opts)
+	if err != nil {
+		return err
+	}
+
+	streamCtx, streamDone := c.shutSig.SoftStopCtx(context.Background())
+
+	eventsCh, errCh := make(chan changeEvent), make(chan error, 1)
+	go func() {
+		defer close(eventsCh)
+		errCh <- readChangeStream(streamCtx, cs, eventsCh)
+	}()
+
+	c.resumed = true
+	c.eventsCh, c.errCh = eventsCh, errCh
+	c.closeFn = streamDone
+	return nil
+}
+
+// readChangeStream reads change events from a change stream until it ends, the
+// context is cancelled or an error occurs.
+func readChangeStream(ctx context.Context, cs *mongo.ChangeStream, eventsCh chan<- changeEvent) error {
+	defer func() {
+		closeCtx, done := context.WithTimeout(context.Background(), 5*time.Second)
+		_ = cs.Close(closeCtx)
+		done()
+	}()
+	for cs.Next(ctx) {
+		select {
+		case eventsCh <- parseChangeEvent(cs.Current, cs.ResumeToken()):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return cs.Err()
+}
+
+func (c *changeStreamInput) disconnect() {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.closeFn != nil {
+		c.closeFn()
+		c.closeFn = nil
+	}
+	c.eventsCh, c.errCh = nil, nil
+}
+
+func (c *changeStreamInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	c.connMut.Lock()
+	eventsCh, errCh := c.eventsCh, c.errCh
+	c.connMut.Unlock()
+
+	if eventsCh == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var event changeEvent
+	var open bool
+	select {
+	case event, open = <-eventsCh:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	if !open {
+		c.disconnect()
+		if err := <-errCh; err != nil {
+			c.log.Errorf("Change stream failed: %v", err)
+		}
+		return nil, nil, service.ErrNotConnected
+	}
+
+	c.connMut.Lock()
+	c.resumeToken = event.token
+	c.connMut.Unlock()
+
+	data, err := bson.MarshalExtJSON(event.raw, c.marshalCanon, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal change event: %w", err)
+	}
+
+	msg := service.NewMessage(data)
+	msg.MetaSetMut("mongo_database", event.database)
+	msg.MetaSetMut("mongo_collection", event.collection)
+	msg.MetaSetMut("mongo_operation_type", event.operation)
+
+	release, err := c.checkpointer.Track(ctx, event.token, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return msg, func(ctx context.Context, err error) error {
+		highestToken := release()
+		if highestToken == nil || c.cache == "" {
+			return nil
+		}
+		var setErr error
+		if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+			setErr = cache.Set(ctx, c.cacheKey, *highestToken, nil)
+		}); err != nil {
+			return err
+		}
+		return setErr
+	}, nil
+}
+
+func (c *changeStreamInput) Close(ctx context.Context) error {
+	c.shutSig.TriggerSoftStop()
+	c.disconnect()
+	return c.client.Disconnect(ctx)
+}
@@ -0,0 +1,201 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+	"github.com/benthosdev/benthos/v4/public/service/integration"
+)
+
+func TestChangeStreamInputConfig(t *testing.T) {
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	for _, test := range []struct {
+		name        string
+		conf        string
+		errContains string
+	}{
+		{
+			name:        "missing collection",
+			conf:        `scope: collection`,
+			errContains: "a collection must be specified",
+		},
+		{
+			name: "pipeline not an array",
+			conf: `
+scope: database
+pipeline: 'root = { "$match": {} }'
+`,
+			errContains: "pipeline mapping must result in an array",
+		},
+		{
+			name: "missing cache",
+			conf: `
+collection: bar
+checkpoint_cache: bar
+`,
+			errContains: "was not found",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := changeStreamInputSpec().ParseYAML(`
+url: mongodb://localhost:27017
+database: foo
+`+test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newChangeStreamInputFromParsed(pConf, mRes)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+
+	pConf, err := changeStreamInputSpec().ParseYAML(`
+url: mongodb://localhost:27017
+database: foo
+scope: deployment
+full_document: updateLookup
+pipeline: 'root = [ { "$match": { "operationType": "insert" } } ]'
+checkpoint_cache: foo
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newChangeStreamInputFromParsed(pConf, mRes)
+	require.NoError(t, err)
+
+	assert.Equal(t, []any{map[string]any{"$match": map[string]any{"operationType": "insert"}}}, i.pipeline)
+	assert.Equal(t, options.UpdateLookup, *i.opts.FullDocument)
+	require.NoError(t, i.Close(context.Background()))
+}
+
+func TestChangeStreamParseEvent(t *testing.T) {
+	raw, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "8263"}}},
+		{Key: "operationType", Value: "insert"},
+		{Key: "ns", Value: bson.D{{Key: "db", Value: "shop"}, {Key: "coll", Value: "orders"}}},
+	})
+	require.NoError(t, err)
+
+	token, err := bson.Marshal(bson.D{{Key: "_data", Value: "8263"}})
+	require.NoError(t, err)
+
+	e := parseChangeEvent(raw, token)
+	assert.Equal(t, "insert", e.operation)
+	assert.Equal(t, "shop", e.database)
+	assert.Equal(t, "orders", e.collection)
+	assert.Equal(t, bson.Raw(token), e.token)
+
+	// The event must not share memory with the change stream.
+	raw[len(raw)-2] = 'x'
+	assert.Equal(t, "orders", e.collection)
+}
+
+func TestChangeStreamInputIntegration(t *testing.T) {
+	integration.CheckSkip(t)
+
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Skipf("Could not connect to docker: %s", err)
+	}
+	pool.MaxWait = time.Minute
+
+	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "mongo",
+		Tag:          "latest",
+		Cmd:          []string{"--replSet", "rs0", "--bind_ip_all"},
+		ExposedPorts: []string{"27017"},
+	})
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		assert.NoError(t, pool.Purge(resource))
+	})
+
+	url := fmt.Sprintf("mongodb://localhost:%v/?directConnection=true", resource.GetPort("27017/tcp"))
+
+	var mongoClient *mongo.Client
+	require.NoError(t, pool.Retry(func() error {
+		if mongoClient, err = mongo.Connect(context.Background(), options.Client().ApplyURI(url)); err != nil {
+			return err
+		}
+		err := mongoClient.Database("admin").RunCommand(context.Background(), bson.D{{Key: "replSetInitiate", Value: bson.D{
+			{Key: "_id", Value: "rs0"},
+			{Key: "members", Value: bson.A{bson.D{{Key: "_id", Value: 0}, {Key: "host", Value: "localhost:27017"}}}},
+		}}}).Err()
+		if err != nil {
+			_ = mongoClient.Disconnect(context.Background())
+			return err
+		}
+		return nil
+	}))
+	t.Cleanup(func() {
+		_ = mongoClient.Disconnect(context.Background())
+	})
+
+	coll := mongoClient.Database("shop").Collection("orders")
+	require.NoError(t, pool.Retry(func() error {
+		_, err := coll.InsertOne(context.Background(), bson.M{"name": "init"})
+		return err
+	}))
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	newInput := func() *changeStreamInput {
+		pConf, err := changeStreamInputSpec().ParseYAML(fmt.Sprintf(`
+url: %v
+database: shop
+collection: orders
+json_marshal_mode: relaxed
+pipeline: 'root = [ { "$match": { "operationType": "insert" } } ]'
+checkpoint_cache: foo
+`, url), nil)
+		require.NoError(t, err)
+
+		i, err := newChangeStreamInputFromParsed(pConf, mRes)
+		require.NoError(t, err)
+		require.NoError(t, i.Connect(context.Background()))
+		return i
+	}
+
+	readName := func(i *changeStreamInput) string {
+		ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+		defer done()
+
+		msg, ackFn, err := i.Read(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		op, _ := msg.MetaGet("mongo_operation_type")
+		assert.Equal(t, "insert", op)
+
+		v, err := msg.AsStructured()
+		require.NoError(t, err)
+		name, _ := v.(map[string]any)["fullDocument"].(map[string]any)["name"].(string)
+		return name
+	}
+
+	i := newInput()
+	_, err = coll.InsertOne(context.Background(), bson.M{"name": "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, "foo", readName(i))
+	require.NoError(t, i.Close(context.Background()))
+
+	// Changes made while the input is stopped are consumed from the stored
+	// resume token.
+	_, err = coll.InsertOne(context.Background(), bson.M{"name": "bar"})
+	require.NoError(t, err)
+
+	i = newInput()
+	assert.Equal(t, "bar", readName(i))
+	require.NoError(t, i.Close(context.Background()))
+}
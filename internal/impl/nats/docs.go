@@ -8,6 +8,7 @@ import (
 
 const (
 	kvFieldBucket = "bucket"
+	osFieldBucket = "bucket"
 )
 
 const (
@@ -46,3 +47,15 @@ func kvDocs(extraFields ...*service.ConfigField) []*service.ConfigField {
 
 	return fields
 }
+
+func objectStoreDocs(extraFields ...*service.ConfigField) []*service.ConfigField {
+	fields := append(
+		connectionHeadFields(),
+		service.NewStringField(osFieldBucket).
+			Description("The name of the object store bucket.").Example("my_object_store"),
+	)
+	fields = append(fields, extraFields...)
+	fields = append(fields, connectionTailFields()...)
+
+	return fields
+}
@@ -0,0 +1,250 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	osiFieldUpdatesOnly   = "updates_only"
+	osiFieldDeleteObjects = "delete_objects"
+)
+
+func natsObjectStoreInputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Watches for objects put in a NATS object store bucket and reads them.").
+		Description(`
+Each object is consumed as a message once it has been put in the bucket, where objects
+that already exist when the input connects are consumed first unless ` + "`updates_only`" + `
+is set. Deleted objects and links are ignored. When an object is replaced while the input
+is disconnected only the latest version of it is consumed.
+
+Objects can be deleted from the bucket once they have been processed successfully by
+setting ` + "`delete_objects`" + ` to ` + "`true`" + `, which is useful for passing payloads
+between pipelines without accumulating them.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+` + "``` text" + `
+- nats_object_store_bucket
+- nats_object_store_name
+- nats_object_store_size
+- nats_object_store_digest
+- nats_object_store_modified
+- All object headers
+` + "```" + `
+
+` + connectionNameDescription() + authDescription()).
+		Fields(objectStoreDocs([]*service.ConfigField{
+			service.NewBoolField(osiFieldUpdatesOnly).
+				Description("Only consume objects put in the bucket after the input connects, skipping existing objects.").
+				Default(false).
+				Advanced(),
+			service.NewBoolField(osiFieldDeleteObjects).
+				Description("Whether to delete objects from the bucket once they are processed successfully.").
+				Default(false),
+			service.NewAutoRetryNacksToggleField(),
+		}...)...)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"nats_object_store", natsObjectStoreInputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			reader, err := newObjectStoreReader(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, reader)
+		},
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+type objectStoreReader struct {
+	connDetails   connectionDetails
+	bucket        string
+	updatesOnly   bool
+	deleteObjects bool
+
+	log *service.Logger
+
+	shutSig *shutdown.Signaller
+
+	connMut     sync.Mutex
+	natsConn    *nats.Conn
+	objectStore jetstream.ObjectStore
+	watcher     jetstream.ObjectWatcher
+}
+
+func newObjectStoreReader(conf *service.ParsedConfig, mgr *service.Resources) (*objectStoreReader, error) {
+	r := &objectStoreReader{
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if r.connDetails, err = connectionDetailsFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+
+	if r.bucket, err = conf.FieldString(osFieldBucket); err != nil {
+		return nil, err
+	}
+
+	if r.updatesOnly, err = conf.FieldBool(osiFieldUpdatesOnly); err != nil {
+		return nil, err
+	}
+
+	if r.deleteObjects, err = conf.FieldBool(osiFieldDeleteObjects); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *objectStoreReader) Connect(ctx context.Context) (err error) {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.natsConn != nil {
+		return nil
+	}
+
+	defer func() {
+		if err != nil {
+			if r.watcher != nil {
+				_ = r.watcher.Stop()
+				r.watcher = nil
+			}
+			if r.natsConn != nil {
+				r.natsConn.Close()
+				r.natsConn = nil
+			}
+		}
+	}()
+
+	if r.natsConn, err = r.connDetails.get(ctx); err != nil {
+		return err
+	}
+
+	js, err := jetstream.New(r.natsConn)
+	if err != nil {
+		return err
+	}
+
+	if r.objectStore, err = js.ObjectStore(ctx, r.bucket); err != nil {
+		return err
+	}
+
+	watchOpts := []jetstream.WatchOpt{jetstream.IgnoreDeletes()}
+	if r.updatesOnly {
+		watchOpts = append(watchOpts, jetstream.UpdatesOnly())
+	}
+
+	r.watcher, err = r.objectStore.Watch(ctx, watchOpts...)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *objectStoreReader) disconnect() {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.watcher != nil {
+		_ = r.watcher.Stop()
+		r.watcher = nil
+	}
+	if r.natsConn != nil {
+		r.natsConn.Close()
+		r.natsConn = nil
+	}
+	r.objectStore = nil
+}
+
+func (r *objectStoreReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	r.connMut.Lock()
+	watcher, objectStore := r.watcher, r.objectStore
+	r.connMut.Unlock()
+
+	if watcher == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for {
+		var info *jetstream.ObjectInfo
+		var open bool
+		select {
+		case info, open = <-watcher.Updates():
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+
+		if !open {
+			r.disconnect()
+			return nil, nil, service.ErrNotConnected
+		}
+
+		// A nil update marks the end of the initial objects, and links have no
+		// contents of their own.
+		if info == nil || info.Deleted || (info.Opts != nil && info.Opts.Link != nil) {
+			continue
+		}
+
+		data, err := objectStore.GetBytes(ctx, info.Name)
+		if err != nil {
+			if errors.Is(err, jetstream.ErrObjectNotFound) {
+				// The object was deleted since the update was received.
+				continue
+			}
+			return nil, nil, err
+		}
+
+		r.log.With(
+			metaObjectStoreBucket, info.Bucket,
+			metaObjectStoreName, info.Name,
+			metaObjectStoreSize, info.Size,
+		).Debugf("Received object store update")
+
+		name := info.Name
+		return newMessageFromObject(info, data), func(ctx context.Context, res error) error {
+			if res != nil || !r.deleteObjects {
+				return nil
+			}
+			if err := objectStore.Delete(ctx, name); err != nil && !errors.Is(err, jetstream.ErrObjectNotFound) {
+				return err
+			}
+			return nil
+		}, nil
+	}
+}
+
+func (r *objectStoreReader) Close(ctx context.Context) error {
+	go func() {
+		r.disconnect()
+		r.shutSig.TriggerHasStopped()
+	}()
+	select {
+	case <-r.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
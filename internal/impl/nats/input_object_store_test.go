@@ -0,0 +1,58 @@
+package nats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestInputObjectStoreParse(t *testing.T) {
+	spec := natsObjectStoreInputConfig()
+	env := service.NewEnvironment()
+
+	conf, err := spec.ParseYAML(`
+urls: [ url1, url2 ]
+bucket: testbucket
+updates_only: true
+delete_objects: true
+`, env)
+	require.NoError(t, err)
+
+	r, err := newObjectStoreReader(conf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, "url1,url2", r.connDetails.urls)
+	assert.Equal(t, "testbucket", r.bucket)
+	assert.True(t, r.updatesOnly)
+	assert.True(t, r.deleteObjects)
+}
+
+func TestOutputObjectStoreParse(t *testing.T) {
+	spec := natsObjectStoreOutputConfig()
+	env := service.NewEnvironment()
+
+	conf, err := spec.ParseYAML(`
+urls: [ url1 ]
+bucket: testbucket
+object_name: ${! @name }.json
+metadata:
+  include_prefixes: [ foo_ ]
+`, env)
+	require.NoError(t, err)
+
+	o, err := newObjectStoreOutput(conf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, "url1", o.connDetails.urls)
+	assert.Equal(t, "testbucket", o.bucket)
+	assert.NotNil(t, o.metaFilter)
+
+	msg := service.NewMessage(nil)
+	msg.MetaSetMut("name", "foo")
+	name, err := o.objectName.TryString(msg)
+	require.NoError(t, err)
+	assert.Equal(t, "foo.json", name)
+}
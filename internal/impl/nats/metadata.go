@@ -15,6 +15,14 @@ const (
 	metaKVCreated   = "nats_kv_created"
 )
 
+const (
+	metaObjectStoreBucket   = "nats_object_store_bucket"
+	metaObjectStoreName     = "nats_object_store_name"
+	metaObjectStoreSize     = "nats_object_store_size"
+	metaObjectStoreDigest   = "nats_object_store_digest"
+	metaObjectStoreModified = "nats_object_store_modified"
+)
+
 func newMessageFromKVEntry(entry jetstream.KeyValueEntry) *service.Message {
 	msg := service.NewMessage(entry.Value())
 	msg.MetaSetMut(metaKVKey, entry.Key())
@@ -26,3 +34,17 @@ func newMessageFromKVEntry(entry jetstream.KeyValueEntry) *service.Message {
 
 	return msg
 }
+
+func newMessageFromObject(info *jetstream.ObjectInfo, data []byte) *service.Message {
+	msg := service.NewMessage(data)
+	for k := range info.Headers {
+		msg.MetaSetMut(k, info.Headers.Get(k))
+	}
+	msg.MetaSetMut(metaObjectStoreBucket, info.Bucket)
+	msg.MetaSetMut(metaObjectStoreName, info.Name)
+	msg.MetaSetMut(metaObjectStoreSize, info.Size)
+	msg.MetaSetMut(metaObjectStoreDigest, info.Digest)
+	msg.MetaSetMut(metaObjectStoreModified, info.ModTime)
+
+	return msg
+}
@@ -0,0 +1,212 @@
+package nats
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/Jeffail/shutdown"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	osoFieldObjectName = "object_name"
+	osoFieldMetadata   = "metadata"
+)
+
+func natsObjectStoreOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Put messages as objects in a NATS object store bucket.").
+		Description(`
+The field ` + "`object_name`" + ` supports
+[interpolation functions](/docs/configuration/interpolation#bloblang-queries), allowing
+you to create a unique name for each object. Putting an object with the name of an
+existing object replaces it.
+
+Objects are split into chunks by the NATS client and can therefore be larger than the
+maximum message size of the NATS server, which makes the object store suitable for
+passing payloads too large for a stream or KV bucket.
+
+` + connectionNameDescription() + authDescription()).
+		Fields(objectStoreDocs([]*service.ConfigField{
+			service.NewInterpolatedStringField(osoFieldObjectName).
+				Description("The name of each object.").
+				Example("foo.json").
+				Example(`reports/${! timestamp_unix() }-${! uuid_v4() }.json`),
+			service.NewMetadataFilterField(osoFieldMetadata).
+				Description("Determine which (if any) metadata values should be added to objects as headers.").
+				Optional(),
+			service.NewOutputMaxInFlightField().Default(64),
+		}...)...)
+}
+
+func init() {
+	err := service.RegisterOutput(
+		"nats_object_store", natsObjectStoreOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
+			maxInFlight, err := conf.FieldInt("max_in_flight")
+			if err != nil {
+				return nil, 0, err
+			}
+			w, err := newObjectStoreOutput(conf, mgr)
+			return w, maxInFlight, err
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type objectStoreOutput struct {
+	connDetails connectionDetails
+	bucket      string
+	objectName  *service.InterpolatedString
+	metaFilter  *service.MetadataFilter
+
+	log *service.Logger
+
+	connMut     sync.Mutex
+	natsConn    *nats.Conn
+	objectStore jetstream.ObjectStore
+
+	shutSig *shutdown.Signaller
+}
+
+func newObjectStoreOutput(conf *service.ParsedConfig, mgr *service.Resources) (*objectStoreOutput, error) {
+	o := objectStoreOutput{
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if o.connDetails, err = connectionDetailsFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+
+	if o.bucket, err = conf.FieldString(osFieldBucket); err != nil {
+		return nil, err
+	}
+
+	if o.objectName, err = conf.FieldInterpolatedString(osoFieldObjectName); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(osoFieldMetadata) {
+		if o.metaFilter, err = conf.FieldMetadataFilter(osoFieldMetadata); err != nil {
+			return nil, err
+		}
+	}
+	return &o, nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *objectStoreOutput) Connect(ctx context.Context) (err error) {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.natsConn != nil {
+		return nil
+	}
+
+	var natsConn *nats.Conn
+
+	defer func() {
+		if err != nil && natsConn != nil {
+			natsConn.Close()
+		}
+	}()
+
+	if natsConn, err = o.connDetails.get(ctx); err != nil {
+		return err
+	}
+
+	jsc, err := jetstream.New(natsConn)
+	if err != nil {
+		return err
+	}
+
+	o.objectStore, err = jsc.ObjectStore(ctx, o.bucket)
+	if err != nil {
+		return err
+	}
+
+	o.natsConn = natsConn
+	return nil
+}
+
+func (o *objectStoreOutput) disconnect() {
+	o.connMut.Lock()
+	defer o.connMut.Unlock()
+
+	if o.natsConn != nil {
+		o.natsConn.Close()
+		o.natsConn = nil
+	}
+	o.objectStore = nil
+}
+
+//------------------------------------------------------------------------------
+
+func (o *objectStoreOutput) Write(ctx context.Context, msg *service.Message) error {
+	o.connMut.Lock()
+	objectStore := o.objectStore
+	o.connMut.Unlock()
+	if objectStore == nil {
+		return service.ErrNotConnected
+	}
+
+	data, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+
+	name, err := o.objectName.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("object_name interpolation error: %w", err)
+	}
+
+	meta := jetstream.ObjectMeta{
+		Name:    name,
+		Headers: nats.Header{},
+	}
+	_ = o.metaFilter.Walk(msg, func(key, value string) error {
+		meta.Headers.Add(key, value)
+		return nil
+	})
+
+	info, err := objectStore.Put(ctx, meta, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	o.log.With(
+		metaObjectStoreBucket, info.Bucket,
+		metaObjectStoreName, info.Name,
+		metaObjectStoreSize, info.Size,
+	).Debug("Put object in object store bucket")
+
+	return nil
+}
+
+func (o *objectStoreOutput) Close(ctx context.Context) error {
+	go func() {
+		o.disconnect()
+		o.shutSig.TriggerHasStopped()
+	}()
+	select {
+	case <-o.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package netlookup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	lFieldTimeout          = "timeout"
+	lFieldCache            = "cache"
+	lFieldCacheTTL         = "cache_ttl"
+	lFieldNegativeCacheTTL = "negative_cache_ttl"
+	lFieldRateLimit        = "rate_limit"
+)
+
+// errNotFound is returned by lookup functions when the subject of the lookup
+// does not exist, which is a result that is cached with the negative TTL.
+var errNotFound = errors.New("not found")
+
+func lookupFields(defaultTimeout string) []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewDurationField(lFieldTimeout).
+			Description("The maximum period of time to wait for each lookup to complete.").
+			Default(defaultTimeout),
+		service.NewStringField(lFieldCache).
+			Description("An optional [`cache` resource](/docs/components/caches/about) used to store the results of lookups, which are reused for subsequent messages with the same query.").
+			Optional(),
+		service.NewDurationField(lFieldCacheTTL).
+			Description("The period of time for which successful lookup results are cached. Some caches only have a general TTL and will therefore ignore this setting.").
+			Default("1h").
+			Advanced(),
+		service.NewDurationField(lFieldNegativeCacheTTL).
+			Description("The period of time for which lookups of records that do not exist are cached. Some caches only have a general TTL and will therefore ignore this setting.").
+			Default("5m").
+			Advanced(),
+		service.NewStringField(lFieldRateLimit).
+			Description("An optional [`rate_limit` resource](/docs/components/rate_limits/about) to throttle lookups by, cache hits do not count towards the limit. Referencing the same resource from multiple components allows them to share a single limit.").
+			Optional(),
+	}
+}
+
+// cachedLookup wraps the caching, negative caching and rate limiting that is
+// common to all lookup processors.
+type cachedLookup struct {
+	mgr       *service.Resources
+	timeout   time.Duration
+	cache     string
+	ttl       time.Duration
+	negTTL    time.Duration
+	rateLimit string
+}
+
+func cachedLookupFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*cachedLookup, error) {
+	c := &cachedLookup{mgr: mgr}
+
+	var err error
+	if c.timeout, err = conf.FieldDuration(lFieldTimeout); err != nil {
+		return nil, err
+	}
+	if conf.Contains(lFieldCache) {
+		if c.cache, err = conf.FieldString(lFieldCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(c.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", c.cache)
+		}
+	}
+	if c.ttl, err = conf.FieldDuration(lFieldCacheTTL); err != nil {
+		return nil, err
+	}
+	if c.negTTL, err = conf.FieldDuration(lFieldNegativeCacheTTL); err != nil {
+		return nil, err
+	}
+	if conf.Contains(lFieldRateLimit) {
+		if c.rateLimit, err = conf.FieldString(lFieldRateLimit); err != nil {
+			return nil, err
+		}
+		if !mgr.HasRateLimit(c.rateLimit) {
+			return nil, fmt.Errorf("rate limit resource '%v' was not found", c.rateLimit)
+		}
+	}
+	return c, nil
+}
+
+func (c *cachedLookup) waitForAccess(ctx context.Context) error {
+	if c.rateLimit == "" {
+		return nil
+	}
+	for {
+		var period time.Duration
+		var err error
+		if rerr := c.mgr.AccessRateLimit(ctx, c.rateLimit, func(rl service.RateLimit) {
+			period, err = rl.Access(ctx)
+		}); rerr != nil {
+			err = rerr
+		}
+		if err != nil {
+			c.mgr.Logger().Errorf("Rate limit error: %v", err)
+			period = time.Second
+		}
+		if period <= 0 {
+			return nil
+		}
+		select {
+		case <-time.After(period):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// cachedResult is the format of lookup results stored within a cache, where
+// a nil result represents a negative entry.
+type cachedResult struct {
+	Result   any  `json:"result"`
+	NotFound bool `json:"not_found,omitempty"`
+}
+
+func (c *cachedLookup) fromCache(ctx context.Context, key string) (res cachedResult, hit bool) {
+	if c.cache == "" {
+		return
+	}
+	var resBytes []byte
+	var err error
+	if cerr := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+		resBytes, err = cache.Get(ctx, key)
+	}); cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		if !errors.Is(err, service.ErrKeyNotFound) {
+			c.mgr.Logger().Warnf("Failed to read lookup result from cache: %v", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(resBytes, &res); err != nil {
+		c.mgr.Logger().Warnf("Failed to parse cached lookup result: %v", err)
+		return
+	}
+	return res, true
+}
+
+func (c *cachedLookup) toCache(ctx context.Context, key string, res cachedResult) {
+	if c.cache == "" {
+		return
+	}
+	resBytes, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	ttl := c.ttl
+	if res.NotFound {
+		ttl = c.negTTL
+	}
+	if cerr := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+		err = cache.Set(ctx, key, resBytes, &ttl)
+	}); cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		c.mgr.Logger().Warnf("Failed to write lookup result to cache: %v", err)
+	}
+}
+
+// Do returns the result of a lookup identified by key, either from the cache
+// or by calling fn. A lookup that yields errNotFound is returned as such.
+func (c *cachedLookup) Do(ctx context.Context, key string, fn func(ctx context.Context) (any, error)) (any, error) {
+	if res, hit := c.fromCache(ctx, key); hit {
+		if res.NotFound {
+			return nil, errNotFound
+		}
+		return res.Result, nil
+	}
+
+	if err := c.waitForAccess(ctx); err != nil {
+		return nil, err
+	}
+
+	lCtx, done := context.WithTimeout(ctx, c.timeout)
+	defer done()
+
+	res, err := fn(lCtx)
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			c.toCache(ctx, key, cachedResult{NotFound: true})
+		}
+		return nil, err
+	}
+	c.toCache(ctx, key, cachedResult{Result: res})
+	return res, nil
+}
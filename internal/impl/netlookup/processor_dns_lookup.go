@@ -0,0 +1,289 @@
+package netlookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dlFieldQuery     = "query"
+	dlFieldTypes     = "types"
+	dlFieldResolvers = "resolvers"
+)
+
+var dnsLookupTypes = map[string]struct{}{
+	"A": {}, "AAAA": {}, "CNAME": {}, "MX": {}, "NS": {}, "PTR": {}, "TXT": {},
+}
+
+func dnsLookupProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Performs DNS lookups of a name or IP address derived from each message and replaces the message with the results.").
+		Description(`
+The result of each lookup is an object containing the query and a field for each record type requested, where each field contains an array of results (or a string in the case of `+"`cname`"+`). Record types that do not exist for a given query are given empty results, and therefore a lookup of a name that does not exist at all results in an object where all record types are empty.
+
+Since the contents of messages are replaced with the lookup results this processor is usually combined with a `+"[`branch` processor](/docs/components/processors/branch)"+` in order to map the results back into the original message.
+
+### Caching
+
+When a `+"`cache`"+` is configured lookup results are stored within it and reused for subsequent messages resulting in the same query. Lookups of names that do not exist are stored with the `+"`negative_cache_ttl`"+` so that repeated lookups of them don't reach the resolvers.
+
+### Rate Limiting
+
+A `+"`rate_limit`"+` resource can be referenced in order to protect resolvers from excessive traffic, this limit can be shared with other components such as the `+"[`rdap_lookup` processor](/docs/components/processors/rdap_lookup)"+`.`).
+		Example(
+			"Enriching logs with reverse DNS",
+			"Adds the names that the source IP address of each log reverse maps to, using a dedicated resolver and caching the results.",
+			`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - dns_lookup:
+              query: ${! this.source_ip }
+              types: [ PTR ]
+              resolvers: [ 10.0.0.53:53 ]
+              cache: dns_cache
+        result_map: 'root.source_names = this.ptr'
+
+cache_resources:
+  - label: dns_cache
+    memory:
+      default_ttl: 1h
+`,
+		).
+		Fields(
+			service.NewInterpolatedStringField(dlFieldQuery).
+				Description("The name or, for PTR lookups, IP address to look up.").
+				Examples(`${! this.domain }`, `${! meta("host") }`),
+			service.NewStringListField(dlFieldTypes).
+				Description("The record types to look up. Supported types are `A`, `AAAA`, `CNAME`, `MX`, `NS`, `PTR` and `TXT`. Results of `MX` lookups are objects containing a `host` and `preference`.").
+				Default([]any{"A", "AAAA"}),
+			service.NewStringListField(dlFieldResolvers).
+				Description("An optional list of resolver addresses of the form `host:port` to send queries to, one of which is chosen at random for each query. When empty the resolvers configured for the host system are used.").
+				Example([]any{"1.1.1.1:53", "8.8.8.8:53"}).
+				Default([]any{}),
+		).
+		Fields(lookupFields("5s")...)
+}
+
+func init() {
+	err := service.RegisterProcessor("dns_lookup", dnsLookupProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return dnsLookupProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// dnsResolver contains the subset of net.Resolver methods used by the
+// processor.
+type dnsResolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+type dnsLookupProc struct {
+	query    *service.InterpolatedString
+	types    []string
+	resolver dnsResolver
+	lookup   *cachedLookup
+}
+
+func dnsLookupProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*dnsLookupProc, error) {
+	p := &dnsLookupProc{}
+
+	var err error
+	if p.query, err = conf.FieldInterpolatedString(dlFieldQuery); err != nil {
+		return nil, err
+	}
+
+	if p.types, err = conf.FieldStringList(dlFieldTypes); err != nil {
+		return nil, err
+	}
+	if len(p.types) == 0 {
+		return nil, errors.New("at least one record type must be specified")
+	}
+	for i, t := range p.types {
+		t = strings.ToUpper(t)
+		if _, exists := dnsLookupTypes[t]; !exists {
+			return nil, fmt.Errorf("unsupported record type: %v", t)
+		}
+		p.types[i] = t
+	}
+	sort.Strings(p.types)
+
+	resolvers, err := conf.FieldStringList(dlFieldResolvers)
+	if err != nil {
+		return nil, err
+	}
+	p.resolver = newResolver(resolvers)
+
+	if p.lookup, err = cachedLookupFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func newResolver(addresses []string) *net.Resolver {
+	if len(addresses) == 0 {
+		return net.DefaultResolver
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addresses[rand.Intn(len(addresses))])
+		},
+	}
+}
+
+func isNotFound(err error) bool {
+	var dErr *net.DNSError
+	return errors.As(err, &dErr) && dErr.IsNotFound
+}
+
+func stringsToAny(s []string) []any {
+	a := make([]any, len(s))
+	for i, v := range s {
+		a[i] = v
+	}
+	return a
+}
+
+func (p *dnsLookupProc) lookupType(ctx context.Context, t, query string) (any, error) {
+	switch t {
+	case "A", "AAAA":
+		network := "ip4"
+		if t == "AAAA" {
+			network = "ip6"
+		}
+		ips, err := p.resolver.LookupIP(ctx, network, query)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]any, len(ips))
+		for i, ip := range ips {
+			res[i] = ip.String()
+		}
+		return res, nil
+	case "CNAME":
+		return p.resolver.LookupCNAME(ctx, query)
+	case "MX":
+		mxs, err := p.resolver.LookupMX(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]any, len(mxs))
+		for i, mx := range mxs {
+			res[i] = map[string]any{
+				"host":       mx.Host,
+				"preference": int64(mx.Pref),
+			}
+		}
+		return res, nil
+	case "NS":
+		nss, err := p.resolver.LookupNS(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]any, len(nss))
+		for i, ns := range nss {
+			res[i] = ns.Host
+		}
+		return res, nil
+	case "PTR":
+		names, err := p.resolver.LookupAddr(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return stringsToAny(names), nil
+	case "TXT":
+		txts, err := p.resolver.LookupTXT(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return stringsToAny(txts), nil
+	}
+	return nil, fmt.Errorf("unsupported record type: %v", t)
+}
+
+func (p *dnsLookupProc) resolve(ctx context.Context, query string) (any, error) {
+	res := map[string]any{"query": query}
+
+	notFound := 0
+	for _, t := range p.types {
+		v, err := p.lookupType(ctx, t, query)
+		if err != nil {
+			if !isNotFound(err) {
+				return nil, fmt.Errorf("%v lookup failed: %w", t, err)
+			}
+			notFound++
+			if t == "CNAME" {
+				v = ""
+			} else {
+				v = []any{}
+			}
+		}
+		res[strings.ToLower(t)] = v
+	}
+	if notFound == len(p.types) {
+		return nil, errNotFound
+	}
+	return res, nil
+}
+
+// emptyResult returns the result of a query where no records exist.
+func (p *dnsLookupProc) emptyResult(query string) map[string]any {
+	res := map[string]any{"query": query}
+	for _, t := range p.types {
+		if t == "CNAME" {
+			res["cname"] = ""
+		} else {
+			res[strings.ToLower(t)] = []any{}
+		}
+	}
+	return res
+}
+
+func (p *dnsLookupProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	query, err := p.query.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("query interpolation error: %w", err)
+	}
+	if query == "" {
+		return nil, errors.New("query must not be empty")
+	}
+
+	key := "dns:" + strings.Join(p.types, ",") + ":" + query
+	res, err := p.lookup.Do(ctx, key, func(ctx context.Context) (any, error) {
+		return p.resolve(ctx, query)
+	})
+	if err != nil {
+		if !errors.Is(err, errNotFound) {
+			return nil, err
+		}
+		res = p.emptyResult(query)
+	}
+
+	msg.SetStructuredMut(res)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *dnsLookupProc) Close(ctx context.Context) error {
+	return nil
+}
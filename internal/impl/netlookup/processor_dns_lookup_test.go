@@ -0,0 +1,233 @@
+package netlookup
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeResolver struct {
+	calls atomic.Int64
+	hosts map[string][]net.IP
+	mxs   map[string][]*net.MX
+	txts  map[string][]string
+	ptrs  map[string][]string
+	err   error
+}
+
+func (f *fakeResolver) notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (f *fakeResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	f.calls.Add(1)
+	if f.err != nil {
+		return nil, f.err
+	}
+	var res []net.IP
+	for _, ip := range f.hosts[host] {
+		if (ip.To4() != nil) == (network == "ip4") {
+			res = append(res, ip)
+		}
+	}
+	if len(res) == 0 {
+		return nil, f.notFound(host)
+	}
+	return res, nil
+}
+
+func (f *fakeResolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	f.calls.Add(1)
+	return "", f.notFound(host)
+}
+
+func (f *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	f.calls.Add(1)
+	if mxs, ok := f.mxs[name]; ok {
+		return mxs, nil
+	}
+	return nil, f.notFound(name)
+}
+
+func (f *fakeResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	f.calls.Add(1)
+	return nil, f.notFound(name)
+}
+
+func (f *fakeResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	f.calls.Add(1)
+	if ptrs, ok := f.ptrs[addr]; ok {
+		return ptrs, nil
+	}
+	return nil, f.notFound(addr)
+}
+
+func (f *fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	f.calls.Add(1)
+	if txts, ok := f.txts[name]; ok {
+		return txts, nil
+	}
+	return nil, f.notFound(name)
+}
+
+func testDNSProc(t testing.TB, confStr string, res *service.Resources, resolver dnsResolver) *dnsLookupProc {
+	t.Helper()
+
+	pConf, err := dnsLookupProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := dnsLookupProcFromParsed(pConf, res)
+	require.NoError(t, err)
+
+	proc.resolver = resolver
+	return proc
+}
+
+func processStructured(t testing.TB, proc service.Processor, content string) any {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage([]byte(content)))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	return v
+}
+
+func TestDNSLookup(t *testing.T) {
+	resolver := &fakeResolver{
+		hosts: map[string][]net.IP{
+			"example.com": {net.IPv4(93, 184, 216, 34), net.ParseIP("2606:2800:220:1:248:1893:25c8:1946")},
+		},
+		mxs: map[string][]*net.MX{
+			"example.com": {{Host: "mail.example.com.", Pref: 10}},
+		},
+		txts: map[string][]string{
+			"example.com": {"v=spf1 -all"},
+		},
+	}
+
+	proc := testDNSProc(t, `
+query: ${! content() }
+types: [ a, AAAA, MX, TXT ]
+`, service.MockResources(), resolver)
+
+	assert.Equal(t, map[string]any{
+		"query": "example.com",
+		"a":     []any{"93.184.216.34"},
+		"aaaa":  []any{"2606:2800:220:1:248:1893:25c8:1946"},
+		"mx": []any{
+			map[string]any{"host": "mail.example.com.", "preference": int64(10)},
+		},
+		"txt": []any{"v=spf1 -all"},
+	}, processStructured(t, proc, "example.com"))
+
+	assert.Equal(t, map[string]any{
+		"query": "nope.example.com",
+		"a":     []any{},
+		"aaaa":  []any{},
+		"mx":    []any{},
+		"txt":   []any{},
+	}, processStructured(t, proc, "nope.example.com"))
+}
+
+func TestDNSLookupCaching(t *testing.T) {
+	resolver := &fakeResolver{
+		ptrs: map[string][]string{
+			"10.0.0.1": {"foo.internal."},
+		},
+	}
+
+	proc := testDNSProc(t, `
+query: ${! content() }
+types: [ PTR ]
+cache: foocache
+`, service.MockResources(service.MockResourcesOptAddCache("foocache")), resolver)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, map[string]any{
+			"query": "10.0.0.1",
+			"ptr":   []any{"foo.internal."},
+		}, processStructured(t, proc, "10.0.0.1"))
+	}
+	assert.Equal(t, int64(1), resolver.calls.Load())
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, map[string]any{
+			"query": "10.0.0.2",
+			"ptr":   []any{},
+		}, processStructured(t, proc, "10.0.0.2"))
+	}
+	assert.Equal(t, int64(2), resolver.calls.Load())
+}
+
+func TestDNSLookupErrors(t *testing.T) {
+	resolver := &fakeResolver{err: errors.New("nope")}
+
+	proc := testDNSProc(t, `
+query: ${! content() }
+cache: foocache
+`, service.MockResources(service.MockResourcesOptAddCache("foocache")), resolver)
+
+	for i := 0; i < 2; i++ {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte("example.com")))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "nope")
+	}
+	assert.Equal(t, int64(2), resolver.calls.Load())
+}
+
+func TestDNSLookupRateLimit(t *testing.T) {
+	var accessed atomic.Int64
+	res := service.MockResources(service.MockResourcesOptAddRateLimit("foolimit", func(context.Context) (time.Duration, error) {
+		accessed.Add(1)
+		return 0, nil
+	}))
+
+	resolver := &fakeResolver{}
+	proc := testDNSProc(t, `
+query: ${! content() }
+types: [ A ]
+rate_limit: foolimit
+`, res, resolver)
+
+	_ = processStructured(t, proc, "foo")
+	_ = processStructured(t, proc, "bar")
+	assert.Equal(t, int64(2), accessed.Load())
+}
+
+func TestDNSLookupBadConfig(t *testing.T) {
+	for _, confStr := range []string{
+		`
+query: foo
+types: [ NOPE ]
+`,
+		`
+query: foo
+types: []
+`,
+		`
+query: foo
+cache: nocache
+`,
+		`
+query: foo
+rate_limit: nolimit
+`,
+	} {
+		pConf, err := dnsLookupProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = dnsLookupProcFromParsed(pConf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
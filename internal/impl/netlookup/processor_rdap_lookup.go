@@ -0,0 +1,374 @@
+package netlookup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rlFieldQuery   = "query"
+	rlFieldType    = "type"
+	rlFieldBaseURL = "base_url"
+)
+
+func rdapLookupProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Integration").
+		Summary("Performs [RDAP](https://about.rdap.org/) queries for domains, IP addresses and autonomous system numbers derived from each message and replaces the message with a normalised summary of the registration data.").
+		Description(`
+RDAP is the structured successor of WHOIS and is supported by all regional internet registries and generic top-level domain registries. By default queries are sent to [rdap.org](https://rdap.org), which redirects them to the authoritative server of the queried resource.
+
+The fields of the resulting object depend on the type of the resource queried, all results contain the fields `+"`query`"+`, `+"`object_class`"+`, `+"`handle`"+` and `+"`name`"+`, and in addition:
+
+- Domains contain `+"`status`"+`, `+"`nameservers`"+`, `+"`registrar`"+` (an object containing a `+"`name`"+` and `+"`iana_id`"+`), `+"`registered`"+`, `+"`expires`"+` and `+"`last_changed`"+`.
+- IP networks contain `+"`start_address`"+`, `+"`end_address`"+`, `+"`ip_version`"+`, `+"`type`"+`, `+"`country`"+`, `+"`parent_handle`"+`, `+"`registrant`"+` and, when provided by the registry, `+"`origin_asns`"+`.
+- Autonomous system numbers contain `+"`start_autnum`"+`, `+"`end_autnum`"+`, `+"`type`"+`, `+"`country`"+` and `+"`registrant`"+`.
+
+Queries for resources that do not exist result in an error, which can be handled using [error handling patterns](/docs/configuration/error_handling).
+
+### Caching
+
+When a `+"`cache`"+` is configured lookup results are stored within it and reused for subsequent messages resulting in the same query. Queries for resources that do not exist are stored with the `+"`negative_cache_ttl`"+` so that repeated queries of them don't reach the RDAP servers.
+
+### Rate Limiting
+
+Public RDAP servers are strict about request rates, it is therefore strongly recommended to reference a `+"`rate_limit`"+` resource, which can be shared with other components such as the `+"[`dns_lookup` processor](/docs/components/processors/dns_lookup)"+`.`).
+		Example(
+			"Domain registration details",
+			"Adds the registrar and registration date of the domain of each message, caching results for a day and limiting queries to one per second.",
+			`
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - rdap_lookup:
+              query: ${! this.domain }
+              cache: rdap_cache
+              cache_ttl: 24h
+              rate_limit: rdap_limit
+        result_map: |
+          root.registrar = this.registrar.name
+          root.registered = this.registered
+
+cache_resources:
+  - label: rdap_cache
+    memory:
+      default_ttl: 24h
+
+rate_limit_resources:
+  - label: rdap_limit
+    local:
+      count: 1
+      interval: 1s
+`,
+		).
+		Fields(
+			service.NewInterpolatedStringField(rlFieldQuery).
+				Description("The domain, IP address, CIDR block or autonomous system number to query.").
+				Examples(`${! this.domain }`, `${! this.source_ip }`, `AS${! this.asn }`),
+			service.NewStringAnnotatedEnumField(rlFieldType, map[string]string{
+				"auto":   "Detect the type of resource from the query, IP addresses and CIDR blocks are queried as `ip`, numbers optionally prefixed with `AS` are queried as `autnum` and everything else as `domain`.",
+				"domain": "Query a domain name.",
+				"ip":     "Query an IP address or CIDR block.",
+				"autnum": "Query an autonomous system number.",
+			}).
+				Description("The type of resource being queried.").
+				Default("auto"),
+			service.NewURLField(rlFieldBaseURL).
+				Description("The base URL of the RDAP service to query.").
+				Default("https://rdap.org").
+				Advanced(),
+		).
+		Fields(lookupFields("10s")...)
+}
+
+func init() {
+	err := service.RegisterProcessor("rdap_lookup", rdapLookupProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return rdapLookupProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type rdapLookupProc struct {
+	query     *service.InterpolatedString
+	queryType string
+	baseURL   string
+	client    *http.Client
+	lookup    *cachedLookup
+}
+
+func rdapLookupProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*rdapLookupProc, error) {
+	p := &rdapLookupProc{client: &http.Client{}}
+
+	var err error
+	if p.query, err = conf.FieldInterpolatedString(rlFieldQuery); err != nil {
+		return nil, err
+	}
+	if p.queryType, err = conf.FieldString(rlFieldType); err != nil {
+		return nil, err
+	}
+	if p.baseURL, err = conf.FieldString(rlFieldBaseURL); err != nil {
+		return nil, err
+	}
+	p.baseURL = strings.TrimSuffix(p.baseURL, "/")
+	if p.lookup, err = cachedLookupFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+var autnumRegexp = regexp.MustCompile(`^(?i:AS)?(\d+)$`)
+
+// resolvePath returns the RDAP path segment and normalised query for a query
+// and type.
+func resolvePath(queryType, query string) (string, string, error) {
+	if queryType == "auto" {
+		switch {
+		case net.ParseIP(query) != nil:
+			queryType = "ip"
+		case strings.Contains(query, "/"):
+			if _, _, err := net.ParseCIDR(query); err != nil {
+				return "", "", fmt.Errorf("invalid CIDR block: %w", err)
+			}
+			queryType = "ip"
+		case autnumRegexp.MatchString(query):
+			queryType = "autnum"
+		default:
+			queryType = "domain"
+		}
+	}
+	switch queryType {
+	case "autnum":
+		m := autnumRegexp.FindStringSubmatch(query)
+		if m == nil {
+			return "", "", fmt.Errorf("invalid autonomous system number: %v", query)
+		}
+		return "autnum", m[1], nil
+	case "domain":
+		return "domain", strings.TrimSuffix(strings.ToLower(query), "."), nil
+	}
+	return queryType, query, nil
+}
+
+func (p *rdapLookupProc) fetch(ctx context.Context, path, query string) (map[string]any, error) {
+	// CIDR blocks are represented as two path segments.
+	if path != "ip" {
+		query = url.PathEscape(query)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"/"+path+"/"+query, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil, errNotFound
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil, fmt.Errorf("RDAP server returned status: %v", res.Status)
+	}
+
+	var obj map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("failed to parse RDAP response: %w", err)
+	}
+	return obj, nil
+}
+
+func (p *rdapLookupProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	query, err := p.query.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("query interpolation error: %w", err)
+	}
+	if query = strings.TrimSpace(query); query == "" {
+		return nil, errors.New("query must not be empty")
+	}
+
+	path, query, err := resolvePath(p.queryType, query)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := p.lookup.Do(ctx, "rdap:"+path+":"+query, func(ctx context.Context) (any, error) {
+		obj, err := p.fetch(ctx, path, query)
+		if err != nil {
+			return nil, err
+		}
+		return normaliseRDAP(query, obj), nil
+	})
+	if err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, fmt.Errorf("no RDAP %v record found for %v", path, query)
+		}
+		return nil, err
+	}
+
+	msg.SetStructuredMut(res)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *rdapLookupProc) Close(ctx context.Context) error {
+	p.client.CloseIdleConnections()
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func getStr(obj map[string]any, key string) string {
+	s, _ := obj[key].(string)
+	return s
+}
+
+func getObjs(obj map[string]any, key string) (objs []map[string]any) {
+	arr, _ := obj[key].([]any)
+	for _, v := range arr {
+		if o, ok := v.(map[string]any); ok {
+			objs = append(objs, o)
+		}
+	}
+	return
+}
+
+// vcardName extracts the formatted name from the jCard of an RDAP entity.
+func vcardName(entity map[string]any) string {
+	vcard, _ := entity["vcardArray"].([]any)
+	if len(vcard) < 2 {
+		return ""
+	}
+	props, _ := vcard[1].([]any)
+	for _, p := range props {
+		prop, _ := p.([]any)
+		if len(prop) < 4 {
+			continue
+		}
+		if name, _ := prop[0].(string); name == "fn" {
+			v, _ := prop[3].(string)
+			return v
+		}
+	}
+	return ""
+}
+
+func entityWithRole(obj map[string]any, role string) map[string]any {
+	for _, e := range getObjs(obj, "entities") {
+		roles, _ := e["roles"].([]any)
+		for _, r := range roles {
+			if r == role {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func eventDate(obj map[string]any, action string) string {
+	for _, e := range getObjs(obj, "events") {
+		if getStr(e, "eventAction") == action {
+			return getStr(e, "eventDate")
+		}
+	}
+	return ""
+}
+
+func anyStrings(obj map[string]any, key string) []any {
+	res := []any{}
+	arr, _ := obj[key].([]any)
+	for _, v := range arr {
+		if s, ok := v.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func normaliseRDAP(query string, obj map[string]any) map[string]any {
+	class := getStr(obj, "objectClassName")
+	res := map[string]any{
+		"query":        query,
+		"object_class": class,
+		"handle":       getStr(obj, "handle"),
+		"name":         getStr(obj, "name"),
+	}
+
+	registrant := ""
+	if e := entityWithRole(obj, "registrant"); e != nil {
+		registrant = vcardName(e)
+	}
+
+	switch class {
+	case "domain":
+		if name := getStr(obj, "ldhName"); name != "" {
+			res["name"] = strings.ToLower(name)
+		}
+		res["status"] = anyStrings(obj, "status")
+
+		nameservers := []any{}
+		for _, ns := range getObjs(obj, "nameservers") {
+			nameservers = append(nameservers, strings.ToLower(getStr(ns, "ldhName")))
+		}
+		res["nameservers"] = nameservers
+
+		registrar := map[string]any{"name": "", "iana_id": ""}
+		if e := entityWithRole(obj, "registrar"); e != nil {
+			registrar["name"] = vcardName(e)
+			for _, id := range getObjs(e, "publicIds") {
+				if getStr(id, "type") == "IANA Registrar ID" {
+					registrar["iana_id"] = getStr(id, "identifier")
+				}
+			}
+		}
+		res["registrar"] = registrar
+		res["registered"] = eventDate(obj, "registration")
+		res["expires"] = eventDate(obj, "expiration")
+		res["last_changed"] = eventDate(obj, "last changed")
+	case "ip network":
+		res["start_address"] = getStr(obj, "startAddress")
+		res["end_address"] = getStr(obj, "endAddress")
+		res["ip_version"] = getStr(obj, "ipVersion")
+		res["type"] = getStr(obj, "type")
+		res["country"] = getStr(obj, "country")
+		res["parent_handle"] = getStr(obj, "parentHandle")
+		res["registrant"] = registrant
+		if asns, ok := obj["arin_originas0_originautnums"].([]any); ok {
+			originASNs := make([]any, 0, len(asns))
+			for _, asn := range asns {
+				if f, ok := asn.(float64); ok {
+					originASNs = append(originASNs, int64(f))
+				}
+			}
+			res["origin_asns"] = originASNs
+		}
+	case "autnum":
+		start, _ := obj["startAutnum"].(float64)
+		end, _ := obj["endAutnum"].(float64)
+		res["start_autnum"] = int64(start)
+		res["end_autnum"] = int64(end)
+		res["type"] = getStr(obj, "type")
+		res["country"] = getStr(obj, "country")
+		res["registrant"] = registrant
+	}
+	return res
+}
@@ -0,0 +1,212 @@
+package netlookup
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const testRDAPDomain = `{
+  "objectClassName": "domain",
+  "handle": "2336799_DOMAIN_COM-VRSN",
+  "ldhName": "EXAMPLE.COM",
+  "status": ["client delete prohibited", "client transfer prohibited"],
+  "entities": [{
+    "objectClassName": "entity",
+    "roles": ["registrar"],
+    "publicIds": [{"type": "IANA Registrar ID", "identifier": "376"}],
+    "vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "RESERVED-Internet Assigned Numbers Authority"]]]
+  }],
+  "events": [
+    {"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+    {"eventAction": "expiration", "eventDate": "2024-08-13T04:00:00Z"},
+    {"eventAction": "last changed", "eventDate": "2023-08-14T07:01:38Z"}
+  ],
+  "nameservers": [
+    {"objectClassName": "nameserver", "ldhName": "A.IANA-SERVERS.NET"},
+    {"objectClassName": "nameserver", "ldhName": "B.IANA-SERVERS.NET"}
+  ]
+}`
+
+const testRDAPNetwork = `{
+  "objectClassName": "ip network",
+  "handle": "NET-8-8-8-0-2",
+  "name": "GOGL",
+  "startAddress": "8.8.8.0",
+  "endAddress": "8.8.8.255",
+  "ipVersion": "v4",
+  "type": "DIRECT ALLOCATION",
+  "parentHandle": "NET-8-0-0-0-0",
+  "arin_originas0_originautnums": [15169],
+  "entities": [{
+    "roles": ["registrant"],
+    "vcardArray": ["vcard", [["fn", {}, "text", "Google LLC"]]]
+  }]
+}`
+
+const testRDAPAutnum = `{
+  "objectClassName": "autnum",
+  "handle": "AS15169",
+  "name": "GOOGLE",
+  "startAutnum": 15169,
+  "endAutnum": 15169,
+  "type": "DIRECT ALLOCATION",
+  "country": "US"
+}`
+
+func testRDAPServer(t testing.TB) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+
+	var reqs atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqs.Add(1)
+		w.Header().Set("Content-Type", "application/rdap+json")
+		switch r.URL.Path {
+		case "/domain/example.com":
+			_, _ = w.Write([]byte(testRDAPDomain))
+		case "/ip/8.8.8.8", "/ip/8.8.8.0/24":
+			_, _ = w.Write([]byte(testRDAPNetwork))
+		case "/autnum/15169":
+			_, _ = w.Write([]byte(testRDAPAutnum))
+		case "/domain/broken.com":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &reqs
+}
+
+func testRDAPProc(t testing.TB, confStr string, res *service.Resources) *rdapLookupProc {
+	t.Helper()
+
+	pConf, err := rdapLookupProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := rdapLookupProcFromParsed(pConf, res)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = proc.Close(context.Background())
+	})
+	return proc
+}
+
+func TestRDAPLookupTypes(t *testing.T) {
+	ts, _ := testRDAPServer(t)
+
+	proc := testRDAPProc(t, `
+query: ${! content() }
+base_url: `+ts.URL+`
+`, service.MockResources())
+
+	assert.Equal(t, map[string]any{
+		"query":        "example.com",
+		"object_class": "domain",
+		"handle":       "2336799_DOMAIN_COM-VRSN",
+		"name":         "example.com",
+		"status":       []any{"client delete prohibited", "client transfer prohibited"},
+		"nameservers":  []any{"a.iana-servers.net", "b.iana-servers.net"},
+		"registrar": map[string]any{
+			"name":    "RESERVED-Internet Assigned Numbers Authority",
+			"iana_id": "376",
+		},
+		"registered":   "1995-08-14T04:00:00Z",
+		"expires":      "2024-08-13T04:00:00Z",
+		"last_changed": "2023-08-14T07:01:38Z",
+	}, processStructured(t, proc, "Example.com."))
+
+	expNetwork := map[string]any{
+		"query":         "8.8.8.8",
+		"object_class":  "ip network",
+		"handle":        "NET-8-8-8-0-2",
+		"name":          "GOGL",
+		"start_address": "8.8.8.0",
+		"end_address":   "8.8.8.255",
+		"ip_version":    "v4",
+		"type":          "DIRECT ALLOCATION",
+		"country":       "",
+		"parent_handle": "NET-8-0-0-0-0",
+		"registrant":    "Google LLC",
+		"origin_asns":   []any{int64(15169)},
+	}
+	assert.Equal(t, expNetwork, processStructured(t, proc, "8.8.8.8"))
+
+	expNetwork["query"] = "8.8.8.0/24"
+	assert.Equal(t, expNetwork, processStructured(t, proc, "8.8.8.0/24"))
+
+	assert.Equal(t, map[string]any{
+		"query":        "15169",
+		"object_class": "autnum",
+		"handle":       "AS15169",
+		"name":         "GOOGLE",
+		"start_autnum": int64(15169),
+		"end_autnum":   int64(15169),
+		"type":         "DIRECT ALLOCATION",
+		"country":      "US",
+		"registrant":   "",
+	}, processStructured(t, proc, "AS15169"))
+}
+
+func TestRDAPLookupNegativeCache(t *testing.T) {
+	ts, reqs := testRDAPServer(t)
+
+	proc := testRDAPProc(t, `
+query: ${! content() }
+base_url: `+ts.URL+`
+cache: foocache
+`, service.MockResources(service.MockResourcesOptAddCache("foocache")))
+
+	for i := 0; i < 3; i++ {
+		res := processStructured(t, proc, "example.com").(map[string]any)
+		assert.Equal(t, "example.com", res["name"])
+	}
+	assert.Equal(t, int64(1), reqs.Load())
+
+	for i := 0; i < 3; i++ {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte("nope.com")))
+		require.EqualError(t, err, "no RDAP domain record found for nope.com")
+	}
+	assert.Equal(t, int64(2), reqs.Load())
+
+	for i := 0; i < 3; i++ {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte("broken.com")))
+		require.Error(t, err)
+	}
+	assert.Equal(t, int64(5), reqs.Load())
+}
+
+func TestRDAPResolvePath(t *testing.T) {
+	for _, test := range []struct {
+		queryType, query string
+		path, normalised string
+		errContains      string
+	}{
+		{queryType: "auto", query: "10.0.0.1", path: "ip", normalised: "10.0.0.1"},
+		{queryType: "auto", query: "2001:db8::1", path: "ip", normalised: "2001:db8::1"},
+		{queryType: "auto", query: "10.0.0.0/8", path: "ip", normalised: "10.0.0.0/8"},
+		{queryType: "auto", query: "10.0.0.0/nope", errContains: "invalid CIDR"},
+		{queryType: "auto", query: "as123", path: "autnum", normalised: "123"},
+		{queryType: "auto", query: "123", path: "autnum", normalised: "123"},
+		{queryType: "auto", query: "Foo.Com.", path: "domain", normalised: "foo.com"},
+		{queryType: "autnum", query: "foo", errContains: "invalid autonomous system number"},
+		{queryType: "domain", query: "123", path: "domain", normalised: "123"},
+	} {
+		path, normalised, err := resolvePath(test.queryType, test.query)
+		if test.errContains != "" {
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, test.path, path, test.query)
+		assert.Equal(t, test.normalised, normalised, test.query)
+	}
+}
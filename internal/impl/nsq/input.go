@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/nsqio/go-nsq"
 
@@ -23,8 +24,14 @@ const (
 	niFieldChannel      = "channel"
 	niFieldUserAgent    = "user_agent"
 	niFieldMaxAttempts  = "max_attempts"
+	niFieldEphemeral    = "ephemeral"
+	niFieldRequeueDelay = "requeue_delay"
 )
 
+// nsqEphemeralSuffix is the suffix of topic and channel names that are not
+// persisted to disk, and are deleted once their last client disconnects.
+const nsqEphemeralSuffix = "#ephemeral"
+
 func inputConfigSpec() *service.ConfigSpec {
 	return service.NewConfigSpec().
 		Stable().
@@ -54,6 +61,10 @@ You can access these metadata fields using [function interpolation](/docs/config
 				Description("The topic to consume from."),
 			service.NewStringField(niFieldChannel).
 				Description("The channel to consume from."),
+			service.NewBoolField(niFieldEphemeral).
+				Description("Whether the channel is ephemeral, in which case messages of the channel are not persisted to disk and the channel is deleted once the last consumer disconnects. This is achieved by adding the suffix `"+nsqEphemeralSuffix+"` to the channel name, and is useful for fanning out messages to consumers that do not need to receive messages published whilst they are offline.").
+				Default(false).
+				Version("4.28.0"),
 			service.NewStringField(niFieldUserAgent).
 				Description("A user agent to assume when connecting.").
 				Optional(),
@@ -63,6 +74,12 @@ You can access these metadata fields using [function interpolation](/docs/config
 			service.NewIntField(niFieldMaxAttempts).
 				Description("The maximum number of attempts to successfully consume a messages.").
 				Default(5),
+			service.NewDurationField(niFieldRequeueDelay).
+				Description("An optional delay to apply to messages that are requeued after failing to be processed. When omitted the delay is calculated from the number of attempts of the message.").
+				Example("10s").
+				Optional().
+				Advanced().
+				Version("4.28.0"),
 		)
 }
 
@@ -89,6 +106,7 @@ type nsqReader struct {
 	userAgent       string
 	maxInFlight     int
 	maxAttempts     uint16
+	requeueDelay    time.Duration
 	log             *service.Logger
 
 	internalMessages chan *nsq.Message
@@ -136,6 +154,13 @@ func newNSQReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 	if n.channel, err = conf.FieldString(niFieldChannel); err != nil {
 		return
 	}
+	var ephemeral bool
+	if ephemeral, err = conf.FieldBool(niFieldEphemeral); err != nil {
+		return
+	}
+	if ephemeral && !strings.HasSuffix(n.channel, nsqEphemeralSuffix) {
+		n.channel += nsqEphemeralSuffix
+	}
 	n.userAgent, _ = conf.FieldString(niFieldUserAgent)
 	if n.maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
 		return
@@ -145,6 +170,15 @@ func newNSQReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources)
 		return
 	}
 	n.maxAttempts = uint16(tmpMA)
+
+	// A negative delay instructs the client to calculate the delay from the
+	// number of attempts.
+	n.requeueDelay = -1
+	if conf.Contains(niFieldRequeueDelay) {
+		if n.requeueDelay, err = conf.FieldDuration(niFieldRequeueDelay); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -241,7 +275,7 @@ func (n *nsqReader) Read(ctx context.Context) (*service.Message, service.AckFunc
 
 	return part, func(rctx context.Context, res error) error {
 		if res != nil {
-			msg.Requeue(-1)
+			msg.Requeue(n.requeueDelay)
 		}
 		msg.Finish()
 		return nil
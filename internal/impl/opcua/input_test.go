@@ -0,0 +1,664 @@
+package opcua
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+	"github.com/gopcua/opcua/uacp"
+	"github.com/gopcua/opcua/uasc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeServer implements enough of an OPC UA server to create sessions with
+// subscriptions and publish changes over channels with the None security
+// policy.
+type fakeServer struct {
+	t        testing.TB
+	listener net.Listener
+
+	userName string
+	password string
+	cert     []byte
+
+	changes chan []*ua.MonitoredItemNotification
+
+	mut      sync.Mutex
+	items    []*ua.MonitoredItemCreateRequest
+	acks     []uint32
+	sessions int
+	closed   int
+}
+
+func newFakeServer(t testing.TB) *fakeServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	s := &fakeServer{
+		t:        t,
+		listener: l,
+		changes:  make(chan []*ua.MonitoredItemNotification, 10),
+	}
+	s.cert, _ = testCertificate(t)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn.(*net.TCPConn))
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) endpoint() string {
+	return "opc.tcp://" + s.listener.Addr().String() + "/test"
+}
+
+func (s *fakeServer) endpoints() []*ua.EndpointDescription {
+	return []*ua.EndpointDescription{{
+		EndpointURL: s.endpoint(),
+		Server: &ua.ApplicationDescription{
+			ApplicationURI:  "urn:fake",
+			ApplicationName: ua.NewLocalizedText("Fake"),
+			ApplicationType: ua.ApplicationTypeServer,
+		},
+		ServerCertificate: s.cert,
+		SecurityMode:      ua.MessageSecurityModeNone,
+		SecurityPolicyURI: ua.SecurityPolicyURINone,
+		UserIdentityTokens: []*ua.UserTokenPolicy{
+			{PolicyID: "anonymous", TokenType: ua.UserTokenTypeAnonymous},
+			{PolicyID: "username", TokenType: ua.UserTokenTypeUserName, SecurityPolicyURI: ua.SecurityPolicyURINone},
+		},
+		TransportProfileURI: "http://opcfoundation.org/UA-Profile/Transport/uatcp-uasc-uabinary",
+	}}
+}
+
+func testCertificate(t testing.TB) ([]byte, *rsa.PrivateKey) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func responseHeader(req ua.Request, status ua.StatusCode) *ua.ResponseHeader {
+	return &ua.ResponseHeader{
+		Timestamp:          time.Now(),
+		RequestHandle:      req.Header().RequestHandle,
+		ServiceResult:      status,
+		ServiceDiagnostics: &ua.DiagnosticInfo{},
+		AdditionalHeader:   ua.NewExtensionObject(nil),
+	}
+}
+
+func (s *fakeServer) serve(tcpConn *net.TCPConn) {
+	conn, err := uacp.NewConn(tcpConn, uacp.DefaultServerACK)
+	require.NoError(s.t, err)
+	defer conn.Close()
+
+	b, err := conn.Receive()
+	if err != nil || string(b[:4]) != "HELF" {
+		return
+	}
+	if err := conn.Send("ACKF", uacp.DefaultServerACK); err != nil {
+		return
+	}
+
+	var seqNum, notifSeqNum uint32
+	for {
+		b, err := conn.Receive()
+		if err != nil {
+			return
+		}
+		if string(b[:3]) == "CLO" {
+			s.mut.Lock()
+			s.closed++
+			s.mut.Unlock()
+			return
+		}
+
+		m := new(uasc.Message)
+		if _, err := m.Decode(b); err != nil {
+			s.t.Errorf("failed to decode message: %v", err)
+			return
+		}
+		req, ok := m.Service.(ua.Request)
+		if !ok {
+			s.t.Errorf("unexpected message %T", m.Service)
+			return
+		}
+
+		msgType := "MSG"
+		var res any
+		switch r := req.(type) {
+		case *ua.OpenSecureChannelRequest:
+			msgType = "OPN"
+			res = &ua.OpenSecureChannelResponse{
+				ResponseHeader: responseHeader(r, ua.StatusOK),
+				SecurityToken: &ua.ChannelSecurityToken{
+					ChannelID:       1,
+					TokenID:         1,
+					CreatedAt:       time.Now(),
+					RevisedLifetime: r.RequestedLifetime,
+				},
+				ServerNonce: []byte{},
+			}
+
+		case *ua.GetEndpointsRequest:
+			res = &ua.GetEndpointsResponse{
+				ResponseHeader: responseHeader(r, ua.StatusOK),
+				Endpoints:      s.endpoints(),
+			}
+
+		case *ua.CreateSessionRequest:
+			res = &ua.CreateSessionResponse{
+				ResponseHeader:        responseHeader(r, ua.StatusOK),
+				SessionID:             ua.NewNumericNodeID(1, 1),
+				AuthenticationToken:   ua.NewByteStringNodeID(1, []byte{1, 2, 3}),
+				RevisedSessionTimeout: r.RequestedSessionTimeout,
+				ServerNonce:           make([]byte, 32),
+				ServerCertificate:     s.cert,
+				ServerEndpoints:       s.endpoints(),
+				ServerSignature:       &ua.SignatureData{},
+			}
+
+		case *ua.ActivateSessionRequest:
+			status := ua.StatusOK
+			switch token := r.UserIdentityToken.Value.(type) {
+			case *ua.AnonymousIdentityToken:
+				if s.userName != "" || token.PolicyID != "anonymous" {
+					status = ua.StatusBadIdentityTokenRejected
+				}
+			case *ua.UserNameIdentityToken:
+				if token.PolicyID != "username" || token.UserName != s.userName || string(token.Password) != s.password {
+					status = ua.StatusBadIdentityTokenRejected
+				}
+			default:
+				status = ua.StatusBadIdentityTokenInvalid
+			}
+			if status != ua.StatusOK {
+				res = &ua.ServiceFault{ResponseHeader: responseHeader(r, status)}
+				break
+			}
+			s.mut.Lock()
+			s.sessions++
+			s.mut.Unlock()
+			res = &ua.ActivateSessionResponse{
+				ResponseHeader: responseHeader(r, ua.StatusOK),
+				ServerNonce:    make([]byte, 32),
+			}
+
+		case *ua.ReadRequest:
+			results := make([]*ua.DataValue, len(r.NodesToRead))
+			for i := range results {
+				results[i] = &ua.DataValue{
+					EncodingMask: ua.DataValueValue,
+					Value:        ua.MustVariant([]string{"http://opcfoundation.org/UA/", "urn:fake"}),
+				}
+			}
+			res = &ua.ReadResponse{
+				ResponseHeader: responseHeader(r, ua.StatusOK),
+				Results:        results,
+			}
+
+		case *ua.CreateSubscriptionRequest:
+			res = &ua.CreateSubscriptionResponse{
+				ResponseHeader:            responseHeader(r, ua.StatusOK),
+				SubscriptionID:            7,
+				RevisedPublishingInterval: r.RequestedPublishingInterval,
+				RevisedLifetimeCount:      r.RequestedLifetimeCount,
+				RevisedMaxKeepAliveCount:  r.RequestedMaxKeepAliveCount,
+			}
+
+		case *ua.CreateMonitoredItemsRequest:
+			results := make([]*ua.MonitoredItemCreateResult, len(r.ItemsToCreate))
+			for i, item := range r.ItemsToCreate {
+				status := ua.StatusOK
+				if item.ItemToMonitor.NodeID.String() == "ns=2;s=missing" {
+					status = ua.StatusBadNodeIDUnknown
+				}
+				results[i] = &ua.MonitoredItemCreateResult{
+					StatusCode:              status,
+					MonitoredItemID:         uint32(i + 100),
+					RevisedSamplingInterval: item.RequestedParameters.SamplingInterval,
+					RevisedQueueSize:        item.RequestedParameters.QueueSize,
+					FilterResult:            ua.NewExtensionObject(nil),
+				}
+			}
+			s.mut.Lock()
+			s.items = append(s.items, r.ItemsToCreate...)
+			s.mut.Unlock()
+			res = &ua.CreateMonitoredItemsResponse{
+				ResponseHeader: responseHeader(r, ua.StatusOK),
+				Results:        results,
+			}
+
+		case *ua.PublishRequest:
+			s.mut.Lock()
+			for _, ack := range r.SubscriptionAcknowledgements {
+				s.acks = append(s.acks, ack.SequenceNumber)
+			}
+			s.mut.Unlock()
+
+			var changes []*ua.MonitoredItemNotification
+			select {
+			case changes = <-s.changes:
+			case <-time.After(time.Millisecond * 50):
+			}
+
+			notif := &ua.NotificationMessage{
+				SequenceNumber: notifSeqNum + 1,
+				PublishTime:    time.Now(),
+			}
+			if len(changes) > 0 {
+				notifSeqNum++
+				notif.NotificationData = []*ua.ExtensionObject{
+					ua.NewExtensionObject(&ua.DataChangeNotification{MonitoredItems: changes}),
+				}
+			}
+			res = &ua.PublishResponse{
+				ResponseHeader:      responseHeader(r, ua.StatusOK),
+				SubscriptionID:      7,
+				NotificationMessage: notif,
+			}
+
+		case *ua.DeleteSubscriptionsRequest:
+			res = &ua.DeleteSubscriptionsResponse{
+				ResponseHeader: responseHeader(r, ua.StatusOK),
+				Results:        make([]ua.StatusCode, len(r.SubscriptionIDs)),
+			}
+
+		case *ua.CloseSessionRequest:
+			res = &ua.CloseSessionResponse{ResponseHeader: responseHeader(r, ua.StatusOK)}
+
+		default:
+			res = &ua.ServiceFault{ResponseHeader: responseHeader(r, ua.StatusBadServiceUnsupported)}
+		}
+
+		seqNum++
+		header := &uasc.MessageHeader{
+			Header: uasc.NewHeader(msgType, uasc.ChunkTypeFinal, 1),
+			SequenceHeader: &uasc.SequenceHeader{
+				SequenceNumber: seqNum,
+				RequestID:      m.SequenceHeader.RequestID,
+			},
+		}
+		if msgType == "OPN" {
+			header.AsymmetricSecurityHeader = uasc.NewAsymmetricSecurityHeader(ua.SecurityPolicyURINone, nil, nil)
+		} else {
+			header.SymmetricSecurityHeader = &uasc.SymmetricSecurityHeader{TokenID: 1}
+		}
+		out, err := (&uasc.Message{
+			MessageHeader: header,
+			TypeID:        ua.NewFourByteExpandedNodeID(0, ua.ServiceTypeID(res)),
+			Service:       res,
+		}).Encode()
+		require.NoError(s.t, err)
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func testDataValue(v any, status ua.StatusCode, sourceTime time.Time) *ua.DataValue {
+	dv := &ua.DataValue{
+		EncodingMask:    ua.DataValueValue | ua.DataValueStatusCode | ua.DataValueServerTimestamp,
+		Value:           ua.MustVariant(v),
+		Status:          status,
+		ServerTimestamp: sourceTime.Add(time.Millisecond),
+	}
+	if !sourceTime.IsZero() {
+		dv.EncodingMask |= ua.DataValueSourceTimestamp
+		dv.SourceTimestamp = sourceTime
+	}
+	return dv
+}
+
+func testInput(t testing.TB, confStr string) *opcuaInput {
+	t.Helper()
+
+	conf, err := inputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newOPCUAInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	return i
+}
+
+func TestInputSubscribe(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*20)
+	defer done()
+
+	server := newFakeServer(t)
+
+	i := testInput(t, `
+endpoint: `+server.endpoint()+`
+nodes:
+  - node_id: ns=2;s=Line1.Temperature
+    name: temperature
+    deadband_type: absolute
+    deadband_value: 0.5
+  - node_id: i=2258
+publishing_interval: 100ms
+`)
+	require.NoError(t, i.Connect(ctx))
+
+	server.mut.Lock()
+	require.Len(t, server.items, 2)
+	assert.Equal(t, "ns=2;s=Line1.Temperature", server.items[0].ItemToMonitor.NodeID.String())
+	assert.Equal(t, uint32(0), server.items[0].RequestedParameters.ClientHandle)
+	assert.Equal(t, uint32(10), server.items[0].RequestedParameters.QueueSize)
+	assert.Equal(t, &ua.DataChangeFilter{
+		Trigger:       ua.DataChangeTriggerStatusValue,
+		DeadbandType:  uint32(ua.DeadbandTypeAbsolute),
+		DeadbandValue: 0.5,
+	}, server.items[0].RequestedParameters.Filter.Value)
+	assert.Equal(t, "i=2258", server.items[1].ItemToMonitor.NodeID.String())
+	assert.Equal(t, uint32(1), server.items[1].RequestedParameters.ClientHandle)
+	assert.Nil(t, server.items[1].RequestedParameters.Filter.Value)
+	server.mut.Unlock()
+
+	sourceTime := time.Date(2024, 5, 6, 7, 8, 9, 100, time.UTC)
+	server.changes <- []*ua.MonitoredItemNotification{
+		{ClientHandle: 0, Value: testDataValue(21.5, ua.StatusOK, sourceTime)},
+		{ClientHandle: 1, Value: testDataValue(sourceTime, ua.StatusOK, time.Time{})},
+	}
+
+	batch, ackFn, err := i.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, 21.5, v)
+	for k, exp := range map[string]string{
+		"opcua_node_id":          "ns=2;s=Line1.Temperature",
+		"opcua_name":             "temperature",
+		"opcua_type":             "Double",
+		"opcua_status":           "Good",
+		"opcua_quality":          "good",
+		"opcua_source_timestamp": "2024-05-06T07:08:09.0000001Z",
+		"opcua_server_timestamp": "2024-05-06T07:08:09.0010001Z",
+	} {
+		act, _ := batch[0].MetaGetMut(k)
+		assert.Equal(t, exp, act, k)
+	}
+
+	v, err = batch[1].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, sourceTime, v)
+	name, _ := batch[1].MetaGetMut("opcua_name")
+	assert.Equal(t, "i=2258", name)
+	_, exists := batch[1].MetaGetMut("opcua_source_timestamp")
+	assert.False(t, exists)
+
+	require.NoError(t, ackFn(ctx, nil))
+
+	server.changes <- []*ua.MonitoredItemNotification{
+		{ClientHandle: 0, Value: testDataValue([][]int32{{1, 2}, {3, 4}}, 0x40920000, sourceTime)},
+	}
+
+	batch, _, err = i.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	v, err = batch[0].AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, []any{[]any{int64(1), int64(2)}, []any{int64(3), int64(4)}}, v)
+	typeName, _ := batch[0].MetaGetMut("opcua_type")
+	assert.Equal(t, "Int32[]", typeName)
+	quality, _ := batch[0].MetaGetMut("opcua_quality")
+	assert.Equal(t, "uncertain", quality)
+
+	// Notifications are acknowledged with subsequent publish requests.
+	assert.Eventually(t, func() bool {
+		server.mut.Lock()
+		defer server.mut.Unlock()
+		return len(server.acks) >= 2
+	}, time.Second*5, time.Millisecond*10)
+	server.mut.Lock()
+	assert.Equal(t, []uint32{1, 2}, server.acks[:2])
+	server.mut.Unlock()
+
+	require.NoError(t, i.Close(ctx))
+	assert.Eventually(t, func() bool {
+		server.mut.Lock()
+		defer server.mut.Unlock()
+		return server.closed >= 1
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestInputUserName(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*20)
+	defer done()
+
+	server := newFakeServer(t)
+	server.userName = "foo"
+	server.password = "bar"
+
+	i := testInput(t, `
+endpoint: `+server.endpoint()+`
+username: foo
+password: baz
+nodes:
+  - node_id: ns=2;s=Line1.Temperature
+`)
+	err := i.Connect(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "BadIdentityTokenRejected")
+
+	i = testInput(t, `
+endpoint: `+server.endpoint()+`
+username: foo
+password: bar
+nodes:
+  - node_id: ns=2;s=Line1.Temperature
+`)
+	require.NoError(t, i.Connect(ctx))
+
+	server.mut.Lock()
+	assert.Equal(t, 1, server.sessions)
+	server.mut.Unlock()
+}
+
+func TestInputMissingNode(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*20)
+	defer done()
+
+	server := newFakeServer(t)
+
+	i := testInput(t, `
+endpoint: `+server.endpoint()+`
+nodes:
+  - node_id: ns=2;s=Line1.Temperature
+  - node_id: ns=2;s=missing
+`)
+	require.EqualError(t, i.Connect(ctx), "failed to monitor nodes: ns=2;s=missing (BadNodeIDUnknown)")
+}
+
+func writeTestPEM(t testing.TB, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+func TestInputSecurity(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*20)
+	defer done()
+
+	server := newFakeServer(t)
+
+	cert, key := testCertificate(t)
+	certFile := writeTestPEM(t, "client.pem", "CERTIFICATE", cert)
+	keyFile := writeTestPEM(t, "client.key", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+	i := testInput(t, `
+endpoint: `+server.endpoint()+`
+security_policy: Basic256Sha256
+security_mode: SignAndEncrypt
+certificate_file: `+certFile+`
+private_key_file: `+keyFile+`
+nodes:
+  - node_id: ns=2;s=Line1.Temperature
+`)
+	require.EqualError(t, i.Connect(ctx), "server has no endpoint with security policy Basic256Sha256 and mode SignAndEncrypt")
+
+	i = testInput(t, `
+endpoint: `+server.endpoint()+`
+server_certificate_file: `+certFile+`
+nodes:
+  - node_id: ns=2;s=Line1.Temperature
+`)
+	require.EqualError(t, i.Connect(ctx), "certificate of server does not match server_certificate_file")
+
+	i = testInput(t, `
+endpoint: `+server.endpoint()+`
+server_certificate_file: `+writeTestPEM(t, "server.pem", "CERTIFICATE", server.cert)+`
+nodes:
+  - node_id: ns=2;s=Line1.Temperature
+`)
+	require.NoError(t, i.Connect(ctx))
+}
+
+func TestInputConfigErrors(t *testing.T) {
+	cert, key := testCertificate(t)
+	certFile := writeTestPEM(t, "client.pem", "CERTIFICATE", cert)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	keyFile := writeTestPEM(t, "client.key", "PRIVATE KEY", pkcs8)
+
+	for _, test := range []struct {
+		name string
+		conf string
+		err  string
+	}{
+		{
+			name: "no nodes",
+			conf: `nodes: []`,
+			err:  "at least one node must be specified",
+		},
+		{
+			name: "bad node id",
+			conf: `
+nodes:
+  - node_id: ns=foo;s=bar
+`,
+			err: "node 0: ",
+		},
+		{
+			name: "bad deadband",
+			conf: `
+nodes:
+  - node_id: i=2258
+    deadband_type: percent
+    deadband_value: 101
+`,
+			err: "node 0: deadband value 101 is out of range",
+		},
+		{
+			name: "mode without policy",
+			conf: `
+security_mode: Sign
+nodes:
+  - node_id: i=2258
+`,
+			err: "security_mode must be None when security_policy is None",
+		},
+		{
+			name: "policy without mode",
+			conf: `
+security_policy: Basic256Sha256
+certificate_file: ` + certFile + `
+private_key_file: ` + keyFile + `
+nodes:
+  - node_id: i=2258
+`,
+			err: "security_mode must be Sign or SignAndEncrypt when security_policy is Basic256Sha256",
+		},
+		{
+			name: "policy without certificate",
+			conf: `
+security_policy: Aes256_Sha256_RsaPss
+security_mode: Sign
+nodes:
+  - node_id: i=2258
+`,
+			err: "certificate_file and private_key_file must be set when security_policy is Aes256_Sha256_RsaPss",
+		},
+		{
+			name: "certificate without key",
+			conf: `
+certificate_file: ` + certFile + `
+nodes:
+  - node_id: i=2258
+`,
+			err: "certificate_file and private_key_file must be set together",
+		},
+		{
+			name: "key as certificate",
+			conf: `
+certificate_file: ` + keyFile + `
+private_key_file: ` + keyFile + `
+nodes:
+  - node_id: i=2258
+`,
+			err: "certificate_file: unexpected PEM block of type PRIVATE KEY",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := inputSpec().ParseYAML("endpoint: opc.tcp://localhost:4840\n"+test.conf, nil)
+			require.NoError(t, err)
+
+			_, err = newOPCUAInputFromParsed(conf, service.MockResources())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+
+	conf, err := inputSpec().ParseYAML(`
+endpoint: opc.tcp://localhost:4840
+security_policy: Basic256Sha256
+security_mode: SignAndEncrypt
+certificate_file: `+certFile+`
+private_key_file: `+keyFile+`
+nodes:
+  - node_id: i=2258
+`, nil)
+	require.NoError(t, err)
+	_, err = newOPCUAInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+}
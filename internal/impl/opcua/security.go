@@ -0,0 +1,82 @@
+package opcua
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/gopcua/opcua/ua"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+)
+
+var securityPolicies = map[string]string{
+	"None":                  "Messages are neither signed nor encrypted.",
+	"Basic128Rsa15":         "Deprecated, and only supported for older servers.",
+	"Basic256":              "Deprecated, and only supported for older servers.",
+	"Basic256Sha256":        "RSA with SHA-256 signatures and AES-256 encryption.",
+	"Aes128_Sha256_RsaOaep": "RSA-OAEP with SHA-256 signatures and AES-128 encryption.",
+	"Aes256_Sha256_RsaPss":  "RSA-PSS with SHA-256 signatures and AES-256 encryption.",
+}
+
+var securityModes = map[string]ua.MessageSecurityMode{
+	"None":           ua.MessageSecurityModeNone,
+	"Sign":           ua.MessageSecurityModeSign,
+	"SignAndEncrypt": ua.MessageSecurityModeSignAndEncrypt,
+}
+
+// readPEMOrDER reads a file containing a single PEM block of the given type,
+// or raw DER bytes.
+func readPEMOrDER(f fs.FS, path string, types ...string) ([]byte, error) {
+	b, err := ifs.ReadFile(f, path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return b, nil
+	}
+	for _, t := range types {
+		if block.Type == t {
+			return block.Bytes, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected PEM block of type %v", block.Type)
+}
+
+// readCertificate reads an X.509 certificate in PEM or DER encoding, and
+// returns it in DER encoding.
+func readCertificate(f fs.FS, path string) ([]byte, error) {
+	der, err := readPEMOrDER(f, path, "CERTIFICATE")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := x509.ParseCertificate(der); err != nil {
+		return nil, err
+	}
+	return der, nil
+}
+
+// readPrivateKey reads an RSA private key in PKCS #1 or PKCS #8 form, in PEM or
+// DER encoding.
+func readPrivateKey(f fs.FS, path string) (*rsa.PrivateKey, error) {
+	der, err := readPEMOrDER(f, path, "RSA PRIVATE KEY", "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,38 @@
+package opcua
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gopcua/opcua/ua"
+)
+
+// statusName returns the symbolic name of a status code, or its severity and
+// hex value when the code is not known.
+func statusName(code ua.StatusCode) string {
+	if code == ua.StatusOK {
+		return "Good"
+	}
+	// The lower 16 bits hold flags and info bits, which are ignored when
+	// looking up names.
+	if desc, exists := ua.StatusCodes[code&0xffff0000]; exists {
+		return strings.TrimPrefix(desc.Name, "Status")
+	}
+	return fmt.Sprintf("%v (%#08x)", statusQuality(code), uint32(code))
+}
+
+// statusQuality returns the severity of a status code, which is either good,
+// uncertain or bad.
+func statusQuality(code ua.StatusCode) string {
+	switch code >> 30 {
+	case 0:
+		return "good"
+	case 1:
+		return "uncertain"
+	}
+	return "bad"
+}
+
+func isBad(code ua.StatusCode) bool {
+	return code&0x80000000 != 0
+}
@@ -0,0 +1,167 @@
+package opcua
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/gopcua/opcua/ua"
+)
+
+var typeNames = map[ua.TypeID]string{
+	ua.TypeIDBoolean:         "Boolean",
+	ua.TypeIDSByte:           "SByte",
+	ua.TypeIDByte:            "Byte",
+	ua.TypeIDInt16:           "Int16",
+	ua.TypeIDUint16:          "UInt16",
+	ua.TypeIDInt32:           "Int32",
+	ua.TypeIDUint32:          "UInt32",
+	ua.TypeIDInt64:           "Int64",
+	ua.TypeIDUint64:          "UInt64",
+	ua.TypeIDFloat:           "Float",
+	ua.TypeIDDouble:          "Double",
+	ua.TypeIDString:          "String",
+	ua.TypeIDDateTime:        "DateTime",
+	ua.TypeIDGUID:            "Guid",
+	ua.TypeIDByteString:      "ByteString",
+	ua.TypeIDXMLElement:      "XmlElement",
+	ua.TypeIDNodeID:          "NodeId",
+	ua.TypeIDExpandedNodeID:  "ExpandedNodeId",
+	ua.TypeIDStatusCode:      "StatusCode",
+	ua.TypeIDQualifiedName:   "QualifiedName",
+	ua.TypeIDLocalizedText:   "LocalizedText",
+	ua.TypeIDExtensionObject: "ExtensionObject",
+	ua.TypeIDDataValue:       "DataValue",
+	ua.TypeIDVariant:         "Variant",
+	ua.TypeIDDiagnosticInfo:  "DiagnosticInfo",
+}
+
+// variantTypeName returns the name of the type of a variant, suffixed with []
+// for arrays.
+func variantTypeName(v *ua.Variant) string {
+	if v == nil || v.Type() == ua.TypeIDNull {
+		return "Null"
+	}
+	name, exists := typeNames[v.Type()]
+	if !exists {
+		name = fmt.Sprintf("Unknown(%v)", byte(v.Type()))
+	}
+	if v.Has(ua.VariantArrayValues) {
+		name += "[]"
+	}
+	return name
+}
+
+// variantValue converts the value of a variant into a structured form, where
+// arrays, including multi-dimensional arrays, are converted into nested
+// arrays.
+func variantValue(v *ua.Variant) any {
+	if v == nil {
+		return nil
+	}
+	if v.Has(ua.VariantArrayValues) {
+		return arrayValue(reflect.ValueOf(v.Value()))
+	}
+	return scalarValue(v.Value())
+}
+
+func arrayValue(v reflect.Value) any {
+	if v.Kind() != reflect.Slice {
+		return nil
+	}
+	values := make([]any, v.Len())
+	for i := range values {
+		e := v.Index(i)
+		if e.Kind() == reflect.Slice && e.Type() != reflect.TypeOf([]byte(nil)) {
+			values[i] = arrayValue(e)
+		} else {
+			values[i] = scalarValue(e.Interface())
+		}
+	}
+	return values
+}
+
+// scalarValue converts a single value of a built-in type into a structured
+// form.
+func scalarValue(v any) any {
+	switch t := v.(type) {
+	case int8:
+		return int64(t)
+	case byte:
+		return uint64(t)
+	case int16:
+		return int64(t)
+	case uint16:
+		return uint64(t)
+	case int32:
+		return int64(t)
+	case uint32:
+		return uint64(t)
+	case float32:
+		return float64(t)
+	case ua.XMLElement:
+		return string(t)
+	case time.Time:
+		if t.IsZero() {
+			return nil
+		}
+		return t
+	case []byte:
+		if t == nil {
+			return nil
+		}
+		return t
+	case *ua.GUID:
+		return t.String()
+	case *ua.NodeID:
+		return t.String()
+	case *ua.ExpandedNodeID:
+		return t.String()
+	case ua.StatusCode:
+		return statusName(t)
+	case *ua.QualifiedName:
+		if t.NamespaceIndex == 0 {
+			return t.Name
+		}
+		return fmt.Sprintf("%v:%v", t.NamespaceIndex, t.Name)
+	case *ua.LocalizedText:
+		return t.Text
+	case *ua.ExtensionObject:
+		return extensionObjectValue(t)
+	case *ua.DataValue:
+		return variantValue(t.Value)
+	case *ua.Variant:
+		return variantValue(t)
+	case *ua.DiagnosticInfo:
+		return nil
+	}
+	return v
+}
+
+// extensionObjectValue converts a structure into an object containing the node
+// id of its encoding, and its fields when the type is known.
+func extensionObjectValue(e *ua.ExtensionObject) any {
+	if e == nil {
+		return nil
+	}
+	obj := map[string]any{}
+	if e.TypeID != nil {
+		obj["type_id"] = e.TypeID.String()
+	}
+	switch t := e.Value.(type) {
+	case nil:
+	case *ua.XMLElement:
+		obj["body"] = string(*t)
+	default:
+		// Known types are decoded into their Go structs, which are converted
+		// into generic objects via their JSON form.
+		if b, err := json.Marshal(t); err == nil {
+			var fields any
+			if err := json.Unmarshal(b, &fields); err == nil {
+				obj["value"] = fields
+			}
+		}
+	}
+	return obj
+}
@@ -0,0 +1,350 @@
+package opsgenie
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ogoFieldAPIKey      = "api_key"
+	ogoFieldAction      = "action"
+	ogoFieldAlias       = "alias"
+	ogoFieldMessage     = "message"
+	ogoFieldDescription = "description"
+	ogoFieldPriority    = "priority"
+	ogoFieldPriorityMap = "priority_map"
+	ogoFieldSource      = "source"
+	ogoFieldEntity      = "entity"
+	ogoFieldTags        = "tags"
+	ogoFieldDetails     = "details"
+	ogoFieldBaseURL     = "base_url"
+	ogoFieldRetries     = "retries"
+)
+
+var ogPriorities = map[string]struct{}{
+	"P1": {},
+	"P2": {},
+	"P3": {},
+	"P4": {},
+	"P5": {},
+}
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Creates, acknowledges and closes Opsgenie alerts using the Alert API.").
+		Description(`
+Each message performs a request against the [Opsgenie Alert API](https://docs.opsgenie.com/docs/alert-api), authenticated with the API key of an integration.
+
+The action of each message is resolved from the `+"`action`"+` field, which allows a single output to both create and close alerts based on the contents of messages. Opsgenie deduplicates open alerts that share an `+"`alias`"+`, and the alias is also used to identify the alert to acknowledge or close, and is therefore required for those actions.
+
+### Priority
+
+The `+"`priority`"+` of created alerts must resolve to one of `+"`P1`"+` to `+"`P5`"+`. Severity values used by other systems can be translated with `+"`priority_map`"+`, where a resolved priority that matches a key of the map is replaced with its value.
+
+### Rate Limiting and Retries
+
+Requests that fail with a rate limit error, a server error or a network error are retried according to the `+"`retries`"+` policy, where a rate limit error is retried once the period given by the `+"`Retry-After`"+` header has elapsed.`).
+		Fields(
+			service.NewStringField(ogoFieldAPIKey).
+				Description("The API key of an API integration.").
+				Secret(),
+			service.NewInterpolatedStringField(ogoFieldAction).
+				Description("The action to perform, which must resolve to `create`, `acknowledge` or `close`.").
+				Example(`${! if this.status == "resolved" { "close" } else { "create" } }`).
+				Default("create"),
+			service.NewInterpolatedStringField(ogoFieldAlias).
+				Description("A key used to deduplicate alerts, which also identifies the alert to acknowledge or close.").
+				Example(`${! this.alert_id }`).
+				Default(""),
+			service.NewInterpolatedStringField(ogoFieldMessage).
+				Description("The message of created alerts, which is truncated by Opsgenie to 130 characters.").
+				Example(`${! this.title }`).
+				Default(`${! content() }`),
+			service.NewInterpolatedStringField(ogoFieldDescription).
+				Description("An optional description of created alerts.").
+				Default(""),
+			service.NewInterpolatedStringField(ogoFieldPriority).
+				Description("The priority of created alerts.").
+				Example(`${! this.severity }`).
+				Default("P3"),
+			service.NewStringMapField(ogoFieldPriorityMap).
+				Description("A map of severity values to Opsgenie priorities, which is applied to the resolved priority of each alert.").
+				Example(map[string]any{"critical": "P1", "error": "P2", "warning": "P3", "info": "P5"}).
+				Default(map[string]any{}),
+			service.NewInterpolatedStringField(ogoFieldSource).
+				Description("The source of alerts, which is also set on acknowledge and close requests.").
+				Default(`${! hostname() }`),
+			service.NewInterpolatedStringField(ogoFieldEntity).
+				Description("An optional entity that the alert is related to, such as the name of a service.").
+				Default(""),
+			service.NewStringListField(ogoFieldTags).
+				Description("A list of tags to add to created alerts.").
+				Default([]any{}),
+			service.NewBloblangField(ogoFieldDetails).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of string values to add as the details of created alerts.").
+				Example(`root = this.labels`).
+				Optional(),
+			service.NewStringField(ogoFieldBaseURL).
+				Description("The base URL of the Opsgenie API, which must be set to `https://api.eu.opsgenie.com` for accounts in the EU region.").
+				Default("https://api.opsgenie.com").
+				Advanced(),
+			service.NewRetryPolicyField(ogoFieldRetries),
+			service.NewOutputMaxInFlightField().Default(4),
+		).
+		Example("Auto-Closing Alerts", "Alerts from a monitoring system are created and closed based on their status, where the ID of each alert is used as the alias.", `
+output:
+  opsgenie:
+    api_key: ${OPSGENIE_API_KEY}
+    action: '${! if this.status == "resolved" { "close" } else { "create" } }'
+    alias: ${! this.fingerprint }
+    message: ${! this.annotations.summary }
+    priority: ${! this.labels.severity }
+    priority_map:
+      critical: P1
+      warning: P3
+    tags: [ prometheus ]
+    details: root = this.labels
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("opsgenie", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newOpsgenieWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type opsgenieWriter struct {
+	apiKey      string
+	action      *service.InterpolatedString
+	alias       *service.InterpolatedString
+	message     *service.InterpolatedString
+	description *service.InterpolatedString
+	priority    *service.InterpolatedString
+	priorityMap map[string]string
+	source      *service.InterpolatedString
+	entity      *service.InterpolatedString
+	tags        []string
+	details     *bloblang.Executor
+	baseURL     string
+
+	client  *http.Client
+	retries *service.RetryPolicy
+	log     *service.Logger
+}
+
+func newOpsgenieWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*opsgenieWriter, error) {
+	o := &opsgenieWriter{
+		client: &http.Client{Timeout: 30 * time.Second},
+		log:    mgr.Logger(),
+	}
+
+	var err error
+	if o.apiKey, err = conf.FieldString(ogoFieldAPIKey); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		name string
+		dst  **service.InterpolatedString
+	}{
+		{ogoFieldAction, &o.action},
+		{ogoFieldAlias, &o.alias},
+		{ogoFieldMessage, &o.message},
+		{ogoFieldDescription, &o.description},
+		{ogoFieldPriority, &o.priority},
+		{ogoFieldSource, &o.source},
+		{ogoFieldEntity, &o.entity},
+	} {
+		if *f.dst, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+	if o.priorityMap, err = conf.FieldStringMap(ogoFieldPriorityMap); err != nil {
+		return nil, err
+	}
+	for k, v := range o.priorityMap {
+		if _, exists := ogPriorities[v]; !exists {
+			return nil, fmt.Errorf("priority_map value of key %v is not a valid priority: %v", k, v)
+		}
+	}
+	if o.tags, err = conf.FieldStringList(ogoFieldTags); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ogoFieldDetails) {
+		if o.details, err = conf.FieldBloblang(ogoFieldDetails); err != nil {
+			return nil, err
+		}
+	}
+	if o.baseURL, err = conf.FieldString(ogoFieldBaseURL); err != nil {
+		return nil, err
+	}
+	o.baseURL = strings.TrimSuffix(o.baseURL, "/")
+	if o.retries, err = conf.FieldRetryPolicy(ogoFieldRetries); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *opsgenieWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+// request returns the path and body of the request to perform for a message.
+func (o *opsgenieWriter) request(msg *service.Message) (string, map[string]any, error) {
+	strs := map[string]string{}
+	for k, v := range map[string]*service.InterpolatedString{
+		ogoFieldAction:      o.action,
+		ogoFieldAlias:       o.alias,
+		ogoFieldMessage:     o.message,
+		ogoFieldDescription: o.description,
+		ogoFieldPriority:    o.priority,
+		ogoFieldSource:      o.source,
+		ogoFieldEntity:      o.entity,
+	} {
+		var err error
+		if strs[k], err = v.TryString(msg); err != nil {
+			return "", nil, fmt.Errorf("%v interpolation: %w", k, err)
+		}
+	}
+
+	switch action := strs[ogoFieldAction]; action {
+	case "create":
+	case "acknowledge", "close":
+		if strs[ogoFieldAlias] == "" {
+			return "", nil, fmt.Errorf("an alias is required in order to %v alerts", action)
+		}
+		body := map[string]any{}
+		if source := strs[ogoFieldSource]; source != "" {
+			body["source"] = source
+		}
+		return "/v2/alerts/" + url.PathEscape(strs[ogoFieldAlias]) + "/" + action + "?identifierType=alias", body, nil
+	default:
+		return "", nil, fmt.Errorf("action must be one of create, acknowledge or close, got: %v", action)
+	}
+
+	priority := strs[ogoFieldPriority]
+	if mapped, exists := o.priorityMap[priority]; exists {
+		priority = mapped
+	}
+	if _, exists := ogPriorities[priority]; !exists {
+		return "", nil, fmt.Errorf("priority must be one of P1, P2, P3, P4 or P5, got: %v", priority)
+	}
+
+	body := map[string]any{
+		"message":  strs[ogoFieldMessage],
+		"priority": priority,
+	}
+	for _, k := range []string{ogoFieldAlias, ogoFieldDescription, ogoFieldSource, ogoFieldEntity} {
+		if v := strs[k]; v != "" {
+			body[k] = v
+		}
+	}
+	if len(o.tags) > 0 {
+		body["tags"] = o.tags
+	}
+
+	if o.details != nil {
+		detailsMsg, err := msg.BloblangQuery(o.details)
+		if err != nil {
+			return "", nil, fmt.Errorf("details mapping failed: %w", err)
+		}
+		if detailsMsg != nil {
+			v, err := detailsMsg.AsStructured()
+			if err != nil {
+				return "", nil, fmt.Errorf("details mapping result: %w", err)
+			}
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return "", nil, fmt.Errorf("details mapping must result in an object, got: %T", v)
+			}
+			// Opsgenie only accepts string values within details.
+			details := make(map[string]string, len(obj))
+			for k, v := range obj {
+				if str, ok := v.(string); ok {
+					details[k] = str
+					continue
+				}
+				vBytes, err := json.Marshal(v)
+				if err != nil {
+					return "", nil, err
+				}
+				details[k] = string(vBytes)
+			}
+			body["details"] = details
+		}
+	}
+	return "/v2/alerts", body, nil
+}
+
+func (o *opsgenieWriter) Write(ctx context.Context, msg *service.Message) error {
+	path, reqBody, err := o.request(msg)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	return o.retries.Do(ctx, func(ctx context.Context) error {
+		return o.post(ctx, path, body)
+	})
+}
+
+// post performs a request.
+func (o *opsgenieWriter) post(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+o.apiKey)
+
+	res, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := service.ParseRetryAfter(res.Header.Get("Retry-After"))
+		return service.NewErrBackOff(errors.New("rate limited by Opsgenie"), retryAfter)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(resBody, &apiErr); err == nil && apiErr.Message != "" {
+			return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: %v", res.StatusCode, apiErr.Message))
+		}
+		return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody)))
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func (o *opsgenieWriter) Close(ctx context.Context) error {
+	return nil
+}
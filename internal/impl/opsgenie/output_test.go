@@ -0,0 +1,144 @@
+package opsgenie
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/public/components/io"
+)
+
+func TestOpsgenieOutput(t *testing.T) {
+	type request struct {
+		Path string
+		Body map[string]any
+	}
+
+	var reqMut sync.Mutex
+	var requests []request
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GenieKey fookey", r.Header.Get("Authorization"))
+
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		reqMut.Lock()
+		requests = append(requests, request{Path: r.URL.RequestURI(), Body: body})
+		reqMut.Unlock()
+
+		if body["message"] == "nope" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"message":"Request body is not processable.","took":0.001}`))
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"result":"Request will be processed","took":0.302,"requestId":"43a29c5c"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+api_key: fookey
+action: '${! if this.status == "resolved" { "close" } else { "create" } }'
+alias: ${! this.id }
+message: ${! this.title }
+priority: ${! this.level }
+priority_map:
+  critical: P1
+source: benthos
+tags: [ foo, bar ]
+details: root = this.labels
+base_url: %v
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	w, err := newOpsgenieWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, w.Connect(ctx))
+
+	require.NoError(t, w.Write(ctx, service.NewMessage([]byte(
+		`{"id":"a/1","status":"firing","title":"Disk full","level":"critical","labels":{"disk":"/dev/sda","usage":0.98}}`,
+	))))
+	require.NoError(t, w.Write(ctx, service.NewMessage([]byte(
+		`{"id":"a/1","status":"resolved","title":"Disk full","level":"critical"}`,
+	))))
+
+	err = w.Write(ctx, service.NewMessage([]byte(
+		`{"id":"b","status":"firing","title":"nope","level":"P5","labels":{}}`,
+	)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Request body is not processable.")
+
+	reqMut.Lock()
+	assert.Equal(t, []request{
+		{
+			Path: "/v2/alerts",
+			Body: map[string]any{
+				"message":  "Disk full",
+				"alias":    "a/1",
+				"priority": "P1",
+				"source":   "benthos",
+				"tags":     []any{"foo", "bar"},
+				"details":  map[string]any{"disk": "/dev/sda", "usage": "0.98"},
+			},
+		},
+		{
+			Path: "/v2/alerts/a%2F1/close?identifierType=alias",
+			Body: map[string]any{"source": "benthos"},
+		},
+		{
+			Path: "/v2/alerts",
+			Body: map[string]any{
+				"message":  "nope",
+				"alias":    "b",
+				"priority": "P5",
+				"source":   "benthos",
+				"tags":     []any{"foo", "bar"},
+				"details":  map[string]any{},
+			},
+		},
+	}, requests)
+	reqMut.Unlock()
+
+	require.NoError(t, w.Close(ctx))
+}
+
+func TestOpsgenieRequestErrors(t *testing.T) {
+	conf, err := outputSpec().ParseYAML(`
+api_key: fookey
+action: ${! this.action }
+alias: ${! this.id | "" }
+priority: ${! this.level | "P3" }
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newOpsgenieWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		content     string
+		errContains string
+	}{
+		{content: `{"action":"close"}`, errContains: "an alias is required"},
+		{content: `{"action":"explode"}`, errContains: "action must be one of"},
+		{content: `{"action":"create","level":"P9"}`, errContains: "priority must be one of"},
+	} {
+		_, _, err := w.request(service.NewMessage([]byte(test.content)))
+		require.Error(t, err, test.content)
+		assert.Contains(t, err.Error(), test.errContains, test.content)
+	}
+}
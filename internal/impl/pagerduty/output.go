@@ -0,0 +1,335 @@
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	pdoFieldRoutingKey    = "routing_key"
+	pdoFieldAction        = "action"
+	pdoFieldDedupKey      = "dedup_key"
+	pdoFieldSummary       = "summary"
+	pdoFieldSource        = "source"
+	pdoFieldSeverity      = "severity"
+	pdoFieldSeverityMap   = "severity_map"
+	pdoFieldComponent     = "component"
+	pdoFieldGroup         = "group"
+	pdoFieldClass         = "class"
+	pdoFieldCustomDetails = "custom_details"
+	pdoFieldBaseURL       = "base_url"
+	pdoFieldRetries       = "retries"
+)
+
+var pdSeverities = map[string]struct{}{
+	"critical": {},
+	"error":    {},
+	"warning":  {},
+	"info":     {},
+}
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Sends events to PagerDuty using the Events API v2, which can trigger, acknowledge and resolve incidents.").
+		Description(`
+Each message is sent as an [event](https://developer.pagerduty.com/docs/ZG9jOjExMDI5NTgw-events-api-v2-overview) to the integration identified by the `+"`routing_key`"+`.
+
+The action of each event is resolved from the `+"`action`"+` field, which allows a single output to both trigger and resolve alerts based on the contents of messages. Events that share a `+"`dedup_key`"+` are grouped into the same alert, and it is therefore required in order to acknowledge or resolve alerts. When the dedup key is empty PagerDuty generates one for triggered alerts.
+
+### Severity
+
+The `+"`severity`"+` of triggered alerts must resolve to one of `+"`critical`, `error`, `warning` or `info`"+`. Severity values used by other systems can be translated with `+"`severity_map`"+`, where a resolved severity that matches a key of the map is replaced with its value.
+
+### Rate Limiting and Retries
+
+Events that fail with a rate limit error, a server error or a network error are retried according to the `+"`retries`"+` policy, where a rate limit error is retried once the period given by the `+"`Retry-After`"+` header has elapsed.`).
+		Fields(
+			service.NewStringField(pdoFieldRoutingKey).
+				Description("The integration key of a service or ruleset to send events to.").
+				Secret(),
+			service.NewInterpolatedStringField(pdoFieldAction).
+				Description("The action of the event, which must resolve to `trigger`, `acknowledge` or `resolve`.").
+				Example(`${! if this.status == "resolved" { "resolve" } else { "trigger" } }`).
+				Default("trigger"),
+			service.NewInterpolatedStringField(pdoFieldDedupKey).
+				Description("A key used to deduplicate events, where events with the same key update the same alert.").
+				Example(`${! this.alert_id }`).
+				Default(""),
+			service.NewInterpolatedStringField(pdoFieldSummary).
+				Description("A brief description of the alert, which is required when triggering alerts.").
+				Example(`${! this.title }`).
+				Default(`${! content() }`),
+			service.NewInterpolatedStringField(pdoFieldSource).
+				Description("The unique location of the affected system, such as a hostname.").
+				Default(`${! hostname() }`),
+			service.NewInterpolatedStringField(pdoFieldSeverity).
+				Description("The severity of the alert.").
+				Example(`${! this.level }`).
+				Default("error"),
+			service.NewStringMapField(pdoFieldSeverityMap).
+				Description("A map of severity values to PagerDuty severities, which is applied to the resolved severity of each event.").
+				Example(map[string]any{"P1": "critical", "P2": "error", "P3": "warning"}).
+				Default(map[string]any{}),
+			service.NewInterpolatedStringField(pdoFieldComponent).
+				Description("An optional component of the source system that is responsible for the alert.").
+				Default(""),
+			service.NewInterpolatedStringField(pdoFieldGroup).
+				Description("An optional logical grouping of components of a service.").
+				Default(""),
+			service.NewInterpolatedStringField(pdoFieldClass).
+				Description("An optional class or type of the alert.").
+				Default(""),
+			service.NewBloblangField(pdoFieldCustomDetails).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of additional details about the alert. When not set messages that are JSON objects are added as the custom details.").
+				Example(`root = this.labels`).
+				Optional(),
+			service.NewStringField(pdoFieldBaseURL).
+				Description("The base URL of the Events API.").
+				Default("https://events.pagerduty.com").
+				Advanced(),
+			service.NewRetryPolicyField(pdoFieldRetries),
+			service.NewOutputMaxInFlightField().Default(4),
+		).
+		Example("Auto-Resolving Alerts", "Alerts from a monitoring system are triggered and resolved based on their status, where the ID of each alert is used as the dedup key.", `
+output:
+  pagerduty:
+    routing_key: ${PAGERDUTY_ROUTING_KEY}
+    action: '${! if this.status == "resolved" { "resolve" } else { "trigger" } }'
+    dedup_key: ${! this.fingerprint }
+    summary: ${! this.annotations.summary }
+    source: ${! this.labels.instance }
+    severity: ${! this.labels.severity }
+    severity_map:
+      page: critical
+      ticket: warning
+    custom_details: root = this.labels
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("pagerduty", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newPagerDutyWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pagerDutyWriter struct {
+	routingKey    string
+	action        *service.InterpolatedString
+	dedupKey      *service.InterpolatedString
+	summary       *service.InterpolatedString
+	source        *service.InterpolatedString
+	severity      *service.InterpolatedString
+	severityMap   map[string]string
+	component     *service.InterpolatedString
+	group         *service.InterpolatedString
+	class         *service.InterpolatedString
+	customDetails *bloblang.Executor
+	baseURL       string
+
+	client  *http.Client
+	retries *service.RetryPolicy
+	log     *service.Logger
+}
+
+func newPagerDutyWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*pagerDutyWriter, error) {
+	p := &pagerDutyWriter{
+		client: &http.Client{Timeout: 30 * time.Second},
+		log:    mgr.Logger(),
+	}
+
+	var err error
+	if p.routingKey, err = conf.FieldString(pdoFieldRoutingKey); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		name string
+		dst  **service.InterpolatedString
+	}{
+		{pdoFieldAction, &p.action},
+		{pdoFieldDedupKey, &p.dedupKey},
+		{pdoFieldSummary, &p.summary},
+		{pdoFieldSource, &p.source},
+		{pdoFieldSeverity, &p.severity},
+		{pdoFieldComponent, &p.component},
+		{pdoFieldGroup, &p.group},
+		{pdoFieldClass, &p.class},
+	} {
+		if *f.dst, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+	if p.severityMap, err = conf.FieldStringMap(pdoFieldSeverityMap); err != nil {
+		return nil, err
+	}
+	for k, v := range p.severityMap {
+		if _, exists := pdSeverities[v]; !exists {
+			return nil, fmt.Errorf("severity_map value of key %v is not a valid severity: %v", k, v)
+		}
+	}
+	if conf.Contains(pdoFieldCustomDetails) {
+		if p.customDetails, err = conf.FieldBloblang(pdoFieldCustomDetails); err != nil {
+			return nil, err
+		}
+	}
+	if p.baseURL, err = conf.FieldString(pdoFieldBaseURL); err != nil {
+		return nil, err
+	}
+	p.baseURL = strings.TrimSuffix(p.baseURL, "/")
+	if p.retries, err = conf.FieldRetryPolicy(pdoFieldRetries); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *pagerDutyWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (p *pagerDutyWriter) event(msg *service.Message) (map[string]any, error) {
+	strs := map[string]string{}
+	for k, v := range map[string]*service.InterpolatedString{
+		pdoFieldAction:    p.action,
+		pdoFieldDedupKey:  p.dedupKey,
+		pdoFieldSummary:   p.summary,
+		pdoFieldSource:    p.source,
+		pdoFieldSeverity:  p.severity,
+		pdoFieldComponent: p.component,
+		pdoFieldGroup:     p.group,
+		pdoFieldClass:     p.class,
+	} {
+		var err error
+		if strs[k], err = v.TryString(msg); err != nil {
+			return nil, fmt.Errorf("%v interpolation: %w", k, err)
+		}
+	}
+
+	event := map[string]any{
+		"routing_key":  p.routingKey,
+		"event_action": strs[pdoFieldAction],
+	}
+	if dedupKey := strs[pdoFieldDedupKey]; dedupKey != "" {
+		event["dedup_key"] = dedupKey
+	}
+
+	switch strs[pdoFieldAction] {
+	case "trigger":
+	case "acknowledge", "resolve":
+		if strs[pdoFieldDedupKey] == "" {
+			return nil, fmt.Errorf("a dedup_key is required in order to %v alerts", strs[pdoFieldAction])
+		}
+		return event, nil
+	default:
+		return nil, fmt.Errorf("action must be one of trigger, acknowledge or resolve, got: %v", strs[pdoFieldAction])
+	}
+
+	severity := strs[pdoFieldSeverity]
+	if mapped, exists := p.severityMap[severity]; exists {
+		severity = mapped
+	}
+	if _, exists := pdSeverities[severity]; !exists {
+		return nil, fmt.Errorf("severity must be one of critical, error, warning or info, got: %v", severity)
+	}
+
+	payload := map[string]any{
+		"summary":  strs[pdoFieldSummary],
+		"source":   strs[pdoFieldSource],
+		"severity": severity,
+	}
+	for _, k := range []string{pdoFieldComponent, pdoFieldGroup, pdoFieldClass} {
+		if v := strs[k]; v != "" {
+			payload[k] = v
+		}
+	}
+
+	if p.customDetails != nil {
+		detailsMsg, err := msg.BloblangQuery(p.customDetails)
+		if err != nil {
+			return nil, fmt.Errorf("custom_details mapping failed: %w", err)
+		}
+		if detailsMsg != nil {
+			if payload["custom_details"], err = detailsMsg.AsStructured(); err != nil {
+				return nil, fmt.Errorf("custom_details mapping result: %w", err)
+			}
+		}
+	} else if v, err := msg.AsStructured(); err == nil {
+		if obj, ok := v.(map[string]any); ok {
+			payload["custom_details"] = obj
+		}
+	}
+
+	event["payload"] = payload
+	return event, nil
+}
+
+func (p *pagerDutyWriter) Write(ctx context.Context, msg *service.Message) error {
+	event, err := p.event(msg)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return p.retries.Do(ctx, func(ctx context.Context) error {
+		return p.post(ctx, body)
+	})
+}
+
+// post sends an event.
+func (p *pagerDutyWriter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v2/enqueue", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := service.ParseRetryAfter(res.Header.Get("Retry-After"))
+		return service.NewErrBackOff(errors.New("rate limited by PagerDuty"), retryAfter)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		var apiErr struct {
+			Message string   `json:"message"`
+			Errors  []string `json:"errors"`
+		}
+		if err := json.Unmarshal(resBody, &apiErr); err == nil && apiErr.Message != "" {
+			return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: %v: %v", res.StatusCode, apiErr.Message, strings.Join(apiErr.Errors, ", ")))
+		}
+		return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody)))
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func (p *pagerDutyWriter) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,153 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/public/components/io"
+)
+
+func TestPagerDutyOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var events []any
+	rateLimited := false
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/enqueue", r.URL.Path)
+
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		if !rateLimited {
+			rateLimited = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		var event map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		if event["dedup_key"] == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"invalid event","message":"Event object is invalid","errors":["Length of 'routing_key' is incorrect"]}`))
+			return
+		}
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"success","message":"Event processed","dedup_key":"foo"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+routing_key: fookey
+action: '${! if this.status == "resolved" { "resolve" } else { "trigger" } }'
+dedup_key: ${! this.id }
+summary: ${! this.title }
+source: ${! this.host }
+severity: ${! this.level }
+severity_map:
+  page: critical
+component: ${! this.component | "" }
+custom_details: root = this.labels
+base_url: %v
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	w, err := newPagerDutyWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, w.Connect(ctx))
+
+	require.NoError(t, w.Write(ctx, service.NewMessage([]byte(
+		`{"id":"a1","status":"firing","title":"Disk full","host":"db1","level":"page","component":"postgres","labels":{"disk":"/dev/sda"}}`,
+	))))
+	require.NoError(t, w.Write(ctx, service.NewMessage([]byte(
+		`{"id":"a1","status":"resolved","title":"Disk full","host":"db1","level":"page"}`,
+	))))
+
+	err = w.Write(ctx, service.NewMessage([]byte(
+		`{"id":"bad","status":"firing","title":"Nope","host":"db1","level":"info"}`,
+	)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Length of 'routing_key' is incorrect")
+
+	reqMut.Lock()
+	assert.Equal(t, []any{
+		map[string]any{
+			"routing_key":  "fookey",
+			"event_action": "trigger",
+			"dedup_key":    "a1",
+			"payload": map[string]any{
+				"summary":        "Disk full",
+				"source":         "db1",
+				"severity":       "critical",
+				"component":      "postgres",
+				"custom_details": map[string]any{"disk": "/dev/sda"},
+			},
+		},
+		map[string]any{
+			"routing_key":  "fookey",
+			"event_action": "resolve",
+			"dedup_key":    "a1",
+		},
+	}, events)
+	reqMut.Unlock()
+
+	require.NoError(t, w.Close(ctx))
+}
+
+func TestPagerDutyEventErrors(t *testing.T) {
+	conf, err := outputSpec().ParseYAML(`
+routing_key: fookey
+action: ${! this.action }
+dedup_key: ${! this.id | "" }
+severity: ${! this.level | "error" }
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newPagerDutyWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	for _, test := range []struct {
+		content     string
+		errContains string
+	}{
+		{content: `{"action":"resolve"}`, errContains: "a dedup_key is required"},
+		{content: `{"action":"explode"}`, errContains: "action must be one of"},
+		{content: `{"action":"trigger","level":"meh"}`, errContains: "severity must be one of"},
+	} {
+		_, err := w.event(service.NewMessage([]byte(test.content)))
+		require.Error(t, err, test.content)
+		assert.Contains(t, err.Error(), test.errContains, test.content)
+	}
+
+	event, err := w.event(service.NewMessage([]byte(`{"action":"trigger","foo":"bar"}`)))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"action": "trigger", "foo": "bar"}, event["payload"].(map[string]any)["custom_details"])
+}
+
+func TestPagerDutySeverityMapValidation(t *testing.T) {
+	conf, err := outputSpec().ParseYAML(`
+routing_key: fookey
+severity_map:
+  page: urgent
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newPagerDutyWriterFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a valid severity")
+}
@@ -0,0 +1,372 @@
+package pcap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	pdpFieldLinkType  = "link_type"
+	pdpFieldProtocols = "protocols"
+)
+
+func pcapDissectProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Parsing").
+		Summary("Dissects raw network packets into structured records, including application layer details for common protocols.").
+		Description(`
+Each message is expected to contain a single raw packet, such as those emitted by the `+"[`pcap` scanner](/docs/components/scanners/pcap)"+`. The packet is decoded starting from the configured link layer and the resulting message is an object containing a field for each layer that was successfully decoded:
+
+- `+"`ethernet`"+`: The source and destination MAC addresses along with the EtherType.
+- `+"`ip`"+`: The IP version, source and destination addresses, transport protocol and TTL (or hop limit).
+- `+"`tcp`"+` or `+"`udp`"+`: The source and destination ports along with transport specific details such as TCP flags.
+- `+"`dns`"+`: The questions and answers of DNS queries and responses.
+- `+"`http`"+`: The request line or status line and headers of HTTP/1.x requests and responses.
+- `+"`tls`"+`: Details of TLS client hellos including the server name, ALPN protocols and a [JA3 fingerprint](https://github.com/salesforce/ja3).
+
+Application layer protocols are detected by inspecting the payload of each packet and therefore only messages that fit within a single packet are dissected, no stream reassembly is performed.
+
+Packets that cannot be decoded at the link layer are flagged with an error, which can be handled using [error handling patterns](/docs/configuration/error_handling).`).
+		Example(
+			"JA3 fingerprinting",
+			"Extract the server name and JA3 fingerprint of every TLS client hello within a capture.",
+			`
+input:
+  file:
+    paths: [ ./captures/*.pcap ]
+    scanner:
+      pcap: {}
+
+pipeline:
+  processors:
+    - pcap_dissect:
+        protocols: [ tls ]
+    - mapping: 'root = if this.tls == null { deleted() }'
+    - mapping: |
+        root.src = this.ip.src
+        root.dst = this.ip.dst
+        root.sni = this.tls.client_hello.server_name
+        root.ja3 = this.tls.client_hello.ja3_hash
+`,
+		).
+		Fields(
+			service.NewInterpolatedStringField(pdpFieldLinkType).
+				Description("The link type of each packet, which determines the first layer to decode. By default the link type is taken from the `pcap_link_type` metadata key added by the `pcap` scanner, falling back to `Ethernet`.").
+				Examples("Ethernet", "Raw", "LinuxSLL").
+				Default(`${! meta("pcap_link_type").or("Ethernet") }`).
+				Advanced(),
+			service.NewStringListField(pdpFieldProtocols).
+				Description("A list of application layer protocols to dissect. Supported protocols are `dns`, `http` and `tls`.").
+				Default([]any{"dns", "http", "tls"}),
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor("pcap_dissect", pcapDissectProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return pcapDissectProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pcapDissectProc struct {
+	linkType *service.InterpolatedString
+	dns      bool
+	http     bool
+	tls      bool
+}
+
+func pcapDissectProcFromParsed(conf *service.ParsedConfig) (*pcapDissectProc, error) {
+	p := &pcapDissectProc{}
+
+	var err error
+	if p.linkType, err = conf.FieldInterpolatedString(pdpFieldLinkType); err != nil {
+		return nil, err
+	}
+
+	protocols, err := conf.FieldStringList(pdpFieldProtocols)
+	if err != nil {
+		return nil, err
+	}
+	for _, proto := range protocols {
+		switch proto {
+		case "dns":
+			p.dns = true
+		case "http":
+			p.http = true
+		case "tls":
+			p.tls = true
+		default:
+			return nil, fmt.Errorf("unsupported protocol: %v", proto)
+		}
+	}
+	return p, nil
+}
+
+var linkTypesByName = func() map[string]layers.LinkType {
+	m := map[string]layers.LinkType{}
+	for i, md := range layers.LinkTypeMetadata {
+		if md.DecodeWith != nil && md.Name != "" {
+			m[strings.ToLower(md.Name)] = layers.LinkType(i)
+		}
+	}
+	return m
+}()
+
+func (p *pcapDissectProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	linkTypeStr, err := p.linkType.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("link type interpolation error: %w", err)
+	}
+	linkType, exists := linkTypesByName[strings.ToLower(linkTypeStr)]
+	if !exists {
+		return nil, fmt.Errorf("unsupported link type: %v", linkTypeStr)
+	}
+
+	data, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	packet := gopacket.NewPacket(data, linkType, gopacket.DecodeOptions{
+		Lazy:   true,
+		NoCopy: true,
+	})
+	if len(packet.Layers()) == 0 {
+		if errLayer := packet.ErrorLayer(); errLayer != nil {
+			return nil, errLayer.Error()
+		}
+		return nil, errors.New("failed to decode packet")
+	}
+
+	msg.SetStructuredMut(p.dissect(packet))
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *pcapDissectProc) dissect(packet gopacket.Packet) map[string]any {
+	result := map[string]any{}
+
+	if eth, ok := packet.Layer(layers.LayerTypeEthernet).(*layers.Ethernet); ok {
+		result["ethernet"] = map[string]any{
+			"src_mac":       eth.SrcMAC.String(),
+			"dst_mac":       eth.DstMAC.String(),
+			"ethernet_type": eth.EthernetType.String(),
+		}
+	}
+
+	switch ip := packet.NetworkLayer().(type) {
+	case *layers.IPv4:
+		result["ip"] = map[string]any{
+			"version":  int64(4),
+			"src":      ip.SrcIP.String(),
+			"dst":      ip.DstIP.String(),
+			"protocol": ip.Protocol.String(),
+			"ttl":      int64(ip.TTL),
+			"length":   int64(ip.Length),
+		}
+	case *layers.IPv6:
+		result["ip"] = map[string]any{
+			"version":  int64(6),
+			"src":      ip.SrcIP.String(),
+			"dst":      ip.DstIP.String(),
+			"protocol": ip.NextHeader.String(),
+			"ttl":      int64(ip.HopLimit),
+			"length":   int64(ip.Length),
+		}
+	}
+
+	var appPayload []byte
+	switch t := packet.TransportLayer().(type) {
+	case *layers.TCP:
+		result["tcp"] = map[string]any{
+			"src_port":       int64(t.SrcPort),
+			"dst_port":       int64(t.DstPort),
+			"seq":            int64(t.Seq),
+			"ack":            int64(t.Ack),
+			"flags":          tcpFlags(t),
+			"window":         int64(t.Window),
+			"payload_length": int64(len(t.Payload)),
+		}
+		appPayload = t.Payload
+	case *layers.UDP:
+		result["udp"] = map[string]any{
+			"src_port":       int64(t.SrcPort),
+			"dst_port":       int64(t.DstPort),
+			"payload_length": int64(len(t.Payload)),
+		}
+	}
+
+	if p.dns {
+		if dns, ok := packet.Layer(layers.LayerTypeDNS).(*layers.DNS); ok {
+			result["dns"] = dnsToStructured(dns)
+		}
+	}
+	if len(appPayload) > 0 {
+		if p.http {
+			if h := parseHTTP(appPayload); h != nil {
+				result["http"] = h
+			}
+		}
+		if p.tls {
+			if hello, err := parseClientHello(appPayload); err == nil {
+				result["tls"] = map[string]any{
+					"client_hello": hello.toStructured(),
+				}
+			}
+		}
+	}
+	return result
+}
+
+func (p *pcapDissectProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+func tcpFlags(t *layers.TCP) []any {
+	flags := []any{}
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{t.FIN, "FIN"}, {t.SYN, "SYN"}, {t.RST, "RST"}, {t.PSH, "PSH"},
+		{t.ACK, "ACK"}, {t.URG, "URG"}, {t.ECE, "ECE"}, {t.CWR, "CWR"}, {t.NS, "NS"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	return flags
+}
+
+func dnsRecordData(rr *layers.DNSResourceRecord) string {
+	switch rr.Type {
+	case layers.DNSTypeA, layers.DNSTypeAAAA:
+		return rr.IP.String()
+	case layers.DNSTypeNS:
+		return string(rr.NS)
+	case layers.DNSTypeCNAME:
+		return string(rr.CNAME)
+	case layers.DNSTypePTR:
+		return string(rr.PTR)
+	case layers.DNSTypeMX:
+		return fmt.Sprintf("%v %s", rr.MX.Preference, rr.MX.Name)
+	case layers.DNSTypeTXT:
+		txts := make([]string, len(rr.TXTs))
+		for i, t := range rr.TXTs {
+			txts[i] = string(t)
+		}
+		return strings.Join(txts, "")
+	case layers.DNSTypeSOA:
+		return fmt.Sprintf("%s %s %v %v %v %v %v", rr.SOA.MName, rr.SOA.RName, rr.SOA.Serial, rr.SOA.Refresh, rr.SOA.Retry, rr.SOA.Expire, rr.SOA.Minimum)
+	case layers.DNSTypeSRV:
+		return fmt.Sprintf("%v %v %v %s", rr.SRV.Priority, rr.SRV.Weight, rr.SRV.Port, rr.SRV.Name)
+	}
+	return fmt.Sprintf("%x", rr.Data)
+}
+
+func dnsRecordsToStructured(rrs []layers.DNSResourceRecord) []any {
+	records := make([]any, 0, len(rrs))
+	for i := range rrs {
+		rr := &rrs[i]
+		records = append(records, map[string]any{
+			"name":  string(rr.Name),
+			"type":  rr.Type.String(),
+			"class": rr.Class.String(),
+			"ttl":   int64(rr.TTL),
+			"data":  dnsRecordData(rr),
+		})
+	}
+	return records
+}
+
+func dnsToStructured(dns *layers.DNS) map[string]any {
+	questions := make([]any, 0, len(dns.Questions))
+	for _, q := range dns.Questions {
+		questions = append(questions, map[string]any{
+			"name":  string(q.Name),
+			"type":  q.Type.String(),
+			"class": q.Class.String(),
+		})
+	}
+	return map[string]any{
+		"id":          int64(dns.ID),
+		"response":    dns.QR,
+		"opcode":      dns.OpCode.String(),
+		"rcode":       dns.ResponseCode.String(),
+		"questions":   questions,
+		"answers":     dnsRecordsToStructured(dns.Answers),
+		"authorities": dnsRecordsToStructured(dns.Authorities),
+	}
+}
+
+var httpMethodPrefixes = [][]byte{
+	[]byte("GET "), []byte("POST "), []byte("PUT "), []byte("DELETE "),
+	[]byte("HEAD "), []byte("OPTIONS "), []byte("PATCH "), []byte("CONNECT "),
+	[]byte("TRACE "),
+}
+
+func headersToStructured(h http.Header) map[string]any {
+	headers := make(map[string]any, len(h))
+	for k, v := range h {
+		headers[k] = strings.Join(v, ", ")
+	}
+	return headers
+}
+
+// parseHTTP attempts to parse the request or status line and headers of an
+// HTTP/1.x message, returning nil if the payload doesn't look like one.
+func parseHTTP(payload []byte) map[string]any {
+	if bytes.HasPrefix(payload, []byte("HTTP/1.")) {
+		res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(payload)), nil)
+		if err != nil {
+			return nil
+		}
+		return map[string]any{
+			"type":        "response",
+			"version":     res.Proto,
+			"status_code": int64(res.StatusCode),
+			"headers":     headersToStructured(res.Header),
+		}
+	}
+
+	isReq := false
+	for _, prefix := range httpMethodPrefixes {
+		if bytes.HasPrefix(payload, prefix) {
+			isReq = true
+			break
+		}
+	}
+	if !isReq {
+		return nil
+	}
+
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(payload)))
+	if err != nil {
+		return nil
+	}
+	return map[string]any{
+		"type":       "request",
+		"version":    req.Proto,
+		"method":     req.Method,
+		"uri":        req.RequestURI,
+		"host":       req.Host,
+		"user_agent": req.UserAgent(),
+		"headers":    headersToStructured(req.Header),
+	}
+}
@@ -0,0 +1,201 @@
+package pcap
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func serializePacket(t testing.TB, ls ...gopacket.SerializableLayer) []byte {
+	t.Helper()
+
+	buf := gopacket.NewSerializeBuffer()
+	require.NoError(t, gopacket.SerializeLayers(buf, gopacket.SerializeOptions{
+		FixLengths:       true,
+		ComputeChecksums: true,
+	}, ls...))
+	return buf.Bytes()
+}
+
+func testEthIPv4(proto layers.IPProtocol) (*layers.Ethernet, *layers.IPv4) {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0x00, 0x11, 0x22, 0x33, 0x44, 0x55},
+		DstMAC:       net.HardwareAddr{0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: proto,
+		SrcIP:    net.IPv4(10, 0, 0, 1),
+		DstIP:    net.IPv4(10, 0, 0, 2),
+	}
+	return eth, ip
+}
+
+func dissectProc(t testing.TB, yamlStr string) *pcapDissectProc {
+	t.Helper()
+
+	pConf, err := pcapDissectProcSpec().ParseYAML(yamlStr, nil)
+	require.NoError(t, err)
+
+	proc, err := pcapDissectProcFromParsed(pConf)
+	require.NoError(t, err)
+	return proc
+}
+
+func dissect(t testing.TB, proc *pcapDissectProc, data []byte) map[string]any {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), service.NewMessage(data))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+	return v.(map[string]any)
+}
+
+func TestPCAPDissectDNS(t *testing.T) {
+	eth, ip := testEthIPv4(layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 53, DstPort: 40000}
+	require.NoError(t, udp.SetNetworkLayerForChecksum(ip))
+	dns := &layers.DNS{
+		ID:           0xbeef,
+		QR:           true,
+		OpCode:       layers.DNSOpCodeQuery,
+		ResponseCode: layers.DNSResponseCodeNoErr,
+		Questions: []layers.DNSQuestion{
+			{Name: []byte("example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN},
+		},
+		Answers: []layers.DNSResourceRecord{
+			{Name: []byte("example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN, TTL: 300, IP: net.IPv4(93, 184, 216, 34)},
+		},
+	}
+
+	res := dissect(t, dissectProc(t, `{}`), serializePacket(t, eth, ip, udp, dns))
+
+	assert.Equal(t, map[string]any{
+		"src_mac":       "00:11:22:33:44:55",
+		"dst_mac":       "66:77:88:99:aa:bb",
+		"ethernet_type": "IPv4",
+	}, res["ethernet"])
+
+	ipRes := res["ip"].(map[string]any)
+	assert.Equal(t, "10.0.0.1", ipRes["src"])
+	assert.Equal(t, "10.0.0.2", ipRes["dst"])
+	assert.Equal(t, "UDP", ipRes["protocol"])
+
+	udpRes := res["udp"].(map[string]any)
+	assert.Equal(t, int64(53), udpRes["src_port"])
+	assert.Equal(t, int64(40000), udpRes["dst_port"])
+
+	assert.Equal(t, map[string]any{
+		"id":       int64(0xbeef),
+		"response": true,
+		"opcode":   "Query",
+		"rcode":    "No Error",
+		"questions": []any{
+			map[string]any{"name": "example.com", "type": "A", "class": "IN"},
+		},
+		"answers": []any{
+			map[string]any{"name": "example.com", "type": "A", "class": "IN", "ttl": int64(300), "data": "93.184.216.34"},
+		},
+		"authorities": []any{},
+	}, res["dns"])
+}
+
+func TestPCAPDissectDNSDisabled(t *testing.T) {
+	eth, ip := testEthIPv4(layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 40000, DstPort: 53}
+	require.NoError(t, udp.SetNetworkLayerForChecksum(ip))
+	dns := &layers.DNS{
+		ID: 1,
+		Questions: []layers.DNSQuestion{
+			{Name: []byte("example.com"), Type: layers.DNSTypeA, Class: layers.DNSClassIN},
+		},
+	}
+
+	res := dissect(t, dissectProc(t, `protocols: [ http ]`), serializePacket(t, eth, ip, udp, dns))
+	assert.Contains(t, res, "udp")
+	assert.NotContains(t, res, "dns")
+}
+
+func TestPCAPDissectHTTP(t *testing.T) {
+	eth, ip := testEthIPv4(layers.IPProtocolTCP)
+	tcp := &layers.TCP{SrcPort: 50000, DstPort: 80, Seq: 10, PSH: true, ACK: true, Window: 512}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+	payload := gopacket.Payload("GET /foo?bar=baz HTTP/1.1\r\nHost: example.com\r\nUser-Agent: curl/8.0\r\n\r\n")
+
+	res := dissect(t, dissectProc(t, `{}`), serializePacket(t, eth, ip, tcp, payload))
+
+	tcpRes := res["tcp"].(map[string]any)
+	assert.Equal(t, []any{"PSH", "ACK"}, tcpRes["flags"])
+	assert.Equal(t, int64(80), tcpRes["dst_port"])
+
+	assert.Equal(t, map[string]any{
+		"type":       "request",
+		"version":    "HTTP/1.1",
+		"method":     "GET",
+		"uri":        "/foo?bar=baz",
+		"host":       "example.com",
+		"user_agent": "curl/8.0",
+		"headers": map[string]any{
+			"User-Agent": "curl/8.0",
+		},
+	}, res["http"])
+	assert.NotContains(t, res, "tls")
+}
+
+func TestPCAPDissectHTTPResponse(t *testing.T) {
+	eth, ip := testEthIPv4(layers.IPProtocolTCP)
+	tcp := &layers.TCP{SrcPort: 80, DstPort: 50000, ACK: true}
+	require.NoError(t, tcp.SetNetworkLayerForChecksum(ip))
+	payload := gopacket.Payload("HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n")
+
+	res := dissect(t, dissectProc(t, `{}`), serializePacket(t, eth, ip, tcp, payload))
+	assert.Equal(t, map[string]any{
+		"type":        "response",
+		"version":     "HTTP/1.1",
+		"status_code": int64(404),
+		"headers": map[string]any{
+			"Content-Length": "0",
+		},
+	}, res["http"])
+}
+
+func TestPCAPDissectRawLinkType(t *testing.T) {
+	_, ip := testEthIPv4(layers.IPProtocolUDP)
+	udp := &layers.UDP{SrcPort: 1000, DstPort: 2000}
+	require.NoError(t, udp.SetNetworkLayerForChecksum(ip))
+
+	data := serializePacket(t, ip, udp, gopacket.Payload("hello"))
+
+	msg := service.NewMessage(data)
+	msg.MetaSetMut("pcap_link_type", "Raw")
+
+	res, err := dissectProc(t, `{}`).Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	v, err := res[0].AsStructured()
+	require.NoError(t, err)
+
+	obj := v.(map[string]any)
+	assert.NotContains(t, obj, "ethernet")
+	assert.Equal(t, "10.0.0.1", obj["ip"].(map[string]any)["src"])
+	assert.Equal(t, int64(2000), obj["udp"].(map[string]any)["dst_port"])
+}
+
+func TestPCAPDissectBadLinkType(t *testing.T) {
+	proc := dissectProc(t, `link_type: nope`)
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte("foo")))
+	require.Error(t, err)
+}
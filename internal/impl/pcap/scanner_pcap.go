@@ -0,0 +1,160 @@
+package pcap
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	pcapngMagic = 0x0A0D0D0A
+)
+
+func pcapScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary("Consume packets from PCAP or PCAPNG capture files, emitting one message per packet.").
+		Description(`
+The capture format is detected automatically from the leading magic bytes of the file. The contents of each message is the raw packet data as it was captured, which can be broken down into structured records with the `+"[`pcap_dissect` processor](/docs/components/processors/pcap_dissect)"+`.
+
+### Metadata
+
+This scanner adds the following metadata to each message:
+
+- `+"`pcap_index`"+` The index of the packet within the capture, beginning at 0.
+- `+"`pcap_timestamp_unix_nano`"+` The capture timestamp of the packet in nanoseconds since the unix epoch.
+- `+"`pcap_capture_length`"+` The number of bytes of the packet that were captured.
+- `+"`pcap_length`"+` The original length of the packet on the wire.
+- `+"`pcap_link_type`"+` The link type of the capture (or interface for PCAPNG), e.g. `+"`Ethernet`"+`.
+- `+"`pcap_interface_index`"+` The index of the interface the packet was captured on (PCAPNG only).
+`).
+		Example(
+			"Dissect DNS traffic from captures",
+			"Read capture files from a directory and extract structured DNS records from each packet, dropping everything else.",
+			`
+input:
+  file:
+    paths: [ ./captures/*.pcapng ]
+    scanner:
+      pcap: {}
+
+pipeline:
+  processors:
+    - pcap_dissect:
+        protocols: [ dns ]
+    - mapping: 'root = if this.dns == null { deleted() }'
+`,
+		).
+		Field(service.NewObjectField("").Default(map[string]any{}))
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("pcap", pcapScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return &pcapScannerCreator{}, nil
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pcapScannerCreator struct{}
+
+type packetSource interface {
+	ReadPacketData() ([]byte, gopacket.CaptureInfo, error)
+}
+
+func (c *pcapScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	bRdr := bufio.NewReader(rdr)
+
+	magic, err := bRdr.Peek(4)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &pcapScanner{r: rdr}
+	if binary.LittleEndian.Uint32(magic) == pcapngMagic {
+		opts := pcapgo.DefaultNgReaderOptions
+		opts.WantMixedLinkType = true
+		ngRdr, err := pcapgo.NewNgReader(bRdr, opts)
+		if err != nil {
+			return nil, err
+		}
+		s.src = ngRdr
+		s.linkTypeFn = func(ci gopacket.CaptureInfo) layers.LinkType {
+			if iface, err := ngRdr.Interface(ci.InterfaceIndex); err == nil {
+				return iface.LinkType
+			}
+			return ngRdr.LinkType()
+		}
+		s.isNg = true
+	} else {
+		pRdr, err := pcapgo.NewReader(bRdr)
+		if err != nil {
+			return nil, err
+		}
+		s.src = pRdr
+		s.linkTypeFn = func(gopacket.CaptureInfo) layers.LinkType {
+			return pRdr.LinkType()
+		}
+	}
+
+	return service.AutoAggregateBatchScannerAcks(s, aFn), nil
+}
+
+func (c *pcapScannerCreator) Close(context.Context) error {
+	return nil
+}
+
+type pcapScanner struct {
+	r          io.ReadCloser
+	src        packetSource
+	linkTypeFn func(gopacket.CaptureInfo) layers.LinkType
+	isNg       bool
+	index      int
+}
+
+func (p *pcapScanner) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	if p.r == nil {
+		return nil, io.EOF
+	}
+
+	data, ci, err := p.src.ReadPacketData()
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			// A capture that was cut short mid-packet is treated as complete
+			// rather than failing the whole file.
+			err = io.EOF
+		}
+		return nil, err
+	}
+
+	msg := service.NewMessage(data)
+	msg.MetaSetMut("pcap_index", p.index)
+	msg.MetaSetMut("pcap_timestamp_unix_nano", ci.Timestamp.UnixNano())
+	msg.MetaSetMut("pcap_capture_length", len(data))
+	msg.MetaSetMut("pcap_length", ci.Length)
+	msg.MetaSetMut("pcap_link_type", p.linkTypeFn(ci).String())
+	if p.isNg {
+		msg.MetaSetMut("pcap_interface_index", ci.InterfaceIndex)
+	}
+	p.index++
+
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *pcapScanner) Close(ctx context.Context) error {
+	if p.r == nil {
+		return nil
+	}
+	return p.r.Close()
+}
@@ -0,0 +1,143 @@
+package pcap
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testPackets() [][]byte {
+	return [][]byte{
+		[]byte("packet one"),
+		[]byte("packet two"),
+		[]byte("packet three"),
+	}
+}
+
+func writeTestPCAP(t testing.TB, packets [][]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := pcapgo.NewWriter(&buf)
+	require.NoError(t, w.WriteFileHeader(65536, layers.LinkTypeEthernet))
+	for i, p := range packets {
+		require.NoError(t, w.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     time.Unix(int64(i), 0),
+			CaptureLength: len(p),
+			Length:        len(p),
+		}, p))
+	}
+	return buf.Bytes()
+}
+
+func writeTestPCAPNG(t testing.TB, packets [][]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := pcapgo.NewNgWriter(&buf, layers.LinkTypeRaw)
+	require.NoError(t, err)
+	for i, p := range packets {
+		require.NoError(t, w.WritePacket(gopacket.CaptureInfo{
+			Timestamp:     time.Unix(int64(i), 0),
+			CaptureLength: len(p),
+			Length:        len(p),
+		}, p))
+	}
+	require.NoError(t, w.Flush())
+	return buf.Bytes()
+}
+
+func scanAll(t testing.TB, data []byte) service.MessageBatch {
+	t.Helper()
+
+	confSpec := service.NewConfigSpec().Field(service.NewScannerField("test"))
+	pConf, err := confSpec.ParseYAML(`
+test:
+  pcap: {}
+`, nil)
+	require.NoError(t, err)
+
+	rdr, err := pConf.FieldScanner("test")
+	require.NoError(t, err)
+
+	var acked bool
+	s, err := rdr.Create(io.NopCloser(bytes.NewReader(data)), func(ctx context.Context, err error) error {
+		require.NoError(t, err)
+		acked = true
+		return nil
+	}, service.NewScannerSourceDetails())
+	require.NoError(t, err)
+
+	var msgs service.MessageBatch
+	for {
+		b, aFn, err := s.NextBatch(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.NoError(t, aFn(context.Background(), nil))
+		msgs = append(msgs, b...)
+	}
+	require.NoError(t, s.Close(context.Background()))
+	assert.True(t, acked)
+	return msgs
+}
+
+func TestPCAPScanner(t *testing.T) {
+	packets := testPackets()
+	msgs := scanAll(t, writeTestPCAP(t, packets))
+	require.Len(t, msgs, len(packets))
+
+	for i, m := range msgs {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, string(packets[i]), string(b))
+
+		v, _ := m.MetaGetMut("pcap_index")
+		assert.Equal(t, i, v)
+
+		v, _ = m.MetaGetMut("pcap_timestamp_unix_nano")
+		assert.Equal(t, time.Unix(int64(i), 0).UnixNano(), v)
+
+		v, _ = m.MetaGetMut("pcap_link_type")
+		assert.Equal(t, "Ethernet", v)
+
+		_, exists := m.MetaGetMut("pcap_interface_index")
+		assert.False(t, exists)
+	}
+}
+
+func TestPCAPNGScanner(t *testing.T) {
+	packets := testPackets()
+	msgs := scanAll(t, writeTestPCAPNG(t, packets))
+	require.Len(t, msgs, len(packets))
+
+	for i, m := range msgs {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, string(packets[i]), string(b))
+
+		v, _ := m.MetaGetMut("pcap_link_type")
+		assert.Equal(t, "Raw", v)
+
+		v, _ = m.MetaGetMut("pcap_interface_index")
+		assert.Equal(t, 0, v)
+	}
+}
+
+func TestPCAPScannerTruncated(t *testing.T) {
+	packets := testPackets()
+	data := writeTestPCAP(t, packets)
+	msgs := scanAll(t, data[:len(data)-3])
+	require.Len(t, msgs, len(packets)-1)
+}
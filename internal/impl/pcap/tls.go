@@ -0,0 +1,238 @@
+package pcap
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var errNotClientHello = errors.New("payload is not a TLS client hello")
+
+const (
+	tlsRecordTypeHandshake    = 0x16
+	tlsHandshakeClientHello   = 0x01
+	tlsExtServerName          = 0
+	tlsExtSupportedGroups     = 10
+	tlsExtECPointFormats      = 11
+	tlsExtALPN                = 16
+	tlsExtSupportedVersions   = 43
+	tlsServerNameTypeHostname = 0
+)
+
+// clientHello contains the fields of a TLS client hello that are of interest
+// for fingerprinting and enrichment.
+type clientHello struct {
+	version           uint16
+	cipherSuites      []uint16
+	extensions        []uint16
+	supportedGroups   []uint16
+	ecPointFormats    []uint8
+	serverName        string
+	alpn              []string
+	supportedVersions []uint16
+}
+
+// isGREASE returns true for the reserved values described in RFC 8701, which
+// are excluded from JA3 fingerprints.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+// byteReader is a tiny cursor over a byte slice that reports truncation as a
+// single sticky failure, which keeps the parsing code below linear.
+type byteReader struct {
+	b   []byte
+	err bool
+}
+
+func (r *byteReader) u8() uint8 {
+	if r.err || len(r.b) < 1 {
+		r.err = true
+		return 0
+	}
+	v := r.b[0]
+	r.b = r.b[1:]
+	return v
+}
+
+func (r *byteReader) u16() uint16 {
+	if r.err || len(r.b) < 2 {
+		r.err = true
+		return 0
+	}
+	v := binary.BigEndian.Uint16(r.b)
+	r.b = r.b[2:]
+	return v
+}
+
+func (r *byteReader) bytes(n int) []byte {
+	if r.err || len(r.b) < n {
+		r.err = true
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *byteReader) u24() int {
+	b := r.bytes(3)
+	if b == nil {
+		return 0
+	}
+	return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+}
+
+// parseClientHello attempts to parse a TLS client hello from the payload of a
+// TCP segment. Only hellos that fit within the segment are supported.
+func parseClientHello(payload []byte) (*clientHello, error) {
+	rec := &byteReader{b: payload}
+	if rec.u8() != tlsRecordTypeHandshake {
+		return nil, errNotClientHello
+	}
+	if major := rec.u8(); major != 3 {
+		return nil, errNotClientHello
+	}
+	_ = rec.u8()
+	body := rec.bytes(int(rec.u16()))
+	if rec.err {
+		return nil, errNotClientHello
+	}
+
+	hs := &byteReader{b: body}
+	if hs.u8() != tlsHandshakeClientHello {
+		return nil, errNotClientHello
+	}
+	hs = &byteReader{b: hs.bytes(hs.u24())}
+	if hs.err {
+		return nil, errors.New("truncated TLS client hello")
+	}
+
+	h := &clientHello{}
+	h.version = hs.u16()
+	_ = hs.bytes(32)           // Random
+	_ = hs.bytes(int(hs.u8())) // Session ID
+	suites := hs.bytes(int(hs.u16()))
+	for i := 0; i+1 < len(suites); i += 2 {
+		h.cipherSuites = append(h.cipherSuites, binary.BigEndian.Uint16(suites[i:]))
+	}
+	_ = hs.bytes(int(hs.u8())) // Compression methods
+	if hs.err {
+		return nil, errors.New("truncated TLS client hello")
+	}
+	if len(hs.b) == 0 {
+		return h, nil
+	}
+
+	exts := &byteReader{b: hs.bytes(int(hs.u16()))}
+	for !exts.err && len(exts.b) > 0 {
+		extType := exts.u16()
+		ext := &byteReader{b: exts.bytes(int(exts.u16()))}
+		if exts.err {
+			break
+		}
+		h.extensions = append(h.extensions, extType)
+
+		switch extType {
+		case tlsExtServerName:
+			names := &byteReader{b: ext.bytes(int(ext.u16()))}
+			for !names.err && len(names.b) > 0 {
+				nameType := names.u8()
+				name := names.bytes(int(names.u16()))
+				if !names.err && nameType == tlsServerNameTypeHostname {
+					h.serverName = string(name)
+				}
+			}
+		case tlsExtSupportedGroups:
+			groups := &byteReader{b: ext.bytes(int(ext.u16()))}
+			for !groups.err && len(groups.b) > 1 {
+				h.supportedGroups = append(h.supportedGroups, groups.u16())
+			}
+		case tlsExtECPointFormats:
+			h.ecPointFormats = append(h.ecPointFormats, ext.bytes(int(ext.u8()))...)
+		case tlsExtALPN:
+			protos := &byteReader{b: ext.bytes(int(ext.u16()))}
+			for !protos.err && len(protos.b) > 0 {
+				if p := protos.bytes(int(protos.u8())); !protos.err {
+					h.alpn = append(h.alpn, string(p))
+				}
+			}
+		case tlsExtSupportedVersions:
+			versions := &byteReader{b: ext.bytes(int(ext.u8()))}
+			for !versions.err && len(versions.b) > 1 {
+				h.supportedVersions = append(h.supportedVersions, versions.u16())
+			}
+		}
+	}
+	if exts.err {
+		return nil, errors.New("truncated TLS client hello extensions")
+	}
+	return h, nil
+}
+
+func joinUint16s(vs []uint16, skipGREASE bool) string {
+	var sb strings.Builder
+	for _, v := range vs {
+		if skipGREASE && isGREASE(v) {
+			continue
+		}
+		if sb.Len() > 0 {
+			_ = sb.WriteByte('-')
+		}
+		_, _ = sb.WriteString(strconv.Itoa(int(v)))
+	}
+	return sb.String()
+}
+
+// ja3 returns the JA3 fingerprint string of the client hello along with its
+// MD5 hash.
+func (h *clientHello) ja3() (str, hash string) {
+	formats := make([]uint16, len(h.ecPointFormats))
+	for i, f := range h.ecPointFormats {
+		formats[i] = uint16(f)
+	}
+	str = strings.Join([]string{
+		strconv.Itoa(int(h.version)),
+		joinUint16s(h.cipherSuites, true),
+		joinUint16s(h.extensions, true),
+		joinUint16s(h.supportedGroups, true),
+		joinUint16s(formats, false),
+	}, ",")
+	sum := md5.Sum([]byte(str))
+	return str, hex.EncodeToString(sum[:])
+}
+
+func uint16sToAny(vs []uint16) []any {
+	a := make([]any, len(vs))
+	for i, v := range vs {
+		a[i] = int64(v)
+	}
+	return a
+}
+
+func (h *clientHello) toStructured() map[string]any {
+	ja3Str, ja3Hash := h.ja3()
+	formats := make([]any, len(h.ecPointFormats))
+	for i, f := range h.ecPointFormats {
+		formats[i] = int64(f)
+	}
+	alpn := make([]any, len(h.alpn))
+	for i, p := range h.alpn {
+		alpn[i] = p
+	}
+	return map[string]any{
+		"version":            int64(h.version),
+		"server_name":        h.serverName,
+		"cipher_suites":      uint16sToAny(h.cipherSuites),
+		"extensions":         uint16sToAny(h.extensions),
+		"supported_groups":   uint16sToAny(h.supportedGroups),
+		"ec_point_formats":   formats,
+		"alpn":               alpn,
+		"supported_versions": uint16sToAny(h.supportedVersions),
+		"ja3":                ja3Str,
+		"ja3_hash":           ja3Hash,
+	}
+}
@@ -0,0 +1,89 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func u16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
+
+func withLen16(b []byte) []byte {
+	return append(u16(uint16(len(b))), b...)
+}
+
+func testClientHello() []byte {
+	var exts []byte
+
+	// GREASE extension, should be omitted from JA3
+	exts = append(exts, u16(0x0a0a)...)
+	exts = append(exts, withLen16(nil)...)
+
+	// Server name
+	sni := append([]byte{0}, withLen16([]byte("example.com"))...)
+	exts = append(exts, u16(tlsExtServerName)...)
+	exts = append(exts, withLen16(withLen16(sni))...)
+
+	// Supported groups
+	exts = append(exts, u16(tlsExtSupportedGroups)...)
+	exts = append(exts, withLen16(withLen16(append(append(u16(0x1a1a), u16(29)...), u16(23)...)))...)
+
+	// EC point formats
+	exts = append(exts, u16(tlsExtECPointFormats)...)
+	exts = append(exts, withLen16([]byte{1, 0})...)
+
+	// ALPN
+	exts = append(exts, u16(tlsExtALPN)...)
+	exts = append(exts, withLen16(withLen16(append([]byte{2}, []byte("h2")...)))...)
+
+	var body []byte
+	body = append(body, u16(0x0303)...)
+	body = append(body, make([]byte, 32)...)
+	body = append(body, 0)
+	body = append(body, withLen16(append(append(u16(0x2a2a), u16(4865)...), u16(49195)...))...)
+	body = append(body, 1, 0)
+	body = append(body, withLen16(exts)...)
+
+	hs := append([]byte{tlsHandshakeClientHello, 0}, u16(uint16(len(body)))...)
+	hs = append(hs, body...)
+
+	rec := []byte{tlsRecordTypeHandshake, 3, 1}
+	return append(rec, withLen16(hs)...)
+}
+
+func TestParseClientHello(t *testing.T) {
+	h, err := parseClientHello(testClientHello())
+	require.NoError(t, err)
+
+	assert.Equal(t, uint16(0x0303), h.version)
+	assert.Equal(t, "example.com", h.serverName)
+	assert.Equal(t, []string{"h2"}, h.alpn)
+	assert.Equal(t, []uint16{0x2a2a, 4865, 49195}, h.cipherSuites)
+	assert.Equal(t, []uint16{0x0a0a, 0, 10, 11, 16}, h.extensions)
+	assert.Equal(t, []uint16{0x1a1a, 29, 23}, h.supportedGroups)
+	assert.Equal(t, []uint8{0}, h.ecPointFormats)
+
+	str, hash := h.ja3()
+	assert.Equal(t, "771,4865-49195,0-10-11-16,29-23,0", str)
+	assert.Equal(t, "53962ec19dcdb5203d1ae1d50be37d3d", hash)
+}
+
+func TestParseClientHelloErrors(t *testing.T) {
+	_, err := parseClientHello([]byte("GET / HTTP/1.1\r\n\r\n"))
+	require.ErrorIs(t, err, errNotClientHello)
+
+	hello := testClientHello()
+	_, err = parseClientHello(hello[:len(hello)-10])
+	require.Error(t, err)
+}
+
+func TestIsGREASE(t *testing.T) {
+	assert.True(t, isGREASE(0x0a0a))
+	assert.True(t, isGREASE(0xfafa))
+	assert.False(t, isGREASE(0x0a1a))
+	assert.False(t, isGREASE(4865))
+}
@@ -0,0 +1,392 @@
+package postgresql
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+type changeKind int
+
+const (
+	changeBegin changeKind = iota
+	changeCommit
+	changeInsert
+	changeUpdate
+	changeDelete
+	changeTruncate
+)
+
+var changeOperations = map[changeKind]string{
+	changeInsert:   "insert",
+	changeUpdate:   "update",
+	changeDelete:   "delete",
+	changeTruncate: "truncate",
+}
+
+// change is a decoded message of a logical replication stream, which is either
+// a transaction boundary or a change to a table.
+type change struct {
+	kind      changeKind
+	xid       uint32
+	timestamp time.Time
+
+	schema string
+	table  string
+	before map[string]any
+	after  map[string]any
+}
+
+// structured returns the structured form of a table change.
+func (c change) structured() map[string]any {
+	obj := map[string]any{
+		"operation": changeOperations[c.kind],
+		"schema":    c.schema,
+		"table":     c.table,
+	}
+	if c.before != nil {
+		obj["before"] = c.before
+	}
+	if c.after != nil {
+		obj["after"] = c.after
+	}
+	return obj
+}
+
+// decoder decodes the messages emitted by a logical decoding output plugin.
+type decoder interface {
+	// pluginArgs returns the options provided to the plugin when starting
+	// replication.
+	pluginArgs() []string
+
+	// decode a message into zero or more changes.
+	decode(data []byte) ([]change, error)
+}
+
+//------------------------------------------------------------------------------
+
+type pgColumn struct {
+	name    string
+	typeOID uint32
+}
+
+type pgRelation struct {
+	schema  string
+	table   string
+	columns []pgColumn
+}
+
+// pgoutputDecoder decodes version 1 of the pgoutput protocol, which is the
+// built in output plugin of PostgreSQL.
+type pgoutputDecoder struct {
+	publication string
+	relations   map[uint32]pgRelation
+}
+
+func newPgoutputDecoder(publication string) *pgoutputDecoder {
+	return &pgoutputDecoder{
+		publication: publication,
+		relations:   map[uint32]pgRelation{},
+	}
+}
+
+func (d *pgoutputDecoder) pluginArgs() []string {
+	return []string{
+		"proto_version '1'",
+		"publication_names " + quoteLiteral(d.publication),
+	}
+}
+
+type msgReader struct {
+	b   []byte
+	err error
+}
+
+func (r *msgReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.b) < n {
+		r.err = errors.New("message is truncated")
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *msgReader) uint8() uint8 {
+	if b := r.next(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *msgReader) uint16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *msgReader) uint32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *msgReader) uint64() uint64 {
+	if b := r.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (r *msgReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	i := bytes.IndexByte(r.b, 0)
+	if i < 0 {
+		r.err = errors.New("message is truncated")
+		return ""
+	}
+	s := string(r.b[:i])
+	r.b = r.b[i+1:]
+	return s
+}
+
+func (d *pgoutputDecoder) decode(data []byte) ([]change, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty message")
+	}
+	r := &msgReader{b: data[1:]}
+
+	var changes []change
+	switch data[0] {
+	case 'B':
+		_ = r.uint64() // Final LSN of the transaction
+		ts := int64(r.uint64())
+		changes = append(changes, change{
+			kind:      changeBegin,
+			timestamp: pgTime(ts),
+			xid:       r.uint32(),
+		})
+	case 'C':
+		changes = append(changes, change{kind: changeCommit})
+	case 'R':
+		id := r.uint32()
+		rel := pgRelation{schema: r.string(), table: r.string()}
+		_ = r.uint8() // Replica identity
+		n := int(r.uint16())
+		for i := 0; i < n && r.err == nil; i++ {
+			_ = r.uint8() // Flags
+			col := pgColumn{name: r.string(), typeOID: r.uint32()}
+			_ = r.uint32() // Type modifier
+			rel.columns = append(rel.columns, col)
+		}
+		if r.err == nil {
+			d.relations[id] = rel
+		}
+	case 'I':
+		c, rel, err := d.relationChange(r, changeInsert)
+		if err != nil {
+			return nil, err
+		}
+		if r.uint8() != 'N' && r.err == nil {
+			return nil, errors.New("insert message is missing a new tuple")
+		}
+		c.after = d.tuple(r, rel)
+		changes = append(changes, c)
+	case 'U':
+		c, rel, err := d.relationChange(r, changeUpdate)
+		if err != nil {
+			return nil, err
+		}
+		kind := r.uint8()
+		if kind == 'K' || kind == 'O' {
+			c.before = d.tuple(r, rel)
+			kind = r.uint8()
+		}
+		if kind != 'N' && r.err == nil {
+			return nil, errors.New("update message is missing a new tuple")
+		}
+		c.after = d.tuple(r, rel)
+		changes = append(changes, c)
+	case 'D':
+		c, rel, err := d.relationChange(r, changeDelete)
+		if err != nil {
+			return nil, err
+		}
+		if kind := r.uint8(); kind != 'K' && kind != 'O' && r.err == nil {
+			return nil, errors.New("delete message is missing an old tuple")
+		}
+		c.before = d.tuple(r, rel)
+		changes = append(changes, c)
+	case 'T':
+		n := int(r.uint32())
+		_ = r.uint8() // Options
+		for i := 0; i < n && r.err == nil; i++ {
+			rel, exists := d.relations[r.uint32()]
+			if !exists {
+				return nil, errors.New("truncate message references an unknown relation")
+			}
+			changes = append(changes, change{kind: changeTruncate, schema: rel.schema, table: rel.table})
+		}
+	default:
+		// Origin, type and logical decoding messages are ignored.
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return changes, nil
+}
+
+func (d *pgoutputDecoder) relationChange(r *msgReader, kind changeKind) (change, pgRelation, error) {
+	id := r.uint32()
+	if r.err != nil {
+		return change{}, pgRelation{}, r.err
+	}
+	rel, exists := d.relations[id]
+	if !exists {
+		return change{}, pgRelation{}, fmt.Errorf("change references unknown relation %v", id)
+	}
+	return change{kind: kind, schema: rel.schema, table: rel.table}, rel, nil
+}
+
+// tuple reads tuple data into a map of column names to values. Unchanged TOAST
+// values are not sent by the server and are therefore omitted.
+func (d *pgoutputDecoder) tuple(r *msgReader, rel pgRelation) map[string]any {
+	n := int(r.uint16())
+	row := make(map[string]any, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		var col pgColumn
+		if i < len(rel.columns) {
+			col = rel.columns[i]
+		} else {
+			col.name = strconv.Itoa(i)
+		}
+		switch r.uint8() {
+		case 'n':
+			row[col.name] = nil
+		case 'u':
+		case 't':
+			row[col.name] = textValue(col.typeOID, string(r.next(int(r.uint32()))))
+		}
+	}
+	return row
+}
+
+// Type OIDs of the values converted from their text form.
+const (
+	oidBool    = 16
+	oidInt8    = 20
+	oidInt2    = 21
+	oidInt4    = 23
+	oidOID     = 26
+	oidJSON    = 114
+	oidFloat4  = 700
+	oidFloat8  = 701
+	oidNumeric = 1700
+	oidJSONB   = 3802
+)
+
+// textValue converts the text form of a value to its structured equivalent
+// where possible, otherwise the string is returned.
+func textValue(typeOID uint32, s string) any {
+	switch typeOID {
+	case oidBool:
+		return s == "t"
+	case oidInt2, oidInt4, oidInt8, oidOID:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case oidFloat4, oidFloat8:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case oidNumeric:
+		return json.Number(s)
+	case oidJSON, oidJSONB:
+		var v any
+		if err := json.Unmarshal([]byte(s), &v); err == nil {
+			return v
+		}
+	}
+	return s
+}
+
+//------------------------------------------------------------------------------
+
+// wal2jsonDecoder decodes version 2 of the wal2json format, where each change
+// is emitted as an individual JSON object.
+type wal2jsonDecoder struct{}
+
+func (wal2jsonDecoder) pluginArgs() []string {
+	return []string{
+		`"format-version" '2'`,
+		`"include-xids" '1'`,
+		`"include-timestamp" '1'`,
+	}
+}
+
+type wal2jsonColumn struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+func wal2jsonRow(cols []wal2jsonColumn) map[string]any {
+	if cols == nil {
+		return nil
+	}
+	row := make(map[string]any, len(cols))
+	for _, c := range cols {
+		row[c.Name] = c.Value
+	}
+	return row
+}
+
+func (wal2jsonDecoder) decode(data []byte) ([]change, error) {
+	var msg struct {
+		Action    string           `json:"action"`
+		XID       uint32           `json:"xid"`
+		Timestamp string           `json:"timestamp"`
+		Schema    string           `json:"schema"`
+		Table     string           `json:"table"`
+		Columns   []wal2jsonColumn `json:"columns"`
+		Identity  []wal2jsonColumn `json:"identity"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&msg); err != nil {
+		return nil, fmt.Errorf("failed to parse wal2json message: %w", err)
+	}
+
+	c := change{schema: msg.Schema, table: msg.Table}
+	switch msg.Action {
+	case "B":
+		c.kind, c.xid = changeBegin, msg.XID
+		if ts, err := time.Parse("2006-01-02 15:04:05.999999-07", msg.Timestamp); err == nil {
+			c.timestamp = ts
+		}
+	case "C":
+		c.kind = changeCommit
+	case "I":
+		c.kind, c.after = changeInsert, wal2jsonRow(msg.Columns)
+	case "U":
+		c.kind, c.before, c.after = changeUpdate, wal2jsonRow(msg.Identity), wal2jsonRow(msg.Columns)
+	case "D":
+		c.kind, c.before = changeDelete, wal2jsonRow(msg.Identity)
+	case "T":
+		c.kind = changeTruncate
+	default:
+		// Logical decoding messages are ignored.
+		return nil, nil
+	}
+	return []change{c}, nil
+}
@@ -0,0 +1,159 @@
+package postgresql
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pgoutputBuilder []byte
+
+func (b pgoutputBuilder) u8(v uint8) pgoutputBuilder {
+	return append(b, v)
+}
+
+func (b pgoutputBuilder) u16(v uint16) pgoutputBuilder {
+	return binary.BigEndian.AppendUint16(b, v)
+}
+
+func (b pgoutputBuilder) u32(v uint32) pgoutputBuilder {
+	return binary.BigEndian.AppendUint32(b, v)
+}
+
+func (b pgoutputBuilder) u64(v uint64) pgoutputBuilder {
+	return binary.BigEndian.AppendUint64(b, v)
+}
+
+func (b pgoutputBuilder) str(s string) pgoutputBuilder {
+	return append(append(b, s...), 0)
+}
+
+func (b pgoutputBuilder) text(s string) pgoutputBuilder {
+	return append(b.u8('t').u32(uint32(len(s))), s...)
+}
+
+func testRelationMessage() []byte {
+	return pgoutputBuilder{'R'}.u32(16384).str("public").str("orders").u8('d').u16(4).
+		u8(1).str("id").u32(oidInt4).u32(0).
+		u8(0).str("status").u32(25).u32(0).
+		u8(0).str("details").u32(oidJSONB).u32(0).
+		u8(0).str("total").u32(oidNumeric).u32(0)
+}
+
+func TestPgoutputDecoder(t *testing.T) {
+	d := newPgoutputDecoder("foo")
+	assert.Equal(t, []string{"proto_version '1'", "publication_names 'foo'"}, d.pluginArgs())
+
+	changes, err := d.decode(pgoutputBuilder{'B'}.u64(100).u64(uint64(time.Hour.Microseconds())).u32(7))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, changeBegin, changes[0].kind)
+	assert.Equal(t, uint32(7), changes[0].xid)
+	assert.Equal(t, time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC), changes[0].timestamp)
+
+	changes, err = d.decode(testRelationMessage())
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = d.decode(pgoutputBuilder{'I'}.u32(16384).u8('N').u16(4).
+		text("1").text("new").text(`{"a":[1,2]}`).text("10.50"))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, map[string]any{
+		"operation": "insert",
+		"schema":    "public",
+		"table":     "orders",
+		"after": map[string]any{
+			"id":      int64(1),
+			"status":  "new",
+			"details": map[string]any{"a": []any{1.0, 2.0}},
+			"total":   json.Number("10.50"),
+		},
+	}, changes[0].structured())
+
+	changes, err = d.decode(pgoutputBuilder{'U'}.u32(16384).
+		u8('K').u16(4).text("1").u8('n').u8('n').u8('n').
+		u8('N').u16(4).text("1").text("shipped").u8('u').u8('n'))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, map[string]any{
+		"operation": "update",
+		"schema":    "public",
+		"table":     "orders",
+		"before":    map[string]any{"id": int64(1), "status": nil, "details": nil, "total": nil},
+		"after":     map[string]any{"id": int64(1), "status": "shipped", "total": nil},
+	}, changes[0].structured())
+
+	changes, err = d.decode(pgoutputBuilder{'D'}.u32(16384).u8('K').u16(4).text("1").u8('n').u8('n').u8('n'))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, changeDelete, changes[0].kind)
+	assert.Nil(t, changes[0].after)
+	assert.Equal(t, int64(1), changes[0].before["id"])
+
+	changes, err = d.decode(pgoutputBuilder{'T'}.u32(1).u8(0).u32(16384))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, map[string]any{
+		"operation": "truncate",
+		"schema":    "public",
+		"table":     "orders",
+	}, changes[0].structured())
+
+	changes, err = d.decode(pgoutputBuilder{'C'}.u8(0).u64(100).u64(120).u64(0))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, changeCommit, changes[0].kind)
+}
+
+func TestPgoutputDecoderErrors(t *testing.T) {
+	d := newPgoutputDecoder("foo")
+
+	_, err := d.decode(pgoutputBuilder{'I'}.u32(1).u8('N').u16(0))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown relation")
+
+	_, err = d.decode(testRelationMessage()[:10])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "truncated")
+
+	_, err = d.decode(testRelationMessage())
+	require.NoError(t, err)
+
+	_, err = d.decode(pgoutputBuilder{'I'}.u32(16384).u8('N').u16(1).u8('t').u32(10))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "truncated")
+}
+
+func TestWal2JSONDecoder(t *testing.T) {
+	d := wal2jsonDecoder{}
+
+	changes, err := d.decode([]byte(`{"action":"B","xid":512,"timestamp":"2024-05-10 12:00:00.123456+00"}`))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, changeBegin, changes[0].kind)
+	assert.Equal(t, uint32(512), changes[0].xid)
+	assert.Equal(t, time.Date(2024, 5, 10, 12, 0, 0, 123456000, time.UTC), changes[0].timestamp.UTC())
+
+	changes, err = d.decode([]byte(`{"action":"U","schema":"public","table":"orders","columns":[{"name":"id","type":"integer","value":1},{"name":"status","type":"text","value":"shipped"}],"identity":[{"name":"id","type":"integer","value":1}]}`))
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, map[string]any{
+		"operation": "update",
+		"schema":    "public",
+		"table":     "orders",
+		"before":    map[string]any{"id": json.Number("1")},
+		"after":     map[string]any{"id": json.Number("1"), "status": "shipped"},
+	}, changes[0].structured())
+
+	changes, err = d.decode([]byte(`{"action":"M","transactional":false,"prefix":"foo","content":"bar"}`))
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	_, err = d.decode([]byte(`nope`))
+	require.Error(t, err)
+}
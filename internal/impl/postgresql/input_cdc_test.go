@@ -0,0 +1,77 @@
+package postgresql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestLSN(t *testing.T) {
+	lsn, err := parseLSN("16/B374D848")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0x16B374D848), lsn)
+	assert.Equal(t, "16/B374D848", formatLSN(lsn))
+
+	_, err = parseLSN("nope")
+	require.Error(t, err)
+}
+
+func TestStreamStateFlushable(t *testing.T) {
+	s := &streamState{}
+
+	// Two transactions where the first has two changes and the second has
+	// one.
+	s.lastEmitted = 20
+	s.pending = append(s.pending, txEnd{lastEmitted: 20, end: 30})
+	s.lastEmitted = 40
+	s.pending = append(s.pending, txEnd{lastEmitted: 40, end: 50})
+	s.serverWALEnd = 60
+
+	assert.Equal(t, uint64(0), s.flushable(10))
+	s.flushed = s.flushable(20)
+	assert.Equal(t, uint64(30), s.flushed)
+
+	// Once all changes are acknowledged the latest location of the server can
+	// be flushed.
+	s.inTx = true
+	s.flushed = s.flushable(40)
+	assert.Equal(t, uint64(50), s.flushed)
+
+	s.inTx = false
+	assert.Equal(t, uint64(60), s.flushable(40))
+}
+
+func TestPostgresCDCConfigErrors(t *testing.T) {
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	for _, test := range []struct {
+		conf        string
+		errContains string
+	}{
+		{conf: "slot: foo", errContains: "a publication must be specified"},
+		{conf: "slot: Foo-Bar\npublication: foo", errContains: "must only contain"},
+		{conf: "slot: foo\nplugin: wal2json\ncheckpoint_cache: bar", errContains: "was not found"},
+	} {
+		pConf, err := postgresCDCInputSpec().ParseYAML("dsn: postgres://foo@localhost:5432/bar\n"+test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newPostgresCDCInputFromParsed(pConf, mRes)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.errContains)
+	}
+
+	pConf, err := postgresCDCInputSpec().ParseYAML(`
+dsn: postgres://foo@localhost:5432/bar
+slot: foo
+plugin: wal2json
+checkpoint_cache: foo
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newPostgresCDCInputFromParsed(pConf, mRes)
+	require.NoError(t, err)
+	assert.Equal(t, "database", i.pgConf.RuntimeParams["replication"])
+}
@@ -0,0 +1,131 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgproto3/v2"
+)
+
+// The replication protocol represents timestamps as microseconds since the
+// PostgreSQL epoch.
+var pgEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func pgTime(micros int64) time.Time {
+	return pgEpoch.Add(time.Duration(micros) * time.Microsecond)
+}
+
+// formatLSN formats a WAL location in the textual form used by PostgreSQL.
+func formatLSN(lsn uint64) string {
+	return fmt.Sprintf("%X/%X", uint32(lsn>>32), uint32(lsn))
+}
+
+// parseLSN parses a WAL location in the textual form used by PostgreSQL.
+func parseLSN(s string) (uint64, error) {
+	var hi, lo uint32
+	if _, err := fmt.Sscanf(s, "%X/%X", &hi, &lo); err != nil {
+		return 0, fmt.Errorf("failed to parse LSN '%v': %w", s, err)
+	}
+	return uint64(hi)<<32 | uint64(lo), nil
+}
+
+// quoteLiteral quotes a string for use as a literal within a replication
+// command.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+//------------------------------------------------------------------------------
+
+// createSlot creates a logical replication slot, returning without error if it
+// already exists.
+func createSlot(ctx context.Context, conn *pgconn.PgConn, slot, plugin string) error {
+	_, err := conn.Exec(ctx, fmt.Sprintf("CREATE_REPLICATION_SLOT %v LOGICAL %v", slot, plugin)).ReadAll()
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "42710" {
+		return nil
+	}
+	return err
+}
+
+// startReplication begins streaming changes from a logical replication slot,
+// after which the connection is in copy both mode.
+func startReplication(ctx context.Context, conn *pgconn.PgConn, slot string, lsn uint64, options []string) error {
+	sql := fmt.Sprintf("START_REPLICATION SLOT %v LOGICAL %v", slot, formatLSN(lsn))
+	if len(options) > 0 {
+		sql += " (" + strings.Join(options, ", ") + ")"
+	}
+	buf, err := (&pgproto3.Query{String: sql}).Encode(nil)
+	if err != nil {
+		return err
+	}
+	if err := conn.SendBytes(ctx, buf); err != nil {
+		return err
+	}
+	for {
+		msg, err := conn.ReceiveMessage(ctx)
+		if err != nil {
+			return err
+		}
+		switch t := msg.(type) {
+		case *pgproto3.CopyBothResponse:
+			return nil
+		case *pgproto3.ErrorResponse:
+			return pgconn.ErrorResponseToPgError(t)
+		case *pgproto3.NoticeResponse:
+		default:
+			return fmt.Errorf("unexpected message while starting replication: %T", msg)
+		}
+	}
+}
+
+// sendStandbyStatus reports the location up to which changes have been
+// flushed, allowing the server to discard WAL prior to it.
+func sendStandbyStatus(ctx context.Context, conn *pgconn.PgConn, flushed uint64) error {
+	data := make([]byte, 34)
+	data[0] = 'r'
+	binary.BigEndian.PutUint64(data[1:], flushed)
+	binary.BigEndian.PutUint64(data[9:], flushed)
+	binary.BigEndian.PutUint64(data[17:], flushed)
+	binary.BigEndian.PutUint64(data[25:], uint64(time.Since(pgEpoch).Microseconds()))
+	buf, err := (&pgproto3.CopyData{Data: data}).Encode(nil)
+	if err != nil {
+		return err
+	}
+	return conn.SendBytes(ctx, buf)
+}
+
+type primaryKeepalive struct {
+	serverWALEnd   uint64
+	replyRequested bool
+}
+
+func parsePrimaryKeepalive(data []byte) (primaryKeepalive, error) {
+	if len(data) != 17 {
+		return primaryKeepalive{}, fmt.Errorf("keepalive message has unexpected length: %v", len(data))
+	}
+	return primaryKeepalive{
+		serverWALEnd:   binary.BigEndian.Uint64(data),
+		replyRequested: data[16] != 0,
+	}, nil
+}
+
+type xLogData struct {
+	walStart uint64
+	data     []byte
+}
+
+func parseXLogData(data []byte) (xLogData, error) {
+	if len(data) < 24 {
+		return xLogData{}, fmt.Errorf("xlog data message has unexpected length: %v", len(data))
+	}
+	return xLogData{
+		walStart: binary.BigEndian.Uint64(data),
+		data:     data[24:],
+	}, nil
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 
 	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
 	"google.golang.org/protobuf/types/dynamicpb"
 )
 
@@ -44,3 +48,98 @@ func RegistriesFromMap(filesMap map[string]string) (*protoregistry.Files, *proto
 	}
 	return files, types, nil
 }
+
+// RegistriesFromDescriptorSets attempts to parse a list of serialized
+// FileDescriptorSets, such as those produced by `protoc --descriptor_set_out`,
+// into a registry of protobuf files and protobuf types. When a file is present
+// within multiple sets the first occurrence is used, and dependencies that are
+// missing from the sets, such as the well known types, are resolved from the
+// definitions linked into the binary.
+func RegistriesFromDescriptorSets(sets ...[]byte) (*protoregistry.Files, *protoregistry.Types, error) {
+	var names []string
+	pending := map[string]*descriptorpb.FileDescriptorProto{}
+	for _, b := range sets {
+		var set descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(b, &set); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse descriptor set: %w", err)
+		}
+		for _, fdp := range set.GetFile() {
+			if _, exists := pending[fdp.GetName()]; !exists {
+				pending[fdp.GetName()] = fdp
+				names = append(names, fdp.GetName())
+			}
+		}
+	}
+
+	files := &protoregistry.Files{}
+	var register func(name string) error
+	register = func(name string) error {
+		if _, err := files.FindFileByPath(name); err == nil {
+			return nil
+		}
+		fdp, exists := pending[name]
+		if !exists {
+			fd, err := protoregistry.GlobalFiles.FindFileByPath(name)
+			if err != nil {
+				return fmt.Errorf("dependency '%v' was not found", name)
+			}
+			return files.RegisterFile(fd)
+		}
+		delete(pending, name)
+		for _, dep := range fdp.GetDependency() {
+			if err := register(dep); err != nil {
+				return err
+			}
+		}
+		fd, err := protodesc.NewFile(fdp, files)
+		if err != nil {
+			return fmt.Errorf("failed to parse file '%v': %w", name, err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			return fmt.Errorf("failed to register file '%v': %w", name, err)
+		}
+		return nil
+	}
+	for _, name := range names {
+		if err := register(name); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	types := &protoregistry.Types{}
+	var rangeErr error
+	files.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		rangeErr = registerTypes(types, fd.Messages(), fd.Enums(), fd.Extensions())
+		return rangeErr == nil
+	})
+	if rangeErr != nil {
+		return nil, nil, rangeErr
+	}
+	return files, types, nil
+}
+
+func registerTypes(types *protoregistry.Types, msgs protoreflect.MessageDescriptors, enums protoreflect.EnumDescriptors, exts protoreflect.ExtensionDescriptors) error {
+	for i := 0; i < enums.Len(); i++ {
+		if err := types.RegisterEnum(dynamicpb.NewEnumType(enums.Get(i))); err != nil {
+			return fmt.Errorf("failed to register type '%v': %w", enums.Get(i).FullName(), err)
+		}
+	}
+	for i := 0; i < exts.Len(); i++ {
+		if err := types.RegisterExtension(dynamicpb.NewExtensionType(exts.Get(i))); err != nil {
+			return fmt.Errorf("failed to register type '%v': %w", exts.Get(i).FullName(), err)
+		}
+	}
+	for i := 0; i < msgs.Len(); i++ {
+		md := msgs.Get(i)
+		if md.IsMapEntry() {
+			continue
+		}
+		if err := types.RegisterMessage(dynamicpb.NewMessageType(md)); err != nil {
+			return fmt.Errorf("failed to register type '%v': %w", md.FullName(), err)
+		}
+		if err := registerTypes(types, md.Messages(), md.Enums(), md.Extensions()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -158,7 +158,7 @@ func newProtobufToJSONOperator(f ifs.FS, msg string, importPaths []string, usePr
 		return nil, errors.New("message field must not be empty")
 	}
 
-	descriptors, types, err := loadDescriptors(f, importPaths)
+	descriptors, types, err := LoadDescriptors(f, importPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -203,7 +203,7 @@ func newProtobufFromJSONOperator(f ifs.FS, msg string, importPaths []string, dis
 		return nil, errors.New("message field must not be empty")
 	}
 
-	_, types, err := loadDescriptors(f, importPaths)
+	_, types, err := LoadDescriptors(f, importPaths)
 	if err != nil {
 		return nil, err
 	}
@@ -253,7 +253,9 @@ func strToProtobufOperator(f ifs.FS, opStr, message string, importPaths []string
 	return nil, fmt.Errorf("operator not recognised: %v", opStr)
 }
 
-func loadDescriptors(f ifs.FS, importPaths []string) (*protoregistry.Files, *protoregistry.Types, error) {
+// LoadDescriptors parses all .proto files found within a list of import paths
+// into registries of protobuf files and types.
+func LoadDescriptors(f ifs.FS, importPaths []string) (*protoregistry.Files, *protoregistry.Types, error) {
 	files := map[string]string{}
 	for _, importPath := range importPaths {
 		if err := fs.WalkDir(f, importPath, func(path string, info fs.DirEntry, ferr error) error {
@@ -0,0 +1,358 @@
+package protobuf
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	paFieldOperator       = "operator"
+	paFieldMessage        = "message"
+	paFieldDescriptorSets = "descriptor_sets"
+	paFieldReloadInterval = "reload_interval"
+	paFieldDiscardUnknown = "discard_unknown"
+	paFieldUseProtoNames  = "use_proto_names"
+)
+
+func protobufAnyProcessorSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Parsing").
+		Version("4.28.0").
+		Summary("Performs conversions to or from protobuf messages using descriptor sets that are reloaded when they change, where the message type can be selected for each message.").
+		Description(`
+Schemas are loaded from serialized `+"`FileDescriptorSet`"+`s, which can be produced with `+"`protoc --include_imports --descriptor_set_out=schemas.binpb`"+` or `+"`buf build -o schemas.binpb`"+`. Each entry of `+"`descriptor_sets`"+` is either the path of a file or an HTTP(S) URL, such as the endpoint of a schema registry, from which a descriptor set is fetched. Dependencies that are missing from the descriptor sets, such as the [well known types](https://protobuf.dev/reference/protobuf/google.protobuf/), are resolved from the definitions built into Benthos.
+
+Descriptor sets are fetched again every `+"`reload_interval`"+` and, when their contents have changed, new schemas are used for all subsequent messages without restarting the pipeline. When a reload fails an error is logged and the previous schemas continue to be used.
+
+Fields of the type `+"`google.protobuf.Any`"+` are resolved from the types within the descriptor sets, and are represented in JSON with an `+"`@type`"+` field as described in the [JSON mapping of protobuf messages](https://protobuf.dev/programming-guides/proto3/#json).
+
+## Operators
+
+### `+"`to_json`"+`
+
+Converts protobuf messages into a generic JSON structure. This makes it easier to manipulate the contents of the document within Benthos.
+
+### `+"`from_json`"+`
+
+Attempts to create a target protobuf message from a generic JSON structure.`).
+		Example(
+			"Decoding by Message Type",
+			"Here we consume protobuf messages from Kafka where the type of each message is specified by a header, and decode them using descriptor sets published to an HTTP endpoint by our build pipeline.",
+			`
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: benthos
+
+pipeline:
+  processors:
+    - protobuf_any:
+        operator: to_json
+        message: ${! @message_type }
+        descriptor_sets: [ https://schemas.example.com/events.binpb ]
+        reload_interval: 5m
+`,
+		).
+		Fields(
+			service.NewStringEnumField(paFieldOperator, "to_json", "from_json").
+				Description("The [operator](#operators) to execute."),
+			service.NewInterpolatedStringField(paFieldMessage).
+				Description("The fully qualified name of the protobuf message to convert to/from, which is resolved for each message.").
+				Examples("testing.Person", `${! @message_type }`),
+			service.NewStringListField(paFieldDescriptorSets).
+				Description("A list of paths or HTTP(S) URLs of serialized `FileDescriptorSet`s containing all definitions required for converting messages.").
+				Example([]string{"./schemas.binpb"}).
+				Example([]string{"https://schemas.example.com/events.binpb"}),
+			service.NewDurationField(paFieldReloadInterval).
+				Description("The period between attempts to reload the descriptor sets, set to `0s` in order to disable reloading.").
+				Default("1m"),
+			service.NewBoolField(paFieldDiscardUnknown).
+				Description("If `true`, the `from_json` operator discards fields that are unknown to the schema.").
+				Default(false),
+			service.NewBoolField(paFieldUseProtoNames).
+				Description("If `true`, the `to_json` operator deserializes fields exactly as named in schema file.").
+				Default(false),
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor("protobuf_any", protobufAnyProcessorSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newProtobufAnyFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// anyResolver resolves types from a registry loaded from descriptor sets, and
+// falls back to the types linked into the binary.
+type anyResolver struct {
+	types *protoregistry.Types
+}
+
+func (r *anyResolver) FindMessageByName(name protoreflect.FullName) (protoreflect.MessageType, error) {
+	if mt, err := r.types.FindMessageByName(name); err == nil {
+		return mt, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByName(name)
+}
+
+func (r *anyResolver) FindMessageByURL(url string) (protoreflect.MessageType, error) {
+	if mt, err := r.types.FindMessageByURL(url); err == nil {
+		return mt, nil
+	}
+	return protoregistry.GlobalTypes.FindMessageByURL(url)
+}
+
+func (r *anyResolver) FindExtensionByName(field protoreflect.FullName) (protoreflect.ExtensionType, error) {
+	if et, err := r.types.FindExtensionByName(field); err == nil {
+		return et, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByName(field)
+}
+
+func (r *anyResolver) FindExtensionByNumber(message protoreflect.FullName, field protoreflect.FieldNumber) (protoreflect.ExtensionType, error) {
+	if et, err := r.types.FindExtensionByNumber(message, field); err == nil {
+		return et, nil
+	}
+	return protoregistry.GlobalTypes.FindExtensionByNumber(message, field)
+}
+
+// anySchemas is a snapshot of the schemas loaded from descriptor sets.
+type anySchemas struct {
+	resolver *anyResolver
+	hash     [sha256.Size]byte
+}
+
+type protobufAnyProc struct {
+	operator       string
+	message        *service.InterpolatedString
+	descriptorSets []string
+	discardUnknown bool
+	useProtoNames  bool
+
+	fs     *service.FS
+	client *http.Client
+	log    *service.Logger
+
+	schemas atomic.Pointer[anySchemas]
+
+	reloadCtx    context.Context
+	reloadCancel context.CancelFunc
+	reloadWG     sync.WaitGroup
+}
+
+func newProtobufAnyFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*protobufAnyProc, error) {
+	p := &protobufAnyProc{
+		fs:     mgr.FS(),
+		client: &http.Client{Timeout: time.Second * 30},
+		log:    mgr.Logger(),
+	}
+
+	var err error
+	if p.operator, err = conf.FieldString(paFieldOperator); err != nil {
+		return nil, err
+	}
+	if p.message, err = conf.FieldInterpolatedString(paFieldMessage); err != nil {
+		return nil, err
+	}
+	if p.descriptorSets, err = conf.FieldStringList(paFieldDescriptorSets); err != nil {
+		return nil, err
+	}
+	if len(p.descriptorSets) == 0 {
+		return nil, errors.New("at least one descriptor set must be specified")
+	}
+	if p.discardUnknown, err = conf.FieldBool(paFieldDiscardUnknown); err != nil {
+		return nil, err
+	}
+	if p.useProtoNames, err = conf.FieldBool(paFieldUseProtoNames); err != nil {
+		return nil, err
+	}
+
+	reloadInterval, err := conf.FieldDuration(paFieldReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.reload(context.Background()); err != nil {
+		return nil, err
+	}
+
+	p.reloadCtx, p.reloadCancel = context.WithCancel(context.Background())
+	if reloadInterval > 0 {
+		p.reloadWG.Add(1)
+		go p.reloadLoop(reloadInterval)
+	}
+	return p, nil
+}
+
+func (p *protobufAnyProc) fetch(ctx context.Context, location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return ifs.ReadFile(p.fs, location)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code: %v", res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// reload fetches the descriptor sets and, when their contents have changed,
+// replaces the current schemas. Returns true when the schemas were replaced.
+func (p *protobufAnyProc) reload(ctx context.Context) (bool, error) {
+	sets := make([][]byte, 0, len(p.descriptorSets))
+	h := sha256.New()
+	for _, location := range p.descriptorSets {
+		b, err := p.fetch(ctx, location)
+		if err != nil {
+			return false, fmt.Errorf("failed to fetch descriptor set '%v': %w", location, err)
+		}
+		sets = append(sets, b)
+		_, _ = h.Write(b)
+		_, _ = h.Write([]byte{0})
+	}
+
+	var hash [sha256.Size]byte
+	copy(hash[:], h.Sum(nil))
+	if current := p.schemas.Load(); current != nil && current.hash == hash {
+		return false, nil
+	}
+
+	_, types, err := RegistriesFromDescriptorSets(sets...)
+	if err != nil {
+		return false, err
+	}
+	p.schemas.Store(&anySchemas{
+		resolver: &anyResolver{types: types},
+		hash:     hash,
+	})
+	return true, nil
+}
+
+func (p *protobufAnyProc) reloadLoop(interval time.Duration) {
+	defer p.reloadWG.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.reloadCtx.Done():
+			return
+		}
+		changed, err := p.reload(p.reloadCtx)
+		if err != nil {
+			if p.reloadCtx.Err() != nil {
+				return
+			}
+			p.log.Errorf("Failed to reload descriptor sets, continuing with previous schemas: %v", err)
+			continue
+		}
+		if changed {
+			p.log.Infof("Reloaded protobuf schemas from descriptor sets")
+		}
+	}
+}
+
+func (p *protobufAnyProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	name, err := p.message.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("message interpolation error: %w", err)
+	}
+	if name == "" {
+		return nil, errors.New("message interpolation resulted in an empty string")
+	}
+
+	resolver := p.schemas.Load().resolver
+	mt, err := resolver.FindMessageByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find message '%v' definition within descriptor sets", name)
+	}
+
+	msgBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	dynMsg := dynamicpb.NewMessage(mt.Descriptor())
+
+	var data []byte
+	switch p.operator {
+	case "to_json":
+		if err := (proto.UnmarshalOptions{Resolver: resolver}).Unmarshal(msgBytes, dynMsg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal protobuf message '%v': %w", name, err)
+		}
+		opts := protojson.MarshalOptions{
+			Resolver:      resolver,
+			UseProtoNames: p.useProtoNames,
+		}
+		if data, err = opts.Marshal(dynMsg); err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON protobuf message '%v': %w", name, err)
+		}
+	case "from_json":
+		opts := protojson.UnmarshalOptions{
+			Resolver:       resolver,
+			DiscardUnknown: p.discardUnknown,
+		}
+		if err := opts.Unmarshal(msgBytes, dynMsg); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON message '%v': %w", name, err)
+		}
+		if data, err = proto.Marshal(dynMsg); err != nil {
+			return nil, fmt.Errorf("failed to marshal protobuf message '%v': %w", name, err)
+		}
+	default:
+		return nil, fmt.Errorf("operator not recognised: %v", p.operator)
+	}
+
+	msg.SetBytes(data)
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *protobufAnyProc) Close(ctx context.Context) error {
+	p.reloadCancel()
+
+	waitChan := make(chan struct{})
+	go func() {
+		p.reloadWG.Wait()
+		close(waitChan)
+	}()
+	select {
+	case <-waitChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package protobuf
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testDescriptorSet(t testing.TB, files map[string]string) []byte {
+	t.Helper()
+
+	reg, _, err := RegistriesFromMap(files)
+	require.NoError(t, err)
+
+	var set descriptorpb.FileDescriptorSet
+	reg.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+		return true
+	})
+
+	b, err := proto.Marshal(&set)
+	require.NoError(t, err)
+	return b
+}
+
+func testSchemaFiles(t testing.TB) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+	for _, name := range []string{"envelope.proto", "house.proto", "person.proto"} {
+		b, err := os.ReadFile(filepath.Join("../../../config/test/protobuf/schema", name))
+		require.NoError(t, err)
+		files[name] = string(b)
+	}
+	return files
+}
+
+func testProtobufAnyProc(t testing.TB, confStr string) *protobufAnyProc {
+	t.Helper()
+
+	conf, err := protobufAnyProcessorSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newProtobufAnyFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func TestProtobufAnyRoundTrip(t *testing.T) {
+	setPath := filepath.Join(t.TempDir(), "schemas.binpb")
+	require.NoError(t, os.WriteFile(setPath, testDescriptorSet(t, testSchemaFiles(t)), 0o644))
+
+	fromJSON := testProtobufAnyProc(t, fmt.Sprintf(`
+operator: from_json
+message: ${! @type }
+descriptor_sets: [ %v ]
+`, setPath))
+	toJSON := testProtobufAnyProc(t, fmt.Sprintf(`
+operator: to_json
+message: ${! @type }
+descriptor_sets: [ %v ]
+`, setPath))
+
+	for _, test := range []struct {
+		typeName string
+		input    string
+	}{
+		{
+			typeName: "testing.Person",
+			input:    `{"firstName":"caleb","lastName":"quaye","lastUpdated":"2024-01-01T00:00:00Z"}`,
+		},
+		{
+			typeName: "testing.Envelope",
+			input:    `{"id":747,"content":{"@type":"type.googleapis.com/testing.Person","firstName":"bob"}}`,
+		},
+		{
+			typeName: "testing.Envelope",
+			input:    `{"id":747,"content":{"@type":"type.googleapis.com/testing.House","address":"123","mailbox":{"color":"red"}}}`,
+		},
+		{
+			typeName: "testing.Envelope",
+			input:    `{"id":747,"content":{"@type":"type.googleapis.com/google.protobuf.Timestamp","value":"2024-01-01T00:00:00Z"}}`,
+		},
+		{
+			typeName: "testing.House.Mailbox",
+			input:    `{"color":"red","identifier":"123"}`,
+		},
+	} {
+		msg := service.NewMessage([]byte(test.input))
+		msg.MetaSetMut("type", test.typeName)
+
+		res, err := fromJSON.Process(context.Background(), msg)
+		require.NoError(t, err, test.input)
+		require.Len(t, res, 1)
+
+		res, err = toJSON.Process(context.Background(), res[0])
+		require.NoError(t, err, test.input)
+		require.Len(t, res, 1)
+
+		mBytes, err := res[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, test.input, string(mBytes), test.input)
+	}
+
+	msg := service.NewMessage([]byte(`{}`))
+	msg.MetaSetMut("type", "testing.Nope")
+	_, err := fromJSON.Process(context.Background(), msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to find message 'testing.Nope'")
+}
+
+func TestProtobufAnyReload(t *testing.T) {
+	files := map[string]string{
+		"event.proto": `
+syntax = "proto3";
+package testing;
+
+message Event {
+  string id = 1;
+}
+`,
+	}
+	set := testDescriptorSet(t, files)
+
+	setMut := make(chan []byte, 1)
+	setMut <- set
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := <-setMut
+		setMut <- b
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(server.Close)
+
+	proc := testProtobufAnyProc(t, fmt.Sprintf(`
+operator: from_json
+message: testing.Event
+descriptor_sets: [ %v ]
+reload_interval: 0s
+`, server.URL))
+
+	process := func(input string) error {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		return err
+	}
+
+	require.NoError(t, process(`{"id":"foo"}`))
+	require.Error(t, process(`{"id":"foo","source":"bar"}`))
+
+	changed, err := proc.reload(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	files["event.proto"] = `
+syntax = "proto3";
+package testing;
+
+message Event {
+  string id = 1;
+  string source = 2;
+}
+`
+	<-setMut
+	setMut <- testDescriptorSet(t, files)
+
+	changed, err = proc.reload(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	require.NoError(t, process(`{"id":"foo","source":"bar"}`))
+
+	// A broken descriptor set keeps the previous schemas.
+	<-setMut
+	setMut <- []byte("not a descriptor set")
+
+	_, err = proc.reload(context.Background())
+	require.Error(t, err)
+	require.NoError(t, process(`{"id":"foo","source":"bar"}`))
+}
+
+func TestRegistriesFromDescriptorSets(t *testing.T) {
+	_, types, err := RegistriesFromDescriptorSets(testDescriptorSet(t, testSchemaFiles(t)))
+	require.NoError(t, err)
+
+	for _, name := range []string{"testing.Person", "testing.House", "testing.House.Mailbox", "testing.Envelope"} {
+		_, err := types.FindMessageByName(protoreflect.FullName(name))
+		assert.NoError(t, err, name)
+	}
+
+	_, _, err = RegistriesFromDescriptorSets([]byte("nope"))
+	require.Error(t, err)
+
+	// Missing dependencies that are not well known types fail.
+	reg, _, err := LoadDescriptors(ifs.OS(), []string{"../../../config/test/protobuf/schema"})
+	require.NoError(t, err)
+	fd, err := reg.FindFileByPath("house.proto")
+	require.NoError(t, err)
+
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(fd)},
+	})
+	require.NoError(t, err)
+
+	_, _, err = RegistriesFromDescriptorSets(b)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "person.proto")
+}
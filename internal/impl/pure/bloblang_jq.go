@@ -0,0 +1,154 @@
+package pure
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/itchyny/gojq"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func init() {
+	if err := bloblang.RegisterMethodV2("jq",
+		bloblang.NewPluginSpec().
+			Category(query.MethodCategoryObjectAndArray).
+			Description(`Executes a [jq](https://stedolan.github.io/jq/manual/) query against the target value and returns the result. When the query emits a single value that value is returned, when it emits multiple values they are returned as an array, and when it emits no values `+"`null`"+` is returned. Setting `+"`all`"+` to `+"`true`"+` always returns an array of the emitted values.
+
+This method uses the [gojq library](https://github.com/itchyny/gojq), which has [some differences](https://github.com/itchyny/gojq#difference-to-jq) to the jq cli. Queries are unable to read files, environment variables or further inputs.`).
+			Param(bloblang.NewStringParam("query").Description("The jq query to execute.")).
+			Param(bloblang.NewBoolParam("all").Description("Whether to always return an array of all emitted values.").Default(false)).
+			Example("", `root.adults = this.jq("[.users[] | select(.age >= 18) | .name]")`,
+				[2]string{
+					`{"users":[{"name":"ash","age":32},{"name":"bo","age":12},{"name":"cy","age":21}]}`,
+					`{"adults":["ash","cy"]}`,
+				},
+			).
+			Example("Queries that emit multiple values can be collected into an array with the `all` parameter.", `root.names = this.jq(query: ".users[].name", all: true)`,
+				[2]string{
+					`{"users":[{"name":"ash"}]}`,
+					`{"names":["ash"]}`,
+				},
+				[2]string{
+					`{"users":[]}`,
+					`{"names":[]}`,
+				},
+			),
+		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			queryStr, err := args.GetString("query")
+			if err != nil {
+				return nil, err
+			}
+			all, err := args.GetBool("all")
+			if err != nil {
+				return nil, err
+			}
+
+			parsed, err := gojq.Parse(queryStr)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing jq query: %w", err)
+			}
+			code, err := gojq.Compile(parsed)
+			if err != nil {
+				return nil, fmt.Errorf("error compiling jq query: %w", err)
+			}
+
+			return func(v any) (any, error) {
+				emitted, err := safeQuery(toJQValue(v), code)
+				if err != nil {
+					return nil, err
+				}
+				for i, e := range emitted {
+					emitted[i] = fromJQValue(e)
+				}
+				if all {
+					if emitted == nil {
+						emitted = []any{}
+					}
+					return emitted, nil
+				}
+				switch len(emitted) {
+				case 0:
+					return nil, nil
+				case 1:
+					return emitted[0], nil
+				}
+				return emitted, nil
+			}, nil
+		}); err != nil {
+		panic(err)
+	}
+}
+
+// toJQValue converts a Bloblang value into the types supported by gojq, which
+// are restricted to those produced when decoding JSON along with int and
+// *big.Int numbers.
+func toJQValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = toJQValue(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = toJQValue(e)
+		}
+		return s
+	}
+
+	switch t := value.ISanitize(v).(type) {
+	case int64:
+		if t >= math.MinInt && t <= math.MaxInt {
+			return int(t)
+		}
+		return new(big.Int).SetInt64(t)
+	case uint64:
+		if t <= math.MaxInt {
+			return int(t)
+		}
+		return new(big.Int).SetUint64(t)
+	case []byte:
+		return string(t)
+	case value.Delete, value.Nothing:
+		return nil
+	default:
+		return t
+	}
+}
+
+// fromJQValue converts a value emitted by gojq into a Bloblang value. Emitted
+// values are copied as they may be shared with constants of the query.
+func fromJQValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			m[k] = fromJQValue(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			s[i] = fromJQValue(e)
+		}
+		return s
+	case int:
+		return int64(t)
+	case *big.Int:
+		if t.IsInt64() {
+			return t.Int64()
+		}
+		if t.IsUint64() {
+			return t.Uint64()
+		}
+		f, _ := new(big.Float).SetInt(t).Float64()
+		return f
+	}
+	return v
+}
@@ -0,0 +1,108 @@
+package pure
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+)
+
+func TestJQMethod(t *testing.T) {
+	testCases := []struct {
+		name     string
+		mapping  string
+		input    any
+		output   any
+		parseErr string
+		execErr  string
+	}{
+		{
+			name:    "single value",
+			mapping: `root = this.jq(".a.b")`,
+			input:   map[string]any{"a": map[string]any{"b": "c"}},
+			output:  "c",
+		},
+		{
+			name:    "multiple values",
+			mapping: `root = this.jq(".[] | . * 2")`,
+			input:   []any{int64(1), 2.5, json.Number("3")},
+			output:  []any{int64(2), 5.0, int64(6)},
+		},
+		{
+			name:    "no values",
+			mapping: `root = this.jq("empty")`,
+			input:   map[string]any{},
+			output:  nil,
+		},
+		{
+			name:    "all values",
+			mapping: `root = this.jq(query: ".a", all: true)`,
+			input:   map[string]any{"a": []any{"b"}},
+			output:  []any{[]any{"b"}},
+		},
+		{
+			name:    "no values all",
+			mapping: `root = this.jq(query: "empty", all: true)`,
+			input:   map[string]any{},
+			output:  []any{},
+		},
+		{
+			name:    "large integers",
+			mapping: `root = this.jq("[.a, .b + 1]")`,
+			input:   map[string]any{"a": uint64(18446744073709551615), "b": int64(9007199254740993)},
+			output:  []any{uint64(18446744073709551615), int64(9007199254740994)},
+		},
+		{
+			name:    "bytes and timestamps",
+			mapping: `root = this.jq("[.a, .b]")`,
+			input: map[string]any{
+				"a": []byte("foo"),
+				"b": time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+			output: []any{"foo", "2023-01-02T03:04:05Z"},
+		},
+		{
+			name:    "constant objects",
+			mapping: `root = [ this.jq("{\"a\":1}"), this.jq("{\"a\":1}") ]`,
+			input:   nil,
+			output:  []any{map[string]any{"a": int64(1)}, map[string]any{"a": int64(1)}},
+		},
+		{
+			name:     "bad query",
+			mapping:  `root = this.jq(".a | ")`,
+			parseErr: "error parsing jq query",
+		},
+		{
+			name:    "query error",
+			mapping: `root = this.jq(".a | error(\"nope\")")`,
+			input:   map[string]any{},
+			execErr: "nope",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			exec, err := bloblang.Parse(test.mapping)
+			if test.parseErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.parseErr)
+				return
+			}
+			require.NoError(t, err)
+
+			res, err := exec.Query(test.input)
+			if test.execErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, test.output, res)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.execErr)
+			}
+		})
+	}
+}
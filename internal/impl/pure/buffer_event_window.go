@@ -0,0 +1,639 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ewbFieldTimestampMapping = "timestamp_mapping"
+	ewbFieldSize             = "size"
+	ewbFieldSlide            = "slide"
+	ewbFieldOffset           = "offset"
+	ewbFieldSession          = "session"
+	ewbFieldSessionGap       = "gap"
+	ewbFieldSessionKey       = "key"
+	ewbFieldAllowedLateness  = "allowed_lateness"
+	ewbFieldIdleTimeout      = "idle_timeout"
+	ewbFieldLateData         = "late_data"
+)
+
+const (
+	ewbLateDataDrop = "drop"
+	ewbLateDataEmit = "emit"
+)
+
+func eventWindowBufferConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Windowing").
+		Summary("Chops a stream of messages into tumbling, sliding or session windows following the event time of messages.").
+		Description(`
+Unlike the `+"[`system_window`](/docs/components/buffers/system_window)"+` buffer, windows are closed according to a watermark that is derived from the event timestamps of the messages themselves, as provided by the `+"[`timestamp_mapping` field](#timestamp_mapping)"+`, rather than the system clock. This means historical data can be replayed through the buffer and produce the same windows as it did when consumed live.
+
+The watermark is the latest event timestamp observed, minus the `+"[`allowed_lateness`](#allowed_lateness)"+`, and a window is flushed once the watermark has reached its end. Since the watermark only advances as messages arrive the final windows of a stream that has gone quiet are not flushed until more data arrives, unless an `+"[`idle_timeout`](#idle_timeout)"+` is specified, in which case the watermark begins advancing with the system clock once no messages have been written for that duration.
+
+When a window is flushed its messages have the metadata fields `+"`window_start_timestamp`"+` and `+"`window_end_timestamp`"+` added to them, containing the start and end of the window as RFC3339 strings.
+
+## Tumbling and Sliding Windows
+
+Specifying a `+"[`size`](#size)"+` produces tumbling windows, where the beginning of a window immediately follows the end of the prior window. Windows are aligned to the zeroth minute and zeroth hour of the UTC clock, which can be adjusted with the `+"[`offset`](#offset)"+`. Specifying a `+"[`slide`](#slide)"+` as well produces sliding windows, which begin from an offset of the prior windows' beginning rather than its end, and therefore messages may belong to multiple windows.
+
+## Session Windows
+
+Specifying a `+"[`session`](#session)"+` instead of a size produces session windows, which are periods of activity separated by a gap of inactivity. A session begins with the first message of a key and is extended by each message of the same key that arrives within the gap of its other messages, and is flushed once the watermark passes the timestamp of its latest message plus the gap. When two sessions of the same key become bridged by a message they are merged. Messages of session windows also have the metadata field `+"`window_key`"+` added to them, containing the key of the session.
+
+## Late Data
+
+A message is late when every window it would belong to has already been flushed. The `+"[`late_data`](#late_data)"+` field determines whether late messages are dropped, or emitted immediately in batches of their own with the metadata field `+"`window_late`"+` set to `+"`true`"+`.
+
+## Delivery Guarantees
+
+This buffer honours the transaction model within Benthos in order to ensure that messages are not acknowledged until they are either intentionally dropped or successfully delivered to outputs. However, late messages are intentionally dropped by default and therefore there are circumstances where not all messages entering the system will be delivered.
+
+Windows are held in memory until they are flushed, and therefore you should ensure that there is enough system memory to store all windows open at a given time, which is determined by the window size or session gap, the allowed lateness and the range of event timestamps being consumed concurrently.
+
+When this buffer is configured with a slide duration it is possible for messages to belong to multiple windows, and therefore be delivered multiple times. In this case the first time the message is delivered it will be acked (or nacked) and subsequent deliveries of the same message will be a "best attempt".
+
+During graceful termination messages of windows that have not been flushed are nacked such that they are re-consumed the next time the service starts.
+`).
+		Fields(
+			service.NewBloblangField(ewbFieldTimestampMapping).
+				Description(`
+A [Bloblang mapping](/docs/guides/bloblang/about) applied to each message during ingestion that provides the event timestamp used for allocating it a window and for advancing the watermark.
+
+The timestamp value assigned to `+"`root`"+` must either be a numerical unix time in seconds (with up to nanosecond precision via decimals), or a string in ISO 8601 format. If the mapping fails or provides an invalid result the message will be dropped (with logging to describe the problem).
+`).
+				Example("root = this.created_at").Example(`root = meta("kafka_timestamp_unix").number()`),
+			service.NewDurationField(ewbFieldSize).
+				Description("A duration string describing the size of tumbling or sliding windows. Exactly one of `size` or `session` must be specified.").
+				Example("30s").Example("10m").
+				Optional(),
+			service.NewDurationField(ewbFieldSlide).
+				Description("An optional duration string describing by how much time the beginning of each window should be offset from the beginning of the previous, and therefore creates sliding windows instead of tumbling. When specified this duration must be smaller than the `size` of the window.").
+				Example("30s").Example("10m").
+				Optional(),
+			service.NewDurationField(ewbFieldOffset).
+				Description("An optional duration string to offset the beginning of each window by, otherwise they are aligned to the zeroth minute and zeroth hour on the UTC clock. The offset cannot be a larger or equal measure to the window size or the slide.").
+				Example("-6h").Example("30m").
+				Optional(),
+			service.NewObjectField(ewbFieldSession,
+				service.NewDurationField(ewbFieldSessionGap).
+					Description("A duration string describing the period of inactivity after which a session is closed.").
+					Example("30s").Example("10m"),
+				service.NewBloblangField(ewbFieldSessionKey).
+					Description("A [Bloblang mapping](/docs/guides/bloblang/about) that provides the key of each message, where sessions are tracked separately for each key.").
+					Example("root = this.user_id").
+					Default(`root = ""`),
+			).
+				Description("Produces session windows instead of tumbling or sliding windows. Exactly one of `size` or `session` must be specified.").
+				Optional(),
+			service.NewDurationField(ewbFieldAllowedLateness).
+				Description("An optional duration string describing how far the watermark trails behind the latest event timestamp observed, allowing messages that arrive out of order to be included in their windows.").
+				Example("10s").Example("1m").
+				Optional(),
+			service.NewDurationField(ewbFieldIdleTimeout).
+				Description("An optional duration string describing how long to wait without any messages being written before the watermark begins advancing with the system clock, allowing windows to be flushed when the stream goes quiet.").
+				Example("30s").Example("5m").
+				Optional().
+				Advanced(),
+			service.NewStringAnnotatedEnumField(ewbFieldLateData, map[string]string{
+				ewbLateDataDrop: "Late messages are dropped and acknowledged.",
+				ewbLateDataEmit: "Late messages are emitted immediately in batches of their own, with the metadata field `window_late` set to `true`.",
+			}).
+				Description("Determines what happens to messages that arrive after all of the windows they belong to have been flushed.").
+				Default(ewbLateDataDrop),
+		).
+		Example("User Sessions", `Given a stream of page view events of the form:
+
+`+"```json"+`
+{
+  "user_id": "f6b8a0c4",
+  "page": "/checkout",
+  "viewed_at": "2021-08-07T09:49:35Z"
+}
+`+"```"+`
+
+We can use session windows in order to summarise each visit of a user, where a visit ends after ten minutes of inactivity, into messages of the form:
+
+`+"```json"+`
+{
+  "user_id": "f6b8a0c4",
+  "started_at": "2021-08-07T09:41:02Z",
+  "ended_at": "2021-08-07T09:59:35Z",
+  "pages": [ "/", "/products", "/checkout" ]
+}
+`+"```"+`
+
+With the following config:`,
+			`
+buffer:
+  event_window:
+    timestamp_mapping: root = this.viewed_at
+    allowed_lateness: 1m
+    session:
+      gap: 10m
+      key: root = this.user_id
+
+pipeline:
+  processors:
+    # Reduce each batch to a single message by deleting indexes > 0, and
+    # aggregate the pages viewed.
+    - mapping: |
+        root = if batch_index() == 0 {
+          {
+            "user_id": this.user_id,
+            "started_at": json("viewed_at").from(0),
+            "ended_at": json("viewed_at").from(-1),
+            "pages": json("page").from_all(),
+          }
+        } else { deleted() }
+`,
+		)
+}
+
+func init() {
+	err := service.RegisterBatchBuffer(
+		"event_window", eventWindowBufferConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchBuffer, error) {
+			return newEventWindowBufferFromParsed(conf, mgr.Logger())
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func getOptionalDuration(conf *service.ParsedConfig, name string) (time.Duration, error) {
+	if !conf.Contains(name) {
+		return 0, nil
+	}
+	return conf.FieldDuration(name)
+}
+
+//------------------------------------------------------------------------------
+
+type eventWindow struct {
+	key        string
+	start, end time.Time
+	pending    []*tsMessage
+}
+
+type eventWindowBuffer struct {
+	logger *service.Logger
+
+	tsMapping  *bloblang.Executor
+	keyMapping *bloblang.Executor
+	clock      utcNowProvider
+
+	size, slide, offset, gap time.Duration
+	allowedLateness          time.Duration
+	idleTimeout              time.Duration
+	emitLate                 bool
+
+	mut sync.Mutex
+
+	// The watermark only ever moves forwards, and once idle it advances from
+	// the watermark observed at the last write.
+	watermark     time.Time
+	idleWatermark time.Time
+	lastWrite     time.Time
+
+	windows  map[int64]*eventWindow
+	sessions map[string][]*eventWindow
+	late     []*tsMessage
+
+	writtenChan         chan struct{}
+	endOfInputChan      chan struct{}
+	closeEndOfInputOnce sync.Once
+}
+
+func newEventWindowBufferFromParsed(conf *service.ParsedConfig, logger *service.Logger) (*eventWindowBuffer, error) {
+	w := &eventWindowBuffer{
+		logger: logger,
+		clock: func() time.Time {
+			return time.Now().UTC()
+		},
+		windows:        map[int64]*eventWindow{},
+		sessions:       map[string][]*eventWindow{},
+		writtenChan:    make(chan struct{}, 1),
+		endOfInputChan: make(chan struct{}),
+	}
+
+	var err error
+	if w.tsMapping, err = conf.FieldBloblang(ewbFieldTimestampMapping); err != nil {
+		return nil, err
+	}
+	if w.size, err = getOptionalDuration(conf, ewbFieldSize); err != nil {
+		return nil, err
+	}
+	if w.slide, err = getOptionalDuration(conf, ewbFieldSlide); err != nil {
+		return nil, err
+	}
+	if w.offset, err = getOptionalDuration(conf, ewbFieldOffset); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ewbFieldSession) {
+		sConf := conf.Namespace(ewbFieldSession)
+		if w.gap, err = sConf.FieldDuration(ewbFieldSessionGap); err != nil {
+			return nil, err
+		}
+		if w.gap <= 0 {
+			return nil, fmt.Errorf("invalid session gap '%v' must be greater than zero", w.gap)
+		}
+		if w.keyMapping, err = sConf.FieldBloblang(ewbFieldSessionKey); err != nil {
+			return nil, err
+		}
+	}
+	if w.allowedLateness, err = getOptionalDuration(conf, ewbFieldAllowedLateness); err != nil {
+		return nil, err
+	}
+	if w.idleTimeout, err = getOptionalDuration(conf, ewbFieldIdleTimeout); err != nil {
+		return nil, err
+	}
+
+	lateData, err := conf.FieldString(ewbFieldLateData)
+	if err != nil {
+		return nil, err
+	}
+	w.emitLate = lateData == ewbLateDataEmit
+
+	if (w.size > 0) == (w.gap > 0) {
+		return nil, errors.New("exactly one of size or session must be specified")
+	}
+	if w.gap > 0 && (w.slide != 0 || w.offset != 0) {
+		return nil, errors.New("slide and offset cannot be specified for session windows")
+	}
+	if w.slide < 0 || (w.size > 0 && w.slide >= w.size) {
+		return nil, fmt.Errorf("invalid window slide '%v' must be lower than the size '%v'", w.slide, w.size)
+	}
+	if w.size > 0 && w.offset >= w.size {
+		return nil, fmt.Errorf("invalid offset '%v' must be lower than the size '%v'", w.offset, w.size)
+	}
+	if w.slide > 0 && w.offset >= w.slide {
+		return nil, fmt.Errorf("invalid offset '%v' must be lower than the slide '%v'", w.offset, w.slide)
+	}
+	if w.allowedLateness < 0 {
+		return nil, fmt.Errorf("invalid allowed_lateness '%v' must not be negative", w.allowedLateness)
+	}
+	return w, nil
+}
+
+func (w *eventWindowBuffer) getKey(i int, batch service.MessageBatch) (string, error) {
+	keyMsg, err := batch.BloblangQuery(i, w.keyMapping)
+	if err != nil {
+		w.logger.Errorf("Session key mapping failed for message: %v", err)
+		return "", fmt.Errorf("session key mapping failed: %w", err)
+	}
+	keyBytes, err := keyMsg.AsBytes()
+	if err != nil {
+		return "", err
+	}
+	return string(keyBytes), nil
+}
+
+// advanceIdle moves the watermark forwards with the system clock when no
+// messages have been written for the idle timeout. Must be called with the
+// mutex held.
+func (w *eventWindowBuffer) advanceIdle() {
+	if w.idleTimeout <= 0 || w.lastWrite.IsZero() {
+		return
+	}
+	if idle := w.clock().Sub(w.lastWrite) - w.idleTimeout; idle > 0 {
+		if wm := w.idleWatermark.Add(idle); wm.After(w.watermark) {
+			w.watermark = wm
+		}
+	}
+}
+
+// addToTimeWindows adds a message to each tumbling or sliding window it
+// belongs to that has not yet been closed, returning false if there were none.
+// Must be called with the mutex held.
+func (w *eventWindowBuffer) addToTimeWindows(msg *tsMessage) bool {
+	epoch := w.size
+	if w.slide > 0 {
+		epoch = w.slide
+	}
+
+	// The start of the newest window containing the timestamp, where any
+	// earlier windows that also contain it are found by rolling back by the
+	// epoch.
+	start := msg.ts.Add(-w.offset).Truncate(epoch).Add(w.offset)
+	if start.After(msg.ts) {
+		start = start.Add(-epoch)
+	}
+
+	added := false
+	for ; start.Add(w.size).After(msg.ts); start = start.Add(-epoch) {
+		end := start.Add(w.size)
+		if !end.After(w.watermark) {
+			break
+		}
+		win, exists := w.windows[start.UnixNano()]
+		if !exists {
+			win = &eventWindow{start: start, end: end}
+			w.windows[start.UnixNano()] = win
+		}
+		win.pending = append(win.pending, msg)
+		added = true
+	}
+	return added
+}
+
+// addToSession adds a message to the open session of a key that it falls
+// within the gap of, merging any sessions that it bridges, or creates a new
+// session, returning false if the session would already be closed. Must be
+// called with the mutex held.
+func (w *eventWindowBuffer) addToSession(key string, msg *tsMessage) bool {
+	msgEnd := msg.ts.Add(w.gap)
+
+	var merged *eventWindow
+	sessions := w.sessions[key]
+	remaining := sessions[:0]
+	for _, s := range sessions {
+		if !s.end.After(w.watermark) || !msg.ts.Before(s.end) || !msgEnd.After(s.start) {
+			remaining = append(remaining, s)
+			continue
+		}
+		if merged == nil {
+			merged = s
+			remaining = append(remaining, s)
+			continue
+		}
+		merged.pending = append(merged.pending, s.pending...)
+		if s.start.Before(merged.start) {
+			merged.start = s.start
+		}
+		if s.end.After(merged.end) {
+			merged.end = s.end
+		}
+	}
+
+	if merged == nil {
+		if !msgEnd.After(w.watermark) {
+			return false
+		}
+		merged = &eventWindow{key: key, start: msg.ts, end: msgEnd}
+		remaining = append(remaining, merged)
+	} else {
+		if msg.ts.Before(merged.start) {
+			merged.start = msg.ts
+		}
+		if msgEnd.After(merged.end) {
+			merged.end = msgEnd
+		}
+	}
+	merged.pending = append(merged.pending, msg)
+
+	w.sessions[key] = remaining
+	return true
+}
+
+func (w *eventWindowBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageBatch, aFn service.AckFunc) error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	w.advanceIdle()
+
+	type stagedMessage struct {
+		ts  time.Time
+		key string
+	}
+
+	// Timestamps and keys are resolved up front so that mapping errors reject
+	// the entire batch before any of it is added to windows.
+	staged := make([]stagedMessage, len(msgBatch))
+	for i := range msgBatch {
+		var err error
+		if staged[i].ts, err = getWindowTimestamp(w.logger, w.tsMapping, i, msgBatch); err != nil {
+			return err
+		}
+		if w.keyMapping != nil {
+			if staged[i].key, err = w.getKey(i, msgBatch); err != nil {
+				return err
+			}
+		}
+	}
+
+	messageAdded := false
+	aggregatedAck := batch.NewCombinedAcker(batch.AckFunc(aFn))
+
+	for i, msg := range msgBatch {
+		pending := &tsMessage{ts: staged[i].ts, m: msg}
+
+		var added bool
+		if w.gap > 0 {
+			added = w.addToSession(staged[i].key, pending)
+		} else {
+			added = w.addToTimeWindows(pending)
+		}
+		if !added {
+			if !w.emitLate {
+				continue
+			}
+			w.late = append(w.late, pending)
+		}
+
+		// Ack funcs are only derived for messages that are kept, and the
+		// watermark is only advanced after a message is allocated in order to
+		// treat each message of a batch consistently.
+		messageAdded = true
+		pending.ackFn = service.AckFunc(aggregatedAck.Derive())
+	}
+
+	for _, s := range staged {
+		if wm := s.ts.Add(-w.allowedLateness); wm.After(w.watermark) {
+			w.watermark = wm
+		}
+	}
+	w.idleWatermark = w.watermark
+	w.lastWrite = w.clock()
+
+	if !messageAdded {
+		// If none of the messages have fit into a window we reject them by
+		// acknowledging the batch.
+		_ = aFn(ctx, nil)
+	}
+
+	select {
+	case w.writtenChan <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// nextWindow returns the window with the earliest end, optionally only when
+// the watermark has reached it, and removes it from the buffer. Must be called
+// with the mutex held.
+func (w *eventWindowBuffer) nextWindow(onlyClosed bool) *eventWindow {
+	var next *eventWindow
+	isEarlier := func(win *eventWindow) bool {
+		if onlyClosed && win.end.After(w.watermark) {
+			return false
+		}
+		if next == nil || win.end.Before(next.end) {
+			return true
+		}
+		if win.end.Equal(next.end) {
+			if win.start.Equal(next.start) {
+				return win.key < next.key
+			}
+			return win.start.Before(next.start)
+		}
+		return false
+	}
+
+	for _, win := range w.windows {
+		if isEarlier(win) {
+			next = win
+		}
+	}
+	for _, sessions := range w.sessions {
+		for _, win := range sessions {
+			if isEarlier(win) {
+				next = win
+			}
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	if w.gap > 0 {
+		sessions := w.sessions[next.key]
+		for i, s := range sessions {
+			if s == next {
+				sessions = append(sessions[:i], sessions[i+1:]...)
+				break
+			}
+		}
+		if len(sessions) == 0 {
+			delete(w.sessions, next.key)
+		} else {
+			w.sessions[next.key] = sessions
+		}
+	} else {
+		delete(w.windows, next.start.UnixNano())
+	}
+	return next
+}
+
+// nextEnd returns the earliest end of all windows, or false if there are none.
+// Must be called with the mutex held.
+func (w *eventWindowBuffer) nextEnd() (end time.Time, exists bool) {
+	for _, win := range w.windows {
+		if !exists || win.end.Before(end) {
+			end, exists = win.end, true
+		}
+	}
+	for _, sessions := range w.sessions {
+		for _, win := range sessions {
+			if !exists || win.end.Before(end) {
+				end, exists = win.end, true
+			}
+		}
+	}
+	return
+}
+
+func flushEventWindow(pending []*tsMessage, setMeta func(m *service.Message)) (service.MessageBatch, service.AckFunc) {
+	// Messages of a session are appended in the order that they arrive, and
+	// therefore are sorted by their timestamps before being flushed.
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].ts.Before(pending[j].ts)
+	})
+
+	flushBatch := make(service.MessageBatch, 0, len(pending))
+	flushAcks := make([]service.AckFunc, 0, len(pending))
+	for _, p := range pending {
+		tmpMsg := p.m.Copy()
+		setMeta(tmpMsg)
+		flushBatch = append(flushBatch, tmpMsg)
+		flushAcks = append(flushAcks, p.ackFn)
+	}
+	return flushBatch, func(ctx context.Context, err error) error {
+		for _, aFn := range flushAcks {
+			_ = aFn(ctx, err)
+		}
+		return nil
+	}
+}
+
+func (w *eventWindowBuffer) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	for {
+		w.mut.Lock()
+		w.advanceIdle()
+
+		if len(w.late) > 0 {
+			late := w.late
+			w.late = nil
+			w.mut.Unlock()
+
+			msgBatch, aFn := flushEventWindow(late, func(m *service.Message) {
+				m.MetaSetMut("window_late", true)
+			})
+			return msgBatch, aFn, nil
+		}
+
+		if win := w.nextWindow(true); win != nil {
+			w.mut.Unlock()
+
+			msgBatch, aFn := flushEventWindow(win.pending, func(m *service.Message) {
+				m.MetaSet("window_start_timestamp", win.start.Format(time.RFC3339Nano))
+				m.MetaSet("window_end_timestamp", win.end.Format(time.RFC3339Nano))
+				if w.gap > 0 {
+					m.MetaSet("window_key", win.key)
+				}
+			})
+			return msgBatch, aFn, nil
+		}
+
+		// Nothing is ready, so if the watermark would eventually advance with
+		// the system clock we wait until it reaches the next window end.
+		var idleChan <-chan time.Time
+		if end, exists := w.nextEnd(); exists && w.idleTimeout > 0 && !w.lastWrite.IsZero() {
+			waitFor := w.lastWrite.Add(w.idleTimeout).Add(end.Sub(w.idleWatermark)).Sub(w.clock())
+			if waitFor <= 0 {
+				waitFor = time.Millisecond
+			}
+			idleChan = time.After(waitFor)
+		}
+		w.mut.Unlock()
+
+		select {
+		case <-w.writtenChan:
+		case <-idleChan:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-w.endOfInputChan:
+			// Nack all pending messages so that we re-consume them on the next
+			// start up.
+			w.mut.Lock()
+			for win := w.nextWindow(false); win != nil; win = w.nextWindow(false) {
+				for _, pending := range win.pending {
+					_ = pending.ackFn(ctx, errWindowClosed)
+				}
+			}
+			w.mut.Unlock()
+			return nil, nil, service.ErrEndOfBuffer
+		}
+	}
+}
+
+func (w *eventWindowBuffer) EndOfInput() {
+	w.closeEndOfInputOnce.Do(func() {
+		close(w.endOfInputChan)
+	})
+}
+
+func (w *eventWindowBuffer) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,364 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func newEventWindowBufferFromYAML(t testing.TB, conf string) *eventWindowBuffer {
+	t.Helper()
+
+	pConf, err := eventWindowBufferConfig().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	w, err := newEventWindowBufferFromParsed(pConf, nil)
+	require.NoError(t, err)
+	return w
+}
+
+func eventWindowMessages(t testing.TB, docs ...string) service.MessageBatch {
+	t.Helper()
+
+	var b service.MessageBatch
+	for _, d := range docs {
+		b = append(b, service.NewMessage([]byte(d)))
+	}
+	return b
+}
+
+func readEventWindow(t testing.TB, w *eventWindowBuffer) (docs []string, meta map[string]string) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second)
+	defer done()
+
+	msgBatch, aFn, err := w.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.NoError(t, aFn(ctx, nil))
+
+	meta = map[string]string{}
+	for _, m := range msgBatch {
+		mBytes, err := m.AsBytes()
+		require.NoError(t, err)
+		docs = append(docs, string(mBytes))
+		_ = m.MetaWalk(func(k, v string) error {
+			meta[k] = v
+			return nil
+		})
+	}
+	return
+}
+
+func assertNoEventWindow(t testing.TB, w *eventWindowBuffer) {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	_, _, err := w.ReadBatch(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestEventWindowBufferConfigs(t *testing.T) {
+	tests := []struct {
+		config           string
+		buildErrContains string
+	}{
+		{
+			config: `
+timestamp_mapping: root = this.ts
+size: 60m
+slide: 5m
+offset: 1m
+allowed_lateness: 2m
+late_data: emit
+`,
+		},
+		{
+			config: `
+timestamp_mapping: root = this.ts
+session:
+  gap: 10m
+  key: root = this.id
+`,
+		},
+		{
+			config: `
+timestamp_mapping: root = this.ts
+`,
+			buildErrContains: "exactly one of size or session",
+		},
+		{
+			config: `
+timestamp_mapping: root = this.ts
+size: 60m
+session:
+  gap: 10m
+`,
+			buildErrContains: "exactly one of size or session",
+		},
+		{
+			config: `
+timestamp_mapping: root = this.ts
+size: 60m
+slide: 60m
+`,
+			buildErrContains: "invalid window slide",
+		},
+		{
+			config: `
+timestamp_mapping: root = this.ts
+size: 60m
+slide: 10m
+offset: 10m
+`,
+			buildErrContains: "invalid offset",
+		},
+		{
+			config: `
+timestamp_mapping: root = this.ts
+slide: 1m
+session:
+  gap: 10m
+`,
+			buildErrContains: "cannot be specified for session windows",
+		},
+	}
+
+	for i, test := range tests {
+		test := test
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			pConf, err := eventWindowBufferConfig().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newEventWindowBufferFromParsed(pConf, nil)
+			if test.buildErrContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.buildErrContains)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestEventWindowTumbling(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+timestamp_mapping: root = this.ts
+size: 10s
+allowed_lateness: 2s
+`)
+	ctx := context.Background()
+
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"1","ts":3}`,
+		`{"id":"2","ts":9.5}`,
+		`{"id":"3","ts":11}`,
+	), noopAck))
+
+	// The watermark is at 9s and therefore the first window remains open.
+	assertNoEventWindow(t, w)
+
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"4","ts":8}`,
+		`{"id":"5","ts":12}`,
+	), noopAck))
+
+	docs, meta := readEventWindow(t, w)
+	assert.Equal(t, []string{
+		`{"id":"1","ts":3}`,
+		`{"id":"4","ts":8}`,
+		`{"id":"2","ts":9.5}`,
+	}, docs)
+	assert.Equal(t, "1970-01-01T00:00:00Z", meta["window_start_timestamp"])
+	assert.Equal(t, "1970-01-01T00:00:10Z", meta["window_end_timestamp"])
+
+	// Late message is dropped and acknowledged.
+	var lateAcked bool
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"6","ts":7}`,
+	), func(ctx context.Context, err error) error {
+		lateAcked = true
+		return err
+	}))
+	assert.True(t, lateAcked)
+
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"7","ts":25}`,
+	), noopAck))
+
+	docs, meta = readEventWindow(t, w)
+	assert.Equal(t, []string{
+		`{"id":"3","ts":11}`,
+		`{"id":"5","ts":12}`,
+	}, docs)
+	assert.Equal(t, "1970-01-01T00:00:10Z", meta["window_start_timestamp"])
+	assert.Equal(t, "1970-01-01T00:00:20Z", meta["window_end_timestamp"])
+
+	assertNoEventWindow(t, w)
+}
+
+func TestEventWindowSliding(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+timestamp_mapping: root = this.ts
+size: 10s
+slide: 5s
+`)
+	ctx := context.Background()
+
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"1","ts":3}`,
+		`{"id":"2","ts":7}`,
+		`{"id":"3","ts":12}`,
+		`{"id":"4","ts":16}`,
+	), noopAck))
+
+	docs, meta := readEventWindow(t, w)
+	assert.Equal(t, []string{`{"id":"1","ts":3}`}, docs)
+	assert.Equal(t, "1969-12-31T23:59:55Z", meta["window_start_timestamp"])
+	assert.Equal(t, "1970-01-01T00:00:05Z", meta["window_end_timestamp"])
+
+	docs, meta = readEventWindow(t, w)
+	assert.Equal(t, []string{`{"id":"1","ts":3}`, `{"id":"2","ts":7}`}, docs)
+	assert.Equal(t, "1970-01-01T00:00:10Z", meta["window_end_timestamp"])
+
+	docs, meta = readEventWindow(t, w)
+	assert.Equal(t, []string{`{"id":"2","ts":7}`, `{"id":"3","ts":12}`}, docs)
+	assert.Equal(t, "1970-01-01T00:00:15Z", meta["window_end_timestamp"])
+
+	assertNoEventWindow(t, w)
+}
+
+func TestEventWindowSessions(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+timestamp_mapping: root = this.ts
+allowed_lateness: 3s
+session:
+  gap: 5s
+  key: root = this.user
+`)
+	ctx := context.Background()
+
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"user":"a","ts":1}`,
+		`{"user":"b","ts":1}`,
+		`{"user":"a","ts":10}`,
+		`{"user":"a","ts":4}`,
+	), noopAck))
+
+	// Watermark is at 7s, and therefore only the session of b, which ends at
+	// 6s, is closed.
+	docs, meta := readEventWindow(t, w)
+	assert.Equal(t, []string{`{"user":"b","ts":1}`}, docs)
+	assert.Equal(t, "b", meta["window_key"])
+	assert.Equal(t, "1970-01-01T00:00:01Z", meta["window_start_timestamp"])
+	assert.Equal(t, "1970-01-01T00:00:06Z", meta["window_end_timestamp"])
+
+	assertNoEventWindow(t, w)
+
+	// The message at 7s bridges both open sessions of a.
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"user":"a","ts":7}`,
+		`{"user":"b","ts":20}`,
+	), noopAck))
+
+	docs, meta = readEventWindow(t, w)
+	assert.Equal(t, []string{
+		`{"user":"a","ts":1}`,
+		`{"user":"a","ts":4}`,
+		`{"user":"a","ts":7}`,
+		`{"user":"a","ts":10}`,
+	}, docs)
+	assert.Equal(t, "a", meta["window_key"])
+	assert.Equal(t, "1970-01-01T00:00:01Z", meta["window_start_timestamp"])
+	assert.Equal(t, "1970-01-01T00:00:15Z", meta["window_end_timestamp"])
+
+	assertNoEventWindow(t, w)
+}
+
+func TestEventWindowLateEmit(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+timestamp_mapping: root = this.ts
+size: 10s
+late_data: emit
+`)
+	ctx := context.Background()
+
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"1","ts":3}`,
+		`{"id":"2","ts":15}`,
+	), noopAck))
+
+	docs, _ := readEventWindow(t, w)
+	assert.Equal(t, []string{`{"id":"1","ts":3}`}, docs)
+
+	var ackErr error
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"3","ts":4}`,
+	), func(ctx context.Context, err error) error {
+		ackErr = errors.New("acked")
+		return nil
+	}))
+	assert.NoError(t, ackErr)
+
+	docs, meta := readEventWindow(t, w)
+	assert.Equal(t, []string{`{"id":"3","ts":4}`}, docs)
+	assert.Equal(t, "true", meta["window_late"])
+	assert.Empty(t, meta["window_end_timestamp"])
+	assert.EqualError(t, ackErr, "acked")
+}
+
+func TestEventWindowIdleTimeout(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+timestamp_mapping: root = this.ts
+size: 10s
+idle_timeout: 30s
+`)
+	ctx := context.Background()
+
+	currentTS := time.Unix(1000, 0).UTC()
+	w.clock = func() time.Time {
+		return currentTS
+	}
+
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"1","ts":3}`,
+	), noopAck))
+
+	currentTS = currentTS.Add(time.Second * 35)
+	assertNoEventWindow(t, w)
+
+	currentTS = currentTS.Add(time.Second * 2)
+	docs, _ := readEventWindow(t, w)
+	assert.Equal(t, []string{`{"id":"1","ts":3}`}, docs)
+}
+
+func TestEventWindowEndOfInput(t *testing.T) {
+	w := newEventWindowBufferFromYAML(t, `
+timestamp_mapping: root = this.ts
+size: 10s
+`)
+	ctx := context.Background()
+
+	var ackErr error
+	require.NoError(t, w.WriteBatch(ctx, eventWindowMessages(t,
+		`{"id":"1","ts":3}`,
+	), func(ctx context.Context, err error) error {
+		ackErr = err
+		return nil
+	}))
+
+	w.EndOfInput()
+
+	_, _, err := w.ReadBatch(ctx)
+	require.ErrorIs(t, err, service.ErrEndOfBuffer)
+	assert.ErrorIs(t, ackErr, errWindowClosed)
+}
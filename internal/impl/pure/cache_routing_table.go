@@ -0,0 +1,389 @@
+package pure
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rtcFieldURL            = "url"
+	rtcFieldCache          = "cache"
+	rtcFieldCacheKey       = "cache_key"
+	rtcFieldReloadInterval = "reload_interval"
+	rtcFieldDefaultRoute   = "default_route"
+)
+
+// errRoutingTableReadOnly is returned when attempting to modify a routing table.
+var errRoutingTableReadOnly = errors.New("routing tables are read-only")
+
+func routingTableCacheSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary("A read-only cache that resolves keys to routes by consulting a routing table that is loaded from a file, an HTTP endpoint or another cache, and reloaded when it changes.").
+		Description(`
+Routing tables allow routing rules to be managed separately from the config, for example by another team, without deploying config changes. A routing table is usually consulted by the `+"[`switch` output](/docs/components/outputs/switch)"+` with its `+"`routing_table`"+` field, which routes each message to the case with the matching `+"`route`"+`, but as a cache it can be consulted by any component that reads from caches.
+
+Getting a key from the cache returns the name of the route that the key matches, and keys that match no route and have no default route are not found. Writing to the cache is not supported.
+
+### Routing Tables
+
+A routing table is a YAML or JSON document that lists routes, where each route has either a `+"`key`"+` that must match exactly, or a `+"`pattern`"+` that is matched against the key, and the name of the `+"`route`"+` that matching keys resolve to:
+
+`+"```yaml"+`
+routes:
+  - key: orders
+    route: orders
+  - pattern: payments.*
+    route: payments
+default: archive
+`+"```"+`
+
+Exact keys take precedence over patterns, and patterns are tried in the order they are listed. Patterns follow the syntax of [Go's `+"`path.Match`"+` function](https://pkg.go.dev/path#Match), where `+"`*`"+` matches any sequence of characters other than `+"`/`"+`. Keys that match no route resolve to the `+"`default`"+` route of the table, or else the `+"`default_route`"+` of this config.
+
+The table is loaded from exactly one of `+"`url`"+` or `+"`cache`"+`, and is fetched again every `+"`reload_interval`"+`. A table is only replaced once it has been fetched and validated successfully, and therefore a bad table is logged and the previous table continues to be used.
+
+### Metrics
+
+The counter `+"`routing_table_reloads`"+` counts the number of times the table has been replaced.`).
+		Example(
+			"Externally Managed Routes",
+			"Here we route events to Kafka clusters owned by different teams based on their type, where the routes are served by a HTTP endpoint and events with an unknown type are archived to S3.",
+			`
+output:
+  switch:
+    routing_table:
+      cache: routes
+      key: ${! this.type }
+    cases:
+      - route: orders
+        output:
+          kafka_franz:
+            seed_brokers: [ orders-kafka:9092 ]
+            topic: orders
+      - route: payments
+        output:
+          kafka_franz:
+            seed_brokers: [ payments-kafka:9092 ]
+            topic: payments
+      - route: archive
+        output:
+          aws_s3:
+            bucket: unrouted-events
+            path: ${! uuid_v4() }.json
+
+cache_resources:
+  - label: routes
+    routing_table:
+      url: https://routes.example.com/events.yaml
+      reload_interval: 30s
+      default_route: archive
+`,
+		).
+		Fields(
+			service.NewStringField(rtcFieldURL).
+				Description("The location of the routing table, either a file path or a HTTP(S) URL.").
+				Example("./routes.yaml").
+				Example("https://routes.example.com/events.yaml").
+				Optional(),
+			service.NewStringField(rtcFieldCache).
+				Description("A [`cache` resource](/docs/components/caches/about) to read the routing table from.").
+				Optional(),
+			service.NewStringField(rtcFieldCacheKey).
+				Description("The key of the routing table within the `cache`.").
+				Default("routing_table"),
+			service.NewDurationField(rtcFieldReloadInterval).
+				Description("The interval at which the routing table is fetched again in order to detect changes. Set to `0s` in order to disable reloading.").
+				Default("1m"),
+			service.NewStringField(rtcFieldDefaultRoute).
+				Description("An optional route for keys that match no route, when the routing table does not specify a default.").
+				Optional(),
+		)
+}
+
+func init() {
+	err := service.RegisterCache("routing_table", routingTableCacheSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Cache, error) {
+			return newRoutingTableFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// routingTableDoc is the document format of a routing table.
+type routingTableDoc struct {
+	Routes []struct {
+		Key     string `yaml:"key"`
+		Pattern string `yaml:"pattern"`
+		Route   string `yaml:"route"`
+	} `yaml:"routes"`
+	Default string `yaml:"default"`
+}
+
+type routingPattern struct {
+	pattern string
+	route   string
+}
+
+// routingTable is a validated snapshot of a routing table.
+type routingTable struct {
+	keys     map[string]string
+	patterns []routingPattern
+	fallback string
+	hash     [sha256.Size]byte
+}
+
+// lookup returns the route of a key, or an empty string when the key matches
+// no route.
+func (t *routingTable) lookup(key string) string {
+	if route, exists := t.keys[key]; exists {
+		return route
+	}
+	for _, p := range t.patterns {
+		if matched, _ := path.Match(p.pattern, key); matched {
+			return p.route
+		}
+	}
+	return t.fallback
+}
+
+type routingTableCache struct {
+	url          string
+	cache        string
+	cacheKey     string
+	defaultRoute string
+
+	fs        *service.FS
+	client    *http.Client
+	log       *service.Logger
+	readCache func(ctx context.Context, name, key string) ([]byte, error)
+
+	table atomic.Pointer[routingTable]
+
+	mReloads *service.MetricCounter
+
+	reloadCtx    context.Context
+	reloadCancel context.CancelFunc
+	reloadWG     sync.WaitGroup
+}
+
+func newRoutingTableFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*routingTableCache, error) {
+	r := &routingTableCache{
+		fs:     mgr.FS(),
+		client: &http.Client{Timeout: time.Second * 30},
+		log:    mgr.Logger(),
+		readCache: func(ctx context.Context, name, key string) (b []byte, err error) {
+			if aErr := mgr.AccessCache(ctx, name, func(c service.Cache) {
+				b, err = c.Get(ctx, key)
+			}); aErr != nil {
+				return nil, aErr
+			}
+			return
+		},
+		mReloads: mgr.Metrics().NewCounter("routing_table_reloads"),
+	}
+
+	var err error
+	if conf.Contains(rtcFieldURL) {
+		if r.url, err = conf.FieldString(rtcFieldURL); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(rtcFieldCache) {
+		if r.cache, err = conf.FieldString(rtcFieldCache); err != nil {
+			return nil, err
+		}
+	}
+	if (r.url == "") == (r.cache == "") {
+		return nil, errors.New("exactly one of url or cache must be specified")
+	}
+	if r.cache != "" && !mgr.HasCache(r.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", r.cache)
+	}
+	if r.cacheKey, err = conf.FieldString(rtcFieldCacheKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(rtcFieldDefaultRoute) {
+		if r.defaultRoute, err = conf.FieldString(rtcFieldDefaultRoute); err != nil {
+			return nil, err
+		}
+	}
+
+	reloadInterval, err := conf.FieldDuration(rtcFieldReloadInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.reload(context.Background()); err != nil {
+		return nil, err
+	}
+
+	r.reloadCtx, r.reloadCancel = context.WithCancel(context.Background())
+	if reloadInterval > 0 {
+		r.reloadWG.Add(1)
+		go r.reloadLoop(reloadInterval)
+	}
+	return r, nil
+}
+
+func (r *routingTableCache) fetch(ctx context.Context) ([]byte, error) {
+	if r.cache != "" {
+		return r.readCache(ctx, r.cache, r.cacheKey)
+	}
+	if !strings.HasPrefix(r.url, "http://") && !strings.HasPrefix(r.url, "https://") {
+		return ifs.ReadFile(r.fs, r.url)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status code: %v", res.StatusCode)
+	}
+	return io.ReadAll(res.Body)
+}
+
+// parseRoutingTable parses and validates a routing table document.
+func (r *routingTableCache) parseRoutingTable(b []byte) (*routingTable, error) {
+	var doc routingTableDoc
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse routing table: %w", err)
+	}
+
+	t := &routingTable{
+		keys:     map[string]string{},
+		fallback: doc.Default,
+	}
+	for i, route := range doc.Routes {
+		if route.Route == "" {
+			return nil, fmt.Errorf("route %v: a route must be specified", i)
+		}
+		if (route.Key == "") == (route.Pattern == "") {
+			return nil, fmt.Errorf("route %v: exactly one of key or pattern must be specified", i)
+		}
+		if route.Key != "" {
+			if _, exists := t.keys[route.Key]; !exists {
+				t.keys[route.Key] = route.Route
+			}
+			continue
+		}
+		if _, err := path.Match(route.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("route %v: invalid pattern '%v': %w", i, route.Pattern, err)
+		}
+		t.patterns = append(t.patterns, routingPattern{
+			pattern: route.Pattern,
+			route:   route.Route,
+		})
+	}
+	if t.fallback == "" {
+		t.fallback = r.defaultRoute
+	}
+	return t, nil
+}
+
+// reload fetches the routing table and, when its contents have changed,
+// replaces the current table. Returns true when the table was replaced.
+func (r *routingTableCache) reload(ctx context.Context) (bool, error) {
+	b, err := r.fetch(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch routing table: %w", err)
+	}
+
+	hash := sha256.Sum256(b)
+	if current := r.table.Load(); current != nil && current.hash == hash {
+		return false, nil
+	}
+
+	t, err := r.parseRoutingTable(b)
+	if err != nil {
+		return false, err
+	}
+	t.hash = hash
+	r.table.Store(t)
+	r.mReloads.Incr(1)
+	return true, nil
+}
+
+func (r *routingTableCache) reloadLoop(interval time.Duration) {
+	defer r.reloadWG.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.reloadCtx.Done():
+			return
+		}
+		changed, err := r.reload(r.reloadCtx)
+		if err != nil {
+			if r.reloadCtx.Err() != nil {
+				return
+			}
+			r.log.Errorf("Failed to reload routing table, continuing with previous table: %v", err)
+			continue
+		}
+		if changed {
+			r.log.Infof("Reloaded routing table")
+		}
+	}
+}
+
+func (r *routingTableCache) Get(ctx context.Context, key string) ([]byte, error) {
+	route := r.table.Load().lookup(key)
+	if route == "" {
+		return nil, service.ErrKeyNotFound
+	}
+	return []byte(route), nil
+}
+
+func (r *routingTableCache) Set(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return errRoutingTableReadOnly
+}
+
+func (r *routingTableCache) Add(ctx context.Context, key string, value []byte, ttl *time.Duration) error {
+	return errRoutingTableReadOnly
+}
+
+func (r *routingTableCache) Delete(ctx context.Context, key string) error {
+	return errRoutingTableReadOnly
+}
+
+func (r *routingTableCache) Close(ctx context.Context) error {
+	r.reloadCancel()
+
+	waitChan := make(chan struct{})
+	go func() {
+		r.reloadWG.Wait()
+		close(waitChan)
+	}()
+	select {
+	case <-waitChan:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
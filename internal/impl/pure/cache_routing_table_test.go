@@ -0,0 +1,236 @@
+package pure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testRoutingTable(t *testing.T, config string, res *service.Resources) *routingTableCache {
+	t.Helper()
+
+	conf, err := routingTableCacheSpec().ParseYAML(config, nil)
+	require.NoError(t, err)
+
+	if res == nil {
+		res = service.MockResources()
+	}
+	r, err := newRoutingTableFromParsed(conf, res)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(context.Background()))
+	})
+	return r
+}
+
+func assertRoutes(t *testing.T, r *routingTableCache, routes map[string]string) {
+	t.Helper()
+
+	for key, exp := range routes {
+		route, err := r.Get(context.Background(), key)
+		if exp == "" {
+			assert.ErrorIs(t, err, service.ErrKeyNotFound, key)
+			continue
+		}
+		require.NoError(t, err, key)
+		assert.Equal(t, exp, string(route), key)
+	}
+}
+
+func TestRoutingTableLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+routes:
+  - pattern: orders.*
+    route: orders_b
+  - key: orders.eu
+    route: orders_a
+  - pattern: "refunds*"
+    route: refunds
+`), 0o644))
+
+	r := testRoutingTable(t, `
+url: `+path+`
+reload_interval: 0s
+`, nil)
+
+	assertRoutes(t, r, map[string]string{
+		"orders.eu":     "orders_a",
+		"orders.us":     "orders_b",
+		"refunds.eu":    "refunds",
+		"payments":      "",
+		"refunds/other": "",
+	})
+}
+
+func TestRoutingTableDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"routes":[{"key":"foo","route":"a"}]}`), 0o644))
+
+	r := testRoutingTable(t, `
+url: `+path+`
+reload_interval: 0s
+default_route: b
+`, nil)
+
+	assertRoutes(t, r, map[string]string{
+		"foo": "a",
+		"bar": "b",
+	})
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"routes":[{"key":"foo","route":"a"}],"default":"c"}`), 0o644))
+	_, err := r.reload(context.Background())
+	require.NoError(t, err)
+
+	assertRoutes(t, r, map[string]string{
+		"foo": "a",
+		"bar": "c",
+	})
+}
+
+func TestRoutingTableReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`routes: []`), 0o644))
+
+	r := testRoutingTable(t, `
+url: `+path+`
+reload_interval: 0s
+`, nil)
+
+	ctx := context.Background()
+	assert.ErrorIs(t, r.Set(ctx, "foo", []byte("a"), nil), errRoutingTableReadOnly)
+	assert.ErrorIs(t, r.Add(ctx, "foo", []byte("a"), nil), errRoutingTableReadOnly)
+	assert.ErrorIs(t, r.Delete(ctx, "foo"), errRoutingTableReadOnly)
+}
+
+func TestRoutingTableInvalid(t *testing.T) {
+	tests := map[string]string{
+		"no route":        `routes: [ { key: foo } ]`,
+		"key and pattern": `routes: [ { key: foo, pattern: "f*", route: a } ]`,
+		"bad pattern":     `routes: [ { pattern: "[", route: a } ]`,
+		"bad document":    `routes: {`,
+	}
+
+	r := &routingTableCache{}
+	for name, doc := range tests {
+		doc := doc
+		t.Run(name, func(t *testing.T) {
+			_, err := r.parseRoutingTable([]byte(doc))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRoutingTableReloadHTTP(t *testing.T) {
+	var mut sync.Mutex
+	doc := `routes: [ { key: foo, route: a } ]`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mut.Lock()
+		defer mut.Unlock()
+		_, _ = w.Write([]byte(doc))
+	}))
+	t.Cleanup(srv.Close)
+
+	r := testRoutingTable(t, `
+url: `+srv.URL+`
+reload_interval: 0s
+`, nil)
+	assertRoutes(t, r, map[string]string{"foo": "a"})
+
+	changed, err := r.reload(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	mut.Lock()
+	doc = `routes: [ { key: foo } ]`
+	mut.Unlock()
+
+	_, err = r.reload(context.Background())
+	require.Error(t, err)
+	assertRoutes(t, r, map[string]string{"foo": "a"})
+
+	mut.Lock()
+	doc = `routes: [ { key: foo, route: b } ]`
+	mut.Unlock()
+
+	changed, err = r.reload(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assertRoutes(t, r, map[string]string{"foo": "b"})
+}
+
+func TestRoutingTableReloadLoop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`routes: [ { key: foo, route: a } ]`), 0o644))
+
+	r := testRoutingTable(t, `
+url: `+path+`
+reload_interval: 10ms
+`, nil)
+	assertRoutes(t, r, map[string]string{"foo": "a"})
+
+	require.NoError(t, os.WriteFile(path, []byte(`routes: [ { key: foo, route: b } ]`), 0o644))
+	assert.Eventually(t, func() bool {
+		route, err := r.Get(context.Background(), "foo")
+		return err == nil && string(route) == "b"
+	}, time.Second*5, time.Millisecond*10)
+}
+
+func TestRoutingTableFromCache(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	require.NoError(t, res.AccessCache(context.Background(), "foo", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "routes", []byte(`routes: [ { key: foo, route: a } ]`), nil))
+	}))
+
+	r := testRoutingTable(t, `
+cache: foo
+cache_key: routes
+reload_interval: 0s
+`, res)
+	assertRoutes(t, r, map[string]string{"foo": "a"})
+}
+
+func TestRoutingTableConfig(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		err    string
+	}{
+		"no source": {
+			config: `default_route: foo`,
+			err:    "exactly one of url or cache must be specified",
+		},
+		"both sources": {
+			config: `
+url: ./routes.yaml
+cache: foo
+`,
+			err: "exactly one of url or cache must be specified",
+		},
+		"missing cache": {
+			config: `cache: nope`,
+			err:    "cache resource 'nope' was not found",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := routingTableCacheSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newRoutingTableFromParsed(conf, service.MockResources())
+			require.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package extended
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	zpFieldOperator        = "operator"
+	zpFieldLevel           = "level"
+	zpFieldDictionaryPaths = "dictionary_paths"
+	zpFieldDictionaryCache = "dictionary_cache"
+	zpFieldTrain           = "train"
+	zpFieldTrainSamples    = "samples"
+	zpFieldTrainMaxSize    = "max_size"
+	zpFieldTrainID         = "id"
+
+	zpMetaDictionaryID = "zstd_dictionary_id"
+
+	// The lower bound of dictionary IDs that are not reserved by the zstd
+	// specification.
+	zpMinDictionaryID = 32768
+)
+
+func zstdProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Categories("Parsing").
+		Summary("Compresses or decompresses messages using [zstd](https://facebook.github.io/zstd/), optionally with dictionaries that are either loaded from files or trained from samples of the messages being compressed.").
+		Description(`
+Compression dictionaries significantly improve the compression ratio of small messages with a similar structure (such as JSON documents), as the content common to all messages only needs to be stored once within the dictionary rather than within each message.
+
+Dictionaries can be created with the `+"`zstd --train`"+` command and loaded with the field `+"`dictionary_paths`"+`, in which case the first dictionary is used for compression. Alternatively, when `+"`train.enabled`"+` is `+"`true`"+` the processor collects samples of the messages it compresses and, once enough samples are collected, trains a dictionary from them that is used for all subsequent messages. Messages compressed before training is complete are compressed without a dictionary.
+
+### Sharing Dictionaries
+
+Each dictionary has an ID that is written to the frames of messages compressed with it, and is also added to compressed messages as the metadata field `+"`zstd_dictionary_id`"+`. When decompressing, the dictionary is selected by the ID within each frame, and therefore messages compressed with different dictionaries can be decompressed by the same processor.
+
+When a `+"`dictionary_cache`"+` is configured trained dictionaries are stored within it under the key of their ID, and dictionaries that are referenced by messages being decompressed and are not already known are obtained from it. This allows pipelines that decompress messages to obtain dictionaries trained by the pipelines that compressed them.`).
+		Example(
+			"Training a Shared Dictionary",
+			"Here we compress small JSON documents with a dictionary trained from the first 1000 messages, which is stored within a Redis cache so that it can be obtained by the pipelines that consume them.",
+			`
+pipeline:
+  processors:
+    - zstd:
+        operator: compress
+        dictionary_cache: dictionaries
+        train:
+          enabled: true
+          samples: 1000
+
+cache_resources:
+  - label: dictionaries
+    redis:
+      url: redis://localhost:6379
+      prefix: zstd_dict_
+`,
+		).
+		Example(
+			"Decompressing With Shared Dictionaries",
+			"Here we decompress messages compressed by the pipeline above, where the dictionaries are obtained from the same cache.",
+			`
+pipeline:
+  processors:
+    - zstd:
+        operator: decompress
+        dictionary_cache: dictionaries
+
+cache_resources:
+  - label: dictionaries
+    redis:
+      url: redis://localhost:6379
+      prefix: zstd_dict_
+`,
+		).
+		Fields(
+			service.NewStringEnumField(zpFieldOperator, "compress", "decompress").
+				Description("Whether to compress or decompress messages."),
+			service.NewIntField(zpFieldLevel).
+				Description("The level of compression to use, in the range of zstd levels (1 to 22), which is mapped to the closest level supported by the encoder.").
+				Default(3),
+			service.NewStringListField(zpFieldDictionaryPaths).
+				Description("A list of paths to dictionaries in the zstd dictionary format to load.").
+				Example([]string{"./dictionaries/orders.dict"}).
+				Default([]string{}),
+			service.NewStringField(zpFieldDictionaryCache).
+				Description("An optional [cache resource](/docs/components/caches/about) to store trained dictionaries in and obtain unknown dictionaries from.").
+				Optional(),
+			service.NewObjectField(zpFieldTrain,
+				service.NewBoolField("enabled").
+					Description("Whether to train a dictionary from samples of the messages being compressed.").
+					Default(false),
+				service.NewIntField(zpFieldTrainSamples).
+					Description("The number of messages to sample before training a dictionary.").
+					Default(1000),
+				service.NewStringField(zpFieldTrainMaxSize).
+					Description("The maximum size of a trained dictionary.").
+					Default("110KB"),
+				service.NewIntField(zpFieldTrainID).
+					Description("An explicit ID to give a trained dictionary, which must be at least 32768. When zero an ID is derived from the content of the dictionary.").
+					Default(0).
+					Advanced(),
+			).
+				Description("Train a dictionary from samples of messages being compressed. This is only relevant when compressing.").
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("zstd", zstdProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newZstdProcFromConfig(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type zstdProc struct {
+	compress bool
+	level    zstd.EncoderLevel
+	cache    string
+
+	train        bool
+	trainSamples int
+	trainMaxSize int
+	trainID      uint32
+
+	mut      sync.Mutex
+	encoder  *zstd.Encoder
+	encDict  uint32
+	samples  [][]byte
+	dicts    map[uint32][]byte
+	decoders map[uint32]*zstd.Decoder
+
+	mgr *service.Resources
+}
+
+func newZstdProcFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (*zstdProc, error) {
+	p := &zstdProc{
+		dicts:    map[uint32][]byte{},
+		decoders: map[uint32]*zstd.Decoder{},
+		mgr:      mgr,
+	}
+
+	operator, err := conf.FieldString(zpFieldOperator)
+	if err != nil {
+		return nil, err
+	}
+	p.compress = operator == "compress"
+
+	level, err := conf.FieldInt(zpFieldLevel)
+	if err != nil {
+		return nil, err
+	}
+	p.level = zstd.EncoderLevelFromZstd(level)
+
+	if conf.Contains(zpFieldDictionaryCache) {
+		if p.cache, err = conf.FieldString(zpFieldDictionaryCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(p.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+		}
+	}
+
+	dictPaths, err := conf.FieldStringList(zpFieldDictionaryPaths)
+	if err != nil {
+		return nil, err
+	}
+	var encDict []byte
+	for _, path := range dictPaths {
+		dictBytes, err := ifs.ReadFile(mgr.FS(), path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dictionary: %w", err)
+		}
+		id, err := zstdDictionaryID(dictBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load dictionary '%v': %w", path, err)
+		}
+		p.dicts[id] = dictBytes
+		if encDict == nil {
+			encDict, p.encDict = dictBytes, id
+		}
+	}
+
+	tConf := conf.Namespace(zpFieldTrain)
+	if p.train, err = tConf.FieldBool("enabled"); err != nil {
+		return nil, err
+	}
+	if p.train && p.compress {
+		if encDict != nil {
+			return nil, errors.New("dictionary training cannot be enabled when compressing with a dictionary from dictionary_paths")
+		}
+		if p.trainSamples, err = tConf.FieldInt(zpFieldTrainSamples); err != nil {
+			return nil, err
+		}
+		if p.trainSamples < 1 {
+			return nil, errors.New("the number of training samples must be greater than zero")
+		}
+		maxSizeStr, err := tConf.FieldString(zpFieldTrainMaxSize)
+		if err != nil {
+			return nil, err
+		}
+		maxSize, err := humanize.ParseBytes(maxSizeStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse max_size: %w", err)
+		}
+		if maxSize < 8 || maxSize > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid max_size: %v", maxSizeStr)
+		}
+		p.trainMaxSize = int(maxSize)
+
+		trainID, err := tConf.FieldInt(zpFieldTrainID)
+		if err != nil {
+			return nil, err
+		}
+		if trainID != 0 && (trainID < zpMinDictionaryID || trainID > (1<<31)-1) {
+			return nil, fmt.Errorf("dictionary id %v is outside of the permitted range", trainID)
+		}
+		p.trainID = uint32(trainID)
+	} else {
+		p.train = false
+	}
+
+	if p.compress {
+		if p.encoder, err = p.newEncoder(encDict); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+func zstdDictionaryID(dict []byte) (uint32, error) {
+	d, err := zstd.InspectDictionary(dict)
+	if err != nil {
+		return 0, err
+	}
+	return d.ID(), nil
+}
+
+func (p *zstdProc) newEncoder(dict []byte) (*zstd.Encoder, error) {
+	opts := []zstd.EOption{zstd.WithEncoderLevel(p.level)}
+	if dict != nil {
+		opts = append(opts, zstd.WithEncoderDict(dict))
+	}
+	return zstd.NewWriter(nil, opts...)
+}
+
+// trainDictionary builds a dictionary from the collected samples, where the
+// content of the dictionary is made up of the most recent samples.
+func (p *zstdProc) trainDictionary() (dict []byte, id uint32, err error) {
+	// BuildDict panics when the samples are too small to produce enough
+	// sequences.
+	defer func() {
+		if r := recover(); r != nil {
+			dict, id, err = nil, 0, fmt.Errorf("insufficient samples: %v", r)
+		}
+	}()
+
+	start, size := len(p.samples), 0
+	for start > 0 && size < p.trainMaxSize {
+		start--
+		size += len(p.samples[start])
+	}
+	history := make([]byte, 0, size)
+	for _, s := range p.samples[start:] {
+		history = append(history, s...)
+	}
+	if len(history) > p.trainMaxSize {
+		history = history[len(history)-p.trainMaxSize:]
+	}
+
+	id = p.trainID
+	if id == 0 {
+		id = zpMinDictionaryID + crc32.ChecksumIEEE(history)%((1<<31)-zpMinDictionaryID)
+	}
+
+	dict, err = zstd.BuildDict(zstd.BuildDictOptions{
+		ID:       id,
+		Contents: p.samples,
+		History:  history,
+		Offsets:  [3]int{1, 4, 8},
+		Level:    p.level,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return dict, id, nil
+}
+
+// sample adds messages to the training samples, and trains a dictionary once
+// enough samples have been collected. Must be called with the mutex held.
+func (p *zstdProc) sample(ctx context.Context, batch service.MessageBatch) {
+	for _, msg := range batch {
+		if len(p.samples) >= p.trainSamples {
+			break
+		}
+		mBytes, err := msg.AsBytes()
+		if err != nil || len(mBytes) == 0 {
+			continue
+		}
+		p.samples = append(p.samples, append([]byte(nil), mBytes...))
+	}
+	if len(p.samples) < p.trainSamples {
+		return
+	}
+
+	// Only a single attempt is made at training, if it fails then compression
+	// continues without a dictionary.
+	p.train = false
+	defer func() {
+		p.samples = nil
+	}()
+
+	dict, id, err := p.trainDictionary()
+	if err != nil {
+		p.mgr.Logger().Errorf("Failed to train dictionary, continuing without a dictionary: %v", err)
+		return
+	}
+	enc, err := p.newEncoder(dict)
+	if err != nil {
+		p.mgr.Logger().Errorf("Failed to create encoder from trained dictionary, continuing without a dictionary: %v", err)
+		return
+	}
+
+	if p.cache != "" {
+		var setErr error
+		if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+			setErr = c.Set(ctx, strconv.FormatUint(uint64(id), 10), dict, nil)
+		}); err != nil {
+			setErr = err
+		}
+		if setErr != nil {
+			p.mgr.Logger().Errorf("Failed to store trained dictionary, continuing without a dictionary: %v", setErr)
+			return
+		}
+	}
+
+	p.mgr.Logger().Infof("Trained dictionary %v from %v samples", id, len(p.samples))
+	p.encoder, p.encDict = enc, id
+	p.dicts[id] = dict
+}
+
+// getDecoder returns a decoder for a given dictionary ID, obtaining the
+// dictionary from the cache if it isn't already known. Must be called with the
+// mutex held.
+func (p *zstdProc) getDecoder(ctx context.Context, id uint32) (*zstd.Decoder, error) {
+	if dec, exists := p.decoders[id]; exists {
+		return dec, nil
+	}
+
+	var opts []zstd.DOption
+	if id != 0 {
+		dict, exists := p.dicts[id]
+		if !exists {
+			if p.cache == "" {
+				return nil, fmt.Errorf("dictionary %v is unknown", id)
+			}
+			var getErr error
+			if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+				dict, getErr = c.Get(ctx, strconv.FormatUint(uint64(id), 10))
+			}); err != nil {
+				getErr = err
+			}
+			if getErr != nil {
+				return nil, fmt.Errorf("failed to obtain dictionary %v: %w", id, getErr)
+			}
+			p.dicts[id] = dict
+		}
+		opts = append(opts, zstd.WithDecoderDicts(dict))
+	}
+
+	dec, err := zstd.NewReader(nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+	p.decoders[id] = dec
+	return dec, nil
+}
+
+func (p *zstdProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.compress && p.train {
+		p.sample(ctx, batch)
+	}
+
+	batch = batch.Copy()
+	for _, msg := range batch {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			msg.SetError(err)
+			continue
+		}
+
+		if p.compress {
+			msg.SetBytes(p.encoder.EncodeAll(mBytes, nil))
+			if p.encDict != 0 {
+				msg.MetaSetMut(zpMetaDictionaryID, int64(p.encDict))
+			}
+			continue
+		}
+
+		var header zstd.Header
+		if err := header.Decode(mBytes); err != nil {
+			msg.SetError(fmt.Errorf("failed to read frame header: %w", err))
+			continue
+		}
+		dec, err := p.getDecoder(ctx, header.DictionaryID)
+		if err != nil {
+			msg.SetError(err)
+			continue
+		}
+		newBytes, err := dec.DecodeAll(mBytes, nil)
+		if err != nil {
+			msg.SetError(fmt.Errorf("failed to decompress message: %w", err))
+			continue
+		}
+		msg.SetBytes(newBytes)
+	}
+	return []service.MessageBatch{batch}, nil
+}
+
+func (p *zstdProc) Close(ctx context.Context) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.encoder != nil {
+		_ = p.encoder.Close()
+	}
+	for _, dec := range p.decoders {
+		dec.Close()
+	}
+	return nil
+}
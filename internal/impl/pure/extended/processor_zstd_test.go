@@ -0,0 +1,156 @@
+package extended
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testZstdProc(t testing.TB, confStr string, mgr *service.Resources) *zstdProc {
+	t.Helper()
+
+	pConf, err := zstdProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newZstdProcFromConfig(pConf, mgr)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func testZstdDocs(n int) (docs []string) {
+	for i := 0; i < n; i++ {
+		docs = append(docs, fmt.Sprintf(`{"id":"order-%v","customer":{"name":"customer %v","tier":"gold"},"status":"OPEN","items":[{"sku":"sku-%v","quantity":%v}]}`, i, i%7, i%13, i%5))
+	}
+	return
+}
+
+func zstdRoundTrip(t testing.TB, comp, decomp *zstdProc, docs []string) service.MessageBatch {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+
+	res, err := comp.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	compressed := res[0]
+
+	res, err = decomp.ProcessBatch(context.Background(), compressed)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], len(docs))
+
+	for i, msg := range res[0] {
+		require.NoError(t, msg.GetError())
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, docs[i], string(mBytes))
+	}
+	return compressed
+}
+
+func TestZstdProcTrainWithCache(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("dicts"))
+
+	comp := testZstdProc(t, `
+operator: compress
+dictionary_cache: dicts
+train:
+  enabled: true
+  samples: 500
+  id: 40000
+`, mgr)
+	decomp := testZstdProc(t, `
+operator: decompress
+dictionary_cache: dicts
+`, mgr)
+
+	docs := testZstdDocs(1000)
+
+	// The first batch is used for training and is therefore compressed with
+	// the trained dictionary.
+	compressed := zstdRoundTrip(t, comp, decomp, docs[:500])
+	for _, msg := range compressed {
+		v, exists := msg.MetaGetMut("zstd_dictionary_id")
+		require.True(t, exists)
+		assert.Equal(t, int64(40000), v)
+	}
+
+	require.NoError(t, mgr.AccessCache(context.Background(), "dicts", func(c service.Cache) {
+		dict, err := c.Get(context.Background(), strconv.Itoa(40000))
+		require.NoError(t, err)
+		id, err := zstdDictionaryID(dict)
+		require.NoError(t, err)
+		assert.Equal(t, uint32(40000), id)
+	}))
+
+	plain := testZstdProc(t, `
+operator: compress
+`, service.MockResources())
+
+	var dictSize, plainSize int
+	for _, d := range docs[500:] {
+		dictSize += len(comp.encoder.EncodeAll([]byte(d), nil))
+		plainSize += len(plain.encoder.EncodeAll([]byte(d), nil))
+	}
+	assert.Less(t, dictSize, plainSize)
+
+	_ = zstdRoundTrip(t, comp, decomp, docs[500:])
+}
+
+func TestZstdProcDictionaryPaths(t *testing.T) {
+	trainer := testZstdProc(t, `
+operator: compress
+train:
+  enabled: true
+  samples: 500
+`, service.MockResources())
+
+	docs := testZstdDocs(500)
+	for _, d := range docs {
+		trainer.samples = append(trainer.samples, []byte(d))
+	}
+	dict, id, err := trainer.trainDictionary()
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, id, uint32(zpMinDictionaryID))
+
+	dictPath := filepath.Join(t.TempDir(), "orders.dict")
+	require.NoError(t, os.WriteFile(dictPath, dict, 0o644))
+
+	comp := testZstdProc(t, `
+operator: compress
+dictionary_paths: [ `+dictPath+` ]
+`, service.MockResources())
+	decomp := testZstdProc(t, `
+operator: decompress
+dictionary_paths: [ `+dictPath+` ]
+`, service.MockResources())
+
+	_ = zstdRoundTrip(t, comp, decomp, docs)
+
+	// Decompressing without the dictionary fails.
+	res, err := comp.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(docs[0])),
+	})
+	require.NoError(t, err)
+
+	noDict := testZstdProc(t, `
+operator: decompress
+`, service.MockResources())
+	res, err = noDict.ProcessBatch(context.Background(), res[0])
+	require.NoError(t, err)
+	require.EqualError(t, res[0][0].GetError(), fmt.Sprintf("dictionary %v is unknown", id))
+}
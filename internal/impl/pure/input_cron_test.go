@@ -0,0 +1,127 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testCronInput(t testing.TB, res *service.Resources, confStr string) *cronInput {
+	t.Helper()
+
+	pConf, err := cronInputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newCronInputFromParsed(pConf, res)
+	require.NoError(t, err)
+	return i
+}
+
+func TestCronInputSchedule(t *testing.T) {
+	i := testCronInput(t, service.MockResources(), `
+schedule: '@every 1s'
+mapping: 'root.at = @cron_scheduled_time'
+`)
+	require.NoError(t, i.Connect(context.Background()))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*5)
+	defer done()
+
+	for j := 0; j < 2; j++ {
+		msg, ackFn, err := i.Read(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		scheduled, _ := msg.MetaGet("cron_scheduled_time")
+		catchUp, _ := msg.MetaGetMut("cron_catch_up")
+		assert.Equal(t, false, catchUp)
+
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, `{"at":"`+scheduled+`"}`, string(mBytes))
+	}
+}
+
+func TestCronInputTimezone(t *testing.T) {
+	i := testCronInput(t, service.MockResources(), `
+schedule: '0 30 9 * * *'
+timezone: America/New_York
+`)
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	next := i.schedule.Next(time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 1, 10, 9, 30, 0, 0, loc).Unix(), next.Unix())
+}
+
+func TestCronInputCatchUp(t *testing.T) {
+	res := service.MockResources(service.MockResourcesOptAddCache("checkpoints"))
+	confStr := `
+schedule: '0 0 * * * *'
+catch_up:
+  cache: checkpoints
+  max_ticks: 2
+`
+	ctx := context.Background()
+
+	// Without a stored tick nothing is caught up.
+	i := testCronInput(t, res, confStr)
+	i.nowFn = func() time.Time { return time.Date(2024, 1, 10, 12, 30, 0, 0, time.UTC) }
+	require.NoError(t, i.Connect(ctx))
+	assert.Empty(t, i.catchUps)
+
+	require.NoError(t, i.storeTick(ctx, time.Date(2024, 1, 10, 8, 0, 0, 0, time.UTC)))
+
+	// Ticks from 9 to 12 were missed, and only the last two are caught up.
+	i = testCronInput(t, res, confStr)
+	i.nowFn = func() time.Time { return time.Date(2024, 1, 10, 12, 30, 0, 0, time.UTC) }
+	require.NoError(t, i.Connect(ctx))
+
+	for _, exp := range []string{"2024-01-10T11:00:00Z", "2024-01-10T12:00:00Z"} {
+		msg, ackFn, err := i.Read(ctx)
+		require.NoError(t, err)
+
+		scheduled, _ := msg.MetaGet("cron_scheduled_time")
+		assert.Equal(t, exp, scheduled)
+		catchUp, _ := msg.MetaGetMut("cron_catch_up")
+		assert.Equal(t, true, catchUp)
+
+		require.NoError(t, ackFn(ctx, nil))
+	}
+
+	// Older ticks acknowledged out of order do not rewind the checkpoint.
+	require.NoError(t, i.storeTick(ctx, time.Date(2024, 1, 10, 10, 0, 0, 0, time.UTC)))
+
+	var stored []byte
+	require.NoError(t, res.AccessCache(ctx, "checkpoints", func(c service.Cache) {
+		stored, _ = c.Get(ctx, "cron_last_tick")
+	}))
+	assert.Equal(t, "2024-01-10T12:00:00Z", string(stored))
+}
+
+func TestCronInputBadConfig(t *testing.T) {
+	for _, confStr := range []string{
+		`schedule: 'not a schedule'`,
+		`schedule: 'TZ=UTC 0 * * * *'`,
+		`
+schedule: '@hourly'
+timezone: Nowhere/Special
+`,
+		`
+schedule: '@hourly'
+catch_up:
+  cache: nope
+`,
+	} {
+		pConf, err := cronInputSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newCronInputFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, confStr)
+	}
+}
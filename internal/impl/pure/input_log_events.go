@@ -0,0 +1,239 @@
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	leiFieldLevel         = "level"
+	leiFieldLabels        = "labels"
+	leiFieldExcludeLabels = "exclude_labels"
+	leiFieldBufferSize    = "buffer_size"
+)
+
+func logEventsInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Consumes the log events emitted by this Benthos process as messages.").
+		Description(`
+This input allows pipelines to report on the health of Benthos itself, such as forwarding errors to a chat channel or a SIEM, without the need to scrape the logs from stdout.
+
+Only log events of levels that are enabled by the [logger config](/docs/components/logger/about) are emitted, and therefore the `+"`level`"+` of this input should not be more verbose than the level of the logger.
+
+Each message is a JSON object of the form:
+
+`+"```json"+`
+{
+  "time": "2024-01-01T00:00:00.000000000Z",
+  "level": "ERROR",
+  "message": "Failed to send message to http_client: 503 Service Unavailable",
+  "fields": {
+    "label": "my_output",
+    "path": "root.output"
+  }
+}
+`+"```"+`
+
+### Avoiding Feedback Loops
+
+Logs emitted by the components that process these messages are consumed by this input as well, which could result in a loop where, for example, an output that fails to deliver log events logs errors that are themselves delivered. Log events from the component with the same label as this input are always ignored, and the labels of other components can be ignored with the `+"`exclude_labels`"+` field.
+
+Events are buffered up to the `+"`buffer_size`"+`, beyond which events are dropped rather than applying back pressure to the components that emit them, and the counter `+"`log_events_dropped`"+` is incremented.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- log_level
+- log_label
+- log_path
+`+"```"+``).
+		Fields(
+			service.NewStringEnumField(leiFieldLevel, log.Levels...).
+				Description("The minimum level of log events to consume.").
+				Default("WARN"),
+			service.NewStringListField(leiFieldLabels).
+				Description("An optional list of component labels, where only log events of components with these labels are consumed.").
+				Example([]string{"orders_output", "enrichment"}).
+				Default([]any{}),
+			service.NewStringListField(leiFieldExcludeLabels).
+				Description("A list of component labels of which log events are ignored.").
+				Example([]string{"slack_output"}).
+				Default([]any{}),
+			service.NewIntField(leiFieldBufferSize).
+				Description("The maximum number of log events to buffer before further events are dropped.").
+				Default(1000).
+				Advanced(),
+		).
+		Example("Errors to Slack", "Here we forward error logs emitted by any component to a Slack channel, where the output that delivers them is excluded in order to avoid a loop.", `
+input:
+  label: logs
+  log_events:
+    level: ERROR
+    exclude_labels: [ slack_alerts ]
+  processors:
+    - mapping: |
+        root.text = "Benthos %v error: %v".format(@log_label.or("unknown"), this.message)
+
+output:
+  label: slack_alerts
+  http_client:
+    url: ${SLACK_WEBHOOK_URL}
+    verb: POST
+`)
+}
+
+func init() {
+	err := service.RegisterInput("log_events", logEventsInputSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+		return newLogEventsInputFromParsed(conf, mgr)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type logEventsInput struct {
+	levels        map[string]struct{}
+	labels        map[string]struct{}
+	excludeLabels map[string]struct{}
+	bufferSize    int
+
+	sub      *log.EventSubscription
+	mDropped *service.MetricCounter
+}
+
+func newLogEventsInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*logEventsInput, error) {
+	l := &logEventsInput{
+		levels:        map[string]struct{}{},
+		labels:        map[string]struct{}{},
+		excludeLabels: map[string]struct{}{},
+		mDropped:      mgr.Metrics().NewCounter("log_events_dropped"),
+	}
+
+	minLevel, err := conf.FieldString(leiFieldLevel)
+	if err != nil {
+		return nil, err
+	}
+	for _, lvl := range log.Levels {
+		l.levels[lvl] = struct{}{}
+		if lvl == minLevel {
+			break
+		}
+	}
+
+	labels, err := conf.FieldStringList(leiFieldLabels)
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		l.labels[label] = struct{}{}
+	}
+
+	excludeLabels, err := conf.FieldStringList(leiFieldExcludeLabels)
+	if err != nil {
+		return nil, err
+	}
+	for _, label := range excludeLabels {
+		l.excludeLabels[label] = struct{}{}
+	}
+	if label := mgr.Label(); label != "" {
+		l.excludeLabels[label] = struct{}{}
+	}
+
+	if l.bufferSize, err = conf.FieldInt(leiFieldBufferSize); err != nil {
+		return nil, err
+	}
+	if l.bufferSize < 1 {
+		return nil, fmt.Errorf("%v must be greater than zero", leiFieldBufferSize)
+	}
+	return l, nil
+}
+
+func (l *logEventsInput) Connect(ctx context.Context) error {
+	if l.sub == nil {
+		l.sub = log.SubscribeEvents(l.bufferSize)
+	}
+	return nil
+}
+
+func (l *logEventsInput) accept(e log.Event) bool {
+	if _, exists := l.levels[e.Level]; !exists {
+		return false
+	}
+	label, _ := e.Fields["label"].(string)
+	if _, exists := l.excludeLabels[label]; exists {
+		return false
+	}
+	if len(l.labels) > 0 {
+		if _, exists := l.labels[label]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *logEventsInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	if l.sub == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for {
+		var e log.Event
+		select {
+		case e = <-l.sub.Events():
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if dropped := l.sub.Dropped(); dropped > 0 {
+			l.mDropped.Incr(dropped)
+		}
+		if !l.accept(e) {
+			continue
+		}
+
+		fields := make(map[string]any, len(e.Fields))
+		for k, v := range e.Fields {
+			if vErr, isErr := v.(error); isErr {
+				v = vErr.Error()
+			}
+			fields[k] = v
+		}
+		msgBytes, err := json.Marshal(map[string]any{
+			"time":    e.Time.Format(time.RFC3339Nano),
+			"level":   e.Level,
+			"message": e.Message,
+			"fields":  fields,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		msg := service.NewMessage(msgBytes)
+		msg.MetaSetMut("log_level", e.Level)
+		if label, ok := e.Fields["label"].(string); ok {
+			msg.MetaSetMut("log_label", label)
+		}
+		if path, ok := e.Fields["path"].(string); ok {
+			msg.MetaSetMut("log_path", path)
+		}
+		return msg, func(ctx context.Context, err error) error {
+			return nil
+		}, nil
+	}
+}
+
+func (l *logEventsInput) Close(ctx context.Context) error {
+	if l.sub != nil {
+		l.sub.Close()
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package pure
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/internal/log"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestLogEventsInput(t *testing.T) {
+	loggerConf := log.NewConfig()
+	loggerConf.LogLevel = "DEBUG"
+
+	var buf bytes.Buffer
+	logger, err := log.New(&buf, ifs.OS(), loggerConf)
+	require.NoError(t, err)
+
+	conf, err := logEventsInputSpec().ParseYAML(`
+level: INFO
+exclude_labels: [ bar ]
+`, nil)
+	require.NoError(t, err)
+
+	in, err := newLogEventsInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, in.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, in.Close(context.Background()))
+	})
+
+	logger.With("label", "foo", "path", "root.input").Error("first")
+	logger.With("label", "foo").Debug("too verbose")
+	logger.With("label", "bar").Error("excluded")
+	logger.Info("second")
+
+	msg, ackFn, err := in.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	structured, err := msg.AsStructured()
+	require.NoError(t, err)
+
+	obj := structured.(map[string]any)
+	assert.Equal(t, "ERROR", obj["level"])
+	assert.Equal(t, "first", obj["message"])
+	assert.Equal(t, map[string]any{"@service": "benthos", "label": "foo", "path": "root.input"}, obj["fields"])
+	assert.NotEmpty(t, obj["time"])
+
+	v, _ := msg.MetaGet("log_level")
+	assert.Equal(t, "ERROR", v)
+	v, _ = msg.MetaGet("log_label")
+	assert.Equal(t, "foo", v)
+	v, _ = msg.MetaGet("log_path")
+	assert.Equal(t, "root.input", v)
+
+	msg, _, err = in.Read(ctx)
+	require.NoError(t, err)
+
+	structured, err = msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "second", structured.(map[string]any)["message"])
+}
+
+func TestLogEventsInputLabels(t *testing.T) {
+	loggerConf := log.NewConfig()
+
+	var buf bytes.Buffer
+	logger, err := log.New(&buf, ifs.OS(), loggerConf)
+	require.NoError(t, err)
+
+	conf, err := logEventsInputSpec().ParseYAML(`labels: [ foo ]`, nil)
+	require.NoError(t, err)
+
+	in, err := newLogEventsInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, in.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, in.Close(context.Background()))
+	})
+
+	logger.With("label", "bar").Error("not included")
+	logger.Error("no label")
+	logger.With("label", "foo").Warn("included")
+
+	msg, _, err := in.Read(ctx)
+	require.NoError(t, err)
+
+	structured, err := msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "included", structured.(map[string]any)["message"])
+}
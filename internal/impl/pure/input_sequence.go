@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,6 +13,11 @@ import (
 
 	"github.com/Jeffail/shutdown"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/component/input"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -24,6 +30,10 @@ const (
 	siFieldShardedJoinIterations    = "iterations"
 	siFieldShardedJoinMergeStrategy = "merge_strategy"
 	siFieldShardedJoin              = "sharded_join"
+	siFieldCutoverCheck             = "check"
+	siFieldCutoverDedupeCache       = "dedupe_cache"
+	siFieldCutoverDedupeKey         = "dedupe_key"
+	siFieldCutover                  = "cutover"
 	siFieldInputs                   = "inputs"
 )
 
@@ -56,6 +66,27 @@ When configured the sequence of inputs will be consumed one or more times accord
 Each message must be structured (JSON or otherwise processed into a structured form) and the fields will be aggregated with those of other messages sharing the ID. At the end of each iteration the joined messages are flushed downstream before the next iteration begins, hence keeping memory usage limited.`).
 				Version("3.40.0").
 				Advanced(),
+			service.NewObjectField(siFieldCutover,
+				service.NewBloblangField(siFieldCutoverCheck).
+					Description("A [Bloblang query](/docs/guides/bloblang/about/) executed against each message consumed from any input other than the last, which should return a boolean value indicating whether the message lies beyond the cutover point. When the query returns `true` the message, and all messages consumed from the same input thereafter, are dropped and the sequence moves onto the next input.").
+					Examples(
+						`this.created_at.ts_unix() >= 1704067200`,
+						`@kafka_offset.number() >= 1500000`,
+					).
+					Optional(),
+				service.NewStringField(siFieldCutoverDedupeCache).
+					Description("An optional [`cache` resource](/docs/components/caches/about) used to deduplicate messages across the boundaries of the sequence. The keys of messages consumed from each input are written to the cache, and messages consumed from a later input with a key that was seen on a previous input are dropped.").
+					Default(""),
+				service.NewInterpolatedStringField(siFieldCutoverDedupeKey).
+					Description("An interpolated string yielding the key to deduplicate messages by, which must be set when `"+siFieldCutoverDedupeCache+"` is set.").
+					Examples(`${! @kafka_key }`, `${! this.id }`).
+					Default(""),
+			).
+				Description(`Provides a way to switch from one input of the sequence to the next at an explicit cutover point rather than waiting for the input to terminate, and to deduplicate messages that overlap across the boundary. This is useful for zero-gap migrations where a historical backfill is followed by a live source.
+
+Since the cache used for deduplication holds the keys of messages even when they fail to be delivered it should be configured with a TTL that comfortably covers the overlap between inputs. This option cannot be combined with a `+"`sharded_join`"+`.`).
+				Version("4.28.0").
+				Advanced(),
 			service.NewInputListField(siFieldInputs).
 				Description("An array of inputs to read from sequentially."),
 		).
@@ -73,6 +104,36 @@ input:
       - generate:
           count: 1
           mapping: 'root = {"status":"finished"}'
+`,
+		).
+		Example(
+			"Backfill Then Live",
+			"In this example historical events are read from S3 up until a cutover timestamp, at which point the input switches to consuming live events from Kafka. Events are deduplicated by their ID across the boundary so that any overlap between the two sources does not result in duplicates.",
+			`
+input:
+  sequence:
+    cutover:
+      check: this.timestamp.ts_unix() >= 1704067200
+      dedupe_cache: cutover_ids
+      dedupe_key: ${! this.id }
+    inputs:
+      - aws_s3:
+          bucket: TODO
+          prefix: events/
+          scanner:
+            lines: {}
+        processors:
+          - mapping: root = content().parse_json()
+      - kafka:
+          addresses: [ TODO ]
+          topics: [ events ]
+          consumer_group: foogroup
+          start_from_oldest: false
+
+cache_resources:
+  - label: cutover_ids
+    memory:
+      default_ttl: 24h
 `,
 		).
 		Example(
@@ -332,7 +393,8 @@ type sequenceInput struct {
 	remaining []sequenceTarget
 	spent     []sequenceTarget
 
-	joiner *messageJoiner
+	joiner  *messageJoiner
+	cutover *sequenceCutover
 
 	log *service.Logger
 
@@ -375,6 +437,12 @@ func newSequenceInputFromParsed(conf *service.ParsedConfig, res *service.Resourc
 	if rdr.joiner, err = shardedConfigFromParsed(conf.Namespace(siFieldShardedJoin)); err != nil {
 		return nil, fmt.Errorf("invalid sharded join config: %w", err)
 	}
+	if rdr.cutover, err = cutoverConfigFromParsed(conf.Namespace(siFieldCutover), res); err != nil {
+		return nil, fmt.Errorf("invalid cutover config: %w", err)
+	}
+	if rdr.joiner != nil && rdr.cutover != nil {
+		return nil, errors.New("a cutover cannot be combined with a sharded join")
+	}
 
 	if target, _, err := rdr.createNextTarget(); err != nil {
 		return nil, err
@@ -435,6 +503,108 @@ func shardedConfigFromParsed(conf *service.ParsedConfig) (*messageJoiner, error)
 	}, nil
 }
 
+func cutoverConfigFromParsed(conf *service.ParsedConfig, res *service.Resources) (*sequenceCutover, error) {
+	mgr := interop.UnwrapManagement(res)
+	c := &sequenceCutover{mgr: mgr}
+
+	var err error
+	if checkStr, _ := conf.FieldString(siFieldCutoverCheck); checkStr != "" {
+		if c.check, err = mgr.BloblEnvironment().NewMapping(checkStr); err != nil {
+			return nil, fmt.Errorf("failed to parse check query: %w", err)
+		}
+	}
+
+	if c.cacheName, err = conf.FieldString(siFieldCutoverDedupeCache); err != nil {
+		return nil, err
+	}
+	keyStr, err := conf.FieldString(siFieldCutoverDedupeKey)
+	if err != nil {
+		return nil, err
+	}
+	if c.cacheName != "" {
+		if keyStr == "" {
+			return nil, errors.New("a dedupe key must be specified when a dedupe cache is set")
+		}
+		if c.key, err = mgr.BloblEnvironment().NewField(keyStr); err != nil {
+			return nil, fmt.Errorf("failed to parse dedupe key expression: %v", err)
+		}
+		if !mgr.ProbeCache(c.cacheName) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", c.cacheName)
+		}
+	}
+
+	if c.check == nil && c.key == nil {
+		return nil, nil
+	}
+	return c, nil
+}
+
+//------------------------------------------------------------------------------
+
+type sequenceCutover struct {
+	mgr       bundle.NewManagement
+	check     *mapping.Executor
+	cacheName string
+	key       *field.Expression
+}
+
+// filter removes the messages of a batch, consumed from the input of the
+// sequence at a given index, that either lie beyond the cutover point of that
+// input or that were already consumed from a previous input. Returns the
+// remaining messages and a boolean indicating whether the cutover point was
+// reached.
+func (c *sequenceCutover) filter(ctx context.Context, index int, final bool, batch message.Batch) (message.Batch, bool) {
+	newBatch := make(message.Batch, 0, batch.Len())
+	for i, p := range batch {
+		if c.check != nil && !final {
+			beyond, err := c.check.QueryPart(i, batch)
+			if err != nil {
+				c.mgr.Logger().Error("Failed to execute cutover check query: %v\n", err)
+			} else if beyond {
+				return newBatch, true
+			}
+		}
+		if c.key != nil && c.isDuplicate(ctx, index, final, i, batch) {
+			continue
+		}
+		newBatch = append(newBatch, p)
+	}
+	return newBatch, false
+}
+
+func (c *sequenceCutover) isDuplicate(ctx context.Context, index int, final bool, i int, batch message.Batch) (duplicate bool) {
+	key, err := c.key.String(i, batch)
+	if err != nil {
+		c.mgr.Logger().Error("Dedupe key interpolation error: %v\n", err)
+		return false
+	}
+
+	if cerr := c.mgr.AccessCache(ctx, c.cacheName, func(ca cache.V1) {
+		var seenBytes []byte
+		if seenBytes, err = ca.Get(ctx, key); err == nil {
+			seenIndex, _ := strconv.Atoi(string(seenBytes))
+			if duplicate = seenIndex < index; duplicate {
+				return
+			}
+		} else if !errors.Is(err, component.ErrKeyNotFound) {
+			return
+		}
+
+		// Keys of the final input never need to be checked.
+		if !final {
+			err = ca.Set(ctx, key, []byte(strconv.Itoa(index)), nil)
+		} else {
+			err = nil
+		}
+	}); cerr != nil {
+		err = cerr
+	}
+	if err != nil {
+		c.mgr.Logger().Error("Cache error: %v\n", err)
+	}
+	return
+}
+
 //------------------------------------------------------------------------------
 
 func (r *sequenceInput) getTarget() (input.Streamed, bool) {
@@ -530,6 +700,7 @@ func (r *sequenceInput) loop() {
 	}()
 
 	target, finalInSequence := r.getTarget()
+	var cutoverReached bool
 
 runLoop:
 	for {
@@ -575,12 +746,38 @@ runLoop:
 		case tran, open = <-target.TransactionChan():
 			if !open {
 				target = nil
+				cutoverReached = false
 				continue runLoop
 			}
 		case <-r.shutSig.SoftStopChan():
 			return
 		}
 
+		if r.cutover != nil {
+			var filtered message.Batch
+			if !cutoverReached {
+				index := len(r.spent) - 1
+				if filtered, cutoverReached = r.cutover.filter(shutNowCtx, index, finalInSequence, tran.Payload); cutoverReached {
+					r.log.Infof("Reached the cutover point of sequence input %v, moving onto the next input.", index)
+					target.TriggerStopConsuming()
+				}
+			}
+			if len(filtered) == 0 {
+				// Messages beyond the cutover point are covered by the next
+				// input and therefore dropped.
+				if err := tran.Ack(shutNowCtx, nil); err != nil && shutNowCtx.Err() != nil {
+					return
+				}
+				continue runLoop
+			}
+			if len(filtered) < tran.Payload.Len() {
+				origTran := tran
+				tran = message.NewTransactionFunc(filtered, func(ctx context.Context, err error) error {
+					return origTran.Ack(ctx, err)
+				})
+			}
+		}
+
 		if r.joiner != nil {
 			r.joiner.Add(tran.Payload, finalInSequence, func(msg message.Batch) {
 				r.dispatchJoinedMessage(&shardJoinWG, msg)
@@ -490,3 +490,118 @@ sequence:
 	rdr.TriggerCloseNow()
 	assert.NoError(t, rdr.WaitForClose(ctx))
 }
+
+func TestSequenceCutover(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Minute)
+	defer done()
+
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	writeFiles(t, tmpDir, map[string]string{
+		"backfill": "1\n2\n3\n4\n5\n6",
+		"live":     "3\n4\n5\n6\n7\n8",
+	})
+
+	conf, err := testutil.InputFromYAML(fmt.Sprintf(`
+sequence:
+  cutover:
+    check: content().number() >= 5
+    dedupe_cache: foocache
+    dedupe_key: ${! content() }
+  inputs:
+    - file:
+        paths: [ "%v" ]
+    - file:
+        paths: [ "%v" ]
+`, filepath.Join(tmpDir, "backfill"), filepath.Join(tmpDir, "live")))
+	require.NoError(t, err)
+
+	mgr := mock.NewManager()
+	mgr.Caches["foocache"] = map[string]mock.CacheItem{}
+
+	rdr, err := mgr.NewInput(conf)
+	require.NoError(t, err)
+
+	exp, act := []string{
+		"1", "2", "3", "4", "5", "6", "7", "8",
+	}, []string{}
+
+consumeLoop:
+	for {
+		select {
+		case tran, open := <-rdr.TransactionChan():
+			if !open {
+				break consumeLoop
+			}
+			assert.Equal(t, 1, tran.Payload.Len())
+			act = append(act, string(tran.Payload.Get(0).AsBytes()))
+			require.NoError(t, tran.Ack(ctx, nil))
+		case <-time.After(time.Minute):
+			t.Fatalf("Failed to consume message after: %v", act)
+		}
+	}
+
+	assert.Equal(t, exp, act)
+
+	rdr.TriggerStopConsuming()
+	assert.NoError(t, rdr.WaitForClose(ctx))
+}
+
+func TestSequenceCutoverErrors(t *testing.T) {
+	for name, test := range map[string]struct {
+		conf        string
+		errContains string
+	}{
+		"dedupe without key": {
+			conf: `
+sequence:
+  cutover:
+    dedupe_cache: foocache
+  inputs:
+    - generate:
+        mapping: 'root = "foo"'
+`,
+			errContains: "a dedupe key must be specified",
+		},
+		"missing cache": {
+			conf: `
+sequence:
+  cutover:
+    dedupe_cache: barcache
+    dedupe_key: ${! content() }
+  inputs:
+    - generate:
+        mapping: 'root = "foo"'
+`,
+			errContains: "cache resource 'barcache' was not found",
+		},
+		"sharded join": {
+			conf: `
+sequence:
+  sharded_join:
+    type: full-outer
+    id_path: id
+  cutover:
+    check: 'true'
+  inputs:
+    - generate:
+        mapping: 'root = "foo"'
+`,
+			errContains: "cannot be combined with a sharded join",
+		},
+	} {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := testutil.InputFromYAML(test.conf)
+			require.NoError(t, err)
+
+			mgr := mock.NewManager()
+			mgr.Caches["foocache"] = map[string]mock.CacheItem{}
+
+			_, err = mgr.NewInput(conf)
+			require.ErrorContains(t, err, test.errContains)
+		})
+	}
+}
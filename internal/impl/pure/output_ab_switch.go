@@ -0,0 +1,293 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	absFieldStable         = "stable"
+	absFieldCanary         = "canary"
+	absFieldCanaryWeight   = "canary_weight"
+	absFieldBudget         = "budget"
+	absFieldWindow         = "window"
+	absFieldMinWrites      = "min_writes"
+	absFieldMaxErrorRate   = "max_error_rate"
+	absFieldMaxLatency     = "max_latency"
+	absFieldRecoveryPeriod = "recovery_period"
+	absFieldFallback       = "fallback"
+	absFieldMaxInFlight    = "max_in_flight"
+)
+
+func abSwitchOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Splits traffic between a stable and a canary output by weight, and automatically shifts all traffic back to the stable output when the canary exceeds an error or latency budget.").
+		Description(`
+Each batch is routed to the `+"`canary`"+` output with a probability of `+"`canary_weight`"+` percent, and to the `+"`stable`"+` output otherwise.
+
+### Error Budgets
+
+The writes to the canary output are measured over tumbling windows of the `+"`budget.window`"+` duration. Once at least `+"`budget.min_writes`"+` batches have been written within a window, the canary is rolled back if the ratio of failed writes exceeds `+"`budget.max_error_rate`"+` or the mean latency of writes exceeds `+"`budget.max_latency`"+`. Once rolled back all traffic is routed to the stable output.
+
+When a `+"`recovery_period`"+` is set the canary receives traffic again after that period has passed since it was rolled back, otherwise it remains rolled back until Benthos is restarted.
+
+When `+"`fallback`"+` is enabled batches that fail to be written to the canary are written to the stable output instead, and therefore canary failures do not result in messages being nacked.
+
+### Transitions
+
+Each transition is logged, and the counter `+"`ab_switch_transition`"+` is incremented with the label `+"`state`"+` set to either `+"`rolled_back` or `recovered`"+`. The gauge `+"`ab_switch_canary_active`"+` is set to 1 whilst the canary receives traffic and 0 whilst it is rolled back.`).
+		Example(
+			"Gradual Rollout",
+			"Here we route 5% of traffic to a new HTTP endpoint and roll it back if more than 1% of writes fail or writes take longer than 500ms on average, trying again after an hour.",
+			`
+output:
+  ab_switch:
+    canary_weight: 5
+    budget:
+      window: 1m
+      min_writes: 50
+      max_error_rate: 0.01
+      max_latency: 500ms
+    recovery_period: 1h
+    stable:
+      http_client:
+        url: http://api-v1.example.com/events
+    canary:
+      http_client:
+        url: http://api-v2.example.com/events
+`,
+		).
+		Fields(
+			service.NewOutputField(absFieldStable).
+				Description("The stable output, which receives all traffic that is not routed to the canary."),
+			service.NewOutputField(absFieldCanary).
+				Description("The canary output."),
+			service.NewFloatField(absFieldCanaryWeight).
+				Description("The percentage of batches, from 0 to 100, to route to the canary output.").
+				Default(10.0),
+			service.NewObjectField(absFieldBudget,
+				service.NewDurationField(absFieldWindow).
+					Description("The duration of the tumbling window over which writes to the canary are measured.").
+					Default("1m"),
+				service.NewIntField(absFieldMinWrites).
+					Description("The minimum number of writes to the canary within a window before the budget is evaluated.").
+					Default(10),
+				service.NewFloatField(absFieldMaxErrorRate).
+					Description("The maximum ratio of failed writes to the canary, from 0 to 1, within a window.").
+					Default(0.05),
+				service.NewDurationField(absFieldMaxLatency).
+					Description("An optional maximum mean latency of writes to the canary within a window.").
+					Optional().
+					Example("500ms"),
+			).
+				Description("The error and latency budget of the canary output."),
+			service.NewDurationField(absFieldRecoveryPeriod).
+				Description("An optional period after which a rolled back canary receives traffic again.").
+				Optional().
+				Example("1h"),
+			service.NewBoolField(absFieldFallback).
+				Description("Whether batches that fail to be written to the canary should be written to the stable output instead.").
+				Default(true),
+			service.NewIntField(absFieldMaxInFlight).
+				Description("The maximum number of batches to have in flight at a given time.").
+				Default(64),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("ab_switch", abSwitchOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(absFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newABSwitchOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type abSwitchOutput struct {
+	stable         batchWriteCloser
+	canary         batchWriteCloser
+	weight         float64
+	window         time.Duration
+	minWrites      int
+	maxErrorRate   float64
+	maxLatency     time.Duration
+	recoveryPeriod time.Duration
+	fallback       bool
+	log            *service.Logger
+	randFn         func() float64
+	nowFn          func() time.Time
+
+	mut          sync.Mutex
+	rolledBack   bool
+	rolledBackAt time.Time
+	windowStart  time.Time
+	writes       int
+	failures     int
+	latency      time.Duration
+
+	mTransition *service.MetricCounter
+	mActive     *service.MetricGauge
+}
+
+func newABSwitchOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*abSwitchOutput, error) {
+	a := &abSwitchOutput{
+		log:         mgr.Logger(),
+		randFn:      rand.Float64,
+		nowFn:       time.Now,
+		mTransition: mgr.Metrics().NewCounter("ab_switch_transition", "state"),
+		mActive:     mgr.Metrics().NewGauge("ab_switch_canary_active"),
+	}
+
+	var err error
+	if a.weight, err = conf.FieldFloat(absFieldCanaryWeight); err != nil {
+		return nil, err
+	}
+	if a.weight < 0 || a.weight > 100 {
+		return nil, errors.New("canary_weight must be between 0 and 100")
+	}
+	if a.window, err = conf.FieldDuration(absFieldBudget, absFieldWindow); err != nil {
+		return nil, err
+	}
+	if a.window <= 0 {
+		return nil, errors.New("budget.window must be greater than zero")
+	}
+	if a.minWrites, err = conf.FieldInt(absFieldBudget, absFieldMinWrites); err != nil {
+		return nil, err
+	}
+	if a.maxErrorRate, err = conf.FieldFloat(absFieldBudget, absFieldMaxErrorRate); err != nil {
+		return nil, err
+	}
+	if a.maxErrorRate < 0 || a.maxErrorRate > 1 {
+		return nil, errors.New("budget.max_error_rate must be between 0 and 1")
+	}
+	if conf.Contains(absFieldBudget, absFieldMaxLatency) {
+		if a.maxLatency, err = conf.FieldDuration(absFieldBudget, absFieldMaxLatency); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(absFieldRecoveryPeriod) {
+		if a.recoveryPeriod, err = conf.FieldDuration(absFieldRecoveryPeriod); err != nil {
+			return nil, err
+		}
+	}
+	if a.fallback, err = conf.FieldBool(absFieldFallback); err != nil {
+		return nil, err
+	}
+
+	if a.stable, err = conf.FieldOutput(absFieldStable); err != nil {
+		return nil, err
+	}
+	if a.canary, err = conf.FieldOutput(absFieldCanary); err != nil {
+		return nil, err
+	}
+
+	a.mActive.Set(1)
+	return a, nil
+}
+
+func (a *abSwitchOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// useCanary returns whether the next batch should be routed to the canary,
+// recovering the canary first if its recovery period has passed.
+func (a *abSwitchOutput) useCanary() bool {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.rolledBack {
+		if a.recoveryPeriod <= 0 || a.nowFn().Sub(a.rolledBackAt) < a.recoveryPeriod {
+			return false
+		}
+		a.rolledBack = false
+		a.resetWindow(a.nowFn())
+		a.log.Infof("Canary output recovered after %v, resuming traffic at %v%%", a.recoveryPeriod, a.weight)
+		a.mTransition.Incr(1, "recovered")
+		a.mActive.Set(1)
+	}
+	return a.weight > 0 && a.randFn()*100 < a.weight
+}
+
+func (a *abSwitchOutput) resetWindow(now time.Time) {
+	a.windowStart = now
+	a.writes, a.failures, a.latency = 0, 0, 0
+}
+
+// record adds the outcome of a write to the canary to the current window and
+// rolls the canary back if the budget has been exceeded.
+func (a *abSwitchOutput) record(latency time.Duration, failed bool) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if a.rolledBack {
+		return
+	}
+
+	now := a.nowFn()
+	if a.windowStart.IsZero() || now.Sub(a.windowStart) >= a.window {
+		a.resetWindow(now)
+	}
+	a.writes++
+	a.latency += latency
+	if failed {
+		a.failures++
+	}
+	if a.writes < a.minWrites {
+		return
+	}
+
+	if errRate := float64(a.failures) / float64(a.writes); errRate > a.maxErrorRate {
+		a.log.Warnf("Rolling back canary output as the error rate %.3f exceeds the budget of %v", errRate, a.maxErrorRate)
+	} else if meanLatency := a.latency / time.Duration(a.writes); a.maxLatency > 0 && meanLatency > a.maxLatency {
+		a.log.Warnf("Rolling back canary output as the mean latency %v exceeds the budget of %v", meanLatency, a.maxLatency)
+	} else {
+		return
+	}
+
+	a.rolledBack = true
+	a.rolledBackAt = now
+	a.mTransition.Incr(1, "rolled_back")
+	a.mActive.Set(0)
+}
+
+func (a *abSwitchOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	if !a.useCanary() {
+		return a.stable.WriteBatch(ctx, batch)
+	}
+
+	var fallbackBatch service.MessageBatch
+	if a.fallback {
+		fallbackBatch = batch.Copy()
+	}
+
+	start := time.Now()
+	err := a.canary.WriteBatch(ctx, batch)
+	a.record(time.Since(start), err != nil)
+	if err == nil || !a.fallback {
+		return err
+	}
+
+	a.log.Debugf("Failed to write to canary output, falling back to stable: %v", err)
+	return a.stable.WriteBatch(ctx, fallbackBatch)
+}
+
+func (a *abSwitchOutput) Close(ctx context.Context) error {
+	canaryErr := a.canary.Close(ctx)
+	if err := a.stable.Close(ctx); err != nil {
+		return err
+	}
+	return canaryErr
+}
@@ -0,0 +1,154 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testABSwitchOutput(t testing.TB, confStr string) (a *abSwitchOutput, stable, canary *fakeBatchWriter, now *time.Time) {
+	t.Helper()
+
+	pConf, err := abSwitchOutputSpec().ParseYAML(confStr+`
+stable:
+  drop: {}
+canary:
+  drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	a, err = newABSwitchOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	stable, canary = &fakeBatchWriter{}, &fakeBatchWriter{}
+	a.stable, a.canary = stable, canary
+
+	tNow := time.Unix(1000, 0)
+	now = &tNow
+	a.nowFn = func() time.Time {
+		return *now
+	}
+	return
+}
+
+func TestABSwitchOutputWeight(t *testing.T) {
+	a, stable, canary, _ := testABSwitchOutput(t, `canary_weight: 25`)
+
+	rolls := []float64{0.1, 0.3, 0.24, 0.9}
+	a.randFn = func() float64 {
+		r := rolls[0]
+		rolls = rolls[1:]
+		return r
+	}
+
+	for _, c := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, a.WriteBatch(context.Background(), shadowBatch(c)))
+	}
+	require.NoError(t, a.Close(context.Background()))
+
+	assert.Equal(t, []string{"b", "d"}, stable.getWritten())
+	assert.Equal(t, []string{"a", "c"}, canary.getWritten())
+	assert.True(t, stable.closed)
+	assert.True(t, canary.closed)
+}
+
+func TestABSwitchOutputRollback(t *testing.T) {
+	a, stable, canary, now := testABSwitchOutput(t, `
+canary_weight: 100
+budget:
+  window: 1m
+  min_writes: 4
+  max_error_rate: 0.3
+recovery_period: 1h
+`)
+
+	// Writes that fail are written to the stable output instead.
+	canary.err = errors.New("nope")
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("a")))
+	canary.err = nil
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("b")))
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("c")))
+
+	// A new window begins and therefore the previous failure is forgotten.
+	*now = now.Add(time.Minute)
+	canary.err = errors.New("nope")
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("d")))
+	canary.err = nil
+	for _, c := range []string{"e", "f", "g"} {
+		require.NoError(t, a.WriteBatch(context.Background(), shadowBatch(c)))
+	}
+	assert.False(t, a.rolledBack)
+
+	// Two failures out of five exceeds the budget.
+	canary.err = errors.New("nope")
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("h")))
+	assert.True(t, a.rolledBack)
+
+	canary.err = nil
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("i")))
+
+	// The canary is recovered after the recovery period.
+	*now = now.Add(time.Hour)
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("j")))
+	assert.False(t, a.rolledBack)
+
+	assert.Equal(t, []string{"a", "d", "h", "i"}, stable.getWritten())
+	assert.Equal(t, []string{"b", "c", "e", "f", "g", "j"}, canary.getWritten())
+}
+
+func TestABSwitchOutputLatencyRollback(t *testing.T) {
+	a, stable, canary, _ := testABSwitchOutput(t, `
+canary_weight: 100
+fallback: false
+budget:
+  min_writes: 2
+  max_latency: 10ms
+`)
+
+	canary.block = make(chan struct{})
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		close(canary.block)
+	}()
+
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("a")))
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("b")))
+	assert.True(t, a.rolledBack)
+
+	canary.err = errors.New("nope")
+	require.NoError(t, a.WriteBatch(context.Background(), shadowBatch("c")))
+
+	assert.Equal(t, []string{"c"}, stable.getWritten())
+	assert.Equal(t, []string{"a", "b"}, canary.getWritten())
+}
+
+func TestABSwitchOutputNoFallback(t *testing.T) {
+	a, stable, canary, _ := testABSwitchOutput(t, `
+canary_weight: 100
+fallback: false
+`)
+
+	canary.err = errors.New("nope")
+	require.EqualError(t, a.WriteBatch(context.Background(), shadowBatch("a")), "nope")
+	assert.Empty(t, stable.getWritten())
+}
+
+func TestABSwitchOutputConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		"canary_weight: 101",
+		"budget: { max_error_rate: 2 }",
+		"budget: { window: 0s }",
+	} {
+		pConf, err := abSwitchOutputSpec().ParseYAML(confStr+"\nstable: { drop: {} }\ncanary: { drop: {} }", nil)
+		require.NoError(t, err)
+
+		_, err = newABSwitchOutputFromParsed(pConf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
@@ -0,0 +1,283 @@
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// rejectingBatchWriter fails the messages of each write whose contents are
+// listed, and records the contents of each attempt.
+type rejectingBatchWriter struct {
+	mut      sync.Mutex
+	reject   map[string]error
+	attempts [][]string
+}
+
+func (r *rejectingBatchWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	var contents []string
+	var bErr *service.BatchError
+	for i, m := range b {
+		mBytes, _ := m.AsBytes()
+		contents = append(contents, string(mBytes))
+		if err, exists := r.reject[string(mBytes)]; exists {
+			if bErr == nil {
+				bErr = service.NewBatchError(b, err)
+			}
+			bErr.Failed(i, err)
+		}
+	}
+	r.attempts = append(r.attempts, contents)
+	if bErr != nil {
+		return bErr
+	}
+	return nil
+}
+
+func (r *rejectingBatchWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+type capturingBatchWriter struct {
+	mut     sync.Mutex
+	written service.MessageBatch
+	err     error
+}
+
+func (c *capturingBatchWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.written = append(c.written, b...)
+	return nil
+}
+
+func (c *capturingBatchWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+func testDeadLetterOutput(t testing.TB, confStr string) *deadLetterOutput {
+	t.Helper()
+
+	pConf, err := deadLetterOutputSpec().ParseYAML(confStr+`
+output:
+  label: foo_api
+  drop: {}
+dlq:
+  drop: {}
+backoff:
+  initial_interval: 1ms
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	d, err := newDeadLetterOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	d.nowFn = func() time.Time {
+		return time.Unix(1000, 0).UTC()
+	}
+	return d
+}
+
+func TestDeadLetterOutputRetriesFailedMessages(t *testing.T) {
+	d := testDeadLetterOutput(t, `max_retries: 2`)
+
+	primary := &rejectingBatchWriter{reject: map[string]error{
+		"b": errors.New("nope"),
+	}}
+	dlq := &capturingBatchWriter{}
+	d.primary, d.dlq = primary, dlq
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("a")),
+		service.NewMessage([]byte("b")),
+		service.NewMessage([]byte("c")),
+	}
+	batch[1].MetaSetMut("kafka_topic", "orders")
+	require.NoError(t, d.WriteBatch(context.Background(), batch))
+
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"b"}, {"b"}}, primary.attempts)
+
+	require.Len(t, dlq.written, 1)
+	msg := dlq.written[0]
+
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(mBytes))
+
+	for k, exp := range map[string]string{
+		"dlq_error":          "nope",
+		"dlq_component":      "foo_api",
+		"dlq_attempts":       "3",
+		"dlq_original_topic": "orders",
+		"dlq_failed_at":      "1970-01-01T00:16:40Z",
+		"kafka_topic":        "orders",
+	} {
+		v, _ := msg.MetaGet(k)
+		assert.Equal(t, exp, v, k)
+	}
+
+	// The original batch is not modified.
+	_, exists := batch[1].MetaGet("dlq_error")
+	assert.False(t, exists)
+}
+
+func TestDeadLetterOutputRecovers(t *testing.T) {
+	d := testDeadLetterOutput(t, `max_retries: 3`)
+
+	var calls int
+	primary := &rejectingBatchWriter{reject: map[string]error{
+		"a": errors.New("nope"),
+	}}
+	dlq := &capturingBatchWriter{}
+	d.primary, d.dlq = batchWriteCloserFunc(func(ctx context.Context, b service.MessageBatch) error {
+		if calls++; calls == 2 {
+			delete(primary.reject, "a")
+		}
+		return primary.WriteBatch(ctx, b)
+	}), dlq
+
+	require.NoError(t, d.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("a")),
+	}))
+	assert.Equal(t, [][]string{{"a"}, {"a"}}, primary.attempts)
+	assert.Empty(t, dlq.written)
+}
+
+func TestDeadLetterOutputDLQFailure(t *testing.T) {
+	d := testDeadLetterOutput(t, `max_retries: 1`)
+
+	d.primary = &rejectingBatchWriter{reject: map[string]error{
+		"a": errors.New("nope"),
+	}}
+	d.dlq = &capturingBatchWriter{err: errors.New("dlq down")}
+
+	err := d.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("a")),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dlq down")
+}
+
+func TestDeadLetterOutputCloudEventsEnvelope(t *testing.T) {
+	d := testDeadLetterOutput(t, `
+max_retries: 1
+envelope: cloudevents
+original_topic: ${! @source }
+`)
+
+	d.primary = &rejectingBatchWriter{reject: map[string]error{
+		`{"id":"1"}`: errors.New("nope"),
+		"raw":        errors.New("nope"),
+	}}
+	dlq := &capturingBatchWriter{}
+	d.dlq = dlq
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"1"}`)),
+		service.NewMessage([]byte("raw")),
+	}
+	batch[0].MetaSetMut("source", "orders")
+	require.NoError(t, d.WriteBatch(context.Background(), batch))
+	require.Len(t, dlq.written, 2)
+
+	var event map[string]any
+	mBytes, err := dlq.written[0].AsBytes()
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(mBytes, &event))
+
+	assert.NotEmpty(t, event["id"])
+	delete(event, "id")
+	assert.Equal(t, map[string]any{
+		"specversion":      "1.0",
+		"source":           "benthos/foo_api",
+		"type":             "com.benthos.dead_letter",
+		"time":             "1970-01-01T00:16:40Z",
+		"datacontenttype":  "application/json",
+		"data":             map[string]any{"id": "1"},
+		"dlqerror":         "nope",
+		"dlqcomponent":     "foo_api",
+		"dlqattempts":      2.0,
+		"dlqoriginaltopic": "orders",
+	}, event)
+
+	mBytes, err = dlq.written[1].AsBytes()
+	require.NoError(t, err)
+	event = nil
+	require.NoError(t, json.Unmarshal(mBytes, &event))
+	assert.Equal(t, "cmF3", event["data_base64"])
+	assert.Equal(t, "application/octet-stream", event["datacontenttype"])
+}
+
+func TestDeadLetterOutputMappingEnvelope(t *testing.T) {
+	d := testDeadLetterOutput(t, `
+max_retries: 1
+envelope: mapping
+envelope_mapping: |
+  root.payload = content().string()
+  root.reason = @dlq_error
+  root.attempts = @dlq_attempts.number()
+`)
+
+	d.primary = &rejectingBatchWriter{reject: map[string]error{
+		"a": errors.New("nope"),
+	}}
+	dlq := &capturingBatchWriter{}
+	d.dlq = dlq
+
+	require.NoError(t, d.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("a")),
+	}))
+	require.Len(t, dlq.written, 1)
+
+	mBytes, err := dlq.written[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"payload":"a","reason":"nope","attempts":2}`, string(mBytes))
+}
+
+func TestDeadLetterOutputConfigErrors(t *testing.T) {
+	pConf, err := deadLetterOutputSpec().ParseYAML(`
+envelope: mapping
+output:
+  drop: {}
+dlq:
+  drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newDeadLetterOutputFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "envelope_mapping")
+}
+
+func TestDeadLetterComponentName(t *testing.T) {
+	assert.Equal(t, "foo", dlqComponentName(map[string]any{"label": "foo", "drop": map[string]any{}}))
+	assert.Equal(t, "http_client", dlqComponentName(map[string]any{
+		"http_client": map[string]any{},
+		"processors":  []any{},
+	}))
+}
+
+type batchWriteCloserFunc func(ctx context.Context, b service.MessageBatch) error
+
+func (f batchWriteCloserFunc) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	return f(ctx, b)
+}
+
+func (f batchWriteCloserFunc) Close(ctx context.Context) error {
+	return nil
+}
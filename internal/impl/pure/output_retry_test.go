@@ -326,6 +326,98 @@ retry:
 	require.NoError(t, output.WaitForClose(ctx))
 }
 
+func TestRetryOrdered(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	conf := parseYAMLOutputConf(t, `
+retry:
+  output:
+    drop: {}
+  ordered_delivery: true
+  backoff:
+    initial_interval: 10us
+    max_interval: 10us
+`)
+
+	output, err := bundle.AllOutputs.Init(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	ret, ok := output.(*indefiniteRetry)
+	require.True(t, ok)
+
+	mOut := &mock.OutputChanneled{}
+	ret.wrapped = mOut
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, ret.Consume(tChan))
+
+	resChan1, resChan2 := make(chan error), make(chan error)
+	sendForRetry("first", tChan, resChan1, t)
+	sendForRetry("second", tChan, resChan2, t)
+
+	// The second batch is held back until the first is delivered, including
+	// retries of the first.
+	expectFromRetry(component.ErrFailedSend, mOut.TChan, t, "first")
+	expectFromRetry(component.ErrFailedSend, mOut.TChan, t, "first")
+	expectFromRetry(nil, mOut.TChan, t, "first")
+	ackForRetry(nil, resChan1, t)
+
+	expectFromRetry(nil, mOut.TChan, t, "second")
+	ackForRetry(nil, resChan2, t)
+
+	output.TriggerCloseNow()
+	require.NoError(t, output.WaitForClose(ctx))
+}
+
+func TestRetryOrderedByKey(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	conf := parseYAMLOutputConf(t, `
+retry:
+  output:
+    drop: {}
+  ordered_delivery: true
+  ordering_key: ${! content().string().slice(0, 1) }
+  backoff:
+    initial_interval: 10us
+    max_interval: 10us
+`)
+
+	output, err := bundle.AllOutputs.Init(conf, mock.NewManager())
+	require.NoError(t, err)
+
+	ret, ok := output.(*indefiniteRetry)
+	require.True(t, ok)
+
+	mOut := &mock.OutputChanneled{}
+	ret.wrapped = mOut
+
+	tChan := make(chan message.Transaction)
+	require.NoError(t, ret.Consume(tChan))
+
+	resChanA1, resChanA2, resChanB1 := make(chan error), make(chan error), make(chan error)
+	sendForRetry("a1", tChan, resChanA1, t)
+	expectFromRetry(component.ErrFailedSend, mOut.TChan, t, "a1")
+
+	// Batches of other keys are delivered while a1 is being retried, but a2 is
+	// held back.
+	sendForRetry("a2", tChan, resChanA2, t)
+	sendForRetry("b1", tChan, resChanB1, t)
+	expectFromRetry(component.ErrFailedSend, mOut.TChan, t, "a1", "b1")
+
+	expectFromRetry(nil, mOut.TChan, t, "a1", "b1")
+	ackForRetry(nil, resChanB1, t)
+	ackForRetry(nil, resChanA1, t)
+
+	expectFromRetry(nil, mOut.TChan, t, "a2")
+	ackForRetry(nil, resChanA2, t)
+
+	output.TriggerCloseNow()
+	require.NoError(t, output.WaitForClose(ctx))
+}
+
 func TestRetryMutations(t *testing.T) {
 	mockOutput := &mock.OutputChanneled{}
 
@@ -0,0 +1,277 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	shoFieldOutput            = "output"
+	shoFieldShadow            = "shadow"
+	shoFieldPercentage        = "percentage"
+	shoFieldCheck             = "check"
+	shoFieldShadowMaxInFlight = "shadow_max_in_flight"
+	shoFieldMaxInFlight       = "max_in_flight"
+)
+
+func shadowOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Writes messages to a child output and duplicates a portion of them to a shadow output, where the outcome of writes to the shadow output never affect the acknowledgement of messages.").
+		Description(`
+This output is useful for validating a new sink against production traffic without risking the delivery of that traffic. Messages are acknowledged according to the result of the primary `+"`output`"+` only, and copies of the messages are written to the `+"`shadow`"+` output in parallel.
+
+The traffic that is duplicated can be restricted to a random `+"`percentage`"+` of messages and/or to messages that pass a Bloblang `+"`check`"+`. When both are set a percentage of the messages that pass the check are duplicated.
+
+The shadow output never applies back pressure to the primary output. When the number of shadow writes in flight reaches `+"`shadow_max_in_flight`"+`, for example because the shadow output is slow or has lost its connection, further copies are dropped until writes complete.
+
+### Metrics
+
+The following metrics are emitted in order to compare the shadow output with the primary output:
+
+- `+"`shadow_sent`"+`: A counter of messages successfully written to the shadow output.
+- `+"`shadow_error`"+`: A counter of messages that failed to be written to the shadow output.
+- `+"`shadow_dropped`"+`: A counter of messages that were not duplicated due to `+"`shadow_max_in_flight`"+`.
+- `+"`shadow_mismatch`"+`: A counter of duplicated batches where only one of the outputs failed, labelled by the `+"`failed`"+` output (`+"`primary` or `shadow`"+`).
+- `+"`shadow_latency_ns`"+`: A timer of the writes of duplicated batches, labelled by the `+"`output`"+` written to (`+"`primary` or `shadow`"+`).`).
+		Example(
+			"Validating a Migration",
+			"Here we continue writing to our existing Kafka cluster whilst sending 10% of the traffic to a new cluster, where we can compare error rates and latencies before switching over.",
+			`
+output:
+  shadow:
+    percentage: 10
+    output:
+      kafka_franz:
+        seed_brokers: [ old-cluster:9092 ]
+        topic: events
+    shadow:
+      kafka_franz:
+        seed_brokers: [ new-cluster:9092 ]
+        topic: events
+`,
+		).
+		Fields(
+			service.NewOutputField(shoFieldOutput).
+				Description("The primary output, which determines whether messages are acknowledged."),
+			service.NewOutputField(shoFieldShadow).
+				Description("The shadow output, which receives copies of messages."),
+			service.NewFloatField(shoFieldPercentage).
+				Description("The percentage of messages, from 0 to 100, to randomly select for duplication to the shadow output.").
+				Default(100.0),
+			service.NewBloblangField(shoFieldCheck).
+				Description("An optional Bloblang query that should return a boolean value indicating whether a message should be duplicated to the shadow output.").
+				Example(`this.type == "order"`).
+				Optional(),
+			service.NewIntField(shoFieldShadowMaxInFlight).
+				Description("The maximum number of batches to have in flight to the shadow output at a given time, copies beyond this limit are dropped.").
+				Default(64).
+				Advanced(),
+			service.NewIntField(shoFieldMaxInFlight).
+				Description("The maximum number of batches to have in flight to the primary output at a given time.").
+				Default(64),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("shadow", shadowOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(shoFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newShadowOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type batchWriteCloser interface {
+	WriteBatch(ctx context.Context, b service.MessageBatch) error
+	Close(ctx context.Context) error
+}
+
+type shadowOutput struct {
+	primary    batchWriteCloser
+	shadow     batchWriteCloser
+	percentage float64
+	check      *bloblang.Executor
+	log        *service.Logger
+	randFn     func() float64
+
+	pending      chan struct{}
+	pendingWG    sync.WaitGroup
+	shadowCtx    context.Context
+	shadowCancel context.CancelFunc
+
+	mSent     *service.MetricCounter
+	mError    *service.MetricCounter
+	mDropped  *service.MetricCounter
+	mMismatch *service.MetricCounter
+	mLatency  *service.MetricTimer
+}
+
+func newShadowOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*shadowOutput, error) {
+	s := &shadowOutput{
+		log:       mgr.Logger(),
+		randFn:    rand.Float64,
+		mSent:     mgr.Metrics().NewCounter("shadow_sent"),
+		mError:    mgr.Metrics().NewCounter("shadow_error"),
+		mDropped:  mgr.Metrics().NewCounter("shadow_dropped"),
+		mMismatch: mgr.Metrics().NewCounter("shadow_mismatch", "failed"),
+		mLatency:  mgr.Metrics().NewTimer("shadow_latency_ns", "output"),
+	}
+
+	var err error
+	if s.percentage, err = conf.FieldFloat(shoFieldPercentage); err != nil {
+		return nil, err
+	}
+	if s.percentage < 0 || s.percentage > 100 {
+		return nil, errors.New("percentage must be between 0 and 100")
+	}
+	if conf.Contains(shoFieldCheck) {
+		if s.check, err = conf.FieldBloblang(shoFieldCheck); err != nil {
+			return nil, err
+		}
+	}
+
+	maxPending, err := conf.FieldInt(shoFieldShadowMaxInFlight)
+	if err != nil {
+		return nil, err
+	}
+	if maxPending < 1 {
+		return nil, errors.New("shadow_max_in_flight must be greater than zero")
+	}
+	s.pending = make(chan struct{}, maxPending)
+
+	if s.primary, err = conf.FieldOutput(shoFieldOutput); err != nil {
+		return nil, err
+	}
+	if s.shadow, err = conf.FieldOutput(shoFieldShadow); err != nil {
+		return nil, err
+	}
+
+	s.shadowCtx, s.shadowCancel = context.WithCancel(context.Background())
+	return s, nil
+}
+
+func (s *shadowOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// selectShadow returns copies of the messages of a batch that should be
+// written to the shadow output.
+func (s *shadowOutput) selectShadow(batch service.MessageBatch) service.MessageBatch {
+	var selected service.MessageBatch
+	for i, msg := range batch {
+		if s.percentage < 100 && s.randFn()*100 >= s.percentage {
+			continue
+		}
+		if s.check != nil {
+			resMsg, err := batch.BloblangQuery(i, s.check)
+			if err != nil {
+				s.log.Debugf("Shadow check failed: %v", err)
+				continue
+			}
+			if resMsg == nil {
+				continue
+			}
+			res, err := resMsg.AsStructured()
+			if err != nil {
+				s.log.Debugf("Shadow check failed: %v", err)
+				continue
+			}
+			if pass, _ := res.(bool); !pass {
+				continue
+			}
+		}
+		selected = append(selected, msg.Copy())
+	}
+	return selected
+}
+
+func (s *shadowOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	shadowBatch := s.selectShadow(batch)
+	if len(shadowBatch) == 0 {
+		return s.primary.WriteBatch(ctx, batch)
+	}
+
+	select {
+	case s.pending <- struct{}{}:
+	default:
+		s.mDropped.Incr(int64(len(shadowBatch)))
+		return s.primary.WriteBatch(ctx, batch)
+	}
+
+	primaryRes := make(chan error, 1)
+	s.pendingWG.Add(1)
+	go func() {
+		defer func() {
+			<-s.pending
+			s.pendingWG.Done()
+		}()
+
+		start := time.Now()
+		err := s.shadow.WriteBatch(s.shadowCtx, shadowBatch)
+		s.mLatency.Timing(time.Since(start).Nanoseconds(), "shadow")
+		if err != nil {
+			s.log.Debugf("Failed to write to shadow output: %v", err)
+			s.mError.Incr(int64(len(shadowBatch)))
+		} else {
+			s.mSent.Incr(int64(len(shadowBatch)))
+		}
+
+		var pErr error
+		select {
+		case pErr = <-primaryRes:
+		case <-s.shadowCtx.Done():
+			return
+		}
+		if (pErr == nil) != (err == nil) {
+			if pErr != nil {
+				s.mMismatch.Incr(1, "primary")
+			} else {
+				s.mMismatch.Incr(1, "shadow")
+			}
+		}
+	}()
+
+	start := time.Now()
+	err := s.primary.WriteBatch(ctx, batch)
+	s.mLatency.Timing(time.Since(start).Nanoseconds(), "primary")
+	primaryRes <- err
+	return err
+}
+
+func (s *shadowOutput) Close(ctx context.Context) error {
+	waitChan := make(chan struct{})
+	go func() {
+		s.pendingWG.Wait()
+		close(waitChan)
+	}()
+
+	// Give pending shadow writes until the close deadline to complete, after
+	// which they are abandoned.
+	select {
+	case <-waitChan:
+	case <-ctx.Done():
+		s.shadowCancel()
+		<-waitChan
+	}
+	s.shadowCancel()
+
+	shadowErr := s.shadow.Close(ctx)
+	if err := s.primary.Close(ctx); err != nil {
+		return err
+	}
+	return shadowErr
+}
@@ -0,0 +1,180 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeBatchWriter struct {
+	mut     sync.Mutex
+	written []string
+	err     error
+	block   chan struct{}
+	closed  bool
+}
+
+func (f *fakeBatchWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	for _, m := range b {
+		mBytes, _ := m.AsBytes()
+		f.written = append(f.written, string(mBytes))
+	}
+	return nil
+}
+
+func (f *fakeBatchWriter) Close(ctx context.Context) error {
+	f.mut.Lock()
+	f.closed = true
+	f.mut.Unlock()
+	return nil
+}
+
+func (f *fakeBatchWriter) getWritten() []string {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return append([]string(nil), f.written...)
+}
+
+func testShadowOutput(t testing.TB, confStr string) (s *shadowOutput, primary, shadow *fakeBatchWriter) {
+	t.Helper()
+
+	pConf, err := shadowOutputSpec().ParseYAML(confStr+`
+output:
+  drop: {}
+shadow:
+  drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	s, err = newShadowOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	primary, shadow = &fakeBatchWriter{}, &fakeBatchWriter{}
+	s.primary, s.shadow = primary, shadow
+	return
+}
+
+func shadowBatch(contents ...string) (batch service.MessageBatch) {
+	for _, c := range contents {
+		batch = append(batch, service.NewMessage([]byte(c)))
+	}
+	return
+}
+
+func TestShadowOutputCheck(t *testing.T) {
+	s, primary, shadow := testShadowOutput(t, `check: this.shadow`)
+
+	require.NoError(t, s.WriteBatch(context.Background(), shadowBatch(
+		`{"id":1,"shadow":true}`,
+		`{"id":2,"shadow":false}`,
+		`{"id":3}`,
+		`{"id":4,"shadow":true}`,
+	)))
+	require.NoError(t, s.Close(context.Background()))
+
+	assert.Equal(t, []string{
+		`{"id":1,"shadow":true}`,
+		`{"id":2,"shadow":false}`,
+		`{"id":3}`,
+		`{"id":4,"shadow":true}`,
+	}, primary.getWritten())
+	assert.Equal(t, []string{
+		`{"id":1,"shadow":true}`,
+		`{"id":4,"shadow":true}`,
+	}, shadow.getWritten())
+	assert.True(t, shadow.closed)
+	assert.True(t, primary.closed)
+}
+
+func TestShadowOutputPercentage(t *testing.T) {
+	s, primary, shadow := testShadowOutput(t, `percentage: 50`)
+
+	rolls := []float64{0.1, 0.6, 0.49, 0.5}
+	s.randFn = func() float64 {
+		r := rolls[0]
+		rolls = rolls[1:]
+		return r
+	}
+
+	require.NoError(t, s.WriteBatch(context.Background(), shadowBatch("a", "b", "c", "d")))
+	require.NoError(t, s.Close(context.Background()))
+
+	assert.Equal(t, []string{"a", "b", "c", "d"}, primary.getWritten())
+	assert.Equal(t, []string{"a", "c"}, shadow.getWritten())
+}
+
+func TestShadowOutputErrors(t *testing.T) {
+	s, primary, shadow := testShadowOutput(t, ``)
+
+	// Shadow failures do not affect the result.
+	shadow.err = errors.New("nope")
+	require.NoError(t, s.WriteBatch(context.Background(), shadowBatch("a")))
+	s.pendingWG.Wait()
+
+	// Primary failures are returned.
+	shadow.err = nil
+	primary.err = errors.New("also nope")
+	require.EqualError(t, s.WriteBatch(context.Background(), shadowBatch("b")), "also nope")
+
+	require.NoError(t, s.Close(context.Background()))
+	assert.Equal(t, []string{"b"}, shadow.getWritten())
+}
+
+func TestShadowOutputBackPressure(t *testing.T) {
+	s, primary, shadow := testShadowOutput(t, `shadow_max_in_flight: 1`)
+
+	shadow.block = make(chan struct{})
+
+	// The shadow output blocking does not block the primary, and copies beyond
+	// the maximum in flight are dropped.
+	require.NoError(t, s.WriteBatch(context.Background(), shadowBatch("a")))
+	require.NoError(t, s.WriteBatch(context.Background(), shadowBatch("b")))
+	assert.Equal(t, []string{"a", "b"}, primary.getWritten())
+
+	close(shadow.block)
+	require.NoError(t, s.Close(context.Background()))
+	assert.Equal(t, []string{"a"}, shadow.getWritten())
+
+	// Pending shadow writes are abandoned when the close deadline is reached.
+	s, _, shadow = testShadowOutput(t, ``)
+	shadow.block = make(chan struct{})
+	require.NoError(t, s.WriteBatch(context.Background(), shadowBatch("c")))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	require.NoError(t, s.Close(ctx))
+	assert.Empty(t, shadow.getWritten())
+}
+
+func TestShadowOutputConfigErrors(t *testing.T) {
+	for _, confStr := range []string{
+		"percentage: 101\noutput: { drop: {} }\nshadow: { drop: {} }",
+		"shadow_max_in_flight: 0\noutput: { drop: {} }\nshadow: { drop: {} }",
+	} {
+		pConf, err := shadowOutputSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newShadowOutputFromParsed(pConf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
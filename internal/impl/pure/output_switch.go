@@ -12,9 +12,11 @@ import (
 	"github.com/Jeffail/shutdown"
 
 	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
 	"github.com/benthosdev/benthos/v4/internal/log"
@@ -25,8 +27,12 @@ import (
 const (
 	soFieldRetryUntilSuccess = "retry_until_success"
 	soFieldStrictMode        = "strict_mode"
+	soFieldRoutingTable      = "routing_table"
+	soFieldRoutingTableCache = "cache"
+	soFieldRoutingTableKey   = "key"
 	soFieldCases             = "cases"
 	soFieldCasesCheck        = "check"
+	soFieldCasesRoute        = "route"
 	soFieldCasesContinue     = "continue"
 	soFieldCasesOutput       = "output"
 )
@@ -91,6 +97,37 @@ output:
           gcp_pubsub:
             project: people
             topic: that_i_dont_want_to_hang_with
+`,
+		).
+		Example(
+			"Routing Tables",
+			`
+The `+"`routing_table`"+` field allows routes to be managed outside of the config. Here a `+"[`routing_table` cache](/docs/components/caches/routing_table)"+` resolves the tenant of each message to a route, which is loaded from a file and reloaded when it changes, and messages are sent to the case with the matching `+"`route`"+`. Tenants that match no route are sent to the final case, which has no route.`,
+			`
+output:
+  switch:
+    routing_table:
+      cache: tenant_routes
+      key: ${! meta("tenant") }
+    cases:
+      - route: premium
+        output:
+          kafka_franz:
+            seed_brokers: [ premium-kafka:9092 ]
+            topic: events
+      - route: standard
+        output:
+          kafka_franz:
+            seed_brokers: [ standard-kafka:9092 ]
+            topic: events
+      - output:
+          drop: {}
+
+cache_resources:
+  - label: tenant_routes
+    routing_table:
+      url: ./tenant_routes.yaml
+      reload_interval: 10s
 `,
 		).
 		LintRule(`if this.exists("retry_until_success") && this.retry_until_success {
@@ -109,6 +146,17 @@ If a message can be routed to >1 outputs it is usually best to set this to true
 				Description(`This field determines whether an error should be reported if no condition is met. If set to true, an error is propagated back to the input level. The default behavior is false, which will drop the message.`).
 				Advanced().
 				Default(false),
+			service.NewObjectField(soFieldRoutingTable,
+				service.NewStringField(soFieldRoutingTableCache).
+					Description("A [`cache` resource](/docs/components/caches/about) to resolve routes from, which is usually a [`routing_table` cache](/docs/components/caches/routing_table)."),
+				service.NewInterpolatedStringField(soFieldRoutingTableKey).
+					Description("The key to resolve the route of each message with. Messages whose key does not exist within the cache have no route.").
+					Example(`${! this.type }`).
+					Example(`${! meta("tenant") }`),
+			).
+				Description("An optional cache that resolves the route of each message, which is matched against the `route` of each case.").
+				Version("4.28.0").
+				Optional(),
 			service.NewObjectListField(soFieldCases,
 				service.NewBloblangField(soFieldCasesCheck).
 					Description("A [Bloblang query](/docs/guides/bloblang/about/) that should return a boolean value indicating whether a message should be routed to the case output. If left empty the case always passes.").
@@ -117,6 +165,11 @@ If a message can be routed to >1 outputs it is usually best to set this to true
 						`this.contents.urls.contains("https://benthos.dev/")`,
 					).
 					Default(""),
+				service.NewStringField(soFieldCasesRoute).
+					Description("A route resolved by the `routing_table` that a message must have in order to pass this case. When a `check` is also specified a message must pass both. If left empty the route of a message is not considered.").
+					Example("orders").
+					Version("4.28.0").
+					Default(""),
 				service.NewOutputField(soFieldCasesOutput).
 					Description("An [output](/docs/components/outputs/about/) for messages that pass the check to be routed to."),
 				service.NewBoolField(soFieldCasesContinue).
@@ -180,6 +233,7 @@ func init() {
 
 type switchOutput struct {
 	logger log.Modular
+	mgr    bundle.NewManagement
 
 	transactions <-chan message.Transaction
 
@@ -187,9 +241,13 @@ type switchOutput struct {
 	outputTSChans []chan message.Transaction
 	outputs       []output.Streamed
 	checks        []*mapping.Executor
+	routes        []string
 	continues     []bool
 	fallthroughs  []bool
 
+	routeCache string
+	routeKey   *field.Expression
+
 	shutSig *shutdown.Signaller
 }
 
@@ -211,6 +269,7 @@ func switchOutputFromParsed(conf *service.ParsedConfig, mgr bundle.NewManagement
 
 	o := &switchOutput{
 		logger:       mgr.Logger(),
+		mgr:          mgr,
 		transactions: nil,
 		strictMode:   strictMode,
 		shutSig:      shutdown.NewSignaller(),
@@ -223,10 +282,27 @@ func switchOutputFromParsed(conf *service.ParsedConfig, mgr bundle.NewManagement
 	if lCases > 0 {
 		o.outputs = make([]output.Streamed, lCases)
 		o.checks = make([]*mapping.Executor, lCases)
+		o.routes = make([]string, lCases)
 		o.continues = make([]bool, lCases)
 		o.fallthroughs = make([]bool, lCases)
 	}
 
+	if conf.Contains(soFieldRoutingTable) {
+		if o.routeCache, err = conf.FieldString(soFieldRoutingTable, soFieldRoutingTableCache); err != nil {
+			return nil, err
+		}
+		if !mgr.ProbeCache(o.routeCache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", o.routeCache)
+		}
+		keyStr, err := conf.FieldString(soFieldRoutingTable, soFieldRoutingTableKey)
+		if err != nil {
+			return nil, err
+		}
+		if o.routeKey, err = mgr.BloblEnvironment().NewField(keyStr); err != nil {
+			return nil, fmt.Errorf("failed to parse routing table key: %v", err)
+		}
+	}
+
 	for i, cConf := range cases {
 		w, err := cConf.FieldOutput(soFieldCasesOutput)
 		if err != nil {
@@ -246,6 +322,12 @@ func switchOutputFromParsed(conf *service.ParsedConfig, mgr bundle.NewManagement
 				return nil, fmt.Errorf("failed to parse case '%v' check mapping: %v", i, err)
 			}
 		}
+		if o.routes[i], err = cConf.FieldString(soFieldCasesRoute); err != nil {
+			return nil, err
+		}
+		if o.routes[i] != "" && o.routeKey == nil {
+			return nil, fmt.Errorf("case '%v' has a route but no routing_table is configured", i)
+		}
 		if o.continues[i], err = cConf.FieldBool(soFieldCasesContinue); err != nil {
 			return nil, err
 		}
@@ -378,6 +460,30 @@ func (o *switchOutput) dispatchToTargets(
 	}
 }
 
+// route returns the route of a message resolved by the routing table, or an
+// empty string when the message has no route.
+func (o *switchOutput) route(ctx context.Context, index int, msg message.Batch) string {
+	key, err := o.routeKey.String(index, msg)
+	if err != nil {
+		o.logger.Error("Failed to interpolate routing table key: %v\n", err)
+		return ""
+	}
+
+	var route []byte
+	if cErr := o.mgr.AccessCache(ctx, o.routeCache, func(c cache.V1) {
+		route, err = c.Get(ctx, key)
+	}); cErr != nil {
+		err = cErr
+	}
+	if err != nil {
+		if !errors.Is(err, component.ErrKeyNotFound) {
+			o.logger.Error("Failed to resolve route of key '%v': %v\n", key, err)
+		}
+		return ""
+	}
+	return string(route)
+}
+
 func (o *switchOutput) loop() {
 	ackInterruptChan := make(chan struct{})
 	var ackPending int64
@@ -426,10 +532,15 @@ func (o *switchOutput) loop() {
 
 		outputTargets := make([][]*message.Part, len(o.checks))
 		if checksErr := trackedMsg.Iter(func(i int, p *message.Part) error {
+			var route string
+			if o.routeKey != nil {
+				route = o.route(shutCtx, i, trackedMsg)
+			}
+
 			routedAtLeastOnce := false
 			for j, exe := range o.checks {
-				test := true
-				if exe != nil {
+				test := o.routes[j] == "" || o.routes[j] == route
+				if test && exe != nil {
 					var err error
 					if test, err = exe.QueryPart(i, trackedMsg); err != nil {
 						test = false
@@ -19,8 +19,11 @@ import (
 
 func newSwitch(t testing.TB, mockOutputs []*mock.OutputChanneled, confStr string, args ...any) *switchOutput {
 	t.Helper()
+	return newSwitchWithManager(t, mock.NewManager(), mockOutputs, confStr, args...)
+}
 
-	mgr := mock.NewManager()
+func newSwitchWithManager(t testing.TB, mgr *mock.Manager, mockOutputs []*mock.OutputChanneled, confStr string, args ...any) *switchOutput {
+	t.Helper()
 
 	pConf, err := switchOutputSpec().ParseYAML(fmt.Sprintf(confStr, args...), nil)
 	require.NoError(t, err)
@@ -676,6 +679,108 @@ cases:
 	require.NoError(t, s.WaitForClose(ctx))
 }
 
+func TestSwitchRoutingTable(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	mgr := mock.NewManager()
+	mgr.Caches["routes"] = map[string]mock.CacheItem{
+		"a": {Value: "first"},
+		"b": {Value: "second"},
+		"c": {Value: "second"},
+	}
+
+	mockOutputs := []*mock.OutputChanneled{{}, {}, {}}
+	s := newSwitchWithManager(t, mgr, mockOutputs, `
+routing_table:
+  cache: routes
+  key: ${! this.id }
+cases:
+  - route: first
+    output:
+      drop: {}
+  - route: second
+    check: 'this.id != "c"'
+    output:
+      drop: {}
+  - output:
+      drop: {}
+`)
+
+	readChan := make(chan message.Transaction)
+	resChan := make(chan error, 1)
+
+	require.NoError(t, s.Consume(readChan))
+
+	for _, test := range []struct {
+		id     string
+		output int
+	}{
+		{id: "a", output: 0},
+		{id: "b", output: 1},
+		{id: "c", output: 2},
+		{id: "d", output: 2},
+	} {
+		content := []byte(fmt.Sprintf(`{"id":%q}`, test.id))
+		select {
+		case readChan <- message.NewTransaction(message.QuickBatch([][]byte{content}), resChan):
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for output send")
+		}
+
+		select {
+		case ts := <-mockOutputs[test.output].TChan:
+			assert.Equal(t, string(content), string(ts.Payload.Get(0).AsBytes()), test.id)
+			require.NoError(t, ts.Ack(ctx, nil))
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for output %v to receive %v", test.output, test.id)
+		}
+
+		select {
+		case res := <-resChan:
+			require.NoError(t, res)
+		case <-time.After(time.Second):
+			t.Fatal("Timed out responding to output")
+		}
+	}
+
+	s.TriggerCloseNow()
+	require.NoError(t, s.WaitForClose(ctx))
+}
+
+func TestSwitchRoutingTableErrors(t *testing.T) {
+	mgr := mock.NewManager()
+
+	pConf, err := switchOutputSpec().ParseYAML(`
+cases:
+  - route: foo
+    output:
+      drop: {}
+  - output:
+      drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	_, err = switchOutputFromParsed(pConf, mgr)
+	require.EqualError(t, err, "case '0' has a route but no routing_table is configured")
+
+	pConf, err = switchOutputSpec().ParseYAML(`
+routing_table:
+  cache: nope
+  key: ${! this.id }
+cases:
+  - route: foo
+    output:
+      drop: {}
+  - output:
+      drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	_, err = switchOutputFromParsed(pConf, mgr)
+	require.EqualError(t, err, "cache resource 'nope' was not found")
+}
+
 func TestSwitchNoMatchStrict(t *testing.T) {
 	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
 	defer done()
@@ -0,0 +1,488 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	trFieldTenantID    = "tenant_id"
+	trFieldOutput      = "output"
+	trFieldIdleTimeout = "idle_timeout"
+	trFieldMaxTenants  = "max_tenants"
+	trFieldRateLimit   = "rate_limit"
+	trFieldQuota       = "quota"
+	trFieldCount       = "count"
+	trFieldInterval    = "interval"
+	trFieldPeriod      = "period"
+	trFieldMaxInFlight = "max_in_flight"
+
+	trMetaTenantID = "tenant_id"
+)
+
+func tenantRouterOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Routes messages to isolated per-tenant instances of an output, which are created on demand from a template and closed once idle, with optional per-tenant rate limits and quotas.").
+		Description(`
+A tenant ID is derived from each message with the `+"`tenant_id`"+` interpolation and is stored within the metadata field `+"`tenant_id`"+` of the message. Each tenant is then given its own instance of the templated `+"`output`"+`, and therefore its own connections and batching, which is created when the first message of the tenant arrives and is closed once no messages have been written for the duration of `+"`idle_timeout`"+`. Interpolation functions within the template can refer to the tenant with `+"`${! @tenant_id }`"+`.
+
+### Rate Limits and Quotas
+
+When a `+"`rate_limit`"+` is configured the writes of each tenant are throttled to the given number of messages per interval without throttling other tenants, provided that `+"`max_in_flight`"+` allows writes to progress in parallel.
+
+When a `+"`quota`"+` is configured messages of a tenant that has already written the given number of messages within the current period are rejected. Rejected messages are nacked and will therefore be retried by the input unless they are routed elsewhere, for example with a `+"[`fallback` output](/docs/components/outputs/fallback)"+`.
+
+### Metrics
+
+The counters `+"`tenant_router_sent`, `tenant_router_throttled`"+` and `+"`tenant_router_rejected`"+` are labelled by `+"`tenant`"+` and count the messages that were written, delayed by the rate limit and rejected by the quota respectively. The gauge `+"`tenant_router_active_outputs`"+` tracks the number of tenant outputs currently open.`).
+		Example(
+			"Per-Tenant Topics",
+			"Here we write the events of each customer to a dedicated Kafka topic with a daily quota per customer, where events beyond the quota are archived to S3.",
+			`
+output:
+  fallback:
+    - tenant_router:
+        tenant_id: ${! @customer_id }
+        rate_limit:
+          count: 500
+          interval: 1s
+        quota:
+          count: 1000000
+          period: 24h
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: events-${! @tenant_id }
+    - aws_s3:
+        bucket: over-quota
+        path: ${! @tenant_id }/${! uuid_v4() }.json
+`,
+		).
+		Fields(
+			service.NewInterpolatedStringField(trFieldTenantID).
+				Description("An interpolated string that derives the tenant ID of each message.").
+				Example(`${! @kafka_key }`).
+				Example(`${! this.account.id }`),
+			service.NewOutputField(trFieldOutput).
+				Description("A template output from which an instance is created for each tenant."),
+			service.NewDurationField(trFieldIdleTimeout).
+				Description("The period of time after which the output of a tenant that has not been written to is closed.").
+				Default("5m"),
+			service.NewIntField(trFieldMaxTenants).
+				Description("The maximum number of tenant outputs that may be open at once, messages of further tenants are rejected until an output is closed. Set to zero to disable the limit.").
+				Default(0).
+				Advanced(),
+			service.NewObjectField(trFieldRateLimit,
+				service.NewIntField(trFieldCount).
+					Description("The maximum number of messages of each tenant to write within the interval. Set to zero to disable the rate limit.").
+					Default(0),
+				service.NewDurationField(trFieldInterval).
+					Description("The interval over which messages are counted.").
+					Default("1s"),
+			).
+				Description("An optional rate limit applied to each tenant individually."),
+			service.NewObjectField(trFieldQuota,
+				service.NewIntField(trFieldCount).
+					Description("The maximum number of messages of each tenant to accept within a period. Set to zero to disable the quota.").
+					Default(0),
+				service.NewDurationField(trFieldPeriod).
+					Description("The period after which the quota of a tenant is replenished, starting from the first message of the tenant.").
+					Default("24h"),
+			).
+				Description("An optional quota applied to each tenant individually."),
+			service.NewIntField(trFieldMaxInFlight).
+				Description("The maximum number of batches to have in flight at a given time, across all tenants.").
+				Default(64),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("tenant_router", tenantRouterOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(trFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newTenantRouterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type tenantWriter interface {
+	WriteBatch(ctx context.Context, b service.MessageBatch) error
+	Close(ctx context.Context) error
+}
+
+type tenantState struct {
+	out      tenantWriter
+	inFlight int
+	lastUsed time.Time
+
+	// Protected by the tenants mutex of the router.
+	quotaStart time.Time
+	quotaCount int
+
+	rateMut   sync.Mutex
+	rateStart time.Time
+	rateCount int
+}
+
+type tenantRouter struct {
+	tenantID      *service.InterpolatedString
+	newOutput     func() (tenantWriter, error)
+	idleTimeout   time.Duration
+	maxTenants    int
+	rateCount     int
+	rateInterval  time.Duration
+	quotaCount    int
+	quotaPeriod   time.Duration
+	log           *service.Logger
+	nowFn         func() time.Time
+	tenantsMut    sync.Mutex
+	tenants       map[string]*tenantState
+	activeOutputs int
+
+	mSent      *service.MetricCounter
+	mThrottled *service.MetricCounter
+	mRejected  *service.MetricCounter
+	mActive    *service.MetricGauge
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+func newTenantRouterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*tenantRouter, error) {
+	r := &tenantRouter{
+		newOutput: func() (tenantWriter, error) {
+			return conf.FieldOutput(trFieldOutput)
+		},
+		log:        mgr.Logger(),
+		nowFn:      time.Now,
+		tenants:    map[string]*tenantState{},
+		mSent:      mgr.Metrics().NewCounter("tenant_router_sent", "tenant"),
+		mThrottled: mgr.Metrics().NewCounter("tenant_router_throttled", "tenant"),
+		mRejected:  mgr.Metrics().NewCounter("tenant_router_rejected", "tenant"),
+		mActive:    mgr.Metrics().NewGauge("tenant_router_active_outputs"),
+		closeChan:  make(chan struct{}),
+	}
+
+	var err error
+	if r.tenantID, err = conf.FieldInterpolatedString(trFieldTenantID); err != nil {
+		return nil, err
+	}
+	if r.idleTimeout, err = conf.FieldDuration(trFieldIdleTimeout); err != nil {
+		return nil, err
+	}
+	if r.idleTimeout <= 0 {
+		return nil, errors.New("idle_timeout must be greater than zero")
+	}
+	if r.maxTenants, err = conf.FieldInt(trFieldMaxTenants); err != nil {
+		return nil, err
+	}
+	if r.rateCount, err = conf.FieldInt(trFieldRateLimit, trFieldCount); err != nil {
+		return nil, err
+	}
+	if r.rateInterval, err = conf.FieldDuration(trFieldRateLimit, trFieldInterval); err != nil {
+		return nil, err
+	}
+	if r.rateCount > 0 && r.rateInterval <= 0 {
+		return nil, errors.New("rate_limit.interval must be greater than zero")
+	}
+	if r.quotaCount, err = conf.FieldInt(trFieldQuota, trFieldCount); err != nil {
+		return nil, err
+	}
+	if r.quotaPeriod, err = conf.FieldDuration(trFieldQuota, trFieldPeriod); err != nil {
+		return nil, err
+	}
+	if r.quotaCount > 0 && r.quotaPeriod <= 0 {
+		return nil, errors.New("quota.period must be greater than zero")
+	}
+
+	go r.reapLoop()
+	return r, nil
+}
+
+func (r *tenantRouter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (r *tenantRouter) reapLoop() {
+	interval := r.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.reap(context.Background())
+		case <-r.closeChan:
+			return
+		}
+	}
+}
+
+// reap closes the outputs of tenants that have been idle for longer than the
+// idle timeout, and forgets tenants entirely once their quota has replenished.
+func (r *tenantRouter) reap(ctx context.Context) {
+	now := r.nowFn()
+
+	var idle []tenantWriter
+	r.tenantsMut.Lock()
+	for id, ts := range r.tenants {
+		if ts.inFlight > 0 || now.Sub(ts.lastUsed) < r.idleTimeout {
+			continue
+		}
+		if ts.out != nil {
+			r.log.Debugf("Closing output of tenant %v after being idle", id)
+			idle = append(idle, ts.out)
+			ts.out = nil
+			r.activeOutputs--
+		}
+		if r.quotaCount <= 0 || now.Sub(ts.quotaStart) >= r.quotaPeriod {
+			delete(r.tenants, id)
+		}
+	}
+	r.mActive.Set(int64(r.activeOutputs))
+	r.tenantsMut.Unlock()
+
+	for _, out := range idle {
+		if err := out.Close(ctx); err != nil {
+			r.log.Errorf("Failed to close idle tenant output: %v", err)
+		}
+	}
+}
+
+// acquire returns the state of a tenant with an open output, creating both if
+// necessary, and marks the tenant as having a write in flight.
+func (r *tenantRouter) acquire(id string) (*tenantState, error) {
+	r.tenantsMut.Lock()
+	defer r.tenantsMut.Unlock()
+
+	ts, exists := r.tenants[id]
+	if !exists {
+		ts = &tenantState{}
+		r.tenants[id] = ts
+	}
+	if ts.out == nil {
+		if r.maxTenants > 0 && r.activeOutputs >= r.maxTenants {
+			return nil, fmt.Errorf("unable to open an output for tenant %v as the maximum of %v tenants are active", id, r.maxTenants)
+		}
+		out, err := r.newOutput()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output for tenant %v: %w", id, err)
+		}
+		ts.out = out
+		r.activeOutputs++
+		r.mActive.Set(int64(r.activeOutputs))
+	}
+	ts.inFlight++
+	ts.lastUsed = r.nowFn()
+	return ts, nil
+}
+
+func (r *tenantRouter) release(ts *tenantState) {
+	r.tenantsMut.Lock()
+	ts.inFlight--
+	ts.lastUsed = r.nowFn()
+	r.tenantsMut.Unlock()
+}
+
+// takeQuota returns the number of n messages that fit within the remaining
+// quota of a tenant, and consumes that amount from the quota.
+func (r *tenantRouter) takeQuota(ts *tenantState, n int) int {
+	if r.quotaCount <= 0 {
+		return n
+	}
+
+	r.tenantsMut.Lock()
+	defer r.tenantsMut.Unlock()
+
+	now := r.nowFn()
+	if ts.quotaStart.IsZero() || now.Sub(ts.quotaStart) >= r.quotaPeriod {
+		ts.quotaStart = now
+		ts.quotaCount = 0
+	}
+	if remaining := r.quotaCount - ts.quotaCount; n > remaining {
+		n = remaining
+	}
+	ts.quotaCount += n
+	return n
+}
+
+// waitRate blocks until n messages of a tenant may be written within the rate
+// limit, and returns whether the write was delayed.
+func (r *tenantRouter) waitRate(ctx context.Context, ts *tenantState, n int) (bool, error) {
+	if r.rateCount <= 0 {
+		return false, nil
+	}
+
+	ts.rateMut.Lock()
+	defer ts.rateMut.Unlock()
+
+	var throttled bool
+	for n > 0 {
+		now := r.nowFn()
+		if ts.rateStart.IsZero() || now.Sub(ts.rateStart) >= r.rateInterval {
+			ts.rateStart = now
+			ts.rateCount = 0
+		}
+		if remaining := r.rateCount - ts.rateCount; remaining > 0 {
+			if remaining > n {
+				remaining = n
+			}
+			ts.rateCount += remaining
+			n -= remaining
+			continue
+		}
+
+		throttled = true
+		select {
+		case <-time.After(r.rateInterval - now.Sub(ts.rateStart)):
+		case <-ctx.Done():
+			return throttled, ctx.Err()
+		}
+	}
+	return throttled, nil
+}
+
+// writeTenant writes the messages of a tenant that fit within its quota and
+// returns the number of messages that were not rejected by the quota.
+func (r *tenantRouter) writeTenant(ctx context.Context, id string, batch service.MessageBatch) (accepted int, err error) {
+	ts, err := r.acquire(id)
+	if err != nil {
+		return len(batch), err
+	}
+	defer r.release(ts)
+
+	accepted = r.takeQuota(ts, len(batch))
+	if accepted == 0 {
+		return 0, nil
+	}
+
+	throttled, err := r.waitRate(ctx, ts, accepted)
+	if throttled {
+		r.mThrottled.Incr(int64(accepted), id)
+	}
+	if err != nil {
+		return accepted, err
+	}
+	if err := ts.out.WriteBatch(ctx, batch[:accepted]); err != nil {
+		return accepted, err
+	}
+	r.mSent.Incr(int64(accepted), id)
+	return accepted, nil
+}
+
+func (r *tenantRouter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	var order []string
+	groups := map[string]service.MessageBatch{}
+	indexes := map[string][]int{}
+
+	for i, msg := range batch {
+		id, err := batch.TryInterpolatedString(i, r.tenantID)
+		if err != nil {
+			return fmt.Errorf("tenant_id interpolation error: %w", err)
+		}
+		if id == "" {
+			return errors.New("tenant_id resolved to an empty string")
+		}
+		if _, exists := groups[id]; !exists {
+			order = append(order, id)
+		}
+		msg = msg.Copy()
+		msg.MetaSetMut(trMetaTenantID, id)
+		groups[id] = append(groups[id], msg)
+		indexes[id] = append(indexes[id], i)
+	}
+
+	var batchErr *service.BatchError
+	var errMut sync.Mutex
+	fail := func(i int, err error) {
+		errMut.Lock()
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+		errMut.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for _, id := range order {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+
+			group, groupIndexes := groups[id], indexes[id]
+			accepted, err := r.writeTenant(ctx, id, group)
+			if rejected := len(group) - accepted; rejected > 0 {
+				r.mRejected.Incr(int64(rejected), id)
+				qErr := fmt.Errorf("tenant %v has exceeded its quota of %v messages", id, r.quotaCount)
+				for _, i := range groupIndexes[accepted:] {
+					fail(i, qErr)
+				}
+			}
+			if err == nil {
+				return
+			}
+
+			var gErr *service.BatchError
+			if errors.As(err, &gErr) {
+				gErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+					if err != nil && i < accepted {
+						fail(groupIndexes[i], err)
+					}
+					return true
+				})
+				return
+			}
+			for _, i := range groupIndexes[:accepted] {
+				fail(i, err)
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (r *tenantRouter) Close(ctx context.Context) error {
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
+
+	r.tenantsMut.Lock()
+	var outs []tenantWriter
+	for _, ts := range r.tenants {
+		if ts.out != nil {
+			outs = append(outs, ts.out)
+			ts.out = nil
+		}
+	}
+	r.tenants = map[string]*tenantState{}
+	r.activeOutputs = 0
+	r.tenantsMut.Unlock()
+
+	var closeErr error
+	for _, out := range outs {
+		if err := out.Close(ctx); err != nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
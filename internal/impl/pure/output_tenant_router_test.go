@@ -0,0 +1,245 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeTenantWriter struct {
+	mut     sync.Mutex
+	written []string
+	closed  bool
+	err     error
+}
+
+func (f *fakeTenantWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	for _, m := range b {
+		tenant, _ := m.MetaGet("tenant_id")
+		mBytes, _ := m.AsBytes()
+		f.written = append(f.written, tenant+":"+string(mBytes))
+	}
+	return nil
+}
+
+func (f *fakeTenantWriter) Close(ctx context.Context) error {
+	f.mut.Lock()
+	f.closed = true
+	f.mut.Unlock()
+	return nil
+}
+
+func testTenantRouter(t testing.TB, confStr string) (*tenantRouter, *[]*fakeTenantWriter) {
+	t.Helper()
+
+	pConf, err := tenantRouterOutputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	r, err := newTenantRouterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, r.Close(context.Background()))
+	})
+
+	var writers []*fakeTenantWriter
+	r.newOutput = func() (tenantWriter, error) {
+		w := &fakeTenantWriter{}
+		writers = append(writers, w)
+		return w, nil
+	}
+	return r, &writers
+}
+
+// tenantWrites returns the messages written to each output by the tenant that
+// they belong to, as tenant outputs may be created in any order.
+func tenantWrites(writers []*fakeTenantWriter) map[string][]string {
+	res := map[string][]string{}
+	for _, w := range writers {
+		for _, s := range w.written {
+			tenant, _, _ := strings.Cut(s, ":")
+			res[tenant] = append(res[tenant], s)
+		}
+	}
+	return res
+}
+
+func tenantBatch(tenantContents ...string) (batch service.MessageBatch) {
+	for i := 0; i < len(tenantContents); i += 2 {
+		msg := service.NewMessage([]byte(tenantContents[i+1]))
+		msg.MetaSetMut("customer", tenantContents[i])
+		batch = append(batch, msg)
+	}
+	return
+}
+
+func TestTenantRouterRouting(t *testing.T) {
+	r, writers := testTenantRouter(t, `
+tenant_id: ${! @customer }
+output:
+  drop: {}
+`)
+
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch(
+		"a", "first", "b", "second", "a", "third",
+	)))
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch(
+		"b", "fourth",
+	)))
+
+	require.Len(t, *writers, 2)
+	assert.Equal(t, map[string][]string{
+		"a": {"a:first", "a:third"},
+		"b": {"b:second", "b:fourth"},
+	}, tenantWrites(*writers))
+}
+
+func TestTenantRouterIdleExpiry(t *testing.T) {
+	r, writers := testTenantRouter(t, `
+tenant_id: ${! @customer }
+idle_timeout: 1m
+output:
+  drop: {}
+`)
+
+	now := time.Unix(1000, 0)
+	r.nowFn = func() time.Time { return now }
+
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch("a", "first")))
+	now = now.Add(30 * time.Second)
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch("b", "second")))
+
+	now = now.Add(45 * time.Second)
+	r.reap(context.Background())
+
+	require.Len(t, *writers, 2)
+	assert.True(t, (*writers)[0].closed)
+	assert.False(t, (*writers)[1].closed)
+	assert.Equal(t, 1, r.activeOutputs)
+
+	// A new output is created once the tenant becomes active again.
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch("a", "third")))
+	require.Len(t, *writers, 3)
+	assert.Equal(t, []string{"a:third"}, (*writers)[2].written)
+}
+
+func TestTenantRouterQuota(t *testing.T) {
+	r, writers := testTenantRouter(t, `
+tenant_id: ${! @customer }
+quota:
+  count: 2
+  period: 1h
+output:
+  drop: {}
+`)
+
+	now := time.Unix(1000, 0)
+	r.nowFn = func() time.Time { return now }
+
+	batch := tenantBatch("a", "first", "b", "second", "a", "third", "a", "fourth")
+	err := r.WriteBatch(context.Background(), batch)
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	var failed []int
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			failed = append(failed, i)
+			assert.EqualError(t, err, "tenant a has exceeded its quota of 2 messages")
+		}
+		return true
+	})
+	assert.Equal(t, []int{3}, failed)
+	assert.Equal(t, map[string][]string{
+		"a": {"a:first", "a:third"},
+		"b": {"b:second"},
+	}, tenantWrites(*writers))
+
+	// The quota is replenished after the period.
+	now = now.Add(time.Hour)
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch("a", "fourth")))
+	assert.Equal(t, []string{"a:first", "a:third", "a:fourth"}, tenantWrites(*writers)["a"])
+}
+
+func TestTenantRouterOutputErrors(t *testing.T) {
+	r, writers := testTenantRouter(t, `
+tenant_id: ${! @customer }
+max_tenants: 1
+output:
+  drop: {}
+`)
+
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch("a", "first")))
+	(*writers)[0].err = errors.New("nope")
+
+	err := r.WriteBatch(context.Background(), tenantBatch("a", "second", "b", "third"))
+	require.Error(t, err)
+
+	var bErr *service.BatchError
+	require.True(t, errors.As(err, &bErr))
+
+	errs := map[int]string{}
+	bErr.WalkMessages(func(i int, _ *service.Message, err error) bool {
+		if err != nil {
+			errs[i] = err.Error()
+		}
+		return true
+	})
+	assert.Equal(t, map[int]string{
+		0: "nope",
+		1: "unable to open an output for tenant b as the maximum of 1 tenants are active",
+	}, errs)
+}
+
+func TestTenantRouterRateLimit(t *testing.T) {
+	r, writers := testTenantRouter(t, `
+tenant_id: ${! @customer }
+rate_limit:
+  count: 2
+  interval: 50ms
+output:
+  drop: {}
+`)
+
+	start := time.Now()
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch(
+		"a", "first", "a", "second", "a", "third",
+	)))
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	assert.Equal(t, []string{"a:first", "a:second", "a:third"}, (*writers)[0].written)
+
+	ctx, done := context.WithCancel(context.Background())
+	done()
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch("b", "fourth")))
+	require.Error(t, r.WriteBatch(ctx, tenantBatch("b", "fifth", "b", "sixth", "b", "seventh")))
+}
+
+func TestTenantRouterConfigOutput(t *testing.T) {
+	pConf, err := tenantRouterOutputSpec().ParseYAML(`
+tenant_id: ${! @customer }
+output:
+  drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newTenantRouterFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	require.NoError(t, r.WriteBatch(context.Background(), tenantBatch("a", "first", "b", "second")))
+	assert.Equal(t, 2, r.activeOutputs)
+	require.NoError(t, r.Close(context.Background()))
+}
@@ -0,0 +1,192 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	tpcFieldOutputs     = "outputs"
+	tpcFieldMaxInFlight = "max_in_flight"
+)
+
+func twoPhaseCommitOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Writes each batch to a list of child outputs using a two-phase commit, where the batch only becomes visible within the child outputs once all of them have staged it successfully.").
+		Description(`
+Each batch is first written to all child `+"`outputs`"+` in parallel, where the outputs stage the batch without making it visible to consumers. When all outputs have staged the batch successfully the staged writes are committed, otherwise they are aborted and the batch is retried. The batch is only acknowledged once all staged writes have been committed, and therefore the input of the pipeline only commits its position (such as consumer group offsets) after the batch is visible within every output.
+
+### Staging Outputs
+
+Only outputs that support staging can be used as children of this output, those outputs and the forms in which they stage batches are:
+
+- `+"`kafka_franz`"+`: The batch is produced within a Kafka transaction, which requires a `+"`transactional_id`"+`. Offsets committed with `+"`transaction_consumer_group`"+` are committed within the same transaction.
+- `+"`aws_s3`"+`: Each message is uploaded to a temporary object alongside its target key, which is copied to the target key when committed and deleted when aborted.
+
+An output that completes a write without staging it causes the batch to be rejected, with an error logged, since its write is not covered by the commit.
+
+### Delivery Guarantees
+
+A two-phase commit narrows, but does not remove, the window in which a batch can be partially visible. When a staged write fails to be committed after other outputs have already committed theirs the error is logged and the batch is retried, in which case the outputs that committed observe the batch again. A batch is also redelivered when the process terminates after the commit but before the input commits its position.`).
+		Example(
+			"Kafka and S3",
+			"Here we write each batch consumed from a Kafka topic both to another topic and to S3, where consumers of either only ever observe batches that were written to both.",
+			`
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: orders_archiver
+
+output:
+  two_phase_commit:
+    outputs:
+      - kafka_franz:
+          seed_brokers: [ localhost:9092 ]
+          topic: orders_archived
+          transactional_id: orders_archiver
+      - aws_s3:
+          bucket: orders
+          path: ${! meta("kafka_partition") }/${! meta("kafka_offset") }.json
+`,
+		).
+		Fields(
+			service.NewOutputListField(tpcFieldOutputs).
+				Description("A list of child outputs that support staging."),
+			service.NewIntField(tpcFieldMaxInFlight).
+				Description("The maximum number of batches to have in flight at a given time.").
+				Default(1),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("two_phase_commit", twoPhaseCommitOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(tpcFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newTwoPhaseCommitOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type twoPhaseCommitOutput struct {
+	outputs []batchWriteCloser
+	log     *service.Logger
+
+	mCommitted *service.MetricCounter
+	mAborted   *service.MetricCounter
+	mCommitErr *service.MetricCounter
+}
+
+func newTwoPhaseCommitOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*twoPhaseCommitOutput, error) {
+	outputs, err := conf.FieldOutputList(tpcFieldOutputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(outputs) == 0 {
+		return nil, errors.New("at least one output must be specified")
+	}
+
+	t := &twoPhaseCommitOutput{
+		log:        mgr.Logger(),
+		mCommitted: mgr.Metrics().NewCounter("two_phase_commit_committed"),
+		mAborted:   mgr.Metrics().NewCounter("two_phase_commit_aborted"),
+		mCommitErr: mgr.Metrics().NewCounter("two_phase_commit_commit_error"),
+	}
+	for _, o := range outputs {
+		t.outputs = append(t.outputs, o)
+	}
+	return t, nil
+}
+
+func (t *twoPhaseCommitOutput) Connect(ctx context.Context) error {
+	return nil
+}
+
+// stage writes a batch to each output with a separate staging, returning the
+// stagings of all outputs along with the first error encountered.
+func (t *twoPhaseCommitOutput) stage(ctx context.Context, batch service.MessageBatch) ([]*service.OutputStaging, error) {
+	stagings := make([]*service.OutputStaging, len(t.outputs))
+	errs := make([]error, len(t.outputs))
+
+	var wg sync.WaitGroup
+	for i, o := range t.outputs {
+		stagings[i] = service.NewOutputStaging()
+
+		stagedBatch := make(service.MessageBatch, len(batch))
+		for j, msg := range batch {
+			stagedBatch[j] = msg.Copy().WithContext(service.ContextWithOutputStaging(msg.Context(), stagings[i]))
+		}
+
+		wg.Add(1)
+		go func(i int, o batchWriteCloser) {
+			defer wg.Done()
+			if err := o.WriteBatch(ctx, stagedBatch); err != nil {
+				errs[i] = fmt.Errorf("output %v: %w", i, err)
+				return
+			}
+			if stagings[i].Len() == 0 {
+				errs[i] = fmt.Errorf("output %v does not support staging", i)
+			}
+		}(i, o)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return stagings, err
+		}
+	}
+	return stagings, nil
+}
+
+func (t *twoPhaseCommitOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	stagings, err := t.stage(ctx, batch)
+	if err != nil {
+		// Staged writes are aborted even when the write was cancelled, as
+		// otherwise they might linger until they expire.
+		abortCtx := context.WithoutCancel(ctx)
+		for i, s := range stagings {
+			if abortErr := s.Abort(abortCtx); abortErr != nil {
+				t.log.Errorf("Failed to abort staged write of output %v: %v", i, abortErr)
+			}
+		}
+		t.mAborted.Incr(1)
+		return err
+	}
+
+	var commitErrs []error
+	for i, s := range stagings {
+		if err := s.Commit(ctx); err != nil {
+			t.log.Errorf("Failed to commit staged write of output %v: %v", i, err)
+			commitErrs = append(commitErrs, fmt.Errorf("output %v: %w", i, err))
+		}
+	}
+	if len(commitErrs) > 0 {
+		t.mCommitErr.Incr(1)
+		return fmt.Errorf("failed to commit staged writes: %w", errors.Join(commitErrs...))
+	}
+	t.mCommitted.Incr(1)
+	return nil
+}
+
+func (t *twoPhaseCommitOutput) Close(ctx context.Context) error {
+	var errs []error
+	for _, o := range t.outputs {
+		if err := o.Close(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
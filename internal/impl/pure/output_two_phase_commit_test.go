@@ -0,0 +1,139 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type fakeStagingWriter struct {
+	mut       sync.Mutex
+	staged    []string
+	committed []string
+	aborted   []string
+	noStaging bool
+	err       error
+	commitErr error
+}
+
+func (f *fakeStagingWriter) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, m := range b {
+		mBytes, _ := m.AsBytes()
+		content := string(mBytes)
+
+		f.mut.Lock()
+		f.staged = append(f.staged, content)
+		f.mut.Unlock()
+
+		staging, ok := service.OutputStagingFromContext(m.Context())
+		if !ok || f.noStaging {
+			continue
+		}
+		staging.Stage(func(context.Context) error {
+			f.mut.Lock()
+			defer f.mut.Unlock()
+			if f.commitErr != nil {
+				return f.commitErr
+			}
+			f.committed = append(f.committed, content)
+			return nil
+		}, func(context.Context) error {
+			f.mut.Lock()
+			defer f.mut.Unlock()
+			f.aborted = append(f.aborted, content)
+			return nil
+		})
+	}
+	return nil
+}
+
+func (f *fakeStagingWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+func testTwoPhaseCommitOutput(t testing.TB, writers ...*fakeStagingWriter) *twoPhaseCommitOutput {
+	t.Helper()
+
+	pConf, err := twoPhaseCommitOutputSpec().ParseYAML(`
+outputs:
+  - drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newTwoPhaseCommitOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	o.outputs = nil
+	for _, w := range writers {
+		o.outputs = append(o.outputs, w)
+	}
+	return o
+}
+
+func TestTwoPhaseCommitOutputCommits(t *testing.T) {
+	a, b := &fakeStagingWriter{}, &fakeStagingWriter{}
+	o := testTwoPhaseCommitOutput(t, a, b)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+	}
+	require.NoError(t, o.WriteBatch(context.Background(), batch))
+
+	for _, w := range []*fakeStagingWriter{a, b} {
+		assert.Equal(t, []string{"foo", "bar"}, w.committed)
+		assert.Empty(t, w.aborted)
+	}
+
+	// The messages of the batch are not modified by the staging.
+	_, staged := service.OutputStagingFromContext(batch[0].Context())
+	assert.False(t, staged)
+}
+
+func TestTwoPhaseCommitOutputAborts(t *testing.T) {
+	a, b := &fakeStagingWriter{}, &fakeStagingWriter{err: errors.New("nope")}
+	o := testTwoPhaseCommitOutput(t, a, b)
+
+	err := o.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+	})
+	require.ErrorContains(t, err, "nope")
+
+	assert.Empty(t, a.committed)
+	assert.Equal(t, []string{"foo"}, a.aborted)
+}
+
+func TestTwoPhaseCommitOutputNoStaging(t *testing.T) {
+	a, b := &fakeStagingWriter{}, &fakeStagingWriter{noStaging: true}
+	o := testTwoPhaseCommitOutput(t, a, b)
+
+	err := o.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+	})
+	require.ErrorContains(t, err, "output 1 does not support staging")
+
+	assert.Empty(t, a.committed)
+	assert.Equal(t, []string{"foo"}, a.aborted)
+}
+
+func TestTwoPhaseCommitOutputCommitError(t *testing.T) {
+	a, b := &fakeStagingWriter{commitErr: errors.New("nope")}, &fakeStagingWriter{}
+	o := testTwoPhaseCommitOutput(t, a, b)
+
+	err := o.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+	})
+	require.ErrorContains(t, err, "nope")
+
+	// Remaining outputs are still committed once the outcome is decided.
+	assert.Equal(t, []string{"foo"}, b.committed)
+}
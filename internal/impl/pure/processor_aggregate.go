@@ -0,0 +1,529 @@
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	aggProcFieldCache         = "cache"
+	aggProcFieldKey           = "key"
+	aggProcFieldTTL           = "ttl"
+	aggProcFieldAccumulators  = "accumulators"
+	aggProcFieldAccType       = "type"
+	aggProcFieldAccValue      = "value"
+	aggProcFieldAccReducer    = "reducer"
+	aggProcFieldEmit          = "emit"
+	aggProcFieldFlushInterval = "flush_interval"
+	aggProcFieldResetOnFlush  = "reset_on_flush"
+)
+
+const (
+	aggEmitEach          = "each"
+	aggEmitAfterInterval = "after_interval"
+)
+
+// aggregateReducers are the reducers of the built in accumulator types, which
+// are executed in the same way as custom reducers.
+var aggregateReducers = map[string]string{
+	"count": `root = this.state.or(0) + 1`,
+	"sum":   `root = this.state.or(0) + this.value`,
+	"min":   `root = if this.state == null || this.value < this.state { this.value } else { this.state }`,
+	"max":   `root = if this.state == null || this.value > this.state { this.value } else { this.state }`,
+	"first": `root = if this.state == null { this.value } else { this.state }`,
+	"last":  `root = this.value`,
+}
+
+func aggregateProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Maintains aggregates of messages identified by a key within a cache resource, such as counts, sums and the last value seen, and emits the updated aggregate either with each message or periodically.").
+		Description(`
+Each message resolves a key, and the aggregate of that key is read from the cache, updated by each of the configured accumulators, and written back. An aggregate is an object where each accumulator sets a field of the same name. Updates to the aggregate of a key are serialised within this processor, and therefore aggregates are not corrupted by messages of the same key being processed in parallel. However, updates are not serialised across separate processors or Benthos instances that share a cache.
+
+### Accumulators
+
+Each accumulator has a `+"`type`"+`, and all types except `+"`count`"+` accumulate the result of the `+"`value`"+` mapping executed against each message. If the `+"`value`"+` mapping deletes the root then the accumulator is not updated by the message, which allows accumulators to only consider certain messages. The types are:
+
+- `+"`count`"+`: The number of messages.
+- `+"`sum`"+`: The sum of numerical values.
+- `+"`min`"+`: The lowest numerical value.
+- `+"`max`"+`: The highest numerical value.
+- `+"`first`"+`: The first value.
+- `+"`last`"+`: The latest value.
+- `+"`reduce`"+`: A custom `+"`reducer`"+` mapping, which is executed against an object containing the previous result of the accumulator as `+"`state`"+` (null for the first message) and the result of the `+"`value`"+` mapping as `+"`value`"+`, and provides the new result.
+
+### Emitting Aggregates
+
+When `+"`emit`"+` is `+"`each`"+` the contents of each message are replaced with the updated aggregate of its key. In order to instead add the aggregate to the original message place this processor within a `+"[`branch`](/docs/components/processors/branch)"+`.
+
+When `+"`emit`"+` is `+"`after_interval`"+` messages are dropped once they have updated their aggregates, and the aggregates of all keys updated by this processor since the previous flush are emitted as new messages by the first message processed after the `+"`flush_interval`"+` has passed. Processors only run when messages arrive, and therefore aggregates are not emitted while the input is idle. In order to flush on a timer regardless of traffic, messages with an empty key do not update any aggregate and are only used to trigger a flush, which means a `+"[`generate` input](/docs/components/inputs/generate)"+` can be combined with the input of the pipeline in order to emit ticks, as shown in the examples. When `+"`reset_on_flush`"+` is set the aggregates are deleted from the cache as they are flushed, which produces aggregates of each interval.
+
+Messages emitted by this processor have the metadata field `+"`aggregate_key`"+` set to the key of the aggregate.`).
+		Fields(
+			service.NewStringField(aggProcFieldCache).
+				Description("The [cache resource](/docs/components/caches/about) to store aggregates in."),
+			service.NewInterpolatedStringField(aggProcFieldKey).
+				Description("The key of the aggregate to update with each message.").
+				Example(`${! this.user_id }`).
+				Example(`${! meta("kafka_key") }`),
+			service.NewInterpolatedStringField(aggProcFieldTTL).
+				Description("An optional expiry period to set for each aggregate, which is reset each time it is updated. Some caches only have a general TTL and will therefore ignore this setting.").
+				Example("24h").
+				Optional().
+				Advanced(),
+			service.NewObjectMapField(aggProcFieldAccumulators,
+				service.NewStringAnnotatedEnumField(aggProcFieldAccType, map[string]string{
+					"count":  "Counts the number of messages.",
+					"sum":    "Sums the numerical result of the value mapping.",
+					"min":    "Keeps the lowest numerical result of the value mapping.",
+					"max":    "Keeps the highest numerical result of the value mapping.",
+					"first":  "Keeps the first result of the value mapping.",
+					"last":   "Keeps the latest result of the value mapping.",
+					"reduce": "Executes a custom reducer mapping.",
+				}).
+					Description("The type of accumulator."),
+				service.NewBloblangField(aggProcFieldAccValue).
+					Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) executed against each message that provides the value to accumulate. If the mapping deletes the root the accumulator is not updated by the message. When omitted the contents of each message are accumulated, or for the `count` type every message is counted.").
+					Example("root = this.price * this.quantity").
+					Example(`root = if this.status != "error" { deleted() } else { this }`).
+					Optional(),
+				service.NewBloblangField(aggProcFieldAccReducer).
+					Description("A [Bloblang mapping](/docs/guides/bloblang/about) required by the `reduce` type, which is executed against an object containing the previous result of the accumulator as `state` and the accumulated value as `value`, and provides the new result.").
+					Example(`root = this.state.or([]).append(this.value).unique()`).
+					Optional(),
+			).
+				Description("A map of accumulators to update for each message, where the name of each accumulator is the field of the aggregate that it sets."),
+			service.NewStringAnnotatedEnumField(aggProcFieldEmit, map[string]string{
+				aggEmitEach:          "The contents of each message are replaced with its updated aggregate.",
+				aggEmitAfterInterval: "Messages are dropped and updated aggregates are emitted by the first message processed after each flush interval.",
+			}).
+				Description("Determines when aggregates are emitted.").
+				Default(aggEmitEach),
+			service.NewDurationField(aggProcFieldFlushInterval).
+				Description("The interval at which updated aggregates are emitted when `emit` is `after_interval`.").
+				Default("1m"),
+			service.NewBoolField(aggProcFieldResetOnFlush).
+				Description("Whether to delete aggregates from the cache as they are flushed when `emit` is `after_interval`.").
+				Default(false).
+				Advanced(),
+		).
+		Example("Running Totals", "Here we enrich each order with the running number of orders and total spend of its customer, as well as the time of their latest order.", `
+pipeline:
+  processors:
+    - branch:
+        processors:
+          - aggregate:
+              cache: customer_totals
+              key: ${! this.customer_id }
+              accumulators:
+                orders:
+                  type: count
+                spend:
+                  type: sum
+                  value: root = this.total
+                last_order_at:
+                  type: last
+                  value: root = this.created_at
+        result_map: root.customer = this
+
+cache_resources:
+  - label: customer_totals
+    redis:
+      url: redis://localhost:6379
+`).
+		Example("Periodic Summaries", "Here we emit a summary of the errors of each service every minute, including the distinct error codes seen. A `generate` input emits an empty tick every ten seconds, which has an empty key and therefore ensures summaries are flushed even when no logs arrive.", `
+input:
+  broker:
+    inputs:
+      - kafka_franz:
+          seed_brokers: [ localhost:9092 ]
+          topics: [ logs ]
+          consumer_group: error_summaries
+      - generate:
+          interval: 10s
+          mapping: root = {}
+
+pipeline:
+  processors:
+    - aggregate:
+        cache: error_counts
+        key: ${! this.service.or("") }
+        accumulators:
+          errors:
+            type: count
+            value: 'root = if this.level != "error" { deleted() } else { this }'
+          codes:
+            type: reduce
+            value: root = this.code
+            reducer: root = this.state.or([]).append(this.value).unique()
+        emit: after_interval
+        flush_interval: 1m
+        reset_on_flush: true
+
+cache_resources:
+  - label: error_counts
+    memory: {}
+`)
+}
+
+func init() {
+	err := service.RegisterProcessor(
+		"aggregate", aggregateProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newAggregateProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type aggregateAccumulator struct {
+	name     string
+	useValue bool
+	value    *bloblang.Executor
+	reducer  *bloblang.Executor
+}
+
+type aggregateProc struct {
+	mgr *service.Resources
+	log *service.Logger
+
+	cacheName    string
+	key          *service.InterpolatedString
+	ttl          *service.InterpolatedString
+	accumulators []aggregateAccumulator
+
+	emitAfterInterval bool
+	flushInterval     time.Duration
+	resetOnFlush      bool
+	nowFn             func() time.Time
+
+	// Updates are serialised by striping keys across a fixed set of mutexes.
+	keyMuts [64]sync.Mutex
+
+	flushMut  sync.Mutex
+	dirty     map[string]struct{}
+	lastFlush time.Time
+}
+
+func newAggregateProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*aggregateProc, error) {
+	p := &aggregateProc{
+		mgr:   mgr,
+		log:   mgr.Logger(),
+		nowFn: time.Now,
+		dirty: map[string]struct{}{},
+	}
+
+	var err error
+	if p.cacheName, err = conf.FieldString(aggProcFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cacheName) {
+		return nil, fmt.Errorf("cache named %v not found", p.cacheName)
+	}
+	if p.key, err = conf.FieldInterpolatedString(aggProcFieldKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(aggProcFieldTTL) {
+		if p.ttl, err = conf.FieldInterpolatedString(aggProcFieldTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	accConfs, err := conf.FieldObjectMap(aggProcFieldAccumulators)
+	if err != nil {
+		return nil, err
+	}
+	if len(accConfs) == 0 {
+		return nil, errors.New("at least one accumulator must be specified")
+	}
+	for name, accConf := range accConfs {
+		acc := aggregateAccumulator{name: name}
+
+		accType, err := accConf.FieldString(aggProcFieldAccType)
+		if err != nil {
+			return nil, err
+		}
+		acc.useValue = accType != "count"
+		if accConf.Contains(aggProcFieldAccValue) {
+			if acc.value, err = accConf.FieldBloblang(aggProcFieldAccValue); err != nil {
+				return nil, err
+			}
+		}
+		if accType == "reduce" {
+			if !accConf.Contains(aggProcFieldAccReducer) {
+				return nil, fmt.Errorf("accumulator %v of type reduce requires a reducer", name)
+			}
+			if acc.reducer, err = accConf.FieldBloblang(aggProcFieldAccReducer); err != nil {
+				return nil, err
+			}
+		} else {
+			if accConf.Contains(aggProcFieldAccReducer) {
+				return nil, fmt.Errorf("accumulator %v of type %v cannot have a reducer", name, accType)
+			}
+			if acc.reducer, err = bloblang.Parse(aggregateReducers[accType]); err != nil {
+				return nil, err
+			}
+		}
+		p.accumulators = append(p.accumulators, acc)
+	}
+	sort.Slice(p.accumulators, func(i, j int) bool {
+		return p.accumulators[i].name < p.accumulators[j].name
+	})
+
+	emit, err := conf.FieldString(aggProcFieldEmit)
+	if err != nil {
+		return nil, err
+	}
+	p.emitAfterInterval = emit == aggEmitAfterInterval
+	if p.flushInterval, err = conf.FieldDuration(aggProcFieldFlushInterval); err != nil {
+		return nil, err
+	}
+	if p.resetOnFlush, err = conf.FieldBool(aggProcFieldResetOnFlush); err != nil {
+		return nil, err
+	}
+	p.lastFlush = p.nowFn()
+	return p, nil
+}
+
+func (p *aggregateProc) keyMut(key string) *sync.Mutex {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return &p.keyMuts[h.Sum32()%uint32(len(p.keyMuts))]
+}
+
+func (p *aggregateProc) getAggregate(ctx context.Context, key string) (map[string]any, error) {
+	var aggBytes []byte
+	var err error
+	if cerr := p.mgr.AccessCache(ctx, p.cacheName, func(c service.Cache) {
+		aggBytes, err = c.Get(ctx, key)
+	}); cerr != nil {
+		return nil, cerr
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrKeyNotFound) {
+			return map[string]any{}, nil
+		}
+		return nil, err
+	}
+
+	// Numbers are decoded as json.Number in order to preserve the precision of
+	// large integers such as counters.
+	dec := json.NewDecoder(bytes.NewReader(aggBytes))
+	dec.UseNumber()
+
+	var agg map[string]any
+	if err := dec.Decode(&agg); err != nil {
+		return nil, fmt.Errorf("failed to parse aggregate, this indicates the data was not set by this processor: %w", err)
+	}
+	if agg == nil {
+		agg = map[string]any{}
+	}
+	return agg, nil
+}
+
+func (p *aggregateProc) setAggregate(ctx context.Context, key string, agg map[string]any, ttl *time.Duration) error {
+	aggBytes, err := json.Marshal(agg)
+	if err != nil {
+		return err
+	}
+	if cerr := p.mgr.AccessCache(ctx, p.cacheName, func(c service.Cache) {
+		err = c.Set(ctx, key, aggBytes, ttl)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+func (p *aggregateProc) deleteAggregate(ctx context.Context, key string) error {
+	var err error
+	if cerr := p.mgr.AccessCache(ctx, p.cacheName, func(c service.Cache) {
+		err = c.Delete(ctx, key)
+	}); cerr != nil {
+		return cerr
+	}
+	if errors.Is(err, service.ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// accumulatorValues resolves the value of each accumulator for a message,
+// where accumulators whose value mapping deleted the root are omitted.
+func (p *aggregateProc) accumulatorValues(msg *service.Message) (map[string]any, error) {
+	values := make(map[string]any, len(p.accumulators))
+	for _, acc := range p.accumulators {
+		resMsg := msg
+		if acc.value != nil {
+			var err error
+			if resMsg, err = msg.BloblangQuery(acc.value); err != nil {
+				return nil, fmt.Errorf("accumulator %v value mapping failed: %w", acc.name, err)
+			}
+			if resMsg == nil {
+				continue
+			}
+		}
+		if !acc.useValue {
+			values[acc.name] = nil
+			continue
+		}
+
+		v, err := resMsg.AsStructured()
+		if err != nil {
+			// Values that aren't structured, such as strings, are accumulated
+			// as raw strings.
+			vBytes, bErr := resMsg.AsBytes()
+			if bErr != nil {
+				return nil, fmt.Errorf("accumulator %v value could not be read: %w", acc.name, bErr)
+			}
+			v = string(vBytes)
+		}
+		values[acc.name] = v
+	}
+	return values, nil
+}
+
+func (p *aggregateProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to interpolate key expression: %w", err)
+	}
+	if p.emitAfterInterval && key == "" {
+		return p.flushIfDue(ctx)
+	}
+
+	var ttl *time.Duration
+	if p.ttl != nil {
+		ttlStr, err := p.ttl.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to interpolate ttl expression: %w", err)
+		}
+		tmpTTL, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl expression: %w", err)
+		}
+		ttl = &tmpTTL
+	}
+
+	values, err := p.accumulatorValues(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	mut := p.keyMut(key)
+	mut.Lock()
+	agg, err := p.updateAggregate(ctx, key, values, ttl)
+	mut.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.emitAfterInterval {
+		msg.SetStructuredMut(agg)
+		msg.MetaSetMut("aggregate_key", key)
+		return service.MessageBatch{msg}, nil
+	}
+
+	p.flushMut.Lock()
+	p.dirty[key] = struct{}{}
+	p.flushMut.Unlock()
+
+	return p.flushIfDue(ctx)
+}
+
+// flushIfDue flushes the updated aggregates when the flush interval has
+// passed since the previous flush.
+func (p *aggregateProc) flushIfDue(ctx context.Context) (service.MessageBatch, error) {
+	p.flushMut.Lock()
+	defer p.flushMut.Unlock()
+
+	if p.nowFn().Sub(p.lastFlush) < p.flushInterval {
+		return nil, nil
+	}
+	return p.flush(ctx)
+}
+
+// updateAggregate applies accumulator values to the aggregate of a key. Must
+// be called with the mutex of the key held.
+func (p *aggregateProc) updateAggregate(ctx context.Context, key string, values map[string]any, ttl *time.Duration) (map[string]any, error) {
+	agg, err := p.getAggregate(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, acc := range p.accumulators {
+		v, exists := values[acc.name]
+		if !exists {
+			continue
+		}
+		res, err := acc.reducer.Query(map[string]any{
+			"state": agg[acc.name],
+			"value": v,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("accumulator %v reducer failed: %w", acc.name, err)
+		}
+		agg[acc.name] = res
+	}
+
+	if err := p.setAggregate(ctx, key, agg, ttl); err != nil {
+		return nil, err
+	}
+	return agg, nil
+}
+
+// flush emits the aggregates of all keys updated since the last flush, where
+// keys that fail to be read are kept for the next flush. Must be called with
+// the flush mutex held.
+func (p *aggregateProc) flush(ctx context.Context) (service.MessageBatch, error) {
+	keys := make([]string, 0, len(p.dirty))
+	for k := range p.dirty {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var batch service.MessageBatch
+	remaining := map[string]struct{}{}
+	for _, key := range keys {
+		mut := p.keyMut(key)
+		mut.Lock()
+		agg, err := p.getAggregate(ctx, key)
+		if err == nil && p.resetOnFlush {
+			err = p.deleteAggregate(ctx, key)
+		}
+		mut.Unlock()
+		if err != nil {
+			p.log.Errorf("Failed to flush aggregate %v: %v", key, err)
+			remaining[key] = struct{}{}
+			continue
+		}
+
+		msg := service.NewMessage(nil)
+		msg.SetStructuredMut(agg)
+		msg.MetaSetMut("aggregate_key", key)
+		batch = append(batch, msg)
+	}
+
+	p.dirty = remaining
+	p.lastFlush = p.nowFn()
+	return batch, nil
+}
+
+func (p *aggregateProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,288 @@
+package pure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestAggregateEach(t *testing.T) {
+	conf, err := aggregateProcSpec().ParseYAML(`
+cache: foo
+key: ${! this.user }
+accumulators:
+  count:
+    type: count
+  total:
+    type: sum
+    value: root = this.amount
+  smallest:
+    type: min
+    value: root = this.amount
+  largest:
+    type: max
+    value: root = this.amount
+  first_seen:
+    type: first
+    value: root = this.ts
+  last_seen:
+    type: last
+    value: root = this.ts
+  errors:
+    type: count
+    value: 'root = if !this.error.or(false) { deleted() } else { this }'
+  pages:
+    type: reduce
+    value: root = this.page
+    reducer: root = this.state.or([]).append(this.value).unique()
+`, nil)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	proc, err := newAggregateProcFromParsed(conf, mRes)
+	require.NoError(t, err)
+
+	tCtx := context.Background()
+	for _, test := range []struct {
+		input  string
+		output string
+	}{
+		{
+			input:  `{"user":"a","amount":5,"ts":1,"page":"/"}`,
+			output: `{"count":1,"first_seen":1,"largest":5,"last_seen":1,"pages":["/"],"smallest":5,"total":5}`,
+		},
+		{
+			input:  `{"user":"b","amount":2.5,"ts":2,"page":"/"}`,
+			output: `{"count":1,"first_seen":2,"largest":2.5,"last_seen":2,"pages":["/"],"smallest":2.5,"total":2.5}`,
+		},
+		{
+			input:  `{"user":"a","amount":10,"ts":3,"page":"/a","error":true}`,
+			output: `{"count":2,"errors":1,"first_seen":1,"largest":10,"last_seen":3,"pages":["/","/a"],"smallest":5,"total":15}`,
+		},
+		{
+			input:  `{"user":"a","amount":1,"ts":4,"page":"/"}`,
+			output: `{"count":3,"errors":1,"first_seen":1,"largest":10,"last_seen":4,"pages":["/","/a"],"smallest":1,"total":16}`,
+		},
+	} {
+		resBatch, err := proc.Process(tCtx, service.NewMessage([]byte(test.input)))
+		require.NoError(t, err)
+		require.Len(t, resBatch, 1)
+
+		mBytes, err := resBatch[0].AsBytes()
+		require.NoError(t, err)
+		assert.JSONEq(t, test.output, string(mBytes), test.input)
+	}
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		aggBytes, err := c.Get(tCtx, "b")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"count":1,"first_seen":2,"largest":2.5,"last_seen":2,"pages":["/"],"smallest":2.5,"total":2.5}`, string(aggBytes))
+	}))
+}
+
+func TestAggregateLargeCounter(t *testing.T) {
+	conf, err := aggregateProcSpec().ParseYAML(`
+cache: foo
+key: foo
+accumulators:
+  count:
+    type: count
+`, nil)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	proc, err := newAggregateProcFromParsed(conf, mRes)
+	require.NoError(t, err)
+
+	tCtx := context.Background()
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		require.NoError(t, c.Set(tCtx, "foo", []byte(`{"count":9007199254740993}`), nil))
+	}))
+
+	resBatch, err := proc.Process(tCtx, service.NewMessage(nil))
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+
+	mBytes, err := resBatch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"count":9007199254740994}`, string(mBytes))
+}
+
+func TestAggregateParallel(t *testing.T) {
+	conf, err := aggregateProcSpec().ParseYAML(`
+cache: foo
+key: ${! this.key }
+accumulators:
+  count:
+    type: count
+`, nil)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	proc, err := newAggregateProcFromParsed(conf, mRes)
+	require.NoError(t, err)
+
+	tCtx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, err := proc.Process(tCtx, service.NewMessage([]byte(fmt.Sprintf(`{"key":"k%v"}`, j%3))))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		for k, exp := range map[string]string{"k0": "340", "k1": "330", "k2": "330"} {
+			aggBytes, err := c.Get(tCtx, k)
+			require.NoError(t, err)
+			assert.Equal(t, `{"count":`+exp+`}`, string(aggBytes), k)
+		}
+	}))
+}
+
+func TestAggregateAfterInterval(t *testing.T) {
+	conf, err := aggregateProcSpec().ParseYAML(`
+cache: foo
+key: ${! this.key.or("") }
+accumulators:
+  total:
+    type: sum
+    value: root = this.n
+emit: after_interval
+flush_interval: 1m
+reset_on_flush: true
+`, nil)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+	proc, err := newAggregateProcFromParsed(conf, mRes)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	proc.nowFn = func() time.Time { return now }
+	proc.lastFlush = now
+
+	tCtx := context.Background()
+	for _, input := range []string{
+		`{"key":"b","n":1}`,
+		`{"key":"a","n":2}`,
+		`{"key":"b","n":3}`,
+	} {
+		resBatch, err := proc.Process(tCtx, service.NewMessage([]byte(input)))
+		require.NoError(t, err)
+		assert.Empty(t, resBatch)
+	}
+
+	now = now.Add(time.Minute)
+	resBatch, err := proc.Process(tCtx, service.NewMessage([]byte(`{"key":"a","n":4}`)))
+	require.NoError(t, err)
+	require.Len(t, resBatch, 2)
+
+	for i, exp := range []struct {
+		key, agg string
+	}{
+		{key: "a", agg: `{"total":6}`},
+		{key: "b", agg: `{"total":4}`},
+	} {
+		mBytes, err := resBatch[i].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp.agg, string(mBytes))
+
+		key, _ := resBatch[i].MetaGet("aggregate_key")
+		assert.Equal(t, exp.key, key)
+	}
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		_, err := c.Get(tCtx, "a")
+		assert.ErrorIs(t, err, service.ErrKeyNotFound)
+	}))
+
+	resBatch, err = proc.Process(tCtx, service.NewMessage([]byte(`{"key":"a","n":5}`)))
+	require.NoError(t, err)
+	assert.Empty(t, resBatch)
+
+	// Messages with an empty key only trigger flushes.
+	resBatch, err = proc.Process(tCtx, service.NewMessage([]byte(`{}`)))
+	require.NoError(t, err)
+	assert.Empty(t, resBatch)
+
+	now = now.Add(time.Minute)
+	resBatch, err = proc.Process(tCtx, service.NewMessage([]byte(`{}`)))
+	require.NoError(t, err)
+	require.Len(t, resBatch, 1)
+
+	mBytes, err := resBatch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"total":5}`, string(mBytes))
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		_, err := c.Get(tCtx, "")
+		assert.ErrorIs(t, err, service.ErrKeyNotFound)
+	}))
+}
+
+func TestAggregateBadConfig(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		config string
+		errStr string
+	}{
+		{
+			name: "missing cache",
+			config: `
+cache: bar
+key: foo
+accumulators:
+  count:
+    type: count
+`,
+			errStr: "cache named bar not found",
+		},
+		{
+			name: "reduce without reducer",
+			config: `
+cache: foo
+key: foo
+accumulators:
+  things:
+    type: reduce
+`,
+			errStr: "accumulator things of type reduce requires a reducer",
+		},
+		{
+			name: "reducer without reduce",
+			config: `
+cache: foo
+key: foo
+accumulators:
+  things:
+    type: sum
+    reducer: root = this.value
+`,
+			errStr: "accumulator things of type sum cannot have a reducer",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := aggregateProcSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+			_, err = newAggregateProcFromParsed(conf, mRes)
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
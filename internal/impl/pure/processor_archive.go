@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/benthosdev/benthos/v4/internal/batch"
+	"github.com/benthosdev/benthos/v4/internal/batchformat"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -32,6 +33,7 @@ The functionality of this processor depends on being applied across messages tha
 			`binary`:      `Archive messages to a [binary blob format](https://github.com/benthosdev/benthos/blob/main/internal/message/message.go#L96).`,
 			`lines`:       `Join the raw contents of each message and insert a line break between each one.`,
 			`json_array`:  `Attempt to parse each message as a JSON document and append the result to an array, which becomes the contents of the resulting message.`,
+			`csv`:         "Attempt to parse each message as a JSON object and write it as a row of a csv file, where the first row is a header of all keys of the objects sorted alphabetically. Keys that are missing from an object result in empty values, and values that are objects or arrays are written as JSON. Requires version 4.28.0 or newer.",
 		}).Description("The archiving format to apply.")).
 		Field(service.NewInterpolatedStringField("path").
 			Description("The path to set for each message in the archive (when applicable).").
@@ -181,6 +183,15 @@ func jsonArrayArchive(hFunc headerFunc, msg service.MessageBatch) (*service.Mess
 	return msg[0], nil
 }
 
+func csvArchive(hFunc headerFunc, msg service.MessageBatch) (*service.Message, error) {
+	data, err := batchformat.EncodeCSV(msg, nil)
+	if err != nil {
+		return nil, err
+	}
+	msg[0].SetBytes(data)
+	return msg[0], nil
+}
+
 func strToArchiver(str string) (archiveFunc, error) {
 	switch str {
 	case "tar":
@@ -195,6 +206,8 @@ func strToArchiver(str string) (archiveFunc, error) {
 		return jsonArrayArchive, nil
 	case "concatenate":
 		return concatenateArchive, nil
+	case "csv":
+		return csvArchive, nil
 	}
 	return nil, fmt.Errorf("archive format not recognised: %v", str)
 }
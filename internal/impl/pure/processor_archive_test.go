@@ -247,6 +247,44 @@ format: json_array
 	assert.Equal(t, `[{"foo":"bar"},5,"testing 123",["nested","array"],true]`, string(bBytes))
 }
 
+func TestArchiveCSV(t *testing.T) {
+	conf, err := archiveProcConfig().ParseYAML(`
+format: csv
+`, nil)
+	require.NoError(t, err)
+
+	proc, err := newArchiveFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	var msg service.MessageBatch
+	for _, e := range []string{
+		`{"id":1,"name":"foo","tags":["a","b"]}`,
+		`{"id":2,"name":"bar, baz","active":true}`,
+		`{"id":3,"name":null}`,
+	} {
+		msg = append(msg, service.NewMessage([]byte(e)))
+	}
+
+	batches, err := proc.ProcessBatch(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 1)
+
+	bBytes, err := batches[0][0].AsBytes()
+	require.NoError(t, err)
+
+	assert.Equal(t, `active,id,name,tags
+,1,foo,"[""a"",""b""]"
+true,2,"bar, baz",
+,3,,
+`, string(bBytes))
+
+	_, err = proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`["not","an","object"]`)),
+	})
+	require.Error(t, err)
+}
+
 func TestArchiveEmpty(t *testing.T) {
 	conf, err := archiveProcConfig().ParseYAML(`
 format: json_array
@@ -3,7 +3,9 @@ package pure
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"time"
@@ -26,11 +28,18 @@ func newCachedProcessorConfigSpec() *service.ConfigSpec {
 			Example("errored()").
 			Optional()).
 		Field(service.NewInterpolatedStringField("key").
-			Description("A key to be resolved for each message, if the key already exists in the cache then the cached result is used, otherwise the processors are applied and the result is cached under this key. The key could be static and therefore apply generally to all messages or it could be an interpolated expression that is potentially unique for each message.").
+			Description("A key to be resolved for each message, if the key already exists in the cache then the cached result is used, otherwise the processors are applied and the result is cached under this key. The key could be static and therefore apply generally to all messages or it could be an interpolated expression that is potentially unique for each message. If neither this field nor `key_mapping` are set then the key is a SHA-256 hash of the contents of each message.").
 			Example("my_foo_result").
 			Example(`${! this.document.id }`).
 			Example(`${! meta("kafka_key") }`).
-			Example(`${! meta("kafka_topic") }`)).
+			Example(`${! meta("kafka_topic") }`).
+			Optional()).
+		Field(service.NewBloblangField("key_mapping").
+			Description("An alternative to `key` where a Bloblang mapping is executed on each message and the key is a SHA-256 hash of the result. This is useful for memoizing transformations of messages by the parts of their contents that affect the result, without the need to construct a key by hand. When sharing a cache between multiple `cached` processors include a distinguishing value in the result in order to avoid collisions.").
+			Example(`root = [ "geo", this.address, this.postcode ]`).
+			Example(`root = this.without("request_id", "received_at")`).
+			Optional().
+			Version("4.28.0")).
 		Field(service.NewInterpolatedStringField("ttl").
 			Description("An optional expiry period to set for each cache entry. Some caches only have a general TTL and will therefore ignore this setting.").
 			Optional()).
@@ -58,6 +67,26 @@ cache_resources:
     memory:
       # Disable compaction so that cached items never expire
       compaction_interval: ""
+`,
+		).
+		Example(
+			"Memoized Transformations",
+			"In the following example an expensive transformation is memoized by the contents of the message that it depends on, where messages that only differ by their `request_id` reuse the result of the first.",
+			`
+pipeline:
+  processors:
+    - cached:
+        key_mapping: 'root = this.without("request_id")'
+        cache: results_cache
+        ttl: 1h
+        processors:
+          - http:
+              url: http://example.com/classify
+              verb: POST
+
+cache_resources:
+  - label: results_cache
+    memory: {}
 `,
 		).
 		Example(
@@ -102,6 +131,7 @@ type cachedProcessor struct {
 
 	cacheName  string
 	key        *service.InterpolatedString
+	keyMapping *bloblang.Executor
 	ttl        *service.InterpolatedString
 	processors []*service.OwnedProcessor
 	skipOn     *bloblang.Executor
@@ -119,8 +149,18 @@ func newCachedProcessorFromParsedConf(manager *service.Resources, conf *service.
 		return nil, fmt.Errorf("cache named %v not found", proc.cacheName)
 	}
 
-	if proc.key, err = conf.FieldInterpolatedString("key"); err != nil {
-		return nil, err
+	if conf.Contains("key") {
+		if proc.key, err = conf.FieldInterpolatedString("key"); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains("key_mapping") {
+		if proc.key != nil {
+			return nil, errors.New("cannot set both key and key_mapping")
+		}
+		if proc.keyMapping, err = conf.FieldBloblang("key_mapping"); err != nil {
+			return nil, err
+		}
 	}
 
 	if conf.Contains("ttl") {
@@ -145,10 +185,38 @@ func newCachedProcessorFromParsedConf(manager *service.Resources, conf *service.
 	return
 }
 
+func (proc *cachedProcessor) getKey(msg *service.Message) (string, error) {
+	if proc.key != nil {
+		cacheKey, err := proc.key.TryString(msg)
+		if err != nil {
+			return "", fmt.Errorf("failed to interpolate key expression: %w", err)
+		}
+		return cacheKey, nil
+	}
+
+	keyMsg := msg
+	if proc.keyMapping != nil {
+		var err error
+		if keyMsg, err = msg.BloblangQuery(proc.keyMapping); err != nil {
+			return "", fmt.Errorf("failed to execute key_mapping: %w", err)
+		}
+		if keyMsg == nil {
+			return "", errors.New("key_mapping resulted in a deleted message")
+		}
+	}
+
+	keyBytes, err := keyMsg.AsBytes()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(keyBytes)
+	return hex.EncodeToString(hash[:]), nil
+}
+
 func (proc *cachedProcessor) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
-	cacheKey, err := proc.key.TryString(msg)
+	cacheKey, err := proc.getKey(msg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to interpolate key expression: %w", err)
+		return nil, err
 	}
 
 	var ttl *time.Duration
@@ -229,3 +229,73 @@ processors:
 
 	assert.NoError(t, proc.Close(tCtx))
 }
+
+func TestCachedKeyMapping(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		conf   string
+		inputs []string
+		hits   []bool
+	}{
+		{
+			name:   "content hash",
+			conf:   ``,
+			inputs: []string{`{"id":"a","n":1}`, `{"id":"b","n":1}`, `{"id":"a","n":1}`},
+			hits:   []bool{false, false, true},
+		},
+		{
+			name:   "key mapping",
+			conf:   `key_mapping: 'root = this.without("id")'`,
+			inputs: []string{`{"id":"a","n":1}`, `{"id":"b","n":1}`, `{"id":"c","n":2}`},
+			hits:   []bool{false, true, false},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := newCachedProcessorConfigSpec().ParseYAML(test.conf+`
+cache: foo
+processors:
+  - mapping: 'root = this.merge({"result": uuid_v4()})'
+`, nil)
+			require.NoError(t, err)
+
+			mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+			proc, err := newCachedProcessorFromParsedConf(mRes, conf)
+			require.NoError(t, err)
+
+			tCtx := context.Background()
+
+			var results []string
+			for i, input := range test.inputs {
+				resBatch, err := proc.Process(tCtx, service.NewMessage([]byte(input)))
+				require.NoError(t, err)
+				require.Len(t, resBatch, 1)
+
+				resBytes, err := resBatch[0].AsBytes()
+				require.NoError(t, err)
+
+				if test.hits[i] {
+					assert.Contains(t, results, string(resBytes), i)
+				} else {
+					assert.NotContains(t, results, string(resBytes), i)
+				}
+				results = append(results, string(resBytes))
+			}
+			require.NoError(t, proc.Close(tCtx))
+		})
+	}
+}
+
+func TestCachedKeyConflict(t *testing.T) {
+	conf, err := newCachedProcessorConfigSpec().ParseYAML(`
+key: foo
+key_mapping: root = this.id
+cache: foo
+processors: []
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newCachedProcessorFromParsedConf(service.MockResources(service.MockResourcesOptAddCache("foo")), conf)
+	require.EqualError(t, err, "cannot set both key and key_mapping")
+}
@@ -0,0 +1,143 @@
+package pure
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	cpFieldMaxSize = "max_size"
+
+	chunkMetaID    = "chunk_id"
+	chunkMetaIndex = "chunk_index"
+	chunkMetaCount = "chunk_count"
+)
+
+func chunkProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Splits messages that exceed a maximum size into multiple chunks that can later be reassembled with the `dechunk` processor.").
+		Description(`
+Messages that are within the maximum size are left unchanged, other messages are split into as many messages as are needed to fit their raw contents, each of which is given the following metadata fields:
+
+- `+"`chunk_id`"+`: A unique identifier shared by all chunks of the original message.
+- `+"`chunk_index`"+`: The position of the chunk, starting at zero.
+- `+"`chunk_count`"+`: The total number of chunks of the original message.
+
+All chunks retain the metadata of the original message. Since outputs may include metadata within their payloads (such as message attributes) the `+"`max_size`"+` should allow some headroom below the hard limit of a sink.`).
+		Example(
+			"Sending Large Messages via SQS",
+			"SQS rejects messages larger than 256KiB, here we split larger messages into chunks that are sent as individual messages and reassembled by the consumer.",
+			`
+output:
+  aws_sqs:
+    url: https://sqs.us-east-2.amazonaws.com/123456789012/MyQueue
+  processors:
+    - chunk:
+        max_size: 200KiB
+`,
+		).
+		Fields(
+			service.NewStringField(cpFieldMaxSize).
+				Description("The maximum size of each chunk, either as a number of bytes or a human readable size.").
+				Example("200KiB").
+				Example("1MB"),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("chunk", chunkProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newChunkProcFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type chunkProc struct {
+	maxSize int
+}
+
+func newChunkProcFromParsed(conf *service.ParsedConfig) (*chunkProc, error) {
+	maxSizeStr, err := conf.FieldString(cpFieldMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := humanize.ParseBytes(maxSizeStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse max_size: %w", err)
+	}
+	if maxSize == 0 || maxSize > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid max_size: %v", maxSizeStr)
+	}
+	return &chunkProc{maxSize: int(maxSize)}, nil
+}
+
+func (p *chunkProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	newBatch := make(service.MessageBatch, 0, len(batch))
+	for _, msg := range batch {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(mBytes) <= p.maxSize {
+			newBatch = append(newBatch, msg)
+			continue
+		}
+
+		u4, err := uuid.NewV4()
+		if err != nil {
+			return nil, err
+		}
+		id := u4.String()
+
+		count := (len(mBytes) + p.maxSize - 1) / p.maxSize
+		for i := 0; i < count; i++ {
+			end := (i + 1) * p.maxSize
+			if end > len(mBytes) {
+				end = len(mBytes)
+			}
+			chunk := msg.Copy()
+			chunk.SetBytes(mBytes[i*p.maxSize : end])
+			chunk.MetaSetMut(chunkMetaID, id)
+			chunk.MetaSetMut(chunkMetaIndex, int64(i))
+			chunk.MetaSetMut(chunkMetaCount, int64(count))
+			newBatch = append(newBatch, chunk)
+		}
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (p *chunkProc) Close(ctx context.Context) error {
+	return nil
+}
+
+// chunkMetaInt reads an integer chunk metadata field, which may have been
+// carried over as a string by an output and input.
+func chunkMetaInt(msg *service.Message, key string) (int, error) {
+	v, exists := msg.MetaGetMut(key)
+	if !exists {
+		return 0, fmt.Errorf("metadata field %v is missing", key)
+	}
+	switch t := v.(type) {
+	case int64:
+		return int(t), nil
+	case int:
+		return t, nil
+	case float64:
+		return int(t), nil
+	case string:
+		return strconv.Atoi(t)
+	}
+	return 0, fmt.Errorf("metadata field %v has unexpected type %T", key, v)
+}
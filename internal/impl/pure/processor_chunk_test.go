@@ -0,0 +1,126 @@
+package pure
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testChunkProcs(t testing.TB, maxSize, timeout string) (*chunkProc, *dechunkProc) {
+	t.Helper()
+
+	cConf, err := chunkProcSpec().ParseYAML(`max_size: `+maxSize, nil)
+	require.NoError(t, err)
+	chunker, err := newChunkProcFromParsed(cConf)
+	require.NoError(t, err)
+
+	dConf, err := dechunkProcSpec().ParseYAML(`timeout: `+timeout, nil)
+	require.NoError(t, err)
+	dechunker, err := newDechunkProcFromParsed(dConf, service.MockResources())
+	require.NoError(t, err)
+
+	return chunker, dechunker
+}
+
+func TestChunkDechunk(t *testing.T) {
+	chunker, dechunker := testChunkProcs(t, "4", "1m")
+
+	large := service.NewMessage([]byte("hello world"))
+	large.MetaSetMut("foo", "bar")
+
+	batches, err := chunker.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte("hey")),
+		large,
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Len(t, batches[0], 4)
+
+	var chunks []string
+	for i, msg := range batches[0][1:] {
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		chunks = append(chunks, string(mBytes))
+
+		v, _ := msg.MetaGetMut("chunk_index")
+		assert.Equal(t, int64(i), v)
+		v, _ = msg.MetaGetMut("chunk_count")
+		assert.Equal(t, int64(3), v)
+		v, _ = msg.MetaGetMut("foo")
+		assert.Equal(t, "bar", v)
+	}
+	assert.Equal(t, []string{"hell", "o wo", "rld"}, chunks)
+
+	// Deliver chunks out of order and across batches, with metadata converted
+	// to strings as if carried over by headers.
+	stringify := func(msg *service.Message) *service.Message {
+		msg = msg.Copy()
+		for _, k := range []string{"chunk_index", "chunk_count"} {
+			v, _ := msg.MetaGet(k)
+			msg.MetaSetMut(k, v)
+		}
+		return msg
+	}
+
+	out, err := dechunker.ProcessBatch(context.Background(), service.MessageBatch{
+		stringify(batches[0][3]),
+		batches[0][0],
+	})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Len(t, out[0], 1)
+	mBytes, err := out[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hey", string(mBytes))
+
+	out, err = dechunker.ProcessBatch(context.Background(), service.MessageBatch{
+		stringify(batches[0][1]),
+		stringify(batches[0][2]),
+	})
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	require.Len(t, out[0], 1)
+
+	mBytes, err = out[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(mBytes))
+
+	v, _ := out[0][0].MetaGetMut("foo")
+	assert.Equal(t, "bar", v)
+	_, exists := out[0][0].MetaGetMut("chunk_id")
+	assert.False(t, exists)
+	assert.Empty(t, dechunker.pending)
+}
+
+func TestDechunkExpiry(t *testing.T) {
+	chunker, dechunker := testChunkProcs(t, "1KB", "1m")
+
+	now := time.Now()
+	dechunker.nowFn = func() time.Time { return now }
+
+	batches, err := chunker.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(strings.Repeat("a", 3000))),
+	})
+	require.NoError(t, err)
+	require.Len(t, batches[0], 3)
+
+	out, err := dechunker.ProcessBatch(context.Background(), batches[0][:2])
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	assert.Len(t, dechunker.pending, 1)
+
+	now = now.Add(time.Minute * 2)
+	out, err = dechunker.ProcessBatch(context.Background(), batches[0][2:])
+	require.NoError(t, err)
+	assert.Empty(t, out)
+	assert.Len(t, dechunker.pending, 1)
+	for _, pc := range dechunker.pending {
+		assert.Equal(t, 1, pc.received)
+	}
+}
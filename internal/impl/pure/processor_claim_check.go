@@ -0,0 +1,304 @@
+package pure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ccpFieldOperator  = "operator"
+	ccpFieldCache     = "cache"
+	ccpFieldThreshold = "threshold"
+	ccpFieldKey       = "key"
+	ccpFieldTTL       = "ttl"
+	ccpFieldDelete    = "delete"
+
+	// ccpMetaKey is the metadata key that holds the key of an offloaded
+	// payload.
+	ccpMetaKey = "claim_check_key"
+)
+
+func claimCheckProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Integration").
+		Beta().
+		Version("4.28.0").
+		Summary("Offloads large payloads to a cache, such as object storage, and replaces them with a small reference that can be resolved back into the full payload downstream, which is known as the claim check pattern.").
+		Description(`
+This processor allows messages that exceed the size limits of a broker to flow through it. The `+"`store`"+` operator is used before writing messages to the broker, and the `+"`resolve`"+` operator is used after reading them back.
+
+### Storing
+
+Messages with a payload larger than the `+"`threshold`"+` are written to the `+"`cache`"+` under the `+"`key`"+`, and their payload is replaced with a reference of the form:
+
+`+"```json"+`
+{"claim_check":{"key":"3a6f0b7e-8d4c-4b0e-9f5e-1c2d3e4f5a6b","size":1048576}}
+`+"```"+`
+
+The metadata field `+"`claim_check_key`"+` is also set to the key. Metadata is otherwise left unchanged and is not stored, and therefore must be carried by the broker if it is needed downstream. Messages no larger than the `+"`threshold`"+` are left unchanged.
+
+### Resolving
+
+Messages that contain a reference have their payload replaced with the payload read from the `+"`cache`"+`, and all other messages are left unchanged. When `+"`delete`"+` is `+"`true`"+` the stored payload is removed from the cache once it is resolved, which means a message can no longer be resolved should it be consumed again, and so a time to live or lifecycle policy on the storage is usually preferable.
+
+Any cache can be used for storage, but caches backed by object storage such as [`+"`aws_s3`"+`](/docs/components/caches/aws_s3), [`+"`gcp_cloud_storage`"+`](/docs/components/caches/gcp_cloud_storage) and [`+"`azure_blob_storage`"+`](/docs/components/caches/azure_blob_storage) are best suited to large payloads.
+
+### Metrics
+
+The counters `+"`claim_check_stored`"+` and `+"`claim_check_resolved`"+` are incremented for each payload stored and resolved respectively.`).
+		Example(
+			"Offloading to S3",
+			"Here we write messages to SQS, which has a limit of 256KiB per message, and offload larger payloads to an S3 bucket. Messages are resolved by another pipeline consuming from the queue.",
+			`
+output:
+  aws_sqs:
+    url: https://sqs.us-west-2.amazonaws.com/123456789012/events
+  processors:
+    - claim_check:
+        operator: store
+        cache: claims
+        threshold: 200000
+        key: events/${! uuid_v4() }
+
+cache_resources:
+  - label: claims
+    aws_s3:
+      bucket: event-claims
+`,
+		).
+		Example(
+			"Resolving from S3",
+			"Here we resolve the references written by the previous example back into full payloads.",
+			`
+input:
+  aws_sqs:
+    url: https://sqs.us-west-2.amazonaws.com/123456789012/events
+  processors:
+    - claim_check:
+        operator: resolve
+        cache: claims
+
+cache_resources:
+  - label: claims
+    aws_s3:
+      bucket: event-claims
+`,
+		).
+		Fields(
+			service.NewStringAnnotatedEnumField(ccpFieldOperator, map[string]string{
+				"store":   "Offload payloads larger than the `threshold` to the cache and replace them with a reference.",
+				"resolve": "Replace references with the payloads read from the cache.",
+			}).
+				Description("The operation to perform on messages."),
+			service.NewStringField(ccpFieldCache).
+				Description("The [`cache` resource](/docs/components/caches/about) to store payloads in."),
+			service.NewIntField(ccpFieldThreshold).
+				Description("The size in bytes above which payloads are offloaded. Used by the `store` operator.").
+				Default(262144),
+			service.NewInterpolatedStringField(ccpFieldKey).
+				Description("The key to store each payload under. Used by the `store` operator.").
+				Example(`events/${! uuid_v4() }`).
+				Default(`${! uuid_v4() }`),
+			service.NewInterpolatedStringField(ccpFieldTTL).
+				Description("An optional time to live for stored payloads, for caches that support it. Used by the `store` operator.").
+				Example("72h").
+				Advanced().
+				Optional(),
+			service.NewBoolField(ccpFieldDelete).
+				Description("Whether to delete payloads from the cache once they are resolved. Used by the `resolve` operator.").
+				Default(false).
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor("claim_check", claimCheckProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newClaimCheckProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// claimCheckRef identifies an offloaded payload.
+type claimCheckRef struct {
+	Key  string `json:"key"`
+	Size int    `json:"size"`
+}
+
+// claimCheckEnvelope is the payload that replaces an offloaded payload.
+type claimCheckEnvelope struct {
+	ClaimCheck *claimCheckRef `json:"claim_check"`
+}
+
+type claimCheckProc struct {
+	operator  string
+	cache     string
+	threshold int
+	key       *service.InterpolatedString
+	ttl       *service.InterpolatedString
+	delete    bool
+	mgr       *service.Resources
+
+	mStored   *service.MetricCounter
+	mResolved *service.MetricCounter
+}
+
+func newClaimCheckProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*claimCheckProc, error) {
+	p := &claimCheckProc{
+		mgr:       mgr,
+		mStored:   mgr.Metrics().NewCounter("claim_check_stored"),
+		mResolved: mgr.Metrics().NewCounter("claim_check_resolved"),
+	}
+
+	var err error
+	if p.operator, err = conf.FieldString(ccpFieldOperator); err != nil {
+		return nil, err
+	}
+	if p.cache, err = conf.FieldString(ccpFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(p.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", p.cache)
+	}
+	if p.threshold, err = conf.FieldInt(ccpFieldThreshold); err != nil {
+		return nil, err
+	}
+	if p.threshold < 0 {
+		return nil, fmt.Errorf("threshold must not be negative, got %v", p.threshold)
+	}
+	if p.key, err = conf.FieldInterpolatedString(ccpFieldKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(ccpFieldTTL) {
+		if p.ttl, err = conf.FieldInterpolatedString(ccpFieldTTL); err != nil {
+			return nil, err
+		}
+	}
+	if p.delete, err = conf.FieldBool(ccpFieldDelete); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *claimCheckProc) store(ctx context.Context, msg *service.Message) error {
+	payload, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+	if len(payload) <= p.threshold {
+		return nil
+	}
+
+	key, err := p.key.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("key interpolation error: %w", err)
+	}
+	if key == "" {
+		return errors.New("key interpolation resulted in an empty string")
+	}
+
+	var ttl *time.Duration
+	if p.ttl != nil {
+		ttlStr, err := p.ttl.TryString(msg)
+		if err != nil {
+			return fmt.Errorf("ttl interpolation error: %w", err)
+		}
+		if ttlStr != "" {
+			d, err := time.ParseDuration(ttlStr)
+			if err != nil {
+				return fmt.Errorf("failed to parse ttl: %w", err)
+			}
+			ttl = &d
+		}
+	}
+
+	var setErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		setErr = c.Set(ctx, key, payload, ttl)
+	}); err != nil {
+		return err
+	}
+	if setErr != nil {
+		return fmt.Errorf("failed to store payload: %w", setErr)
+	}
+
+	refBytes, err := json.Marshal(claimCheckEnvelope{
+		ClaimCheck: &claimCheckRef{Key: key, Size: len(payload)},
+	})
+	if err != nil {
+		return err
+	}
+	msg.SetBytes(refBytes)
+	msg.MetaSetMut(ccpMetaKey, key)
+	p.mStored.Incr(1)
+	return nil
+}
+
+// parseClaimCheckRef returns the key of a reference, or an empty string when a
+// payload is not a reference.
+func parseClaimCheckRef(payload []byte) string {
+	if !bytes.HasPrefix(bytes.TrimSpace(payload), []byte(`{"claim_check"`)) {
+		return ""
+	}
+	var env claimCheckEnvelope
+	if err := json.Unmarshal(payload, &env); err != nil || env.ClaimCheck == nil {
+		return ""
+	}
+	return env.ClaimCheck.Key
+}
+
+func (p *claimCheckProc) resolve(ctx context.Context, msg *service.Message) error {
+	payload, err := msg.AsBytes()
+	if err != nil {
+		return err
+	}
+	key := parseClaimCheckRef(payload)
+	if key == "" {
+		return nil
+	}
+
+	var getErr, delErr error
+	if err := p.mgr.AccessCache(ctx, p.cache, func(c service.Cache) {
+		if payload, getErr = c.Get(ctx, key); getErr == nil && p.delete {
+			delErr = c.Delete(ctx, key)
+		}
+	}); err != nil {
+		return err
+	}
+	if getErr != nil {
+		return fmt.Errorf("failed to resolve payload '%v': %w", key, getErr)
+	}
+	if delErr != nil {
+		return fmt.Errorf("failed to delete payload '%v': %w", key, delErr)
+	}
+
+	msg.SetBytes(payload)
+	msg.MetaDelete(ccpMetaKey)
+	p.mResolved.Incr(1)
+	return nil
+}
+
+func (p *claimCheckProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var err error
+	if p.operator == "store" {
+		err = p.store(ctx, msg)
+	} else {
+		err = p.resolve(ctx, msg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *claimCheckProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,120 @@
+package pure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testClaimCheckProc(t testing.TB, mgr *service.Resources, confStr string) *claimCheckProc {
+	t.Helper()
+
+	pConf, err := claimCheckProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newClaimCheckProcFromParsed(pConf, mgr)
+	require.NoError(t, err)
+	return proc
+}
+
+func claimCheckProcess(t testing.TB, proc *claimCheckProc, msg *service.Message) *service.Message {
+	t.Helper()
+
+	res, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	return res[0]
+}
+
+func TestClaimCheckRoundTrip(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("claims"))
+
+	store := testClaimCheckProc(t, mgr, `
+operator: store
+cache: claims
+threshold: 10
+key: 'claims/${! @id }'
+`)
+	resolve := testClaimCheckProc(t, mgr, `
+operator: resolve
+cache: claims
+`)
+
+	large := strings.Repeat("x", 11)
+
+	msg := service.NewMessage([]byte(large))
+	msg.MetaSetMut("id", "foo")
+	msg = claimCheckProcess(t, store, msg)
+
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"claim_check":{"key":"claims/foo","size":11}}`, string(mBytes))
+	key, _ := msg.MetaGet("claim_check_key")
+	assert.Equal(t, "claims/foo", key)
+
+	require.NoError(t, mgr.AccessCache(context.Background(), "claims", func(c service.Cache) {
+		v, err := c.Get(context.Background(), "claims/foo")
+		require.NoError(t, err)
+		assert.Equal(t, large, string(v))
+	}))
+
+	msg = claimCheckProcess(t, resolve, msg)
+	mBytes, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, large, string(mBytes))
+	_, exists := msg.MetaGet("claim_check_key")
+	assert.False(t, exists)
+
+	// Small payloads and payloads that are not references are unchanged.
+	for _, payload := range []string{"small", `{"claim_check":"nope"}`, `{"foo":"bar"}`} {
+		msg = claimCheckProcess(t, store, service.NewMessage([]byte(payload)))
+		msg = claimCheckProcess(t, resolve, msg)
+		mBytes, err = msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, payload, string(mBytes))
+	}
+}
+
+func TestClaimCheckResolveDelete(t *testing.T) {
+	mgr := service.MockResources(service.MockResourcesOptAddCache("claims"))
+
+	store := testClaimCheckProc(t, mgr, `
+operator: store
+cache: claims
+threshold: 0
+`)
+	resolve := testClaimCheckProc(t, mgr, `
+operator: resolve
+cache: claims
+delete: true
+`)
+
+	ref := claimCheckProcess(t, store, service.NewMessage([]byte("hello world")))
+
+	msg := claimCheckProcess(t, resolve, ref.Copy())
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(mBytes))
+
+	// The payload has been deleted so resolving again fails.
+	_, err = resolve.Process(context.Background(), ref.Copy())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to resolve payload")
+}
+
+func TestClaimCheckMissingCache(t *testing.T) {
+	pConf, err := claimCheckProcSpec().ParseYAML(`
+operator: store
+cache: nope
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newClaimCheckProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "was not found")
+}
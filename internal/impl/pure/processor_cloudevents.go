@@ -0,0 +1,542 @@
+package pure
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	cedFieldMode    = "mode"
+	cedFieldBinding = "binding"
+
+	ceeFieldMode            = "mode"
+	ceeFieldBinding         = "binding"
+	ceeFieldID              = "id"
+	ceeFieldSource          = "source"
+	ceeFieldType            = "type"
+	ceeFieldSubject         = "subject"
+	ceeFieldDataContentType = "datacontenttype"
+	ceeFieldDataSchema      = "dataschema"
+	ceeFieldExtensions      = "extensions"
+
+	ceMetaPrefix               = "cloudevents_"
+	ceStructuredContentType    = "application/cloudevents+json"
+	ceSpecVersion              = "1.0"
+	ceMaxAttributeNameLength   = 20
+	ceAttrDataContentType      = "datacontenttype"
+	ceAttrSpecVersion          = "specversion"
+	ceStructuredDataField      = "data"
+	ceStructuredDataB64Field   = "data_base64"
+	ceBindingHTTPHeaderPrefix  = "ce-"
+	ceBindingKafkaHeaderPrefix = "ce_"
+)
+
+func ceBindingField(name string) *service.ConfigField {
+	return service.NewStringAnnotatedEnumField(name, map[string]string{
+		"http":  "The [HTTP protocol binding](https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/http-protocol-binding.md), where attributes are headers prefixed with `ce-` and the content type is the header `Content-Type`.",
+		"kafka": "The [Kafka protocol binding](https://github.com/cloudevents/spec/blob/v1.0.2/cloudevents/bindings/kafka-protocol-binding.md), where attributes are headers prefixed with `ce_` and the content type is the header `content-type`.",
+	}).
+		Description("The protocol binding that determines the metadata fields of attributes in the binary content mode, and the metadata field of the content type.").
+		Default("http")
+}
+
+func cloudEventsDecodeProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Beta().
+		Version("4.28.0").
+		Summary("Decodes and validates [CloudEvents](https://cloudevents.io/) v1.0, replacing each message with the data of the event and adding its attributes as metadata.").
+		Description(`
+Events can be decoded from either the structured content mode, where the message is a JSON object of the form `+"`application/cloudevents+json`"+` containing both the attributes and the data of the event, or the binary content mode, where the message is the data of the event and the attributes are metadata fields that originate from the headers of a protocol binding, such as those added by the `+"`http_server`"+` and `+"`kafka`"+` inputs.
+
+Events that are missing required attributes, that have a `+"`specversion`"+` other than `+"`1.0`"+`, or that have attributes of an invalid format are rejected with an error, allowing you to use [standard processor error handling patterns](/docs/configuration/error_handling).
+
+### Metadata
+
+Each attribute of an event, including extension attributes, is added to the message as a metadata field prefixed with `+"`cloudevents_`"+`, e.g. `+"`cloudevents_id`, `cloudevents_type`"+` and `+"`cloudevents_source`"+`. These fields are used by the `+"[`cloudevents_encode`](/docs/components/processors/cloudevents_encode)"+` processor in order to encode events.`).
+		Example(
+			"Receiving Events over HTTP",
+			"Events are received from an event source such as Knative in either content mode, and routed by their type.",
+			`
+input:
+  http_server:
+    path: /
+  processors:
+    - cloudevents_decode: {}
+
+output:
+  switch:
+    cases:
+      - check: '@cloudevents_type == "com.example.order.created"'
+        output:
+          kafka:
+            addresses: [ localhost:9092 ]
+            topic: orders
+      - output:
+          drop: {}
+`,
+		).
+		Fields(
+			service.NewStringAnnotatedEnumField(cedFieldMode, map[string]string{
+				"auto":       "The content mode is detected from the content type of the message, where messages with a content type of `application/cloudevents+json` are decoded in the structured mode, messages with a `specversion` attribute in their metadata are decoded in the binary mode, and other messages are decoded in the structured mode.",
+				"structured": "Messages are decoded in the structured content mode.",
+				"binary":     "Messages are decoded in the binary content mode.",
+			}).
+				Description("The content mode of events.").
+				Default("auto"),
+			ceBindingField(cedFieldBinding),
+		)
+}
+
+func cloudEventsEncodeProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Beta().
+		Version("4.28.0").
+		Summary("Encodes messages as [CloudEvents](https://cloudevents.io/) v1.0, where the contents of each message is the data of the event.").
+		Description(`
+The attributes of each event are taken from the metadata fields prefixed with `+"`cloudevents_`"+`, as added by the `+"[`cloudevents_decode`](/docs/components/processors/cloudevents_decode)"+` processor, and can be set or overridden with the fields of this processor. When not otherwise set the `+"`id`"+` of an event is a random UUID and the `+"`time`"+` of an event is the time it was encoded. Events that are missing a `+"`source`"+` or `+"`type`"+`, or that have attributes of an invalid format, are rejected with an error.
+
+In the structured content mode the message is replaced with a JSON object containing both the attributes and the data of the event, where the data is a JSON value when the content type is JSON and the message is valid JSON, a string when the content type is textual, and is otherwise encoded as `+"`data_base64`"+`. The content type metadata field of the protocol binding is set to `+"`application/cloudevents+json`"+`.
+
+In the binary content mode the message is left unchanged and the attributes are added as metadata fields named according to the protocol binding, and the `+"`datacontenttype`"+` is set as the content type metadata field of the binding. Outputs such as `+"`kafka`"+` send all metadata fields as headers by default, whereas others must be configured to include them, e.g. with `+"`metadata.include_prefixes`"+` for the `+"`http_client`"+` output.`).
+		Example(
+			"Publishing Events to Kafka",
+			"Rows are published to Kafka as events in the binary content mode.",
+			`
+pipeline:
+  processors:
+    - cloudevents_encode:
+        mode: binary
+        binding: kafka
+        source: /shop/orders
+        type: com.example.order.created
+        subject: ${! this.id }
+        datacontenttype: application/json
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders
+`,
+		).
+		Fields(
+			service.NewStringAnnotatedEnumField(ceeFieldMode, map[string]string{
+				"structured": "Messages are encoded in the structured content mode.",
+				"binary":     "Messages are encoded in the binary content mode.",
+			}).
+				Description("The content mode of events.").
+				Default("structured"),
+			ceBindingField(ceeFieldBinding),
+			service.NewInterpolatedStringField(ceeFieldID).
+				Description("The `id` of events, which defaults to the metadata field `cloudevents_id` or a random UUID.").
+				Optional(),
+			service.NewInterpolatedStringField(ceeFieldSource).
+				Description("The `source` of events, which defaults to the metadata field `cloudevents_source`.").
+				Example("/shop/orders").
+				Optional(),
+			service.NewInterpolatedStringField(ceeFieldType).
+				Description("The `type` of events, which defaults to the metadata field `cloudevents_type`.").
+				Example("com.example.order.created").
+				Optional(),
+			service.NewInterpolatedStringField(ceeFieldSubject).
+				Description("The `subject` of events, which defaults to the metadata field `cloudevents_subject`.").
+				Optional(),
+			service.NewInterpolatedStringField(ceeFieldDataContentType).
+				Description("The `datacontenttype` of events, which defaults to the metadata field `cloudevents_datacontenttype`.").
+				Example("application/json").
+				Optional(),
+			service.NewInterpolatedStringField(ceeFieldDataSchema).
+				Description("The `dataschema` of events, which defaults to the metadata field `cloudevents_dataschema`.").
+				Optional().
+				Advanced(),
+			service.NewBloblangField(ceeFieldExtensions).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in an object of extension attributes to add to events, which take precedence over extension attributes from metadata.").
+				Example(`root.tenant = @tenant`).
+				Optional().
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor("cloudevents_decode", cloudEventsDecodeProcSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return newCloudEventsDecodeFromParsed(conf)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = service.RegisterProcessor("cloudevents_encode", cloudEventsEncodeProcSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return newCloudEventsEncodeFromParsed(conf)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// ceBinding describes how attributes are carried as metadata in the binary
+// content mode of a protocol binding.
+type ceBinding struct {
+	headerPrefix   string
+	contentTypeKey string
+}
+
+func ceBindingFromParsed(conf *service.ParsedConfig, name string) (ceBinding, error) {
+	b, err := conf.FieldString(name)
+	if err != nil {
+		return ceBinding{}, err
+	}
+	switch b {
+	case "http":
+		return ceBinding{headerPrefix: ceBindingHTTPHeaderPrefix, contentTypeKey: "Content-Type"}, nil
+	case "kafka":
+		return ceBinding{headerPrefix: ceBindingKafkaHeaderPrefix, contentTypeKey: "content-type"}, nil
+	}
+	return ceBinding{}, fmt.Errorf("unrecognised binding: %v", b)
+}
+
+// contentType returns the value of the content type metadata field of the
+// binding, which is matched case insensitively.
+func (b ceBinding) contentType(msg *service.Message) string {
+	var cType string
+	_ = msg.MetaWalk(func(k, v string) error {
+		if strings.EqualFold(k, b.contentTypeKey) {
+			cType = v
+		}
+		return nil
+	})
+	return cType
+}
+
+// attributes returns the attributes carried by the metadata of a message in
+// the binary content mode.
+func (b ceBinding) attributes(msg *service.Message) map[string]any {
+	attrs := map[string]any{}
+	_ = msg.MetaWalk(func(k, v string) error {
+		if len(k) > len(b.headerPrefix) && strings.EqualFold(k[:len(b.headerPrefix)], b.headerPrefix) {
+			attrs[strings.ToLower(k[len(b.headerPrefix):])] = v
+		}
+		return nil
+	})
+	if cType := b.contentType(msg); cType != "" {
+		attrs[ceAttrDataContentType] = cType
+	}
+	return attrs
+}
+
+func ceIsJSONContentType(cType string) bool {
+	if cType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(cType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || mediaType == "text/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func ceIsTextContentType(cType string) bool {
+	mediaType, _, err := mime.ParseMediaType(cType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+xml") || mediaType == "application/xml"
+}
+
+// ceValidateAttributes checks that a set of attributes conforms to v1.0 of the
+// CloudEvents specification.
+func ceValidateAttributes(attrs map[string]any) error {
+	for _, k := range []string{"id", "source", ceAttrSpecVersion, "type"} {
+		v, exists := attrs[k]
+		if !exists {
+			return fmt.Errorf("required attribute %v is missing", k)
+		}
+		if s, ok := v.(string); !ok || s == "" {
+			return fmt.Errorf("required attribute %v must be a non-empty string", k)
+		}
+	}
+	if v := attrs[ceAttrSpecVersion]; v != ceSpecVersion {
+		return fmt.Errorf("unsupported specversion: %v", v)
+	}
+	if v, exists := attrs["time"]; exists {
+		s, _ := v.(string)
+		if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+			return fmt.Errorf("attribute time must be an RFC 3339 timestamp: %v", v)
+		}
+	}
+	for k := range attrs {
+		if k == "" || len(k) > ceMaxAttributeNameLength {
+			return fmt.Errorf("attribute name '%v' must be between 1 and %v characters", k, ceMaxAttributeNameLength)
+		}
+		for _, c := range k {
+			if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+				return fmt.Errorf("attribute name '%v' must consist of lower-case letters and digits", k)
+			}
+		}
+	}
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type cloudEventsDecodeProc struct {
+	mode    string
+	binding ceBinding
+}
+
+func newCloudEventsDecodeFromParsed(conf *service.ParsedConfig) (*cloudEventsDecodeProc, error) {
+	c := &cloudEventsDecodeProc{}
+
+	var err error
+	if c.mode, err = conf.FieldString(cedFieldMode); err != nil {
+		return nil, err
+	}
+	if c.binding, err = ceBindingFromParsed(conf, cedFieldBinding); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *cloudEventsDecodeProc) isStructured(msg *service.Message) bool {
+	switch c.mode {
+	case "structured":
+		return true
+	case "binary":
+		return false
+	}
+	if mediaType, _, _ := mime.ParseMediaType(c.binding.contentType(msg)); mediaType == ceStructuredContentType {
+		return true
+	}
+	_, isBinary := c.binding.attributes(msg)[ceAttrSpecVersion]
+	return !isBinary
+}
+
+func (c *cloudEventsDecodeProc) decodeStructured(msg *service.Message) (map[string]any, error) {
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse structured event: %w", err)
+	}
+	attrs, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected structured event to be an object, got %T", v)
+	}
+
+	data, hasData := attrs[ceStructuredDataField]
+	dataB64, hasDataB64 := attrs[ceStructuredDataB64Field]
+	delete(attrs, ceStructuredDataField)
+	delete(attrs, ceStructuredDataB64Field)
+
+	switch {
+	case hasData && hasDataB64:
+		return nil, errors.New("structured event must not contain both data and data_base64")
+	case hasDataB64:
+		s, ok := dataB64.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected data_base64 to be a string, got %T", dataB64)
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode data_base64: %w", err)
+		}
+		msg.SetBytes(b)
+	case hasData:
+		cType, _ := attrs[ceAttrDataContentType].(string)
+		if s, ok := data.(string); ok && !ceIsJSONContentType(cType) {
+			msg.SetBytes([]byte(s))
+		} else {
+			msg.SetStructuredMut(data)
+		}
+	default:
+		msg.SetBytes(nil)
+	}
+
+	// The content type of the message no longer describes its contents.
+	_ = msg.MetaWalk(func(k, _ string) error {
+		if strings.EqualFold(k, c.binding.contentTypeKey) {
+			msg.MetaDelete(k)
+		}
+		return nil
+	})
+	return attrs, nil
+}
+
+func (c *cloudEventsDecodeProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	var attrs map[string]any
+	if c.isStructured(msg) {
+		var err error
+		if attrs, err = c.decodeStructured(msg); err != nil {
+			return nil, err
+		}
+	} else {
+		attrs = c.binding.attributes(msg)
+	}
+
+	if err := ceValidateAttributes(attrs); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+	for k, v := range attrs {
+		msg.MetaSetMut(ceMetaPrefix+k, v)
+	}
+	return service.MessageBatch{msg}, nil
+}
+
+func (c *cloudEventsDecodeProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type cloudEventsEncodeProc struct {
+	structured bool
+	binding    ceBinding
+	attrs      map[string]*service.InterpolatedString
+	extensions *bloblang.Executor
+	nowFn      func() time.Time
+}
+
+func newCloudEventsEncodeFromParsed(conf *service.ParsedConfig) (*cloudEventsEncodeProc, error) {
+	c := &cloudEventsEncodeProc{
+		attrs: map[string]*service.InterpolatedString{},
+		nowFn: time.Now,
+	}
+
+	mode, err := conf.FieldString(ceeFieldMode)
+	if err != nil {
+		return nil, err
+	}
+	c.structured = mode == "structured"
+	if c.binding, err = ceBindingFromParsed(conf, ceeFieldBinding); err != nil {
+		return nil, err
+	}
+
+	for _, k := range []string{
+		ceeFieldID, ceeFieldSource, ceeFieldType, ceeFieldSubject,
+		ceeFieldDataContentType, ceeFieldDataSchema,
+	} {
+		if !conf.Contains(k) {
+			continue
+		}
+		if c.attrs[k], err = conf.FieldInterpolatedString(k); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(ceeFieldExtensions) {
+		if c.extensions, err = conf.FieldBloblang(ceeFieldExtensions); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *cloudEventsEncodeProc) attributes(msg *service.Message) (map[string]any, error) {
+	attrs := map[string]any{}
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		if strings.HasPrefix(k, ceMetaPrefix) {
+			attrs[strings.TrimPrefix(k, ceMetaPrefix)] = v
+		}
+		return nil
+	})
+
+	if c.extensions != nil {
+		resMsg, err := msg.BloblangQuery(c.extensions)
+		if err != nil {
+			return nil, fmt.Errorf("extensions mapping failed: %w", err)
+		}
+		if resMsg != nil {
+			v, err := resMsg.AsStructured()
+			if err != nil {
+				return nil, fmt.Errorf("extensions mapping result: %w", err)
+			}
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("extensions mapping returned non-object result: %T", v)
+			}
+			for k, v := range obj {
+				attrs[k] = v
+			}
+		}
+	}
+
+	for k, v := range c.attrs {
+		s, err := v.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("%v interpolation: %w", k, err)
+		}
+		attrs[k] = s
+	}
+
+	attrs[ceAttrSpecVersion] = ceSpecVersion
+	if _, exists := attrs["id"]; !exists {
+		attrs["id"] = uuid.Must(uuid.NewV4()).String()
+	}
+	if _, exists := attrs["time"]; !exists {
+		attrs["time"] = c.nowFn().UTC().Format(time.RFC3339Nano)
+	}
+	for k, v := range attrs {
+		if v == nil || v == "" {
+			delete(attrs, k)
+		}
+	}
+	return attrs, nil
+}
+
+func (c *cloudEventsEncodeProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	attrs, err := c.attributes(msg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ceValidateAttributes(attrs); err != nil {
+		return nil, fmt.Errorf("invalid event: %w", err)
+	}
+
+	if !c.structured {
+		for k, v := range attrs {
+			if k == ceAttrDataContentType {
+				msg.MetaSetMut(c.binding.contentTypeKey, value.IToString(v))
+				continue
+			}
+			msg.MetaSetMut(c.binding.headerPrefix+k, value.IToString(v))
+		}
+		return service.MessageBatch{msg}, nil
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	cType, _ := attrs[ceAttrDataContentType].(string)
+	switch {
+	case ceIsJSONContentType(cType) && json.Valid(mBytes):
+		attrs[ceStructuredDataField] = json.RawMessage(mBytes)
+	case ceIsTextContentType(cType):
+		attrs[ceStructuredDataField] = string(mBytes)
+	case len(mBytes) > 0:
+		attrs[ceStructuredDataB64Field] = base64.StdEncoding.EncodeToString(mBytes)
+	}
+
+	eventBytes, err := json.Marshal(attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	msg.SetBytes(eventBytes)
+	msg.MetaSetMut(c.binding.contentTypeKey, ceStructuredContentType)
+	return service.MessageBatch{msg}, nil
+}
+
+func (c *cloudEventsEncodeProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,208 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testCloudEventsDecodeProc(t testing.TB, confStr string) *cloudEventsDecodeProc {
+	t.Helper()
+
+	pConf, err := cloudEventsDecodeProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newCloudEventsDecodeFromParsed(pConf)
+	require.NoError(t, err)
+	return proc
+}
+
+func testCloudEventsEncodeProc(t testing.TB, confStr string) *cloudEventsEncodeProc {
+	t.Helper()
+
+	pConf, err := cloudEventsEncodeProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newCloudEventsEncodeFromParsed(pConf)
+	require.NoError(t, err)
+
+	proc.nowFn = func() time.Time {
+		return time.Date(2024, 4, 23, 10, 0, 0, 0, time.UTC)
+	}
+	return proc
+}
+
+func TestCloudEventsDecodeStructured(t *testing.T) {
+	proc := testCloudEventsDecodeProc(t, ``)
+
+	msg := service.NewMessage([]byte(`{
+  "specversion": "1.0",
+  "id": "A234-1234-1234",
+  "source": "/mycontext",
+  "type": "com.example.someevent",
+  "time": "2018-04-05T17:31:00Z",
+  "comexampleextension1": "value",
+  "datacontenttype": "application/json",
+  "data": {"foo":"bar"}
+}`))
+	msg.MetaSetMut("Content-Type", "application/cloudevents+json; charset=UTF-8")
+
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"bar"}`, string(mBytes))
+
+	for k, exp := range map[string]string{
+		"cloudevents_id":                   "A234-1234-1234",
+		"cloudevents_type":                 "com.example.someevent",
+		"cloudevents_comexampleextension1": "value",
+		"cloudevents_datacontenttype":      "application/json",
+	} {
+		v, _ := batch[0].MetaGet(k)
+		assert.Equal(t, exp, v, k)
+	}
+	_, exists := batch[0].MetaGet("Content-Type")
+	assert.False(t, exists)
+
+	msg = service.NewMessage([]byte(`{"specversion":"1.0","id":"1","source":"/a","type":"b","data_base64":"aGVsbG8="}`))
+	batch, err = proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	mBytes, err = batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(mBytes))
+}
+
+func TestCloudEventsDecodeBinary(t *testing.T) {
+	proc := testCloudEventsDecodeProc(t, `binding: kafka`)
+
+	msg := service.NewMessage([]byte(`hello world`))
+	msg.MetaSetMut("ce_specversion", "1.0")
+	msg.MetaSetMut("ce_id", "1")
+	msg.MetaSetMut("ce_source", "/a")
+	msg.MetaSetMut("ce_type", "b")
+	msg.MetaSetMut("content-type", "text/plain")
+
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(mBytes))
+
+	v, _ := batch[0].MetaGet("cloudevents_datacontenttype")
+	assert.Equal(t, "text/plain", v)
+	v, _ = batch[0].MetaGet("cloudevents_source")
+	assert.Equal(t, "/a", v)
+}
+
+func TestCloudEventsDecodeInvalid(t *testing.T) {
+	proc := testCloudEventsDecodeProc(t, ``)
+
+	for _, input := range []string{
+		`{"specversion":"1.0","id":"1","source":"/a"}`,
+		`{"specversion":"0.3","id":"1","source":"/a","type":"b"}`,
+		`{"specversion":"1.0","id":"1","source":"/a","type":"b","time":"yesterday"}`,
+		`{"specversion":"1.0","id":"1","source":"/a","type":"b","Bad_Name":"c"}`,
+		`{"specversion":"1.0","id":"1","source":"/a","type":"b","data":"a","data_base64":"YQ=="}`,
+		`not an event`,
+	} {
+		_, err := proc.Process(context.Background(), service.NewMessage([]byte(input)))
+		assert.Error(t, err, input)
+	}
+}
+
+func TestCloudEventsEncodeStructured(t *testing.T) {
+	proc := testCloudEventsEncodeProc(t, `
+id: ${! this.id }
+source: /shop/orders
+type: com.example.order.created
+extensions: 'root.tenant = @tenant'
+`)
+
+	msg := service.NewMessage([]byte(`{"id":"123"}`))
+	msg.MetaSetMut("tenant", "acme")
+
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "specversion": "1.0",
+  "id": "123",
+  "source": "/shop/orders",
+  "type": "com.example.order.created",
+  "time": "2024-04-23T10:00:00Z",
+  "tenant": "acme",
+  "data": {"id":"123"}
+}`, string(mBytes))
+
+	v, _ := batch[0].MetaGet("Content-Type")
+	assert.Equal(t, "application/cloudevents+json", v)
+
+	proc = testCloudEventsEncodeProc(t, `
+id: "1"
+source: /a
+type: b
+datacontenttype: application/octet-stream
+`)
+	batch, err = proc.Process(context.Background(), service.NewMessage([]byte(`hello`)))
+	require.NoError(t, err)
+
+	mBytes, err = batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "specversion": "1.0",
+  "id": "1",
+  "source": "/a",
+  "type": "b",
+  "time": "2024-04-23T10:00:00Z",
+  "datacontenttype": "application/octet-stream",
+  "data_base64": "aGVsbG8="
+}`, string(mBytes))
+
+	proc = testCloudEventsEncodeProc(t, `type: b`)
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`hello`)))
+	require.Error(t, err)
+}
+
+func TestCloudEventsRoundTripBinary(t *testing.T) {
+	decode := testCloudEventsDecodeProc(t, ``)
+	encode := testCloudEventsEncodeProc(t, `
+mode: binary
+binding: kafka
+`)
+
+	msg := service.NewMessage([]byte(`{"specversion":"1.0","id":"1","source":"/a","type":"b","subject":"c","datacontenttype":"text/plain","data":"hello"}`))
+	batch, err := decode.Process(context.Background(), msg)
+	require.NoError(t, err)
+
+	batch, err = encode.Process(context.Background(), batch[0])
+	require.NoError(t, err)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(mBytes))
+
+	for k, exp := range map[string]string{
+		"ce_specversion": "1.0",
+		"ce_id":          "1",
+		"ce_source":      "/a",
+		"ce_type":        "b",
+		"ce_subject":     "c",
+		"ce_time":        "2024-04-23T10:00:00Z",
+		"content-type":   "text/plain",
+	} {
+		v, _ := batch[0].MetaGet(k)
+		assert.Equal(t, exp, v, k)
+	}
+}
@@ -0,0 +1,392 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dbzuFieldDropTombstones = "drop_tombstones"
+	dbzuFieldDeleteHandling = "delete_handling"
+
+	dbzwFieldOperation      = "operation"
+	dbzwFieldBefore         = "before"
+	dbzwFieldSource         = "source"
+	dbzwFieldEmitTombstones = "emit_tombstones"
+
+	dbzMetaSourcePrefix = "debezium_source_"
+)
+
+func debeziumUnwrapProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Beta().
+		Version("4.28.0").
+		Summary("Unwraps [Debezium](https://debezium.io/) change event envelopes into the state of the changed row, equivalent to the `ExtractNewRecordState` transformation of Debezium.").
+		Description(`
+Change events are expected to be JSON objects containing the fields `+"`op`, `before`, `after` and `source`"+`, optionally wrapped within the `+"`payload`"+` of an object that also contains a `+"`schema`"+`, as produced by the JSON converter of Kafka Connect with schemas enabled.
+
+Create (`+"`c`"+`), update (`+"`u`"+`) and snapshot read (`+"`r`"+`) events are replaced with their `+"`after`"+` state. Delete (`+"`d`"+`) events are handled according to `+"`delete_handling`"+`, and truncate (`+"`t`"+`) and message (`+"`m`"+`) events are dropped. Tombstones, which are empty messages emitted by Debezium after a delete in order to support log compaction, are handled according to `+"`drop_tombstones`"+`.
+
+### Metadata
+
+This processor adds the following metadata fields to each message:
+
+`+"```text"+`
+- debezium_op
+- debezium_ts_ms
+- debezium_source_* (for each scalar field of the source block, e.g. debezium_source_table)
+- debezium_transaction_id
+- debezium_transaction_total_order
+- debezium_transaction_data_collection_order
+`+"```"+`
+
+These fields are used by the `+"[`debezium_wrap`](/docs/components/processors/debezium_wrap)"+` processor in order to reconstruct envelopes.`).
+		Example(
+			"Flattening Change Events",
+			"Change events of a Postgres table consumed from Kafka are flattened into rows, where deleted rows are marked with a `__deleted` field, before being written to a table of another database.",
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ dbserver1.inventory.customers ]
+    consumer_group: sink
+  processors:
+    - debezium_unwrap:
+        delete_handling: rewrite
+
+output:
+  sql_insert:
+    driver: postgres
+    dsn: postgres://localhost:5432/replica
+    table: customers
+    columns: [ id, email, deleted ]
+    args_mapping: 'root = [ this.id, this.email, this.__deleted == "true" ]'
+`,
+		).
+		Fields(
+			service.NewBoolField(dbzuFieldDropTombstones).
+				Description("Whether tombstone messages should be dropped, otherwise they are passed through unchanged.").
+				Default(true),
+			service.NewStringAnnotatedEnumField(dbzuFieldDeleteHandling, map[string]string{
+				"drop":    "Delete events are dropped.",
+				"rewrite": "Delete events are replaced with their `before` state, and a field `__deleted` is added to all rows with the value `\"true\"` for deletes and `\"false\"` otherwise.",
+				"none":    "Delete events are replaced with an empty message, similar to a tombstone.",
+			}).
+				Description("Determines how delete events are handled.").
+				Default("drop"),
+		)
+}
+
+func debeziumWrapProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Parsing").
+		Beta().
+		Version("4.28.0").
+		Summary("Wraps messages in [Debezium](https://debezium.io/) change event envelopes, allowing Benthos to produce events for consumers of Debezium.").
+		Description(`
+Each message is treated as the state of a changed row, and is replaced with a JSON envelope of the form:
+
+`+"```json"+`
+{
+  "before": null,
+  "after": { "id": 1, "email": "foo@example.com" },
+  "source": { "connector": "benthos", "table": "customers" },
+  "op": "c",
+  "ts_ms": 1700000000000
+}
+`+"```"+`
+
+For delete (`+"`d`"+`) events the message is the `+"`before`"+` state and the `+"`after`"+` state is null. When `+"`emit_tombstones`"+` is enabled each delete event is followed by an empty message with the same metadata, which allows topics to be compacted when the key of the row is set as the key of the record.
+
+The `+"`source`"+` block defaults to the metadata fields prefixed with `+"`debezium_source_`"+`, and a `+"`transaction`"+` block is added when the metadata fields `+"`debezium_transaction_*`"+` are present, such that messages unwrapped with the `+"[`debezium_unwrap`](/docs/components/processors/debezium_unwrap)"+` processor can be wrapped again without losing information. Envelopes are produced without a schema.`).
+		Example(
+			"Producing Change Events",
+			"Rows polled from a table are emitted as snapshot read events to a topic consumed by Debezium sinks, keyed by the ID of each row.",
+			`
+input:
+  sql_select:
+    driver: postgres
+    dsn: postgres://localhost:5432/shop
+    table: customers
+    columns: [ '*' ]
+  processors:
+    - debezium_wrap:
+        operation: r
+        source: 'root = { "connector": "benthos", "db": "shop", "table": "customers" }'
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: shop.public.customers
+    key: ${! this.after.id }
+`,
+		).
+		Fields(
+			service.NewInterpolatedStringField(dbzwFieldOperation).
+				Description("The operation of each event, which must resolve to one of `c`, `u`, `d` or `r`.").
+				Default(`${! @debezium_op | "c" }`),
+			service.NewBloblangField(dbzwFieldBefore).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the `before` state of update events.").
+				Example(`root = this.previous`).
+				Optional(),
+			service.NewBloblangField(dbzwFieldSource).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that results in the `source` block of events. When not set the source block consists of the metadata fields prefixed with `debezium_source_`.").
+				Example(`root = { "connector": "benthos", "table": @table }`).
+				Optional(),
+			service.NewBoolField(dbzwFieldEmitTombstones).
+				Description("Whether each delete event should be followed by a tombstone.").
+				Default(true),
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor("debezium_unwrap", debeziumUnwrapProcSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return newDebeziumUnwrapFromParsed(conf, mgr)
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = service.RegisterProcessor("debezium_wrap", debeziumWrapProcSpec(), func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+		return newDebeziumWrapFromParsed(conf, mgr)
+	})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type debeziumUnwrapProc struct {
+	dropTombstones bool
+	deleteHandling string
+	log            *service.Logger
+}
+
+func newDebeziumUnwrapFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*debeziumUnwrapProc, error) {
+	d := &debeziumUnwrapProc{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if d.dropTombstones, err = conf.FieldBool(dbzuFieldDropTombstones); err != nil {
+		return nil, err
+	}
+	if d.deleteHandling, err = conf.FieldString(dbzuFieldDeleteHandling); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *debeziumUnwrapProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(mBytes) == 0 {
+		if d.dropTombstones {
+			return nil, nil
+		}
+		return service.MessageBatch{msg}, nil
+	}
+
+	v, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse change event: %w", err)
+	}
+	env, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected change event to be an object, got %T", v)
+	}
+	if payload, ok := env["payload"].(map[string]any); ok {
+		if _, hasSchema := env["schema"]; hasSchema {
+			env = payload
+		}
+	}
+
+	op, _ := env["op"].(string)
+	if op == "" {
+		return nil, errors.New("change event does not contain an op field")
+	}
+
+	msg.MetaSetMut("debezium_op", op)
+	if ts, exists := env["ts_ms"]; exists {
+		msg.MetaSetMut("debezium_ts_ms", ts)
+	}
+	if source, ok := env["source"].(map[string]any); ok {
+		for k, v := range source {
+			switch v.(type) {
+			case map[string]any, []any, nil:
+			default:
+				msg.MetaSetMut(dbzMetaSourcePrefix+k, v)
+			}
+		}
+	}
+	if txn, ok := env["transaction"].(map[string]any); ok {
+		for k, v := range txn {
+			msg.MetaSetMut("debezium_transaction_"+k, v)
+		}
+	}
+
+	var state any
+	switch op {
+	case "c", "u", "r":
+		state = env["after"]
+	case "d":
+		switch d.deleteHandling {
+		case "drop":
+			return nil, nil
+		case "none":
+			msg.SetBytes(nil)
+			return service.MessageBatch{msg}, nil
+		}
+		state = env["before"]
+	default:
+		d.log.Debugf("Dropping change event with op %v", op)
+		return nil, nil
+	}
+
+	row, ok := state.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("expected row state of %v event to be an object, got %T", op, state)
+	}
+	if d.deleteHandling == "rewrite" {
+		if op == "d" {
+			row["__deleted"] = "true"
+		} else {
+			row["__deleted"] = "false"
+		}
+	}
+	msg.SetStructuredMut(row)
+	return service.MessageBatch{msg}, nil
+}
+
+func (d *debeziumUnwrapProc) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+type debeziumWrapProc struct {
+	operation      *service.InterpolatedString
+	before         *bloblang.Executor
+	source         *bloblang.Executor
+	emitTombstones bool
+	nowFn          func() time.Time
+}
+
+func newDebeziumWrapFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*debeziumWrapProc, error) {
+	d := &debeziumWrapProc{
+		nowFn: time.Now,
+	}
+
+	var err error
+	if d.operation, err = conf.FieldInterpolatedString(dbzwFieldOperation); err != nil {
+		return nil, err
+	}
+	if conf.Contains(dbzwFieldBefore) {
+		if d.before, err = conf.FieldBloblang(dbzwFieldBefore); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(dbzwFieldSource) {
+		if d.source, err = conf.FieldBloblang(dbzwFieldSource); err != nil {
+			return nil, err
+		}
+	}
+	if d.emitTombstones, err = conf.FieldBool(dbzwFieldEmitTombstones); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *debeziumWrapProc) queryMapping(msg *service.Message, exec *bloblang.Executor, name string) (any, error) {
+	resMsg, err := msg.BloblangQuery(exec)
+	if err != nil {
+		return nil, fmt.Errorf("%v mapping failed: %w", name, err)
+	}
+	if resMsg == nil {
+		return nil, nil
+	}
+	v, err := resMsg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("%v mapping result: %w", name, err)
+	}
+	return v, nil
+}
+
+func (d *debeziumWrapProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	op, err := d.operation.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("operation interpolation: %w", err)
+	}
+	switch op {
+	case "c", "u", "d", "r":
+	default:
+		return nil, fmt.Errorf("operation must be one of c, u, d or r, got: %v", op)
+	}
+
+	state, err := msg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse row: %w", err)
+	}
+
+	env := map[string]any{
+		"op":     op,
+		"ts_ms":  d.nowFn().UnixMilli(),
+		"before": nil,
+		"after":  nil,
+	}
+	if op == "d" {
+		env["before"] = state
+	} else {
+		env["after"] = state
+		if d.before != nil && op == "u" {
+			if env["before"], err = d.queryMapping(msg, d.before, dbzwFieldBefore); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	source := map[string]any{}
+	txn := map[string]any{}
+	_ = msg.MetaWalkMut(func(k string, v any) error {
+		if strings.HasPrefix(k, dbzMetaSourcePrefix) {
+			source[strings.TrimPrefix(k, dbzMetaSourcePrefix)] = v
+		} else if strings.HasPrefix(k, "debezium_transaction_") {
+			txn[strings.TrimPrefix(k, "debezium_transaction_")] = v
+		}
+		return nil
+	})
+	if d.source != nil {
+		if env["source"], err = d.queryMapping(msg, d.source, dbzwFieldSource); err != nil {
+			return nil, err
+		}
+	} else {
+		env["source"] = source
+	}
+	if len(txn) > 0 {
+		env["transaction"] = txn
+	}
+
+	event := msg.Copy()
+	event.SetStructuredMut(env)
+	if op != "d" || !d.emitTombstones {
+		return service.MessageBatch{event}, nil
+	}
+
+	tombstone := msg.Copy()
+	tombstone.SetBytes(nil)
+	return service.MessageBatch{event, tombstone}, nil
+}
+
+func (d *debeziumWrapProc) Close(ctx context.Context) error {
+	return nil
+}
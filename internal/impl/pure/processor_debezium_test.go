@@ -0,0 +1,217 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testDebeziumUnwrapProc(t testing.TB, confStr string) *debeziumUnwrapProc {
+	t.Helper()
+
+	pConf, err := debeziumUnwrapProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newDebeziumUnwrapFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func testDebeziumWrapProc(t testing.TB, confStr string) *debeziumWrapProc {
+	t.Helper()
+
+	pConf, err := debeziumWrapProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newDebeziumWrapFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.nowFn = func() time.Time {
+		return time.UnixMilli(1700000000000)
+	}
+	return proc
+}
+
+func TestDebeziumUnwrap(t *testing.T) {
+	tests := []struct {
+		name     string
+		conf     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "create",
+			input:    `{"op":"c","before":null,"after":{"id":1},"source":{"table":"customers"},"ts_ms":10}`,
+			expected: []string{`{"id":1}`},
+		},
+		{
+			name:     "schema envelope",
+			input:    `{"schema":{"type":"struct"},"payload":{"op":"u","before":{"id":1},"after":{"id":2}}}`,
+			expected: []string{`{"id":2}`},
+		},
+		{
+			name:     "delete dropped",
+			input:    `{"op":"d","before":{"id":1},"after":null}`,
+			expected: nil,
+		},
+		{
+			name:     "delete rewrite",
+			conf:     `delete_handling: rewrite`,
+			input:    `{"op":"d","before":{"id":1},"after":null}`,
+			expected: []string{`{"__deleted":"true","id":1}`},
+		},
+		{
+			name:     "create rewrite",
+			conf:     `delete_handling: rewrite`,
+			input:    `{"op":"r","before":null,"after":{"id":1}}`,
+			expected: []string{`{"__deleted":"false","id":1}`},
+		},
+		{
+			name:     "delete none",
+			conf:     `delete_handling: none`,
+			input:    `{"op":"d","before":{"id":1},"after":null}`,
+			expected: []string{``},
+		},
+		{
+			name:     "truncate",
+			input:    `{"op":"t","before":null,"after":null}`,
+			expected: nil,
+		},
+		{
+			name:     "tombstone dropped",
+			input:    ``,
+			expected: nil,
+		},
+		{
+			name:     "tombstone kept",
+			conf:     `drop_tombstones: false`,
+			input:    ``,
+			expected: []string{``},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			proc := testDebeziumUnwrapProc(t, test.conf)
+
+			batch, err := proc.Process(context.Background(), service.NewMessage([]byte(test.input)))
+			require.NoError(t, err)
+
+			var actual []string
+			for _, m := range batch {
+				mBytes, err := m.AsBytes()
+				require.NoError(t, err)
+				actual = append(actual, string(mBytes))
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestDebeziumUnwrapErrors(t *testing.T) {
+	proc := testDebeziumUnwrapProc(t, ``)
+
+	_, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"after":{"id":1}}`)))
+	require.Error(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`not json`)))
+	require.Error(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"op":"c","after":null}`)))
+	require.Error(t, err)
+}
+
+func TestDebeziumRoundTrip(t *testing.T) {
+	unwrap := testDebeziumUnwrapProc(t, ``)
+	wrap := testDebeziumWrapProc(t, ``)
+
+	batch, err := unwrap.Process(context.Background(), service.NewMessage([]byte(`{
+  "op": "u",
+  "before": {"id":1,"email":"old@example.com"},
+  "after": {"id":1,"email":"new@example.com"},
+  "source": {"connector":"postgresql","table":"customers","lsn":33},
+  "ts_ms": 10,
+  "transaction": {"id":"571:53195829","total_order":1,"data_collection_order":1}
+}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	op, _ := batch[0].MetaGet("debezium_op")
+	assert.Equal(t, "u", op)
+	table, _ := batch[0].MetaGet("debezium_source_table")
+	assert.Equal(t, "customers", table)
+
+	batch, err = wrap.Process(context.Background(), batch[0])
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "op": "u",
+  "before": null,
+  "after": {"id":1,"email":"new@example.com"},
+  "source": {"connector":"postgresql","table":"customers","lsn":33},
+  "ts_ms": 1700000000000,
+  "transaction": {"id":"571:53195829","total_order":1,"data_collection_order":1}
+}`, string(mBytes))
+}
+
+func TestDebeziumWrap(t *testing.T) {
+	proc := testDebeziumWrapProc(t, `
+operation: ${! @op }
+before: 'root = this.without("email").merge({"email":"old@example.com"})'
+source: 'root = { "connector": "benthos", "table": @table }'
+`)
+
+	msg := service.NewMessage([]byte(`{"id":1,"email":"new@example.com"}`))
+	msg.MetaSetMut("op", "u")
+	msg.MetaSetMut("table", "customers")
+
+	batch, err := proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	mBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "op": "u",
+  "before": {"id":1,"email":"old@example.com"},
+  "after": {"id":1,"email":"new@example.com"},
+  "source": {"connector":"benthos","table":"customers"},
+  "ts_ms": 1700000000000
+}`, string(mBytes))
+
+	msg = service.NewMessage([]byte(`{"id":1}`))
+	msg.MetaSetMut("op", "d")
+	msg.MetaSetMut("table", "customers")
+
+	batch, err = proc.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 2)
+
+	mBytes, err = batch[0].AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+  "op": "d",
+  "before": {"id":1},
+  "after": null,
+  "source": {"connector":"benthos","table":"customers"},
+  "ts_ms": 1700000000000
+}`, string(mBytes))
+
+	mBytes, err = batch[1].AsBytes()
+	require.NoError(t, err)
+	assert.Empty(t, mBytes)
+
+	msg = service.NewMessage([]byte(`{"id":1}`))
+	msg.MetaSetMut("op", "x")
+	_, err = proc.Process(context.Background(), msg)
+	require.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package pure
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	dcpFieldTimeout = "timeout"
+)
+
+func dechunkProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Reassembles messages that were split by the `chunk` processor.").
+		Description(`
+Messages with the metadata fields `+"`chunk_id`, `chunk_index` and `chunk_count`"+` are held in memory until all chunks of the original message have been received, at which point a single message is emitted containing the concatenated contents of all chunks and the metadata of the first chunk (without the chunk metadata fields). Messages without chunk metadata are left unchanged.
+
+Chunks may arrive in any order and across any number of batches. Chunks of a message that is not completed within the `+"`timeout`"+` are discarded, and are counted by the metric `+"`dechunk_expired`"+`.
+
+Chunks that are held are acknowledged immediately, and therefore chunks of an incomplete message are lost if the process terminates before it completes. Inputs with a fixed order of delivery (such as a single Kafka partition) are best suited to consuming chunked messages, as all chunks of a message are received together.`).
+		Example(
+			"Receiving Large Messages via SQS",
+			"Here we reassemble messages sent by the `chunk` processor example.",
+			`
+input:
+  aws_sqs:
+    url: https://sqs.us-east-2.amazonaws.com/123456789012/MyQueue
+  processors:
+    - dechunk:
+        timeout: 5m
+`,
+		).
+		Fields(
+			service.NewDurationField(dcpFieldTimeout).
+				Description("The maximum period of time to wait for all chunks of a message to arrive after receiving its first chunk.").
+				Default("1m"),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("dechunk", dechunkProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newDechunkProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type pendingChunks struct {
+	first    *service.Message
+	chunks   [][]byte
+	received int
+	started  time.Time
+}
+
+type dechunkProc struct {
+	timeout time.Duration
+
+	mut     sync.Mutex
+	pending map[string]*pendingChunks
+
+	mExpired *service.MetricCounter
+	log      *service.Logger
+	nowFn    func() time.Time
+}
+
+func newDechunkProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*dechunkProc, error) {
+	timeout, err := conf.FieldDuration(dcpFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &dechunkProc{
+		timeout:  timeout,
+		pending:  map[string]*pendingChunks{},
+		mExpired: mgr.Metrics().NewCounter("dechunk_expired"),
+		log:      mgr.Logger(),
+		nowFn:    time.Now,
+	}, nil
+}
+
+func (p *dechunkProc) expire(now time.Time) {
+	for id, pc := range p.pending {
+		if now.Sub(pc.started) < p.timeout {
+			continue
+		}
+		p.log.Warnf("Discarding %v of %v chunks of message %v as the remaining chunks did not arrive in time", pc.received, len(pc.chunks), id)
+		p.mExpired.Incr(1)
+		delete(p.pending, id)
+	}
+}
+
+func (p *dechunkProc) add(msg *service.Message, now time.Time) (*service.Message, error) {
+	id, exists := msg.MetaGet(chunkMetaID)
+	if !exists {
+		return msg, nil
+	}
+	index, err := chunkMetaInt(msg, chunkMetaIndex)
+	if err != nil {
+		return nil, err
+	}
+	count, err := chunkMetaInt(msg, chunkMetaCount)
+	if err != nil {
+		return nil, err
+	}
+	if count < 1 || index < 0 || index >= count {
+		return nil, fmt.Errorf("chunk index %v is out of bounds for a count of %v", index, count)
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	pc, exists := p.pending[id]
+	if !exists {
+		pc = &pendingChunks{
+			chunks:  make([][]byte, count),
+			started: now,
+		}
+		p.pending[id] = pc
+	}
+	if len(pc.chunks) != count {
+		return nil, fmt.Errorf("chunk count %v does not match previous chunks of message %v with a count of %v", count, id, len(pc.chunks))
+	}
+	if pc.chunks[index] == nil {
+		pc.received++
+	}
+	pc.chunks[index] = mBytes
+	if index == 0 {
+		pc.first = msg
+	}
+	if pc.received < count {
+		return nil, nil
+	}
+
+	delete(p.pending, id)
+
+	var size int
+	for _, c := range pc.chunks {
+		size += len(c)
+	}
+	joined := make([]byte, 0, size)
+	for _, c := range pc.chunks {
+		joined = append(joined, c...)
+	}
+
+	newMsg := pc.first.Copy()
+	newMsg.MetaDelete(chunkMetaID)
+	newMsg.MetaDelete(chunkMetaIndex)
+	newMsg.MetaDelete(chunkMetaCount)
+	newMsg.SetBytes(joined)
+	return newMsg, nil
+}
+
+func (p *dechunkProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	now := p.nowFn()
+	p.expire(now)
+
+	var newBatch service.MessageBatch
+	for _, msg := range batch {
+		newMsg, err := p.add(msg, now)
+		if err != nil {
+			msg = msg.Copy()
+			msg.SetError(err)
+			newBatch = append(newBatch, msg)
+			continue
+		}
+		if newMsg != nil {
+			newBatch = append(newBatch, newMsg)
+		}
+	}
+	if len(newBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (p *dechunkProc) Close(ctx context.Context) error {
+	return nil
+}
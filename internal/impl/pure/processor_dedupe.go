@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
+
+	"github.com/Jeffail/shutdown"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
@@ -20,6 +23,14 @@ const (
 	dedupFieldCache          = "cache"
 	dedupFieldKey            = "key"
 	dedupFieldDropOnCacheErr = "drop_on_err"
+
+	dedupFieldProbabilistic     = "probabilistic"
+	dedupFieldCapacity          = "capacity"
+	dedupFieldFalsePositiveRate = "false_positive_rate"
+	dedupFieldWindow            = "window"
+	dedupFieldBuckets           = "buckets"
+	dedupFieldSnapshotPath      = "snapshot_path"
+	dedupFieldSnapshotInterval  = "snapshot_interval"
 )
 
 func dedupeProcSpec() *service.ConfigSpec {
@@ -36,6 +47,16 @@ When using this processor with an output target that might fail you should alway
 
 This processor enacts on individual messages only, in order to perform a deduplication on behalf of a batch (or window) of messages instead use the `+"[`cache` processor](/docs/components/processors/cache#examples)"+`.
 
+## Probabilistic Deduplication
+
+Storing every key in a cache becomes expensive when deduplicating billions of keys. As an alternative the field `+"`probabilistic`"+` can be set instead of `+"`cache`"+`, in which case keys are tracked within an in-memory [bloom filter](https://en.wikipedia.org/wiki/Bloom_filter) that uses a fixed amount of memory regardless of the size of the keys.
+
+Bloom filters can produce false positives, meaning a small proportion of messages that have not been seen before are dropped as duplicates, at a rate that can be configured with `+"`false_positive_rate`"+`. False negatives are not possible, and therefore duplicates within the window are always dropped.
+
+The `+"`window`"+` is split into a number of `+"`buckets`"+`, each with a filter of its own, and keys are added to the filter of the current bucket. Once the current bucket has lasted for its share of the window the filter of the oldest bucket is discarded, which means keys are forgotten after between `+"`window - window / buckets`"+` and `+"`window`"+` has passed. Each filter is sized so that the false positive rate holds when `+"`capacity`"+` unique keys are seen within the window, and when more keys are seen the rate will be higher than configured. The memory used is approximately `+"`1.44 * log2(buckets / false_positive_rate) * capacity`"+` bits, which for a capacity of one billion keys with the default settings is around 2.8GB.
+
+The state of the filters can be persisted to a file by setting `+"`snapshot_path`"+`, in which case the file is written periodically and when the processor is closed, and read when the processor is created so that restarts don't reset deduplication. Snapshots written with a different capacity, false positive rate, window or number of buckets are ignored.
+
 ## Delivery Guarantees
 
 Performing deduplication on a stream using a distributed cache voids any at-least-once guarantees that it previously had. This is because the cache will preserve message signatures even if the message fails to leave the Benthos pipeline, which would cause message loss in the event of an outage at the output sink followed by a restart of the Benthos instance (or a server crash, etc).
@@ -55,17 +76,57 @@ cache_resources:
   - label: keycache
     memory:
       default_ttl: 60s
+`,
+		).
+		Example(
+			"Probabilistic deduplication",
+			"The following configuration deduplicates messages by their ID over a 24 hour window without a cache, where the state of the filters is written to a file every minute so that it survives restarts.",
+			`
+pipeline:
+  processors:
+    - dedupe:
+        key: ${! this.id }
+        probabilistic:
+          capacity: 100000000
+          false_positive_rate: 0.0001
+          window: 24h
+          snapshot_path: ./dedupe.snapshot
 `,
 		).
 		Fields(
 			service.NewStringField(dedupFieldCache).
-				Description("The [`cache` resource](/docs/components/caches/about) to target with this processor."),
+				Description("The [`cache` resource](/docs/components/caches/about) to target with this processor. Exactly one of `cache` or `probabilistic` must be set.").
+				Optional(),
 			service.NewInterpolatedStringField(dedupFieldKey).
 				Description("An interpolated string yielding the key to deduplicate by for each message.").
 				Examples(`${! meta("kafka_key") }`, `${! content().hash("xxhash64") }`),
 			service.NewBoolField(dedupFieldDropOnCacheErr).
 				Description("Whether messages should be dropped when the cache returns a general error such as a network issue.").
 				Default(true),
+			service.NewObjectField(dedupFieldProbabilistic,
+				service.NewIntField(dedupFieldCapacity).
+					Description("The number of unique keys expected within the window."),
+				service.NewFloatField(dedupFieldFalsePositiveRate).
+					Description("The maximum proportion of unique messages that may be dropped as duplicates.").
+					Default(0.0001),
+				service.NewDurationField(dedupFieldWindow).
+					Description("The period of time for which keys are remembered.").
+					Default("24h"),
+				service.NewIntField(dedupFieldBuckets).
+					Description("The number of buckets the window is split into, more buckets means keys are forgotten closer to the end of the window at the cost of more memory.").
+					Default(4).
+					Advanced(),
+				service.NewStringField(dedupFieldSnapshotPath).
+					Description("An optional file path to persist the state of the filters to.").
+					Default(""),
+				service.NewDurationField(dedupFieldSnapshotInterval).
+					Description("The period of time between writing snapshots of the filters.").
+					Default("1m").
+					Advanced(),
+			).
+				Description("Deduplicate keys using an in-memory bloom filter instead of a cache. Exactly one of `cache` or `probabilistic` must be set.").
+				Optional().
+				Version("4.28.0"),
 		)
 }
 
@@ -73,11 +134,6 @@ func init() {
 	err := service.RegisterBatchProcessor(
 		"dedupe", dedupeProcSpec(),
 		func(conf *service.ParsedConfig, res *service.Resources) (service.BatchProcessor, error) {
-			cache, err := conf.FieldString(dedupFieldCache)
-			if err != nil {
-				return nil, err
-			}
-
 			keyStr, err := conf.FieldString(dedupFieldKey)
 			if err != nil {
 				return nil, err
@@ -89,9 +145,23 @@ func init() {
 			}
 
 			mgr := interop.UnwrapManagement(res)
-			p, err := newDedupe(cache, keyStr, dropOnErr, mgr)
-			if err != nil {
-				return nil, err
+
+			var p *dedupeProc
+			switch hasCache, hasFilter := conf.Contains(dedupFieldCache), conf.Contains(dedupFieldProbabilistic); {
+			case hasCache == hasFilter:
+				return nil, errors.New("exactly one of cache or probabilistic must be specified")
+			case hasCache:
+				cache, err := conf.FieldString(dedupFieldCache)
+				if err != nil {
+					return nil, err
+				}
+				if p, err = newDedupe(cache, keyStr, dropOnErr, mgr); err != nil {
+					return nil, err
+				}
+			default:
+				if p, err = newProbabilisticDedupeFromParsed(conf.Namespace(dedupFieldProbabilistic), keyStr, mgr); err != nil {
+					return nil, err
+				}
 			}
 			return interop.NewUnwrapInternalBatchProcessor(processor.NewAutoObservedBatchedProcessor("dedupe", p, mgr)), nil
 		})
@@ -107,9 +177,15 @@ type dedupeProc struct {
 	key       *field.Expression
 	mgr       bundle.NewManagement
 	cacheName string
+
+	// Set instead of cacheName for probabilistic deduplication.
+	filter           *dedupeFilter
+	snapshotPath     string
+	snapshotInterval time.Duration
+	shutSig          *shutdown.Signaller
 }
 
-func newDedupe(cache, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (*dedupeProc, error) {
+func newDedupeKey(keyStr string, mgr bundle.NewManagement) (*field.Expression, error) {
 	if keyStr == "" {
 		return nil, errors.New("dedupe key must not be empty")
 	}
@@ -117,6 +193,14 @@ func newDedupe(cache, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse key expression: %v", err)
 	}
+	return key, nil
+}
+
+func newDedupe(cache, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (*dedupeProc, error) {
+	key, err := newDedupeKey(keyStr, mgr)
+	if err != nil {
+		return nil, err
+	}
 
 	if !mgr.ProbeCache(cache) {
 		return nil, fmt.Errorf("cache resource '%v' was not found", cache)
@@ -131,6 +215,89 @@ func newDedupe(cache, keyStr string, dropOnErr bool, mgr bundle.NewManagement) (
 	}, nil
 }
 
+func newProbabilisticDedupeFromParsed(conf *service.ParsedConfig, keyStr string, mgr bundle.NewManagement) (*dedupeProc, error) {
+	key, err := newDedupeKey(keyStr, mgr)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity, err := conf.FieldInt(dedupFieldCapacity)
+	if err != nil {
+		return nil, err
+	}
+	fpRate, err := conf.FieldFloat(dedupFieldFalsePositiveRate)
+	if err != nil {
+		return nil, err
+	}
+	window, err := conf.FieldDuration(dedupFieldWindow)
+	if err != nil {
+		return nil, err
+	}
+	buckets, err := conf.FieldInt(dedupFieldBuckets)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := newDedupeFilter(capacity, fpRate, window, buckets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filter: %w", err)
+	}
+
+	d := &dedupeProc{
+		log:     mgr.Logger(),
+		key:     key,
+		mgr:     mgr,
+		filter:  filter,
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	if d.snapshotPath, err = conf.FieldString(dedupFieldSnapshotPath); err != nil {
+		return nil, err
+	}
+	if d.snapshotInterval, err = conf.FieldDuration(dedupFieldSnapshotInterval); err != nil {
+		return nil, err
+	}
+
+	if d.snapshotPath == "" {
+		d.shutSig.TriggerHasStopped()
+		return d, nil
+	}
+
+	if loaded, err := filter.LoadSnapshot(d.snapshotPath); err != nil {
+		d.log.Warn("Failed to load dedupe snapshot '%v', starting with empty filters: %v\n", d.snapshotPath, err)
+	} else if loaded {
+		d.log.Info("Loaded dedupe snapshot '%v'\n", d.snapshotPath)
+	}
+
+	go d.snapshotLoop()
+	return d, nil
+}
+
+func (d *dedupeProc) snapshotLoop() {
+	defer d.shutSig.TriggerHasStopped()
+
+	var tickerChan <-chan time.Time
+	if d.snapshotInterval > 0 {
+		ticker := time.NewTicker(d.snapshotInterval)
+		defer ticker.Stop()
+		tickerChan = ticker.C
+	}
+
+	for {
+		select {
+		case <-tickerChan:
+			if err := d.filter.SaveSnapshot(d.snapshotPath); err != nil {
+				d.log.Error("Failed to write dedupe snapshot '%v': %v\n", d.snapshotPath, err)
+			}
+		case <-d.shutSig.SoftStopChan():
+			if err := d.filter.SaveSnapshot(d.snapshotPath); err != nil {
+				d.log.Error("Failed to write dedupe snapshot '%v': %v\n", d.snapshotPath, err)
+			}
+			return
+		}
+	}
+}
+
 func (d *dedupeProc) ProcessBatch(ctx *processor.BatchProcContext, batch message.Batch) ([]message.Batch, error) {
 	newBatch := message.QuickBatch(nil)
 	_ = batch.Iter(func(i int, p *message.Part) error {
@@ -141,6 +308,15 @@ func (d *dedupeProc) ProcessBatch(ctx *processor.BatchProcContext, batch message
 			return nil
 		}
 
+		if d.filter != nil {
+			if d.filter.TestAndAdd(key) {
+				ctx.Span(i).LogKV("event", "dropped", "type", "deduplicated")
+				return nil
+			}
+			newBatch = append(newBatch, p)
+			return nil
+		}
+
 		if cerr := d.mgr.AccessCache(context.Background(), d.cacheName, func(cache cache.V1) {
 			err = cache.Add(context.Background(), key, []byte{'t'}, nil)
 		}); cerr != nil {
@@ -171,6 +347,15 @@ func (d *dedupeProc) ProcessBatch(ctx *processor.BatchProcContext, batch message
 	return []message.Batch{newBatch}, nil
 }
 
-func (d *dedupeProc) Close(context.Context) error {
+func (d *dedupeProc) Close(ctx context.Context) error {
+	if d.shutSig == nil {
+		return nil
+	}
+	d.shutSig.TriggerSoftStop()
+	select {
+	case <-d.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 	return nil
 }
@@ -0,0 +1,336 @@
+package pure
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/OneOfOne/xxhash"
+)
+
+// dedupeFilter is a time bucketed bloom filter used for probabilistic
+// deduplication. Keys are added to the filter of the current bucket and are
+// considered duplicates when any retained bucket contains them, where the
+// oldest bucket is discarded each time the current bucket expires.
+type dedupeFilter struct {
+	mut sync.Mutex
+
+	bits      uint64
+	hashes    uint32
+	bucketDur time.Duration
+	nowFn     func() time.Time
+
+	// Ordered from oldest to newest, where the newest bucket receives new
+	// keys. Buckets that haven't been reached yet are nil.
+	buckets []*dedupeFilterBucket
+	dirty   bool
+}
+
+type dedupeFilterBucket struct {
+	start time.Time
+	words []uint64
+}
+
+// newDedupeFilter creates a filter that retains keys for the provided window,
+// split into a number of buckets that are each sized to hold their share of
+// the capacity such that the false positive rate across all buckets does not
+// exceed the one provided.
+func newDedupeFilter(capacity int, fpRate float64, window time.Duration, buckets int) (*dedupeFilter, error) {
+	if capacity <= 0 {
+		return nil, errors.New("capacity must be greater than zero")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		return nil, errors.New("false positive rate must be between 0 and 1")
+	}
+	if buckets <= 0 {
+		return nil, errors.New("buckets must be greater than zero")
+	}
+	if window < time.Duration(buckets) {
+		return nil, errors.New("window must be at least one nanosecond per bucket")
+	}
+
+	n := math.Ceil(float64(capacity) / float64(buckets))
+	p := fpRate / float64(buckets)
+
+	bits := uint64(math.Ceil(-n * math.Log(p) / (math.Ln2 * math.Ln2)))
+	bits = ((bits + 63) / 64) * 64
+	hashes := uint32(math.Round(float64(bits) / n * math.Ln2))
+	if hashes == 0 {
+		hashes = 1
+	}
+
+	return &dedupeFilter{
+		bits:      bits,
+		hashes:    hashes,
+		bucketDur: window / time.Duration(buckets),
+		nowFn:     time.Now,
+		buckets:   make([]*dedupeFilterBucket, buckets),
+	}, nil
+}
+
+// rotate discards buckets that have expired. Must be called with the mutex
+// held.
+func (f *dedupeFilter) rotate(now time.Time) {
+	newest := f.buckets[len(f.buckets)-1]
+	if newest == nil {
+		f.buckets[len(f.buckets)-1] = &dedupeFilterBucket{
+			start: now,
+			words: make([]uint64, f.bits/64),
+		}
+		return
+	}
+
+	elapsed := now.Sub(newest.start)
+	if elapsed < f.bucketDur {
+		return
+	}
+
+	steps := int(elapsed / f.bucketDur)
+	if steps > len(f.buckets) {
+		steps = len(f.buckets)
+	}
+	start := newest.start.Add(time.Duration(steps) * f.bucketDur)
+	if steps == len(f.buckets) {
+		start = now
+	}
+
+	for i := 0; i < steps; i++ {
+		// Reuse the memory of the discarded bucket where possible.
+		b := f.buckets[0]
+		copy(f.buckets, f.buckets[1:])
+		if b == nil {
+			b = &dedupeFilterBucket{words: make([]uint64, f.bits/64)}
+		} else {
+			clear(b.words)
+		}
+		b.start = start.Add(-time.Duration(steps-1-i) * f.bucketDur)
+		f.buckets[len(f.buckets)-1] = b
+	}
+	f.dirty = true
+}
+
+// TestAndAdd returns true if the key has already been seen within the window
+// and otherwise adds it to the filter.
+func (f *dedupeFilter) TestAndAdd(key string) bool {
+	h1, h2 := dedupeFilterHashes(key)
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	f.rotate(f.nowFn())
+
+	for _, b := range f.buckets {
+		if b != nil && f.contains(b, h1, h2) {
+			return true
+		}
+	}
+
+	newest := f.buckets[len(f.buckets)-1]
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % f.bits
+		newest.words[bit/64] |= 1 << (bit % 64)
+	}
+	f.dirty = true
+	return false
+}
+
+// dedupeFilterHashes returns the two hashes of a key from which the bits it
+// sets are derived using double hashing.
+func dedupeFilterHashes(key string) (h1, h2 uint64) {
+	h1 = xxhash.ChecksumString64(key)
+	h2 = xxhash.ChecksumString64S(key, h1) | 1
+	return
+}
+
+func (f *dedupeFilter) contains(b *dedupeFilterBucket, h1, h2 uint64) bool {
+	for i := uint64(0); i < uint64(f.hashes); i++ {
+		bit := (h1 + i*h2) % f.bits
+		if b.words[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+var dedupeSnapshotMagic = [4]byte{'B', 'D', 'D', '1'}
+
+type dedupeSnapshotHeader struct {
+	Magic     [4]byte
+	Bits      uint64
+	Hashes    uint32
+	Buckets   uint32
+	BucketDur int64
+}
+
+// WriteSnapshot writes the state of the filter to w. The buckets are copied
+// while the filter is locked so that keys can be added during the write.
+func (f *dedupeFilter) WriteSnapshot(w io.Writer) error {
+	f.mut.Lock()
+	header := dedupeSnapshotHeader{
+		Magic:     dedupeSnapshotMagic,
+		Bits:      f.bits,
+		Hashes:    f.hashes,
+		Buckets:   uint32(len(f.buckets)),
+		BucketDur: int64(f.bucketDur),
+	}
+	buckets := make([]*dedupeFilterBucket, len(f.buckets))
+	for i, b := range f.buckets {
+		if b != nil {
+			buckets[i] = &dedupeFilterBucket{
+				start: b.start,
+				words: append([]uint64(nil), b.words...),
+			}
+		}
+	}
+	f.dirty = false
+	f.mut.Unlock()
+
+	if err := writeDedupeSnapshot(w, header, buckets); err != nil {
+		f.mut.Lock()
+		f.dirty = true
+		f.mut.Unlock()
+		return err
+	}
+	return nil
+}
+
+func writeDedupeSnapshot(w io.Writer, header dedupeSnapshotHeader, buckets []*dedupeFilterBucket) error {
+	bw := bufio.NewWriter(w)
+	if err := binary.Write(bw, binary.BigEndian, header); err != nil {
+		return err
+	}
+
+	for _, b := range buckets {
+		if b == nil {
+			if err := binary.Write(bw, binary.BigEndian, int64(-1)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := binary.Write(bw, binary.BigEndian, b.start.UnixNano()); err != nil {
+			return err
+		}
+		// Words are written individually as encoding them all at once would
+		// allocate another copy of the entire bucket.
+		var buf [8]byte
+		for _, w := range b.words {
+			binary.BigEndian.PutUint64(buf[:], w)
+			if _, err := bw.Write(buf[:]); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadSnapshot replaces the state of the filter with a snapshot read from r,
+// which must have been written by a filter with the same configuration.
+func (f *dedupeFilter) ReadSnapshot(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	var header dedupeSnapshotHeader
+	if err := binary.Read(br, binary.BigEndian, &header); err != nil {
+		return fmt.Errorf("failed to read snapshot header: %w", err)
+	}
+	if header.Magic != dedupeSnapshotMagic {
+		return errors.New("unrecognised snapshot format")
+	}
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	if header.Bits != f.bits ||
+		header.Hashes != f.hashes ||
+		int(header.Buckets) != len(f.buckets) ||
+		time.Duration(header.BucketDur) != f.bucketDur {
+		return errors.New("snapshot was written with a different filter configuration")
+	}
+
+	buckets := make([]*dedupeFilterBucket, len(f.buckets))
+	for i := range buckets {
+		var start int64
+		if err := binary.Read(br, binary.BigEndian, &start); err != nil {
+			return fmt.Errorf("failed to read snapshot bucket: %w", err)
+		}
+		if start < 0 {
+			continue
+		}
+		b := &dedupeFilterBucket{
+			start: time.Unix(0, start),
+			words: make([]uint64, f.bits/64),
+		}
+		var buf [8]byte
+		for j := range b.words {
+			if _, err := io.ReadFull(br, buf[:]); err != nil {
+				return fmt.Errorf("failed to read snapshot bucket: %w", err)
+			}
+			b.words[j] = binary.BigEndian.Uint64(buf[:])
+		}
+		buckets[i] = b
+	}
+
+	f.buckets = buckets
+	f.dirty = false
+	f.rotate(f.nowFn())
+	return nil
+}
+
+// SaveSnapshot writes a snapshot of the filter to a file, replacing it
+// atomically, if the filter has changed since the last snapshot.
+func (f *dedupeFilter) SaveSnapshot(path string) error {
+	f.mut.Lock()
+	dirty := f.dirty
+	f.mut.Unlock()
+	if !dirty {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if err := f.WriteSnapshot(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		f.mut.Lock()
+		f.dirty = true
+		f.mut.Unlock()
+	}
+	return err
+}
+
+// LoadSnapshot reads a snapshot of the filter from a file, returning false if
+// the file does not exist.
+func (f *dedupeFilter) LoadSnapshot(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	if err := f.ReadSnapshot(file); err != nil {
+		return false, err
+	}
+	return true, nil
+}
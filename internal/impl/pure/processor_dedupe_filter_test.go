@@ -0,0 +1,114 @@
+package pure
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupeFilterRotation(t *testing.T) {
+	f, err := newDedupeFilter(100, 0.001, time.Hour, 4)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	f.nowFn = func() time.Time { return now }
+
+	assert.False(t, f.TestAndAdd("foo"))
+	assert.True(t, f.TestAndAdd("foo"))
+
+	// Keys are retained while their bucket is within the window.
+	now = now.Add(45 * time.Minute)
+	assert.True(t, f.TestAndAdd("foo"))
+	assert.False(t, f.TestAndAdd("bar"))
+
+	// The bucket of foo is discarded once the window has passed.
+	now = now.Add(15 * time.Minute)
+	assert.False(t, f.TestAndAdd("foo"))
+	assert.True(t, f.TestAndAdd("bar"))
+
+	// Long gaps discard all buckets.
+	now = now.Add(48 * time.Hour)
+	assert.False(t, f.TestAndAdd("bar"))
+}
+
+func TestDedupeFilterFalsePositives(t *testing.T) {
+	f, err := newDedupeFilter(10000, 0.01, time.Hour, 1)
+	require.NoError(t, err)
+
+	for i := 0; i < 10000; i++ {
+		f.TestAndAdd("seen" + strconv.Itoa(i))
+	}
+
+	var positives int
+	for i := 0; i < 10000; i++ {
+		h1, h2 := dedupeFilterHashes("unseen" + strconv.Itoa(i))
+		if f.contains(f.buckets[0], h1, h2) {
+			positives++
+		}
+	}
+	assert.Less(t, positives, 200)
+}
+
+func TestDedupeFilterSnapshot(t *testing.T) {
+	f, err := newDedupeFilter(100, 0.001, time.Hour, 4)
+	require.NoError(t, err)
+
+	now := time.Unix(1000, 0)
+	f.nowFn = func() time.Time { return now }
+
+	f.TestAndAdd("foo")
+	now = now.Add(20 * time.Minute)
+	f.TestAndAdd("bar")
+
+	var buf bytes.Buffer
+	require.NoError(t, f.WriteSnapshot(&buf))
+	snapshot := buf.Bytes()
+
+	g, err := newDedupeFilter(100, 0.001, time.Hour, 4)
+	require.NoError(t, err)
+	g.nowFn = func() time.Time { return now }
+
+	require.NoError(t, g.ReadSnapshot(bytes.NewReader(snapshot)))
+	assert.True(t, g.TestAndAdd("foo"))
+	assert.True(t, g.TestAndAdd("bar"))
+	assert.False(t, g.TestAndAdd("baz"))
+
+	// Buckets that expired while the snapshot was stored are discarded.
+	h, err := newDedupeFilter(100, 0.001, time.Hour, 4)
+	require.NoError(t, err)
+	h.nowFn = func() time.Time { return now.Add(45 * time.Minute) }
+
+	require.NoError(t, h.ReadSnapshot(bytes.NewReader(snapshot)))
+	assert.False(t, h.TestAndAdd("foo"))
+	assert.True(t, h.TestAndAdd("bar"))
+
+	mismatched, err := newDedupeFilter(200, 0.001, time.Hour, 4)
+	require.NoError(t, err)
+	require.Error(t, mismatched.ReadSnapshot(bytes.NewReader(snapshot)))
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("nope")
+}
+
+func TestDedupeFilterSnapshotWriteError(t *testing.T) {
+	_, err := newDedupeFilter(100, 0.001, 3, 4)
+	require.EqualError(t, err, "window must be at least one nanosecond per bucket")
+
+	f, err := newDedupeFilter(100, 0.001, time.Hour, 4)
+	require.NoError(t, err)
+
+	f.TestAndAdd("foo")
+	require.Error(t, f.WriteSnapshot(errWriter{}))
+	assert.True(t, f.dirty)
+
+	require.NoError(t, f.WriteSnapshot(&bytes.Buffer{}))
+	assert.False(t, f.dirty)
+}
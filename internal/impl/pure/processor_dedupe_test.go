@@ -2,6 +2,7 @@ package pure_test
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -107,3 +108,104 @@ dedupe:
 	require.NoError(t, err)
 	assert.Len(t, msgs, 1)
 }
+
+func TestDedupeProbabilistic(t *testing.T) {
+	mgr := mock.NewManager()
+
+	conf, err := testutil.ProcessorFromYAML(`
+dedupe:
+  key: ${! content() }
+  probabilistic:
+    capacity: 1000
+`)
+	require.NoError(t, err)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	msgOut, err := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("foo"), []byte("bar"), []byte("foo"),
+	}))
+	require.NoError(t, err)
+	require.Len(t, msgOut, 1)
+	assert.Equal(t, [][]byte{[]byte("foo"), []byte("bar")}, message.GetAllBytes(msgOut[0]))
+
+	msgOut, err = proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("bar"), []byte("baz"),
+	}))
+	require.NoError(t, err)
+	require.Len(t, msgOut, 1)
+	assert.Equal(t, [][]byte{[]byte("baz")}, message.GetAllBytes(msgOut[0]))
+}
+
+func TestDedupeProbabilisticSnapshot(t *testing.T) {
+	mgr := mock.NewManager()
+
+	conf, err := testutil.ProcessorFromYAML(`
+dedupe:
+  key: ${! content() }
+  probabilistic:
+    capacity: 1000
+    snapshot_path: ` + filepath.Join(t.TempDir(), "dedupe.snapshot") + `
+`)
+	require.NoError(t, err)
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgOut, err := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte("foo")}))
+	require.NoError(t, err)
+	require.Len(t, msgOut, 1)
+	require.NoError(t, proc.Close(context.Background()))
+
+	proc, err = mgr.NewProcessor(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+
+	msgOut, err = proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("foo"), []byte("bar"),
+	}))
+	require.NoError(t, err)
+	require.Len(t, msgOut, 1)
+	assert.Equal(t, [][]byte{[]byte("bar")}, message.GetAllBytes(msgOut[0]))
+}
+
+func TestDedupeBadMode(t *testing.T) {
+	for name, yaml := range map[string]string{
+		"neither": `
+dedupe:
+  key: ${! content() }
+`,
+		"both": `
+dedupe:
+  cache: foocache
+  key: ${! content() }
+  probabilistic:
+    capacity: 1000
+`,
+		"bad rate": `
+dedupe:
+  key: ${! content() }
+  probabilistic:
+    capacity: 1000
+    false_positive_rate: 2
+`,
+	} {
+		yaml := yaml
+		t.Run(name, func(t *testing.T) {
+			conf, err := testutil.ProcessorFromYAML(yaml)
+			require.NoError(t, err)
+
+			mgr := mock.NewManager()
+			mgr.Caches["foocache"] = map[string]mock.CacheItem{}
+
+			_, err = mgr.NewProcessor(conf)
+			require.Error(t, err)
+		})
+	}
+}
@@ -172,14 +172,14 @@ func (j *jqProc) getPartValue(part *message.Part, raw bool) (obj any, err error)
 	return obj, nil
 }
 
-func safeQuery(input any, meta map[string]any, c *gojq.Code) (emitted []any, err error) {
+func safeQuery(input any, c *gojq.Code, vars ...any) (emitted []any, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = fmt.Errorf("jq panic: %v", r)
 		}
 	}()
 
-	iter := c.Run(input, meta)
+	iter := c.Run(input, vars...)
 	for {
 		out, ok := iter.Next()
 		if !ok {
@@ -201,7 +201,7 @@ func (j *jqProc) Process(ctx context.Context, msg *message.Part) ([]*message.Par
 	}
 	metadata := j.getPartMetadata(msg)
 
-	emitted, err := safeQuery(in, metadata, j.code)
+	emitted, err := safeQuery(in, j.code, metadata)
 	if err != nil {
 		j.log.Debug(err.Error())
 		return nil, err
@@ -0,0 +1,304 @@
+package pure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	linFieldTarget         = "target"
+	linFieldKey            = "key"
+	linFieldSource         = "source"
+	linFieldPipelineID     = "pipeline_id"
+	linFieldConfigPath     = "config_path"
+	linFieldOffsetMetadata = "offset_metadata"
+)
+
+func lineageProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Stamps messages with an envelope of provenance metadata describing where they originated and which pipelines they have passed through.").
+		Description(`
+The first time a message passes through a `+"`lineage`"+` processor it is given an envelope describing its origin, containing the `+"`source`"+`, the hostname of the machine it was processed on, the `+"`pipeline_id`"+`, a hash of the configuration file at `+"`config_path`"+` and the input offsets found within the metadata fields listed in `+"`offset_metadata`"+`. Each subsequent time the message passes through a `+"`lineage`"+` processor, including processors of other Benthos instances where the envelope has been carried over as a header or field, a hop is appended to the envelope instead.
+
+Each hop contains the label of the processor (when set), the hostname, the pipeline ID and the time at which the hop was recorded. An envelope therefore looks like this:
+
+`+"```json"+`
+{
+  "source": "orders_consumer",
+  "hostname": "ingest-1",
+  "pipeline_id": "orders",
+  "config_hash": "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03",
+  "offsets": { "kafka_topic": "orders", "kafka_partition": 2, "kafka_offset": 1804 },
+  "hops": [
+    { "component": "stamp_origin", "hostname": "ingest-1", "pipeline_id": "orders", "timestamp": "2024-04-23T10:11:12.123456789Z" }
+  ]
+}
+`+"```"+`
+
+### Targets
+
+When the `+"`target`"+` is `+"`metadata`"+` the envelope is stored within the metadata key `+"`key`"+`, which outputs that support headers will forward as a JSON string. When the `+"`target`"+` is `+"`body`"+` the envelope is injected into the structured contents of the message at the dot path `+"`key`"+`, in which case the message must be a JSON object.`).
+		Example(
+			"Auditing Kafka Replication",
+			"Records consumed from Kafka are stamped with their origin, and the envelope is forwarded as a header to the destination topic.",
+			`
+input:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topics: [ orders ]
+    consumer_group: replicator
+  processors:
+    - label: stamp_origin
+      lineage:
+        source: orders_consumer
+        pipeline_id: ${PIPELINE_ID:replicator}
+        config_path: ./config.yaml
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders_replica
+`,
+		).
+		Fields(
+			service.NewStringAnnotatedEnumField(linFieldTarget, map[string]string{
+				"metadata": "Store the envelope within a metadata key.",
+				"body":     "Inject the envelope into the structured contents of the message.",
+			}).
+				Description("Where the lineage envelope is stored.").
+				Default("metadata"),
+			service.NewStringField(linFieldKey).
+				Description("The metadata key, or for the `body` target the dot path, at which the envelope is stored.").
+				Examples("benthos_lineage", "meta.lineage").
+				Default("benthos_lineage"),
+			service.NewInterpolatedStringField(linFieldSource).
+				Description("A name describing the source of messages, which is added to new envelopes.").
+				Examples("orders_consumer", `kafka:${! meta("kafka_topic") }`).
+				Default(""),
+			service.NewStringField(linFieldPipelineID).
+				Description("An identifier of the pipeline, which is added to new envelopes and each hop.").
+				Example("${PIPELINE_ID}").
+				Default(""),
+			service.NewStringField(linFieldConfigPath).
+				Description("An optional path to the config file of the pipeline, the SHA-256 hash of which is added to new envelopes in order to identify the version of the config that processed the message.").
+				Example("./config.yaml").
+				Default(""),
+			service.NewStringListField(linFieldOffsetMetadata).
+				Description("A list of metadata keys that identify the position of a message within its input, which are captured within new envelopes when present.").
+				Default([]any{
+					"kafka_topic", "kafka_partition", "kafka_offset",
+					"kinesis_stream", "kinesis_shard", "kinesis_sequence_number",
+					"s3_bucket", "s3_key",
+				}).
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterProcessor("lineage", lineageProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Processor, error) {
+			return newLineageProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type lineageProc struct {
+	toBody     bool
+	key        string
+	source     *service.InterpolatedString
+	pipelineID string
+	configHash string
+	offsetKeys []string
+	label      string
+	hostname   string
+	nowFn      func() time.Time
+}
+
+func newLineageProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*lineageProc, error) {
+	p := &lineageProc{
+		label: mgr.Label(),
+		nowFn: time.Now,
+	}
+
+	target, err := conf.FieldString(linFieldTarget)
+	if err != nil {
+		return nil, err
+	}
+	p.toBody = target == "body"
+
+	if p.key, err = conf.FieldString(linFieldKey); err != nil {
+		return nil, err
+	}
+	if p.key == "" {
+		return nil, errors.New("key must not be empty")
+	}
+
+	if p.source, err = conf.FieldInterpolatedString(linFieldSource); err != nil {
+		return nil, err
+	}
+	if p.pipelineID, err = conf.FieldString(linFieldPipelineID); err != nil {
+		return nil, err
+	}
+
+	configPath, err := conf.FieldString(linFieldConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	if configPath != "" {
+		configBytes, err := ifs.ReadFile(mgr.FS(), configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		hash := sha256.Sum256(configBytes)
+		p.configHash = hex.EncodeToString(hash[:])
+	}
+
+	if p.offsetKeys, err = conf.FieldStringList(linFieldOffsetMetadata); err != nil {
+		return nil, err
+	}
+
+	if p.hostname, err = os.Hostname(); err != nil {
+		mgr.Logger().Warnf("Failed to obtain hostname: %v", err)
+	}
+	return p, nil
+}
+
+// existingEnvelope attempts to extract a lineage envelope from a message,
+// which may have been carried over from another pipeline as a JSON string.
+func (p *lineageProc) existingEnvelope(msg *service.Message) (map[string]any, error) {
+	var v any
+	if p.toBody {
+		structured, err := msg.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse message as structured: %w", err)
+		}
+		v = gabs.Wrap(structured).Path(p.key).Data()
+	} else {
+		var exists bool
+		if v, exists = msg.MetaGetMut(p.key); !exists {
+			return nil, nil
+		}
+	}
+
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]any:
+		return t, nil
+	case string:
+		var env map[string]any
+		if err := json.Unmarshal([]byte(t), &env); err != nil {
+			return nil, fmt.Errorf("failed to parse existing lineage envelope: %w", err)
+		}
+		return env, nil
+	}
+	return nil, fmt.Errorf("expected existing lineage envelope to be an object, got %T", v)
+}
+
+func (p *lineageProc) newEnvelope(msg *service.Message) (map[string]any, error) {
+	env := map[string]any{
+		"hostname": p.hostname,
+	}
+
+	source, err := p.source.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("source interpolation error: %w", err)
+	}
+	if source != "" {
+		env["source"] = source
+	}
+	if p.pipelineID != "" {
+		env["pipeline_id"] = p.pipelineID
+	}
+	if p.configHash != "" {
+		env["config_hash"] = p.configHash
+	}
+
+	offsets := map[string]any{}
+	for _, k := range p.offsetKeys {
+		if v, exists := msg.MetaGetMut(k); exists {
+			offsets[k] = v
+		}
+	}
+	if len(offsets) > 0 {
+		env["offsets"] = offsets
+	}
+	return env, nil
+}
+
+func (p *lineageProc) hop() map[string]any {
+	hop := map[string]any{
+		"hostname":  p.hostname,
+		"timestamp": p.nowFn().UTC().Format(time.RFC3339Nano),
+	}
+	if p.label != "" {
+		hop["component"] = p.label
+	}
+	if p.pipelineID != "" {
+		hop["pipeline_id"] = p.pipelineID
+	}
+	return hop
+}
+
+func (p *lineageProc) Process(ctx context.Context, msg *service.Message) (service.MessageBatch, error) {
+	prev, err := p.existingEnvelope(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var env map[string]any
+	if prev != nil {
+		env = make(map[string]any, len(prev))
+		for k, v := range prev {
+			env[k] = v
+		}
+	} else if env, err = p.newEnvelope(msg); err != nil {
+		return nil, err
+	}
+
+	// Always copy the hops so that envelopes shared by copies of a message
+	// (within branches for example) are never mutated.
+	prevHops, _ := env["hops"].([]any)
+	hops := make([]any, 0, len(prevHops)+1)
+	hops = append(hops, prevHops...)
+	env["hops"] = append(hops, p.hop())
+
+	if !p.toBody {
+		msg.MetaSetMut(p.key, env)
+		return service.MessageBatch{msg}, nil
+	}
+
+	structured, err := msg.AsStructuredMut()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse message as structured: %w", err)
+	}
+	if _, isObj := structured.(map[string]any); !isObj {
+		return nil, fmt.Errorf("expected message to be an object, got %T", structured)
+	}
+	gObj := gabs.Wrap(structured)
+	if _, err := gObj.SetP(env, p.key); err != nil {
+		return nil, fmt.Errorf("failed to set lineage envelope at path %v: %w", p.key, err)
+	}
+	msg.SetStructuredMut(gObj.Data())
+	return service.MessageBatch{msg}, nil
+}
+
+func (p *lineageProc) Close(ctx context.Context) error {
+	return nil
+}
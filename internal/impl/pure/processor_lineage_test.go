@@ -0,0 +1,148 @@
+package pure
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testLineageProc(t testing.TB, confStr string) *lineageProc {
+	t.Helper()
+
+	pConf, err := lineageProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newLineageProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	proc.hostname = "testhost"
+	proc.nowFn = func() time.Time {
+		return time.Date(2024, 4, 23, 10, 0, 0, 0, time.UTC)
+	}
+	return proc
+}
+
+func TestLineageMetadata(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(confPath, []byte("foo: bar"), 0o644))
+
+	first := testLineageProc(t, `
+source: 'kafka:${! meta("kafka_topic") }'
+pipeline_id: foo
+config_path: `+confPath+`
+`)
+	first.label = "first"
+
+	second := testLineageProc(t, `
+pipeline_id: bar
+`)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("kafka_topic", "orders")
+	msg.MetaSetMut("kafka_partition", 2)
+	msg.MetaSetMut("kafka_offset", 1804)
+
+	batch, err := first.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	expHops := []any{
+		map[string]any{
+			"component":   "first",
+			"hostname":    "testhost",
+			"pipeline_id": "foo",
+			"timestamp":   "2024-04-23T10:00:00Z",
+		},
+	}
+
+	env, exists := batch[0].MetaGetMut("benthos_lineage")
+	require.True(t, exists)
+	assert.Equal(t, map[string]any{
+		"source":      "kafka:orders",
+		"hostname":    "testhost",
+		"pipeline_id": "foo",
+		"config_hash": "07091d9e7b63ac86966e39652ca5327568145ae7b61a16b7d5df29f918641ea5",
+		"offsets": map[string]any{
+			"kafka_topic":     "orders",
+			"kafka_partition": 2,
+			"kafka_offset":    1804,
+		},
+		"hops": expHops,
+	}, env)
+
+	// Simulate the envelope being carried over to another pipeline as a
+	// header.
+	envStr, exists := batch[0].MetaGet("benthos_lineage")
+	require.True(t, exists)
+
+	msg = service.NewMessage([]byte("hello world"))
+	msg.MetaSetMut("benthos_lineage", envStr)
+
+	batch, err = second.Process(context.Background(), msg)
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	env, exists = batch[0].MetaGetMut("benthos_lineage")
+	require.True(t, exists)
+
+	envMap := env.(map[string]any)
+	assert.Equal(t, "kafka:orders", envMap["source"])
+	assert.Equal(t, "foo", envMap["pipeline_id"])
+	assert.Equal(t, []any{
+		map[string]any{
+			"component":   "first",
+			"hostname":    "testhost",
+			"pipeline_id": "foo",
+			"timestamp":   "2024-04-23T10:00:00Z",
+		},
+		map[string]any{
+			"hostname":    "testhost",
+			"pipeline_id": "bar",
+			"timestamp":   "2024-04-23T10:00:00Z",
+		},
+	}, envMap["hops"])
+}
+
+func TestLineageBody(t *testing.T) {
+	proc := testLineageProc(t, `
+target: body
+key: meta.lineage
+source: foo
+`)
+
+	batch, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"id":"a"}`)))
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	batch, err = proc.Process(context.Background(), batch[0])
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+
+	v, err := batch[0].AsStructured()
+	require.NoError(t, err)
+
+	hop := map[string]any{
+		"hostname":  "testhost",
+		"timestamp": "2024-04-23T10:00:00Z",
+	}
+	assert.Equal(t, map[string]any{
+		"id": "a",
+		"meta": map[string]any{
+			"lineage": map[string]any{
+				"source":   "foo",
+				"hostname": "testhost",
+				"hops":     []any{hop, hop},
+			},
+		},
+	}, v)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`["not","an","object"]`)))
+	require.Error(t, err)
+}
@@ -7,12 +7,12 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/interop"
 	"github.com/benthosdev/benthos/v4/internal/component/metrics"
-	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/message"
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -23,6 +23,9 @@ const (
 	metProcFieldName   = "name"
 	metProcFieldLabels = "labels"
 	metProcFieldValue  = "value"
+
+	metProcFieldMaxCardinality = "max_cardinality"
+	metProcFieldOverflowValue  = "overflow_value"
 )
 
 func metProcSpec() *service.ConfigSpec {
@@ -78,7 +81,11 @@ pipeline:
 
 ### `+"`timing`"+`
 
-Equivalent to `+"`gauge`"+` where instead the metric is a timing. It is recommended that timing values are recorded in nanoseconds in order to be consistent with standard Benthos timing metrics, as in some cases these values are automatically converted into other units such as when exporting timings as histograms with Prometheus metrics.`).
+Equivalent to `+"`gauge`"+` where instead the metric is a timing. It is recommended that timing values are recorded in nanoseconds in order to be consistent with standard Benthos timing metrics, as in some cases these values are automatically converted into other units such as when exporting timings as histograms with Prometheus metrics.
+
+## Cardinality
+
+Label values that are derived from the contents of messages can result in an unbounded number of metric series, which can overwhelm metrics destinations. The field `+"`max_cardinality`"+` limits the number of unique combinations of label values that are emitted by the processor, once the limit is reached any new combination of label values is replaced by the `+"`overflow_value`"+` for all labels, and a warning is logged.`).
 		Example(
 			"Counter",
 			"In this example we emit a counter metric called `Foos`, which increments for every message processed, and we label the metric with some metadata about where the message came from and a field from the document that states what type it is. We also configure our metrics to emit to CloudWatch, and explicitly only allow our custom metric and some internal Benthos metrics to emit.",
@@ -102,6 +109,22 @@ metrics:
     ].contains(this) { deleted() }
   aws_cloudwatch:
     namespace: ProdConsumer
+`,
+		).
+		Example(
+			"Business Metrics",
+			"In this example we count the revenue of orders by country and payment method, where the country is derived with a Bloblang query. In order to protect our metrics destination from an unexpected number of countries we cap the number of label combinations at 500.",
+			`
+pipeline:
+  processors:
+    - metric:
+        name: OrderRevenue
+        type: counter_by
+        value: ${! this.total.round() }
+        labels:
+          country: ${! this.shipping.country.uppercase().or("unknown") }
+          payment_method: ${! this.payment.method }
+        max_cardinality: 500
 `,
 		).
 		Example(
@@ -137,6 +160,16 @@ metrics:
 			service.NewInterpolatedStringField(metProcFieldValue).
 				Description("For some metric types specifies a value to set, increment. Certain metrics exporters such as Prometheus support floating point values, but those that do not will cast a floating point value into an integer.").
 				Default(""),
+			service.NewIntField(metProcFieldMaxCardinality).
+				Description("The maximum number of unique combinations of label values to emit, beyond which label values are replaced with the `overflow_value`. Set to zero in order to disable the limit.").
+				Default(0).
+				Advanced().
+				Version("4.28.0"),
+			service.NewStringField(metProcFieldOverflowValue).
+				Description("The value given to all labels of a metric once the `max_cardinality` has been reached.").
+				Default("__overflow__").
+				Advanced().
+				Version("4.28.0"),
 		)
 }
 
@@ -166,11 +199,22 @@ func init() {
 				return nil, err
 			}
 
+			maxCardinality, err := conf.FieldInt(metProcFieldMaxCardinality)
+			if err != nil {
+				return nil, err
+			}
+
+			overflowValue, err := conf.FieldString(metProcFieldOverflowValue)
+			if err != nil {
+				return nil, err
+			}
+
 			mgr := interop.UnwrapManagement(res)
 			p, err := newMetricProcessor(procTypeStr, procName, valueStr, labelMap, mgr)
 			if err != nil {
 				return nil, err
 			}
+			p.maxCardinality, p.overflowValue = maxCardinality, overflowValue
 
 			return interop.NewUnwrapInternalBatchProcessor(p), nil
 		})
@@ -194,6 +238,12 @@ type metricProcessor struct {
 	mTimerVec   metrics.StatTimerVec
 
 	handler func(string, int, message.Batch) error
+
+	maxCardinality int
+	overflowValue  string
+	seenMut        sync.Mutex
+	seen           map[string]struct{}
+	overflowed     bool
 }
 
 type (
@@ -228,7 +278,7 @@ func (l labels) values(index int, msg message.Batch) ([]string, error) {
 	return values, nil
 }
 
-func newMetricProcessor(typeStr, name, valueStr string, labels map[string]string, mgr bundle.NewManagement) (processor.V1, error) {
+func newMetricProcessor(typeStr, name, valueStr string, labels map[string]string, mgr bundle.NewManagement) (*metricProcessor, error) {
 	value, err := mgr.BloblEnvironment().NewField(valueStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse value expression: %v", err)
@@ -237,6 +287,7 @@ func newMetricProcessor(typeStr, name, valueStr string, labels map[string]string
 	m := &metricProcessor{
 		log:   mgr.Logger(),
 		value: value,
+		seen:  map[string]struct{}{},
 	}
 
 	if name == "" {
@@ -297,9 +348,39 @@ func newMetricProcessor(typeStr, name, valueStr string, labels map[string]string
 	return m, nil
 }
 
+// labelValues returns the label values of a message, where combinations of
+// values beyond the maximum cardinality are replaced with the overflow value.
+func (m *metricProcessor) labelValues(index int, msg message.Batch) ([]string, error) {
+	values, err := m.labels.values(index, msg)
+	if err != nil || m.maxCardinality <= 0 {
+		return values, err
+	}
+
+	key := strings.Join(values, "\x00")
+
+	m.seenMut.Lock()
+	defer m.seenMut.Unlock()
+
+	if _, exists := m.seen[key]; exists {
+		return values, nil
+	}
+	if len(m.seen) < m.maxCardinality {
+		m.seen[key] = struct{}{}
+		return values, nil
+	}
+	if !m.overflowed {
+		m.overflowed = true
+		m.log.Warn("Metric label cardinality limit of %v reached, further label values will be replaced with %v", m.maxCardinality, m.overflowValue)
+	}
+	for i := range values {
+		values[i] = m.overflowValue
+	}
+	return values, nil
+}
+
 func (m *metricProcessor) handleCounter(val string, index int, msg message.Batch) error {
 	if len(m.labels) > 0 {
-		labelValues, err := m.labels.values(index, msg)
+		labelValues, err := m.labelValues(index, msg)
 		if err != nil {
 			return err
 		}
@@ -330,7 +411,7 @@ func withNumberStr(val string, ifn func(i int64) error, ffn func(f float64) erro
 
 func (m *metricProcessor) handleCounterBy(val string, index int, msg message.Batch) error {
 	if len(m.labels) > 0 {
-		labelValues, err := m.labels.values(index, msg)
+		labelValues, err := m.labelValues(index, msg)
 		if err != nil {
 			return err
 		}
@@ -353,7 +434,7 @@ func (m *metricProcessor) handleCounterBy(val string, index int, msg message.Bat
 
 func (m *metricProcessor) handleGauge(val string, index int, msg message.Batch) error {
 	if len(m.labels) > 0 {
-		labelValues, err := m.labels.values(index, msg)
+		labelValues, err := m.labelValues(index, msg)
 		if err != nil {
 			return err
 		}
@@ -383,7 +464,7 @@ func (m *metricProcessor) handleTimer(val string, index int, msg message.Batch)
 		return errors.New("value is negative")
 	}
 	if len(m.labels) > 0 {
-		labelValues, err := m.labels.values(index, msg)
+		labelValues, err := m.labelValues(index, msg)
 		if err != nil {
 			return err
 		}
@@ -241,3 +241,40 @@ metric:
 
 	assert.Equal(t, expTimingAvgs, actTimingAvgs)
 }
+
+func TestMetricCardinality(t *testing.T) {
+	conf, err := testutil.ProcessorFromYAML(`
+metric:
+  type: counter
+  name: foo
+  labels:
+    country: '${! this.country }'
+  max_cardinality: 2
+  overflow_value: other
+`)
+	require.NoError(t, err)
+
+	mockMetrics := metrics.NewLocal()
+
+	mgr := mock.NewManager()
+	mgr.M = mockMetrics
+
+	proc, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+
+	msg, res := proc.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte(`{"country":"uk"}`),
+		[]byte(`{"country":"us"}`),
+		[]byte(`{"country":"fr"}`),
+		[]byte(`{"country":"uk"}`),
+		[]byte(`{"country":"de"}`),
+	}))
+	assert.Len(t, msg, 1)
+	assert.NoError(t, res)
+
+	assert.Equal(t, map[string]int64{
+		`foo{country="uk"}`:    2,
+		`foo{country="us"}`:    1,
+		`foo{country="other"}`: 2,
+	}, mockMetrics.FlushCounters())
+}
@@ -24,6 +24,8 @@ func init() {
 			Description(`
 The field `+"`cap`"+`, if greater than zero, caps the maximum number of parallel processing threads.
 
+Regardless of the order in which messages finish processing the resulting batch preserves the order of the original messages, where any messages produced from a single message (for example by an `+"[`unarchive`](/docs/components/processors/unarchive)"+` processor) are placed at the position of their origin. Errors encountered by the child processors are flagged on the individual messages that caused them, and can be handled with [error handling patterns](/docs/configuration/error_handling) after this processor.
+
 The functionality of this processor depends on being applied across messages that are batched. You can find out more about batching [in this doc](/docs/configuration/batching).`).
 			Fields(
 				service.NewIntField(parProcFieldCap).
@@ -84,7 +86,14 @@ func (p *parallelProc) ProcessBatch(ctx *processor.BatchProcContext, msg message
 			for index := range reqChan {
 				resMsgs, err := processor.ExecuteAll(ctx.Context(), p.children, resultMsgs[index])
 				if err != nil {
-					return
+					// An unrecoverable error is attributed to the message that
+					// caused it only, and the worker continues to drain
+					// requests so that the remaining messages aren't blocked.
+					_ = resultMsgs[index].Iter(func(i int, p *message.Part) error {
+						processor.MarkErr(p, nil, err)
+						return nil
+					})
+					continue
 				}
 				resultParts := []*message.Part{}
 				for _, m := range resMsgs {
@@ -97,8 +106,14 @@ func (p *parallelProc) ProcessBatch(ctx *processor.BatchProcContext, msg message
 			}
 		}()
 	}
+
+feedLoop:
 	for i := 0; i < msg.Len(); i++ {
-		reqChan <- i
+		select {
+		case reqChan <- i:
+		case <-ctx.Context().Done():
+			break feedLoop
+		}
 	}
 	close(reqChan)
 	wg.Wait()
@@ -169,3 +169,72 @@ parallel:
 		t.Errorf("Wrong result: %v != %v", act, exp)
 	}
 }
+
+func TestParallelOrdering(t *testing.T) {
+	conf := parseYAMLConf(t, `
+parallel:
+  cap: 3
+  processors:
+    - sleep:
+        duration: '${! 50 - (content().number() * 10) }ms'
+    - mapping: 'root = content().string() + "_done"'
+`)
+
+	h, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	msgs, res := h.ProcessBatch(context.Background(), message.QuickBatch([][]byte{
+		[]byte("0"),
+		[]byte("1"),
+		[]byte("2"),
+		[]byte("3"),
+		[]byte("4"),
+	}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, [][]byte{
+		[]byte("0_done"),
+		[]byte("1_done"),
+		[]byte("2_done"),
+		[]byte("3_done"),
+		[]byte("4_done"),
+	}, message.GetAllBytes(msgs[0]))
+}
+
+func TestParallelCancelled(t *testing.T) {
+	conf := parseYAMLConf(t, `
+parallel:
+  cap: 1
+  processors:
+    - sleep:
+        duration: 1s
+`)
+
+	h, err := mock.NewManager().NewProcessor(conf)
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+
+	resChan := make(chan []message.Batch, 1)
+	go func() {
+		msgs, _ := h.ProcessBatch(ctx, message.QuickBatch([][]byte{
+			[]byte("foo"),
+			[]byte("bar"),
+			[]byte("baz"),
+		}))
+		resChan <- msgs
+	}()
+
+	select {
+	case msgs := <-resChan:
+		require.Len(t, msgs, 1)
+		require.Equal(t, 3, msgs[0].Len())
+		_ = msgs[0].Iter(func(i int, p *message.Part) error {
+			assert.Error(t, p.ErrorGet())
+			return nil
+		})
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out waiting for cancelled batch")
+	}
+}
@@ -0,0 +1,471 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	quoFieldCache        = "cache"
+	quoFieldKey          = "key"
+	quoFieldCost         = "cost"
+	quoFieldDailyLimit   = "daily_limit"
+	quoFieldMonthlyLimit = "monthly_limit"
+	quoFieldOperation    = "operation"
+	quoFieldOnExceeded   = "on_exceeded"
+	quoFieldTimezone     = "timezone"
+	quoFieldLockTTL      = "lock_ttl"
+)
+
+func quotaProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Tracks the units consumed by messages against daily and/or monthly budgets per key, stored within a cache, and flags or drops messages that exceed them.").
+		Description(`
+The units consumed by each message are determined by the `+"`cost`"+` mapping, which by default counts one unit per message. This allows budgets to be expressed as a number of messages, bytes, API calls or any other measure that can be derived from a message. Budgets are tracked separately for each `+"`key`"+`, and the usage of a key is shared by all `+"`quota`"+` processors that target the same cache with the same key, allowing a budget to be checked in one place and consumed in another.
+
+Usage is stored within the [`+"`cache`"+` resource](/docs/components/caches/about) under the key followed by the current day (`+"`<key>:2006-01-02`"+`) or month (`+"`<key>:2006-01`"+`), and therefore resets at the start of each day and month in the configured `+"`timezone`"+`. Entries are set with a TTL that expires them a day after their period ends, although some caches only support a general TTL and will ignore this.
+
+When the `+"`operation`"+` is `+"`consume`"+` a message only consumes units when its cost fits within the remaining budgets of all configured periods, and when the `+"`operation`"+` is `+"`check`"+` messages are checked against the remaining budgets without consuming any units, which is useful for avoiding work that would later be rejected.
+
+### Metadata
+
+This processor adds the metadata fields `+"`quota_daily_remaining` and `quota_monthly_remaining`"+` for each configured budget, containing the units that remain after the message was processed. Messages that exceed a budget and are not dropped additionally have the metadata field `+"`quota_exceeded`"+` set to the period that was exceeded (`+"`daily` or `monthly`"+`) and are flagged with an error, and can therefore be routed differently with a `+"[`switch` output](/docs/components/outputs/switch)"+` or handled using the methods outlined [here](/docs/configuration/error_handling). The counter `+"`quota_exceeded`"+` is incremented for each message that exceeds a budget, labelled by the `+"`period`"+`.
+
+### Concurrency
+
+The usage of a cache is read and updated under a lock shared by all `+"`quota`"+` processors of a Benthos instance that target the same cache, and therefore any number of processors and processing threads can check and consume the same budgets without exceeding them.
+
+When multiple Benthos instances share budgets through a distributed cache the updates of different instances can race, resulting in budgets being marginally exceeded. This is prevented by setting `+"`lock_ttl`"+`, in which case usage is only updated whilst holding a lock that is added to the cache under the key followed by `+"`:lock`"+`. This requires a cache that adds keys atomically and expires them promptly, such as `+"`redis`, `memcached` and `couchbase`"+`. The caches `+"`aws_s3`, `gcp_cloud_storage`, `ristretto` and `multilevel`"+` do not add keys atomically, and the caches `+"`aws_dynamodb`, `azure_blob_storage`, `mongodb`, `nats_kv` and `sql`"+` do not promptly expire the keys they add, and they are therefore not suitable for locks.`).
+		Example(
+			"Cost-Capped API Enrichment",
+			"Here we enrich messages with a third party API that bills per call, limiting each customer to 1000 calls per day and 20000 per month, and send messages that exceed the quota to a separate topic instead of calling the API.",
+			`
+pipeline:
+  processors:
+    - quota:
+        cache: quotas
+        key: ${! @customer_id }
+        daily_limit: 1000
+        monthly_limit: 20000
+    - switch:
+        - check: '@quota_exceeded == null'
+          processors:
+            - http:
+                url: http://api.example.com/enrich
+                verb: POST
+
+output:
+  switch:
+    cases:
+      - check: '@quota_exceeded != null'
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: over_quota
+      - output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: enriched
+
+cache_resources:
+  - label: quotas
+    redis:
+      url: redis://localhost:6379
+`,
+		).
+		Example(
+			"Byte Budget",
+			"The cost of each message can be its size in order to cap the bytes sent per day, where messages over the budget are dropped.",
+			`
+pipeline:
+  processors:
+    - quota:
+        cache: quotas
+        key: uploads
+        cost: root = content().length()
+        daily_limit: 10000000000
+        on_exceeded: drop
+
+cache_resources:
+  - label: quotas
+    file:
+      directory: ./quotas
+`,
+		).
+		Fields(
+			service.NewStringField(quoFieldCache).
+				Description("The [`cache` resource](/docs/components/caches/about) to store usage within."),
+			service.NewInterpolatedStringField(quoFieldKey).
+				Description("An interpolated string yielding the key to track the budget of for each message.").
+				Examples(`${! @customer_id }`, `${! this.tenant }`).
+				Default("default"),
+			service.NewBloblangField(quoFieldCost).
+				Description("An optional Bloblang mapping that returns the number of units consumed by each message. When omitted each message consumes one unit.").
+				Examples(`root = content().length()`, `root = this.items.length()`).
+				Optional(),
+			service.NewIntField(quoFieldDailyLimit).
+				Description("The maximum number of units that may be consumed per key each day.").
+				Optional(),
+			service.NewIntField(quoFieldMonthlyLimit).
+				Description("The maximum number of units that may be consumed per key each month.").
+				Optional(),
+			service.NewStringAnnotatedEnumField(quoFieldOperation, map[string]string{
+				"consume": "Consume the units of messages that are within the budgets.",
+				"check":   "Check that the units of messages are within the budgets without consuming them.",
+			}).
+				Description("The operation to perform for each message.").
+				Default("consume"),
+			service.NewStringAnnotatedEnumField(quoFieldOnExceeded, map[string]string{
+				"flag": "Flag messages that exceed a budget with an error and the metadata field `quota_exceeded`.",
+				"drop": "Drop messages that exceed a budget.",
+			}).
+				Description("The action to take on messages that exceed a budget.").
+				Default("flag"),
+			service.NewStringField(quoFieldTimezone).
+				Description("The [IANA timezone](https://en.wikipedia.org/wiki/List_of_tz_database_time_zones) that determines the start of each day and month.").
+				Example("America/New_York").
+				Default("UTC").
+				Advanced(),
+			service.NewDurationField(quoFieldLockTTL).
+				Description("An optional TTL of a lock added to the cache whilst the usage of a key is updated, which prevents multiple Benthos instances sharing a cache from exceeding budgets. The TTL should exceed the time taken to update usage, and releases the lock of an instance that stopped without releasing it.").
+				Example("5s").
+				Optional().
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor(
+		"quota", quotaProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newQuotaProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type quotaProc struct {
+	mgr          *service.Resources
+	cacheName    string
+	key          *service.InterpolatedString
+	cost         *bloblang.Executor
+	dailyLimit   int64
+	monthlyLimit int64
+	consume      bool
+	drop         bool
+	location     *time.Location
+	lockTTL      time.Duration
+	nowFn        func() time.Time
+
+	mut *sync.Mutex
+
+	mExceeded *service.MetricCounter
+}
+
+func newQuotaProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*quotaProc, error) {
+	q := &quotaProc{
+		mgr:          mgr,
+		dailyLimit:   -1,
+		monthlyLimit: -1,
+		nowFn:        time.Now,
+		mExceeded:    mgr.Metrics().NewCounter("quota_exceeded", "period"),
+	}
+
+	var err error
+	if q.cacheName, err = conf.FieldString(quoFieldCache); err != nil {
+		return nil, err
+	}
+	if !mgr.HasCache(q.cacheName) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", q.cacheName)
+	}
+
+	// Processors that share a cache share a lock in order to serialise their
+	// updates of usage.
+	mut, _ := mgr.GetOrSetGeneric(quotaCacheLockKey{cache: q.cacheName}, &sync.Mutex{})
+	q.mut = mut.(*sync.Mutex)
+	if q.key, err = conf.FieldInterpolatedString(quoFieldKey); err != nil {
+		return nil, err
+	}
+	if conf.Contains(quoFieldCost) {
+		if q.cost, err = conf.FieldBloblang(quoFieldCost); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, l := range []struct {
+		field string
+		limit *int64
+	}{
+		{field: quoFieldDailyLimit, limit: &q.dailyLimit},
+		{field: quoFieldMonthlyLimit, limit: &q.monthlyLimit},
+	} {
+		if !conf.Contains(l.field) {
+			continue
+		}
+		limit, err := conf.FieldInt(l.field)
+		if err != nil {
+			return nil, err
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("%v must not be negative", l.field)
+		}
+		*l.limit = int64(limit)
+	}
+	if q.dailyLimit < 0 && q.monthlyLimit < 0 {
+		return nil, fmt.Errorf("at least one of %v or %v must be specified", quoFieldDailyLimit, quoFieldMonthlyLimit)
+	}
+
+	operation, err := conf.FieldString(quoFieldOperation)
+	if err != nil {
+		return nil, err
+	}
+	q.consume = operation == "consume"
+
+	onExceeded, err := conf.FieldString(quoFieldOnExceeded)
+	if err != nil {
+		return nil, err
+	}
+	q.drop = onExceeded == "drop"
+
+	timezone, err := conf.FieldString(quoFieldTimezone)
+	if err != nil {
+		return nil, err
+	}
+	if q.location, err = time.LoadLocation(timezone); err != nil {
+		return nil, fmt.Errorf("failed to parse timezone: %w", err)
+	}
+	if conf.Contains(quoFieldLockTTL) {
+		if q.lockTTL, err = conf.FieldDuration(quoFieldLockTTL); err != nil {
+			return nil, err
+		}
+		if q.lockTTL <= 0 {
+			return nil, fmt.Errorf("%v must be greater than zero", quoFieldLockTTL)
+		}
+	}
+	return q, nil
+}
+
+// quotaCacheLockKey is the key of the lock shared by the quota processors of a
+// cache.
+type quotaCacheLockKey struct {
+	cache string
+}
+
+// quotaPeriod is a budget period that usage is tracked within.
+type quotaPeriod struct {
+	name     string
+	limit    int64
+	cacheKey string
+	ttl      time.Duration
+}
+
+func (q *quotaProc) periods(key string, now time.Time) []quotaPeriod {
+	now = now.In(q.location)
+
+	var periods []quotaPeriod
+	if q.dailyLimit >= 0 {
+		end := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, q.location)
+		periods = append(periods, quotaPeriod{
+			name:     "daily",
+			limit:    q.dailyLimit,
+			cacheKey: key + ":" + now.Format("2006-01-02"),
+			ttl:      end.Sub(now) + 24*time.Hour,
+		})
+	}
+	if q.monthlyLimit >= 0 {
+		end := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, q.location)
+		periods = append(periods, quotaPeriod{
+			name:     "monthly",
+			limit:    q.monthlyLimit,
+			cacheKey: key + ":" + now.Format("2006-01"),
+			ttl:      end.Sub(now) + 24*time.Hour,
+		})
+	}
+	return periods
+}
+
+func (q *quotaProc) messageCost(msg *service.Message) (int64, error) {
+	if q.cost == nil {
+		return 1, nil
+	}
+
+	res, err := msg.BloblangQuery(q.cost)
+	if err != nil {
+		return 0, fmt.Errorf("cost mapping failed: %w", err)
+	}
+	if res == nil {
+		return 0, errors.New("cost mapping deleted the message")
+	}
+	v, err := res.AsStructured()
+	if err != nil {
+		return 0, fmt.Errorf("cost mapping did not return a number: %w", err)
+	}
+	cost, err := value.IGetInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("cost mapping did not return an integer: %w", err)
+	}
+	if cost < 0 {
+		return 0, fmt.Errorf("cost mapping returned a negative cost: %v", cost)
+	}
+	return cost, nil
+}
+
+func (q *quotaProc) getUsage(ctx context.Context, cacheKey string) (usage int64, err error) {
+	var usageBytes []byte
+	if cerr := q.mgr.AccessCache(ctx, q.cacheName, func(c service.Cache) {
+		usageBytes, err = c.Get(ctx, cacheKey)
+	}); cerr != nil {
+		return 0, cerr
+	}
+	if err != nil {
+		if errors.Is(err, service.ErrKeyNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if usage, err = strconv.ParseInt(string(usageBytes), 10, 64); err != nil {
+		return 0, fmt.Errorf("failed to parse usage of %v: %w", cacheKey, err)
+	}
+	return usage, nil
+}
+
+func (q *quotaProc) setUsage(ctx context.Context, cacheKey string, usage int64, ttl time.Duration) (err error) {
+	if cerr := q.mgr.AccessCache(ctx, q.cacheName, func(c service.Cache) {
+		err = c.Set(ctx, cacheKey, []byte(strconv.FormatInt(usage, 10)), &ttl)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// lock adds a lock entry for a key to the cache, waiting for as long as the
+// entry exists, and returns a function that releases it.
+func (q *quotaProc) lock(ctx context.Context, key string) (func(), error) {
+	lockKey := key + ":lock"
+	for {
+		var err error
+		if cerr := q.mgr.AccessCache(ctx, q.cacheName, func(c service.Cache) {
+			err = c.Add(ctx, lockKey, []byte("locked"), &q.lockTTL)
+		}); cerr != nil {
+			return nil, cerr
+		}
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, service.ErrKeyAlreadyExists) {
+			return nil, fmt.Errorf("failed to lock %v: %w", key, err)
+		}
+		select {
+		case <-time.After(quotaLockRetryPeriod):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return func() {
+		_ = q.mgr.AccessCache(ctx, q.cacheName, func(c service.Cache) {
+			_ = c.Delete(ctx, lockKey)
+		})
+	}, nil
+}
+
+const quotaLockRetryPeriod = 10 * time.Millisecond
+
+// processMessage checks and consumes the budgets of a message, and returns the
+// name of the period that was exceeded, if any.
+func (q *quotaProc) processMessage(ctx context.Context, batch service.MessageBatch, i int, now time.Time) (string, error) {
+	msg := batch[i]
+
+	key, err := batch.TryInterpolatedString(i, q.key)
+	if err != nil {
+		return "", fmt.Errorf("key interpolation error: %w", err)
+	}
+	cost, err := q.messageCost(msg)
+	if err != nil {
+		return "", err
+	}
+
+	if q.lockTTL > 0 && q.consume && cost > 0 {
+		unlock, err := q.lock(ctx, key)
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+	}
+
+	periods := q.periods(key, now)
+	usages := make([]int64, len(periods))
+	var exceeded string
+	for j, p := range periods {
+		if usages[j], err = q.getUsage(ctx, p.cacheKey); err != nil {
+			return "", err
+		}
+		if exceeded == "" && usages[j]+cost > p.limit {
+			exceeded = p.name
+		}
+	}
+
+	for j, p := range periods {
+		if exceeded == "" && q.consume && cost > 0 {
+			usages[j] += cost
+			if err := q.setUsage(ctx, p.cacheKey, usages[j], p.ttl); err != nil {
+				return "", err
+			}
+		}
+		remaining := p.limit - usages[j]
+		if remaining < 0 {
+			remaining = 0
+		}
+		msg.MetaSetMut("quota_"+p.name+"_remaining", remaining)
+	}
+	return exceeded, nil
+}
+
+func (q *quotaProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	now := q.nowFn()
+
+	newBatch := make(service.MessageBatch, 0, len(batch))
+	for i, msg := range batch {
+		exceeded, err := q.processMessage(ctx, batch, i, now)
+		if err != nil {
+			msg.SetError(err)
+			newBatch = append(newBatch, msg)
+			continue
+		}
+		if exceeded == "" {
+			newBatch = append(newBatch, msg)
+			continue
+		}
+
+		q.mExceeded.Incr(1, exceeded)
+		if q.drop {
+			continue
+		}
+		msg.MetaSetMut("quota_exceeded", exceeded)
+		msg.SetError(fmt.Errorf("%v quota exceeded", exceeded))
+		newBatch = append(newBatch, msg)
+	}
+
+	if len(newBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (q *quotaProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,298 @@
+package pure
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testQuotaProc(t testing.TB, mRes *service.Resources, confStr string, now *time.Time) *quotaProc {
+	t.Helper()
+
+	conf, err := quotaProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	q, err := newQuotaProcFromParsed(conf, mRes)
+	require.NoError(t, err)
+
+	q.nowFn = func() time.Time {
+		return *now
+	}
+	return q
+}
+
+func quotaMessages(contents ...string) service.MessageBatch {
+	batch := make(service.MessageBatch, len(contents))
+	for i, c := range contents {
+		batch[i] = service.NewMessage([]byte(c))
+		batch[i].MetaSetMut("customer", "acme")
+	}
+	return batch
+}
+
+func TestQuotaProcessorDaily(t *testing.T) {
+	tCtx := context.Background()
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	now := time.Date(2024, 5, 10, 23, 0, 0, 0, time.UTC)
+	q := testQuotaProc(t, mRes, `
+cache: foo
+key: ${! @customer }
+daily_limit: 2
+`, &now)
+
+	res, err := q.ProcessBatch(tCtx, quotaMessages("a", "b", "c"))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 3)
+
+	for i, exp := range []struct {
+		remaining int64
+		exceeded  string
+	}{
+		{remaining: 1},
+		{remaining: 0},
+		{remaining: 0, exceeded: "daily"},
+	} {
+		v, _ := res[0][i].MetaGetMut("quota_daily_remaining")
+		assert.Equal(t, exp.remaining, v, i)
+
+		exceeded, _ := res[0][i].MetaGet("quota_exceeded")
+		assert.Equal(t, exp.exceeded, exceeded, i)
+		if exp.exceeded == "" {
+			assert.NoError(t, res[0][i].GetError(), i)
+		} else {
+			assert.EqualError(t, res[0][i].GetError(), "daily quota exceeded", i)
+		}
+	}
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		v, err := c.Get(tCtx, "acme:2024-05-10")
+		require.NoError(t, err)
+		assert.Equal(t, "2", string(v))
+	}))
+
+	// The budget resets on the following day.
+	now = now.Add(time.Hour)
+	res, err = q.ProcessBatch(tCtx, quotaMessages("d"))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+	assert.NoError(t, res[0][0].GetError())
+}
+
+func TestQuotaProcessorMonthlyCost(t *testing.T) {
+	tCtx := context.Background()
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	q := testQuotaProc(t, mRes, `
+cache: foo
+cost: root = content().length()
+daily_limit: 100
+monthly_limit: 10
+on_exceeded: drop
+`, &now)
+
+	res, err := q.ProcessBatch(tCtx, quotaMessages("hello", "world!", "foo"))
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 2)
+
+	mBytes, err := res[0][1].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(mBytes))
+
+	v, _ := res[0][1].MetaGetMut("quota_monthly_remaining")
+	assert.Equal(t, int64(2), v)
+	v, _ = res[0][1].MetaGetMut("quota_daily_remaining")
+	assert.Equal(t, int64(92), v)
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		v, err := c.Get(tCtx, "default:2024-05")
+		require.NoError(t, err)
+		assert.Equal(t, "8", string(v))
+	}))
+}
+
+func TestQuotaProcessorCheck(t *testing.T) {
+	tCtx := context.Background()
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	check := testQuotaProc(t, mRes, `
+cache: foo
+daily_limit: 1
+operation: check
+`, &now)
+	consume := testQuotaProc(t, mRes, `
+cache: foo
+daily_limit: 1
+`, &now)
+
+	res, err := check.ProcessBatch(tCtx, quotaMessages("a", "b"))
+	require.NoError(t, err)
+	require.Len(t, res[0], 2)
+	assert.NoError(t, res[0][0].GetError())
+	assert.NoError(t, res[0][1].GetError())
+
+	res, err = consume.ProcessBatch(tCtx, quotaMessages("a"))
+	require.NoError(t, err)
+	require.Len(t, res[0], 1)
+	assert.NoError(t, res[0][0].GetError())
+
+	res, err = check.ProcessBatch(tCtx, quotaMessages("b"))
+	require.NoError(t, err)
+	require.Len(t, res[0], 1)
+	assert.Error(t, res[0][0].GetError())
+}
+
+func TestQuotaProcessorSharedConcurrently(t *testing.T) {
+	tCtx := context.Background()
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	var procs []*quotaProc
+	for i := 0; i < 4; i++ {
+		procs = append(procs, testQuotaProc(t, mRes, `
+cache: foo
+daily_limit: 100
+`, &now))
+	}
+
+	var wg sync.WaitGroup
+	var accepted int64
+	for _, q := range procs {
+		wg.Add(1)
+		go func(q *quotaProc) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				res, err := q.ProcessBatch(tCtx, quotaMessages("a"))
+				require.NoError(t, err)
+				if res[0][0].GetError() == nil {
+					atomic.AddInt64(&accepted, 1)
+				}
+			}
+		}(q)
+	}
+	wg.Wait()
+
+	// Processors sharing a cache never exceed the budget.
+	assert.Equal(t, int64(100), atomic.LoadInt64(&accepted))
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		v, err := c.Get(tCtx, "default:2024-05-10")
+		require.NoError(t, err)
+		assert.Equal(t, "100", string(v))
+	}))
+}
+
+func TestQuotaProcessorLock(t *testing.T) {
+	tCtx := context.Background()
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	q := testQuotaProc(t, mRes, `
+cache: foo
+key: bar
+daily_limit: 10
+lock_ttl: 1m
+`, &now)
+
+	// A lock held by another instance delays the update until it is released.
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		require.NoError(t, c.Add(tCtx, "bar:lock", []byte("locked"), nil))
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		res, err := q.ProcessBatch(tCtx, quotaMessages("a"))
+		require.NoError(t, err)
+		assert.NoError(t, res[0][0].GetError())
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected processing to wait for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		require.NoError(t, c.Delete(tCtx, "bar:lock"))
+	}))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the lock")
+	}
+
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		v, err := c.Get(tCtx, "bar:2024-05-10")
+		require.NoError(t, err)
+		assert.Equal(t, "1", string(v))
+
+		_, err = c.Get(tCtx, "bar:lock")
+		assert.ErrorIs(t, err, service.ErrKeyNotFound)
+	}))
+
+	// Waiting for a lock is abandoned when the context is cancelled.
+	require.NoError(t, mRes.AccessCache(tCtx, "foo", func(c service.Cache) {
+		require.NoError(t, c.Add(tCtx, "bar:lock", []byte("locked"), nil))
+	}))
+
+	cancelCtx, cancel := context.WithTimeout(tCtx, 50*time.Millisecond)
+	defer cancel()
+
+	res, err := q.ProcessBatch(cancelCtx, quotaMessages("a"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, res[0][0].GetError(), context.DeadlineExceeded)
+}
+
+func TestQuotaProcessorTimezone(t *testing.T) {
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	now := time.Date(2024, 5, 31, 23, 0, 0, 0, time.UTC)
+	q := testQuotaProc(t, mRes, `
+cache: foo
+key: bar
+daily_limit: 1
+monthly_limit: 1
+timezone: Europe/Berlin
+`, &now)
+
+	periods := q.periods("bar", now)
+	require.Len(t, periods, 2)
+	assert.Equal(t, "bar:2024-06-01", periods[0].cacheKey)
+	assert.Equal(t, 47*time.Hour, periods[0].ttl)
+	assert.Equal(t, "bar:2024-06", periods[1].cacheKey)
+}
+
+func TestQuotaProcessorConfigErrors(t *testing.T) {
+	mRes := service.MockResources(service.MockResourcesOptAddCache("foo"))
+
+	for _, test := range []struct {
+		conf        string
+		errContains string
+	}{
+		{conf: `cache: foo`, errContains: "at least one of"},
+		{conf: "cache: bar\ndaily_limit: 1", errContains: "was not found"},
+		{conf: "cache: foo\ndaily_limit: -1", errContains: "must not be negative"},
+		{conf: "cache: foo\ndaily_limit: 1\nlock_ttl: 0s", errContains: "must be greater than zero"},
+	} {
+		conf, err := quotaProcSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newQuotaProcFromParsed(conf, mRes)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.errContains)
+	}
+}
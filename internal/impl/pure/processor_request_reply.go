@@ -0,0 +1,355 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rrpFieldOutput             = "output"
+	rrpFieldInput              = "input"
+	rrpFieldCorrelationID      = "correlation_id"
+	rrpFieldMetadataKey        = "metadata_key"
+	rrpFieldReplyCorrelationID = "reply_correlation_id"
+	rrpFieldReplyTo            = "reply_to"
+	rrpFieldTimeout            = "timeout"
+	rrpFieldResultMap          = "result_map"
+)
+
+func requestReplyProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Integration").
+		Beta().
+		Version("4.28.0").
+		Summary("Publishes each message as a request to an output and waits for a correlated reply to arrive on an input, merging the reply into the message.").
+		Description(`
+Each message is given a correlation ID, which is stored within the metadata field named by `+"`metadata_key`"+`, and is then written to the `+"`output`"+` as a request. Replies are consumed from the `+"`input`"+` for the lifetime of the processor, and are matched to pending requests by resolving the `+"`reply_correlation_id`"+` of each reply. Responders are therefore expected to copy the correlation ID of a request into the metadata of its reply, which with Benthos can be done by preserving the metadata of messages.
+
+When a `+"`reply_to`"+` value is configured it is added to requests within the metadata field `+"`reply_to`"+`, allowing responders to determine where replies should be sent.
+
+By default the contents of each message are replaced with the contents of its reply. When a `+"`result_map`"+` is configured it is instead executed against the reply, where `+"`this`"+` refers to the reply and `+"`root`"+` refers to the original message. Metadata of the reply can be accessed with the `+"`metadata`"+` function, whereas the `+"[`@` operator](/docs/guides/bloblang/about#metadata)"+` references the metadata of the original message.
+
+Messages that fail to be published or that do not receive a reply within the `+"`timeout`"+` are left unchanged and flagged as having failed, which allows them to be handled with [error handling methods](/docs/configuration/error_handling). Replies that do not match a pending request, for example because the request has timed out, are acknowledged and dropped.
+
+### Metrics
+
+The counters `+"`request_reply_timeout`"+` and `+"`request_reply_unmatched`"+` count requests that timed out and replies that did not match a pending request respectively.`).
+		Example(
+			"Kafka RPC",
+			"Here we request enrichment data from a service that consumes the topic `enrich_requests` and writes replies to the topic `enrich_replies`.",
+			`
+pipeline:
+  processors:
+    - request_reply:
+        timeout: 10s
+        reply_to: enrich_replies
+        output:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topic: enrich_requests
+            metadata:
+              include_patterns: [ correlation_id, reply_to ]
+        input:
+          kafka_franz:
+            seed_brokers: [ localhost:9092 ]
+            topics: [ enrich_replies ]
+            consumer_group: enrich_replies_${! hostname() }
+        result_map: root.enrichment = this
+`,
+		).
+		Fields(
+			service.NewOutputField(rrpFieldOutput).
+				Description("An output to which requests are written."),
+			service.NewInputField(rrpFieldInput).
+				Description("An input from which replies are consumed."),
+			service.NewInterpolatedStringField(rrpFieldCorrelationID).
+				Description("A correlation ID to assign to each request, which must be unique amongst requests awaiting a reply.").
+				Default(`${! uuid_v4() }`).
+				Advanced(),
+			service.NewStringField(rrpFieldMetadataKey).
+				Description("The metadata field of requests in which the correlation ID is stored.").
+				Default("correlation_id").
+				Advanced(),
+			service.NewInterpolatedStringField(rrpFieldReplyCorrelationID).
+				Description("An interpolated string that resolves the correlation ID of a reply.").
+				Default(`${! @correlation_id }`).
+				Advanced(),
+			service.NewStringField(rrpFieldReplyTo).
+				Description("An optional value to add to requests within the metadata field `reply_to`, such as the topic or subject that replies should be sent to.").
+				Example("enrich_replies").
+				Optional(),
+			service.NewDurationField(rrpFieldTimeout).
+				Description("The maximum period of time to wait for the reply of a request.").
+				Default("5s"),
+			service.NewBloblangField(rrpFieldResultMap).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) that merges a reply into the original message.").
+				Example(`root.enrichment = this`).
+				Example(`meta status = metadata("status")
+root.result = this.result`).
+				Optional(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("request_reply", requestReplyProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newRequestReplyProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type replyReader interface {
+	ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error)
+	Close(ctx context.Context) error
+}
+
+type requestWriter interface {
+	WriteBatch(ctx context.Context, b service.MessageBatch) error
+	Close(ctx context.Context) error
+}
+
+type requestReplyProc struct {
+	out         requestWriter
+	in          replyReader
+	corrID      *service.InterpolatedString
+	metaKey     string
+	replyCorrID *service.InterpolatedString
+	replyTo     string
+	timeout     time.Duration
+	resultMap   *bloblang.Executor
+
+	pendingMut sync.Mutex
+	pending    map[string]chan *service.Message
+
+	log        *service.Logger
+	mTimeout   *service.MetricCounter
+	mUnmatched *service.MetricCounter
+
+	readCtx  context.Context
+	readDone func()
+	readWG   sync.WaitGroup
+}
+
+func newRequestReplyProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*requestReplyProc, error) {
+	p := &requestReplyProc{
+		pending:    map[string]chan *service.Message{},
+		log:        mgr.Logger(),
+		mTimeout:   mgr.Metrics().NewCounter("request_reply_timeout"),
+		mUnmatched: mgr.Metrics().NewCounter("request_reply_unmatched"),
+	}
+
+	var err error
+	if p.corrID, err = conf.FieldInterpolatedString(rrpFieldCorrelationID); err != nil {
+		return nil, err
+	}
+	if p.metaKey, err = conf.FieldString(rrpFieldMetadataKey); err != nil {
+		return nil, err
+	}
+	if p.metaKey == "" {
+		return nil, errors.New("metadata_key must not be empty")
+	}
+	if p.replyCorrID, err = conf.FieldInterpolatedString(rrpFieldReplyCorrelationID); err != nil {
+		return nil, err
+	}
+	if conf.Contains(rrpFieldReplyTo) {
+		if p.replyTo, err = conf.FieldString(rrpFieldReplyTo); err != nil {
+			return nil, err
+		}
+	}
+	if p.timeout, err = conf.FieldDuration(rrpFieldTimeout); err != nil {
+		return nil, err
+	}
+	if conf.Contains(rrpFieldResultMap) {
+		if p.resultMap, err = conf.FieldBloblang(rrpFieldResultMap); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := conf.FieldOutput(rrpFieldOutput)
+	if err != nil {
+		return nil, err
+	}
+	in, err := conf.FieldInput(rrpFieldInput)
+	if err != nil {
+		_ = out.Close(context.Background())
+		return nil, err
+	}
+
+	p.start(out, in)
+	return p, nil
+}
+
+func (p *requestReplyProc) start(out requestWriter, in replyReader) {
+	p.out, p.in = out, in
+	p.readCtx, p.readDone = context.WithCancel(context.Background())
+
+	p.readWG.Add(1)
+	go p.readLoop()
+}
+
+func (p *requestReplyProc) readLoop() {
+	defer p.readWG.Done()
+
+	for {
+		batch, ackFn, err := p.in.ReadBatch(p.readCtx)
+		if err != nil {
+			if p.readCtx.Err() != nil || errors.Is(err, service.ErrEndOfInput) {
+				return
+			}
+			p.log.Errorf("Failed to read replies: %v", err)
+			select {
+			case <-time.After(time.Second):
+			case <-p.readCtx.Done():
+				return
+			}
+			continue
+		}
+
+		for i, msg := range batch {
+			id, err := batch.TryInterpolatedString(i, p.replyCorrID)
+			if err != nil {
+				p.log.Errorf("Failed to resolve reply correlation ID: %v", err)
+				p.mUnmatched.Incr(1)
+				continue
+			}
+
+			p.pendingMut.Lock()
+			replyChan, exists := p.pending[id]
+			delete(p.pending, id)
+			p.pendingMut.Unlock()
+
+			if !exists {
+				p.log.Debugf("Dropping reply with unknown correlation ID: %v", id)
+				p.mUnmatched.Incr(1)
+				continue
+			}
+			replyChan <- msg
+		}
+		_ = ackFn(p.readCtx, nil)
+	}
+}
+
+func (p *requestReplyProc) release(ids []string) {
+	p.pendingMut.Lock()
+	for _, id := range ids {
+		delete(p.pending, id)
+	}
+	p.pendingMut.Unlock()
+}
+
+func (p *requestReplyProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	newBatch := batch.Copy()
+
+	var requests service.MessageBatch
+	var indexes []int
+	var ids []string
+	var replyChans []chan *service.Message
+
+	p.pendingMut.Lock()
+	for i, msg := range newBatch {
+		id, err := batch.TryInterpolatedString(i, p.corrID)
+		if err != nil {
+			msg.SetError(fmt.Errorf("correlation_id interpolation error: %w", err))
+			continue
+		}
+		if _, exists := p.pending[id]; exists || id == "" {
+			msg.SetError(fmt.Errorf("correlation ID %q is empty or already awaiting a reply", id))
+			continue
+		}
+
+		replyChan := make(chan *service.Message, 1)
+		p.pending[id] = replyChan
+
+		req := msg.Copy()
+		req.MetaSetMut(p.metaKey, id)
+		if p.replyTo != "" {
+			req.MetaSetMut("reply_to", p.replyTo)
+		}
+
+		requests = append(requests, req)
+		indexes = append(indexes, i)
+		ids = append(ids, id)
+		replyChans = append(replyChans, replyChan)
+	}
+	p.pendingMut.Unlock()
+	defer p.release(ids)
+
+	if len(requests) == 0 {
+		return []service.MessageBatch{newBatch}, nil
+	}
+
+	if err := p.out.WriteBatch(ctx, requests); err != nil {
+		for _, i := range indexes {
+			newBatch[i].SetError(fmt.Errorf("failed to send request: %w", err))
+		}
+		return []service.MessageBatch{newBatch}, nil
+	}
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	timedOut := false
+	for j, replyChan := range replyChans {
+		i := indexes[j]
+
+		var reply *service.Message
+		if !timedOut {
+			select {
+			case reply = <-replyChan:
+			case <-timer.C:
+				timedOut = true
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		} else {
+			select {
+			case reply = <-replyChan:
+			default:
+			}
+		}
+		if reply == nil {
+			p.mTimeout.Incr(1)
+			newBatch[i].SetError(fmt.Errorf("timed out waiting for reply to request %v", ids[j]))
+			continue
+		}
+
+		if p.resultMap == nil {
+			replyBytes, err := reply.AsBytes()
+			if err != nil {
+				newBatch[i].SetError(err)
+				continue
+			}
+			newBatch[i].SetBytes(replyBytes)
+			continue
+		}
+
+		res, err := newBatch[i].Copy().BloblangMutateFrom(p.resultMap, reply)
+		if err != nil {
+			newBatch[i].SetError(fmt.Errorf("result_map failed: %w", err))
+			continue
+		}
+		if res != nil {
+			newBatch[i] = res
+		}
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (p *requestReplyProc) Close(ctx context.Context) error {
+	p.readDone()
+	p.readWG.Wait()
+
+	inErr := p.in.Close(ctx)
+	if err := p.out.Close(ctx); err != nil {
+		return err
+	}
+	return inErr
+}
@@ -0,0 +1,111 @@
+package pure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testRequestReplyProc(t testing.TB, confStr string) *requestReplyProc {
+	t.Helper()
+
+	pConf, err := requestReplyProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newRequestReplyProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, proc.Close(context.Background()))
+	})
+	return proc
+}
+
+func TestRequestReplyEcho(t *testing.T) {
+	// Requests are written to an inproc pipe that is also consumed for
+	// replies, and therefore each request is its own reply.
+	proc := testRequestReplyProc(t, `
+output:
+  inproc: loop
+input:
+  inproc: loop
+reply_to: loop
+result_map: |
+  root.reply = this
+  meta reply_to = metadata("reply_to")
+`)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+		service.NewMessage([]byte(`{"id":2}`)),
+		service.NewMessage([]byte(`{"id":3}`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 3)
+
+	for i, msg := range res[0] {
+		require.NoError(t, msg.GetError())
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`{"id":%v,"reply":{"id":%v}}`, i+1, i+1), string(mBytes))
+
+		replyTo, _ := msg.MetaGet("reply_to")
+		assert.Equal(t, "loop", replyTo)
+	}
+	assert.Empty(t, proc.pending)
+}
+
+func TestRequestReplyReplaceContents(t *testing.T) {
+	proc := testRequestReplyProc(t, `
+output:
+  inproc: loop
+  processors:
+    - mapping: 'root = content().uppercase()'
+input:
+  inproc: loop
+`)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`hello world`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 1)
+	require.NoError(t, res[0][0].GetError())
+
+	mBytes, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO WORLD", string(mBytes))
+}
+
+func TestRequestReplyTimeout(t *testing.T) {
+	proc := testRequestReplyProc(t, `
+output:
+  drop: {}
+input:
+  inproc: nowhere
+correlation_id: 'req-${! content() }'
+timeout: 50ms
+`)
+
+	res, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`a`)),
+		service.NewMessage([]byte(`b`)),
+	})
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Len(t, res[0], 2)
+
+	require.EqualError(t, res[0][0].GetError(), "timed out waiting for reply to request req-a")
+	require.EqualError(t, res[0][1].GetError(), "timed out waiting for reply to request req-b")
+
+	mBytes, err := res[0][0].AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "a", string(mBytes))
+	assert.Empty(t, proc.pending)
+}
@@ -0,0 +1,244 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sampFieldMode       = "mode"
+	sampFieldRatio      = "ratio"
+	sampFieldPerSecond  = "per_second"
+	sampFieldKey        = "key"
+	sampFieldAlwaysKeep = "always_keep"
+)
+
+func sampleProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Drops a portion of messages in order to reduce the volume of a stream, with rules for messages that should always be kept.").
+		Description(`
+Messages for which the `+"`always_keep`"+` query returns `+"`true`"+` are always kept and do not count towards the sample. All other messages are sampled according to the `+"`mode`"+`.
+
+### Metrics
+
+The counters `+"`sample_kept`"+` and `+"`sample_dropped`"+` are incremented for each message kept and dropped respectively.`).
+		Example(
+			"Sampling Traces",
+			"Here we keep 10% of traces, where all spans of a trace are either kept or dropped together, and always keep spans that contain errors.",
+			`
+pipeline:
+  processors:
+    - sample:
+        mode: hash
+        key: ${! this.trace_id }
+        ratio: 0.1
+        always_keep: this.status == "ERROR"
+`,
+		).
+		Example(
+			"Capping Debug Logs",
+			"Here we limit debug logs to 100 per second, while all other logs are kept.",
+			`
+pipeline:
+  processors:
+    - sample:
+        mode: rate
+        per_second: 100
+        always_keep: this.level != "debug"
+`,
+		).
+		Fields(
+			service.NewStringAnnotatedEnumField(sampFieldMode, map[string]string{
+				"probabilistic": "Keep each message at random with a probability of `ratio`.",
+				"rate":          "Keep up to `per_second` messages within each second, dropping the remainder.",
+				"hash":          "Keep messages where a hash of the `key` falls within the `ratio`, which means messages with the same key are consistently kept or dropped, even across separate instances of Benthos.",
+			}).
+				Description("The sampling strategy to use.").
+				Default("probabilistic"),
+			service.NewFloatField(sampFieldRatio).
+				Description("The proportion of messages to keep, between 0 and 1. Used by the `probabilistic` and `hash` modes.").
+				Example(0.1).
+				Default(1.0),
+			service.NewIntField(sampFieldPerSecond).
+				Description("The maximum number of messages to keep within each second. Used by the `rate` mode.").
+				Default(100),
+			service.NewInterpolatedStringField(sampFieldKey).
+				Description("A key to hash for each message. Used by the `hash` mode.").
+				Example(`${! this.trace_id }`).
+				Default(""),
+			service.NewBloblangField(sampFieldAlwaysKeep).
+				Description("An optional [Bloblang query](/docs/guides/bloblang/about) that should return a boolean value indicating whether a message should always be kept. If the query fails the message is kept and flagged as having failed.").
+				Example(`this.status == "ERROR"`).
+				Optional(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("sample", sampleProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newSampleProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sampleProc struct {
+	mode       string
+	ratio      float64
+	perSecond  int
+	key        *service.InterpolatedString
+	alwaysKeep *bloblang.Executor
+
+	windowMut   sync.Mutex
+	windowStart time.Time
+	windowCount int
+
+	mKept    *service.MetricCounter
+	mDropped *service.MetricCounter
+
+	randFn func() float64
+	nowFn  func() time.Time
+}
+
+func newSampleProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sampleProc, error) {
+	p := &sampleProc{
+		mKept:    mgr.Metrics().NewCounter("sample_kept"),
+		mDropped: mgr.Metrics().NewCounter("sample_dropped"),
+		randFn:   rand.Float64,
+		nowFn:    time.Now,
+	}
+
+	var err error
+	if p.mode, err = conf.FieldString(sampFieldMode); err != nil {
+		return nil, err
+	}
+	if p.ratio, err = conf.FieldFloat(sampFieldRatio); err != nil {
+		return nil, err
+	}
+	if p.ratio < 0 || p.ratio > 1 {
+		return nil, fmt.Errorf("ratio must be between 0 and 1, got %v", p.ratio)
+	}
+	if p.perSecond, err = conf.FieldInt(sampFieldPerSecond); err != nil {
+		return nil, err
+	}
+	if p.perSecond < 0 {
+		return nil, fmt.Errorf("per_second must not be negative, got %v", p.perSecond)
+	}
+	if p.mode == "hash" {
+		keyStr, err := conf.FieldString(sampFieldKey)
+		if err != nil {
+			return nil, err
+		}
+		if keyStr == "" {
+			return nil, errors.New("a key must be specified when the mode is hash")
+		}
+		if p.key, err = conf.FieldInterpolatedString(sampFieldKey); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(sampFieldAlwaysKeep) {
+		if p.alwaysKeep, err = conf.FieldBloblang(sampFieldAlwaysKeep); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// hashRatio maps a key onto the range [0, 1).
+func hashRatio(key []byte) float64 {
+	h := fnv.New64a()
+	_, _ = h.Write(key)
+
+	// FNV distributes similar short keys poorly across the upper bits, so the
+	// hash is mixed with the splitmix64 finalizer.
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x>>11) / (1 << 53)
+}
+
+func (p *sampleProc) keepRate() bool {
+	p.windowMut.Lock()
+	defer p.windowMut.Unlock()
+
+	now := p.nowFn()
+	if now.Sub(p.windowStart) >= time.Second {
+		p.windowStart = now
+		p.windowCount = 0
+	}
+	if p.windowCount >= p.perSecond {
+		return false
+	}
+	p.windowCount++
+	return true
+}
+
+func (p *sampleProc) keep(batch service.MessageBatch, i int) (bool, error) {
+	if p.alwaysKeep != nil {
+		resMsg, err := batch.BloblangQuery(i, p.alwaysKeep)
+		if err != nil {
+			return true, fmt.Errorf("always_keep query failed: %w", err)
+		}
+		if resMsg != nil {
+			res, err := resMsg.AsStructured()
+			if err != nil {
+				return true, fmt.Errorf("always_keep query failed: %w", err)
+			}
+			if b, _ := res.(bool); b {
+				return true, nil
+			}
+		}
+	}
+
+	switch p.mode {
+	case "rate":
+		return p.keepRate(), nil
+	case "hash":
+		key, err := batch.TryInterpolatedBytes(i, p.key)
+		if err != nil {
+			return true, fmt.Errorf("key interpolation error: %w", err)
+		}
+		return hashRatio(key) < p.ratio, nil
+	}
+	return p.randFn() < p.ratio, nil
+}
+
+func (p *sampleProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var newBatch service.MessageBatch
+	for i, msg := range batch {
+		keep, err := p.keep(batch, i)
+		if err != nil {
+			msg = msg.Copy()
+			msg.SetError(err)
+		}
+		if !keep {
+			p.mDropped.Incr(1)
+			continue
+		}
+		p.mKept.Incr(1)
+		newBatch = append(newBatch, msg)
+	}
+	if len(newBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (p *sampleProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,134 @@
+package pure
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testSampleProc(t testing.TB, confStr string) *sampleProc {
+	t.Helper()
+
+	pConf, err := sampleProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newSampleProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return proc
+}
+
+func sampleDocs(t testing.TB, proc *sampleProc, docs ...string) (kept []string) {
+	t.Helper()
+
+	var batch service.MessageBatch
+	for _, d := range docs {
+		batch = append(batch, service.NewMessage([]byte(d)))
+	}
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	for _, b := range res {
+		for _, m := range b {
+			mBytes, err := m.AsBytes()
+			require.NoError(t, err)
+			kept = append(kept, string(mBytes))
+		}
+	}
+	return
+}
+
+func TestSampleProbabilistic(t *testing.T) {
+	proc := testSampleProc(t, `
+ratio: 0.5
+always_keep: this.keep
+`)
+	rolls := []float64{0.1, 0.9, 0.4, 0.6}
+	proc.randFn = func() float64 {
+		r := rolls[0]
+		rolls = rolls[1:]
+		return r
+	}
+
+	assert.Equal(t, []string{
+		`{"id":1,"keep":false}`,
+		`{"id":2,"keep":true}`,
+		`{"id":4,"keep":false}`,
+	}, sampleDocs(t, proc,
+		`{"id":1,"keep":false}`,
+		`{"id":2,"keep":true}`,
+		`{"id":3,"keep":false}`,
+		`{"id":4,"keep":false}`,
+		`{"id":5,"keep":false}`,
+	))
+}
+
+func TestSampleRate(t *testing.T) {
+	proc := testSampleProc(t, `
+mode: rate
+per_second: 2
+`)
+	now := time.Now()
+	proc.nowFn = func() time.Time { return now }
+
+	assert.Equal(t, []string{"a", "b"}, sampleDocs(t, proc, "a", "b", "c"))
+	assert.Empty(t, sampleDocs(t, proc, "d"))
+
+	now = now.Add(time.Second)
+	assert.Equal(t, []string{"e", "f"}, sampleDocs(t, proc, "e", "f", "g"))
+}
+
+func TestSampleHash(t *testing.T) {
+	procA := testSampleProc(t, `
+mode: hash
+key: ${! this.trace }
+ratio: 0.3
+`)
+	procB := testSampleProc(t, `
+mode: hash
+key: ${! this.trace }
+ratio: 0.3
+`)
+
+	var docs []string
+	for i := 0; i < 1000; i++ {
+		docs = append(docs, fmt.Sprintf(`{"trace":"t%v","span":%v}`, i%100, i))
+	}
+
+	keptA := sampleDocs(t, procA, docs...)
+	keptB := sampleDocs(t, procB, docs...)
+	assert.Equal(t, keptA, keptB)
+
+	// All spans of a kept trace are kept.
+	assert.Equal(t, 0, len(keptA)%10)
+	assert.InDelta(t, 300, len(keptA), 100)
+
+	all := testSampleProc(t, `
+mode: hash
+key: ${! this.trace }
+ratio: 1
+`)
+	assert.Len(t, sampleDocs(t, all, docs...), 1000)
+}
+
+func TestSampleBadConfig(t *testing.T) {
+	for _, confStr := range []string{
+		`ratio: 1.5`,
+		`mode: hash`,
+		`
+mode: rate
+per_second: -1
+`,
+	} {
+		pConf, err := sampleProcSpec().ParseYAML(confStr, nil)
+		require.NoError(t, err)
+
+		_, err = newSampleProcFromParsed(pConf, service.MockResources())
+		require.Error(t, err, confStr)
+	}
+}
@@ -0,0 +1,162 @@
+package pure
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	slpFieldMaxSize = "max_size"
+	slpFieldAction  = "action"
+	slpFieldMarker  = "truncate_marker"
+
+	slpMetaOriginalSize = "size_limit_original_size"
+)
+
+func sizeLimitProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Enforces a maximum size on the raw contents of messages, rejecting, truncating or dropping messages that exceed it.").
+		Description(`
+Messages within the limit are left unchanged. Messages that exceed the limit are given the metadata field `+"`size_limit_original_size`"+` containing their original size in bytes, and are then handled according to the `+"`action`"+`.
+
+Rejected messages are flagged as having failed, which allows them to be routed to a dead letter queue with [error handling methods](/docs/configuration/error_handling), as in the example below. Messages that must be delivered in their entirety to a sink with a hard payload limit can instead be split with the `+"[`chunk` processor](/docs/components/processors/chunk)"+`.`).
+		Example(
+			"Routing Oversized Messages to a Dead Letter Queue",
+			"SQS rejects messages larger than 256KiB, here we route such messages to an S3 bucket instead of attempting to deliver them.",
+			`
+pipeline:
+  processors:
+    - size_limit:
+        max_size: 256KiB
+        action: reject
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          aws_s3:
+            bucket: oversized-messages
+            path: ${! uuid_v4() }.json
+      - output:
+          aws_sqs:
+            url: https://sqs.us-east-2.amazonaws.com/123456789012/MyQueue
+`,
+		).
+		Fields(
+			service.NewStringField(slpFieldMaxSize).
+				Description("The maximum size of a message, either as a number of bytes or a human readable size.").
+				Example("1MB").
+				Example("256KiB").
+				Example("1024"),
+			service.NewStringAnnotatedEnumField(slpFieldAction, map[string]string{
+				"reject":   "Flag messages that exceed the limit as having failed.",
+				"truncate": "Truncate messages to the limit, ending with the `truncate_marker`.",
+				"drop":     "Drop messages that exceed the limit.",
+			}).
+				Description("The action to take on messages that exceed the limit.").
+				Default("reject"),
+			service.NewStringField(slpFieldMarker).
+				Description("A marker to end truncated messages with, which counts towards the limit.").
+				Default("...[truncated]").
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("size_limit", sizeLimitProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newSizeLimitProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type sizeLimitProc struct {
+	maxSize int
+	action  string
+	marker  []byte
+
+	mExceeded *service.MetricCounter
+}
+
+func newSizeLimitProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sizeLimitProc, error) {
+	p := &sizeLimitProc{
+		mExceeded: mgr.Metrics().NewCounter("size_limit_exceeded"),
+	}
+
+	maxSizeStr, err := conf.FieldString(slpFieldMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := humanize.ParseBytes(maxSizeStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse max_size: %w", err)
+	}
+	if maxSize == 0 || maxSize > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid max_size: %v", maxSizeStr)
+	}
+	p.maxSize = int(maxSize)
+
+	if p.action, err = conf.FieldString(slpFieldAction); err != nil {
+		return nil, err
+	}
+
+	marker, err := conf.FieldString(slpFieldMarker)
+	if err != nil {
+		return nil, err
+	}
+	if p.action == "truncate" && len(marker) > p.maxSize {
+		return nil, fmt.Errorf("truncate_marker must not exceed the max_size of %v bytes", p.maxSize)
+	}
+	p.marker = []byte(marker)
+	return p, nil
+}
+
+func (p *sizeLimitProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	var newBatch service.MessageBatch
+	for _, msg := range batch {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(mBytes) <= p.maxSize {
+			newBatch = append(newBatch, msg)
+			continue
+		}
+
+		p.mExceeded.Incr(1)
+		switch p.action {
+		case "drop":
+			continue
+		case "truncate":
+			msg = msg.Copy()
+			truncated := make([]byte, 0, p.maxSize)
+			truncated = append(truncated, mBytes[:p.maxSize-len(p.marker)]...)
+			truncated = append(truncated, p.marker...)
+			msg.SetBytes(truncated)
+		default:
+			msg = msg.Copy()
+			msg.SetError(fmt.Errorf("message size of %v bytes exceeds the limit of %v bytes", len(mBytes), p.maxSize))
+		}
+		msg.MetaSetMut(slpMetaOriginalSize, int64(len(mBytes)))
+		newBatch = append(newBatch, msg)
+	}
+	if len(newBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (p *sizeLimitProc) Close(ctx context.Context) error {
+	return nil
+}
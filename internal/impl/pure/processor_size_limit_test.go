@@ -0,0 +1,84 @@
+package pure
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestSizeLimitActions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		conf     string
+		expected []string
+		errored  []bool
+	}{
+		{
+			name:     "reject",
+			conf:     `max_size: 10`,
+			expected: []string{"hello", "hello world this is long"},
+			errored:  []bool{false, true},
+		},
+		{
+			name: "truncate",
+			conf: `
+max_size: 10
+action: truncate
+truncate_marker: '...'
+`,
+			expected: []string{"hello", "hello w..."},
+			errored:  []bool{false, false},
+		},
+		{
+			name: "drop",
+			conf: `
+max_size: 10
+action: drop
+`,
+			expected: []string{"hello"},
+			errored:  []bool{false},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			pConf, err := sizeLimitProcSpec().ParseYAML(test.conf, nil)
+			require.NoError(t, err)
+
+			proc, err := newSizeLimitProcFromParsed(pConf, service.MockResources())
+			require.NoError(t, err)
+
+			batches, err := proc.ProcessBatch(context.Background(), service.MessageBatch{
+				service.NewMessage([]byte("hello")),
+				service.NewMessage([]byte("hello world this is long")),
+			})
+			require.NoError(t, err)
+			require.Len(t, batches, 1)
+			require.Len(t, batches[0], len(test.expected))
+
+			for i, msg := range batches[0] {
+				mBytes, err := msg.AsBytes()
+				require.NoError(t, err)
+				assert.Equal(t, test.expected[i], string(mBytes))
+				assert.Equal(t, test.errored[i], msg.GetError() != nil)
+
+				_, exists := msg.MetaGetMut("size_limit_original_size")
+				assert.Equal(t, i == 1, exists)
+			}
+		})
+	}
+}
+
+func TestSizeLimitBadMarker(t *testing.T) {
+	pConf, err := sizeLimitProcSpec().ParseYAML(`
+max_size: 2
+action: truncate
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newSizeLimitProcFromParsed(pConf, service.MockResources())
+	require.Error(t, err)
+}
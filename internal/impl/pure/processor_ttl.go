@@ -0,0 +1,239 @@
+package pure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	ttlpFieldTTL       = "ttl"
+	ttlpFieldExpiresAt = "expires_at"
+	ttlpFieldOverwrite = "overwrite"
+	ttlpFieldOnExpired = "on_expired"
+
+	// ttlMetaExpiresAt is the metadata key that holds the expiry of a message
+	// in RFC 3339 format.
+	ttlMetaExpiresAt = "expires_at"
+
+	// ttlMetaExpired is the metadata key added to expired messages when they
+	// are flagged.
+	ttlMetaExpired = "expired"
+)
+
+func ttlProcSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Categories("Utility").
+		Beta().
+		Version("4.28.0").
+		Summary("Stamps messages with an expiry and handles messages that have expired, in order to prevent stale data from being delivered after a long outage.").
+		Description(`
+The expiry of a message is stored as the metadata field `+"`expires_at`"+` in RFC 3339 format. A message that has no expiry is stamped with one by executing the `+"`expires_at`"+` mapping, which is useful for deriving an expiry from broker metadata, or when the mapping is not set or returns `+"`null`"+` it is stamped with the current time plus the `+"`ttl`"+`. Messages that already have an expiry keep it unless `+"`overwrite`"+` is `+"`true`"+`.
+
+A typical deployment stamps messages with an expiry within the processors of an input, and then checks for expired messages with another `+"`ttl`"+` processor within the processors of an output, where the action to take on expired messages is set with `+"`on_expired`"+`. Since messages that fail to be written are replayed through the processors of the stream, the check is performed again before each attempt at delivering a message.
+
+Expired messages can be routed to a dead letter queue by setting `+"`on_expired`"+` to `+"`error`"+` and handling the failed messages with a [`+"`switch`"+` output](/docs/components/outputs/switch) as shown in the examples, for more information read about [error handling](/docs/configuration/error_handling).
+
+### Metrics
+
+The counter `+"`ttl_stamped`"+` is incremented for each message stamped with an expiry, and the counter `+"`ttl_expired`"+` is incremented for each expired message and is labelled by the `+"`action`"+` taken.`).
+		Example(
+			"Expiring Stale Events",
+			"Here we stamp events consumed from Kafka with an expiry of one hour after the time they were produced, falling back to one hour after they were consumed, and drop any that have expired before they are written.",
+			`
+input:
+  kafka_franz:
+    seed_brokers: [ localhost:9092 ]
+    topics: [ events ]
+    consumer_group: benthos
+  processors:
+    - ttl:
+        ttl: 1h
+        expires_at: 'root = if metadata("kafka_timestamp_unix") != null { metadata("kafka_timestamp_unix") + 3600 }'
+
+output:
+  http_client:
+    url: http://localhost:8080/events
+  processors:
+    - ttl:
+        on_expired: drop
+`,
+		).
+		Example(
+			"Dead Lettering Expired Messages",
+			"Here expired messages are flagged as failed and routed to a dead letter queue instead of being written to their target.",
+			`
+pipeline:
+  processors:
+    - ttl:
+        ttl: 5m
+        on_expired: error
+
+output:
+  switch:
+    cases:
+      - check: errored()
+        output:
+          file:
+            path: ./expired.jsonl
+            codec: lines
+      - output:
+          http_client:
+            url: http://localhost:8080/events
+`,
+		).
+		Fields(
+			service.NewDurationField(ttlpFieldTTL).
+				Description("An optional duration after the time a message is processed at which it expires, used for messages that have no expiry.").
+				Example("1h").
+				Optional(),
+			service.NewBloblangField(ttlpFieldExpiresAt).
+				Description("An optional Bloblang mapping that returns the expiry of a message, either as a timestamp, an RFC 3339 string or a unix timestamp in seconds. When the mapping returns `null` the `ttl` is used instead.").
+				Example(`root = metadata("kafka_timestamp_unix") + 3600`).
+				Example(`root = this.expires_at`).
+				Optional(),
+			service.NewBoolField(ttlpFieldOverwrite).
+				Description("Whether to replace the expiry of messages that already have one.").
+				Default(false).
+				Advanced(),
+			service.NewStringAnnotatedEnumField(ttlpFieldOnExpired, map[string]string{
+				"none":  "Expired messages are not checked.",
+				"drop":  "Expired messages are dropped.",
+				"error": "Expired messages are flagged as having failed, so that they can be handled with error handling patterns such as routing them to a dead letter queue.",
+				"flag":  "Expired messages have the metadata field `expired` set to `true`.",
+			}).
+				Description("The action to take on messages that have expired.").
+				Default("none"),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchProcessor("ttl", ttlProcSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchProcessor, error) {
+			return newTTLProcFromParsed(conf, mgr)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type ttlProc struct {
+	ttl       time.Duration
+	expiresAt *bloblang.Executor
+	overwrite bool
+	onExpired string
+
+	mStamped *service.MetricCounter
+	mExpired *service.MetricCounter
+
+	nowFn func() time.Time
+}
+
+func newTTLProcFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*ttlProc, error) {
+	p := &ttlProc{
+		mStamped: mgr.Metrics().NewCounter("ttl_stamped"),
+		mExpired: mgr.Metrics().NewCounter("ttl_expired", "action"),
+		nowFn:    time.Now,
+	}
+
+	var err error
+	if conf.Contains(ttlpFieldTTL) {
+		if p.ttl, err = conf.FieldDuration(ttlpFieldTTL); err != nil {
+			return nil, err
+		}
+		if p.ttl <= 0 {
+			return nil, fmt.Errorf("ttl must be greater than zero, got %v", p.ttl)
+		}
+	}
+	if conf.Contains(ttlpFieldExpiresAt) {
+		if p.expiresAt, err = conf.FieldBloblang(ttlpFieldExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+	if p.overwrite, err = conf.FieldBool(ttlpFieldOverwrite); err != nil {
+		return nil, err
+	}
+	if p.onExpired, err = conf.FieldString(ttlpFieldOnExpired); err != nil {
+		return nil, err
+	}
+	if p.ttl == 0 && p.expiresAt == nil && p.onExpired == "none" {
+		return nil, errors.New("at least one of ttl, expires_at or on_expired must be set")
+	}
+	return p, nil
+}
+
+// expiry returns the expiry of a message, stamping it with a new expiry where
+// appropriate. A zero time is returned when the message has no expiry.
+func (p *ttlProc) expiry(batch service.MessageBatch, i int, now time.Time) (time.Time, error) {
+	msg := batch[i]
+	if v, exists := msg.MetaGet(ttlMetaExpiresAt); exists && !p.overwrite {
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse expiry: %w", err)
+		}
+		return t, nil
+	}
+
+	var expiresAt time.Time
+	if p.expiresAt != nil {
+		v, err := batch.BloblangQueryValue(i, p.expiresAt)
+		if err != nil && !errors.Is(err, bloblang.ErrRootDeleted) {
+			return time.Time{}, fmt.Errorf("expires_at mapping failed: %w", err)
+		}
+		if v != nil {
+			if expiresAt, err = value.IGetTimestamp(v); err != nil {
+				return time.Time{}, fmt.Errorf("expires_at mapping failed: %w", err)
+			}
+		}
+	}
+	if expiresAt.IsZero() && p.ttl > 0 {
+		expiresAt = now.Add(p.ttl)
+	}
+	if !expiresAt.IsZero() {
+		msg.MetaSetMut(ttlMetaExpiresAt, expiresAt.UTC().Format(time.RFC3339Nano))
+		p.mStamped.Incr(1)
+	}
+	return expiresAt, nil
+}
+
+func (p *ttlProc) ProcessBatch(ctx context.Context, batch service.MessageBatch) ([]service.MessageBatch, error) {
+	now := p.nowFn()
+
+	newBatch := make(service.MessageBatch, 0, len(batch))
+	for i, msg := range batch {
+		expiresAt, err := p.expiry(batch, i, now)
+		if err != nil {
+			msg.SetError(err)
+			newBatch = append(newBatch, msg)
+			continue
+		}
+		if p.onExpired == "none" || expiresAt.IsZero() || now.Before(expiresAt) {
+			newBatch = append(newBatch, msg)
+			continue
+		}
+
+		p.mExpired.Incr(1, p.onExpired)
+		switch p.onExpired {
+		case "drop":
+			continue
+		case "error":
+			msg.SetError(fmt.Errorf("message expired at %v", expiresAt.UTC().Format(time.RFC3339)))
+		case "flag":
+			msg.MetaSetMut(ttlMetaExpired, true)
+		}
+		newBatch = append(newBatch, msg)
+	}
+	if len(newBatch) == 0 {
+		return nil, nil
+	}
+	return []service.MessageBatch{newBatch}, nil
+}
+
+func (p *ttlProc) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,126 @@
+package pure
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testTTLProc(t testing.TB, confStr string, now time.Time) *ttlProc {
+	t.Helper()
+
+	pConf, err := ttlProcSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	proc, err := newTTLProcFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	proc.nowFn = func() time.Time { return now }
+	return proc
+}
+
+func ttlProcess(t testing.TB, proc *ttlProc, batch service.MessageBatch) service.MessageBatch {
+	t.Helper()
+
+	res, err := proc.ProcessBatch(context.Background(), batch)
+	require.NoError(t, err)
+	if len(res) == 0 {
+		return nil
+	}
+	require.Len(t, res, 1)
+	return res[0]
+}
+
+func TestTTLProcStamp(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	proc := testTTLProc(t, `
+ttl: 1h
+expires_at: 'root = if metadata("produced") != null { metadata("produced") + 60 }'
+`, now)
+
+	existing := service.NewMessage(nil)
+	existing.MetaSetMut("expires_at", "2024-01-02T00:00:00Z")
+
+	fromMeta := service.NewMessage(nil)
+	fromMeta.MetaSetMut("produced", now.Unix())
+
+	res := ttlProcess(t, proc, service.MessageBatch{
+		service.NewMessage(nil), existing, fromMeta,
+	})
+	require.Len(t, res, 3)
+
+	for i, exp := range []string{
+		"2024-01-01T13:00:00Z",
+		"2024-01-02T00:00:00Z",
+		"2024-01-01T12:01:00Z",
+	} {
+		v, _ := res[i].MetaGet("expires_at")
+		assert.Equal(t, exp, v, i)
+		assert.NoError(t, res[i].GetError(), i)
+	}
+
+	// Overwriting replaces the existing expiry.
+	proc.overwrite = true
+	res = ttlProcess(t, proc, service.MessageBatch{existing})
+	v, _ := res[0].MetaGet("expires_at")
+	assert.Equal(t, "2024-01-01T13:00:00Z", v)
+}
+
+func TestTTLProcOnExpired(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	newBatch := func() service.MessageBatch {
+		expired := service.NewMessage([]byte("expired"))
+		expired.MetaSetMut("expires_at", "2024-01-01T11:00:00Z")
+
+		live := service.NewMessage([]byte("live"))
+		live.MetaSetMut("expires_at", "2024-01-01T13:00:00Z")
+
+		return service.MessageBatch{expired, live, service.NewMessage([]byte("none"))}
+	}
+
+	res := ttlProcess(t, testTTLProc(t, `on_expired: drop`, now), newBatch())
+	require.Len(t, res, 2)
+	mBytes, _ := res[0].AsBytes()
+	assert.Equal(t, "live", string(mBytes))
+	mBytes, _ = res[1].AsBytes()
+	assert.Equal(t, "none", string(mBytes))
+
+	res = ttlProcess(t, testTTLProc(t, `on_expired: error`, now), newBatch())
+	require.Len(t, res, 3)
+	assert.EqualError(t, res[0].GetError(), "message expired at 2024-01-01T11:00:00Z")
+	assert.NoError(t, res[1].GetError())
+	assert.NoError(t, res[2].GetError())
+
+	res = ttlProcess(t, testTTLProc(t, `on_expired: flag`, now), newBatch())
+	require.Len(t, res, 3)
+	v, _ := res[0].MetaGet("expired")
+	assert.Equal(t, "true", v)
+	_, exists := res[1].MetaGet("expired")
+	assert.False(t, exists)
+
+	// Expired messages are dropped entirely.
+	batch := newBatch()
+	assert.Nil(t, ttlProcess(t, testTTLProc(t, `on_expired: drop`, now), batch[:1]))
+
+	// Invalid expiries flag the message as failed.
+	bad := service.NewMessage(nil)
+	bad.MetaSetMut("expires_at", "tomorrow")
+	res = ttlProcess(t, testTTLProc(t, `on_expired: drop`, now), service.MessageBatch{bad})
+	require.Len(t, res, 1)
+	assert.Error(t, res[0].GetError())
+}
+
+func TestTTLProcConfigErrors(t *testing.T) {
+	for _, conf := range []string{`{}`, `ttl: 0s`} {
+		pConf, err := ttlProcSpec().ParseYAML(conf, nil)
+		require.NoError(t, err)
+
+		_, err = newTTLProcFromParsed(pConf, service.MockResources())
+		assert.Error(t, err, conf)
+	}
+}
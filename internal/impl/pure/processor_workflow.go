@@ -112,6 +112,12 @@ For example, if our meta object is stored at the path `+"`meta.workflow`"+` and
 
 However, if structured metadata is disabled by setting the field `+"`meta_path`"+` to empty then the workflow processor instead adds a general error flag to messages when any executed branch fails. In this case it's possible to handle failures using [standard error handling patterns][configuration.error-handling].
 
+## Durable Execution
+
+Structured metadata only allows branches to be skipped when the message carrying it is replayed, which isn't the case when a message is redelivered by the input after a crash or a nack. When a `+"`state.cache`"+` is configured the workflow processor stores the contents and metadata of each message, along with the branches that have succeeded, within the cache after each tier of branches is executed. When a message with the same `+"`state.key`"+` is later processed its contents and metadata are replaced with those stored and the branches that already succeeded are skipped, which prevents calls such as payment requests from being performed twice.
+
+If the stored state of a message cannot be obtained, for example because the cache is unavailable, then no branches are executed for the message and it is flagged as having failed.
+
 [dag_wiki]: https://en.wikipedia.org/wiki/Directed_acyclic_graph
 [processors.switch]: /docs/components/processors/switch
 [processors.http]: /docs/components/processors/http
@@ -245,6 +251,7 @@ processor_resources:
 			service.NewObjectMapField(wflowProcFieldBranches, branchSpecFields()...).
 				Description("An object of named [`branch` processors](/docs/components/processors/branch) that make up the workflow. The order and parallelism in which branches are executed can either be made explicit with the field `order`, or if omitted an attempt is made to automatically resolve an ordering based on the mappings of each branch.").
 				Default(map[string]any{}),
+			workflowStateField(),
 		)
 }
 
@@ -275,6 +282,7 @@ type Workflow struct {
 	children  *workflowBranchMap
 	allStages map[string]struct{}
 	metaPath  []string
+	state     *workflowState
 
 	// Metrics
 	mReceived      metrics.StatCounter
@@ -311,6 +319,10 @@ func NewWorkflow(conf *service.ParsedConfig, mgr bundle.NewManagement) (*Workflo
 		w.metaPath = gabs.DotPathToSlice(metaStr)
 	}
 
+	if w.state, err = workflowStateFromParsed(conf, mgr); err != nil {
+		return nil, err
+	}
+
 	if w.children, err = newWorkflowBranchMap(conf, mgr); err != nil {
 		return nil, err
 	}
@@ -471,6 +483,24 @@ func (w *Workflow) ProcessBatch(ctx context.Context, msg message.Batch) ([]messa
 	}
 	defer unlock()
 
+	// Restore the state of messages that were previously partially processed,
+	// which replaces their contents with those of the last run.
+	var stateKeys []string
+	var stateSucceeded []map[string]struct{}
+	if w.state != nil {
+		stateKeys = make([]string, msg.Len())
+		stateSucceeded = make([]map[string]struct{}, msg.Len())
+		_ = msg.Iter(func(i int, p *message.Part) error {
+			var err error
+			if stateKeys[i], stateSucceeded[i], err = w.state.restore(ctx, i, msg); err != nil {
+				w.mError.Incr(1)
+				w.log.Error("Failed to restore workflow state: %v\n", err)
+				p.ErrorSet(fmt.Errorf("failed to restore workflow state: %w", err))
+			}
+			return nil
+		})
+	}
+
 	skipOnMeta := make([]map[string]struct{}, msg.Len())
 	_ = msg.Iter(func(i int, p *message.Part) error {
 		// TODO: Do we want to evaluate bytes here? And metadata?
@@ -481,6 +511,22 @@ func (w *Workflow) ProcessBatch(ctx context.Context, msg message.Batch) ([]messa
 		}
 		return nil
 	})
+	if w.state != nil {
+		for i, succeeded := range stateSucceeded {
+			if succeeded == nil {
+				// Without a known state we cannot safely execute any branch.
+				for _, layer := range dag {
+					for _, k := range layer {
+						skipOnMeta[i][k] = struct{}{}
+					}
+				}
+				continue
+			}
+			for k := range succeeded {
+				skipOnMeta[i][k] = struct{}{}
+			}
+		}
+	}
 
 	propMsg, _ := tracing.WithChildSpans(w.tracer, "workflow", msg)
 
@@ -549,6 +595,10 @@ func (w *Workflow) ProcessBatch(ctx context.Context, msg message.Batch) ([]messa
 				records[e.index].Failed(id, e.err.Error())
 			}
 		}
+
+		if w.state != nil {
+			w.saveState(ctx, msg, layer, records, stateKeys, stateSucceeded)
+		}
 	}
 
 	// Finally, set the meta records of each document.
@@ -595,6 +645,30 @@ func (w *Workflow) ProcessBatch(ctx context.Context, msg message.Batch) ([]messa
 	return []message.Batch{msg}, nil
 }
 
+// saveState persists the state of each message that had branches succeed
+// within a tier.
+func (w *Workflow) saveState(ctx context.Context, msg message.Batch, layer []string, records []*resultTracker, keys []string, succeeded []map[string]struct{}) {
+	for i, r := range records {
+		if succeeded[i] == nil {
+			continue
+		}
+		changed := false
+		for _, id := range layer {
+			if _, exists := r.succeeded[id]; exists {
+				succeeded[i][id] = struct{}{}
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := w.state.save(ctx, keys[i], msg.Get(i), succeeded[i]); err != nil {
+			w.mError.Incr(1)
+			w.log.Error("Failed to store workflow state: %v\n", err)
+		}
+	}
+}
+
 // Close shuts down the processor and stops processing requests.
 func (w *Workflow) Close(ctx context.Context) error {
 	return w.children.Close(ctx)
@@ -0,0 +1,167 @@
+package pure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/bloblang/field"
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/component"
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	wflowProcFieldState      = "state"
+	wflowProcFieldStateCache = "cache"
+	wflowProcFieldStateKey   = "key"
+	wflowProcFieldStateTTL   = "ttl"
+)
+
+func workflowStateField() *service.ConfigField {
+	return service.NewObjectField(wflowProcFieldState,
+		service.NewStringField(wflowProcFieldStateCache).
+			Description("A [cache resource](/docs/components/caches/about) in which the execution state of each message is stored. When omitted execution state is not persisted.").
+			Optional(),
+		service.NewInterpolatedStringField(wflowProcFieldStateKey).
+			Description("A key that uniquely identifies each message, which must resolve to the same value when a message is redelivered. The key is resolved before any branches are executed.").
+			Examples(`${! @kafka_topic }-${! @kafka_partition }-${! @kafka_offset }`, `${! json("id") }`).
+			Default(`${! content().hash("xxhash64").encode("hex") }`),
+		service.NewStringField(wflowProcFieldStateTTL).
+			Description("An optional TTL to set for stored execution state, if supported by the cache.").
+			Example("24h").
+			Optional(),
+	).
+		Description("Allows the execution state of messages to be persisted within a cache after each tier of branches, so that when a message is redelivered after a crash or failure the branches that already succeeded are skipped. See [durable execution](#durable-execution) for more information.").
+		Version("4.28.0").
+		Advanced()
+}
+
+// workflowState persists the branches that have succeeded for each message,
+// along with the resulting message contents, so that a redelivered message can
+// resume from where it left off.
+type workflowState struct {
+	mgr   bundle.NewManagement
+	cache string
+	key   *field.Expression
+	ttl   *time.Duration
+}
+
+func workflowStateFromParsed(conf *service.ParsedConfig, mgr bundle.NewManagement) (*workflowState, error) {
+	conf = conf.Namespace(wflowProcFieldState)
+	if !conf.Contains(wflowProcFieldStateCache) {
+		return nil, nil
+	}
+
+	s := &workflowState{mgr: mgr}
+
+	var err error
+	if s.cache, err = conf.FieldString(wflowProcFieldStateCache); err != nil {
+		return nil, err
+	}
+	if !mgr.ProbeCache(s.cache) {
+		return nil, fmt.Errorf("cache resource '%v' was not found", s.cache)
+	}
+
+	keyStr, err := conf.FieldString(wflowProcFieldStateKey)
+	if err != nil {
+		return nil, err
+	}
+	if s.key, err = mgr.BloblEnvironment().NewField(keyStr); err != nil {
+		return nil, fmt.Errorf("failed to parse key expression: %v", err)
+	}
+
+	if conf.Contains(wflowProcFieldStateTTL) {
+		ttlStr, err := conf.FieldString(wflowProcFieldStateTTL)
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ttl: %w", err)
+		}
+		s.ttl = &ttl
+	}
+	return s, nil
+}
+
+type workflowStateRecord struct {
+	Succeeded []string       `json:"succeeded"`
+	Content   string         `json:"content"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+}
+
+// restore obtains the stored state of a message, and if it exists the contents
+// and metadata of the message are replaced with those stored. Returns the key
+// of the message and the set of branches that have already succeeded.
+func (s *workflowState) restore(ctx context.Context, index int, msg message.Batch) (key string, succeeded map[string]struct{}, err error) {
+	succeeded = map[string]struct{}{}
+	if key, err = s.key.String(index, msg); err != nil {
+		return "", nil, fmt.Errorf("key interpolation error: %w", err)
+	}
+
+	var stateBytes []byte
+	var cErr error
+	if err = s.mgr.AccessCache(ctx, s.cache, func(c cache.V1) {
+		stateBytes, cErr = c.Get(ctx, key)
+	}); err != nil {
+		return "", nil, err
+	}
+	if errors.Is(cErr, component.ErrKeyNotFound) {
+		return key, succeeded, nil
+	}
+	if cErr != nil {
+		return "", nil, cErr
+	}
+
+	var record workflowStateRecord
+	if err = json.Unmarshal(stateBytes, &record); err != nil {
+		return "", nil, fmt.Errorf("failed to parse stored state: %w", err)
+	}
+
+	part := msg.Get(index)
+	part.SetBytes([]byte(record.Content))
+	for k, v := range record.Metadata {
+		part.MetaSetMut(k, v)
+	}
+	for _, id := range record.Succeeded {
+		succeeded[id] = struct{}{}
+	}
+	return key, succeeded, nil
+}
+
+// save stores the contents and metadata of a message along with the set of
+// branches that have succeeded.
+func (s *workflowState) save(ctx context.Context, key string, part *message.Part, succeeded map[string]struct{}) error {
+	record := workflowStateRecord{
+		Succeeded: make([]string, 0, len(succeeded)),
+		Content:   string(part.AsBytes()),
+		Metadata:  map[string]any{},
+	}
+	for id := range succeeded {
+		record.Succeeded = append(record.Succeeded, id)
+	}
+	sort.Strings(record.Succeeded)
+	_ = part.MetaIterMut(func(k string, v any) error {
+		record.Metadata[k] = v
+		return nil
+	})
+
+	stateBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	var cErr error
+	if err := s.mgr.AccessCache(ctx, s.cache, func(c cache.V1) {
+		cErr = c.Set(ctx, key, stateBytes, s.ttl)
+	}); err != nil {
+		return err
+	}
+	return cErr
+}
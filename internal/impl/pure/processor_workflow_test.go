@@ -1050,3 +1050,61 @@ workflow:
 		},
 	}, tracer.ProcessorEvents())
 }
+
+func TestWorkflowDurableState(t *testing.T) {
+	mConf, err := testutil.ManagerFromYAML(`
+cache_resources:
+  - label: wstate
+    memory: {}
+`)
+	require.NoError(t, err)
+
+	mgr, err := manager.New(mConf)
+	require.NoError(t, err)
+
+	conf, err := testutil.ProcessorFromYAML(`
+workflow:
+  order: [ [ pay ], [ ship ] ]
+  state:
+    cache: wstate
+    key: ${! json("id") }
+  branches:
+    pay:
+      request_map: 'root = this.id'
+      processors:
+        - mapping: 'root = count("workflow_durable_pay")'
+      result_map: 'root.paid = this'
+    ship:
+      request_map: |
+        root = if count("workflow_durable_ship") < 2 { throw("shipping unavailable") } else { this.paid }
+      processors:
+        - noop: {}
+      result_map: 'root.shipped = this'
+`)
+	require.NoError(t, err)
+
+	p, err := mgr.NewProcessor(conf)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, p.Close(context.Background()))
+	})
+
+	// The first attempt pays but fails to ship.
+	msgs, res := p.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte(`{"id":"a"}`)}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"id":"a","meta":{"workflow":{"failed":{"ship":"request mapping failed: failed assignment (line 1): shipping unavailable"},"succeeded":["pay"]}},"paid":1}`, string(msgs[0].Get(0).AsBytes()))
+
+	// The redelivered message resumes with the result of the payment without
+	// paying again.
+	msgs, res = p.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte(`{"id":"a"}`)}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"id":"a","meta":{"workflow":{"skipped":["pay"],"succeeded":["ship"]}},"paid":1,"shipped":1}`, string(msgs[0].Get(0).AsBytes()))
+
+	// A different message is processed from scratch.
+	msgs, res = p.ProcessBatch(context.Background(), message.QuickBatch([][]byte{[]byte(`{"id":"b"}`)}))
+	require.NoError(t, res)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, `{"id":"b","meta":{"workflow":{"succeeded":["pay","ship"]}},"paid":2,"shipped":2}`, string(msgs[0].Get(0).AsBytes()))
+}
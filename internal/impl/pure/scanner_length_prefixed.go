@@ -0,0 +1,151 @@
+package pure
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	slpFieldPrefixSize    = "prefix_size"
+	slpFieldByteOrder     = "byte_order"
+	slpFieldIncludePrefix = "include_prefix"
+	slpFieldMaxLength     = "max_length"
+)
+
+func lengthPrefixedScannerSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Version("4.28.0").
+		Summary("Split an input stream into frames that are each preceded by their length as an unsigned integer.").
+		Fields(
+			service.NewIntField(slpFieldPrefixSize).
+				Description("The size of the length prefix in bytes, which must be 1, 2 or 4.").
+				Default(4),
+			service.NewStringEnumField(slpFieldByteOrder, "big_endian", "little_endian").
+				Description("The byte order of the length prefix.").
+				Default("big_endian"),
+			service.NewBoolField(slpFieldIncludePrefix).
+				Description("Whether to include the length prefix in the contents of each message.").
+				Default(false),
+			service.NewIntField(slpFieldMaxLength).
+				Description("The maximum length of a frame, where frames that are longer result in an error.").
+				Default(bufio.MaxScanTokenSize).
+				Advanced(),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchScannerCreator("length_prefixed", lengthPrefixedScannerSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return lengthPrefixedScannerFromParsed(conf)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+func lengthPrefixedScannerFromParsed(conf *service.ParsedConfig) (l *lengthPrefixedScannerCreator, err error) {
+	l = &lengthPrefixedScannerCreator{}
+	if l.prefixSize, err = conf.FieldInt(slpFieldPrefixSize); err != nil {
+		return
+	}
+	switch l.prefixSize {
+	case 1, 2, 4:
+	default:
+		return nil, fmt.Errorf("prefix size must be 1, 2 or 4, got %v", l.prefixSize)
+	}
+
+	var byteOrder string
+	if byteOrder, err = conf.FieldString(slpFieldByteOrder); err != nil {
+		return
+	}
+	l.byteOrder = binary.BigEndian
+	if byteOrder == "little_endian" {
+		l.byteOrder = binary.LittleEndian
+	}
+	if l.includePrefix, err = conf.FieldBool(slpFieldIncludePrefix); err != nil {
+		return
+	}
+	if l.maxLength, err = conf.FieldInt(slpFieldMaxLength); err != nil {
+		return
+	}
+	return
+}
+
+type lengthPrefixedScannerCreator struct {
+	prefixSize    int
+	byteOrder     binary.ByteOrder
+	includePrefix bool
+	maxLength     int
+}
+
+func (c *lengthPrefixedScannerCreator) Create(rdr io.ReadCloser, aFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	return service.AutoAggregateBatchScannerAcks(&lengthPrefixedScanner{
+		conf: c,
+		r:    rdr,
+	}, aFn), nil
+}
+
+func (c *lengthPrefixedScannerCreator) Close(context.Context) error {
+	return nil
+}
+
+type lengthPrefixedScanner struct {
+	conf *lengthPrefixedScannerCreator
+	r    io.ReadCloser
+}
+
+func (l *lengthPrefixedScanner) NextBatch(ctx context.Context) (service.MessageBatch, error) {
+	if l.r == nil {
+		return nil, io.EOF
+	}
+
+	prefix := make([]byte, l.conf.prefixSize)
+	if _, err := io.ReadFull(l.r, prefix); err != nil {
+		_ = l.r.Close()
+		l.r = nil
+		return nil, err
+	}
+
+	var length int
+	switch l.conf.prefixSize {
+	case 1:
+		length = int(prefix[0])
+	case 2:
+		length = int(l.conf.byteOrder.Uint16(prefix))
+	default:
+		length = int(l.conf.byteOrder.Uint32(prefix))
+	}
+	if length > l.conf.maxLength {
+		return nil, fmt.Errorf("frame length %v exceeds the maximum of %v", length, l.conf.maxLength)
+	}
+
+	offset := 0
+	if l.conf.includePrefix {
+		offset = l.conf.prefixSize
+	}
+	frame := make([]byte, offset+length)
+	copy(frame, prefix[:offset])
+	if _, err := io.ReadFull(l.r, frame[offset:]); err != nil {
+		_ = l.r.Close()
+		l.r = nil
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return service.MessageBatch{service.NewMessage(frame)}, nil
+}
+
+func (l *lengthPrefixedScanner) Close(ctx context.Context) error {
+	if l.r == nil {
+		return nil
+	}
+	return l.r.Close()
+}
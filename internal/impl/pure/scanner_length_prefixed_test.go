@@ -0,0 +1,103 @@
+package pure_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/scanner/testutil"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func lengthPrefixedScanner(t *testing.T, confStr string) *service.OwnedScannerCreator {
+	t.Helper()
+
+	confSpec := service.NewConfigSpec().Field(service.NewScannerField("test"))
+	pConf, err := confSpec.ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	rdr, err := pConf.FieldScanner("test")
+	require.NoError(t, err)
+	return rdr
+}
+
+func TestLengthPrefixedScannerSuite(t *testing.T) {
+	rdr := lengthPrefixedScanner(t, `
+test:
+  length_prefixed: {}
+`)
+	testutil.ScannerTestSuite(t, rdr, nil, []byte("\x00\x00\x00\x05hello\x00\x00\x00\x00\x00\x00\x00\x05world"), "hello", "", "world")
+
+	rdr = lengthPrefixedScanner(t, `
+test:
+  length_prefixed:
+    prefix_size: 2
+    byte_order: little_endian
+`)
+	testutil.ScannerTestSuite(t, rdr, nil, []byte("\x05\x00hello\x03\x00foo"), "hello", "foo")
+
+	rdr = lengthPrefixedScanner(t, `
+test:
+  length_prefixed:
+    prefix_size: 1
+    include_prefix: true
+`)
+	testutil.ScannerTestSuite(t, rdr, nil, []byte("\x05hello\x03foo"), "\x05hello", "\x03foo")
+}
+
+func TestLengthPrefixedScannerErrors(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		conf string
+		data string
+		err  string
+	}{
+		{
+			name: "truncated frame",
+			conf: `length_prefixed: { prefix_size: 1 }`,
+			data: "\x05hel",
+			err:  "unexpected EOF",
+		},
+		{
+			name: "truncated prefix",
+			conf: `length_prefixed: { prefix_size: 4 }`,
+			data: "\x00\x00",
+			err:  "unexpected EOF",
+		},
+		{
+			name: "frame too long",
+			conf: `length_prefixed: { prefix_size: 2, max_length: 10 }`,
+			data: "\x00\x0bhello world",
+			err:  "frame length 11 exceeds the maximum of 10",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			rdr := lengthPrefixedScanner(t, "test:\n  "+test.conf)
+
+			strm, err := rdr.Create(io.NopCloser(bytes.NewReader([]byte(test.data))), func(ctx context.Context, err error) error {
+				return nil
+			}, service.NewScannerSourceDetails())
+			require.NoError(t, err)
+
+			_, _, err = strm.NextBatch(context.Background())
+			assert.EqualError(t, err, test.err)
+			require.NoError(t, strm.Close(context.Background()))
+		})
+	}
+
+	confSpec := service.NewConfigSpec().Field(service.NewScannerField("test"))
+	pConf, err := confSpec.ParseYAML(`
+test:
+  length_prefixed:
+    prefix_size: 3
+`, nil)
+	require.NoError(t, err)
+
+	_, err = pConf.FieldScanner("test")
+	require.ErrorContains(t, err, "prefix size must be 1, 2 or 4, got 3")
+}
@@ -0,0 +1,563 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/checkpoint"
+	"github.com/Jeffail/shutdown"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	metaStream       = "rabbitmq_stream"
+	metaStreamOffset = "rabbitmq_stream_offset"
+)
+
+func streamInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Consumes messages from a RabbitMQ stream or super stream.").
+		Description(`
+[RabbitMQ streams](https://www.rabbitmq.com/docs/streams) are append-only logs that are read non-destructively, and are consumed by this input using the stream protocol rather than AMQP 0.9.1, which means the stream plugin must be enabled on the server.
+
+### Offset Tracking
+
+The offset of the latest message processed from each stream is stored on the server against the `+"`consumer_name`"+` every `+"`commit_period`"+`, where an offset is only stored once all messages prior to it have been acknowledged. When the input connects it resumes each stream from the message after its stored offset, and streams without a stored offset are consumed from the position set by `+"`start_from`"+`.
+
+### Super Streams
+
+When a `+"`super_stream`"+` is consumed each of its partition streams is consumed and has its offset tracked separately, and the order of messages is only preserved within a partition. By enabling `+"`single_active_consumer`"+` the partitions are shared out between all consumers with the same `+"`consumer_name`"+`, such that each partition is consumed by only one consumer at a time, allowing consumption to be scaled horizontally.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- rabbitmq_stream
+- rabbitmq_stream_offset
+- All application properties
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(streamConnectionFields()...).
+		Fields(streamNameFields("consume")...).
+		Fields(
+			service.NewStringField(rsiFieldConsumerName).
+				Description("The name to store offsets against, which must be shared by all consumers of a `single_active_consumer` group."),
+			service.NewStringAnnotatedEnumField(rsiFieldStartFrom, map[string]string{
+				"first": "Consume from the first message available in the stream.",
+				"last":  "Consume from the last chunk of messages written to the stream.",
+				"next":  "Consume only messages written to the stream after the input connects.",
+			}).
+				Description("Where to start consuming streams that do not yet have an offset stored for the consumer name.").
+				Default("first"),
+			service.NewBoolField(rsiFieldSingleActiveConsumer).
+				Description("Whether to enable single active consumer, where only one consumer with the same consumer name consumes each stream at a time while other consumers wait to take over.").
+				Default(false),
+			service.NewDurationField(rsiFieldCommitPeriod).
+				Description("The period of time between each attempt to store the offsets of streams.").
+				Default("5s").
+				Advanced(),
+			service.NewIntField(rsiFieldCheckpointLimit).
+				Description("The maximum number of messages of each stream that can be processed at a given time. Increasing this limit enables parallel processing and batching at the output level.").
+				Default(1024).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		)
+}
+
+func init() {
+	err := service.RegisterInput(
+		"rabbitmq_stream", streamInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			r, err := newStreamReaderFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, r)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type streamMessage struct {
+	partition *streamPartition
+	offset    int64
+	msg       *amqp.Message
+	release   func() *int64
+}
+
+// streamPartition tracks the offsets of messages consumed from a stream, which
+// is a partition when consuming a super stream.
+type streamPartition struct {
+	name         string
+	checkpointer *checkpoint.Capped[int64]
+
+	mut       sync.Mutex
+	consumer  *stream.Consumer
+	toCommit  int64
+	committed int64
+}
+
+// commit stores the highest offset that can be committed, if it has changed
+// since the last commit.
+func (p *streamPartition) commit() error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if p.consumer == nil || p.toCommit <= p.committed {
+		return nil
+	}
+	if err := p.consumer.StoreCustomOffset(p.toCommit); err != nil {
+		return err
+	}
+	p.committed = p.toCommit
+	return nil
+}
+
+// streamConn holds the consumers of a single connection attempt.
+type streamConn struct {
+	env           *stream.Environment
+	consumers     []*stream.Consumer
+	superConsumer *stream.SuperStreamConsumer
+
+	msgChan chan streamMessage
+
+	partMut    sync.Mutex
+	partitions map[string]*streamPartition
+
+	// Closed when the consumers are being closed in order to unblock message
+	// handlers.
+	closeChan chan struct{}
+	closeOnce sync.Once
+
+	// Closed when a consumer has been closed by the server.
+	lostChan chan struct{}
+	lostOnce sync.Once
+}
+
+type streamReader struct {
+	connDetails          streamConnectionDetails
+	streamName           string
+	superStream          string
+	consumerName         string
+	startFrom            stream.OffsetSpecification
+	singleActiveConsumer bool
+	commitPeriod         time.Duration
+	checkpointLimit      int
+
+	log *service.Logger
+
+	connMut sync.Mutex
+	conn    *streamConn
+
+	shutSig *shutdown.Signaller
+}
+
+func newStreamReaderFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*streamReader, error) {
+	r := &streamReader{
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var err error
+	if r.connDetails, err = streamConnectionDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if r.streamName, r.superStream, err = streamNamesFromParsed(conf); err != nil {
+		return nil, err
+	}
+
+	if r.consumerName, err = conf.FieldString(rsiFieldConsumerName); err != nil {
+		return nil, err
+	}
+	if r.consumerName == "" {
+		return nil, errors.New("consumer_name must not be empty")
+	}
+
+	startFrom, err := conf.FieldString(rsiFieldStartFrom)
+	if err != nil {
+		return nil, err
+	}
+	switch startFrom {
+	case "first":
+		r.startFrom = stream.OffsetSpecification{}.First()
+	case "last":
+		r.startFrom = stream.OffsetSpecification{}.Last()
+	case "next":
+		r.startFrom = stream.OffsetSpecification{}.Next()
+	default:
+		return nil, fmt.Errorf("unrecognised start_from value: %v", startFrom)
+	}
+
+	if r.singleActiveConsumer, err = conf.FieldBool(rsiFieldSingleActiveConsumer); err != nil {
+		return nil, err
+	}
+	if r.commitPeriod, err = conf.FieldDuration(rsiFieldCommitPeriod); err != nil {
+		return nil, err
+	}
+	if r.commitPeriod <= 0 {
+		return nil, errors.New("commit_period must be greater than zero")
+	}
+	if r.checkpointLimit, err = conf.FieldInt(rsiFieldCheckpointLimit); err != nil {
+		return nil, err
+	}
+	if r.checkpointLimit < 1 {
+		return nil, errors.New("checkpoint_limit must be greater than zero")
+	}
+
+	go r.commitLoop()
+	return r, nil
+}
+
+// offsetFor returns the offset to consume a stream from, which follows the
+// offset stored for the consumer name when there is one.
+func (r *streamReader) offsetFor(env *stream.Environment, streamName string) (stream.OffsetSpecification, error) {
+	offset, err := env.QueryOffset(r.consumerName, streamName)
+	if err != nil {
+		if errors.Is(err, stream.OffsetNotFoundError) {
+			return r.startFrom, nil
+		}
+		return stream.OffsetSpecification{}, err
+	}
+	return stream.OffsetSpecification{}.Offset(offset + 1), nil
+}
+
+func (r *streamReader) Connect(ctx context.Context) (err error) {
+	r.connMut.Lock()
+	defer r.connMut.Unlock()
+
+	if r.conn != nil {
+		return nil
+	}
+
+	env, err := r.connDetails.newEnvironment()
+	if err != nil {
+		return err
+	}
+
+	conn := &streamConn{
+		env:        env,
+		msgChan:    make(chan streamMessage),
+		partitions: map[string]*streamPartition{},
+		closeChan:  make(chan struct{}),
+		lostChan:   make(chan struct{}),
+	}
+	defer func() {
+		if err != nil {
+			r.closeConn(conn)
+		}
+	}()
+
+	handler := r.messageHandler(conn)
+
+	var sac *stream.SingleActiveConsumer
+	if r.singleActiveConsumer {
+		sac = stream.NewSingleActiveConsumer(func(streamName string, isActive bool) stream.OffsetSpecification {
+			offset, err := r.offsetFor(env, streamName)
+			if err != nil {
+				r.log.Errorf("Failed to query offset of stream %v, falling back to start_from: %v", streamName, err)
+				return r.startFrom
+			}
+			return offset
+		})
+	}
+
+	var streams []string
+	switch {
+	case r.streamName != "":
+		streams = []string{r.streamName}
+	case r.singleActiveConsumer:
+		// Partitions are assigned to the active consumers of a super stream
+		// by the server, which requires a super stream consumer.
+		opts := stream.NewSuperStreamConsumerOptions().
+			SetConsumerName(r.consumerName).
+			SetClientProvidedName("benthos").
+			SetSingleActiveConsumer(sac).
+			SetOffset(r.startFrom)
+		if conn.superConsumer, err = env.NewSuperStreamConsumer(r.superStream, handler, opts); err != nil {
+			return fmt.Errorf("failed to consume super stream %v: %w", r.superStream, err)
+		}
+		go func(closes <-chan stream.CPartitionClose) {
+			for c := range closes {
+				r.onConsumerClosed(conn, c.Partition, c.Event)
+			}
+		}(conn.superConsumer.NotifyPartitionClose(1))
+	default:
+		if streams, err = env.QueryPartitions(r.superStream); err != nil {
+			return fmt.Errorf("failed to query partitions of super stream %v: %w", r.superStream, err)
+		}
+	}
+
+	for _, s := range streams {
+		opts := stream.NewConsumerOptions().
+			SetConsumerName(r.consumerName).
+			SetClientProvidedName("benthos").
+			SetManualCommit()
+		if sac != nil {
+			opts = opts.SetSingleActiveConsumer(sac)
+		} else {
+			offset, err := r.offsetFor(env, s)
+			if err != nil {
+				return fmt.Errorf("failed to query offset of stream %v: %w", s, err)
+			}
+			opts = opts.SetOffset(offset)
+		}
+
+		consumer, err := env.NewConsumer(s, handler, opts)
+		if err != nil {
+			return fmt.Errorf("failed to consume stream %v: %w", s, err)
+		}
+		conn.consumers = append(conn.consumers, consumer)
+
+		go func(name string, closes stream.ChannelClose) {
+			for e := range closes {
+				r.onConsumerClosed(conn, name, e)
+			}
+		}(s, consumer.NotifyClose())
+	}
+
+	r.conn = conn
+	return nil
+}
+
+func (r *streamReader) onConsumerClosed(conn *streamConn, name string, e stream.Event) {
+	select {
+	case <-conn.closeChan:
+		// Consumers are expected to close when we close the connection.
+		return
+	default:
+	}
+	r.log.Warnf("Consumer of stream %v was closed: %v", name, e.Reason)
+	conn.lostOnce.Do(func() {
+		close(conn.lostChan)
+	})
+}
+
+// messageHandler returns a handler that tracks the offset of each message and
+// passes it to Read, blocking while the checkpoint limit of its stream is
+// reached in order to apply back pressure to the consumer.
+func (r *streamReader) messageHandler(conn *streamConn) stream.MessagesHandler {
+	ctx := conn.closeCtx()
+
+	return func(consumerContext stream.ConsumerContext, msg *amqp.Message) {
+		consumer := consumerContext.Consumer
+		offset := consumer.GetOffset()
+
+		part := conn.partition(consumer, r.checkpointLimit)
+		release, err := part.checkpointer.Track(ctx, offset, 1)
+		if err != nil {
+			return
+		}
+
+		select {
+		case conn.msgChan <- streamMessage{
+			partition: part,
+			offset:    offset,
+			msg:       msg,
+			release:   release,
+		}:
+		case <-conn.closeChan:
+		}
+	}
+}
+
+// closeCtx returns a context that is cancelled once the connection is closed.
+func (c *streamConn) closeCtx() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-c.closeChan
+		cancel()
+	}()
+	return ctx
+}
+
+// partition returns the offset tracking of the stream consumed by a consumer,
+// which is created on its first message.
+func (c *streamConn) partition(consumer *stream.Consumer, checkpointLimit int) *streamPartition {
+	name := consumer.GetStreamName()
+
+	c.partMut.Lock()
+	defer c.partMut.Unlock()
+
+	p, exists := c.partitions[name]
+	if !exists {
+		p = &streamPartition{
+			name:         name,
+			checkpointer: checkpoint.NewCapped[int64](int64(checkpointLimit)),
+			committed:    -1,
+			toCommit:     -1,
+		}
+		c.partitions[name] = p
+	}
+
+	// Consumers of a super stream can be replaced when partitions are
+	// reassigned, in which case offsets are stored with the latest one.
+	p.mut.Lock()
+	p.consumer = consumer
+	p.mut.Unlock()
+	return p
+}
+
+func (c *streamConn) commit(log *service.Logger) {
+	c.partMut.Lock()
+	parts := make([]*streamPartition, 0, len(c.partitions))
+	for _, p := range c.partitions {
+		parts = append(parts, p)
+	}
+	c.partMut.Unlock()
+
+	for _, p := range parts {
+		if err := p.commit(); err != nil {
+			log.Errorf("Failed to store offset of stream %v: %v", p.name, err)
+		}
+	}
+}
+
+func (r *streamReader) commitLoop() {
+	ticker := time.NewTicker(r.commitPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-r.shutSig.SoftStopChan():
+			return
+		}
+
+		r.connMut.Lock()
+		conn := r.conn
+		r.connMut.Unlock()
+
+		if conn != nil {
+			conn.commit(r.log)
+		}
+	}
+}
+
+func (r *streamReader) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	r.connMut.Lock()
+	conn := r.conn
+	r.connMut.Unlock()
+
+	if conn == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var sm streamMessage
+	select {
+	case sm = <-conn.msgChan:
+	case <-conn.lostChan:
+		r.disconnect()
+		return nil, nil, service.ErrNotConnected
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	msg := newMessageFromStream(sm.msg)
+	msg.MetaSetMut(metaStream, sm.partition.name)
+	msg.MetaSetMut(metaStreamOffset, sm.offset)
+
+	return msg, func(ctx context.Context, res error) error {
+		if res != nil {
+			// Without auto_replay_nacks rejected messages are skipped.
+			r.log.Debugf("Message at offset %v of stream %v was rejected: %v", sm.offset, sm.partition.name, res)
+		}
+		if highest := sm.release(); highest != nil {
+			sm.partition.mut.Lock()
+			if *highest > sm.partition.toCommit {
+				sm.partition.toCommit = *highest
+			}
+			sm.partition.mut.Unlock()
+		}
+		return nil
+	}, nil
+}
+
+func newMessageFromStream(m *amqp.Message) *service.Message {
+	var body []byte
+	switch {
+	case len(m.Data) == 1:
+		body = m.Data[0]
+	case len(m.Data) > 1:
+		body = bytes.Join(m.Data, nil)
+	default:
+		switch v := m.Value.(type) {
+		case string:
+			body = []byte(v)
+		case []byte:
+			body = v
+		case nil:
+		default:
+			body = []byte(fmt.Sprintf("%v", v))
+		}
+	}
+
+	msg := service.NewMessage(body)
+	for k, v := range m.ApplicationProperties {
+		msg.MetaSetMut(k, v)
+	}
+	return msg
+}
+
+func (r *streamReader) closeConn(conn *streamConn) {
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+	})
+
+	// Store the offsets of acknowledged messages before the consumers are
+	// closed.
+	conn.commit(r.log)
+
+	if conn.superConsumer != nil {
+		if err := conn.superConsumer.Close(); err != nil {
+			r.log.Debugf("Failed to close super stream consumer: %v", err)
+		}
+	}
+	for _, c := range conn.consumers {
+		if err := c.Close(); err != nil && !errors.Is(err, stream.AlreadyClosed) {
+			r.log.Debugf("Failed to close consumer of stream %v: %v", c.GetStreamName(), err)
+		}
+	}
+	if err := conn.env.Close(); err != nil {
+		r.log.Debugf("Failed to close environment: %v", err)
+	}
+}
+
+func (r *streamReader) disconnect() {
+	r.connMut.Lock()
+	conn := r.conn
+	r.conn = nil
+	r.connMut.Unlock()
+
+	if conn != nil {
+		r.closeConn(conn)
+	}
+}
+
+func (r *streamReader) Close(ctx context.Context) error {
+	r.shutSig.TriggerSoftStop()
+	go func() {
+		r.disconnect()
+		r.shutSig.TriggerHasStopped()
+	}()
+	select {
+	case <-r.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestStreamInputConfig(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		err    string
+	}{
+		"no stream": {
+			config: `
+urls: [ rabbitmq-stream://localhost:5552 ]
+consumer_name: foo
+`,
+			err: "exactly one of stream or super_stream must be specified",
+		},
+		"both streams": {
+			config: `
+urls: [ rabbitmq-stream://localhost:5552 ]
+stream: foo
+super_stream: bar
+consumer_name: foo
+`,
+			err: "exactly one of stream or super_stream must be specified",
+		},
+		"empty consumer name": {
+			config: `
+urls: [ rabbitmq-stream://localhost:5552 ]
+stream: foo
+consumer_name: ""
+`,
+			err: "consumer_name must not be empty",
+		},
+		"no urls": {
+			config: `
+urls: [ "" ]
+stream: foo
+consumer_name: foo
+`,
+			err: "must specify at least one URL",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := streamInputSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newStreamReaderFromParsed(conf, service.MockResources())
+			require.EqualError(t, err, test.err)
+		})
+	}
+
+	conf, err := streamInputSpec().ParseYAML(`
+urls: [ "rabbitmq-stream://localhost:5552,rabbitmq-stream://localhost:5553" ]
+super_stream: foo
+consumer_name: bar
+start_from: next
+`, nil)
+	require.NoError(t, err)
+
+	r, err := newStreamReaderFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"rabbitmq-stream://localhost:5552", "rabbitmq-stream://localhost:5553"}, r.connDetails.urls)
+	assert.Equal(t, "foo", r.superStream)
+	assert.Equal(t, "next", r.startFrom.String())
+	require.NoError(t, r.Close(context.Background()))
+}
+
+func TestStreamMessageConversion(t *testing.T) {
+	m := amqp.NewMessage([]byte("hello"))
+	m.ApplicationProperties = map[string]any{
+		"foo": "bar",
+		"baz": int64(10),
+	}
+	data, err := m.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded amqp.Message
+	require.NoError(t, decoded.UnmarshalBinary(data))
+
+	msg := newMessageFromStream(&decoded)
+
+	b, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+
+	v, _ := msg.MetaGetMut("foo")
+	assert.Equal(t, "bar", v)
+	v, _ = msg.MetaGetMut("baz")
+	assert.Equal(t, int64(10), v)
+
+	msg = newMessageFromStream(&amqp.Message{Value: "from value"})
+	b, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "from value", string(b))
+
+	msg = newMessageFromStream(&amqp.Message{Data: [][]byte{[]byte("foo"), []byte("bar")}})
+	b, err = msg.AsBytes()
+	require.NoError(t, err)
+	assert.Equal(t, "foobar", string(b))
+}
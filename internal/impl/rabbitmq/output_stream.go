@@ -0,0 +1,515 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/amqp"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/message"
+	"github.com/rabbitmq/rabbitmq-stream-go-client/pkg/stream"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func streamOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Writes messages to a RabbitMQ stream or super stream.").
+		Description(`
+[RabbitMQ streams](https://www.rabbitmq.com/docs/streams) are written to by this output using the stream protocol rather than AMQP 0.9.1, which means the stream plugin must be enabled on the server. Messages are acknowledged once they have been confirmed by the server.
+
+### Super Streams
+
+When writing to a `+"`super_stream`"+` each message is written to the partitions selected with its `+"`routing_key`"+`. The `+"`hash`"+` routing strategy selects a partition by hashing the key, and is compatible with the other RabbitMQ stream clients, whereas the `+"`key`"+` strategy selects the partitions bound to the super stream exchange with a binding key matching the routing key, and messages with keys that do not match any binding are rejected.
+
+### Sub-Entry Batching
+
+Setting `+"`sub_entry_size`"+` greater than one enables sub-entry batching, where messages of a batch are stored together as a single entry of the stream, which increases throughput and reduces disk usage at the cost of latency. Sub-entries can also be compressed with `+"`compression`"+`. Since sub-entries are created from the messages of each batch it is important to configure [batching](/docs/configuration/batching) in order to benefit from them.`).
+		Fields(streamConnectionFields()...).
+		Fields(streamNameFields("write to")...).
+		Fields(
+			service.NewInterpolatedStringField(rsoFieldRoutingKey).
+				Description("The routing key of each message, used to select the partitions of a super stream the message is written to. Required when writing to a super stream.").
+				Example(`${! meta("kafka_key") }`).
+				Example(`${! json("customer.id") }`).
+				Optional(),
+			service.NewStringAnnotatedEnumField(rsoFieldRoutingStrategy, map[string]string{
+				"hash": "Write each message to a partition selected by hashing its routing key.",
+				"key":  "Write each message to the partitions with a binding key that matches its routing key.",
+			}).
+				Description("The strategy used to select the partitions of a super stream to write messages to.").
+				Default("hash"),
+			service.NewIntField(rsoFieldSubEntrySize).
+				Description("The maximum number of messages of a batch to store as a single entry of the stream, where a value of one disables sub-entry batching.").
+				Default(1).
+				Advanced(),
+			service.NewStringEnumField(rsoFieldCompression, "none", "gzip", "snappy", "lz4", "zstd").
+				Description("The compression algorithm used for sub-entries, which requires sub-entry batching to be enabled.").
+				Default("none").
+				Advanced(),
+			service.NewMetadataFilterField(rsoFieldMetadata).
+				Description("Determine which (if any) metadata values should be added to messages as application properties.").
+				Optional(),
+			service.NewOutputMaxInFlightField(),
+			service.NewBatchPolicyField(rsoFieldBatching),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput(
+		"rabbitmq_stream", streamOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			if batchPolicy, err = conf.FieldBatchPolicy(rsoFieldBatching); err != nil {
+				return
+			}
+			out, err = newStreamWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+// streamBatchResult collects the confirmations of the messages of a batch.
+type streamBatchResult struct {
+	mut       sync.Mutex
+	remaining int
+	errs      map[int]error
+	done      chan struct{}
+}
+
+func newStreamBatchResult(n int) *streamBatchResult {
+	r := &streamBatchResult{
+		remaining: n,
+		errs:      map[int]error{},
+		done:      make(chan struct{}),
+	}
+	if n == 0 {
+		close(r.done)
+	}
+	return r
+}
+
+func (r *streamBatchResult) resolve(index int, err error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if err != nil {
+		if _, exists := r.errs[index]; !exists {
+			r.errs[index] = err
+		}
+	}
+	if r.remaining--; r.remaining == 0 {
+		close(r.done)
+	}
+}
+
+type streamPending struct {
+	result *streamBatchResult
+	index  int
+}
+
+// streamProducers holds the producers of a single connection attempt.
+type streamProducers struct {
+	env        *stream.Environment
+	partitions []string
+	producers  map[string]*stream.Producer
+
+	routeMut   sync.Mutex
+	routeCache map[string][]string
+
+	closeChan chan struct{}
+	closeOnce sync.Once
+
+	// Closed when a producer has been closed by the server.
+	lostChan chan struct{}
+	lostOnce sync.Once
+}
+
+type streamWriter struct {
+	connDetails     streamConnectionDetails
+	streamName      string
+	superStream     string
+	routingKey      *service.InterpolatedString
+	routingStrategy string
+	subEntrySize    int
+	compression     stream.Compression
+	metaFilter      *service.MetadataFilter
+
+	log *service.Logger
+
+	connMut sync.Mutex
+	conn    *streamProducers
+
+	pendingMut sync.Mutex
+	pending    map[message.StreamMessage]streamPending
+}
+
+func newStreamWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*streamWriter, error) {
+	w := &streamWriter{
+		log:     mgr.Logger(),
+		pending: map[message.StreamMessage]streamPending{},
+	}
+
+	var err error
+	if w.connDetails, err = streamConnectionDetailsFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if w.streamName, w.superStream, err = streamNamesFromParsed(conf); err != nil {
+		return nil, err
+	}
+
+	if conf.Contains(rsoFieldRoutingKey) {
+		if w.routingKey, err = conf.FieldInterpolatedString(rsoFieldRoutingKey); err != nil {
+			return nil, err
+		}
+	}
+	if w.superStream != "" && w.routingKey == nil {
+		return nil, errors.New("a routing_key must be specified when writing to a super stream")
+	}
+
+	if w.routingStrategy, err = conf.FieldString(rsoFieldRoutingStrategy); err != nil {
+		return nil, err
+	}
+	if w.routingStrategy != "hash" && w.routingStrategy != "key" {
+		return nil, fmt.Errorf("unrecognised routing_strategy value: %v", w.routingStrategy)
+	}
+
+	if w.subEntrySize, err = conf.FieldInt(rsoFieldSubEntrySize); err != nil {
+		return nil, err
+	}
+	if w.subEntrySize < 1 {
+		return nil, errors.New("sub_entry_size must be greater than zero")
+	}
+
+	compression, err := conf.FieldString(rsoFieldCompression)
+	if err != nil {
+		return nil, err
+	}
+	switch compression {
+	case "none":
+		w.compression = stream.Compression{}.None()
+	case "gzip":
+		w.compression = stream.Compression{}.Gzip()
+	case "snappy":
+		w.compression = stream.Compression{}.Snappy()
+	case "lz4":
+		w.compression = stream.Compression{}.Lz4()
+	case "zstd":
+		w.compression = stream.Compression{}.Zstd()
+	default:
+		return nil, fmt.Errorf("unrecognised compression value: %v", compression)
+	}
+	if compression != "none" && w.subEntrySize == 1 {
+		return nil, errors.New("compression requires a sub_entry_size greater than one")
+	}
+
+	if conf.Contains(rsoFieldMetadata) {
+		if w.metaFilter, err = conf.FieldMetadataFilter(rsoFieldMetadata); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *streamWriter) Connect(ctx context.Context) (err error) {
+	w.connMut.Lock()
+	defer w.connMut.Unlock()
+
+	if w.conn != nil {
+		return nil
+	}
+
+	env, err := w.connDetails.newEnvironment()
+	if err != nil {
+		return err
+	}
+
+	conn := &streamProducers{
+		env:        env,
+		producers:  map[string]*stream.Producer{},
+		routeCache: map[string][]string{},
+		closeChan:  make(chan struct{}),
+		lostChan:   make(chan struct{}),
+	}
+	defer func() {
+		if err != nil {
+			w.closeConn(conn)
+		}
+	}()
+
+	if w.streamName != "" {
+		conn.partitions = []string{w.streamName}
+	} else if conn.partitions, err = env.QueryPartitions(w.superStream); err != nil {
+		return fmt.Errorf("failed to query partitions of super stream %v: %w", w.superStream, err)
+	}
+
+	for _, p := range conn.partitions {
+		opts := stream.NewProducerOptions().
+			SetClientProvidedName("benthos").
+			SetSubEntrySize(w.subEntrySize)
+		if w.subEntrySize > 1 {
+			opts = opts.SetCompression(w.compression)
+		}
+
+		producer, err := env.NewProducer(p, opts)
+		if err != nil {
+			return fmt.Errorf("failed to create producer of stream %v: %w", p, err)
+		}
+		conn.producers[p] = producer
+
+		go w.confirmLoop(conn, producer.NotifyPublishConfirmation())
+		go func(name string, closes stream.ChannelClose) {
+			select {
+			case e := <-closes:
+				select {
+				case <-conn.closeChan:
+					return
+				default:
+				}
+				w.log.Warnf("Producer of stream %v was closed: %v", name, e.Reason)
+				conn.lostOnce.Do(func() {
+					close(conn.lostChan)
+				})
+			case <-conn.closeChan:
+			}
+		}(p, producer.NotifyClose())
+	}
+
+	w.conn = conn
+	return nil
+}
+
+// confirmLoop resolves the pending messages of confirmations, which must be
+// consumed for as long as the producer is open.
+func (w *streamWriter) confirmLoop(conn *streamProducers, confirms stream.ChannelPublishConfirm) {
+	for {
+		select {
+		case statuses := <-confirms:
+			for _, s := range statuses {
+				var err error
+				if !s.IsConfirmed() {
+					if err = s.GetError(); err == nil {
+						err = fmt.Errorf("message was not confirmed, error code: %v", s.GetErrorCode())
+					}
+				}
+				w.resolve(s.GetMessage(), err)
+			}
+		case <-conn.closeChan:
+			return
+		}
+	}
+}
+
+func (w *streamWriter) resolve(msg message.StreamMessage, err error) {
+	w.pendingMut.Lock()
+	p, exists := w.pending[msg]
+	delete(w.pending, msg)
+	w.pendingMut.Unlock()
+
+	if exists {
+		p.result.resolve(p.index, err)
+	}
+}
+
+// route returns the partitions a message with a given routing key is written
+// to.
+func (w *streamWriter) route(conn *streamProducers, key string) ([]string, error) {
+	if w.routingStrategy == "hash" {
+		return stream.NewHashRoutingStrategy(func(message.StreamMessage) string {
+			return key
+		}).Route(nil, conn.partitions)
+	}
+
+	conn.routeMut.Lock()
+	defer conn.routeMut.Unlock()
+
+	if routes, exists := conn.routeCache[key]; exists {
+		return routes, nil
+	}
+	routes, err := conn.env.QueryRoute(w.superStream, key)
+	if err != nil {
+		return nil, err
+	}
+	conn.routeCache[key] = routes
+	return routes, nil
+}
+
+func (w *streamWriter) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	w.connMut.Lock()
+	conn := w.conn
+	w.connMut.Unlock()
+
+	if conn == nil {
+		return service.ErrNotConnected
+	}
+
+	select {
+	case <-conn.lostChan:
+		w.disconnect()
+		return service.ErrNotConnected
+	default:
+	}
+
+	type partitionBatch struct {
+		indexes  []int
+		messages []message.StreamMessage
+	}
+	batches := map[string]*partitionBatch{}
+
+	var batchErr *service.BatchError
+	failed := func(i int, err error) {
+		if batchErr == nil {
+			batchErr = service.NewBatchError(batch, err)
+		}
+		batchErr.Failed(i, err)
+	}
+
+	var total int
+	for i, msg := range batch {
+		body, err := msg.AsBytes()
+		if err != nil {
+			failed(i, err)
+			continue
+		}
+
+		partitions := conn.partitions
+		if w.superStream != "" {
+			key, err := w.routingKey.TryString(msg)
+			if err != nil {
+				failed(i, fmt.Errorf("routing_key interpolation error: %w", err))
+				continue
+			}
+			if partitions, err = w.route(conn, key); err != nil {
+				failed(i, fmt.Errorf("failed to route message with key '%v': %w", key, err))
+				continue
+			}
+			if len(partitions) == 0 {
+				failed(i, fmt.Errorf("no partitions matched routing key '%v'", key))
+				continue
+			}
+		}
+
+		props := map[string]any{}
+		_ = w.metaFilter.Walk(msg, func(key, value string) error {
+			props[key] = value
+			return nil
+		})
+
+		for _, p := range partitions {
+			pb, exists := batches[p]
+			if !exists {
+				pb = &partitionBatch{}
+				batches[p] = pb
+			}
+
+			m := amqp.NewMessage(body)
+			if len(props) > 0 {
+				m.ApplicationProperties = props
+			}
+			pb.indexes = append(pb.indexes, i)
+			pb.messages = append(pb.messages, m)
+			total++
+		}
+	}
+
+	result := newStreamBatchResult(total)
+
+	w.pendingMut.Lock()
+	for _, pb := range batches {
+		for j, m := range pb.messages {
+			w.pending[m] = streamPending{result: result, index: pb.indexes[j]}
+		}
+	}
+	w.pendingMut.Unlock()
+
+	for p, pb := range batches {
+		producer, exists := conn.producers[p]
+		if !exists {
+			err := fmt.Errorf("partition %v of super stream %v is not known, the super stream may have changed", p, w.superStream)
+			for _, m := range pb.messages {
+				w.resolve(m, err)
+			}
+			continue
+		}
+		if err := producer.BatchSend(pb.messages); err != nil {
+			for _, m := range pb.messages {
+				w.resolve(m, err)
+			}
+		}
+	}
+
+	select {
+	case <-result.done:
+	case <-ctx.Done():
+		w.pendingMut.Lock()
+		for _, pb := range batches {
+			for _, m := range pb.messages {
+				delete(w.pending, m)
+			}
+		}
+		w.pendingMut.Unlock()
+		return ctx.Err()
+	}
+
+	result.mut.Lock()
+	for i, err := range result.errs {
+		failed(i, err)
+	}
+	result.mut.Unlock()
+
+	if batchErr != nil {
+		return batchErr
+	}
+	return nil
+}
+
+func (w *streamWriter) closeConn(conn *streamProducers) {
+	for name, p := range conn.producers {
+		if err := p.Close(); err != nil && !errors.Is(err, stream.AlreadyClosed) {
+			w.log.Debugf("Failed to close producer of stream %v: %v", name, err)
+		}
+	}
+
+	conn.closeOnce.Do(func() {
+		close(conn.closeChan)
+	})
+
+	if err := conn.env.Close(); err != nil {
+		w.log.Debugf("Failed to close environment: %v", err)
+	}
+}
+
+func (w *streamWriter) disconnect() {
+	w.connMut.Lock()
+	conn := w.conn
+	w.conn = nil
+	w.connMut.Unlock()
+
+	if conn != nil {
+		w.closeConn(conn)
+	}
+}
+
+func (w *streamWriter) Close(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.disconnect()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
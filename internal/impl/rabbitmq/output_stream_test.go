@@ -0,0 +1,93 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestStreamOutputConfig(t *testing.T) {
+	tests := map[string]struct {
+		config string
+		err    string
+	}{
+		"no stream": {
+			config: `urls: [ rabbitmq-stream://localhost:5552 ]`,
+			err:    "exactly one of stream or super_stream must be specified",
+		},
+		"super stream without routing key": {
+			config: `
+urls: [ rabbitmq-stream://localhost:5552 ]
+super_stream: foo
+`,
+			err: "a routing_key must be specified when writing to a super stream",
+		},
+		"compression without sub entries": {
+			config: `
+urls: [ rabbitmq-stream://localhost:5552 ]
+stream: foo
+compression: zstd
+`,
+			err: "compression requires a sub_entry_size greater than one",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			conf, err := streamOutputSpec().ParseYAML(test.config, nil)
+			require.NoError(t, err)
+
+			_, err = newStreamWriterFromParsed(conf, service.MockResources())
+			require.EqualError(t, err, test.err)
+		})
+	}
+
+	conf, err := streamOutputSpec().ParseYAML(`
+urls: [ rabbitmq-stream://localhost:5552 ]
+super_stream: foo
+routing_key: ${! this.id }
+routing_strategy: key
+sub_entry_size: 100
+compression: gzip
+`, nil)
+	require.NoError(t, err)
+
+	w, err := newStreamWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	assert.Equal(t, "foo", w.superStream)
+	assert.Equal(t, "key", w.routingStrategy)
+	assert.Equal(t, 100, w.subEntrySize)
+
+	require.ErrorIs(t, w.WriteBatch(context.Background(), service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"foo"}`)),
+	}), service.ErrNotConnected)
+	require.NoError(t, w.Close(context.Background()))
+}
+
+func TestStreamBatchResult(t *testing.T) {
+	r := newStreamBatchResult(0)
+	select {
+	case <-r.done:
+	default:
+		t.Fatal("expected empty result to be done")
+	}
+
+	r = newStreamBatchResult(3)
+	r.resolve(0, nil)
+	r.resolve(2, errors.New("nope"))
+	select {
+	case <-r.done:
+		t.Fatal("expected result to be pending")
+	default:
+	}
+
+	r.resolve(2, errors.New("second"))
+	<-r.done
+	assert.Equal(t, map[int]error{2: errors.New("nope")}, r.errs)
+}
@@ -2,6 +2,8 @@ package redis
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"sync"
 
 	"github.com/redis/go-redis/v9"
@@ -11,8 +13,11 @@ import (
 )
 
 const (
-	psiFieldChannels    = "channels"
-	psiFieldUsePatterns = "use_patterns"
+	psiFieldChannels             = "channels"
+	psiFieldUsePatterns          = "use_patterns"
+	psiFieldSharded              = "sharded"
+	psiFieldKeyspaceNotification = "keyspace_notifications"
+	psiFieldNotifyKeyspaceEvents = "notify_keyspace_events"
 )
 
 func redisPubSubInputConfig() *service.ConfigSpec {
@@ -26,7 +31,34 @@ In order to subscribe to channels using the `+"`PSUBSCRIBE`"+` command set the f
 - `+"`h*llo`"+` subscribes to hllo and heeeello
 - `+"`h[ae]llo`"+` subscribes to hello and hallo, but not hillo
 
-Use `+"`\\`"+` to escape special characters if you want to match them verbatim.`).
+Use `+"`\\`"+` to escape special characters if you want to match them verbatim.
+
+### Sharded Pub/Sub
+
+Redis 7 introduced sharded channels, which are consumed with the `+"`SSUBSCRIBE`"+` command by setting the field `+"`sharded` to `true`"+`. Patterns are not supported for sharded channels.
+
+### Keyspace Notifications
+
+When the field `+"`keyspace_notifications` is set to `true`"+` the channels are instead treated as glob-style patterns of keys, and [keyspace notifications](https://redis.io/docs/manual/keyspace-notifications/) for matching keys across all databases are consumed. This is useful for invalidating caches or triggering work when keys are modified. Keyspace notifications are disabled by default within Redis, and can be enabled by the input with the field `+"`notify_keyspace_events`"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- redis_pubsub_channel
+- redis_pubsub_pattern
+`+"```"+`
+
+Where `+"`redis_pubsub_pattern`"+` is only added when the message was matched with a pattern. When consuming keyspace notifications the following metadata fields are also added:
+
+`+"```text"+`
+- redis_key
+- redis_operation
+- redis_database
+`+"```"+`
+
+Where `+"`redis_operation`"+` is the name of the command that modified the key, e.g. `+"`set`, `del` or `expired`"+`, which is also the contents of the message.`).
 		Categories("Services").
 		Fields(clientFields()...).
 		Fields(
@@ -35,8 +67,47 @@ Use `+"`\\`"+` to escape special characters if you want to match them verbatim.`
 			service.NewBoolField(psiFieldUsePatterns).
 				Description("Whether to use the PSUBSCRIBE command, allowing for glob-style patterns within target channel names.").
 				Default(false),
+			service.NewBoolField(psiFieldSharded).
+				Description("Whether to use the SSUBSCRIBE command in order to consume from sharded channels, which requires Redis 7 or later.").
+				Version("4.28.0").
+				Advanced().
+				Default(false),
+			service.NewBoolField(psiFieldKeyspaceNotification).
+				Description("Whether to consume keyspace notifications for keys matching the glob-style patterns listed in `channels`, rather than consuming from the channels themselves.").
+				Version("4.28.0").
+				Advanced().
+				Default(false),
+			service.NewStringField(psiFieldNotifyKeyspaceEvents).
+				Description("An optional set of keyspace event flags to set with the CONFIG SET command upon connecting, which is required when keyspace notifications have not already been enabled on the server. This replaces any flags that have already been set, and requires permission to run the CONFIG command.").
+				Example("K$gx").
+				Example("KA").
+				Version("4.28.0").
+				Advanced().
+				Optional(),
 			service.NewAutoRetryNacksToggleField(),
-		)
+		).
+		Example("Cache Invalidation", "Keyspace notifications for all keys prefixed with `user:` are consumed, and each key that is modified or expires is removed from a local cache.", `
+input:
+  redis_pubsub:
+    url: tcp://localhost:6379
+    channels: [ 'user:*' ]
+    keyspace_notifications: true
+    notify_keyspace_events: K$gx
+
+pipeline:
+  processors:
+    - cache:
+        resource: users
+        operator: delete
+        key: ${! @redis_key }
+
+output:
+  drop: {}
+
+cache_resources:
+  - label: users
+    memory: {}
+`)
 }
 
 func init() {
@@ -59,8 +130,11 @@ type redisPubSubReader struct {
 	pubsub *redis.PubSub
 	cMut   sync.Mutex
 
-	channels    []string
-	usePatterns bool
+	channels     []string
+	usePatterns  bool
+	sharded      bool
+	keyspace     bool
+	notifyEvents string
 
 	log *service.Logger
 }
@@ -80,9 +154,45 @@ func newRedisPubSubReader(conf *service.ParsedConfig, mgr *service.Resources) (*
 	if r.usePatterns, err = conf.FieldBool(psiFieldUsePatterns); err != nil {
 		return nil, err
 	}
+	if r.sharded, err = conf.FieldBool(psiFieldSharded); err != nil {
+		return nil, err
+	}
+	if r.keyspace, err = conf.FieldBool(psiFieldKeyspaceNotification); err != nil {
+		return nil, err
+	}
+	if conf.Contains(psiFieldNotifyKeyspaceEvents) {
+		if r.notifyEvents, err = conf.FieldString(psiFieldNotifyKeyspaceEvents); err != nil {
+			return nil, err
+		}
+	}
+	if r.sharded && (r.usePatterns || r.keyspace) {
+		return nil, errors.New("sharded channels cannot be combined with patterns or keyspace notifications")
+	}
 	return r, nil
 }
 
+const keyspaceChannelPrefix = "__keyspace@"
+
+// keyspaceChannels returns the channels of keyspace notifications for keys
+// matching a list of patterns within any database.
+func keyspaceChannels(patterns []string) []string {
+	channels := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		channels = append(channels, keyspaceChannelPrefix+"*__:"+p)
+	}
+	return channels
+}
+
+// parseKeyspaceChannel extracts the database and key from the channel of a
+// keyspace notification, e.g. `__keyspace@0__:foo`.
+func parseKeyspaceChannel(channel string) (database, key string, ok bool) {
+	if !strings.HasPrefix(channel, keyspaceChannelPrefix) {
+		return "", "", false
+	}
+	database, key, ok = strings.Cut(channel[len(keyspaceChannelPrefix):], "__:")
+	return
+}
+
 func (r *redisPubSubReader) Connect(ctx context.Context) error {
 	r.cMut.Lock()
 	defer r.cMut.Unlock()
@@ -95,9 +205,20 @@ func (r *redisPubSubReader) Connect(ctx context.Context) error {
 		return err
 	}
 
-	if r.usePatterns {
+	if r.notifyEvents != "" {
+		if err := r.client.ConfigSet(ctx, "notify-keyspace-events", r.notifyEvents).Err(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case r.keyspace:
+		r.pubsub = r.client.PSubscribe(ctx, keyspaceChannels(r.channels)...)
+	case r.sharded:
+		r.pubsub = r.client.SSubscribe(ctx, r.channels...)
+	case r.usePatterns:
 		r.pubsub = r.client.PSubscribe(ctx, r.channels...)
-	} else {
+	default:
 		r.pubsub = r.client.Subscribe(ctx, r.channels...)
 	}
 	return nil
@@ -120,7 +241,19 @@ func (r *redisPubSubReader) Read(ctx context.Context) (*service.Message, service
 			_ = r.disconnect()
 			return nil, nil, component.ErrTypeClosed
 		}
-		return service.NewMessage([]byte(rMsg.Payload)), func(ctx context.Context, err error) error {
+		msg := service.NewMessage([]byte(rMsg.Payload))
+		msg.MetaSetMut("redis_pubsub_channel", rMsg.Channel)
+		if rMsg.Pattern != "" {
+			msg.MetaSetMut("redis_pubsub_pattern", rMsg.Pattern)
+		}
+		if r.keyspace {
+			if database, key, ok := parseKeyspaceChannel(rMsg.Channel); ok {
+				msg.MetaSetMut("redis_key", key)
+				msg.MetaSetMut("redis_operation", rMsg.Payload)
+				msg.MetaSetMut("redis_database", database)
+			}
+		}
+		return msg, func(ctx context.Context, err error) error {
 			return nil
 		}, nil
 	case <-ctx.Done():
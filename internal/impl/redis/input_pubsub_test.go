@@ -0,0 +1,47 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestRedisPubSubInputConfErrors(t *testing.T) {
+	conf, err := redisPubSubInputConfig().ParseYAML(`
+url: redis://localhost:6379
+channels: [ foo ]
+sharded: true
+use_patterns: true
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newRedisPubSubReader(conf, service.MockResources())
+	require.EqualError(t, err, "sharded channels cannot be combined with patterns or keyspace notifications")
+}
+
+func TestRedisPubSubKeyspaceChannels(t *testing.T) {
+	assert.Equal(t, []string{
+		"__keyspace@*__:user:*",
+		"__keyspace@*__:foo",
+	}, keyspaceChannels([]string{"user:*", "foo"}))
+
+	for _, test := range []struct {
+		channel  string
+		database string
+		key      string
+		ok       bool
+	}{
+		{channel: "__keyspace@0__:foo", database: "0", key: "foo", ok: true},
+		{channel: "__keyspace@12__:user:1__:bar", database: "12", key: "user:1__:bar", ok: true},
+		{channel: "__keyevent@0__:set"},
+		{channel: "foo"},
+	} {
+		database, key, ok := parseKeyspaceChannel(test.channel)
+		assert.Equal(t, test.ok, ok, test.channel)
+		assert.Equal(t, test.database, database, test.channel)
+		assert.Equal(t, test.key, key, test.channel)
+	}
+}
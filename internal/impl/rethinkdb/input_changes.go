@@ -0,0 +1,308 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Jeffail/shutdown"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	rcFieldAddresses      = "addresses"
+	rcFieldDatabase       = "database"
+	rcFieldTable          = "table"
+	rcFieldUsername       = "username"
+	rcFieldPassword       = "password"
+	rcFieldTLS            = "tls"
+	rcFieldIncludeInitial = "include_initial"
+	rcFieldSquash         = "squash"
+)
+
+func changesInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Consumes document changes from a RethinkDB table using a changefeed.").
+		Description(`
+Each change within the feed is emitted as a structured message containing the fields `+"`type`, `old_val` and `new_val`"+`, where `+"`type`"+` is one of `+"`add`, `remove`, `change` or `initial`"+`, and `+"`old_val` or `new_val`"+` is null when a document was added or removed respectively.
+
+### Delivery Guarantees
+
+RethinkDB changefeeds cannot be resumed from a position, and therefore changes that occur whilst the input is disconnected are not consumed. When `+"`include_initial`"+` is enabled the current contents of the table are emitted with the type `+"`initial`"+` each time the feed is opened, followed by all changes made after, which allows downstream systems to converge with the table after a disconnect.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- rethinkdb_database
+- rethinkdb_table
+- rethinkdb_type
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewStringListField(rcFieldAddresses).
+				Description("A list of addresses of RethinkDB servers to connect to.").
+				Example([]string{"localhost:28015"}),
+			service.NewStringField(rcFieldDatabase).
+				Description("The name of the database containing the table."),
+			service.NewStringField(rcFieldTable).
+				Description("The name of the table to consume changes from."),
+			service.NewStringField(rcFieldUsername).
+				Description("The user to authenticate as, when empty the `admin` user is used.").
+				Default(""),
+			service.NewStringField(rcFieldPassword).
+				Description("The password of the user.").
+				Secret().
+				Default(""),
+			service.NewTLSToggledField(rcFieldTLS),
+			service.NewBoolField(rcFieldIncludeInitial).
+				Description("Whether to emit the current contents of the table each time the feed is opened, before any changes.").
+				Default(false),
+			service.NewBoolField(rcFieldSquash).
+				Description("Whether multiple changes to the same document that occur before they're read are squashed into a single change by the server.").
+				Default(false).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Replicate Orders", "The contents of an orders table are written to Kafka, followed by all subsequent changes, keyed by the ID of each document.", `
+input:
+  rethinkdb_changes:
+    addresses: [ localhost:28015 ]
+    database: shop
+    table: orders
+    include_initial: true
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: orders
+    key: ${! this.new_val.id | this.old_val.id }
+`)
+}
+
+func init() {
+	err := service.RegisterInput("rethinkdb_changes", changesInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newChangesInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type changesInput struct {
+	database       string
+	table          string
+	includeInitial bool
+	squash         bool
+
+	connect func() (r.QueryExecutor, func() error, error)
+
+	connMut   sync.Mutex
+	changesCh chan r.ChangeResponse
+	errCh     chan error
+	closeFn   func()
+
+	log     *service.Logger
+	shutSig *shutdown.Signaller
+}
+
+func newChangesInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*changesInput, error) {
+	c := &changesInput{
+		log:     mgr.Logger(),
+		shutSig: shutdown.NewSignaller(),
+	}
+
+	var opts r.ConnectOpts
+	var err error
+	if opts.Addresses, err = conf.FieldStringList(rcFieldAddresses); err != nil {
+		return nil, err
+	}
+	if len(opts.Addresses) == 0 {
+		return nil, errors.New("at least one address must be specified")
+	}
+	if c.database, err = conf.FieldString(rcFieldDatabase); err != nil {
+		return nil, err
+	}
+	if c.database == "" {
+		return nil, errors.New("database must not be empty")
+	}
+	if c.table, err = conf.FieldString(rcFieldTable); err != nil {
+		return nil, err
+	}
+	if c.table == "" {
+		return nil, errors.New("table must not be empty")
+	}
+	if opts.Username, err = conf.FieldString(rcFieldUsername); err != nil {
+		return nil, err
+	}
+	if opts.Password, err = conf.FieldString(rcFieldPassword); err != nil {
+		return nil, err
+	}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(rcFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		opts.TLSConfig = tlsConf
+	}
+	if c.includeInitial, err = conf.FieldBool(rcFieldIncludeInitial); err != nil {
+		return nil, err
+	}
+	if c.squash, err = conf.FieldBool(rcFieldSquash); err != nil {
+		return nil, err
+	}
+
+	// Numbers are kept as json.Number in order to avoid losing the precision
+	// of large integers.
+	opts.UseJSONNumber = true
+
+	c.connect = func() (r.QueryExecutor, func() error, error) {
+		session, err := r.Connect(opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return session, func() error { return session.Close() }, nil
+	}
+	return c, nil
+}
+
+func (c *changesInput) changesTerm() r.Term {
+	return r.DB(c.database).Table(c.table).Changes(r.ChangesOpts{
+		IncludeInitial: c.includeInitial,
+		IncludeTypes:   true,
+		Squash:         c.squash,
+	})
+}
+
+func (c *changesInput) Connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.changesCh != nil {
+		return nil
+	}
+
+	exec, closeExec, err := c.connect()
+	if err != nil {
+		return err
+	}
+
+	// The cursor fetches changes with the context of the query for as long as
+	// the feed is open, and therefore it must outlive the connect call.
+	feedCtx, feedDone := c.shutSig.SoftStopCtx(context.Background())
+	cursor, err := c.changesTerm().Run(exec, r.RunOpts{Context: feedCtx})
+	if err != nil {
+		feedDone()
+		_ = closeExec()
+		return fmt.Errorf("failed to open changefeed: %w", err)
+	}
+
+	changesCh, errCh := make(chan r.ChangeResponse), make(chan error, 1)
+	go func() {
+		defer close(changesCh)
+		errCh <- readChanges(feedCtx, cursor, changesCh)
+	}()
+
+	c.changesCh, c.errCh = changesCh, errCh
+	c.closeFn = func() {
+		feedDone()
+		_ = cursor.Close()
+		_ = closeExec()
+	}
+	return nil
+}
+
+// readChanges reads the changes of a cursor until it is closed or the context
+// is cancelled.
+func readChanges(ctx context.Context, cursor *r.Cursor, changesCh chan<- r.ChangeResponse) error {
+	var change r.ChangeResponse
+	for cursor.Next(&change) {
+		if change.Error != "" {
+			return errors.New(change.Error)
+		}
+		// Status documents only indicate the progress of initial values.
+		if change.Type == "state" || (change.State != "" && change.Type == "") {
+			continue
+		}
+		select {
+		case changesCh <- change:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return cursor.Err()
+}
+
+func (c *changesInput) disconnect() {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.closeFn != nil {
+		c.closeFn()
+		c.closeFn = nil
+	}
+	c.changesCh, c.errCh = nil, nil
+}
+
+func (c *changesInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	c.connMut.Lock()
+	changesCh, errCh := c.changesCh, c.errCh
+	c.connMut.Unlock()
+
+	if changesCh == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	var change r.ChangeResponse
+	var open bool
+	select {
+	case change, open = <-changesCh:
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	if !open {
+		c.disconnect()
+		if err := <-errCh; err != nil {
+			c.log.Errorf("Changefeed failed: %v", err)
+		}
+		return nil, nil, service.ErrNotConnected
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(map[string]any{
+		"type":    change.Type,
+		"old_val": change.OldValue,
+		"new_val": change.NewValue,
+	})
+	msg.MetaSetMut("rethinkdb_database", c.database)
+	msg.MetaSetMut("rethinkdb_table", c.table)
+	msg.MetaSetMut("rethinkdb_type", change.Type)
+
+	return msg, func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (c *changesInput) Close(ctx context.Context) error {
+	c.shutSig.TriggerSoftStop()
+	c.disconnect()
+	return nil
+}
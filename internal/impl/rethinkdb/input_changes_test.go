@@ -0,0 +1,149 @@
+package rethinkdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	r "gopkg.in/rethinkdb/rethinkdb-go.v6"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testChangesInput(t testing.TB, conf string) *changesInput {
+	t.Helper()
+
+	pConf, err := changesInputSpec().ParseYAML(conf, nil)
+	require.NoError(t, err)
+
+	c, err := newChangesInputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	return c
+}
+
+func TestChangesInputConfig(t *testing.T) {
+	for _, test := range []struct {
+		conf string
+		err  string
+	}{
+		{
+			conf: `
+addresses: []
+database: foo
+table: bar
+`,
+			err: "at least one address must be specified",
+		},
+		{
+			conf: `
+addresses: [ localhost:28015 ]
+database: ""
+table: bar
+`,
+			err: "database must not be empty",
+		},
+		{
+			conf: `
+addresses: [ localhost:28015 ]
+database: foo
+table: ""
+`,
+			err: "table must not be empty",
+		},
+	} {
+		pConf, err := changesInputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newChangesInputFromParsed(pConf, service.MockResources())
+		require.EqualError(t, err, test.err)
+	}
+}
+
+func TestChangesInput(t *testing.T) {
+	c := testChangesInput(t, `
+addresses: [ localhost:28015 ]
+database: shop
+table: orders
+include_initial: true
+`)
+
+	mock := r.NewMock()
+	mock.On(c.changesTerm()).Return([]any{
+		map[string]any{"type": "initial", "new_val": map[string]any{"id": "foo", "v": 1}},
+		map[string]any{"state": "ready"},
+		map[string]any{"type": "change", "old_val": map[string]any{"id": "foo", "v": 1}, "new_val": map[string]any{"id": "foo", "v": 2}},
+		map[string]any{"type": "remove", "old_val": map[string]any{"id": "foo", "v": 2}},
+	}, nil).Once()
+
+	var closed bool
+	c.connect = func() (r.QueryExecutor, func() error, error) {
+		return mock, func() error {
+			closed = true
+			return nil
+		}, nil
+	}
+
+	ctx := context.Background()
+	require.NoError(t, c.Connect(ctx))
+
+	var types []string
+	var docs []any
+	for i := 0; i < 3; i++ {
+		msg, ackFn, err := c.Read(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+
+		mType, _ := msg.MetaGet("rethinkdb_type")
+		types = append(types, mType)
+
+		mDB, _ := msg.MetaGet("rethinkdb_database")
+		assert.Equal(t, "shop", mDB)
+		mTable, _ := msg.MetaGet("rethinkdb_table")
+		assert.Equal(t, "orders", mTable)
+
+		structured, err := msg.AsStructured()
+		require.NoError(t, err)
+		docs = append(docs, structured)
+	}
+	assert.Equal(t, []string{"initial", "change", "remove"}, types)
+	assert.Equal(t, map[string]any{
+		"type":    "change",
+		"old_val": map[string]any{"id": "foo", "v": 1.0},
+		"new_val": map[string]any{"id": "foo", "v": 2.0},
+	}, docs[1])
+
+	// The feed ending causes a reconnect.
+	_, _, err := c.Read(ctx)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+	assert.True(t, closed)
+
+	require.NoError(t, c.Close(ctx))
+	mock.AssertExpectations(t)
+}
+
+func TestChangesInputConnectError(t *testing.T) {
+	c := testChangesInput(t, `
+addresses: [ localhost:28015 ]
+database: shop
+table: orders
+`)
+
+	mock := r.NewMock()
+	mock.On(c.changesTerm()).Return(nil, errors.New("table does not exist"))
+
+	var closed bool
+	c.connect = func() (r.QueryExecutor, func() error, error) {
+		return mock, func() error {
+			closed = true
+			return nil
+		}, nil
+	}
+
+	require.ErrorContains(t, c.Connect(context.Background()), "table does not exist")
+	assert.True(t, closed)
+
+	_, _, err := c.Read(context.Background())
+	require.ErrorIs(t, err, service.ErrNotConnected)
+}
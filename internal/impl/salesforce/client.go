@@ -0,0 +1,232 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sfFieldLoginURL     = "login_url"
+	sfFieldClientID     = "client_id"
+	sfFieldClientSecret = "client_secret"
+	sfFieldAPIVersion   = "api_version"
+)
+
+func clientFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewURLField(sfFieldLoginURL).
+			Description("The URL used to obtain access tokens via the OAuth 2.0 client credentials flow, which must be the My Domain URL of your org.").
+			Example("https://mycompany.my.salesforce.com"),
+		service.NewStringField(sfFieldClientID).
+			Description("The consumer key of a connected app with the client credentials flow enabled."),
+		service.NewStringField(sfFieldClientSecret).
+			Description("The consumer secret of the connected app.").
+			Secret(),
+		service.NewStringField(sfFieldAPIVersion).
+			Description("The version of the Salesforce REST API to use.").
+			Default("v60.0").
+			Advanced(),
+	}
+}
+
+// errSFResponse describes an unexpected response from the Salesforce API.
+type errSFResponse struct {
+	status int
+	body   string
+}
+
+func (e *errSFResponse) Error() string {
+	return fmt.Sprintf("unexpected status code %v: %v", e.status, e.body)
+}
+
+// sfClient performs authenticated requests against the Salesforce REST API,
+// obtaining a new access token when the current one expires.
+type sfClient struct {
+	loginURL     *url.URL
+	clientID     string
+	clientSecret string
+	apiVersion   string
+
+	http *http.Client
+
+	authMut     sync.Mutex
+	token       string
+	instanceURL string
+}
+
+func clientFromParsed(conf *service.ParsedConfig) (*sfClient, error) {
+	c := &sfClient{}
+
+	var err error
+	if c.loginURL, err = conf.FieldURL(sfFieldLoginURL); err != nil {
+		return nil, err
+	}
+	if c.clientID, err = conf.FieldString(sfFieldClientID); err != nil {
+		return nil, err
+	}
+	if c.clientSecret, err = conf.FieldString(sfFieldClientSecret); err != nil {
+		return nil, err
+	}
+	if c.apiVersion, err = conf.FieldString(sfFieldAPIVersion); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(c.apiVersion, "v") {
+		c.apiVersion = "v" + c.apiVersion
+	}
+
+	// The streaming API relies on cookies in order to route requests to the
+	// same server.
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	c.http = &http.Client{Jar: jar}
+	return c, nil
+}
+
+func (c *sfClient) authenticate(ctx context.Context) error {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.loginURL.JoinPath("services/oauth2/token").String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to obtain access token: %w", &errSFResponse{status: res.StatusCode, body: string(resBody)})
+	}
+
+	var tokenRes struct {
+		AccessToken string `json:"access_token"`
+		InstanceURL string `json:"instance_url"`
+	}
+	if err := json.Unmarshal(resBody, &tokenRes); err != nil {
+		return fmt.Errorf("failed to parse access token response: %w", err)
+	}
+	if tokenRes.AccessToken == "" || tokenRes.InstanceURL == "" {
+		return errors.New("access token response is missing the access_token or instance_url")
+	}
+
+	c.authMut.Lock()
+	c.token, c.instanceURL = tokenRes.AccessToken, strings.TrimSuffix(tokenRes.InstanceURL, "/")
+	c.authMut.Unlock()
+	return nil
+}
+
+// dataPath returns a path relative to the versioned data API.
+func (c *sfClient) dataPath(elem ...string) string {
+	return "/services/data/" + c.apiVersion + "/" + strings.Join(elem, "/")
+}
+
+// do performs a request against a path of the instance, authenticating first
+// if required. When the access token has expired the request is attempted once
+// more with a new token. The response is returned only when its status code
+// is within the 2XX range.
+func (c *sfClient) do(ctx context.Context, method, path, contentType string, body []byte, header http.Header) (*http.Response, error) {
+	c.authMut.Lock()
+	token := c.token
+	c.authMut.Unlock()
+	if token == "" {
+		if err := c.authenticate(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		c.authMut.Lock()
+		token, instanceURL := c.token, c.instanceURL
+		c.authMut.Unlock()
+
+		target := path
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+			target = instanceURL + path
+		}
+
+		var bodyRdr io.Reader
+		if body != nil {
+			bodyRdr = strings.NewReader(string(body))
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target, bodyRdr)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if req.Header.Get("Accept") == "" {
+			req.Header.Set("Accept", "application/json")
+		}
+
+		res, err := c.http.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			return res, nil
+		}
+
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		_ = res.Body.Close()
+		if res.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			if err := c.authenticate(ctx); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, &errSFResponse{status: res.StatusCode, body: strings.TrimSpace(string(resBody))}
+	}
+}
+
+// doJSON performs a request with an optional JSON body, and parses the JSON
+// response into out when it is non-nil.
+func (c *sfClient) doJSON(ctx context.Context, method, path string, in, out any) error {
+	var body []byte
+	var contentType string
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	}
+
+	res, err := c.do(ctx, method, path, contentType, body, nil)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
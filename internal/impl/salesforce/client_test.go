@@ -0,0 +1,102 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// newTestServer creates a server that issues access tokens, where requests are
+// only passed to the handler when they use the most recently issued token.
+func newTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var tokens int32
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/oauth2/token" {
+			require.NoError(t, r.ParseForm())
+			assert.Equal(t, "client_credentials", r.PostForm.Get("grant_type"))
+			if r.PostForm.Get("client_id") != "foo" || r.PostForm.Get("client_secret") != "bar" {
+				http.Error(w, `{"error":"invalid_client"}`, http.StatusBadRequest)
+				return
+			}
+			n := atomic.AddInt32(&tokens, 1)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": fmt.Sprintf("token%v", n),
+				"instance_url": ts.URL + "/",
+			})
+			return
+		}
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer token%v", atomic.LoadInt32(&tokens)) {
+			http.Error(w, `[{"errorCode":"INVALID_SESSION_ID"}]`, http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &tokens
+}
+
+func testClientConfig(url string) string {
+	return fmt.Sprintf(`
+login_url: %v
+client_id: foo
+client_secret: bar
+`, url)
+}
+
+func TestClientReauthenticates(t *testing.T) {
+	ts, tokens := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/services/data/v60.0/sobjects", r.URL.Path)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+
+	conf, err := service.NewConfigSpec().Fields(clientFields()...).ParseYAML(testClientConfig(ts.URL), nil)
+	require.NoError(t, err)
+
+	c, err := clientFromParsed(conf)
+	require.NoError(t, err)
+
+	var res struct {
+		OK bool `json:"ok"`
+	}
+	require.NoError(t, c.doJSON(context.Background(), http.MethodGet, c.dataPath("sobjects"), nil, &res))
+	assert.True(t, res.OK)
+	assert.Equal(t, int32(1), atomic.LoadInt32(tokens))
+
+	// Simulate the expiry of the access token.
+	atomic.AddInt32(tokens, 1)
+
+	res.OK = false
+	require.NoError(t, c.doJSON(context.Background(), http.MethodGet, c.dataPath("sobjects"), nil, &res))
+	assert.True(t, res.OK)
+	assert.Equal(t, int32(3), atomic.LoadInt32(tokens))
+}
+
+func TestClientBadCredentials(t *testing.T) {
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	conf, err := service.NewConfigSpec().Fields(clientFields()...).ParseYAML(fmt.Sprintf(`
+login_url: %v
+client_id: foo
+client_secret: nope
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	c, err := clientFromParsed(conf)
+	require.NoError(t, err)
+
+	err = c.authenticate(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_client")
+}
@@ -0,0 +1,417 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Jeffail/checkpoint"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sciFieldChannel         = "channel"
+	sciFieldReplayID        = "replay_id"
+	sciFieldCheckpointCache = "checkpoint_cache"
+	sciFieldCheckpointKey   = "checkpoint_key"
+	sciFieldCheckpointLimit = "checkpoint_limit"
+)
+
+func cdcInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Consumes Change Data Capture and platform events from Salesforce via the Streaming API.").
+		Description(`
+Subscribes to a channel of the [Streaming API](https://developer.salesforce.com/docs/atlas.en-us.api_streaming.meta/api_streaming/intro_stream.htm) using the CometD long polling protocol, and creates a message for each event received containing the payload of the event.
+
+### Replaying Events
+
+Events are retained by Salesforce for a limited period (72 hours for Change Data Capture events), and each event has a replay ID which can be used to resume a subscription. When a `+"`checkpoint_cache`"+` is configured the replay ID of the latest event that has been acknowledged, along with all prior events, is stored within the cache, and upon starting the subscription resumes from the stored replay ID. Otherwise the subscription starts from the replay ID set with `+"`replay_id`"+`.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- salesforce_channel
+- salesforce_replay_id
+- salesforce_entity_name
+- salesforce_change_type
+`+"```"+`
+
+Where `+"`salesforce_entity_name` and `salesforce_change_type`"+` are only added to Change Data Capture events.
+
+### Authentication
+
+Requests are authenticated with access tokens obtained via the [OAuth 2.0 client credentials flow](https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_client_credentials_flow.htm) of a connected app.`).
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringField(sciFieldChannel).
+				Description("The channel to subscribe to.").
+				Examples("/data/ChangeEvents", "/data/AccountChangeEvent", "/event/Order_Placed__e"),
+			service.NewIntField(sciFieldReplayID).
+				Description("The replay ID to subscribe from when no checkpoint has been stored, where `-1` consumes only new events and `-2` consumes all retained events.").
+				Default(-1),
+			service.NewStringField(sciFieldCheckpointCache).
+				Description("A [cache resource](/docs/components/caches/about) in which the replay ID of the latest acknowledged event is stored.").
+				Optional(),
+			service.NewStringField(sciFieldCheckpointKey).
+				Description("The key under which the replay ID of the latest acknowledged event is stored.").
+				Default("salesforce_replay_id").
+				Advanced(),
+			service.NewIntField(sciFieldCheckpointLimit).
+				Description("The maximum number of events that can be pending acknowledgement at any given time.").
+				Default(1024).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Account Changes", "Changes to accounts are consumed and written to Kafka, keyed by the ID of the record that changed.", `
+input:
+  salesforce_cdc:
+    login_url: https://mycompany.my.salesforce.com
+    client_id: ${SALESFORCE_CLIENT_ID}
+    client_secret: ${SALESFORCE_CLIENT_SECRET}
+    channel: /data/AccountChangeEvent
+    checkpoint_cache: checkpoints
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: salesforce_accounts
+    key: ${! this.ChangeEventHeader.recordIds.index(0) }
+
+cache_resources:
+  - label: checkpoints
+    file:
+      directory: ./checkpoints
+`)
+}
+
+func init() {
+	err := service.RegisterInput("salesforce_cdc", cdcInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newCDCInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type bayeuxMessage struct {
+	Channel                  string          `json:"channel"`
+	ClientID                 string          `json:"clientId,omitempty"`
+	Successful               *bool           `json:"successful,omitempty"`
+	Error                    string          `json:"error,omitempty"`
+	Subscription             string          `json:"subscription,omitempty"`
+	Version                  string          `json:"version,omitempty"`
+	SupportedConnectionTypes []string        `json:"supportedConnectionTypes,omitempty"`
+	ConnectionType           string          `json:"connectionType,omitempty"`
+	Ext                      map[string]any  `json:"ext,omitempty"`
+	Advice                   *bayeuxAdvice   `json:"advice,omitempty"`
+	Data                     json.RawMessage `json:"data,omitempty"`
+}
+
+type bayeuxAdvice struct {
+	Reconnect string `json:"reconnect"`
+}
+
+type cdcEvent struct {
+	payload  json.RawMessage
+	replayID int64
+}
+
+type cdcInput struct {
+	client   *sfClient
+	channel  string
+	replayID int64
+
+	cache    string
+	cacheKey string
+
+	checkpointer *checkpoint.Capped[int64]
+
+	connMut  sync.Mutex
+	resumed  bool
+	clientID string
+	pending  []cdcEvent
+
+	mgr *service.Resources
+	log *service.Logger
+}
+
+func newCDCInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*cdcInput, error) {
+	c := &cdcInput{
+		mgr: mgr,
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if c.client, err = clientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if c.channel, err = conf.FieldString(sciFieldChannel); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(c.channel, "/") {
+		return nil, errors.New("channel must begin with a forward slash")
+	}
+	replayID, err := conf.FieldInt(sciFieldReplayID)
+	if err != nil {
+		return nil, err
+	}
+	c.replayID = int64(replayID)
+
+	if conf.Contains(sciFieldCheckpointCache) {
+		if c.cache, err = conf.FieldString(sciFieldCheckpointCache); err != nil {
+			return nil, err
+		}
+		if !mgr.HasCache(c.cache) {
+			return nil, fmt.Errorf("cache resource '%v' was not found", c.cache)
+		}
+	}
+	if c.cacheKey, err = conf.FieldString(sciFieldCheckpointKey); err != nil {
+		return nil, err
+	}
+	limit, err := conf.FieldInt(sciFieldCheckpointLimit)
+	if err != nil {
+		return nil, err
+	}
+	c.checkpointer = checkpoint.NewCapped[int64](int64(limit))
+	return c, nil
+}
+
+func (c *cdcInput) cometdPath() string {
+	return "/cometd/" + strings.TrimPrefix(c.client.apiVersion, "v")
+}
+
+func (c *cdcInput) send(ctx context.Context, msg bayeuxMessage) ([]bayeuxMessage, error) {
+	var res []bayeuxMessage
+	if err := c.client.doJSON(ctx, http.MethodPost, c.cometdPath(), []bayeuxMessage{msg}, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// metaResponse finds the response to a meta message, and returns an error if
+// it was not successful.
+func metaResponse(channel string, res []bayeuxMessage) (bayeuxMessage, error) {
+	for _, m := range res {
+		if m.Channel != channel {
+			continue
+		}
+		if m.Successful == nil || !*m.Successful {
+			return m, fmt.Errorf("%v failed: %v", channel, m.Error)
+		}
+		return m, nil
+	}
+	return bayeuxMessage{}, fmt.Errorf("no response received for %v", channel)
+}
+
+func (c *cdcInput) readCheckpoint(ctx context.Context) (int64, bool, error) {
+	if c.cache == "" {
+		return 0, false, nil
+	}
+	var idBytes []byte
+	var cErr error
+	if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+		idBytes, cErr = cache.Get(ctx, c.cacheKey)
+	}); err != nil {
+		return 0, false, err
+	}
+	if errors.Is(cErr, service.ErrKeyNotFound) {
+		return 0, false, nil
+	}
+	if cErr != nil {
+		return 0, false, cErr
+	}
+	id, err := strconv.ParseInt(string(idBytes), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse stored replay ID: %w", err)
+	}
+	return id, true, nil
+}
+
+func (c *cdcInput) Connect(ctx context.Context) error {
+	c.connMut.Lock()
+	defer c.connMut.Unlock()
+
+	if c.clientID != "" {
+		return nil
+	}
+
+	// A stored checkpoint only takes precedence upon the first connection,
+	// after which reconnects resume from the latest event read.
+	if !c.resumed {
+		id, exists, err := c.readCheckpoint(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to obtain checkpoint: %w", err)
+		}
+		if exists {
+			c.replayID = id
+		}
+	}
+
+	res, err := c.send(ctx, bayeuxMessage{
+		Channel:                  "/meta/handshake",
+		Version:                  "1.0",
+		SupportedConnectionTypes: []string{"long-polling"},
+	})
+	if err != nil {
+		return err
+	}
+	hsRes, err := metaResponse("/meta/handshake", res)
+	if err != nil {
+		return err
+	}
+
+	if res, err = c.send(ctx, bayeuxMessage{
+		Channel:      "/meta/subscribe",
+		ClientID:     hsRes.ClientID,
+		Subscription: c.channel,
+		Ext: map[string]any{
+			"replay": map[string]int64{c.channel: c.replayID},
+		},
+	}); err != nil {
+		return err
+	}
+	if _, err = metaResponse("/meta/subscribe", res); err != nil {
+		return err
+	}
+
+	c.resumed = true
+	c.clientID = hsRes.ClientID
+	return nil
+}
+
+// poll performs a long polling connect request, returning the events received.
+func (c *cdcInput) poll(ctx context.Context, clientID string) ([]cdcEvent, error) {
+	res, err := c.send(ctx, bayeuxMessage{
+		Channel:        "/meta/connect",
+		ClientID:       clientID,
+		ConnectionType: "long-polling",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var events []cdcEvent
+	for _, m := range res {
+		if m.Channel == "/meta/connect" {
+			if m.Successful == nil || !*m.Successful {
+				if m.Advice != nil && m.Advice.Reconnect == "retry" {
+					continue
+				}
+				return nil, fmt.Errorf("/meta/connect failed: %v", m.Error)
+			}
+			continue
+		}
+		if m.Channel != c.channel || m.Data == nil {
+			continue
+		}
+		var data struct {
+			Event struct {
+				ReplayID int64 `json:"replayId"`
+			} `json:"event"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := json.Unmarshal(m.Data, &data); err != nil {
+			return nil, fmt.Errorf("failed to parse event: %w", err)
+		}
+		events = append(events, cdcEvent{payload: data.Payload, replayID: data.Event.ReplayID})
+	}
+	return events, nil
+}
+
+func (c *cdcInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	c.connMut.Lock()
+	clientID := c.clientID
+	c.connMut.Unlock()
+
+	if clientID == "" {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	// Pending events are only accessed by calls to Read, which are never
+	// concurrent.
+	for len(c.pending) == 0 {
+		events, err := c.poll(ctx, clientID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			c.log.Errorf("Subscription failed, reconnecting: %v", err)
+			c.connMut.Lock()
+			c.clientID = ""
+			c.connMut.Unlock()
+			return nil, nil, service.ErrNotConnected
+		}
+		c.pending = events
+	}
+
+	event := c.pending[0]
+	c.pending = c.pending[1:]
+
+	c.connMut.Lock()
+	c.replayID = event.replayID
+	c.connMut.Unlock()
+
+	msg := service.NewMessage(event.payload)
+	msg.MetaSetMut("salesforce_channel", c.channel)
+	msg.MetaSetMut("salesforce_replay_id", strconv.FormatInt(event.replayID, 10))
+
+	var header struct {
+		ChangeEventHeader *struct {
+			EntityName string `json:"entityName"`
+			ChangeType string `json:"changeType"`
+		} `json:"ChangeEventHeader"`
+	}
+	if err := json.Unmarshal(event.payload, &header); err == nil && header.ChangeEventHeader != nil {
+		msg.MetaSetMut("salesforce_entity_name", header.ChangeEventHeader.EntityName)
+		msg.MetaSetMut("salesforce_change_type", header.ChangeEventHeader.ChangeType)
+	}
+
+	release, err := c.checkpointer.Track(ctx, event.replayID, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return msg, func(ctx context.Context, err error) error {
+		highestID := release()
+		if highestID == nil || c.cache == "" {
+			return nil
+		}
+		var setErr error
+		if err := c.mgr.AccessCache(ctx, c.cache, func(cache service.Cache) {
+			setErr = cache.Set(ctx, c.cacheKey, []byte(strconv.FormatInt(*highestID, 10)), nil)
+		}); err != nil {
+			return err
+		}
+		return setErr
+	}, nil
+}
+
+func (c *cdcInput) Close(ctx context.Context) error {
+	c.connMut.Lock()
+	clientID := c.clientID
+	c.clientID = ""
+	c.connMut.Unlock()
+
+	if clientID != "" {
+		_, _ = c.send(ctx, bayeuxMessage{
+			Channel:  "/meta/disconnect",
+			ClientID: clientID,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestCDCInput(t *testing.T) {
+	var reqMut sync.Mutex
+	var subscribeReplays []any
+	var connects int
+	var disconnected bool
+
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cometd/60.0", r.URL.Path)
+
+		var msgs []map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msgs))
+		require.Len(t, msgs, 1)
+
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		switch msgs[0]["channel"] {
+		case "/meta/handshake":
+			_, _ = w.Write([]byte(`[{"channel":"/meta/handshake","clientId":"c1","successful":true}]`))
+		case "/meta/subscribe":
+			assert.Equal(t, "c1", msgs[0]["clientId"])
+			assert.Equal(t, "/data/AccountChangeEvent", msgs[0]["subscription"])
+			subscribeReplays = append(subscribeReplays, msgs[0]["ext"].(map[string]any)["replay"].(map[string]any)["/data/AccountChangeEvent"])
+			_, _ = w.Write([]byte(`[{"channel":"/meta/subscribe","clientId":"c1","successful":true}]`))
+		case "/meta/connect":
+			connects++
+			switch connects {
+			case 1:
+				_, _ = w.Write([]byte(`[
+  {"channel":"/data/AccountChangeEvent","data":{"event":{"replayId":11},"payload":{"ChangeEventHeader":{"entityName":"Account","changeType":"CREATE","recordIds":["001a"]},"Name":"Foo"}}},
+  {"channel":"/data/AccountChangeEvent","data":{"event":{"replayId":12},"payload":{"ChangeEventHeader":{"entityName":"Account","changeType":"UPDATE","recordIds":["001b"]},"Name":"Bar"}}},
+  {"channel":"/meta/connect","clientId":"c1","successful":true}
+]`))
+			case 2:
+				_, _ = w.Write([]byte(`[{"channel":"/meta/connect","clientId":"c1","successful":false,"error":"403::Unknown client","advice":{"reconnect":"handshake"}}]`))
+			default:
+				_, _ = w.Write([]byte(`[{"channel":"/meta/connect","clientId":"c1","successful":true}]`))
+			}
+		case "/meta/disconnect":
+			disconnected = true
+			_, _ = w.Write([]byte(`[{"channel":"/meta/disconnect","successful":true}]`))
+		default:
+			t.Errorf("unexpected channel: %v", msgs[0]["channel"])
+		}
+	})
+
+	conf, err := cdcInputSpec().ParseYAML(testClientConfig(ts.URL)+`
+channel: /data/AccountChangeEvent
+checkpoint_cache: checkpoints
+`, nil)
+	require.NoError(t, err)
+
+	res := service.MockResources(service.MockResourcesOptAddCache("checkpoints"))
+	require.NoError(t, res.AccessCache(context.Background(), "checkpoints", func(c service.Cache) {
+		require.NoError(t, c.Set(context.Background(), "salesforce_replay_id", []byte("10"), nil))
+	}))
+
+	i, err := newCDCInputFromParsed(conf, res)
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, i.Connect(ctx))
+
+	msg, ackFn, err := i.Read(ctx)
+	require.NoError(t, err)
+	mBytes, err := msg.AsBytes()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"ChangeEventHeader":{"entityName":"Account","changeType":"CREATE","recordIds":["001a"]},"Name":"Foo"}`, string(mBytes))
+	v, _ := msg.MetaGetMut("salesforce_replay_id")
+	assert.Equal(t, "11", v)
+	v, _ = msg.MetaGetMut("salesforce_entity_name")
+	assert.Equal(t, "Account", v)
+	v, _ = msg.MetaGetMut("salesforce_change_type")
+	assert.Equal(t, "CREATE", v)
+	v, _ = msg.MetaGetMut("salesforce_channel")
+	assert.Equal(t, "/data/AccountChangeEvent", v)
+
+	msg2, ackFn2, err := i.Read(ctx)
+	require.NoError(t, err)
+	v, _ = msg2.MetaGetMut("salesforce_replay_id")
+	assert.Equal(t, "12", v)
+
+	getCheckpoint := func() string {
+		var id []byte
+		require.NoError(t, res.AccessCache(ctx, "checkpoints", func(c service.Cache) {
+			id, err = c.Get(ctx, "salesforce_replay_id")
+		}))
+		require.NoError(t, err)
+		return string(id)
+	}
+
+	// Acknowledging out of order must not checkpoint beyond unacknowledged
+	// events.
+	require.NoError(t, ackFn2(ctx, nil))
+	assert.Equal(t, "10", getCheckpoint())
+	require.NoError(t, ackFn(ctx, nil))
+	assert.Equal(t, "12", getCheckpoint())
+
+	// A failed connect triggers a new handshake that resumes from the latest
+	// event read.
+	_, _, err = i.Read(ctx)
+	require.ErrorIs(t, err, service.ErrNotConnected)
+	require.NoError(t, i.Connect(ctx))
+	require.NoError(t, i.Close(ctx))
+
+	reqMut.Lock()
+	assert.Equal(t, []any{float64(10), float64(12)}, subscribeReplays)
+	assert.True(t, disconnected)
+	reqMut.Unlock()
+}
+
+func TestCDCInputSubscribeFailed(t *testing.T) {
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var msgs []map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&msgs))
+		switch msgs[0]["channel"] {
+		case "/meta/handshake":
+			_, _ = w.Write([]byte(`[{"channel":"/meta/handshake","clientId":"c1","successful":true}]`))
+		default:
+			_, _ = w.Write([]byte(`[{"channel":"/meta/subscribe","successful":false,"error":"403:denied:Unknown channel"}]`))
+		}
+	})
+
+	conf, err := cdcInputSpec().ParseYAML(testClientConfig(ts.URL)+`
+channel: /data/NopeChangeEvent
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newCDCInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = i.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Unknown channel")
+}
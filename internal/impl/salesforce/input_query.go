@@ -0,0 +1,297 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sqiFieldQuery        = "query"
+	sqiFieldBulk         = "bulk"
+	sqiFieldPollInterval = "poll_interval"
+	sqiFieldMaxRecords   = "max_records"
+)
+
+func queryInputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Executes a SOQL query against Salesforce and creates a message for each record returned.").
+		Description(`
+Once all records of the query have been consumed the input shuts down, which can be used alongside a `+"[`sequence`](/docs/components/inputs/sequence)"+` or `+"[`read_until`](/docs/components/inputs/read_until)"+` input in order to periodically execute a query.
+
+By default the query is executed with the REST API, where each message is a JSON object of the record including its `+"`attributes`"+`. Large queries can instead be executed as a Bulk API 2.0 query job by setting `+"`bulk` to `true`"+`, in which case each message is a JSON object of the string values of the record.
+
+### Authentication
+
+Requests are authenticated with access tokens obtained via the [OAuth 2.0 client credentials flow](https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_client_credentials_flow.htm) of a connected app.`).
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringField(sqiFieldQuery).
+				Description("The SOQL query to execute.").
+				Example("SELECT Id, Name, LastModifiedDate FROM Account"),
+			service.NewBoolField(sqiFieldBulk).
+				Description("Whether to execute the query as a Bulk API 2.0 query job, which is recommended for queries that return many records.").
+				Default(false),
+			service.NewDurationField(sqiFieldPollInterval).
+				Description("The interval at which the state of a bulk query job is polled until it has completed.").
+				Default("5s").
+				Advanced(),
+			service.NewIntField(sqiFieldMaxRecords).
+				Description("The maximum number of records to obtain for each page of results of a bulk query job.").
+				Default(10000).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Account Export", "All accounts are queried with the Bulk API and written to a file as lines of JSON, after which Benthos shuts down.", `
+input:
+  salesforce_query:
+    login_url: https://mycompany.my.salesforce.com
+    client_id: ${SALESFORCE_CLIENT_ID}
+    client_secret: ${SALESFORCE_CLIENT_SECRET}
+    query: SELECT Id, Name, Industry FROM Account
+    bulk: true
+
+output:
+  file:
+    path: ./accounts.jsonl
+    codec: lines
+`)
+}
+
+func init() {
+	err := service.RegisterInput("salesforce_query", queryInputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newQueryInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type queryInput struct {
+	client       *sfClient
+	query        string
+	bulk         bool
+	pollInterval time.Duration
+	maxRecords   int
+
+	mut     sync.Mutex
+	started bool
+	pending []any
+	nextFn  func(ctx context.Context) ([]any, error)
+
+	log *service.Logger
+}
+
+func newQueryInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*queryInput, error) {
+	q := &queryInput{log: mgr.Logger()}
+
+	var err error
+	if q.client, err = clientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if q.query, err = conf.FieldString(sqiFieldQuery); err != nil {
+		return nil, err
+	}
+	if q.bulk, err = conf.FieldBool(sqiFieldBulk); err != nil {
+		return nil, err
+	}
+	if q.pollInterval, err = conf.FieldDuration(sqiFieldPollInterval); err != nil {
+		return nil, err
+	}
+	if q.maxRecords, err = conf.FieldInt(sqiFieldMaxRecords); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *queryInput) Connect(ctx context.Context) error {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	if q.started {
+		return nil
+	}
+	if err := q.client.authenticate(ctx); err != nil {
+		return err
+	}
+
+	if q.bulk {
+		nextFn, err := q.startBulkQuery(ctx)
+		if err != nil {
+			return err
+		}
+		q.nextFn = nextFn
+	} else {
+		q.nextFn = q.restQuery()
+	}
+	q.started = true
+	return nil
+}
+
+// restQuery returns a func that obtains each page of records of a query via
+// the REST API, returning nil once all pages are consumed.
+func (q *queryInput) restQuery() func(ctx context.Context) ([]any, error) {
+	nextURL := q.client.dataPath("query") + "?q=" + url.QueryEscape(q.query)
+	return func(ctx context.Context) ([]any, error) {
+		if nextURL == "" {
+			return nil, nil
+		}
+		var res struct {
+			Done           bool   `json:"done"`
+			NextRecordsURL string `json:"nextRecordsUrl"`
+			Records        []any  `json:"records"`
+		}
+		if err := q.client.doJSON(ctx, http.MethodGet, nextURL, nil, &res); err != nil {
+			return nil, err
+		}
+		nextURL = ""
+		if !res.Done {
+			nextURL = res.NextRecordsURL
+		}
+		if res.Records == nil {
+			res.Records = []any{}
+		}
+		return res.Records, nil
+	}
+}
+
+// startBulkQuery creates a bulk query job and waits for it to complete,
+// returning a func that obtains each page of the results.
+func (q *queryInput) startBulkQuery(ctx context.Context) (func(ctx context.Context) ([]any, error), error) {
+	var job struct {
+		ID           string `json:"id"`
+		State        string `json:"state"`
+		ErrorMessage string `json:"errorMessage"`
+	}
+	if err := q.client.doJSON(ctx, http.MethodPost, q.client.dataPath("jobs", "query"), map[string]any{
+		"operation": "query",
+		"query":     q.query,
+	}, &job); err != nil {
+		return nil, fmt.Errorf("failed to create bulk query job: %w", err)
+	}
+	q.log.Debugf("Created bulk query job %v", job.ID)
+
+	for job.State != "JobComplete" {
+		switch job.State {
+		case "Failed", "Aborted":
+			return nil, fmt.Errorf("bulk query job %v finished in state %v: %v", job.ID, job.State, job.ErrorMessage)
+		}
+		select {
+		case <-time.After(q.pollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if err := q.client.doJSON(ctx, http.MethodGet, q.client.dataPath("jobs", "query", job.ID), nil, &job); err != nil {
+			return nil, fmt.Errorf("failed to obtain state of bulk query job: %w", err)
+		}
+	}
+
+	jobID, locator, done := job.ID, "", false
+	return func(ctx context.Context) ([]any, error) {
+		if done {
+			return nil, nil
+		}
+
+		resultsPath := q.client.dataPath("jobs", "query", jobID, "results") + "?maxRecords=" + strconv.Itoa(q.maxRecords)
+		if locator != "" {
+			resultsPath += "&locator=" + url.QueryEscape(locator)
+		}
+		res, err := q.client.do(ctx, http.MethodGet, resultsPath, "", nil, http.Header{"Accept": []string{"text/csv"}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to obtain bulk query results: %w", err)
+		}
+		defer res.Body.Close()
+
+		if locator = res.Header.Get("Sforce-Locator"); locator == "" || locator == "null" {
+			done = true
+		}
+		records, err := csvRecords(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse bulk query results: %w", err)
+		}
+		return records, nil
+	}, nil
+}
+
+// csvRecords parses CSV with a header row into a slice of objects.
+func csvRecords(r io.Reader) ([]any, error) {
+	rdr := csv.NewReader(r)
+	header, err := rdr.Read()
+	if errors.Is(err, io.EOF) {
+		return []any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := []any{}
+	for {
+		row, err := rdr.Read()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		obj := make(map[string]any, len(header))
+		for i, k := range header {
+			if i < len(row) {
+				obj[k] = row[i]
+			}
+		}
+		records = append(records, obj)
+	}
+}
+
+func (q *queryInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	if q.nextFn == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	for len(q.pending) == 0 {
+		records, err := q.nextFn(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if records == nil {
+			return nil, nil, service.ErrEndOfInput
+		}
+		q.pending = records
+	}
+
+	record := q.pending[0]
+	q.pending = q.pending[1:]
+
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service.NewMessage(recordBytes), func(ctx context.Context, err error) error {
+		return nil
+	}, nil
+}
+
+func (q *queryInput) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,133 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func readAllRecords(t *testing.T, i *queryInput) []string {
+	t.Helper()
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, i.Connect(ctx))
+
+	var records []string
+	for {
+		msg, ackFn, err := i.Read(ctx)
+		if errors.Is(err, service.ErrEndOfInput) {
+			return records
+		}
+		require.NoError(t, err)
+		mBytes, err := msg.AsBytes()
+		require.NoError(t, err)
+		records = append(records, string(mBytes))
+		require.NoError(t, ackFn(ctx, nil))
+	}
+}
+
+func TestQueryInputREST(t *testing.T) {
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data/v60.0/query":
+			assert.Equal(t, "SELECT Id FROM Account", r.URL.Query().Get("q"))
+			_, _ = w.Write([]byte(`{"done":false,"nextRecordsUrl":"/services/data/v60.0/query/01g-2000","records":[{"Id":"a"},{"Id":"b"}]}`))
+		case "/services/data/v60.0/query/01g-2000":
+			_, _ = w.Write([]byte(`{"done":true,"records":[{"Id":"c"}]}`))
+		default:
+			t.Errorf("unexpected path: %v", r.URL.Path)
+		}
+	})
+
+	conf, err := queryInputSpec().ParseYAML(testClientConfig(ts.URL)+`
+query: SELECT Id FROM Account
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newQueryInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{`{"Id":"a"}`, `{"Id":"b"}`, `{"Id":"c"}`}, readAllRecords(t, i))
+	require.NoError(t, i.Close(context.Background()))
+}
+
+func TestQueryInputBulk(t *testing.T) {
+	var polls int32
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/data/v60.0/jobs/query":
+			assert.Equal(t, http.MethodPost, r.Method)
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, map[string]any{"operation": "query", "query": "SELECT Id, Name FROM Account"}, body)
+			_, _ = w.Write([]byte(`{"id":"750R","state":"UploadComplete"}`))
+		case "/services/data/v60.0/jobs/query/750R":
+			state := "InProgress"
+			if atomic.AddInt32(&polls, 1) > 1 {
+				state = "JobComplete"
+			}
+			_, _ = w.Write([]byte(`{"id":"750R","state":"` + state + `"}`))
+		case "/services/data/v60.0/jobs/query/750R/results":
+			assert.Equal(t, "text/csv", r.Header.Get("Accept"))
+			assert.Equal(t, "2", r.URL.Query().Get("maxRecords"))
+			if r.URL.Query().Get("locator") == "" {
+				w.Header().Set("Sforce-Locator", "MjAwMDAw")
+				_, _ = w.Write([]byte("\"Id\",\"Name\"\n\"a\",\"Foo\"\n\"b\",\"Bar, Baz\"\n"))
+				return
+			}
+			assert.Equal(t, "MjAwMDAw", r.URL.Query().Get("locator"))
+			w.Header().Set("Sforce-Locator", "null")
+			_, _ = w.Write([]byte("\"Id\",\"Name\"\n\"c\",\"\"\n"))
+		default:
+			t.Errorf("unexpected path: %v", r.URL.Path)
+		}
+	})
+
+	conf, err := queryInputSpec().ParseYAML(testClientConfig(ts.URL)+`
+query: SELECT Id, Name FROM Account
+bulk: true
+poll_interval: 1ms
+max_records: 2
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newQueryInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`{"Id":"a","Name":"Foo"}`,
+		`{"Id":"b","Name":"Bar, Baz"}`,
+		`{"Id":"c","Name":""}`,
+	}, readAllRecords(t, i))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&polls))
+}
+
+func TestQueryInputBulkFailed(t *testing.T) {
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"750R","state":"Failed","errorMessage":"INVALID_FIELD: No such column 'Nope'"}`))
+	})
+
+	conf, err := queryInputSpec().ParseYAML(testClientConfig(ts.URL)+`
+query: SELECT Nope FROM Account
+bulk: true
+`, nil)
+	require.NoError(t, err)
+
+	i, err := newQueryInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	err = i.Connect(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "No such column")
+}
@@ -0,0 +1,298 @@
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	sboFieldObject          = "object"
+	sboFieldOperation       = "operation"
+	sboFieldExternalIDField = "external_id_field"
+	sboFieldWaitForResults  = "wait_for_results"
+	sboFieldPollInterval    = "poll_interval"
+	sboFieldBatching        = "batching"
+)
+
+func bulkOutputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Writes records to a Salesforce object with Bulk API 2.0 ingest jobs.").
+		Description(`
+Each message must be a JSON object where the keys are field names of the object. A new ingest job is created for each batch of messages, where the records are uploaded as CSV data. Fields that are explicitly set to `+"`null`"+` are cleared, whereas fields that are missing from a record are left unchanged.
+
+When `+"`wait_for_results`"+` is enabled the output waits for each job to complete, and a batch is only acknowledged once all records have been processed successfully. Otherwise batches are acknowledged as soon as their job has been queued for processing.
+
+### Authentication
+
+Requests are authenticated with access tokens obtained via the [OAuth 2.0 client credentials flow](https://help.salesforce.com/s/articleView?id=sf.remoteaccess_oauth_client_credentials_flow.htm) of a connected app.
+
+## Performance
+
+This output benefits from sending multiple messages in flight in parallel for improved performance. You can tune the max number of in flight messages (or message batches) with the field `+"`max_in_flight`"+`.
+
+This output benefits from sending messages as a batch for improved performance. Batches can be formed at both the input and output level. You can find out more [in this doc](/docs/configuration/batching).
+`).
+		Fields(clientFields()...).
+		Fields(
+			service.NewStringField(sboFieldObject).
+				Description("The name of the object to write records to.").
+				Examples("Account", "Contact", "Invoice__c"),
+			service.NewStringAnnotatedEnumField(sboFieldOperation, map[string]string{
+				"insert":     "Insert new records.",
+				"update":     "Update existing records, where each record must include an `Id` field.",
+				"upsert":     "Insert or update records based on the field `external_id_field`.",
+				"delete":     "Delete records, where each record must include an `Id` field.",
+				"hardDelete": "Delete records permanently, bypassing the recycle bin.",
+			}).
+				Description("The operation to perform for each record.").
+				Default("upsert"),
+			service.NewStringField(sboFieldExternalIDField).
+				Description("The external ID field used to match records when performing upserts.").
+				Example("External_Id__c").
+				Default("Id"),
+			service.NewBoolField(sboFieldWaitForResults).
+				Description("Whether to wait for each ingest job to complete before acknowledging the batch.").
+				Default(true),
+			service.NewDurationField(sboFieldPollInterval).
+				Description("The interval at which the state of an ingest job is polled whilst waiting for it to complete.").
+				Default("5s").
+				Advanced(),
+			service.NewOutputMaxInFlightField().Default(4),
+			service.NewBatchPolicyField(sboFieldBatching),
+		).
+		Example("Warehouse to CRM", "Customer records are upserted into Salesforce accounts, matched by an external ID.", `
+output:
+  salesforce_bulk:
+    login_url: https://mycompany.my.salesforce.com
+    client_id: ${SALESFORCE_CLIENT_ID}
+    client_secret: ${SALESFORCE_CLIENT_SECRET}
+    object: Account
+    operation: upsert
+    external_id_field: Customer_Id__c
+    batching:
+      count: 10000
+      period: 30s
+  processors:
+    - mapping: |
+        root.Customer_Id__c = this.id
+        root.Name = this.name
+        root.Industry = this.industry
+`)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("salesforce_bulk", bulkOutputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if batchPolicy, err = conf.FieldBatchPolicy(sboFieldBatching); err != nil {
+				return
+			}
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newBulkOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type bulkOutput struct {
+	client          *sfClient
+	object          string
+	operation       string
+	externalIDField string
+	waitForResults  bool
+	pollInterval    time.Duration
+
+	log *service.Logger
+}
+
+func newBulkOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*bulkOutput, error) {
+	b := &bulkOutput{log: mgr.Logger()}
+
+	var err error
+	if b.client, err = clientFromParsed(conf); err != nil {
+		return nil, err
+	}
+	if b.object, err = conf.FieldString(sboFieldObject); err != nil {
+		return nil, err
+	}
+	if b.operation, err = conf.FieldString(sboFieldOperation); err != nil {
+		return nil, err
+	}
+	if b.externalIDField, err = conf.FieldString(sboFieldExternalIDField); err != nil {
+		return nil, err
+	}
+	if b.waitForResults, err = conf.FieldBool(sboFieldWaitForResults); err != nil {
+		return nil, err
+	}
+	if b.pollInterval, err = conf.FieldDuration(sboFieldPollInterval); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *bulkOutput) Connect(ctx context.Context) error {
+	return b.client.authenticate(ctx)
+}
+
+// csvValue formats a field value for Bulk API CSV data, where `#N/A` clears
+// the field.
+func csvValue(v any) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "#N/A", nil
+	case string:
+		return t, nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case json.Number:
+		return t.String(), nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case uint64:
+		return strconv.FormatUint(t, 10), nil
+	case int:
+		return strconv.Itoa(t), nil
+	}
+	vBytes, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(vBytes), nil
+}
+
+// batchToCSV encodes a batch of JSON object messages as CSV data, with a
+// column for each field found within any of the messages.
+func batchToCSV(batch service.MessageBatch) ([]byte, error) {
+	records := make([]map[string]any, 0, len(batch))
+	columnSet := map[string]struct{}{}
+	for i, msg := range batch {
+		v, err := msg.AsStructured()
+		if err != nil {
+			return nil, fmt.Errorf("message %v: %w", i, err)
+		}
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("message %v: expected an object, got %T", i, v)
+		}
+		for k := range obj {
+			columnSet[k] = struct{}{}
+		}
+		records = append(records, obj)
+	}
+
+	columns := make([]string, 0, len(columnSet))
+	for k := range columnSet {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	row := make([]string, len(columns))
+	for i, obj := range records {
+		for j, k := range columns {
+			v, exists := obj[k]
+			if !exists {
+				row[j] = ""
+				continue
+			}
+			var err error
+			if row[j], err = csvValue(v); err != nil {
+				return nil, fmt.Errorf("message %v: %w", i, err)
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+type ingestJob struct {
+	ID                     string `json:"id"`
+	State                  string `json:"state"`
+	ErrorMessage           string `json:"errorMessage"`
+	NumberRecordsProcessed int    `json:"numberRecordsProcessed"`
+	NumberRecordsFailed    int    `json:"numberRecordsFailed"`
+}
+
+func (b *bulkOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	csvData, err := batchToCSV(batch)
+	if err != nil {
+		return err
+	}
+
+	jobReq := map[string]any{
+		"object":      b.object,
+		"operation":   b.operation,
+		"contentType": "CSV",
+		"lineEnding":  "LF",
+	}
+	if b.operation == "upsert" {
+		jobReq["externalIdFieldName"] = b.externalIDField
+	}
+
+	var job ingestJob
+	if err := b.client.doJSON(ctx, http.MethodPost, b.client.dataPath("jobs", "ingest"), jobReq, &job); err != nil {
+		return fmt.Errorf("failed to create ingest job: %w", err)
+	}
+
+	res, err := b.client.do(ctx, http.MethodPut, b.client.dataPath("jobs", "ingest", job.ID, "batches"), "text/csv", csvData, nil)
+	if err != nil {
+		_ = b.client.doJSON(ctx, http.MethodPatch, b.client.dataPath("jobs", "ingest", job.ID), map[string]any{"state": "Aborted"}, nil)
+		return fmt.Errorf("failed to upload data to ingest job %v: %w", job.ID, err)
+	}
+	_ = res.Body.Close()
+
+	if err := b.client.doJSON(ctx, http.MethodPatch, b.client.dataPath("jobs", "ingest", job.ID), map[string]any{"state": "UploadComplete"}, &job); err != nil {
+		return fmt.Errorf("failed to close ingest job %v: %w", job.ID, err)
+	}
+	if !b.waitForResults {
+		return nil
+	}
+
+	for {
+		switch job.State {
+		case "JobComplete":
+			if job.NumberRecordsFailed > 0 {
+				return fmt.Errorf("ingest job %v failed to process %v of %v records", job.ID, job.NumberRecordsFailed, len(batch))
+			}
+			return nil
+		case "Failed", "Aborted":
+			return fmt.Errorf("ingest job %v finished in state %v: %v", job.ID, job.State, job.ErrorMessage)
+		}
+		select {
+		case <-time.After(b.pollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := b.client.doJSON(ctx, http.MethodGet, b.client.dataPath("jobs", "ingest", job.ID), nil, &job); err != nil {
+			return fmt.Errorf("failed to obtain state of ingest job %v: %w", job.ID, err)
+		}
+	}
+}
+
+func (b *bulkOutput) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,109 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestBatchToCSV(t *testing.T) {
+	csvData, err := batchToCSV(service.MessageBatch{
+		service.NewMessage([]byte(`{"Name":"Foo, Inc","Ext__c":"a","Employees":10}`)),
+		service.NewMessage([]byte(`{"Ext__c":"b","Active__c":true,"Industry":null}`)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `Active__c,Employees,Ext__c,Industry,Name
+,10,a,,"Foo, Inc"
+true,,b,#N/A,
+`, string(csvData))
+
+	_, err = batchToCSV(service.MessageBatch{
+		service.NewMessage([]byte(`["not","an","object"]`)),
+	})
+	require.Error(t, err)
+}
+
+func TestBulkOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var jobReq map[string]any
+	var uploaded string
+	var states []any
+	failed := 0
+
+	ts, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		switch r.Method + " " + r.URL.Path {
+		case "POST /services/data/v60.0/jobs/ingest":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&jobReq))
+			_, _ = w.Write([]byte(`{"id":"750J","state":"Open"}`))
+		case "PUT /services/data/v60.0/jobs/ingest/750J/batches":
+			assert.Equal(t, "text/csv", r.Header.Get("Content-Type"))
+			b, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			uploaded = string(b)
+			w.WriteHeader(http.StatusCreated)
+		case "PATCH /services/data/v60.0/jobs/ingest/750J":
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			states = append(states, body["state"])
+			_, _ = w.Write([]byte(`{"id":"750J","state":"UploadComplete"}`))
+		case "GET /services/data/v60.0/jobs/ingest/750J":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":                     "750J",
+				"state":                  "JobComplete",
+				"numberRecordsProcessed": 2,
+				"numberRecordsFailed":    failed,
+			})
+		default:
+			t.Errorf("unexpected request: %v %v", r.Method, r.URL.Path)
+		}
+	})
+
+	conf, err := bulkOutputSpec().ParseYAML(testClientConfig(ts.URL)+`
+object: Account
+external_id_field: Ext__c
+poll_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	o, err := newBulkOutputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, o.Connect(ctx))
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte(`{"Ext__c":"a","Name":"Foo"}`)),
+		service.NewMessage([]byte(`{"Ext__c":"b","Name":"Bar"}`)),
+	}
+	require.NoError(t, o.WriteBatch(ctx, batch))
+
+	reqMut.Lock()
+	assert.Equal(t, map[string]any{
+		"object":              "Account",
+		"operation":           "upsert",
+		"externalIdFieldName": "Ext__c",
+		"contentType":         "CSV",
+		"lineEnding":          "LF",
+	}, jobReq)
+	assert.Equal(t, "Ext__c,Name\na,Foo\nb,Bar\n", uploaded)
+	assert.Equal(t, []any{"UploadComplete"}, states)
+	failed = 1
+	reqMut.Unlock()
+
+	err = o.WriteBatch(ctx, batch)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to process 1 of 2 records")
+
+	require.NoError(t, o.Close(ctx))
+}
@@ -0,0 +1,239 @@
+package serial
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	siFieldPath     = "path"
+	siFieldBaudRate = "baud_rate"
+	siFieldDataBits = "data_bits"
+	siFieldParity   = "parity"
+	siFieldStopBits = "stop_bits"
+	siFieldRTSCTS   = "rts_cts"
+	siFieldScanner  = "scanner"
+
+	parityNone = "none"
+	parityEven = "even"
+	parityOdd  = "odd"
+)
+
+type portConfig struct {
+	baudRate int
+	dataBits int
+	parity   string
+	stopBits int
+	rtsCts   bool
+}
+
+func inputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Local").
+		Version("4.28.0").
+		Summary("Reads data from a serial port, chopping it into individual messages according to the specified scanner.").
+		Description(`
+The port is opened in raw mode with the configured line settings, which must match those of the device, and is opened again when it is lost, such as when a USB adapter is unplugged. RS-485 adapters that are exposed as serial ports can be read in the same way.
+
+Data arriving over a serial port is framed either by a delimiter, with the `+"[`lines` scanner](/docs/components/scanners/lines)"+` and a custom delimiter, by a fixed size with the `+"[`chunker` scanner](/docs/components/scanners/chunker)"+`, or by a length prefix with the `+"[`length_prefixed` scanner](/docs/components/scanners/length_prefixed)"+`.
+
+Serial ports are currently only supported on Linux.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- serial_path
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewStringField(siFieldPath).
+				Description("The path of the serial port.").
+				Examples("/dev/ttyUSB0", "/dev/serial/by-id/usb-FTDI_FT232R_USB_UART_A50285BI-if00-port0"),
+			service.NewIntField(siFieldBaudRate).
+				Description("The baud rate of the port, which must be a standard rate.").
+				Default(9600),
+			service.NewIntField(siFieldDataBits).
+				Description("The number of data bits of each character, between 5 and 8.").
+				Default(8),
+			service.NewStringEnumField(siFieldParity, parityNone, parityEven, parityOdd).
+				Description("The parity of each character.").
+				Default(parityNone),
+			service.NewIntField(siFieldStopBits).
+				Description("The number of stop bits of each character, either 1 or 2.").
+				Default(1),
+			service.NewBoolField(siFieldRTSCTS).
+				Description("Whether to enable RTS/CTS hardware flow control.").
+				Default(false).
+				Advanced(),
+			service.NewScannerField(siFieldScanner).
+				Description("The [scanner](/docs/components/scanners/about) by which the stream of bytes read from the port is broken out into individual messages.").
+				Default(map[string]any{"lines": map[string]any{}}),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Carriage Return Delimited Readings", "Reads readings from a sensor that terminates each of them with a carriage return.", `
+input:
+  serial:
+    path: /dev/ttyUSB0
+    baud_rate: 115200
+    scanner:
+      lines:
+        custom_delimiter: "\r"
+`).
+		Example("Length Prefixed Frames", "Reads binary frames from an RS-485 adapter that are each preceded by their length as a 16 bit integer.", `
+input:
+  serial:
+    path: /dev/ttyUSB0
+    baud_rate: 19200
+    parity: even
+    scanner:
+      length_prefixed:
+        prefix_size: 2
+`)
+}
+
+func init() {
+	err := service.RegisterBatchInput("serial", inputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
+			i, err := newSerialInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksBatchedToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type serialInput struct {
+	path        string
+	port        portConfig
+	scannerCtor *service.OwnedScannerCreator
+
+	scannerMut sync.Mutex
+	scanner    *service.OwnedScanner
+
+	log *service.Logger
+}
+
+func newSerialInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*serialInput, error) {
+	s := &serialInput{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if s.path, err = conf.FieldString(siFieldPath); err != nil {
+		return nil, err
+	}
+	if s.port.baudRate, err = conf.FieldInt(siFieldBaudRate); err != nil {
+		return nil, err
+	}
+	if s.port.dataBits, err = conf.FieldInt(siFieldDataBits); err != nil {
+		return nil, err
+	}
+	if s.port.dataBits < 5 || s.port.dataBits > 8 {
+		return nil, fmt.Errorf("data bits must be between 5 and 8, got %v", s.port.dataBits)
+	}
+	if s.port.parity, err = conf.FieldString(siFieldParity); err != nil {
+		return nil, err
+	}
+	if s.port.stopBits, err = conf.FieldInt(siFieldStopBits); err != nil {
+		return nil, err
+	}
+	if s.port.stopBits != 1 && s.port.stopBits != 2 {
+		return nil, fmt.Errorf("stop bits must be 1 or 2, got %v", s.port.stopBits)
+	}
+	if s.port.rtsCts, err = conf.FieldBool(siFieldRTSCTS); err != nil {
+		return nil, err
+	}
+	if s.scannerCtor, err = conf.FieldScanner(siFieldScanner); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *serialInput) Connect(ctx context.Context) error {
+	s.scannerMut.Lock()
+	defer s.scannerMut.Unlock()
+
+	if s.scanner != nil {
+		return nil
+	}
+
+	f, err := openPort(s.path, s.port)
+	if err != nil {
+		return err
+	}
+
+	details := service.NewScannerSourceDetails()
+	details.SetName(s.path)
+	if s.scanner, err = s.scannerCtor.Create(f, func(ctx context.Context, err error) error {
+		return nil
+	}, details); err != nil {
+		_ = f.Close()
+		return err
+	}
+	s.log.Infof("Reading from serial port %v", s.path)
+	return nil
+}
+
+func (s *serialInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	s.scannerMut.Lock()
+	scanner := s.scanner
+	s.scannerMut.Unlock()
+
+	if scanner == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	batch, codecAckFn, err := scanner.NextBatch(ctx)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, nil, err
+		}
+
+		// The port is opened again after any other error, such as when the
+		// device was disconnected.
+		s.scannerMut.Lock()
+		if s.scanner == scanner {
+			_ = s.scanner.Close(ctx)
+			s.scanner = nil
+		}
+		s.scannerMut.Unlock()
+		if errors.Is(err, io.EOF) {
+			return nil, nil, service.ErrNotConnected
+		}
+		return nil, nil, err
+	}
+
+	// Rejected messages are retried downstream so there's no benefit to
+	// aggregating acks.
+	_ = codecAckFn(ctx, nil)
+
+	for _, msg := range batch {
+		msg.MetaSetMut("serial_path", s.path)
+	}
+	return batch, func(context.Context, error) error {
+		return nil
+	}, nil
+}
+
+func (s *serialInput) Close(ctx context.Context) (err error) {
+	s.scannerMut.Lock()
+	defer s.scannerMut.Unlock()
+
+	if s.scanner != nil {
+		err = s.scanner.Close(ctx)
+		s.scanner = nil
+	}
+	return
+}
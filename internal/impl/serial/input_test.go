@@ -0,0 +1,168 @@
+//go:build linux
+
+package serial
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/internal/impl/pure"
+)
+
+// testPTY opens a pseudo terminal, returning its master and the path of its
+// slave, which behaves as a serial port.
+func testPTY(t testing.TB) (*os.File, string) {
+	t.Helper()
+
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("Pseudo terminals are unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = master.Close() })
+
+	fd := int(master.Fd())
+	require.NoError(t, unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0))
+	n, err := unix.IoctlGetUint32(fd, unix.TIOCGPTN)
+	require.NoError(t, err)
+	return master, "/dev/pts/" + strconv.FormatUint(uint64(n), 10)
+}
+
+func testInput(t testing.TB, confStr string) *serialInput {
+	t.Helper()
+
+	conf, err := inputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newSerialInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	return i
+}
+
+func TestInputDelimited(t *testing.T) {
+	master, path := testPTY(t)
+
+	i := testInput(t, `
+path: `+path+`
+baud_rate: 115200
+stop_bits: 2
+scanner:
+  lines:
+    custom_delimiter: "\r"
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	require.NoError(t, i.Connect(ctx))
+
+	// The settings of a terminal are shared by each file that opens it,
+	// although pseudo terminals always use eight data bits without parity.
+	slave, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	require.NoError(t, err)
+	defer slave.Close()
+
+	term, err := unix.IoctlGetTermios(int(slave.Fd()), unix.TCGETS)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(unix.B115200), term.Cflag&unix.CBAUD)
+	assert.Equal(t, uint32(unix.CSTOPB), term.Cflag&unix.CSTOPB)
+	assert.Zero(t, term.Lflag&(unix.ICANON|unix.ECHO))
+
+	_, err = master.Write([]byte("first\rsecond\r"))
+	require.NoError(t, err)
+
+	for _, exp := range []string{"first", "second"} {
+		batch, ackFn, err := i.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.NoError(t, ackFn(ctx, nil))
+		require.Len(t, batch, 1)
+
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(b))
+
+		p, _ := batch[0].MetaGet("serial_path")
+		assert.Equal(t, path, p)
+	}
+
+	// Closing the input interrupts a pending read.
+	readErr := make(chan error, 1)
+	go func() {
+		_, _, err := i.ReadBatch(ctx)
+		readErr <- err
+	}()
+	time.Sleep(time.Millisecond * 50)
+	require.NoError(t, i.Close(ctx))
+	select {
+	case err := <-readErr:
+		require.Error(t, err)
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for read to end")
+	}
+}
+
+func TestInputLengthPrefixed(t *testing.T) {
+	master, path := testPTY(t)
+
+	i := testInput(t, `
+path: `+path+`
+scanner:
+  length_prefixed:
+    prefix_size: 1
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	require.NoError(t, i.Connect(ctx))
+
+	// Bytes that would be translated by a terminal in cooked mode are read
+	// as they are.
+	_, err := master.Write([]byte("\x03\x00\r\n\x02\x7f\x11"))
+	require.NoError(t, err)
+
+	for _, exp := range []string{"\x00\r\n", "\x7f\x11"} {
+		batch, _, err := i.ReadBatch(ctx)
+		require.NoError(t, err)
+		require.Len(t, batch, 1)
+
+		b, err := batch[0].AsBytes()
+		require.NoError(t, err)
+		assert.Equal(t, exp, string(b))
+	}
+}
+
+func TestInputErrors(t *testing.T) {
+	i := testInput(t, `path: /dev/does-not-exist`)
+	require.EqualError(t, i.Connect(context.Background()), "open /dev/does-not-exist: no such file or directory")
+
+	_, path := testPTY(t)
+	i = testInput(t, `
+path: `+path+`
+baud_rate: 12345
+`)
+	require.EqualError(t, i.Connect(context.Background()), "unsupported baud rate 12345")
+
+	for _, test := range []struct {
+		conf string
+		err  string
+	}{
+		{conf: "path: foo\ndata_bits: 9", err: "data bits must be between 5 and 8, got 9"},
+		{conf: "path: foo\nstop_bits: 3", err: "stop bits must be 1 or 2, got 3"},
+	} {
+		conf, err := inputSpec().ParseYAML(test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newSerialInputFromParsed(conf, service.MockResources())
+		assert.EqualError(t, err, test.err)
+	}
+}
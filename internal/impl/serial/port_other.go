@@ -0,0 +1,12 @@
+//go:build !linux
+
+package serial
+
+import (
+	"errors"
+	"os"
+)
+
+func openPort(path string, conf portConfig) (*os.File, error) {
+	return nil, errors.New("serial ports are only supported on linux")
+}
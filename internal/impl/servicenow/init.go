@@ -0,0 +1,23 @@
+package servicenow
+
+import (
+	_ "embed"
+
+	"github.com/benthosdev/benthos/v4/internal/bundle"
+	"github.com/benthosdev/benthos/v4/internal/template"
+)
+
+//go:embed template_table_input.yaml
+var tableInputTemplate []byte
+
+//go:embed template_table_output.yaml
+var tableOutputTemplate []byte
+
+func init() {
+	if err := template.RegisterTemplateYAML(bundle.GlobalEnvironment, tableInputTemplate); err != nil {
+		panic(err)
+	}
+	if err := template.RegisterTemplateYAML(bundle.GlobalEnvironment, tableOutputTemplate); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,244 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	soFieldBotToken = "bot_token"
+	soFieldChannel  = "channel"
+	soFieldThreadTS = "thread_ts"
+	soFieldMessage  = "message"
+	soFieldBaseURL  = "base_url"
+	soFieldRetries  = "retries"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services", "Social").
+		Version("4.28.0").
+		Summary("Posts messages to Slack channels using the Web API.").
+		Description(`
+Each message is posted to a channel with the [`+"`chat.postMessage`"+`](https://api.slack.com/methods/chat.postMessage) method of the Slack Web API, authenticated with the token of a bot that has the `+"`chat:write`"+` scope.
+
+The body of the request is constructed with the `+"`message`"+` mapping when it is set, or from the message itself otherwise. When the result is a JSON object it is sent as the body of the request, which allows the use of [blocks](https://api.slack.com/block-kit) and other message options, otherwise the result is sent as the text of the message.
+
+The channel and thread of each message can be set dynamically with interpolation functions, which allows routing messages based on their contents or metadata.
+
+### Rate Limiting and Retries
+
+Requests that fail with a rate limit error, a server error or a network error are retried according to the `+"`retries`"+` policy, where a rate limit error is retried once the period given by the `+"`Retry-After`"+` header has elapsed. Slack only allows posting roughly one message per second to each channel, and therefore increasing `+"`max_in_flight`"+` mostly benefits streams where messages are posted to many channels.`).
+		Fields(
+			service.NewStringField(soFieldBotToken).
+				Description("A bot token used for authentication.").
+				Secret(),
+			service.NewInterpolatedStringField(soFieldChannel).
+				Description("The channel to post messages to, which can be a channel ID or name.").
+				Examples("C0123456789", "#alerts", `${! @slack_channel }`),
+			service.NewInterpolatedStringField(soFieldThreadTS).
+				Description("An optional timestamp of a parent message, in which case messages are posted as replies within its thread. Messages where this field resolves to an empty string are posted to the channel.").
+				Example(`${! @slack_thread_ts }`).
+				Default(""),
+			service.NewBloblangField(soFieldMessage).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) used to construct the Slack message from each message, which results in either the text of the message or an object matching the arguments of `chat.postMessage`.").
+				Example(`root.text = "New order from %v".format(this.customer.name)`).
+				Optional(),
+			service.NewStringField(soFieldBaseURL).
+				Description("The base URL of the Slack Web API.").
+				Default("https://slack.com/api").
+				Advanced(),
+			service.NewRetryPolicyField(soFieldRetries),
+			service.NewOutputMaxInFlightField().Default(1),
+		).
+		Example("Alerting With Blocks", "Alerts are posted to a channel determined by their severity, with a header block followed by the alert description.", `
+output:
+  slack:
+    bot_token: ${SLACK_BOT_TOKEN}
+    channel: '${! if this.severity == "critical" { "#incidents" } else { "#alerts" } }'
+    message: |
+      root.text = this.title
+      root.blocks = [
+        { "type": "header", "text": { "type": "plain_text", "text": this.title } },
+        { "type": "section", "text": { "type": "mrkdwn", "text": this.description } }
+      ]
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("slack", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newSlackWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type slackWriter struct {
+	botToken string
+	channel  *service.InterpolatedString
+	threadTS *service.InterpolatedString
+	message  *bloblang.Executor
+	baseURL  string
+
+	client  *http.Client
+	retries *service.RetryPolicy
+	log     *service.Logger
+}
+
+func newSlackWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*slackWriter, error) {
+	s := &slackWriter{
+		client: &http.Client{Timeout: 30 * time.Second},
+		log:    mgr.Logger(),
+	}
+
+	var err error
+	if s.botToken, err = conf.FieldString(soFieldBotToken); err != nil {
+		return nil, err
+	}
+	if s.channel, err = conf.FieldInterpolatedString(soFieldChannel); err != nil {
+		return nil, err
+	}
+	if s.threadTS, err = conf.FieldInterpolatedString(soFieldThreadTS); err != nil {
+		return nil, err
+	}
+	if conf.Contains(soFieldMessage) {
+		if s.message, err = conf.FieldBloblang(soFieldMessage); err != nil {
+			return nil, err
+		}
+	}
+	if s.baseURL, err = conf.FieldString(soFieldBaseURL); err != nil {
+		return nil, err
+	}
+	s.baseURL = strings.TrimSuffix(s.baseURL, "/")
+	if s.retries, err = conf.FieldRetryPolicy(soFieldRetries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *slackWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+// messagePayload constructs the arguments of chat.postMessage from a message,
+// where non-object messages become the text of the Slack message.
+func messagePayload(msg *service.Message, mapping *bloblang.Executor) (map[string]any, error) {
+	if mapping != nil {
+		var err error
+		if msg, err = msg.BloblangQuery(mapping); err != nil {
+			return nil, fmt.Errorf("message mapping failed: %w", err)
+		}
+		if msg == nil {
+			return nil, errors.New("message mapping resulted in a deleted message")
+		}
+	}
+
+	if v, err := msg.AsStructured(); err == nil {
+		if obj, ok := v.(map[string]any); ok {
+			return obj, nil
+		}
+		if str, ok := v.(string); ok {
+			return map[string]any{"text": str}, nil
+		}
+	}
+
+	mBytes, err := msg.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"text": string(mBytes)}, nil
+}
+
+func (s *slackWriter) Write(ctx context.Context, msg *service.Message) error {
+	payload, err := messagePayload(msg, s.message)
+	if err != nil {
+		return err
+	}
+
+	if payload["channel"], err = s.channel.TryString(msg); err != nil {
+		return fmt.Errorf("channel interpolation: %w", err)
+	}
+	threadTS, err := s.threadTS.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("thread_ts interpolation: %w", err)
+	}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return s.retries.Do(ctx, func(ctx context.Context) error {
+		return s.post(ctx, body)
+	})
+}
+
+// post performs a chat.postMessage request.
+func (s *slackWriter) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/chat.postMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.botToken)
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := service.ParseRetryAfter(res.Header.Get("Retry-After"))
+		return service.NewErrBackOff(errors.New("rate limited by Slack"), retryAfter)
+	}
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody)))
+	}
+
+	var apiRes struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(resBody, &apiRes); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if !apiRes.OK {
+		err := fmt.Errorf("chat.postMessage failed: %v", apiRes.Error)
+		switch apiRes.Error {
+		case "internal_error", "fatal_error", "service_unavailable", "request_timeout":
+			return err
+		}
+		return service.NewErrRetryPermanent(err)
+	}
+	return nil
+}
+
+func (s *slackWriter) Close(ctx context.Context) error {
+	return nil
+}
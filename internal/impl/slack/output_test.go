@@ -0,0 +1,116 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestSlackOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var payloads []map[string]any
+	rateLimited := false
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/chat.postMessage", r.URL.Path)
+		assert.Equal(t, "Bearer xoxb-foo", r.Header.Get("Authorization"))
+
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		if !rateLimited {
+			rateLimited = true
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		var payload map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads = append(payloads, payload)
+
+		if payload["channel"] == "#nope" {
+			_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"ts":"1700000000.000100"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+bot_token: xoxb-foo
+channel: ${! @channel }
+thread_ts: ${! @thread.or("") }
+message: |
+  root = if this.type == "blocks" {
+    { "blocks": [ { "type": "section", "text": { "type": "mrkdwn", "text": this.text } } ] }
+  } else {
+    this.text
+  }
+base_url: %v
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	w, err := newSlackWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, w.Connect(ctx))
+
+	msg := service.NewMessage([]byte(`{"type":"text","text":"hello world"}`))
+	msg.MetaSetMut("channel", "#alerts")
+	require.NoError(t, w.Write(ctx, msg))
+
+	msg = service.NewMessage([]byte(`{"type":"blocks","text":"*bold*"}`))
+	msg.MetaSetMut("channel", "C0123")
+	msg.MetaSetMut("thread", "1700000000.000100")
+	require.NoError(t, w.Write(ctx, msg))
+
+	msg = service.NewMessage([]byte(`{"type":"text","text":"nope"}`))
+	msg.MetaSetMut("channel", "#nope")
+	err = w.Write(ctx, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel_not_found")
+
+	reqMut.Lock()
+	assert.Equal(t, []map[string]any{
+		{"channel": "#alerts", "text": "hello world"},
+		{
+			"channel":   "C0123",
+			"thread_ts": "1700000000.000100",
+			"blocks": []any{
+				map[string]any{"type": "section", "text": map[string]any{"type": "mrkdwn", "text": "*bold*"}},
+			},
+		},
+		{"channel": "#nope", "text": "nope"},
+	}, payloads)
+	reqMut.Unlock()
+
+	require.NoError(t, w.Close(ctx))
+}
+
+func TestSlackMessagePayload(t *testing.T) {
+	payload, err := messagePayload(service.NewMessage([]byte(`not json`)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"text": "not json"}, payload)
+
+	payload, err = messagePayload(service.NewMessage([]byte(`{"text":"hi","unfurl_links":false}`)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"text": "hi", "unfurl_links": false}, payload)
+
+	payload, err = messagePayload(service.NewMessage([]byte(`"quoted"`)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"text": "quoted"}, payload)
+}
@@ -0,0 +1,762 @@
+package snmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"github.com/gosnmp/gosnmp"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	siFieldVersion           = "version"
+	siFieldCommunity         = "community"
+	siFieldSecurity          = "security"
+	siFieldUserName          = "user_name"
+	siFieldAuthProtocol      = "auth_protocol"
+	siFieldAuthPassword      = "auth_password"
+	siFieldPrivProtocol      = "priv_protocol"
+	siFieldPrivPassword      = "priv_password"
+	siFieldContextName       = "context_name"
+	siFieldMIBPaths          = "mib_paths"
+	siFieldPoll              = "poll"
+	siFieldPollTargets       = "targets"
+	siFieldPollGet           = "get"
+	siFieldPollWalk          = "walk"
+	siFieldPollInterval      = "interval"
+	siFieldPollMaxRepetition = "max_repetitions"
+	siFieldTraps             = "traps"
+	siFieldTrapsAddress      = "address"
+	siFieldTimeout           = "timeout"
+	siFieldRetries           = "retries"
+)
+
+func inputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Network").
+		Version("4.28.0").
+		Summary("Polls objects from SNMP agents on an interval and receives SNMP traps and informs.").
+		Description(`
+This input supports SNMPv2c, and SNMPv3 with the User-based Security Model (USM). It can poll agents, receive notifications, or both at the same time.
+
+### Polling
+
+Each interval the objects listed in `+"`poll.get`"+` are retrieved from every target, along with all objects within the subtrees listed in `+"`poll.walk`"+`, and a message is emitted for each target containing the variables retrieved:
+
+`+"```json"+`
+{
+  "target": "10.0.0.1:161",
+  "variables": [
+    { "oid": "1.3.6.1.2.1.1.3.0", "name": "sysUpTime.0", "type": "TimeTicks", "value": 360012 },
+    { "oid": "1.3.6.1.2.1.31.1.1.1.6.1", "name": "ifHCInOctets.1", "type": "Counter64", "value": 4230582 }
+  ]
+}
+`+"```"+`
+
+Targets that fail to respond are logged and skipped until the next interval.
+
+### Traps
+
+When `+"`traps.address`"+` is set SNMPv2c traps and informs, and SNMPv3 traps, are received on that address and emitted as messages containing the trap OID, the uptime of the sender and the remaining variables:
+
+`+"```json"+`
+{
+  "source": "10.0.0.1:50123",
+  "trap_oid": "1.3.6.1.6.3.1.1.5.3",
+  "trap_name": "linkDown",
+  "uptime": 360012,
+  "variables": [
+    { "oid": "1.3.6.1.2.1.2.2.1.1.3", "name": "ifIndex.3", "type": "Integer", "value": 3 }
+  ]
+}
+`+"```"+`
+
+SNMPv2c notifications with a community other than `+"`community`"+` are ignored, as are SNMPv3 notifications that fail authentication. Informs are acknowledged once they have been received.
+
+### Object Names
+
+Objects can be referenced by numeric OIDs, such as `+"`1.3.6.1.2.1.1.3.0`"+`, or by name with an optional module prefix and instance suffix, such as `+"`IF-MIB::ifHCInOctets`"+` or `+"`sysUpTime.0`"+`. Names of the objects within the system and interfaces groups, the IF-MIB and SNMPv2-MIB notifications are built in, and further names can be loaded from MIB modules with `+"`mib_paths`"+`. Variables are also given a name resolved from the same MIBs, which is the numeric OID when no name is known.
+
+### Metadata
+
+This input adds the following metadata fields to each message:
+
+`+"```text"+`
+- snmp_kind (poll, trap or inform)
+- snmp_source
+`+"```"+`
+
+You can access these metadata fields using [function interpolation](/docs/configuration/interpolation#bloblang-queries).`).
+		Fields(
+			service.NewStringEnumField(siFieldVersion, "v2c", "v3").
+				Description("The version of SNMP to use.").
+				Default("v2c"),
+			service.NewStringField(siFieldCommunity).
+				Description("The community string of SNMPv2c requests and notifications.").
+				Secret().
+				Default("public"),
+			service.NewObjectField(siFieldSecurity,
+				service.NewStringField(siFieldUserName).
+					Description("The name of the user.").
+					Default(""),
+				service.NewStringEnumField(siFieldAuthProtocol, "none", "MD5", "SHA", "SHA224", "SHA256", "SHA384", "SHA512").
+					Description("The authentication protocol to use.").
+					Default("none"),
+				service.NewStringField(siFieldAuthPassword).
+					Description("The authentication password of the user.").
+					Secret().
+					Default(""),
+				service.NewStringEnumField(siFieldPrivProtocol, "none", "DES", "AES").
+					Description("The privacy protocol to encrypt messages with, which requires an authentication protocol.").
+					Default("none"),
+				service.NewStringField(siFieldPrivPassword).
+					Description("The privacy password of the user.").
+					Secret().
+					Default(""),
+				service.NewStringField(siFieldContextName).
+					Description("The context name of requests.").
+					Default("").
+					Advanced(),
+			).Description("SNMPv3 security settings."),
+			service.NewStringListField(siFieldMIBPaths).
+				Description("A list of MIB module files, or directories containing them, from which object names are loaded.").
+				Example([]string{"/usr/share/snmp/mibs"}).
+				Default([]string{}),
+			service.NewObjectField(siFieldPoll,
+				service.NewStringListField(siFieldPollTargets).
+					Description("The addresses of agents to poll, where the port defaults to 161.").
+					Example([]string{"10.0.0.1", "switch1.example.com:1161"}).
+					Default([]string{}),
+				service.NewStringListField(siFieldPollGet).
+					Description("Objects to retrieve from each target.").
+					Example([]string{"sysUpTime.0", "sysName.0"}).
+					Default([]string{}),
+				service.NewStringListField(siFieldPollWalk).
+					Description("Subtrees of objects to retrieve from each target.").
+					Example([]string{"ifHCInOctets", "ifHCOutOctets", "1.3.6.1.4.1.2021.10.1.3"}).
+					Default([]string{}),
+				service.NewDurationField(siFieldPollInterval).
+					Description("The period between polls.").
+					Default("60s"),
+				service.NewIntField(siFieldPollMaxRepetition).
+					Description("The maximum number of objects to request within each GetBulk request of a walk.").
+					Default(10).
+					Advanced(),
+			).Description("Settings for polling agents."),
+			service.NewObjectField(siFieldTraps,
+				service.NewStringField(siFieldTrapsAddress).
+					Description("The UDP address to receive notifications on, where an empty string disables receiving notifications.").
+					Example("0.0.0.0:162").
+					Default(""),
+			).Description("Settings for receiving notifications."),
+			service.NewDurationField(siFieldTimeout).
+				Description("The maximum period to wait for a response to each request.").
+				Default("5s").
+				Advanced(),
+			service.NewIntField(siFieldRetries).
+				Description("The number of times a request is retried after timing out.").
+				Default(2).
+				Advanced(),
+			service.NewAutoRetryNacksToggleField(),
+		).
+		Example("Interface Counters", "The uptime and traffic counters of each interface are polled from two switches every 30 seconds with SNMPv3, and written as one message per interface.", `
+input:
+  snmp:
+    version: v3
+    security:
+      user_name: monitor
+      auth_protocol: SHA256
+      auth_password: ${SNMP_AUTH_PASSWORD}
+      priv_protocol: AES
+      priv_password: ${SNMP_PRIV_PASSWORD}
+    poll:
+      targets: [ 10.0.0.1, 10.0.0.2 ]
+      get: [ sysUpTime.0 ]
+      walk: [ ifName, ifHCInOctets, ifHCOutOctets ]
+      interval: 30s
+  processors:
+    - mapping: |
+        let vars = this.variables.filter(v -> v.name.has_prefix("if")).map_each(v -> v.merge({
+          "index": v.name.split(".").index(1),
+          "metric": v.name.split(".").index(0),
+        }))
+        root = $vars.map_each(v -> v.index).unique().map_each(idx -> {
+          "target": this.target,
+          "interface": $vars.filter(v -> v.index == idx && v.metric == "ifName").index(0).value,
+          "in_octets": $vars.filter(v -> v.index == idx && v.metric == "ifHCInOctets").index(0).value,
+          "out_octets": $vars.filter(v -> v.index == idx && v.metric == "ifHCOutOctets").index(0).value,
+        })
+    - unarchive:
+        format: json_array
+`).
+		Example("Trap Receiver", "Traps sent by devices with the community `secret` are received and forwarded to Kafka.", `
+input:
+  snmp:
+    community: secret
+    traps:
+      address: 0.0.0.0:162
+
+output:
+  kafka:
+    addresses: [ localhost:9092 ]
+    topic: snmp_traps
+`)
+}
+
+var authProtocols = map[string]gosnmp.SnmpV3AuthProtocol{
+	"none":   gosnmp.NoAuth,
+	"MD5":    gosnmp.MD5,
+	"SHA":    gosnmp.SHA,
+	"SHA224": gosnmp.SHA224,
+	"SHA256": gosnmp.SHA256,
+	"SHA384": gosnmp.SHA384,
+	"SHA512": gosnmp.SHA512,
+}
+
+var privProtocols = map[string]gosnmp.SnmpV3PrivProtocol{
+	"none": gosnmp.NoPriv,
+	"DES":  gosnmp.DES,
+	"AES":  gosnmp.AES,
+}
+
+func init() {
+	err := service.RegisterInput("snmp", inputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+			i, err := newSNMPInputFromParsed(conf, mgr)
+			if err != nil {
+				return nil, err
+			}
+			return service.AutoRetryNacksToggled(conf, i)
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+// maxMessageSize is the largest SNMP message that fits within a UDP datagram.
+const maxMessageSize = 65507
+
+type snmpInput struct {
+	version     gosnmp.SnmpVersion
+	community   string
+	msgFlags    gosnmp.SnmpV3MsgFlags
+	usm         *gosnmp.UsmSecurityParameters
+	contextName string
+	timeout     time.Duration
+	retries     int
+	mib         *mibTree
+
+	targets        []string
+	gets           []string
+	walks          []string
+	interval       time.Duration
+	maxRepetitions int
+	trapAddress    string
+
+	connMut  sync.Mutex
+	clients  []*gosnmp.GoSNMP
+	trapConn net.PacketConn
+	msgChan  chan *service.Message
+	shutSig  *shutdown.Signaller
+
+	log *service.Logger
+}
+
+func newSNMPInputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*snmpInput, error) {
+	s := &snmpInput{
+		mib:     newMIBTree(),
+		msgChan: make(chan *service.Message),
+		shutSig: shutdown.NewSignaller(),
+		log:     mgr.Logger(),
+	}
+
+	versionStr, err := conf.FieldString(siFieldVersion)
+	if err != nil {
+		return nil, err
+	}
+	if s.community, err = conf.FieldString(siFieldCommunity); err != nil {
+		return nil, err
+	}
+	if versionStr == "v3" {
+		s.version = gosnmp.Version3
+		if err := s.securityFromParsed(conf.Namespace(siFieldSecurity)); err != nil {
+			return nil, err
+		}
+	} else {
+		s.version = gosnmp.Version2c
+	}
+	if s.timeout, err = conf.FieldDuration(siFieldTimeout); err != nil {
+		return nil, err
+	}
+	if s.retries, err = conf.FieldInt(siFieldRetries); err != nil {
+		return nil, err
+	}
+
+	mibPaths, err := conf.FieldStringList(siFieldMIBPaths)
+	if err != nil {
+		return nil, err
+	}
+	if len(mibPaths) > 0 {
+		unresolved, err := s.mib.loadMIBs(mibPaths)
+		if err != nil {
+			return nil, err
+		}
+		if len(unresolved) > 0 {
+			s.log.Debugf("Unable to resolve the parents of %v MIB objects: %v", len(unresolved), unresolved)
+		}
+	}
+
+	pollConf := conf.Namespace(siFieldPoll)
+	targets, err := pollConf.FieldStringList(siFieldPollTargets)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		if _, _, err := net.SplitHostPort(t); err != nil {
+			t = net.JoinHostPort(t, "161")
+		}
+		if _, _, err := splitTarget(t); err != nil {
+			return nil, fmt.Errorf("%v.%v: %w", siFieldPoll, siFieldPollTargets, err)
+		}
+		s.targets = append(s.targets, t)
+	}
+	for _, f := range []struct {
+		name string
+		dst  *[]string
+	}{
+		{siFieldPollGet, &s.gets},
+		{siFieldPollWalk, &s.walks},
+	} {
+		objs, err := pollConf.FieldStringList(f.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range objs {
+			oid, err := s.mib.resolve(o)
+			if err != nil {
+				return nil, fmt.Errorf("%v.%v: %w", siFieldPoll, f.name, err)
+			}
+			*f.dst = append(*f.dst, "."+formatOID(oid))
+		}
+	}
+	if len(s.targets) > 0 && len(s.gets) == 0 && len(s.walks) == 0 {
+		return nil, errors.New("polling requires at least one object to get or walk")
+	}
+	if s.interval, err = pollConf.FieldDuration(siFieldPollInterval); err != nil {
+		return nil, err
+	}
+	if s.interval <= 0 {
+		return nil, errors.New("poll interval must be greater than zero")
+	}
+	if s.maxRepetitions, err = pollConf.FieldInt(siFieldPollMaxRepetition); err != nil {
+		return nil, err
+	}
+	if s.maxRepetitions <= 0 {
+		return nil, errors.New("poll max repetitions must be greater than zero")
+	}
+
+	if s.trapAddress, err = conf.Namespace(siFieldTraps).FieldString(siFieldTrapsAddress); err != nil {
+		return nil, err
+	}
+	if len(s.targets) == 0 && s.trapAddress == "" {
+		return nil, errors.New("at least one poll target or a trap address must be set")
+	}
+	return s, nil
+}
+
+func (s *snmpInput) securityFromParsed(conf *service.ParsedConfig) error {
+	s.usm = &gosnmp.UsmSecurityParameters{}
+
+	var err error
+	if s.usm.UserName, err = conf.FieldString(siFieldUserName); err != nil {
+		return err
+	}
+	if s.usm.UserName == "" {
+		return errors.New("a user name is required for SNMPv3")
+	}
+
+	authStr, err := conf.FieldString(siFieldAuthProtocol)
+	if err != nil {
+		return err
+	}
+	s.usm.AuthenticationProtocol = authProtocols[authStr]
+	if s.usm.AuthenticationPassphrase, err = conf.FieldString(siFieldAuthPassword); err != nil {
+		return err
+	}
+
+	privStr, err := conf.FieldString(siFieldPrivProtocol)
+	if err != nil {
+		return err
+	}
+	s.usm.PrivacyProtocol = privProtocols[privStr]
+	if s.usm.PrivacyPassphrase, err = conf.FieldString(siFieldPrivPassword); err != nil {
+		return err
+	}
+
+	switch {
+	case s.usm.PrivacyProtocol != gosnmp.NoPriv:
+		if s.usm.AuthenticationProtocol == gosnmp.NoAuth {
+			return errors.New("privacy requires an authentication protocol")
+		}
+		s.msgFlags = gosnmp.AuthPriv
+	case s.usm.AuthenticationProtocol != gosnmp.NoAuth:
+		s.msgFlags = gosnmp.AuthNoPriv
+	default:
+		s.msgFlags = gosnmp.NoAuthNoPriv
+	}
+	if s.usm.AuthenticationProtocol != gosnmp.NoAuth && len(s.usm.AuthenticationPassphrase) < 8 {
+		return errors.New("the authentication password must be at least 8 characters")
+	}
+	if s.usm.PrivacyProtocol != gosnmp.NoPriv && len(s.usm.PrivacyPassphrase) < 8 {
+		return errors.New("the privacy password must be at least 8 characters")
+	}
+
+	s.contextName, err = conf.FieldString(siFieldContextName)
+	return err
+}
+
+func splitTarget(target string) (host string, port uint16, err error) {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return "", 0, err
+	}
+	p, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid port of target '%v'", target)
+	}
+	return host, uint16(p), nil
+}
+
+// newClient creates a client for a single agent. Clients perform one request
+// at a time and are therefore not shared between goroutines.
+func (s *snmpInput) newClient(ctx context.Context, target string) (*gosnmp.GoSNMP, error) {
+	host, port, err := splitTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	c := &gosnmp.GoSNMP{
+		Target:         host,
+		Port:           port,
+		Transport:      "udp",
+		Community:      s.community,
+		Version:        s.version,
+		Context:        ctx,
+		Timeout:        s.timeout,
+		Retries:        s.retries,
+		MaxRepetitions: uint32(s.maxRepetitions),
+	}
+	if s.version == gosnmp.Version3 {
+		c.SecurityModel = gosnmp.UserSecurityModel
+		c.MsgFlags = s.msgFlags
+		c.SecurityParameters = s.usm.Copy()
+		c.ContextName = s.contextName
+	}
+	if err := c.Connect(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *snmpInput) Connect(ctx context.Context) error {
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.clients != nil || s.trapConn != nil {
+		return nil
+	}
+
+	var clients []*gosnmp.GoSNMP
+	closeClients := func() {
+		for _, c := range clients {
+			_ = c.Conn.Close()
+		}
+	}
+	pollCtx, done := s.shutSig.SoftStopCtx(context.Background())
+	for _, t := range s.targets {
+		c, err := s.newClient(pollCtx, t)
+		if err != nil {
+			done()
+			closeClients()
+			return fmt.Errorf("target %v: %w", t, err)
+		}
+		clients = append(clients, c)
+	}
+
+	if s.trapAddress != "" {
+		conn, err := net.ListenPacket("udp", s.trapAddress)
+		if err != nil {
+			done()
+			closeClients()
+			return err
+		}
+		s.trapConn = conn
+		go s.receiveTraps(conn)
+	}
+
+	if len(clients) > 0 {
+		s.clients = clients
+		go func() {
+			defer done()
+			s.pollLoop(pollCtx, clients)
+		}()
+	} else {
+		done()
+	}
+	return nil
+}
+
+func (s *snmpInput) pollLoop(ctx context.Context, clients []*gosnmp.GoSNMP) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		var wg sync.WaitGroup
+		for _, c := range clients {
+			wg.Add(1)
+			go func(c *gosnmp.GoSNMP) {
+				defer wg.Done()
+				target := net.JoinHostPort(c.Target, strconv.Itoa(int(c.Port)))
+				msg, err := s.poll(c, target)
+				if err != nil {
+					if ctx.Err() == nil {
+						s.log.Errorf("Failed to poll %v: %v", target, err)
+					}
+					return
+				}
+				select {
+				case s.msgChan <- msg:
+				case <-ctx.Done():
+				}
+			}(c)
+		}
+		wg.Wait()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *snmpInput) poll(c *gosnmp.GoSNMP, target string) (*service.Message, error) {
+	var pdus []gosnmp.SnmpPDU
+	if len(s.gets) > 0 {
+		res, err := c.Get(s.gets)
+		if err != nil {
+			return nil, err
+		}
+		if res.Error != gosnmp.NoError {
+			return nil, fmt.Errorf("agent responded with error status %v at index %v", res.Error, res.ErrorIndex)
+		}
+		pdus = append(pdus, res.Variables...)
+	}
+	for _, root := range s.walks {
+		res, err := c.BulkWalkAll(root)
+		if err != nil {
+			oid, _ := parseOID(root)
+			return nil, fmt.Errorf("walk of %v: %w", s.mib.name(oid), err)
+		}
+		pdus = append(pdus, res...)
+	}
+
+	variables, err := s.structuredVariables(pdus)
+	if err != nil {
+		return nil, err
+	}
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(map[string]any{
+		"target":    target,
+		"variables": variables,
+	})
+	msg.MetaSetMut("snmp_kind", "poll")
+	msg.MetaSetMut("snmp_source", target)
+	return msg, nil
+}
+
+func (s *snmpInput) structuredVariables(pdus []gosnmp.SnmpPDU) ([]any, error) {
+	variables := make([]any, 0, len(pdus))
+	for _, p := range pdus {
+		oid, err := parseOID(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := structuredValue(p)
+		if err != nil {
+			return nil, fmt.Errorf("variable %v: %w", formatOID(oid), err)
+		}
+		typeName := typeNames[p.Type]
+		if typeName == "" {
+			typeName = fmt.Sprintf("Unknown(%#x)", byte(p.Type))
+		}
+		variables = append(variables, map[string]any{
+			"oid":   formatOID(oid),
+			"name":  s.mib.name(oid),
+			"type":  typeName,
+			"value": value,
+		})
+	}
+	return variables, nil
+}
+
+func (s *snmpInput) receiveTraps(conn net.PacketConn) {
+	// Notifications are decoded with the security parameters of the user,
+	// localised with the engine ID of each sender.
+	params := &gosnmp.GoSNMP{
+		Version:   s.version,
+		Community: s.community,
+	}
+	if s.version == gosnmp.Version3 {
+		params.SecurityModel = gosnmp.UserSecurityModel
+		params.MsgFlags = s.msgFlags
+		params.SecurityParameters = s.usm.Copy()
+	}
+
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !s.shutSig.IsSoftStopSignalled() {
+				s.log.Errorf("Failed to receive notification: %v", err)
+			}
+			return
+		}
+
+		msg, err := s.handleNotification(params, conn, addr, append([]byte(nil), buf[:n]...))
+		if err != nil {
+			s.log.Debugf("Ignoring notification from %v: %v", addr, err)
+			continue
+		}
+		select {
+		case s.msgChan <- msg:
+		case <-s.shutSig.SoftStopChan():
+			return
+		}
+	}
+}
+
+func (s *snmpInput) handleNotification(params *gosnmp.GoSNMP, conn net.PacketConn, addr net.Addr, b []byte) (*service.Message, error) {
+	p, err := params.UnmarshalTrap(b, false)
+	if err != nil {
+		return nil, err
+	}
+	if p.Version != s.version {
+		return nil, fmt.Errorf("unexpected SNMP version %v", p.Version)
+	}
+	if p.Version == gosnmp.Version2c && p.Community != s.community {
+		return nil, errors.New("community does not match")
+	}
+	if p.Version == gosnmp.Version3 {
+		sp, ok := p.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		if !ok || sp.UserName != s.usm.UserName {
+			return nil, errors.New("unknown user")
+		}
+		// Messages are only verified and decrypted according to their own
+		// flags, and therefore those with a lower security level than
+		// configured must be rejected.
+		if p.MsgFlags&gosnmp.AuthPriv != s.msgFlags {
+			return nil, errors.New("security level does not match the configured level")
+		}
+	}
+
+	kind := "trap"
+	switch p.PDUType {
+	case gosnmp.SNMPv2Trap:
+	case gosnmp.InformRequest:
+		if p.Version == gosnmp.Version3 {
+			return nil, errors.New("SNMPv3 informs are not supported")
+		}
+		kind = "inform"
+		res := *p
+		res.PDUType = gosnmp.GetResponse
+		res.Error = gosnmp.NoError
+		res.ErrorIndex = 0
+		resBytes, err := res.MarshalMsg()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := conn.WriteTo(resBytes, addr); err != nil {
+			s.log.Errorf("Failed to acknowledge inform from %v: %v", addr, err)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected pdu type %v", p.PDUType)
+	}
+
+	obj := map[string]any{"source": addr.String()}
+	var others []gosnmp.SnmpPDU
+	for _, v := range p.Variables {
+		oid, err := parseOID(v.Name)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case oidEqual(oid, oidSysUpTime):
+			if obj["uptime"], err = structuredValue(v); err != nil {
+				return nil, err
+			}
+		case oidEqual(oid, oidSnmpTrapOID):
+			oidStr, ok := v.Value.(string)
+			if !ok {
+				return nil, fmt.Errorf("unexpected trap OID value of type %T", v.Value)
+			}
+			trapOID, err := parseOID(oidStr)
+			if err != nil {
+				return nil, err
+			}
+			obj["trap_oid"] = formatOID(trapOID)
+			obj["trap_name"] = s.mib.name(trapOID)
+		default:
+			others = append(others, v)
+		}
+	}
+	if obj["variables"], err = s.structuredVariables(others); err != nil {
+		return nil, err
+	}
+
+	msg := service.NewMessage(nil)
+	msg.SetStructuredMut(obj)
+	msg.MetaSetMut("snmp_kind", kind)
+	msg.MetaSetMut("snmp_source", addr.String())
+	return msg, nil
+}
+
+func (s *snmpInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
+	s.connMut.Lock()
+	connected := s.clients != nil || s.trapConn != nil
+	s.connMut.Unlock()
+	if !connected {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	select {
+	case msg := <-s.msgChan:
+		return msg, func(context.Context, error) error { return nil }, nil
+	case <-s.shutSig.SoftStopChan():
+		return nil, nil, service.ErrEndOfInput
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (s *snmpInput) Close(ctx context.Context) error {
+	s.shutSig.TriggerSoftStop()
+
+	s.connMut.Lock()
+	defer s.connMut.Unlock()
+
+	if s.trapConn != nil {
+		_ = s.trapConn.Close()
+	}
+	for _, c := range s.clients {
+		_ = c.Conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,381 @@
+package snmp
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+// fakeAgent responds to SNMPv2c requests from a fixed set of objects.
+type fakeAgent struct {
+	t       testing.TB
+	conn    net.PacketConn
+	objects []gosnmp.SnmpPDU
+}
+
+func newFakeAgent(t testing.TB, objects []gosnmp.SnmpPDU) *fakeAgent {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	sort.Slice(objects, func(i, j int) bool {
+		return oidAfter(testOID(t, objects[j].Name), testOID(t, objects[i].Name))
+	})
+	a := &fakeAgent{
+		t:       t,
+		conn:    conn,
+		objects: objects,
+	}
+	go a.serve()
+	return a
+}
+
+func (a *fakeAgent) addr() string {
+	return a.conn.LocalAddr().String()
+}
+
+func (a *fakeAgent) serve() {
+	decoder := &gosnmp.GoSNMP{Version: gosnmp.Version2c}
+	buf := make([]byte, maxMessageSize)
+	for {
+		n, addr, err := a.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req, err := decoder.SnmpDecodePacket(buf[:n])
+		if err != nil {
+			a.t.Errorf("Failed to decode request: %v", err)
+			continue
+		}
+
+		res := &gosnmp.SnmpPacket{
+			Version:   req.Version,
+			Community: req.Community,
+			PDUType:   gosnmp.GetResponse,
+			RequestID: req.RequestID,
+		}
+		switch req.PDUType {
+		case gosnmp.GetRequest:
+			for _, v := range req.Variables {
+				res.Variables = append(res.Variables, a.get(v.Name))
+			}
+		case gosnmp.GetBulkRequest:
+			res.Variables = a.bulk(req.Variables[0].Name, int(req.MaxRepetitions))
+		}
+
+		b, err := res.MarshalMsg()
+		if err != nil {
+			a.t.Errorf("Failed to encode response: %v", err)
+			continue
+		}
+		_, _ = a.conn.WriteTo(b, addr)
+	}
+}
+
+func (a *fakeAgent) get(name string) gosnmp.SnmpPDU {
+	oid := testOID(a.t, name)
+	for _, v := range a.objects {
+		if oidEqual(testOID(a.t, v.Name), oid) {
+			return v
+		}
+	}
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.NoSuchObject}
+}
+
+func (a *fakeAgent) bulk(from string, maxRepetitions int) (pdus []gosnmp.SnmpPDU) {
+	fromOID := testOID(a.t, from)
+	for _, v := range a.objects {
+		if len(pdus) == maxRepetitions {
+			return
+		}
+		if oidAfter(testOID(a.t, v.Name), fromOID) {
+			pdus = append(pdus, v)
+		}
+	}
+	if len(pdus) < maxRepetitions {
+		pdus = append(pdus, gosnmp.SnmpPDU{Name: from, Type: gosnmp.EndOfMibView})
+	}
+	return
+}
+
+// oidAfter returns whether a is lexicographically after b.
+func oidAfter(a, b []uint32) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+	return len(a) > len(b)
+}
+
+func testOID(t testing.TB, s string) []uint32 {
+	t.Helper()
+	oid, err := parseOID(s)
+	require.NoError(t, err)
+	return oid
+}
+
+func testObjects() []gosnmp.SnmpPDU {
+	return []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(360012)},
+		{Name: ".1.3.6.1.2.1.1.5.0", Type: gosnmp.OctetString, Value: "switch1"},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.1.1", Type: gosnmp.OctetString, Value: "eth0"},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.1.2", Type: gosnmp.OctetString, Value: "eth1"},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.1.3", Type: gosnmp.OctetString, Value: "eth2"},
+		{Name: ".1.3.6.1.2.1.31.1.1.1.6.1", Type: gosnmp.Counter64, Value: uint64(1 << 40)},
+	}
+}
+
+func testInput(t testing.TB, confStr string) *snmpInput {
+	t.Helper()
+
+	conf, err := inputSpec().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	i, err := newSNMPInputFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = i.Close(context.Background())
+	})
+	return i
+}
+
+func TestInputPoll(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	agent := newFakeAgent(t, testObjects())
+	i := testInput(t, `
+community: foo
+poll:
+  targets: [ `+agent.addr()+` ]
+  get: [ sysUpTime.0, SNMPv2-MIB::sysName.0, sysLocation.0 ]
+  walk: [ ifName ]
+  max_repetitions: 2
+timeout: 1s
+retries: 0
+`)
+	require.NoError(t, i.Connect(ctx))
+
+	msg, ackFn, err := i.Read(ctx)
+	require.NoError(t, err)
+	require.NoError(t, ackFn(ctx, nil))
+
+	v, err := msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"target": agent.addr(),
+		"variables": []any{
+			map[string]any{"oid": "1.3.6.1.2.1.1.3.0", "name": "sysUpTime.0", "type": "TimeTicks", "value": uint64(360012)},
+			map[string]any{"oid": "1.3.6.1.2.1.1.5.0", "name": "sysName.0", "type": "OctetString", "value": "switch1"},
+			map[string]any{"oid": "1.3.6.1.2.1.1.6.0", "name": "sysLocation.0", "type": "NoSuchObject", "value": nil},
+			map[string]any{"oid": "1.3.6.1.2.1.31.1.1.1.1.1", "name": "ifName.1", "type": "OctetString", "value": "eth0"},
+			map[string]any{"oid": "1.3.6.1.2.1.31.1.1.1.1.2", "name": "ifName.2", "type": "OctetString", "value": "eth1"},
+			map[string]any{"oid": "1.3.6.1.2.1.31.1.1.1.1.3", "name": "ifName.3", "type": "OctetString", "value": "eth2"},
+		},
+	}, v)
+
+	kind, _ := msg.MetaGetMut("snmp_kind")
+	assert.Equal(t, "poll", kind)
+	source, _ := msg.MetaGetMut("snmp_source")
+	assert.Equal(t, agent.addr(), source)
+}
+
+func sendNotification(t testing.TB, to string, sender *gosnmp.GoSNMP, inform bool) *gosnmp.SnmpPacket {
+	t.Helper()
+
+	host, portStr, err := net.SplitHostPort(to)
+	require.NoError(t, err)
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	require.NoError(t, err)
+
+	sender.Target = host
+	sender.Port = uint16(port)
+	sender.Timeout = time.Second * 5
+	require.NoError(t, sender.Connect())
+	defer sender.Conn.Close()
+
+	res, err := sender.SendTrap(gosnmp.SnmpTrap{
+		Variables: []gosnmp.SnmpPDU{
+			{Name: ".1.3.6.1.2.1.1.3.0", Type: gosnmp.TimeTicks, Value: uint32(1200)},
+			{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.6.3.1.1.5.3"},
+			{Name: ".1.3.6.1.2.1.2.2.1.1.3", Type: gosnmp.Integer, Value: 3},
+		},
+		IsInform: inform,
+	})
+	require.NoError(t, err)
+	return res
+}
+
+func TestInputTrapsV2c(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	i := testInput(t, `
+community: foo
+traps:
+  address: 127.0.0.1:0
+`)
+	require.NoError(t, i.Connect(ctx))
+	addr := i.trapConn.LocalAddr().String()
+
+	// Traps from another community are ignored.
+	sendNotification(t, addr, &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "bar"}, false)
+
+	res := sendNotification(t, addr, &gosnmp.GoSNMP{Version: gosnmp.Version2c, Community: "foo"}, true)
+	assert.Equal(t, gosnmp.GetResponse, res.PDUType)
+	assert.Len(t, res.Variables, 3)
+
+	msg, _, err := i.Read(ctx)
+	require.NoError(t, err)
+
+	v, err := msg.AsStructured()
+	require.NoError(t, err)
+	source := v.(map[string]any)["source"]
+	assert.Equal(t, map[string]any{
+		"source":    source,
+		"trap_oid":  "1.3.6.1.6.3.1.1.5.3",
+		"trap_name": "linkDown",
+		"uptime":    uint64(1200),
+		"variables": []any{
+			map[string]any{"oid": "1.3.6.1.2.1.2.2.1.1.3", "name": "ifIndex.3", "type": "Integer", "value": int64(3)},
+		},
+	}, v)
+
+	kind, _ := msg.MetaGetMut("snmp_kind")
+	assert.Equal(t, "inform", kind)
+}
+
+func TestInputTrapsV3(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	i := testInput(t, `
+version: v3
+security:
+  user_name: foo
+  auth_protocol: SHA
+  auth_password: barbazbuz
+  priv_protocol: DES
+  priv_password: buzbazbar
+traps:
+  address: 127.0.0.1:0
+`)
+	require.NoError(t, i.Connect(ctx))
+	addr := i.trapConn.LocalAddr().String()
+
+	sender := func(user string, flags gosnmp.SnmpV3MsgFlags, authPassword string) *gosnmp.GoSNMP {
+		return &gosnmp.GoSNMP{
+			Version:       gosnmp.Version3,
+			SecurityModel: gosnmp.UserSecurityModel,
+			MsgFlags:      flags,
+			SecurityParameters: &gosnmp.UsmSecurityParameters{
+				UserName:                 user,
+				AuthoritativeEngineID:    "\x80\x00\x1f\x88\x04sender",
+				AuthoritativeEngineBoots: 1,
+				AuthoritativeEngineTime:  10,
+				AuthenticationProtocol:   gosnmp.SHA,
+				AuthenticationPassphrase: authPassword,
+				PrivacyProtocol:          gosnmp.DES,
+				PrivacyPassphrase:        "buzbazbar",
+			},
+		}
+	}
+
+	// Traps that fail authentication, are from another user or have a lower
+	// security level are ignored.
+	sendNotification(t, addr, sender("foo", gosnmp.AuthPriv, "wrongpassword"), false)
+	sendNotification(t, addr, sender("bar", gosnmp.AuthPriv, "barbazbuz"), false)
+	sendNotification(t, addr, sender("foo", gosnmp.AuthNoPriv, "barbazbuz"), false)
+
+	sendNotification(t, addr, sender("foo", gosnmp.AuthPriv, "barbazbuz"), false)
+
+	msg, _, err := i.Read(ctx)
+	require.NoError(t, err)
+
+	v, err := msg.AsStructured()
+	require.NoError(t, err)
+	assert.Equal(t, "linkDown", v.(map[string]any)["trap_name"])
+
+	kind, _ := msg.MetaGetMut("snmp_kind")
+	assert.Equal(t, "trap", kind)
+
+	select {
+	case msg := <-i.msgChan:
+		t.Errorf("Unexpected message: %v", msg)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestInputConfigErrors(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		confStr string
+		errStr  string
+	}{
+		{
+			name:    "nothing to do",
+			confStr: `community: foo`,
+			errStr:  "at least one poll target or a trap address must be set",
+		},
+		{
+			name: "no objects",
+			confStr: `
+poll:
+  targets: [ localhost ]
+`,
+			errStr: "polling requires at least one object to get or walk",
+		},
+		{
+			name: "unknown object",
+			confStr: `
+poll:
+  targets: [ localhost ]
+  get: [ nope.0 ]
+`,
+			errStr: "poll.get: unknown object name 'nope'",
+		},
+		{
+			name: "v3 without user",
+			confStr: `
+version: v3
+traps:
+  address: 127.0.0.1:0
+`,
+			errStr: "a user name is required for SNMPv3",
+		},
+		{
+			name: "v3 privacy without authentication",
+			confStr: `
+version: v3
+security:
+  user_name: foo
+  priv_protocol: AES
+  priv_password: buzbazbar
+traps:
+  address: 127.0.0.1:0
+`,
+			errStr: "privacy requires an authentication protocol",
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := inputSpec().ParseYAML(test.confStr, nil)
+			require.NoError(t, err)
+
+			_, err = newSNMPInputFromParsed(conf, service.MockResources())
+			require.EqualError(t, err, test.errStr)
+		})
+	}
+}
@@ -0,0 +1,312 @@
+package snmp
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// builtinMIB contains the names of common objects from RFC1213-MIB, IF-MIB and
+// SNMPv2-MIB, relative to their parents.
+var builtinMIB = []struct {
+	name   string
+	parent string
+	id     uint32
+}{
+	{"org", "iso", 3},
+	{"dod", "org", 6},
+	{"internet", "dod", 1},
+	{"mgmt", "internet", 2},
+	{"private", "internet", 4},
+	{"enterprises", "private", 1},
+	{"snmpV2", "internet", 6},
+	{"snmpModules", "snmpV2", 3},
+	{"mib-2", "mgmt", 1},
+
+	{"system", "mib-2", 1},
+	{"sysDescr", "system", 1},
+	{"sysObjectID", "system", 2},
+	{"sysUpTime", "system", 3},
+	{"sysContact", "system", 4},
+	{"sysName", "system", 5},
+	{"sysLocation", "system", 6},
+	{"sysServices", "system", 7},
+
+	{"interfaces", "mib-2", 2},
+	{"ifNumber", "interfaces", 1},
+	{"ifTable", "interfaces", 2},
+	{"ifEntry", "ifTable", 1},
+	{"ifIndex", "ifEntry", 1},
+	{"ifDescr", "ifEntry", 2},
+	{"ifType", "ifEntry", 3},
+	{"ifMtu", "ifEntry", 4},
+	{"ifSpeed", "ifEntry", 5},
+	{"ifPhysAddress", "ifEntry", 6},
+	{"ifAdminStatus", "ifEntry", 7},
+	{"ifOperStatus", "ifEntry", 8},
+	{"ifLastChange", "ifEntry", 9},
+	{"ifInOctets", "ifEntry", 10},
+	{"ifInUcastPkts", "ifEntry", 11},
+	{"ifInNUcastPkts", "ifEntry", 12},
+	{"ifInDiscards", "ifEntry", 13},
+	{"ifInErrors", "ifEntry", 14},
+	{"ifInUnknownProtos", "ifEntry", 15},
+	{"ifOutOctets", "ifEntry", 16},
+	{"ifOutUcastPkts", "ifEntry", 17},
+	{"ifOutNUcastPkts", "ifEntry", 18},
+	{"ifOutDiscards", "ifEntry", 19},
+	{"ifOutErrors", "ifEntry", 20},
+	{"ifOutQLen", "ifEntry", 21},
+	{"ifSpecific", "ifEntry", 22},
+
+	{"ifMIB", "mib-2", 31},
+	{"ifMIBObjects", "ifMIB", 1},
+	{"ifXTable", "ifMIBObjects", 1},
+	{"ifXEntry", "ifXTable", 1},
+	{"ifName", "ifXEntry", 1},
+	{"ifInMulticastPkts", "ifXEntry", 2},
+	{"ifInBroadcastPkts", "ifXEntry", 3},
+	{"ifOutMulticastPkts", "ifXEntry", 4},
+	{"ifOutBroadcastPkts", "ifXEntry", 5},
+	{"ifHCInOctets", "ifXEntry", 6},
+	{"ifHCInUcastPkts", "ifXEntry", 7},
+	{"ifHCInMulticastPkts", "ifXEntry", 8},
+	{"ifHCInBroadcastPkts", "ifXEntry", 9},
+	{"ifHCOutOctets", "ifXEntry", 10},
+	{"ifHCOutUcastPkts", "ifXEntry", 11},
+	{"ifHCOutMulticastPkts", "ifXEntry", 12},
+	{"ifHCOutBroadcastPkts", "ifXEntry", 13},
+	{"ifLinkUpDownTrapEnable", "ifXEntry", 14},
+	{"ifHighSpeed", "ifXEntry", 15},
+	{"ifPromiscuousMode", "ifXEntry", 16},
+	{"ifConnectorPresent", "ifXEntry", 17},
+	{"ifAlias", "ifXEntry", 18},
+	{"ifCounterDiscontinuityTime", "ifXEntry", 19},
+
+	{"snmpMIB", "snmpModules", 1},
+	{"snmpMIBObjects", "snmpMIB", 1},
+	{"snmpTrap", "snmpMIBObjects", 4},
+	{"snmpTrapOID", "snmpTrap", 1},
+	{"snmpTrapEnterprise", "snmpTrap", 3},
+	{"snmpTraps", "snmpMIBObjects", 5},
+	{"coldStart", "snmpTraps", 1},
+	{"warmStart", "snmpTraps", 2},
+	{"linkDown", "snmpTraps", 3},
+	{"linkUp", "snmpTraps", 4},
+	{"authenticationFailure", "snmpTraps", 5},
+}
+
+// mibTree resolves names of objects to their identifiers and vice versa.
+type mibTree struct {
+	oids  map[string][]uint32
+	names map[string]string
+}
+
+func newMIBTree() *mibTree {
+	t := &mibTree{
+		oids: map[string][]uint32{
+			"ccitt":           {0},
+			"iso":             {1},
+			"joint-iso-ccitt": {2},
+		},
+		names: map[string]string{},
+	}
+	for _, o := range builtinMIB {
+		t.add(o.name, append(append([]uint32{}, t.oids[o.parent]...), o.id))
+	}
+	return t
+}
+
+func (t *mibTree) add(name string, oid []uint32) {
+	t.oids[name] = oid
+	t.names[formatOID(oid)] = name
+}
+
+// resolve converts an object, which is either a dotted identifier or a name
+// optionally prefixed with a module and followed by a dotted suffix such as
+// IF-MIB::ifDescr.1, into an identifier.
+func (t *mibTree) resolve(s string) ([]uint32, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty object identifier")
+	}
+	if c := s[0]; c == '.' || (c >= '0' && c <= '9') {
+		return parseOID(s)
+	}
+	if i := strings.Index(s, "::"); i >= 0 {
+		s = s[i+2:]
+	}
+
+	name, suffix, _ := strings.Cut(s, ".")
+	base, exists := t.oids[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown object name '%v'", name)
+	}
+	oid := append([]uint32{}, base...)
+	if suffix != "" {
+		rest, err := parseOID(suffix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid suffix of object '%v'", s)
+		}
+		oid = append(oid, rest...)
+	}
+	return oid, nil
+}
+
+// name returns the name of an identifier in the form name.suffix from the
+// longest prefix that has a known name, or the dotted form when no prefix is
+// known.
+func (t *mibTree) name(oid []uint32) string {
+	for i := len(oid); i > 0; i-- {
+		if name, exists := t.names[formatOID(oid[:i])]; exists {
+			if i == len(oid) {
+				return name
+			}
+			return name + "." + formatOID(oid[i:])
+		}
+	}
+	return formatOID(oid)
+}
+
+// parseOID parses an object identifier in dotted form, with an optional leading
+// dot.
+func parseOID(s string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "."), ".")
+	oid := make([]uint32, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object identifier '%v'", s)
+		}
+		oid = append(oid, uint32(n))
+	}
+	return oid, nil
+}
+
+// formatOID formats an object identifier in dotted form.
+func formatOID(oid []uint32) string {
+	var sb strings.Builder
+	for i, n := range oid {
+		if i > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(strconv.FormatUint(uint64(n), 10))
+	}
+	return sb.String()
+}
+
+//------------------------------------------------------------------------------
+
+var (
+	mibCommentRegexp    = regexp.MustCompile(`--.*?(--|$)`)
+	mibDefinitionRegexp = regexp.MustCompile(`(?m)(?:^|[\s;])([a-z][A-Za-z0-9-]*)\s+(?:OBJECT-TYPE|OBJECT\s+IDENTIFIER|MODULE-IDENTITY|OBJECT-IDENTITY|NOTIFICATION-TYPE|OBJECT-GROUP|NOTIFICATION-GROUP|MODULE-COMPLIANCE|AGENT-CAPABILITIES)\b[^{]*?::=\s*\{([^}]*)\}`)
+	mibComponentRegexp  = regexp.MustCompile(`^(?:([a-zA-Z][A-Za-z0-9-]*)\((\d+)\)|(\d+)|([a-zA-Z][A-Za-z0-9-]*))$`)
+)
+
+type mibDefinition struct {
+	parent string
+	ids    []uint32
+}
+
+// parseMIB extracts the object definitions of a MIB module, where each
+// definition is the name of its parent followed by the identifiers beneath
+// it.
+func parseMIB(content string) map[string]mibDefinition {
+	lines := strings.Split(content, "\n")
+	for i, l := range lines {
+		lines[i] = mibCommentRegexp.ReplaceAllString(l, "")
+	}
+	content = strings.Join(lines, "\n")
+
+	defs := map[string]mibDefinition{}
+	for _, match := range mibDefinitionRegexp.FindAllStringSubmatch(content, -1) {
+		components := strings.Fields(match[2])
+		if len(components) < 2 {
+			continue
+		}
+
+		var def mibDefinition
+		valid := true
+		for i, c := range components {
+			parts := mibComponentRegexp.FindStringSubmatch(c)
+			if parts == nil {
+				valid = false
+				break
+			}
+			switch {
+			case i == 0 && parts[4] != "":
+				def.parent = parts[4]
+			case i == 0 && parts[1] != "":
+				// A parent in the form name(n) is resolved by its name when it
+				// is known, and otherwise by its number.
+				def.parent = parts[1]
+			case parts[3] != "":
+				n, _ := strconv.ParseUint(parts[3], 10, 32)
+				def.ids = append(def.ids, uint32(n))
+			case parts[2] != "":
+				n, _ := strconv.ParseUint(parts[2], 10, 32)
+				def.ids = append(def.ids, uint32(n))
+			default:
+				valid = false
+			}
+		}
+		if valid && def.parent != "" && len(def.ids) > 0 {
+			defs[match[1]] = def
+		}
+	}
+	return defs
+}
+
+// loadMIBs parses MIB modules from files and directories, adding the objects
+// they define to the tree, and returns the names of objects that could not be
+// resolved.
+func (t *mibTree) loadMIBs(paths []string) ([]string, error) {
+	defs := map[string]mibDefinition{}
+	for _, p := range paths {
+		err := filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+				return nil
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			for k, v := range parseMIB(string(content)) {
+				defs[k] = v
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load MIBs from %v: %w", p, err)
+		}
+	}
+
+	// Definitions may reference parents defined later or in other modules, and
+	// are therefore resolved repeatedly until no progress is made.
+	for progress := true; progress && len(defs) > 0; {
+		progress = false
+		for name, def := range defs {
+			parent, exists := t.oids[def.parent]
+			if !exists {
+				continue
+			}
+			oid := append(append([]uint32{}, parent...), def.ids...)
+			t.add(name, oid)
+			delete(defs, name)
+			progress = true
+		}
+	}
+
+	unresolved := make([]string, 0, len(defs))
+	for name := range defs {
+		unresolved = append(unresolved, name)
+	}
+	return unresolved, nil
+}
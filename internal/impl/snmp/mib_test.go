@@ -0,0 +1,94 @@
+package snmp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMIB = `
+UCD-SNMP-MIB DEFINITIONS ::= BEGIN
+
+IMPORTS
+    enterprises FROM SNMPv2-SMI;
+
+ucdavis MODULE-IDENTITY
+    LAST-UPDATED "201107070000Z"
+    ORGANIZATION "University of California, Davis"
+    DESCRIPTION "This file defines the private UCD-SNMP-MIB extensions."
+    ::= { enterprises 2021 }
+
+laTable OBJECT-TYPE
+    SYNTAX      SEQUENCE OF LaEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "Load average information."
+    ::= { ucdavis 10 }
+
+laEntry OBJECT-TYPE
+    SYNTAX      LaEntry
+    MAX-ACCESS  not-accessible
+    STATUS      current
+    DESCRIPTION "An entry containing a load average."
+    ::= { laTable 1 }
+
+-- laNames OBJECT-TYPE ::= { laEntry 99 } is commented out
+laLoad OBJECT-TYPE
+    SYNTAX      DisplayString
+    MAX-ACCESS  read-only
+    STATUS      current
+    DESCRIPTION "The 1, 5 and 15 minute load averages."
+    ::= { laEntry 3 }
+
+orphan OBJECT IDENTIFIER ::= { unknownParent 1 }
+
+END
+`
+
+func TestMIBResolve(t *testing.T) {
+	tree := newMIBTree()
+
+	for _, test := range []struct {
+		input  string
+		output []uint32
+	}{
+		{input: "1.3.6.1.2.1.1.3.0", output: []uint32{1, 3, 6, 1, 2, 1, 1, 3, 0}},
+		{input: ".1.3.6.1", output: []uint32{1, 3, 6, 1}},
+		{input: "sysUpTime.0", output: []uint32{1, 3, 6, 1, 2, 1, 1, 3, 0}},
+		{input: "IF-MIB::ifHCInOctets", output: []uint32{1, 3, 6, 1, 2, 1, 31, 1, 1, 1, 6}},
+		{input: "IF-MIB::ifDescr.12", output: []uint32{1, 3, 6, 1, 2, 1, 2, 2, 1, 2, 12}},
+	} {
+		oid, err := tree.resolve(test.input)
+		require.NoError(t, err, test.input)
+		assert.Equal(t, test.output, oid, test.input)
+	}
+
+	_, err := tree.resolve("nope.0")
+	require.EqualError(t, err, "unknown object name 'nope'")
+
+	assert.Equal(t, "sysUpTime.0", tree.name([]uint32{1, 3, 6, 1, 2, 1, 1, 3, 0}))
+	assert.Equal(t, "linkDown", tree.name([]uint32{1, 3, 6, 1, 6, 3, 1, 1, 5, 3}))
+	assert.Equal(t, "enterprises.9.1.1", tree.name([]uint32{1, 3, 6, 1, 4, 1, 9, 1, 1}))
+	assert.Equal(t, "2.999", tree.name([]uint32{2, 999}))
+}
+
+func TestMIBLoad(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "UCD-SNMP-MIB.txt"), []byte(testMIB), 0o644))
+
+	tree := newMIBTree()
+	unresolved, err := tree.loadMIBs([]string{dir})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"orphan"}, unresolved)
+
+	oid, err := tree.resolve("UCD-SNMP-MIB::laLoad.1")
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{1, 3, 6, 1, 4, 1, 2021, 10, 1, 3, 1}, oid)
+	assert.Equal(t, "laLoad.2", tree.name([]uint32{1, 3, 6, 1, 4, 1, 2021, 10, 1, 3, 2}))
+
+	_, err = tree.resolve("laNames")
+	require.Error(t, err)
+}
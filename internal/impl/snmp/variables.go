@@ -0,0 +1,111 @@
+package snmp
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+var (
+	oidSysUpTime   = []uint32{1, 3, 6, 1, 2, 1, 1, 3, 0}
+	oidSnmpTrapOID = []uint32{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0}
+)
+
+var typeNames = map[gosnmp.Asn1BER]string{
+	gosnmp.Integer:          "Integer",
+	gosnmp.OctetString:      "OctetString",
+	gosnmp.Null:             "Null",
+	gosnmp.ObjectIdentifier: "ObjectIdentifier",
+	gosnmp.IPAddress:        "IpAddress",
+	gosnmp.Counter32:        "Counter32",
+	gosnmp.Gauge32:          "Gauge32",
+	gosnmp.TimeTicks:        "TimeTicks",
+	gosnmp.Opaque:           "Opaque",
+	gosnmp.Counter64:        "Counter64",
+	gosnmp.Uinteger32:       "Unsigned32",
+	gosnmp.OpaqueFloat:      "OpaqueFloat",
+	gosnmp.OpaqueDouble:     "OpaqueDouble",
+	gosnmp.NoSuchObject:     "NoSuchObject",
+	gosnmp.NoSuchInstance:   "NoSuchInstance",
+	gosnmp.EndOfMibView:     "EndOfMibView",
+}
+
+func oidHasPrefix(oid, prefix []uint32) bool {
+	if len(oid) < len(prefix) {
+		return false
+	}
+	for i, n := range prefix {
+		if oid[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func oidEqual(a, b []uint32) bool {
+	return len(a) == len(b) && oidHasPrefix(a, b)
+}
+
+// structuredValue converts the value of a variable into a structured form,
+// where octet strings that aren't printable are formatted as hex.
+func structuredValue(v gosnmp.SnmpPDU) (any, error) {
+	switch v.Type {
+	case gosnmp.Integer:
+		return gosnmp.ToBigInt(v.Value).Int64(), nil
+	case gosnmp.Counter32, gosnmp.Gauge32, gosnmp.TimeTicks, gosnmp.Counter64, gosnmp.Uinteger32:
+		return gosnmp.ToBigInt(v.Value).Uint64(), nil
+	case gosnmp.OctetString:
+		b, ok := v.Value.([]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected octet string value of type %T", v.Value)
+		}
+		if isPrintable(b) {
+			return string(b), nil
+		}
+		return formatHex(b), nil
+	case gosnmp.ObjectIdentifier:
+		s, ok := v.Value.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object identifier value of type %T", v.Value)
+		}
+		oid, err := parseOID(s)
+		if err != nil {
+			return nil, err
+		}
+		return formatOID(oid), nil
+	case gosnmp.IPAddress, gosnmp.OpaqueFloat, gosnmp.OpaqueDouble:
+		return v.Value, nil
+	case gosnmp.Opaque:
+		if b, ok := v.Value.([]byte); ok {
+			return formatHex(b), nil
+		}
+		return v.Value, nil
+	}
+	return nil, nil
+}
+
+func isPrintable(b []byte) bool {
+	if !utf8.Valid(b) {
+		return false
+	}
+	for _, r := range string(b) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func formatHex(b []byte) string {
+	const digits = "0123456789abcdef"
+	out := make([]byte, 0, len(b)*3)
+	for i, c := range b {
+		if i > 0 {
+			out = append(out, ':')
+		}
+		out = append(out, digits[c>>4], digits[c&0x0f])
+	}
+	return string(out)
+}
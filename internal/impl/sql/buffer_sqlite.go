@@ -14,6 +14,7 @@ import (
 	"github.com/cenkalti/backoff/v4"
 	"github.com/vmihailenco/msgpack/v5"
 
+	"github.com/benthosdev/benthos/v4/internal/atrest"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/public/service"
 )
@@ -43,6 +44,7 @@ Messages that are logically batched at the point where they are added to the buf
 		Field(service.NewProcessorListField("post_processors").
 			Description("An optional list of processors to apply to messages after they are consumed from the buffer. These processors are useful for undoing any compression, archiving, etc that may have been done by your `pre_processors`.").
 			Optional()).
+		Field(atrest.FieldSpec("encryption")).
 		Example("Batching for optimisation", "Batching at the input level greatly increases the throughput of this buffer. If logical batches aren't needed for processing add a [`split` processor](/docs/components/processors/split) to the `post_processors`.", `
 input:
   batched:
@@ -96,7 +98,12 @@ func NewSQLiteBufferFromConfig(conf *service.ParsedConfig, res *service.Resource
 		}
 	}
 
-	return newSQLiteBuffer(path, preProcs, postProcs)
+	c, err := atrest.CipherFromParsed(conf, "encryption")
+	if err != nil {
+		return nil, err
+	}
+
+	return newSQLiteBuffer(path, preProcs, postProcs, c)
 }
 
 //------------------------------------------------------------------------------
@@ -106,6 +113,7 @@ type SQLiteBuffer struct {
 	db        *sql.DB
 	preProcs  []*service.OwnedProcessor
 	postProcs []*service.OwnedProcessor
+	cipher    *atrest.Cipher
 
 	pending     []ackableBatch
 	cond        *sync.Cond
@@ -115,7 +123,7 @@ type SQLiteBuffer struct {
 	closed      bool
 }
 
-func newSQLiteBuffer(path string, preProcs, postProcs []*service.OwnedProcessor) (*SQLiteBuffer, error) {
+func newSQLiteBuffer(path string, preProcs, postProcs []*service.OwnedProcessor, c *atrest.Cipher) (*SQLiteBuffer, error) {
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
 		return nil, err
@@ -137,6 +145,7 @@ CREATE TABLE IF NOT EXISTS messages (
 		db:        db,
 		preProcs:  preProcs,
 		postProcs: postProcs,
+		cipher:    c,
 		cond:      sync.NewCond(&sync.Mutex{}),
 	}, nil
 }
@@ -172,6 +181,11 @@ func (m *SQLiteBuffer) tryGetBatch(ctx context.Context) (service.MessageBatch, i
 	}
 	m.nextIndex = index + 1
 
+	contentBytes, err := m.cipher.Open(contentBytes)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	batch, _, err := readBatch(contentBytes)
 	return batch, index, err
 }
@@ -315,6 +329,9 @@ func (m *SQLiteBuffer) WriteBatch(ctx context.Context, msgBatch service.MessageB
 		if err != nil {
 			return err
 		}
+		if contentBytes, err = m.cipher.Seal(contentBytes); err != nil {
+			return err
+		}
 		builder = builder.Values(contentBytes, maxRequeue)
 	}
 
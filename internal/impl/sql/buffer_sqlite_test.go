@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -634,3 +635,31 @@ post_processors:
 
 	wg.Wait()
 }
+
+func TestBufferSQLiteEncryption(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "foo.db")
+
+	ctx := context.Background()
+	block := memBufFromConf(t, fmt.Sprintf(`
+path: "%v"
+encryption:
+  key: YWFhYWFhYWFhYWFhYWFhYWFhYWFhYWFhYWFhYWFhYWE=
+`, dbPath))
+	defer block.Close(ctx)
+
+	msg := service.NewMessage([]byte("hello world"))
+	msg.MetaSet("foo", "secret metadata")
+	require.NoError(t, block.WriteBatch(ctx, service.MessageBatch{msg}, func(ctx context.Context, err error) error { return nil }))
+
+	rawBytes, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(rawBytes), "hello world")
+	assert.NotContains(t, string(rawBytes), "secret metadata")
+
+	m, ackFunc, err := block.ReadBatch(ctx)
+	require.NoError(t, err)
+	require.Len(t, m, 1)
+	msgEqual(t, msg, m[0])
+	require.NoError(t, ackFunc(ctx, nil))
+}
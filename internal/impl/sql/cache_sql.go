@@ -161,14 +161,13 @@ func newSQLCacheFromConfig(conf *service.ParsedConfig, mgr *service.Resources) (
 		return nil, err
 	}
 
-	if s.db, err = sqlOpenWithReworks(s.logger, s.driver, s.dsn); err != nil {
+	if s.db, err = connSettings.open(context.Background(), s.logger, s.driver, s.dsn); err != nil {
 		return nil, err
 	}
-	connSettings.apply(context.Background(), s.db, s.logger)
 
 	go func() {
 		<-s.shutSig.HardStopChan()
-		_ = s.db.Close()
+		_ = connSettings.close(s.db)
 		s.shutSig.TriggerHasStopped()
 	}()
 	return s, nil
@@ -80,6 +80,7 @@ CREATE TABLE IF NOT EXISTS some_table (
 			Optional().
 			Advanced().
 			Version("4.10.0"),
+		migrationsField(),
 		service.NewDurationField("conn_max_idle_time").
 			Description("An optional maximum amount of time a connection may be idle. Expired connections may be closed lazily before reuse. If `value <= 0`, connections are not closed due to a connections idle time.").
 			Optional().
@@ -97,6 +98,22 @@ CREATE TABLE IF NOT EXISTS some_table (
 			Description("An optional maximum number of open connections to the database. If conn_max_idle is greater than 0 and the new conn_max_open is less than conn_max_idle, then conn_max_idle will be reduced to match the new conn_max_open limit. If `value <= 0`, then there is no limit on the number of open connections. The default is 0 (unlimited).").
 			Optional().
 			Advanced(),
+		service.NewDurationField("conn_health_check_period").
+			Description("An optional period at which the database is pinged in order to check the health of the connection pool. A warning is logged when a check fails and the pool is reported as recovered once a check succeeds again.").
+			Example("30s").
+			Optional().
+			Advanced().
+			Version("4.28.0"),
+		service.NewStringField("pool").
+			Description(`
+An optional name of a connection pool to share with all other SQL components configured with the same pool name, rather than opening a pool for this component alone. The pool is opened by the first of these components to connect, using its connection settings (the ` + "`conn_` fields" + `), and is closed once all of them are closed.
+
+All components that share a pool must use the same ` + "`driver` and `dsn`" + `. The ` + "`init_files`, `init_statement` and `migrations`" + ` of each component are still executed upon its first connection.
+`).
+			Example("orders_db").
+			Optional().
+			Advanced().
+			Version("4.28.0"),
 	}
 }
 
@@ -124,16 +141,39 @@ type connSettings struct {
 	maxIdleConns    int
 	maxOpenConns    int
 
+	pool              string
+	healthCheckPeriod time.Duration
+	healthCheckMut    sync.Mutex
+	stopHealthCheck   func()
+
 	initOnce           sync.Once
 	initFileStatements [][2]string // (path,statement)
 	initStatement      string
+
+	migrator *sqlMigrator
+}
+
+func (c *connSettings) apply(ctx context.Context, db *sql.DB, log *service.Logger) error {
+	c.configure(db)
+	return c.initialise(ctx, db, log)
 }
 
-func (c *connSettings) apply(ctx context.Context, db *sql.DB, log *service.Logger) {
+// configure sets the limits of a connection pool.
+func (c *connSettings) configure(db *sql.DB) {
 	db.SetConnMaxIdleTime(c.connMaxIdleTime)
 	db.SetConnMaxLifetime(c.connMaxLifetime)
 	db.SetMaxIdleConns(c.maxIdleConns)
 	db.SetMaxOpenConns(c.maxOpenConns)
+}
+
+// initialise runs the migrations and init statements of a component, where
+// init statements are only executed upon the first call.
+func (c *connSettings) initialise(ctx context.Context, db *sql.DB, log *service.Logger) error {
+	if c.migrator != nil {
+		if err := c.migrator.run(ctx, db, log); err != nil {
+			return err
+		}
+	}
 
 	c.initOnce.Do(func() {
 		for _, fileStmt := range c.initFileStatements {
@@ -151,6 +191,7 @@ func (c *connSettings) apply(ctx context.Context, db *sql.DB, log *service.Logge
 			}
 		}
 	})
+	return nil
 }
 
 func connSettingsFromParsed(
@@ -208,9 +249,70 @@ func connSettingsFromParsed(
 			})
 		}
 	}
+
+	if conf.Contains("conn_health_check_period") {
+		if c.healthCheckPeriod, err = conf.FieldDuration("conn_health_check_period"); err != nil {
+			return
+		}
+	}
+
+	if conf.Contains("pool") {
+		if c.pool, err = conf.FieldString("pool"); err != nil {
+			return
+		}
+	}
+
+	c.migrator, err = migratorFromParsed(conf, mgr)
 	return
 }
 
+// open returns a connection pool for a driver and dsn with the connection
+// settings applied. When a pool name is configured the pool is shared with all
+// other components of the same pool name, otherwise a new pool is opened.
+func (c *connSettings) open(ctx context.Context, logger *service.Logger, driver, dsn string) (*sql.DB, error) {
+	if c.pool != "" {
+		db, err := globalSQLPools.acquire(c, logger, driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.initialise(ctx, db, logger); err != nil {
+			_ = globalSQLPools.release(c.pool)
+			return nil, err
+		}
+		return db, nil
+	}
+
+	db, err := sqlOpenWithReworks(logger, driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.apply(ctx, db, logger); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	c.healthCheckMut.Lock()
+	c.stopHealthCheck = startSQLHealthCheck(db, c.healthCheckPeriod, logger)
+	c.healthCheckMut.Unlock()
+	return db, nil
+}
+
+// close releases a connection pool obtained with open, where shared pools are
+// only closed once released by all of the components that share them.
+func (c *connSettings) close(db *sql.DB) error {
+	if c.pool != "" {
+		return globalSQLPools.release(c.pool)
+	}
+
+	c.healthCheckMut.Lock()
+	if c.stopHealthCheck != nil {
+		c.stopHealthCheck()
+		c.stopHealthCheck = nil
+	}
+	c.healthCheckMut.Unlock()
+	return db.Close()
+}
+
 func sqlOpenWithReworks(logger *service.Logger, driver, dsn string) (*sql.DB, error) {
 	if driver == "clickhouse" && strings.HasPrefix(dsn, "tcp") {
 		u, err := url.Parse(dsn)
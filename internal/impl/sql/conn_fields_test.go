@@ -184,3 +184,189 @@ sql_select:
 		`{"bar":"third bar","baz":"third baz","foo":"third"}`,
 	}, msgs)
 }
+
+func TestConnSettingsMigrations(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	tmpDir := t.TempDir()
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "0001_create_things.sql"), []byte(`
+CREATE TABLE things (
+  foo varchar(50) not null,
+  bar varchar(50) not null,
+  primary key (foo)
+) WITHOUT ROWID;
+`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "0002_add_baz.sql"), []byte(`
+ALTER TABLE things
+ADD COLUMN baz varchar(50);
+`), 0o644))
+
+	outputConf := fmt.Sprintf(`
+sql_insert:
+  driver: sqlite
+  dsn: file:%v/foo.db
+  table: things
+  columns: [ foo, bar, baz ]
+  args_mapping: 'root = [ this.foo, this.bar, this.baz ]'
+  migrations:
+    files: [ "%v/*.sql" ]
+    statements:
+      - version: 3
+        statement: INSERT INTO things (foo, bar, baz) VALUES ('seed', 'seed bar', 'seed baz');
+`, tmpDir, tmpDir)
+
+	// Running the output twice ensures that migrations which have already been
+	// applied are skipped.
+	for _, foo := range []string{"first", "second"} {
+		streamInBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamInBuilder.SetLoggerYAML(`level: OFF`))
+		require.NoError(t, streamInBuilder.AddOutputYAML(outputConf))
+
+		inFn, err := streamInBuilder.AddBatchProducerFunc()
+		require.NoError(t, err)
+
+		streamIn, err := streamInBuilder.Build()
+		require.NoError(t, err)
+
+		go func() {
+			assert.NoError(t, streamIn.Run(tCtx))
+		}()
+
+		require.NoError(t, inFn(tCtx, service.MessageBatch{
+			service.NewMessage([]byte(fmt.Sprintf(`{"foo":"%[1]v","bar":"%[1]v bar","baz":"%[1]v baz"}`, foo))),
+		}))
+
+		require.NoError(t, streamIn.Stop(tCtx))
+	}
+
+	readAll := func(table string, columns string) []string {
+		t.Helper()
+
+		streamOutBuilder := service.NewStreamBuilder()
+		require.NoError(t, streamOutBuilder.SetLoggerYAML(`level: OFF`))
+		require.NoError(t, streamOutBuilder.AddInputYAML(fmt.Sprintf(`
+sql_select:
+  driver: sqlite
+  dsn: file:%v/foo.db
+  table: %v
+  columns: %v
+`, tmpDir, table, columns)))
+
+		var msgs []string
+		require.NoError(t, streamOutBuilder.AddConsumerFunc(func(ctx context.Context, m *service.Message) error {
+			bMsg, err := m.AsBytes()
+			require.NoError(t, err)
+			msgs = append(msgs, string(bMsg))
+			return nil
+		}))
+
+		streamOut, err := streamOutBuilder.Build()
+		require.NoError(t, err)
+
+		assert.NoError(t, streamOut.Run(tCtx))
+		return msgs
+	}
+
+	assert.Equal(t, []string{
+		`{"bar":"first bar","baz":"first baz","foo":"first"}`,
+		`{"bar":"second bar","baz":"second baz","foo":"second"}`,
+		`{"bar":"seed bar","baz":"seed baz","foo":"seed"}`,
+	}, readAll("things", "[ foo, bar, baz ]"))
+
+	assert.Equal(t, []string{
+		`{"version":1}`,
+		`{"version":2}`,
+		`{"version":3}`,
+	}, readAll("benthos_schema_migrations", "[ version ]"))
+}
+
+func TestConnSettingsMigrationsErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "create_things.sql"), []byte(`CREATE TABLE things (foo varchar(50));`), 0o644))
+
+	tests := []struct {
+		name        string
+		conf        string
+		errContains string
+	}{
+		{
+			name: "file without version",
+			conf: fmt.Sprintf(`
+sql_raw:
+  driver: sqlite
+  dsn: file:%[1]v/foo.db
+  query: 'SELECT 1'
+  migrations:
+    files: [ "%[1]v/create_things.sql" ]
+`, tmpDir),
+			errContains: "does not begin with a version number",
+		},
+		{
+			name: "duplicate versions",
+			conf: fmt.Sprintf(`
+sql_raw:
+  driver: sqlite
+  dsn: file:%v/foo.db
+  query: 'SELECT 1'
+  migrations:
+    statements:
+      - version: 1
+        statement: 'CREATE TABLE foo (a integer);'
+      - version: 1
+        statement: 'CREATE TABLE bar (a integer);'
+`, tmpDir),
+			errContains: "share the version 1",
+		},
+		{
+			name: "unsupported driver",
+			conf: `
+sql_raw:
+  driver: trino
+  dsn: http://localhost:8080
+  query: 'SELECT 1'
+  migrations:
+    statements:
+      - version: 1
+        statement: 'CREATE TABLE foo (a integer);'
+`,
+			errContains: "migrations are not supported by the trino driver",
+		},
+		{
+			name: "failed migration",
+			conf: fmt.Sprintf(`
+sql_raw:
+  driver: sqlite
+  dsn: file:%v/foo.db
+  query: 'SELECT 1'
+  migrations:
+    statements:
+      - version: 1
+        statement: 'NOT VALID SQL;'
+`, tmpDir),
+			errContains: "failed to apply migration 1 (statements[0])",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			builder := service.NewStreamBuilder()
+			require.NoError(t, builder.SetLoggerYAML(`level: OFF`))
+			require.NoError(t, builder.AddInputYAML(`generate: { count: 1, interval: "", mapping: 'root = {}' }`))
+			require.NoError(t, builder.AddProcessorYAML(test.conf))
+			require.NoError(t, builder.AddOutputYAML(`drop: {}`))
+
+			strm, err := builder.Build()
+			require.NoError(t, err)
+
+			tCtx, done := context.WithTimeout(context.Background(), time.Second*5)
+			defer done()
+
+			err = strm.Run(tCtx)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.errContains)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+type sharedSQLPool struct {
+	db              *sql.DB
+	driver          string
+	dsn             string
+	refs            int
+	stopHealthCheck func()
+}
+
+type sharedSQLPools struct {
+	mut   sync.Mutex
+	pools map[string]*sharedSQLPool
+}
+
+var globalSQLPools = &sharedSQLPools{
+	pools: map[string]*sharedSQLPool{},
+}
+
+// acquire returns the shared pool of the configured name, opening it with the
+// connection settings provided when it is not already open.
+func (p *sharedSQLPools) acquire(c *connSettings, logger *service.Logger, driver, dsn string) (*sql.DB, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	if s, exists := p.pools[c.pool]; exists {
+		if s.driver != driver || s.dsn != dsn {
+			return nil, fmt.Errorf("pool %v is already open with a different driver or dsn", c.pool)
+		}
+		s.refs++
+		return s.db, nil
+	}
+
+	db, err := sqlOpenWithReworks(logger, driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	c.configure(db)
+
+	p.pools[c.pool] = &sharedSQLPool{
+		db:              db,
+		driver:          driver,
+		dsn:             dsn,
+		refs:            1,
+		stopHealthCheck: startSQLHealthCheck(db, c.healthCheckPeriod, logger),
+	}
+	return db, nil
+}
+
+// release decrements the references of a shared pool and closes it once no
+// references remain.
+func (p *sharedSQLPools) release(name string) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	s, exists := p.pools[name]
+	if !exists {
+		return nil
+	}
+	if s.refs--; s.refs > 0 {
+		return nil
+	}
+
+	delete(p.pools, name)
+	s.stopHealthCheck()
+	return s.db.Close()
+}
+
+// startSQLHealthCheck pings a database at the given period until the returned
+// func is called, logging whenever the health of the pool changes. A period of
+// zero disables health checks.
+func startSQLHealthCheck(db *sql.DB, period time.Duration, logger *service.Logger) func() {
+	if period <= 0 {
+		return func() {}
+	}
+
+	stopChan := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		healthy := true
+		for {
+			select {
+			case <-ticker.C:
+			case <-stopChan:
+				return
+			}
+
+			ctx, done := context.WithTimeout(context.Background(), period)
+			err := db.PingContext(ctx)
+			done()
+
+			if err != nil {
+				if healthy {
+					logger.Warnf("SQL connection pool health check failed: %v", err)
+				}
+				healthy = false
+			} else if !healthy {
+				logger.Info("SQL connection pool health check succeeded, pool has recovered")
+				healthy = true
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopChan)
+		})
+	}
+}
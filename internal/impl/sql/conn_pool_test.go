@@ -0,0 +1,88 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestConnSettingsSharedPool(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	newOutput := func(dsn string) *sqlRawOutput {
+		conf, err := sqlRawOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+query: 'INSERT INTO things (id) VALUES (?)'
+args_mapping: 'root = [ this.id ]'
+init_statement: 'CREATE TABLE IF NOT EXISTS things (id integer not null primary key)'
+pool: shared_foo
+conn_max_open: 3
+conn_health_check_period: 10ms
+`, dsn), nil)
+		require.NoError(t, err)
+
+		out, err := newSQLRawOutputFromConfig(conf, service.MockResources())
+		require.NoError(t, err)
+		return out
+	}
+
+	first, second := newOutput(dsn), newOutput(dsn)
+	require.NoError(t, first.Connect(ctx))
+	require.NoError(t, second.Connect(ctx))
+
+	assert.Same(t, first.db, second.db)
+	assert.Equal(t, 3, first.db.Stats().MaxOpenConnections)
+
+	// Components of the same pool must target the same database.
+	require.ErrorContains(t, newOutput(dsn+"?foo=bar").Connect(ctx), "already open with a different driver or dsn")
+
+	db := first.db
+	require.NoError(t, first.Close(ctx))
+
+	// The pool remains open for the remaining component.
+	require.NoError(t, second.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1}`)),
+	}))
+	require.NoError(t, db.PingContext(ctx))
+
+	require.NoError(t, second.Close(ctx))
+	assert.Error(t, db.PingContext(ctx))
+
+	globalSQLPools.mut.Lock()
+	assert.NotContains(t, globalSQLPools.pools, "shared_foo")
+	globalSQLPools.mut.Unlock()
+}
+
+func TestConnSettingsUnsharedPool(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	logger := service.MockResources().Logger()
+
+	c := &connSettings{healthCheckPeriod: time.Millisecond * 10}
+	first, err := c.open(ctx, logger, "sqlite", dsn)
+	require.NoError(t, err)
+
+	second, err := (&connSettings{}).open(ctx, logger, "sqlite", dsn)
+	require.NoError(t, err)
+	assert.NotSame(t, first, second)
+
+	require.NoError(t, c.close(first))
+	assert.Nil(t, c.stopHealthCheck)
+	require.NoError(t, second.Close())
+
+	assert.Error(t, first.PingContext(ctx))
+}
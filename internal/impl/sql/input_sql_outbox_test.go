@@ -0,0 +1,187 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/public/components/pure"
+	_ "github.com/benthosdev/benthos/v4/public/components/sql"
+)
+
+func testOutboxTable(t testing.TB) (string, *sql.DB) {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "outbox.db") + "?_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`CREATE TABLE outbox (
+  id integer not null primary key,
+  topic varchar(50) not null,
+  payload text not null,
+  processed_at timestamp
+);`)
+	require.NoError(t, err)
+
+	for i := 1; i <= 5; i++ {
+		_, err = db.Exec(`INSERT INTO outbox (id, topic, payload) VALUES (?, ?, ?);`, i, "orders", fmt.Sprintf(`{"n":%v}`, i))
+		require.NoError(t, err)
+	}
+	return dsn, db
+}
+
+func TestSQLOutboxDelete(t *testing.T) {
+	dsn, db := testOutboxTable(t)
+
+	var consumeMut sync.Mutex
+	var received []string
+	nacked := false
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	go func() {
+		defer done()
+		assert.Eventually(t, func() bool {
+			consumeMut.Lock()
+			defer consumeMut.Unlock()
+			return len(received) == 5
+		}, time.Second*10, time.Millisecond*50)
+
+		assert.Eventually(t, func() bool {
+			var count int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM outbox`).Scan(&count); err != nil {
+				return false
+			}
+			return count == 0
+		}, time.Second*10, time.Millisecond*50)
+	}()
+
+	inputConf := fmt.Sprintf(`
+sql_outbox:
+  driver: sqlite
+  dsn: %v
+  table: outbox
+  columns: [ id, payload ]
+  batch_size: 2
+  poll_interval: 10ms
+`, dsn)
+
+	tCtx, tDone := context.WithTimeout(ctx, time.Second*20)
+	defer tDone()
+
+	streamBuilder := service.NewStreamBuilder()
+	require.NoError(t, streamBuilder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, streamBuilder.AddInputYAML(inputConf))
+	require.NoError(t, streamBuilder.AddBatchConsumerFunc(func(ctx context.Context, mb service.MessageBatch) error {
+		consumeMut.Lock()
+		defer consumeMut.Unlock()
+
+		var ns []string
+		for _, m := range mb {
+			s, err := m.AsStructured()
+			require.NoError(t, err)
+			ns = append(ns, s.(map[string]any)["payload"].(string))
+		}
+		// Reject the second batch the first time it is seen.
+		if len(received) == 2 && !nacked {
+			nacked = true
+			return errors.New("nope")
+		}
+		received = append(received, ns...)
+		return nil
+	}))
+
+	stream, err := streamBuilder.Build()
+	require.NoError(t, err)
+
+	err = stream.Run(tCtx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	consumeMut.Lock()
+	assert.True(t, nacked)
+	assert.Equal(t, []string{
+		`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`, `{"n":5}`,
+	}, received)
+	consumeMut.Unlock()
+}
+
+func TestSQLOutboxMark(t *testing.T) {
+	dsn, db := testOutboxTable(t)
+
+	_, err := db.Exec(`UPDATE outbox SET processed_at = CURRENT_TIMESTAMP WHERE id = 2;`)
+	require.NoError(t, err)
+
+	var consumeMut sync.Mutex
+	var received []int64
+
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	go func() {
+		defer done()
+		assert.Eventually(t, func() bool {
+			var count int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM outbox WHERE processed_at IS NULL`).Scan(&count); err != nil {
+				return false
+			}
+			return count == 0
+		}, time.Second*10, time.Millisecond*50)
+	}()
+
+	inputConf := fmt.Sprintf(`
+sql_outbox:
+  driver: sqlite
+  dsn: %v
+  table: outbox
+  where: topic = ?
+  args_mapping: 'root = [ "orders" ]'
+  poll_interval: 10ms
+  on_ack: mark
+`, dsn)
+
+	tCtx, tDone := context.WithTimeout(ctx, time.Second*20)
+	defer tDone()
+
+	streamBuilder := service.NewStreamBuilder()
+	require.NoError(t, streamBuilder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, streamBuilder.AddInputYAML(inputConf))
+	require.NoError(t, streamBuilder.AddBatchConsumerFunc(func(ctx context.Context, mb service.MessageBatch) error {
+		consumeMut.Lock()
+		defer consumeMut.Unlock()
+		for _, m := range mb {
+			s, err := m.AsStructured()
+			require.NoError(t, err)
+			received = append(received, s.(map[string]any)["id"].(int64))
+		}
+		return nil
+	}))
+
+	stream, err := streamBuilder.Build()
+	require.NoError(t, err)
+
+	err = stream.Run(tCtx)
+	require.ErrorIs(t, err, context.Canceled)
+
+	consumeMut.Lock()
+	assert.Equal(t, []int64{1, 3, 4, 5}, received)
+	consumeMut.Unlock()
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM outbox`).Scan(&count))
+	assert.Equal(t, 5, count)
+}
@@ -0,0 +1,159 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "modernc.org/sqlite"
+)
+
+func testSQLPollInput(t testing.TB, mRes *service.Resources, confStr string) *sqlPollInput {
+	t.Helper()
+
+	pConf, err := sqlPollInputConfig().ParseYAML(confStr, nil)
+	require.NoError(t, err)
+
+	s, err := newSQLPollInputFromConfig(pConf, mRes)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close(context.Background())
+	})
+	return s
+}
+
+func readPollBatch(t testing.TB, s *sqlPollInput) ([]string, service.AckFunc) {
+	t.Helper()
+
+	batch, ackFn, err := s.ReadBatch(context.Background())
+	require.NoError(t, err)
+
+	var rows []string
+	for _, m := range batch {
+		b, err := m.AsBytes()
+		require.NoError(t, err)
+		rows = append(rows, string(b))
+	}
+	return rows, ackFn
+}
+
+func TestSQLPollInput(t *testing.T) {
+	tCtx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE things (id integer not null primary key, name varchar(50) not null)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO things (id, name) VALUES (1, 'foo'), (2, 'bar'), (3, 'baz')`)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("cursors"))
+	confStr := `
+driver: sqlite
+dsn: ` + dsn + `
+table: things
+columns: [ id, name ]
+cursor_column: id
+batch_size: 2
+poll_interval: 10ms
+cache: cursors
+`
+
+	s := testSQLPollInput(t, mRes, confStr)
+	require.NoError(t, s.Connect(tCtx))
+
+	rows, ackFn := readPollBatch(t, s)
+	assert.Equal(t, []string{`{"id":1,"name":"foo"}`, `{"id":2,"name":"bar"}`}, rows)
+	require.NoError(t, ackFn(tCtx, nil))
+
+	rows, _ = readPollBatch(t, s)
+	assert.Equal(t, []string{`{"id":3,"name":"baz"}`}, rows)
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		_, _ = db.Exec(`INSERT INTO things (id, name) VALUES (4, 'buz')`)
+	}()
+	rows, _ = readPollBatch(t, s)
+	assert.Equal(t, []string{`{"id":4,"name":"buz"}`}, rows)
+
+	require.NoError(t, s.Close(tCtx))
+
+	// Only the first batch was acknowledged, and therefore a new input resumes
+	// after it.
+	require.NoError(t, mRes.AccessCache(tCtx, "cursors", func(c service.Cache) {
+		v, err := c.Get(tCtx, "sql_poll_cursor")
+		require.NoError(t, err)
+		assert.Equal(t, "2", string(v))
+	}))
+
+	s = testSQLPollInput(t, mRes, confStr)
+	require.NoError(t, s.Connect(tCtx))
+
+	rows, _ = readPollBatch(t, s)
+	assert.Equal(t, []string{`{"id":3,"name":"baz"}`, `{"id":4,"name":"buz"}`}, rows)
+}
+
+func TestSQLPollInputInitialCursor(t *testing.T) {
+	tCtx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec(`CREATE TABLE things (id integer not null primary key, kind varchar(50) not null)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO things (id, kind) VALUES (1, 'a'), (2, 'b'), (3, 'a'), (4, 'a')`)
+	require.NoError(t, err)
+
+	mRes := service.MockResources(service.MockResourcesOptAddCache("cursors"))
+	s := testSQLPollInput(t, mRes, `
+driver: sqlite
+dsn: `+dsn+`
+table: things
+cursor_column: id
+initial_cursor: "1"
+where: kind = ?
+args_mapping: 'root = [ "a" ]'
+cache: cursors
+`)
+	require.NoError(t, s.Connect(tCtx))
+
+	rows, _ := readPollBatch(t, s)
+	assert.Equal(t, []string{`{"id":3,"kind":"a"}`, `{"id":4,"kind":"a"}`}, rows)
+}
+
+func TestSQLPollInputConfigErrors(t *testing.T) {
+	mRes := service.MockResources(service.MockResourcesOptAddCache("cursors"))
+
+	for _, test := range []struct {
+		conf        string
+		errContains string
+	}{
+		{conf: "cache: nope", errContains: "was not found"},
+		{conf: "cache: cursors\ncolumns: [ name ]", errContains: "must include the cursor column"},
+		{conf: "cache: cursors\nbatch_size: 0", errContains: "must be greater than zero"},
+	} {
+		pConf, err := sqlPollInputConfig().ParseYAML(`
+driver: sqlite
+dsn: file:foo.db
+table: things
+cursor_column: id
+`+test.conf, nil)
+		require.NoError(t, err)
+
+		_, err = newSQLPollInputFromConfig(pConf, mRes)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), test.errContains)
+	}
+}
@@ -132,17 +132,15 @@ func (s *sqlRawInput) Connect(ctx context.Context) (err error) {
 	}
 
 	var db *sql.DB
-	if db, err = sqlOpenWithReworks(s.logger, s.driver, s.dsn); err != nil {
+	if db, err = s.connSettings.open(ctx, s.logger, s.driver, s.dsn); err != nil {
 		return err
 	}
 	defer func() {
 		if err != nil {
-			_ = db.Close()
+			_ = s.connSettings.close(db)
 		}
 	}()
 
-	s.connSettings.apply(ctx, db, s.logger)
-
 	var args []any
 	if s.argsMapping != nil {
 		var iargs any
@@ -176,7 +174,7 @@ func (s *sqlRawInput) Connect(ctx context.Context) (err error) {
 			s.rows = nil
 		}
 		if s.db != nil {
-			_ = s.db.Close()
+			_ = s.connSettings.close(s.db)
 			s.db = nil
 		}
 		s.dbMut.Unlock()
@@ -19,7 +19,13 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 		Beta().
 		Categories("Services").
 		Summary("Executes a select query and creates a message for each row received.").
-		Description(`Once the rows from the query are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).`).
+		Description(`Once the rows from the query are exhausted this input shuts down, allowing the pipeline to gracefully terminate (or the next input in a [sequence](/docs/components/inputs/sequence) to execute).
+
+### Large Result Sets
+
+Rows are read from the database as they are consumed by the pipeline, and therefore backpressure from the pipeline also applies to the query. Rows can be consumed in batches of up to ` + "`batch_size`" + ` messages, where each message is a row.
+
+Most drivers, including ` + "`mysql`" + `, stream the rows of a result set as they are read. However, a PostgreSQL server will send the entire result set of a query as soon as it is executed. Setting ` + "`fetch_size`" + ` when using the ` + "`postgres`" + ` driver executes the query with a server side cursor instead, which is read within a read-only transaction by fetching that many rows at a time.`).
 		Field(driverField).
 		Field(dsnField).
 		Field(service.NewStringField("table").
@@ -46,6 +52,15 @@ func sqlSelectInputConfig() *service.ConfigSpec {
 			Description("An optional suffix to append to the select query.").
 			Optional().
 			Advanced()).
+		Field(service.NewIntField("batch_size").
+			Description("The maximum number of rows to consume as a batch of messages.").
+			Default(1).
+			Version("4.28.0")).
+		Field(service.NewIntField("fetch_size").
+			Description("When greater than zero and the `postgres` driver is used the query is executed with a server side cursor, and this many rows are fetched from the cursor at a time.").
+			Default(0).
+			Advanced().
+			Version("4.28.0")).
 		Field(service.NewAutoRetryNacksToggleField())
 
 	for _, f := range connFields() {
@@ -75,14 +90,14 @@ input:
 }
 
 func init() {
-	err := service.RegisterInput(
+	err := service.RegisterBatchInput(
 		"sql_select", sqlSelectInputConfig(),
-		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Input, error) {
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchInput, error) {
 			i, err := newSQLSelectInputFromConfig(conf, mgr)
 			if err != nil {
 				return nil, err
 			}
-			return service.AutoRetryNacksToggled(conf, i)
+			return service.AutoRetryNacksBatchedToggled(conf, i)
 		})
 	if err != nil {
 		panic(err)
@@ -99,6 +114,11 @@ type sqlSelectInput struct {
 	builder squirrel.SelectBuilder
 	dbMut   sync.Mutex
 
+	batchSize int
+	fetchSize int
+	tx        *sql.Tx
+	fetched   int
+
 	where       string
 	argsMapping *bloblang.Executor
 
@@ -146,6 +166,20 @@ func newSQLSelectInputFromConfig(conf *service.ParsedConfig, mgr *service.Resour
 		}
 	}
 
+	if s.batchSize, err = conf.FieldInt("batch_size"); err != nil {
+		return nil, err
+	}
+	if s.batchSize < 1 {
+		return nil, fmt.Errorf("batch_size must be greater than zero, got %v", s.batchSize)
+	}
+
+	if s.fetchSize, err = conf.FieldInt("fetch_size"); err != nil {
+		return nil, err
+	}
+	if s.driver != "postgres" {
+		s.fetchSize = 0
+	}
+
 	s.builder = squirrel.Select(columns...).From(tableStr)
 	if s.driver == "postgres" || s.driver == "clickhouse" {
 		s.builder = s.builder.PlaceholderFormat(squirrel.Dollar)
@@ -184,17 +218,15 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	}
 
 	var db *sql.DB
-	if db, err = sqlOpenWithReworks(s.logger, s.driver, s.dsn); err != nil {
+	if db, err = s.connSettings.open(ctx, s.logger, s.driver, s.dsn); err != nil {
 		return
 	}
 	defer func() {
 		if err != nil {
-			_ = db.Close()
+			_ = s.connSettings.close(db)
 		}
 	}()
 
-	s.connSettings.apply(ctx, db, s.logger)
-
 	var args []any
 	if s.argsMapping != nil {
 		var iargs any
@@ -213,26 +245,29 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	if s.where != "" {
 		queryBuilder = queryBuilder.Where(s.where, args...)
 	}
-	var rows *sql.Rows
-	if rows, err = queryBuilder.RunWith(db).Query(); err != nil {
-		return
-	} else if err = rows.Err(); err != nil {
-		s.logger.With("err", err).Warn("unexpected error while execute raw select")
-	}
 
+	if s.fetchSize > 0 {
+		if err = s.declareCursor(ctx, db, queryBuilder); err != nil {
+			return
+		}
+	} else {
+		var rows *sql.Rows
+		if rows, err = queryBuilder.RunWith(db).Query(); err != nil {
+			return
+		} else if err = rows.Err(); err != nil {
+			s.logger.With("err", err).Warn("unexpected error while execute raw select")
+		}
+		s.rows = rows
+	}
 	s.db = db
-	s.rows = rows
 
 	go func() {
 		<-s.shutSig.HardStopChan()
 
 		s.dbMut.Lock()
-		if s.rows != nil {
-			_ = s.rows.Close()
-			s.rows = nil
-		}
+		s.closeRows()
 		if s.db != nil {
-			_ = s.db.Close()
+			_ = s.connSettings.close(s.db)
 		}
 		s.dbMut.Unlock()
 
@@ -241,38 +276,112 @@ func (s *sqlSelectInput) Connect(ctx context.Context) (err error) {
 	return nil
 }
 
-func (s *sqlSelectInput) Read(ctx context.Context) (*service.Message, service.AckFunc, error) {
-	s.dbMut.Lock()
-	defer s.dbMut.Unlock()
+const sqlSelectCursorName = "benthos_sql_select"
 
-	if s.db == nil && s.rows == nil {
-		return nil, nil, service.ErrNotConnected
+// declareCursor declares a server side cursor for the query within a read-only
+// transaction and fetches the first rows from it.
+func (s *sqlSelectInput) declareCursor(ctx context.Context, db *sql.DB, queryBuilder squirrel.SelectBuilder) error {
+	query, args, err := queryBuilder.ToSql()
+	if err != nil {
+		return err
 	}
 
-	if s.rows == nil {
-		return nil, nil, service.ErrEndOfInput
+	// The transaction outlives the connect call and is closed along with the
+	// rows.
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DECLARE "+sqlSelectCursorName+" NO SCROLL CURSOR FOR "+query, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	s.tx = tx
+	if err = s.fetchRows(); err != nil {
+		s.closeRows()
+		return err
 	}
+	return nil
+}
+
+func (s *sqlSelectInput) fetchRows() (err error) {
+	s.fetched = 0
+	s.rows, err = s.tx.Query(fmt.Sprintf("FETCH FORWARD %v FROM %v", s.fetchSize, sqlSelectCursorName))
+	return
+}
 
-	if !s.rows.Next() {
-		err := s.rows.Err()
-		if err == nil {
-			err = service.ErrEndOfInput
+// nextRow returns the next row of the query, fetching more rows from the cursor
+// when one is used, or nil once all rows have been read.
+func (s *sqlSelectInput) nextRow() (map[string]any, error) {
+	for s.rows != nil {
+		if s.rows.Next() {
+			s.fetched++
+			return sqlRowToMap(s.rows)
+		}
+		if err := s.rows.Err(); err != nil {
+			return nil, err
 		}
 		_ = s.rows.Close()
 		s.rows = nil
-		return nil, nil, err
+
+		// A cursor is exhausted once a fetch returns fewer rows than requested.
+		if s.tx == nil || s.fetched < s.fetchSize {
+			break
+		}
+		if err := s.fetchRows(); err != nil {
+			return nil, err
+		}
 	}
+	return nil, nil
+}
 
-	obj, err := sqlRowToMap(s.rows)
-	if err != nil {
+func (s *sqlSelectInput) closeRows() {
+	if s.rows != nil {
 		_ = s.rows.Close()
 		s.rows = nil
-		return nil, nil, err
 	}
+	if s.tx != nil {
+		// The transaction is read-only, and rolling it back also closes the
+		// cursor.
+		_ = s.tx.Rollback()
+		s.tx = nil
+	}
+}
+
+func (s *sqlSelectInput) ReadBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	s.dbMut.Lock()
+	defer s.dbMut.Unlock()
+
+	if s.db == nil && s.rows == nil {
+		return nil, nil, service.ErrNotConnected
+	}
+
+	if s.rows == nil {
+		s.closeRows()
+		return nil, nil, service.ErrEndOfInput
+	}
+
+	var batch service.MessageBatch
+	for len(batch) < s.batchSize {
+		obj, err := s.nextRow()
+		if err != nil {
+			s.closeRows()
+			return nil, nil, err
+		}
+		if obj == nil {
+			break
+		}
 
-	msg := service.NewMessage(nil)
-	msg.SetStructuredMut(obj)
-	return msg, func(ctx context.Context, err error) error {
+		msg := service.NewMessage(nil)
+		msg.SetStructuredMut(obj)
+		batch = append(batch, msg)
+	}
+	if len(batch) == 0 {
+		s.closeRows()
+		return nil, nil, service.ErrEndOfInput
+	}
+	return batch, func(ctx context.Context, err error) error {
 		// Nacks are handled by AutoRetryNacks because we don't have an explicit
 		// ack mechanism right now.
 		return nil
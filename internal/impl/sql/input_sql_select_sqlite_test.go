@@ -0,0 +1,75 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/public/components/pure"
+	_ "github.com/benthosdev/benthos/v4/public/components/sql"
+)
+
+func TestSQLSelectInputBatches(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE things (id integer not null primary key, name varchar(50) not null)`)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = db.Exec(`INSERT INTO things (id, name) VALUES (?, ?)`, i, fmt.Sprintf("thing %v", i))
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+
+	streamBuilder := service.NewStreamBuilder()
+	require.NoError(t, streamBuilder.SetLoggerYAML(`level: OFF`))
+	require.NoError(t, streamBuilder.AddInputYAML(fmt.Sprintf(`
+sql_select:
+  driver: sqlite
+  dsn: %v
+  table: things
+  columns: [ id, name ]
+  suffix: ORDER BY id
+  batch_size: 2
+`, dsn)))
+
+	var batchesMut sync.Mutex
+	var batches [][]string
+	require.NoError(t, streamBuilder.AddBatchConsumerFunc(func(ctx context.Context, mb service.MessageBatch) error {
+		var batch []string
+		for _, m := range mb {
+			b, err := m.AsBytes()
+			require.NoError(t, err)
+			batch = append(batch, string(b))
+		}
+		batchesMut.Lock()
+		batches = append(batches, batch)
+		batchesMut.Unlock()
+		return nil
+	}))
+
+	stream, err := streamBuilder.Build()
+	require.NoError(t, err)
+	require.NoError(t, stream.Run(tCtx))
+
+	batchesMut.Lock()
+	defer batchesMut.Unlock()
+	assert.Equal(t, [][]string{
+		{`{"id":0,"name":"thing 0"}`, `{"id":1,"name":"thing 1"}`},
+		{`{"id":2,"name":"thing 2"}`, `{"id":3,"name":"thing 3"}`},
+		{`{"id":4,"name":"thing 4"}`},
+	}, batches)
+}
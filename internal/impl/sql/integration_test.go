@@ -372,6 +372,8 @@ sql_select:
   table: $table
   columns: [ "*" ]
   suffix: ' ORDER BY "bar" ASC'
+  # Only applies to postgres, where rows are fetched from a cursor.
+  fetch_size: 3
 processors:
   # For some reason MySQL driver doesn't resolve to integer by default.
   - bloblang: |
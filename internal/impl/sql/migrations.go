@@ -0,0 +1,313 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	migFieldMigrations = "migrations"
+	migFieldFiles      = "files"
+	migFieldStatements = "statements"
+	migFieldVersion    = "version"
+	migFieldStatement  = "statement"
+	migFieldTable      = "table"
+	migFieldLock       = "lock"
+)
+
+func migrationsField() *service.ConfigField {
+	return service.NewObjectField(migFieldMigrations,
+		service.NewStringListField(migFieldFiles).
+			Description("A list of file paths containing versioned migrations. Glob patterns are supported, including super globs (double star). The version of each migration is parsed from the leading digits of its file name, e.g. `0001_create_things.sql` has the version `1`.").
+			Example([]any{`./migrations/*.sql`}).
+			Default([]any{}),
+		service.NewObjectListField(migFieldStatements,
+			service.NewIntField(migFieldVersion).
+				Description("The version of the migration, which must be unique across all migrations."),
+			service.NewStringField(migFieldStatement).
+				Description("The statement to execute."),
+		).
+			Description("A list of versioned migrations embedded within the config.").
+			Example([]any{
+				map[string]any{
+					"version":   1,
+					"statement": "CREATE TABLE things (foo varchar(50) not null, bar integer, primary key (foo));",
+				},
+			}).
+			Default([]any{}),
+		service.NewStringField(migFieldTable).
+			Description("The table in which applied migration versions are recorded, which is created if it does not already exist.").
+			Default("benthos_schema_migrations"),
+		service.NewBoolField(migFieldLock).
+			Description("Whether to hold a database lock whilst migrations are applied, preventing multiple instances from applying migrations concurrently. The `postgres` driver uses an advisory lock, the `mysql` driver uses a named lock and the `mssql` driver uses an application lock. The `sqlite` driver relies on its database level write lock.").
+			Default(true),
+	).
+		Description(`
+Versioned schema migrations to apply immediately upon the first connection to the target database, allowing pipelines that own their tables to manage their schema alongside their config. Migrations from both ` + "`files` and `statements`" + ` are applied in order of their version, each within its own transaction, and migrations with a version that has already been recorded within the migrations table are skipped.
+
+Unlike ` + "`init_files` and `init_statement`" + `, if a migration fails the component fails to connect and the migration is attempted again upon the next connection attempt. Migrations are currently supported by the ` + "`mysql`, `postgres`, `mssql` and `sqlite`" + ` drivers. Note that the ` + "`mysql`" + ` driver does not support transactional DDL, and therefore a failed migration may be partially applied. A migration containing multiple statements may also require a driver specific DSN parameter, such as ` + "`multiStatements=true`" + ` for ` + "`mysql`" + `.
+`).
+		Advanced().
+		Version("4.28.0")
+}
+
+var migrationFileVersionRegexp = regexp.MustCompile(`^([0-9]+)`)
+
+type sqlMigration struct {
+	version   int64
+	source    string
+	statement string
+}
+
+// sqlMigrator applies versioned migrations to a database, recording the
+// versions that have been applied within a table.
+type sqlMigrator struct {
+	driver     string
+	table      string
+	lock       bool
+	migrations []sqlMigration
+
+	mut  sync.Mutex
+	done bool
+}
+
+func migratorFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*sqlMigrator, error) {
+	if !conf.Contains(migFieldMigrations) {
+		return nil, nil
+	}
+
+	driver, err := conf.FieldString("driver")
+	if err != nil {
+		return nil, err
+	}
+
+	conf = conf.Namespace(migFieldMigrations)
+	m := &sqlMigrator{driver: driver}
+
+	files, err := conf.FieldStringList(migFieldFiles)
+	if err != nil {
+		return nil, err
+	}
+	if files, err = filepath.Globs(mgr.FS(), files); err != nil {
+		return nil, fmt.Errorf("failed to expand migration file glob patterns: %w", err)
+	}
+	for _, p := range files {
+		vStr := migrationFileVersionRegexp.FindString(path.Base(p))
+		if vStr == "" {
+			return nil, fmt.Errorf("migration file '%v' does not begin with a version number", p)
+		}
+		version, err := strconv.ParseInt(vStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file '%v' has an invalid version: %w", p, err)
+		}
+		stmtBytes, err := ifs.ReadFile(mgr.FS(), p)
+		if err != nil {
+			return nil, err
+		}
+		m.migrations = append(m.migrations, sqlMigration{
+			version:   version,
+			source:    p,
+			statement: string(stmtBytes),
+		})
+	}
+
+	stmtConfs, err := conf.FieldObjectList(migFieldStatements)
+	if err != nil {
+		return nil, err
+	}
+	for i, sConf := range stmtConfs {
+		version, err := sConf.FieldInt(migFieldVersion)
+		if err != nil {
+			return nil, err
+		}
+		stmt, err := sConf.FieldString(migFieldStatement)
+		if err != nil {
+			return nil, err
+		}
+		m.migrations = append(m.migrations, sqlMigration{
+			version:   int64(version),
+			source:    fmt.Sprintf("statements[%v]", i),
+			statement: stmt,
+		})
+	}
+
+	if len(m.migrations) == 0 {
+		return nil, nil
+	}
+
+	switch driver {
+	case "mysql", "postgres", "mssql", "sqlite":
+	default:
+		return nil, fmt.Errorf("migrations are not supported by the %v driver", driver)
+	}
+
+	sort.SliceStable(m.migrations, func(i, j int) bool {
+		return m.migrations[i].version < m.migrations[j].version
+	})
+	for i := 1; i < len(m.migrations); i++ {
+		if m.migrations[i].version == m.migrations[i-1].version {
+			return nil, fmt.Errorf("migrations %v and %v share the version %v", m.migrations[i-1].source, m.migrations[i].source, m.migrations[i].version)
+		}
+	}
+
+	if m.table, err = conf.FieldString(migFieldTable); err != nil {
+		return nil, err
+	}
+	if m.table == "" {
+		return nil, errors.New("migrations table must not be empty")
+	}
+	if m.lock, err = conf.FieldBool(migFieldLock); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *sqlMigrator) placeholderFormat() squirrel.PlaceholderFormat {
+	if m.driver == "postgres" {
+		return squirrel.Dollar
+	}
+	return squirrel.Question
+}
+
+func (m *sqlMigrator) createTableStatement() string {
+	if m.driver == "mssql" {
+		return fmt.Sprintf("IF OBJECT_ID(N'%[1]v', N'U') IS NULL CREATE TABLE %[1]v (version BIGINT NOT NULL PRIMARY KEY, applied_at VARCHAR(64) NOT NULL)", m.table)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %v (version BIGINT NOT NULL PRIMARY KEY, applied_at VARCHAR(64) NOT NULL)", m.table)
+}
+
+// acquireLock obtains a session level lock on the connection for drivers that
+// support one, and returns a func that releases it.
+func (m *sqlMigrator) acquireLock(ctx context.Context, conn *sql.Conn) (func(), error) {
+	lockName := "benthos_migrations_" + m.table
+
+	var lockStmt, unlockStmt string
+	var lockArg any = lockName
+	switch m.driver {
+	case "postgres":
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(lockName))
+		lockArg = int64(h.Sum64())
+		lockStmt, unlockStmt = "SELECT pg_advisory_lock($1)", "SELECT pg_advisory_unlock($1)"
+	case "mysql":
+		lockStmt, unlockStmt = "SELECT GET_LOCK(?, -1)", "SELECT RELEASE_LOCK(?)"
+	case "mssql":
+		lockStmt = "EXEC sp_getapplock @Resource = ?, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = -1"
+		unlockStmt = "EXEC sp_releaseapplock @Resource = ?, @LockOwner = 'Session'"
+	default:
+		return func() {}, nil
+	}
+
+	if _, err := conn.ExecContext(ctx, lockStmt, lockArg); err != nil {
+		return nil, fmt.Errorf("failed to acquire migrations lock: %w", err)
+	}
+	return func() {
+		_, _ = conn.ExecContext(context.Background(), unlockStmt, lockArg)
+	}, nil
+}
+
+func (m *sqlMigrator) appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]struct{}, error) {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf("SELECT version FROM %v", m.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]struct{}{}
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		applied[v] = struct{}{}
+	}
+	return applied, rows.Err()
+}
+
+func (m *sqlMigrator) applyMigration(ctx context.Context, conn *sql.Conn, mig sqlMigration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, mig.statement); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	insertStmt, args, err := squirrel.Insert(m.table).
+		Columns("version", "applied_at").
+		Values(mig.version, time.Now().UTC().Format(time.RFC3339)).
+		PlaceholderFormat(m.placeholderFormat()).
+		ToSql()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, insertStmt, args...); err != nil {
+		_ = tx.Rollback()
+		return fmt.Errorf("failed to record version: %w", err)
+	}
+	return tx.Commit()
+}
+
+// run applies all migrations that have not yet been recorded as applied. Once
+// a run succeeds subsequent calls are a no-op.
+func (m *sqlMigrator) run(ctx context.Context, db *sql.DB, log *service.Logger) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if m.done {
+		return nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.lock {
+		unlock, err := m.acquireLock(ctx, conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+
+	if _, err := conn.ExecContext(ctx, m.createTableStatement()); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	applied, err := m.appliedVersions(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, mig := range m.migrations {
+		if _, exists := applied[mig.version]; exists {
+			continue
+		}
+		if err := m.applyMigration(ctx, conn, mig); err != nil {
+			return fmt.Errorf("failed to apply migration %v (%v): %w", mig.version, mig.source, err)
+		}
+		log.Infof("Applied migration %v (%v)", mig.version, mig.source)
+	}
+
+	m.done = true
+	return nil
+}
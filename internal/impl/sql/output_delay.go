@@ -0,0 +1,453 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"github.com/Masterminds/squirrel"
+	"github.com/cenkalti/backoff/v4"
+
+	"github.com/benthosdev/benthos/v4/internal/atrest"
+	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	doFieldOutput      = "output"
+	doFieldPath        = "path"
+	doFieldDelay       = "delay"
+	doFieldUntil       = "until"
+	doFieldEncryption  = "encryption"
+	doFieldBatchSize   = "batch_size"
+	doFieldMaxInFlight = "max_in_flight"
+)
+
+func delayOutputConfig() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Utility").
+		Version("4.28.0").
+		Summary("Holds messages until a fixed delay or a per-message timestamp has elapsed before writing them to a child output, storing scheduled messages in an SQLite database so that they survive restarts.").
+		Description(`
+The time at which each message is due is either the time it was written plus the `+"`delay`"+`, or the timestamp returned by the Bloblang mapping `+"`until`"+`. Messages that are already due are written to the child `+"`output`"+` immediately, and the remaining messages are stored in the database at `+"`path`"+` and acknowledged.
+
+Stored messages are written to the child output in the order of their due times, in batches of up to `+"`batch_size`"+` messages, and are only deleted from the database once they have been written successfully. Messages that fail to be written are retried with an exponential backoff of up to a minute. Scheduled messages therefore have at-least-once delivery guarantees that survive restarts of the service, but not corruption or loss of the database file.
+
+The counters `+"`delay_scheduled`"+` and `+"`delay_released`"+` are incremented for each message that is stored and for each stored message that is written to the child output respectively.`).
+		Example(
+			"Retry Later",
+			"Here we delay messages that were rejected by an API for five minutes before attempting to deliver them again.",
+			`
+output:
+  fallback:
+    - http_client:
+        url: http://orders.example.com/ingest
+    - delay:
+        path: ./retry_later.db
+        delay: 5m
+        output:
+          http_client:
+            url: http://orders.example.com/ingest
+`,
+		).
+		Example(
+			"Scheduled Delivery",
+			"Messages can be scheduled for delivery at a timestamp within the message.",
+			`
+output:
+  delay:
+    path: ./reminders.db
+    until: root = this.send_at.ts_parse("2006-01-02T15:04:05Z07:00")
+    output:
+      kafka_franz:
+        seed_brokers: [ localhost:9092 ]
+        topic: reminders
+`,
+		).
+		Fields(
+			service.NewOutputField(doFieldOutput).
+				Description("The child output to write messages to once they are due."),
+			service.NewStringField(doFieldPath).
+				Description("The path of the database file that scheduled messages are stored in, which will be created if it does not already exist."),
+			service.NewDurationField(doFieldDelay).
+				Description("A fixed period to delay all messages by. Either this field or `until` must be specified.").
+				Example("30s").
+				Example("1h").
+				Optional(),
+			service.NewBloblangField(doFieldUntil).
+				Description("A Bloblang mapping that returns the time at which each message is due, either as a timestamp, a unix timestamp in seconds or an RFC 3339 formatted string. Either this field or `delay` must be specified.").
+				Example(`root = this.deliver_at`).
+				Example(`root = @retry_at.number()`).
+				Optional(),
+			atrest.FieldSpec(doFieldEncryption),
+			service.NewIntField(doFieldBatchSize).
+				Description("The maximum number of stored messages to write to the child output as a single batch.").
+				Default(64).
+				Advanced(),
+			service.NewIntField(doFieldMaxInFlight).
+				Description("The maximum number of batches to have in flight at a given time.").
+				Default(64),
+		)
+}
+
+func init() {
+	err := service.RegisterBatchOutput("delay", delayOutputConfig(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.BatchOutput, batchPolicy service.BatchPolicy, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldInt(doFieldMaxInFlight); err != nil {
+				return
+			}
+			out, err = newDelayOutputFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+//------------------------------------------------------------------------------
+
+type delayChildOutput interface {
+	WriteBatch(ctx context.Context, b service.MessageBatch) error
+	Close(ctx context.Context) error
+}
+
+type delayOutput struct {
+	child     delayChildOutput
+	db        *sql.DB
+	delay     time.Duration
+	until     *bloblang.Executor
+	cipher    *atrest.Cipher
+	batchSize int
+	log       *service.Logger
+	nowFn     func() time.Time
+
+	startOnce sync.Once
+	scheduled chan struct{}
+	shutSig   *shutdown.Signaller
+
+	mScheduled *service.MetricCounter
+	mReleased  *service.MetricCounter
+}
+
+func newDelayOutputFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*delayOutput, error) {
+	d := &delayOutput{
+		log:        mgr.Logger(),
+		nowFn:      time.Now,
+		scheduled:  make(chan struct{}, 1),
+		shutSig:    shutdown.NewSignaller(),
+		mScheduled: mgr.Metrics().NewCounter("delay_scheduled"),
+		mReleased:  mgr.Metrics().NewCounter("delay_released"),
+	}
+
+	var err error
+	if conf.Contains(doFieldDelay) {
+		if d.delay, err = conf.FieldDuration(doFieldDelay); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(doFieldUntil) {
+		if d.until, err = conf.FieldBloblang(doFieldUntil); err != nil {
+			return nil, err
+		}
+	}
+	if (d.until == nil) == !conf.Contains(doFieldDelay) {
+		return nil, fmt.Errorf("exactly one of %v or %v must be specified", doFieldDelay, doFieldUntil)
+	}
+	if d.cipher, err = atrest.CipherFromParsed(conf, doFieldEncryption); err != nil {
+		return nil, err
+	}
+	if d.batchSize, err = conf.FieldInt(doFieldBatchSize); err != nil {
+		return nil, err
+	}
+	if d.batchSize < 1 {
+		return nil, fmt.Errorf("%v must be greater than zero", doFieldBatchSize)
+	}
+
+	path, err := conf.FieldString(doFieldPath)
+	if err != nil {
+		return nil, err
+	}
+	if d.db, err = openDelayDB(path); err != nil {
+		return nil, err
+	}
+
+	if d.child, err = conf.FieldOutput(doFieldOutput); err != nil {
+		_ = d.db.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func openDelayDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = db.Exec(`
+CREATE TABLE IF NOT EXISTS scheduled (
+  id       INTEGER PRIMARY KEY AUTOINCREMENT,
+  due      INTEGER NOT NULL,
+  content  BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS scheduled_due ON scheduled (due, id);
+`); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// dueAt returns the time at which a message should be written to the child
+// output.
+func (d *delayOutput) dueAt(msg *service.Message, now time.Time) (time.Time, error) {
+	if d.until == nil {
+		return now.Add(d.delay), nil
+	}
+
+	res, err := msg.BloblangQuery(d.until)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if res == nil {
+		return time.Time{}, errors.New("mapping deleted the message")
+	}
+
+	// String results are raw bytes rather than structured values.
+	var v any
+	if v, err = res.AsStructured(); err != nil {
+		if v, err = res.AsBytes(); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return value.IGetTimestamp(v)
+}
+
+func (d *delayOutput) Connect(ctx context.Context) error {
+	d.startOnce.Do(func() {
+		go d.releaseLoop()
+	})
+	return nil
+}
+
+// WriteBatch writes messages that are already due to the child output and
+// stores the remaining messages until they are due.
+func (d *delayOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+	now := d.nowFn()
+
+	var dueNow service.MessageBatch
+	builder := squirrel.Insert("scheduled").Columns("due", "content")
+	var pending int
+
+	for i, msg := range batch {
+		due, err := d.dueAt(msg, now)
+		if err != nil {
+			return fmt.Errorf("failed to determine the due time of message %v: %w", i, err)
+		}
+		if !due.After(now) {
+			dueNow = append(dueNow, msg)
+			continue
+		}
+		contentBytes, err := appendBatchV0(nil, service.MessageBatch{msg})
+		if err != nil {
+			return err
+		}
+		if contentBytes, err = d.cipher.Seal(contentBytes); err != nil {
+			return err
+		}
+		builder = builder.Values(due.UnixNano(), contentBytes)
+		pending++
+	}
+
+	if len(dueNow) > 0 {
+		if err := d.child.WriteBatch(ctx, dueNow); err != nil {
+			return err
+		}
+	}
+	if pending == 0 {
+		return nil
+	}
+
+	if _, err := execRetries(ctx, builder.RunWith(d.db)); err != nil {
+		return fmt.Errorf("failed to store scheduled messages: %w", err)
+	}
+	d.mScheduled.Incr(int64(pending))
+
+	select {
+	case d.scheduled <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// releaseLoop writes stored messages to the child output as they become due
+// until the output is closed.
+func (d *delayOutput) releaseLoop() {
+	defer d.shutSig.TriggerHasStopped()
+
+	ctx, done := d.shutSig.SoftStopCtx(context.Background())
+	defer done()
+
+	boff := backoff.NewExponentialBackOff()
+	boff.InitialInterval = time.Second
+	boff.MaxInterval = time.Minute
+	boff.MaxElapsedTime = 0
+
+	for {
+		wait, err := d.releaseDue(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			wait = boff.NextBackOff()
+			d.log.Errorf("Failed to write scheduled messages, retrying in %v: %v", wait, err)
+		} else {
+			boff.Reset()
+		}
+		if wait <= 0 {
+			continue
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-d.scheduled:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// releaseDue writes a batch of stored messages that are due to the child
+// output, and returns the period to wait before more messages are due.
+func (d *delayOutput) releaseDue(ctx context.Context) (time.Duration, error) {
+	now := d.nowFn()
+
+	rows, err := squirrel.Select("id", "content").
+		From("scheduled").
+		Where(squirrel.LtOrEq{"due": now.UnixNano()}).
+		OrderBy("due", "id").
+		Limit(uint64(d.batchSize)).
+		RunWith(d.db).
+		QueryContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var ids []int64
+	var batch service.MessageBatch
+	for rows.Next() {
+		var id int64
+		var contentBytes []byte
+		if err := rows.Scan(&id, &contentBytes); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if contentBytes, err = d.cipher.Open(contentBytes); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to decrypt scheduled message %v: %w", id, err)
+		}
+		msgs, _, err := readBatch(contentBytes)
+		if err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to read scheduled message %v: %w", id, err)
+		}
+		for _, m := range msgs {
+			ids = append(ids, id)
+			batch = append(batch, m)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	if len(batch) == 0 {
+		return d.untilNextDue(ctx, now)
+	}
+
+	indexer := batch.Index()
+	wErr := d.child.WriteBatch(ctx, batch)
+
+	written := ids
+	if wErr != nil {
+		var bErr *service.BatchError
+		if !errors.As(wErr, &bErr) || bErr.IndexedErrors() == 0 {
+			return 0, wErr
+		}
+		failed := map[int64]struct{}{}
+		bErr.WalkMessagesIndexedBy(indexer, func(i int, _ *service.Message, err error) bool {
+			if err != nil && i >= 0 && i < len(ids) {
+				failed[ids[i]] = struct{}{}
+			}
+			return true
+		})
+		written = nil
+		for _, id := range ids {
+			if _, exists := failed[id]; !exists {
+				written = append(written, id)
+			}
+		}
+	}
+
+	if len(written) > 0 {
+		if _, err := execRetries(ctx, squirrel.Delete("scheduled").
+			Where(squirrel.Eq{"id": written}).
+			RunWith(d.db)); err != nil {
+			return 0, fmt.Errorf("failed to delete released messages: %w", err)
+		}
+		d.mReleased.Incr(int64(len(written)))
+	}
+	return 0, wErr
+}
+
+// untilNextDue returns the period until the next stored message is due, or an
+// hour when there are none.
+func (d *delayOutput) untilNextDue(ctx context.Context, now time.Time) (time.Duration, error) {
+	var nextDue sql.NullInt64
+	if err := queryRowRetries(ctx, squirrel.Select("MIN(due)").
+		From("scheduled").
+		RunWith(d.db), &nextDue); err != nil {
+		return 0, err
+	}
+	if !nextDue.Valid {
+		return time.Hour, nil
+	}
+	wait := time.Unix(0, nextDue.Int64).Sub(now)
+	if wait <= 0 {
+		// Messages were scheduled after the query for due messages.
+		wait = time.Millisecond
+	}
+	return wait, nil
+}
+
+func (d *delayOutput) Close(ctx context.Context) error {
+	d.shutSig.TriggerSoftStop()
+
+	// When the release loop was never started there is nothing to wait for.
+	d.startOnce.Do(func() {
+		d.shutSig.TriggerHasStopped()
+	})
+	select {
+	case <-d.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	childErr := d.child.Close(ctx)
+	if err := d.db.Close(); err != nil {
+		return err
+	}
+	return childErr
+}
@@ -0,0 +1,279 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+
+	_ "github.com/benthosdev/benthos/v4/public/components/pure"
+	_ "modernc.org/sqlite"
+)
+
+type delayTestChild struct {
+	mut     sync.Mutex
+	written []string
+	reject  map[string]error
+}
+
+func (c *delayTestChild) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	var bErr *service.BatchError
+	for i, m := range b {
+		mBytes, err := m.AsBytes()
+		if err != nil {
+			return err
+		}
+		if err, exists := c.reject[string(mBytes)]; exists {
+			if bErr == nil {
+				bErr = service.NewBatchError(b, err)
+			}
+			bErr.Failed(i, err)
+			continue
+		}
+		c.written = append(c.written, string(mBytes))
+	}
+	if bErr != nil {
+		return bErr
+	}
+	return nil
+}
+
+func (c *delayTestChild) Close(ctx context.Context) error {
+	return nil
+}
+
+func (c *delayTestChild) Written() []string {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return append([]string(nil), c.written...)
+}
+
+func testDelayOutput(t testing.TB, confStr string, now *time.Time) (*delayOutput, *delayTestChild) {
+	t.Helper()
+
+	pConf, err := delayOutputConfig().ParseYAML(confStr+`
+output:
+  drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	d, err := newDelayOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = d.Close(context.Background())
+	})
+
+	child := &delayTestChild{}
+	d.child = child
+	d.nowFn = func() time.Time {
+		return *now
+	}
+	return d, child
+}
+
+func TestDelayOutputFixedDelay(t *testing.T) {
+	tCtx := context.Background()
+	path := filepath.Join(t.TempDir(), "delay.db")
+
+	now := time.Unix(1000, 0)
+	d, child := testDelayOutput(t, `
+path: `+path+`
+delay: 1m
+`, &now)
+
+	batch := service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+	}
+	batch[1].MetaSetMut("baz", "buz")
+	require.NoError(t, d.WriteBatch(tCtx, batch))
+	assert.Empty(t, child.Written())
+
+	wait, err := d.releaseDue(tCtx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, wait)
+	assert.Empty(t, child.Written())
+
+	now = now.Add(time.Minute)
+
+	var released service.MessageBatch
+	d.child = delayChildFunc(func(ctx context.Context, b service.MessageBatch) error {
+		released = append(released, b...)
+		return child.WriteBatch(ctx, b)
+	})
+	wait, err = d.releaseDue(tCtx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+	assert.Equal(t, []string{"foo", "bar"}, child.Written())
+
+	require.Len(t, released, 2)
+	v, _ := released[1].MetaGet("baz")
+	assert.Equal(t, "buz", v)
+
+	wait, err = d.releaseDue(tCtx)
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, wait)
+	assert.Equal(t, []string{"foo", "bar"}, child.Written())
+}
+
+func TestDelayOutputUntil(t *testing.T) {
+	tCtx := context.Background()
+	path := filepath.Join(t.TempDir(), "delay.db")
+
+	now := time.Unix(1000, 0)
+	d, child := testDelayOutput(t, `
+path: `+path+`
+until: root = this.at
+`, &now)
+
+	require.NoError(t, d.WriteBatch(tCtx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"a","at":1030}`)),
+		service.NewMessage([]byte(`{"id":"b","at":"1970-01-01T00:17:00Z"}`)),
+		service.NewMessage([]byte(`{"id":"c","at":900}`)),
+	}))
+	assert.Equal(t, []string{`{"id":"c","at":900}`}, child.Written())
+
+	wait, err := d.releaseDue(tCtx)
+	require.NoError(t, err)
+	assert.Equal(t, 20*time.Second, wait)
+
+	now = time.Unix(1030, 0)
+	_, err = d.releaseDue(tCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		`{"id":"c","at":900}`,
+		`{"id":"b","at":"1970-01-01T00:17:00Z"}`,
+		`{"id":"a","at":1030}`,
+	}, child.Written())
+
+	err = d.WriteBatch(tCtx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":"d","at":"nope"}`)),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "due time")
+}
+
+func TestDelayOutputRetainsFailed(t *testing.T) {
+	tCtx := context.Background()
+	path := filepath.Join(t.TempDir(), "delay.db")
+
+	now := time.Unix(1000, 0)
+	d, child := testDelayOutput(t, `
+path: `+path+`
+delay: 1s
+`, &now)
+
+	require.NoError(t, d.WriteBatch(tCtx, service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+		service.NewMessage([]byte("bar")),
+	}))
+
+	now = now.Add(time.Second)
+	child.reject = map[string]error{"foo": errors.New("nope")}
+
+	_, err := d.releaseDue(tCtx)
+	require.Error(t, err)
+	assert.Equal(t, []string{"bar"}, child.Written())
+
+	child.reject = nil
+	_, err = d.releaseDue(tCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "foo"}, child.Written())
+}
+
+func TestDelayOutputSurvivesRestart(t *testing.T) {
+	tCtx := context.Background()
+	path := filepath.Join(t.TempDir(), "delay.db")
+	confStr := `
+path: ` + path + `
+delay: 1s
+`
+
+	now := time.Unix(1000, 0)
+	d, _ := testDelayOutput(t, confStr, &now)
+	require.NoError(t, d.WriteBatch(tCtx, service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+	}))
+	require.NoError(t, d.Close(tCtx))
+
+	now = now.Add(time.Second)
+	d, child := testDelayOutput(t, confStr, &now)
+	_, err := d.releaseDue(tCtx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo"}, child.Written())
+}
+
+func TestDelayOutputReleaseLoop(t *testing.T) {
+	tCtx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	path := filepath.Join(t.TempDir(), "delay.db")
+
+	pConf, err := delayOutputConfig().ParseYAML(`
+path: `+path+`
+delay: 10ms
+output:
+  drop: {}
+`, nil)
+	require.NoError(t, err)
+
+	d, err := newDelayOutputFromParsed(pConf, service.MockResources())
+	require.NoError(t, err)
+
+	child := &delayTestChild{}
+	d.child = child
+
+	require.NoError(t, d.Connect(tCtx))
+	require.NoError(t, d.WriteBatch(tCtx, service.MessageBatch{
+		service.NewMessage([]byte("foo")),
+	}))
+
+	assert.Eventually(t, func() bool {
+		return len(child.Written()) == 1
+	}, time.Second*5, time.Millisecond*10)
+
+	require.NoError(t, d.Close(tCtx))
+}
+
+func TestDelayOutputConfigErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "delay.db")
+
+	for _, confStr := range []string{
+		`path: ` + path,
+		`
+path: ` + path + `
+delay: 1s
+until: root = now()
+`,
+	} {
+		pConf, err := delayOutputConfig().ParseYAML(confStr+`
+output:
+  drop: {}
+`, nil)
+		require.NoError(t, err)
+
+		_, err = newDelayOutputFromParsed(pConf, service.MockResources())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exactly one of")
+	}
+}
+
+type delayChildFunc func(ctx context.Context, b service.MessageBatch) error
+
+func (f delayChildFunc) WriteBatch(ctx context.Context, b service.MessageBatch) error {
+	return f(ctx, b)
+}
+
+func (f delayChildFunc) Close(ctx context.Context) error {
+	return nil
+}
@@ -2,8 +2,13 @@ package sql
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/benthosdev/benthos/v4/public/service"
@@ -28,3 +33,299 @@ args_mapping: 'root = [ this.id ]'
 	require.NoError(t, err)
 	require.NoError(t, insertOutput.Close(context.Background()))
 }
+
+func TestSQLInsertOutputTables(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`CREATE TABLE orders (id integer not null primary key, customer varchar(50) not null)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`CREATE TABLE items (order_id integer not null, name varchar(50) not null)`)
+	require.NoError(t, err)
+
+	conf, err := sqlInsertOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+tables:
+  - table: orders
+    columns: [ id, customer ]
+    args_mapping: 'root = if this.customer != null { [ this.id, this.customer ] } else { deleted() }'
+  - table: items
+    columns: [ order_id, name ]
+    args_mapping: 'root = [ this.id, this.item ]'
+`, dsn), nil)
+	require.NoError(t, err)
+
+	out, err := newSQLInsertOutputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, out.Close(context.Background()))
+	})
+
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"customer":"foo","item":"a"}`)),
+		service.NewMessage([]byte(`{"id":1,"item":"b"}`)),
+		service.NewMessage([]byte(`{"id":2,"customer":"bar","item":"c"}`)),
+	}))
+
+	// The second order violates the primary key of the orders table, and
+	// therefore none of the rows of the batch are written.
+	require.Error(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":3,"customer":"baz","item":"d"}`)),
+		service.NewMessage([]byte(`{"id":3,"customer":"baz","item":"e"}`)),
+	}))
+
+	rowStrs := func(query string) (res []string) {
+		rows, err := db.Query(query)
+		require.NoError(t, err)
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			var name string
+			require.NoError(t, rows.Scan(&id, &name))
+			res = append(res, fmt.Sprintf("%v:%v", id, name))
+		}
+		require.NoError(t, rows.Err())
+		return
+	}
+
+	assert.Equal(t, []string{"1:foo", "2:bar"}, rowStrs(`SELECT id, customer FROM orders ORDER BY id`))
+	assert.Equal(t, []string{"1:a", "1:b", "2:c"}, rowStrs(`SELECT order_id, name FROM items ORDER BY name`))
+}
+
+func TestSQLInsertOutputTablesConfigErrors(t *testing.T) {
+	conf, err := sqlInsertOutputConfig().ParseYAML(`
+driver: sqlite
+dsn: foo
+table: foo
+tables:
+  - table: orders
+    columns: [ id ]
+    args_mapping: 'root = [ this.id ]'
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newSQLInsertOutputFromConfig(conf, service.MockResources())
+	require.ErrorContains(t, err, "cannot be set along with tables")
+}
+
+func TestSQLInsertOutputUpsert(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`CREATE TABLE users (id integer not null primary key, name varchar(50) not null, created varchar(50) not null)`)
+	require.NoError(t, err)
+
+	conf, err := sqlInsertOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+table: users
+columns: [ id, name, created ]
+args_mapping: 'root = [ this.id, this.name, this.created ]'
+on_conflict:
+  columns: [ id ]
+  update_columns: [ name ]
+`, dsn), nil)
+	require.NoError(t, err)
+
+	out, err := newSQLInsertOutputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, out.Close(context.Background()))
+	})
+
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":"foo","created":"a"}`)),
+		service.NewMessage([]byte(`{"id":2,"name":"bar","created":"b"}`)),
+	}))
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":2,"name":"baz","created":"c"}`)),
+		service.NewMessage([]byte(`{"id":3,"name":"buz","created":"d"}`)),
+	}))
+
+	rows, err := db.Query(`SELECT id, name, created FROM users ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	var res []string
+	for rows.Next() {
+		var id int
+		var name, created string
+		require.NoError(t, rows.Scan(&id, &name, &created))
+		res = append(res, fmt.Sprintf("%v:%v:%v", id, name, created))
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"1:foo:a", "2:baz:b", "3:buz:d"}, res)
+}
+
+func TestSQLInsertOutputOnConflictQueries(t *testing.T) {
+	tests := []struct {
+		name   string
+		driver string
+		conf   string
+		query  string
+		errStr string
+	}{
+		{
+			name:   "postgres default update columns",
+			driver: "postgres",
+			conf: `
+on_conflict:
+  columns: [ id ]
+`,
+			query: "INSERT INTO foo (id,name,age) VALUES ($1,$2,$3) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age",
+		},
+		{
+			name:   "postgres do nothing",
+			driver: "postgres",
+			conf: `
+on_conflict:
+  columns: [ id, name ]
+  action: nothing
+`,
+			query: "INSERT INTO foo (id,name,age) VALUES ($1,$2,$3) ON CONFLICT (id, name) DO NOTHING",
+		},
+		{
+			name:   "mysql update",
+			driver: "mysql",
+			conf: `
+on_conflict:
+  columns: [ id ]
+  update_columns: [ age ]
+`,
+			query: "INSERT INTO foo (id,name,age) VALUES (?,?,?) ON DUPLICATE KEY UPDATE age = VALUES(age)",
+		},
+		{
+			name:   "mysql ignore",
+			driver: "mysql",
+			conf: `
+on_conflict:
+  columns: [ id ]
+  action: nothing
+`,
+			query: "INSERT IGNORE INTO foo (id,name,age) VALUES (?,?,?)",
+		},
+		{
+			name:   "unsupported driver",
+			driver: "trino",
+			conf: `
+on_conflict:
+  columns: [ id ]
+`,
+			errStr: "not supported by the trino driver",
+		},
+		{
+			name:   "with suffix",
+			driver: "postgres",
+			conf: `
+suffix: ON CONFLICT DO NOTHING
+on_conflict:
+  columns: [ id ]
+`,
+			errStr: "cannot both be set",
+		},
+		{
+			name:   "nothing to update",
+			driver: "postgres",
+			conf: `
+on_conflict:
+  columns: [ id, name, age ]
+`,
+			errStr: "no columns to update",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := sqlInsertOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: %v
+dsn: foo
+table: foo
+columns: [ id, name, age ]
+args_mapping: 'root = [ this.id, this.name, this.age ]'
+`, test.driver)+test.conf, nil)
+			require.NoError(t, err)
+
+			out, err := newSQLInsertOutputFromConfig(conf, service.MockResources())
+			if test.errStr != "" {
+				require.ErrorContains(t, err, test.errStr)
+				return
+			}
+			require.NoError(t, err)
+
+			query, _, err := out.builder.Values(1, "a", 2).ToSql()
+			require.NoError(t, err)
+			assert.Equal(t, test.query, query)
+		})
+	}
+}
+
+func TestSQLInsertMaxRows(t *testing.T) {
+	assert.Equal(t, 21845, sqlInsertMaxRows("postgres", 3))
+	assert.Equal(t, 10922, sqlInsertMaxRows("sqlite", 3))
+	assert.Equal(t, 699, sqlInsertMaxRows("mssql", 3))
+	assert.Equal(t, 1000, sqlInsertMaxRows("mssql", 1))
+	assert.Equal(t, 1, sqlInsertMaxRows("mssql", 3000))
+}
+
+func TestSQLInsertOutputLargeBatch(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`CREATE TABLE things (id integer not null primary key, a integer, b integer, c integer)`)
+	require.NoError(t, err)
+
+	conf, err := sqlInsertOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+table: things
+columns: [ id, a, b, c ]
+args_mapping: 'root = [ this.id, this.id, this.id, this.id ]'
+`, dsn), nil)
+	require.NoError(t, err)
+
+	out, err := newSQLInsertOutputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, out.Close(context.Background()))
+	})
+
+	// Exceeds the argument limit of a single sqlite statement.
+	batch := make(service.MessageBatch, 10000)
+	for i := range batch {
+		batch[i] = service.NewMessage([]byte(fmt.Sprintf(`{"id":%v}`, i)))
+	}
+	require.NoError(t, out.WriteBatch(ctx, batch))
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM things`).Scan(&count))
+	assert.Equal(t, 10000, count)
+}
@@ -31,6 +31,11 @@ func sqlRawOutputConfig() *service.ConfigSpec {
 			Example("root = [ this.cat.meow, this.doc.woofs[0] ]").
 			Example(`root = [ meta("user.id") ]`).
 			Optional()).
+		Field(service.NewBoolField("transaction").
+			Description("Whether to execute the queries of each batch within a single transaction, in which case either all of the queries of a batch are committed or none of them are, and static queries are prepared once per batch. This greatly reduces the number of round trips and commits when writing large batches.").
+			Advanced().
+			Default(false).
+			Version("4.28.0")).
 		Field(service.NewIntField("max_in_flight").
 			Description("The maximum number of inserts to run in parallel.").
 			Default(64))
@@ -91,6 +96,7 @@ type sqlRawOutput struct {
 	queryDyn    *service.InterpolatedString
 
 	argsMapping *bloblang.Executor
+	transaction bool
 
 	connSettings *connSettings
 
@@ -134,7 +140,12 @@ func newSQLRawOutputFromConfig(conf *service.ParsedConfig, mgr *service.Resource
 	if err != nil {
 		return nil, err
 	}
-	return newSQLRawOutput(mgr.Logger(), driverStr, dsnStr, queryStatic, queryDyn, argsMapping, connSettings), nil
+
+	s := newSQLRawOutput(mgr.Logger(), driverStr, dsnStr, queryStatic, queryDyn, argsMapping, connSettings)
+	if s.transaction, err = conf.FieldBool("transaction"); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 func newSQLRawOutput(
@@ -166,17 +177,15 @@ func (s *sqlRawOutput) Connect(ctx context.Context) error {
 	}
 
 	var err error
-	if s.db, err = sqlOpenWithReworks(s.logger, s.driver, s.dsn); err != nil {
+	if s.db, err = s.connSettings.open(ctx, s.logger, s.driver, s.dsn); err != nil {
 		return err
 	}
 
-	s.connSettings.apply(ctx, s.db, s.logger)
-
 	go func() {
 		<-s.shutSig.HardStopChan()
 
 		s.dbMut.Lock()
-		_ = s.db.Close()
+		_ = s.connSettings.close(s.db)
 		s.dbMut.Unlock()
 
 		s.shutSig.TriggerHasStopped()
@@ -184,10 +193,34 @@ func (s *sqlRawOutput) Connect(ctx context.Context) error {
 	return nil
 }
 
-func (s *sqlRawOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) error {
+func (s *sqlRawOutput) WriteBatch(ctx context.Context, batch service.MessageBatch) (err error) {
 	s.dbMut.RLock()
 	defer s.dbMut.RUnlock()
 
+	var tx *sql.Tx
+	var execFn func(ctx context.Context, query string, args ...any) (sql.Result, error) = s.db.ExecContext
+	if s.transaction {
+		if tx, err = s.db.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				_ = tx.Rollback()
+			}
+		}()
+
+		execFn = tx.ExecContext
+		if s.queryDyn == nil {
+			stmt, err := tx.PrepareContext(ctx, s.queryStatic)
+			if err != nil {
+				return err
+			}
+			execFn = func(ctx context.Context, _ string, args ...any) (sql.Result, error) {
+				return stmt.ExecContext(ctx, args...)
+			}
+		}
+	}
+
 	for i := range batch {
 		var args []any
 		if s.argsMapping != nil {
@@ -215,10 +248,14 @@ func (s *sqlRawOutput) WriteBatch(ctx context.Context, batch service.MessageBatc
 			}
 		}
 
-		if _, err := s.db.ExecContext(ctx, queryStr, args...); err != nil {
+		if _, err := execFn(ctx, queryStr, args...); err != nil {
 			return err
 		}
 	}
+
+	if tx != nil {
+		return tx.Commit()
+	}
 	return nil
 }
 
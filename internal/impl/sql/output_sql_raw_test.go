@@ -0,0 +1,71 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestSQLRawOutputTransaction(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "foo.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.Exec(`CREATE TABLE users (id integer not null primary key, name varchar(50) not null)`)
+	require.NoError(t, err)
+
+	conf, err := sqlRawOutputConfig().ParseYAML(fmt.Sprintf(`
+driver: sqlite
+dsn: %v
+query: 'INSERT INTO users (id, name) VALUES (?, ?)'
+args_mapping: 'root = [ this.id, this.name ]'
+transaction: true
+`, dsn), nil)
+	require.NoError(t, err)
+
+	out, err := newSQLRawOutputFromConfig(conf, service.MockResources())
+	require.NoError(t, err)
+	require.NoError(t, out.Connect(ctx))
+	t.Cleanup(func() {
+		require.NoError(t, out.Close(context.Background()))
+	})
+
+	require.NoError(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":1,"name":"foo"}`)),
+		service.NewMessage([]byte(`{"id":2,"name":"bar"}`)),
+	}))
+
+	// The second row violates the primary key, and therefore none of the rows
+	// of the batch are written.
+	require.Error(t, out.WriteBatch(ctx, service.MessageBatch{
+		service.NewMessage([]byte(`{"id":3,"name":"baz"}`)),
+		service.NewMessage([]byte(`{"id":1,"name":"buz"}`)),
+	}))
+
+	var names []string
+	rows, err := db.Query(`SELECT name FROM users ORDER BY id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		require.NoError(t, rows.Scan(&name))
+		names = append(names, name)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{"foo", "bar"}, names)
+}
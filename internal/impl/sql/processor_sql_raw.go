@@ -167,16 +167,15 @@ func newSQLRawProcessor(
 	}
 
 	var err error
-	if s.db, err = sqlOpenWithReworks(logger, driverStr, dsnStr); err != nil {
+	if s.db, err = connSettings.open(context.Background(), logger, driverStr, dsnStr); err != nil {
 		return nil, err
 	}
-	connSettings.apply(context.Background(), s.db, s.logger)
 
 	go func() {
 		<-s.shutSig.HardStopChan()
 
 		s.dbMut.Lock()
-		_ = s.db.Close()
+		_ = connSettings.close(s.db)
 		s.dbMut.Unlock()
 
 		s.shutSig.TriggerHasStopped()
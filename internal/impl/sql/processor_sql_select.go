@@ -171,16 +171,15 @@ func NewSQLSelectProcessorFromConfig(conf *service.ParsedConfig, mgr *service.Re
 		return nil, err
 	}
 
-	if s.db, err = sqlOpenWithReworks(mgr.Logger(), driverStr, dsnStr); err != nil {
+	if s.db, err = connSettings.open(context.Background(), mgr.Logger(), driverStr, dsnStr); err != nil {
 		return nil, err
 	}
-	connSettings.apply(context.Background(), s.db, s.logger)
 
 	go func() {
 		<-s.shutSig.HardStopChan()
 
 		s.dbMut.Lock()
-		_ = s.db.Close()
+		_ = connSettings.close(s.db)
 		s.dbMut.Unlock()
 
 		s.shutSig.TriggerHasStopped()
@@ -0,0 +1,226 @@
+package teams
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	toFieldWebhookURL = "webhook_url"
+	toFieldMessage    = "message"
+	toFieldRetries    = "retries"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services", "Social").
+		Version("4.28.0").
+		Summary("Posts messages to Microsoft Teams channels as Adaptive Cards via incoming webhooks.").
+		Description(`
+Each message is posted to a Microsoft Teams channel or chat via an incoming webhook, which can be created either with a [Workflows](https://support.microsoft.com/en-us/office/create-incoming-webhooks-with-workflows-for-microsoft-teams-8ae491c7-0394-4861-ba59-055e33f75498) template or an Office 365 connector.
+
+The card is constructed with the `+"`message`"+` mapping when it is set, or from the message itself otherwise:
+
+- An object with a `+"`type`"+` of `+"`AdaptiveCard`"+` is sent as an [Adaptive Card](https://adaptivecards.io/explorer/).
+- An object with a `+"`type`"+` of `+"`message`"+` is sent as is, allowing full control over the request body.
+- Anything else is sent as the text of an Adaptive Card.
+
+The webhook URL can be set dynamically with interpolation functions, which allows routing messages to different channels based on their contents or metadata.
+
+### Rate Limiting and Retries
+
+Requests that fail with a rate limit error, a server error or a network error are retried according to the `+"`retries`"+` policy, where a rate limit error is retried once the period given by the `+"`Retry-After`"+` header has elapsed.`).
+		Fields(
+			service.NewInterpolatedStringField(toFieldWebhookURL).
+				Description("The URL of the incoming webhook to post messages to.").
+				Examples("https://prod-00.westus.logic.azure.com:443/workflows/abc/triggers/manual/paths/invoke", `${! @teams_webhook }`).
+				Secret(),
+			service.NewBloblangField(toFieldMessage).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) used to construct the card from each message, which results in either the text of the card or an Adaptive Card object.").
+				Example(`root = "Deployment of %v finished".format(this.service)`).
+				Optional(),
+			service.NewRetryPolicyField(toFieldRetries),
+			service.NewOutputMaxInFlightField().Default(1),
+		).
+		Example("Alerting With Adaptive Cards", "Alerts are posted as Adaptive Cards showing the title of the alert followed by a set of its facts.", `
+output:
+  teams:
+    webhook_url: ${TEAMS_WEBHOOK_URL}
+    message: |
+      root.type = "AdaptiveCard"
+      root."$schema" = "http://adaptivecards.io/schemas/adaptive-card.json"
+      root.version = "1.4"
+      root.body = [
+        { "type": "TextBlock", "size": "Large", "weight": "Bolder", "text": this.title },
+        { "type": "FactSet", "facts": [
+          { "title": "Severity", "value": this.severity },
+          { "title": "Host", "value": this.host }
+        ] }
+      ]
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("teams", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newTeamsWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type teamsWriter struct {
+	webhookURL *service.InterpolatedString
+	message    *bloblang.Executor
+
+	client  *http.Client
+	retries *service.RetryPolicy
+	log     *service.Logger
+}
+
+func newTeamsWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*teamsWriter, error) {
+	t := &teamsWriter{
+		client: &http.Client{Timeout: 30 * time.Second},
+		log:    mgr.Logger(),
+	}
+
+	var err error
+	if t.webhookURL, err = conf.FieldInterpolatedString(toFieldWebhookURL); err != nil {
+		return nil, err
+	}
+	if conf.Contains(toFieldMessage) {
+		if t.message, err = conf.FieldBloblang(toFieldMessage); err != nil {
+			return nil, err
+		}
+	}
+	if t.retries, err = conf.FieldRetryPolicy(toFieldRetries); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *teamsWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func adaptiveCardMessage(card any) map[string]any {
+	return map[string]any{
+		"type": "message",
+		"attachments": []any{
+			map[string]any{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"contentUrl":  nil,
+				"content":     card,
+			},
+		},
+	}
+}
+
+// messagePayload constructs the body of a webhook request from a message.
+func messagePayload(msg *service.Message, mapping *bloblang.Executor) (map[string]any, error) {
+	if mapping != nil {
+		var err error
+		if msg, err = msg.BloblangQuery(mapping); err != nil {
+			return nil, fmt.Errorf("message mapping failed: %w", err)
+		}
+		if msg == nil {
+			return nil, errors.New("message mapping resulted in a deleted message")
+		}
+	}
+
+	var text string
+	if v, err := msg.AsStructured(); err == nil {
+		switch t := v.(type) {
+		case map[string]any:
+			switch t["type"] {
+			case "AdaptiveCard":
+				return adaptiveCardMessage(t), nil
+			case "message":
+				return t, nil
+			}
+		case string:
+			text = t
+		}
+	}
+	if text == "" {
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		text = string(mBytes)
+	}
+
+	return adaptiveCardMessage(map[string]any{
+		"type":    "AdaptiveCard",
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"version": "1.4",
+		"body": []any{
+			map[string]any{"type": "TextBlock", "text": text, "wrap": true},
+		},
+	}), nil
+}
+
+func (t *teamsWriter) Write(ctx context.Context, msg *service.Message) error {
+	payload, err := messagePayload(msg, t.message)
+	if err != nil {
+		return err
+	}
+	webhookURL, err := t.webhookURL.TryString(msg)
+	if err != nil {
+		return fmt.Errorf("webhook_url interpolation: %w", err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return t.retries.Do(ctx, func(ctx context.Context) error {
+		return t.post(ctx, webhookURL, body)
+	})
+}
+
+// post performs a webhook request.
+func (t *teamsWriter) post(ctx context.Context, webhookURL string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := service.ParseRetryAfter(res.Header.Get("Retry-After"))
+		return service.NewErrBackOff(errors.New("rate limited by Teams"), retryAfter)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody)))
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
+
+func (t *teamsWriter) Close(ctx context.Context) error {
+	return nil
+}
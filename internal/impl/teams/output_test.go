@@ -0,0 +1,104 @@
+package teams
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestTeamsOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	payloads := map[string][]any{}
+	rateLimited := false
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		if !rateLimited {
+			rateLimited = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if r.URL.Path == "/nope" {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		var payload any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		payloads[r.URL.Path] = append(payloads[r.URL.Path], payload)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+webhook_url: %v/${! @team }
+message: |
+  root = if this.card { { "type": "AdaptiveCard", "version": "1.4", "body": [] } } else { this.text }
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	w, err := newTeamsWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, w.Connect(ctx))
+
+	msg := service.NewMessage([]byte(`{"card":false,"text":"hello world"}`))
+	msg.MetaSetMut("team", "ops")
+	require.NoError(t, w.Write(ctx, msg))
+
+	msg = service.NewMessage([]byte(`{"card":true}`))
+	msg.MetaSetMut("team", "dev")
+	require.NoError(t, w.Write(ctx, msg))
+
+	msg = service.NewMessage([]byte(`{"card":false,"text":"nope"}`))
+	msg.MetaSetMut("team", "nope")
+	err = w.Write(ctx, msg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Webhook not found")
+
+	reqMut.Lock()
+	assert.Equal(t, map[string][]any{
+		"/ops": {adaptiveCardMessage(map[string]any{
+			"type":    "AdaptiveCard",
+			"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+			"version": "1.4",
+			"body": []any{
+				map[string]any{"type": "TextBlock", "text": "hello world", "wrap": true},
+			},
+		})},
+		"/dev": {adaptiveCardMessage(map[string]any{
+			"type":    "AdaptiveCard",
+			"version": "1.4",
+			"body":    []any{},
+		})},
+	}, payloads)
+	reqMut.Unlock()
+
+	require.NoError(t, w.Close(ctx))
+}
+
+func TestTeamsMessagePayload(t *testing.T) {
+	raw := map[string]any{"type": "message", "text": "legacy"}
+	payload, err := messagePayload(service.NewMessage([]byte(`{"type":"message","text":"legacy"}`)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, raw, payload)
+
+	payload, err = messagePayload(service.NewMessage([]byte(`not json`)), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "not json", payload["attachments"].([]any)[0].(map[string]any)["content"].(map[string]any)["body"].([]any)[0].(map[string]any)["text"])
+}
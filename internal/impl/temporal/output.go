@@ -0,0 +1,369 @@
+package temporal
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/benthosdev/benthos/v4/public/bloblang"
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	toFieldURL                  = "url"
+	toFieldNamespace            = "namespace"
+	toFieldAction               = "action"
+	toFieldWorkflowID           = "workflow_id"
+	toFieldWorkflowType         = "workflow_type"
+	toFieldTaskQueue            = "task_queue"
+	toFieldSignalName           = "signal_name"
+	toFieldArgsMapping          = "args_mapping"
+	toFieldIgnoreAlreadyStarted = "ignore_already_started"
+	toFieldAPIKey               = "api_key"
+	toFieldTLS                  = "tls"
+	toFieldTimeout              = "timeout"
+
+	toActionStart           = "start"
+	toActionSignal          = "signal"
+	toActionSignalWithStart = "signal_with_start"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Starts or signals [Temporal](https://temporal.io/) workflows for each message.").
+		Description(`
+Requests are made to the [HTTP API](https://docs.temporal.io/references/http-api) of a Temporal frontend service, which is available from Temporal server version 1.22.0. Clients can be authenticated with mutual TLS by configuring the `+"`tls`"+` field with a client certificate, or with an `+"`api_key`"+` for Temporal Cloud.
+
+### Actions
+
+- `+"`start`"+`: Starts a workflow execution with the arguments resolved from `+"`args_mapping`"+`. When `+"`ignore_already_started`"+` is enabled messages that attempt to start a workflow with the ID of a running workflow are considered delivered, which allows messages to be redelivered without starting duplicate workflows.
+- `+"`signal`"+`: Sends a signal with the arguments resolved from `+"`args_mapping`"+` to a running workflow execution.
+- `+"`signal_with_start`"+`: Sends a signal with the arguments resolved from `+"`args_mapping`"+` to a workflow execution, starting the workflow without arguments when it is not already running.
+
+### Arguments
+
+By default each message is sent as a single argument, where messages that are valid JSON are sent as structured values and other messages are sent as strings. The `+"`args_mapping`"+` field can be used to map messages into an array of arguments instead.`).
+		Fields(
+			service.NewStringField(toFieldURL).
+				Description("The base URL of the HTTP API of the Temporal frontend service.").
+				Example("https://localhost:7243").
+				Example("https://my-namespace.a1b2c.tmprl.cloud:7243"),
+			service.NewInterpolatedStringField(toFieldNamespace).
+				Description("The namespace of workflows.").
+				Default("default"),
+			service.NewStringAnnotatedEnumField(toFieldAction, map[string]string{
+				toActionStart:           "Start a workflow execution.",
+				toActionSignal:          "Signal a running workflow execution.",
+				toActionSignalWithStart: "Signal a workflow execution, starting it if it is not already running.",
+			}).
+				Description("The action to perform for each message.").
+				Default(toActionStart),
+			service.NewInterpolatedStringField(toFieldWorkflowID).
+				Description("The ID of the workflow to start or signal.").
+				Example(`order-${! this.order_id }`).
+				Default(`${! uuid_v4() }`),
+			service.NewInterpolatedStringField(toFieldWorkflowType).
+				Description("The type of the workflow to start, which is required by the actions `start` and `signal_with_start`.").
+				Example("ProcessOrder").
+				Default(""),
+			service.NewInterpolatedStringField(toFieldTaskQueue).
+				Description("The task queue of the workflow to start, which is required by the actions `start` and `signal_with_start`.").
+				Example("orders").
+				Default(""),
+			service.NewInterpolatedStringField(toFieldSignalName).
+				Description("The name of the signal to send, which is required by the actions `signal` and `signal_with_start`.").
+				Example(`${! this.event_type }`).
+				Default(""),
+			service.NewBloblangField(toFieldArgsMapping).
+				Description("An optional [Bloblang mapping](/docs/guides/bloblang/about) which should evaluate to an array of arguments of the workflow or signal.").
+				Example(`root = [ this.order_id, this.items ]`).
+				Optional(),
+			service.NewBoolField(toFieldIgnoreAlreadyStarted).
+				Description("Whether messages that attempt to start a workflow that is already running should be considered delivered.").
+				Default(true).
+				Advanced(),
+			service.NewStringField(toFieldAPIKey).
+				Description("An optional API key sent as a bearer token with each request.").
+				Default("").
+				Secret().
+				Advanced(),
+			service.NewTLSToggledField(toFieldTLS),
+			service.NewDurationField(toFieldTimeout).
+				Description("The maximum period to wait for a request to complete.").
+				Default("10s").
+				Advanced(),
+			service.NewOutputMaxInFlightField(),
+		).
+		Example("Order Workflows", "Here we start a workflow for each new order and signal the workflow of an order when its payment status changes, where the workflow ID is derived from the order ID.", `
+output:
+  switch:
+    cases:
+      - check: this.type == "order_created"
+        output:
+          temporal:
+            url: https://temporal-frontend:7243
+            namespace: shop
+            workflow_id: order-${! this.order_id }
+            workflow_type: ProcessOrder
+            task_queue: orders
+            tls:
+              enabled: true
+              client_certs:
+                - cert_file: ./client.pem
+                  key_file: ./client.key
+      - output:
+          temporal:
+            url: https://temporal-frontend:7243
+            namespace: shop
+            action: signal
+            workflow_id: order-${! this.order_id }
+            signal_name: ${! this.type }
+            args_mapping: 'root = [ this.payment ]'
+            tls:
+              enabled: true
+              client_certs:
+                - cert_file: ./client.pem
+                  key_file: ./client.key
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("temporal", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newTemporalWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type temporalWriter struct {
+	baseURL              string
+	namespace            *service.InterpolatedString
+	action               string
+	workflowID           *service.InterpolatedString
+	workflowType         *service.InterpolatedString
+	taskQueue            *service.InterpolatedString
+	signalName           *service.InterpolatedString
+	argsMapping          *bloblang.Executor
+	ignoreAlreadyStarted bool
+	apiKey               string
+
+	client *http.Client
+	log    *service.Logger
+}
+
+func newTemporalWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*temporalWriter, error) {
+	t := &temporalWriter{
+		log: mgr.Logger(),
+	}
+
+	var err error
+	if t.baseURL, err = conf.FieldString(toFieldURL); err != nil {
+		return nil, err
+	}
+	t.baseURL = strings.TrimSuffix(t.baseURL, "/")
+	if t.action, err = conf.FieldString(toFieldAction); err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		name string
+		dst  **service.InterpolatedString
+	}{
+		{toFieldNamespace, &t.namespace},
+		{toFieldWorkflowID, &t.workflowID},
+		{toFieldWorkflowType, &t.workflowType},
+		{toFieldTaskQueue, &t.taskQueue},
+		{toFieldSignalName, &t.signalName},
+	} {
+		if *f.dst, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Contains(toFieldArgsMapping) {
+		if t.argsMapping, err = conf.FieldBloblang(toFieldArgsMapping); err != nil {
+			return nil, err
+		}
+	}
+	if t.ignoreAlreadyStarted, err = conf.FieldBool(toFieldIgnoreAlreadyStarted); err != nil {
+		return nil, err
+	}
+	if t.apiKey, err = conf.FieldString(toFieldAPIKey); err != nil {
+		return nil, err
+	}
+
+	timeout, err := conf.FieldDuration(toFieldTimeout)
+	if err != nil {
+		return nil, err
+	}
+	t.client = &http.Client{Timeout: timeout}
+
+	tlsConf, tlsEnabled, err := conf.FieldTLSToggled(toFieldTLS)
+	if err != nil {
+		return nil, err
+	}
+	if tlsEnabled {
+		t.client.Transport = tlsTransport(tlsConf)
+	}
+	return t, nil
+}
+
+func tlsTransport(tlsConf *tls.Config) http.RoundTripper {
+	if c, ok := http.DefaultTransport.(*http.Transport); ok {
+		cloned := c.Clone()
+		cloned.TLSClientConfig = tlsConf
+		return cloned
+	}
+	return &http.Transport{TLSClientConfig: tlsConf}
+}
+
+func (t *temporalWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (t *temporalWriter) args(msg *service.Message) ([]any, error) {
+	if t.argsMapping == nil {
+		if v, err := msg.AsStructured(); err == nil {
+			return []any{v}, nil
+		}
+		mBytes, err := msg.AsBytes()
+		if err != nil {
+			return nil, err
+		}
+		return []any{string(mBytes)}, nil
+	}
+
+	resMsg, err := msg.BloblangQuery(t.argsMapping)
+	if err != nil {
+		return nil, fmt.Errorf("args_mapping failed: %w", err)
+	}
+	if resMsg == nil {
+		return nil, nil
+	}
+	v, err := resMsg.AsStructured()
+	if err != nil {
+		return nil, fmt.Errorf("args_mapping result: %w", err)
+	}
+	args, ok := v.([]any)
+	if !ok {
+		return nil, fmt.Errorf("args_mapping returned non-array result: %T", v)
+	}
+	return args, nil
+}
+
+// request returns the path and body of the request for a message.
+func (t *temporalWriter) request(msg *service.Message) (path string, body map[string]any, err error) {
+	strs := map[string]string{}
+	for k, v := range map[string]*service.InterpolatedString{
+		toFieldNamespace:    t.namespace,
+		toFieldWorkflowID:   t.workflowID,
+		toFieldWorkflowType: t.workflowType,
+		toFieldTaskQueue:    t.taskQueue,
+		toFieldSignalName:   t.signalName,
+	} {
+		if strs[k], err = v.TryString(msg); err != nil {
+			return "", nil, fmt.Errorf("%v interpolation: %w", k, err)
+		}
+	}
+	required := []string{toFieldNamespace, toFieldWorkflowID}
+	switch t.action {
+	case toActionStart:
+		required = append(required, toFieldWorkflowType, toFieldTaskQueue)
+	case toActionSignal:
+		required = append(required, toFieldSignalName)
+	case toActionSignalWithStart:
+		required = append(required, toFieldWorkflowType, toFieldTaskQueue, toFieldSignalName)
+	}
+	for _, k := range required {
+		if strs[k] == "" {
+			return "", nil, fmt.Errorf("%v must not be empty for the %v action", k, t.action)
+		}
+	}
+
+	args, err := t.args(msg)
+	if err != nil {
+		return "", nil, err
+	}
+	if args == nil {
+		args = []any{}
+	}
+
+	path = fmt.Sprintf("/api/v1/namespaces/%v/workflows/%v", url.PathEscape(strs[toFieldNamespace]), url.PathEscape(strs[toFieldWorkflowID]))
+	body = map[string]any{}
+	switch t.action {
+	case toActionStart:
+		body["input"] = args
+	case toActionSignal:
+		path += "/signal/" + url.PathEscape(strs[toFieldSignalName])
+		body["input"] = args
+	case toActionSignalWithStart:
+		path += "/signal-with-start/" + url.PathEscape(strs[toFieldSignalName])
+		body["signalInput"] = args
+	}
+	if t.action != toActionSignal {
+		body["workflowType"] = map[string]any{"name": strs[toFieldWorkflowType]}
+		body["taskQueue"] = map[string]any{"name": strs[toFieldTaskQueue]}
+	}
+	return path, body, nil
+}
+
+func (t *temporalWriter) Write(ctx context.Context, msg *service.Message) error {
+	path, body, err := t.request(msg)
+	if err != nil {
+		return err
+	}
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+path, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode <= 299 {
+		_, _ = io.Copy(io.Discard, res.Body)
+		return nil
+	}
+	if res.StatusCode == http.StatusConflict && t.action == toActionStart && t.ignoreAlreadyStarted {
+		t.log.Debugf("Workflow at %v is already started", path)
+		return nil
+	}
+
+	resBody, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(resBody, &apiErr); err == nil && apiErr.Message != "" {
+		return fmt.Errorf("unexpected status code %v: %v", res.StatusCode, apiErr.Message)
+	}
+	return fmt.Errorf("unexpected status code %v: %s", res.StatusCode, bytes.TrimSpace(resBody))
+}
+
+func (t *temporalWriter) Close(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,116 @@
+package temporal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func testTemporalWriter(t *testing.T, confStr string, args ...any) *temporalWriter {
+	t.Helper()
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(confStr, args...), nil)
+	require.NoError(t, err)
+
+	w, err := newTemporalWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+	return w
+}
+
+func TestTemporalOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	reqs := map[string]any{}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer fookey", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		if r.URL.Path == "/api/v1/namespaces/shop/workflows/order-2" {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"code":6,"message":"Workflow execution is already running"}`))
+			return
+		}
+		if r.URL.Path == "/api/v1/namespaces/shop/workflows/order-3/signal/paid" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"code":5,"message":"workflow execution not found"}`))
+			return
+		}
+
+		var body any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		reqMut.Lock()
+		reqs[r.URL.Path] = body
+		reqMut.Unlock()
+		_, _ = w.Write([]byte(`{"runId":"foo"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	start := testTemporalWriter(t, `
+url: %v/
+namespace: shop
+workflow_id: order-${! this.id }
+workflow_type: ProcessOrder
+task_queue: orders
+api_key: fookey
+`, ts.URL)
+
+	require.NoError(t, start.Write(context.Background(), service.NewMessage([]byte(`{"id":1}`))))
+	require.NoError(t, start.Write(context.Background(), service.NewMessage([]byte(`{"id":2}`))))
+
+	signal := testTemporalWriter(t, `
+url: %v
+namespace: shop
+action: signal
+workflow_id: order-${! this.id }
+signal_name: ${! this.type }
+args_mapping: 'root = [ this.amount, "usd" ]'
+api_key: fookey
+`, ts.URL)
+
+	require.NoError(t, signal.Write(context.Background(), service.NewMessage([]byte(`{"id":1,"type":"paid","amount":10}`))))
+	require.EqualError(t, signal.Write(context.Background(), service.NewMessage([]byte(`{"id":3,"type":"paid","amount":10}`))), "unexpected status code 404: workflow execution not found")
+
+	signalStart := testTemporalWriter(t, `
+url: %v
+namespace: shop
+action: signal_with_start
+workflow_id: ${! @customer | "" }
+workflow_type: Loyalty
+task_queue: customers
+signal_name: purchase
+api_key: fookey
+`, ts.URL)
+
+	msg := service.NewMessage([]byte(`not json`))
+	msg.MetaSetMut("customer", "bob")
+	require.NoError(t, signalStart.Write(context.Background(), msg))
+	require.EqualError(t, signalStart.Write(context.Background(), service.NewMessage([]byte(`{}`))), "workflow_id must not be empty for the signal_with_start action")
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+	assert.Equal(t, map[string]any{
+		"/api/v1/namespaces/shop/workflows/order-1": map[string]any{
+			"workflowType": map[string]any{"name": "ProcessOrder"},
+			"taskQueue":    map[string]any{"name": "orders"},
+			"input":        []any{map[string]any{"id": 1.0}},
+		},
+		"/api/v1/namespaces/shop/workflows/order-1/signal/paid": map[string]any{
+			"input": []any{10.0, "usd"},
+		},
+		"/api/v1/namespaces/shop/workflows/bob/signal-with-start/purchase": map[string]any{
+			"workflowType": map[string]any{"name": "Loyalty"},
+			"taskQueue":    map[string]any{"name": "customers"},
+			"signalInput":  []any{"not json"},
+		},
+	}, reqs)
+}
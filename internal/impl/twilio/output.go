@@ -0,0 +1,410 @@
+package twilio
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+const (
+	toFieldAccountSID             = "account_sid"
+	toFieldAuthToken              = "auth_token"
+	toFieldChannel                = "channel"
+	toFieldFrom                   = "from"
+	toFieldMessagingServiceSID    = "messaging_service_sid"
+	toFieldTo                     = "to"
+	toFieldBody                   = "body"
+	toFieldMediaURL               = "media_url"
+	toFieldStatusCallback         = "status_callback"
+	toFieldPerDestinationInterval = "per_destination_interval"
+	toFieldRateLimit              = "rate_limit"
+	toFieldBaseURL                = "base_url"
+	toFieldRetries                = "retries"
+)
+
+func outputSpec() *service.ConfigSpec {
+	return service.NewConfigSpec().
+		Beta().
+		Categories("Services").
+		Version("4.28.0").
+		Summary("Sends SMS and WhatsApp messages using the Twilio Programmable Messaging API.").
+		Description(`
+Each message is sent to the recipient resolved from the `+"`to`"+` field, with a body resolved from the `+"`body`"+` field. Messages are sent either from the number set with `+"`from`"+` or via a [Messaging Service](https://www.twilio.com/docs/messaging/services), one of which must be set.
+
+When the `+"`channel`"+` is `+"`whatsapp`"+` the recipient and sender numbers are prefixed with `+"`whatsapp:`"+`, unless they already are.
+
+### Delivery Status
+
+Twilio accepts messages for delivery asynchronously, and reports the delivery status of each message by sending requests to the `+"`status_callback`"+` URL. The callback URL supports interpolation functions, which makes it possible to propagate metadata of the original message to the callback as query parameters, for example `+"`https://hooks.example.com/twilio?order=${! @order_id }`"+`. The callbacks can be consumed with an `+"[`http_server`](/docs/components/inputs/http_server)"+` input.
+
+### Rate Limiting and Retries
+
+Carriers limit the rate at which messages can be sent to a single recipient, which can be respected by setting `+"`per_destination_interval`"+` to the minimum period between consecutive messages sent to the same recipient. A `+"`rate_limit`"+` resource can also be set in order to limit the overall rate of requests. Requests that fail with a rate limit error, a server error or a network error are retried according to the `+"`retries`"+` policy, where a rate limit error is retried once the period given by the `+"`Retry-After`"+` header has elapsed.`).
+		Fields(
+			service.NewStringField(toFieldAccountSID).
+				Description("The SID of the Twilio account."),
+			service.NewStringField(toFieldAuthToken).
+				Description("The auth token of the account, or the secret of an API key.").
+				Secret(),
+			service.NewStringEnumField(toFieldChannel, "sms", "whatsapp").
+				Description("The channel to send messages with.").
+				Default("sms"),
+			service.NewInterpolatedStringField(toFieldFrom).
+				Description("The phone number or alphanumeric sender ID to send messages from.").
+				Example("+15557122661").
+				Default(""),
+			service.NewStringField(toFieldMessagingServiceSID).
+				Description("The SID of a Messaging Service to send messages with, which takes precedence over `from`.").
+				Default(""),
+			service.NewInterpolatedStringField(toFieldTo).
+				Description("The phone number to send each message to, in E.164 format.").
+				Example(`${! this.phone }`),
+			service.NewInterpolatedStringField(toFieldBody).
+				Description("The text of each message.").
+				Example(`Your order ${! this.order_id } has shipped!`).
+				Default(`${! content() }`),
+			service.NewInterpolatedStringField(toFieldMediaURL).
+				Description("An optional URL of media to attach to each message.").
+				Default("").
+				Advanced(),
+			service.NewInterpolatedStringField(toFieldStatusCallback).
+				Description("An optional URL that Twilio sends delivery status updates of each message to.").
+				Example(`https://hooks.example.com/twilio?order=${! @order_id }`).
+				Default(""),
+			service.NewDurationField(toFieldPerDestinationInterval).
+				Description("The minimum period between consecutive messages sent to the same recipient, where `0s` disables the limit.").
+				Default("0s"),
+			service.NewStringField(toFieldRateLimit).
+				Description("An optional [rate limit resource](/docs/components/rate_limits/about) to restrict requests with.").
+				Default("").
+				Advanced(),
+			service.NewStringField(toFieldBaseURL).
+				Description("The base URL of the Twilio API.").
+				Default("https://api.twilio.com").
+				Advanced(),
+			service.NewRetryPolicyField(toFieldRetries),
+			service.NewOutputMaxInFlightField().Default(16),
+		).
+		Example("Shipping Notifications", "Customers are notified over WhatsApp once their order has shipped, with delivery status updates sent back to a Benthos endpoint.", `
+output:
+  twilio:
+    account_sid: ${TWILIO_ACCOUNT_SID}
+    auth_token: ${TWILIO_AUTH_TOKEN}
+    channel: whatsapp
+    from: "+15557122661"
+    to: ${! this.customer.phone }
+    body: Your order ${! this.order_id } has shipped!
+    status_callback: https://hooks.example.com/twilio/status?order=${! this.order_id }
+    per_destination_interval: 1s
+`)
+}
+
+func init() {
+	err := service.RegisterOutput("twilio", outputSpec(),
+		func(conf *service.ParsedConfig, mgr *service.Resources) (out service.Output, maxInFlight int, err error) {
+			if maxInFlight, err = conf.FieldMaxInFlight(); err != nil {
+				return
+			}
+			out, err = newTwilioWriterFromParsed(conf, mgr)
+			return
+		})
+	if err != nil {
+		panic(err)
+	}
+}
+
+type twilioWriter struct {
+	accountSID          string
+	authToken           string
+	whatsapp            bool
+	from                *service.InterpolatedString
+	messagingServiceSID string
+	to                  *service.InterpolatedString
+	body                *service.InterpolatedString
+	mediaURL            *service.InterpolatedString
+	statusCallback      *service.InterpolatedString
+	rateLimit           string
+	baseURL             string
+
+	destLimiter *destinationLimiter
+
+	client  *http.Client
+	retries *service.RetryPolicy
+	mgr     *service.Resources
+	log     *service.Logger
+}
+
+func newTwilioWriterFromParsed(conf *service.ParsedConfig, mgr *service.Resources) (*twilioWriter, error) {
+	t := &twilioWriter{
+		client: &http.Client{Timeout: 30 * time.Second},
+		mgr:    mgr,
+		log:    mgr.Logger(),
+	}
+
+	var err error
+	if t.accountSID, err = conf.FieldString(toFieldAccountSID); err != nil {
+		return nil, err
+	}
+	if t.authToken, err = conf.FieldString(toFieldAuthToken); err != nil {
+		return nil, err
+	}
+	channel, err := conf.FieldString(toFieldChannel)
+	if err != nil {
+		return nil, err
+	}
+	t.whatsapp = channel == "whatsapp"
+	for _, f := range []struct {
+		name string
+		dst  **service.InterpolatedString
+	}{
+		{toFieldFrom, &t.from},
+		{toFieldTo, &t.to},
+		{toFieldBody, &t.body},
+		{toFieldMediaURL, &t.mediaURL},
+		{toFieldStatusCallback, &t.statusCallback},
+	} {
+		if *f.dst, err = conf.FieldInterpolatedString(f.name); err != nil {
+			return nil, err
+		}
+	}
+	if t.messagingServiceSID, err = conf.FieldString(toFieldMessagingServiceSID); err != nil {
+		return nil, err
+	}
+	if t.messagingServiceSID == "" {
+		if static, isStatic := t.from.Static(); isStatic && static == "" {
+			return nil, errors.New("either a from number or a messaging_service_sid must be set")
+		}
+	}
+	interval, err := conf.FieldDuration(toFieldPerDestinationInterval)
+	if err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		t.destLimiter = newDestinationLimiter(interval)
+	}
+	if t.rateLimit, err = conf.FieldString(toFieldRateLimit); err != nil {
+		return nil, err
+	}
+	if t.rateLimit != "" && !mgr.HasRateLimit(t.rateLimit) {
+		return nil, fmt.Errorf("rate limit resource '%v' was not found", t.rateLimit)
+	}
+	if t.baseURL, err = conf.FieldString(toFieldBaseURL); err != nil {
+		return nil, err
+	}
+	t.baseURL = strings.TrimSuffix(t.baseURL, "/")
+	if t.retries, err = conf.FieldRetryPolicy(toFieldRetries); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *twilioWriter) Connect(ctx context.Context) error {
+	return nil
+}
+
+func (t *twilioWriter) withChannel(number string) string {
+	if t.whatsapp && number != "" && !strings.HasPrefix(number, "whatsapp:") {
+		return "whatsapp:" + number
+	}
+	return number
+}
+
+func (t *twilioWriter) form(msg *service.Message) (url.Values, error) {
+	form := url.Values{}
+
+	to, err := t.to.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("to interpolation: %w", err)
+	}
+	if to == "" {
+		return nil, errors.New("to resolved to an empty string")
+	}
+	form.Set("To", t.withChannel(to))
+
+	if t.messagingServiceSID != "" {
+		form.Set("MessagingServiceSid", t.messagingServiceSID)
+	} else {
+		from, err := t.from.TryString(msg)
+		if err != nil {
+			return nil, fmt.Errorf("from interpolation: %w", err)
+		}
+		form.Set("From", t.withChannel(from))
+	}
+
+	body, err := t.body.TryString(msg)
+	if err != nil {
+		return nil, fmt.Errorf("body interpolation: %w", err)
+	}
+	form.Set("Body", body)
+
+	if mediaURL, err := t.mediaURL.TryString(msg); err != nil {
+		return nil, fmt.Errorf("media_url interpolation: %w", err)
+	} else if mediaURL != "" {
+		form.Set("MediaUrl", mediaURL)
+	}
+	if statusCallback, err := t.statusCallback.TryString(msg); err != nil {
+		return nil, fmt.Errorf("status_callback interpolation: %w", err)
+	} else if statusCallback != "" {
+		form.Set("StatusCallback", statusCallback)
+	}
+	return form, nil
+}
+
+func (t *twilioWriter) waitForRateLimit(ctx context.Context) error {
+	if t.rateLimit == "" {
+		return nil
+	}
+	for {
+		var waitFor time.Duration
+		var err error
+		if rerr := t.mgr.AccessRateLimit(ctx, t.rateLimit, func(rl service.RateLimit) {
+			waitFor, err = rl.Access(ctx)
+		}); rerr != nil {
+			return rerr
+		}
+		if err != nil {
+			return err
+		}
+		if waitFor == 0 {
+			return nil
+		}
+		select {
+		case <-time.After(waitFor):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (t *twilioWriter) Write(ctx context.Context, msg *service.Message) error {
+	form, err := t.form(msg)
+	if err != nil {
+		return err
+	}
+
+	if t.destLimiter != nil {
+		if err := t.destLimiter.wait(ctx, form.Get("To")); err != nil {
+			return err
+		}
+	}
+
+	return t.retries.Do(ctx, func(ctx context.Context) error {
+		if err := t.waitForRateLimit(ctx); err != nil {
+			return err
+		}
+		return t.send(ctx, form)
+	})
+}
+
+// send creates a message.
+func (t *twilioWriter) send(ctx context.Context, form url.Values) error {
+	endpoint := t.baseURL + "/2010-04-01/Accounts/" + url.PathEscape(t.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.accountSID, t.authToken)
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode == http.StatusTooManyRequests {
+		retryAfter, _ := service.ParseRetryAfter(res.Header.Get("Retry-After"))
+		return service.NewErrBackOff(errors.New("rate limited by Twilio"), retryAfter)
+	}
+
+	var apiRes struct {
+		SID     string `json:"sid"`
+		Status  string `json:"status"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		if err := json.Unmarshal(resBody, &apiRes); err == nil && apiRes.Message != "" {
+			return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: error %v: %v", res.StatusCode, apiRes.Code, apiRes.Message))
+		}
+		return service.NewHTTPStatusError(res.StatusCode, fmt.Errorf("unexpected status code %v: %s", res.StatusCode, resBody))
+	}
+	if err := json.Unmarshal(resBody, &apiRes); err == nil {
+		t.log.Tracef("Message %v to %v accepted with status %v", apiRes.SID, form.Get("To"), apiRes.Status)
+	}
+	return nil
+}
+
+func (t *twilioWriter) Close(ctx context.Context) error {
+	return nil
+}
+
+//------------------------------------------------------------------------------
+
+// destinationLimiter enforces a minimum interval between consecutive sends to
+// the same destination.
+type destinationLimiter struct {
+	interval time.Duration
+
+	mut       sync.Mutex
+	next      map[string]time.Time
+	lastPrune time.Time
+}
+
+func newDestinationLimiter(interval time.Duration) *destinationLimiter {
+	return &destinationLimiter{
+		interval: interval,
+		next:     map[string]time.Time{},
+	}
+}
+
+// reserve claims the next available slot for a destination and returns the
+// period to wait until it begins.
+func (d *destinationLimiter) reserve(dest string, now time.Time) time.Duration {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	// Forget destinations whose slots have passed so that the map doesn't
+	// grow unbounded.
+	if now.Sub(d.lastPrune) > d.interval {
+		for k, v := range d.next {
+			if !v.After(now) {
+				delete(d.next, k)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	slot := now
+	if next, exists := d.next[dest]; exists && next.After(now) {
+		slot = next
+	}
+	d.next[dest] = slot.Add(d.interval)
+	return slot.Sub(now)
+}
+
+func (d *destinationLimiter) wait(ctx context.Context, dest string) error {
+	waitFor := d.reserve(dest, time.Now())
+	if waitFor <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(waitFor):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
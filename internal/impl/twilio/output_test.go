@@ -0,0 +1,167 @@
+package twilio
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/public/service"
+)
+
+func TestTwilioOutput(t *testing.T) {
+	var reqMut sync.Mutex
+	var forms []url.Values
+	rateLimited := false
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/ACfoo/Messages.json", r.URL.Path)
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "ACfoo", user)
+		assert.Equal(t, "bar", pass)
+
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		if !rateLimited {
+			rateLimited = true
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		require.NoError(t, r.ParseForm())
+		forms = append(forms, r.PostForm)
+
+		if r.PostForm.Get("To") == "whatsapp:+15550000000" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":21211,"message":"The 'To' number is not a valid phone number.","status":400}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+account_sid: ACfoo
+auth_token: bar
+channel: whatsapp
+from: "+15557122661"
+to: ${! this.phone }
+body: Order ${! this.id } has shipped
+status_callback: https://example.com/status?order=${! this.id }
+base_url: %v
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	w, err := newTwilioWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	require.NoError(t, w.Connect(ctx))
+	require.NoError(t, w.Write(ctx, service.NewMessage([]byte(`{"id":"a1","phone":"+15551234567"}`))))
+	require.NoError(t, w.Write(ctx, service.NewMessage([]byte(`{"id":"a2","phone":"whatsapp:+15557654321"}`))))
+
+	err = w.Write(ctx, service.NewMessage([]byte(`{"id":"a3","phone":"+15550000000"}`)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "21211")
+
+	err = w.Write(ctx, service.NewMessage([]byte(`{"id":"a4","phone":""}`)))
+	require.Error(t, err)
+
+	require.NoError(t, w.Close(ctx))
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+
+	require.Len(t, forms, 3)
+	assert.Equal(t, url.Values{
+		"To":             []string{"whatsapp:+15551234567"},
+		"From":           []string{"whatsapp:+15557122661"},
+		"Body":           []string{"Order a1 has shipped"},
+		"StatusCallback": []string{"https://example.com/status?order=a1"},
+	}, forms[0])
+	assert.Equal(t, "whatsapp:+15557654321", forms[1].Get("To"))
+}
+
+func TestTwilioOutputMessagingService(t *testing.T) {
+	var reqMut sync.Mutex
+	var forms []url.Values
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqMut.Lock()
+		defer reqMut.Unlock()
+
+		require.NoError(t, r.ParseForm())
+		forms = append(forms, r.PostForm)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid":"SM123","status":"accepted"}`))
+	}))
+	t.Cleanup(ts.Close)
+
+	conf, err := outputSpec().ParseYAML(fmt.Sprintf(`
+account_sid: ACfoo
+auth_token: bar
+messaging_service_sid: MGfoo
+to: +15551234567
+media_url: https://example.com/${! @image }
+base_url: %v
+`, ts.URL), nil)
+	require.NoError(t, err)
+
+	w, err := newTwilioWriterFromParsed(conf, service.MockResources())
+	require.NoError(t, err)
+
+	ctx, done := context.WithTimeout(context.Background(), time.Second*10)
+	defer done()
+
+	msg := service.NewMessage([]byte(`hello world`))
+	msg.MetaSetMut("image", "cat.png")
+	require.NoError(t, w.Write(ctx, msg))
+
+	reqMut.Lock()
+	defer reqMut.Unlock()
+
+	assert.Equal(t, []url.Values{{
+		"To":                  []string{"+15551234567"},
+		"MessagingServiceSid": []string{"MGfoo"},
+		"Body":                []string{"hello world"},
+		"MediaUrl":            []string{"https://example.com/cat.png"},
+	}}, forms)
+}
+
+func TestTwilioOutputNoSender(t *testing.T) {
+	conf, err := outputSpec().ParseYAML(`
+account_sid: ACfoo
+auth_token: bar
+to: +15551234567
+`, nil)
+	require.NoError(t, err)
+
+	_, err = newTwilioWriterFromParsed(conf, service.MockResources())
+	require.Error(t, err)
+}
+
+func TestDestinationLimiter(t *testing.T) {
+	d := newDestinationLimiter(time.Second)
+	now := time.Unix(1700000000, 0)
+
+	assert.Equal(t, time.Duration(0), d.reserve("a", now))
+	assert.Equal(t, time.Duration(0), d.reserve("b", now))
+	assert.Equal(t, time.Second, d.reserve("a", now))
+	assert.Equal(t, 2*time.Second, d.reserve("a", now))
+	assert.Equal(t, 500*time.Millisecond, d.reserve("b", now.Add(500*time.Millisecond)))
+
+	// Slots that have passed are forgotten.
+	assert.Equal(t, time.Duration(0), d.reserve("c", now.Add(10*time.Second)))
+	assert.Len(t, d.next, 1)
+}
@@ -1,6 +1,8 @@
 package xml
 
 import (
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -35,20 +37,104 @@ Attempts to parse a string as an XML document and returns a structured result, w
 				`{"doc":"<root><title>This is a title</title><number id=99>123</number><bool>True</bool></root>"}`,
 				`{"doc":{"root":{"bool":true,"number":{"#text":123,"-id":99},"title":"This is a title"}}}`,
 			}).
+			Example("Namespace prefixes can be preserved, and elements that may occur once or many times can be parsed as arrays consistently.",
+				`root.doc = this.doc.parse_xml(preserve_namespaces: true, arrays: ["s:Envelope.s:Body.item"])`, [2]string{
+					`{"doc":"<s:Envelope xmlns:s=\"http://www.w3.org/2003/05/soap-envelope\"><s:Body><item>foo</item></s:Body></s:Envelope>"}`,
+					`{"doc":{"s:Envelope":{"-xmlns:s":"http://www.w3.org/2003/05/soap-envelope","s:Body":{"item":["foo"]}}}}`,
+				},
+			).
+			Example("Type hints cast the values of specific paths, where attributes are addressed by their key.",
+				`root.doc = this.doc.parse_xml(types: {"order.id": "string", "order.total": "number", "order.@paid": "bool"}, attr_prefix: "@")`, [2]string{
+					`{"doc":"<order paid=\"true\"><id>0012</id><total>10.50</total></order>"}`,
+					`{"doc":{"order":{"@paid":true,"id":"0012","total":10.5}}}`,
+				},
+			).
 			Param(bloblang.NewBoolParam("cast").
 				Description("whether to try to cast values that are numbers and booleans to the right type.").
-				Optional().Default(false)),
+				Optional().Default(false)).
+			Param(bloblang.NewBoolParam("preserve_namespaces").
+				Description("Whether to keep the namespace prefixes of element and attribute names, and namespace declarations as attributes, as they appear within the document.").
+				Default(false)).
+			Param(bloblang.NewAnyParam("arrays").
+				Description("An array of paths of elements that are always parsed as arrays, even when they only occur once. A path is the names of elements from the root of the document joined with dots, such as `catalog.book`.").
+				Default([]any{})).
+			Param(bloblang.NewAnyParam("types").
+				Description("An object of paths to type hints, where each type is one of `string`, `number`, `int` or `bool`. The values of hinted paths are always converted to the given type regardless of `cast`, and attributes are addressed by their key, such as `catalog.book.-id`.").
+				Default(map[string]any{})).
+			Param(bloblang.NewStringParam("attr_prefix").
+				Description("A prefix added to the keys of attributes.").
+				Default("-")).
+			Param(bloblang.NewStringParam("text_key").
+				Description("The key given to the text of elements that also contain attributes or child elements.").
+				Default("#text")),
 		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
 			castOpt, err := args.GetOptionalBool("cast")
 			if err != nil {
 				return nil, err
 			}
-			cast := false
+			opts := DecodeOptions{}
 			if castOpt != nil {
-				cast = *castOpt
+				opts.Cast = *castOpt
+			}
+			if opts.PreserveNamespaces, err = args.GetBool("preserve_namespaces"); err != nil {
+				return nil, err
+			}
+			if opts.AttrPrefix, err = args.GetString("attr_prefix"); err != nil {
+				return nil, err
+			}
+			if opts.TextKey, err = args.GetString("text_key"); err != nil {
+				return nil, err
+			}
+
+			arraysV, err := args.Get("arrays")
+			if err != nil {
+				return nil, err
+			}
+			arrays, ok := arraysV.([]any)
+			if !ok {
+				return nil, fmt.Errorf("expected arrays to be an array, got %T", arraysV)
+			}
+			for _, v := range arrays {
+				p, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected arrays to contain strings, got %T", v)
+				}
+				opts.Arrays = append(opts.Arrays, p)
+			}
+
+			typesV, err := args.Get("types")
+			if err != nil {
+				return nil, err
+			}
+			types, ok := typesV.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("expected types to be an object, got %T", typesV)
+			}
+			opts.Types = make(map[string]string, len(types))
+			for k, v := range types {
+				t, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected type hint of path %v to be a string, got %T", k, v)
+				}
+				opts.Types[k] = t
+			}
+
+			// Documents are parsed with mxj unless the structure is customised,
+			// which preserves the established behaviour of this method.
+			useMXJ := !opts.PreserveNamespaces && len(opts.Arrays) == 0 && len(opts.Types) == 0 &&
+				opts.AttrPrefix == "-" && opts.TextKey == "#text"
+			if err := opts.validate(); err != nil {
+				return nil, err
 			}
+
 			return bloblang.BytesMethod(func(xmlBytes []byte) (any, error) {
-				xmlObj, err := ToMap(xmlBytes, cast)
+				var xmlObj map[string]any
+				var err error
+				if useMXJ {
+					xmlObj, err = ToMap(xmlBytes, opts.Cast)
+				} else {
+					xmlObj, err = Decode(xmlBytes, opts)
+				}
 				if err != nil {
 					return nil, fmt.Errorf("failed to parse value as XML: %w", err)
 				}
@@ -96,30 +182,104 @@ Serializes a target value into an XML byte array.
 					`<foo><bar><baz>foo bar baz</baz></bar></foo>`,
 				},
 			).
+			Example("Namespace prefixes are kept as they are named, and the keys of attributes and text can be customised in order to match documents parsed with custom keys.",
+				`root = this.format_xml(no_indent: true, attr_prefix: "@", text_key: "value", declaration: true)`, [2]string{
+					`{"s:Envelope":{"@xmlns:s":"http://www.w3.org/2003/05/soap-envelope","s:Body":{"item":[{"@id":"1","value":"foo"},{"@id":"2","value":"bar"}]}}}`,
+					`<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body><item id="1">foo</item><item id="2">bar</item></s:Body></s:Envelope>`,
+				},
+			).
 			Param(bloblang.NewStringParam("indent").Description(
 				"Indentation string. Each element in an XML object or array will begin on a new, indented line followed by one or more copies of indent according to the indentation nesting.").
 				Default(strings.Repeat(" ", 4))).
 			Param(bloblang.NewBoolParam("no_indent").Description(
 				"Disable indentation.").
+				Default(false)).
+			Param(bloblang.NewStringParam("attr_prefix").Description(
+				"The prefix of keys that are serialized as attributes.").
+				Default("-")).
+			Param(bloblang.NewStringParam("text_key").Description(
+				"The key of values that are serialized as the text of elements that also contain attributes or child elements.").
+				Default("#text")).
+			Param(bloblang.NewBoolParam("declaration").Description(
+				"Whether to begin the document with an XML declaration.").
 				Default(false)),
 		func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+			indent := ""
+			if indentOpt, err := args.GetOptionalString("indent"); err != nil {
+				return nil, err
+			} else if indentOpt != nil {
+				indent = *indentOpt
+			}
+			noIndent, err := args.GetBool("no_indent")
+			if err != nil {
+				return nil, err
+			}
+			attrPrefix, err := args.GetString("attr_prefix")
+			if err != nil {
+				return nil, err
+			}
+			if attrPrefix == "" {
+				return nil, errors.New("attr_prefix must not be empty")
+			}
+			textKey, err := args.GetString("text_key")
+			if err != nil {
+				return nil, err
+			}
+			declaration, err := args.GetBool("declaration")
+			if err != nil {
+				return nil, err
+			}
 			return bloblang.ObjectMethod(func(obj map[string]any) (any, error) {
-				indent := ""
-				if indentOpt, err := args.GetOptionalString("indent"); err != nil {
-					return nil, err
-				} else if indentOpt != nil {
-					indent = *indentOpt
+				if attrPrefix != "-" || textKey != "#text" {
+					obj = mxjKeys(obj, attrPrefix, textKey)
+				}
+				var b []byte
+				var err error
+				if noIndent {
+					b, err = mxj.Map(obj).Xml()
+				} else {
+					b, err = mxj.Map(obj).XmlIndent("", indent)
 				}
-				noIndentOpt, err := args.GetOptionalBool("no_indent")
 				if err != nil {
 					return nil, err
 				}
-				if *noIndentOpt {
-					return mxj.Map(obj).Xml()
+				if declaration {
+					b = append([]byte(xml.Header), b...)
 				}
-				return mxj.Map(obj).XmlIndent("", indent)
+				return b, nil
 			}), nil
 		}); err != nil {
 		panic(err)
 	}
 }
+
+// mxjKeys returns a copy of a structure where keys with a custom attribute
+// prefix or text key are replaced with those expected by mxj.
+func mxjKeys(obj map[string]any, attrPrefix, textKey string) map[string]any {
+	res := make(map[string]any, len(obj))
+	for k, v := range obj {
+		switch {
+		case k == textKey:
+			k = "#text"
+		case strings.HasPrefix(k, attrPrefix):
+			k = "-" + strings.TrimPrefix(k, attrPrefix)
+		}
+		res[k] = mxjValueKeys(v, attrPrefix, textKey)
+	}
+	return res
+}
+
+func mxjValueKeys(v any, attrPrefix, textKey string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		return mxjKeys(t, attrPrefix, textKey)
+	case []any:
+		res := make([]any, len(t))
+		for i, e := range t {
+			res[i] = mxjValueKeys(e, attrPrefix, textKey)
+		}
+		return res
+	}
+	return v
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/benthosdev/benthos/v4/internal/bloblang/query"
 	_ "github.com/benthosdev/benthos/v4/internal/impl/xml"
 	"github.com/benthosdev/benthos/v4/internal/value"
+	"github.com/benthosdev/benthos/v4/public/bloblang"
 )
 
 func TestParseXML(t *testing.T) {
@@ -64,3 +65,103 @@ func TestParseXML(t *testing.T) {
 		})
 	}
 }
+
+func TestParseXMLOptions(t *testing.T) {
+	testCases := []struct {
+		name        string
+		mapping     string
+		input       string
+		exp         any
+		errContains string
+	}{
+		{
+			name:    "preserve namespaces",
+			mapping: `root = this.parse_xml(preserve_namespaces: true)`,
+			input:   `<soap:Envelope xmlns:soap="http://s" xmlns="http://d"><soap:Body><x:Item xmlns:x="http://x" x:id="1">foo</x:Item></soap:Body></soap:Envelope>`,
+			exp: map[string]any{"soap:Envelope": map[string]any{
+				"-xmlns:soap": "http://s",
+				"-xmlns":      "http://d",
+				"soap:Body": map[string]any{
+					"x:Item": map[string]any{"-xmlns:x": "http://x", "-x:id": "1", "#text": "foo"},
+				},
+			}},
+		},
+		{
+			name:    "arrays",
+			mapping: `root = this.parse_xml(arrays: ["root.items.item", "root.tags"])`,
+			input:   `<root><items><item>a</item></items><tags><tag>x</tag></tags><other>b</other><other>c</other></root>`,
+			exp: map[string]any{"root": map[string]any{
+				"items": map[string]any{"item": []any{"a"}},
+				"tags":  []any{map[string]any{"tag": "x"}},
+				"other": []any{"b", "c"},
+			}},
+		},
+		{
+			name:    "types and casting",
+			mapping: `root = this.parse_xml(cast: true, types: {"root.id": "string", "root.count": "int", "root.-ok": "bool"})`,
+			input:   `<root ok="1"><id>007</id><count>12</count><ratio>0.5</ratio><flag>true</flag><n>NaN</n></root>`,
+			exp: map[string]any{"root": map[string]any{
+				"-ok":   true,
+				"id":    "007",
+				"count": int64(12),
+				"ratio": 0.5,
+				"flag":  true,
+				"n":     "NaN",
+			}},
+		},
+		{
+			name:    "custom keys",
+			mapping: `root = this.parse_xml(attr_prefix: "@", text_key: "value")`,
+			input:   `<root><a id="1">foo</a><b id="2"/></root>`,
+			exp: map[string]any{"root": map[string]any{
+				"a": map[string]any{"@id": "1", "value": "foo"},
+				"b": map[string]any{"@id": "2"},
+			}},
+		},
+		{
+			name:        "bad type hint value",
+			mapping:     `root = this.parse_xml(types: {"root.id": "int"})`,
+			input:       `<root><id>abc</id></root>`,
+			errContains: "failed to parse value at path root.id as int",
+		},
+		{
+			name:        "unclosed",
+			mapping:     `root = this.parse_xml(arrays: ["root.id"])`,
+			input:       `<root><id>abc</id>`,
+			errContains: "unexpected EOF",
+		},
+	}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			exec, err := bloblang.Parse(test.mapping)
+			require.NoError(t, err)
+
+			res, err := exec.Query(test.input)
+			if test.errContains != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.exp, res)
+		})
+	}
+
+	_, err := bloblang.Parse(`root = this.parse_xml(types: {"root.id": "date"})`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unrecognised type hint 'date'")
+}
+
+func TestFormatXMLRoundTrip(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://s"><s:Body><item id="1">foo</item></s:Body></s:Envelope>`
+
+	exec, err := bloblang.Parse(`root = this.parse_xml(preserve_namespaces: true, attr_prefix: "@", text_key: "value").format_xml(no_indent: true, attr_prefix: "@", text_key: "value", declaration: true).string()`)
+	require.NoError(t, err)
+
+	res, err := exec.Query(input)
+	require.NoError(t, err)
+	assert.Equal(t, input, res)
+}
@@ -0,0 +1,237 @@
+package xml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// DecodeOptions customise the structure produced when parsing XML documents
+// with Decode.
+type DecodeOptions struct {
+	// Cast attempts to cast values that are numbers and booleans to the right
+	// type.
+	Cast bool
+
+	// PreserveNamespaces keeps the namespace prefixes of element and attribute
+	// names, and namespace declarations as attributes, as they appear within
+	// the document.
+	PreserveNamespaces bool
+
+	// AttrPrefix is prefixed to the keys of attributes, defaults to `-`.
+	AttrPrefix string
+
+	// TextKey is the key given to the text of elements that also contain
+	// attributes or child elements, defaults to `#text`.
+	TextKey string
+
+	// Arrays are the paths of elements that are always parsed as arrays, even
+	// when they only occur once.
+	Arrays []string
+
+	// Types are hints for the types of values at specific paths, where each
+	// type is one of `string`, `number`, `int` or `bool`. Hinted values are
+	// not cast.
+	Types map[string]string
+}
+
+// Type hints supported by DecodeOptions.
+const (
+	TypeHintString = "string"
+	TypeHintNumber = "number"
+	TypeHintInt    = "int"
+	TypeHintBool   = "bool"
+)
+
+func (o DecodeOptions) validate() error {
+	for path, t := range o.Types {
+		switch t {
+		case TypeHintString, TypeHintNumber, TypeHintInt, TypeHintBool:
+		default:
+			return fmt.Errorf("unrecognised type hint '%v' for path %v", t, path)
+		}
+	}
+	return nil
+}
+
+type decoder struct {
+	dec    *xml.Decoder
+	opts   DecodeOptions
+	arrays map[string]struct{}
+}
+
+// Decode parses a byte slice as XML and returns a generic structure that can
+// be serialized to JSON, following the same conventions as ToMap. Paths within
+// the options are the names of elements from the root of the document joined
+// with dots, such as `catalog.book`, where an attribute is addressed by its key
+// such as `catalog.book.-id`.
+func Decode(xmlBytes []byte, opts DecodeOptions) (map[string]any, error) {
+	if opts.AttrPrefix == "" {
+		opts.AttrPrefix = "-"
+	}
+	if opts.TextKey == "" {
+		opts.TextKey = "#text"
+	}
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	d := &decoder{
+		dec:    xml.NewDecoder(bytes.NewReader(xmlBytes)),
+		opts:   opts,
+		arrays: make(map[string]struct{}, len(opts.Arrays)),
+	}
+	d.dec.Strict = false
+	d.dec.CharsetReader = charset.NewReaderLabel
+	for _, p := range opts.Arrays {
+		d.arrays[p] = struct{}{}
+	}
+
+	for {
+		tok, err := d.token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, errors.New("no root element found")
+			}
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			name := d.name(start.Name)
+			v, err := d.element(start, name)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]any{name: v}, nil
+		}
+	}
+}
+
+func (d *decoder) token() (xml.Token, error) {
+	// Raw tokens retain the namespace prefixes of names rather than resolving
+	// them to URLs.
+	if d.opts.PreserveNamespaces {
+		return d.dec.RawToken()
+	}
+	return d.dec.Token()
+}
+
+func (d *decoder) name(n xml.Name) string {
+	if d.opts.PreserveNamespaces && n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+func (d *decoder) element(start xml.StartElement, path string) (any, error) {
+	obj := map[string]any{}
+	for _, attr := range start.Attr {
+		key := d.opts.AttrPrefix + d.name(attr.Name)
+		v, err := d.value(path+"."+key, attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = v
+	}
+
+	var text strings.Builder
+	for done := false; !done; {
+		tok, err := d.token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := d.name(t.Name)
+			childPath := path + "." + name
+			v, err := d.element(t, childPath)
+			if err != nil {
+				return nil, err
+			}
+			if existing, exists := obj[name]; exists {
+				if arr, isArr := existing.([]any); isArr {
+					obj[name] = append(arr, v)
+				} else {
+					obj[name] = []any{existing, v}
+				}
+			} else if _, isArr := d.arrays[childPath]; isArr {
+				obj[name] = []any{v}
+			} else {
+				obj[name] = v
+			}
+		case xml.CharData:
+			_, _ = text.Write(t)
+		case xml.EndElement:
+			done = true
+		}
+	}
+
+	textStr := strings.Trim(text.String(), "\t\r\b\n ")
+	if len(obj) == 0 {
+		return d.value(path, textStr)
+	}
+	if textStr != "" {
+		v, err := d.value(path, textStr)
+		if err != nil {
+			return nil, err
+		}
+		obj[d.opts.TextKey] = v
+	}
+	return obj, nil
+}
+
+func (d *decoder) value(path, s string) (any, error) {
+	hint, exists := d.opts.Types[path]
+	if !exists {
+		if d.opts.Cast {
+			return castValue(s), nil
+		}
+		return s, nil
+	}
+
+	var v any
+	var err error
+	switch hint {
+	case TypeHintNumber:
+		v, err = strconv.ParseFloat(s, 64)
+	case TypeHintInt:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case TypeHintBool:
+		v, err = strconv.ParseBool(s)
+	default:
+		v = s
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse value at path %v as %v: %w", path, hint, err)
+	}
+	return v, nil
+}
+
+// castValue attempts to cast a string to a number or boolean in the same way as
+// ToMap.
+func castValue(s string) any {
+	switch strings.ToLower(s) {
+	case "nan", "inf", "-inf":
+		return s
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if len(s) > 0 && len(s) < 6 {
+		switch s[:1] {
+		case "t", "T", "f", "F":
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	}
+	return s
+}
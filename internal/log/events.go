@@ -0,0 +1,112 @@
+package log
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event is a single log event emitted by a logger.
+type Event struct {
+	Time    time.Time
+	Level   string
+	Message string
+	Fields  map[string]any
+}
+
+// Levels lists the levels of log events in order of severity.
+var Levels = []string{"FATAL", "ERROR", "WARN", "INFO", "DEBUG", "TRACE"}
+
+type eventSub struct {
+	events  chan Event
+	dropped atomic.Int64
+}
+
+var (
+	eventSubsMut sync.RWMutex
+	eventSubs    = map[*eventSub]struct{}{}
+	eventSubsN   atomic.Int32
+)
+
+// EventSubscription receives the log events emitted by all loggers created
+// with New.
+type EventSubscription struct {
+	sub *eventSub
+}
+
+// SubscribeEvents begins receiving copies of the log events emitted by all
+// loggers created with New. Events are buffered up to the given size, after
+// which further events are dropped until the buffer is drained. Events are
+// only emitted for levels that are enabled by the logger config.
+func SubscribeEvents(bufferSize int) *EventSubscription {
+	s := &eventSub{events: make(chan Event, bufferSize)}
+
+	eventSubsMut.Lock()
+	eventSubs[s] = struct{}{}
+	eventSubsN.Store(int32(len(eventSubs)))
+	eventSubsMut.Unlock()
+
+	return &EventSubscription{sub: s}
+}
+
+// Events returns a channel of log events.
+func (e *EventSubscription) Events() <-chan Event {
+	return e.sub.events
+}
+
+// Dropped returns the number of events dropped since the last call due to the
+// buffer being full.
+func (e *EventSubscription) Dropped() int64 {
+	return e.sub.dropped.Swap(0)
+}
+
+// Close stops the subscription from receiving further events.
+func (e *EventSubscription) Close() {
+	eventSubsMut.Lock()
+	delete(eventSubs, e.sub)
+	eventSubsN.Store(int32(len(eventSubs)))
+	eventSubsMut.Unlock()
+}
+
+type eventHook struct{}
+
+func (eventHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (eventHook) Fire(entry *logrus.Entry) error {
+	if eventSubsN.Load() == 0 {
+		return nil
+	}
+
+	level := strings.ToUpper(entry.Level.String())
+	if level == "WARNING" {
+		level = "WARN"
+	}
+
+	fields := make(map[string]any, len(entry.Data))
+	for k, v := range entry.Data {
+		fields[k] = v
+	}
+	e := Event{
+		Time:    entry.Time,
+		Level:   level,
+		Message: entry.Message,
+		Fields:  fields,
+	}
+
+	eventSubsMut.RLock()
+	defer eventSubsMut.RUnlock()
+
+	for s := range eventSubs {
+		select {
+		case s.events <- e:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+)
+
+func TestSubscribeEvents(t *testing.T) {
+	loggerConfig := NewConfig()
+	loggerConfig.LogLevel = "INFO"
+
+	var buf bytes.Buffer
+
+	logger, err := New(&buf, ifs.OS(), loggerConfig)
+	require.NoError(t, err)
+
+	// Events emitted without subscribers are not buffered.
+	logger.Warn("before subscribing")
+
+	sub := SubscribeEvents(2)
+
+	logger.With("label", "foo").Warn("first %v", "message")
+	logger.Debug("not enabled")
+	logger.Error("second message")
+	logger.Info("dropped message")
+
+	sub.Close()
+	logger.Error("after closing")
+
+	var events []Event
+	for len(sub.Events()) > 0 {
+		events = append(events, <-sub.Events())
+	}
+	require.Len(t, events, 2)
+
+	assert.Equal(t, "WARN", events[0].Level)
+	assert.Equal(t, "first message", events[0].Message)
+	assert.Equal(t, "foo", events[0].Fields["label"])
+	assert.False(t, events[0].Time.IsZero())
+
+	assert.Equal(t, "ERROR", events[1].Level)
+	assert.Equal(t, "second message", events[1].Message)
+
+	assert.Equal(t, int64(1), sub.Dropped())
+	assert.Equal(t, int64(0), sub.Dropped())
+}
@@ -46,6 +46,7 @@ func New(stream io.Writer, fs ifs.FS, config Config) (Modular, error) {
 
 	logger := logrus.New()
 	logger.Out = stream
+	logger.AddHook(eventHook{})
 
 	switch config.Format {
 	case "json":
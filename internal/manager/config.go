@@ -25,6 +25,7 @@ type ResourceConfig struct {
 	ResourceOutputs    []output.Config    `yaml:"output_resources,omitempty"`
 	ResourceCaches     []cache.Config     `yaml:"cache_resources,omitempty"`
 	ResourceRateLimits []ratelimit.Config `yaml:"rate_limit_resources,omitempty"`
+	ResourceHooks      []HookConfig       `yaml:"resource_hooks,omitempty"`
 }
 
 // NewResourceConfig creates a ResourceConfig with default values.
@@ -35,6 +36,7 @@ func NewResourceConfig() ResourceConfig {
 		ResourceOutputs:    []output.Config{},
 		ResourceCaches:     []cache.Config{},
 		ResourceRateLimits: []ratelimit.Config{},
+		ResourceHooks:      []HookConfig{},
 	}
 }
 
@@ -46,6 +48,7 @@ func (r *ResourceConfig) AddFrom(extra *ResourceConfig) error {
 	r.ResourceOutputs = append(r.ResourceOutputs, extra.ResourceOutputs...)
 	r.ResourceCaches = append(r.ResourceCaches, extra.ResourceCaches...)
 	r.ResourceRateLimits = append(r.ResourceRateLimits, extra.ResourceRateLimits...)
+	r.ResourceHooks = append(r.ResourceHooks, extra.ResourceHooks...)
 	return nil
 }
 
@@ -132,5 +135,11 @@ func FromParsed(prov docs.Provider, pConf *docs.ParsedConfig) (conf ResourceConf
 		}
 		conf.ResourceRateLimits = append(conf.ResourceRateLimits, c)
 	}
+
+	var hooks []HookConfig
+	if hooks, err = hooksFromParsed(prov, pConf); err != nil {
+		return
+	}
+	conf.ResourceHooks = append(conf.ResourceHooks, hooks...)
 	return
 }
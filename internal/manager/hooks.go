@@ -0,0 +1,167 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/component/processor"
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/message"
+)
+
+const (
+	fieldResourceHooks   = "resource_hooks"
+	fieldHookResource    = "resource"
+	fieldHookInit        = "init"
+	fieldHookOnFailure   = "on_failure"
+	fieldHookTimeout     = "timeout"
+	hookOnFailureFail    = "fail"
+	hookOnFailureDegrade = "degrade"
+)
+
+// HookConfig describes a lifecycle hook of a resource, which is a list of
+// processors executed once all resources have been initialised.
+type HookConfig struct {
+	Resource  string             `yaml:"resource"`
+	Init      []processor.Config `yaml:"init"`
+	OnFailure string             `yaml:"on_failure"`
+	Timeout   string             `yaml:"timeout"`
+}
+
+// NewHookConfig creates a HookConfig with default values.
+func NewHookConfig() HookConfig {
+	return HookConfig{
+		Init:      []processor.Config{},
+		OnFailure: hookOnFailureFail,
+		Timeout:   "30s",
+	}
+}
+
+func hooksFieldSpec() docs.FieldSpec {
+	return docs.FieldObject(
+		fieldResourceHooks, "A list of lifecycle hooks for resources, which are executed once all resources have been initialised and before any inputs, pipelines or outputs of the config are started. Hooks can be used to warm caches or probe that dependencies such as database tables exist, allowing misconfigurations to surface at boot rather than upon the first message.",
+	).Array().WithChildren(
+		docs.FieldString(fieldHookResource, "The label of the resource that the hook belongs to, which must match an existing resource."),
+		docs.FieldProcessor(fieldHookInit, "A list of processors to execute against a single empty message. The hook fails if any processor returns an error or flags the message as having failed.").Array().HasDefault([]any{}),
+		docs.FieldString(fieldHookOnFailure, "Determines what happens when the hook fails.").HasAnnotatedOptions(
+			hookOnFailureFail, "Abort startup with an error.",
+			hookOnFailureDegrade, "Log the error and continue in a degraded state.",
+		).HasDefault(hookOnFailureFail),
+		docs.FieldString(fieldHookTimeout, "The maximum period of time to wait for the hook to complete.").HasDefault("30s"),
+	).HasDefault([]any{}).Advanced().AtVersion("4.28.0")
+}
+
+func hooksFromParsed(prov docs.Provider, pConf *docs.ParsedConfig) (hooks []HookConfig, err error) {
+	var l []*docs.ParsedConfig
+	if l, err = pConf.FieldObjectList(fieldResourceHooks); err != nil {
+		return
+	}
+	for _, p := range l {
+		h := NewHookConfig()
+		if h.Resource, err = p.FieldString(fieldHookResource); err != nil {
+			return
+		}
+		var procs []*docs.ParsedConfig
+		if procs, err = p.FieldAnyList(fieldHookInit); err != nil {
+			return
+		}
+		for _, pp := range procs {
+			var v any
+			if v, err = pp.FieldAny(); err != nil {
+				return
+			}
+			var c processor.Config
+			if c, err = processor.FromAny(prov, v); err != nil {
+				return
+			}
+			h.Init = append(h.Init, c)
+		}
+		if h.OnFailure, err = p.FieldString(fieldHookOnFailure); err != nil {
+			return
+		}
+		if h.Timeout, err = p.FieldString(fieldHookTimeout); err != nil {
+			return
+		}
+		hooks = append(hooks, h)
+	}
+	return
+}
+
+// probeResource returns whether a resource of any type exists with a label.
+func (t *Type) probeResource(label string) bool {
+	return t.ProbeCache(label) ||
+		t.ProbeInput(label) ||
+		t.ProbeOutput(label) ||
+		t.ProbeProcessor(label) ||
+		t.ProbeRateLimit(label)
+}
+
+func (t *Type) runHook(index int, conf HookConfig) (err error) {
+	timeout, err := time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return fmt.Errorf("failed to parse timeout: %w", err)
+	}
+
+	procs := make([]processor.V1, 0, len(conf.Init))
+	defer func() {
+		for _, p := range procs {
+			_ = p.Close(context.Background())
+		}
+	}()
+	for i, pConf := range conf.Init {
+		p, err := t.intoPath(fieldResourceHooks, strconv.Itoa(index), fieldHookInit, strconv.Itoa(i)).NewProcessor(pConf)
+		if err != nil {
+			return fmt.Errorf("failed to initialise processor %v: %w", i, err)
+		}
+		procs = append(procs, p)
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), timeout)
+	defer done()
+
+	batches, err := processor.ExecuteTryAll(ctx, procs, message.QuickBatch([][]byte{nil}))
+	if err != nil {
+		return err
+	}
+	for _, b := range batches {
+		for _, p := range b {
+			if pErr := p.ErrorGet(); pErr != nil {
+				return pErr
+			}
+		}
+	}
+	return nil
+}
+
+// runHooks executes the lifecycle hooks of resources in the order they were
+// configured.
+func (t *Type) runHooks(hooks []HookConfig) error {
+	for i, h := range hooks {
+		if h.Resource == "" {
+			return errors.New("resource hook has an empty resource label")
+		}
+		if !t.probeResource(h.Resource) {
+			return fmt.Errorf("resource hook refers to resource '%v', which does not exist", h.Resource)
+		}
+		switch h.OnFailure {
+		case hookOnFailureFail, hookOnFailureDegrade:
+		default:
+			return fmt.Errorf("resource hook for '%v' has an unrecognised on_failure value: %v", h.Resource, h.OnFailure)
+		}
+
+		err := t.runHook(i, h)
+		if err == nil {
+			t.logger.Debug("Resource hook for '%v' succeeded", h.Resource)
+			continue
+		}
+		if h.OnFailure == hookOnFailureFail {
+			return fmt.Errorf("resource hook for '%v' failed: %w", h.Resource, err)
+		}
+		t.logger.Error("Resource hook for '%v' failed, continuing in a degraded state: %v", h.Resource, err)
+		t.stats.GetCounterVec("resource_hook_failed", "label").With(h.Resource).Incr(1)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package manager_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/cache"
+	"github.com/benthosdev/benthos/v4/internal/component/testutil"
+	"github.com/benthosdev/benthos/v4/internal/manager"
+)
+
+func TestResourceHooksWarmCache(t *testing.T) {
+	conf, err := testutil.ManagerFromYAML(`
+cache_resources:
+  - label: foocache
+    memory: {}
+resource_hooks:
+  - resource: foocache
+    init:
+      - mapping: 'root = "warm value"'
+      - cache:
+          resource: foocache
+          operator: set
+          key: warm
+          value: ${! content() }
+`)
+	require.NoError(t, err)
+	require.Len(t, conf.ResourceHooks, 1)
+	assert.Equal(t, "fail", conf.ResourceHooks[0].OnFailure)
+
+	mgr, err := manager.New(conf)
+	require.NoError(t, err)
+
+	var v []byte
+	require.NoError(t, mgr.AccessCache(context.Background(), "foocache", func(c cache.V1) {
+		v, err = c.Get(context.Background(), "warm")
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "warm value", string(v))
+}
+
+func TestResourceHooksFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		errContains string
+	}{
+		{
+			name: "probe fails",
+			input: `
+cache_resources:
+  - label: foocache
+    memory: {}
+resource_hooks:
+  - resource: foocache
+    init:
+      - cache:
+          resource: foocache
+          operator: get
+          key: missing
+`,
+			errContains: "resource hook for 'foocache' failed: operator failed for key 'missing': key does not exist",
+		},
+		{
+			name: "probe fails degraded",
+			input: `
+cache_resources:
+  - label: foocache
+    memory: {}
+resource_hooks:
+  - resource: foocache
+    on_failure: degrade
+    init:
+      - cache:
+          resource: foocache
+          operator: get
+          key: missing
+`,
+		},
+		{
+			name: "mapping fails",
+			input: `
+processor_resources:
+  - label: fooproc
+    noop: {}
+resource_hooks:
+  - resource: fooproc
+    init:
+      - mapping: 'root = throw("table does not exist")'
+`,
+			errContains: "table does not exist",
+		},
+		{
+			name: "unknown resource",
+			input: `
+resource_hooks:
+  - resource: nope
+    init:
+      - noop: {}
+`,
+			errContains: "resource hook refers to resource 'nope', which does not exist",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			conf, err := testutil.ManagerFromYAML(test.input)
+			require.NoError(t, err)
+
+			_, err = manager.New(conf)
+			if test.errContains == "" {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), test.errContains)
+			}
+		})
+	}
+}
@@ -33,6 +33,7 @@ type Manager struct {
 	Outputs    map[string]OutputWriter
 	Processors map[string]Processor
 	Pipes      map[string]<-chan message.Transaction
+	Generics   sync.Map
 	lock       sync.Mutex
 
 	// OnRegisterEndpoint can be set in order to intercept endpoints registered
@@ -362,3 +363,19 @@ func (m *Manager) SetPipe(name string, t <-chan message.Transaction) {
 func (m *Manager) UnsetPipe(name string, t <-chan message.Transaction) {
 	delete(m.Pipes, name)
 }
+
+// GetGeneric attempts to obtain a generic value stored under a key.
+func (m *Manager) GetGeneric(key any) (any, bool) {
+	return m.Generics.Load(key)
+}
+
+// GetOrSetGeneric returns the generic value stored under a key if it exists,
+// otherwise the provided value is stored and returned.
+func (m *Manager) GetOrSetGeneric(key, value any) (any, bool) {
+	return m.Generics.LoadOrStore(key, value)
+}
+
+// SetGeneric stores a generic value under a key.
+func (m *Manager) SetGeneric(key, value any) {
+	m.Generics.Store(key, value)
+}
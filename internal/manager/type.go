@@ -88,6 +88,10 @@ type Type struct {
 
 	pipes    map[string]<-chan message.Transaction
 	pipeLock *sync.RWMutex
+
+	// Values shared by components of the manager, and of all managers derived
+	// from it.
+	genericValues *sync.Map
 }
 
 // OptFunc is an opt setting for a manager type.
@@ -192,6 +196,8 @@ func New(conf ResourceConfig, opts ...OptFunc) (*Type, error) {
 
 		pipes:    map[string]<-chan message.Transaction{},
 		pipeLock: &sync.RWMutex{},
+
+		genericValues: &sync.Map{},
 	}
 
 	for _, opt := range opts {
@@ -279,6 +285,9 @@ func New(conf ResourceConfig, opts ...OptFunc) (*Type, error) {
 		}
 	}
 
+	if err := t.runHooks(conf.ResourceHooks); err != nil {
+		return nil, err
+	}
 	return t, nil
 }
 
@@ -396,6 +405,24 @@ func (t *Type) UnsetPipe(name string, tran <-chan message.Transaction) {
 	t.pipeLock.Unlock()
 }
 
+// GetGeneric attempts to obtain and return a generic value shared by components
+// of the manager.
+func (t *Type) GetGeneric(key any) (any, bool) {
+	return t.genericValues.Load(key)
+}
+
+// GetOrSetGeneric returns the generic value stored under a key if it exists,
+// otherwise the provided value is stored and returned. The loaded result is
+// true if the value was loaded and false if stored.
+func (t *Type) GetOrSetGeneric(key, value any) (any, bool) {
+	return t.genericValues.LoadOrStore(key, value)
+}
+
+// SetGeneric stores a generic value shared by components of the manager.
+func (t *Type) SetGeneric(key, value any) {
+	t.genericValues.Store(key, value)
+}
+
 //------------------------------------------------------------------------------
 
 // WithMetricsMapping returns a manager with the stored metrics exporter wrapped
@@ -0,0 +1,86 @@
+package memguard
+
+import (
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+const (
+	fieldEnabled                  = "enabled"
+	fieldLimit                    = "limit"
+	fieldCheckInterval            = "check_interval"
+	fieldThresholds               = "thresholds"
+	fieldThresholdsShrinkBatches  = "shrink_batches"
+	fieldThresholdsRejectRequests = "reject_requests"
+	fieldThresholdsPauseInputs    = "pause_inputs"
+)
+
+// Config holds configuration options for the memory guard.
+type Config struct {
+	Enabled       bool       `yaml:"enabled"`
+	Limit         string     `yaml:"limit"`
+	CheckInterval string     `yaml:"check_interval"`
+	Thresholds    Thresholds `yaml:"thresholds"`
+}
+
+// Thresholds describes the fractions of the memory limit at which each load
+// shedding policy is applied.
+type Thresholds struct {
+	ShrinkBatches  float64 `yaml:"shrink_batches"`
+	RejectRequests float64 `yaml:"reject_requests"`
+	PauseInputs    float64 `yaml:"pause_inputs"`
+}
+
+// NewConfig returns a config struct with the default values for each field.
+func NewConfig() Config {
+	return Config{
+		Enabled:       false,
+		Limit:         "",
+		CheckInterval: "1s",
+		Thresholds: Thresholds{
+			ShrinkBatches:  0.7,
+			RejectRequests: 0.8,
+			PauseInputs:    0.9,
+		},
+	}
+}
+
+// Spec returns a field spec for the memory guard configuration fields.
+func Spec() docs.FieldSpecs {
+	return docs.FieldSpecs{
+		docs.FieldBool(fieldEnabled, "Whether the memory guard is enabled.").HasDefault(false),
+		docs.FieldString(fieldLimit, "The memory ceiling of the process, which thresholds are relative to. When left empty the soft memory limit of the Go runtime (`GOMEMLIMIT`) is used, and one of the two must be set in order to enable the memory guard.", "2GiB", "512MB").HasDefault(""),
+		docs.FieldString(fieldCheckInterval, "The period of time between each check of the memory used by the process.").HasDefault("1s"),
+		docs.FieldObject(fieldThresholds, "Fractions of the memory limit at which each load shedding policy is applied. A policy is lifted once the memory used drops below 95% of its threshold, and a threshold of zero disables the policy.").WithChildren(
+			docs.FieldFloat(fieldThresholdsShrinkBatches, "The threshold at which batch policies flush their batches at a quarter of their configured `count`, `byte_size` and `period`.").HasDefault(0.7),
+			docs.FieldFloat(fieldThresholdsRejectRequests, "The threshold at which HTTP server inputs reject requests with a 503 status code.").HasDefault(0.8),
+			docs.FieldFloat(fieldThresholdsPauseInputs, "The threshold at which inputs stop consuming new messages.").HasDefault(0.9),
+		),
+	}
+}
+
+// FromParsed extracts a memory guard config from a parsed config.
+func FromParsed(pConf *docs.ParsedConfig) (conf Config, err error) {
+	conf = NewConfig()
+	if conf.Enabled, err = pConf.FieldBool(fieldEnabled); err != nil {
+		return
+	}
+	if conf.Limit, err = pConf.FieldString(fieldLimit); err != nil {
+		return
+	}
+	if conf.CheckInterval, err = pConf.FieldString(fieldCheckInterval); err != nil {
+		return
+	}
+	if pConf.Contains(fieldThresholds) {
+		tConf := pConf.Namespace(fieldThresholds)
+		if conf.Thresholds.ShrinkBatches, err = tConf.FieldFloat(fieldThresholdsShrinkBatches); err != nil {
+			return
+		}
+		if conf.Thresholds.RejectRequests, err = tConf.FieldFloat(fieldThresholdsRejectRequests); err != nil {
+			return
+		}
+		if conf.Thresholds.PauseInputs, err = tConf.FieldFloat(fieldThresholdsPauseInputs); err != nil {
+			return
+		}
+	}
+	return
+}
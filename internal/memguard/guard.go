@@ -0,0 +1,246 @@
+// Package memguard implements a process wide watchdog that monitors the memory
+// used by the process and, when it crosses configured thresholds, applies load
+// shedding policies that components observe in order to avoid being killed for
+// running out of memory.
+package memguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Jeffail/shutdown"
+	"github.com/dustin/go-humanize"
+
+	imetrics "github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+// Policy describes a load shedding behaviour applied by components whilst the
+// memory used by the process exceeds a threshold.
+type Policy int
+
+// Load shedding policies.
+const (
+	PolicyShrinkBatches Policy = iota
+	PolicyRejectRequests
+	PolicyPauseInputs
+)
+
+// String returns a name for the policy, which is used in logs and metrics.
+func (p Policy) String() string {
+	switch p {
+	case PolicyShrinkBatches:
+		return "shrink_batches"
+	case PolicyRejectRequests:
+		return "reject_requests"
+	case PolicyPauseInputs:
+		return "pause_inputs"
+	}
+	return "unknown"
+}
+
+var policies = []Policy{PolicyShrinkBatches, PolicyRejectRequests, PolicyPauseInputs}
+
+// ShrinkDivisor is the factor by which batch policies reduce the size of their
+// batches whilst the PolicyShrinkBatches policy is active.
+const ShrinkDivisor = 4
+
+// The fraction of a threshold that the memory used must drop below before the
+// corresponding policy is lifted, which prevents policies from flapping.
+const releaseFactor = 0.95
+
+//------------------------------------------------------------------------------
+
+var (
+	activePolicies [3]atomic.Bool
+
+	changedMut sync.Mutex
+	changed    = make(chan struct{})
+)
+
+// Active returns whether a given load shedding policy is currently applied.
+func Active(p Policy) bool {
+	return activePolicies[p].Load()
+}
+
+// WaitUntilInactive blocks until a given load shedding policy is no longer
+// applied, or the context is cancelled.
+func WaitUntilInactive(ctx context.Context, p Policy) error {
+	for {
+		changedMut.Lock()
+		changedChan := changed
+		changedMut.Unlock()
+
+		if !Active(p) {
+			return nil
+		}
+		select {
+		case <-changedChan:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func setActive(p Policy, active bool) bool {
+	if activePolicies[p].Swap(active) == active {
+		return false
+	}
+
+	changedMut.Lock()
+	close(changed)
+	changed = make(chan struct{})
+	changedMut.Unlock()
+	return true
+}
+
+//------------------------------------------------------------------------------
+
+// Guard periodically measures the memory used by the process and applies or
+// lifts load shedding policies according to configured thresholds.
+type Guard struct {
+	limit      uint64
+	interval   time.Duration
+	thresholds [3]float64
+	readUsage  func() uint64
+
+	log       log.Modular
+	mUsage    imetrics.StatGauge
+	mActive   imetrics.StatGaugeVec
+	mTriggers imetrics.StatCounterVec
+
+	shutSig *shutdown.Signaller
+}
+
+// New creates a memory guard from a config, the guard does not begin
+// monitoring memory until Run is called. Returns nil if the guard is disabled.
+func New(conf Config, logger log.Modular, stats *imetrics.Namespaced) (*Guard, error) {
+	if !conf.Enabled {
+		return nil, nil
+	}
+
+	g := &Guard{
+		thresholds: [3]float64{
+			PolicyShrinkBatches:  conf.Thresholds.ShrinkBatches,
+			PolicyRejectRequests: conf.Thresholds.RejectRequests,
+			PolicyPauseInputs:    conf.Thresholds.PauseInputs,
+		},
+		readUsage: readMemoryUsage,
+		log:       logger,
+		mUsage:    stats.GetGauge("memory_guard_usage_bytes"),
+		mActive:   stats.GetGaugeVec("memory_guard_policy_active", "policy"),
+		mTriggers: stats.GetCounterVec("memory_guard_policy_triggered", "policy"),
+		shutSig:   shutdown.NewSignaller(),
+	}
+
+	var err error
+	if conf.Limit != "" {
+		if g.limit, err = humanize.ParseBytes(conf.Limit); err != nil {
+			return nil, fmt.Errorf("failed to parse limit: %w", err)
+		}
+	} else if l := debug.SetMemoryLimit(-1); l != math.MaxInt64 {
+		g.limit = uint64(l)
+	}
+	if g.limit == 0 {
+		return nil, errors.New("a limit must be set when the Go runtime has no memory limit")
+	}
+
+	if g.interval, err = time.ParseDuration(conf.CheckInterval); err != nil {
+		return nil, fmt.Errorf("failed to parse check interval: %w", err)
+	}
+	if g.interval <= 0 {
+		return nil, errors.New("check interval must be greater than zero")
+	}
+
+	for _, p := range policies {
+		if t := g.thresholds[p]; t < 0 || t > 1 {
+			return nil, fmt.Errorf("threshold %v must be between 0 and 1, got %v", p, t)
+		}
+	}
+
+	stats.GetGauge("memory_guard_limit_bytes").Set(int64(g.limit))
+	return g, nil
+}
+
+func readMemoryUsage() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+}
+
+// Run begins monitoring memory in the background until Close is called.
+func (g *Guard) Run() {
+	go func() {
+		defer func() {
+			for _, p := range policies {
+				g.setPolicy(p, false, 0)
+			}
+			g.shutSig.TriggerHasStopped()
+		}()
+
+		ticker := time.NewTicker(g.interval)
+		defer ticker.Stop()
+
+		for {
+			g.check()
+			select {
+			case <-ticker.C:
+			case <-g.shutSig.SoftStopChan():
+				return
+			}
+		}
+	}()
+}
+
+func (g *Guard) check() {
+	usage := g.readUsage()
+	g.mUsage.Set(int64(usage))
+
+	ratio := float64(usage) / float64(g.limit)
+	for _, p := range policies {
+		threshold := g.thresholds[p]
+		if threshold == 0 {
+			continue
+		}
+		if ratio >= threshold {
+			g.setPolicy(p, true, usage)
+		} else if ratio < threshold*releaseFactor {
+			g.setPolicy(p, false, usage)
+		}
+	}
+}
+
+func (g *Guard) setPolicy(p Policy, active bool, usage uint64) {
+	if !setActive(p, active) {
+		return
+	}
+	if active {
+		g.mTriggers.With(p.String()).Incr(1)
+		g.mActive.With(p.String()).Set(1)
+		g.log.Warn("Memory usage of %v has exceeded the %v threshold of the %v limit, applying load shedding policy", humanize.IBytes(usage), p, humanize.IBytes(g.limit))
+	} else {
+		g.mActive.With(p.String()).Set(0)
+		g.log.Info("Lifting load shedding policy %v", p)
+	}
+}
+
+// Close stops monitoring memory and lifts all load shedding policies.
+func (g *Guard) Close(ctx context.Context) error {
+	g.shutSig.TriggerSoftStop()
+	select {
+	case <-g.shutSig.HasStoppedChan():
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
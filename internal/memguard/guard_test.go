@@ -0,0 +1,121 @@
+package memguard
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/component/metrics"
+	"github.com/benthosdev/benthos/v4/internal/log"
+)
+
+func testGuard(t *testing.T, usage *atomic.Uint64) *Guard {
+	t.Helper()
+
+	conf := NewConfig()
+	conf.Enabled = true
+	conf.Limit = "1000B"
+
+	g, err := New(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	g.readUsage = usage.Load
+
+	t.Cleanup(func() {
+		for _, p := range policies {
+			setActive(p, false)
+		}
+	})
+	return g
+}
+
+func TestGuardPolicies(t *testing.T) {
+	var usage atomic.Uint64
+	g := testGuard(t, &usage)
+
+	for _, test := range []struct {
+		usage  uint64
+		active [3]bool
+	}{
+		{usage: 500, active: [3]bool{false, false, false}},
+		{usage: 700, active: [3]bool{true, false, false}},
+		{usage: 850, active: [3]bool{true, true, false}},
+		{usage: 950, active: [3]bool{true, true, true}},
+		{usage: 870, active: [3]bool{true, true, true}},
+		{usage: 800, active: [3]bool{true, true, false}},
+		{usage: 700, active: [3]bool{true, false, false}},
+		{usage: 600, active: [3]bool{false, false, false}},
+	} {
+		usage.Store(test.usage)
+		g.check()
+		for _, p := range policies {
+			assert.Equal(t, test.active[p], Active(p), "usage: %v, policy: %v", test.usage, p)
+		}
+	}
+}
+
+func TestGuardWaitUntilInactive(t *testing.T) {
+	var usage atomic.Uint64
+	usage.Store(950)
+
+	g := testGuard(t, &usage)
+	g.check()
+	require.True(t, Active(PolicyPauseInputs))
+
+	ctx, done := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer done()
+	require.Error(t, WaitUntilInactive(ctx, PolicyPauseInputs))
+
+	waitErr := make(chan error)
+	go func() {
+		waitErr <- WaitUntilInactive(context.Background(), PolicyPauseInputs)
+	}()
+
+	usage.Store(100)
+	g.check()
+
+	select {
+	case err := <-waitErr:
+		require.NoError(t, err)
+	case <-time.After(time.Second * 5):
+		t.Fatal("timed out")
+	}
+}
+
+func TestGuardCloseLiftsPolicies(t *testing.T) {
+	var usage atomic.Uint64
+	usage.Store(950)
+
+	g := testGuard(t, &usage)
+	g.interval = time.Millisecond
+	g.Run()
+
+	assert.Eventually(t, func() bool {
+		return Active(PolicyPauseInputs)
+	}, time.Second*5, time.Millisecond)
+
+	require.NoError(t, g.Close(context.Background()))
+	for _, p := range policies {
+		assert.False(t, Active(p), p.String())
+	}
+}
+
+func TestGuardConfigErrors(t *testing.T) {
+	conf := NewConfig()
+	g, err := New(conf, log.Noop(), metrics.Noop())
+	require.NoError(t, err)
+	assert.Nil(t, g)
+
+	conf.Enabled = true
+	conf.Limit = "not a size"
+	_, err = New(conf, log.Noop(), metrics.Noop())
+	require.ErrorContains(t, err, "failed to parse limit")
+
+	conf.Limit = "1GB"
+	conf.Thresholds.PauseInputs = 1.5
+	_, err = New(conf, log.Noop(), metrics.Noop())
+	require.ErrorContains(t, err, "threshold pause_inputs must be between 0 and 1")
+}
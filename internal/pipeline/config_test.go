@@ -37,6 +37,18 @@ processors:
 				assert.Equal(t, "mapping", v.Processors[1].Type)
 			},
 		},
+		{
+			name: "ordering key",
+			input: `
+threads: 4
+ordering_key: '@kafka_key'
+processors: []
+`,
+			validateFn: func(t testing.TB, v pipeline.Config) {
+				assert.Equal(t, 4, v.Threads)
+				assert.Equal(t, "@kafka_key", v.OrderingKey)
+			},
+		},
 	}
 
 	for _, test := range tests {
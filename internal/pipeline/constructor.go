@@ -14,11 +14,17 @@ import (
 
 var threadsField = docs.FieldInt("threads", "The number of threads to execute processing pipelines across.").HasDefault(-1)
 
+var orderingKeyField = docs.FieldBloblang(
+	"ordering_key", "An optional [Bloblang query](/docs/guides/bloblang/about) that resolves a key for each batch of messages, where batches that share a key are always processed by the same thread and are therefore processed and passed to the output in the order that they were received, whilst batches of other keys are processed in parallel. The key is resolved from the first message of each batch, and batches where the query fails are processed by the first thread.",
+	"@kafka_key", "this.user.id",
+).AtVersion("4.28.0").Advanced().HasDefault("")
+
 func ConfigSpec() docs.FieldSpec {
 	return docs.FieldObject(
 		"pipeline", "Describes optional processing pipelines used for mutating messages.",
 	).WithChildren(
 		threadsField,
+		orderingKeyField,
 		docs.FieldProcessor("processors", "A list of processors to apply to messages.").Array().HasDefault([]any{}),
 	)
 }
@@ -32,8 +38,9 @@ func ConfigSpec() docs.FieldSpec {
 // number of parallel inputs that matches or surpasses the number of pipeline
 // threads, or use a memory buffer.
 type Config struct {
-	Threads    int                `json:"threads" yaml:"threads"`
-	Processors []processor.Config `json:"processors" yaml:"processors"`
+	Threads     int                `json:"threads" yaml:"threads"`
+	OrderingKey string             `json:"ordering_key" yaml:"ordering_key"`
+	Processors  []processor.Config `json:"processors" yaml:"processors"`
 }
 
 // NewConfig returns a configuration struct fully populated with default values.
@@ -60,6 +67,13 @@ func New(conf Config, mgr bundle.NewManagement) (processor.Pipeline, error) {
 	if conf.Threads == 1 {
 		return NewProcessor(processors...), nil
 	}
+	if conf.OrderingKey != "" {
+		key, err := mgr.BloblEnvironment().NewMapping(conf.OrderingKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ordering_key: %w", err)
+		}
+		return NewOrderedPool(conf.Threads, key, mgr.Logger(), processors...)
+	}
 	return NewPool(conf.Threads, mgr.Logger(), processors...)
 }
 
@@ -87,6 +101,14 @@ func fromMap(prov docs.Provider, val map[string]any) (conf Config, err error) {
 		conf.Threads = int(threads64)
 	}
 
+	if keyV, exists := val["ordering_key"]; exists {
+		var ok bool
+		if conf.OrderingKey, ok = keyV.(string); !ok {
+			err = fmt.Errorf("expected string value for ordering_key, got %T", keyV)
+			return
+		}
+	}
+
 	if procVs, ok := val["processors"].([]any); ok {
 		for _, iv := range procVs {
 			var tmpProc processor.Config
@@ -107,6 +129,10 @@ func fromYAML(prov docs.Provider, val *yaml.Node) (conf Config, err error) {
 			if err = val.Content[i+1].Decode(&conf.Threads); err != nil {
 				return
 			}
+		case "ordering_key":
+			if err = val.Content[i+1].Decode(&conf.OrderingKey); err != nil {
+				return
+			}
 		case "processors":
 			node := val.Content[i+1]
 			if node.Kind != yaml.SequenceNode {
@@ -2,12 +2,14 @@ package pipeline
 
 import (
 	"context"
+	"hash/fnv"
 	"runtime"
 	"sync"
 	"sync/atomic"
 
 	"github.com/Jeffail/shutdown"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang/mapping"
 	"github.com/benthosdev/benthos/v4/internal/component"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/log"
@@ -18,7 +20,8 @@ import (
 // channel. Inputs remain coupled to their outputs as they propagate the
 // response channel in the transaction.
 type Pool struct {
-	workers []processor.Pipeline
+	workers     []processor.Pipeline
+	orderingKey *mapping.Executor
 
 	log log.Modular
 
@@ -48,8 +51,69 @@ func NewPool(threads int, log log.Modular, msgProcessors ...processor.V1) (*Pool
 	return p, nil
 }
 
+// NewOrderedPool creates a new processing pool where each transaction is
+// dispatched to a pipeline chosen by the hash of an ordering key resolved from
+// its first message. Transactions that share a key are therefore processed in
+// order by the same pipeline.
+func NewOrderedPool(threads int, orderingKey *mapping.Executor, log log.Modular, msgProcessors ...processor.V1) (*Pool, error) {
+	p, err := NewPool(threads, log, msgProcessors...)
+	if err != nil {
+		return nil, err
+	}
+	p.orderingKey = orderingKey
+	return p, nil
+}
+
 //------------------------------------------------------------------------------
 
+// laneFor returns the index of the worker that should process a batch.
+func (p *Pool) laneFor(batch message.Batch) int {
+	if len(batch) == 0 {
+		return 0
+	}
+
+	part, err := p.orderingKey.MapPart(0, batch)
+	if err != nil {
+		p.log.Error("Failed to resolve ordering key: %v\n", err)
+		return 0
+	}
+	if part == nil {
+		return 0
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(part.AsBytes())
+	return int(h.Sum32() % uint32(len(p.workers)))
+}
+
+// dispatchLanes routes transactions from the input channel to the lane of
+// each worker according to their ordering key.
+func (p *Pool) dispatchLanes(lanes []chan message.Transaction) {
+	defer func() {
+		for _, l := range lanes {
+			close(l)
+		}
+	}()
+
+	for {
+		var t message.Transaction
+		var open bool
+		select {
+		case t, open = <-p.messagesIn:
+			if !open {
+				return
+			}
+		case <-p.shutSig.HardStopChan():
+			return
+		}
+		select {
+		case lanes[p.laneFor(t.Payload)] <- t:
+		case <-p.shutSig.HardStopChan():
+			return
+		}
+	}
+}
+
 // loop is the processing loop of this pipeline.
 func (p *Pool) loop() {
 	// Note this is currently kept open as we only have our children as a
@@ -75,8 +139,22 @@ func (p *Pool) loop() {
 
 	var closeInternalOnce sync.Once
 
-	for _, worker := range p.workers {
-		if err := worker.Consume(p.messagesIn); err != nil {
+	workerInputs := make([]<-chan message.Transaction, len(p.workers))
+	if p.orderingKey != nil {
+		lanes := make([]chan message.Transaction, len(p.workers))
+		for i := range lanes {
+			lanes[i] = make(chan message.Transaction)
+			workerInputs[i] = lanes[i]
+		}
+		go p.dispatchLanes(lanes)
+	} else {
+		for i := range workerInputs {
+			workerInputs[i] = p.messagesIn
+		}
+	}
+
+	for i, worker := range p.workers {
+		if err := worker.Consume(workerInputs[i]); err != nil {
 			p.log.Error("Failed to start pipeline worker: %v\n", err)
 			atomic.AddInt64(&remainingWorkers, -1)
 			continue
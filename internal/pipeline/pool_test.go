@@ -3,12 +3,14 @@ package pipeline_test
 import (
 	"context"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/benthosdev/benthos/v4/internal/bloblang"
 	"github.com/benthosdev/benthos/v4/internal/component/processor"
 	"github.com/benthosdev/benthos/v4/internal/log"
 	"github.com/benthosdev/benthos/v4/internal/manager/mock"
@@ -278,3 +280,68 @@ func TestPoolMultiNaturalClose(t *testing.T) {
 	close(tChan)
 	require.NoError(t, proc.WaitForClose(context.Background()))
 }
+
+type mockSleepProcessor struct{}
+
+func (m *mockSleepProcessor) ProcessBatch(ctx context.Context, msg message.Batch) ([]message.Batch, error) {
+	if msg.Get(0).MetaGetStr("key") == "slow" {
+		time.Sleep(time.Millisecond * 5)
+	}
+	return []message.Batch{msg}, nil
+}
+
+func (m *mockSleepProcessor) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestPoolOrderingKey(t *testing.T) {
+	ctx, done := context.WithTimeout(context.Background(), time.Second*30)
+	defer done()
+
+	key, err := bloblang.GlobalEnvironment().NewMapping(`root = @key`)
+	require.NoError(t, err)
+
+	proc, err := pipeline.NewOrderedPool(4, key, log.Noop(), &mockSleepProcessor{})
+	require.NoError(t, err)
+
+	tChan, resChan := make(chan message.Transaction), make(chan error, 100)
+	require.NoError(t, proc.Consume(tChan))
+
+	keys := []string{"slow", "fast", "other", "another"}
+	go func() {
+		for i := 0; i < 100; i++ {
+			part := message.NewPart([]byte(strconv.Itoa(i)))
+			part.MetaSetMut("key", keys[i%len(keys)])
+			select {
+			case tChan <- message.NewTransaction(message.Batch{part}, resChan):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	lastSeen := map[string]int{}
+	for i := 0; i < 100; i++ {
+		var tran message.Transaction
+		select {
+		case tran = <-proc.TransactionChan():
+		case <-ctx.Done():
+			t.Fatal("Timed out")
+		}
+
+		part := tran.Payload.Get(0)
+		n, err := strconv.Atoi(string(part.AsBytes()))
+		require.NoError(t, err)
+
+		k := part.MetaGetStr("key")
+		if last, exists := lastSeen[k]; exists {
+			assert.Greater(t, n, last, "key %v", k)
+		}
+		lastSeen[k] = n
+		require.NoError(t, tran.Ack(ctx, nil))
+	}
+	assert.Len(t, lastSeen, len(keys))
+
+	close(tChan)
+	require.NoError(t, proc.WaitForClose(ctx))
+}
@@ -0,0 +1,271 @@
+// Package retries implements retry policies with exponential back off and full
+// jitter, which can optionally be limited by a retry budget shared by all of
+// the calls made with a policy.
+package retries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExhausted is returned when a call is not retried because the retry
+// budget of a policy has been exhausted.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Config describes a retry policy.
+type Config struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the
+	// first, where zero means unlimited.
+	MaxAttempts int
+
+	// InitialInterval is the upper bound of the first back off period, which
+	// doubles with each retry until reaching MaxInterval.
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+
+	// MaxElapsedTime is the maximum period of time to spend on a call before
+	// giving up, where zero means unlimited.
+	MaxElapsedTime time.Duration
+
+	// BudgetRatio is the maximum ratio of retries to calls within each
+	// BudgetWindow, where zero disables the budget. BudgetMinRetries retries
+	// are allowed within each window regardless of the ratio.
+	BudgetRatio      float64
+	BudgetMinRetries int
+	BudgetWindow     time.Duration
+}
+
+// NewConfig returns a config with default values.
+func NewConfig() Config {
+	return Config{
+		MaxAttempts:      3,
+		InitialInterval:  time.Millisecond * 500,
+		MaxInterval:      time.Second * 10,
+		MaxElapsedTime:   time.Minute,
+		BudgetMinRetries: 10,
+		BudgetWindow:     time.Second * 10,
+	}
+}
+
+// AfterError wraps an error with a period of time to wait before retrying the
+// call, such as one given by a Retry-After header.
+type AfterError struct {
+	Err  error
+	Wait time.Duration
+}
+
+// After wraps an error with a period of time to wait before retrying.
+func After(wait time.Duration, err error) error {
+	return &AfterError{Err: err, Wait: wait}
+}
+
+func (e *AfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *AfterError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError wraps an error that should not be retried.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps an error in order to prevent the call from being retried.
+func Permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// Policy executes calls with retries.
+type Policy struct {
+	conf   Config
+	budget *budget
+
+	randMut sync.Mutex
+	rand    *rand.Rand
+
+	now func() time.Time
+}
+
+// NewPolicy returns a policy from a config.
+func NewPolicy(conf Config) (*Policy, error) {
+	if conf.MaxAttempts < 0 {
+		return nil, errors.New("max attempts must not be negative")
+	}
+	if conf.InitialInterval < 0 || conf.MaxInterval < 0 || conf.MaxElapsedTime < 0 {
+		return nil, errors.New("intervals must not be negative")
+	}
+	if conf.MaxInterval < conf.InitialInterval {
+		return nil, errors.New("max interval must not be lower than the initial interval")
+	}
+	if conf.BudgetRatio < 0 {
+		return nil, errors.New("budget ratio must not be negative")
+	}
+
+	p := &Policy{
+		conf: conf,
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:  time.Now,
+	}
+	if conf.BudgetRatio > 0 {
+		if conf.BudgetWindow <= 0 {
+			return nil, errors.New("budget window must be greater than zero")
+		}
+		p.budget = &budget{
+			ratio:      conf.BudgetRatio,
+			minRetries: conf.BudgetMinRetries,
+			window:     conf.BudgetWindow,
+		}
+	}
+	return p, nil
+}
+
+// Interval returns the period to wait before a retry, where attempt is the
+// number of attempts already made minus one. The period is chosen at random
+// between zero and the exponential back off period (full jitter).
+func (p *Policy) Interval(attempt int) time.Duration {
+	ceiling := p.conf.InitialInterval
+	for i := 0; i < attempt && ceiling < p.conf.MaxInterval; i++ {
+		ceiling *= 2
+	}
+	if ceiling > p.conf.MaxInterval {
+		ceiling = p.conf.MaxInterval
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+
+	p.randMut.Lock()
+	defer p.randMut.Unlock()
+	return time.Duration(p.rand.Int63n(int64(ceiling) + 1))
+}
+
+// Do executes a call until it succeeds or the policy gives up, returning the
+// last error. Errors wrapped with After are retried once the given period has
+// elapsed, and errors wrapped with Permanent are not retried.
+func (p *Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	start := p.now()
+	if p.budget != nil {
+		p.budget.call(start)
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var permErr *PermanentError
+		if errors.As(err, &permErr) {
+			return permErr.Err
+		}
+
+		wait := p.Interval(attempt)
+		if afterErr, ok := err.(*AfterError); ok {
+			wait, err = afterErr.Wait, afterErr.Err
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+		if p.conf.MaxAttempts > 0 && attempt+1 >= p.conf.MaxAttempts {
+			return err
+		}
+
+		now := p.now()
+		if p.conf.MaxElapsedTime > 0 && now.Add(wait).Sub(start) > p.conf.MaxElapsedTime {
+			return err
+		}
+		if p.budget != nil && !p.budget.retry(now) {
+			return fmt.Errorf("%w: %w", ErrBudgetExhausted, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// budget limits the retries of a policy to a ratio of its calls within a
+// window of time.
+type budget struct {
+	ratio      float64
+	minRetries int
+	window     time.Duration
+
+	mut         sync.Mutex
+	windowStart time.Time
+	calls       int
+	retries     int
+}
+
+func (b *budget) roll(now time.Time) {
+	if now.Sub(b.windowStart) >= b.window {
+		b.windowStart = now
+		b.calls, b.retries = 0, 0
+	}
+}
+
+func (b *budget) call(now time.Time) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.roll(now)
+	b.calls++
+}
+
+func (b *budget) retry(now time.Time) bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.roll(now)
+	if b.retries >= b.minRetries && float64(b.retries+1) > b.ratio*float64(b.calls) {
+		return false
+	}
+	b.retries++
+	return true
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date, into a period of time to wait.
+func ParseRetryAfter(value string) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	wait := time.Until(t)
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
@@ -0,0 +1,185 @@
+package retries
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPolicy(t *testing.T, fn func(c *Config)) *Policy {
+	t.Helper()
+	conf := NewConfig()
+	conf.InitialInterval = time.Millisecond
+	conf.MaxInterval = time.Millisecond * 5
+	if fn != nil {
+		fn(&conf)
+	}
+	p, err := NewPolicy(conf)
+	require.NoError(t, err)
+	return p
+}
+
+func TestPolicyInterval(t *testing.T) {
+	p := newTestPolicy(t, func(c *Config) {
+		c.InitialInterval = time.Second
+		c.MaxInterval = time.Second * 5
+	})
+
+	for i := 0; i < 100; i++ {
+		for attempt, ceiling := range []time.Duration{
+			time.Second, time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5,
+		} {
+			d := p.Interval(attempt)
+			assert.GreaterOrEqual(t, d, time.Duration(0))
+			assert.LessOrEqual(t, d, ceiling)
+		}
+	}
+
+	// Large attempt counts must not overflow.
+	assert.LessOrEqual(t, p.Interval(1000), time.Second*5)
+}
+
+func TestPolicyMaxAttempts(t *testing.T) {
+	p := newTestPolicy(t, nil)
+
+	var calls int
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return errors.New("nope")
+	})
+	require.EqualError(t, err, "nope")
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = p.Do(context.Background(), func(ctx context.Context) error {
+		if calls++; calls < 3 {
+			return errors.New("nope")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestPolicyPermanent(t *testing.T) {
+	p := newTestPolicy(t, nil)
+
+	var calls int
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return Permanent(errors.New("nope"))
+	})
+	require.EqualError(t, err, "nope")
+	assert.Equal(t, 1, calls)
+}
+
+func TestPolicyAfter(t *testing.T) {
+	p := newTestPolicy(t, nil)
+
+	var calls []time.Time
+	err := p.Do(context.Background(), func(ctx context.Context) error {
+		calls = append(calls, time.Now())
+		if len(calls) == 1 {
+			return After(time.Millisecond*50, errors.New("rate limited"))
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, calls, 2)
+	assert.GreaterOrEqual(t, calls[1].Sub(calls[0]), time.Millisecond*50)
+
+	// A wait beyond the maximum elapsed time gives up immediately.
+	p = newTestPolicy(t, func(c *Config) {
+		c.MaxElapsedTime = time.Second
+	})
+	err = p.Do(context.Background(), func(ctx context.Context) error {
+		return After(time.Hour, errors.New("rate limited"))
+	})
+	require.EqualError(t, err, "rate limited")
+}
+
+func TestPolicyContextCancelled(t *testing.T) {
+	p := newTestPolicy(t, func(c *Config) {
+		c.MaxAttempts = 0
+	})
+
+	ctx, done := context.WithCancel(context.Background())
+	var calls int
+	err := p.Do(ctx, func(ctx context.Context) error {
+		if calls++; calls == 5 {
+			done()
+		}
+		return errors.New("nope")
+	})
+	require.Error(t, err)
+	assert.Equal(t, 5, calls)
+}
+
+func TestPolicyBudget(t *testing.T) {
+	p := newTestPolicy(t, func(c *Config) {
+		c.MaxAttempts = 2
+		c.BudgetRatio = 0.5
+		c.BudgetMinRetries = 1
+		c.BudgetWindow = time.Hour
+	})
+
+	failing := func(ctx context.Context) error {
+		return errors.New("nope")
+	}
+
+	// The first retry is allowed by the minimum.
+	require.EqualError(t, p.Do(context.Background(), failing), "nope")
+
+	// Two calls allow one retry, which has already been spent.
+	err := p.Do(context.Background(), failing)
+	require.ErrorIs(t, err, ErrBudgetExhausted)
+
+	// Four calls allow two retries.
+	require.NoError(t, p.Do(context.Background(), func(ctx context.Context) error { return nil }))
+	require.EqualError(t, p.Do(context.Background(), failing), "nope")
+	require.ErrorIs(t, p.Do(context.Background(), failing), ErrBudgetExhausted)
+
+	// The budget is reset with each window.
+	p.budget.mut.Lock()
+	p.budget.windowStart = time.Now().Add(-time.Hour)
+	p.budget.mut.Unlock()
+	require.EqualError(t, p.Do(context.Background(), failing), "nope")
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	for _, fn := range []func(c *Config){
+		func(c *Config) { c.MaxAttempts = -1 },
+		func(c *Config) { c.MaxInterval = 0 },
+		func(c *Config) { c.BudgetRatio = -1 },
+		func(c *Config) { c.BudgetRatio = 0.1; c.BudgetWindow = 0 },
+	} {
+		conf := NewConfig()
+		fn(&conf)
+		_, err := NewPolicy(conf)
+		assert.Error(t, err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	d, ok := ParseRetryAfter("120")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute*2, d)
+
+	d, ok = ParseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, d, float64(time.Second*2))
+
+	d, ok = ParseRetryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), d)
+
+	for _, v := range []string{"", "-1", "soon"} {
+		_, ok = ParseRetryAfter(v)
+		assert.False(t, ok, v)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/gorilla/mux"
 	"gopkg.in/yaml.v3"
 
+	"github.com/benthosdev/benthos/v4/internal/audit"
 	"github.com/benthosdev/benthos/v4/internal/component/cache"
 	"github.com/benthosdev/benthos/v4/internal/component/input"
 	"github.com/benthosdev/benthos/v4/internal/component/output"
@@ -65,6 +66,20 @@ type lintErrors struct {
 	LintErrs []string `json:"lint_errors"`
 }
 
+// audit records a change made through the API as an event, attributed to the
+// principal of the request.
+func (m *Type) audit(r *http.Request, kind audit.Kind, id string, confBytes []byte, err error) {
+	e := audit.Event{
+		Kind:   kind,
+		Source: id,
+		Error:  audit.ErrorString(err),
+	}
+	if confBytes != nil {
+		e.ConfigHash = audit.Hash(confBytes)
+	}
+	audit.Record(r.Context(), e)
+}
+
 func (m *Type) lintCtx() docs.LintContext {
 	lConf := docs.NewLintConfig(m.manager.Environment())
 	lConf.BloblangEnv = bloblang.XWrapEnvironment(m.manager.BloblEnvironment()).Deactivated()
@@ -212,6 +227,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 	for i, id := range toDelete {
 		go func(sid string, j int) {
 			errDelete[j] = m.Delete(r.Context(), sid)
+			m.audit(r, audit.KindStreamDelete, sid, nil, errDelete[j])
 			wg.Done()
 		}(id, i)
 	}
@@ -220,6 +236,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
 			errUpdate[j] = m.Update(r.Context(), sid, *sconf)
+			m.audit(r, audit.KindStreamUpdate, sid, setBytes, errUpdate[j])
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -229,6 +246,7 @@ func (m *Type) HandleStreamsCRUD(w http.ResponseWriter, r *http.Request) {
 		newConf := conf
 		go func(sid string, sconf *stream.Config, j int) {
 			errCreate[j] = m.Create(sid, *sconf)
+			m.audit(r, audit.KindStreamCreate, sid, setBytes, errCreate[j])
 			wg.Done()
 		}(id, &newConf, i)
 		i++
@@ -284,15 +302,20 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The body of the request prior to interpolations, which is hashed within
+	// audit events.
+	var rawConf []byte
+
 	readConfig := func() (confOut stream.Config, lints []string, err error) {
 		var confBytes []byte
 		if confBytes, err = io.ReadAll(r.Body); err != nil {
 			return
 		}
+		rawConf = confBytes
 
 		ignoreLints := r.URL.Query().Get("chilled") == "true"
 
-		if confBytes, err = config.ReplaceEnvVariables(confBytes, os.LookupEnv); err != nil {
+		if confBytes, err = config.ReplaceEnvVariables(confBytes, audit.WrapLookupEnv(r.Context(), id, os.LookupEnv)); err != nil {
 			var errEnvMissing *config.ErrMissingEnvVars
 			if ignoreLints && errors.As(err, &errEnvMissing) {
 				confBytes = errEnvMissing.BestAttempt
@@ -328,6 +351,7 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 		if patchBytes, err = io.ReadAll(r.Body); err != nil {
 			return
 		}
+		rawConf = patchBytes
 
 		cRoot := value.IClone(confIn.GetRawSource())
 
@@ -373,6 +397,7 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		serverErr = m.Create(id, conf)
+		m.audit(r, audit.KindStreamCreate, id, rawConf, serverErr)
 	case "GET":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
@@ -411,8 +436,10 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		serverErr = m.Update(r.Context(), id, conf)
+		m.audit(r, audit.KindStreamUpdate, id, rawConf, serverErr)
 	case "DELETE":
 		serverErr = m.Delete(r.Context(), id)
+		m.audit(r, audit.KindStreamDelete, id, nil, serverErr)
 	case "PATCH":
 		var info *StreamStatus
 		if info, serverErr = m.Read(id); serverErr == nil {
@@ -420,6 +447,7 @@ func (m *Type) HandleStreamCRUD(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			serverErr = m.Update(r.Context(), id, conf)
+			m.audit(r, audit.KindStreamUpdate, id, rawConf, serverErr)
 		}
 	default:
 		requestErr = fmt.Errorf("verb not supported: %v", r.Method)
@@ -520,16 +548,17 @@ func (m *Type) HandleResourceCRUD(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var confNode *yaml.Node
+	var rawConf []byte
 	var lints []string
 	{
-		var confBytes []byte
-		if confBytes, requestErr = io.ReadAll(r.Body); requestErr != nil {
+		if rawConf, requestErr = io.ReadAll(r.Body); requestErr != nil {
 			return
 		}
 
 		ignoreLints := r.URL.Query().Get("chilled") == "true"
 
-		if confBytes, requestErr = config.ReplaceEnvVariables(confBytes, os.LookupEnv); requestErr != nil {
+		var confBytes []byte
+		if confBytes, requestErr = config.ReplaceEnvVariables(rawConf, audit.WrapLookupEnv(ctx, string(docType)+":"+id, os.LookupEnv)); requestErr != nil {
 			var errEnvMissing *config.ErrMissingEnvVars
 			if ignoreLints && errors.As(requestErr, &errEnvMissing) {
 				confBytes = errEnvMissing.BestAttempt
@@ -563,6 +592,9 @@ func (m *Type) HandleResourceCRUD(w http.ResponseWriter, r *http.Request) {
 	}
 
 	storeFn(confNode)
+	if requestErr == nil {
+		m.audit(r, audit.KindResourceUpdate, string(docType)+":"+id, rawConf, serverErr)
+	}
 }
 
 // HandleStreamStats is an http.HandleFunc for obtaining metrics for a stream.
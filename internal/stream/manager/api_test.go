@@ -20,6 +20,7 @@ import (
 	"github.com/stretchr/testify/require"
 	yaml "gopkg.in/yaml.v3"
 
+	"github.com/benthosdev/benthos/v4/internal/audit"
 	"github.com/benthosdev/benthos/v4/internal/bundle"
 	"github.com/benthosdev/benthos/v4/internal/component/testutil"
 	"github.com/benthosdev/benthos/v4/internal/config"
@@ -1011,3 +1012,50 @@ output:
 		return response.Code == http.StatusServiceUnavailable
 	}, time.Second*10, time.Millisecond*50)
 }
+
+func TestTypeAPIAuditEvents(t *testing.T) {
+	var buf bytes.Buffer
+	audit.SetSink(audit.NewWriterSink(&buf), nil)
+	t.Cleanup(func() {
+		audit.SetSink(nil, nil)
+	})
+
+	res, err := bmanager.New(bmanager.NewResourceConfig())
+	require.NoError(t, err)
+
+	mgr := manager.New(res)
+	r := router(mgr)
+
+	confBytes, err := json.Marshal(harmlessConf())
+	require.NoError(t, err)
+
+	request := genRequest("POST", "/streams/foo", string(confBytes))
+	request = request.WithContext(audit.ContextWithPrincipal(request.Context(), "alice"))
+	response := httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	request = genRequest("DELETE", "/streams/foo", nil)
+	request = request.WithContext(audit.ContextWithPrincipal(request.Context(), "bob"))
+	response = httptest.NewRecorder()
+	r.ServeHTTP(response, request)
+	require.Equal(t, http.StatusOK, response.Code, response.Body.String())
+
+	var events []audit.Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e audit.Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		events = append(events, e)
+	}
+	require.Len(t, events, 2)
+
+	assert.Equal(t, audit.KindStreamCreate, events[0].Kind)
+	assert.Equal(t, "alice", events[0].Principal)
+	assert.Equal(t, "foo", events[0].Source)
+	assert.Equal(t, audit.Hash(confBytes), events[0].ConfigHash)
+	assert.Empty(t, events[0].Error)
+
+	assert.Equal(t, audit.KindStreamDelete, events[1].Kind)
+	assert.Equal(t, "bob", events[1].Principal)
+	assert.Empty(t, events[1].ConfigHash)
+}
@@ -1,6 +1,19 @@
 package tls
 
-import "github.com/benthosdev/benthos/v4/internal/docs"
+import (
+	"fmt"
+
+	"github.com/benthosdev/benthos/v4/internal/docs"
+)
+
+func lintSkipCertVerify(ctx docs.LintContext, line, col int, value any) []docs.Lint {
+	if b, _ := value.(bool); b {
+		if p := CurrentPolicy(); !p.AllowSkipVerify {
+			return []docs.Lint{docs.NewLintError(line, docs.LintCustom, fmt.Errorf("skipping certificate verification is not permitted by the %v tls policy", p.Name))}
+		}
+	}
+	return nil
+}
 
 // FieldSpec returns a spec for a common TLS field.
 func FieldSpec() docs.FieldSpec {
@@ -13,7 +26,7 @@ func FieldSpec() docs.FieldSpec {
 
 		docs.FieldBool(
 			"skip_cert_verify", "Whether to skip server side certificate verification.",
-		).HasDefault(false),
+		).HasDefault(false).LinterFunc(lintSkipCertVerify),
 
 		docs.FieldBool(
 			"enable_renegotiation", "Whether to allow the remote server to repeatedly request renegotiation. Enable this option if you're seeing the error message `local error: tls: no renegotiation`.",
@@ -0,0 +1,180 @@
+package tls
+
+import (
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Policy describes the TLS versions, cipher suites and key exchange curves that
+// connections made or accepted by components are permitted to use.
+type Policy struct {
+	Name string
+
+	MinVersion uint16
+	MaxVersion uint16
+
+	// CipherSuites restricts the cipher suites of TLS 1.2 connections, when nil
+	// the crypto/tls defaults are used.
+	CipherSuites []uint16
+
+	// CurvePreferences restricts the key exchange curves, when nil the
+	// crypto/tls defaults are used.
+	CurvePreferences []tls.CurveID
+
+	AllowSkipVerify bool
+}
+
+// PolicyDefault is the policy used unless otherwise configured, which leaves
+// the crypto/tls defaults in place.
+var PolicyDefault = Policy{
+	Name:            "default",
+	MinVersion:      tls.VersionTLS12,
+	AllowSkipVerify: true,
+}
+
+// PolicyFIPS restricts connections to the cipher suites and curves approved by
+// FIPS 140-2 and NIST SP 800-52.
+//
+// The cipher suites of TLS 1.3 cannot be restricted with crypto/tls, and
+// therefore only the TLS 1.2 suites are listed here. TLS 1.3 remains enabled as
+// it is required by NIST SP 800-52r2, and the restriction of its suites to
+// AES-GCM is left to the FIPS mode of the Go runtime.
+//
+// The policy only applies to connections configured via this package, the
+// components that bring their own TLS stacks are listed in the docs of the
+// HTTP server.
+var PolicyFIPS = Policy{
+	Name:       "fips",
+	MinVersion: tls.VersionTLS12,
+	CipherSuites: []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	},
+	CurvePreferences: []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521},
+	AllowSkipVerify:  false,
+}
+
+var policies = map[string]Policy{
+	PolicyDefault.Name: PolicyDefault,
+	PolicyFIPS.Name:    PolicyFIPS,
+}
+
+// PolicyNames returns the names of all policies.
+func PolicyNames() []string {
+	names := make([]string, 0, len(policies))
+	for k := range policies {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var (
+	currentPolicy    = defaultPolicy
+	currentPolicyMut sync.RWMutex
+)
+
+// SetPolicy sets the policy enforced on all TLS configs created from this
+// point onwards, identified by its name.
+func SetPolicy(name string) error {
+	p, exists := policies[name]
+	if !exists {
+		return fmt.Errorf("tls policy '%v' was not recognised, expected one of %v", name, PolicyNames())
+	}
+	currentPolicyMut.Lock()
+	currentPolicy = p
+	currentPolicyMut.Unlock()
+	return nil
+}
+
+// CurrentPolicy returns the policy that is currently enforced.
+func CurrentPolicy() Policy {
+	currentPolicyMut.RLock()
+	defer currentPolicyMut.RUnlock()
+	return currentPolicy
+}
+
+// Apply enforces the policy on a TLS config, restricting its versions, cipher
+// suites and curves. An error is returned if the config has settings that are
+// not permitted by the policy.
+func (p Policy) Apply(conf *tls.Config) error {
+	if conf.InsecureSkipVerify && !p.AllowSkipVerify {
+		return fmt.Errorf("skipping certificate verification is not permitted by the %v tls policy", p.Name)
+	}
+	if conf.MinVersion < p.MinVersion {
+		conf.MinVersion = p.MinVersion
+	}
+	if p.MaxVersion != 0 && (conf.MaxVersion == 0 || conf.MaxVersion > p.MaxVersion) {
+		conf.MaxVersion = p.MaxVersion
+	}
+	if p.CipherSuites != nil {
+		conf.CipherSuites = intersect(conf.CipherSuites, p.CipherSuites)
+	}
+	if p.CurvePreferences != nil {
+		conf.CurvePreferences = intersect(conf.CurvePreferences, p.CurvePreferences)
+	}
+	return nil
+}
+
+// intersect returns the values of a that are permitted, or all permitted
+// values when a is empty.
+func intersect[T comparable](a, permitted []T) []T {
+	if len(a) == 0 {
+		return append([]T(nil), permitted...)
+	}
+	var res []T
+	for _, v := range a {
+		for _, p := range permitted {
+			if v == p {
+				res = append(res, v)
+				break
+			}
+		}
+	}
+	return res
+}
+
+// ApplyPolicy enforces the current policy on a TLS config.
+func ApplyPolicy(conf *tls.Config) error {
+	return CurrentPolicy().Apply(conf)
+}
+
+// PolicyConfig returns a new TLS config that conforms to the current policy,
+// which is useful for servers that load their certificates separately.
+func PolicyConfig() *tls.Config {
+	conf := defaultTLSConfig()
+	_ = ApplyPolicy(conf)
+	return conf
+}
+
+// Describe returns a structured summary of the policy that is suitable for
+// reporting.
+func (p Policy) Describe() map[string]any {
+	versionName := func(v uint16) any {
+		if v == 0 {
+			return nil
+		}
+		return tls.VersionName(v)
+	}
+
+	var suites []any
+	for _, s := range p.CipherSuites {
+		suites = append(suites, tls.CipherSuiteName(s))
+	}
+	var curves []any
+	for _, c := range p.CurvePreferences {
+		curves = append(curves, c.String())
+	}
+	return map[string]any{
+		"name":              p.Name,
+		"min_version":       versionName(p.MinVersion),
+		"max_version":       versionName(p.MaxVersion),
+		"cipher_suites":     suites,
+		"curve_preferences": curves,
+		"allow_skip_verify": p.AllowSkipVerify,
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !fips
+
+package tls
+
+var defaultPolicy = PolicyDefault
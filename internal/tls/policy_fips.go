@@ -0,0 +1,6 @@
+//go:build fips
+
+package tls
+
+// Builds with the fips tag enforce the FIPS policy unless configured otherwise.
+var defaultPolicy = PolicyFIPS
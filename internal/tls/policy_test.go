@@ -0,0 +1,85 @@
+package tls
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/benthosdev/benthos/v4/internal/docs"
+	"github.com/benthosdev/benthos/v4/internal/filepath/ifs"
+)
+
+func setTestPolicy(t testing.TB, name string) {
+	t.Helper()
+
+	prev := CurrentPolicy()
+	require.NoError(t, SetPolicy(name))
+	t.Cleanup(func() {
+		require.NoError(t, SetPolicy(prev.Name))
+	})
+}
+
+func TestPolicyFIPSApply(t *testing.T) {
+	conf := &tls.Config{
+		MinVersion: tls.VersionTLS10,
+		CipherSuites: []uint16{
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+		},
+	}
+	require.NoError(t, PolicyFIPS.Apply(conf))
+
+	assert.Equal(t, uint16(tls.VersionTLS12), conf.MinVersion)
+	assert.Equal(t, uint16(0), conf.MaxVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, conf.CipherSuites)
+	assert.Equal(t, []tls.CurveID{tls.CurveP256, tls.CurveP384, tls.CurveP521}, conf.CurvePreferences)
+
+	err := PolicyFIPS.Apply(&tls.Config{InsecureSkipVerify: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not permitted by the fips tls policy")
+
+	assert.NoError(t, PolicyDefault.Apply(&tls.Config{InsecureSkipVerify: true}))
+}
+
+func TestPolicyConfigGet(t *testing.T) {
+	setTestPolicy(t, "fips")
+
+	conf := NewConfig()
+	conf.Enabled = true
+
+	tConf, err := conf.Get(ifs.OS())
+	require.NoError(t, err)
+	assert.Equal(t, PolicyFIPS.CipherSuites, tConf.CipherSuites)
+	assert.Equal(t, uint16(0), tConf.MaxVersion)
+
+	conf.InsecureSkipVerify = true
+	_, err = conf.Get(ifs.OS())
+	require.Error(t, err)
+}
+
+func TestPolicyLintSkipCertVerify(t *testing.T) {
+	setTestPolicy(t, "default")
+	assert.Empty(t, lintSkipCertVerify(docs.LintContext{}, 0, 0, true))
+
+	setTestPolicy(t, "fips")
+	assert.Empty(t, lintSkipCertVerify(docs.LintContext{}, 0, 0, false))
+	assert.Len(t, lintSkipCertVerify(docs.LintContext{}, 0, 0, true), 1)
+}
+
+func TestSetPolicyUnknown(t *testing.T) {
+	err := SetPolicy("nope")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected one of [default fips]")
+}
+
+func TestPolicyDescribe(t *testing.T) {
+	desc := PolicyFIPS.Describe()
+	assert.Equal(t, "fips", desc["name"])
+	assert.Equal(t, "TLS 1.2", desc["min_version"])
+	assert.Nil(t, desc["max_version"])
+	assert.Equal(t, []any{"CurveP256", "CurveP384", "CurveP521"}, desc["curve_preferences"])
+	assert.Len(t, desc["cipher_suites"], 4)
+}
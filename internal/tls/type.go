@@ -103,6 +103,11 @@ func (c *Config) GetNonToggled(f ifs.FS) (*tls.Config, error) {
 		tlsConf.InsecureSkipVerify = true
 	}
 
+	if tlsConf != nil {
+		if err := ApplyPolicy(tlsConf); err != nil {
+			return nil, err
+		}
+	}
 	return tlsConf, nil
 }
 
@@ -117,7 +122,7 @@ func (c *Config) Get(f ifs.FS) (*tls.Config, error) {
 		return nil, err
 	}
 	if tConf == nil {
-		tConf = defaultTLSConfig()
+		tConf = PolicyConfig()
 	}
 	return tConf, nil
 }
@@ -21,6 +21,12 @@ type ResultStoreKeyType int
 // message context.
 const ResultStoreKey ResultStoreKeyType = iota
 
+// resultIndexKeyType is the type of the context key that holds the index of a
+// message within the batch that a ResultStore was added to.
+type resultIndexKeyType int
+
+const resultIndexKey resultIndexKeyType = iota
+
 // ResultStore is a type designed to be propagated along with a message as a way
 // for an output destination to store the final version of the message payload
 // as it saw it.
@@ -33,39 +39,87 @@ type ResultStore interface {
 	// ownership of the message is about to be yielded.
 	Add(msg message.Batch)
 
-	// Get the stored slice of messages.
+	// AddMetadata adds result metadata, which describes the delivery of a
+	// message to an output, to the stored messages that originated from the
+	// message at the given index of the batch that the store was added to.
+	AddMetadata(index int, key string, value any)
+
+	// Get the stored slice of messages, with any result metadata added to each
+	// message.
 	Get() []message.Batch
 
-	// Clear any currently stored messages.
+	// Clear any currently stored messages and result metadata.
 	Clear()
 }
 
 //------------------------------------------------------------------------------
 
+type resultMetadata struct {
+	index int
+	key   string
+	value any
+}
+
 type resultStoreImpl struct {
 	payloads []message.Batch
+	indexes  [][]int
+	metadata []resultMetadata
+	onAdd    func(message.Batch)
 	sync.RWMutex
 }
 
 func (r *resultStoreImpl) Add(msg message.Batch) {
 	newBatch := make(message.Batch, len(msg))
+	indexes := make([]int, len(msg))
 	for i, p := range msg {
+		indexes[i] = resultIndex(p)
 		newBatch[i] = message.WithContext(context.Background(), p.DeepCopy())
 	}
 	r.Lock()
 	r.payloads = append(r.payloads, newBatch)
+	r.indexes = append(r.indexes, indexes)
+	r.Unlock()
+	if r.onAdd != nil {
+		r.onAdd(newBatch)
+	}
+}
+
+func (r *resultStoreImpl) AddMetadata(index int, key string, value any) {
+	r.Lock()
+	r.metadata = append(r.metadata, resultMetadata{index: index, key: key, value: value})
 	r.Unlock()
 }
 
 func (r *resultStoreImpl) Get() []message.Batch {
 	r.RLock()
 	defer r.RUnlock()
-	return r.payloads
+	if len(r.metadata) == 0 {
+		return r.payloads
+	}
+
+	// Result metadata may be added after a message was stored, and is
+	// therefore applied to copies of the messages as they're read.
+	payloads := make([]message.Batch, len(r.payloads))
+	for i, b := range r.payloads {
+		payloads[i] = make(message.Batch, len(b))
+		for j, p := range b {
+			p = p.ShallowCopy()
+			for _, m := range r.metadata {
+				if m.index == r.indexes[i][j] {
+					p.MetaSetMut(m.key, m.value)
+				}
+			}
+			payloads[i][j] = p
+		}
+	}
+	return payloads
 }
 
 func (r *resultStoreImpl) Clear() {
 	r.Lock()
 	r.payloads = nil
+	r.indexes = nil
+	r.metadata = nil
 	r.Unlock()
 }
 
@@ -76,6 +130,14 @@ func NewResultStore() ResultStore {
 	return &resultStoreImpl{}
 }
 
+// NewResultStoreWithCallback returns an implementation of ResultStore that
+// also calls fn with each batch as it is added, which allows results to be
+// consumed as they arrive rather than once a transaction has completed. The
+// callback must not block.
+func NewResultStoreWithCallback(fn func(msg message.Batch)) ResultStore {
+	return &resultStoreImpl{onAdd: fn}
+}
+
 //------------------------------------------------------------------------------
 
 // AddResultStore sets a result store within the context of the provided message
@@ -83,9 +145,18 @@ func NewResultStore() ResultStore {
 // resulting message back to the origin.
 func AddResultStore(msg message.Batch, store ResultStore) {
 	for i, p := range msg {
-		ctx := message.GetContext(p)
-		msg[i] = message.WithContext(context.WithValue(ctx, ResultStoreKey, store), p)
+		ctx := context.WithValue(message.GetContext(p), ResultStoreKey, store)
+		msg[i] = message.WithContext(context.WithValue(ctx, resultIndexKey, i), p)
+	}
+}
+
+// resultIndex returns the index of the message that a part originated from
+// within the batch that a result store was added to, or -1 if unknown.
+func resultIndex(p *message.Part) int {
+	if i, ok := message.GetContext(p).Value(resultIndexKey).(int); ok {
+		return i
 	}
+	return -1
 }
 
 // SetAsResponse takes a mutated message and stores it as a response message,
@@ -100,3 +171,19 @@ func SetAsResponse(msg message.Batch) error {
 	store.Add(msg)
 	return nil
 }
+
+// SetResultMetadata sets metadata on a message that describes the result of
+// delivering it to an output, such as where it was written to. The metadata is
+// also added to the result store within the context of the message, if any, so
+// that it is included in the synchronous response of the message that it
+// originated from.
+func SetResultMetadata(p *message.Part, key string, value any) {
+	p.MetaSetMut(key, value)
+	store, ok := message.GetContext(p).Value(ResultStoreKey).(ResultStore)
+	if !ok {
+		return
+	}
+	if index := resultIndex(p); index >= 0 {
+		store.AddMetadata(index, key, value)
+	}
+}
@@ -2,6 +2,7 @@ package transaction
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/benthosdev/benthos/v4/internal/message"
@@ -38,3 +39,99 @@ func TestResultStore(t *testing.T) {
 		t.Errorf("Unexpected count of stored messages: %v != %v", act, exp)
 	}
 }
+
+func TestResultStoreMetadata(t *testing.T) {
+	impl := &resultStoreImpl{}
+
+	batch := message.QuickBatch([][]byte{[]byte("foo")})
+	AddResultStore(batch, impl)
+
+	part := batch.Get(0)
+	part.MetaSetMut("a", "from input")
+
+	impl.Add(message.Batch{part})
+	SetResultMetadata(part, "a", "from output")
+	SetResultMetadata(part, "b", 10)
+
+	if v, _ := part.MetaGetMut("b"); v != 10 {
+		t.Errorf("Wrong metadata value on delivered message: %v", v)
+	}
+
+	results := impl.Get()
+	if len(results) != 1 || results[0].Len() != 1 {
+		t.Fatalf("Wrong count of results: %v", results)
+	}
+	if exp, act := "from output", results[0].Get(0).MetaGetStr("a"); exp != act {
+		t.Errorf("Wrong metadata value: %v != %v", act, exp)
+	}
+	if v, _ := results[0].Get(0).MetaGetMut("b"); v != 10 {
+		t.Errorf("Wrong metadata value: %v", v)
+	}
+
+	// Messages without a store are still given the metadata.
+	part = message.NewPart([]byte("bar"))
+	SetResultMetadata(part, "c", "baz")
+	if exp, act := "baz", part.MetaGetStr("c"); exp != act {
+		t.Errorf("Wrong metadata value: %v != %v", act, exp)
+	}
+}
+
+func TestResultStoreMetadataBatch(t *testing.T) {
+	impl := &resultStoreImpl{}
+
+	batch := message.QuickBatch([][]byte{[]byte("foo"), []byte("bar"), []byte("baz")})
+	AddResultStore(batch, impl)
+
+	// The response is a copy of the batch in a different order, where each
+	// message should only be given the metadata of the message it originated
+	// from.
+	impl.Add(message.Batch{batch.Get(2), batch.Get(0), batch.Get(1)})
+	for i, p := range batch {
+		SetResultMetadata(p.ShallowCopy(), "offset", i)
+	}
+	SetResultMetadata(batch.Get(1), "key", "bar.json")
+
+	results := impl.Get()
+	if len(results) != 1 || results[0].Len() != 3 {
+		t.Fatalf("Wrong count of results: %v", results)
+	}
+	for i, exp := range []struct {
+		content string
+		offset  int
+		key     string
+	}{
+		{content: "baz", offset: 2},
+		{content: "foo", offset: 0},
+		{content: "bar", offset: 1, key: "bar.json"},
+	} {
+		p := results[0].Get(i)
+		if act := string(p.AsBytes()); act != exp.content {
+			t.Errorf("Wrong content of result %v: %v != %v", i, act, exp.content)
+		}
+		if v, _ := p.MetaGetMut("offset"); v != exp.offset {
+			t.Errorf("Wrong offset of result %v: %v != %v", i, v, exp.offset)
+		}
+		if act := p.MetaGetStr("key"); act != exp.key {
+			t.Errorf("Wrong key of result %v: %v != %v", i, act, exp.key)
+		}
+	}
+}
+
+func TestResultStoreWithCallback(t *testing.T) {
+	var added []string
+	store := NewResultStoreWithCallback(func(msg message.Batch) {
+		for _, p := range msg {
+			added = append(added, string(p.AsBytes()))
+		}
+	})
+
+	store.Add(message.QuickBatch([][]byte{[]byte("foo")}))
+	store.Add(message.QuickBatch([][]byte{[]byte("bar"), []byte("baz")}))
+
+	if exp, act := "foo,bar,baz", strings.Join(added, ","); exp != act {
+		t.Errorf("Wrong added messages: %v != %v", act, exp)
+	}
+	if exp, act := 2, len(store.Get()); exp != act {
+		t.Errorf("Wrong count of result batches: %v != %v", act, exp)
+	}
+}
@@ -13,42 +13,67 @@ import (
 	_ "github.com/benthosdev/benthos/v4/public/components/beanstalkd"
 	_ "github.com/benthosdev/benthos/v4/public/components/cassandra"
 	_ "github.com/benthosdev/benthos/v4/public/components/changelog"
+	_ "github.com/benthosdev/benthos/v4/public/components/coap"
 	_ "github.com/benthosdev/benthos/v4/public/components/cockroachdb"
 	_ "github.com/benthosdev/benthos/v4/public/components/confluent"
 	_ "github.com/benthosdev/benthos/v4/public/components/couchbase"
+	_ "github.com/benthosdev/benthos/v4/public/components/couchdb"
 	_ "github.com/benthosdev/benthos/v4/public/components/crypto"
 	_ "github.com/benthosdev/benthos/v4/public/components/dgraph"
 	_ "github.com/benthosdev/benthos/v4/public/components/discord"
 	_ "github.com/benthosdev/benthos/v4/public/components/elasticsearch"
 	_ "github.com/benthosdev/benthos/v4/public/components/gcp"
+	_ "github.com/benthosdev/benthos/v4/public/components/gearman"
+	_ "github.com/benthosdev/benthos/v4/public/components/github"
+	_ "github.com/benthosdev/benthos/v4/public/components/gitlab"
+	_ "github.com/benthosdev/benthos/v4/public/components/grpc"
 	_ "github.com/benthosdev/benthos/v4/public/components/hdfs"
 	_ "github.com/benthosdev/benthos/v4/public/components/influxdb"
 	_ "github.com/benthosdev/benthos/v4/public/components/io"
 	_ "github.com/benthosdev/benthos/v4/public/components/jaeger"
 	_ "github.com/benthosdev/benthos/v4/public/components/javascript"
+	_ "github.com/benthosdev/benthos/v4/public/components/jira"
 	_ "github.com/benthosdev/benthos/v4/public/components/kafka"
+	_ "github.com/benthosdev/benthos/v4/public/components/ldap"
 	_ "github.com/benthosdev/benthos/v4/public/components/maxmind"
 	_ "github.com/benthosdev/benthos/v4/public/components/memcached"
+	_ "github.com/benthosdev/benthos/v4/public/components/modbus"
 	_ "github.com/benthosdev/benthos/v4/public/components/mongodb"
 	_ "github.com/benthosdev/benthos/v4/public/components/mqtt"
 	_ "github.com/benthosdev/benthos/v4/public/components/msgpack"
 	_ "github.com/benthosdev/benthos/v4/public/components/nanomsg"
 	_ "github.com/benthosdev/benthos/v4/public/components/nats"
+	_ "github.com/benthosdev/benthos/v4/public/components/netlookup"
 	_ "github.com/benthosdev/benthos/v4/public/components/nsq"
+	_ "github.com/benthosdev/benthos/v4/public/components/opcua"
 	_ "github.com/benthosdev/benthos/v4/public/components/opensearch"
+	_ "github.com/benthosdev/benthos/v4/public/components/opsgenie"
 	_ "github.com/benthosdev/benthos/v4/public/components/otlp"
+	_ "github.com/benthosdev/benthos/v4/public/components/pagerduty"
+	_ "github.com/benthosdev/benthos/v4/public/components/pcap"
+	_ "github.com/benthosdev/benthos/v4/public/components/postgresql"
 	_ "github.com/benthosdev/benthos/v4/public/components/prometheus"
 	_ "github.com/benthosdev/benthos/v4/public/components/pulsar"
 	_ "github.com/benthosdev/benthos/v4/public/components/pure"
 	_ "github.com/benthosdev/benthos/v4/public/components/pure/extended"
 	_ "github.com/benthosdev/benthos/v4/public/components/pusher"
+	_ "github.com/benthosdev/benthos/v4/public/components/rabbitmq"
 	_ "github.com/benthosdev/benthos/v4/public/components/redis"
+	_ "github.com/benthosdev/benthos/v4/public/components/rethinkdb"
+	_ "github.com/benthosdev/benthos/v4/public/components/salesforce"
 	_ "github.com/benthosdev/benthos/v4/public/components/sentry"
+	_ "github.com/benthosdev/benthos/v4/public/components/serial"
+	_ "github.com/benthosdev/benthos/v4/public/components/servicenow"
 	_ "github.com/benthosdev/benthos/v4/public/components/sftp"
+	_ "github.com/benthosdev/benthos/v4/public/components/slack"
+	_ "github.com/benthosdev/benthos/v4/public/components/snmp"
 	_ "github.com/benthosdev/benthos/v4/public/components/snowflake"
 	_ "github.com/benthosdev/benthos/v4/public/components/splunk"
 	_ "github.com/benthosdev/benthos/v4/public/components/sql"
 	_ "github.com/benthosdev/benthos/v4/public/components/statsd"
+	_ "github.com/benthosdev/benthos/v4/public/components/teams"
+	_ "github.com/benthosdev/benthos/v4/public/components/temporal"
+	_ "github.com/benthosdev/benthos/v4/public/components/twilio"
 	_ "github.com/benthosdev/benthos/v4/public/components/twitter"
 	_ "github.com/benthosdev/benthos/v4/public/components/wasm"
 )
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/benthosdev/benthos/v4/internal/retries"
+)
+
+// ErrRetryBudgetExhausted is returned by RetryPolicy.Do when a call is not
+// retried because the retry budget of the policy has been exhausted.
+var ErrRetryBudgetExhausted = retries.ErrBudgetExhausted
+
+// NewRetryPolicyField defines a new object type config field that describes a
+// retry policy, consisting of a maximum number of attempts, an exponential
+// back off with full jitter and an optional retry budget that is shared by all
+// calls made with the policy. It is then possible to extract a *RetryPolicy
+// from the resulting parsed config with the method FieldRetryPolicy.
+func NewRetryPolicyField(name string) *ConfigField {
+	def := retries.NewConfig()
+	return NewObjectField(name,
+		NewIntField("max_attempts").
+			Description("The maximum number of attempts of each request, including the first. Setting this value to `0` results in unlimited attempts.").
+			Default(def.MaxAttempts),
+		NewDurationField("initial_interval").
+			Description("The initial upper bound of the period to wait between attempts, which doubles with each attempt. The period waited is chosen at random between zero and this bound (full jitter).").
+			Default(def.InitialInterval.String()).Example("50ms").Example("1s"),
+		NewDurationField("max_interval").
+			Description("The maximum upper bound of the period to wait between attempts.").
+			Default(def.MaxInterval.String()).Example("5s").Example("1m"),
+		NewDurationField("max_elapsed_time").
+			Description("The maximum overall period of time to spend on attempts of a request before it is abandoned. Setting this value to a zeroed duration (such as `0s`) removes the limit.").
+			Default(def.MaxElapsedTime.String()).Example("1m").Example("1h"),
+		NewObjectField("budget",
+			NewFloatField("ratio").
+				Description("The maximum ratio of retries to requests within each window, where `0` disables the budget. For example, a ratio of `0.2` allows at most one retry for every five requests.").
+				Default(def.BudgetRatio).Example(0.2),
+			NewIntField("min_retries").
+				Description("A number of retries allowed within each window regardless of the ratio, which prevents the budget from blocking retries when there are few requests.").
+				Default(def.BudgetMinRetries),
+			NewDurationField("window").
+				Description("The period of time over which requests and retries are counted.").
+				Default(def.BudgetWindow.String()),
+		).
+			Description("A retry budget shared by all requests of the component, which prevents retries from amplifying the load on a service that is already failing.").
+			Advanced(),
+	).Description("Determine the number of attempts, the time intervals between them and cut offs for retrying requests. When a service responds with a period to wait before retrying, such as with a `Retry-After` header, that period is waited instead.")
+}
+
+// RetryPolicy executes calls with retries as described by a config field
+// defined with NewRetryPolicyField. A policy is safe to use concurrently, and
+// its retry budget is shared by all calls made with it.
+type RetryPolicy struct {
+	p *retries.Policy
+}
+
+// FieldRetryPolicy accesses a field from a parsed config that was defined with
+// NewRetryPolicyField and returns a *RetryPolicy, or an error if the
+// configuration was invalid.
+func (p *ParsedConfig) FieldRetryPolicy(path ...string) (*RetryPolicy, error) {
+	conf := retries.NewConfig()
+
+	var err error
+	if conf.MaxAttempts, err = p.FieldInt(append(path, "max_attempts")...); err != nil {
+		return nil, err
+	}
+	if conf.InitialInterval, err = p.FieldDuration(append(path, "initial_interval")...); err != nil {
+		return nil, err
+	}
+	if conf.MaxInterval, err = p.FieldDuration(append(path, "max_interval")...); err != nil {
+		return nil, err
+	}
+	if conf.MaxElapsedTime, err = p.FieldDuration(append(path, "max_elapsed_time")...); err != nil {
+		return nil, err
+	}
+	if conf.BudgetRatio, err = p.FieldFloat(append(path, "budget", "ratio")...); err != nil {
+		return nil, err
+	}
+	if conf.BudgetMinRetries, err = p.FieldInt(append(path, "budget", "min_retries")...); err != nil {
+		return nil, err
+	}
+	if conf.BudgetWindow, err = p.FieldDuration(append(path, "budget", "window")...); err != nil {
+		return nil, err
+	}
+
+	rp, err := retries.NewPolicy(conf)
+	if err != nil {
+		return nil, err
+	}
+	return &RetryPolicy{p: rp}, nil
+}
+
+// Do executes a call until it succeeds or the policy gives up, returning the
+// last error encountered. Errors wrapped with NewErrBackOff are retried once
+// the specified period of time has elapsed, which is useful for respecting
+// periods given by services (see ParseRetryAfter), and errors wrapped with
+// NewErrRetryPermanent are returned without being retried.
+func (r *RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.p.Do(ctx, func(ctx context.Context) error {
+		err := fn(ctx)
+		var permErr *ErrRetryPermanent
+		if errors.As(err, &permErr) {
+			return retries.Permanent(permErr.Err)
+		}
+		var boErr *ErrBackOff
+		if errors.As(err, &boErr) {
+			return retries.After(boErr.Wait, boErr.Err)
+		}
+		return err
+	})
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date, into a period of time to wait. Returns
+// false when the value is empty or invalid.
+func ParseRetryAfter(value string) (time.Duration, bool) {
+	return retries.ParseRetryAfter(value)
+}
+
+// NewHTTPStatusError returns an error for an HTTP request that failed with a
+// status code, which is wrapped with NewErrRetryPermanent unless the status
+// code indicates a transient failure (408, 425, 429 and 5xx codes).
+func NewHTTPStatusError(code int, err error) error {
+	switch {
+	case code == http.StatusRequestTimeout, code == http.StatusTooEarly, code == http.StatusTooManyRequests, code >= 500:
+		return err
+	}
+	return NewErrRetryPermanent(err)
+}
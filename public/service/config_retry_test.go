@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyConfig(t *testing.T) {
+	spec := NewConfigSpec().Field(NewRetryPolicyField("a"))
+
+	parsed, err := spec.ParseYAML(`
+a:
+  max_attempts: 4
+  initial_interval: 1ms
+  max_interval: 2ms
+`, nil)
+	require.NoError(t, err)
+
+	rp, err := parsed.FieldRetryPolicy("a")
+	require.NoError(t, err)
+
+	var calls int
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return NewHTTPStatusError(http.StatusServiceUnavailable, errors.New("unavailable"))
+	})
+	require.EqualError(t, err, "unavailable")
+	assert.Equal(t, 4, calls)
+
+	calls = 0
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return NewHTTPStatusError(http.StatusBadRequest, errors.New("bad request"))
+	})
+	require.EqualError(t, err, "bad request")
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return fmt.Errorf("send failed: %w", NewErrRetryPermanent(errors.New("invalid key")))
+	})
+	require.EqualError(t, err, "invalid key")
+	assert.Equal(t, 1, calls)
+
+	var times []time.Time
+	err = rp.Do(context.Background(), func(ctx context.Context) error {
+		if times = append(times, time.Now()); len(times) == 1 {
+			return NewErrBackOff(errors.New("rate limited"), time.Millisecond*20)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, times, 2)
+	assert.GreaterOrEqual(t, times[1].Sub(times[0]), time.Millisecond*20)
+
+	parsed, err = spec.ParseYAML(`
+a:
+  initial_interval: 1s
+  max_interval: 1ms
+`, nil)
+	require.NoError(t, err)
+
+	_, err = parsed.FieldRetryPolicy("a")
+	require.Error(t, err)
+}
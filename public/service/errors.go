@@ -61,6 +61,28 @@ func (e *ErrBackOff) Error() string {
 	return e.Err.Error()
 }
 
+// ErrRetryPermanent is an error that plugins can wrap another error with in
+// order to instruct RetryPolicy.Do not to retry the errored call.
+type ErrRetryPermanent struct {
+	Err error
+}
+
+// NewErrRetryPermanent wraps an error in order to instruct RetryPolicy.Do to
+// return it without retrying the call.
+func NewErrRetryPermanent(err error) *ErrRetryPermanent {
+	return &ErrRetryPermanent{err}
+}
+
+// Error returns the Error string.
+func (e *ErrRetryPermanent) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (e *ErrRetryPermanent) Unwrap() error {
+	return e.Err
+}
+
 // BatchError groups the errors that were encountered while processing a
 // collection (usually a batch) of messages and provides methods to iterate
 // over these errors.
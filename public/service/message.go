@@ -323,6 +323,19 @@ func (m *Message) MetaSetMut(key string, value any) {
 	m.part.MetaSetMut(key, value)
 }
 
+// SetResultMetadata sets a metadata key on a message that describes the result
+// of delivering it, such as the location it was written to, and is intended to
+// be called by outputs once a message has been delivered successfully.
+//
+// The key is set as regular metadata of the message, making it available to
+// components that observe the message after delivery. When the message
+// originated from an input that supports synchronous responses the key is also
+// added to the messages of the response that originated from the same input
+// message.
+func (m *Message) SetResultMetadata(key string, value any) {
+	transaction.SetResultMetadata(m.part, key, value)
+}
+
 // MetaDelete removes a key from the message metadata.
 func (m *Message) MetaDelete(key string) {
 	m.part.MetaDelete(key)
@@ -9,6 +9,7 @@ import (
 
 	ibloblang "github.com/benthosdev/benthos/v4/internal/bloblang"
 	"github.com/benthosdev/benthos/v4/internal/message"
+	"github.com/benthosdev/benthos/v4/internal/transaction"
 	"github.com/benthosdev/benthos/v4/public/bloblang"
 )
 
@@ -302,6 +303,26 @@ func TestNewMessageMutate(t *testing.T) {
 	assert.Equal(t, map[string]any{"foo": "new bar", "bar": "baz"}, seen)
 }
 
+func TestMessageSetResultMetadata(t *testing.T) {
+	store := transaction.NewResultStore()
+	batch := message.Batch{message.NewPart([]byte("foo"))}
+	transaction.AddResultStore(batch, store)
+
+	msg := NewInternalMessage(batch[0])
+	require.NoError(t, MessageBatch{msg}.AddSyncResponse())
+
+	msg.SetResultMetadata("s3_key", "foo.json")
+
+	v, exists := msg.MetaGetMut("s3_key")
+	assert.True(t, exists)
+	assert.Equal(t, "foo.json", v)
+
+	responses := store.Get()
+	require.Len(t, responses, 1)
+	require.Len(t, responses[0], 1)
+	assert.Equal(t, "foo.json", responses[0][0].MetaGetStr("s3_key"))
+}
+
 func TestMessageMapping(t *testing.T) {
 	part := NewMessage(nil)
 	part.SetStructured(map[string]any{
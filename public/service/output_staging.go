@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// OutputStaging coordinates a two-phase commit of a batch of messages across
+// outputs. An output that writes messages carrying an OutputStaging within
+// their context (obtained with OutputStagingFromContext) should, where
+// possible, write them in a staged form that is invisible to consumers, such
+// as an open transaction or a temporary object, and register functions that
+// either commit or abort the staged write with Stage.
+//
+// The owner of the OutputStaging then calls Commit once all outputs have
+// staged their writes successfully, or Abort otherwise. Each registered stage
+// is committed or aborted exactly once, and stages registered after the
+// staging has been committed or aborted are aborted immediately.
+type OutputStaging struct {
+	mut    sync.Mutex
+	stages []outputStage
+	done   bool
+}
+
+type outputStage struct {
+	commit func(context.Context) error
+	abort  func(context.Context) error
+}
+
+// NewOutputStaging creates an empty OutputStaging.
+func NewOutputStaging() *OutputStaging {
+	return &OutputStaging{}
+}
+
+// Stage registers a staged write with a function that makes it visible to
+// consumers and a function that discards it.
+func (s *OutputStaging) Stage(commit, abort func(ctx context.Context) error) {
+	s.mut.Lock()
+	if !s.done {
+		s.stages = append(s.stages, outputStage{commit: commit, abort: abort})
+		s.mut.Unlock()
+		return
+	}
+	s.mut.Unlock()
+
+	// The outcome has already been decided without this stage, which most
+	// likely belongs to a write that was abandoned.
+	_ = abort(context.Background())
+}
+
+// Len returns the number of stages registered.
+func (s *OutputStaging) Len() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return len(s.stages)
+}
+
+func (s *OutputStaging) finish(ctx context.Context, commit bool) error {
+	s.mut.Lock()
+	stages := s.stages
+	s.stages, s.done = nil, true
+	s.mut.Unlock()
+
+	var errs []error
+	for _, stage := range stages {
+		fn := stage.abort
+		if commit {
+			fn = stage.commit
+		}
+		if err := fn(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Commit all registered stages in the order that they were registered. Every
+// stage is attempted even when others fail, and the errors of all failed
+// stages are returned.
+func (s *OutputStaging) Commit(ctx context.Context) error {
+	return s.finish(ctx, true)
+}
+
+// Abort all registered stages, returning the errors of any that failed.
+func (s *OutputStaging) Abort(ctx context.Context) error {
+	return s.finish(ctx, false)
+}
+
+type outputStagingKey struct{}
+
+// ContextWithOutputStaging returns a context carrying an OutputStaging, which
+// can be added to messages with Message.WithContext.
+func ContextWithOutputStaging(ctx context.Context, s *OutputStaging) context.Context {
+	return context.WithValue(ctx, outputStagingKey{}, s)
+}
+
+// OutputStagingFromContext returns the OutputStaging carried by a context,
+// usually that of a message being written, if any.
+func OutputStagingFromContext(ctx context.Context) (*OutputStaging, bool) {
+	s, ok := ctx.Value(outputStagingKey{}).(*OutputStaging)
+	return s, ok
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputStaging(t *testing.T) {
+	var events []string
+	stage := func(name string, commitErr error) (func(context.Context) error, func(context.Context) error) {
+		return func(context.Context) error {
+				events = append(events, "commit "+name)
+				return commitErr
+			}, func(context.Context) error {
+				events = append(events, "abort "+name)
+				return nil
+			}
+	}
+
+	s := NewOutputStaging()
+	s.Stage(stage("a", nil))
+	s.Stage(stage("b", errors.New("nope")))
+	s.Stage(stage("c", nil))
+	assert.Equal(t, 3, s.Len())
+
+	require.EqualError(t, s.Commit(context.Background()), "nope")
+	assert.Equal(t, []string{"commit a", "commit b", "commit c"}, events)
+	assert.Equal(t, 0, s.Len())
+
+	// Stages registered after the outcome was decided are aborted.
+	events = nil
+	s.Stage(stage("d", nil))
+	assert.Equal(t, []string{"abort d"}, events)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestOutputStagingContext(t *testing.T) {
+	_, ok := OutputStagingFromContext(context.Background())
+	assert.False(t, ok)
+
+	s := NewOutputStaging()
+	msg := NewMessage(nil).WithContext(ContextWithOutputStaging(context.Background(), s))
+
+	got, ok := OutputStagingFromContext(msg.Context())
+	require.True(t, ok)
+	assert.Same(t, s, got)
+}
@@ -233,6 +233,31 @@ func (r *Resources) HasRateLimit(name string) bool {
 	return r.mgr.ProbeRateLimit(name)
 }
 
+// GetGeneric attempts to obtain a generic value that was stored under a key by
+// a component sharing these resources. Keys should be of an unexported type in
+// order to avoid collisions between packages, similar to context values.
+func (r *Resources) GetGeneric(key any) (any, bool) {
+	return r.mgr.GetGeneric(key)
+}
+
+// GetOrSetGeneric returns the generic value stored under a key if one exists,
+// otherwise the provided value is stored and returned. The loaded result is
+// true if the value was already stored.
+//
+// Generic values are shared by all components of a Benthos instance, including
+// all streams when running in streams mode, and are never removed. Components
+// that share state which grows with the components using it should therefore
+// store a single value that releases that state once it is no longer used.
+func (r *Resources) GetOrSetGeneric(key, value any) (actual any, loaded bool) {
+	return r.mgr.GetOrSetGeneric(key, value)
+}
+
+// SetGeneric stores a generic value under a key, which is shared by all
+// components of these resources.
+func (r *Resources) SetGeneric(key, value any) {
+	r.mgr.SetGeneric(key, value)
+}
+
 //------------------------------------------------------------------------------
 
 type resourcesUnwrapper struct {